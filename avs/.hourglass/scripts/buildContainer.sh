@@ -38,7 +38,7 @@ fi
 echo "Building container: $fullImage" >&2
 
 # Simple docker build
-docker build -t "$fullImage" . >&2
+docker build --build-arg VERSION="${VERSION:-dev}" -t "$fullImage" . >&2
 
 # Get the image ID
 IMAGE_ID=$(docker images --format "table {{.ID}}" --no-trunc "$fullImage" | tail -1)
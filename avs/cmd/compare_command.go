@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/najnomics/crosscow-avs/pkg/resultdiff"
+)
+
+const compareCommandUsage = `usage: yieldavs compare [flags] <result> <result> [<result>...]
+
+Compares the results several operators answered the same task with, field by field,
+to find the non-determinism failing a quorum. Each result is a file holding a result
+envelope as the performer answers it, compressed or not, or an ABI encoded result,
+raw or as 0x prefixed hex. Operators are named after their file, or as name=path.
+
+Numbers differing within --tolerance-bps or --tolerance match, and are listed apart.
+The trace and the operator, timestamp and signature of attestations are never
+compared, and --ignore leaves out more fields. The command exits 1 when the results
+differ on a field.
+
+flags:
+`
+
+// errResultsDiffer makes the compare command exit 1 after printing the report
+var errResultsDiffer = errors.New("the results differ")
+
+// compareOptions are the flags of the compare command
+type compareOptions struct {
+	files      []string
+	jsonOutput bool
+	opts       resultdiff.Options
+}
+
+// runCompareCommand runs the compare subcommand with args, the arguments after
+// "compare". The report is printed to stdout, and usage and errors to stderr. It returns
+// the exit code of the process.
+func runCompareCommand(args []string, stdout, stderr io.Writer) int {
+	opts := compareOptions{}
+	var ignore string
+	flags := flag.NewFlagSet("compare", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, compareCommandUsage)
+		flags.PrintDefaults()
+	}
+	flags.Float64Var(&opts.opts.ToleranceBps, "tolerance-bps", 0, "largest difference between numbers that match, in basis points of the largest")
+	flags.Float64Var(&opts.opts.Tolerance, "tolerance", 0, "largest absolute difference between numbers that match")
+	flags.StringVar(&ignore, "ignore", "", "comma separated paths of more fields to leave out, such as result.timestamp")
+	flags.BoolVar(&opts.jsonOutput, "json", false, "print the report as JSON")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	opts.files = flags.Args()
+	if len(opts.files) < 2 {
+		fmt.Fprintln(stderr, "Error: at least two results are needed")
+		flags.Usage()
+		return 2
+	}
+	if opts.opts.ToleranceBps < 0 || opts.opts.Tolerance < 0 {
+		fmt.Fprintln(stderr, "Error: tolerances must not be negative")
+		return 2
+	}
+	for _, path := range strings.Split(ignore, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			opts.opts.Ignore = append(opts.opts.Ignore, path)
+		}
+	}
+
+	if err := runCompare(opts, stdout); err != nil {
+		if !errors.Is(err, errResultsDiffer) {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+		}
+		return 1
+	}
+	return 0
+}
+
+// runCompare compares the results of opts and prints the report
+func runCompare(opts compareOptions, stdout io.Writer) error {
+	results := make([]resultdiff.Result, 0, len(opts.files))
+	for _, file := range opts.files {
+		operator, path := operatorFile(file)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read the result: %w", err)
+		}
+		results = append(results, resultdiff.Result{Operator: operator, Data: data})
+	}
+	report, err := resultdiff.Compare(results, opts.opts)
+	if err != nil {
+		return err
+	}
+	if err := printCompareReport(stdout, report, opts.jsonOutput); err != nil {
+		return err
+	}
+	if !report.Agree() {
+		return errResultsDiffer
+	}
+	return nil
+}
+
+// operatorFile splits a name=path argument, naming the operator of a bare path after
+// its file
+func operatorFile(arg string) (string, string) {
+	if name, path, ok := strings.Cut(arg, "="); ok && name != "" {
+		return name, path
+	}
+	return strings.TrimSuffix(filepath.Base(arg), filepath.Ext(arg)), arg
+}
+
+// printCompareReport writes report as a table of the differing fields, or as JSON
+func printCompareReport(w io.Writer, report *resultdiff.Report, asJSON bool) error {
+	if asJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+	fmt.Fprintf(w, "%d fields compared across %s, %d differ, %d within tolerance\n",
+		report.Fields, strings.Join(report.Operators, ", "), len(report.Differences), len(report.Tolerated))
+	for _, section := range []struct {
+		title       string
+		differences []resultdiff.Difference
+	}{
+		{"differences", report.Differences},
+		{"within tolerance", report.Tolerated},
+	} {
+		if len(section.differences) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s:\n", section.title)
+		table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(table, "FIELD\t"+strings.Join(report.Operators, "\t"))
+		for _, difference := range section.differences {
+			row := []string{difference.Path}
+			for _, operator := range report.Operators {
+				value, ok := difference.Values[operator]
+				if !ok {
+					value = "(missing)"
+				}
+				row = append(row, value)
+			}
+			fmt.Fprintln(table, strings.Join(row, "\t"))
+		}
+		if err := table.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/najnomics/crosscow-avs/pkg/resultdiff"
+)
+
+func writeResult(t *testing.T, dir, name, data string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	return path
+}
+
+func Test_CompareCommandExitCodes(t *testing.T) {
+	dir := t.TempDir()
+	a := writeResult(t, dir, "a.json", `{"result":{"supply_rate":"4.5000","best":"aave_v3"}}`)
+	b := writeResult(t, dir, "b.json", `{"result":{"supply_rate":"4.5002","best":"aave_v3"}}`)
+	testCases := map[string]struct {
+		args   []string
+		code   int
+		stdout string
+		stderr string
+	}{
+		"one result":         {args: []string{a}, code: 2, stderr: "at least two results"},
+		"negative tolerance": {args: []string{"--tolerance-bps", "-1", a, b}, code: 2, stderr: "must not be negative"},
+		"missing file":       {args: []string{a, filepath.Join(dir, "c.json")}, code: 1, stderr: "failed to read the result"},
+		"differ":             {args: []string{a, b}, code: 1, stdout: "result.supply_rate  \"4.5000\"  \"4.5002\""},
+		"within tolerance":   {args: []string{"--tolerance-bps", "1", "first=" + a, b}, code: 0, stdout: "FIELD               first     b"},
+		"ignored":            {args: []string{"--ignore", "result.supply_rate", a, b}, code: 0, stdout: "1 fields compared across a, b, 0 differ"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := runCompareCommand(tc.args, &stdout, &stderr); code != tc.code {
+				t.Errorf("Expected exit code %d, got %d: %s", tc.code, code, stderr.String())
+			}
+			if !strings.Contains(stdout.String(), tc.stdout) {
+				t.Errorf("Expected %q on stdout, got %q", tc.stdout, stdout.String())
+			}
+			if !strings.Contains(stderr.String(), tc.stderr) {
+				t.Errorf("Expected %q on stderr, got %q", tc.stderr, stderr.String())
+			}
+		})
+	}
+}
+
+func Test_CompareCommandJSON(t *testing.T) {
+	dir := t.TempDir()
+	a := writeResult(t, dir, "a.json", `{"result":{"best":"aave_v3"}}`)
+	b := writeResult(t, dir, "b.json", `{"result":{"best":"morpho"}}`)
+	var stdout, stderr bytes.Buffer
+	if code := runCompareCommand([]string{"--json", a, b}, &stdout, &stderr); code != 1 {
+		t.Fatalf("Expected the results to differ, got %d: %s", code, stderr.String())
+	}
+	var report resultdiff.Report
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatalf("Expected a JSON report, got %q: %v", stdout.String(), err)
+	}
+	if len(report.Differences) != 1 || report.Differences[0].Values["b"] != `"morpho"` {
+		t.Errorf("Unexpected report %+v", report)
+	}
+}
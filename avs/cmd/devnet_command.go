@@ -0,0 +1,312 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/client"
+	"github.com/najnomics/crosscow-avs/pkg/config"
+	"github.com/najnomics/crosscow-avs/pkg/devnet"
+	"github.com/najnomics/crosscow-avs/pkg/grpcserver"
+	"github.com/najnomics/crosscow-avs/pkg/logging"
+	"github.com/najnomics/crosscow-avs/pkg/resultdiff"
+	"github.com/najnomics/crosscow-avs/pkg/tokens"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const devnetCommandUsage = `usage: yieldavs devnet <command> [flags]
+
+Drives the local stack of the devnet directory. The chains of the config are Anvil
+forks, which the stack seeds and runs tasks against.
+
+commands:
+  seed       funds --user with ETH and USDC on every chain of the config
+  aggregate  --targets <name=host:port,...>
+             sends the tasks of a full lifecycle to the performers every --interval, as
+             the aggregator and executors would, and compares their results
+
+The lifecycle reads capabilities, rates, rankings and risk, checks moving funds between
+the first two chains of the config and dry-runs a rebalance of --user on the first one.
+With --rounds, aggregate exits 1 when a performer failed a task or the performers
+disagreed on a result.
+`
+
+// errRoundFailed makes the aggregate command exit 1 after printing its rounds
+var errRoundFailed = errors.New("a round failed")
+
+// devnetOptions are the flags of the devnet commands. Each command reads the ones it
+// needs.
+type devnetOptions struct {
+	configPath string
+	user       string
+	eth        string
+	usdc       string
+	timeout    time.Duration
+
+	targets      string
+	interval     time.Duration
+	rounds       int
+	taskTimeout  time.Duration
+	toleranceBps float64
+	tokenEnv     string
+	jsonOutput   bool
+}
+
+// runDevnetCommand runs the devnet subcommand with args, the arguments after "devnet".
+// Reports are printed to stdout, and usage, progress and errors to stderr. It returns
+// the exit code of the process.
+func runDevnetCommand(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || (args[0] != "seed" && args[0] != "aggregate") {
+		fmt.Fprint(stderr, devnetCommandUsage)
+		return 2
+	}
+	name := args[0]
+	opts := devnetOptions{}
+	flags := flag.NewFlagSet("devnet "+name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, devnetCommandUsage)
+		fmt.Fprintf(stderr, "\nflags of %s:\n", name)
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&opts.configPath, "config", os.Getenv("PERFORMER_CONFIG"), "path to the performer YAML config file")
+	flags.StringVar(&opts.user, "user", devnet.DefaultUser.Hex(), "account the stack is seeded with and rebalances for")
+	if name == "seed" {
+		seed := devnet.DefaultSeed()
+		flags.StringVar(&opts.eth, "eth", seed.ETH.String(), "ETH to fund --user with, in wei")
+		flags.StringVar(&opts.usdc, "usdc", seed.USDC.String(), "USDC to fund --user with, in base units")
+		flags.DurationVar(&opts.timeout, "timeout", 2*time.Minute, "how long to wait for the chains to answer")
+	} else {
+		flags.StringVar(&opts.targets, "targets", "", "comma separated gRPC addresses of the performers, as name=host:port or host:port")
+		flags.DurationVar(&opts.interval, "interval", time.Minute, "time between the starts of rounds")
+		flags.IntVar(&opts.rounds, "rounds", 0, "rounds to run, 0 to run until interrupted")
+		flags.DurationVar(&opts.taskTimeout, "task-timeout", time.Minute, "timeout of each task")
+		flags.Float64Var(&opts.toleranceBps, "tolerance-bps", 0, "largest difference between numbers of results that match, in basis points of the largest")
+		flags.StringVar(&opts.tokenEnv, "token-env", "", "variable holding the bearer token of the gRPC servers, for performers requiring one")
+		flags.BoolVar(&opts.jsonOutput, "json", false, "print the reports of rounds as JSON lines")
+	}
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if err := opts.check(name); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		flags.Usage()
+		return 2
+	}
+
+	cfg, err := config.Load(opts.configPath)
+	if err == nil && len(cfg.Chains) == 0 {
+		err = fmt.Errorf("the config has no chains")
+	}
+	if err == nil {
+		if name == "seed" {
+			err = seedDevnet(ctx, cfg, opts, stdout)
+		} else {
+			err = aggregateDevnet(ctx, cfg, opts, stdout, stderr)
+		}
+	}
+	if err != nil {
+		if !errors.Is(err, errRoundFailed) {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+		}
+		return 1
+	}
+	return 0
+}
+
+// check rejects flags command cannot run with
+func (o devnetOptions) check(command string) error {
+	if !common.IsHexAddress(o.user) {
+		return fmt.Errorf("--user must be an address")
+	}
+	if command == "aggregate" {
+		if strings.TrimSpace(o.targets) == "" {
+			return fmt.Errorf("--targets is required")
+		}
+		if o.interval <= 0 || o.taskTimeout <= 0 {
+			return fmt.Errorf("--interval and --task-timeout must be positive")
+		}
+		if o.rounds < 0 || o.toleranceBps < 0 {
+			return fmt.Errorf("--rounds and --tolerance-bps must not be negative")
+		}
+	}
+	return nil
+}
+
+// seedDevnet funds the user of opts on every chain of cfg
+func seedDevnet(ctx context.Context, cfg *config.Config, opts devnetOptions, stdout io.Writer) error {
+	seed := devnet.Seed{User: common.HexToAddress(opts.user)}
+	var err error
+	if seed.ETH, err = tokens.ParseAmount(opts.eth); err != nil {
+		return fmt.Errorf("invalid --eth: %w", err)
+	}
+	if seed.USDC, err = tokens.ParseAmount(opts.usdc); err != nil {
+		return fmt.Errorf("invalid --usdc: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.timeout)
+	defer cancel()
+	for _, ch := range cfg.Chains {
+		if err := devnet.SeedChain(ctx, ch, seed); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "Seeded %s on chain %d with %s wei and %s USDC base units\n", seed.User.Hex(), ch.ChainID, opts.eth, opts.usdc)
+	}
+	return nil
+}
+
+// aggregateDevnet runs rounds of the lifecycle against the performers of opts until the
+// rounds of opts ran or ctx is done
+func aggregateDevnet(ctx context.Context, cfg *config.Config, opts devnetOptions, stdout, stderr io.Writer) error {
+	chainIDs := make([]uint64, len(cfg.Chains))
+	for i, ch := range cfg.Chains {
+		chainIDs[i] = ch.ChainID
+	}
+	steps, err := devnet.Lifecycle(chainIDs, common.HexToAddress(opts.user))
+	if err != nil {
+		return err
+	}
+	performers, closeAll, err := dialDevnetPerformers(opts)
+	if err != nil {
+		return err
+	}
+	defer closeAll()
+
+	diffOpts := resultdiff.Options{ToleranceBps: opts.toleranceBps, Ignore: resultdiff.DefaultIgnore}
+	runID := "devnet-" + logging.NewCorrelationID()
+	failed := false
+	ticker := time.NewTicker(opts.interval)
+	defer ticker.Stop()
+	for round := 1; opts.rounds == 0 || round <= opts.rounds; round++ {
+		roundID := fmt.Sprintf("%s-%d", runID, round)
+		fmt.Fprintf(stderr, "Sending round %s to %d performers\n", roundID, len(performers))
+		reports, err := devnet.Round(ctx, performers, steps, roundID, opts.taskTimeout, diffOpts)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if err := printDevnetRound(stdout, roundID, reports, opts.jsonOutput); err != nil {
+			return err
+		}
+		if len(devnet.Failures(reports)) > 0 {
+			failed = true
+		}
+		if opts.rounds != 0 && round == opts.rounds {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+	if failed {
+		return errRoundFailed
+	}
+	return nil
+}
+
+// dialDevnetPerformers connects to the performers of opts, and returns a function
+// closing the connections
+func dialDevnetPerformers(opts devnetOptions) ([]devnet.Performer, func(), error) {
+	dialOpts := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	if opts.tokenEnv != "" {
+		token := os.Getenv(opts.tokenEnv)
+		if token == "" {
+			return nil, nil, fmt.Errorf("environment variable %s is not set", opts.tokenEnv)
+		}
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(grpcserver.TokenCredentials(token)))
+	}
+
+	var (
+		performers []devnet.Performer
+		conns      []*grpc.ClientConn
+	)
+	closeAll := func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}
+	seen := make(map[string]bool)
+	for _, target := range strings.Split(opts.targets, ",") {
+		target = strings.TrimSpace(target)
+		if target == "" {
+			continue
+		}
+		name, address, ok := strings.Cut(target, "=")
+		if !ok {
+			name, address = target, target
+		}
+		if seen[name] {
+			closeAll()
+			return nil, nil, fmt.Errorf("performer %s is listed twice", name)
+		}
+		seen[name] = true
+		conn, err := grpc.NewClient(address, dialOpts...)
+		if err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("failed to dial %s: %w", address, err)
+		}
+		conns = append(conns, conn)
+		performers = append(performers, devnet.Performer{Name: name, Client: client.New(conn)})
+	}
+	return performers, closeAll, nil
+}
+
+// printDevnetRound writes the reports of a round as a table of the answers and the fields
+// the performers disagree on, or as a JSON line
+func printDevnetRound(w io.Writer, roundID string, reports []devnet.StepReport, asJSON bool) error {
+	failed := devnet.Failures(reports)
+	if asJSON {
+		return json.NewEncoder(w).Encode(map[string]interface{}{"round": roundID, "steps": reports, "failed": failed})
+	}
+	fmt.Fprintf(w, "Round %s: %d steps, %d failed\n", roundID, len(reports), len(failed))
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "STEP\tPERFORMER\tLATENCY\tRESULT")
+	var differences []string
+	for _, report := range reports {
+		for _, answer := range report.Answers {
+			result := "ok"
+			if answer.Error != "" {
+				result = answer.Error
+			}
+			fmt.Fprintf(table, "%s\t%s\t%v\t%s\n", report.Step, answer.Performer, answer.Latency.Round(time.Millisecond), result)
+		}
+		for _, difference := range report.Differences {
+			performers := make([]string, 0, len(difference.Values))
+			for performer := range difference.Values {
+				performers = append(performers, performer)
+			}
+			sort.Strings(performers)
+			values := make([]string, len(performers))
+			for i, performer := range performers {
+				values[i] = performer + "=" + difference.Values[performer]
+			}
+			differences = append(differences, fmt.Sprintf("  %s %s: %s", report.Step, difference.Path, strings.Join(values, " ")))
+		}
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+	if len(differences) > 0 {
+		fmt.Fprintln(w, "\ndisagreements:")
+		for _, line := range differences {
+			fmt.Fprintln(w, line)
+		}
+	}
+	fmt.Fprintln(w)
+	return nil
+}
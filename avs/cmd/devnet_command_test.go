@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/najnomics/crosscow-avs/pkg/config"
+	"github.com/najnomics/crosscow-avs/pkg/loadgen"
+	"go.uber.org/zap"
+)
+
+// writeDevnetConfig writes a performer config reading chains 1 and 8453
+func writeDevnetConfig(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "performer.yaml")
+	config := `chains:
+  - chainId: 1
+    name: mainnet
+    rpcUrl: http://127.0.0.1:1
+  - chainId: 8453
+    name: base
+    rpcUrl: http://127.0.0.1:1
+`
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	return path
+}
+
+func Test_DevnetCommandExitCodes(t *testing.T) {
+	t.Setenv("PERFORMER_CONFIG", "")
+	config := writeDevnetConfig(t)
+
+	testCases := map[string]struct {
+		args   []string
+		code   int
+		stderr string
+	}{
+		"no command":      {args: nil, code: 2, stderr: "usage: yieldavs devnet"},
+		"unknown command": {args: []string{"deploy"}, code: 2, stderr: "usage: yieldavs devnet"},
+		"invalid user":    {args: []string{"seed", "--user", "alice"}, code: 2, stderr: "--user must be an address"},
+		"no targets":      {args: []string{"aggregate", "--config", config}, code: 2, stderr: "--targets is required"},
+		"no interval":     {args: []string{"aggregate", "--targets", "127.0.0.1:8080", "--interval", "0s"}, code: 2, stderr: "must be positive"},
+		"no chains":       {args: []string{"seed"}, code: 1, stderr: "the config has no chains"},
+		"invalid amount":  {args: []string{"seed", "--config", config, "--usdc", "1.5"}, code: 1, stderr: "invalid --usdc"},
+		"twice listed":    {args: []string{"aggregate", "--config", config, "--targets", "a=127.0.0.1:1,a=127.0.0.1:2"}, code: 1, stderr: "listed twice"},
+		"chain down":      {args: []string{"seed", "--config", config, "--timeout", "100ms"}, code: 1, stderr: "did not answer"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := runDevnetCommand(context.Background(), tc.args, &stdout, &stderr); code != tc.code {
+				t.Errorf("Expected exit code %d, got %d: %s", tc.code, code, stderr.String())
+			}
+			if !strings.Contains(stderr.String(), tc.stderr) {
+				t.Errorf("Expected %q on stderr, got %q", tc.stderr, stderr.String())
+			}
+		})
+	}
+}
+
+func Test_DevnetAggregateRunsRounds(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	target, err := loadgen.ServeStub(ctx, 0, zap.NewNop())
+	if err != nil {
+		t.Fatalf("ServeStub failed: %v", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"aggregate", "--config", writeDevnetConfig(t), "--targets", "stub=" + target, "--rounds", "2", "--interval", "1ms", "--json"}
+	code := runDevnetCommand(ctx, args, &stdout, &stderr)
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected a report per round, got %q: %s", stdout.String(), stderr.String())
+	}
+	var round struct {
+		Round string `json:"round"`
+		Steps []struct {
+			Step    string `json:"step"`
+			Answers []struct {
+				Performer string `json:"performer"`
+				Error     string `json:"error"`
+			} `json:"answers"`
+		} `json:"steps"`
+		Failed []string `json:"failed"`
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &round); err != nil {
+		t.Fatalf("Expected a JSON report, got %q: %v", lines[1], err)
+	}
+	if !strings.HasSuffix(round.Round, "-2") || len(round.Steps) != 8 || round.Steps[0].Answers[0].Performer != "stub" {
+		t.Errorf("Unexpected round %+v", round)
+	}
+	// the stub performer simulates nothing, so it answers every task but dry runs
+	if code != 1 || len(round.Failed) != 1 || round.Failed[0] != "rebalance-dry-run" {
+		t.Errorf("Expected the dry run alone to fail with exit code 1, got %v and %d: %s", round.Failed, code, stdout.String())
+	}
+	for _, step := range round.Steps {
+		if step.Step != "rebalance-dry-run" && step.Answers[0].Error != "" {
+			t.Errorf("Expected the stub to answer %s, got %s", step.Step, step.Answers[0].Error)
+		}
+	}
+}
+
+// Test_DevnetConfigLoads keeps the config of the devnet stack loadable as the performer
+// evolves
+func Test_DevnetConfigLoads(t *testing.T) {
+	cfg, err := config.Load(filepath.Join("..", "devnet", "performer.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to load the devnet config: %v", err)
+	}
+	if len(cfg.Chains) != 2 || !cfg.Redis.Enabled || !cfg.Admin.Enabled {
+		t.Errorf("Expected two forks, Redis and the admin API, got %+v, %v and %v", cfg.Chains, cfg.Redis.Enabled, cfg.Admin.Enabled)
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/najnomics/crosscow-avs/pkg/export"
+	"github.com/najnomics/crosscow-avs/pkg/transport"
+)
+
+const exportCommandUsage = `usage: yieldavs export <dataset> [flags]
+
+Downloads a dataset a performer recorded from its admin API, as CSV or Parquet:
+
+  yield_history  supply rate, utilization and share price history of every market
+  risk_scores    overall score of every risk assessment
+  executions     ledger of executed rebalances
+
+Rows are selected by time with --from and --to, and by market with --protocols and
+--chains. The file is written to --out, or stdout without it.
+
+flags:
+`
+
+// exportOptions are the flags of the export command
+type exportOptions struct {
+	dataset   string
+	url       string
+	tokenEnv  string
+	caFile    string
+	certFile  string
+	keyFile   string
+	format    string
+	from      string
+	to        string
+	protocols string
+	chains    string
+	out       string
+}
+
+// runExportCommand runs the export subcommand with args, the arguments after "export".
+// The file is written to stdout unless --out is set, and usage and errors to stderr. It
+// returns the exit code of the process.
+func runExportCommand(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Fprint(stderr, exportCommandUsage)
+		return 2
+	}
+	opts := exportOptions{dataset: args[0]}
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, exportCommandUsage)
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&opts.url, "url", "http://127.0.0.1:8091", "address of the admin API of the performer")
+	flags.StringVar(&opts.tokenEnv, "token-env", "", "variable holding the bearer token of the admin API, for performers requiring one")
+	flags.StringVar(&opts.caFile, "ca-file", "", "PEM authorities the certificate of an https admin API is verified against")
+	flags.StringVar(&opts.certFile, "cert-file", "", "PEM client certificate, for admin APIs requiring one")
+	flags.StringVar(&opts.keyFile, "key-file", "", "PEM key of --cert-file")
+	flags.StringVar(&opts.format, "format", export.FormatCSV, "format of the file, csv or parquet")
+	flags.StringVar(&opts.from, "from", "", "first time exported, as an RFC 3339 time or a date")
+	flags.StringVar(&opts.to, "to", "", "time the export ends before, as an RFC 3339 time or a date")
+	flags.StringVar(&opts.protocols, "protocols", "", "comma separated protocols exported, every protocol when empty")
+	flags.StringVar(&opts.chains, "chains", "", "comma separated chains exported, every chain when empty")
+	flags.StringVar(&opts.out, "out", "", "file to write, stdout when empty")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if !slices.Contains(export.Datasets, opts.dataset) {
+		fmt.Fprintf(stderr, "Error: unknown dataset %s, must be one of %s\n", opts.dataset, strings.Join(export.Datasets, ", "))
+		return 2
+	}
+
+	if err := runExport(ctx, opts, stdout); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// exportURL returns the admin API URL of the export of opts, checking its filter
+func exportURL(opts exportOptions) (string, error) {
+	if err := export.CheckFormat(opts.format); err != nil {
+		return "", err
+	}
+	query := url.Values{"from": {opts.from}, "to": {opts.to}, "protocol": {opts.protocols}, "chain_id": {opts.chains}}
+	filter, err := export.ParseFilter(query)
+	if err != nil {
+		return "", err
+	}
+	query = filter.Query()
+	query.Set("format", opts.format)
+	base, err := url.Parse(opts.url)
+	if err != nil || base.Host == "" {
+		return "", fmt.Errorf("invalid admin API address %q", opts.url)
+	}
+	return base.JoinPath("export", opts.dataset).String() + "?" + query.Encode(), nil
+}
+
+// runExport downloads the export of opts and writes it
+func runExport(ctx context.Context, opts exportOptions, stdout io.Writer) error {
+	target, err := exportURL(opts)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	if opts.tokenEnv != "" {
+		token := os.Getenv(opts.tokenEnv)
+		if token == "" {
+			return fmt.Errorf("environment variable %s is not set", opts.tokenEnv)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	httpClient := &http.Client{}
+	if opts.caFile != "" || opts.certFile != "" {
+		tlsConfig, err := transport.ClientConfig(opts.caFile, opts.certFile, opts.keyFile)
+		if err != nil {
+			return err
+		}
+		httpClient.Transport = &http.Transport{TLSClientConfig: tlsConfig}
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach the admin API: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var failed struct {
+			Error string `json:"error"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&failed) == nil && failed.Error != "" {
+			return fmt.Errorf("export failed: %s", failed.Error)
+		}
+		return fmt.Errorf("export failed: %s", resp.Status)
+	}
+
+	if opts.out == "" {
+		_, err = io.Copy(stdout, resp.Body)
+		return err
+	}
+	f, err := os.Create(opts.out)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s: %w", opts.out, err)
+	}
+	return f.Close()
+}
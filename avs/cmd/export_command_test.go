@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_ExportCommandExitCodes(t *testing.T) {
+	testCases := map[string]struct {
+		args   []string
+		code   int
+		stderr string
+	}{
+		"no dataset":      {args: nil, code: 2, stderr: "usage: yieldavs export"},
+		"flags first":     {args: []string{"--format", "csv"}, code: 2, stderr: "usage: yieldavs export"},
+		"unknown dataset": {args: []string{"balances"}, code: 2, stderr: "unknown dataset balances"},
+		"unknown format":  {args: []string{"executions", "--format", "json"}, code: 1, stderr: "unknown format"},
+		"invalid from":    {args: []string{"executions", "--from", "yesterday"}, code: 1, stderr: "invalid from"},
+		"missing token":   {args: []string{"executions", "--token-env", "EXPORT_TEST_UNSET"}, code: 1, stderr: "EXPORT_TEST_UNSET is not set"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := runExportCommand(context.Background(), tc.args, &stdout, &stderr); code != tc.code {
+				t.Errorf("Expected exit code %d, got %d: %s", tc.code, code, stderr.String())
+			}
+			if !strings.Contains(stderr.String(), tc.stderr) {
+				t.Errorf("Expected %q on stderr, got %q", tc.stderr, stderr.String())
+			}
+		})
+	}
+}
+
+func Test_ExportCommandDownloads(t *testing.T) {
+	var query string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/export/yield_history" || r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		query = r.URL.RawQuery
+		w.Write([]byte("time,protocol\n"))
+	}))
+	defer ts.Close()
+	t.Setenv("EXPORT_TEST_TOKEN", "secret")
+
+	out := filepath.Join(t.TempDir(), "history.csv")
+	args := []string{"yield_history", "--url", ts.URL, "--token-env", "EXPORT_TEST_TOKEN", "--from", "2026-03-01", "--chains", "1,8453", "--out", out}
+	var stdout, stderr bytes.Buffer
+	if code := runExportCommand(context.Background(), args, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected the export to succeed, got %d: %s", code, stderr.String())
+	}
+	if want := "chain_id=1%2C8453&format=csv&from=2026-03-01T00%3A00%3A00Z"; query != want {
+		t.Errorf("Expected query %s, got %s", want, query)
+	}
+	if data, err := os.ReadFile(out); err != nil || string(data) != "time,protocol\n" {
+		t.Errorf("Unexpected file %q: %v", data, err)
+	}
+
+	stderr.Reset()
+	args = []string{"risk_scores", "--url", ts.URL}
+	if code := runExportCommand(context.Background(), args, &stdout, &stderr); code != 1 || !strings.Contains(stderr.String(), "404") {
+		t.Errorf("Expected the failed export to be reported, got %d: %s", code, stderr.String())
+	}
+}
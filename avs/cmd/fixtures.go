@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+
+	"github.com/najnomics/crosscow-avs/pkg/config"
+	"github.com/najnomics/crosscow-avs/pkg/fixture"
+)
+
+// fixtureFlags select simulation mode or fixture recording on the command line, over the
+// fixtures section of the config
+type fixtureFlags struct {
+	simulation *bool
+	record     *bool
+	path       *string
+}
+
+func registerFixtureFlags(flags *flag.FlagSet) *fixtureFlags {
+	return &fixtureFlags{
+		simulation: flags.Bool("simulation", false, "serve every RPC and API call from the fixture file instead of calling out"),
+		record:     flags.Bool("record-fixtures", false, "record every RPC and API call to the fixture file"),
+		path:       flags.String("fixtures", "", "fixture file, overriding fixtures.path of the config"),
+	}
+}
+
+// apply sets the fixtures of cfg from the flags
+func (f *fixtureFlags) apply(cfg *config.Config) error {
+	if *f.simulation && *f.record {
+		return fmt.Errorf("--simulation and --record-fixtures cannot be combined")
+	}
+	if *f.simulation {
+		cfg.Fixtures.Mode = fixture.ModeReplay
+	}
+	if *f.record {
+		cfg.Fixtures.Mode = fixture.ModeRecord
+	}
+	if *f.path != "" {
+		cfg.Fixtures.Path = *f.path
+	}
+	if err := cfg.Fixtures.Validate(); err != nil {
+		return fmt.Errorf("fixtures: %w", err)
+	}
+	return nil
+}
+
+// fixtureAliases names the configured endpoints in fixtures, so recordings hold no API
+// keys of their URLs and replay against whatever URLs the simulation is configured with
+func fixtureAliases(cfg *config.Config) fixture.Aliases {
+	aliases := fixture.Aliases{}
+	for _, ch := range cfg.Chains {
+		aliases[ch.RpcUrl] = fmt.Sprintf("chain-%d", ch.ChainID)
+		if ch.ArchiveRpcUrl != "" {
+			aliases[ch.ArchiveRpcUrl] = fmt.Sprintf("chain-%d-archive", ch.ChainID)
+		}
+	}
+	for _, endpoint := range cfg.Subgraphs {
+		aliases[endpoint.Url] = fmt.Sprintf("subgraph-%s-%d", endpoint.Protocol, endpoint.ChainID)
+	}
+	if cfg.Circle != nil {
+		aliases[cfg.Circle.ApiBaseUrl] = "circle"
+	}
+	return aliases
+}
+
+// setupFixtures installs the fixture transport of cfg as http.DefaultTransport, which
+// the RPC and API clients send through. WebSocket subscriptions cannot be recorded, so
+// with fixtures consumers poll RpcUrl instead. Which calls share a Multicall3 batch
+// depends on timing, so calls are not batched either. The returned function saves
+// recordings.
+func setupFixtures(cfg *config.Config) (func() error, error) {
+	if cfg.Fixtures.Mode == "" {
+		return func() error { return nil }, nil
+	}
+	for i := range cfg.Chains {
+		cfg.Chains[i].WsUrl = ""
+	}
+	cfg.Multicall.Enabled = false
+
+	aliases := fixtureAliases(cfg)
+	if cfg.Fixtures.Mode == fixture.ModeRecord {
+		recorder := fixture.NewRecorder(http.DefaultTransport, aliases)
+		http.DefaultTransport = recorder
+		return func() error { return recorder.Save(cfg.Fixtures.Path) }, nil
+	}
+
+	set, err := fixture.Load(cfg.Fixtures.Path)
+	if err != nil {
+		return nil, err
+	}
+	http.DefaultTransport = fixture.NewReplayer(set, aliases)
+	return func() error { return nil }, nil
+}
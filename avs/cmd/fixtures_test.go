@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/config"
+	"github.com/najnomics/crosscow-avs/pkg/fixture"
+)
+
+// connect dials the chains of cfg through the fixtures of cfg and checks chain 1
+func connect(t *testing.T, cfg *config.Config) func() error {
+	t.Helper()
+	transport := http.DefaultTransport
+	t.Cleanup(func() { http.DefaultTransport = transport })
+
+	save, err := setupFixtures(cfg)
+	if err != nil {
+		t.Fatalf("setupFixtures failed: %v", err)
+	}
+	chains, err := chain.NewManagerFromConfig(context.Background(), cfg.Chains)
+	if err != nil {
+		t.Fatalf("NewManagerFromConfig failed: %v", err)
+	}
+	defer chains.Close()
+	for i := 0; i < 2; i++ {
+		if err := chains.CheckConnectivity(context.Background(), 1); err != nil {
+			t.Fatalf("CheckConnectivity failed: %v", err)
+		}
+	}
+	return save
+}
+
+func Test_SimulationReplaysRecordedCalls(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			ID json.RawMessage `json:"id"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":"0x1"}`, request.ID)
+	}))
+	path := filepath.Join(t.TempDir(), "fixtures.json")
+
+	recording := config.Default()
+	recording.Chains = []chain.Config{{ChainID: 1, Name: "ethereum", RpcUrl: server.URL + "/v2/secret-key", WsUrl: "wss://example.invalid"}}
+	recording.Fixtures = fixture.Config{Mode: fixture.ModeRecord, Path: path}
+	if err := connect(t, recording)(); err != nil {
+		t.Fatalf("Failed to save fixtures: %v", err)
+	}
+	server.Close()
+	if recording.Chains[0].WsUrl != "" {
+		t.Error("Expected subscriptions to be disabled while recording")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Expected fixtures to be saved: %v", err)
+	}
+	if bytes.Contains(data, []byte("secret-key")) || !bytes.Contains(data, []byte(`"chain-1"`)) {
+		t.Errorf("Expected the RPC URL to be recorded by its alias, got %s", data)
+	}
+
+	// the recording server is gone, and the simulation is configured without a key
+	simulation := config.Default()
+	simulation.Chains = []chain.Config{{ChainID: 1, Name: "ethereum", RpcUrl: "http://simulated.invalid"}}
+	simulation.Fixtures = fixture.Config{Mode: fixture.ModeReplay, Path: path}
+	connect(t, simulation)
+}
+
+func Test_FixtureFlags(t *testing.T) {
+	testCases := map[string]struct {
+		args []string
+		mode string
+		err  string
+	}{
+		"none":        {args: nil},
+		"simulation":  {args: []string{"--simulation", "--fixtures", "f.json"}, mode: fixture.ModeReplay},
+		"record":      {args: []string{"--record-fixtures", "--fixtures", "f.json"}, mode: fixture.ModeRecord},
+		"both":        {args: []string{"--simulation", "--record-fixtures", "--fixtures", "f.json"}, err: "cannot be combined"},
+		"no fixtures": {args: []string{"--simulation"}, err: "path is required"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			flags := flag.NewFlagSet(name, flag.ContinueOnError)
+			fixtures := registerFixtureFlags(flags)
+			if err := flags.Parse(tc.args); err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			cfg := config.Default()
+			err := fixtures.apply(cfg)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Errorf("Expected %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil || cfg.Fixtures.Mode != tc.mode {
+				t.Errorf("Expected mode %q, got %q (%v)", tc.mode, cfg.Fixtures.Mode, err)
+			}
+		})
+	}
+}
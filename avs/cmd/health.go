@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/circle"
+	"github.com/najnomics/crosscow-avs/pkg/config"
+	"github.com/najnomics/crosscow-avs/pkg/health"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+)
+
+// registerHealthChecks wires the readiness of every upstream dependency into registry.
+// A performer with a broken dependency reports not-ready instead of serving bad results.
+func registerHealthChecks(registry *health.Registry, cfg *config.Config, kv store.KV, chains *chain.Manager, circleClient *circle.Client) {
+	registry.AddLivenessCheck(health.NewChecker("process", func(ctx context.Context) error {
+		return nil
+	}))
+
+	registry.AddReadinessCheck(health.NewChecker("config", func(ctx context.Context) error {
+		return cfg.Validate()
+	}))
+
+	registry.AddReadinessCheck(health.NewChecker("store", func(ctx context.Context) error {
+		_, err := kv.Get(ctx, []byte("health:probe"))
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			return err
+		}
+		return nil
+	}))
+
+	for _, chainID := range chains.ChainIDs() {
+		chainID := chainID
+		registry.AddReadinessCheck(health.NewChecker(fmt.Sprintf("chain:%s", chains.Name(chainID)), func(ctx context.Context) error {
+			return chains.CheckConnectivity(ctx, chainID)
+		}))
+	}
+
+	if circleClient != nil {
+		registry.AddReadinessCheck(health.NewChecker("circle_api", circleClient.Ping))
+	}
+}
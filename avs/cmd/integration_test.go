@@ -0,0 +1,174 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"testing"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/anviltest"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/config"
+	"github.com/najnomics/crosscow-avs/pkg/devnet"
+	"github.com/najnomics/crosscow-avs/pkg/performer"
+	"go.uber.org/zap/zaptest"
+)
+
+// Integration tests run the handlers against Anvil forks of live chains:
+//
+//	MAINNET_RPC_URL=... BASE_RPC_URL=... ARBITRUM_RPC_URL=... go test -tags integration ./cmd -run Integration
+
+var forkedChains = []struct {
+	chainID uint64
+	name    string
+}{
+	{adapters.ChainIDEthereum, "MAINNET"},
+	{adapters.ChainIDBase, "BASE"},
+	{adapters.ChainIDArbitrum, "ARBITRUM"},
+}
+
+// newForkedPerformer creates a performer reading the forks, configured like the
+// performer server
+func newForkedPerformer(t *testing.T, forks ...*anviltest.Fork) *performer.YieldIntelligencePerformer {
+	t.Helper()
+	cfg := config.Default()
+	for _, fork := range forks {
+		cfg.Chains = append(cfg.Chains, chain.Config{ChainID: fork.ChainID, Name: fmt.Sprintf("fork-%d", fork.ChainID), RpcUrl: fork.URL})
+	}
+	logger := zaptest.NewLogger(t)
+	svc, err := openServices(context.Background(), cfg, logger)
+	t.Cleanup(func() { svc.close(logger) })
+	if err != nil {
+		t.Fatalf("Failed to open services: %v", err)
+	}
+	return svc.performer(cfg, logger)
+}
+
+// runForkedTask validates and handles a task, and returns its result
+func runForkedTask(t *testing.T, yip *performer.YieldIntelligencePerformer, id string, taskType performer.TaskType, params map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	payload, err := json.Marshal(map[string]interface{}{"type": taskType, "parameters": params})
+	if err != nil {
+		t.Fatalf("Failed to encode task: %v", err)
+	}
+	task := &performerV1.TaskRequest{TaskId: []byte(id), Payload: payload}
+	if err := yip.ValidateTask(task); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	resp, err := yip.HandleTask(task)
+	if err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+	var envelope struct {
+		Result map[string]interface{} `json:"result"`
+	}
+	if err := json.Unmarshal(resp.Result, &envelope); err != nil {
+		t.Fatalf("Failed to decode result: %v", err)
+	}
+	if envelope.Result["status"] != string(performer.ResultStatusCompleted) {
+		t.Fatalf("Expected the task to complete, got %s", resp.Result)
+	}
+	return envelope.Result
+}
+
+// decimal reads a canonical decimal of a result
+func decimal(t *testing.T, result map[string]interface{}, key string) float64 {
+	t.Helper()
+	text, ok := result[key].(string)
+	if !ok {
+		t.Fatalf("Expected %s to be a decimal, got %v", key, result[key])
+	}
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		t.Fatalf("Expected %s to be a decimal, got %q", key, text)
+	}
+	return value
+}
+
+func Test_IntegrationYieldMonitoring(t *testing.T) {
+	for _, forked := range forkedChains {
+		t.Run(forked.name, func(t *testing.T) {
+			fork := anviltest.Start(t, forked.chainID, forked.name)
+			yip := newForkedPerformer(t, fork)
+
+			for _, protocol := range []string{"aave_v3", "compound_v3"} {
+				result := runForkedTask(t, yip, "yield-"+protocol, performer.TaskTypeYieldMonitoring, map[string]interface{}{
+					"protocol": protocol, "token": "USDC", "chain_id": forked.chainID,
+				})
+				if rate := decimal(t, result, "supply_rate"); rate <= 0 || rate >= 100 {
+					t.Errorf("Expected a plausible %s supply rate, got %v%%", protocol, rate)
+				}
+				if utilization := decimal(t, result, "utilization"); utilization <= 0 || utilization > 1 {
+					t.Errorf("Expected %s utilization between 0 and 1, got %v", protocol, utilization)
+				}
+				if supply := decimal(t, result, "total_supply"); supply < 1_000_000 {
+					t.Errorf("Expected over a million USDC supplied to %s, got %v", protocol, supply)
+				}
+			}
+		})
+	}
+}
+
+func Test_IntegrationRiskAssessment(t *testing.T) {
+	fork := anviltest.Start(t, adapters.ChainIDEthereum, "MAINNET")
+	yip := newForkedPerformer(t, fork)
+
+	for _, protocol := range []string{"aave_v3", "compound_v3"} {
+		result := runForkedTask(t, yip, "risk-"+protocol, performer.TaskTypeRiskAssessment, map[string]interface{}{
+			"protocol": protocol, "chain_id": adapters.ChainIDEthereum, "assessment_type": "full",
+		})
+		report, ok := result["report"].(map[string]interface{})
+		if !ok {
+			t.Fatalf("Expected a %s risk report, got %v", protocol, result)
+		}
+		if score := decimal(t, report, "overall_score"); score < 0 || score > 100 {
+			t.Errorf("Expected a %s score from 0 to 100, got %v", protocol, score)
+		}
+		if report["paused"] != false {
+			t.Errorf("Expected the %s market to be live, got %v", protocol, report["paused"])
+		}
+	}
+}
+
+func Test_IntegrationDryRunRebalance(t *testing.T) {
+	fork := anviltest.Start(t, adapters.ChainIDEthereum, "MAINNET")
+	yip := newForkedPerformer(t, fork)
+
+	user := common.HexToAddress("0x00000000000000000000000000000000000a11ce")
+	usdc, err := adapters.USDCAddress(adapters.ChainIDEthereum)
+	if err != nil {
+		t.Fatalf("USDCAddress failed: %v", err)
+	}
+	fork.SetBalance(t, user, big.NewInt(1e18))
+	fork.SetERC20Balance(t, usdc, user, devnet.USDCBalancesSlot, big.NewInt(10_000_000_000))
+
+	result := runForkedTask(t, yip, "dry-run", performer.TaskTypeRebalanceExecution, map[string]interface{}{
+		"user_address": user.Hex(), "amount": "1000000000", "target_protocol": "aave_v3", "target_chain": adapters.ChainIDEthereum, "dry_run": true,
+	})
+	simulation, ok := result["simulation"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected a simulation, got %v", result)
+	}
+	if simulation["feasible"] != true {
+		t.Errorf("Expected the rebalance to be feasible, got %v", simulation)
+	}
+	steps, _ := simulation["steps"].([]interface{})
+	if len(steps) == 0 {
+		t.Fatalf("Expected simulated steps, got %v", simulation)
+	}
+	for _, step := range steps {
+		if step.(map[string]interface{})["reverted"] == true {
+			t.Errorf("Expected no step to revert, got %v", step)
+		}
+	}
+	if rate := decimal(t, simulation, "target_rate"); rate <= 0 {
+		t.Errorf("Expected a positive target rate, got %v", rate)
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/najnomics/crosscow-avs/pkg/client"
+	"github.com/najnomics/crosscow-avs/pkg/grpcserver"
+	"github.com/najnomics/crosscow-avs/pkg/loadgen"
+	"github.com/najnomics/crosscow-avs/pkg/logging"
+	"github.com/najnomics/crosscow-avs/pkg/transport"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const loadCommandUsage = `usage: yieldavs load (--target <host:port> | --stub) [flags]
+
+Fires tasks of mixed types at the Ponos server of a performer, --concurrency at a time,
+until --tasks were sent or --duration elapsed, and reports the throughput and latency
+percentiles of the performer. With --stub, the performer is served in process from
+stub adapters answering after --stub-latency, which measures the performer and its
+gRPC server alone.
+
+Task ids are prefixed with --run-id, which must differ between runs against one
+performer. The command exits 1 when the run breaks a --max-* or --min-* threshold.
+
+flags:
+`
+
+// errThresholdBroken makes the load command exit 1 after printing the report
+var errThresholdBroken = errors.New("the run broke a threshold")
+
+// loadOptions are the flags of the load command
+type loadOptions struct {
+	target         string
+	stub           bool
+	stubLatency    time.Duration
+	mixPath        string
+	privateKeyEnv  string
+	tokenEnv       string
+	tls            bool
+	caFile         string
+	certFile       string
+	keyFile        string
+	jsonOutput     bool
+	maxP99         time.Duration
+	minThroughput  float64
+	maxFailureRate float64
+	cfg            loadgen.Config
+}
+
+// runLoadCommand runs the load subcommand with args, the arguments after "load". The
+// report is printed to stdout, and usage and errors to stderr. It returns the exit code
+// of the process.
+func runLoadCommand(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	opts := loadOptions{}
+	flags := flag.NewFlagSet("load", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, loadCommandUsage)
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&opts.target, "target", "", "gRPC address of the performer")
+	flags.BoolVar(&opts.stub, "stub", false, "serve a performer with stub adapters in process and load it")
+	flags.DurationVar(&opts.stubLatency, "stub-latency", 20*time.Millisecond, "latency of each market read of the stub adapters")
+	flags.StringVar(&opts.mixPath, "mix", "", "JSON file of the payloads to send and their weights, a read-heavy mix by default")
+	flags.StringVar(&opts.privateKeyEnv, "private-key-env", "", "variable holding the hex key to sign payloads with, for performers requiring signed tasks")
+	flags.StringVar(&opts.tokenEnv, "token-env", "", "variable holding the bearer token of the gRPC server, for performers requiring one")
+	flags.BoolVar(&opts.tls, "tls", false, "connect over TLS, trusting the system authorities unless --ca-file is set")
+	flags.StringVar(&opts.caFile, "ca-file", "", "PEM authorities the certificate of the performer is verified against, implies --tls")
+	flags.StringVar(&opts.certFile, "cert-file", "", "PEM client certificate, for performers requiring one, implies --tls")
+	flags.StringVar(&opts.keyFile, "key-file", "", "PEM key of --cert-file")
+	flags.IntVar(&opts.cfg.Concurrency, "concurrency", 64, "tasks in flight at once")
+	flags.IntVar(&opts.cfg.Tasks, "tasks", 1000, "tasks to send, 0 to send until --duration elapsed")
+	flags.DurationVar(&opts.cfg.Duration, "duration", 0, "how long to send tasks for, 0 to send --tasks")
+	flags.DurationVar(&opts.cfg.Timeout, "timeout", 30*time.Second, "timeout of each task")
+	flags.StringVar(&opts.cfg.RunID, "run-id", "", "prefix of the task ids, random when empty")
+	flags.BoolVar(&opts.jsonOutput, "json", false, "print the report as JSON")
+	flags.DurationVar(&opts.maxP99, "max-p99", 0, "fail when the p99 latency exceeds it, 0 for no limit")
+	flags.Float64Var(&opts.minThroughput, "min-throughput", 0, "fail when fewer tasks per second were answered, 0 for no limit")
+	flags.Float64Var(&opts.maxFailureRate, "max-failure-rate", 0, "fail when a larger share of tasks failed, from 0 to 1")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if (opts.target == "") == !opts.stub {
+		fmt.Fprintln(stderr, "Error: one of --target and --stub is required")
+		flags.Usage()
+		return 2
+	}
+
+	if err := runLoad(ctx, opts, stdout, stderr); err != nil {
+		if !errors.Is(err, errThresholdBroken) {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+		}
+		return 1
+	}
+	return 0
+}
+
+// runLoad runs the load of opts and prints its report
+func runLoad(ctx context.Context, opts loadOptions, stdout, stderr io.Writer) error {
+	opts.cfg.Mix = loadgen.DefaultMix()
+	if opts.mixPath != "" {
+		data, err := os.ReadFile(opts.mixPath)
+		if err != nil {
+			return fmt.Errorf("failed to read the mix: %w", err)
+		}
+		if opts.cfg.Mix, err = loadgen.ParseMix(data); err != nil {
+			return err
+		}
+	}
+	if opts.cfg.RunID == "" {
+		opts.cfg.RunID = "load-" + logging.NewCorrelationID()
+	}
+	if err := opts.cfg.Validate(); err != nil {
+		return err
+	}
+	var clientOpts []client.ClientOption
+	if opts.privateKeyEnv != "" {
+		key, err := crypto.HexToECDSA(strings.TrimPrefix(strings.TrimSpace(os.Getenv(opts.privateKeyEnv)), "0x"))
+		if err != nil {
+			return fmt.Errorf("invalid private key in %s: %w", opts.privateKeyEnv, err)
+		}
+		clientOpts = append(clientOpts, client.WithSigningKey(key))
+	}
+
+	target := opts.target
+	if opts.stub {
+		stubCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		var err error
+		// the server logs every call at info, which would drown the report
+		if target, err = loadgen.ServeStub(stubCtx, opts.stubLatency, zap.NewNop()); err != nil {
+			return fmt.Errorf("failed to serve the stub performer: %w", err)
+		}
+	}
+	dialOpts, err := loadDialOptions(opts)
+	if err != nil {
+		return err
+	}
+	conn, err := grpc.NewClient(target, dialOpts...)
+	if err != nil {
+		return fmt.Errorf("failed to dial %s: %w", target, err)
+	}
+	defer conn.Close()
+
+	fmt.Fprintf(stderr, "Sending tasks to %s, %d at a time\n", target, opts.cfg.Concurrency)
+	report, err := loadgen.Run(ctx, client.New(conn, clientOpts...), opts.cfg)
+	if err != nil {
+		return err
+	}
+	if err := printLoadReport(stdout, report, opts.jsonOutput); err != nil {
+		return err
+	}
+	return checkLoadThresholds(report, opts, stderr)
+}
+
+// loadDialOptions returns the credentials the performer of opts is dialed with
+func loadDialOptions(opts loadOptions) ([]grpc.DialOption, error) {
+	creds := insecure.NewCredentials()
+	if opts.tls || opts.caFile != "" || opts.certFile != "" {
+		tlsConfig, err := transport.ClientConfig(opts.caFile, opts.certFile, opts.keyFile)
+		if err != nil {
+			return nil, err
+		}
+		creds = credentials.NewTLS(tlsConfig)
+	}
+	dialOpts := []grpc.DialOption{grpc.WithTransportCredentials(creds)}
+	if opts.tokenEnv != "" {
+		token := os.Getenv(opts.tokenEnv)
+		if token == "" {
+			return nil, fmt.Errorf("environment variable %s is not set", opts.tokenEnv)
+		}
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(grpcserver.TokenCredentials(token)))
+	}
+	return dialOpts, nil
+}
+
+// checkLoadThresholds prints the thresholds report broke, failing when it broke any
+func checkLoadThresholds(report *loadgen.Report, opts loadOptions, stderr io.Writer) error {
+	broken := false
+	if report.Tasks == 0 {
+		fmt.Fprintln(stderr, "No task was answered")
+		broken = true
+	}
+	if opts.maxP99 > 0 && report.Latency.P99 > opts.maxP99 {
+		fmt.Fprintf(stderr, "p99 latency %v exceeds %v\n", report.Latency.P99, opts.maxP99)
+		broken = true
+	}
+	if opts.minThroughput > 0 && report.Throughput < opts.minThroughput {
+		fmt.Fprintf(stderr, "Throughput %.1f tasks/s is below %.1f\n", report.Throughput, opts.minThroughput)
+		broken = true
+	}
+	if rate := report.FailureRate(); rate > opts.maxFailureRate {
+		fmt.Fprintf(stderr, "Failure rate %.2f%% exceeds %.2f%%\n", rate*100, opts.maxFailureRate*100)
+		broken = true
+	}
+	if broken {
+		return errThresholdBroken
+	}
+	return nil
+}
+
+// printLoadReport writes report as a table, or as JSON
+func printLoadReport(w io.Writer, report *loadgen.Report, asJSON bool) error {
+	if asJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+	taskTypes := make([]string, 0, len(report.TaskTypes))
+	for taskType := range report.TaskTypes {
+		taskTypes = append(taskTypes, string(taskType))
+	}
+	sort.Strings(taskTypes)
+
+	fmt.Fprintf(w, "%d tasks in %v, %.1f tasks/s, %d failed\n\n", report.Tasks, report.Elapsed.Round(time.Millisecond), report.Throughput, report.Failed)
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "TASK TYPE\tTASKS\tFAILED\tP50\tP90\tP99\tMAX")
+	row := func(name string, tasks, failed int, l loadgen.Latency) {
+		fmt.Fprintf(table, "%s\t%d\t%d\t%v\t%v\t%v\t%v\n", name, tasks, failed,
+			l.P50.Round(time.Microsecond), l.P90.Round(time.Microsecond), l.P99.Round(time.Microsecond), l.Max.Round(time.Microsecond))
+	}
+	for _, taskType := range taskTypes {
+		tr := report.TaskTypes[client.TaskType(taskType)]
+		row(taskType, tr.Tasks, tr.Failed, tr.Latency)
+	}
+	row("all", report.Tasks, report.Failed, report.Latency)
+	if err := table.Flush(); err != nil {
+		return err
+	}
+
+	if len(report.Errors) > 0 {
+		messages := make([]string, 0, len(report.Errors))
+		for message := range report.Errors {
+			messages = append(messages, message)
+		}
+		sort.Strings(messages)
+		fmt.Fprintln(w, "\nerrors:")
+		for _, message := range messages {
+			fmt.Fprintf(w, "  %dx %s\n", report.Errors[message], message)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/najnomics/crosscow-avs/pkg/loadgen"
+)
+
+func Test_LoadCommandExitCodes(t *testing.T) {
+	testCases := map[string]struct {
+		args   []string
+		code   int
+		stderr string
+	}{
+		"no target":       {args: nil, code: 2, stderr: "one of --target and --stub is required"},
+		"target and stub": {args: []string{"--target", "127.0.0.1:8080", "--stub"}, code: 2, stderr: "one of --target and --stub is required"},
+		"endless":         {args: []string{"--stub", "--tasks", "0"}, code: 1, stderr: "tasks or duration is required"},
+		"missing mix":     {args: []string{"--stub", "--mix", "testdata/missing.json"}, code: 1, stderr: "failed to read the mix"},
+		"invalid mix":     {args: []string{"--stub", "--mix", "load_command_test.go"}, code: 1, stderr: "mix must be a JSON array"},
+		"slow":            {args: []string{"--stub", "--tasks", "4", "--max-p99", "1ns"}, code: 1, stderr: "p99 latency"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := runLoadCommand(context.Background(), tc.args, &stdout, &stderr); code != tc.code {
+				t.Errorf("Expected exit code %d, got %d: %s", tc.code, code, stderr.String())
+			}
+			if !strings.Contains(stderr.String(), tc.stderr) {
+				t.Errorf("Expected %q on stderr, got %q", tc.stderr, stderr.String())
+			}
+		})
+	}
+}
+
+func Test_LoadCommandReports(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	args := []string{"--stub", "--stub-latency", "0", "--tasks", "12", "--concurrency", "4", "--json"}
+	if code := runLoadCommand(context.Background(), args, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected the run to pass, got exit code %d: %s", code, stderr.String())
+	}
+	var report loadgen.Report
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatalf("Expected a JSON report, got %q: %v", stdout.String(), err)
+	}
+	if report.Tasks != 12 || report.Failed != 0 || len(report.TaskTypes) == 0 || report.Latency.P99 == 0 {
+		t.Errorf("Unexpected report %+v", report)
+	}
+
+	stdout.Reset()
+	if err := printLoadReport(&stdout, &report, false); err != nil {
+		t.Fatalf("printLoadReport failed: %v", err)
+	}
+	if !strings.Contains(stdout.String(), "12 tasks in") || !strings.Contains(stdout.String(), "yield_monitoring") {
+		t.Errorf("Expected a table of the task types, got %q", stdout.String())
+	}
+}
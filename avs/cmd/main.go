@@ -2,305 +2,290 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"runtime/debug"
+	"syscall"
 	"time"
 
-	"github.com/Layr-Labs/hourglass-monorepo/ponos/pkg/performer/server"
-	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/najnomics/crosscow-avs/pkg/admin"
+	"github.com/najnomics/crosscow-avs/pkg/collector"
+	"github.com/najnomics/crosscow-avs/pkg/config"
+	"github.com/najnomics/crosscow-avs/pkg/evidence"
+	"github.com/najnomics/crosscow-avs/pkg/grpcserver"
+	"github.com/najnomics/crosscow-avs/pkg/health"
+	"github.com/najnomics/crosscow-avs/pkg/indexer"
+	"github.com/najnomics/crosscow-avs/pkg/logging"
+	"github.com/najnomics/crosscow-avs/pkg/opportunity"
+	"github.com/najnomics/crosscow-avs/pkg/performer"
+	"github.com/najnomics/crosscow-avs/pkg/recovery"
+	"github.com/najnomics/crosscow-avs/pkg/reload"
+	"github.com/najnomics/crosscow-avs/pkg/reorg"
+	"github.com/najnomics/crosscow-avs/pkg/secrets"
+	"github.com/najnomics/crosscow-avs/pkg/servicemanager"
+	"github.com/najnomics/crosscow-avs/pkg/taskapi"
+	"github.com/najnomics/crosscow-avs/pkg/tasklistener"
+	"github.com/najnomics/crosscow-avs/pkg/tracing"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
 )
 
-// TaskType represents the different types of USDC Yield Intelligence tasks
-type TaskType string
+// version is the performer release, set at build time with
+// -ldflags "-X main.version=<version>"
+var version = "dev"
 
-const (
-	TaskTypeYieldMonitoring        TaskType = "yield_monitoring"
-	TaskTypeCrossChainYieldCheck   TaskType = "cross_chain_yield_check"
-	TaskTypeRebalanceExecution     TaskType = "rebalance_execution"
-	TaskTypeRiskAssessment         TaskType = "risk_assessment"
-)
-
-// TaskPayload represents the structure of task payload data
-type TaskPayload struct {
-	Type       TaskType               `json:"type"`
-	Parameters map[string]interface{} `json:"parameters"`
-}
+// commit is the revision the performer was built from, set at build time with
+// -ldflags "-X main.commit=<hash>". Binaries built from a git checkout without it report
+// the revision go build stamped them with.
+var commit = ""
 
-// parseTaskPayload extracts and parses the task payload from TaskRequest
-func parseTaskPayload(t *performerV1.TaskRequest) (*TaskPayload, error) {
-	var payload TaskPayload
-	if err := json.Unmarshal(t.Payload, &payload); err != nil {
-		return nil, fmt.Errorf("failed to parse task payload: %w", err)
+// buildInfo returns the release of the binary
+func buildInfo() performer.BuildInfo {
+	info := performer.BuildInfo{Version: version, Commit: commit}
+	if info.Commit != "" {
+		return info
+	}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			if setting.Key == "vcs.revision" {
+				info.Commit = setting.Value
+			}
+		}
 	}
-	return &payload, nil
+	return info
 }
 
-// YieldIntelligencePerformer implements the Hourglass Performer interface for USDC Yield tasks.
-// This offchain binary is run by Operators running the Hourglass Executor. It contains
-// the business logic of the USDC Yield Intelligence AVS and performs work based on tasks sent to it.
-//
-// The Hourglass Aggregator ingests tasks from the TaskMailbox and distributes work
-// to Executors configured to run the Yield Intelligence Performer. Performers execute the work and
-// return the result to the Executor where the result is signed and returned to the
-// Aggregator to place in the outbox once the signing threshold is met.
-type YieldIntelligencePerformer struct {
-	logger *zap.Logger
+// subcommand runs a command named by the first argument instead of the performer and
+// returns its exit code
+type subcommand func(ctx context.Context, args []string, stdout, stderr io.Writer) int
+
+// subcommands are the commands of the performer binary, keyed by name
+var subcommands = map[string]subcommand{
+	"task":   runTaskCommand,
+	"load":   runLoadCommand,
+	"replay": runReplayCommand,
+	"export": runExportCommand,
+	"compare": func(_ context.Context, args []string, stdout, stderr io.Writer) int {
+		return runCompareCommand(args, stdout, stderr)
+	},
+	"devnet":   runDevnetCommand,
+	"operator": runOperatorCommand,
 }
 
-func NewYieldIntelligencePerformer(logger *zap.Logger) *YieldIntelligencePerformer {
-	return &YieldIntelligencePerformer{
-		logger: logger,
+func main() {
+	if len(os.Args) > 1 {
+		if command, ok := subcommands[os.Args[1]]; ok {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			code := command(ctx, os.Args[2:], os.Stdout, os.Stderr)
+			stop()
+			os.Exit(code)
+		}
 	}
-}
 
-func (yip *YieldIntelligencePerformer) ValidateTask(t *performerV1.TaskRequest) error {
-	yip.logger.Sugar().Infow("Validating USDC Yield Intelligence task",
-		zap.Any("task", t),
-	)
+	l, _ := zap.NewProduction()
 
-	// ------------------------------------------------------------------------
-	// USDC Yield Intelligence Task Validation Logic
-	// ------------------------------------------------------------------------
-	// Validate that the task request data is well-formed for yield optimization operations
-	
-	if len(t.TaskId) == 0 {
-		return fmt.Errorf("task ID cannot be empty")
-	}
+	configPath := flag.String("config", os.Getenv("PERFORMER_CONFIG"), "path to the performer YAML config file")
+	fixtures := registerFixtureFlags(flag.CommandLine)
+	flag.Parse()
 
-	if len(t.Payload) == 0 {
-		return fmt.Errorf("task payload cannot be empty")
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		l.Sugar().Fatalw("Failed to load performer config", "error", err)
 	}
-
-	// Parse and validate task payload
-	payload, err := parseTaskPayload(t)
+	if err := fixtures.apply(cfg); err != nil {
+		l.Sugar().Fatalw("Invalid fixture flags", "error", err)
+	}
+	configured, level, err := logging.NewWithLevel(cfg.Logging)
 	if err != nil {
-		return fmt.Errorf("failed to parse task payload: %w", err)
+		l.Sugar().Fatalw("Failed to create logger", "error", err)
 	}
+	l = configured
 
-	// Validate task type specific requirements
-	switch payload.Type {
-	case TaskTypeYieldMonitoring:
-		if err := yip.validateYieldMonitoringTask(payload); err != nil {
-			return fmt.Errorf("yield monitoring validation failed: %w", err)
-		}
-	case TaskTypeCrossChainYieldCheck:
-		if err := yip.validateCrossChainYieldCheckTask(payload); err != nil {
-			return fmt.Errorf("cross-chain yield check validation failed: %w", err)
-		}
-	case TaskTypeRebalanceExecution:
-		if err := yip.validateRebalanceExecutionTask(payload); err != nil {
-			return fmt.Errorf("rebalance execution validation failed: %w", err)
-		}
-	case TaskTypeRiskAssessment:
-		if err := yip.validateRiskAssessmentTask(payload); err != nil {
-			return fmt.Errorf("risk assessment validation failed: %w", err)
-		}
-	default:
-		return fmt.Errorf("unknown task type: %s", payload.Type)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	secretStore, l, err := expandSecrets(ctx, cfg, l)
+	if err != nil {
+		l.Sugar().Fatalw("Failed to read secrets", "error", err)
 	}
-
-	yip.logger.Sugar().Infow("Task validation successful", "taskId", string(t.TaskId))
-	return nil
+	if err := run(ctx, *configPath, cfg, secretStore, l, level); err != nil {
+		l.Sugar().Errorw("Performer stopped", "error", err)
+		stop()
+		os.Exit(1)
+	}
+	l.Sugar().Infow("Performer stopped")
 }
 
-func (yip *YieldIntelligencePerformer) HandleTask(t *performerV1.TaskRequest) (*performerV1.TaskResponse, error) {
-	yip.logger.Sugar().Infow("Handling USDC Yield Intelligence task",
-		zap.Any("task", t),
-	)
-
-	// ------------------------------------------------------------------------
-	// USDC Yield Intelligence Task Processing Logic
-	// ------------------------------------------------------------------------
-	// This is where the Performer will execute yield optimization work
-	
-	var resultBytes []byte
-	var err error
+// run serves tasks until ctx is cancelled, then stops accepting tasks, waits up to the
+// shutdown timeout for the ones in flight, and closes the store and RPC connections.
+// cfg was loaded from configPath, which is reloaded on SIGHUP and when secrets in
+// secretStore rotate. level is the level l logs at, which the admin API and reloads can
+// change.
+func run(ctx context.Context, configPath string, cfg *config.Config, secretStore *secrets.Store, l *zap.Logger, level zap.AtomicLevel) error {
+	shutdownTracing, err := tracing.Setup(cfg.Tracing)
+	if err != nil {
+		return fmt.Errorf("failed to set up tracing: %w", err)
+	}
+	defer func() {
+		// the run context is cancelled by now, and pending spans still need flushing
+		flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(flushCtx); err != nil {
+			l.Sugar().Errorw("Failed to flush spans", "error", err)
+		}
+	}()
 
-	// Parse task payload to determine task type
-	payload, err := parseTaskPayload(t)
+	saveFixtures, err := setupFixtures(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse task payload: %w", err)
+		return fmt.Errorf("failed to set up fixtures: %w", err)
 	}
-	
-	// Route to appropriate handler based on task type
-	switch payload.Type {
-	case TaskTypeYieldMonitoring:
-		resultBytes, err = yip.handleYieldMonitoring(t, payload)
-	case TaskTypeCrossChainYieldCheck:
-		resultBytes, err = yip.handleCrossChainYieldCheck(t, payload)
-	case TaskTypeRebalanceExecution:
-		resultBytes, err = yip.handleRebalanceExecution(t, payload)
-	case TaskTypeRiskAssessment:
-		resultBytes, err = yip.handleRiskAssessment(t, payload)
-	default:
-		return nil, fmt.Errorf("unknown task type '%s' for task %s", payload.Type, string(t.TaskId))
+	defer func() {
+		if err := saveFixtures(); err != nil {
+			l.Sugar().Errorw("Failed to save fixtures", "error", err)
+		}
+	}()
+	if cfg.Fixtures.Mode != "" {
+		l.Sugar().Infow("Using fixtures for every RPC and API call", "mode", cfg.Fixtures.Mode, "path", cfg.Fixtures.Path)
 	}
 
+	svc, err := openServices(ctx, cfg, l)
+	defer svc.close(l)
 	if err != nil {
-		yip.logger.Sugar().Errorw("Task processing failed", 
-			"taskId", string(t.TaskId), 
-			"error", err,
-		)
-		return nil, err
+		return err
 	}
 
-	yip.logger.Sugar().Infow("Task processing completed successfully", 
-		"taskId", string(t.TaskId),
-		"resultSize", len(resultBytes),
-	)
-
-	return &performerV1.TaskResponse{
-		TaskId: t.TaskId,
-		Result: resultBytes,
-	}, nil
-}
-
-// handleYieldMonitoring processes yield monitoring tasks
-func (yip *YieldIntelligencePerformer) handleYieldMonitoring(t *performerV1.TaskRequest, payload *TaskPayload) ([]byte, error) {
-	yip.logger.Sugar().Infow("Processing yield monitoring task", "taskId", string(t.TaskId))
-	
-	// TODO: Implement yield monitoring logic
-	// Example parameter access:
-	// protocol := payload.Parameters["protocol"].(string)
-	// token := payload.Parameters["token"].(string)
-	
-	// - Fetch yield rates from lending protocols (Aave, Compound, Morpho)
-	// - Calculate risk-adjusted yields
-	// - Monitor for significant rate changes
-	// - Submit yield data to Yield Intelligence Service Manager
-	// - Return monitoring result
-	
-	return []byte("Yield monitoring completed"), nil
-}
-
-// handleCrossChainYieldCheck processes cross-chain yield comparison tasks
-func (yip *YieldIntelligencePerformer) handleCrossChainYieldCheck(t *performerV1.TaskRequest, payload *TaskPayload) ([]byte, error) {
-	yip.logger.Sugar().Infow("Processing cross-chain yield check task", "taskId", string(t.TaskId))
-	
-	// TODO: Implement cross-chain yield comparison logic
-	// - Query yield rates across multiple chains (Ethereum, Base, Arbitrum)
-	// - Factor in cross-chain transfer costs via CCTP
-	// - Calculate net yield differences
-	// - Identify profitable rebalancing opportunities
-	// - Return cross-chain yield analysis
-	
-	return []byte("Cross-chain yield check completed"), nil
-}
-
-// handleRebalanceExecution processes USDC rebalancing execution tasks
-func (yip *YieldIntelligencePerformer) handleRebalanceExecution(t *performerV1.TaskRequest, payload *TaskPayload) ([]byte, error) {
-	yip.logger.Sugar().Infow("Processing rebalance execution task", "taskId", string(t.TaskId))
-	
-	// TODO: Implement rebalance execution logic
-	// - Validate rebalancing opportunity from yield signals
-	// - Calculate optimal allocation across protocols/chains
-	// - Execute via Circle Wallets and CCTP v2
-	// - Monitor execution success and gas costs
-	// - Return execution result with performance metrics
-	
-	return []byte("Rebalance execution completed"), nil
-}
-
-// handleRiskAssessment processes protocol risk assessment tasks
-func (yip *YieldIntelligencePerformer) handleRiskAssessment(t *performerV1.TaskRequest, payload *TaskPayload) ([]byte, error) {
-	yip.logger.Sugar().Infow("Processing risk assessment task", "taskId", string(t.TaskId))
-	
-	// TODO: Implement risk assessment logic
-	// - Analyze protocol TVL and utilization rates
-	// - Check smart contract audit status
-	// - Monitor governance and admin key risks
-	// - Calculate risk-adjusted yield scores
-	// - Return comprehensive risk assessment
-	
-	return []byte("Risk assessment completed"), nil
-}
-
-// USDC Yield Intelligence task validation functions
-func (yip *YieldIntelligencePerformer) validateYieldMonitoringTask(payload *TaskPayload) error {
-	// Validate required parameters for yield monitoring
-	if protocol, ok := payload.Parameters["protocol"].(string); !ok || protocol == "" {
-		return fmt.Errorf("missing or invalid protocol")
-	}
-	
-	if token, ok := payload.Parameters["token"].(string); !ok || token != "USDC" {
-		return fmt.Errorf("missing or invalid token, must be USDC")
+	if cfg.Health.Enabled {
+		registry := health.NewRegistry(cfg.Health.CheckTimeout)
+		registerHealthChecks(registry, cfg, svc.kv, svc.chains, svc.circle)
+		healthServer := health.NewServer(&cfg.Health, registry, l)
+		healthServer.Handle("/metrics", promhttp.HandlerFor(svc.metrics, promhttp.HandlerOpts{}))
+		healthServer.Start(ctx)
+		l.Sugar().Infow("Serving health endpoints", "port", cfg.Health.Port, "checks", registry.CheckNames())
 	}
-	
-	if chainId, ok := payload.Parameters["chain_id"].(float64); !ok || chainId <= 0 {
-		return fmt.Errorf("missing or invalid chain_id")
-	}
-	
-	return nil
-}
 
-func (yip *YieldIntelligencePerformer) validateCrossChainYieldCheckTask(payload *TaskPayload) error {
-	// Validate required parameters for cross-chain yield check
-	if sourceChain, ok := payload.Parameters["source_chain"].(float64); !ok || sourceChain <= 0 {
-		return fmt.Errorf("missing or invalid source_chain")
+	if svc.transactions != nil {
+		for _, chainID := range svc.chains.ChainIDs() {
+			l.Sugar().Infow("Submitting rebalance transactions directly", "chainId", chainID, "account", svc.transactions.Address(chainID).Hex(),
+				"privateMempool", svc.transactions.Protection(chainID).PrivateRpcUrl != "")
+		}
 	}
-	
-	if targetChain, ok := payload.Parameters["target_chain"].(float64); !ok || targetChain <= 0 {
-		return fmt.Errorf("missing or invalid target_chain")
+
+	if cfg.Collector.Enabled {
+		yieldCollector := collector.New(cfg.Collector, svc.adapters, svc.history, svc.chains.ChainIDs(), l)
+		yieldCollector.FollowHeads(svc.chains)
+		if cfg.Opportunities.Enabled {
+			mailbox, err := opportunity.NewMailbox(cfg.Opportunities.Mailbox, svc.transactions)
+			if err != nil {
+				return fmt.Errorf("opportunities: %w", err)
+			}
+			yieldCollector.Observe(opportunity.New(cfg.Opportunities, svc.attester, svc.notifier, mailbox, l).Observe)
+			l.Sugar().Infow("Pushing rebalance opportunities", "thresholdBps", cfg.Opportunities.ThresholdBps, "mailbox", mailbox != nil)
+		}
+		if cfg.Submission.Enabled {
+			submitter, err := servicemanager.NewSubmitter(cfg.Submission, svc.chains, svc.transactions, svc.adapters, l)
+			if err != nil {
+				return fmt.Errorf("submission: %w", err)
+			}
+			yieldCollector.Observe(submitter.Observe)
+			submitter.Start(ctx)
+			l.Sugar().Infow("Submitting yield attestations", "chainId", cfg.Submission.ChainID, "serviceManager", cfg.Submission.ServiceManager,
+				"interval", cfg.Submission.Interval, "respondToAttestations", cfg.Submission.RespondToAttestations)
+		}
+		yieldCollector.Start(ctx)
+		defer yieldCollector.Wait()
+		l.Sugar().Infow("Collecting yield history", "interval", cfg.Collector.Interval, "retention", cfg.Collector.Retention)
 	}
-	
-	if amount, ok := payload.Parameters["amount"].(float64); !ok || amount <= 0 {
-		return fmt.Errorf("missing or invalid amount")
+	var marketIndex *indexer.Indexer
+	if cfg.Indexer.Enabled {
+		marketIndex = indexer.New(cfg.Indexer, svc.adapters, svc.chains, l)
+		marketIndex.Start(ctx)
+		l.Sugar().Infow("Indexing market events", "pollInterval", cfg.Indexer.PollInterval, "refreshInterval", cfg.Indexer.RefreshInterval)
 	}
-	
-	return nil
-}
 
-func (yip *YieldIntelligencePerformer) validateRebalanceExecutionTask(payload *TaskPayload) error {
-	// Validate required parameters for rebalance execution
-	if userAddress, ok := payload.Parameters["user_address"].(string); !ok || userAddress == "" {
-		return fmt.Errorf("missing or invalid user_address")
-	}
-	
-	if amount, ok := payload.Parameters["amount"].(float64); !ok || amount <= 0 {
-		return fmt.Errorf("missing or invalid amount")
+	evidenceChecker := evidence.NewFromConfig(cfg.Evidence, svc.chains, svc.adapters, svc.kv, l)
+	if evidenceChecker != nil {
+		evidenceChecker.Start(ctx)
+		l.Sugar().Infow("Checking yield attestations for evidence", "chainId", cfg.Evidence.ChainID, "serviceManager", cfg.Evidence.ServiceManager,
+			"toleranceBps", cfg.Evidence.ToleranceBps)
 	}
-	
-	if targetProtocol, ok := payload.Parameters["target_protocol"].(string); !ok || targetProtocol == "" {
-		return fmt.Errorf("missing or invalid target_protocol")
+
+	reorgWatcher := reorg.NewFromConfig(cfg.Reorg, svc.chains, svc.kv, l)
+	yip := svc.performer(cfg, l, performer.WithIndexer(marketIndex), performer.WithEvidence(evidenceChecker), performer.WithReorgs(reorgWatcher))
+	if reorgWatcher != nil {
+		reorgWatcher.Start(ctx, yip)
+		l.Sugar().Infow("Watching reference blocks for reorgs", "confirmations", cfg.Reorg.Confirmations, "depth", cfg.Reorg.Depth,
+			"reexecute", cfg.Reorg.Reexecute)
 	}
-	
-	return nil
-}
 
-func (yip *YieldIntelligencePerformer) validateRiskAssessmentTask(payload *TaskPayload) error {
-	// Validate required parameters for risk assessment
-	if protocol, ok := payload.Parameters["protocol"].(string); !ok || protocol == "" {
-		return fmt.Errorf("missing or invalid protocol")
+	if watchdog := recovery.NewFromConfig(cfg.Recovery, l); watchdog != nil {
+		watchdog.Start(ctx, yip)
+		l.Sugar().Infow("Watching CCTP transfers for stuck mints", "sla", cfg.Recovery.SLA, "maxAttempts", cfg.Recovery.MaxAttempts,
+			"pollInterval", cfg.Recovery.PollInterval)
 	}
-	
-	if chainId, ok := payload.Parameters["chain_id"].(float64); !ok || chainId <= 0 {
-		return fmt.Errorf("missing or invalid chain_id")
+
+	if cfg.TaskListener.Enabled {
+		tasklistener.New(cfg.TaskListener, svc.chains, yip, l).Start(ctx)
+		l.Sugar().Infow("Warming tasks created in the task mailbox", "chainId", cfg.TaskListener.ChainID, "taskMailbox", cfg.TaskListener.TaskMailbox)
 	}
-	
-	if assessmentType, ok := payload.Parameters["assessment_type"].(string); !ok || assessmentType == "" {
-		return fmt.Errorf("missing or invalid assessment_type")
+
+	if configPath != "" {
+		reloader := newConfigReloader(configPath, cfg, secretStore, svc, yip, level, l)
+		err := reload.New(cfg.Reload, configPath, l).Start(ctx, func() {
+			if err := reloader.reload(ctx); err != nil {
+				l.Sugar().Errorw("Failed to reload config", "error", err)
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("failed to watch config: %w", err)
+		}
+		// rotated secrets are applied like the settings of a reloaded file
+		secretStore.Start(ctx, func(ctx context.Context) {
+			if err := reloader.reload(ctx); err != nil {
+				l.Sugar().Errorw("Failed to apply rotated secrets", "error", err)
+			}
+		})
 	}
-	
-	return nil
-}
 
-func main() {
-	ctx := context.Background()
-	l, _ := zap.NewProduction()
+	if cfg.Admin.Enabled {
+		adminServer, err := admin.NewServer(&cfg.Admin, l)
+		if err != nil {
+			return fmt.Errorf("failed to create admin server: %w", err)
+		}
+		performer.RegisterAdminRoutes(adminServer, yip, level, svc.chains, cfg.Admin.ProbeTimeout)
+		adminServer.Start(ctx)
+		l.Sugar().Infow("Serving admin API", "host", cfg.Admin.Host, "port", cfg.Admin.Port, "token", cfg.Admin.Token != "",
+			"tls", cfg.Admin.TLS.Enabled, "clientCerts", cfg.Admin.TLS.Mutual())
+	}
 
-	performer := NewYieldIntelligencePerformer(l)
+	if cfg.TaskAPI.Enabled {
+		taskServer, err := taskapi.NewServer(&cfg.TaskAPI, performer.TaskAPIHandler(yip), l)
+		if err != nil {
+			return fmt.Errorf("failed to create task API server: %w", err)
+		}
+		taskServer.Start(ctx)
+		l.Sugar().Infow("Serving task API", "host", cfg.TaskAPI.Host, "port", cfg.TaskAPI.Port, "token", cfg.TaskAPI.Token != "",
+			"tls", cfg.TaskAPI.TLS.Enabled, "clientCerts", cfg.TaskAPI.TLS.Mutual())
+	}
 
-	pp, err := server.NewPonosPerformerWithRpcServer(&server.PonosPerformerConfig{
-		Port:    8080,
-		Timeout: 5 * time.Second,
-	}, performer, l)
+	pp, err := grpcserver.New(cfg.GrpcPort, cfg.Grpc, yip, l)
 	if err != nil {
-		panic(fmt.Errorf("failed to create USDC Yield Intelligence performer: %w", err))
+		return fmt.Errorf("failed to create USDC Yield Intelligence performer: %w", err)
 	}
 
-	l.Info("Starting USDC Yield Intelligence Performer on port 8080...")
+	l.Sugar().Infow("Starting USDC Yield Intelligence Performer", "port", cfg.GrpcPort, "network", cfg.Network, "storage", cfg.Storage.Type,
+		"token", cfg.Grpc.Token != "", "tls", cfg.Grpc.TLS.Enabled, "clientCerts", cfg.Grpc.TLS.Mutual())
+	// blocks until ctx is cancelled, then stops the gRPC server gracefully
 	if err := pp.Start(ctx); err != nil {
-		panic(err)
+		return err
 	}
-}
\ No newline at end of file
+
+	l.Sugar().Infow("Shutting down, draining tasks in flight", "timeout", cfg.ShutdownTimeout)
+	drainCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := yip.Drain(drainCtx); err != nil {
+		l.Sugar().Warnw("Cancelled tasks still running after the shutdown timeout", "error", err)
+	}
+	return nil
+}
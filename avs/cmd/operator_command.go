@@ -0,0 +1,479 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/config"
+	"github.com/najnomics/crosscow-avs/pkg/operator"
+	"github.com/najnomics/crosscow-avs/pkg/signer"
+	"github.com/najnomics/crosscow-avs/pkg/txmgr"
+	"go.uber.org/zap"
+)
+
+const operatorCommandUsage = `usage: yieldavs operator <command> [flags]
+
+Onboards an operator onto the AVS, with the contracts of the operator section of the
+config.
+
+commands:
+  keys generate  --type bls|ecdsa --out <file>    generate a key into an encrypted keystore
+  keys import    --type bls|ecdsa --out <file>    import the hex key of --private-key-env
+  register       [--keystore <file>] [--bls-keystore <file>]
+                                                  register keys and operator sets
+  metadata       --uri <uri> [--keystore <file>]  set the operator metadata URI
+  status         --operator <address>             report the registration, exit 1 when incomplete
+
+Transactions are sent from the account of --keystore, or from the signer of the
+transactions section of the config without it. Keystore passwords are read from
+--password-file, or from the variable named by --password-env.
+`
+
+// errNotRegistered makes the status command exit 1 after printing the status
+var errNotRegistered = errors.New("the operator is not fully registered")
+
+// runOperatorCommand runs the operator subcommand with args, the arguments after
+// "operator". Results are printed to stdout, and usage, progress and errors to stderr. It
+// returns the exit code of the process.
+func runOperatorCommand(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, operatorCommandUsage)
+		return 2
+	}
+	var (
+		name = args[0]
+		rest = args[1:]
+		run  operatorRunner
+	)
+	switch name {
+	case "keys":
+		if len(rest) == 0 {
+			break
+		}
+		switch rest[0] {
+		case "generate":
+			name, run, rest = "keys generate", generateKey, rest[1:]
+		case "import":
+			name, run, rest = "keys import", importKey, rest[1:]
+		}
+	case "register":
+		run = registerOperator
+	case "metadata":
+		run = updateMetadata
+	case "status":
+		run = operatorStatus
+	}
+	if run == nil {
+		fmt.Fprint(stderr, operatorCommandUsage)
+		return 2
+	}
+
+	opts, flags := newOperatorFlags(name, stderr)
+	if err := flags.Parse(rest); err != nil {
+		return 2
+	}
+	if err := opts.check(name); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		flags.Usage()
+		return 2
+	}
+	if err := run(ctx, opts, stdout, stderr); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// operatorRunner runs an operator command with its flags
+type operatorRunner func(ctx context.Context, o *operatorFlags, stdout, stderr io.Writer) error
+
+// operatorFlags are the flags of the operator commands. Each command reads the ones it
+// needs.
+type operatorFlags struct {
+	configPath     string
+	keyType        string
+	out            string
+	privateKeyEnv  string
+	lightKDF       bool
+	keystore       string
+	blsKeystore    string
+	ecdsaKeystore  string
+	uri            string
+	operator       string
+	passwordFile   string
+	passwordEnv    string
+	passwordLoaded bool
+	password       string
+}
+
+func newOperatorFlags(name string, stderr io.Writer) (*operatorFlags, *flag.FlagSet) {
+	opts := &operatorFlags{}
+	flags := flag.NewFlagSet("operator "+name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, operatorCommandUsage)
+		fmt.Fprintf(stderr, "\nflags of %s:\n", name)
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&opts.passwordFile, "password-file", "", "file holding the keystore password")
+	flags.StringVar(&opts.passwordEnv, "password-env", "OPERATOR_KEYSTORE_PASSWORD", "variable holding the keystore password")
+	switch name {
+	case "keys generate", "keys import":
+		flags.StringVar(&opts.keyType, "type", "", "key type, bls or ecdsa")
+		flags.StringVar(&opts.out, "out", "", "path of the keystore to create")
+		flags.BoolVar(&opts.lightKDF, "light-kdf", false, "encrypt with a fast key derivation, for test keys only")
+		if name == "keys import" {
+			flags.StringVar(&opts.privateKeyEnv, "private-key-env", "OPERATOR_PRIVATE_KEY", "variable holding the hex private key to import")
+		}
+		return opts, flags
+	}
+	flags.StringVar(&opts.configPath, "config", os.Getenv("PERFORMER_CONFIG"), "path to the performer YAML config file")
+	switch name {
+	case "register":
+		flags.StringVar(&opts.keystore, "keystore", "", "ECDSA keystore of the operator account")
+		flags.StringVar(&opts.blsKeystore, "bls-keystore", "", "BLS keystore of the key to register for bn254 operator sets")
+		flags.StringVar(&opts.ecdsaKeystore, "ecdsa-keystore", "", "ECDSA keystore of the key to register for ecdsa operator sets, --keystore by default")
+	case "metadata":
+		flags.StringVar(&opts.keystore, "keystore", "", "ECDSA keystore of the operator account")
+		flags.StringVar(&opts.uri, "uri", "", "URI of the operator metadata JSON")
+	case "status":
+		flags.StringVar(&opts.operator, "operator", "", "address of the operator")
+	}
+	return opts, flags
+}
+
+// check rejects flags command cannot run without
+func (o *operatorFlags) check(command string) error {
+	switch command {
+	case "keys generate", "keys import":
+		if o.keyType != "bls" && o.keyType != "ecdsa" {
+			return fmt.Errorf("--type must be bls or ecdsa")
+		}
+		if o.out == "" {
+			return fmt.Errorf("--out is required")
+		}
+	case "metadata":
+		if o.uri == "" {
+			return fmt.Errorf("--uri is required")
+		}
+	case "status":
+		if !common.IsHexAddress(o.operator) {
+			return fmt.Errorf("--operator must be an address")
+		}
+	}
+	return nil
+}
+
+// keystorePassword reads the password of the keystores once
+func (o *operatorFlags) keystorePassword() (string, error) {
+	if !o.passwordLoaded {
+		password, err := signer.KeystoreConfig{PasswordFile: o.passwordFile, PasswordEnv: o.passwordEnv}.ReadPassword()
+		if err != nil {
+			return "", err
+		}
+		o.password, o.passwordLoaded = password, true
+	}
+	return o.password, nil
+}
+
+func (o *operatorFlags) kdf() operator.KDF {
+	if o.lightKDF {
+		return operator.LightKDF
+	}
+	return operator.StandardKDF
+}
+
+// generateKey creates a random key in a new keystore and prints its public key
+func generateKey(ctx context.Context, o *operatorFlags, stdout, stderr io.Writer) error {
+	password, err := o.keystorePassword()
+	if err != nil {
+		return err
+	}
+	if o.keyType == "bls" {
+		key, err := operator.GenerateBLSKey()
+		if err != nil {
+			return err
+		}
+		return saveBLSKey(o, key, password, stdout)
+	}
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return fmt.Errorf("failed to generate ECDSA key: %w", err)
+	}
+	return saveECDSAKey(o, key, password, stdout)
+}
+
+// importKey saves the hex private key of the variable named by --private-key-env in a new
+// keystore and prints its public key. The key is read from the environment so it does not
+// end up in the shell history.
+func importKey(ctx context.Context, o *operatorFlags, stdout, stderr io.Writer) error {
+	hexKey, ok := os.LookupEnv(o.privateKeyEnv)
+	if !ok || strings.TrimSpace(hexKey) == "" {
+		return fmt.Errorf("environment variable %s is not set", o.privateKeyEnv)
+	}
+	password, err := o.keystorePassword()
+	if err != nil {
+		return err
+	}
+	if o.keyType == "bls" {
+		key, err := operator.ParseBLSKey(hexKey)
+		if err != nil {
+			return err
+		}
+		return saveBLSKey(o, key, password, stdout)
+	}
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(strings.TrimSpace(hexKey), "0x"))
+	if err != nil {
+		return fmt.Errorf("invalid ECDSA key: %w", err)
+	}
+	return saveECDSAKey(o, key, password, stdout)
+}
+
+func saveBLSKey(o *operatorFlags, key *operator.BLSKey, password string, stdout io.Writer) error {
+	if err := operator.SaveBLSKey(o.out, key, password, o.kdf()); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(stdout, "BLS public key %s saved to %s\n", key.PublicKey(), o.out)
+	return err
+}
+
+func saveECDSAKey(o *operatorFlags, key *ecdsa.PrivateKey, password string, stdout io.Writer) error {
+	if err := operator.SaveECDSAKey(o.out, key, password, o.kdf()); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(stdout, "ECDSA address %s saved to %s\n", crypto.PubkeyToAddress(key.PublicKey).Hex(), o.out)
+	return err
+}
+
+// operatorSession is what the commands sending transactions from the operator account
+// share
+type operatorSession struct {
+	registrar *operator.Registrar
+	chains    *chain.Manager
+	txs       *txmgr.Manager
+	address   common.Address
+
+	// key is the key of the operator account when it was read from --keystore
+	key *ecdsa.PrivateKey
+}
+
+// openOperatorSession connects to the chain of the operator config. Without --keystore
+// transactions are sent from the signer of the config.
+func openOperatorSession(ctx context.Context, o *operatorFlags, send bool) (*operatorSession, error) {
+	cfg, err := config.Load(o.configPath)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, err := expandSecrets(ctx, cfg, zap.NewNop()); err != nil {
+		return nil, err
+	}
+	chains, err := chain.NewManagerFromConfig(ctx, cfg.Chains)
+	if err != nil {
+		return nil, err
+	}
+	session := &operatorSession{chains: chains}
+	if session.registrar, err = operator.NewRegistrar(cfg.Operator, chains); err != nil {
+		session.close()
+		return nil, err
+	}
+	if !send {
+		return session, nil
+	}
+
+	var set *txmgr.Set
+	if o.keystore != "" {
+		password, err := o.keystorePassword()
+		if err != nil {
+			session.close()
+			return nil, err
+		}
+		if session.key, err = operator.LoadECDSAKey(o.keystore, password); err != nil {
+			session.close()
+			return nil, err
+		}
+		set = txmgr.NewSet(chains, signer.NewKeySigner(session.key), cfg.Transactions)
+	} else {
+		if set, err = txmgr.NewFromConfig(ctx, cfg.Transactions, chains, nil); err != nil {
+			session.close()
+			return nil, err
+		}
+		if set == nil {
+			session.close()
+			return nil, fmt.Errorf("--keystore is required when transactions are disabled in the config")
+		}
+	}
+	if session.txs, err = set.For(cfg.Operator.ChainID); err != nil {
+		session.close()
+		return nil, err
+	}
+	session.address = session.txs.Address()
+	return session, nil
+}
+
+func (s *operatorSession) close() {
+	s.chains.Close()
+}
+
+// send sends call from the operator account and waits until it is final
+func (s *operatorSession) send(ctx context.Context, call chain.Call, what string, progress io.Writer) error {
+	tx, err := s.txs.Send(ctx, txmgr.Request{Call: call})
+	if err != nil {
+		return fmt.Errorf("failed to send %s: %w", what, err)
+	}
+	fmt.Fprintf(progress, "Sent %s in %s, waiting for it to be final\n", what, tx.Hash().Hex())
+	confirmation, err := s.txs.Confirm(ctx, tx)
+	if err != nil {
+		return fmt.Errorf("%s in %s: %w", what, tx.Hash().Hex(), err)
+	}
+	if confirmation.Reverted() {
+		return fmt.Errorf("%s reverted in %s", what, confirmation.Tx.Hash().Hex())
+	}
+	return nil
+}
+
+// registerOperator registers the keys the operator has not registered yet, then the
+// operator sets it is not a member of, and prints the resulting status. Keys go first,
+// as the AVS registrar rejects operators without a key for the set.
+func registerOperator(ctx context.Context, o *operatorFlags, stdout, stderr io.Writer) error {
+	session, err := openOperatorSession(ctx, o, true)
+	if err != nil {
+		return err
+	}
+	defer session.close()
+
+	status, err := session.registrar.Status(ctx, session.address)
+	if err != nil {
+		return err
+	}
+	if !status.IsOperator {
+		return fmt.Errorf("%s is not registered as an operator in the DelegationManager", session.address.Hex())
+	}
+
+	var missing []uint32
+	for _, set := range status.Sets {
+		if !set.Member {
+			missing = append(missing, set.ID)
+		}
+		if set.KeyRegistered {
+			continue
+		}
+		call, err := registrationCall(ctx, o, session, set)
+		if err != nil {
+			return err
+		}
+		if err := session.send(ctx, call, fmt.Sprintf("%s key registration for operator set %d", set.CurveType, set.ID), stderr); err != nil {
+			return err
+		}
+	}
+	if len(missing) > 0 {
+		call, err := session.registrar.RegisterForOperatorSets(session.address, missing)
+		if err != nil {
+			return err
+		}
+		if err := session.send(ctx, call, fmt.Sprintf("registration for operator sets %v", missing), stderr); err != nil {
+			return err
+		}
+	}
+
+	status, err = session.registrar.Status(ctx, session.address)
+	if err != nil {
+		return err
+	}
+	return printStatus(stdout, status)
+}
+
+// registrationCall returns the call registering the key of the curve of set
+func registrationCall(ctx context.Context, o *operatorFlags, session *operatorSession, set operator.SetStatus) (chain.Call, error) {
+	switch set.CurveType {
+	case operator.CurveTypeBN254:
+		if o.blsKeystore == "" {
+			return chain.Call{}, fmt.Errorf("--bls-keystore is required to register for bn254 operator set %d", set.ID)
+		}
+		password, err := o.keystorePassword()
+		if err != nil {
+			return chain.Call{}, err
+		}
+		key, err := operator.LoadBLSKey(o.blsKeystore, password)
+		if err != nil {
+			return chain.Call{}, err
+		}
+		return session.registrar.RegisterBN254Key(ctx, session.address, set.ID, key)
+	case operator.CurveTypeECDSA:
+		key := session.key
+		if o.ecdsaKeystore != "" {
+			password, err := o.keystorePassword()
+			if err != nil {
+				return chain.Call{}, err
+			}
+			if key, err = operator.LoadECDSAKey(o.ecdsaKeystore, password); err != nil {
+				return chain.Call{}, err
+			}
+		}
+		if key == nil {
+			return chain.Call{}, fmt.Errorf("--ecdsa-keystore or --keystore is required to register for ecdsa operator set %d", set.ID)
+		}
+		return session.registrar.RegisterECDSAKey(ctx, session.address, set.ID, key)
+	default:
+		return chain.Call{}, fmt.Errorf("operator set %d has no curve type configured in the KeyRegistrar", set.ID)
+	}
+}
+
+// updateMetadata points the metadata of the operator at --uri
+func updateMetadata(ctx context.Context, o *operatorFlags, stdout, stderr io.Writer) error {
+	session, err := openOperatorSession(ctx, o, true)
+	if err != nil {
+		return err
+	}
+	defer session.close()
+
+	call, err := session.registrar.UpdateMetadataURI(session.address, o.uri)
+	if err != nil {
+		return err
+	}
+	if err := session.send(ctx, call, "metadata URI update", stderr); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(stdout, "Metadata URI of %s set to %s\n", session.address.Hex(), o.uri)
+	return err
+}
+
+// operatorStatus prints the registration status of --operator, failing when it is
+// incomplete so scripts can check it
+func operatorStatus(ctx context.Context, o *operatorFlags, stdout, stderr io.Writer) error {
+	session, err := openOperatorSession(ctx, o, false)
+	if err != nil {
+		return err
+	}
+	defer session.close()
+
+	status, err := session.registrar.Status(ctx, common.HexToAddress(o.operator))
+	if err != nil {
+		return err
+	}
+	if err := printStatus(stdout, status); err != nil {
+		return err
+	}
+	if !status.Registered() {
+		return errNotRegistered
+	}
+	return nil
+}
+
+func printStatus(w io.Writer, status *operator.Status) error {
+	encoded, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", encoded)
+	return err
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/najnomics/crosscow-avs/pkg/operator"
+)
+
+func Test_OperatorCommandExitCodes(t *testing.T) {
+	t.Setenv("PERFORMER_CONFIG", "")
+	t.Setenv("OPERATOR_KEYSTORE_PASSWORD", "password")
+
+	testCases := map[string]struct {
+		args   []string
+		code   int
+		stderr string
+	}{
+		"no command":       {args: nil, code: 2, stderr: "usage: yieldavs operator"},
+		"unknown command":  {args: []string{"deregister"}, code: 2, stderr: "usage: yieldavs operator"},
+		"keys alone":       {args: []string{"keys"}, code: 2, stderr: "usage: yieldavs operator"},
+		"unknown key type": {args: []string{"keys", "generate", "--type", "rsa", "--out", "key.json"}, code: 2, stderr: "--type must be bls or ecdsa"},
+		"missing out":      {args: []string{"keys", "generate", "--type", "bls"}, code: 2, stderr: "--out is required"},
+		"missing uri":      {args: []string{"metadata"}, code: 2, stderr: "--uri is required"},
+		"invalid operator": {args: []string{"status", "--operator", "me"}, code: 2, stderr: "--operator must be an address"},
+		"unset import key": {args: []string{"keys", "import", "--type", "ecdsa", "--out", "key.json", "--private-key-env", "UNSET_OPERATOR_KEY"}, code: 1, stderr: "UNSET_OPERATOR_KEY is not set"},
+		"unconfigured":     {args: []string{"status", "--operator", "0x0000000000000000000000000000000000000001"}, code: 1, stderr: "operator.chainId is required"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := runOperatorCommand(context.Background(), tc.args, &stdout, &stderr); code != tc.code {
+				t.Errorf("Expected exit code %d, got %d: %s", tc.code, code, stderr.String())
+			}
+			if !strings.Contains(stderr.String(), tc.stderr) {
+				t.Errorf("Expected %q on stderr, got %q", tc.stderr, stderr.String())
+			}
+		})
+	}
+}
+
+func Test_OperatorKeysCommands(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("OPERATOR_KEYSTORE_PASSWORD", "password")
+
+	var stdout, stderr bytes.Buffer
+	blsPath := filepath.Join(dir, "bls.json")
+	if code := runOperatorCommand(context.Background(), []string{"keys", "generate", "--type", "bls", "--out", blsPath, "--light-kdf"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected the BLS key to be generated, got %d: %s", code, stderr.String())
+	}
+	key, err := operator.LoadBLSKey(blsPath, "password")
+	if err != nil || !strings.Contains(stdout.String(), key.PublicKey()) {
+		t.Fatalf("Expected the public key of the keystore to be printed, got %q: %v", stdout.String(), err)
+	}
+
+	ecdsaKey, _ := crypto.GenerateKey()
+	t.Setenv("OPERATOR_PRIVATE_KEY", hexutil.Encode(crypto.FromECDSA(ecdsaKey)))
+	ecdsaPath := filepath.Join(dir, "ecdsa.json")
+	stdout.Reset()
+	if code := runOperatorCommand(context.Background(), []string{"keys", "import", "--type", "ecdsa", "--out", ecdsaPath, "--light-kdf"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected the ECDSA key to be imported, got %d: %s", code, stderr.String())
+	}
+	imported, err := operator.LoadECDSAKey(ecdsaPath, "password")
+	if err != nil || !imported.Equal(ecdsaKey) {
+		t.Fatalf("Expected the imported key back, got %v", err)
+	}
+	if !strings.Contains(stdout.String(), crypto.PubkeyToAddress(ecdsaKey.PublicKey).Hex()) {
+		t.Errorf("Expected the address to be printed, got %q", stdout.String())
+	}
+
+	// keystores are never overwritten
+	stderr.Reset()
+	if code := runOperatorCommand(context.Background(), []string{"keys", "import", "--type", "ecdsa", "--out", ecdsaPath, "--light-kdf"}, &stdout, &stderr); code != 1 {
+		t.Errorf("Expected an existing keystore to be kept, got %d: %s", code, stderr.String())
+	}
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/anomaly"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/config"
+	"github.com/najnomics/crosscow-avs/pkg/features"
+	"github.com/najnomics/crosscow-avs/pkg/performer"
+	"github.com/najnomics/crosscow-avs/pkg/quota"
+	"github.com/najnomics/crosscow-avs/pkg/secrets"
+	"github.com/najnomics/crosscow-avs/pkg/taskqueue"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// enableProtocols enables the adapters of protocols and disables every other one. Every
+// adapter is enabled when protocols is empty.
+func enableProtocols(registry *adapters.Registry, protocols []string) error {
+	enabled := make(map[string]bool, len(protocols))
+	for _, protocol := range protocols {
+		if _, _, err := registry.Lookup(protocol); err != nil {
+			return err
+		}
+		enabled[strings.ToLower(strings.TrimSpace(protocol))] = true
+	}
+	for _, protocol := range registry.Registered() {
+		if err := registry.SetEnabled(protocol, len(protocols) == 0 || enabled[protocol]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// configReloader applies the settings of a reloaded config file that can change while
+// tasks run: RPC endpoints, the Circle API key, task quota limits, the task queue,
+// anomaly and cross validation thresholds, the enabled protocols and experimental
+// features, and the log level.
+// Changes to other settings are logged and take effect on the next restart. Only settings
+// that changed in the file, or whose secrets rotated, are applied, so a reload does not
+// undo what was switched through the admin API.
+type configReloader struct {
+	path    string
+	secrets *secrets.Store
+	svc     *services
+	yip     *performer.YieldIntelligencePerformer
+	level   zap.AtomicLevel
+	logger  *zap.Logger
+
+	// mu serializes reloads
+	mu      sync.Mutex
+	current *config.Config
+}
+
+func newConfigReloader(path string, cfg *config.Config, secretStore *secrets.Store, svc *services, yip *performer.YieldIntelligencePerformer, level zap.AtomicLevel, logger *zap.Logger) *configReloader {
+	return &configReloader{path: path, current: cfg, secrets: secretStore, svc: svc, yip: yip, level: level, logger: logger}
+}
+
+// reload reads the config file and applies its reloadable settings. A file that does not
+// load or validate is rejected as a whole and the running config is kept.
+func (r *configReloader) reload(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next, err := config.Load(r.path)
+	if err != nil {
+		return err
+	}
+	// fixtures are selected on the command line, and clear the WebSocket endpoints and
+	// batching
+	next.Fixtures = r.current.Fixtures
+	if next.Fixtures.Mode != "" {
+		for i := range next.Chains {
+			next.Chains[i].WsUrl = ""
+		}
+		next.Multicall.Enabled = false
+	}
+	if r.secrets != nil {
+		if err := r.secrets.ExpandAll(ctx, next); err != nil {
+			return fmt.Errorf("secrets: %w", err)
+		}
+	}
+
+	if pending := restartRequired(r.current, next); len(pending) > 0 {
+		r.logger.Sugar().Warnw("Config changes take effect after a restart", "settings", pending)
+	}
+	applied, err := r.apply(ctx, next)
+	if len(applied) > 0 {
+		r.logger.Sugar().Infow("Reloaded config", "settings", applied)
+	}
+	return err
+}
+
+// apply applies the reloadable settings of next that differ from the current config, and
+// returns the names of those applied
+func (r *configReloader) apply(ctx context.Context, next *config.Config) ([]string, error) {
+	merged := copyConfig(r.current)
+	var applied []string
+	var errs []error
+
+	current := make(map[uint64]int, len(merged.Chains))
+	for i, ch := range merged.Chains {
+		current[ch.ChainID] = i
+	}
+	for _, ch := range next.Chains {
+		i, ok := current[ch.ChainID]
+		if !ok || (merged.Chains[i].RpcUrl == ch.RpcUrl && merged.Chains[i].ArchiveRpcUrl == ch.ArchiveRpcUrl && merged.Chains[i].Name == ch.Name) {
+			continue
+		}
+		redial := merged.Chains[i]
+		redial.RpcUrl, redial.ArchiveRpcUrl, redial.Name = ch.RpcUrl, ch.ArchiveRpcUrl, ch.Name
+		if err := r.svc.chains.Redial(ctx, redial); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		probeEndpoints(ctx, r.svc.chains, r.logger, ch.ChainID)
+		merged.Chains[i] = redial
+		applied = append(applied, fmt.Sprintf("chains[%d].rpcUrl", i))
+	}
+
+	if merged.Circle != nil && next.Circle != nil && merged.Circle.ApiKey != next.Circle.ApiKey && r.svc.circle != nil {
+		r.svc.circle.SetApiKey(next.Circle.ApiKey)
+		merged.Circle.ApiKey = next.Circle.ApiKey
+		applied = append(applied, "circle.apiKey")
+	}
+
+	if !reflect.DeepEqual(merged.Quotas.Limits, next.Quotas.Limits) {
+		r.yip.SetQuotaLimits(next.Quotas.Limits)
+		merged.Quotas.Limits = next.Quotas.Limits
+		applied = append(applied, "quotas.limits")
+	}
+
+	if !reflect.DeepEqual(merged.TaskQueue, next.TaskQueue) && merged.TaskQueue.Enabled == next.TaskQueue.Enabled {
+		r.yip.SetTaskQueue(next.TaskQueue)
+		merged.TaskQueue = next.TaskQueue
+		applied = append(applied, "taskQueue")
+	}
+
+	anomalyChanged := merged.Anomaly != next.Anomaly
+	crossvalChanged := merged.CrossValidation.ToleranceBps != next.CrossValidation.ToleranceBps ||
+		merged.CrossValidation.MinSources != next.CrossValidation.MinSources ||
+		merged.CrossValidation.Quorum != next.CrossValidation.Quorum ||
+		!reflect.DeepEqual(merged.CrossValidation.Weights, next.CrossValidation.Weights)
+	if anomalyChanged {
+		merged.Anomaly = next.Anomaly
+		applied = append(applied, "anomaly")
+	}
+	if crossvalChanged {
+		merged.CrossValidation.ToleranceBps = next.CrossValidation.ToleranceBps
+		merged.CrossValidation.MinSources = next.CrossValidation.MinSources
+		merged.CrossValidation.Quorum = next.CrossValidation.Quorum
+		merged.CrossValidation.Weights = next.CrossValidation.Weights
+		applied = append(applied, "crossValidation")
+	}
+	if anomalyChanged || crossvalChanged {
+		r.yip.SetThresholds(merged.Anomaly, merged.CrossValidation)
+	}
+
+	if !reflect.DeepEqual(merged.Protocols, next.Protocols) {
+		if err := enableProtocols(r.svc.adapters, next.Protocols); err != nil {
+			errs = append(errs, fmt.Errorf("protocols: %w", err))
+		} else {
+			merged.Protocols = next.Protocols
+			applied = append(applied, "protocols")
+		}
+	}
+
+	if !reflect.DeepEqual(merged.Features, next.Features) {
+		if err := r.svc.features.Apply(next.Features); err != nil {
+			errs = append(errs, fmt.Errorf("features: %w", err))
+		} else {
+			merged.Features = next.Features
+			applied = append(applied, "features")
+		}
+	}
+
+	if merged.Logging.Level != next.Logging.Level {
+		level, err := zapcore.ParseLevel(next.Logging.Level)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("logging: %w", err))
+		} else {
+			r.level.SetLevel(level)
+			merged.Logging.Level = next.Logging.Level
+			applied = append(applied, "logging.level")
+		}
+	}
+
+	r.current = merged
+	return applied, errors.Join(errs...)
+}
+
+// restartRequired returns the names of the settings that differ between current and
+// next and cannot be reloaded
+func restartRequired(current, next *config.Config) []string {
+	a, b := withoutReloadable(current), withoutReloadable(next)
+	va, vb := reflect.ValueOf(a).Elem(), reflect.ValueOf(b).Elem()
+	var changed []string
+	for i := 0; i < va.NumField(); i++ {
+		if !reflect.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			name, _, _ := strings.Cut(va.Type().Field(i).Tag.Get("yaml"), ",")
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
+
+// withoutReloadable returns a copy of cfg with the reloadable settings cleared
+func withoutReloadable(cfg *config.Config) *config.Config {
+	c := copyConfig(cfg)
+	for i := range c.Chains {
+		c.Chains[i].RpcUrl, c.Chains[i].ArchiveRpcUrl, c.Chains[i].Name = "", "", ""
+	}
+	if c.Circle != nil {
+		c.Circle.ApiKey = ""
+	}
+	c.Quotas.Limits = nil
+	c.TaskQueue = taskqueue.Config{Enabled: cfg.TaskQueue.Enabled}
+	c.Anomaly = anomaly.Config{}
+	c.CrossValidation.ToleranceBps, c.CrossValidation.MinSources = 0, 0
+	c.CrossValidation.Quorum, c.CrossValidation.Weights = 0, nil
+	c.Protocols = nil
+	c.Features = features.Config{}
+	c.Logging.Level = ""
+	return c
+}
+
+// copyConfig returns a copy of cfg whose reloadable slices, maps and sections can be
+// changed without changing cfg
+func copyConfig(cfg *config.Config) *config.Config {
+	c := *cfg
+	c.Chains = append([]chain.Config(nil), cfg.Chains...)
+	c.Protocols = append([]string(nil), cfg.Protocols...)
+	c.Features.Enabled = append([]string(nil), cfg.Features.Enabled...)
+	if cfg.Circle != nil {
+		circle := *cfg.Circle
+		c.Circle = &circle
+	}
+	if cfg.Quotas.Limits != nil {
+		c.Quotas.Limits = make(map[string]quota.Limit, len(cfg.Quotas.Limits))
+		for taskType, limit := range cfg.Quotas.Limits {
+			c.Quotas.Limits[taskType] = limit
+		}
+	}
+	if cfg.TaskQueue.Types != nil {
+		c.TaskQueue.Types = make(map[string]taskqueue.Lane, len(cfg.TaskQueue.Types))
+		for taskType, lane := range cfg.TaskQueue.Types {
+			c.TaskQueue.Types[taskType] = lane
+		}
+	}
+	if cfg.TaskQueue.Priorities != nil {
+		c.TaskQueue.Priorities = make(map[string]int, len(cfg.TaskQueue.Priorities))
+		for taskType, priority := range cfg.TaskQueue.Priorities {
+			c.TaskQueue.Priorities[taskType] = priority
+		}
+	}
+	return &c
+}
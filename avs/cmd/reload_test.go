@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/config"
+	"github.com/najnomics/crosscow-avs/pkg/performer"
+	"github.com/najnomics/crosscow-avs/pkg/quota"
+	"github.com/najnomics/crosscow-avs/pkg/taskqueue"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// newRPCEndpoint serves block number 1 and counts the calls it answered
+func newRPCEndpoint(t *testing.T) (*httptest.Server, *int) {
+	t.Helper()
+	calls := new(int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID json.RawMessage `json:"id"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		*calls++
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":"0x1"}`, req.ID)
+	}))
+	t.Cleanup(server.Close)
+	return server, calls
+}
+
+func Test_ConfigReload(t *testing.T) {
+	old, _ := newRPCEndpoint(t)
+	rotated, rotatedCalls := newRPCEndpoint(t)
+	path := filepath.Join(t.TempDir(), "performer.yaml")
+	writeReloadedConfig(t, path, fmt.Sprintf("chains:\n  - {chainId: 1, name: ethereum, rpcUrl: %s}\n", old.URL))
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("config.Load failed: %v", err)
+	}
+
+	svc, err := openServices(context.Background(), cfg, zap.NewNop())
+	defer svc.close(zap.NewNop())
+	if err != nil {
+		t.Fatalf("openServices failed: %v", err)
+	}
+	quotas := quota.NewFromConfig(cfg.Quotas, nil)
+	yip := svc.performer(cfg, zap.NewNop(), performer.WithQuotas(quotas))
+	level := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	reloader := newConfigReloader(path, cfg, nil, svc, yip, level, zap.NewNop())
+
+	// an admin switch survives reloads that leave protocols alone
+	if err := svc.adapters.SetEnabled(adapters.ProtocolCompoundV3, false); err != nil {
+		t.Fatalf("SetEnabled failed: %v", err)
+	}
+	if err := reloader.reload(context.Background()); err != nil {
+		t.Fatalf("Reloading the unchanged config failed: %v", err)
+	}
+	if got := svc.adapters.Protocols(); len(got) != len(svc.adapters.Registered())-1 {
+		t.Errorf("Expected the admin switch to be kept, got %v", got)
+	}
+
+	writeReloadedConfig(t, path, fmt.Sprintf(`
+grpcPort: 9000
+chains:
+  - {chainId: 1, name: ethereum, rpcUrl: %s}
+protocols: [compound_v3]
+logging: {format: json, level: debug}
+anomaly: {lookback: 24h, minSamples: 12, warningSigma: 2, criticalSigma: 4, minStdDev: 0.0001}
+quotas:
+  enabled: true
+  limits:
+    yield_monitoring: {maxConcurrent: 1}
+`, rotated.URL))
+	if err := reloader.reload(context.Background()); err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	// the rotated endpoint was probed on reload
+	*rotatedCalls = 0
+
+	client, err := svc.chains.Client(1)
+	if err != nil {
+		t.Fatalf("Client failed: %v", err)
+	}
+	if _, err := client.BlockNumber(context.Background()); err != nil || *rotatedCalls != 1 {
+		t.Errorf("Expected calls to go to the rotated endpoint, got %d calls, %v", *rotatedCalls, err)
+	}
+	if got := svc.adapters.Protocols(); len(got) != 1 || got[0] != adapters.ProtocolCompoundV3 {
+		t.Errorf("Expected only compound_v3 to be enabled, got %v", got)
+	}
+	if level.Level() != zapcore.DebugLevel {
+		t.Errorf("Expected the debug level, got %s", level.Level())
+	}
+	if anomalyCfg, _ := yip.Thresholds(); anomalyCfg.WarningSigma != 2 || anomalyCfg.MinSamples != 12 {
+		t.Errorf("Expected the reloaded anomaly thresholds, got %+v", anomalyCfg)
+	}
+	release, err := quotas.Admit(string(performer.TaskTypeYieldMonitoring))
+	if err != nil {
+		t.Fatalf("Admit failed: %v", err)
+	}
+	defer release()
+	if _, err := quotas.Admit(string(performer.TaskTypeYieldMonitoring)); !errors.Is(err, quota.ErrQuotaExceeded) {
+		t.Errorf("Expected the reloaded concurrency limit, got %v", err)
+	}
+	if reloader.current.GrpcPort != 8080 {
+		t.Errorf("Expected the gRPC port to change only on restart, got %d", reloader.current.GrpcPort)
+	}
+
+	// a broken file is rejected as a whole
+	writeReloadedConfig(t, path, "logging: {format: json, level: error}\nconcurrency: 0\n")
+	if err := reloader.reload(context.Background()); err == nil {
+		t.Errorf("Expected the invalid config to be rejected")
+	}
+	if level.Level() != zapcore.DebugLevel {
+		t.Errorf("Expected the level of the rejected config to be ignored, got %s", level.Level())
+	}
+}
+
+func Test_RestartRequired(t *testing.T) {
+	current := config.Default()
+	next := copyConfig(current)
+	next.Logging.Level = "debug"
+	next.Protocols = []string{adapters.ProtocolAaveV3}
+	next.Quotas.Limits[string(performer.TaskTypeBatch)] = quota.Limit{MaxConcurrent: 1}
+	next.TaskQueue.Types[string(performer.TaskTypeBatch)] = taskqueue.Lane{MaxConcurrent: 2, Overflow: taskqueue.OverflowReject}
+	next.TaskQueue.Priorities[string(performer.TaskTypeBatch)] = 5
+	if pending := restartRequired(current, next); len(pending) != 0 {
+		t.Errorf("Expected reloadable settings to need no restart, got %v", pending)
+	}
+
+	next.GrpcPort = 9000
+	next.Quotas.Enabled = false
+	if pending := restartRequired(current, next); len(pending) != 2 || pending[0] != "grpcPort" || pending[1] != "quotas" {
+		t.Errorf("Expected grpcPort and quotas to need a restart, got %v", pending)
+	}
+	if current.Quotas.Limits[string(performer.TaskTypeBatch)].MaxConcurrent != 4 {
+		t.Errorf("Expected copies not to share limits")
+	}
+	if _, ok := current.TaskQueue.Types[string(performer.TaskTypeBatch)]; ok || current.TaskQueue.Priorities[string(performer.TaskTypeBatch)] != 0 {
+		t.Errorf("Expected copies not to share lanes or priorities")
+	}
+}
+
+func writeReloadedConfig(t *testing.T, path, contents string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+}
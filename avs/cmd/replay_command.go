@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+	"github.com/najnomics/crosscow-avs/pkg/config"
+	"github.com/najnomics/crosscow-avs/pkg/export"
+	"github.com/najnomics/crosscow-avs/pkg/logging"
+	"github.com/najnomics/crosscow-avs/pkg/replay"
+)
+
+const replayCommandUsage = `usage: yieldavs replay --chain <chain id> --from <block> --to <block> [flags]
+
+Replays the market reads of yield_monitoring tasks over past blocks of --chain, every
+--step blocks, against the chains of the config. Other --chains are read at their last
+block produced by the time of each step. With --source-chain, --target-chain and
+--amount, each step also compares moving the amount between the chains, as
+cross_chain_yield_check tasks do.
+
+The supply rate and utilization of every market are recorded in the yield history of the
+configured store at the time of each step, and what was read is exported to --out as
+markets and cross_chain files in --format. Past state is only served by archive nodes:
+chains replayed beyond the blocks their node keeps need an archiveRpcUrl. Performers
+sharing a badger store must be stopped first, and the collector's retention prunes
+points older than it keeps.
+
+flags:
+`
+
+// replayOptions are the flags of the replay command
+type replayOptions struct {
+	configPath  string
+	chains      string
+	sourceChain uint64
+	targetChain uint64
+	amount      string
+	out         string
+	format      string
+	cfg         replay.Config
+}
+
+// runReplayCommand runs the replay subcommand with args, the arguments after "replay".
+// The files written are printed to stdout, and usage and errors to stderr. Logs go to
+// stderr as well. It returns the exit code of the process.
+func runReplayCommand(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	opts := replayOptions{}
+	flags := flag.NewFlagSet("replay", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, replayCommandUsage)
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&opts.configPath, "config", os.Getenv("PERFORMER_CONFIG"), "path to the performer YAML config file")
+	flags.Uint64Var(&opts.cfg.ChainID, "chain", 0, "chain whose blocks are stepped through")
+	flags.Uint64Var(&opts.cfg.From, "from", 0, "first block of --chain to replay")
+	flags.Uint64Var(&opts.cfg.To, "to", 0, "last block of --chain to replay")
+	flags.Uint64Var(&opts.cfg.Step, "step", 100, "blocks between steps")
+	flags.StringVar(&opts.chains, "chains", "", "comma separated chains whose markets are read, --chain alone when empty")
+	flags.Uint64Var(&opts.sourceChain, "source-chain", 0, "chain to compare moving --amount from")
+	flags.Uint64Var(&opts.targetChain, "target-chain", 0, "chain to compare moving --amount to")
+	flags.StringVar(&opts.amount, "amount", "", "USDC moved in the comparison, such as 250000")
+	flags.StringVar(&opts.out, "out", "replay", "directory the exports are written to")
+	flags.StringVar(&opts.format, "format", export.FormatCSV, "format of the exports, csv or parquet")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if opts.cfg.ChainID == 0 || opts.cfg.To == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	if err := runReplay(ctx, opts, stdout); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// parse completes the replay config of opts with the chains and comparison flags
+func (opts *replayOptions) parse() error {
+	if err := export.CheckFormat(opts.format); err != nil {
+		return err
+	}
+	for _, field := range strings.Split(opts.chains, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		chainID, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid chain %q in --chains", field)
+		}
+		opts.cfg.ChainIDs = append(opts.cfg.ChainIDs, chainID)
+	}
+	if opts.sourceChain == 0 && opts.targetChain == 0 && opts.amount == "" {
+		return opts.cfg.Validate()
+	}
+	amount, err := canonical.ParseDecimal(opts.amount)
+	if err != nil {
+		return fmt.Errorf("invalid --amount: %w", err)
+	}
+	baseUnits := new(big.Rat).Mul(amount.Rat(), big.NewRat(1_000_000, 1))
+	opts.cfg.CrossChain = &replay.CrossChain{
+		SourceChain: opts.sourceChain,
+		TargetChain: opts.targetChain,
+		Amount:      new(big.Int).Quo(baseUnits.Num(), baseUnits.Denom()),
+	}
+	return opts.cfg.Validate()
+}
+
+// runReplay replays the blocks of opts against the services of the config and exports
+// what was read
+func runReplay(ctx context.Context, opts replayOptions, stdout io.Writer) error {
+	if err := opts.parse(); err != nil {
+		return err
+	}
+	cfg, err := config.Load(opts.configPath)
+	if err != nil {
+		return err
+	}
+	logCfg := cfg.Logging
+	logCfg.Format = logging.FormatConsole
+	l, err := logging.New(logCfg)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = l.Sync() }()
+	if _, l, err = expandSecrets(ctx, cfg, l); err != nil {
+		return err
+	}
+
+	// the replay only reads markets and records them in the store
+	cfg.TaskQueue.Enabled = false
+	cfg.Transactions.Enabled = false
+
+	svc, err := openServices(ctx, cfg, l)
+	defer svc.close(l)
+	if err != nil {
+		return err
+	}
+	report, err := replay.New(svc.chains, svc.adapters, svc.history, l).Run(ctx, opts.cfg)
+	if report == nil {
+		return err
+	}
+	// the steps replayed before a failure are exported all the same
+	written, exportErr := report.Export(opts.out, opts.format)
+	for _, path := range written {
+		fmt.Fprintln(stdout, path)
+	}
+	l.Sugar().Infow("Replayed blocks", "steps", report.Steps, "markets", len(report.Markets), "failures", len(report.Failures))
+	return errors.Join(err, exportErr)
+}
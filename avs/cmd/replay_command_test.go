@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/najnomics/crosscow-avs/pkg/export"
+	"github.com/najnomics/crosscow-avs/pkg/replay"
+)
+
+func Test_ReplayCommandExitCodes(t *testing.T) {
+	t.Setenv("PERFORMER_CONFIG", "")
+
+	testCases := map[string]struct {
+		args   []string
+		code   int
+		stderr string
+	}{
+		"no flags":       {args: nil, code: 2, stderr: "usage: yieldavs replay"},
+		"missing to":     {args: []string{"--chain", "1", "--from", "100"}, code: 2, stderr: "-chain"},
+		"reversed range": {args: []string{"--chain", "1", "--from", "200", "--to", "100"}, code: 1, stderr: "is after to block"},
+		"unknown format": {args: []string{"--chain", "1", "--to", "100", "--format", "xlsx"}, code: 1, stderr: "unknown format"},
+		"invalid chains": {args: []string{"--chain", "1", "--to", "100", "--chains", "1,base"}, code: 1, stderr: "invalid chain"},
+		"no amount":      {args: []string{"--chain", "1", "--to", "100", "--source-chain", "1", "--target-chain", "8453"}, code: 1, stderr: "invalid --amount"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := runReplayCommand(context.Background(), tc.args, &stdout, &stderr); code != tc.code {
+				t.Errorf("Expected exit code %d, got %d: %s", tc.code, code, stderr.String())
+			}
+			if !strings.Contains(stderr.String(), tc.stderr) {
+				t.Errorf("Expected %q on stderr, got %q", tc.stderr, stderr.String())
+			}
+			if stdout.Len() != 0 {
+				t.Errorf("Expected no export, got %q", stdout.String())
+			}
+		})
+	}
+}
+
+func Test_ReplayOptionsParse(t *testing.T) {
+	opts := replayOptions{
+		chains:      "1, 8453",
+		sourceChain: 1,
+		targetChain: 8453,
+		amount:      "250000.5",
+		format:      export.FormatParquet,
+		cfg:         replay.Config{ChainID: 1, From: 100, To: 200, Step: 10},
+	}
+	if err := opts.parse(); err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if len(opts.cfg.ChainIDs) != 2 || opts.cfg.ChainIDs[1] != 8453 {
+		t.Errorf("Unexpected chains %v", opts.cfg.ChainIDs)
+	}
+	if cross := opts.cfg.CrossChain; cross == nil || cross.Amount.String() != "250000500000" {
+		t.Errorf("Expected the amount in base units, got %+v", cross)
+	}
+}
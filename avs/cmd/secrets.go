@@ -0,0 +1,25 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/najnomics/crosscow-avs/pkg/config"
+	"github.com/najnomics/crosscow-avs/pkg/logging"
+	"github.com/najnomics/crosscow-avs/pkg/secrets"
+	"go.uber.org/zap"
+)
+
+// expandSecrets replaces the secret references of cfg with the secrets read from its
+// provider. It returns the store holding them, and l hiding them in every line it logs.
+func expandSecrets(ctx context.Context, cfg *config.Config, l *zap.Logger) (*secrets.Store, *zap.Logger, error) {
+	store, err := secrets.NewFromConfig(ctx, cfg.Secrets, l)
+	if err != nil {
+		return nil, l, fmt.Errorf("secrets: %w", err)
+	}
+	l = logging.WithSecretsHidden(l, store.Redact)
+	if err := store.ExpandAll(ctx, cfg); err != nil {
+		return nil, l, fmt.Errorf("secrets: %w", err)
+	}
+	return store, l, nil
+}
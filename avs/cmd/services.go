@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/addressbook"
+	"github.com/najnomics/crosscow-avs/pkg/attestation"
+	"github.com/najnomics/crosscow-avs/pkg/auth"
+	"github.com/najnomics/crosscow-avs/pkg/cache"
+	"github.com/najnomics/crosscow-avs/pkg/cctp"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/circle"
+	"github.com/najnomics/crosscow-avs/pkg/config"
+	"github.com/najnomics/crosscow-avs/pkg/crossval"
+	"github.com/najnomics/crosscow-avs/pkg/features"
+	"github.com/najnomics/crosscow-avs/pkg/gaswindow"
+	"github.com/najnomics/crosscow-avs/pkg/incentives"
+	"github.com/najnomics/crosscow-avs/pkg/killswitch"
+	"github.com/najnomics/crosscow-avs/pkg/ledger"
+	"github.com/najnomics/crosscow-avs/pkg/notify"
+	"github.com/najnomics/crosscow-avs/pkg/operator"
+	"github.com/najnomics/crosscow-avs/pkg/performer"
+	"github.com/najnomics/crosscow-avs/pkg/policy"
+	"github.com/najnomics/crosscow-avs/pkg/positions"
+	"github.com/najnomics/crosscow-avs/pkg/pricefeed"
+	"github.com/najnomics/crosscow-avs/pkg/quota"
+	"github.com/najnomics/crosscow-avs/pkg/redis"
+	"github.com/najnomics/crosscow-avs/pkg/reputation"
+	"github.com/najnomics/crosscow-avs/pkg/resilience"
+	"github.com/najnomics/crosscow-avs/pkg/scan"
+	"github.com/najnomics/crosscow-avs/pkg/security"
+	"github.com/najnomics/crosscow-avs/pkg/sequencer"
+	"github.com/najnomics/crosscow-avs/pkg/simulate"
+	"github.com/najnomics/crosscow-avs/pkg/snapshot"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+	"github.com/najnomics/crosscow-avs/pkg/subgraph"
+	"github.com/najnomics/crosscow-avs/pkg/taskqueue"
+	"github.com/najnomics/crosscow-avs/pkg/taskschema"
+	"github.com/najnomics/crosscow-avs/pkg/txmgr"
+	"github.com/najnomics/crosscow-avs/pkg/userop"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// notificationDrainTimeout bounds how long queued events are delivered for on shutdown
+const notificationDrainTimeout = 10 * time.Second
+
+// endpointProbeTimeout bounds the calls probing what the RPC endpoints serve
+const endpointProbeTimeout = 10 * time.Second
+
+// services are the stores, connections and clients a performer is built on. The
+// performer server and the task command open the same ones from the config.
+type services struct {
+	kv           store.KV
+	chains       *chain.Manager
+	policies     *resilience.Policies
+	circle       *circle.Client
+	metrics      *prometheus.Registry
+	redis        *redis.Client
+	cache        *cache.Cache
+	queue        *taskqueue.Queue
+	transactions *txmgr.Set
+	attester     *attestation.Attester
+	subgraphs    *subgraph.Set
+	history      *store.SeriesStore
+	book         *addressbook.Book
+	adapters     *adapters.Registry
+	notifier     *notify.Notifier
+	schemas      *taskschema.Set
+	features     *features.Set
+}
+
+// openServices opens the task store and connects the chains and clients of cfg. The
+// returned services must be closed, also when opening failed part way.
+func openServices(ctx context.Context, cfg *config.Config, l *zap.Logger) (*services, error) {
+	s := &services{}
+	kv, err := store.Open(&cfg.Storage)
+	if err != nil {
+		return s, fmt.Errorf("failed to open task store: %w", err)
+	}
+	s.kv = kv
+
+	chains, err := chain.NewManagerFromConfig(ctx, cfg.Chains)
+	if err != nil {
+		return s, fmt.Errorf("failed to connect chain clients: %w", err)
+	}
+	s.chains = chains
+
+	s.policies = resilience.NewPolicies(cfg.Resilience)
+	chains.SetPolicies(s.policies)
+	chains.SetBatching(cfg.Multicall)
+	probeEndpoints(ctx, chains, l)
+
+	if cfg.Circle != nil {
+		s.circle = circle.NewClient(cfg.Circle, s.policies.For(resilience.PolicyCircle))
+	}
+
+	s.metrics = prometheus.NewRegistry()
+	if s.redis, err = redis.NewFromConfig(cfg.Redis, s.metrics, l); err != nil {
+		return s, fmt.Errorf("failed to create redis client: %w", err)
+	}
+	if s.cache, err = cache.NewFromConfig(cfg.Cache, s.redis, s.metrics); err != nil {
+		return s, fmt.Errorf("failed to create result cache: %w", err)
+	}
+	if s.queue, err = taskqueue.NewFromConfig(cfg.TaskQueue, s.metrics); err != nil {
+		return s, fmt.Errorf("failed to create task queue: %w", err)
+	}
+
+	if s.transactions, err = txmgr.NewFromConfig(ctx, cfg.Transactions, chains, s.policies.For(resilience.PolicyAPI)); err != nil {
+		return s, fmt.Errorf("failed to create transaction signer: %w", err)
+	}
+
+	if s.attester, err = attestation.NewFromConfig(ctx, cfg.Attestation, version, attestationSources(cfg), s.policies.For(resilience.PolicyAPI)); err != nil {
+		return s, fmt.Errorf("failed to create attestation signer: %w", err)
+	}
+
+	if s.subgraphs, err = subgraph.NewSet(cfg.Subgraphs, s.policies.For(resilience.PolicySubgraph)); err != nil {
+		return s, fmt.Errorf("failed to configure subgraphs: %w", err)
+	}
+
+	if s.notifier, err = notify.NewFromConfig(cfg.Notifications, s.policies.For(resilience.PolicyAPI), l); err != nil {
+		return s, fmt.Errorf("notifications: %w", err)
+	}
+	if s.notifier != nil {
+		s.policies.OnOpen(performer.NotifyCircuitOpen(s.notifier))
+	}
+
+	if s.schemas, err = taskschema.NewFromConfig(cfg.PayloadSchemas); err != nil {
+		return s, fmt.Errorf("payloadSchemas: %w", err)
+	}
+
+	s.history = store.NewSeriesStore(kv)
+	if s.book, err = addressbook.Default().With(cfg.AddressBook); err != nil {
+		return s, fmt.Errorf("addressBook: %w", err)
+	}
+	if s.book, err = s.book.WithDependencies(cfg.Dependencies); err != nil {
+		return s, fmt.Errorf("dependencies: %w", err)
+	}
+	s.adapters = adapters.NewBookRegistry(chains, s.book)
+	s.adapters.Register(adapters.NewFluidAdapter(cfg.Vaults, chains, s.history, adapters.FluidMarkets(s.book)))
+	for _, vault := range adapters.NewVaultAdapters(cfg.Vaults, chains, s.history) {
+		if _, _, err := s.adapters.Lookup(vault.Protocol()); err == nil {
+			return s, fmt.Errorf("vaults: protocol %s is already registered", vault.Protocol())
+		}
+		s.adapters.Register(vault)
+	}
+	for _, pool := range adapters.NewLPAdapters(cfg.LiquidityPools, chains, s.policies.For(resilience.PolicySubgraph)) {
+		if _, _, err := s.adapters.Lookup(pool.Protocol()); err == nil {
+			return s, fmt.Errorf("liquidityPools: protocol %s is already registered", pool.Protocol())
+		}
+		s.adapters.Register(pool)
+	}
+	for _, fund := range adapters.NewRWAAdapters(cfg.RWA, cfg.Vaults, chains, s.history) {
+		if _, _, err := s.adapters.Lookup(fund.Protocol()); err == nil {
+			return s, fmt.Errorf("rwa: protocol %s is already registered", fund.Protocol())
+		}
+		s.adapters.Register(fund)
+	}
+	for _, market := range adapters.NewPendleAdapters(cfg.Pendle, chains) {
+		if _, _, err := s.adapters.Lookup(market.Protocol()); err == nil {
+			return s, fmt.Errorf("pendle: protocol %s is already registered", market.Protocol())
+		}
+		s.adapters.Register(market)
+	}
+	if err := enableProtocols(s.adapters, cfg.Protocols); err != nil {
+		return s, fmt.Errorf("protocols: %w", err)
+	}
+	if s.features, err = features.New(performer.ExperimentalFeatures(), cfg.Features); err != nil {
+		return s, fmt.Errorf("features: %w", err)
+	}
+	s.adapters.SetFeatures(s.features)
+	return s, nil
+}
+
+// performer creates a performer running on s as configured in cfg. opts are applied
+// last, so they override the configured ones.
+func (s *services) performer(cfg *config.Config, l *zap.Logger, opts ...performer.PerformerOption) *performer.YieldIntelligencePerformer {
+	fallback := cfg.Bridges.Fallback
+	if fallback.AttestationURL == "" {
+		fallback.AttestationURL = cctp.AttestationURL(cfg.Network)
+	}
+	attestations := cctp.NewAttestations(fallback.AttestationURL, 0, s.policies.For(resilience.PolicyCircle))
+	var burns *cctp.Monitor
+	if fallback.Enabled {
+		burns = cctp.NewMonitor(attestations, fallback.AttestationSLA)
+	}
+	configured := []performer.PerformerOption{
+		performer.WithTaskStore(store.NewTaskStore(s.kv)),
+		performer.WithRateHistory(s.history),
+		performer.WithAdapters(s.adapters),
+		performer.WithAddressBook(s.book),
+		performer.WithPriceSources(pricefeed.DefaultUSDCSources(s.chains)),
+		performer.WithAnomalyDetection(cfg.Anomaly),
+		performer.WithStability(cfg.Stability),
+		performer.WithHysteresis(cfg.Hysteresis),
+		performer.WithConcurrency(cfg.Concurrency),
+		performer.WithResultCache(s.cache),
+		performer.WithResultLimits(cfg.Results),
+		performer.WithPayloadSchemas(s.schemas),
+		performer.WithFeatures(s.features),
+		performer.WithMarketScans(scan.NewFromConfig(cfg.MarketSnapshots)),
+		performer.WithSimulator(simulate.NewFromConfig(cfg.Simulation, s.chains, s.policies.For(resilience.PolicyAPI))),
+		performer.WithTransactions(s.transactions),
+		performer.WithUserOperations(userop.NewFromConfig(cfg.UserOperations, s.chains, s.transactions, s.policies.For(resilience.PolicyAPI))),
+		performer.WithSecurityFeed(security.NewFromConfig(cfg.Security, s.policies.For(resilience.PolicyAPI))),
+		performer.WithAuthorization(auth.NewFromConfig(cfg.Authorization)),
+		performer.WithQuotas(quota.NewFromConfig(cfg.Quotas, s.redis)),
+		performer.WithSharedIdempotency(s.redis),
+		performer.WithExecutionLocks(s.redis),
+		performer.WithTaskQueue(s.queue),
+		performer.WithLogRedaction(cfg.Logging),
+		performer.WithCrossValidation(cfg.CrossValidation, s.rateSources(cfg)...),
+		performer.WithIncentives(incentives.NewFromConfig(cfg.Incentives, s.chains)),
+		performer.WithPositions(positions.NewFromConfig(cfg.Positions, s.kv)),
+		performer.WithLedger(ledger.NewFromConfig(cfg.Ledger, s.kv, s.chains)),
+		performer.WithAttestation(s.attester),
+		performer.WithSnapshots(snapshot.NewFromConfig(cfg.Snapshots, s.chains)),
+		performer.WithBridgeRoutes(cfg.Bridges),
+		performer.WithBridgeFallback(cfg.Bridges.Fallback, burns),
+		performer.WithStablecoins(cfg.Stablecoins),
+		performer.WithPolicy(policy.NewFromConfig(cfg.Policy)),
+		performer.WithKillSwitch(killswitch.NewFromConfig(cfg.KillSwitch, s.kv, s.chains)),
+		performer.WithStanding(operator.NewStandingFromConfig(cfg.Operator, s.chains)),
+		performer.WithSequencerChecks(sequencer.NewFromConfig(cfg.Sequencers, s.chains, s.book)),
+		performer.WithReputation(reputation.NewFromConfig(cfg.Reputation, s.kv, s.chains)),
+		performer.WithGasWindows(gaswindow.NewFromConfig(cfg.GasWindows, s.chains)),
+		performer.WithFlashLoans(cfg.FlashLoans),
+		performer.WithPermits(cfg.Permits),
+		performer.WithPlanStore(s.kv, s.redis),
+		performer.WithNonceStore(s.kv, s.redis),
+		performer.WithExecutionStore(s.kv),
+		performer.WithTransferRecovery(cfg.Recovery),
+		performer.WithAttestations(attestations),
+		performer.WithNotifier(s.notifier),
+		performer.WithBuildInfo(buildInfo()),
+	}
+	return performer.NewYieldIntelligencePerformer(l, append(configured, opts...)...)
+}
+
+// close delivers the events still queued and closes the RPC connections and the task
+// store
+func (s *services) close(l *zap.Logger) {
+	ctx, cancel := context.WithTimeout(context.Background(), notificationDrainTimeout)
+	defer cancel()
+	if err := s.notifier.Close(ctx); err != nil {
+		l.Sugar().Warnw("Failed to deliver queued notifications", "dropped", s.notifier.Dropped(), "error", err)
+	}
+	if s.chains != nil {
+		s.chains.Close()
+	}
+	if s.kv != nil {
+		if err := s.kv.Close(); err != nil {
+			l.Sugar().Errorw("Failed to close task store", "error", err)
+		}
+	}
+	if err := s.redis.Close(); err != nil {
+		l.Sugar().Warnw("Failed to close redis client", "error", err)
+	}
+}
+
+// probeEndpoints probes what the endpoints of chainIDs, every chain when none are given,
+// serve, and logs it. Archive endpoints that do not serve historical state are warned
+// about, since pinned reads keep going to the rpc endpoint.
+func probeEndpoints(ctx context.Context, chains *chain.Manager, l *zap.Logger, chainIDs ...uint64) {
+	ctx, cancel := context.WithTimeout(ctx, endpointProbeTimeout)
+	defer cancel()
+	for _, e := range chains.Probe(ctx, chainIDs...) {
+		switch {
+		case e.Error != "":
+			l.Sugar().Warnw("Failed to probe RPC endpoint", "chain", e.Chain, "endpoint", e.Endpoint, "error", e.Error)
+		case e.Endpoint == chain.EndpointArchive && !e.Archive:
+			l.Sugar().Warnw("Archive endpoint does not serve historical state", "chain", e.Chain)
+		default:
+			l.Sugar().Infow("Probed RPC endpoint", "chain", e.Chain, "endpoint", e.Endpoint, "archive", e.Archive, "debug", e.Debug, "trace", e.Trace)
+		}
+	}
+}
+
+// rateSources builds the independent rate sources enabled in cfg, with one source per
+// quorum endpoint of the chain listing the most
+func (s *services) rateSources(cfg *config.Config) []crossval.Source {
+	if !cfg.CrossValidation.Enabled {
+		return nil
+	}
+	var sources []crossval.Source
+	if cfg.CrossValidation.DefiLlama.Enabled {
+		sources = append(sources, crossval.NewDefiLlamaSource(cfg.CrossValidation.DefiLlama, s.policies.For(resilience.PolicyAPI)))
+	}
+	if s.subgraphs.Len() > 0 {
+		sources = append(sources, s.subgraphs)
+	}
+	endpoints := 0
+	for _, c := range cfg.Chains {
+		endpoints = max(endpoints, len(c.QuorumRpcUrls))
+	}
+	for i := 0; i < endpoints; i++ {
+		sources = append(sources, crossval.NewEndpointSource(chain.QuorumEndpoint(i), s.adapters, s.chains))
+	}
+	return sources
+}
+
+// attestationSources identifies the RPC endpoints, subgraphs and feeds cfg reads from
+func attestationSources(cfg *config.Config) []attestation.Source {
+	var sources []attestation.Source
+	for _, c := range cfg.Chains {
+		sources = append(sources, attestation.NewSource("rpc:"+strconv.FormatUint(c.ChainID, 10), c.RpcUrl))
+	}
+	for _, s := range cfg.Subgraphs {
+		sources = append(sources, attestation.NewSource(fmt.Sprintf("subgraph:%s:%d", s.Protocol, s.ChainID), s.Url))
+	}
+	if cfg.Security.Enabled {
+		sources = append(sources, attestation.NewSource("security_feed", cfg.Security.Url))
+	}
+	return sources
+}
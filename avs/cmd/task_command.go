@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/najnomics/crosscow-avs/pkg/config"
+	"github.com/najnomics/crosscow-avs/pkg/logging"
+	"github.com/najnomics/crosscow-avs/pkg/performer"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+	"github.com/najnomics/crosscow-avs/pkg/tracing"
+)
+
+const taskCommandUsage = `usage: yieldavs task run --type <task type> --params <file> [flags]
+
+Runs one task with the handlers of the performer, against the chains and APIs of the
+config, without the Ponos server, and prints its result.
+
+flags:
+`
+
+// runTaskCommand runs the task subcommand with args, the arguments after "task". Results
+// are printed to stdout, and usage and errors to stderr. Logs go to stderr as well. It
+// returns the exit code of the process.
+func runTaskCommand(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] != "run" {
+		fmt.Fprint(stderr, taskCommandUsage)
+		return 2
+	}
+
+	flags := flag.NewFlagSet("task run", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, taskCommandUsage)
+		flags.PrintDefaults()
+	}
+	taskType := flags.String("type", "", "type of the task, such as yield_monitoring")
+	paramsPath := flags.String("params", "", "JSON file with the task parameters, - for stdin")
+	configPath := flags.String("config", os.Getenv("PERFORMER_CONFIG"), "path to the performer YAML config file")
+	taskID := flags.String("id", "", "task id, random when empty")
+	execute := flags.Bool("execute", false, "submit rebalance transactions from the configured account; without it only dry runs succeed")
+	fixtures := registerFixtureFlags(flags)
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if *taskType == "" || *paramsPath == "" {
+		flags.Usage()
+		return 2
+	}
+
+	if err := runTask(ctx, taskOptions{
+		taskType:   *taskType,
+		paramsPath: *paramsPath,
+		configPath: *configPath,
+		taskID:     *taskID,
+		execute:    *execute,
+		fixtures:   fixtures,
+	}, stdout); err != nil {
+		if !errors.Is(err, performer.ErrTaskFailed) {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+		}
+		return 1
+	}
+	return 0
+}
+
+// taskOptions are the flags of the task run command
+type taskOptions struct {
+	taskType   string
+	paramsPath string
+	configPath string
+	taskID     string
+	execute    bool
+	fixtures   *fixtureFlags
+}
+
+// runTask runs one task with a performer built from the config. The task runs like one
+// the operator submitted: it is not authorized, counted against quotas or recorded in the
+// configured store, and rebalances are dry runs unless execution was asked for.
+func runTask(ctx context.Context, opts taskOptions, stdout io.Writer) error {
+	cfg, err := config.Load(opts.configPath)
+	if err != nil {
+		return err
+	}
+	if err := opts.fixtures.apply(cfg); err != nil {
+		return err
+	}
+	logCfg := cfg.Logging
+	logCfg.Format = logging.FormatConsole
+	l, err := logging.New(logCfg)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = l.Sync() }()
+	if _, l, err = expandSecrets(ctx, cfg, l); err != nil {
+		return err
+	}
+
+	cfg.Storage = store.Config{Type: store.StorageTypeMemory}
+	cfg.Authorization.Enabled = false
+	cfg.Quotas.Enabled = false
+	cfg.TaskQueue.Enabled = false
+	if !opts.execute {
+		cfg.Transactions.Enabled = false
+	}
+
+	shutdownTracing, err := tracing.Setup(cfg.Tracing)
+	if err != nil {
+		return fmt.Errorf("failed to set up tracing: %w", err)
+	}
+	defer func() {
+		flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(flushCtx); err != nil {
+			l.Sugar().Errorw("Failed to flush spans", "error", err)
+		}
+	}()
+
+	saveFixtures, err := setupFixtures(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to set up fixtures: %w", err)
+	}
+	defer func() {
+		if err := saveFixtures(); err != nil {
+			l.Sugar().Errorw("Failed to save fixtures", "error", err)
+		}
+	}()
+
+	params, err := readTaskParams(opts.paramsPath)
+	if err != nil {
+		return err
+	}
+
+	svc, err := openServices(ctx, cfg, l)
+	defer svc.close(l)
+	if err != nil {
+		return err
+	}
+	return runLocalTask(ctx, svc.performer(cfg, l), opts.taskType, opts.taskID, params, stdout)
+}
+
+// readTaskParams reads the JSON object of task parameters at path, stdin for "-"
+func readTaskParams(path string) (json.RawMessage, error) {
+	var (
+		data []byte
+		err  error
+	)
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read task parameters: %w", err)
+	}
+	var params map[string]interface{}
+	if err := json.Unmarshal(data, &params); err != nil {
+		return nil, fmt.Errorf("task parameters must be a JSON object: %w", err)
+	}
+	return data, nil
+}
+
+// runLocalTask runs a task of taskType with params and prints its result to w, the
+// error result of failed tasks included. Cancelling ctx cancels the task.
+func runLocalTask(ctx context.Context, yip *performer.YieldIntelligencePerformer, taskType, taskID string, params json.RawMessage, w io.Writer) error {
+	if taskID == "" {
+		taskID = "local-" + logging.NewCorrelationID()
+	}
+	result, err := yip.RunTask(ctx, taskType, taskID, params)
+	if result != nil {
+		if err := printResult(w, result); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// printResult writes result indented when it is JSON and hex encoded when it is ABI
+// encoded
+func printResult(w io.Writer, result []byte) error {
+	if !json.Valid(result) {
+		_, err := fmt.Fprintf(w, "0x%s\n", hex.EncodeToString(result))
+		return err
+	}
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, result, "", "  "); err != nil {
+		return err
+	}
+	indented.WriteByte('\n')
+	_, err := indented.WriteTo(w)
+	return err
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func Test_TaskCommandExitCodes(t *testing.T) {
+	t.Setenv("PERFORMER_CONFIG", "")
+
+	testCases := map[string]struct {
+		args   []string
+		code   int
+		stderr string
+	}{
+		"no subcommand":     {args: nil, code: 2, stderr: "usage: yieldavs task run"},
+		"unknown command":   {args: []string{"list"}, code: 2, stderr: "usage: yieldavs task run"},
+		"missing type":      {args: []string{"run", "--params", "params.json"}, code: 2, stderr: "-type"},
+		"missing params":    {args: []string{"run", "--type", "yield_monitoring", "--params", "testdata/missing.json"}, code: 1, stderr: "failed to read task parameters"},
+		"params not object": {args: []string{"run", "--type", "yield_monitoring", "--params", "task_command_test.go"}, code: 1, stderr: "must be a JSON object"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := runTaskCommand(context.Background(), tc.args, &stdout, &stderr); code != tc.code {
+				t.Errorf("Expected exit code %d, got %d: %s", tc.code, code, stderr.String())
+			}
+			if !strings.Contains(stderr.String(), tc.stderr) {
+				t.Errorf("Expected %q on stderr, got %q", tc.stderr, stderr.String())
+			}
+			if stdout.Len() != 0 {
+				t.Errorf("Expected no result, got %q", stdout.String())
+			}
+		})
+	}
+}
+
+func Test_PrintResult(t *testing.T) {
+	var out bytes.Buffer
+	if err := printResult(&out, []byte(`{"task_type":"yield_monitoring","result":{"protocol":"aave_v3"}}`)); err != nil {
+		t.Fatalf("printResult failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "\n  \"") {
+		t.Errorf("Expected the result to be indented, got %q", out.String())
+	}
+
+	out.Reset()
+	if err := printResult(&out, []byte{0x01, 0x02}); err != nil || out.String() != "0x0102\n" {
+		t.Errorf("Expected the ABI result hex encoded, got %q (%v)", out.String(), err)
+	}
+}
@@ -0,0 +1,215 @@
+// Package abicodec ABI-encodes task results into the Solidity structs consumed by the
+// Yield Intelligence Service Manager and the Uniswap v4 yield hook
+// (see src/interfaces/IYieldIntelligenceAVS.sol).
+package abicodec
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+)
+
+// BasisPointsScale is the value of 100% in basis points, the unit used on-chain for
+// yields, utilization, risk scores and confidence
+const BasisPointsScale = 10000
+
+// YieldData mirrors IYieldIntelligenceAVS.YieldData
+type YieldData struct {
+	ProtocolId   [32]byte
+	ChainId      *big.Int
+	CurrentYield *big.Int // basis points
+	Tvl          *big.Int // USDC base units
+	Utilization  *big.Int // basis points
+	RiskScore    *big.Int // 0-10000
+	Confidence   *big.Int // 0-10000
+	Timestamp    *big.Int
+	IsValid      bool
+}
+
+// YieldOpportunity mirrors IYieldIntelligenceAVS.YieldOpportunity
+type YieldOpportunity struct {
+	ProtocolId     [32]byte
+	ChainId        *big.Int
+	ProjectedYield *big.Int // basis points
+	CurrentYield   *big.Int // basis points
+	Improvement    *big.Int // basis points
+	Confidence     *big.Int // 0-10000
+	MaxAmount      *big.Int // USDC base units
+	ExpiresAt      *big.Int
+	AdditionalData []byte
+}
+
+// RiskMetrics mirrors IYieldIntelligenceAVS.RiskMetrics
+type RiskMetrics struct {
+	ProtocolRisk      *big.Int
+	LiquidityRisk     *big.Int
+	SmartContractRisk *big.Int
+	GovernanceRisk    *big.Int
+	OverallRisk       *big.Int
+	RiskCategory      string
+}
+
+// RebalanceInstruction mirrors IYieldIntelligenceAVS.RebalanceInstruction
+type RebalanceInstruction struct {
+	User             common.Address
+	SourceProtocolId [32]byte
+	TargetProtocolId [32]byte
+	SourceChainId    *big.Int
+	TargetChainId    *big.Int
+	Amount           *big.Int
+	Executed         bool
+}
+
+func mustTuple(components []abi.ArgumentMarshaling) abi.Arguments {
+	t, err := abi.NewType("tuple", "", components)
+	if err != nil {
+		panic(fmt.Sprintf("invalid abi tuple definition: %v", err))
+	}
+	return abi.Arguments{{Type: t}}
+}
+
+var (
+	yieldDataArgs = mustTuple([]abi.ArgumentMarshaling{
+		{Name: "protocolId", Type: "bytes32"},
+		{Name: "chainId", Type: "uint256"},
+		{Name: "currentYield", Type: "uint256"},
+		{Name: "tvl", Type: "uint256"},
+		{Name: "utilization", Type: "uint256"},
+		{Name: "riskScore", Type: "uint256"},
+		{Name: "confidence", Type: "uint256"},
+		{Name: "timestamp", Type: "uint256"},
+		{Name: "isValid", Type: "bool"},
+	})
+
+	yieldOpportunityArgs = mustTuple([]abi.ArgumentMarshaling{
+		{Name: "protocolId", Type: "bytes32"},
+		{Name: "chainId", Type: "uint256"},
+		{Name: "projectedYield", Type: "uint256"},
+		{Name: "currentYield", Type: "uint256"},
+		{Name: "improvement", Type: "uint256"},
+		{Name: "confidence", Type: "uint256"},
+		{Name: "maxAmount", Type: "uint256"},
+		{Name: "expiresAt", Type: "uint256"},
+		{Name: "additionalData", Type: "bytes"},
+	})
+
+	riskMetricsArgs = mustTuple([]abi.ArgumentMarshaling{
+		{Name: "protocolRisk", Type: "uint256"},
+		{Name: "liquidityRisk", Type: "uint256"},
+		{Name: "smartContractRisk", Type: "uint256"},
+		{Name: "governanceRisk", Type: "uint256"},
+		{Name: "overallRisk", Type: "uint256"},
+		{Name: "riskCategory", Type: "string"},
+	})
+
+	rebalanceInstructionArgs = mustTuple([]abi.ArgumentMarshaling{
+		{Name: "user", Type: "address"},
+		{Name: "sourceProtocolId", Type: "bytes32"},
+		{Name: "targetProtocolId", Type: "bytes32"},
+		{Name: "sourceChainId", Type: "uint256"},
+		{Name: "targetChainId", Type: "uint256"},
+		{Name: "amount", Type: "uint256"},
+		{Name: "executed", Type: "bool"},
+	})
+)
+
+// EncodeYieldData returns abi.encode(YieldData)
+func EncodeYieldData(d *YieldData) ([]byte, error) {
+	return pack(yieldDataArgs, d)
+}
+
+// EncodeYieldOpportunity returns abi.encode(YieldOpportunity)
+func EncodeYieldOpportunity(o *YieldOpportunity) ([]byte, error) {
+	return pack(yieldOpportunityArgs, o)
+}
+
+// EncodeRiskMetrics returns abi.encode(RiskMetrics)
+func EncodeRiskMetrics(m *RiskMetrics) ([]byte, error) {
+	return pack(riskMetricsArgs, m)
+}
+
+// EncodeRebalanceInstruction returns abi.encode(RebalanceInstruction)
+func EncodeRebalanceInstruction(r *RebalanceInstruction) ([]byte, error) {
+	return pack(rebalanceInstructionArgs, r)
+}
+
+// DecodeYieldData decodes the output of EncodeYieldData
+func DecodeYieldData(data []byte) (*YieldData, error) {
+	var out YieldData
+	return &out, unpack(yieldDataArgs, data, &out)
+}
+
+// DecodeYieldOpportunity decodes the output of EncodeYieldOpportunity
+func DecodeYieldOpportunity(data []byte) (*YieldOpportunity, error) {
+	var out YieldOpportunity
+	return &out, unpack(yieldOpportunityArgs, data, &out)
+}
+
+// DecodeRiskMetrics decodes the output of EncodeRiskMetrics
+func DecodeRiskMetrics(data []byte) (*RiskMetrics, error) {
+	var out RiskMetrics
+	return &out, unpack(riskMetricsArgs, data, &out)
+}
+
+// DecodeRebalanceInstruction decodes the output of EncodeRebalanceInstruction
+func DecodeRebalanceInstruction(data []byte) (*RebalanceInstruction, error) {
+	var out RebalanceInstruction
+	return &out, unpack(rebalanceInstructionArgs, data, &out)
+}
+
+func pack(args abi.Arguments, v interface{}) ([]byte, error) {
+	encoded, err := args.Pack(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to abi encode %T: %w", v, err)
+	}
+	return encoded, nil
+}
+
+func unpack(args abi.Arguments, data []byte, out interface{}) error {
+	values, err := args.Unpack(data)
+	if err != nil {
+		return fmt.Errorf("failed to abi decode %T: %w", out, err)
+	}
+	if len(values) != 1 {
+		return fmt.Errorf("failed to abi decode %T: expected a single tuple, got %d values", out, len(values))
+	}
+	abi.ConvertType(values[0], out)
+	return nil
+}
+
+// ProtocolId returns the on-chain identifier of a protocol, keccak256 of its upper-cased
+// name (e.g. "aave_v3" -> keccak256("AAVE_V3")), as registered in the task hook
+func ProtocolId(protocol string) [32]byte {
+	return crypto.Keccak256Hash([]byte(strings.ToUpper(protocol)))
+}
+
+// PercentToBasisPoints converts a percentage such as 4.1234 into basis points (412),
+// rounding half-to-even
+func PercentToBasisPoints(percent canonical.Decimal) *big.Int {
+	return toInteger(new(big.Rat).Mul(percent.Rat(), big.NewRat(100, 1)))
+}
+
+// RatioToBasisPoints converts a ratio such as 0.87 into basis points (8700), rounding half-to-even
+func RatioToBasisPoints(ratio canonical.Decimal) *big.Int {
+	return toInteger(new(big.Rat).Mul(ratio.Rat(), big.NewRat(BasisPointsScale, 1)))
+}
+
+// AmountToBaseUnits converts a token amount such as 1000.5 into base units given the token decimals
+func AmountToBaseUnits(amount canonical.Decimal, decimals int) *big.Int {
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	return toInteger(new(big.Rat).Mul(amount.Rat(), new(big.Rat).SetInt(scale)))
+}
+
+func toInteger(r *big.Rat) *big.Int {
+	rounded := canonical.NewDecimalFromRat(r, 0)
+	value, _ := new(big.Int).SetString(rounded.String(), 10)
+	if value == nil || value.Sign() < 0 {
+		return new(big.Int)
+	}
+	return value
+}
@@ -0,0 +1,176 @@
+package abicodec
+
+import (
+	"math/big"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+)
+
+func Test_YieldDataRoundTrip(t *testing.T) {
+	in := &YieldData{
+		ProtocolId:   ProtocolId("aave_v3"),
+		ChainId:      big.NewInt(8453),
+		CurrentYield: big.NewInt(412),
+		Tvl:          big.NewInt(1_000_000_000_000),
+		Utilization:  big.NewInt(8700),
+		RiskScore:    big.NewInt(2500),
+		Confidence:   big.NewInt(9500),
+		Timestamp:    big.NewInt(1700000000),
+		IsValid:      true,
+	}
+
+	encoded, err := EncodeYieldData(in)
+	if err != nil {
+		t.Fatalf("EncodeYieldData failed: %v", err)
+	}
+	// 9 static words, no offset: the struct is encoded in place
+	if len(encoded) != 9*32 {
+		t.Errorf("Expected %d bytes, got %d", 9*32, len(encoded))
+	}
+
+	out, err := DecodeYieldData(encoded)
+	if err != nil {
+		t.Fatalf("DecodeYieldData failed: %v", err)
+	}
+	if out.ProtocolId != in.ProtocolId || out.CurrentYield.Cmp(in.CurrentYield) != 0 || out.Tvl.Cmp(in.Tvl) != 0 || !out.IsValid {
+		t.Errorf("Round trip mismatch: %+v", out)
+	}
+}
+
+func Test_DynamicStructsRoundTrip(t *testing.T) {
+	opportunity := &YieldOpportunity{
+		ProtocolId:     ProtocolId("compound_v3"),
+		ChainId:        big.NewInt(42161),
+		ProjectedYield: big.NewInt(530),
+		CurrentYield:   big.NewInt(410),
+		Improvement:    big.NewInt(120),
+		Confidence:     big.NewInt(9000),
+		MaxAmount:      big.NewInt(250_000_000_000),
+		ExpiresAt:      big.NewInt(1700003600),
+		AdditionalData: []byte{0xde, 0xad},
+	}
+	encoded, err := EncodeYieldOpportunity(opportunity)
+	if err != nil {
+		t.Fatalf("EncodeYieldOpportunity failed: %v", err)
+	}
+	decoded, err := DecodeYieldOpportunity(encoded)
+	if err != nil {
+		t.Fatalf("DecodeYieldOpportunity failed: %v", err)
+	}
+	if decoded.Improvement.Int64() != 120 || string(decoded.AdditionalData) != "\xde\xad" {
+		t.Errorf("Round trip mismatch: %+v", decoded)
+	}
+
+	metrics := &RiskMetrics{
+		ProtocolRisk:      big.NewInt(2000),
+		LiquidityRisk:     big.NewInt(1500),
+		SmartContractRisk: big.NewInt(1000),
+		GovernanceRisk:    big.NewInt(500),
+		OverallRisk:       big.NewInt(1250),
+		RiskCategory:      "LOW",
+	}
+	encoded, err = EncodeRiskMetrics(metrics)
+	if err != nil {
+		t.Fatalf("EncodeRiskMetrics failed: %v", err)
+	}
+	decodedMetrics, err := DecodeRiskMetrics(encoded)
+	if err != nil {
+		t.Fatalf("DecodeRiskMetrics failed: %v", err)
+	}
+	if decodedMetrics.RiskCategory != "LOW" || decodedMetrics.OverallRisk.Int64() != 1250 {
+		t.Errorf("Round trip mismatch: %+v", decodedMetrics)
+	}
+
+	instruction := &RebalanceInstruction{
+		User:             common.HexToAddress("0x00000000000000000000000000000000000000aa"),
+		SourceProtocolId: ProtocolId("aave_v3"),
+		TargetProtocolId: ProtocolId("morpho"),
+		SourceChainId:    big.NewInt(1),
+		TargetChainId:    big.NewInt(8453),
+		Amount:           big.NewInt(1_000_500_000),
+		Executed:         true,
+	}
+	encoded, err = EncodeRebalanceInstruction(instruction)
+	if err != nil {
+		t.Fatalf("EncodeRebalanceInstruction failed: %v", err)
+	}
+	decodedInstruction, err := DecodeRebalanceInstruction(encoded)
+	if err != nil {
+		t.Fatalf("DecodeRebalanceInstruction failed: %v", err)
+	}
+	if decodedInstruction.User != instruction.User || decodedInstruction.Amount.Cmp(instruction.Amount) != 0 {
+		t.Errorf("Round trip mismatch: %+v", decodedInstruction)
+	}
+}
+
+var (
+	structPattern = regexp.MustCompile(`struct (\w+) \{([^}]*)\}`)
+	fieldPattern  = regexp.MustCompile(`(?m)^\s*([\w\[\]]+)\s+(\w+);`)
+)
+
+// solidityStructs returns the fields of the structs of a Solidity source, as "type name"
+func solidityStructs(t *testing.T, path string) map[string][]string {
+	t.Helper()
+	source, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	structs := make(map[string][]string)
+	for _, match := range structPattern.FindAllStringSubmatch(string(source), -1) {
+		for _, field := range fieldPattern.FindAllStringSubmatch(match[2], -1) {
+			structs[match[1]] = append(structs[match[1]], field[1]+" "+field[2])
+		}
+	}
+	return structs
+}
+
+func Test_TuplesMatchTheInterface(t *testing.T) {
+	structs := solidityStructs(t, "../../../src/interfaces/IYieldIntelligenceAVS.sol")
+	for name, args := range map[string]abi.Arguments{
+		"YieldData":            yieldDataArgs,
+		"YieldOpportunity":     yieldOpportunityArgs,
+		"RiskMetrics":          riskMetricsArgs,
+		"RebalanceInstruction": rebalanceInstructionArgs,
+	} {
+		t.Run(name, func(t *testing.T) {
+			want, ok := structs[name]
+			if !ok {
+				t.Fatalf("Expected IYieldIntelligenceAVS to define %s", name)
+			}
+			tuple := args[0].Type
+			got := make([]string, len(tuple.TupleElems))
+			for i, elem := range tuple.TupleElems {
+				got[i] = elem.String() + " " + tuple.TupleRawNames[i]
+			}
+			if strings.Join(got, ", ") != strings.Join(want, ", ") {
+				t.Errorf("Expected the fields of %s to be %v, got %v", name, want, got)
+			}
+		})
+	}
+}
+
+func Test_ProtocolIdMatchesHook(t *testing.T) {
+	want := crypto.Keccak256Hash([]byte("AAVE_V3"))
+	if ProtocolId("aave_v3") != want {
+		t.Errorf("Expected keccak256(\"AAVE_V3\"), got %x", ProtocolId("aave_v3"))
+	}
+}
+
+func Test_Conversions(t *testing.T) {
+	if got := PercentToBasisPoints(canonical.APY(4.1250)); got.Int64() != 412 {
+		t.Errorf("Expected 4.1250%% to be 412 bps (half-even), got %s", got)
+	}
+	if got := RatioToBasisPoints(canonical.Ratio(0.87)); got.Int64() != 8700 {
+		t.Errorf("Expected 0.87 to be 8700 bps, got %s", got)
+	}
+	if got := AmountToBaseUnits(canonical.NewDecimalFromFloat(1000.5, 6), 6); got.Int64() != 1_000_500_000 {
+		t.Errorf("Expected 1000.5 USDC to be 1000500000 base units, got %s", got)
+	}
+}
@@ -0,0 +1,583 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/irm"
+	"github.com/najnomics/crosscow-avs/pkg/rates"
+)
+
+// ProtocolAaveV3 is the task parameter name of Aave v3
+const ProtocolAaveV3 = "aave_v3"
+
+// Pool.getReserveData returns a static struct, which is ABI encoded like its flattened fields
+const aavePoolABIJson = `[
+	{"name":"getReserveData","type":"function","stateMutability":"view",
+	 "inputs":[{"name":"asset","type":"address"}],
+	 "outputs":[
+		{"name":"configuration","type":"uint256"},
+		{"name":"liquidityIndex","type":"uint128"},
+		{"name":"currentLiquidityRate","type":"uint128"},
+		{"name":"variableBorrowIndex","type":"uint128"},
+		{"name":"currentVariableBorrowRate","type":"uint128"},
+		{"name":"currentStableBorrowRate","type":"uint128"},
+		{"name":"lastUpdateTimestamp","type":"uint40"},
+		{"name":"id","type":"uint16"},
+		{"name":"aTokenAddress","type":"address"},
+		{"name":"stableDebtTokenAddress","type":"address"},
+		{"name":"variableDebtTokenAddress","type":"address"},
+		{"name":"interestRateStrategyAddress","type":"address"},
+		{"name":"accruedToTreasury","type":"uint128"},
+		{"name":"unbacked","type":"uint128"},
+		{"name":"isolationModeTotalDebt","type":"uint128"}
+	]},
+	{"name":"ReserveDataUpdated","type":"event","anonymous":false,"inputs":[
+		{"name":"reserve","type":"address","indexed":true},
+		{"name":"liquidityRate","type":"uint256","indexed":false},
+		{"name":"stableBorrowRate","type":"uint256","indexed":false},
+		{"name":"variableBorrowRate","type":"uint256","indexed":false},
+		{"name":"liquidityIndex","type":"uint256","indexed":false},
+		{"name":"variableBorrowIndex","type":"uint256","indexed":false}
+	]},
+	{"name":"ADDRESSES_PROVIDER","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"address"}]},
+	{"name":"getReserveDeficit","type":"function","stateMutability":"view","inputs":[{"name":"asset","type":"address"}],"outputs":[{"name":"","type":"uint256"}]},
+	{"name":"supply","type":"function","stateMutability":"nonpayable",
+	 "inputs":[{"name":"asset","type":"address"},{"name":"amount","type":"uint256"},{"name":"onBehalfOf","type":"address"},{"name":"referralCode","type":"uint16"}],
+	 "outputs":[]},
+	{"name":"supplyWithPermit","type":"function","stateMutability":"nonpayable",
+	 "inputs":[{"name":"asset","type":"address"},{"name":"amount","type":"uint256"},{"name":"onBehalfOf","type":"address"},{"name":"referralCode","type":"uint16"},
+		{"name":"deadline","type":"uint256"},{"name":"permitV","type":"uint8"},{"name":"permitR","type":"bytes32"},{"name":"permitS","type":"bytes32"}],
+	 "outputs":[]},
+	{"name":"withdraw","type":"function","stateMutability":"nonpayable",
+	 "inputs":[{"name":"asset","type":"address"},{"name":"amount","type":"uint256"},{"name":"to","type":"address"}],
+	 "outputs":[{"name":"","type":"uint256"}]}
+]`
+
+// DefaultReserveInterestRateStrategyV2.getInterestRateData, values in ray
+const aaveStrategyABIJson = `[
+	{"name":"getInterestRateData","type":"function","stateMutability":"view",
+	 "inputs":[{"name":"reserve","type":"address"}],
+	 "outputs":[
+		{"name":"optimalUsageRatio","type":"uint256"},
+		{"name":"baseVariableBorrowRate","type":"uint256"},
+		{"name":"variableRateSlope1","type":"uint256"},
+		{"name":"variableRateSlope2","type":"uint256"}
+	]}
+]`
+
+// PoolAddressesProvider and AaveOracle getters locating the price source of a reserve
+// and the admin of the pool's roles. Prices are in USD with 8 decimals.
+const aaveOracleABIJson = `[
+	{"name":"getPoolConfigurator","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"address"}]},
+	{"name":"AssetSourceUpdated","type":"event","anonymous":false,"inputs":[{"name":"asset","type":"address","indexed":true},{"name":"source","type":"address","indexed":true}]},
+	{"name":"getACLAdmin","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"address"}]},
+	{"name":"getPriceOracle","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"address"}]},
+	{"name":"getSourceOfAsset","type":"function","stateMutability":"view","inputs":[{"name":"asset","type":"address"}],"outputs":[{"name":"","type":"address"}]},
+	{"name":"getAssetPrice","type":"function","stateMutability":"view","inputs":[{"name":"asset","type":"address"}],"outputs":[{"name":"","type":"uint256"}]}
+]`
+
+const aaveOracleDecimals = 8
+
+// PoolConfigurator events halting, removing or reconfiguring a reserve, and the setter of
+// supply caps governance queues
+const aaveConfiguratorABIJson = `[
+	{"name":"setSupplyCap","type":"function","stateMutability":"nonpayable","inputs":[{"name":"asset","type":"address"},{"name":"newSupplyCap","type":"uint256"}],"outputs":[]},
+	{"name":"SupplyCapChanged","type":"event","anonymous":false,"inputs":[{"name":"asset","type":"address","indexed":true},{"name":"oldSupplyCap","type":"uint256","indexed":false},{"name":"newSupplyCap","type":"uint256","indexed":false}]},
+	{"name":"BorrowCapChanged","type":"event","anonymous":false,"inputs":[{"name":"asset","type":"address","indexed":true},{"name":"oldBorrowCap","type":"uint256","indexed":false},{"name":"newBorrowCap","type":"uint256","indexed":false}]},
+	{"name":"ReserveFactorChanged","type":"event","anonymous":false,"inputs":[{"name":"asset","type":"address","indexed":true},{"name":"oldReserveFactor","type":"uint256","indexed":false},{"name":"newReserveFactor","type":"uint256","indexed":false}]},
+	{"name":"ReserveInterestRateStrategyChanged","type":"event","anonymous":false,"inputs":[{"name":"asset","type":"address","indexed":true},{"name":"oldStrategy","type":"address","indexed":false},{"name":"newStrategy","type":"address","indexed":false}]},
+	{"name":"ReservePaused","type":"event","anonymous":false,"inputs":[{"name":"asset","type":"address","indexed":true},{"name":"paused","type":"bool","indexed":false}]},
+	{"name":"ReserveFrozen","type":"event","anonymous":false,"inputs":[{"name":"asset","type":"address","indexed":true},{"name":"frozen","type":"bool","indexed":false}]},
+	{"name":"ReserveActive","type":"event","anonymous":false,"inputs":[{"name":"asset","type":"address","indexed":true},{"name":"active","type":"bool","indexed":false}]},
+	{"name":"ReserveDropped","type":"event","anonymous":false,"inputs":[{"name":"asset","type":"address","indexed":true}]}
+]`
+
+// RewardsController getters of the emissions to holders of an aToken
+const aaveRewardsABIJson = `[
+	{"name":"getRewardsByAsset","type":"function","stateMutability":"view","inputs":[{"name":"asset","type":"address"}],"outputs":[{"name":"","type":"address[]"}]},
+	{"name":"getRewardsData","type":"function","stateMutability":"view",
+	 "inputs":[{"name":"asset","type":"address"},{"name":"reward","type":"address"}],
+	 "outputs":[
+		{"name":"index","type":"uint256"},
+		{"name":"emissionPerSecond","type":"uint256"},
+		{"name":"lastUpdateTimestamp","type":"uint256"},
+		{"name":"distributionEnd","type":"uint256"}
+	]}
+]`
+
+var (
+	aavePoolABI     = chain.MustParseABI(aavePoolABIJson)
+	aaveRewardsABI  = chain.MustParseABI(aaveRewardsABIJson)
+	aaveStrategyABI = chain.MustParseABI(aaveStrategyABIJson)
+	aaveOracleABI   = chain.MustParseABI(aaveOracleABIJson)
+
+	aaveConfiguratorABI = chain.MustParseABI(aaveConfiguratorABIJson)
+)
+
+// AaveV3Market locates the USDC reserve of an Aave v3 deployment
+type AaveV3Market struct {
+	Pool  common.Address
+	Asset common.Address
+
+	// RewardsController pays the incentives of the deployment, none are read when unset
+	RewardsController common.Address
+}
+
+// AaveV3Adapter reads USDC reserves from Aave v3 pools
+type AaveV3Adapter struct {
+	chains  *chain.Manager
+	markets map[uint64]AaveV3Market
+}
+
+// NewAaveV3Adapter creates an adapter for the given per-chain markets
+func NewAaveV3Adapter(chains *chain.Manager, markets map[uint64]AaveV3Market) *AaveV3Adapter {
+	return &AaveV3Adapter{chains: chains, markets: markets}
+}
+
+func (a *AaveV3Adapter) Protocol() string {
+	return ProtocolAaveV3
+}
+
+func (a *AaveV3Adapter) ChainIDs() []uint64 {
+	return sortedChainIDs(a.markets)
+}
+
+func (a *AaveV3Adapter) MarketState(ctx context.Context, chainID uint64) (*MarketState, error) {
+	market, ok := a.markets[chainID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s on %d", ErrUnsupportedChain, ProtocolAaveV3, chainID)
+	}
+	return a.marketState(ctx, chainID, market.Pool, market.Asset)
+}
+
+// AssetMarketState reads the reserve of asset in the pool holding the USDC reserve
+func (a *AaveV3Adapter) AssetMarketState(ctx context.Context, chainID uint64, asset common.Address) (*MarketState, error) {
+	market, ok := a.markets[chainID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s on %d", ErrUnsupportedChain, ProtocolAaveV3, chainID)
+	}
+	return a.marketState(ctx, chainID, market.Pool, asset)
+}
+
+func (a *AaveV3Adapter) marketState(ctx context.Context, chainID uint64, pool, asset common.Address) (*MarketState, error) {
+	client, err := a.chains.ClientFor(chainID, ProtocolAaveV3)
+	if err != nil {
+		return nil, err
+	}
+
+	reserve, err := chain.CallView(ctx, client, pool, aavePoolABI, "getReserveData", asset)
+	if err != nil {
+		return nil, err
+	}
+	configuration := reserve[0].(*big.Int)
+	aToken := reserve[8].(common.Address)
+	if aToken == (common.Address{}) {
+		return nil, fmt.Errorf("%w: %s has no %s reserve on %d", ErrAssetNotListed, ProtocolAaveV3, asset.Hex(), chainID)
+	}
+	variableDebtToken := reserve[10].(common.Address)
+	strategy := reserve[11].(common.Address)
+
+	batch := chain.NewBatch(client)
+	supplyCall := batch.Add(aToken, erc20ABI, "totalSupply")
+	borrowCall := batch.Add(variableDebtToken, erc20ABI, "totalSupply")
+	ratesCall := batch.Add(strategy, aaveStrategyABI, "getInterestRateData", asset)
+	if err := batch.Do(ctx); err != nil {
+		return nil, err
+	}
+	totalSupply, err := supplyCall.Uint()
+	if err != nil {
+		return nil, err
+	}
+	totalBorrow, err := borrowCall.Uint()
+	if err != nil {
+		return nil, err
+	}
+	strategyRates, err := ratesCall.Values()
+	if err != nil {
+		return nil, err
+	}
+
+	return &MarketState{
+		Protocol: ProtocolAaveV3,
+		ChainID:  chainID,
+		Pool: irm.Pool{
+			TotalSupply: totalSupply,
+			TotalBorrow: totalBorrow,
+			Model: &irm.KinkModel{
+				OptimalUtilization: rates.Ray(strategyRates[0].(*big.Int)),
+				BaseBorrowRate:     rates.Ray(strategyRates[1].(*big.Int)),
+				Slope1:             rates.Ray(strategyRates[2].(*big.Int)),
+				Slope2:             rates.Ray(strategyRates[3].(*big.Int)),
+				ReserveFactor:      rates.Scaled(aaveReserveFactor(configuration), 4),
+			},
+		},
+	}, nil
+}
+
+// RiskState reads the caps and flags of the reserve configuration, the deficit Aave
+// v3.3 accounts bad debt in, and the price source of the reserve
+func (a *AaveV3Adapter) RiskState(ctx context.Context, chainID uint64) (*RiskState, error) {
+	market, ok := a.markets[chainID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s on %d", ErrUnsupportedChain, ProtocolAaveV3, chainID)
+	}
+	client, err := a.chains.ClientFor(chainID, ProtocolAaveV3)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := chain.NewBatch(client)
+	reserveCall := batch.Add(market.Pool, aavePoolABI, "getReserveData", market.Asset)
+	deficitCall := batch.Add(market.Pool, aavePoolABI, "getReserveDeficit", market.Asset)
+	if err := batch.Do(ctx); err != nil {
+		return nil, err
+	}
+	reserve, err := reserveCall.Values()
+	if err != nil {
+		return nil, err
+	}
+	configuration := reserve[0].(*big.Int)
+	unit := new(big.Int).Exp(big.NewInt(10), configBits(configuration, 48, 8), nil)
+	state := &RiskState{
+		Paused: configBits(configuration, 56, 1).Sign() == 0 || configBits(configuration, 60, 1).Sign() != 0,
+		Frozen: configBits(configuration, 57, 1).Sign() != 0,
+	}
+	// caps are in whole tokens, zero meaning uncapped
+	if borrowCap := configBits(configuration, 80, 36); borrowCap.Sign() > 0 {
+		state.BorrowCap = borrowCap.Mul(borrowCap, unit)
+	}
+	if supplyCap := configBits(configuration, 116, 36); supplyCap.Sign() > 0 {
+		state.SupplyCap = supplyCap.Mul(supplyCap, unit)
+	}
+
+	deficit, err := deficitCall.Uint()
+	switch {
+	case unsupported(ctx, err):
+		// pools before v3.3 do not track deficits
+	case err != nil:
+		return nil, err
+	default:
+		state.BadDebt = deficit
+	}
+
+	if state.Oracle, err = a.oracle(ctx, client, market); err != nil {
+		return nil, err
+	}
+	if state.Timestamp, err = blockTime(ctx, client); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func (a *AaveV3Adapter) oracle(ctx context.Context, client chain.Client, market AaveV3Market) (*Oracle, error) {
+	provider, err := chain.CallView(ctx, client, market.Pool, aavePoolABI, "ADDRESSES_PROVIDER")
+	if err != nil {
+		return nil, err
+	}
+	priceOracle, err := chain.CallView(ctx, client, provider[0].(common.Address), aaveOracleABI, "getPriceOracle")
+	if err != nil {
+		return nil, err
+	}
+	oracleAddress := priceOracle[0].(common.Address)
+	batch := chain.NewBatch(client)
+	sourceCall := batch.Add(oracleAddress, aaveOracleABI, "getSourceOfAsset", market.Asset)
+	priceCall := batch.Add(oracleAddress, aaveOracleABI, "getAssetPrice", market.Asset)
+	if err := batch.Do(ctx); err != nil {
+		return nil, err
+	}
+	source, err := sourceCall.Values()
+	if err != nil {
+		return nil, err
+	}
+	price, err := priceCall.Uint()
+	if err != nil {
+		return nil, err
+	}
+	oracle := &Oracle{Feed: source[0].(common.Address), Price: price, Decimals: aaveOracleDecimals}
+	if oracle.UpdatedAt, err = roundUpdatedAt(ctx, client, oracle.Feed); err != nil {
+		return nil, err
+	}
+	return oracle, nil
+}
+
+// Governance inspects the PoolAddressesProvider, which is the immutable admin of the pool
+// proxy and upgrades it through its owner, and the ACL admin granting the roles that
+// configure reserves. Emergency admins hold a role that cannot be listed, so the pause
+// guardian is left unknown.
+func (a *AaveV3Adapter) Governance(ctx context.Context, chainID uint64) (*GovernanceState, error) {
+	market, ok := a.markets[chainID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s on %d", ErrUnsupportedChain, ProtocolAaveV3, chainID)
+	}
+	client, err := a.chains.ClientFor(chainID, ProtocolAaveV3)
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := chain.CallView(ctx, client, market.Pool, aavePoolABI, "ADDRESSES_PROVIDER")
+	if err != nil {
+		return nil, err
+	}
+	providerAddress := provider[0].(common.Address)
+	state := &GovernanceState{}
+	if state.ProxyAdmin, err = inspectController(ctx, client, providerAddress); err != nil {
+		return nil, err
+	}
+	aclAdmin, err := chain.CallView(ctx, client, providerAddress, aaveOracleABI, "getACLAdmin")
+	if err != nil {
+		return nil, err
+	}
+	if state.Governor, err = inspectController(ctx, client, aclAdmin[0].(common.Address)); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// PendingCapChanges returns the setSupplyCap calls on the reserve queued in the timelocks
+// among the ACL admin and its controllers. Changes proposed through payloads of Aave
+// governance v3 are not queued as calls and are not seen.
+func (a *AaveV3Adapter) PendingCapChanges(ctx context.Context, chainID uint64, lookback uint64) ([]CapChange, error) {
+	market, ok := a.markets[chainID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s on %d", ErrUnsupportedChain, ProtocolAaveV3, chainID)
+	}
+	client, err := a.chains.ClientFor(chainID, ProtocolAaveV3)
+	if err != nil {
+		return nil, err
+	}
+
+	reserve, err := chain.CallView(ctx, client, market.Pool, aavePoolABI, "getReserveData", market.Asset)
+	if err != nil {
+		return nil, err
+	}
+	unit := new(big.Int).Exp(big.NewInt(10), configBits(reserve[0].(*big.Int), 48, 8), nil)
+	provider, err := chain.CallView(ctx, client, market.Pool, aavePoolABI, "ADDRESSES_PROVIDER")
+	if err != nil {
+		return nil, err
+	}
+	providerAddress := provider[0].(common.Address)
+	configurator, err := chain.CallView(ctx, client, providerAddress, aaveOracleABI, "getPoolConfigurator")
+	if err != nil {
+		return nil, err
+	}
+	aclAdmin, err := chain.CallView(ctx, client, providerAddress, aaveOracleABI, "getACLAdmin")
+	if err != nil {
+		return nil, err
+	}
+	governor, err := inspectController(ctx, client, aclAdmin[0].(common.Address))
+	if err != nil {
+		return nil, err
+	}
+
+	setSupplyCap := aaveConfiguratorABI.Methods["setSupplyCap"]
+	changes := []CapChange{}
+	for _, timelock := range timelocks(governor) {
+		calls, err := queuedCalls(ctx, client, timelock, lookback)
+		if err != nil {
+			return nil, err
+		}
+		for _, call := range calls {
+			if call.Target != configurator[0].(common.Address) || len(call.Data) < 4 || !bytes.Equal(call.Data[:4], setSupplyCap.ID) {
+				continue
+			}
+			args, err := setSupplyCap.Inputs.Unpack(call.Data[4:])
+			if err != nil || args[0].(common.Address) != market.Asset {
+				continue
+			}
+			// caps are in whole tokens, zero meaning uncapped
+			change := CapChange{Call: call}
+			if supplyCap := args[1].(*big.Int); supplyCap.Sign() > 0 {
+				change.SupplyCap = new(big.Int).Mul(supplyCap, unit)
+			}
+			changes = append(changes, change)
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Call.BlockNumber < changes[j].Call.BlockNumber })
+	return changes, nil
+}
+
+// EmergencyEvents returns pauses, freezes, deactivations and removals of the reserve by
+// the PoolConfigurator, and upgrades of the pool. Unpausing and unfreezing emit the same
+// events and are reported too.
+func (a *AaveV3Adapter) EmergencyEvents(ctx context.Context, chainID uint64, lookback uint64) (*EmergencyLog, error) {
+	market, ok := a.markets[chainID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s on %d", ErrUnsupportedChain, ProtocolAaveV3, chainID)
+	}
+	client, err := a.chains.ClientFor(chainID, ProtocolAaveV3)
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := chain.CallView(ctx, client, market.Pool, aavePoolABI, "ADDRESSES_PROVIDER")
+	if err != nil {
+		return nil, err
+	}
+	configurator, err := chain.CallView(ctx, client, provider[0].(common.Address), aaveOracleABI, "getPoolConfigurator")
+	if err != nil {
+		return nil, err
+	}
+	return filterEvents(ctx, client, lookback,
+		eventFilter{
+			contract:    configurator[0].(common.Address),
+			contractABI: aaveConfiguratorABI,
+			events:      []string{"ReservePaused", "ReserveFrozen", "ReserveActive", "ReserveDropped"},
+			asset:       &market.Asset,
+		},
+		eventFilter{contract: market.Pool, contractABI: proxyEventsABI, events: []string{"Upgraded"}},
+	)
+}
+
+// WatchedEvents returns the pool's rate updates of the reserve, which every supply,
+// borrow, repay and liquidation emits, pool upgrades, cap, factor, strategy and status
+// changes of the reserve by the PoolConfigurator and changes of its price source
+func (a *AaveV3Adapter) WatchedEvents(ctx context.Context, chainID uint64) ([]EventWatch, error) {
+	market, ok := a.markets[chainID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s on %d", ErrUnsupportedChain, ProtocolAaveV3, chainID)
+	}
+	client, err := a.chains.ClientFor(chainID, ProtocolAaveV3)
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := chain.CallView(ctx, client, market.Pool, aavePoolABI, "ADDRESSES_PROVIDER")
+	if err != nil {
+		return nil, err
+	}
+	configurator, err := chain.CallView(ctx, client, provider[0].(common.Address), aaveOracleABI, "getPoolConfigurator")
+	if err != nil {
+		return nil, err
+	}
+	priceOracle, err := chain.CallView(ctx, client, provider[0].(common.Address), aaveOracleABI, "getPriceOracle")
+	if err != nil {
+		return nil, err
+	}
+
+	pool, err := watch(market.Pool, aavePoolABI, &market.Asset, "ReserveDataUpdated")
+	if err != nil {
+		return nil, err
+	}
+	upgrades, err := watch(market.Pool, proxyEventsABI, nil, "Upgraded")
+	if err != nil {
+		return nil, err
+	}
+	reserve, err := watch(configurator[0].(common.Address), aaveConfiguratorABI, &market.Asset,
+		"SupplyCapChanged", "BorrowCapChanged", "ReserveFactorChanged", "ReserveInterestRateStrategyChanged",
+		"ReservePaused", "ReserveFrozen", "ReserveActive", "ReserveDropped")
+	if err != nil {
+		return nil, err
+	}
+	source, err := watch(priceOracle[0].(common.Address), aaveOracleABI, &market.Asset, "AssetSourceUpdated")
+	if err != nil {
+		return nil, err
+	}
+	return []EventWatch{pool, upgrades, reserve, source}, nil
+}
+
+// Incentives reads the emissions of the RewardsController to aToken holders
+func (a *AaveV3Adapter) Incentives(ctx context.Context, chainID uint64) (*Incentives, error) {
+	market, ok := a.markets[chainID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s on %d", ErrUnsupportedChain, ProtocolAaveV3, chainID)
+	}
+	client, err := a.chains.ClientFor(chainID, ProtocolAaveV3)
+	if err != nil {
+		return nil, err
+	}
+	if market.RewardsController == (common.Address{}) {
+		return incentivesAt(ctx, client, nil)
+	}
+
+	reserve, err := chain.CallView(ctx, client, market.Pool, aavePoolABI, "getReserveData", market.Asset)
+	if err != nil {
+		return nil, err
+	}
+	aToken := reserve[8].(common.Address)
+	rewards, err := chain.CallView(ctx, client, market.RewardsController, aaveRewardsABI, "getRewardsByAsset", aToken)
+	if err != nil {
+		return nil, err
+	}
+	var emissions []Emission
+	for _, token := range rewards[0].([]common.Address) {
+		data, err := chain.CallView(ctx, client, market.RewardsController, aaveRewardsABI, "getRewardsData", aToken, token)
+		if err != nil {
+			return nil, err
+		}
+		decimals, err := tokenDecimals(ctx, client, token)
+		if err != nil {
+			return nil, err
+		}
+		emissions = append(emissions, Emission{
+			Token:     token,
+			Decimals:  decimals,
+			PerSecond: data[1].(*big.Int),
+			EndsAt:    data[3].(*big.Int).Uint64(),
+		})
+	}
+	return incentivesAt(ctx, client, emissions)
+}
+
+// PositionOf reads the aToken balance of account, which grows with accrued interest
+func (a *AaveV3Adapter) PositionOf(ctx context.Context, chainID uint64, account common.Address) (*big.Int, error) {
+	market, ok := a.markets[chainID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s on %d", ErrUnsupportedChain, ProtocolAaveV3, chainID)
+	}
+	client, err := a.chains.ClientFor(chainID, ProtocolAaveV3)
+	if err != nil {
+		return nil, err
+	}
+	reserve, err := chain.CallView(ctx, client, market.Pool, aavePoolABI, "getReserveData", market.Asset)
+	if err != nil {
+		return nil, err
+	}
+	return chain.CallUint(ctx, client, reserve[8].(common.Address), erc20ABI, "balanceOf", account)
+}
+
+// SupplyCall builds Pool.supply of amount USDC credited to onBehalfOf
+func (a *AaveV3Adapter) SupplyCall(chainID uint64, onBehalfOf common.Address, amount *big.Int) (*chain.Call, error) {
+	market, ok := a.markets[chainID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s on %d", ErrUnsupportedChain, ProtocolAaveV3, chainID)
+	}
+	return packCall(market.Pool, aavePoolABI, "supply", market.Asset, amount, onBehalfOf, uint16(0))
+}
+
+// SupplyWithPermitCall builds Pool.supplyWithPermit of amount USDC credited to onBehalfOf,
+// which spends permit instead of an allowance
+func (a *AaveV3Adapter) SupplyWithPermitCall(chainID uint64, onBehalfOf common.Address, amount *big.Int, permit *Permit) (*chain.Call, error) {
+	market, ok := a.markets[chainID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s on %d", ErrUnsupportedChain, ProtocolAaveV3, chainID)
+	}
+	return packCall(market.Pool, aavePoolABI, "supplyWithPermit", market.Asset, amount, onBehalfOf, uint16(0), permit.Deadline, permit.V, permit.R, permit.S)
+}
+
+// WithdrawCall builds Pool.withdraw of amount USDC sent to to
+func (a *AaveV3Adapter) WithdrawCall(chainID uint64, to common.Address, amount *big.Int) (*chain.Call, error) {
+	market, ok := a.markets[chainID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s on %d", ErrUnsupportedChain, ProtocolAaveV3, chainID)
+	}
+	return packCall(market.Pool, aavePoolABI, "withdraw", market.Asset, amount, to)
+}
+
+// configBits extracts width bits starting at offset from a reserve configuration bitmap
+func configBits(configuration *big.Int, offset, width uint) *big.Int {
+	bits := new(big.Int).Rsh(configuration, offset)
+	mask := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), width), big.NewInt(1))
+	return bits.And(bits, mask)
+}
+
+// aaveReserveFactor extracts the reserve factor in basis points (bits 64-79) from a
+// reserve configuration bitmap
+func aaveReserveFactor(configuration *big.Int) *big.Int {
+	return configBits(configuration, 64, 16)
+}
@@ -0,0 +1,92 @@
+package adapters
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+)
+
+// Mover builds the transactions moving USDC into and out of a market. Adapters of
+// protocols the performer can rebalance through implement it next to YieldAdapter.
+type Mover interface {
+	// SupplyCall deposits amount USDC base units from the sender, credited to onBehalfOf.
+	// The sender must have approved the call target for amount first.
+	SupplyCall(chainID uint64, onBehalfOf common.Address, amount *big.Int) (*chain.Call, error)
+
+	// WithdrawCall withdraws amount USDC base units of the sender's position to to
+	WithdrawCall(chainID uint64, to common.Address, amount *big.Int) (*chain.Call, error)
+
+	// PositionOf returns the USDC base units account has supplied, interest included
+	PositionOf(ctx context.Context, chainID uint64, account common.Address) (*big.Int, error)
+}
+
+// Permit is an EIP-2612 signature of the sender allowing a deposit to spend its USDC
+// until Deadline, a unix timestamp
+type Permit struct {
+	Deadline *big.Int
+	V        uint8
+	R        [32]byte
+	S        [32]byte
+}
+
+// PermitMover is a Mover whose deposits can spend a permit of the sender, sparing the
+// approval transaction before them
+type PermitMover interface {
+	Mover
+
+	// SupplyWithPermitCall deposits like SupplyCall, spending permit, which must allow
+	// the call target to spend amount
+	SupplyWithPermitCall(chainID uint64, onBehalfOf common.Address, amount *big.Int, permit *Permit) (*chain.Call, error)
+}
+
+// MoverFor returns the Mover of the adapter registered for protocol
+func (r *Registry) MoverFor(protocol string) (Mover, error) {
+	adapter, err := r.Get(protocol)
+	if err != nil {
+		return nil, err
+	}
+	mover, ok := adapter.(Mover)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotMovable, protocol)
+	}
+	return mover, nil
+}
+
+// USDCAddress returns the native USDC token on chainID
+func USDCAddress(chainID uint64) (common.Address, error) {
+	token, ok := usdcAddresses[chainID]
+	if !ok {
+		return common.Address{}, fmt.Errorf("no native USDC on chain %d", chainID)
+	}
+	return token, nil
+}
+
+// USDCBalance reads the native USDC balance of account with client, a client of chainID
+func USDCBalance(ctx context.Context, client chain.Client, chainID uint64, account common.Address) (*big.Int, error) {
+	token, err := USDCAddress(chainID)
+	if err != nil {
+		return nil, err
+	}
+	return chain.CallUint(ctx, client, token, erc20ABI, "balanceOf", account)
+}
+
+// ApproveCall builds a USDC approval of amount base units for spender
+func ApproveCall(chainID uint64, spender common.Address, amount *big.Int) (*chain.Call, error) {
+	token, err := USDCAddress(chainID)
+	if err != nil {
+		return nil, err
+	}
+	return packCall(token, erc20ABI, "approve", spender, amount)
+}
+
+func packCall(contract common.Address, contractABI abi.ABI, method string, args ...interface{}) (*chain.Call, error) {
+	data, err := contractABI.Pack(method, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack %s: %w", method, err)
+	}
+	return &chain.Call{To: contract, Data: data}, nil
+}
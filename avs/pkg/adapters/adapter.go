@@ -0,0 +1,183 @@
+// Package adapters reads USDC market state from lending protocols. Every protocol is
+// wrapped in a YieldAdapter so task handlers stay protocol agnostic.
+package adapters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/najnomics/crosscow-avs/pkg/features"
+	"github.com/najnomics/crosscow-avs/pkg/irm"
+)
+
+var (
+	// ErrUnsupportedProtocol is returned for protocols without a registered adapter
+	ErrUnsupportedProtocol = errors.New("unsupported protocol")
+
+	// ErrUnsupportedChain is returned when a protocol has no known market on a chain
+	ErrUnsupportedChain = errors.New("protocol is not deployed on chain")
+
+	// ErrNotMovable is returned for protocols whose adapter cannot build deposits or withdrawals
+	ErrNotMovable = errors.New("protocol does not support moving funds")
+
+	// ErrProtocolDisabled is returned for protocols an operator disabled at runtime, or
+	// whose experimental adapter the operator did not enable
+	ErrProtocolDisabled = errors.New("protocol is disabled")
+)
+
+// ExperimentalProtocols lists the protocols whose adapters ship disabled, until operators
+// enable their features.Adapter flag. Adapters leave it once they proved themselves.
+var ExperimentalProtocols = map[string]string{}
+
+// MarketState is a snapshot of the USDC market of a protocol on one chain
+type MarketState struct {
+	Protocol string
+	ChainID  uint64
+	Pool     irm.Pool
+
+	// LP is the yield estimate of a liquidity pool, nil for lending markets
+	LP *LPState
+
+	// Fixed is the rate a fixed rate market locks, nil for variable rate markets
+	Fixed *FixedState
+}
+
+// YieldAdapter reads market state from a single protocol
+type YieldAdapter interface {
+	// Protocol returns the protocol name used in task parameters, e.g. "aave_v3"
+	Protocol() string
+
+	// ChainIDs returns the chains the protocol has a known USDC market on
+	ChainIDs() []uint64
+
+	// MarketState reads the current state of the USDC market on chainID
+	MarketState(ctx context.Context, chainID uint64) (*MarketState, error)
+}
+
+// Registry resolves protocol names to adapters. Adapters can be disabled at runtime, for
+// instance while a protocol is paused, after which tasks cannot read it. Experimental
+// adapters are also disabled while their feature flag is.
+type Registry struct {
+	mu       sync.RWMutex
+	adapters map[string]YieldAdapter
+	disabled map[string]bool
+	features *features.Set
+}
+
+// NewRegistry creates a registry holding adapters
+func NewRegistry(adapters ...YieldAdapter) *Registry {
+	r := &Registry{adapters: make(map[string]YieldAdapter), disabled: make(map[string]bool)}
+	for _, a := range adapters {
+		r.Register(a)
+	}
+	return r
+}
+
+// Register adds a, replacing any adapter for the same protocol
+func (r *Registry) Register(a YieldAdapter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.adapters[normalizeProtocol(a.Protocol())] = a
+}
+
+// Get returns the adapter for protocol. Names are case insensitive.
+func (r *Registry) Get(protocol string) (YieldAdapter, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	name := normalizeProtocol(protocol)
+	a, ok := r.adapters[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedProtocol, protocol)
+	}
+	if r.disabled[name] {
+		return nil, fmt.Errorf("%w: %s", ErrProtocolDisabled, protocol)
+	}
+	if !r.features.Enabled(features.Adapter(name)) {
+		return nil, fmt.Errorf("%w: %s is experimental and not enabled by this operator", ErrProtocolDisabled, protocol)
+	}
+	return a, nil
+}
+
+// Protocols returns the enabled protocol names in ascending order
+func (r *Registry) Protocols() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.adapters))
+	for name := range r.adapters {
+		if r.enabled(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Registered returns every registered protocol name, enabled or not, in ascending order
+func (r *Registry) Registered() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.adapters))
+	for name := range r.adapters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns the adapter for protocol whether it is enabled or not, and whether it is
+func (r *Registry) Lookup(protocol string) (YieldAdapter, bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	name := normalizeProtocol(protocol)
+	a, ok := r.adapters[name]
+	if !ok {
+		return nil, false, fmt.Errorf("%w: %s", ErrUnsupportedProtocol, protocol)
+	}
+	return a, r.enabled(name), nil
+}
+
+// SetFeatures gates the experimental adapters on their flags in set
+func (r *Registry) SetFeatures(set *features.Set) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.features = set
+}
+
+// enabled reports whether the adapter of name may be read. The caller holds r.mu.
+func (r *Registry) enabled(name string) bool {
+	return !r.disabled[name] && r.features.Enabled(features.Adapter(name))
+}
+
+// SetEnabled enables or disables the adapter for protocol. Tasks in flight keep the
+// adapter they already resolved.
+func (r *Registry) SetEnabled(protocol string, enabled bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	name := normalizeProtocol(protocol)
+	if _, ok := r.adapters[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrUnsupportedProtocol, protocol)
+	}
+	if enabled {
+		delete(r.disabled, name)
+	} else {
+		r.disabled[name] = true
+	}
+	return nil
+}
+
+func normalizeProtocol(protocol string) string {
+	return strings.ToLower(strings.TrimSpace(protocol))
+}
+
+func sortedChainIDs[V any](markets map[uint64]V) []uint64 {
+	ids := make([]uint64, 0, len(markets))
+	for id := range markets {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
@@ -0,0 +1,1123 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/chain/chaintest"
+	"github.com/najnomics/crosscow-avs/pkg/features"
+	"github.com/najnomics/crosscow-avs/pkg/irm"
+	"github.com/najnomics/crosscow-avs/pkg/rates"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+	"github.com/najnomics/crosscow-avs/pkg/subgraph"
+)
+
+func ray(f float64) *big.Int {
+	r, _ := new(big.Float).Mul(big.NewFloat(f), new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(27), nil))).Int(nil)
+	return r
+}
+
+func Test_AaveV3MarketState(t *testing.T) {
+	pool := common.HexToAddress("0x01")
+	asset := common.HexToAddress("0x02")
+	aToken := common.HexToAddress("0x03")
+	debtToken := common.HexToAddress("0x04")
+	strategy := common.HexToAddress("0x05")
+
+	// reserve factor 10% lives in bits 64-79
+	configuration := new(big.Int).Lsh(big.NewInt(1000), 64)
+
+	contracts := chaintest.NewContracts(1)
+	zero := big.NewInt(0)
+	contracts.Stub(t, pool, aavePoolABI, "getReserveData",
+		configuration, zero, zero, zero, zero, zero, zero, uint16(0),
+		aToken, common.Address{}, debtToken, strategy, zero, zero, zero)
+	contracts.Stub(t, aToken, erc20ABI, "totalSupply", big.NewInt(100_000_000_000))
+	contracts.Stub(t, debtToken, erc20ABI, "totalSupply", big.NewInt(80_000_000_000))
+	contracts.Stub(t, strategy, aaveStrategyABI, "getInterestRateData", ray(0.9), ray(0), ray(0.06), ray(0.6))
+
+	chains := chain.NewManager()
+	chains.Register(1, "ethereum", contracts)
+	adapter := NewAaveV3Adapter(chains, map[uint64]AaveV3Market{1: {Pool: pool, Asset: asset}})
+
+	state, err := adapter.MarketState(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("MarketState failed: %v", err)
+	}
+	// the reserve locates the tokens and strategy, which are read in one batch
+	if calls := contracts.Calls(); calls != 2 {
+		t.Errorf("Expected two eth_calls, got %d", calls)
+	}
+	model, ok := state.Pool.Model.(*irm.KinkModel)
+	if !ok {
+		t.Fatalf("Expected a kink model, got %T", state.Pool.Model)
+	}
+	if model.ReserveFactor != 0.1 || math.Abs(model.OptimalUtilization-0.9) > 1e-12 || math.Abs(model.Slope2-0.6) > 1e-12 {
+		t.Errorf("Unexpected rate model: %+v", model)
+	}
+	if state.Pool.Utilization() != 0.8 {
+		t.Errorf("Expected 80%% utilization, got %f", state.Pool.Utilization())
+	}
+
+	if _, err := adapter.MarketState(context.Background(), 10); !errors.Is(err, ErrUnsupportedChain) {
+		t.Errorf("Expected ErrUnsupportedChain, got %v", err)
+	}
+
+	contracts.Stub(t, aToken, erc20ABI, "balanceOf", big.NewInt(2_500_000))
+	if position, err := adapter.PositionOf(context.Background(), 1, common.HexToAddress("0xaa")); err != nil || position.Int64() != 2_500_000 {
+		t.Errorf("Expected the aToken balance as position, got %v, %v", position, err)
+	}
+}
+
+func Test_AaveV3AssetMarketState(t *testing.T) {
+	pool := common.HexToAddress("0x01")
+	aToken := common.HexToAddress("0x03")
+	debtToken := common.HexToAddress("0x04")
+	strategy := common.HexToAddress("0x05")
+
+	contracts := chaintest.NewContracts(1)
+	zero := big.NewInt(0)
+	contracts.Stub(t, pool, aavePoolABI, "getReserveData",
+		zero, zero, zero, zero, zero, zero, zero, uint16(0),
+		aToken, common.Address{}, debtToken, strategy, zero, zero, zero)
+	contracts.Stub(t, aToken, erc20ABI, "totalSupply", new(big.Int).Mul(big.NewInt(1_000), big.NewInt(1e18)))
+	contracts.Stub(t, debtToken, erc20ABI, "totalSupply", new(big.Int).Mul(big.NewInt(500), big.NewInt(1e18)))
+	contracts.Stub(t, strategy, aaveStrategyABI, "getInterestRateData", ray(0.9), ray(0), ray(0.06), ray(0.6))
+
+	chains := chain.NewManager()
+	chains.Register(1, "ethereum", contracts)
+	registry := NewRegistry(
+		NewAaveV3Adapter(chains, map[uint64]AaveV3Market{1: {Pool: pool, Asset: common.HexToAddress("0x02")}}),
+		NewCompoundV3Adapter(chains, nil),
+	)
+	reader, err := registry.AssetReaderFor(ProtocolAaveV3)
+	if err != nil {
+		t.Fatalf("Expected Aave v3 to read other assets, got %v", err)
+	}
+	state, err := reader.AssetMarketState(context.Background(), 1, common.HexToAddress("0x06"))
+	if err != nil || state.Pool.Utilization() != 0.5 {
+		t.Fatalf("Expected the DAI reserve at 50%% utilization, got %+v, %v", state, err)
+	}
+
+	contracts.Stub(t, pool, aavePoolABI, "getReserveData",
+		zero, zero, zero, zero, zero, zero, zero, uint16(0),
+		common.Address{}, common.Address{}, common.Address{}, common.Address{}, zero, zero, zero)
+	if _, err := reader.AssetMarketState(context.Background(), 1, common.HexToAddress("0x07")); !errors.Is(err, ErrAssetNotListed) {
+		t.Errorf("Expected ErrAssetNotListed, got %v", err)
+	}
+	if _, err := registry.AssetReaderFor(ProtocolCompoundV3); !errors.Is(err, ErrNoAssets) {
+		t.Errorf("Expected ErrNoAssets, got %v", err)
+	}
+}
+
+func Test_CompoundV3MarketState(t *testing.T) {
+	comet := common.HexToAddress("0x10")
+
+	contracts := chaintest.NewContracts(8453)
+	perSecond := func(annual float64) *big.Int {
+		return big.NewInt(int64(annual * 1e18 / rates.SecondsPerYear))
+	}
+	contracts.Stub(t, comet, cometABI, "totalSupply", big.NewInt(50_000_000_000))
+	contracts.Stub(t, comet, cometABI, "totalBorrow", big.NewInt(45_000_000_000))
+	contracts.Stub(t, comet, cometABI, "supplyKink", big.NewInt(900_000_000_000_000_000))
+	contracts.Stub(t, comet, cometABI, "supplyPerSecondInterestRateBase", big.NewInt(0))
+	contracts.Stub(t, comet, cometABI, "supplyPerSecondInterestRateSlopeLow", perSecond(0.05))
+	contracts.Stub(t, comet, cometABI, "supplyPerSecondInterestRateSlopeHigh", perSecond(3))
+
+	chains := chain.NewManager()
+	chains.Register(8453, "base", contracts)
+	adapter := NewCompoundV3Adapter(chains, map[uint64]CompoundV3Market{8453: {Comet: comet}})
+
+	state, err := adapter.MarketState(context.Background(), 8453)
+	if err != nil {
+		t.Fatalf("MarketState failed: %v", err)
+	}
+	if calls := contracts.Calls(); calls != 1 {
+		t.Errorf("Expected the Comet to be read in one batch, got %d eth_calls", calls)
+	}
+	if got := state.Pool.SupplyRate(); math.Abs(got-0.045) > 1e-6 {
+		t.Errorf("Expected 4.5%% supply rate at the kink, got %f", got)
+	}
+
+	contracts.Stub(t, comet, cometABI, "balanceOf", big.NewInt(7_000_000))
+	if position, err := adapter.PositionOf(context.Background(), 8453, common.HexToAddress("0xaa")); err != nil || position.Int64() != 7_000_000 {
+		t.Errorf("Expected the comet balance as position, got %v, %v", position, err)
+	}
+}
+
+func Test_SparkSavingsMarketState(t *testing.T) {
+	vault := common.HexToAddress("0x20")
+	oracle := common.HexToAddress("0x21")
+
+	contracts := chaintest.NewContracts(ChainIDBase)
+	// 5% a year, compounded every second
+	ssr := ray(math.Exp(math.Log1p(0.05) / rates.SecondsPerYear))
+	contracts.Stub(t, vault, erc4626ABI, "asset", usdcAddresses[ChainIDBase])
+	contracts.Stub(t, vault, erc4626ABI, "totalAssets", big.NewInt(30_000_000_000))
+	contracts.Stub(t, oracle, ssrABI, "getSSR", ssr)
+
+	chains := chain.NewManager()
+	chains.Register(ChainIDBase, "base", contracts)
+	adapter := NewSparkSavingsAdapter(chains, map[uint64]SparkSavingsMarket{
+		ChainIDBase: {Vault: vault, RateSource: oracle, Oracle: true},
+	})
+
+	state, err := adapter.MarketState(context.Background(), ChainIDBase)
+	if err != nil {
+		t.Fatalf("MarketState failed: %v", err)
+	}
+	if got := state.Pool.SupplyRate(); math.Abs(got-math.Log1p(0.05)) > 1e-6 {
+		t.Errorf("Expected the continuously compounded savings rate, got %f", got)
+	}
+	if state.Pool.TotalSupply.Int64() != 30_000_000_000 || state.Pool.Utilization() != 0 {
+		t.Errorf("Expected the vault assets with nothing borrowed, got %+v", state.Pool)
+	}
+
+	contracts.Stub(t, vault, erc4626ABI, "balanceOf", big.NewInt(900_000))
+	contracts.Stub(t, vault, erc4626ABI, "convertToAssets", big.NewInt(1_000_000))
+	if position, err := adapter.PositionOf(context.Background(), ChainIDBase, common.HexToAddress("0xaa")); err != nil || position.Int64() != 1_000_000 {
+		t.Errorf("Expected the shares converted to USDC as position, got %v, %v", position, err)
+	}
+
+	// a vault of another asset is not a USDC market
+	contracts.Stub(t, vault, erc4626ABI, "asset", common.HexToAddress("0x22"))
+	if _, err := adapter.MarketState(context.Background(), ChainIDBase); err == nil {
+		t.Errorf("Expected a vault of another asset to be rejected")
+	}
+}
+
+func Test_ERC4626MarketState(t *testing.T) {
+	vault := "0x0000000000000000000000000000000000000030"
+	contracts := chaintest.NewContracts(ChainIDArbitrum)
+	contracts.Stub(t, common.HexToAddress(vault), erc4626ABI, "asset", usdcAddresses[ChainIDArbitrum])
+	contracts.Stub(t, common.HexToAddress(vault), erc4626ABI, "totalAssets", big.NewInt(8_000_000_000))
+	contracts.Stub(t, common.HexToAddress(vault), erc4626ABI, "decimals", uint8(18))
+	contracts.Stub(t, common.HexToAddress(vault), erc4626ABI, "convertToAssets", big.NewInt(1_020_000))
+
+	chains := chain.NewManager()
+	chains.Register(ChainIDArbitrum, "arbitrum", contracts)
+	history := store.NewSeriesStore(store.NewMemoryKV())
+	cfg := DefaultVaultConfig()
+	cfg.Vaults = []Vault{{Protocol: "Steakhouse_USDC", Addresses: map[uint64]string{ChainIDArbitrum: vault}}}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Expected the vault config to be valid: %v", err)
+	}
+	adapter := NewVaultAdapters(cfg, chains, history)[0]
+	now := time.Unix(1_800_000_000, 0)
+	adapter.now = func() time.Time { return now }
+	if adapter.Protocol() != "steakhouse_usdc" {
+		t.Errorf("Expected a normalized protocol name, got %s", adapter.Protocol())
+	}
+
+	price, err := adapter.SharePrice(context.Background(), ChainIDArbitrum)
+	if err != nil || price != 1.02 {
+		t.Fatalf("Expected a share price of 1.02, got %f, %v", price, err)
+	}
+	series := store.SharePriceSeries(adapter.Protocol(), ChainIDArbitrum)
+	if err := history.Append(context.Background(), series, store.SeriesPoint{Time: now.Add(-time.Hour), Value: 1.019}); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	if _, err := adapter.MarketState(context.Background(), ChainIDArbitrum); !errors.Is(err, ErrInsufficientHistory) {
+		t.Errorf("Expected ErrInsufficientHistory, got %v", err)
+	}
+
+	// points before the lookback are ignored in favour of the oldest one within it
+	for _, point := range []store.SeriesPoint{{Time: now.Add(-30 * 24 * time.Hour), Value: 0.9}, {Time: now.Add(-5 * 24 * time.Hour), Value: 1}} {
+		if err := history.Append(context.Background(), series, point); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+	state, err := adapter.MarketState(context.Background(), ChainIDArbitrum)
+	if err != nil {
+		t.Fatalf("MarketState failed: %v", err)
+	}
+	want := math.Log(1.02) / (5 * 24 * 3600) * rates.SecondsPerYear
+	if got := state.Pool.SupplyRate(); math.Abs(got-want) > 1e-9 {
+		t.Errorf("Expected the annualized appreciation %f, got %f", want, got)
+	}
+	if state.Protocol != "steakhouse_usdc" || state.Pool.TotalSupply.Int64() != 8_000_000_000 {
+		t.Errorf("Unexpected market state %+v", state)
+	}
+
+	// reads pinned to a past block measure the appreciation up to the time of the block
+	state, err = adapter.MarketState(chain.WithBlockTime(context.Background(), 100, now.Add(-24*time.Hour)), ChainIDArbitrum)
+	if err != nil {
+		t.Fatalf("MarketState failed: %v", err)
+	}
+	want = math.Log(1.02) / (4 * 24 * 3600) * rates.SecondsPerYear
+	if got := state.Pool.SupplyRate(); math.Abs(got-want) > 1e-9 {
+		t.Errorf("Expected the appreciation up to the block, %f, got %f", want, got)
+	}
+
+	cfg.Vaults = append(cfg.Vaults, Vault{Protocol: "steakhouse_usdc ", Addresses: map[uint64]string{1: vault}})
+	if err := cfg.Validate(); err == nil {
+		t.Errorf("Expected a vault configured twice to be rejected")
+	}
+}
+
+func Test_CompoundV2MarketState(t *testing.T) {
+	mToken := common.HexToAddress("0x40")
+	model := common.HexToAddress("0x41")
+	mantissa := func(f float64) *big.Int {
+		m, _ := new(big.Float).Mul(big.NewFloat(f), big.NewFloat(1e18)).Int(nil)
+		return m
+	}
+
+	contracts := chaintest.NewContracts(ChainIDBase)
+	contracts.Stub(t, mToken, cTokenABI, "underlying", usdcAddresses[ChainIDBase])
+	contracts.Stub(t, mToken, cTokenABI, "getCash", big.NewInt(30_000_000_000))
+	contracts.Stub(t, mToken, cTokenABI, "totalBorrows", big.NewInt(72_000_000_000))
+	contracts.Stub(t, mToken, cTokenABI, "totalReserves", big.NewInt(2_000_000_000))
+	contracts.Stub(t, mToken, cTokenABI, "reserveFactorMantissa", mantissa(0.15))
+	contracts.Stub(t, mToken, cTokenABI, "interestRateModel", model)
+	contracts.Stub(t, model, jumpRateModelABI, "kink", mantissa(0.8))
+	contracts.Stub(t, model, jumpRateModelABI, "baseRatePerTimestamp", big.NewInt(0))
+	contracts.Stub(t, model, jumpRateModelABI, "multiplierPerTimestamp", mantissa(0.05/rates.SecondsPerYear))
+	contracts.Stub(t, model, jumpRateModelABI, "jumpMultiplierPerTimestamp", mantissa(2/rates.SecondsPerYear))
+
+	chains := chain.NewManager()
+	chains.Register(ChainIDBase, "base", contracts)
+	adapter := NewMoonwellAdapter(chains, map[uint64]CompoundV2Market{ChainIDBase: {CToken: mToken, PeriodsPerYear: rates.SecondsPerYear}})
+
+	state, err := adapter.MarketState(context.Background(), ChainIDBase)
+	if err != nil {
+		t.Fatalf("MarketState failed: %v", err)
+	}
+	// supply is cash + borrows - reserves, so 72% of 100k USDC is borrowed
+	if state.Pool.TotalSupply.Int64() != 100_000_000_000 || state.Pool.Utilization() != 0.72 {
+		t.Errorf("Unexpected pool %+v", state.Pool)
+	}
+	want := 0.05 * 0.72 * 0.72 * 0.85
+	if got := state.Pool.SupplyRate(); math.Abs(got-want) > 1e-6 {
+		t.Errorf("Expected supply rate %f below the kink, got %f", want, got)
+	}
+
+	if _, err := NewRegistry(adapter).MoverFor(ProtocolMoonwell); !errors.Is(err, ErrNotMovable) {
+		t.Errorf("Expected Moonwell not to move funds, got %v", err)
+	}
+
+	venus := NewVenusAdapter(chains, map[uint64]CompoundV2Market{ChainIDBase: {CToken: mToken, PeriodsPerYear: rates.SecondsPerYear}})
+	if _, err := venus.MarketState(context.Background(), ChainIDBase); err == nil {
+		t.Errorf("Expected Venus to read per block rate parameters")
+	}
+}
+
+func Test_Incentives(t *testing.T) {
+	pool := common.HexToAddress("0x01")
+	aToken := common.HexToAddress("0x03")
+	controller := common.HexToAddress("0x06")
+	comet := common.HexToAddress("0x10")
+	cometRewards := common.HexToAddress("0x11")
+	mToken := common.HexToAddress("0x40")
+	comptroller := common.HexToAddress("0x42")
+	distributor := common.HexToAddress("0x43")
+	running := common.HexToAddress("0x50")
+	ended := common.HexToAddress("0x51")
+	well := common.HexToAddress("0x52")
+
+	contracts := chaintest.NewContracts(ChainIDBase)
+	contracts.SetHead(100, 1_700_000_000)
+	zero := big.NewInt(0)
+	contracts.Stub(t, pool, aavePoolABI, "getReserveData",
+		zero, zero, zero, zero, zero, zero, zero, uint16(0),
+		aToken, common.Address{}, common.Address{}, common.Address{}, zero, zero, zero)
+	contracts.Stub(t, controller, aaveRewardsABI, "getRewardsByAsset", []common.Address{running})
+	contracts.Stub(t, controller, aaveRewardsABI, "getRewardsData", zero, big.NewInt(5_000), zero, big.NewInt(1_700_100_000))
+	contracts.Stub(t, running, erc20ABI, "decimals", uint8(18))
+	contracts.Stub(t, ended, erc20ABI, "decimals", uint8(18))
+	contracts.Stub(t, well, erc20ABI, "decimals", uint8(18))
+
+	contracts.Stub(t, cometRewards, cometRewardsABI, "rewardConfig", running, uint64(1_000_000_000_000), true)
+	contracts.Stub(t, comet, cometABI, "baseTrackingSupplySpeed", uint64(2_000_000_000_000))
+	contracts.Stub(t, comet, cometABI, "trackingIndexScale", uint64(1_000_000_000_000_000))
+
+	contracts.Stub(t, mToken, cTokenABI, "comptroller", comptroller)
+	contracts.Stub(t, comptroller, moonwellRewardsABI, "rewardDistributor", distributor)
+	contracts.Stub(t, distributor, moonwellRewardsABI, "getAllMarketConfigs", []moonwellMarketConfig{
+		{EmissionToken: well, EndTime: big.NewInt(1_800_000_000), SupplyGlobalIndex: zero, BorrowGlobalIndex: zero, SupplyEmissionsPerSec: big.NewInt(7), BorrowEmissionsPerSec: big.NewInt(9)},
+		{EmissionToken: ended, EndTime: big.NewInt(1_600_000_000), SupplyGlobalIndex: zero, BorrowGlobalIndex: zero, SupplyEmissionsPerSec: big.NewInt(7), BorrowEmissionsPerSec: zero},
+	})
+
+	chains := chain.NewManager()
+	chains.Register(ChainIDBase, "base", contracts)
+	registry := NewRegistry(
+		NewAaveV3Adapter(chains, map[uint64]AaveV3Market{ChainIDBase: {Pool: pool, RewardsController: controller}}),
+		NewCompoundV3Adapter(chains, map[uint64]CompoundV3Market{ChainIDBase: {Comet: comet, Rewards: cometRewards}}),
+		NewMoonwellAdapter(chains, map[uint64]CompoundV2Market{ChainIDBase: {CToken: mToken, PeriodsPerYear: rates.SecondsPerYear}}),
+		NewVenusAdapter(chains, map[uint64]CompoundV2Market{ChainIDBase: {CToken: mToken, PeriodsPerYear: rates.SecondsPerYear}}),
+	)
+
+	tests := []struct {
+		protocol string
+		want     []Emission
+	}{
+		{ProtocolAaveV3, []Emission{{Token: running, Decimals: 18, PerSecond: big.NewInt(5_000), EndsAt: 1_700_100_000}}},
+		// 2e12 * 1e6 / 1e15 = 0.002 COMP in 6 decimals a second, upscaled to 18 decimals
+		{ProtocolCompoundV3, []Emission{{Token: running, Decimals: 18, PerSecond: big.NewInt(2_000_000_000_000_000)}}},
+		{ProtocolMoonwell, []Emission{{Token: well, Decimals: 18, PerSecond: big.NewInt(7), EndsAt: 1_800_000_000}}},
+	}
+	for _, tt := range tests {
+		reader, err := registry.IncentiveReaderFor(tt.protocol)
+		if err != nil {
+			t.Fatalf("Expected %s to read incentives: %v", tt.protocol, err)
+		}
+		incentives, err := reader.Incentives(context.Background(), ChainIDBase)
+		if err != nil {
+			t.Fatalf("Incentives of %s failed: %v", tt.protocol, err)
+		}
+		if incentives.BlockTime != 1_700_000_000 || !reflect.DeepEqual(incentives.Emissions, tt.want) {
+			t.Errorf("Unexpected %s incentives %+v", tt.protocol, incentives)
+		}
+	}
+
+	reader, err := registry.IncentiveReaderFor(ProtocolVenus)
+	if err != nil {
+		t.Fatalf("IncentiveReaderFor failed: %v", err)
+	}
+	if _, err := reader.Incentives(context.Background(), ChainIDBase); !errors.Is(err, ErrNoIncentives) {
+		t.Errorf("Expected Venus not to read incentives, got %v", err)
+	}
+}
+
+func Test_Registry(t *testing.T) {
+	registry := NewDefaultRegistry(chain.NewManager())
+
+	want := []string{ProtocolAaveV3, ProtocolCompoundV3, ProtocolMoonwell, ProtocolSkySavings, ProtocolSparkSavings, ProtocolVenus}
+	if got := registry.Protocols(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected protocols: %v", got)
+	}
+	if _, err := registry.Get("AAVE_V3"); err != nil {
+		t.Errorf("Expected protocol lookup to be case insensitive: %v", err)
+	}
+	if _, err := registry.Get("euler"); !errors.Is(err, ErrUnsupportedProtocol) {
+		t.Errorf("Expected ErrUnsupportedProtocol, got %v", err)
+	}
+}
+
+func Test_RegistryDisable(t *testing.T) {
+	registry := NewDefaultRegistry(chain.NewManager())
+
+	if err := registry.SetEnabled("Compound_V3", false); err != nil {
+		t.Fatalf("SetEnabled failed: %v", err)
+	}
+	if _, err := registry.Get(ProtocolCompoundV3); !errors.Is(err, ErrProtocolDisabled) {
+		t.Errorf("Expected ErrProtocolDisabled, got %v", err)
+	}
+	if got := registry.Protocols(); len(got) != len(registry.Registered())-1 || got[1] != ProtocolMoonwell {
+		t.Errorf("Expected disabled protocols to be left out, got %v", got)
+	}
+	if got := registry.Registered(); len(got) != 6 || got[1] != ProtocolCompoundV3 {
+		t.Errorf("Expected every registered protocol, got %v", got)
+	}
+	if _, enabled, err := registry.Lookup(ProtocolCompoundV3); err != nil || enabled {
+		t.Errorf("Expected Lookup to find the disabled adapter, got %v, %v", enabled, err)
+	}
+
+	if err := registry.SetEnabled(ProtocolCompoundV3, true); err != nil {
+		t.Fatalf("SetEnabled failed: %v", err)
+	}
+	if _, err := registry.Get(ProtocolCompoundV3); err != nil {
+		t.Errorf("Expected the adapter to be enabled again: %v", err)
+	}
+	if err := registry.SetEnabled("euler", false); !errors.Is(err, ErrUnsupportedProtocol) {
+		t.Errorf("Expected ErrUnsupportedProtocol, got %v", err)
+	}
+}
+
+func Test_RegistryGatesExperimentalAdapters(t *testing.T) {
+	registry := NewDefaultRegistry(chain.NewManager())
+	set, err := features.New([]features.Flag{{Name: features.Adapter(ProtocolVenus)}}, features.DefaultConfig())
+	if err != nil {
+		t.Fatalf("features.New failed: %v", err)
+	}
+	registry.SetFeatures(set)
+
+	if _, err := registry.Get(ProtocolVenus); !errors.Is(err, ErrProtocolDisabled) {
+		t.Errorf("Expected the experimental adapter to be disabled, got %v", err)
+	}
+	if got := registry.Protocols(); len(got) != 5 {
+		t.Errorf("Expected the experimental adapter to be left out, got %v", got)
+	}
+
+	if err := set.SetEnabled(features.Adapter(ProtocolVenus), true); err != nil {
+		t.Fatalf("SetEnabled failed: %v", err)
+	}
+	if _, err := registry.Get(ProtocolVenus); err != nil {
+		t.Errorf("Expected the enabled experimental adapter to be served: %v", err)
+	}
+}
+
+func Test_MoverCalls(t *testing.T) {
+	registry := NewDefaultRegistry(chain.NewManager())
+	user := common.HexToAddress("0xaa")
+	amount := big.NewInt(5_000_000)
+
+	aave, err := registry.MoverFor(ProtocolAaveV3)
+	if err != nil {
+		t.Fatalf("MoverFor failed: %v", err)
+	}
+	supply, err := aave.SupplyCall(ChainIDBase, user, amount)
+	if err != nil {
+		t.Fatalf("SupplyCall failed: %v", err)
+	}
+	args, err := aavePoolABI.Methods["supply"].Inputs.Unpack(supply.Data[4:])
+	if err != nil {
+		t.Fatalf("Failed to unpack supply: %v", err)
+	}
+	if supply.To != DefaultAaveV3Markets[ChainIDBase].Pool || args[0].(common.Address) != usdcAddresses[ChainIDBase] || args[2].(common.Address) != user {
+		t.Errorf("Unexpected supply call to %s with %v", supply.To.Hex(), args)
+	}
+	permit := &Permit{Deadline: big.NewInt(1_700_000_000), V: 27, R: [32]byte{1}, S: [32]byte{2}}
+	permitted, err := aave.(PermitMover).SupplyWithPermitCall(ChainIDBase, user, amount, permit)
+	if err != nil {
+		t.Fatalf("SupplyWithPermitCall failed: %v", err)
+	}
+	args, err = aavePoolABI.Methods["supplyWithPermit"].Inputs.Unpack(permitted.Data[4:])
+	if err != nil {
+		t.Fatalf("Failed to unpack supplyWithPermit: %v", err)
+	}
+	if permitted.To != supply.To || args[4].(*big.Int).Int64() != 1_700_000_000 || args[5].(uint8) != 27 || args[6].([32]byte) != permit.R {
+		t.Errorf("Unexpected permitted supply call to %s with %v", permitted.To.Hex(), args)
+	}
+
+	compound, err := registry.MoverFor(ProtocolCompoundV3)
+	if err != nil {
+		t.Fatalf("MoverFor failed: %v", err)
+	}
+	withdraw, err := compound.WithdrawCall(ChainIDArbitrum, user, amount)
+	if err != nil {
+		t.Fatalf("WithdrawCall failed: %v", err)
+	}
+	if withdraw.To != DefaultCompoundV3Markets[ChainIDArbitrum].Comet {
+		t.Errorf("Expected the withdrawal to go to the Comet, got %s", withdraw.To.Hex())
+	}
+	if _, err := compound.SupplyCall(10, user, amount); !errors.Is(err, ErrUnsupportedChain) {
+		t.Errorf("Expected ErrUnsupportedChain, got %v", err)
+	}
+
+	spark, err := registry.MoverFor(ProtocolSparkSavings)
+	if err != nil {
+		t.Fatalf("MoverFor failed: %v", err)
+	}
+	withdraw, err = spark.WithdrawCall(ChainIDEthereum, user, amount)
+	if err != nil {
+		t.Fatalf("WithdrawCall failed: %v", err)
+	}
+	args, err = erc4626ABI.Methods["withdraw"].Inputs.Unpack(withdraw.Data[4:])
+	if err != nil {
+		t.Fatalf("Failed to unpack withdraw: %v", err)
+	}
+	if withdraw.To != DefaultSparkSavingsMarkets[ChainIDEthereum].Vault || args[1].(common.Address) != user || args[2].(common.Address) != user {
+		t.Errorf("Unexpected withdraw call to %s with %v", withdraw.To.Hex(), args)
+	}
+}
+
+func Test_AaveV3RiskState(t *testing.T) {
+	pool := common.HexToAddress("0x01")
+	asset := common.HexToAddress("0x02")
+	provider := common.HexToAddress("0x06")
+	oracle := common.HexToAddress("0x07")
+	feed := common.HexToAddress("0x08")
+
+	// 6 decimals, active, frozen, borrow cap 90M and supply cap 110M
+	configuration := new(big.Int).Lsh(big.NewInt(6), 48)
+	configuration.SetBit(configuration, 56, 1)
+	configuration.SetBit(configuration, 57, 1)
+	configuration.Or(configuration, new(big.Int).Lsh(big.NewInt(90_000_000), 80))
+	configuration.Or(configuration, new(big.Int).Lsh(big.NewInt(110_000_000), 116))
+
+	contracts := chaintest.NewContracts(1)
+	contracts.SetHead(100, 1_700_003_600)
+	zero := big.NewInt(0)
+	contracts.Stub(t, pool, aavePoolABI, "getReserveData",
+		configuration, zero, zero, zero, zero, zero, zero, uint16(0),
+		common.Address{}, common.Address{}, common.Address{}, common.Address{}, zero, zero, zero)
+	contracts.Stub(t, pool, aavePoolABI, "ADDRESSES_PROVIDER", provider)
+	contracts.Stub(t, provider, aaveOracleABI, "getPriceOracle", oracle)
+	contracts.Stub(t, oracle, aaveOracleABI, "getSourceOfAsset", feed)
+	contracts.Stub(t, oracle, aaveOracleABI, "getAssetPrice", big.NewInt(99_980_000))
+	contracts.Stub(t, feed, aggregatorABI, "latestRoundData", big.NewInt(1), big.NewInt(99_980_000), zero, big.NewInt(1_700_000_000), big.NewInt(1))
+
+	chains := chain.NewManager()
+	chains.Register(1, "ethereum", contracts)
+	adapter := NewAaveV3Adapter(chains, map[uint64]AaveV3Market{1: {Pool: pool, Asset: asset}})
+
+	state, err := adapter.RiskState(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("RiskState failed: %v", err)
+	}
+	if state.SupplyCap.Cmp(new(big.Int).Mul(big.NewInt(110_000_000), big.NewInt(1_000_000))) != 0 ||
+		state.BorrowCap.Cmp(new(big.Int).Mul(big.NewInt(90_000_000), big.NewInt(1_000_000))) != 0 {
+		t.Errorf("Unexpected caps: supply %s, borrow %s", state.SupplyCap, state.BorrowCap)
+	}
+	if !state.Frozen || state.Paused {
+		t.Errorf("Expected a frozen, unpaused reserve, got %+v", state)
+	}
+	// the pool predates deficit accounting
+	if state.BadDebt != nil {
+		t.Errorf("Expected unknown bad debt, got %s", state.BadDebt)
+	}
+	if state.Oracle == nil || state.Oracle.Feed != feed || state.Oracle.USD().Cmp(big.NewRat(9998, 10000)) != 0 ||
+		state.Oracle.UpdatedAt != 1_700_000_000 || state.Timestamp != 1_700_003_600 {
+		t.Errorf("Unexpected oracle: %+v at %d", state.Oracle, state.Timestamp)
+	}
+
+	contracts.Stub(t, pool, aavePoolABI, "getReserveDeficit", big.NewInt(5_000_000))
+	if state, err := adapter.RiskState(context.Background(), 1); err != nil || state.BadDebt.Int64() != 5_000_000 {
+		t.Errorf("Expected the reserve deficit as bad debt, got %+v, %v", state, err)
+	}
+}
+
+func Test_CompoundV3RiskState(t *testing.T) {
+	comet := common.HexToAddress("0x10")
+	feed := common.HexToAddress("0x11")
+
+	contracts := chaintest.NewContracts(8453)
+	contracts.Stub(t, comet, cometABI, "isSupplyPaused", false)
+	contracts.Stub(t, comet, cometABI, "isWithdrawPaused", true)
+	contracts.Stub(t, comet, cometABI, "getReserves", big.NewInt(-2_000_000))
+	contracts.Stub(t, comet, cometABI, "baseTokenPriceFeed", feed)
+	contracts.Stub(t, comet, cometABI, "getPrice", big.NewInt(100_000_000))
+
+	chains := chain.NewManager()
+	chains.Register(8453, "base", contracts)
+	adapter := NewCompoundV3Adapter(chains, map[uint64]CompoundV3Market{8453: {Comet: comet}})
+
+	state, err := adapter.RiskState(context.Background(), 8453)
+	if err != nil {
+		t.Fatalf("RiskState failed: %v", err)
+	}
+	if !state.Paused || state.SupplyCap != nil || state.BorrowCap != nil {
+		t.Errorf("Expected a paused, uncapped comet, got %+v", state)
+	}
+	if state.BadDebt.Int64() != 2_000_000 {
+		t.Errorf("Expected negative reserves as bad debt, got %s", state.BadDebt)
+	}
+	// the feed does not expose rounds
+	if state.Oracle.USD().Cmp(big.NewRat(1, 1)) != 0 || state.Oracle.UpdatedAt != 0 {
+		t.Errorf("Unexpected oracle: %+v", state.Oracle)
+	}
+
+	registry := NewRegistry(adapter)
+	if _, err := registry.RiskReaderFor(ProtocolCompoundV3); err != nil {
+		t.Errorf("Expected compound to report risk: %v", err)
+	}
+}
+
+func Test_CompoundV3Governance(t *testing.T) {
+	comet := common.HexToAddress("0x10")
+	proxyAdminContract := common.HexToAddress("0x20")
+	timelock := common.HexToAddress("0x21")
+	governor := common.HexToAddress("0x22")
+	guardian := common.HexToAddress("0x23")
+
+	contracts := chaintest.NewContracts(1)
+	contracts.StubStorage(comet, eip1967AdminSlot, common.BytesToHash(proxyAdminContract.Bytes()))
+	contracts.Stub(t, comet, cometABI, "governor", timelock)
+	contracts.Stub(t, comet, cometABI, "pauseGuardian", guardian)
+	contracts.Stub(t, proxyAdminContract, controllerABI, "owner", timelock)
+	contracts.Stub(t, timelock, controllerABI, "delay", big.NewInt(172_800))
+	contracts.Stub(t, timelock, controllerABI, "admin", governor)
+	// the governor administers the timelock back
+	contracts.Stub(t, governor, controllerABI, "admin", timelock)
+	contracts.Stub(t, guardian, controllerABI, "getThreshold", big.NewInt(4))
+	contracts.Stub(t, guardian, controllerABI, "getOwners", make([]common.Address, 8))
+
+	chains := chain.NewManager()
+	chains.Register(1, "ethereum", contracts)
+	adapter := NewCompoundV3Adapter(chains, map[uint64]CompoundV3Market{1: {Comet: comet}})
+
+	state, err := adapter.Governance(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("Governance failed: %v", err)
+	}
+	upgrade := state.ProxyAdmin
+	if upgrade == nil || upgrade.Address != proxyAdminContract || upgrade.Kind != ControllerContract {
+		t.Fatalf("Expected the proxy admin contract, got %+v", upgrade)
+	}
+	if lock := upgrade.Admin; lock == nil || lock.Kind != ControllerTimelock || lock.Delay != 172_800 {
+		t.Fatalf("Expected the proxy admin to be owned by the timelock, got %+v", lock)
+	}
+	if gov := upgrade.Admin.Admin; gov == nil || gov.Address != governor || gov.Kind != ControllerContract {
+		t.Errorf("Expected the timelock to be administered by the governor, got %+v", gov)
+	}
+	if state.Governor == nil || state.Governor.Address != timelock {
+		t.Errorf("Expected the timelock to govern the comet, got %+v", state.Governor)
+	}
+	if g := state.PauseGuardian; g == nil || g.Kind != ControllerSafe || g.Threshold != 4 || g.Owners != 8 {
+		t.Errorf("Expected a 4 of 8 safe as pause guardian, got %+v", g)
+	}
+
+	registry := NewRegistry(adapter)
+	if _, err := registry.GovernanceReaderFor(ProtocolCompoundV3); err != nil {
+		t.Errorf("Expected compound to report governance: %v", err)
+	}
+}
+
+func Test_AaveV3GovernanceWithSingleKey(t *testing.T) {
+	pool := common.HexToAddress("0x10")
+	provider := common.HexToAddress("0x11")
+	owner := common.HexToAddress("0xaa")
+	aclAdmin := common.HexToAddress("0x30")
+
+	contracts := chaintest.NewContracts(1)
+	contracts.Stub(t, pool, aavePoolABI, "ADDRESSES_PROVIDER", provider)
+	contracts.Stub(t, provider, controllerABI, "owner", owner)
+	contracts.Stub(t, provider, aaveOracleABI, "getACLAdmin", aclAdmin)
+	// an OpenZeppelin timelock whose proposers cannot be listed
+	contracts.Stub(t, aclAdmin, controllerABI, "getMinDelay", big.NewInt(86_400))
+
+	chains := chain.NewManager()
+	chains.Register(1, "ethereum", contracts)
+	adapter := NewAaveV3Adapter(chains, map[uint64]AaveV3Market{1: {Pool: pool, Asset: common.HexToAddress("0x1")}})
+
+	state, err := adapter.Governance(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("Governance failed: %v", err)
+	}
+	if a := state.ProxyAdmin; a == nil || a.Address != provider || a.Admin == nil || a.Admin.Kind != ControllerEOA {
+		t.Errorf("Expected the provider to be owned by a single key, got %+v", a)
+	}
+	if g := state.Governor; g == nil || g.Kind != ControllerTimelock || g.Delay != 86_400 || g.Admin != nil {
+		t.Errorf("Expected the ACL admin to be a timelock, got %+v", g)
+	}
+	if state.PauseGuardian != nil {
+		t.Errorf("Expected the pause guardian to be unknown, got %+v", state.PauseGuardian)
+	}
+}
+
+func Test_AaveV3EmergencyEvents(t *testing.T) {
+	pool := common.HexToAddress("0x10")
+	provider := common.HexToAddress("0x11")
+	configurator := common.HexToAddress("0x12")
+	usdc := common.HexToAddress("0x1")
+	weth := common.HexToAddress("0x2")
+
+	contracts := chaintest.NewContracts(1)
+	contracts.Stub(t, pool, aavePoolABI, "ADDRESSES_PROVIDER", provider)
+	contracts.Stub(t, provider, aaveOracleABI, "getPoolConfigurator", configurator)
+	reserveEvent := func(name string, asset common.Address, block uint64) types.Log {
+		return types.Log{
+			Address:     configurator,
+			Topics:      []common.Hash{aaveConfiguratorABI.Events[name].ID, common.BytesToHash(asset.Bytes())},
+			BlockNumber: block,
+		}
+	}
+	contracts.AddLog(reserveEvent("ReserveFrozen", usdc, 95))
+	contracts.AddLog(reserveEvent("ReservePaused", weth, 96))
+	contracts.AddLog(reserveEvent("ReservePaused", usdc, 50))
+	contracts.AddLog(types.Log{Address: pool, Topics: []common.Hash{proxyEventsABI.Events["Upgraded"].ID, {}}, BlockNumber: 92})
+
+	chains := chain.NewManager()
+	chains.Register(1, "ethereum", contracts)
+	adapter := NewAaveV3Adapter(chains, map[uint64]AaveV3Market{1: {Pool: pool, Asset: usdc}})
+
+	// the last eleven blocks up to the head at 100
+	log, err := adapter.EmergencyEvents(context.Background(), 1, 11)
+	if err != nil {
+		t.Fatalf("EmergencyEvents failed: %v", err)
+	}
+	if log.FromBlock != 90 || log.ToBlock != 100 {
+		t.Errorf("Expected blocks 90 to 100 to be scanned, got %d to %d", log.FromBlock, log.ToBlock)
+	}
+	events := log.Events
+	if len(events) != 2 || events[0].Name != "Upgraded" || events[1].Name != "ReserveFrozen" || events[1].BlockNumber != 95 {
+		t.Errorf("Expected the upgrade and the USDC freeze in chain order, got %+v", events)
+	}
+}
+
+func Test_AaveV3PendingCapChanges(t *testing.T) {
+	pool := common.HexToAddress("0x10")
+	provider := common.HexToAddress("0x11")
+	configurator := common.HexToAddress("0x12")
+	timelock := common.HexToAddress("0x30")
+	usdc := common.HexToAddress("0x1")
+	weth := common.HexToAddress("0x2")
+
+	contracts := chaintest.NewContracts(1)
+	contracts.SetHead(100, 1_700_000_000)
+	zero := big.NewInt(0)
+	// six decimals
+	configuration := new(big.Int).Lsh(big.NewInt(6), 48)
+	contracts.Stub(t, pool, aavePoolABI, "getReserveData",
+		configuration, zero, zero, zero, zero, zero, zero, uint16(0),
+		common.Address{}, common.Address{}, common.Address{}, common.Address{}, zero, zero, zero)
+	contracts.Stub(t, pool, aavePoolABI, "ADDRESSES_PROVIDER", provider)
+	contracts.Stub(t, provider, aaveOracleABI, "getPoolConfigurator", configurator)
+	contracts.Stub(t, provider, aaveOracleABI, "getACLAdmin", timelock)
+	contracts.Stub(t, timelock, controllerABI, "getMinDelay", big.NewInt(86_400))
+
+	schedule := func(id byte, asset common.Address, supplyCap int64, block uint64) {
+		call, err := aaveConfiguratorABI.Pack("setSupplyCap", asset, big.NewInt(supplyCap))
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := timelockEventsABI.Events["CallScheduled"].Inputs.NonIndexed().Pack(configurator, zero, call, [32]byte{}, big.NewInt(86_400))
+		if err != nil {
+			t.Fatal(err)
+		}
+		contracts.AddLog(types.Log{
+			Address:     timelock,
+			Topics:      []common.Hash{timelockEventsABI.Events["CallScheduled"].ID, {id}, {}},
+			Data:        data,
+			BlockNumber: block,
+		})
+	}
+	schedule(1, usdc, 500_000_000, 95)
+	schedule(2, weth, 1_000, 96)
+	schedule(3, usdc, 100, 97)
+	contracts.AddLog(types.Log{Address: timelock, Topics: []common.Hash{timelockEventsABI.Events["Cancelled"].ID, {3}}, BlockNumber: 98})
+	schedule(4, usdc, 0, 99)
+	schedule(5, usdc, 200, 92)
+	executed, err := timelockEventsABI.Events["CallExecuted"].Inputs.NonIndexed().Pack(configurator, zero, []byte{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	contracts.AddLog(types.Log{Address: timelock, Topics: []common.Hash{timelockEventsABI.Events["CallExecuted"].ID, {5}, {}}, Data: executed, BlockNumber: 93})
+
+	chains := chain.NewManager()
+	chains.Register(1, "ethereum", contracts)
+	registry := NewRegistry(NewAaveV3Adapter(chains, map[uint64]AaveV3Market{1: {Pool: pool, Asset: usdc}}))
+	reader, err := registry.CapChangeReaderFor(ProtocolAaveV3)
+	if err != nil {
+		t.Fatalf("Expected aave to report cap changes: %v", err)
+	}
+
+	changes, err := reader.PendingCapChanges(context.Background(), 1, 20)
+	if err != nil {
+		t.Fatalf("PendingCapChanges failed: %v", err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("Expected the pending USDC cap changes, got %+v", changes)
+	}
+	wantCap := new(big.Int).Mul(big.NewInt(500_000_000), big.NewInt(1_000_000))
+	if c := changes[0]; c.SupplyCap == nil || c.SupplyCap.Cmp(wantCap) != 0 || c.Call.ETA != 1_700_086_400 || c.Call.Timelock != timelock || c.Call.BlockNumber != 95 {
+		t.Errorf("Expected a 500M cap executable after the delay, got %+v", c)
+	}
+	if c := changes[1]; c.SupplyCap != nil || c.Call.BlockNumber != 99 {
+		t.Errorf("Expected the cap to be lifted, got %+v", c)
+	}
+
+	if _, err := NewRegistry(NewVenusAdapter(chains, nil)).CapChangeReaderFor(ProtocolVenus); !errors.Is(err, ErrNoCapChanges) {
+		t.Errorf("Expected venus not to report cap changes, got %v", err)
+	}
+}
+
+func Test_CompoundTimelockQueuedCalls(t *testing.T) {
+	timelock := common.HexToAddress("0x30")
+	target := common.HexToAddress("0x40")
+	contracts := chaintest.NewContracts(1)
+	contracts.SetHead(100, 1_700_000_000)
+
+	event := func(name string, txHash byte, signature string, args []byte, block uint64) {
+		data, err := timelockEventsABI.Events[name].Inputs.NonIndexed().Pack(big.NewInt(0), signature, args, big.NewInt(1_700_172_800))
+		if err != nil {
+			t.Fatal(err)
+		}
+		contracts.AddLog(types.Log{
+			Address:     timelock,
+			Topics:      []common.Hash{timelockEventsABI.Events[name].ID, {txHash}, common.BytesToHash(target.Bytes())},
+			Data:        data,
+			BlockNumber: block,
+		})
+	}
+	event("QueueTransaction", 1, "setSupplyCap(address,uint256)", []byte{0xab}, 90)
+	event("QueueTransaction", 2, "", []byte{0xcd}, 91)
+	event("QueueTransaction", 3, "pause()", nil, 92)
+	event("CancelTransaction", 3, "pause()", nil, 93)
+	event("QueueTransaction", 4, "unpause()", nil, 94)
+	event("ExecuteTransaction", 4, "unpause()", nil, 95)
+
+	calls, err := queuedCalls(context.Background(), contracts, timelock, 0)
+	if err != nil {
+		t.Fatalf("queuedCalls failed: %v", err)
+	}
+	if len(calls) != 2 || calls[0].Target != target || calls[0].ETA != 1_700_172_800 {
+		t.Fatalf("Expected the two pending calls, got %+v", calls)
+	}
+	selector := aaveConfiguratorABI.Methods["setSupplyCap"].ID
+	if want := append(selector, 0xab); !reflect.DeepEqual(calls[0].Data, want) {
+		t.Errorf("Expected the signature to be prepended as a selector, got %x", calls[0].Data)
+	}
+	if !reflect.DeepEqual(calls[1].Data, []byte{0xcd}) {
+		t.Errorf("Expected raw calldata to be kept, got %x", calls[1].Data)
+	}
+}
+
+func Test_CompoundV3EmergencyEvents(t *testing.T) {
+	comet := common.HexToAddress("0x10")
+	contracts := chaintest.NewContracts(8453)
+	contracts.AddLog(types.Log{Address: comet, Topics: []common.Hash{cometABI.Events["PauseAction"].ID}, BlockNumber: 99, Index: 3})
+	contracts.AddLog(types.Log{Address: comet, Topics: []common.Hash{proxyEventsABI.Events["AdminChanged"].ID}, BlockNumber: 99, Index: 1})
+	// removed by a reorg
+	contracts.AddLog(types.Log{Address: comet, Topics: []common.Hash{cometABI.Events["PauseAction"].ID}, BlockNumber: 98, Removed: true})
+
+	chains := chain.NewManager()
+	chains.Register(8453, "base", contracts)
+	adapter := NewCompoundV3Adapter(chains, map[uint64]CompoundV3Market{8453: {Comet: comet}})
+
+	// a lookback beyond genesis scans the whole chain
+	log, err := adapter.EmergencyEvents(context.Background(), 8453, 1_000)
+	if err != nil {
+		t.Fatalf("EmergencyEvents failed: %v", err)
+	}
+	events := log.Events
+	if log.FromBlock != 0 || len(events) != 2 || events[0].Name != "AdminChanged" || events[1].Name != "PauseAction" {
+		t.Errorf("Expected the admin change and the pause in log order, got %+v", events)
+	}
+	if _, err := NewRegistry(adapter).EmergencyReaderFor(ProtocolCompoundV3); err != nil {
+		t.Errorf("Expected compound to report emergency events: %v", err)
+	}
+}
+
+func Test_AaveV3WatchedEvents(t *testing.T) {
+	pool := common.HexToAddress("0x10")
+	provider := common.HexToAddress("0x11")
+	configurator := common.HexToAddress("0x12")
+	oracle := common.HexToAddress("0x13")
+	usdc := common.HexToAddress("0x1")
+	weth := common.HexToAddress("0x2")
+
+	contracts := chaintest.NewContracts(1)
+	contracts.Stub(t, pool, aavePoolABI, "ADDRESSES_PROVIDER", provider)
+	contracts.Stub(t, provider, aaveOracleABI, "getPoolConfigurator", configurator)
+	contracts.Stub(t, provider, aaveOracleABI, "getPriceOracle", oracle)
+	chains := chain.NewManager()
+	chains.Register(1, "ethereum", contracts)
+	adapter := NewAaveV3Adapter(chains, map[uint64]AaveV3Market{1: {Pool: pool, Asset: usdc}})
+
+	watches, err := adapter.WatchedEvents(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("WatchedEvents failed: %v", err)
+	}
+	matched := func(log types.Log) bool {
+		for _, w := range watches {
+			if w.Matches(log) {
+				return true
+			}
+		}
+		return false
+	}
+	reserveEvent := func(contract common.Address, contractABI abi.ABI, name string, asset common.Address) types.Log {
+		return types.Log{Address: contract, Topics: []common.Hash{contractABI.Events[name].ID, common.BytesToHash(asset.Bytes())}}
+	}
+
+	if !matched(reserveEvent(pool, aavePoolABI, "ReserveDataUpdated", usdc)) {
+		t.Errorf("Expected rate updates of USDC to be watched")
+	}
+	if matched(reserveEvent(pool, aavePoolABI, "ReserveDataUpdated", weth)) {
+		t.Errorf("Expected rate updates of other reserves to be ignored")
+	}
+	if !matched(reserveEvent(configurator, aaveConfiguratorABI, "SupplyCapChanged", usdc)) {
+		t.Errorf("Expected supply cap changes of USDC to be watched")
+	}
+	if matched(reserveEvent(oracle, aaveConfiguratorABI, "SupplyCapChanged", usdc)) {
+		t.Errorf("Expected events of other contracts to be ignored")
+	}
+	if !matched(reserveEvent(oracle, aaveOracleABI, "AssetSourceUpdated", usdc)) {
+		t.Errorf("Expected price source changes of USDC to be watched")
+	}
+}
+
+// fakePoolSubgraph serves the same trading days for every window
+type fakePoolSubgraph []subgraph.PoolDay
+
+func (f fakePoolSubgraph) PoolDays(ctx context.Context, from, to time.Time) ([]subgraph.PoolDay, error) {
+	return f, nil
+}
+
+func Test_LPMarketState(t *testing.T) {
+	pool := "0x0000000000000000000000000000000000000040"
+	contracts := chaintest.NewContracts(ChainIDEthereum)
+	contracts.Stub(t, common.HexToAddress(pool), curvePoolABI, "coins", usdcAddresses[ChainIDEthereum])
+	contracts.Stub(t, common.HexToAddress(pool), curvePoolABI, "get_virtual_price", new(big.Int).Mul(big.NewInt(101), new(big.Int).Exp(big.NewInt(10), big.NewInt(16), nil)))
+	contracts.Stub(t, common.HexToAddress(pool), curvePoolABI, "calc_withdraw_one_coin", big.NewInt(1_004_950))
+	chains := chain.NewManager()
+	chains.Register(ChainIDEthereum, "ethereum", contracts)
+
+	cfg := DefaultLPConfig()
+	var days fakePoolSubgraph
+	for i := 0; i < 7; i++ {
+		days = append(days, subgraph.PoolDay{FeesUSD: 100, IncentivesUSD: 50, TVLUSD: 1_000_000, PriceLow: 0.999, PriceHigh: 1.001})
+	}
+	curve := newLPAdapter("Curve_3pool", PoolKindCurve, map[uint64]lpDeployment{
+		ChainIDEthereum: {pool: pool, subgraph: days, incentiveRate: 0.01},
+	}, cfg, chains)
+	if !IsLiquidityPool(curve) || curve.Protocol() != "curve_3pool" {
+		t.Fatalf("Expected a liquidity pool named curve_3pool, got %s", curve.Protocol())
+	}
+
+	state, err := curve.MarketState(context.Background(), ChainIDEthereum)
+	if err != nil {
+		t.Fatalf("MarketState failed: %v", err)
+	}
+	lp := state.LP
+	if math.Abs(lp.FeeRate-0.0365) > 1e-9 || math.Abs(lp.IncentiveRate-0.02825) > 1e-9 {
+		t.Errorf("Expected fee and incentive rates of 3.65%% and 2.825%%, got %+v", lp)
+	}
+	// one LP token withdraws 0.5% less USDC than its virtual price
+	if math.Abs(lp.PegDeviation-0.005) > 1e-9 || math.Abs(lp.RiskModifier-0.75) > 1e-9 {
+		t.Errorf("Expected a 0.5%% peg deviation discounting the rate by a quarter, got %+v", lp)
+	}
+	if got, want := state.Pool.SupplyRate(), (0.0365+0.02825)*0.75; math.Abs(got-want) > 1e-9 {
+		t.Errorf("Expected a risk adjusted rate of %f, got %f", want, got)
+	}
+	if state.Pool.TotalSupply.Int64() != 1_000_000_000_000 {
+		t.Errorf("Expected the value locked as USDC units, got %s", state.Pool.TotalSupply)
+	}
+
+	// a pair trading 3% off the peg earns nothing once risk adjusted
+	days[3].PriceLow = 0.97
+	uniswap := newLPAdapter("uniswap_usdc_usdt", PoolKindUniswapV3, map[uint64]lpDeployment{ChainIDEthereum: {pool: pool, subgraph: days}}, cfg, chains)
+	if state, err = uniswap.MarketState(context.Background(), ChainIDEthereum); err != nil {
+		t.Fatalf("MarketState failed: %v", err)
+	}
+	if state.LP.RiskModifier != 0 || state.Pool.SupplyRate() != 0 {
+		t.Errorf("Expected a depegged pool to be discounted to zero, got %+v", state.LP)
+	}
+
+	empty := newLPAdapter("empty", PoolKindUniswapV4, map[uint64]lpDeployment{ChainIDEthereum: {pool: "0x01", subgraph: fakePoolSubgraph{}}}, cfg, chains)
+	if _, err := empty.MarketState(context.Background(), ChainIDEthereum); !errors.Is(err, ErrNoTradingHistory) {
+		t.Errorf("Expected ErrNoTradingHistory, got %v", err)
+	}
+}
+
+func Test_PendleMarketState(t *testing.T) {
+	market, sy := common.HexToAddress("0x0000000000000000000000000000000000000050"), common.HexToAddress("0x0000000000000000000000000000000000000051")
+	now := uint64(1_800_000_000)
+	expiry := now + rates.SecondsPerYear/2
+	lnRate, _ := new(big.Float).Mul(big.NewFloat(math.Log(1.08)), big.NewFloat(1e18)).Int(nil)
+	contracts := chaintest.NewContracts(ChainIDEthereum)
+	contracts.SetHead(100, now)
+	contracts.Stub(t, market, pendleMarketABI, "readTokens", sy, common.Address{1}, common.Address{2})
+	contracts.Stub(t, market, pendleMarketABI, "expiry", new(big.Int).SetUint64(expiry))
+	contracts.Stub(t, market, pendleMarketABI, "_storage", big.NewInt(1_000_000_000_000), big.NewInt(500_000_000_000), lnRate, uint16(0), uint16(1), uint16(1))
+	contracts.Stub(t, sy, pendleSYABI, "assetInfo", uint8(0), usdcAddresses[ChainIDEthereum], uint8(6))
+	contracts.Stub(t, sy, pendleSYABI, "exchangeRate", big.NewInt(1_100_000_000_000_000_000))
+	chains := chain.NewManager()
+	chains.Register(ChainIDEthereum, "ethereum", contracts)
+
+	cfg := PendleConfig{Markets: []PendleMarket{{Protocol: "Pendle_PT_aUSDC", Addresses: map[uint64]string{ChainIDEthereum: market.Hex()}}}}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Expected the pendle config to be valid: %v", err)
+	}
+	adapter := NewPendleAdapters(cfg, chains)[0]
+	if !IsFixedRate(adapter) || IsLiquidityPool(adapter) || adapter.Protocol() != "pendle_pt_ausdc" {
+		t.Fatalf("Expected a fixed rate market named pendle_pt_ausdc, got %s", adapter.Protocol())
+	}
+
+	state, err := adapter.MarketState(context.Background(), ChainIDEthereum)
+	if err != nil {
+		t.Fatalf("MarketState failed: %v", err)
+	}
+	if state.Fixed == nil || state.Fixed.Maturity != expiry || math.Abs(state.Fixed.ImpliedAPY-0.08) > 1e-9 {
+		t.Errorf("Expected an 8%% implied APY to maturity, got %+v", state.Fixed)
+	}
+	if got := state.Pool.SupplyRate(); math.Abs(got-math.Log(1.08)) > 1e-9 {
+		t.Errorf("Expected the implied rate as the supply rate, got %f", got)
+	}
+	// half a year out the principal tokens are worth 1/sqrt(1.08) and the SY 1.1 USDC
+	want := 1_000_000_000_000/math.Sqrt(1.08) + 550_000_000_000
+	if got, _ := new(big.Float).SetInt(state.Pool.TotalSupply).Float64(); math.Abs(got-want) > 1e6 {
+		t.Errorf("Expected %f USDC units supplied, got %f", want, got)
+	}
+
+	contracts.SetHead(101, expiry)
+	if _, err := adapter.MarketState(context.Background(), ChainIDEthereum); !errors.Is(err, ErrMarketMatured) {
+		t.Errorf("Expected ErrMarketMatured, got %v", err)
+	}
+}
+
+func Test_SkySavingsMarketState(t *testing.T) {
+	vault := common.HexToAddress("0x0000000000000000000000000000000000000060")
+	contracts := chaintest.NewContracts(ChainIDEthereum)
+	contracts.Stub(t, vault, susdsABI, "totalAssets", new(big.Int).Mul(big.NewInt(2_000_000_000), new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)))
+	contracts.Stub(t, vault, susdsABI, "ssr", ray(math.Exp(math.Log1p(0.045)/rates.SecondsPerYear)))
+	chains := chain.NewManager()
+	chains.Register(ChainIDEthereum, "ethereum", contracts)
+
+	adapter := NewSkySavingsAdapter(chains, map[uint64]common.Address{ChainIDEthereum: vault})
+	state, err := adapter.MarketState(context.Background(), ChainIDEthereum)
+	if err != nil {
+		t.Fatalf("MarketState failed: %v", err)
+	}
+	if got := state.Pool.SupplyRate(); math.Abs(got-math.Log1p(0.045)) > 1e-6 {
+		t.Errorf("Expected the savings rate, got %f", got)
+	}
+	if state.Pool.TotalSupply.Cmp(big.NewInt(2_000_000_000_000_000)) != 0 {
+		t.Errorf("Expected the USDS held as USDC units, got %s", state.Pool.TotalSupply)
+	}
+	registry := NewRegistry(adapter)
+	if source, err := registry.RWASourceFor(ProtocolSkySavings); err != nil || source.RWAProfile().Transferability != TransferPermissionless {
+		t.Errorf("Expected sUSDS to be a permissionless RWA source, got %v", err)
+	}
+}
+
+func Test_RWAMarketState(t *testing.T) {
+	token, feed := common.HexToAddress("0x0000000000000000000000000000000000000070"), common.HexToAddress("0x0000000000000000000000000000000000000071")
+	now := time.Unix(1_800_000_000, 0)
+	contracts := chaintest.NewContracts(ChainIDEthereum)
+	contracts.SetHead(100, uint64(now.Unix()))
+	contracts.Stub(t, token, erc20ABI, "totalSupply", new(big.Int).Mul(big.NewInt(10_000_000), new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)))
+	contracts.Stub(t, token, erc20ABI, "decimals", uint8(18))
+	contracts.Stub(t, feed, navFeedABI, "decimals", uint8(8))
+	round := func(price int64, updatedAt time.Time) {
+		contracts.Stub(t, feed, navFeedABI, "latestRoundData", big.NewInt(1), big.NewInt(price), big.NewInt(0), big.NewInt(updatedAt.Unix()), big.NewInt(1))
+	}
+	round(110_000_000, now.Add(-12*time.Hour))
+	chains := chain.NewManager()
+	chains.Register(ChainIDEthereum, "ethereum", contracts)
+
+	cfg := DefaultRWAConfig()
+	cfg.Tokens = []RWAToken{{
+		Protocol:        "ondo_usdy",
+		Issuer:          "Ondo",
+		Jurisdiction:    "US",
+		Backing:         "US Treasury bills",
+		Transferability: TransferRestricted,
+		KYC:             true,
+		Deployments:     map[uint64]RWADeployment{ChainIDEthereum: {Token: token.Hex(), Feed: feed.Hex()}},
+	}}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Expected the rwa config to be valid: %v", err)
+	}
+	history := store.NewSeriesStore(store.NewMemoryKV())
+	adapter := NewRWAAdapters(cfg, DefaultVaultConfig(), chains, history)[0]
+	adapter.now = func() time.Time { return now }
+	if profile := adapter.RWAProfile(); !profile.KYC || profile.Jurisdiction != "US" {
+		t.Errorf("Expected the configured profile, got %+v", profile)
+	}
+
+	price, err := adapter.SharePrice(context.Background(), ChainIDEthereum)
+	if err != nil || price != 1.1 {
+		t.Fatalf("Expected a net asset value of 1.1, got %f, %v", price, err)
+	}
+	if err := history.Append(context.Background(), store.SharePriceSeries(adapter.Protocol(), ChainIDEthereum), store.SeriesPoint{Time: now.Add(-5 * 24 * time.Hour), Value: 1.099}); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	state, err := adapter.MarketState(context.Background(), ChainIDEthereum)
+	if err != nil {
+		t.Fatalf("MarketState failed: %v", err)
+	}
+	want := math.Log(1.1/1.099) / (5 * 24 * 3600) * rates.SecondsPerYear
+	if got := state.Pool.SupplyRate(); math.Abs(got-want) > 1e-9 {
+		t.Errorf("Expected the annualized appreciation %f, got %f", want, got)
+	}
+	if state.Pool.TotalSupply.Int64() != 11_000_000_000_000 {
+		t.Errorf("Expected the value of every token issued, got %s", state.Pool.TotalSupply)
+	}
+
+	round(110_000_000, now.Add(-5*24*time.Hour))
+	if _, err := adapter.MarketState(context.Background(), ChainIDEthereum); err == nil {
+		t.Errorf("Expected a stale net asset value to be refused")
+	}
+}
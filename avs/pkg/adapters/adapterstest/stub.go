@@ -0,0 +1,98 @@
+// Package adapterstest provides yield adapters serving fixed markets, for tests and load
+// tests of the performer that should not reach any chain.
+package adapterstest
+
+import (
+	"context"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/irm"
+)
+
+// Stub is a YieldAdapter serving a fixed USDC market on each of its chains. Reads take
+// Latency, standing in for the RPC round trips of a real adapter.
+type Stub struct {
+	protocol string
+	markets  map[uint64]*adapters.MarketState
+	Latency  time.Duration
+}
+
+// NewStub returns a stub of protocol with a market on each of chainIDs. Markets differ
+// by protocol and chain, so tasks comparing them have a best one.
+func NewStub(protocol string, chainIDs ...uint64) *Stub {
+	s := &Stub{protocol: protocol, markets: make(map[uint64]*adapters.MarketState)}
+	for i, chainID := range chainIDs {
+		// utilization from 70% to 90%, varying with the protocol name and the chain
+		utilization := int64(70 + (len(protocol)*7+i*5)%21)
+		supply := new(big.Int).Mul(big.NewInt(100_000_000), big.NewInt(1_000_000))
+		s.markets[chainID] = &adapters.MarketState{
+			Protocol: protocol,
+			ChainID:  chainID,
+			Pool: irm.Pool{
+				TotalSupply: supply,
+				TotalBorrow: new(big.Int).Div(new(big.Int).Mul(supply, big.NewInt(utilization)), big.NewInt(100)),
+				Model: &irm.KinkModel{
+					OptimalUtilization: 0.9,
+					Slope1:             0.06,
+					Slope2:             0.6,
+					ReserveFactor:      0.1,
+				},
+			},
+		}
+	}
+	return s
+}
+
+// NewRegistry returns a registry of stubs of every protocol the performer supports, with
+// markets on the chains the protocol is deployed on, read with latency
+func NewRegistry(latency time.Duration) *adapters.Registry {
+	stubs := []*Stub{
+		NewStub(adapters.ProtocolAaveV3, adapters.ChainIDEthereum, adapters.ChainIDBase, adapters.ChainIDArbitrum),
+		NewStub(adapters.ProtocolCompoundV3, adapters.ChainIDEthereum, adapters.ChainIDBase, adapters.ChainIDArbitrum),
+		NewStub(adapters.ProtocolSparkSavings, adapters.ChainIDEthereum),
+		NewStub(adapters.ProtocolMoonwell, adapters.ChainIDBase),
+		NewStub(adapters.ProtocolVenus, adapters.ChainIDArbitrum),
+		NewStub(adapters.ProtocolFluid, adapters.ChainIDEthereum, adapters.ChainIDBase, adapters.ChainIDArbitrum),
+	}
+	registry := adapters.NewRegistry()
+	for _, stub := range stubs {
+		stub.Latency = latency
+		registry.Register(stub)
+	}
+	return registry
+}
+
+func (s *Stub) Protocol() string { return s.protocol }
+
+func (s *Stub) ChainIDs() []uint64 {
+	ids := make([]uint64, 0, len(s.markets))
+	for id := range s.markets {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// MarketState returns a copy of the market on chainID after Latency
+func (s *Stub) MarketState(ctx context.Context, chainID uint64) (*adapters.MarketState, error) {
+	market, ok := s.markets[chainID]
+	if !ok {
+		return nil, adapters.ErrUnsupportedChain
+	}
+	if s.Latency > 0 {
+		timer := time.NewTimer(s.Latency)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	state := *market
+	state.Pool.TotalSupply = new(big.Int).Set(market.Pool.TotalSupply)
+	state.Pool.TotalBorrow = new(big.Int).Set(market.Pool.TotalBorrow)
+	return &state, nil
+}
@@ -0,0 +1,38 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ErrNoAssets is returned for protocols whose adapter only reads USDC markets
+var ErrNoAssets = errors.New("protocol only reads USDC markets")
+
+// ErrAssetNotListed is returned for assets a protocol has no market for on a chain
+var ErrAssetNotListed = errors.New("asset is not listed")
+
+// AssetReader is implemented by adapters that can read the market of any asset lent on
+// the protocol, to compare the yields of other stablecoins with USDC's
+type AssetReader interface {
+	YieldAdapter
+
+	// AssetMarketState reads the current state of the market of asset on chainID. Amounts
+	// are in the base units of asset.
+	AssetMarketState(ctx context.Context, chainID uint64, asset common.Address) (*MarketState, error)
+}
+
+// AssetReaderFor returns the AssetReader of the adapter registered for protocol
+func (r *Registry) AssetReaderFor(protocol string) (AssetReader, error) {
+	adapter, err := r.Get(protocol)
+	if err != nil {
+		return nil, err
+	}
+	reader, ok := adapter.(AssetReader)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoAssets, protocol)
+	}
+	return reader, nil
+}
@@ -0,0 +1,14 @@
+package adapters
+
+import "github.com/najnomics/crosscow-avs/pkg/chain"
+
+const erc20ABIJson = `[
+	{"name":"totalSupply","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+	{"name":"decimals","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint8"}]},
+	{"name":"balanceOf","type":"function","stateMutability":"view","inputs":[{"name":"account","type":"address"}],"outputs":[{"name":"","type":"uint256"}]},
+	{"name":"approve","type":"function","stateMutability":"nonpayable",
+	 "inputs":[{"name":"spender","type":"address"},{"name":"amount","type":"uint256"}],
+	 "outputs":[{"name":"","type":"bool"}]}
+]`
+
+var erc20ABI = chain.MustParseABI(erc20ABIJson)
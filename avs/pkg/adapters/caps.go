@@ -0,0 +1,198 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+)
+
+// ErrNoCapChanges is returned for protocols whose adapter cannot read queued cap changes
+var ErrNoCapChanges = errors.New("protocol does not report queued cap changes")
+
+// QueuedCall is a call a timelock queued that was neither executed nor cancelled since
+type QueuedCall struct {
+	Timelock common.Address
+	Target   common.Address
+	Data     []byte
+
+	// ETA is the earliest time the call can execute
+	ETA uint64
+
+	BlockNumber uint64
+	TxHash      common.Hash
+}
+
+// CapChange is a queued call setting the supply cap of a market
+type CapChange struct {
+	// SupplyCap is the cap in USDC base units the call sets, nil when it lifts the cap
+	SupplyCap *big.Int
+	Call      QueuedCall
+}
+
+// CapChangeReader reads the supply cap changes governance queued for a protocol's USDC
+// markets
+type CapChangeReader interface {
+	RiskReader
+
+	// PendingCapChanges returns the supply cap changes of the USDC market on chainID
+	// queued in timelocks within the latest lookback blocks, in chain order
+	PendingCapChanges(ctx context.Context, chainID uint64, lookback uint64) ([]CapChange, error)
+}
+
+// CapChangeReaderFor returns the CapChangeReader of the adapter registered for protocol
+func (r *Registry) CapChangeReaderFor(protocol string) (CapChangeReader, error) {
+	adapter, err := r.Get(protocol)
+	if err != nil {
+		return nil, err
+	}
+	reader, ok := adapter.(CapChangeReader)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoCapChanges, protocol)
+	}
+	return reader, nil
+}
+
+// Events of Compound timelocks, keyed by transaction hash, and of OpenZeppelin
+// TimelockControllers, keyed by operation id and call index
+var timelockEventsABI = chain.MustParseABI(`[
+	{"name":"QueueTransaction","type":"event","anonymous":false,"inputs":[
+		{"name":"txHash","type":"bytes32","indexed":true},{"name":"target","type":"address","indexed":true},
+		{"name":"value","type":"uint256","indexed":false},{"name":"signature","type":"string","indexed":false},
+		{"name":"data","type":"bytes","indexed":false},{"name":"eta","type":"uint256","indexed":false}]},
+	{"name":"ExecuteTransaction","type":"event","anonymous":false,"inputs":[
+		{"name":"txHash","type":"bytes32","indexed":true},{"name":"target","type":"address","indexed":true},
+		{"name":"value","type":"uint256","indexed":false},{"name":"signature","type":"string","indexed":false},
+		{"name":"data","type":"bytes","indexed":false},{"name":"eta","type":"uint256","indexed":false}]},
+	{"name":"CancelTransaction","type":"event","anonymous":false,"inputs":[
+		{"name":"txHash","type":"bytes32","indexed":true},{"name":"target","type":"address","indexed":true},
+		{"name":"value","type":"uint256","indexed":false},{"name":"signature","type":"string","indexed":false},
+		{"name":"data","type":"bytes","indexed":false},{"name":"eta","type":"uint256","indexed":false}]},
+	{"name":"CallScheduled","type":"event","anonymous":false,"inputs":[
+		{"name":"id","type":"bytes32","indexed":true},{"name":"index","type":"uint256","indexed":true},
+		{"name":"target","type":"address","indexed":false},{"name":"value","type":"uint256","indexed":false},
+		{"name":"data","type":"bytes","indexed":false},{"name":"predecessor","type":"bytes32","indexed":false},
+		{"name":"delay","type":"uint256","indexed":false}]},
+	{"name":"CallExecuted","type":"event","anonymous":false,"inputs":[
+		{"name":"id","type":"bytes32","indexed":true},{"name":"index","type":"uint256","indexed":true},
+		{"name":"target","type":"address","indexed":false},{"name":"value","type":"uint256","indexed":false},
+		{"name":"data","type":"bytes","indexed":false}]},
+	{"name":"Cancelled","type":"event","anonymous":false,"inputs":[{"name":"id","type":"bytes32","indexed":true}]}
+]`)
+
+// timelocks returns the timelocks among c and the controllers administering it
+func timelocks(c *Controller) []common.Address {
+	var out []common.Address
+	for ; c != nil; c = c.Admin {
+		if c.Kind == ControllerTimelock {
+			out = append(out, c.Address)
+		}
+	}
+	return out
+}
+
+// queuedCalls returns the calls timelock queued within the lookback blocks up to the head,
+// or the block ctx is pinned to, that were not executed or cancelled since, in chain order
+func queuedCalls(ctx context.Context, client chain.Client, timelock common.Address, lookback uint64) ([]QueuedCall, error) {
+	head, err := chain.Head(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	fromBlock := uint64(0)
+	if lookback > 0 && head+1 > lookback {
+		fromBlock = head + 1 - lookback
+	}
+
+	topics := make([]common.Hash, 0, len(timelockEventsABI.Events))
+	for _, event := range timelockEventsABI.Events {
+		topics = append(topics, event.ID)
+	}
+	logs, err := client.FilterLogs(ctx, ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(fromBlock),
+		ToBlock:   new(big.Int).SetUint64(head),
+		Addresses: []common.Address{timelock},
+		Topics:    [][]common.Hash{topics},
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(logs, func(i, j int) bool {
+		if logs[i].BlockNumber != logs[j].BlockNumber {
+			return logs[i].BlockNumber < logs[j].BlockNumber
+		}
+		return logs[i].Index < logs[j].Index
+	})
+
+	// Compound timelocks key calls by transaction hash. OpenZeppelin ones key them by
+	// operation id and index, and cancel all calls of an operation at once.
+	queued := make(map[common.Hash]QueuedCall)
+	var order []common.Hash
+	operations := make(map[common.Hash][]common.Hash)
+	for _, log := range logs {
+		if log.Removed || len(log.Topics) < 2 {
+			continue
+		}
+		event, err := timelockEventsABI.EventByID(log.Topics[0])
+		if err != nil {
+			continue
+		}
+		switch event.Name {
+		case "QueueTransaction", "CallScheduled":
+			values, err := event.Inputs.NonIndexed().Unpack(log.Data)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode %s: %w", event.Name, err)
+			}
+			call := QueuedCall{Timelock: timelock, BlockNumber: log.BlockNumber, TxHash: log.TxHash}
+			key := log.Topics[1]
+			if event.Name == "QueueTransaction" {
+				// calls naming a signature carry only the arguments
+				call.Target = common.BytesToAddress(log.Topics[2].Bytes())
+				call.Data = values[2].([]byte)
+				if signature := values[1].(string); signature != "" {
+					call.Data = append(crypto.Keccak256([]byte(signature))[:4], call.Data...)
+				}
+				call.ETA = values[3].(*big.Int).Uint64()
+			} else {
+				if len(log.Topics) < 3 {
+					continue
+				}
+				header, err := client.HeaderByNumber(ctx, new(big.Int).SetUint64(log.BlockNumber))
+				if err != nil {
+					return nil, err
+				}
+				call.Target = values[0].(common.Address)
+				call.Data = values[2].([]byte)
+				call.ETA = header.Time + values[4].(*big.Int).Uint64()
+				key = crypto.Keccak256Hash(log.Topics[1].Bytes(), log.Topics[2].Bytes())
+				operations[log.Topics[1]] = append(operations[log.Topics[1]], key)
+			}
+			queued[key] = call
+			order = append(order, key)
+		case "ExecuteTransaction", "CancelTransaction":
+			delete(queued, log.Topics[1])
+		case "CallExecuted":
+			if len(log.Topics) > 2 {
+				delete(queued, crypto.Keccak256Hash(log.Topics[1].Bytes(), log.Topics[2].Bytes()))
+			}
+		case "Cancelled":
+			for _, key := range operations[log.Topics[1]] {
+				delete(queued, key)
+			}
+		}
+	}
+
+	calls := []QueuedCall{}
+	for _, key := range order {
+		if call, ok := queued[key]; ok {
+			calls = append(calls, call)
+			delete(queued, key)
+		}
+	}
+	return calls, nil
+}
@@ -0,0 +1,378 @@
+package adapters
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/irm"
+	"github.com/najnomics/crosscow-avs/pkg/rates"
+)
+
+// ProtocolCompoundV3 is the task parameter name of Compound v3
+const ProtocolCompoundV3 = "compound_v3"
+
+// Comet rate parameters are per-second rates in wad; prices have 8 decimals
+const cometPriceDecimals = 8
+
+const cometABIJson = `[
+	{"name":"totalSupply","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+	{"name":"balanceOf","type":"function","stateMutability":"view","inputs":[{"name":"account","type":"address"}],"outputs":[{"name":"","type":"uint256"}]},
+	{"name":"totalBorrow","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+	{"name":"supplyKink","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+	{"name":"supplyPerSecondInterestRateBase","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+	{"name":"supplyPerSecondInterestRateSlopeLow","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+	{"name":"supplyPerSecondInterestRateSlopeHigh","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+	{"name":"baseTrackingSupplySpeed","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint64"}]},
+	{"name":"trackingIndexScale","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint64"}]},
+	{"name":"getReserves","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"int256"}]},
+	{"name":"isSupplyPaused","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"bool"}]},
+	{"name":"isWithdrawPaused","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"bool"}]},
+	{"name":"baseTokenPriceFeed","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"address"}]},
+	{"name":"governor","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"address"}]},
+	{"name":"pauseGuardian","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"address"}]},
+	{"name":"getPrice","type":"function","stateMutability":"view","inputs":[{"name":"priceFeed","type":"address"}],"outputs":[{"name":"","type":"uint256"}]},
+	{"name":"Supply","type":"event","anonymous":false,"inputs":[{"name":"from","type":"address","indexed":true},{"name":"dst","type":"address","indexed":true},{"name":"amount","type":"uint256","indexed":false}]},
+	{"name":"Withdraw","type":"event","anonymous":false,"inputs":[{"name":"src","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"amount","type":"uint256","indexed":false}]},
+	{"name":"AbsorbDebt","type":"event","anonymous":false,"inputs":[{"name":"absorber","type":"address","indexed":true},{"name":"borrower","type":"address","indexed":true},{"name":"basePaidOut","type":"uint256","indexed":false},{"name":"usdValue","type":"uint256","indexed":false}]},
+	{"name":"BuyCollateral","type":"event","anonymous":false,"inputs":[{"name":"buyer","type":"address","indexed":true},{"name":"asset","type":"address","indexed":true},{"name":"baseAmount","type":"uint256","indexed":false},{"name":"collateralAmount","type":"uint256","indexed":false}]},
+	{"name":"WithdrawReserves","type":"event","anonymous":false,"inputs":[{"name":"to","type":"address","indexed":true},{"name":"amount","type":"uint256","indexed":false}]},
+	{"name":"PauseAction","type":"event","anonymous":false,"inputs":[
+		{"name":"supplyPaused","type":"bool","indexed":false},
+		{"name":"transferPaused","type":"bool","indexed":false},
+		{"name":"withdrawPaused","type":"bool","indexed":false},
+		{"name":"absorbPaused","type":"bool","indexed":false},
+		{"name":"buyPaused","type":"bool","indexed":false}
+	]},
+	{"name":"supplyTo","type":"function","stateMutability":"nonpayable",
+	 "inputs":[{"name":"dst","type":"address"},{"name":"asset","type":"address"},{"name":"amount","type":"uint256"}],
+	 "outputs":[]},
+	{"name":"withdrawTo","type":"function","stateMutability":"nonpayable",
+	 "inputs":[{"name":"to","type":"address"},{"name":"asset","type":"address"},{"name":"amount","type":"uint256"}],
+	 "outputs":[]}
+]`
+
+var cometABI = chain.MustParseABI(cometABIJson)
+
+// CometRewards pays out the rewards Comets track, rescaled from 6 decimals to the
+// decimals of the reward token
+var cometRewardsABI = chain.MustParseABI(`[
+	{"name":"rewardConfig","type":"function","stateMutability":"view","inputs":[{"name":"comet","type":"address"}],
+	 "outputs":[
+		{"name":"token","type":"address"},
+		{"name":"rescaleFactor","type":"uint64"},
+		{"name":"shouldUpscale","type":"bool"}
+	]}
+]`)
+
+// cometAccrualDecimals are the decimals Comets track reward accrual in
+const cometAccrualDecimals = 6
+
+// CompoundV3Market locates the USDC Comet of a Compound v3 deployment
+type CompoundV3Market struct {
+	Comet common.Address
+
+	// Rewards is the CometRewards contract of the deployment, no incentives are read when
+	// unset
+	Rewards common.Address
+}
+
+// CompoundV3Adapter reads USDC Comet markets
+type CompoundV3Adapter struct {
+	chains  *chain.Manager
+	markets map[uint64]CompoundV3Market
+}
+
+// NewCompoundV3Adapter creates an adapter for the given per-chain markets
+func NewCompoundV3Adapter(chains *chain.Manager, markets map[uint64]CompoundV3Market) *CompoundV3Adapter {
+	return &CompoundV3Adapter{chains: chains, markets: markets}
+}
+
+func (a *CompoundV3Adapter) Protocol() string {
+	return ProtocolCompoundV3
+}
+
+func (a *CompoundV3Adapter) ChainIDs() []uint64 {
+	return sortedChainIDs(a.markets)
+}
+
+func (a *CompoundV3Adapter) MarketState(ctx context.Context, chainID uint64) (*MarketState, error) {
+	market, ok := a.markets[chainID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s on %d", ErrUnsupportedChain, ProtocolCompoundV3, chainID)
+	}
+	client, err := a.chains.ClientFor(chainID, ProtocolCompoundV3)
+	if err != nil {
+		return nil, err
+	}
+
+	methods := []string{
+		"totalSupply",
+		"totalBorrow",
+		"supplyKink",
+		"supplyPerSecondInterestRateBase",
+		"supplyPerSecondInterestRateSlopeLow",
+		"supplyPerSecondInterestRateSlopeHigh",
+	}
+	batch := chain.NewBatch(client)
+	calls := make([]*chain.BatchCall, len(methods))
+	for i, method := range methods {
+		calls[i] = batch.Add(market.Comet, cometABI, method)
+	}
+	if err := batch.Do(ctx); err != nil {
+		return nil, err
+	}
+
+	values := make([]float64, len(methods))
+	state := &MarketState{Protocol: ProtocolCompoundV3, ChainID: chainID}
+	for i, method := range methods {
+		value, err := calls[i].Uint()
+		if err != nil {
+			return nil, err
+		}
+		switch method {
+		case "totalSupply":
+			state.Pool.TotalSupply = value
+		case "totalBorrow":
+			state.Pool.TotalBorrow = value
+		case "supplyKink":
+			values[i] = rates.Wad(value)
+		default:
+			values[i] = rates.Annualize(rates.Wad(value), rates.SecondsPerYear, rates.Simple)
+		}
+	}
+
+	state.Pool.Model = &irm.CometModel{
+		Kink:      values[2],
+		Base:      values[3],
+		SlopeLow:  values[4],
+		SlopeHigh: values[5],
+	}
+	return state, nil
+}
+
+// SupplyCall builds Comet.supplyTo of amount USDC credited to onBehalfOf
+func (a *CompoundV3Adapter) SupplyCall(chainID uint64, onBehalfOf common.Address, amount *big.Int) (*chain.Call, error) {
+	market, usdc, err := a.market(chainID)
+	if err != nil {
+		return nil, err
+	}
+	return packCall(market.Comet, cometABI, "supplyTo", onBehalfOf, usdc, amount)
+}
+
+// WithdrawCall builds Comet.withdrawTo of amount USDC sent to to
+func (a *CompoundV3Adapter) WithdrawCall(chainID uint64, to common.Address, amount *big.Int) (*chain.Call, error) {
+	market, usdc, err := a.market(chainID)
+	if err != nil {
+		return nil, err
+	}
+	return packCall(market.Comet, cometABI, "withdrawTo", to, usdc, amount)
+}
+
+// RiskState reads the pause flags, reserves and base price feed of the Comet. Comets
+// have no cap on the base asset; losses of absorbed accounts the reserves could not cover
+// leave them negative, which is reported as bad debt.
+func (a *CompoundV3Adapter) RiskState(ctx context.Context, chainID uint64) (*RiskState, error) {
+	market, ok := a.markets[chainID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s on %d", ErrUnsupportedChain, ProtocolCompoundV3, chainID)
+	}
+	client, err := a.chains.ClientFor(chainID, ProtocolCompoundV3)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := chain.NewBatch(client)
+	pauses := []*chain.BatchCall{
+		batch.Add(market.Comet, cometABI, "isSupplyPaused"),
+		batch.Add(market.Comet, cometABI, "isWithdrawPaused"),
+	}
+	reservesCall := batch.Add(market.Comet, cometABI, "getReserves")
+	feedCall := batch.Add(market.Comet, cometABI, "baseTokenPriceFeed")
+	if err := batch.Do(ctx); err != nil {
+		return nil, err
+	}
+
+	state := &RiskState{}
+	for _, call := range pauses {
+		paused, err := call.Values()
+		if err != nil {
+			return nil, err
+		}
+		state.Paused = state.Paused || paused[0].(bool)
+	}
+
+	reserves, err := reservesCall.Values()
+	if err != nil {
+		return nil, err
+	}
+	state.BadDebt = new(big.Int)
+	if balance := reserves[0].(*big.Int); balance.Sign() < 0 {
+		state.BadDebt.Neg(balance)
+	}
+
+	feed, err := feedCall.Values()
+	if err != nil {
+		return nil, err
+	}
+	oracle := &Oracle{Feed: feed[0].(common.Address), Decimals: cometPriceDecimals}
+	if oracle.Price, err = chain.CallUint(ctx, client, market.Comet, cometABI, "getPrice", oracle.Feed); err != nil {
+		return nil, err
+	}
+	if oracle.UpdatedAt, err = roundUpdatedAt(ctx, client, oracle.Feed); err != nil {
+		return nil, err
+	}
+	state.Oracle = oracle
+
+	if state.Timestamp, err = blockTime(ctx, client); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// Governance inspects the admin of the Comet proxy, usually a CometProxyAdmin owned by the
+// governance timelock, the governor configuring the Comet and its pause guardian
+func (a *CompoundV3Adapter) Governance(ctx context.Context, chainID uint64) (*GovernanceState, error) {
+	market, ok := a.markets[chainID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s on %d", ErrUnsupportedChain, ProtocolCompoundV3, chainID)
+	}
+	client, err := a.chains.ClientFor(chainID, ProtocolCompoundV3)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &GovernanceState{}
+	if state.ProxyAdmin, err = proxyAdmin(ctx, client, market.Comet); err != nil {
+		return nil, err
+	}
+	for _, role := range []struct {
+		method     string
+		controller **Controller
+	}{
+		{"governor", &state.Governor},
+		{"pauseGuardian", &state.PauseGuardian},
+	} {
+		account, err := chain.CallView(ctx, client, market.Comet, cometABI, role.method)
+		if err != nil {
+			return nil, err
+		}
+		if *role.controller, err = inspectController(ctx, client, account[0].(common.Address)); err != nil {
+			return nil, err
+		}
+	}
+	return state, nil
+}
+
+// EmergencyEvents returns pause actions of the Comet and changes of its implementation or
+// proxy admin
+func (a *CompoundV3Adapter) EmergencyEvents(ctx context.Context, chainID uint64, lookback uint64) (*EmergencyLog, error) {
+	market, ok := a.markets[chainID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s on %d", ErrUnsupportedChain, ProtocolCompoundV3, chainID)
+	}
+	client, err := a.chains.ClientFor(chainID, ProtocolCompoundV3)
+	if err != nil {
+		return nil, err
+	}
+	return filterEvents(ctx, client, lookback,
+		eventFilter{contract: market.Comet, contractABI: cometABI, events: []string{"PauseAction"}},
+		eventFilter{contract: market.Comet, contractABI: proxyEventsABI, events: []string{"Upgraded", "AdminChanged"}},
+	)
+}
+
+// WatchedEvents returns the Comet's base supply and withdrawal events, which borrows and
+// repayments emit too, absorptions, collateral sales and reserve withdrawals, pause actions
+// and upgrades, which is how its configuration changes
+func (a *CompoundV3Adapter) WatchedEvents(ctx context.Context, chainID uint64) ([]EventWatch, error) {
+	market, ok := a.markets[chainID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s on %d", ErrUnsupportedChain, ProtocolCompoundV3, chainID)
+	}
+	comet, err := watch(market.Comet, cometABI, nil, "Supply", "Withdraw", "AbsorbDebt", "BuyCollateral", "WithdrawReserves", "PauseAction")
+	if err != nil {
+		return nil, err
+	}
+	proxy, err := watch(market.Comet, proxyEventsABI, nil, "Upgraded")
+	if err != nil {
+		return nil, err
+	}
+	return []EventWatch{comet, proxy}, nil
+}
+
+// Incentives reads the reward speed of Comet suppliers. Comets track accrual at a speed
+// scaled by trackingIndexScale in 6 decimals, which CometRewards rescales to the token.
+func (a *CompoundV3Adapter) Incentives(ctx context.Context, chainID uint64) (*Incentives, error) {
+	market, _, err := a.market(chainID)
+	if err != nil {
+		return nil, err
+	}
+	client, err := a.chains.ClientFor(chainID, ProtocolCompoundV3)
+	if err != nil {
+		return nil, err
+	}
+	if market.Rewards == (common.Address{}) {
+		return incentivesAt(ctx, client, nil)
+	}
+
+	config, err := chain.CallView(ctx, client, market.Rewards, cometRewardsABI, "rewardConfig", market.Comet)
+	if err != nil {
+		return nil, err
+	}
+	token := config[0].(common.Address)
+	if token == (common.Address{}) {
+		return incentivesAt(ctx, client, nil)
+	}
+	speed, err := chain.CallView(ctx, client, market.Comet, cometABI, "baseTrackingSupplySpeed")
+	if err != nil {
+		return nil, err
+	}
+	scale, err := chain.CallView(ctx, client, market.Comet, cometABI, "trackingIndexScale")
+	if err != nil {
+		return nil, err
+	}
+	decimals, err := tokenDecimals(ctx, client, token)
+	if err != nil {
+		return nil, err
+	}
+
+	perSecond := new(big.Int).SetUint64(speed[0].(uint64))
+	perSecond.Mul(perSecond, new(big.Int).Exp(big.NewInt(10), big.NewInt(cometAccrualDecimals), nil))
+	rescale := new(big.Int).SetUint64(config[1].(uint64))
+	if config[2].(bool) {
+		perSecond.Mul(perSecond, rescale)
+	} else if rescale.Sign() > 0 {
+		perSecond.Quo(perSecond, rescale)
+	}
+	if scale := scale[0].(uint64); scale > 0 {
+		perSecond.Quo(perSecond, new(big.Int).SetUint64(scale))
+	}
+	return incentivesAt(ctx, client, []Emission{{Token: token, Decimals: decimals, PerSecond: perSecond}})
+}
+
+// PositionOf reads Comet.balanceOf, the present value of account's supply
+func (a *CompoundV3Adapter) PositionOf(ctx context.Context, chainID uint64, account common.Address) (*big.Int, error) {
+	market, _, err := a.market(chainID)
+	if err != nil {
+		return nil, err
+	}
+	client, err := a.chains.ClientFor(chainID, ProtocolCompoundV3)
+	if err != nil {
+		return nil, err
+	}
+	return chain.CallUint(ctx, client, market.Comet, cometABI, "balanceOf", account)
+}
+
+func (a *CompoundV3Adapter) market(chainID uint64) (CompoundV3Market, common.Address, error) {
+	market, ok := a.markets[chainID]
+	if !ok {
+		return CompoundV3Market{}, common.Address{}, fmt.Errorf("%w: %s on %d", ErrUnsupportedChain, ProtocolCompoundV3, chainID)
+	}
+	usdc, err := USDCAddress(chainID)
+	if err != nil {
+		return CompoundV3Market{}, common.Address{}, err
+	}
+	return market, usdc, nil
+}
@@ -0,0 +1,257 @@
+package adapters
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/irm"
+	"github.com/najnomics/crosscow-avs/pkg/rates"
+)
+
+// Task parameter names of the Compound v2 forks
+const (
+	ProtocolMoonwell = "moonwell"
+	ProtocolVenus    = "venus"
+)
+
+const cTokenABIJson = `[
+	{"name":"underlying","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"address"}]},
+	{"name":"getCash","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+	{"name":"totalBorrows","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+	{"name":"totalReserves","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+	{"name":"reserveFactorMantissa","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+	{"name":"interestRateModel","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"address"}]},
+	{"name":"comptroller","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"address"}]}
+]`
+
+var cTokenABI = chain.MustParseABI(cTokenABIJson)
+
+// Moonwell names its rate parameters per timestamp, Venus per block whether its markets
+// accrue by block or by second
+const jumpRateModelABIJson = `[
+	{"name":"kink","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+	{"name":"baseRatePerTimestamp","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+	{"name":"multiplierPerTimestamp","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+	{"name":"jumpMultiplierPerTimestamp","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+	{"name":"baseRatePerBlock","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+	{"name":"multiplierPerBlock","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+	{"name":"jumpMultiplierPerBlock","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]}
+]`
+
+var jumpRateModelABI = chain.MustParseABI(jumpRateModelABIJson)
+
+// The Moonwell comptroller names the MultiRewardDistributor paying every emission token
+// of a market
+var moonwellRewardsABI = chain.MustParseABI(`[
+	{"name":"rewardDistributor","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"address"}]},
+	{"name":"getAllMarketConfigs","type":"function","stateMutability":"view","inputs":[{"name":"mToken","type":"address"}],
+	 "outputs":[{"name":"","type":"tuple[]","components":[
+		{"name":"owner","type":"address"},
+		{"name":"emissionToken","type":"address"},
+		{"name":"endTime","type":"uint256"},
+		{"name":"supplyGlobalIndex","type":"uint224"},
+		{"name":"supplyGlobalTimestamp","type":"uint32"},
+		{"name":"borrowGlobalIndex","type":"uint224"},
+		{"name":"borrowGlobalTimestamp","type":"uint32"},
+		{"name":"supplyEmissionsPerSec","type":"uint256"},
+		{"name":"borrowEmissionsPerSec","type":"uint256"}
+	]}]}
+]`)
+
+// moonwellMarketConfig is a MultiRewardDistributor market config, decoded by field name
+type moonwellMarketConfig struct {
+	Owner                 common.Address
+	EmissionToken         common.Address
+	EndTime               *big.Int
+	SupplyGlobalIndex     *big.Int
+	SupplyGlobalTimestamp uint32
+	BorrowGlobalIndex     *big.Int
+	BorrowGlobalTimestamp uint32
+	SupplyEmissionsPerSec *big.Int
+	BorrowEmissionsPerSec *big.Int
+}
+
+// CompoundV2Market locates the USDC market of a Compound v2 fork deployment
+type CompoundV2Market struct {
+	CToken common.Address
+
+	// PeriodsPerYear annualizes the per-period rates of the market: seconds per year for
+	// markets accruing by timestamp, blocks per year for markets accruing by block
+	PeriodsPerYear float64
+}
+
+// CompoundV2Adapter reads the USDC markets of a Compound v2 fork. It is not a Mover:
+// cTokens are minted to the sender and cannot be credited to the user.
+type CompoundV2Adapter struct {
+	protocol string
+	// period is the suffix of the rate parameters of the interest rate model
+	period  string
+	chains  *chain.Manager
+	markets map[uint64]CompoundV2Market
+}
+
+// NewMoonwellAdapter creates an adapter for the given per-chain Moonwell markets
+func NewMoonwellAdapter(chains *chain.Manager, markets map[uint64]CompoundV2Market) *CompoundV2Adapter {
+	return &CompoundV2Adapter{protocol: ProtocolMoonwell, period: "PerTimestamp", chains: chains, markets: markets}
+}
+
+// NewVenusAdapter creates an adapter for the given per-chain Venus markets
+func NewVenusAdapter(chains *chain.Manager, markets map[uint64]CompoundV2Market) *CompoundV2Adapter {
+	return &CompoundV2Adapter{protocol: ProtocolVenus, period: "PerBlock", chains: chains, markets: markets}
+}
+
+func (a *CompoundV2Adapter) Protocol() string {
+	return a.protocol
+}
+
+func (a *CompoundV2Adapter) ChainIDs() []uint64 {
+	return sortedChainIDs(a.markets)
+}
+
+// MarketState reads the USDC market and its jump rate model. Supply is cash plus borrows
+// minus reserves, the base Compound v2 computes utilization from.
+func (a *CompoundV2Adapter) MarketState(ctx context.Context, chainID uint64) (*MarketState, error) {
+	market, ok := a.markets[chainID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s on %d", ErrUnsupportedChain, a.protocol, chainID)
+	}
+	client, err := a.chains.ClientFor(chainID, a.protocol)
+	if err != nil {
+		return nil, err
+	}
+
+	usdc, err := USDCAddress(chainID)
+	if err != nil {
+		return nil, err
+	}
+	batch := chain.NewBatch(client)
+	underlyingCall := batch.Add(market.CToken, cTokenABI, "underlying")
+	methods := []string{"getCash", "totalBorrows", "totalReserves", "reserveFactorMantissa"}
+	calls := make([]*chain.BatchCall, len(methods))
+	for i, method := range methods {
+		calls[i] = batch.Add(market.CToken, cTokenABI, method)
+	}
+	modelCall := batch.Add(market.CToken, cTokenABI, "interestRateModel")
+	if err := batch.Do(ctx); err != nil {
+		return nil, err
+	}
+
+	underlying, err := underlyingCall.Values()
+	if err != nil {
+		return nil, err
+	}
+	if underlying[0].(common.Address) != usdc {
+		return nil, fmt.Errorf("%s market %s lends %s, not USDC", a.protocol, market.CToken.Hex(), underlying[0].(common.Address).Hex())
+	}
+
+	values := make(map[string]*big.Int, len(methods))
+	for i, method := range methods {
+		if values[method], err = calls[i].Uint(); err != nil {
+			return nil, err
+		}
+	}
+	modelAddress, err := modelCall.Values()
+	if err != nil {
+		return nil, err
+	}
+	model, err := a.rateModel(ctx, client, modelAddress[0].(common.Address), market.PeriodsPerYear)
+	if err != nil {
+		return nil, err
+	}
+	model.ReserveFactor = rates.Wad(values["reserveFactorMantissa"])
+
+	supply := new(big.Int).Add(values["getCash"], values["totalBorrows"])
+	supply.Sub(supply, values["totalReserves"])
+	return &MarketState{
+		Protocol: a.protocol,
+		ChainID:  chainID,
+		Pool: irm.Pool{
+			TotalSupply: supply,
+			TotalBorrow: values["totalBorrows"],
+			Model:       model,
+		},
+	}, nil
+}
+
+// rateModel reads the parameters of the jump rate model at address as annual rates
+func (a *CompoundV2Adapter) rateModel(ctx context.Context, client chain.Client, address common.Address, periodsPerYear float64) (*irm.JumpRateModel, error) {
+	batch := chain.NewBatch(client)
+	kinkCall := batch.Add(address, jumpRateModelABI, "kink")
+	names := []string{"baseRate", "multiplier", "jumpMultiplier"}
+	calls := make([]*chain.BatchCall, len(names))
+	for i, name := range names {
+		calls[i] = batch.Add(address, jumpRateModelABI, name+a.period)
+	}
+	if err := batch.Do(ctx); err != nil {
+		return nil, err
+	}
+
+	kink, err := kinkCall.Uint()
+	if err != nil {
+		return nil, err
+	}
+	annual := make([]float64, len(names))
+	for i := range names {
+		rate, err := calls[i].Uint()
+		if err != nil {
+			return nil, err
+		}
+		annual[i] = rates.Annualize(rates.Wad(rate), periodsPerYear, rates.Simple)
+	}
+	return &irm.JumpRateModel{
+		Kink:           rates.Wad(kink),
+		BaseRate:       annual[0],
+		Multiplier:     annual[1],
+		JumpMultiplier: annual[2],
+	}, nil
+}
+
+// Incentives reads the supplier emissions of the Moonwell MultiRewardDistributor. Venus
+// rewards are paid through its Prime program and are not read.
+func (a *CompoundV2Adapter) Incentives(ctx context.Context, chainID uint64) (*Incentives, error) {
+	if a.protocol != ProtocolMoonwell {
+		return nil, fmt.Errorf("%w: %s", ErrNoIncentives, a.protocol)
+	}
+	market, ok := a.markets[chainID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s on %d", ErrUnsupportedChain, a.protocol, chainID)
+	}
+	client, err := a.chains.ClientFor(chainID, a.protocol)
+	if err != nil {
+		return nil, err
+	}
+
+	comptroller, err := chain.CallView(ctx, client, market.CToken, cTokenABI, "comptroller")
+	if err != nil {
+		return nil, err
+	}
+	distributor, err := chain.CallView(ctx, client, comptroller[0].(common.Address), moonwellRewardsABI, "rewardDistributor")
+	if err != nil {
+		return nil, err
+	}
+	out, err := chain.CallView(ctx, client, distributor[0].(common.Address), moonwellRewardsABI, "getAllMarketConfigs", market.CToken)
+	if err != nil {
+		return nil, err
+	}
+	var configs []moonwellMarketConfig
+	abi.ConvertType(out[0], &configs)
+
+	var emissions []Emission
+	for _, config := range configs {
+		decimals, err := tokenDecimals(ctx, client, config.EmissionToken)
+		if err != nil {
+			return nil, err
+		}
+		emissions = append(emissions, Emission{
+			Token:     config.EmissionToken,
+			Decimals:  decimals,
+			PerSecond: config.SupplyEmissionsPerSec,
+			EndsAt:    config.EndTime.Uint64(),
+		})
+	}
+	return incentivesAt(ctx, client, emissions)
+}
@@ -0,0 +1,136 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+)
+
+// ErrNoEmergencyData is returned for protocols whose adapter cannot read emergency events
+var ErrNoEmergencyData = errors.New("protocol does not report emergency events")
+
+// EmergencyEvent is an event that halts a market or changes its code, such as a pause, a
+// freeze or a proxy upgrade
+type EmergencyEvent struct {
+	Name        string
+	Contract    common.Address
+	BlockNumber uint64
+	TxHash      common.Hash
+	LogIndex    uint
+}
+
+// EmergencyLog is the emergency events of a market in chain order, emitted from FromBlock
+// to ToBlock inclusive
+type EmergencyLog struct {
+	FromBlock uint64
+	ToBlock   uint64
+	Events    []EmergencyEvent
+}
+
+// EmergencyReader reads the emergency events of a protocol's USDC markets
+type EmergencyReader interface {
+	YieldAdapter
+
+	// EmergencyEvents returns the emergency events of the USDC market on chainID in the
+	// latest lookback blocks
+	EmergencyEvents(ctx context.Context, chainID uint64, lookback uint64) (*EmergencyLog, error)
+}
+
+// EmergencyReaderFor returns the EmergencyReader of the adapter registered for protocol
+func (r *Registry) EmergencyReaderFor(protocol string) (EmergencyReader, error) {
+	adapter, err := r.Get(protocol)
+	if err != nil {
+		return nil, err
+	}
+	reader, ok := adapter.(EmergencyReader)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoEmergencyData, protocol)
+	}
+	return reader, nil
+}
+
+// proxyEventsABI are the events of EIP-1967 proxies changing their implementation or admin
+var proxyEventsABI = chain.MustParseABI(`[
+	{"name":"Upgraded","type":"event","anonymous":false,"inputs":[{"name":"implementation","type":"address","indexed":true}]},
+	{"name":"AdminChanged","type":"event","anonymous":false,"inputs":[{"name":"previousAdmin","type":"address","indexed":false},{"name":"newAdmin","type":"address","indexed":false}]}
+]`)
+
+// eventFilter selects events of contractABI emitted by contract. With asset set, only
+// events whose first indexed argument is asset are kept.
+type eventFilter struct {
+	contract    common.Address
+	contractABI abi.ABI
+	events      []string
+	asset       *common.Address
+}
+
+// filterEvents runs filters over the lookback blocks up to the head, or the block ctx is
+// pinned to
+func filterEvents(ctx context.Context, client chain.Client, lookback uint64, filters ...eventFilter) (*EmergencyLog, error) {
+	head, err := chain.Head(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	result := &EmergencyLog{ToBlock: head}
+	if lookback > 0 && head+1 > lookback {
+		result.FromBlock = head + 1 - lookback
+	}
+	fromBlock, toBlock := result.FromBlock, result.ToBlock
+
+	events := []EmergencyEvent{}
+	for _, f := range filters {
+		names := make(map[common.Hash]string, len(f.events))
+		topics := make([]common.Hash, 0, len(f.events))
+		for _, name := range f.events {
+			event, ok := f.contractABI.Events[name]
+			if !ok {
+				return nil, fmt.Errorf("event %s is not part of the abi", name)
+			}
+			names[event.ID] = name
+			topics = append(topics, event.ID)
+		}
+		logs, err := client.FilterLogs(ctx, ethereum.FilterQuery{
+			FromBlock: new(big.Int).SetUint64(fromBlock),
+			ToBlock:   new(big.Int).SetUint64(toBlock),
+			Addresses: []common.Address{f.contract},
+			Topics:    [][]common.Hash{topics},
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, log := range logs {
+			if log.Removed || len(log.Topics) == 0 {
+				continue
+			}
+			name, ok := names[log.Topics[0]]
+			if !ok {
+				continue
+			}
+			if f.asset != nil && (len(log.Topics) < 2 || common.BytesToAddress(log.Topics[1].Bytes()) != *f.asset) {
+				continue
+			}
+			events = append(events, EmergencyEvent{
+				Name:        name,
+				Contract:    log.Address,
+				BlockNumber: log.BlockNumber,
+				TxHash:      log.TxHash,
+				LogIndex:    log.Index,
+			})
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].BlockNumber != events[j].BlockNumber {
+			return events[i].BlockNumber < events[j].BlockNumber
+		}
+		return events[i].LogIndex < events[j].LogIndex
+	})
+	result.Events = events
+	return result, nil
+}
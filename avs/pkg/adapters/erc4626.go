@@ -0,0 +1,82 @@
+package adapters
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/rates"
+)
+
+const erc4626ABIJson = `[
+	{"name":"asset","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"address"}]},
+	{"name":"decimals","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint8"}]},
+	{"name":"totalAssets","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+	{"name":"balanceOf","type":"function","stateMutability":"view","inputs":[{"name":"account","type":"address"}],"outputs":[{"name":"","type":"uint256"}]},
+	{"name":"convertToAssets","type":"function","stateMutability":"view","inputs":[{"name":"shares","type":"uint256"}],"outputs":[{"name":"","type":"uint256"}]},
+	{"name":"deposit","type":"function","stateMutability":"nonpayable",
+	 "inputs":[{"name":"assets","type":"uint256"},{"name":"receiver","type":"address"}],
+	 "outputs":[{"name":"","type":"uint256"}]},
+	{"name":"withdraw","type":"function","stateMutability":"nonpayable",
+	 "inputs":[{"name":"assets","type":"uint256"},{"name":"receiver","type":"address"},{"name":"owner","type":"address"}],
+	 "outputs":[{"name":"","type":"uint256"}]}
+]`
+
+var erc4626ABI = chain.MustParseABI(erc4626ABIJson)
+
+// usdcDecimals are the decimals of native USDC on every supported chain
+const usdcDecimals = 6
+
+// checkVaultAsset verifies that vault holds the native USDC of chainID, so a misconfigured
+// vault is not reported as a USDC market
+func checkVaultAsset(ctx context.Context, client chain.Client, chainID uint64, vault common.Address) error {
+	usdc, err := USDCAddress(chainID)
+	if err != nil {
+		return err
+	}
+	asset, err := chain.CallView(ctx, client, vault, erc4626ABI, "asset")
+	if err != nil {
+		return err
+	}
+	if asset[0].(common.Address) != usdc {
+		return fmt.Errorf("vault %s holds %s, not USDC", vault.Hex(), asset[0].(common.Address).Hex())
+	}
+	return nil
+}
+
+// vaultSupplyCall builds ERC4626.deposit of amount USDC, with the shares minted to
+// onBehalfOf
+func vaultSupplyCall(vault, onBehalfOf common.Address, amount *big.Int) (*chain.Call, error) {
+	return packCall(vault, erc4626ABI, "deposit", amount, onBehalfOf)
+}
+
+// vaultWithdrawCall builds ERC4626.withdraw of amount USDC to to, burning the shares of
+// to, which is the sender
+func vaultWithdrawCall(vault, to common.Address, amount *big.Int) (*chain.Call, error) {
+	return packCall(vault, erc4626ABI, "withdraw", amount, to, to)
+}
+
+// vaultPosition returns the USDC the shares of account in vault are worth
+func vaultPosition(ctx context.Context, client chain.Client, vault, account common.Address) (*big.Int, error) {
+	shares, err := chain.CallUint(ctx, client, vault, erc4626ABI, "balanceOf", account)
+	if err != nil {
+		return nil, err
+	}
+	return chain.CallUint(ctx, client, vault, erc4626ABI, "convertToAssets", shares)
+}
+
+// vaultSharePrice returns the USDC one whole share of vault is worth
+func vaultSharePrice(ctx context.Context, client chain.Client, vault common.Address) (float64, error) {
+	decimals, err := chain.CallView(ctx, client, vault, erc4626ABI, "decimals")
+	if err != nil {
+		return 0, err
+	}
+	share := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals[0].(uint8))), nil)
+	assets, err := chain.CallUint(ctx, client, vault, erc4626ABI, "convertToAssets", share)
+	if err != nil {
+		return 0, err
+	}
+	return rates.Scaled(assets, usdcDecimals), nil
+}
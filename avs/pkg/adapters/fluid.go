@@ -0,0 +1,17 @@
+package adapters
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+)
+
+// ProtocolFluid is the task parameter name of Fluid lending
+const ProtocolFluid = "fluid"
+
+// NewFluidAdapter creates an adapter for the given per-chain Fluid fUSDC tokens. fTokens
+// are ERC-4626 vaults whose exchange price accrues lending interest and Fluid rewards
+// alike, so their rate is measured from share price history as configured in cfg.
+func NewFluidAdapter(cfg VaultConfig, chains *chain.Manager, history *store.SeriesStore, markets map[uint64]common.Address) *ERC4626Adapter {
+	return newERC4626Adapter(ProtocolFluid, markets, cfg, chains, history)
+}
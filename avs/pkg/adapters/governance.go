@@ -0,0 +1,190 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+)
+
+// ErrNoGovernanceData is returned for protocols whose adapter cannot read who controls
+// their markets
+var ErrNoGovernanceData = errors.New("protocol does not report governance")
+
+// ControllerKind is what an account controlling a contract turned out to be
+type ControllerKind string
+
+const (
+	// ControllerEOA is a single private key
+	ControllerEOA ControllerKind = "eoa"
+	// ControllerSafe is a Safe multisig
+	ControllerSafe ControllerKind = "safe"
+	// ControllerTimelock delays the calls of its admin
+	ControllerTimelock ControllerKind = "timelock"
+	// ControllerContract is a contract of any other kind, such as an on-chain governor
+	ControllerContract ControllerKind = "contract"
+)
+
+// maxControllerDepth bounds how many owners are followed from a contract
+const maxControllerDepth = 4
+
+// eip1967AdminSlot is bytes32(uint256(keccak256("eip1967.proxy.admin")) - 1)
+var eip1967AdminSlot = common.HexToHash("0xb53127684a568b3173ae13b9f8a6016e243e63b6e8ee1178d6a717850b5d6103")
+
+// Controller is an account with privileges over a market and, when it is itself
+// controlled, the account controlling it
+type Controller struct {
+	Address common.Address
+	Kind    ControllerKind
+
+	// Threshold and Owners are the confirmations a Safe needs and its number of owners
+	Threshold uint64
+	Owners    uint64
+
+	// Delay is the minimum delay in seconds of a timelock
+	Delay uint64
+
+	// Admin owns or administers this controller, nil when it has no owner or the owner
+	// could not be identified
+	Admin *Controller
+}
+
+// GovernanceState is who can change the USDC market of a protocol on one chain
+type GovernanceState struct {
+	// ProxyAdmin can upgrade the market contract, nil when it is not upgradeable
+	ProxyAdmin *Controller
+
+	// Governor can change the parameters of the market
+	Governor *Controller
+
+	// PauseGuardian can halt the market without delay, nil when the protocol does not
+	// expose it
+	PauseGuardian *Controller
+}
+
+// GovernanceReader reads who controls a protocol's USDC markets
+type GovernanceReader interface {
+	YieldAdapter
+
+	// Governance inspects the admin keys of the USDC market on chainID
+	Governance(ctx context.Context, chainID uint64) (*GovernanceState, error)
+}
+
+// GovernanceReaderFor returns the GovernanceReader of the adapter registered for protocol
+func (r *Registry) GovernanceReaderFor(protocol string) (GovernanceReader, error) {
+	adapter, err := r.Get(protocol)
+	if err != nil {
+		return nil, err
+	}
+	reader, ok := adapter.(GovernanceReader)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoGovernanceData, protocol)
+	}
+	return reader, nil
+}
+
+// Getters of Safe, OpenZeppelin and Compound timelocks and Ownable contracts controllers
+// are told apart by
+var controllerABI = chain.MustParseABI(`[
+	{"name":"getThreshold","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+	{"name":"getOwners","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"address[]"}]},
+	{"name":"getMinDelay","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+	{"name":"delay","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+	{"name":"admin","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"address"}]},
+	{"name":"owner","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"address"}]}
+]`)
+
+// proxyAdmin inspects the admin of an EIP-1967 proxy, nil when proxy keeps no admin in
+// the standard slot
+func proxyAdmin(ctx context.Context, client chain.Client, proxy common.Address) (*Controller, error) {
+	slot, err := chain.StorageAt(ctx, client, proxy, eip1967AdminSlot)
+	if err != nil {
+		return nil, err
+	}
+	admin := common.BytesToAddress(slot)
+	if admin == (common.Address{}) {
+		return nil, nil
+	}
+	return inspectController(ctx, client, admin)
+}
+
+// inspectController identifies account and follows the owners of contracts that have one
+func inspectController(ctx context.Context, client chain.Client, account common.Address) (*Controller, error) {
+	return inspectAt(ctx, client, account, make(map[common.Address]bool))
+}
+
+func inspectAt(ctx context.Context, client chain.Client, account common.Address, seen map[common.Address]bool) (*Controller, error) {
+	c := &Controller{Address: account, Kind: ControllerContract}
+	code, err := chain.CodeAt(ctx, client, account)
+	if err != nil {
+		return nil, err
+	}
+	if len(code) == 0 {
+		c.Kind = ControllerEOA
+		return c, nil
+	}
+	// governors and timelocks commonly administer each other
+	if seen[account] || len(seen) >= maxControllerDepth {
+		return c, nil
+	}
+	seen[account] = true
+
+	threshold, err := optionalUint(ctx, client, account, "getThreshold")
+	if err != nil {
+		return nil, err
+	}
+	if threshold != nil {
+		owners, err := chain.CallView(ctx, client, account, controllerABI, "getOwners")
+		if err != nil {
+			return nil, err
+		}
+		c.Kind = ControllerSafe
+		c.Threshold = threshold.Uint64()
+		c.Owners = uint64(len(owners[0].([]common.Address)))
+		return c, nil
+	}
+
+	for _, method := range []string{"getMinDelay", "delay"} {
+		delay, err := optionalUint(ctx, client, account, method)
+		if err != nil {
+			return nil, err
+		}
+		if delay != nil {
+			c.Kind = ControllerTimelock
+			c.Delay = delay.Uint64()
+			break
+		}
+	}
+
+	// Compound timelocks name their controller admin, ownable contracts owner.
+	// OpenZeppelin timelocks grant roles instead, which cannot be listed.
+	method := "owner"
+	if c.Kind == ControllerTimelock {
+		method = "admin"
+	}
+	owner, err := chain.CallView(ctx, client, account, controllerABI, method)
+	if unsupported(ctx, err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if admin := owner[0].(common.Address); admin != (common.Address{}) {
+		if c.Admin, err = inspectAt(ctx, client, admin, seen); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// optionalUint calls a uint getter, returning nil when contract does not implement it
+func optionalUint(ctx context.Context, client chain.Client, contract common.Address, method string) (*big.Int, error) {
+	value, err := chain.CallUint(ctx, client, contract, controllerABI, method)
+	if unsupported(ctx, err) {
+		return nil, nil
+	}
+	return value, err
+}
@@ -0,0 +1,79 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+)
+
+// ErrNoIncentives is returned for protocols whose adapter does not read reward emissions
+var ErrNoIncentives = errors.New("protocol does not report incentives")
+
+// Emission is a reward token stream paid out to the suppliers of a market
+type Emission struct {
+	Token    common.Address
+	Decimals uint8
+
+	// PerSecond is the reward token base units emitted to all suppliers per second
+	PerSecond *big.Int
+
+	// EndsAt is the unix time emissions stop, 0 when they do not end
+	EndsAt uint64
+}
+
+// Incentives are the reward emissions running for the suppliers of a market
+type Incentives struct {
+	// BlockTime is the time of the block emissions were read at
+	BlockTime uint64
+	Emissions []Emission
+}
+
+// IncentiveReader is implemented by adapters of protocols that pay suppliers rewards on
+// top of the supply rate
+type IncentiveReader interface {
+	// Incentives reads the emissions to suppliers of the USDC market on chainID. Ended
+	// and zero emissions are left out.
+	Incentives(ctx context.Context, chainID uint64) (*Incentives, error)
+}
+
+// IncentiveReaderFor returns the IncentiveReader of the adapter registered for protocol
+func (r *Registry) IncentiveReaderFor(protocol string) (IncentiveReader, error) {
+	adapter, err := r.Get(protocol)
+	if err != nil {
+		return nil, err
+	}
+	reader, ok := adapter.(IncentiveReader)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoIncentives, protocol)
+	}
+	return reader, nil
+}
+
+// incentivesAt collects the running emissions of candidates at the latest block
+func incentivesAt(ctx context.Context, client chain.Client, candidates []Emission) (*Incentives, error) {
+	now, err := blockTime(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	out := &Incentives{BlockTime: now, Emissions: []Emission{}}
+	for _, emission := range candidates {
+		if emission.PerSecond.Sign() <= 0 || (emission.EndsAt != 0 && emission.EndsAt <= now) {
+			continue
+		}
+		out.Emissions = append(out.Emissions, emission)
+	}
+	return out, nil
+}
+
+// tokenDecimals reads the decimals of an ERC-20 token
+func tokenDecimals(ctx context.Context, client chain.Client, token common.Address) (uint8, error) {
+	out, err := chain.CallView(ctx, client, token, erc20ABI, "decimals")
+	if err != nil {
+		return 0, err
+	}
+	return out[0].(uint8), nil
+}
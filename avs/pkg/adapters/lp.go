@@ -0,0 +1,346 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/irm"
+	"github.com/najnomics/crosscow-avs/pkg/rates"
+	"github.com/najnomics/crosscow-avs/pkg/resilience"
+	"github.com/najnomics/crosscow-avs/pkg/subgraph"
+)
+
+// Liquidity pool kinds
+const (
+	PoolKindCurve     = "curve"
+	PoolKindUniswapV3 = "uniswap_v3"
+	PoolKindUniswapV4 = "uniswap_v4"
+)
+
+// ErrNoTradingHistory is returned for pools whose subgraph has no trading days in the
+// window
+var ErrNoTradingHistory = errors.New("no pool trading history")
+
+// maxCurveCoins is the most coins a Curve stable pool holds
+const maxCurveCoins = 8
+
+const curvePoolABIJson = `[
+	{"name":"coins","type":"function","stateMutability":"view","inputs":[{"name":"i","type":"uint256"}],"outputs":[{"name":"","type":"address"}]},
+	{"name":"get_virtual_price","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+	{"name":"calc_withdraw_one_coin","type":"function","stateMutability":"view",
+	 "inputs":[{"name":"token_amount","type":"uint256"},{"name":"i","type":"int128"}],
+	 "outputs":[{"name":"","type":"uint256"}]}
+]`
+
+var curvePoolABI = chain.MustParseABI(curvePoolABIJson)
+
+// LPConfig configures USDC liquidity pools, whose yield is estimated from the swap fees
+// and incentives paid to liquidity providers. Pools are only ranked against lending
+// venues when a task asks for them, as providing liquidity carries the risk of one of
+// the stablecoins losing its peg.
+type LPConfig struct {
+	// Window is the trading history fees and incentives are annualized over
+	Window time.Duration `yaml:"window"`
+
+	// MaxPegDeviation is the deviation from the peg, as a fraction, at which the
+	// estimated rate of a pool is discounted to zero
+	MaxPegDeviation float64 `yaml:"maxPegDeviation"`
+
+	// Pools are the allowed pools. Each is reported as its own protocol.
+	Pools []LPPool `yaml:"pools"`
+}
+
+// LPPool names a USDC liquidity pool and its deployments
+type LPPool struct {
+	// Protocol is the name tasks refer to the pool by
+	Protocol string `yaml:"protocol"`
+
+	// Kind is curve, uniswap_v3 or uniswap_v4
+	Kind string `yaml:"kind"`
+
+	Deployments map[uint64]LPDeployment `yaml:"deployments"`
+}
+
+// LPDeployment locates a pool on one chain and the subgraph indexing it
+type LPDeployment struct {
+	// Pool is the pool contract, or the pool id for Uniswap v4
+	Pool string `yaml:"pool"`
+
+	Subgraph string        `yaml:"subgraph"`
+	ApiKey   string        `yaml:"apiKey"`
+	Timeout  time.Duration `yaml:"timeout"`
+
+	// IncentiveRate is an annual rate of incentives the subgraph does not index, such as
+	// gauge or Merkl rewards, as a fraction
+	IncentiveRate float64 `yaml:"incentiveRate"`
+}
+
+// DefaultLPConfig annualizes a week of trading and discounts pools to zero at a 2%
+// deviation from the peg. No pools are allowed by default.
+func DefaultLPConfig() LPConfig {
+	return LPConfig{
+		Window:          7 * 24 * time.Hour,
+		MaxPegDeviation: 0.02,
+	}
+}
+
+// Validate checks the config for values LP adapters cannot run with
+func (c *LPConfig) Validate() error {
+	if c.Window < 24*time.Hour {
+		return fmt.Errorf("window must be at least a day")
+	}
+	if c.MaxPegDeviation <= 0 || c.MaxPegDeviation >= 1 {
+		return fmt.Errorf("maxPegDeviation must be between 0 and 1")
+	}
+	seen := make(map[string]bool, len(c.Pools))
+	for i, pool := range c.Pools {
+		name := normalizeProtocol(pool.Protocol)
+		if name == "" {
+			return fmt.Errorf("pools[%d].protocol is required", i)
+		}
+		if seen[name] {
+			return fmt.Errorf("pool %s is configured more than once", name)
+		}
+		seen[name] = true
+		switch pool.Kind {
+		case PoolKindCurve, PoolKindUniswapV3, PoolKindUniswapV4:
+		default:
+			return fmt.Errorf("pools[%d].kind must be %s, %s or %s", i, PoolKindCurve, PoolKindUniswapV3, PoolKindUniswapV4)
+		}
+		if len(pool.Deployments) == 0 {
+			return fmt.Errorf("pools[%d].deployments are required", i)
+		}
+		for chainID, deployment := range pool.Deployments {
+			if chainID == 0 {
+				return fmt.Errorf("pools[%d].deployments must be keyed by chain id", i)
+			}
+			if pool.Kind == PoolKindCurve && !common.IsHexAddress(deployment.Pool) {
+				return fmt.Errorf("pools[%d].deployments[%d].pool is not a pool address", i, chainID)
+			}
+			if err := poolEndpoint(pool.Kind, deployment).Validate(); err != nil {
+				return fmt.Errorf("pools[%d].deployments[%d]: %w", i, chainID, err)
+			}
+			if deployment.IncentiveRate < 0 {
+				return fmt.Errorf("pools[%d].deployments[%d].incentiveRate must not be negative", i, chainID)
+			}
+		}
+	}
+	return nil
+}
+
+// LPState is the yield estimate of a liquidity pool. Rates are annual fractions.
+type LPState struct {
+	Kind          string
+	FeeRate       float64
+	IncentiveRate float64
+	TVLUSD        float64
+	VolumeUSD     float64
+
+	// PegDeviation is the largest deviation from the peg seen, as a fraction
+	PegDeviation float64
+
+	// RiskModifier scales the fee and incentive rates down as the pool strays from
+	// the peg, from 1 at the peg to 0 at the maximum deviation
+	RiskModifier float64
+}
+
+// LiquidityPool is implemented by adapters of liquidity pools, which tasks only read
+// when asked to include them
+type LiquidityPool interface {
+	YieldAdapter
+
+	// PoolKind returns curve, uniswap_v3 or uniswap_v4
+	PoolKind() string
+}
+
+// IsLiquidityPool reports whether a reads a liquidity pool rather than a lending market
+func IsLiquidityPool(a YieldAdapter) bool {
+	_, ok := a.(LiquidityPool)
+	return ok
+}
+
+type lpDeployment struct {
+	pool          string
+	subgraph      subgraph.PoolSubgraph
+	incentiveRate float64
+}
+
+// LPAdapter estimates the yield of a USDC liquidity pool from its recent trading, as the
+// fees and incentives paid to liquidity providers relative to the value locked. The
+// estimate is discounted by how far the pool strayed from the peg, standing in for the
+// impermanent loss of a stable pool, and is no promise of future fees.
+type LPAdapter struct {
+	protocol        string
+	kind            string
+	chains          *chain.Manager
+	deployments     map[uint64]lpDeployment
+	window          time.Duration
+	maxPegDeviation float64
+	now             func() time.Time
+}
+
+// NewLPAdapters creates an adapter for every pool in cfg, querying subgraphs under policy
+func NewLPAdapters(cfg LPConfig, chains *chain.Manager, policy *resilience.Policy) []*LPAdapter {
+	out := make([]*LPAdapter, 0, len(cfg.Pools))
+	for _, pool := range cfg.Pools {
+		deployments := make(map[uint64]lpDeployment, len(pool.Deployments))
+		for chainID, d := range pool.Deployments {
+			endpoint := poolEndpoint(pool.Kind, d)
+			client := subgraph.NewClient(endpoint.Url, endpoint.ApiKey, endpoint.Timeout, policy)
+			deployments[chainID] = lpDeployment{pool: d.Pool, subgraph: subgraph.NewPoolSubgraph(endpoint, client), incentiveRate: d.IncentiveRate}
+		}
+		out = append(out, newLPAdapter(pool.Protocol, pool.Kind, deployments, cfg, chains))
+	}
+	return out
+}
+
+func newLPAdapter(protocol, kind string, deployments map[uint64]lpDeployment, cfg LPConfig, chains *chain.Manager) *LPAdapter {
+	return &LPAdapter{
+		protocol:        normalizeProtocol(protocol),
+		kind:            kind,
+		chains:          chains,
+		deployments:     deployments,
+		window:          cfg.Window,
+		maxPegDeviation: cfg.MaxPegDeviation,
+		now:             time.Now,
+	}
+}
+
+func poolEndpoint(kind string, d LPDeployment) subgraph.PoolEndpointConfig {
+	schema := subgraph.SchemaUniswap
+	if kind == PoolKindCurve {
+		schema = subgraph.SchemaMessariDEX
+	}
+	return subgraph.PoolEndpointConfig{Schema: schema, Url: d.Subgraph, ApiKey: d.ApiKey, Pool: d.Pool, Timeout: d.Timeout}
+}
+
+func (a *LPAdapter) Protocol() string {
+	return a.protocol
+}
+
+func (a *LPAdapter) ChainIDs() []uint64 {
+	return sortedChainIDs(a.deployments)
+}
+
+func (a *LPAdapter) PoolKind() string {
+	return a.kind
+}
+
+// MarketState estimates the yield of the pool over the window. The pool is reported as
+// a market supplying its value locked at the risk adjusted fee and incentive rate.
+func (a *LPAdapter) MarketState(ctx context.Context, chainID uint64) (*MarketState, error) {
+	deployment, ok := a.deployments[chainID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s on %d", ErrUnsupportedChain, a.protocol, chainID)
+	}
+	now := chain.TimeOf(ctx, a.now)
+	days, err := deployment.subgraph.PoolDays(ctx, now.Add(-a.window), now)
+	if err != nil {
+		return nil, err
+	}
+	if len(days) == 0 {
+		return nil, fmt.Errorf("%w: %s on %d in %s", ErrNoTradingHistory, a.protocol, chainID, a.window)
+	}
+
+	var fees, incentives, tvl, volume float64
+	for _, day := range days {
+		fees += day.FeesUSD
+		incentives += day.IncentivesUSD
+		tvl += day.TVLUSD
+		volume += day.VolumeUSD
+	}
+	if tvl <= 0 {
+		return nil, fmt.Errorf("%s on %d has no value locked", a.protocol, chainID)
+	}
+	state := &LPState{
+		Kind: a.kind,
+		// the daily fees and incentives over the mean value locked, annualized
+		FeeRate:       fees / tvl * 365,
+		IncentiveRate: incentives/tvl*365 + deployment.incentiveRate,
+		TVLUSD:        days[len(days)-1].TVLUSD,
+		VolumeUSD:     volume,
+	}
+	if a.kind == PoolKindCurve {
+		state.PegDeviation, err = a.curvePegDeviation(ctx, chainID, common.HexToAddress(deployment.pool))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		state.PegDeviation = pricePegDeviation(days)
+	}
+	state.RiskModifier = math.Max(0, 1-state.PegDeviation/a.maxPegDeviation)
+
+	supply, _ := new(big.Float).SetFloat64(state.TVLUSD * math.Pow10(usdcDecimals)).Int(nil)
+	return &MarketState{
+		Protocol: a.protocol,
+		ChainID:  chainID,
+		Pool: irm.Pool{
+			TotalSupply: supply,
+			TotalBorrow: new(big.Int),
+			Model:       &irm.FixedModel{Rate: (state.FeeRate + state.IncentiveRate) * state.RiskModifier},
+		},
+		LP: state,
+	}, nil
+}
+
+// curvePegDeviation compares the USDC one LP token withdraws to its virtual price, the
+// value it would have with every coin of the pool at the peg
+func (a *LPAdapter) curvePegDeviation(ctx context.Context, chainID uint64, pool common.Address) (float64, error) {
+	client, err := a.chains.ClientFor(chainID, a.protocol)
+	if err != nil {
+		return 0, err
+	}
+	usdc, err := USDCAddress(chainID)
+	if err != nil {
+		return 0, err
+	}
+	index := -1
+	for i := 0; i < maxCurveCoins && index < 0; i++ {
+		values, err := chain.CallView(ctx, client, pool, curvePoolABI, "coins", big.NewInt(int64(i)))
+		if err != nil {
+			// coins reverts past the last coin
+			break
+		}
+		if coin, ok := values[0].(common.Address); ok && coin == usdc {
+			index = i
+		}
+	}
+	if index < 0 {
+		return 0, fmt.Errorf("%s on %d does not hold native USDC", a.protocol, chainID)
+	}
+
+	virtualPrice, err := chain.CallUint(ctx, client, pool, curvePoolABI, "get_virtual_price")
+	if err != nil {
+		return 0, err
+	}
+	oneToken := new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
+	withdrawn, err := chain.CallUint(ctx, client, pool, curvePoolABI, "calc_withdraw_one_coin", oneToken, big.NewInt(int64(index)))
+	if err != nil {
+		return 0, err
+	}
+	value := rates.Wad(virtualPrice)
+	if value <= 0 {
+		return 0, fmt.Errorf("%s on %d has a virtual price of zero", a.protocol, chainID)
+	}
+	return math.Abs(1 - rates.Scaled(withdrawn, usdcDecimals)/value), nil
+}
+
+// pricePegDeviation returns the largest deviation of the daily price range of a pool of
+// two stablecoins from the peg
+func pricePegDeviation(days []subgraph.PoolDay) float64 {
+	var deviation float64
+	for _, day := range days {
+		for _, price := range []float64{day.PriceLow, day.PriceHigh} {
+			if price > 0 {
+				deviation = math.Max(deviation, math.Abs(price-1))
+			}
+		}
+	}
+	return deviation
+}
@@ -0,0 +1,120 @@
+package adapters
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/addressbook"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/rates"
+	"github.com/najnomics/crosscow-avs/pkg/tokens"
+)
+
+// Chain ids of the networks with known USDC markets, and of their testnets
+const (
+	ChainIDEthereum uint64 = 1
+	ChainIDBase     uint64 = 8453
+	ChainIDArbitrum uint64 = 42161
+
+	ChainIDSepolia         uint64 = 11155111
+	ChainIDBaseSepolia     uint64 = 84532
+	ChainIDArbitrumSepolia uint64 = 421614
+)
+
+// Native USDC token addresses. Markets of bridged variants are not supported.
+var usdcAddresses = tokens.NativeUSDCAddresses()
+
+// AaveV3Markets are the Aave v3 USDC reserves of book
+func AaveV3Markets(book *addressbook.Book) map[uint64]AaveV3Market {
+	markets := make(map[uint64]AaveV3Market)
+	for _, chainID := range book.Chains(ProtocolAaveV3, addressbook.ContractPool) {
+		markets[chainID] = AaveV3Market{
+			Pool:              book.Address(chainID, ProtocolAaveV3, addressbook.ContractPool),
+			Asset:             usdcAddresses[chainID],
+			RewardsController: book.Address(chainID, ProtocolAaveV3, addressbook.ContractRewards),
+		}
+	}
+	return markets
+}
+
+// CompoundV3Markets are the Compound v3 USDC Comets of book
+func CompoundV3Markets(book *addressbook.Book) map[uint64]CompoundV3Market {
+	markets := make(map[uint64]CompoundV3Market)
+	for _, chainID := range book.Chains(ProtocolCompoundV3, addressbook.ContractComet) {
+		markets[chainID] = CompoundV3Market{
+			Comet:   book.Address(chainID, ProtocolCompoundV3, addressbook.ContractComet),
+			Rewards: book.Address(chainID, ProtocolCompoundV3, addressbook.ContractRewards),
+		}
+	}
+	return markets
+}
+
+// SparkSavingsMarkets are the Spark Savings USDC vaults of book. The rate is read from
+// sUSDS on Ethereum, and from the SSR oracle elsewhere.
+func SparkSavingsMarkets(book *addressbook.Book) map[uint64]SparkSavingsMarket {
+	markets := make(map[uint64]SparkSavingsMarket)
+	for _, chainID := range book.Chains(ProtocolSparkSavings, addressbook.ContractVault) {
+		markets[chainID] = SparkSavingsMarket{
+			Vault:      book.Address(chainID, ProtocolSparkSavings, addressbook.ContractVault),
+			RateSource: book.Address(chainID, ProtocolSparkSavings, addressbook.ContractRateSource),
+			Oracle:     chainID != ChainIDEthereum,
+		}
+	}
+	return markets
+}
+
+// compoundV2Markets are the USDC markets of protocol, a Compound v2 fork, in book. The
+// supported forks accrue by timestamp.
+func compoundV2Markets(book *addressbook.Book, protocol string) map[uint64]CompoundV2Market {
+	markets := make(map[uint64]CompoundV2Market)
+	for _, chainID := range book.Chains(protocol, addressbook.ContractCToken) {
+		markets[chainID] = CompoundV2Market{CToken: book.Address(chainID, protocol, addressbook.ContractCToken), PeriodsPerYear: rates.SecondsPerYear}
+	}
+	return markets
+}
+
+// MoonwellMarkets are the Moonwell USDC markets of book
+func MoonwellMarkets(book *addressbook.Book) map[uint64]CompoundV2Market {
+	return compoundV2Markets(book, ProtocolMoonwell)
+}
+
+// VenusMarkets are the Venus core pool USDC markets of book
+func VenusMarkets(book *addressbook.Book) map[uint64]CompoundV2Market {
+	return compoundV2Markets(book, ProtocolVenus)
+}
+
+// FluidMarkets are the Fluid fUSDC tokens of book
+func FluidMarkets(book *addressbook.Book) map[uint64]common.Address {
+	markets := make(map[uint64]common.Address)
+	for _, chainID := range book.Chains(ProtocolFluid, addressbook.ContractVault) {
+		markets[chainID] = book.Address(chainID, ProtocolFluid, addressbook.ContractVault)
+	}
+	return markets
+}
+
+// The markets of the embedded address book
+var (
+	DefaultAaveV3Markets       = AaveV3Markets(addressbook.Default())
+	DefaultCompoundV3Markets   = CompoundV3Markets(addressbook.Default())
+	DefaultSparkSavingsMarkets = SparkSavingsMarkets(addressbook.Default())
+	DefaultMoonwellMarkets     = MoonwellMarkets(addressbook.Default())
+	DefaultVenusMarkets        = VenusMarkets(addressbook.Default())
+	DefaultFluidMarkets        = FluidMarkets(addressbook.Default())
+)
+
+// NewDefaultRegistry registers every built-in adapter with the markets of the embedded
+// address book. Fluid needs the share price history and is registered with
+// NewFluidAdapter.
+func NewDefaultRegistry(chains *chain.Manager) *Registry {
+	return NewBookRegistry(chains, addressbook.Default())
+}
+
+// NewBookRegistry registers every built-in adapter with the markets of book
+func NewBookRegistry(chains *chain.Manager, book *addressbook.Book) *Registry {
+	return NewRegistry(
+		NewAaveV3Adapter(chains, AaveV3Markets(book)),
+		NewCompoundV3Adapter(chains, CompoundV3Markets(book)),
+		NewSparkSavingsAdapter(chains, SparkSavingsMarkets(book)),
+		NewSkySavingsAdapter(chains, SkySavingsVaults(book)),
+		NewMoonwellAdapter(chains, MoonwellMarkets(book)),
+		NewVenusAdapter(chains, VenusMarkets(book)),
+	)
+}
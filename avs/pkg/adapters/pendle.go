@@ -0,0 +1,231 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/irm"
+	"github.com/najnomics/crosscow-avs/pkg/rates"
+)
+
+// ErrMarketMatured is returned for fixed rate markets past their maturity, which no
+// longer pay a rate
+var ErrMarketMatured = errors.New("market has matured")
+
+const pendleMarketABIJson = `[
+	{"name":"readTokens","type":"function","stateMutability":"view","inputs":[],
+	 "outputs":[{"name":"_SY","type":"address"},{"name":"_PT","type":"address"},{"name":"_YT","type":"address"}]},
+	{"name":"expiry","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+	{"name":"_storage","type":"function","stateMutability":"view","inputs":[],
+	 "outputs":[{"name":"totalPt","type":"int128"},{"name":"totalSy","type":"int128"},{"name":"lastLnImpliedRate","type":"uint96"},
+	  {"name":"observationIndex","type":"uint16"},{"name":"observationCardinality","type":"uint16"},{"name":"observationCardinalityNext","type":"uint16"}]}
+]`
+
+const pendleSYABIJson = `[
+	{"name":"assetInfo","type":"function","stateMutability":"view","inputs":[],
+	 "outputs":[{"name":"assetType","type":"uint8"},{"name":"assetAddress","type":"address"},{"name":"assetDecimals","type":"uint8"}]},
+	{"name":"exchangeRate","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]}
+]`
+
+var (
+	pendleMarketABI = chain.MustParseABI(pendleMarketABIJson)
+	pendleSYABI     = chain.MustParseABI(pendleSYABIJson)
+)
+
+// FixedRateMarket is implemented by adapters of markets locking a rate until maturity,
+// which tasks only read when asked to compare fixed and variable rates
+type FixedRateMarket interface {
+	YieldAdapter
+
+	// Maturity returns the unix time the market on chainID matures
+	Maturity(ctx context.Context, chainID uint64) (uint64, error)
+}
+
+// IsFixedRate reports whether a reads a fixed rate market rather than a lending market
+func IsFixedRate(a YieldAdapter) bool {
+	_, ok := a.(FixedRateMarket)
+	return ok
+}
+
+// FixedState is the rate a fixed rate market locks until maturity
+type FixedState struct {
+	// Maturity is the unix time the market matures
+	Maturity uint64
+
+	// ImpliedAPY is the annual yield of buying the principal token and holding it to
+	// maturity, as a fraction
+	ImpliedAPY float64
+}
+
+// PendleConfig configures Pendle USDC markets, whose principal tokens lock the implied
+// rate until maturity
+type PendleConfig struct {
+	// Markets are the allowed markets. Each maturity is reported as its own protocol.
+	Markets []PendleMarket `yaml:"markets"`
+}
+
+// PendleMarket names a Pendle market of a USDC principal token and its deployments
+type PendleMarket struct {
+	// Protocol is the name tasks refer to the market by, e.g. pendle_pt_ausdc_dec2026
+	Protocol string `yaml:"protocol"`
+
+	// Addresses are the market contracts by chain id
+	Addresses map[uint64]string `yaml:"addresses"`
+}
+
+// Validate checks the config for values Pendle adapters cannot run with
+func (c *PendleConfig) Validate() error {
+	seen := make(map[string]bool, len(c.Markets))
+	for i, market := range c.Markets {
+		name := normalizeProtocol(market.Protocol)
+		if name == "" {
+			return fmt.Errorf("markets[%d].protocol is required", i)
+		}
+		if seen[name] {
+			return fmt.Errorf("market %s is configured more than once", name)
+		}
+		seen[name] = true
+		if len(market.Addresses) == 0 {
+			return fmt.Errorf("markets[%d].addresses are required", i)
+		}
+		for chainID, address := range market.Addresses {
+			if chainID == 0 || !common.IsHexAddress(address) {
+				return fmt.Errorf("markets[%d].addresses[%d] is not a market address on a chain", i, chainID)
+			}
+		}
+	}
+	return nil
+}
+
+// PendleAdapter reads a Pendle market of a USDC principal token. Its rate is the implied
+// rate of the market, the continuously compounded yield of holding the principal token
+// to maturity at the price of the last trade, so a single large swap moves it.
+type PendleAdapter struct {
+	protocol string
+	chains   *chain.Manager
+	markets  map[uint64]common.Address
+}
+
+// NewPendleAdapters creates an adapter for every market in cfg
+func NewPendleAdapters(cfg PendleConfig, chains *chain.Manager) []*PendleAdapter {
+	out := make([]*PendleAdapter, 0, len(cfg.Markets))
+	for _, market := range cfg.Markets {
+		addresses := make(map[uint64]common.Address, len(market.Addresses))
+		for chainID, address := range market.Addresses {
+			addresses[chainID] = common.HexToAddress(address)
+		}
+		out = append(out, &PendleAdapter{protocol: normalizeProtocol(market.Protocol), chains: chains, markets: addresses})
+	}
+	return out
+}
+
+func (a *PendleAdapter) Protocol() string {
+	return a.protocol
+}
+
+func (a *PendleAdapter) ChainIDs() []uint64 {
+	return sortedChainIDs(a.markets)
+}
+
+// Maturity reads the expiry of the market
+func (a *PendleAdapter) Maturity(ctx context.Context, chainID uint64) (uint64, error) {
+	market, client, err := a.market(chainID)
+	if err != nil {
+		return 0, err
+	}
+	expiry, err := chain.CallUint(ctx, client, market, pendleMarketABI, "expiry")
+	if err != nil {
+		return 0, err
+	}
+	return expiry.Uint64(), nil
+}
+
+// MarketState reads the implied rate of the market and the USDC its standardized yield
+// and principal tokens are worth, the principal tokens discounted to maturity at the
+// implied rate
+func (a *PendleAdapter) MarketState(ctx context.Context, chainID uint64) (*MarketState, error) {
+	market, client, err := a.market(chainID)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := chain.CallView(ctx, client, market, pendleMarketABI, "readTokens")
+	if err != nil {
+		return nil, err
+	}
+	sy, ok := tokens[0].(common.Address)
+	if !ok {
+		return nil, fmt.Errorf("unexpected readTokens output type %T", tokens[0])
+	}
+	asset, err := chain.CallView(ctx, client, sy, pendleSYABI, "assetInfo")
+	if err != nil {
+		return nil, err
+	}
+	usdc, err := USDCAddress(chainID)
+	if err != nil {
+		return nil, err
+	}
+	if address, _ := asset[1].(common.Address); address != usdc {
+		return nil, fmt.Errorf("%s on %d is not a market of native USDC: its asset is %s", a.protocol, chainID, address.Hex())
+	}
+
+	maturity, err := a.Maturity(ctx, chainID)
+	if err != nil {
+		return nil, err
+	}
+	now, err := blockTime(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	if now >= maturity {
+		return nil, fmt.Errorf("%w: %s on %d at %d", ErrMarketMatured, a.protocol, chainID, maturity)
+	}
+
+	storage, err := chain.CallView(ctx, client, market, pendleMarketABI, "_storage")
+	if err != nil {
+		return nil, err
+	}
+	totalPt, okPt := storage[0].(*big.Int)
+	totalSy, okSy := storage[1].(*big.Int)
+	lnRate, okRate := storage[2].(*big.Int)
+	if !okPt || !okSy || !okRate {
+		return nil, fmt.Errorf("unexpected _storage output types %T, %T and %T", storage[0], storage[1], storage[2])
+	}
+	exchangeRate, err := chain.CallUint(ctx, client, sy, pendleSYABI, "exchangeRate")
+	if err != nil {
+		return nil, err
+	}
+
+	rate := rates.Wad(lnRate)
+	// principal tokens redeem one asset unit each at maturity
+	discount := math.Exp(-rate * float64(maturity-now) / rates.SecondsPerYear)
+	ptAssets, _ := new(big.Float).Mul(new(big.Float).SetInt(totalPt), big.NewFloat(discount)).Int(nil)
+	syAssets := new(big.Int).Div(new(big.Int).Mul(totalSy, exchangeRate), new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil))
+
+	return &MarketState{
+		Protocol: a.protocol,
+		ChainID:  chainID,
+		Pool: irm.Pool{
+			TotalSupply: new(big.Int).Add(syAssets, ptAssets),
+			TotalBorrow: new(big.Int),
+			Model:       &irm.FixedModel{Rate: rate},
+		},
+		Fixed: &FixedState{Maturity: maturity, ImpliedAPY: rates.ContinuousToAPY(rate)},
+	}, nil
+}
+
+func (a *PendleAdapter) market(chainID uint64) (common.Address, chain.Client, error) {
+	market, ok := a.markets[chainID]
+	if !ok {
+		return common.Address{}, nil, fmt.Errorf("%w: %s on %d", ErrUnsupportedChain, a.protocol, chainID)
+	}
+	client, err := a.chains.ClientFor(chainID, a.protocol)
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	return market, client, nil
+}
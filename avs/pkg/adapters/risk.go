@@ -0,0 +1,119 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/resilience"
+)
+
+// ErrNoRiskData is returned for protocols whose adapter cannot read risk parameters
+var ErrNoRiskData = errors.New("protocol does not report risk parameters")
+
+// RiskState is the configuration and solvency of the USDC market of a protocol on one
+// chain. Amounts are in USDC base units.
+type RiskState struct {
+	// SupplyCap and BorrowCap are nil when the market is uncapped
+	SupplyCap *big.Int
+	BorrowCap *big.Int
+
+	// BadDebt is borrowed USDC no collateral backs any more. It is nil when the
+	// deployment does not account for it.
+	BadDebt *big.Int
+
+	// Paused is set when withdrawals or deposits are halted. Frozen markets still allow
+	// withdrawals and repayments but no new deposits or borrows.
+	Paused bool
+	Frozen bool
+
+	Oracle *Oracle
+
+	// Timestamp is the time of the latest block when the state was read
+	Timestamp uint64
+}
+
+// Oracle is the price source a protocol values USDC with
+type Oracle struct {
+	Feed common.Address
+
+	// Price is the USD price the protocol uses, with Decimals decimals
+	Price    *big.Int
+	Decimals uint8
+
+	// UpdatedAt is the time of the feed's latest round, zero when the feed does not
+	// expose rounds
+	UpdatedAt uint64
+}
+
+// USD returns Price as a USD amount
+func (o *Oracle) USD() *big.Rat {
+	return new(big.Rat).SetFrac(o.Price, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(o.Decimals)), nil))
+}
+
+// RiskReader reads the risk parameters of a protocol's USDC markets
+type RiskReader interface {
+	YieldAdapter
+
+	// RiskState reads the current risk parameters of the USDC market on chainID
+	RiskState(ctx context.Context, chainID uint64) (*RiskState, error)
+}
+
+// RiskReaderFor returns the RiskReader of the adapter registered for protocol
+func (r *Registry) RiskReaderFor(protocol string) (RiskReader, error) {
+	adapter, err := r.Get(protocol)
+	if err != nil {
+		return nil, err
+	}
+	reader, ok := adapter.(RiskReader)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoRiskData, protocol)
+	}
+	return reader, nil
+}
+
+// aggregatorABI is the part of a Chainlink aggregator risk reads need
+var aggregatorABI = chain.MustParseABI(`[
+	{"name":"latestRoundData","type":"function","stateMutability":"view","inputs":[],
+	 "outputs":[
+		{"name":"roundId","type":"uint80"},
+		{"name":"answer","type":"int256"},
+		{"name":"startedAt","type":"uint256"},
+		{"name":"updatedAt","type":"uint256"},
+		{"name":"answeredInRound","type":"uint80"}
+	]}
+]`)
+
+// roundUpdatedAt reads the time of the latest round of feed. Feeds wrapped by adapters
+// without rounds report zero.
+func roundUpdatedAt(ctx context.Context, client chain.Client, feed common.Address) (uint64, error) {
+	round, err := chain.CallView(ctx, client, feed, aggregatorABI, "latestRoundData")
+	if unsupported(ctx, err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return round[3].(*big.Int).Uint64(), nil
+}
+
+// blockTime reads the time of the latest block, or the block ctx is pinned to, which
+// staleness is judged against
+func blockTime(ctx context.Context, client chain.Client) (uint64, error) {
+	head, err := chain.HeadHeader(ctx, client)
+	if err != nil {
+		return 0, err
+	}
+	return head.Time, nil
+}
+
+// unsupported reports whether err is a revert of a call the contract does not implement,
+// as opposed to a failed request or a node without the state of a pinned block
+func unsupported(ctx context.Context, err error) bool {
+	return err != nil && ctx.Err() == nil && !errors.Is(err, resilience.ErrCircuitOpen) &&
+		!errors.Is(err, chain.ErrHistoricalState) &&
+		resilience.Classify(ctx, err) == resilience.ClassFatal
+}
@@ -0,0 +1,395 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/addressbook"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/irm"
+	"github.com/najnomics/crosscow-avs/pkg/rates"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+)
+
+// ProtocolSkySavings is the task parameter name of Sky Savings USDS
+const ProtocolSkySavings = "sky_susds"
+
+// ErrNotRWA is returned for protocols whose yield is not backed by real world assets
+var ErrNotRWA = errors.New("protocol is not backed by real world assets")
+
+// Transferability of RWA tokens
+const (
+	// TransferPermissionless tokens move between any accounts
+	TransferPermissionless = "permissionless"
+
+	// TransferAllowlisted tokens only move between accounts the issuer allowlisted
+	TransferAllowlisted = "allowlisted"
+
+	// TransferRestricted tokens cannot be moved freely at all, for instance during a
+	// lockup after minting
+	TransferRestricted = "restricted"
+)
+
+// RWAProfile describes the real world assets backing a yield source and who may hold
+// it, which institutions weigh alongside the rate
+type RWAProfile struct {
+	Issuer string
+
+	// Jurisdiction is where the issuer or fund is domiciled, empty for protocols
+	// governed on chain
+	Jurisdiction string
+
+	// Backing describes the assets the yield comes from, e.g. US Treasury bills
+	Backing string
+
+	// Transferability is permissionless, allowlisted or restricted
+	Transferability string
+
+	// KYC is set when holders must be verified by the issuer
+	KYC bool
+}
+
+// RWASource is implemented by adapters of yield sources backed by real world assets
+type RWASource interface {
+	YieldAdapter
+
+	// RWAProfile returns the backing and holding restrictions of the source
+	RWAProfile() RWAProfile
+}
+
+// RWASourceFor returns the RWASource of the adapter registered for protocol
+func (r *Registry) RWASourceFor(protocol string) (RWASource, error) {
+	adapter, err := r.Get(protocol)
+	if err != nil {
+		return nil, err
+	}
+	source, ok := adapter.(RWASource)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotRWA, protocol)
+	}
+	return source, nil
+}
+
+// sUSDS is an ERC-4626 vault of USDS exposing the Sky Savings Rate
+var susdsABI = chain.MustParseABI(`[
+	{"name":"totalAssets","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+	{"name":"ssr","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]}
+]`)
+
+// usdsDecimals are the decimals of USDS and sUSDS
+const usdsDecimals = 18
+
+// SkySavingsAdapter reads sUSDS, the USDS savings vault of Sky. USDC reaches it through
+// the Sky peg stability module one for one, so its USDS are reported as USDC. Sky backs
+// USDS in part with tokenized Treasury bills, which its profile reflects.
+type SkySavingsAdapter struct {
+	chains *chain.Manager
+	vaults map[uint64]common.Address
+}
+
+// NewSkySavingsAdapter creates an adapter for the given per-chain sUSDS vaults, which
+// expose the savings rate on Ethereum only
+func NewSkySavingsAdapter(chains *chain.Manager, vaults map[uint64]common.Address) *SkySavingsAdapter {
+	return &SkySavingsAdapter{chains: chains, vaults: vaults}
+}
+
+// SkySavingsVaults are the sUSDS vaults of book
+func SkySavingsVaults(book *addressbook.Book) map[uint64]common.Address {
+	vaults := make(map[uint64]common.Address)
+	for _, chainID := range book.Chains(ProtocolSkySavings, addressbook.ContractVault) {
+		vaults[chainID] = book.Address(chainID, ProtocolSkySavings, addressbook.ContractVault)
+	}
+	return vaults
+}
+
+func (a *SkySavingsAdapter) Protocol() string {
+	return ProtocolSkySavings
+}
+
+func (a *SkySavingsAdapter) ChainIDs() []uint64 {
+	return sortedChainIDs(a.vaults)
+}
+
+func (a *SkySavingsAdapter) RWAProfile() RWAProfile {
+	return RWAProfile{
+		Issuer:          "Sky",
+		Backing:         "Sky collateral, in part tokenized US Treasury bills",
+		Transferability: TransferPermissionless,
+	}
+}
+
+// MarketState reads the USDS held by the vault and the savings rate, as an annual rate
+func (a *SkySavingsAdapter) MarketState(ctx context.Context, chainID uint64) (*MarketState, error) {
+	vault, ok := a.vaults[chainID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s on %d", ErrUnsupportedChain, ProtocolSkySavings, chainID)
+	}
+	client, err := a.chains.ClientFor(chainID, ProtocolSkySavings)
+	if err != nil {
+		return nil, err
+	}
+	batch := chain.NewBatch(client)
+	assetsCall := batch.Add(vault, susdsABI, "totalAssets")
+	ssrCall := batch.Add(vault, susdsABI, "ssr")
+	if err := batch.Do(ctx); err != nil {
+		return nil, err
+	}
+	assets, err := assetsCall.Uint()
+	if err != nil {
+		return nil, err
+	}
+	ssr, err := ssrCall.Uint()
+	if err != nil {
+		return nil, err
+	}
+
+	return &MarketState{
+		Protocol: ProtocolSkySavings,
+		ChainID:  chainID,
+		Pool: irm.Pool{
+			TotalSupply: new(big.Int).Div(assets, new(big.Int).Exp(big.NewInt(10), big.NewInt(usdsDecimals-usdcDecimals), nil)),
+			TotalBorrow: new(big.Int),
+			Model:       &irm.FixedModel{Rate: rates.Annualize(rates.RayFactor(ssr), rates.SecondsPerYear, rates.Simple)},
+		},
+	}, nil
+}
+
+// navFeedABI is the part of a Chainlink style net asset value feed RWA adapters need
+var navFeedABI = chain.MustParseABI(`[
+	{"name":"decimals","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint8"}]},
+	{"name":"latestRoundData","type":"function","stateMutability":"view","inputs":[],
+	 "outputs":[
+		{"name":"roundId","type":"uint80"},
+		{"name":"answer","type":"int256"},
+		{"name":"startedAt","type":"uint256"},
+		{"name":"updatedAt","type":"uint256"},
+		{"name":"answeredInRound","type":"uint80"}
+	]}
+]`)
+
+// RWAConfig configures tokenized Treasury bill funds, whose rate is measured from the
+// appreciation of the net asset value per token their issuer publishes on chain
+type RWAConfig struct {
+	// MaxPriceAge is the oldest net asset value a rate is reported from. Issuers
+	// publish it once a business day.
+	MaxPriceAge time.Duration `yaml:"maxPriceAge"`
+
+	// Tokens are the allowed funds. Each is reported as its own protocol.
+	Tokens []RWAToken `yaml:"tokens"`
+}
+
+// RWAToken names an accumulating fund token and its deployments. Funds distributing
+// their yield at a constant price of one dollar have no rate to read on chain and are
+// not supported.
+type RWAToken struct {
+	// Protocol is the name tasks refer to the fund by, e.g. ondo_usdy
+	Protocol string `yaml:"protocol"`
+
+	Issuer          string `yaml:"issuer"`
+	Jurisdiction    string `yaml:"jurisdiction"`
+	Backing         string `yaml:"backing"`
+	Transferability string `yaml:"transferability"`
+	KYC             bool   `yaml:"kyc"`
+
+	Deployments map[uint64]RWADeployment `yaml:"deployments"`
+}
+
+// RWADeployment locates a fund token on one chain and the feed of its net asset value
+// per token in USD
+type RWADeployment struct {
+	Token string `yaml:"token"`
+	Feed  string `yaml:"feed"`
+}
+
+// DefaultRWAConfig accepts net asset values up to four days old, covering a long
+// weekend. No funds are allowed by default.
+func DefaultRWAConfig() RWAConfig {
+	return RWAConfig{MaxPriceAge: 4 * 24 * time.Hour}
+}
+
+// Validate checks the config for values RWA adapters cannot run with
+func (c *RWAConfig) Validate() error {
+	if c.MaxPriceAge <= 0 {
+		return fmt.Errorf("maxPriceAge must be positive")
+	}
+	seen := make(map[string]bool, len(c.Tokens))
+	for i, token := range c.Tokens {
+		name := normalizeProtocol(token.Protocol)
+		if name == "" {
+			return fmt.Errorf("tokens[%d].protocol is required", i)
+		}
+		if seen[name] {
+			return fmt.Errorf("token %s is configured more than once", name)
+		}
+		seen[name] = true
+		if token.Issuer == "" {
+			return fmt.Errorf("tokens[%d].issuer is required", i)
+		}
+		switch token.Transferability {
+		case TransferPermissionless, TransferAllowlisted, TransferRestricted:
+		default:
+			return fmt.Errorf("tokens[%d].transferability must be %s, %s or %s", i, TransferPermissionless, TransferAllowlisted, TransferRestricted)
+		}
+		if len(token.Deployments) == 0 {
+			return fmt.Errorf("tokens[%d].deployments are required", i)
+		}
+		for chainID, d := range token.Deployments {
+			if chainID == 0 || !common.IsHexAddress(d.Token) || !common.IsHexAddress(d.Feed) {
+				return fmt.Errorf("tokens[%d].deployments[%d] needs token and feed addresses on a chain", i, chainID)
+			}
+		}
+	}
+	return nil
+}
+
+type rwaDeployment struct {
+	token common.Address
+	feed  common.Address
+}
+
+// RWAAdapter reads a tokenized Treasury bill fund. Its rate is the appreciation of the
+// net asset value per token over the lookback, read from the share price series the
+// collector records, as for ERC-4626 vaults. The fund is reported as a market supplying
+// the value of every token issued.
+type RWAAdapter struct {
+	protocol    string
+	profile     RWAProfile
+	chains      *chain.Manager
+	history     *store.SeriesStore
+	deployments map[uint64]rwaDeployment
+	lookback    time.Duration
+	minWindow   time.Duration
+	maxPriceAge time.Duration
+	now         func() time.Time
+}
+
+// NewRWAAdapters creates an adapter for every fund in cfg, measuring rates over the
+// windows of vaults
+func NewRWAAdapters(cfg RWAConfig, vaults VaultConfig, chains *chain.Manager, history *store.SeriesStore) []*RWAAdapter {
+	out := make([]*RWAAdapter, 0, len(cfg.Tokens))
+	for _, token := range cfg.Tokens {
+		deployments := make(map[uint64]rwaDeployment, len(token.Deployments))
+		for chainID, d := range token.Deployments {
+			deployments[chainID] = rwaDeployment{token: common.HexToAddress(d.Token), feed: common.HexToAddress(d.Feed)}
+		}
+		out = append(out, &RWAAdapter{
+			protocol: normalizeProtocol(token.Protocol),
+			profile: RWAProfile{
+				Issuer:          token.Issuer,
+				Jurisdiction:    token.Jurisdiction,
+				Backing:         token.Backing,
+				Transferability: token.Transferability,
+				KYC:             token.KYC,
+			},
+			chains:      chains,
+			history:     history,
+			deployments: deployments,
+			lookback:    vaults.Lookback,
+			minWindow:   vaults.MinWindow,
+			maxPriceAge: cfg.MaxPriceAge,
+			now:         time.Now,
+		})
+	}
+	return out
+}
+
+func (a *RWAAdapter) Protocol() string {
+	return a.protocol
+}
+
+func (a *RWAAdapter) ChainIDs() []uint64 {
+	return sortedChainIDs(a.deployments)
+}
+
+func (a *RWAAdapter) RWAProfile() RWAProfile {
+	return a.profile
+}
+
+// SharePrice returns the net asset value of one whole token in USD
+func (a *RWAAdapter) SharePrice(ctx context.Context, chainID uint64) (float64, error) {
+	deployment, client, err := a.deployment(chainID)
+	if err != nil {
+		return 0, err
+	}
+	return a.navPrice(ctx, client, chainID, deployment.feed)
+}
+
+// MarketState reads the value of the tokens issued. The rate is the continuously
+// compounded appreciation of the net asset value since the oldest recorded one within
+// the lookback.
+func (a *RWAAdapter) MarketState(ctx context.Context, chainID uint64) (*MarketState, error) {
+	deployment, client, err := a.deployment(chainID)
+	if err != nil {
+		return nil, err
+	}
+	price, err := a.navPrice(ctx, client, chainID, deployment.feed)
+	if err != nil {
+		return nil, err
+	}
+	supply, err := chain.CallUint(ctx, client, deployment.token, erc20ABI, "totalSupply")
+	if err != nil {
+		return nil, err
+	}
+	decimals, err := tokenDecimals(ctx, client, deployment.token)
+	if err != nil {
+		return nil, err
+	}
+	rate, err := appreciationRate(ctx, a.history, a.protocol, chainID, price, a.lookback, a.minWindow, chain.TimeOf(ctx, a.now))
+	if err != nil {
+		return nil, err
+	}
+
+	value, _ := new(big.Float).SetFloat64(rates.Scaled(supply, int(decimals)) * price * math.Pow10(usdcDecimals)).Int(nil)
+	return &MarketState{
+		Protocol: a.protocol,
+		ChainID:  chainID,
+		Pool: irm.Pool{
+			TotalSupply: value,
+			TotalBorrow: new(big.Int),
+			Model:       &irm.FixedModel{Rate: rate},
+		},
+	}, nil
+}
+
+// navPrice reads the latest net asset value of feed, failing when the issuer has not
+// published one within the maximum age
+func (a *RWAAdapter) navPrice(ctx context.Context, client chain.Client, chainID uint64, feed common.Address) (float64, error) {
+	round, err := chain.CallView(ctx, client, feed, navFeedABI, "latestRoundData")
+	if err != nil {
+		return 0, err
+	}
+	answer, _ := round[1].(*big.Int)
+	updatedAt, _ := round[3].(*big.Int)
+	if answer == nil || answer.Sign() <= 0 || updatedAt == nil {
+		return 0, fmt.Errorf("%s on %d has no net asset value", a.protocol, chainID)
+	}
+	now, err := blockTime(ctx, client)
+	if err != nil {
+		return 0, err
+	}
+	if age := time.Unix(int64(now), 0).Sub(time.Unix(updatedAt.Int64(), 0)); age > a.maxPriceAge {
+		return 0, fmt.Errorf("net asset value of %s on %d is %s old", a.protocol, chainID, age)
+	}
+	decimals, err := chain.CallView(ctx, client, feed, navFeedABI, "decimals")
+	if err != nil {
+		return 0, err
+	}
+	return rates.Scaled(answer, int(decimals[0].(uint8))), nil
+}
+
+func (a *RWAAdapter) deployment(chainID uint64) (rwaDeployment, chain.Client, error) {
+	deployment, ok := a.deployments[chainID]
+	if !ok {
+		return rwaDeployment{}, nil, fmt.Errorf("%w: %s on %d", ErrUnsupportedChain, a.protocol, chainID)
+	}
+	client, err := a.chains.ClientFor(chainID, a.protocol)
+	if err != nil {
+		return rwaDeployment{}, nil, err
+	}
+	return deployment, client, nil
+}
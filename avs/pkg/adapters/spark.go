@@ -0,0 +1,134 @@
+package adapters
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/irm"
+	"github.com/najnomics/crosscow-avs/pkg/rates"
+)
+
+// ProtocolSparkSavings is the task parameter name of Spark Savings USDC
+const ProtocolSparkSavings = "spark_savings"
+
+// The Sky Savings Rate is a per-second compounding factor in ray: 1e27 earns nothing
+const ssrABIJson = `[
+	{"name":"ssr","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+	{"name":"getSSR","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]}
+]`
+
+var ssrABI = chain.MustParseABI(ssrABIJson)
+
+// SparkSavingsMarket locates the Spark Savings USDC vault of a chain, an ERC-4626 vault
+// of USDC earning the Sky Savings Rate, and where that rate is read
+type SparkSavingsMarket struct {
+	Vault common.Address
+
+	// RateSource is sUSDS on Ethereum, read through ssr(), or the SSR oracle Spark
+	// bridges the rate to other chains with, read through getSSR()
+	RateSource common.Address
+	Oracle     bool
+}
+
+// SparkSavingsAdapter reads Spark Savings USDC vaults. Deposits are swapped to USDS and
+// earn the savings rate whatever the vault holds, so the rate is the same for any amount
+// and every deposit can be withdrawn.
+type SparkSavingsAdapter struct {
+	chains  *chain.Manager
+	markets map[uint64]SparkSavingsMarket
+}
+
+// NewSparkSavingsAdapter creates an adapter for the given per-chain vaults
+func NewSparkSavingsAdapter(chains *chain.Manager, markets map[uint64]SparkSavingsMarket) *SparkSavingsAdapter {
+	return &SparkSavingsAdapter{chains: chains, markets: markets}
+}
+
+func (a *SparkSavingsAdapter) Protocol() string {
+	return ProtocolSparkSavings
+}
+
+func (a *SparkSavingsAdapter) ChainIDs() []uint64 {
+	return sortedChainIDs(a.markets)
+}
+
+// MarketState reads the USDC held by the vault and the savings rate, as an annual rate
+func (a *SparkSavingsAdapter) MarketState(ctx context.Context, chainID uint64) (*MarketState, error) {
+	market, client, err := a.market(chainID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVaultAsset(ctx, client, chainID, market.Vault); err != nil {
+		return nil, err
+	}
+	method := "ssr"
+	if market.Oracle {
+		method = "getSSR"
+	}
+	batch := chain.NewBatch(client)
+	assetsCall := batch.Add(market.Vault, erc4626ABI, "totalAssets")
+	ssrCall := batch.Add(market.RateSource, ssrABI, method)
+	if err := batch.Do(ctx); err != nil {
+		return nil, err
+	}
+	assets, err := assetsCall.Uint()
+	if err != nil {
+		return nil, err
+	}
+	ssr, err := ssrCall.Uint()
+	if err != nil {
+		return nil, err
+	}
+
+	return &MarketState{
+		Protocol: ProtocolSparkSavings,
+		ChainID:  chainID,
+		Pool: irm.Pool{
+			TotalSupply: assets,
+			TotalBorrow: new(big.Int),
+			Model:       &irm.FixedModel{Rate: rates.Annualize(rates.RayFactor(ssr), rates.SecondsPerYear, rates.Simple)},
+		},
+	}, nil
+}
+
+// SupplyCall builds a deposit of amount USDC into the vault, with the shares minted to
+// onBehalfOf
+func (a *SparkSavingsAdapter) SupplyCall(chainID uint64, onBehalfOf common.Address, amount *big.Int) (*chain.Call, error) {
+	market, ok := a.markets[chainID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s on %d", ErrUnsupportedChain, ProtocolSparkSavings, chainID)
+	}
+	return vaultSupplyCall(market.Vault, onBehalfOf, amount)
+}
+
+// WithdrawCall builds a withdrawal of amount USDC from the vault to to
+func (a *SparkSavingsAdapter) WithdrawCall(chainID uint64, to common.Address, amount *big.Int) (*chain.Call, error) {
+	market, ok := a.markets[chainID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s on %d", ErrUnsupportedChain, ProtocolSparkSavings, chainID)
+	}
+	return vaultWithdrawCall(market.Vault, to, amount)
+}
+
+// PositionOf returns the USDC the vault shares of account are worth
+func (a *SparkSavingsAdapter) PositionOf(ctx context.Context, chainID uint64, account common.Address) (*big.Int, error) {
+	market, client, err := a.market(chainID)
+	if err != nil {
+		return nil, err
+	}
+	return vaultPosition(ctx, client, market.Vault, account)
+}
+
+func (a *SparkSavingsAdapter) market(chainID uint64) (SparkSavingsMarket, chain.Client, error) {
+	market, ok := a.markets[chainID]
+	if !ok {
+		return SparkSavingsMarket{}, nil, fmt.Errorf("%w: %s on %d", ErrUnsupportedChain, ProtocolSparkSavings, chainID)
+	}
+	client, err := a.chains.ClientFor(chainID, ProtocolSparkSavings)
+	if err != nil {
+		return SparkSavingsMarket{}, nil, err
+	}
+	return market, client, nil
+}
@@ -0,0 +1,239 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/irm"
+	"github.com/najnomics/crosscow-avs/pkg/rates"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+)
+
+// ErrInsufficientHistory is returned for vaults whose share price has not been recorded
+// for long enough to measure a rate
+var ErrInsufficientHistory = errors.New("insufficient share price history")
+
+// SharePricer is implemented by adapters whose rate is measured from the share price
+// history the collector records
+type SharePricer interface {
+	// SharePrice returns the USDC one whole share of the market on chainID is worth
+	SharePrice(ctx context.Context, chainID uint64) (float64, error)
+}
+
+// VaultConfig configures ERC-4626 USDC vaults whose rate is measured from their share
+// price appreciation, for vaults without a bespoke adapter
+type VaultConfig struct {
+	// Lookback is the window share price appreciation is annualized over
+	Lookback time.Duration `yaml:"lookback"`
+
+	// MinWindow is the shortest history a rate is reported from, so a newly added vault
+	// is not ranked on a few samples
+	MinWindow time.Duration `yaml:"minWindow"`
+
+	// Vaults are the allowed vaults. Each is reported as its own protocol.
+	Vaults []Vault `yaml:"vaults"`
+}
+
+// Vault names an ERC-4626 USDC vault and its deployments
+type Vault struct {
+	// Protocol is the name tasks refer to the vault by
+	Protocol string `yaml:"protocol"`
+
+	// Addresses are the vault contracts by chain id
+	Addresses map[uint64]string `yaml:"addresses"`
+}
+
+// DefaultVaultConfig annualizes a week of share price history and reports no rate before
+// six hours of it were recorded. No vaults are allowed by default.
+func DefaultVaultConfig() VaultConfig {
+	return VaultConfig{
+		Lookback:  7 * 24 * time.Hour,
+		MinWindow: 6 * time.Hour,
+	}
+}
+
+// Validate checks the config for values vault adapters cannot run with. The windows are
+// checked without vaults too, as built-in vault adapters use them.
+func (c *VaultConfig) Validate() error {
+	if c.MinWindow <= 0 || c.Lookback < c.MinWindow {
+		return fmt.Errorf("lookback must not be shorter than a positive minWindow")
+	}
+	seen := make(map[string]bool, len(c.Vaults))
+	for i, vault := range c.Vaults {
+		name := normalizeProtocol(vault.Protocol)
+		if name == "" {
+			return fmt.Errorf("vaults[%d].protocol is required", i)
+		}
+		if seen[name] {
+			return fmt.Errorf("vault %s is configured more than once", name)
+		}
+		seen[name] = true
+		if len(vault.Addresses) == 0 {
+			return fmt.Errorf("vaults[%d].addresses are required", i)
+		}
+		for chainID, address := range vault.Addresses {
+			if chainID == 0 || !common.IsHexAddress(address) {
+				return fmt.Errorf("vaults[%d].addresses[%d] is not a vault address on a chain", i, chainID)
+			}
+		}
+	}
+	return nil
+}
+
+// ERC4626Adapter reads an ERC-4626 USDC vault. Its rate is the share price appreciation
+// over the lookback, read from the share price series the collector records, so operators
+// with different history can report slightly different rates.
+type ERC4626Adapter struct {
+	protocol  string
+	chains    *chain.Manager
+	history   *store.SeriesStore
+	vaults    map[uint64]common.Address
+	lookback  time.Duration
+	minWindow time.Duration
+	now       func() time.Time
+}
+
+// NewVaultAdapters creates an adapter for every vault in cfg
+func NewVaultAdapters(cfg VaultConfig, chains *chain.Manager, history *store.SeriesStore) []*ERC4626Adapter {
+	out := make([]*ERC4626Adapter, 0, len(cfg.Vaults))
+	for _, vault := range cfg.Vaults {
+		addresses := make(map[uint64]common.Address, len(vault.Addresses))
+		for chainID, address := range vault.Addresses {
+			addresses[chainID] = common.HexToAddress(address)
+		}
+		out = append(out, newERC4626Adapter(vault.Protocol, addresses, cfg, chains, history))
+	}
+	return out
+}
+
+func newERC4626Adapter(protocol string, vaults map[uint64]common.Address, cfg VaultConfig, chains *chain.Manager, history *store.SeriesStore) *ERC4626Adapter {
+	return &ERC4626Adapter{
+		protocol:  normalizeProtocol(protocol),
+		chains:    chains,
+		history:   history,
+		vaults:    vaults,
+		lookback:  cfg.Lookback,
+		minWindow: cfg.MinWindow,
+		now:       time.Now,
+	}
+}
+
+func (a *ERC4626Adapter) Protocol() string {
+	return a.protocol
+}
+
+func (a *ERC4626Adapter) ChainIDs() []uint64 {
+	return sortedChainIDs(a.vaults)
+}
+
+// SharePrice returns the USDC one whole share of the vault is worth
+func (a *ERC4626Adapter) SharePrice(ctx context.Context, chainID uint64) (float64, error) {
+	vault, client, err := a.vault(chainID)
+	if err != nil {
+		return 0, err
+	}
+	return vaultSharePrice(ctx, client, vault)
+}
+
+// MarketState reads the USDC held by the vault. The rate is the continuously compounded
+// share price appreciation since the oldest recorded price within the lookback.
+func (a *ERC4626Adapter) MarketState(ctx context.Context, chainID uint64) (*MarketState, error) {
+	vault, client, err := a.vault(chainID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVaultAsset(ctx, client, chainID, vault); err != nil {
+		return nil, err
+	}
+	assets, err := chain.CallUint(ctx, client, vault, erc4626ABI, "totalAssets")
+	if err != nil {
+		return nil, err
+	}
+	price, err := vaultSharePrice(ctx, client, vault)
+	if err != nil {
+		return nil, err
+	}
+	rate, err := a.rate(ctx, chainID, price)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MarketState{
+		Protocol: a.protocol,
+		ChainID:  chainID,
+		Pool: irm.Pool{
+			TotalSupply: assets,
+			TotalBorrow: new(big.Int),
+			Model:       &irm.FixedModel{Rate: rate},
+		},
+	}, nil
+}
+
+// rate annualizes the appreciation from the oldest recorded share price within the
+// lookback to price
+func (a *ERC4626Adapter) rate(ctx context.Context, chainID uint64, price float64) (float64, error) {
+	return appreciationRate(ctx, a.history, a.protocol, chainID, price, a.lookback, a.minWindow, chain.TimeOf(ctx, a.now))
+}
+
+// appreciationRate annualizes the appreciation of the share price of protocol on chainID
+// from the oldest price recorded within lookback of now to price, continuously compounded
+func appreciationRate(ctx context.Context, history *store.SeriesStore, protocol string, chainID uint64, price float64, lookback, minWindow time.Duration, now time.Time) (float64, error) {
+	points, err := history.Range(ctx, store.SharePriceSeries(protocol, chainID), now.Add(-lookback), now)
+	if err != nil {
+		return 0, err
+	}
+	if len(points) == 0 || now.Sub(points[0].Time) < minWindow {
+		return 0, fmt.Errorf("%w: %s on %d needs %s of history", ErrInsufficientHistory, protocol, chainID, minWindow)
+	}
+	if points[0].Value <= 0 || price <= 0 {
+		return 0, fmt.Errorf("%s on %d has a share price of zero", protocol, chainID)
+	}
+	elapsed := now.Sub(points[0].Time).Seconds()
+	return math.Log(price/points[0].Value) / elapsed * rates.SecondsPerYear, nil
+}
+
+// SupplyCall builds a deposit of amount USDC into the vault, with the shares minted to
+// onBehalfOf
+func (a *ERC4626Adapter) SupplyCall(chainID uint64, onBehalfOf common.Address, amount *big.Int) (*chain.Call, error) {
+	vault, ok := a.vaults[chainID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s on %d", ErrUnsupportedChain, a.protocol, chainID)
+	}
+	return vaultSupplyCall(vault, onBehalfOf, amount)
+}
+
+// WithdrawCall builds a withdrawal of amount USDC from the vault to to
+func (a *ERC4626Adapter) WithdrawCall(chainID uint64, to common.Address, amount *big.Int) (*chain.Call, error) {
+	vault, ok := a.vaults[chainID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s on %d", ErrUnsupportedChain, a.protocol, chainID)
+	}
+	return vaultWithdrawCall(vault, to, amount)
+}
+
+// PositionOf returns the USDC the vault shares of account are worth
+func (a *ERC4626Adapter) PositionOf(ctx context.Context, chainID uint64, account common.Address) (*big.Int, error) {
+	vault, client, err := a.vault(chainID)
+	if err != nil {
+		return nil, err
+	}
+	return vaultPosition(ctx, client, vault, account)
+}
+
+func (a *ERC4626Adapter) vault(chainID uint64) (common.Address, chain.Client, error) {
+	vault, ok := a.vaults[chainID]
+	if !ok {
+		return common.Address{}, nil, fmt.Errorf("%w: %s on %d", ErrUnsupportedChain, a.protocol, chainID)
+	}
+	client, err := a.chains.ClientFor(chainID, a.protocol)
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	return vault, client, nil
+}
@@ -0,0 +1,57 @@
+package adapters
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// EventWatch selects the logs of one contract that change a market: events with one of
+// Topics as their signature and, with Asset set, Asset as their first indexed argument
+type EventWatch struct {
+	Address common.Address
+	Topics  []common.Hash
+	Asset   *common.Address
+}
+
+// Matches reports whether log is selected by the watch
+func (w EventWatch) Matches(log types.Log) bool {
+	if log.Address != w.Address || len(log.Topics) == 0 {
+		return false
+	}
+	if w.Asset != nil && (len(log.Topics) < 2 || common.BytesToAddress(log.Topics[1].Bytes()) != *w.Asset) {
+		return false
+	}
+	for _, topic := range w.Topics {
+		if log.Topics[0] == topic {
+			return true
+		}
+	}
+	return false
+}
+
+// EventWatcher names the events after which a protocol's USDC markets must be read again,
+// so their state can be kept up to date from logs instead of being read for every task
+type EventWatcher interface {
+	YieldAdapter
+
+	// WatchedEvents returns the events changing the rates, totals, caps or status of the
+	// USDC market on chainID. The contracts watched do not change between calls.
+	WatchedEvents(ctx context.Context, chainID uint64) ([]EventWatch, error)
+}
+
+// watch selects events of contractABI, which must all be part of it
+func watch(contract common.Address, contractABI abi.ABI, asset *common.Address, events ...string) (EventWatch, error) {
+	w := EventWatch{Address: contract, Asset: asset}
+	for _, name := range events {
+		event, ok := contractABI.Events[name]
+		if !ok {
+			return EventWatch{}, fmt.Errorf("event %s is not part of the abi", name)
+		}
+		w.Topics = append(w.Topics, event.ID)
+	}
+	return w, nil
+}
@@ -0,0 +1,170 @@
+// Package addressbook maps chains and protocols to the addresses of their contracts and
+// to the dependencies they share with other venues. The contracts and dependencies of the
+// supported chains are embedded, and config entries override or add to them, so new
+// deployments are configured rather than hardcoded next to each adapter.
+package addressbook
+
+import (
+	_ "embed"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"gopkg.in/yaml.v3"
+)
+
+// Protocols of the book besides the lending protocols, which use their adapter names
+const (
+	ProtocolUSDC      = "usdc"
+	ProtocolCCTP      = "cctp"
+	ProtocolChainlink = "chainlink"
+)
+
+// Contracts of a protocol deployment
+const (
+	ContractToken              = "token"
+	ContractPool               = "pool"
+	ContractComet              = "comet"
+	ContractCToken             = "cToken"
+	ContractVault              = "vault"
+	ContractRateSource         = "rateSource"
+	ContractRewards            = "rewards"
+	ContractTokenMessenger     = "tokenMessenger"
+	ContractMessageTransmitter = "messageTransmitter"
+
+	// ContractSequencerUptimeFeed is the Chainlink feed reporting whether the sequencer
+	// of an L2 is up
+	ContractSequencerUptimeFeed = "sequencerUptimeFeed"
+)
+
+//go:embed defaults.yaml
+var defaultsYAML []byte
+
+// Entry is a contract address of the addressBook config
+type Entry struct {
+	ChainID  uint64 `yaml:"chainId"`
+	Protocol string `yaml:"protocol"`
+	Contract string `yaml:"contract"`
+	Address  string `yaml:"address"`
+}
+
+// Validate checks entries for addresses the book cannot hold. USDC is checked against
+// the token list tasks are answered with and cannot be overridden.
+func Validate(entries []Entry) error {
+	for i, e := range entries {
+		if e.ChainID == 0 || e.Protocol == "" || e.Contract == "" {
+			return fmt.Errorf("[%d]: chainId, protocol and contract are required", i)
+		}
+		if e.Protocol == ProtocolUSDC {
+			return fmt.Errorf("[%d]: the USDC token cannot be overridden", i)
+		}
+		if !common.IsHexAddress(e.Address) || common.HexToAddress(e.Address) == (common.Address{}) {
+			return fmt.Errorf("[%d]: address must be a non-zero hex address", i)
+		}
+	}
+	return nil
+}
+
+// Book holds contract addresses by chain id, protocol and contract, and dependencies by
+// chain id and protocol
+type Book struct {
+	contracts    map[uint64]map[string]map[string]common.Address
+	dependencies map[uint64]map[string][]Dependency
+}
+
+var (
+	defaultOnce sync.Once
+	defaultBook *Book
+)
+
+// Default returns the book of the embedded contracts. It is shared and must not be
+// changed; use With to override it.
+func Default() *Book {
+	defaultOnce.Do(func() {
+		var raw map[uint64]map[string]map[string]string
+		if err := yaml.Unmarshal(defaultsYAML, &raw); err != nil {
+			panic(fmt.Sprintf("addressbook: invalid embedded defaults: %v", err))
+		}
+		defaultBook = &Book{
+			contracts:    make(map[uint64]map[string]map[string]common.Address),
+			dependencies: make(map[uint64]map[string][]Dependency),
+		}
+		for chainID, protocols := range raw {
+			for protocol, contracts := range protocols {
+				for contract, address := range contracts {
+					if !common.IsHexAddress(address) {
+						panic(fmt.Sprintf("addressbook: invalid embedded address %s of %s %s on chain %d", address, protocol, contract, chainID))
+					}
+					defaultBook.set(chainID, protocol, contract, common.HexToAddress(address))
+				}
+			}
+		}
+		loadDependencies(defaultBook)
+	})
+	return defaultBook
+}
+
+func (b *Book) set(chainID uint64, protocol, contract string, address common.Address) {
+	if b.contracts[chainID] == nil {
+		b.contracts[chainID] = make(map[string]map[string]common.Address)
+	}
+	if b.contracts[chainID][protocol] == nil {
+		b.contracts[chainID][protocol] = make(map[string]common.Address)
+	}
+	b.contracts[chainID][protocol][contract] = address
+}
+
+// With returns a copy of b with entries set, which must be valid
+func (b *Book) With(entries []Entry) (*Book, error) {
+	if err := Validate(entries); err != nil {
+		return nil, err
+	}
+	out := &Book{contracts: make(map[uint64]map[string]map[string]common.Address), dependencies: b.dependencies}
+	for chainID, protocols := range b.contracts {
+		for protocol, contracts := range protocols {
+			for contract, address := range contracts {
+				out.set(chainID, protocol, contract, address)
+			}
+		}
+	}
+	for _, e := range entries {
+		out.set(e.ChainID, e.Protocol, e.Contract, common.HexToAddress(e.Address))
+	}
+	return out, nil
+}
+
+// Lookup returns the address of contract of protocol on chainID
+func (b *Book) Lookup(chainID uint64, protocol, contract string) (common.Address, bool) {
+	address, ok := b.contracts[chainID][protocol][contract]
+	return address, ok
+}
+
+// Address returns the address of contract of protocol on chainID, the zero address when
+// the book has none
+func (b *Book) Address(chainID uint64, protocol, contract string) common.Address {
+	address, _ := b.Lookup(chainID, protocol, contract)
+	return address
+}
+
+// Require returns the address of contract of protocol on chainID, failing when the book
+// has none
+func (b *Book) Require(chainID uint64, protocol, contract string) (common.Address, error) {
+	address, ok := b.Lookup(chainID, protocol, contract)
+	if !ok {
+		return common.Address{}, fmt.Errorf("no %s %s on chain %d in the address book", protocol, contract, chainID)
+	}
+	return address, nil
+}
+
+// Chains returns the chains protocol has contract on, in ascending order
+func (b *Book) Chains(protocol, contract string) []uint64 {
+	var chainIDs []uint64
+	for chainID := range b.contracts {
+		if _, ok := b.Lookup(chainID, protocol, contract); ok {
+			chainIDs = append(chainIDs, chainID)
+		}
+	}
+	sort.Slice(chainIDs, func(i, j int) bool { return chainIDs[i] < chainIDs[j] })
+	return chainIDs
+}
@@ -0,0 +1,92 @@
+package addressbook
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func Test_DefaultBook(t *testing.T) {
+	book := Default()
+	if usdc := book.Address(1, ProtocolUSDC, ContractToken); usdc != common.HexToAddress("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48") {
+		t.Errorf("Unexpected USDC on Ethereum %s", usdc.Hex())
+	}
+	if chains := book.Chains(ProtocolCCTP, ContractTokenMessenger); !reflect.DeepEqual(chains, []uint64{1, 8453, 42161, 84532, 421614, 11155111}) {
+		t.Errorf("Expected CCTP on every supported chain, got %v", chains)
+	}
+	if _, err := book.Require(1, "unknown", ContractPool); err == nil {
+		t.Errorf("Expected unknown contracts to be missing")
+	}
+}
+
+func Test_BookOverrides(t *testing.T) {
+	pool := "0x00000000000000000000000000000000000000aa"
+	book, err := Default().With([]Entry{
+		{ChainID: 1, Protocol: "aave_v3", Contract: ContractPool, Address: pool},
+		{ChainID: 10, Protocol: "aave_v3", Contract: ContractPool, Address: pool},
+	})
+	if err != nil {
+		t.Fatalf("With failed: %v", err)
+	}
+	if book.Address(1, "aave_v3", ContractPool) != common.HexToAddress(pool) || book.Address(1, "aave_v3", ContractRewards) == (common.Address{}) {
+		t.Errorf("Expected the pool overridden and the other contracts kept")
+	}
+	if chains := book.Chains("aave_v3", ContractPool); !reflect.DeepEqual(chains, []uint64{1, 10, 8453, 42161}) {
+		t.Errorf("Expected the added chain listed, got %v", chains)
+	}
+	if Default().Address(1, "aave_v3", ContractPool) == common.HexToAddress(pool) {
+		t.Errorf("Expected the default book unchanged")
+	}
+
+	for name, entry := range map[string]Entry{
+		"missing contract": {ChainID: 1, Protocol: "aave_v3", Address: pool},
+		"usdc":             {ChainID: 1, Protocol: ProtocolUSDC, Contract: ContractToken, Address: pool},
+		"zero address":     {ChainID: 1, Protocol: "aave_v3", Contract: ContractPool, Address: "0x0000000000000000000000000000000000000000"},
+		"not an address":   {ChainID: 1, Protocol: "aave_v3", Contract: ContractPool, Address: "pool"},
+	} {
+		if _, err := Default().With([]Entry{entry}); err == nil {
+			t.Errorf("%s: expected the entry to be rejected", name)
+		}
+	}
+}
+
+func Test_BookDependencies(t *testing.T) {
+	book := Default()
+	if got := book.Dependencies(8453, "aave_v3"); !reflect.DeepEqual(got, []Dependency{"oracle:chainlink", "sequencer:base"}) {
+		t.Errorf("Expected the oracle of aave and the sequencer of Base, got %v", got)
+	}
+	if got := book.Dependencies(8453, "unknown"); !reflect.DeepEqual(got, []Dependency{"sequencer:base"}) {
+		t.Errorf("Expected every venue of Base to depend on its sequencer, got %v", got)
+	}
+
+	overridden, err := book.WithDependencies([]DependencyEntry{
+		{ChainID: 8453, Protocol: "aave_v3"},
+		{ChainID: 1, Protocol: "morpho_steakhouse", DependsOn: []string{"market:morpho_blue", "oracle:chainlink"}},
+	})
+	if err != nil {
+		t.Fatalf("WithDependencies failed: %v", err)
+	}
+	if got := overridden.Dependencies(8453, "aave_v3"); !reflect.DeepEqual(got, []Dependency{"sequencer:base"}) {
+		t.Errorf("Expected the oracle of aave cleared, got %v", got)
+	}
+	if got := overridden.Dependencies(1, "morpho_steakhouse"); len(got) != 2 || got[0].Kind() != DependencyMarket {
+		t.Errorf("Expected the added vault dependencies, got %v", got)
+	}
+	if overridden.Address(1, "aave_v3", ContractPool) != book.Address(1, "aave_v3", ContractPool) {
+		t.Errorf("Expected the contracts kept")
+	}
+	if len(book.Dependencies(8453, "aave_v3")) != 2 {
+		t.Errorf("Expected the default book unchanged")
+	}
+
+	for name, entry := range map[string]DependencyEntry{
+		"missing protocol": {ChainID: 1, DependsOn: []string{"oracle:chainlink"}},
+		"no name":          {ChainID: 1, Protocol: "aave_v3", DependsOn: []string{"oracle"}},
+		"unknown kind":     {ChainID: 1, Protocol: "aave_v3", DependsOn: []string{"team:aave"}},
+	} {
+		if _, err := book.WithDependencies([]DependencyEntry{entry}); err == nil {
+			t.Errorf("%s: expected the entry to be rejected", name)
+		}
+	}
+}
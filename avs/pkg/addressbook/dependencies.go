@@ -0,0 +1,140 @@
+package addressbook
+
+import (
+	_ "embed"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Kinds of dependencies venues share
+const (
+	DependencyOracle    = "oracle"
+	DependencyMarket    = "market"
+	DependencySequencer = "sequencer"
+	DependencyIssuer    = "issuer"
+)
+
+// ProtocolAny keys the dependencies of every venue of a chain
+const ProtocolAny = "*"
+
+//go:embed dependencies.yaml
+var dependenciesYAML []byte
+
+// Dependency is something a venue cannot work without, as kind:name, e.g.
+// oracle:chainlink or sequencer:base
+type Dependency string
+
+// ParseDependency parses kind:name, failing for kinds the book does not know
+func ParseDependency(s string) (Dependency, error) {
+	kind, name, ok := strings.Cut(s, ":")
+	if !ok || name == "" {
+		return "", fmt.Errorf("dependency %q is not kind:name", s)
+	}
+	switch kind {
+	case DependencyOracle, DependencyMarket, DependencySequencer, DependencyIssuer:
+		return Dependency(s), nil
+	}
+	return "", fmt.Errorf("dependency %q is of unknown kind %q", s, kind)
+}
+
+// Kind returns the kind of d
+func (d Dependency) Kind() string {
+	kind, _, _ := strings.Cut(string(d), ":")
+	return kind
+}
+
+// DependencyEntry sets the dependencies of a protocol on a chain, those of every venue
+// of the chain when Protocol is ProtocolAny. An empty DependsOn clears them.
+type DependencyEntry struct {
+	ChainID   uint64   `yaml:"chainId"`
+	Protocol  string   `yaml:"protocol"`
+	DependsOn []string `yaml:"dependsOn"`
+}
+
+// ValidateDependencies checks entries for dependencies the book cannot hold
+func ValidateDependencies(entries []DependencyEntry) error {
+	for i, e := range entries {
+		if e.ChainID == 0 || e.Protocol == "" {
+			return fmt.Errorf("[%d]: chainId and protocol are required", i)
+		}
+		for _, d := range e.DependsOn {
+			if _, err := ParseDependency(d); err != nil {
+				return fmt.Errorf("[%d]: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (b *Book) setDependencies(chainID uint64, protocol string, dependencies []Dependency) {
+	if b.dependencies[chainID] == nil {
+		b.dependencies[chainID] = make(map[string][]Dependency)
+	}
+	if len(dependencies) == 0 {
+		delete(b.dependencies[chainID], protocol)
+		return
+	}
+	b.dependencies[chainID][protocol] = dependencies
+}
+
+// WithDependencies returns a copy of b with the dependencies of entries replaced, which
+// must be valid
+func (b *Book) WithDependencies(entries []DependencyEntry) (*Book, error) {
+	if err := ValidateDependencies(entries); err != nil {
+		return nil, err
+	}
+	out := &Book{contracts: b.contracts, dependencies: make(map[uint64]map[string][]Dependency)}
+	for chainID, protocols := range b.dependencies {
+		for protocol, dependencies := range protocols {
+			out.setDependencies(chainID, protocol, dependencies)
+		}
+	}
+	for _, e := range entries {
+		dependencies := make([]Dependency, len(e.DependsOn))
+		for i, d := range e.DependsOn {
+			dependencies[i] = Dependency(d)
+		}
+		out.setDependencies(e.ChainID, e.Protocol, dependencies)
+	}
+	return out, nil
+}
+
+// Dependencies returns what the venue of protocol on chainID depends on, those of every
+// venue of the chain included, in ascending order
+func (b *Book) Dependencies(chainID uint64, protocol string) []Dependency {
+	seen := make(map[Dependency]bool)
+	var out []Dependency
+	for _, key := range []string{ProtocolAny, protocol} {
+		for _, d := range b.dependencies[chainID][key] {
+			if !seen[d] {
+				seen[d] = true
+				out = append(out, d)
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+func loadDependencies(b *Book) {
+	var raw map[uint64]map[string][]string
+	if err := yaml.Unmarshal(dependenciesYAML, &raw); err != nil {
+		panic(fmt.Sprintf("addressbook: invalid embedded dependencies: %v", err))
+	}
+	for chainID, protocols := range raw {
+		for protocol, dependsOn := range protocols {
+			dependencies := make([]Dependency, len(dependsOn))
+			for i, s := range dependsOn {
+				d, err := ParseDependency(s)
+				if err != nil {
+					panic(fmt.Sprintf("addressbook: invalid embedded dependency of %s on chain %d: %v", protocol, chainID, err))
+				}
+				dependencies[i] = d
+			}
+			b.setDependencies(chainID, protocol, dependencies)
+		}
+	}
+}
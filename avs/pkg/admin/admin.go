@@ -0,0 +1,162 @@
+// Package admin serves the operator API of a performer on its own port: what it is
+// working on, what it answered recently and the state of its protocol adapters, with
+// switches for adapters and the log level. It listens on localhost unless configured
+// otherwise, and requires a bearer token when one is set and client certificates when TLS
+// names their authorities.
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/najnomics/crosscow-avs/pkg/transport"
+	"go.uber.org/zap"
+)
+
+// Config configures the admin API
+type Config struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Host is the interface the API listens on. Defaults to localhost, so the API is only
+	// reachable from the machine the performer runs on.
+	Host string `yaml:"host"`
+	Port int    `yaml:"port"`
+
+	// Token is the bearer token requests must carry. It is required when Host is not a
+	// loopback address, unless TLS requires client certificates.
+	Token string `yaml:"token"`
+
+	// TLS serves the API over HTTPS, requiring client certificates when it names their
+	// authorities
+	TLS transport.TLSConfig `yaml:"tls"`
+
+	// ProbeTimeout bounds the market reads of adapter health probes and the calls of RPC
+	// endpoint probes
+	ProbeTimeout time.Duration `yaml:"probeTimeout"`
+}
+
+// DefaultConfig serves the API on localhost:8091 when enabled
+func DefaultConfig() Config {
+	return Config{Host: "127.0.0.1", Port: 8091, ProbeTimeout: 5 * time.Second}
+}
+
+// Validate checks the config for values the API cannot be served with
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Port <= 0 || c.Port > 65535 {
+		return fmt.Errorf("port must be between 1 and 65535")
+	}
+	if c.ProbeTimeout <= 0 {
+		return fmt.Errorf("probeTimeout must be positive")
+	}
+	if err := c.TLS.Validate(); err != nil {
+		return fmt.Errorf("tls: %w", err)
+	}
+	if c.Token == "" && !c.TLS.Mutual() && !IsLoopback(c.Host) {
+		return fmt.Errorf("token or tls.clientCAFile is required when host is not a loopback address")
+	}
+	return nil
+}
+
+// IsLoopback reports whether host only accepts connections from the local machine
+func IsLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// Server serves the admin API
+type Server struct {
+	httpServer *http.Server
+	mux        *http.ServeMux
+	logger     *zap.Logger
+}
+
+// NewServer creates an admin server listening on cfg.Host and cfg.Port. It fails when
+// the TLS files of cfg cannot be read.
+func NewServer(cfg *Config, logger *zap.Logger) (*Server, error) {
+	tlsConfig, err := transport.ServerConfig(cfg.TLS)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	return &Server{
+		httpServer: &http.Server{
+			Addr:              net.JoinHostPort(cfg.Host, fmt.Sprint(cfg.Port)),
+			Handler:           RequireToken(cfg.Token, mux),
+			TLSConfig:         tlsConfig,
+			ReadHeaderTimeout: 5 * time.Second,
+		},
+		mux:    mux,
+		logger: logger,
+	}, nil
+}
+
+// Handle serves an endpoint of the API. Patterns may name a method and path wildcards,
+// such as "POST /adapters/{protocol}/disable". It must be called before Start.
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// Handler returns the handler of the API, token check included
+func (s *Server) Handler() http.Handler {
+	return s.httpServer.Handler
+}
+
+// Start serves the API until ctx is cancelled
+func (s *Server) Start(ctx context.Context) {
+	go func() {
+		serve := s.httpServer.ListenAndServe
+		if s.httpServer.TLSConfig != nil {
+			serve = func() error { return s.httpServer.ListenAndServeTLS("", "") }
+		}
+		if err := serve(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Sugar().Errorw("Admin server stopped unexpectedly", "error", err)
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = s.httpServer.Shutdown(shutdownCtx)
+	}()
+}
+
+// RequireToken rejects requests to next without the bearer token, when there is one
+func RequireToken(token string, next http.Handler) http.Handler {
+	if token == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		presented, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+			WriteError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// WriteJSON answers with status and v encoded as JSON
+func WriteJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// WriteError answers with status and err as a JSON error
+func WriteError(w http.ResponseWriter, status int, err error) {
+	WriteJSON(w, status, map[string]string{"error": err.Error()})
+}
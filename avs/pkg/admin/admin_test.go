@@ -0,0 +1,82 @@
+package admin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/najnomics/crosscow-avs/pkg/transport"
+	"go.uber.org/zap"
+)
+
+func Test_ConfigValidate(t *testing.T) {
+	valid := DefaultConfig()
+	valid.Enabled = true
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected the default config to be valid: %v", err)
+	}
+
+	exposed := valid
+	exposed.Host = "0.0.0.0"
+	if err := exposed.Validate(); err == nil {
+		t.Errorf("Expected a token to be required beyond localhost")
+	}
+	exposed.Token = "secret"
+	if err := exposed.Validate(); err != nil {
+		t.Errorf("Expected a token to allow any host: %v", err)
+	}
+
+	mutual := valid
+	mutual.Host = "0.0.0.0"
+	mutual.TLS = transport.TLSConfig{Enabled: true, CertFile: "cert.pem", KeyFile: "key.pem", ClientCAFile: "ca.pem"}
+	if err := mutual.Validate(); err != nil {
+		t.Errorf("Expected client certificates to allow any host: %v", err)
+	}
+	mutual.TLS.KeyFile = ""
+	if err := mutual.Validate(); err == nil {
+		t.Errorf("Expected TLS without a key to be rejected")
+	}
+
+	badPort := valid
+	badPort.Port = 0
+	if err := badPort.Validate(); err == nil {
+		t.Errorf("Expected an invalid port to be rejected")
+	}
+}
+
+func Test_ServerRequiresToken(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Token = "secret"
+	server, err := NewServer(&cfg, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewServer failed: %v", err)
+	}
+	server.Handle("GET /ping", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	}))
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	for _, tc := range []struct {
+		name   string
+		header string
+		status int
+	}{
+		{name: "no token", status: http.StatusUnauthorized},
+		{name: "wrong token", header: "Bearer other", status: http.StatusUnauthorized},
+		{name: "token", header: "Bearer secret", status: http.StatusOK},
+	} {
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/ping", nil)
+		if tc.header != "" {
+			req.Header.Set("Authorization", tc.header)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: request failed: %v", tc.name, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tc.status {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.status, resp.StatusCode)
+		}
+	}
+}
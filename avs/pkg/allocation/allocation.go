@@ -0,0 +1,260 @@
+// Package allocation splits an amount across lending markets to maximize net,
+// risk-adjusted yield. Deposits dilute the rate of the market they go to, and moving funds
+// into a market costs a fixed transfer fee, so the best plan often spreads funds over a
+// few markets rather than chasing the highest quoted rate. Markets sharing a critical
+// dependency fail together, so plans may also pay for concentrating funds behind one.
+package allocation
+
+import (
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/najnomics/crosscow-avs/pkg/irm"
+)
+
+const (
+	// DefaultSteps is the number of chunks the amount is split into
+	DefaultSteps = 100
+
+	// DefaultHorizon is the period transfer costs are amortized over
+	DefaultHorizon = 30 * 24 * time.Hour
+
+	// MaxExhaustive is the number of candidates up to which every combination of markets
+	// is evaluated. Larger sets are cut down to the candidates with the best risk-adjusted
+	// rates first.
+	MaxExhaustive = 10
+)
+
+const year = 365 * 24 * time.Hour
+
+// Candidate is a market funds may be allocated to. Amounts are in token base units.
+type Candidate struct {
+	Protocol string
+	ChainID  uint64
+	Pool     irm.Pool
+
+	// TransferCost is paid once when any funds move into the market
+	TransferCost *big.Int
+
+	// RiskPenalty is deducted from the supply rate, as an annual rate (0.005 is 50 bps)
+	RiskPenalty float64
+
+	// MaxAmount caps the allocation to the market. Nil means uncapped.
+	MaxAmount *big.Int
+
+	// Dependencies name what the market shares with others and fails with, such as an
+	// oracle or the sequencer of its chain
+	Dependencies []string
+}
+
+// Options tune the optimizer. Zero values fall back to the defaults.
+type Options struct {
+	Steps   int
+	Horizon time.Duration
+
+	// ProtocolCaps caps the allocation to each protocol, summed over its markets on
+	// every chain. Protocols left out are uncapped.
+	ProtocolCaps map[string]*big.Int
+
+	// ConcentrationPenalty is charged on the funds behind each dependency in proportion
+	// to the share of the total they make up, as an annual rate: a plan putting
+	// everything behind one dependency pays it on the whole amount, one putting half
+	// behind it pays a quarter of it. The penalty grows with the square of the exposure,
+	// so spreading across independent markets is rewarded.
+	ConcentrationPenalty float64
+}
+
+// Allocation is the share of the amount a plan puts into one candidate
+type Allocation struct {
+	// Candidate is the index of the market in the candidates passed to Optimize
+	Candidate int
+	Amount    *big.Int
+
+	// Rate is the supply rate of the market after the deposit
+	Rate float64
+
+	// NetYield is the yield earned over the horizon after the risk penalty and the
+	// transfer cost, in base units
+	NetYield float64
+}
+
+// Plan is the allocation maximizing net yield over the horizon
+type Plan struct {
+	// Allocations are ordered by amount, largest first
+	Allocations []Allocation
+
+	// Unallocated is left where it is, either because caps are reached or because no
+	// market earns back its transfer cost
+	Unallocated *big.Int
+
+	// ConcentrationCost is the concentration penalty over the horizon, in base units,
+	// already deducted from NetYield
+	ConcentrationCost float64
+
+	NetYield float64
+}
+
+// Optimize computes the plan maximizing the net yield of total across candidates.
+//
+// For every combination of candidates, the amount is poured in chunks into whichever
+// market gains the most from the next chunk, accounting for the rate dilution the deposit
+// causes. This is optimal for concave yield curves; transfer costs, which are not concave,
+// are handled by comparing combinations.
+func Optimize(total *big.Int, candidates []Candidate, opts Options) *Plan {
+	if opts.Steps <= 0 {
+		opts.Steps = DefaultSteps
+	}
+	if opts.Horizon <= 0 {
+		opts.Horizon = DefaultHorizon
+	}
+	years := opts.Horizon.Hours() / year.Hours()
+
+	considered := shortlist(candidates)
+	best := &Plan{Allocations: []Allocation{}, Unallocated: new(big.Int).Set(total)}
+	if total.Sign() <= 0 {
+		return best
+	}
+
+	chunks := split(total, opts.Steps)
+	for subset := 1; subset < 1<<len(considered); subset++ {
+		var members []int
+		for i, c := range considered {
+			if subset&(1<<i) != 0 {
+				members = append(members, c)
+			}
+		}
+		plan := fill(candidates, members, chunks, years, opts)
+		if plan.NetYield > best.NetYield {
+			best = plan
+		}
+	}
+	return best
+}
+
+// fill pours chunks into members, each chunk going to the member it earns the most in,
+// net of the concentration it adds, without exceeding the cap of the member or of its
+// protocol
+func fill(candidates []Candidate, members []int, chunks []*big.Int, years float64, opts Options) *Plan {
+	amounts := make(map[int]*big.Int, len(members))
+	protocols := make(map[string]*big.Int)
+	for _, m := range members {
+		amounts[m] = new(big.Int)
+		protocols[candidates[m].Protocol] = new(big.Int)
+	}
+	total := new(big.Int)
+	for _, chunk := range chunks {
+		total.Add(total, chunk)
+	}
+	exposures := make(map[string]float64)
+	// concentration is the penalty over the horizon of exposure behind one dependency
+	concentration := func(exposure float64) float64 {
+		return opts.ConcentrationPenalty * exposure * exposure / toFloat(total) * years
+	}
+
+	unallocated := new(big.Int)
+	for _, chunk := range chunks {
+		bestMember, bestGain := -1, 0.0
+		for _, m := range members {
+			next := new(big.Int).Add(amounts[m], chunk)
+			if limit := candidates[m].MaxAmount; limit != nil && next.Cmp(limit) > 0 {
+				continue
+			}
+			protocol := candidates[m].Protocol
+			if limit := opts.ProtocolCaps[protocol]; limit != nil && new(big.Int).Add(protocols[protocol], chunk).Cmp(limit) > 0 {
+				continue
+			}
+			gain := grossYield(&candidates[m], next, years) - grossYield(&candidates[m], amounts[m], years)
+			for _, d := range candidates[m].Dependencies {
+				gain -= concentration(exposures[d]+toFloat(chunk)) - concentration(exposures[d])
+			}
+			if bestMember == -1 || gain > bestGain {
+				bestMember, bestGain = m, gain
+			}
+		}
+		if bestMember == -1 || bestGain <= 0 {
+			unallocated.Add(unallocated, chunk)
+			continue
+		}
+		amounts[bestMember].Add(amounts[bestMember], chunk)
+		protocols[candidates[bestMember].Protocol].Add(protocols[candidates[bestMember].Protocol], chunk)
+		for _, d := range candidates[bestMember].Dependencies {
+			exposures[d] += toFloat(chunk)
+		}
+	}
+
+	plan := &Plan{Allocations: []Allocation{}, Unallocated: unallocated}
+	for _, m := range members {
+		amount := amounts[m]
+		if amount.Sign() == 0 {
+			continue
+		}
+		c := &candidates[m]
+		net := grossYield(c, amount, years) - toFloat(c.TransferCost)
+		plan.Allocations = append(plan.Allocations, Allocation{
+			Candidate: m,
+			Amount:    amount,
+			Rate:      c.Pool.DepositImpact(amount).SupplyRate,
+			NetYield:  net,
+		})
+		plan.NetYield += net
+	}
+	for _, exposure := range exposures {
+		plan.ConcentrationCost += concentration(exposure)
+	}
+	plan.NetYield -= plan.ConcentrationCost
+	sort.SliceStable(plan.Allocations, func(i, j int) bool {
+		return plan.Allocations[i].Amount.Cmp(plan.Allocations[j].Amount) > 0
+	})
+	return plan
+}
+
+// grossYield is the risk-adjusted yield of amount in c over years, before transfer costs
+func grossYield(c *Candidate, amount *big.Int, years float64) float64 {
+	if amount.Sign() == 0 {
+		return 0
+	}
+	rate := c.Pool.DepositImpact(amount).SupplyRate - c.RiskPenalty
+	return toFloat(amount) * rate * years
+}
+
+// shortlist returns the indexes of the candidates worth combining, best risk-adjusted
+// rate first
+func shortlist(candidates []Candidate) []int {
+	indexes := make([]int, len(candidates))
+	for i := range candidates {
+		indexes[i] = i
+	}
+	if len(indexes) <= MaxExhaustive {
+		return indexes
+	}
+	sort.SliceStable(indexes, func(a, b int) bool {
+		ca, cb := &candidates[indexes[a]], &candidates[indexes[b]]
+		return ca.Pool.SupplyRate()-ca.RiskPenalty > cb.Pool.SupplyRate()-cb.RiskPenalty
+	})
+	return indexes[:MaxExhaustive]
+}
+
+// split divides total into steps chunks, the last one taking the remainder
+func split(total *big.Int, steps int) []*big.Int {
+	chunk := new(big.Int).Quo(total, big.NewInt(int64(steps)))
+	if chunk.Sign() == 0 {
+		return []*big.Int{new(big.Int).Set(total)}
+	}
+	chunks := make([]*big.Int, steps)
+	rest := new(big.Int).Set(total)
+	for i := 0; i < steps-1; i++ {
+		chunks[i] = chunk
+		rest.Sub(rest, chunk)
+	}
+	chunks[steps-1] = rest
+	return chunks
+}
+
+func toFloat(amount *big.Int) float64 {
+	if amount == nil {
+		return 0
+	}
+	f, _ := new(big.Float).SetInt(amount).Float64()
+	return f
+}
@@ -0,0 +1,131 @@
+package allocation
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/najnomics/crosscow-avs/pkg/irm"
+)
+
+func units(amount int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(amount), big.NewInt(1_000_000))
+}
+
+// market is a pool paying about 3.8% that is 80% utilized with 100M supplied
+func market(protocol string, chainID uint64) Candidate {
+	return Candidate{
+		Protocol: protocol,
+		ChainID:  chainID,
+		Pool: irm.Pool{
+			TotalSupply: units(100_000_000),
+			TotalBorrow: units(80_000_000),
+			Model: &irm.KinkModel{
+				OptimalUtilization: 0.9,
+				Slope1:             0.06,
+				Slope2:             0.6,
+				ReserveFactor:      0.1,
+			},
+		},
+	}
+}
+
+func Test_OptimizeSpreadsAcrossEqualMarkets(t *testing.T) {
+	plan := Optimize(units(20_000_000), []Candidate{market("aave_v3", 1), market("compound_v3", 1)}, Options{})
+
+	if len(plan.Allocations) != 2 {
+		t.Fatalf("Expected a large deposit to be split to limit dilution, got %+v", plan.Allocations)
+	}
+	if plan.Allocations[0].Amount.Cmp(units(10_000_000)) != 0 || plan.Unallocated.Sign() != 0 {
+		t.Errorf("Expected an even split, got %s and %s", plan.Allocations[0].Amount, plan.Allocations[1].Amount)
+	}
+}
+
+func Test_OptimizeAvoidsTransferCostsThatDoNotPayOff(t *testing.T) {
+	remote := market("aave_v3", 8453)
+	remote.TransferCost = units(5_000)
+
+	// 30 days of yield on half of 100k is about 80 USDC, far below the transfer cost
+	plan := Optimize(units(100_000), []Candidate{market("aave_v3", 1), remote}, Options{})
+	if len(plan.Allocations) != 1 || plan.Allocations[0].Candidate != 0 {
+		t.Fatalf("Expected everything in the local market, got %+v", plan.Allocations)
+	}
+	if plan.Allocations[0].Amount.Cmp(units(100_000)) != 0 {
+		t.Errorf("Expected the full amount to be allocated, got %s", plan.Allocations[0].Amount)
+	}
+}
+
+func Test_OptimizeRespectsCapsAndPenalties(t *testing.T) {
+	capped := market("aave_v3", 1)
+	capped.MaxAmount = units(1_000_000)
+	risky := market("compound_v3", 1)
+	risky.RiskPenalty = 0.05
+
+	plan := Optimize(units(4_000_000), []Candidate{capped, risky}, Options{Steps: 40})
+	if len(plan.Allocations) != 1 || plan.Allocations[0].Amount.Cmp(units(1_000_000)) != 0 {
+		t.Fatalf("Expected only the capped market to be used, got %+v", plan.Allocations)
+	}
+	if plan.Unallocated.Cmp(units(3_000_000)) != 0 {
+		t.Errorf("Expected the rest to stay unallocated, got %s", plan.Unallocated)
+	}
+	if plan.NetYield <= 0 {
+		t.Errorf("Expected a positive net yield, got %f", plan.NetYield)
+	}
+}
+
+func Test_OptimizeRespectsProtocolCaps(t *testing.T) {
+	candidates := []Candidate{market("aave_v3", 1), market("aave_v3", 8453), market("compound_v3", 1)}
+
+	plan := Optimize(units(30_000_000), candidates, Options{ProtocolCaps: map[string]*big.Int{"aave_v3": units(12_000_000)}})
+	perProtocol := make(map[string]*big.Int)
+	for _, a := range plan.Allocations {
+		protocol := candidates[a.Candidate].Protocol
+		if perProtocol[protocol] == nil {
+			perProtocol[protocol] = new(big.Int)
+		}
+		perProtocol[protocol].Add(perProtocol[protocol], a.Amount)
+	}
+	if perProtocol["aave_v3"].Cmp(units(12_000_000)) != 0 {
+		t.Errorf("Expected Aave to be filled to its 12M cap across chains, got %s", perProtocol["aave_v3"])
+	}
+	if perProtocol["compound_v3"].Cmp(units(18_000_000)) != 0 || plan.Unallocated.Sign() != 0 {
+		t.Errorf("Expected the rest to go to Compound, got %s with %s unallocated", perProtocol["compound_v3"], plan.Unallocated)
+	}
+}
+
+func Test_OptimizePenalizesSharedDependencies(t *testing.T) {
+	candidates := []Candidate{market("aave_v3", 1), market("compound_v3", 1), market("fluid", 1)}
+	candidates[0].Dependencies = []string{"oracle:chainlink"}
+	candidates[1].Dependencies = []string{"oracle:chainlink"}
+	candidates[2].Dependencies = []string{"oracle:redstone"}
+
+	amounts := func(plan *Plan) map[string]*big.Int {
+		out := make(map[string]*big.Int)
+		for _, a := range plan.Allocations {
+			out[candidates[a.Candidate].Protocol] = a.Amount
+		}
+		return out
+	}
+	plan := Optimize(units(30_000_000), candidates, Options{ConcentrationPenalty: 0.02})
+	spread := amounts(plan)
+	// without the penalty each takes about 10M
+	if spread["fluid"].Cmp(units(12_000_000)) < 0 || spread["aave_v3"].Cmp(units(9_000_000)) > 0 {
+		t.Errorf("Expected the market with its own oracle to take more, got %v", spread)
+	}
+	if plan.ConcentrationCost <= 0 {
+		t.Errorf("Expected the remaining concentration to be charged, got %f", plan.ConcentrationCost)
+	}
+}
+
+func BenchmarkOptimize(b *testing.B) {
+	var candidates []Candidate
+	for _, protocol := range []string{"aave_v3", "compound_v3", "fluid"} {
+		for _, chainID := range []uint64{1, 8453, 42161} {
+			candidates = append(candidates, market(protocol, chainID))
+		}
+	}
+	amount := units(100_000)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Optimize(amount, candidates, Options{})
+	}
+}
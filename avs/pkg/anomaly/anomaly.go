@@ -0,0 +1,139 @@
+// Package anomaly flags observations that deviate from their rolling historical baseline,
+// so manipulated or glitched rates are caught before they feed into rebalance decisions.
+package anomaly
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// ErrInsufficientBaseline is returned when the baseline holds fewer than MinSamples points
+var ErrInsufficientBaseline = errors.New("insufficient baseline history")
+
+// Direction tells whether an anomalous value is above or below the baseline
+type Direction string
+
+const (
+	DirectionNone  Direction = "none"
+	DirectionSpike Direction = "spike"
+	DirectionCrash Direction = "crash"
+)
+
+// Severity grades an anomaly
+type Severity string
+
+const (
+	SeverityNone     Severity = "none"
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+// Config configures the rolling baseline and sigma thresholds
+type Config struct {
+	// Lookback is the window of history forming the baseline
+	Lookback time.Duration `yaml:"lookback"`
+
+	// MinSamples is the minimum number of baseline points needed to evaluate a value
+	MinSamples int `yaml:"minSamples"`
+
+	// WarningSigma and CriticalSigma are the z-scores at which each severity starts
+	WarningSigma  float64 `yaml:"warningSigma"`
+	CriticalSigma float64 `yaml:"criticalSigma"`
+
+	// MinStdDev floors the baseline standard deviation so a perfectly flat history does
+	// not flag every tiny move. Expressed in the unit of the series.
+	MinStdDev float64 `yaml:"minStdDev"`
+}
+
+// DefaultConfig uses a 7 day baseline, warns at 3 sigma and flags 5 sigma as critical.
+// The standard deviation floor of 0.0001 is 1 basis point of an annual rate.
+func DefaultConfig() Config {
+	return Config{
+		Lookback:      7 * 24 * time.Hour,
+		MinSamples:    24,
+		WarningSigma:  3,
+		CriticalSigma: 5,
+		MinStdDev:     0.0001,
+	}
+}
+
+// Validate checks the config for values detection cannot run with
+func (c *Config) Validate() error {
+	if c.Lookback <= 0 {
+		return fmt.Errorf("lookback must be positive")
+	}
+	if c.MinSamples < 2 {
+		return fmt.Errorf("minSamples must be at least 2")
+	}
+	if c.WarningSigma <= 0 || c.CriticalSigma < c.WarningSigma {
+		return fmt.Errorf("sigma thresholds must be positive with criticalSigma >= warningSigma")
+	}
+	if c.MinStdDev < 0 {
+		return fmt.Errorf("minStdDev must not be negative")
+	}
+	return nil
+}
+
+// Result describes how a value compares to its baseline
+type Result struct {
+	Detected       bool
+	Direction      Direction
+	Severity       Severity
+	ZScore         float64
+	BaselineMean   float64
+	BaselineStdDev float64
+	Samples        int
+}
+
+// Detect compares value to the mean and standard deviation of baseline
+func Detect(value float64, baseline []float64, cfg Config) (*Result, error) {
+	if len(baseline) < cfg.MinSamples {
+		return nil, ErrInsufficientBaseline
+	}
+
+	var sum float64
+	for _, v := range baseline {
+		sum += v
+	}
+	mean := sum / float64(len(baseline))
+
+	var squares float64
+	for _, v := range baseline {
+		squares += (v - mean) * (v - mean)
+	}
+	stdDev := math.Sqrt(squares / float64(len(baseline)-1))
+
+	z := (value - mean) / math.Max(stdDev, cfg.MinStdDev)
+	if math.IsNaN(z) || math.IsInf(z, 0) {
+		z = 0
+	}
+
+	result := &Result{
+		Direction:      DirectionNone,
+		Severity:       SeverityNone,
+		ZScore:         z,
+		BaselineMean:   mean,
+		BaselineStdDev: stdDev,
+		Samples:        len(baseline),
+	}
+
+	magnitude := math.Abs(z)
+	switch {
+	case magnitude >= cfg.CriticalSigma:
+		result.Severity = SeverityCritical
+	case magnitude >= cfg.WarningSigma:
+		result.Severity = SeverityWarning
+	default:
+		return result, nil
+	}
+
+	result.Detected = true
+	if z > 0 {
+		result.Direction = DirectionSpike
+	} else {
+		result.Direction = DirectionCrash
+	}
+	return result, nil
+}
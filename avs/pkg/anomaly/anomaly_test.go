@@ -0,0 +1,64 @@
+package anomaly
+
+import (
+	"errors"
+	"testing"
+)
+
+func baseline() []float64 {
+	values := make([]float64, 48)
+	for i := range values {
+		// 4% +/- 2 basis points
+		values[i] = 0.04 + float64(i%5-2)*0.0001
+	}
+	return values
+}
+
+func Test_Detect(t *testing.T) {
+	cfg := DefaultConfig()
+
+	testCases := []struct {
+		name      string
+		value     float64
+		detected  bool
+		direction Direction
+		severity  Severity
+	}{
+		{name: "normal", value: 0.0401, detected: false, direction: DirectionNone, severity: SeverityNone},
+		{name: "moderate spike", value: 0.0405, detected: true, direction: DirectionSpike, severity: SeverityWarning},
+		{name: "glitched spike", value: 0.40, detected: true, direction: DirectionSpike, severity: SeverityCritical},
+		{name: "crash", value: 0.01, detected: true, direction: DirectionCrash, severity: SeverityCritical},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := Detect(tc.value, baseline(), cfg)
+			if err != nil {
+				t.Fatalf("Detect failed: %v", err)
+			}
+			if r.Detected != tc.detected || r.Direction != tc.direction || r.Severity != tc.severity {
+				t.Errorf("Expected %v/%s/%s, got %+v", tc.detected, tc.direction, tc.severity, r)
+			}
+		})
+	}
+}
+
+func Test_DetectFlatBaselineUsesStdDevFloor(t *testing.T) {
+	flat := make([]float64, 30)
+	for i := range flat {
+		flat[i] = 0.05
+	}
+	r, err := Detect(0.05005, flat, DefaultConfig())
+	if err != nil {
+		t.Fatalf("Detect failed: %v", err)
+	}
+	if r.Detected {
+		t.Errorf("Expected a half basis point move on a flat baseline not to be flagged: %+v", r)
+	}
+}
+
+func Test_DetectRequiresBaseline(t *testing.T) {
+	if _, err := Detect(0.05, []float64{0.05}, DefaultConfig()); !errors.Is(err, ErrInsufficientBaseline) {
+		t.Errorf("Expected ErrInsufficientBaseline, got %v", err)
+	}
+}
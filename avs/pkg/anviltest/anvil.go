@@ -0,0 +1,130 @@
+// Package anviltest forks live chains with Anvil for integration tests, so handlers can
+// be exercised end to end against real protocol state.
+//
+// A fork of a chain named NAME forks the RPC endpoint in NAME_RPC_URL, the variables the
+// Foundry fork tests use, at the block in NAME_FORK_BLOCK when it is set. Tests are
+// skipped when anvil is not installed or the RPC URL is not set.
+package anviltest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/najnomics/crosscow-avs/pkg/devnet"
+)
+
+// startTimeout is how long a fork may take to answer its first request
+const startTimeout = 60 * time.Second
+
+// Fork is a running Anvil fork of a chain
+type Fork struct {
+	ChainID uint64
+	URL     string
+
+	client *rpc.Client
+}
+
+// Start forks the chain named name, such as MAINNET, and stops the fork when the test
+// ends
+func Start(t testing.TB, chainID uint64, name string) *Fork {
+	t.Helper()
+	anvil, err := exec.LookPath("anvil")
+	if err != nil {
+		t.Skip("anvil is not installed")
+	}
+	forkURL := os.Getenv(name + "_RPC_URL")
+	if forkURL == "" {
+		t.Skipf("%s_RPC_URL is not set", name)
+	}
+
+	port := freePort(t)
+	args := []string{
+		"--fork-url", forkURL,
+		"--port", strconv.Itoa(port),
+		"--chain-id", strconv.FormatUint(chainID, 10),
+		"--no-rate-limit",
+		"--silent",
+	}
+	if block := os.Getenv(name + "_FORK_BLOCK"); block != "" {
+		args = append(args, "--fork-block-number", block)
+	}
+	var output bytes.Buffer
+	cmd := exec.Command(anvil, args...)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start anvil: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+
+	f := &Fork{ChainID: chainID, URL: fmt.Sprintf("http://127.0.0.1:%d", port)}
+	ctx, cancel := context.WithTimeout(context.Background(), startTimeout)
+	defer cancel()
+	for {
+		if f.client, err = rpc.DialContext(ctx, f.URL); err == nil {
+			var id hexutil.Uint64
+			if err = f.client.CallContext(ctx, &id, "eth_chainId"); err == nil {
+				break
+			}
+			f.client.Close()
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatalf("Anvil fork of %s did not start: %v\n%s", name, err, output.String())
+		case <-time.After(250 * time.Millisecond):
+		}
+	}
+	t.Cleanup(f.client.Close)
+	return f
+}
+
+// SetBalance sets the native balance of account, in wei
+func (f *Fork) SetBalance(t testing.TB, account common.Address, wei *big.Int) {
+	t.Helper()
+	f.call(t, "anvil_setBalance", account, hexutil.EncodeBig(wei))
+}
+
+// SetStorageAt sets a storage slot of contract
+func (f *Fork) SetStorageAt(t testing.TB, contract common.Address, slot, value common.Hash) {
+	t.Helper()
+	f.call(t, "anvil_setStorageAt", contract, slot, value)
+}
+
+// SetERC20Balance sets the balance of holder in token, whose balances mapping is at
+// storage slot balancesSlot. USDC keeps its balances at slot 9 on every chain.
+func (f *Fork) SetERC20Balance(t testing.TB, token, holder common.Address, balancesSlot uint64, amount *big.Int) {
+	t.Helper()
+	f.SetStorageAt(t, token, devnet.BalanceSlot(holder, balancesSlot), common.BigToHash(amount))
+}
+
+func (f *Fork) call(t testing.TB, method string, args ...interface{}) {
+	t.Helper()
+	if err := f.client.CallContext(context.Background(), nil, method, args...); err != nil {
+		t.Fatalf("%s failed: %v", method, err)
+	}
+}
+
+// freePort returns a local port nothing listens on
+func freePort(t testing.TB) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find a free port: %v", err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
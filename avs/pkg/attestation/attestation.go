@@ -0,0 +1,171 @@
+// Package attestation signs task results with an operator key, so consumers can audit
+// which operator produced which data beyond the aggregate BLS signature Hourglass
+// collects.
+//
+// An Attestation covers ResultHash, the keccak256 hash of the result as it is encoded
+// without the attestation. Its signature is the EIP-191 personal message signature of
+// the keccak256 hash of the canonical JSON of the attestation without its signature, as
+// task signatures are made in package auth.
+package attestation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+	"github.com/najnomics/crosscow-avs/pkg/resilience"
+	"github.com/najnomics/crosscow-avs/pkg/signer"
+)
+
+// ErrInvalidAttestation is returned for attestations that do not cover a result or were
+// not signed by their operator
+var ErrInvalidAttestation = errors.New("attestation is invalid")
+
+// Config configures result attestations
+type Config struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Signer holds the operator key results are attested with. It may be the key
+	// transactions are signed with, or one kept for attestations alone.
+	Signer signer.Config `yaml:"signer"`
+}
+
+// DefaultConfig leaves attestations disabled
+func DefaultConfig() Config {
+	return Config{Signer: signer.DefaultConfig()}
+}
+
+// Validate checks the config for values results cannot be attested with
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if err := c.Signer.Validate(); err != nil {
+		return fmt.Errorf("signer: %w", err)
+	}
+	return nil
+}
+
+// Source is a data source the operator reads from, identified by the hash of its endpoint
+// so auditors it discloses the endpoint to can check it, while URLs carrying API keys are
+// never published
+type Source struct {
+	Name string      `json:"name"`
+	Hash common.Hash `json:"hash"`
+}
+
+// NewSource identifies the source name served at endpoint
+func NewSource(name, endpoint string) Source {
+	return Source{Name: name, Hash: crypto.Keccak256Hash([]byte(endpoint))}
+}
+
+// Attestation is an operator's signed statement that it produced a result
+type Attestation struct {
+	Operator   common.Address `json:"operator"`
+	Version    string         `json:"version"`
+	TaskID     string         `json:"task_id"`
+	ResultHash common.Hash    `json:"result_hash"`
+	Sources    []Source       `json:"sources"`
+
+	// Timestamp is the unix time the result was attested at
+	Timestamp int64         `json:"timestamp"`
+	Signature hexutil.Bytes `json:"signature,omitempty"`
+}
+
+// message returns the bytes the signature is made over
+func (a *Attestation) message() ([]byte, error) {
+	unsigned := *a
+	unsigned.Signature = nil
+	encoded, err := canonical.Marshal(&unsigned)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode attestation: %w", err)
+	}
+	return crypto.Keccak256(encoded), nil
+}
+
+// Verify checks that the attestation covers result and was signed by its operator
+func (a *Attestation) Verify(result []byte) error {
+	if crypto.Keccak256Hash(result) != a.ResultHash {
+		return fmt.Errorf("%w: result hash does not match", ErrInvalidAttestation)
+	}
+	if len(a.Signature) != crypto.SignatureLength {
+		return fmt.Errorf("%w: malformed signature", ErrInvalidAttestation)
+	}
+	message, err := a.message()
+	if err != nil {
+		return err
+	}
+	pub, err := crypto.SigToPub(accounts.TextHash(message), a.Signature)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidAttestation, err)
+	}
+	if from := crypto.PubkeyToAddress(*pub); from != a.Operator {
+		return fmt.Errorf("%w: signed by %s, not operator %s", ErrInvalidAttestation, from.Hex(), a.Operator.Hex())
+	}
+	return nil
+}
+
+// Attester attests results with an operator key
+type Attester struct {
+	signer  signer.Signer
+	version string
+	sources []Source
+	now     func() time.Time
+}
+
+// New creates an attester signing with s, reporting the performer version and the
+// sources it reads from
+func New(s signer.Signer, version string, sources []Source) *Attester {
+	sorted := make([]Source, len(sources))
+	copy(sorted, sources)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	return &Attester{signer: s, version: version, sources: sorted, now: time.Now}
+}
+
+// NewFromConfig creates an attester with the signer in cfg, or returns nil when
+// attestations are disabled. Requests to remote signers are retried under policy, which
+// may be nil.
+func NewFromConfig(ctx context.Context, cfg Config, version string, sources []Source, policy *resilience.Policy) (*Attester, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	s, err := signer.New(ctx, cfg.Signer, policy)
+	if err != nil {
+		return nil, err
+	}
+	return New(s, version, sources), nil
+}
+
+// Operator is the address results are attested by
+func (a *Attester) Operator() common.Address {
+	return a.signer.Address()
+}
+
+// Attest signs an attestation that the operator produced result for taskID
+func (a *Attester) Attest(ctx context.Context, taskID string, result []byte) (*Attestation, error) {
+	attestation := &Attestation{
+		Operator:   a.signer.Address(),
+		Version:    a.version,
+		TaskID:     taskID,
+		ResultHash: crypto.Keccak256Hash(result),
+		Sources:    a.sources,
+		Timestamp:  a.now().Unix(),
+	}
+	message, err := attestation.message()
+	if err != nil {
+		return nil, err
+	}
+	sig, err := a.signer.SignMessage(ctx, message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign attestation: %w", err)
+	}
+	attestation.Signature = sig
+	return attestation, nil
+}
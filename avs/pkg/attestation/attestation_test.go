@@ -0,0 +1,65 @@
+package attestation
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/najnomics/crosscow-avs/pkg/signer"
+)
+
+func newTestAttester(t *testing.T) *Attester {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	a := New(signer.NewKeySigner(key), "v1.2.3", []Source{
+		NewSource("rpc:8453", "https://base.example/key"),
+		NewSource("rpc:1", "https://ethereum.example/key"),
+	})
+	a.now = func() time.Time { return time.Unix(1_700_000_000, 0) }
+	return a
+}
+
+func Test_AttestAndVerify(t *testing.T) {
+	a := newTestAttester(t)
+	result := []byte(`{"result":{"status":"completed"},"task_type":"yield_monitoring"}`)
+
+	attestation, err := a.Attest(context.Background(), "task-1", result)
+	if err != nil {
+		t.Fatalf("Attest failed: %v", err)
+	}
+	if attestation.Operator != a.Operator() || attestation.Version != "v1.2.3" || attestation.TaskID != "task-1" || attestation.Timestamp != 1_700_000_000 {
+		t.Errorf("Unexpected attestation: %+v", attestation)
+	}
+	if len(attestation.Sources) != 2 || attestation.Sources[0].Name != "rpc:1" {
+		t.Errorf("Expected sources sorted by name, got %+v", attestation.Sources)
+	}
+	if err := attestation.Verify(result); err != nil {
+		t.Errorf("Verify failed: %v", err)
+	}
+
+	if err := attestation.Verify([]byte(`{}`)); !errors.Is(err, ErrInvalidAttestation) {
+		t.Errorf("Expected another result to be rejected, got %v", err)
+	}
+	tampered := *attestation
+	tampered.TaskID = "task-2"
+	if err := tampered.Verify(result); !errors.Is(err, ErrInvalidAttestation) {
+		t.Errorf("Expected a tampered attestation to be rejected, got %v", err)
+	}
+}
+
+func Test_ConfigValidate(t *testing.T) {
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Errorf("Expected the default config to be valid: %v", err)
+	}
+	cfg := DefaultConfig()
+	cfg.Enabled = true
+	cfg.Signer.Type = "ledger"
+	if err := cfg.Validate(); err == nil {
+		t.Errorf("Expected an unknown signer type to be rejected")
+	}
+}
@@ -0,0 +1,179 @@
+// Package auth verifies that tasks were submitted by an authorized party, such as the
+// service manager or an operator of the hook, so unsolicited or spoofed tasks can be
+// rejected before they run.
+//
+// A signed task wraps its payload in an Envelope. The signature is a 65 byte secp256k1
+// signature of Digest(payload), the EIP-191 personal message hash of the keccak256 hash
+// of the payload bytes, which is what wallets produce when asked to sign that hash.
+package auth
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// RequireAll in Config.Required makes every task type require a signature
+const RequireAll = "*"
+
+var (
+	// ErrUnsigned is returned for tasks that must be signed and are not
+	ErrUnsigned = errors.New("task is not signed")
+
+	// ErrInvalidSignature is returned for signatures that are malformed or not made by a
+	// configured signer
+	ErrInvalidSignature = errors.New("task signature is invalid")
+)
+
+// Envelope is a signed task payload. Payload is kept as sent, since the signature covers
+// its exact bytes.
+type Envelope struct {
+	Payload   json.RawMessage `json:"payload"`
+	Signature string          `json:"signature"`
+}
+
+// Digest returns the hash a task payload is signed over
+func Digest(payload []byte) []byte {
+	return accounts.TextHash(crypto.Keccak256(payload))
+}
+
+// Sign wraps payload in an envelope signed with key. The payload is compacted first, as
+// it is once embedded in the envelope.
+func Sign(payload []byte, key *ecdsa.PrivateKey) ([]byte, error) {
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, payload); err != nil {
+		return nil, fmt.Errorf("failed to sign task: %w", err)
+	}
+	payload = compact.Bytes()
+	sig, err := crypto.Sign(Digest(payload), key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign task: %w", err)
+	}
+	return json.Marshal(Envelope{Payload: payload, Signature: hexutil.Encode(sig)})
+}
+
+// Unwrap returns the payload of a task and its signature. Payloads that are not wrapped
+// in an envelope are returned as they are, with a nil signature.
+func Unwrap(raw []byte) ([]byte, []byte, error) {
+	var envelope struct {
+		Payload   json.RawMessage `json:"payload"`
+		Signature *string         `json:"signature"`
+	}
+	if err := json.Unmarshal(raw, &envelope); err != nil {
+		return nil, nil, err
+	}
+	if envelope.Signature == nil {
+		return raw, nil, nil
+	}
+	if len(envelope.Payload) == 0 {
+		return nil, nil, fmt.Errorf("signed task has no payload")
+	}
+	sig, err := hexutil.Decode(*envelope.Signature)
+	if err != nil || len(sig) != crypto.SignatureLength {
+		return nil, nil, fmt.Errorf("%w: malformed signature", ErrInvalidSignature)
+	}
+	return envelope.Payload, sig, nil
+}
+
+// Config configures task authorization
+type Config struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Signers are the addresses tasks may be signed by
+	Signers []string `yaml:"signers"`
+
+	// Required lists the task types that are rejected unless signed, RequireAll for every
+	// type. Signed tasks of other types are verified all the same.
+	Required []string `yaml:"required"`
+}
+
+// Validate checks the config for values authorization cannot run with
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if len(c.Signers) == 0 {
+		return fmt.Errorf("signers are required")
+	}
+	for i, signer := range c.Signers {
+		if !common.IsHexAddress(signer) {
+			return fmt.Errorf("signers[%d] is not an address", i)
+		}
+	}
+	for i, taskType := range c.Required {
+		if taskType == "" {
+			return fmt.Errorf("required[%d] is empty", i)
+		}
+	}
+	return nil
+}
+
+// Verifier checks task signatures against the configured signers. A nil Verifier
+// requires no signatures and accepts any.
+type Verifier struct {
+	signers  map[common.Address]bool
+	required map[string]bool
+}
+
+// NewVerifier creates a verifier of the signers in cfg
+func NewVerifier(cfg Config) *Verifier {
+	v := &Verifier{
+		signers:  make(map[common.Address]bool, len(cfg.Signers)),
+		required: make(map[string]bool, len(cfg.Required)),
+	}
+	for _, signer := range cfg.Signers {
+		v.signers[common.HexToAddress(signer)] = true
+	}
+	for _, taskType := range cfg.Required {
+		v.required[taskType] = true
+	}
+	return v
+}
+
+// NewFromConfig creates the verifier enabled in cfg, nil when it is disabled
+func NewFromConfig(cfg Config) *Verifier {
+	if !cfg.Enabled {
+		return nil
+	}
+	return NewVerifier(cfg)
+}
+
+// Requires reports whether tasks of taskType must be signed
+func (v *Verifier) Requires(taskType string) bool {
+	if v == nil {
+		return false
+	}
+	return v.required[RequireAll] || v.required[taskType]
+}
+
+// Verify checks that signature of payload was made by a configured signer, and returns
+// the signer
+func (v *Verifier) Verify(payload, signature []byte) (common.Address, error) {
+	if len(signature) != crypto.SignatureLength {
+		return common.Address{}, fmt.Errorf("%w: malformed signature", ErrInvalidSignature)
+	}
+	sig := common.CopyBytes(signature)
+	// wallets produce recovery ids of 27 and 28
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	pub, err := crypto.SigToPub(Digest(payload), sig)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	signer := crypto.PubkeyToAddress(*pub)
+	if v == nil {
+		return signer, nil
+	}
+	if !v.signers[signer] {
+		return common.Address{}, fmt.Errorf("%w: %s is not an authorized signer", ErrInvalidSignature, signer.Hex())
+	}
+	return signer, nil
+}
@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const task = `{"type":"rebalance_execution","parameters":{"amount":1000}}`
+
+func Test_VerifySignedTask(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	other, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	signer := crypto.PubkeyToAddress(key.PublicKey)
+	v := NewVerifier(Config{Enabled: true, Signers: []string{signer.Hex()}, Required: []string{"rebalance_execution"}})
+
+	signed, err := Sign([]byte(task), key)
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	payload, signature, err := Unwrap(signed)
+	if err != nil || string(payload) != task {
+		t.Fatalf("Expected the payload to be unwrapped as signed, got %s (%v)", payload, err)
+	}
+	if got, err := v.Verify(payload, signature); err != nil || got != signer {
+		t.Errorf("Expected the task to be signed by %s, got %s (%v)", signer.Hex(), got.Hex(), err)
+	}
+
+	// wallets add 27 to the recovery id
+	signature[crypto.RecoveryIDOffset] += 27
+	if _, err := v.Verify(payload, signature); err != nil {
+		t.Errorf("Expected a wallet signature to verify: %v", err)
+	}
+
+	tampered := []byte(`{"type":"rebalance_execution","parameters":{"amount":9000}}`)
+	if _, err := v.Verify(tampered, signature); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected a tampered payload to be rejected, got %v", err)
+	}
+	spoofed, err := Sign([]byte(task), other)
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	payload, signature, _ = Unwrap(spoofed)
+	if _, err := v.Verify(payload, signature); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected an unknown signer to be rejected, got %v", err)
+	}
+}
+
+func Test_Unwrap(t *testing.T) {
+	payload, signature, err := Unwrap([]byte(task))
+	if err != nil || signature != nil || string(payload) != task {
+		t.Errorf("Expected an unsigned payload to be returned as is, got %s, %x (%v)", payload, signature, err)
+	}
+
+	malformed, _ := json.Marshal(Envelope{Payload: json.RawMessage(task), Signature: hexutil.Encode([]byte{1, 2, 3})})
+	if _, _, err := Unwrap(malformed); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected a malformed signature to be rejected, got %v", err)
+	}
+	if _, _, err := Unwrap([]byte(`{"signature":"0x"}`)); err == nil {
+		t.Errorf("Expected an envelope without payload to be rejected")
+	}
+}
+
+func Test_Requires(t *testing.T) {
+	var disabled *Verifier
+	if disabled.Requires("rebalance_execution") {
+		t.Errorf("Expected a nil verifier to require no signatures")
+	}
+	v := NewVerifier(Config{Required: []string{"rebalance_execution"}})
+	if !v.Requires("rebalance_execution") || v.Requires("yield_monitoring") {
+		t.Errorf("Expected only rebalances to require signatures")
+	}
+	if all := NewVerifier(Config{Required: []string{RequireAll}}); !all.Requires("yield_monitoring") {
+		t.Errorf("Expected every task type to require signatures")
+	}
+}
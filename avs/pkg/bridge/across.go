@@ -0,0 +1,96 @@
+package bridge
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+)
+
+// Across is the intent bridge rebalances fall back to while CCTP attestations are delayed
+const Across = "across"
+
+// acrossSpokePools are the Across SpokePool contracts deposits are sent to, per chain
+var acrossSpokePools = map[uint64]common.Address{
+	1:     common.HexToAddress("0x5c7BCd6E7De5423a257D81B442095A1a6ced35C5"),
+	8453:  common.HexToAddress("0x09aea4b2242abC8bb4BB78D537A67a245A7bEC64"),
+	42161: common.HexToAddress("0xe35e9842fceaCA96570B734083f4a58e8F7C5f2A"),
+}
+
+const spokePoolABIJson = `[
+	{"name":"depositV3","type":"function","stateMutability":"payable",
+	 "inputs":[
+		{"name":"depositor","type":"address"},
+		{"name":"recipient","type":"address"},
+		{"name":"inputToken","type":"address"},
+		{"name":"outputToken","type":"address"},
+		{"name":"inputAmount","type":"uint256"},
+		{"name":"outputAmount","type":"uint256"},
+		{"name":"destinationChainId","type":"uint256"},
+		{"name":"exclusiveRelayer","type":"address"},
+		{"name":"quoteTimestamp","type":"uint32"},
+		{"name":"fillDeadline","type":"uint32"},
+		{"name":"exclusivityDeadline","type":"uint32"},
+		{"name":"message","type":"bytes"}
+	 ],
+	 "outputs":[]}
+]`
+
+var spokePoolABI = chain.MustParseABI(spokePoolABIJson)
+
+// AcrossSpokePool returns the SpokePool of chainID
+func AcrossSpokePool(chainID uint64) (common.Address, error) {
+	pool, ok := acrossSpokePools[chainID]
+	if !ok {
+		return common.Address{}, fmt.Errorf("chain %d is not supported by Across", chainID)
+	}
+	return pool, nil
+}
+
+// AcrossDeposit is USDC deposited on a source chain for any relayer to fill on the
+// destination chain. The relayer keeps InputAmount less OutputAmount as its fee.
+type AcrossDeposit struct {
+	SourceChainID      uint64
+	DestinationChainID uint64
+	Depositor          common.Address
+	Recipient          common.Address
+	InputToken         common.Address
+	OutputToken        common.Address
+	InputAmount        *big.Int
+	OutputAmount       *big.Int
+
+	// QuoteTimestamp dates the fee, and must be close to the time of the deposit. Deposits
+	// not filled by FillDeadline are refunded to the depositor on the source chain.
+	QuoteTimestamp uint32
+	FillDeadline   uint32
+}
+
+// AcrossDepositCall builds the SpokePool call depositing d on its source chain
+func AcrossDepositCall(d *AcrossDeposit) (*chain.Call, error) {
+	pool, err := AcrossSpokePool(d.SourceChainID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := AcrossSpokePool(d.DestinationChainID); err != nil {
+		return nil, err
+	}
+	data, err := spokePoolABI.Pack("depositV3",
+		d.Depositor,
+		d.Recipient,
+		d.InputToken,
+		d.OutputToken,
+		d.InputAmount,
+		d.OutputAmount,
+		new(big.Int).SetUint64(d.DestinationChainID),
+		common.Address{},
+		d.QuoteTimestamp,
+		d.FillDeadline,
+		uint32(0),
+		[]byte{},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack depositV3: %w", err)
+	}
+	return &chain.Call{To: pool, Data: data}, nil
+}
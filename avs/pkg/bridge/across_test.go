@@ -0,0 +1,43 @@
+package bridge
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func Test_AcrossDepositCall(t *testing.T) {
+	account := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	deposit := &AcrossDeposit{
+		SourceChainID:      8453,
+		DestinationChainID: 1,
+		Depositor:          account,
+		Recipient:          account,
+		InputToken:         common.HexToAddress("0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913"),
+		OutputToken:        common.HexToAddress("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48"),
+		InputAmount:        big.NewInt(1_000_000),
+		OutputAmount:       big.NewInt(999_500),
+		QuoteTimestamp:     1_700_000_000,
+		FillDeadline:       1_700_021_600,
+	}
+	call, err := AcrossDepositCall(deposit)
+	if err != nil {
+		t.Fatalf("AcrossDepositCall failed: %v", err)
+	}
+	if call.To != acrossSpokePools[8453] {
+		t.Errorf("Expected the deposit to go to the Base SpokePool, got %s", call.To.Hex())
+	}
+	args, err := spokePoolABI.Methods["depositV3"].Inputs.Unpack(call.Data[4:])
+	if err != nil {
+		t.Fatalf("Failed to unpack call: %v", err)
+	}
+	if args[5].(*big.Int).Int64() != 999_500 || args[6].(*big.Int).Uint64() != 1 || args[9].(uint32) != 1_700_021_600 {
+		t.Errorf("Unexpected deposit arguments %v", args)
+	}
+
+	deposit.DestinationChainID = 137
+	if _, err := AcrossDepositCall(deposit); err == nil {
+		t.Errorf("Expected a chain without a SpokePool to fail")
+	}
+}
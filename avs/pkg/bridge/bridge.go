@@ -0,0 +1,392 @@
+// Package bridge compares the routes USDC can take between two chains: CCTP v2 where
+// Circle supports both chains, and the canonical bridges of chains CCTP does not reach or
+// alternatives to it. Every route is scored on its fee, latency and trust model from 0
+// (no concern) to 100, and routes are ranked by the weighted mean of the three.
+//
+// It also builds the Across deposits rebalances fall back to while Circle's attestations
+// of CCTP burns are delayed.
+package bridge
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/najnomics/crosscow-avs/pkg/cctp"
+	"github.com/najnomics/crosscow-avs/pkg/tokens"
+)
+
+// Names of the CCTP routes, which are derived from the CCTP domains rather than configured
+const (
+	CCTPStandard = "cctp_v2_standard"
+	CCTPFast     = "cctp_v2_fast"
+)
+
+// CCTPBridge returns the name of the CCTP route of speed
+func CCTPBridge(speed cctp.Speed) string {
+	if speed == cctp.SpeedFast {
+		return CCTPFast
+	}
+	return CCTPStandard
+}
+
+// Trust is who a transfer relies on to deliver the funds
+type Trust string
+
+const (
+	// TrustIssuer routes rely on Circle only, which issues USDC on both chains anyway
+	TrustIssuer Trust = "issuer"
+
+	// TrustRollup routes rely on the proof system of a rollup, and on the bridge holding
+	// the USDC backing a bridged variant
+	TrustRollup Trust = "rollup"
+
+	// TrustOracle routes rely on relayers fronting the funds and an optimistic oracle
+	// settling their repayment, as Across does
+	TrustOracle Trust = "oracle"
+
+	// TrustValidators routes rely on a validator set or multisig signing off withdrawals
+	TrustValidators Trust = "validators"
+)
+
+// trustScores are the scores of each trust model
+var trustScores = map[Trust]int64{
+	TrustIssuer:     0,
+	TrustRollup:     30,
+	TrustOracle:     45,
+	TrustValidators: 60,
+}
+
+// Dimension names a scored aspect of a route
+type Dimension string
+
+const (
+	DimensionFee     Dimension = "fee"
+	DimensionLatency Dimension = "latency"
+	DimensionTrust   Dimension = "trust"
+)
+
+// RouteConfig is a bridge moving USDC from one chain to another
+type RouteConfig struct {
+	Bridge             string `yaml:"bridge"`
+	SourceChainID      uint64 `yaml:"sourceChainId"`
+	DestinationChainID uint64 `yaml:"destinationChainId"`
+
+	// FeeBps is the fee the bridge takes, in basis points of the amount. Gas is not
+	// included.
+	FeeBps uint64 `yaml:"feeBps"`
+
+	// Latency is the usual time between sending on the source chain and the funds being
+	// spendable on the destination chain
+	Latency time.Duration `yaml:"latency"`
+	Trust   Trust         `yaml:"trust"`
+
+	// Token is the USDC the destination chain receives: native USDC, or a variant the
+	// bridge mints
+	Token tokens.Kind `yaml:"token"`
+}
+
+// Validate checks the route for values it cannot be scored with
+func (r RouteConfig) Validate() error {
+	if r.Bridge == "" {
+		return fmt.Errorf("bridge is required")
+	}
+	if r.Bridge == CCTPStandard || r.Bridge == CCTPFast {
+		return fmt.Errorf("bridge %s is derived from the CCTP domains and cannot be configured", r.Bridge)
+	}
+	if r.SourceChainID == 0 || r.DestinationChainID == 0 {
+		return fmt.Errorf("sourceChainId and destinationChainId are required")
+	}
+	if r.SourceChainID == r.DestinationChainID {
+		return fmt.Errorf("sourceChainId and destinationChainId must differ")
+	}
+	if r.Latency <= 0 {
+		return fmt.Errorf("latency must be positive")
+	}
+	if _, ok := trustScores[r.Trust]; !ok {
+		return fmt.Errorf("unknown trust %q, must be %s, %s, %s or %s", r.Trust, TrustIssuer, TrustRollup, TrustOracle, TrustValidators)
+	}
+	if r.Token != tokens.KindNative && r.Token != tokens.KindBridged {
+		return fmt.Errorf("unknown token %q, must be %s or %s", r.Token, tokens.KindNative, tokens.KindBridged)
+	}
+	return nil
+}
+
+// Config sets the routes compared with CCTP and how routes are scored
+type Config struct {
+	Enabled bool `yaml:"enabled"`
+
+	// CCTPFastFeeBps is the fee Circle charges for fast transfers, in basis points.
+	// Standard transfers are free.
+	CCTPFastFeeBps uint64 `yaml:"cctpFastFeeBps"`
+
+	// MaxFeeBps is the fee that scores 100 and MaxLatency the latency that does, both
+	// scoring linearly from 0
+	MaxFeeBps  uint64        `yaml:"maxFeeBps"`
+	MaxLatency time.Duration `yaml:"maxLatency"`
+
+	// Weights weigh the dimensions in the overall score of a route
+	Weights map[Dimension]int64 `yaml:"weights"`
+
+	// Routes are the bridges other than CCTP. They replace the default canonical bridges
+	// when set.
+	Routes []RouteConfig `yaml:"routes"`
+
+	// Fallback moves rebalances to another bridge while CCTP attestations are delayed
+	Fallback FallbackConfig `yaml:"fallback"`
+}
+
+// FallbackConfig lets rebalances bridge through Across instead of CCTP once a burn of
+// the performer waited longer than AttestationSLA for Circle's attestation, rather than
+// burning more funds that would be stuck until Circle catches up
+type FallbackConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// AttestationSLA is how long a burn may wait for its attestation
+	AttestationSLA time.Duration `yaml:"attestationSla"`
+
+	// AttestationURL is Circle's attestation service, that of the configured network when
+	// empty
+	AttestationURL string `yaml:"attestationUrl"`
+
+	// Bridge is the bridge fallen back to. Only Across is supported.
+	Bridge string `yaml:"bridge"`
+
+	// FeeBps is the relayer fee offered, in basis points of the amount. Rebalances whose
+	// max slippage is below it keep waiting for CCTP.
+	FeeBps uint64 `yaml:"feeBps"`
+
+	// FillTime is the usual time for a relayer to fill a deposit, and FillDeadline how
+	// long after the deposit an unfilled one is refunded
+	FillTime     time.Duration `yaml:"fillTime"`
+	FillDeadline time.Duration `yaml:"fillDeadline"`
+}
+
+// DefaultFallbackConfig falls back to Across once a burn waited an hour, three times the
+// upper bound of standard attestations, offering relayers 5 bps. Disabled by default.
+func DefaultFallbackConfig() FallbackConfig {
+	return FallbackConfig{
+		AttestationSLA: time.Hour,
+		Bridge:         Across,
+		FeeBps:         5,
+		FillTime:       2 * time.Minute,
+		FillDeadline:   6 * time.Hour,
+	}
+}
+
+// Validate checks the config for values the fallback cannot run with
+func (c FallbackConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.AttestationSLA <= 0 {
+		return fmt.Errorf("attestationSla must be positive")
+	}
+	if c.Bridge != Across {
+		return fmt.Errorf("unsupported bridge %q, must be %s", c.Bridge, Across)
+	}
+	if c.FeeBps >= 10_000 {
+		return fmt.Errorf("feeBps must be below 10000")
+	}
+	if c.FillTime <= 0 || c.FillDeadline <= c.FillTime {
+		return fmt.Errorf("fillTime must be positive and below fillDeadline")
+	}
+	return nil
+}
+
+// DefaultConfig weighs fees and trust above latency, scores the seven day challenge period
+// of optimistic rollups as the longest latency, and knows the canonical bridges between
+// Ethereum and Polygon PoS, Optimism, Arbitrum and Base
+func DefaultConfig() Config {
+	return Config{
+		Enabled:        true,
+		CCTPFastFeeBps: 1,
+		MaxFeeBps:      50,
+		MaxLatency:     7 * 24 * time.Hour,
+		Weights: map[Dimension]int64{
+			DimensionFee:     40,
+			DimensionLatency: 20,
+			DimensionTrust:   40,
+		},
+		Routes:   canonicalRoutes(),
+		Fallback: DefaultFallbackConfig(),
+	}
+}
+
+// canonicalRoutes are the native bridges of the chains. Deposits mint a bridged variant
+// once the deposit is included on the destination chain; withdrawals unlock native USDC
+// on Ethereum after the Polygon checkpoint or the rollup challenge period.
+func canonicalRoutes() []RouteConfig {
+	const week = 7 * 24 * time.Hour
+	return []RouteConfig{
+		{Bridge: "polygon_pos", SourceChainID: 1, DestinationChainID: 137, Latency: 30 * time.Minute, Trust: TrustValidators, Token: tokens.KindBridged},
+		{Bridge: "polygon_pos", SourceChainID: 137, DestinationChainID: 1, Latency: 3 * time.Hour, Trust: TrustValidators, Token: tokens.KindNative},
+		{Bridge: "optimism_standard", SourceChainID: 1, DestinationChainID: 10, Latency: 3 * time.Minute, Trust: TrustRollup, Token: tokens.KindBridged},
+		{Bridge: "optimism_standard", SourceChainID: 10, DestinationChainID: 1, Latency: week, Trust: TrustRollup, Token: tokens.KindNative},
+		{Bridge: "arbitrum_canonical", SourceChainID: 1, DestinationChainID: 42161, Latency: 15 * time.Minute, Trust: TrustRollup, Token: tokens.KindBridged},
+		{Bridge: "arbitrum_canonical", SourceChainID: 42161, DestinationChainID: 1, Latency: week, Trust: TrustRollup, Token: tokens.KindNative},
+		{Bridge: "base_standard", SourceChainID: 1, DestinationChainID: 8453, Latency: 3 * time.Minute, Trust: TrustRollup, Token: tokens.KindBridged},
+		{Bridge: "base_standard", SourceChainID: 8453, DestinationChainID: 1, Latency: week, Trust: TrustRollup, Token: tokens.KindNative},
+	}
+}
+
+// Validate checks the config for values routes cannot be scored with
+func (c Config) Validate() error {
+	if err := c.Fallback.Validate(); err != nil {
+		return fmt.Errorf("fallback: %w", err)
+	}
+	if !c.Enabled {
+		return nil
+	}
+	if c.MaxFeeBps == 0 {
+		return fmt.Errorf("maxFeeBps must be positive")
+	}
+	if c.MaxLatency <= 0 {
+		return fmt.Errorf("maxLatency must be positive")
+	}
+	total := int64(0)
+	for dimension, weight := range c.Weights {
+		switch dimension {
+		case DimensionFee, DimensionLatency, DimensionTrust:
+		default:
+			return fmt.Errorf("unknown weight %q, must be %s, %s or %s", dimension, DimensionFee, DimensionLatency, DimensionTrust)
+		}
+		if weight < 0 {
+			return fmt.Errorf("weight of %s must not be negative", dimension)
+		}
+		total += weight
+	}
+	if total == 0 {
+		return fmt.Errorf("weights must not all be zero")
+	}
+	seen := make(map[string]bool, len(c.Routes))
+	for i, route := range c.Routes {
+		if err := route.Validate(); err != nil {
+			return fmt.Errorf("routes[%d]: %w", i, err)
+		}
+		key := fmt.Sprintf("%s/%d/%d", route.Bridge, route.SourceChainID, route.DestinationChainID)
+		if seen[key] {
+			return fmt.Errorf("routes[%d]: duplicate %s route from chain %d to chain %d", i, route.Bridge, route.SourceChainID, route.DestinationChainID)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// Route is a scored way of moving an amount of USDC between two chains
+type Route struct {
+	RouteConfig
+
+	// Fee is the fee on the amount, in USDC base units
+	Fee *big.Int
+
+	// Scores holds the score of every dimension and Score their weighted mean
+	Scores map[Dimension]*big.Rat
+	Score  *big.Rat
+}
+
+// Engine ranks the routes between chains
+type Engine struct {
+	cfg Config
+}
+
+// New creates an engine ranking CCTP and the routes of cfg
+func New(cfg Config) *Engine {
+	return &Engine{cfg: cfg}
+}
+
+// NewFromConfig creates an engine from cfg, nil when routes are not compared
+func NewFromConfig(cfg Config) *Engine {
+	if !cfg.Enabled {
+		return nil
+	}
+	return New(cfg)
+}
+
+// Routes returns the routes moving amount, in USDC base units, from source to
+// destination, best first. It is empty when no known bridge connects the chains.
+func (e *Engine) Routes(source, destination uint64, amount *big.Int) []Route {
+	if e == nil || source == destination {
+		return nil
+	}
+	var routes []Route
+	for _, cfg := range e.cctpRoutes(source, destination) {
+		routes = append(routes, e.score(cfg, amount))
+	}
+	for _, cfg := range e.cfg.Routes {
+		if cfg.SourceChainID == source && cfg.DestinationChainID == destination {
+			routes = append(routes, e.score(cfg, amount))
+		}
+	}
+	sort.SliceStable(routes, func(i, j int) bool {
+		if c := routes[i].Score.Cmp(routes[j].Score); c != 0 {
+			return c < 0
+		}
+		if routes[i].Latency != routes[j].Latency {
+			return routes[i].Latency < routes[j].Latency
+		}
+		return routes[i].Bridge < routes[j].Bridge
+	})
+	return routes
+}
+
+// cctpRoutes returns the standard and fast CCTP routes, none when CCTP does not support
+// both chains
+func (e *Engine) cctpRoutes(source, destination uint64) []RouteConfig {
+	if _, err := cctp.Domain(source); err != nil {
+		return nil
+	}
+	if _, err := cctp.Domain(destination); err != nil {
+		return nil
+	}
+	var routes []RouteConfig
+	for _, finality := range []uint32{cctp.FinalityStandard, cctp.FinalityFast} {
+		transfer := &cctp.Transfer{SourceChainID: source, DestinationChainID: destination, MinFinality: finality}
+		latency, err := cctp.AttestationTime(transfer)
+		if err != nil {
+			continue
+		}
+		route := RouteConfig{Bridge: CCTPStandard, SourceChainID: source, DestinationChainID: destination, Latency: latency, Trust: TrustIssuer, Token: tokens.KindNative}
+		if finality == cctp.FinalityFast {
+			route.Bridge, route.FeeBps = CCTPFast, e.cfg.CCTPFastFeeBps
+		}
+		routes = append(routes, route)
+	}
+	return routes
+}
+
+func (e *Engine) score(cfg RouteConfig, amount *big.Int) Route {
+	fee := new(big.Int)
+	if amount != nil {
+		fee.Mul(amount, new(big.Int).SetUint64(cfg.FeeBps))
+		fee.Quo(fee, big.NewInt(10_000))
+	}
+	scores := map[Dimension]*big.Rat{
+		DimensionFee:     capped(new(big.Rat).SetFrac(new(big.Int).SetUint64(cfg.FeeBps), new(big.Int).SetUint64(e.cfg.MaxFeeBps))),
+		DimensionLatency: capped(big.NewRat(int64(cfg.Latency), int64(e.cfg.MaxLatency))),
+		DimensionTrust:   big.NewRat(trustScores[cfg.Trust], 1),
+	}
+	sum, total := new(big.Rat), int64(0)
+	for dimension, score := range scores {
+		weight := e.cfg.Weights[dimension]
+		if weight <= 0 {
+			continue
+		}
+		sum.Add(sum, new(big.Rat).Mul(score, big.NewRat(weight, 1)))
+		total += weight
+	}
+	if total > 0 {
+		sum.Quo(sum, big.NewRat(total, 1))
+	}
+	return Route{RouteConfig: cfg, Fee: fee, Scores: scores, Score: sum}
+}
+
+// capped scores ratio, 1 scoring 100, and caps the score at 100
+func capped(ratio *big.Rat) *big.Rat {
+	score := ratio.Mul(ratio, big.NewRat(100, 1))
+	if score.Cmp(big.NewRat(100, 1)) > 0 {
+		return big.NewRat(100, 1)
+	}
+	return score
+}
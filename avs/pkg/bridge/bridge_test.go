@@ -0,0 +1,125 @@
+package bridge
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/najnomics/crosscow-avs/pkg/tokens"
+)
+
+func bridges(routes []Route) string {
+	names := make([]string, len(routes))
+	for i, route := range routes {
+		names[i] = route.Bridge
+	}
+	return strings.Join(names, ",")
+}
+
+func Test_RoutesBetweenCCTPChains(t *testing.T) {
+	e := New(DefaultConfig())
+
+	// 10,000 USDC from Ethereum to Base
+	routes := e.Routes(1, 8453, big.NewInt(10_000_000_000))
+	if got := bridges(routes); got != "cctp_v2_standard,cctp_v2_fast,base_standard" {
+		t.Fatalf("Unexpected routes %s", got)
+	}
+	standard, fast, canonical := routes[0], routes[1], routes[2]
+	if standard.Latency != 19*time.Minute || standard.Fee.Sign() != 0 || standard.Token != tokens.KindNative {
+		t.Errorf("Unexpected standard transfer %+v", standard.RouteConfig)
+	}
+	if fast.Latency != 20*time.Second || fast.Fee.Cmp(big.NewInt(1_000_000)) != 0 {
+		t.Errorf("Expected a fast transfer in 20s for 1 USDC, got %s for %s", fast.Latency, fast.Fee)
+	}
+	// 1 bps of a 50 bps maximum scores 2
+	if fast.Scores[DimensionFee].Cmp(big.NewRat(2, 1)) != 0 {
+		t.Errorf("Expected a fee score of 2, got %s", fast.Scores[DimensionFee].FloatString(4))
+	}
+	if canonical.Trust != TrustRollup || canonical.Token != tokens.KindBridged || canonical.Scores[DimensionTrust].Cmp(big.NewRat(30, 1)) != 0 {
+		t.Errorf("Unexpected canonical deposit %+v", canonical.RouteConfig)
+	}
+
+	// the challenge period scores the maximum latency
+	routes = e.Routes(8453, 1, big.NewInt(10_000_000_000))
+	last := routes[len(routes)-1]
+	if last.Bridge != "base_standard" || last.Scores[DimensionLatency].Cmp(big.NewRat(100, 1)) != 0 || last.Token != tokens.KindNative {
+		t.Errorf("Expected the withdrawal to score the maximum latency last, got %s", bridges(routes))
+	}
+}
+
+func Test_RoutesWithoutCCTP(t *testing.T) {
+	e := New(DefaultConfig())
+
+	routes := e.Routes(1, 137, big.NewInt(1_000_000))
+	if got := bridges(routes); got != "polygon_pos" {
+		t.Fatalf("Expected only the Polygon PoS bridge, got %s", got)
+	}
+	// (20 * 0.2976 latency + 40 * 60 trust) / 100
+	if got := routes[0].Score.FloatString(4); got != "24.0595" {
+		t.Errorf("Unexpected score %s", got)
+	}
+
+	for _, pair := range [][2]uint64{{1, 1}, {137, 42161}, {1, 324}} {
+		if routes := e.Routes(pair[0], pair[1], big.NewInt(1_000_000)); len(routes) != 0 {
+			t.Errorf("Expected no route from %d to %d, got %s", pair[0], pair[1], bridges(routes))
+		}
+	}
+	var disabled *Engine
+	if routes := disabled.Routes(1, 8453, big.NewInt(1)); routes != nil {
+		t.Errorf("Expected no route without an engine")
+	}
+}
+
+func Test_ConfiguredRoutesAndWeights(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Weights = map[Dimension]int64{DimensionLatency: 1}
+	cfg.Routes = []RouteConfig{
+		{Bridge: "fast_bridge", SourceChainID: 42161, DestinationChainID: 8453, FeeBps: 5, Latency: 5 * time.Second, Trust: TrustValidators, Token: tokens.KindNative},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+
+	// scored on latency alone, the configured bridge beats CCTP
+	routes := New(cfg).Routes(42161, 8453, big.NewInt(1_000_000))
+	if got := bridges(routes); got != "fast_bridge,cctp_v2_fast,cctp_v2_standard" {
+		t.Errorf("Unexpected routes %s", got)
+	}
+	if routes := New(cfg).Routes(1, 8453, big.NewInt(1_000_000)); bridges(routes) != "cctp_v2_fast,cctp_v2_standard" {
+		t.Errorf("Expected configured routes to replace the canonical ones, got %s", bridges(routes))
+	}
+}
+
+func Test_ConfigValidate(t *testing.T) {
+	route := RouteConfig{Bridge: "hop", SourceChainID: 1, DestinationChainID: 10, Latency: time.Minute, Trust: TrustValidators, Token: tokens.KindBridged}
+	testCases := map[string]func(c *Config){
+		"fee":          func(c *Config) { c.MaxFeeBps = 0 },
+		"latency":      func(c *Config) { c.MaxLatency = 0 },
+		"zero weights": func(c *Config) { c.Weights = map[Dimension]int64{DimensionFee: 0} },
+		"weight":       func(c *Config) { c.Weights[DimensionTrust] = -1 },
+		"dimension":    func(c *Config) { c.Weights["gas"] = 10 },
+		"cctp":         func(c *Config) { r := route; r.Bridge = CCTPFast; c.Routes = []RouteConfig{r} },
+		"same chain":   func(c *Config) { r := route; r.DestinationChainID = 1; c.Routes = []RouteConfig{r} },
+		"trust":        func(c *Config) { r := route; r.Trust = "multisig"; c.Routes = []RouteConfig{r} },
+		"token":        func(c *Config) { r := route; r.Token = "wrapped"; c.Routes = []RouteConfig{r} },
+		"no latency":   func(c *Config) { r := route; r.Latency = 0; c.Routes = []RouteConfig{r} },
+		"duplicate":    func(c *Config) { c.Routes = []RouteConfig{route, route} },
+		"fallback":     func(c *Config) { c.Fallback.Enabled = true; c.Fallback.Bridge = "hop" },
+		"fill time":    func(c *Config) { c.Fallback.Enabled = true; c.Fallback.FillDeadline = time.Minute },
+		"sla":          func(c *Config) { c.Enabled = false; c.Fallback.Enabled = true; c.Fallback.AttestationSLA = 0 },
+	}
+	for name, mutate := range testCases {
+		cfg := DefaultConfig()
+		mutate(&cfg)
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Errorf("Expected the default config to be valid, got %v", err)
+	}
+	if err := (Config{}).Validate(); err != nil {
+		t.Errorf("Expected a disabled config to be valid, got %v", err)
+	}
+}
@@ -0,0 +1,192 @@
+// Package cache keeps task results for a short time, keyed by task type and normalized
+// parameters, so repeated queries within a block interval are answered without hitting
+// RPC providers again.
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/najnomics/crosscow-avs/pkg/redis"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// BackendMemory keeps entries in process memory
+const BackendMemory = "memory"
+
+// Backend stores cache entries with an expiry
+type Backend interface {
+	// Get returns the value of key, or false when it is missing or expired
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+
+	// Delete removes key, whether or not it is present
+	Delete(ctx context.Context, key string) error
+}
+
+// Config sets which task types are cached and for how long
+type Config struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Backend is memory, the default, or redis to share entries between replicas
+	Backend string `yaml:"backend"`
+
+	// MaxEntries bounds the memory backend, which the redis backend falls back to
+	MaxEntries int `yaml:"maxEntries"`
+
+	// TTLs holds the time to live of each cached task type. Task types that are not
+	// listed are never cached.
+	TTLs map[string]time.Duration `yaml:"ttls"`
+}
+
+// DefaultConfig caches market reads for about one Ethereum block and forecasts, which
+// change slowly, for five minutes. Tasks with side effects are never cached.
+func DefaultConfig() Config {
+	return Config{
+		Enabled:    true,
+		Backend:    BackendMemory,
+		MaxEntries: 10_000,
+		TTLs: map[string]time.Duration{
+			"yield_monitoring":         12 * time.Second,
+			"cross_chain_yield_check":  12 * time.Second,
+			"liquidity_depth_analysis": 12 * time.Second,
+			"depeg_monitoring":         12 * time.Second,
+			"apy_forecast":             5 * time.Minute,
+			"allocation_optimization":  12 * time.Second,
+			"yield_ranking":            12 * time.Second,
+			"full_market_snapshot":     12 * time.Second,
+		},
+	}
+}
+
+// Validate checks the config for values the cache cannot run with
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	switch c.Backend {
+	case "", BackendMemory, BackendRedis:
+		// the memory backend also holds entries while Redis is unavailable
+		if c.MaxEntries <= 0 {
+			return fmt.Errorf("maxEntries must be positive")
+		}
+	default:
+		return fmt.Errorf("unknown backend: %s", c.Backend)
+	}
+	for taskType, ttl := range c.TTLs {
+		if ttl < 0 {
+			return fmt.Errorf("ttls.%s must not be negative", taskType)
+		}
+	}
+	return nil
+}
+
+// Cache stores encoded task results. A nil cache never hits.
+type Cache struct {
+	cfg      Config
+	backend  Backend
+	requests *prometheus.CounterVec
+}
+
+// New creates a cache on backend. Hits and misses are counted per task type in the
+// yieldavs_result_cache_requests_total metric, registered with registerer when it is not nil.
+func New(cfg Config, backend Backend, registerer prometheus.Registerer) (*Cache, error) {
+	c := &Cache{
+		cfg:     cfg,
+		backend: backend,
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "yieldavs_result_cache_requests_total",
+			Help: "Result cache lookups by task type and outcome (hit or miss).",
+		}, []string{"task_type", "outcome"}),
+	}
+	if registerer != nil {
+		if err := registerer.Register(c.requests); err != nil {
+			return nil, fmt.Errorf("failed to register cache metrics: %w", err)
+		}
+	}
+	return c, nil
+}
+
+// NewFromConfig creates a cache with the backend selected in cfg. The redis backend
+// keeps entries in shared, and in memory while it is unavailable.
+func NewFromConfig(cfg Config, shared *redis.Client, registerer prometheus.Registerer) (*Cache, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	backend := Backend(NewMemoryBackend(cfg.MaxEntries))
+	if cfg.Backend == BackendRedis {
+		if shared == nil {
+			return nil, fmt.Errorf("the redis backend requires redis to be enabled")
+		}
+		backend = NewRedisBackend(shared, backend)
+	}
+	return New(cfg, backend, registerer)
+}
+
+// TTL returns how long results of taskType are cached, zero when they are not
+func (c *Cache) TTL(taskType string) time.Duration {
+	if c == nil {
+		return 0
+	}
+	return c.cfg.TTLs[taskType]
+}
+
+// Key derives the cache key of a task from its type and parameters. Parameters decoded
+// from JSON re-encode with sorted keys and shortest number formatting, so key order and
+// number spelling (1000.5 or 1.0005e3) do not matter.
+func Key(taskType string, params map[string]interface{}) (string, error) {
+	encoded, err := json.Marshal(params)
+	if err != nil {
+		return "", fmt.Errorf("failed to normalize parameters: %w", err)
+	}
+	sum := sha256.Sum256(encoded)
+	return "result:" + taskType + ":" + hex.EncodeToString(sum[:]), nil
+}
+
+// Get returns the cached result of a task. Backend errors count as misses.
+func (c *Cache) Get(ctx context.Context, taskType string, params map[string]interface{}) ([]byte, bool) {
+	if c.TTL(taskType) <= 0 {
+		return nil, false
+	}
+	key, err := Key(taskType, params)
+	if err != nil {
+		return nil, false
+	}
+	value, ok, err := c.backend.Get(ctx, key)
+	if err != nil || !ok {
+		c.requests.WithLabelValues(taskType, "miss").Inc()
+		return nil, false
+	}
+	c.requests.WithLabelValues(taskType, "hit").Inc()
+	return value, true
+}
+
+// Put caches the result of a task for the TTL of its type
+func (c *Cache) Put(ctx context.Context, taskType string, params map[string]interface{}, result []byte) error {
+	ttl := c.TTL(taskType)
+	if ttl <= 0 {
+		return nil
+	}
+	key, err := Key(taskType, params)
+	if err != nil {
+		return err
+	}
+	return c.backend.Set(ctx, key, result, ttl)
+}
+
+// Invalidate removes the cached result of a task, so the next identical task is computed
+// again
+func (c *Cache) Invalidate(ctx context.Context, taskType string, params map[string]interface{}) error {
+	if c.TTL(taskType) <= 0 {
+		return nil
+	}
+	key, err := Key(taskType, params)
+	if err != nil {
+		return err
+	}
+	return c.backend.Delete(ctx, key)
+}
@@ -0,0 +1,89 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func params(t *testing.T, raw string) map[string]interface{} {
+	t.Helper()
+	var p map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &p); err != nil {
+		t.Fatalf("Invalid params: %v", err)
+	}
+	return p
+}
+
+func Test_KeyIsNormalized(t *testing.T) {
+	a, err := Key("yield_monitoring", params(t, `{"protocol":"aave_v3","chain_id":1,"amount":1000.5}`))
+	if err != nil {
+		t.Fatalf("Key failed: %v", err)
+	}
+	b, _ := Key("yield_monitoring", params(t, `{"amount":1.0005e3,"chain_id":1.0,"protocol":"aave_v3"}`))
+	c, _ := Key("apy_forecast", params(t, `{"protocol":"aave_v3","chain_id":1,"amount":1000.5}`))
+	if a != b {
+		t.Errorf("Expected equivalent parameters to share a key")
+	}
+	if a == c {
+		t.Errorf("Expected task types to have separate keys")
+	}
+}
+
+func Test_CacheTTLAndMetrics(t *testing.T) {
+	backend := NewMemoryBackend(10)
+	now := time.Unix(0, 0)
+	backend.now = func() time.Time { return now }
+
+	registry := prometheus.NewRegistry()
+	c, err := New(DefaultConfig(), backend, registry)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	ctx := context.Background()
+	p := params(t, `{"protocol":"aave_v3","chain_id":1}`)
+
+	if _, ok := c.Get(ctx, "yield_monitoring", p); ok {
+		t.Fatalf("Expected an empty cache to miss")
+	}
+	if err := c.Put(ctx, "yield_monitoring", p, []byte("result")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if got, ok := c.Get(ctx, "yield_monitoring", p); !ok || string(got) != "result" {
+		t.Errorf("Expected a hit, got %q %v", got, ok)
+	}
+
+	now = now.Add(12 * time.Second)
+	if _, ok := c.Get(ctx, "yield_monitoring", p); ok {
+		t.Errorf("Expected the entry to expire after its TTL")
+	}
+
+	if err := c.Put(ctx, "rebalance_execution", p, []byte("result")); err != nil || backend.Len() != 0 {
+		t.Errorf("Expected task types without a TTL not to be cached")
+	}
+
+	hits := testutil.ToFloat64(c.requests.WithLabelValues("yield_monitoring", "hit"))
+	misses := testutil.ToFloat64(c.requests.WithLabelValues("yield_monitoring", "miss"))
+	if hits != 1 || misses != 2 {
+		t.Errorf("Expected 1 hit and 2 misses, got %v and %v", hits, misses)
+	}
+}
+
+func Test_MemoryBackendEvicts(t *testing.T) {
+	backend := NewMemoryBackend(2)
+	ctx := context.Background()
+	_ = backend.Set(ctx, "a", []byte("a"), time.Second)
+	_ = backend.Set(ctx, "b", []byte("b"), time.Minute)
+	_ = backend.Set(ctx, "c", []byte("c"), time.Minute)
+
+	if backend.Len() != 2 {
+		t.Errorf("Expected the backend to stay at its limit, got %d entries", backend.Len())
+	}
+	if _, ok, _ := backend.Get(ctx, "a"); ok {
+		t.Errorf("Expected the entry closest to expiry to be evicted")
+	}
+}
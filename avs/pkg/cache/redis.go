@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/najnomics/crosscow-avs/pkg/redis"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// BackendRedis keeps entries in the Redis shared by the replicas of a performer
+const BackendRedis = "redis"
+
+// RedisBackend keeps entries in a shared Redis, so a result one replica computed answers
+// identical tasks on the others. While Redis is unavailable, entries are kept in local
+// instead.
+type RedisBackend struct {
+	client *redis.Client
+	local  Backend
+}
+
+// NewRedisBackend creates a backend on client falling back to local
+func NewRedisBackend(client *redis.Client, local Backend) *RedisBackend {
+	return &RedisBackend{client: client, local: local}
+}
+
+func (r *RedisBackend) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	var value []byte
+	err := r.client.Do(ctx, func(ctx context.Context, rdb goredis.Cmdable) error {
+		var err error
+		value, err = rdb.Get(ctx, r.client.Key("cache", key)).Bytes()
+		return err
+	})
+	switch {
+	case err == nil:
+		return value, true, nil
+	case errors.Is(err, redis.Nil):
+		return nil, false, nil
+	default:
+		return r.local.Get(ctx, key)
+	}
+}
+
+func (r *RedisBackend) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	err := r.client.Do(ctx, func(ctx context.Context, rdb goredis.Cmdable) error {
+		return rdb.Set(ctx, r.client.Key("cache", key), value, ttl).Err()
+	})
+	if err != nil {
+		return r.local.Set(ctx, key, value, ttl)
+	}
+	return nil
+}
+
+// Delete removes key from Redis and from the local entries, which may hold it from
+// while Redis was unavailable
+func (r *RedisBackend) Delete(ctx context.Context, key string) error {
+	err := r.client.Do(ctx, func(ctx context.Context, rdb goredis.Cmdable) error {
+		return rdb.Del(ctx, r.client.Key("cache", key)).Err()
+	})
+	if localErr := r.local.Delete(ctx, key); localErr != nil {
+		return localErr
+	}
+	if errors.Is(err, redis.ErrUnavailable) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/najnomics/crosscow-avs/pkg/redis"
+)
+
+func Test_RedisBackendIsShared(t *testing.T) {
+	server := miniredis.RunT(t)
+	cfg := redis.DefaultConfig()
+	cfg.Enabled, cfg.Addr = true, server.Addr()
+	newReplica := func() *Cache {
+		client, err := redis.New(cfg, nil, nil)
+		if err != nil {
+			t.Fatalf("redis.New failed: %v", err)
+		}
+		t.Cleanup(func() { client.Close() })
+		c, err := NewFromConfig(Config{Enabled: true, Backend: BackendRedis, MaxEntries: 10, TTLs: map[string]time.Duration{"yield_monitoring": time.Minute}}, client, nil)
+		if err != nil {
+			t.Fatalf("NewFromConfig failed: %v", err)
+		}
+		return c
+	}
+	a, b := newReplica(), newReplica()
+	ctx := context.Background()
+	p := params(t, `{"protocol":"aave_v3","chain_id":1}`)
+
+	if err := a.Put(ctx, "yield_monitoring", p, []byte("result")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if got, ok := b.Get(ctx, "yield_monitoring", p); !ok || string(got) != "result" {
+		t.Errorf("Expected the other replica to hit, got %q", got)
+	}
+	if ttl := server.TTL(server.Keys()[0]); ttl != time.Minute {
+		t.Errorf("Expected the entry to expire with the TTL of its type, got %v", ttl)
+	}
+
+	// while Redis is down, each replica caches alone
+	server.Close()
+	if err := a.Put(ctx, "yield_monitoring", p, []byte("local")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if got, ok := a.Get(ctx, "yield_monitoring", p); !ok || string(got) != "local" {
+		t.Errorf("Expected the replica to fall back to its own entries, got %q", got)
+	}
+	if _, ok := b.Get(ctx, "yield_monitoring", p); ok {
+		t.Errorf("Expected the other replica not to see the local entry")
+	}
+
+	if _, err := NewFromConfig(Config{Enabled: true, Backend: BackendRedis, MaxEntries: 10}, nil, nil); err == nil {
+		t.Errorf("Expected the redis backend to require a client")
+	}
+}
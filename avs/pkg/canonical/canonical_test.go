@@ -0,0 +1,153 @@
+package canonical
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+type venue struct {
+	Protocol    string  `json:"protocol"`
+	ChainID     uint64  `json:"chain_id"`
+	SupplyAPY   Decimal `json:"supply_apy"`
+	Utilization Decimal `json:"utilization"`
+	TotalSupply string  `json:"total_supply"`
+}
+
+type monitoringResult struct {
+	Token    string            `json:"token"`
+	Venues   []venue           `json:"venues"`
+	Metadata map[string]string `json:"metadata"`
+	Note     string            `json:"note,omitempty"`
+	Healthy  bool              `json:"healthy"`
+}
+
+func sampleResult() monitoringResult {
+	return monitoringResult{
+		Token: "USDC",
+		Venues: []venue{
+			{Protocol: "aave_v3", ChainID: 1, SupplyAPY: APY(4.123456), Utilization: Ratio(0.8712345678), TotalSupply: "1500000000000"},
+			{Protocol: "compound_v3", ChainID: 8453, SupplyAPY: APY(5.00005), Utilization: Ratio(0.9), TotalSupply: "42000000"},
+		},
+		Metadata: map[string]string{"zeta": "<last>", "alpha": "first", "mid": "a&b"},
+		Healthy:  true,
+	}
+}
+
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("Failed to update golden file: %v", err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read golden file: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("Output does not match %s\n got: %s\nwant: %s", path, got, want)
+	}
+}
+
+func Test_MarshalMatchesGolden(t *testing.T) {
+	encoded, err := Marshal(sampleResult())
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	checkGolden(t, "monitoring_result", encoded)
+}
+
+func Test_MarshalIsDeterministic(t *testing.T) {
+	first, err := Marshal(sampleResult())
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	// Go randomizes map iteration, so repeated encodes exercise key ordering
+	for i := 0; i < 50; i++ {
+		again, err := Marshal(sampleResult())
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		if !bytes.Equal(first, again) {
+			t.Fatalf("Marshal output changed between runs:\n%s\n%s", first, again)
+		}
+	}
+}
+
+func Test_MarshalRejectsFloats(t *testing.T) {
+	_, err := Marshal(map[string]interface{}{"apy": 4.5})
+	if err == nil || !strings.Contains(err.Error(), "$.apy") {
+		t.Errorf("Expected float to be rejected with its path, got %v", err)
+	}
+
+	// Integral floats are still floats in the source, but encode identically everywhere
+	if out, err := Marshal(map[string]interface{}{"chain_id": float64(8453)}); err != nil || string(out) != `{"chain_id":8453}` {
+		t.Errorf("Expected integral value to encode as integer, got %s (%v)", out, err)
+	}
+}
+
+func Test_DecimalRounding(t *testing.T) {
+	testCases := []struct {
+		value  float64
+		places int
+		want   string
+	}{
+		{0.125, 2, "0.12"},
+		{0.135, 2, "0.14"},
+		{-1.005, 2, "-1.00"},
+		{4.123456, APYPlaces, "4.1235"},
+		{5.00005, APYPlaces, "5.0000"},
+		{5.00015, APYPlaces, "5.0002"},
+		{0.000001, APYPlaces, "0.0000"},
+		{12, 0, "12"},
+		{1e21, 2, "1000000000000000000000.00"},
+	}
+
+	for _, tc := range testCases {
+		if got := NewDecimalFromFloat(tc.value, tc.places).String(); got != tc.want {
+			t.Errorf("NewDecimalFromFloat(%v, %d) = %s, want %s", tc.value, tc.places, got, tc.want)
+		}
+	}
+
+	// Aave liquidityRate of 3.5% expressed in ray
+	ray, _ := new(big.Int).SetString("35000000000000000000000000", 10)
+	if got := NewDecimalFromInt(ray, 25, APYPlaces).String(); got != "3.5000" {
+		t.Errorf("Expected ray conversion to give 3.5000, got %s", got)
+	}
+}
+
+func Test_DecimalJSONRoundTrip(t *testing.T) {
+	original := APY(3.14159)
+	encoded, err := json.Marshal(original)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if string(encoded) != `"3.1416"` {
+		t.Errorf("Unexpected encoding %s", encoded)
+	}
+
+	var decoded Decimal
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if decoded.Cmp(original) != 0 || decoded.Places() != APYPlaces {
+		t.Errorf("Round trip changed value: %s", decoded)
+	}
+
+	if err := json.Unmarshal([]byte(`3.14`), &decoded); err == nil {
+		t.Errorf("Expected bare JSON numbers to be rejected")
+	}
+	if _, err := ParseDecimal("1e5"); err == nil {
+		t.Errorf("Expected exponent notation to be rejected")
+	}
+}
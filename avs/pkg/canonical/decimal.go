@@ -0,0 +1,189 @@
+package canonical
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+const (
+	// APYPlaces is the number of fractional digits APY percentages are rounded to.
+	// 4 places of a percentage is 0.01 basis points, well below any actionable difference.
+	APYPlaces = 4
+
+	// RatioPlaces is the number of fractional digits used for ratios such as utilization
+	RatioPlaces = 6
+
+	// ScorePlaces is the number of fractional digits used for scores
+	ScorePlaces = 2
+)
+
+// Decimal is an exact fixed-point decimal number. It is encoded in JSON as a string
+// (e.g. "4.1250") so results never contain floating point values whose textual form
+// could differ between operators.
+type Decimal struct {
+	unscaled *big.Int
+	places   int
+}
+
+var bigTen = big.NewInt(10)
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(bigTen, big.NewInt(int64(n)), nil)
+}
+
+// roundHalfEven rounds r to the nearest integer, ties to even
+func roundHalfEven(r *big.Rat) *big.Int {
+	num := new(big.Int).Set(r.Num())
+	den := r.Denom()
+
+	neg := num.Sign() < 0
+	if neg {
+		num.Neg(num)
+	}
+
+	q, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	twice := new(big.Int).Lsh(rem, 1)
+	switch twice.Cmp(den) {
+	case 1:
+		q.Add(q, big.NewInt(1))
+	case 0:
+		if q.Bit(0) == 1 {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+
+	if neg {
+		q.Neg(q)
+	}
+	return q
+}
+
+// NewDecimalFromRat rounds r to places fractional digits using round-half-even
+func NewDecimalFromRat(r *big.Rat, places int) Decimal {
+	scaled := new(big.Rat).Mul(r, new(big.Rat).SetInt(pow10(places)))
+	return Decimal{unscaled: roundHalfEven(scaled), places: places}
+}
+
+// NewDecimalFromFloat rounds f to places fractional digits using round-half-even on the
+// shortest decimal representation of f, so 0.125 rounds like the literal 0.125 rather
+// than its binary approximation. NaN and infinities are mapped to zero.
+func NewDecimalFromFloat(f float64, places int) Decimal {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return Decimal{unscaled: new(big.Int), places: places}
+	}
+	r, ok := new(big.Rat).SetString(strconv.FormatFloat(f, 'g', -1, 64))
+	if !ok {
+		r = new(big.Rat).SetFloat64(f)
+	}
+	return NewDecimalFromRat(r, places)
+}
+
+// NewDecimalFromInt scales an integer with the given number of token decimals
+// (e.g. 6 for USDC base units, 27 for Aave ray values) and rounds it to places.
+func NewDecimalFromInt(i *big.Int, decimals int, places int) Decimal {
+	if i == nil {
+		i = new(big.Int)
+	}
+	r := new(big.Rat).SetFrac(i, pow10(decimals))
+	return NewDecimalFromRat(r, places)
+}
+
+// ParseDecimal parses a decimal string such as "4.125" keeping its precision
+func ParseDecimal(s string) (Decimal, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Decimal{}, fmt.Errorf("empty decimal")
+	}
+	places := 0
+	if idx := strings.IndexByte(s, '.'); idx >= 0 {
+		places = len(s) - idx - 1
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok || strings.ContainsAny(s, "eE/") {
+		return Decimal{}, fmt.Errorf("invalid decimal %q", s)
+	}
+	return NewDecimalFromRat(r, places), nil
+}
+
+// APY rounds a percentage to APYPlaces
+func APY(percent float64) Decimal {
+	return NewDecimalFromFloat(percent, APYPlaces)
+}
+
+// Ratio rounds a ratio to RatioPlaces
+func Ratio(ratio float64) Decimal {
+	return NewDecimalFromFloat(ratio, RatioPlaces)
+}
+
+// Score rounds a score to ScorePlaces
+func Score(score float64) Decimal {
+	return NewDecimalFromFloat(score, ScorePlaces)
+}
+
+// Places returns the number of fractional digits of d
+func (d Decimal) Places() int {
+	return d.places
+}
+
+// Rat returns d as an exact rational number
+func (d Decimal) Rat() *big.Rat {
+	if d.unscaled == nil {
+		return new(big.Rat)
+	}
+	return new(big.Rat).SetFrac(d.unscaled, pow10(d.places))
+}
+
+// Float64 returns the nearest float64 to d. Only use it for internal analytics,
+// never to produce result values.
+func (d Decimal) Float64() float64 {
+	f, _ := d.Rat().Float64()
+	return f
+}
+
+// Cmp compares d and other numerically
+func (d Decimal) Cmp(other Decimal) int {
+	return d.Rat().Cmp(other.Rat())
+}
+
+// String renders d with exactly Places() fractional digits
+func (d Decimal) String() string {
+	unscaled := d.unscaled
+	if unscaled == nil {
+		unscaled = new(big.Int)
+	}
+
+	neg := unscaled.Sign() < 0
+	digits := new(big.Int).Abs(unscaled).String()
+	if d.places > 0 {
+		if len(digits) <= d.places {
+			digits = strings.Repeat("0", d.places-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-d.places] + "." + digits[len(digits)-d.places:]
+	}
+	if neg {
+		return "-" + digits
+	}
+	return digits
+}
+
+// MarshalJSON encodes d as a JSON string
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + d.String() + `"`), nil
+}
+
+// UnmarshalJSON decodes a JSON string produced by MarshalJSON
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return fmt.Errorf("decimal must be a JSON string, got %s", s)
+	}
+	parsed, err := ParseDecimal(s[1 : len(s)-1])
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
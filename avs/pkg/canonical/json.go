@@ -0,0 +1,112 @@
+package canonical
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Marshal encodes v as canonical JSON so every operator produces byte-identical
+// results for the same data, which BLS signature aggregation requires:
+//
+//   - object keys are sorted lexicographically at every level
+//   - no insignificant whitespace and no HTML escaping
+//   - numbers must be integers; fractional values must be carried as Decimal strings
+//
+// Marshal returns an error if v contains a non-integer number.
+func Marshal(v interface{}) ([]byte, error) {
+	raw, err := encode(v)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var generic interface{}
+	if err := dec.Decode(&generic); err != nil {
+		return nil, fmt.Errorf("failed to decode intermediate json: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := writeCanonical(&buf, generic, "$"); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func encode(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, fmt.Errorf("failed to encode result: %w", err)
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+func writeCanonical(buf *bytes.Buffer, v interface{}, path string) error {
+	switch val := v.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		if val {
+			buf.WriteString("true")
+		} else {
+			buf.WriteString("false")
+		}
+	case json.Number:
+		s := val.String()
+		if strings.ContainsAny(s, ".eE") {
+			return fmt.Errorf("non-integer number %s at %s: use canonical.Decimal for fractional values", s, path)
+		}
+		if s == "-0" {
+			s = "0"
+		}
+		buf.WriteString(s)
+	case string:
+		encoded, err := encode(val)
+		if err != nil {
+			return err
+		}
+		buf.Write(encoded)
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, item := range val {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeCanonical(buf, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		buf.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			encodedKey, err := encode(k)
+			if err != nil {
+				return err
+			}
+			buf.Write(encodedKey)
+			buf.WriteByte(':')
+			if err := writeCanonical(buf, val[k], path+"."+k); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	default:
+		return fmt.Errorf("unsupported value of type %T at %s", v, path)
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package cctp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/resilience"
+)
+
+// Circle's attestation service (Iris) of mainnet burns, and its sandbox attesting testnet
+// burns
+const (
+	DefaultAttestationURL = "https://iris-api.circle.com"
+	SandboxAttestationURL = "https://iris-api-sandbox.circle.com"
+)
+
+// AttestationURL returns the attestation service attesting the burns of network
+func AttestationURL(network chain.Network) string {
+	if network == chain.NetworkTestnet {
+		return SandboxAttestationURL
+	}
+	return DefaultAttestationURL
+}
+
+// maxAttestationResponse bounds the body read from the attestation service
+const maxAttestationResponse = 1 << 20
+
+// Statuses of a burn message in the attestation service
+const (
+	AttestationPending  = "pending_confirmations"
+	AttestationComplete = "complete"
+)
+
+// Attestations reads burns and their attestations from Circle's attestation service
+type Attestations struct {
+	url        string
+	httpClient *http.Client
+	policy     *resilience.Policy
+}
+
+// NewAttestations creates a client of the attestation service at url, DefaultAttestationURL
+// when empty. Transient failures are retried under policy, which may be nil.
+func NewAttestations(url string, timeout time.Duration, policy *resilience.Policy) *Attestations {
+	if url == "" {
+		url = DefaultAttestationURL
+	}
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &Attestations{
+		url:        strings.TrimRight(url, "/"),
+		httpClient: &http.Client{Timeout: timeout},
+		policy:     policy,
+	}
+}
+
+// Message is a burn message with Circle's attestation of it, which the mint on the
+// destination chain carries. Both are empty until Status is AttestationComplete.
+type Message struct {
+	Status      string
+	Message     []byte
+	Attestation []byte
+}
+
+// Status returns the attestation status of the burn sent in txHash on sourceChainID.
+// Burns the service has not indexed yet are pending.
+func (a *Attestations) Status(ctx context.Context, sourceChainID uint64, txHash common.Hash) (string, error) {
+	message, err := a.Message(ctx, sourceChainID, txHash)
+	if err != nil {
+		return "", err
+	}
+	return message.Status, nil
+}
+
+// Message returns the message of the burn sent in txHash on sourceChainID, attested once
+// Circle observed the burn
+func (a *Attestations) Message(ctx context.Context, sourceChainID uint64, txHash common.Hash) (*Message, error) {
+	domain, err := Domain(sourceChainID)
+	if err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("%s/v2/messages/%d?transactionHash=%s", a.url, domain, txHash.Hex())
+	var message *Message
+	err = a.policy.Do(ctx, a.url, func(ctx context.Context) error {
+		var err error
+		message, err = a.message(ctx, url)
+		return err
+	})
+	return message, err
+}
+
+func (a *Attestations) message(ctx context.Context, url string) (*Message, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("attestation service: failed to build request: %w", err)
+	}
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("attestation service unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return &Message{Status: AttestationPending}, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("attestation service: %w", &resilience.StatusError{Endpoint: a.url, StatusCode: resp.StatusCode})
+	}
+
+	var body struct {
+		Messages []struct {
+			Status      string `json:"status"`
+			Message     string `json:"message"`
+			Attestation string `json:"attestation"`
+		} `json:"messages"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxAttestationResponse)).Decode(&body); err != nil {
+		return nil, fmt.Errorf("attestation service: failed to decode: %w", err)
+	}
+	if len(body.Messages) == 0 {
+		return &Message{Status: AttestationPending}, nil
+	}
+	first := body.Messages[0]
+	message := &Message{Status: first.Status}
+	if message.Status != AttestationComplete {
+		return message, nil
+	}
+	if message.Message, err = hexutil.Decode(first.Message); err != nil {
+		return nil, fmt.Errorf("attestation service: invalid message: %w", err)
+	}
+	if message.Attestation, err = hexutil.Decode(first.Attestation); err != nil {
+		return nil, fmt.Errorf("attestation service: invalid attestation: %w", err)
+	}
+	return message, nil
+}
+
+// Burn is a burn sent on its source chain and waiting for Circle's attestation
+type Burn struct {
+	SourceChainID uint64
+	TxHash        common.Hash
+	SentAt        time.Time
+}
+
+// Monitor tracks the burns of the performer until Circle attests them, to tell when
+// attestations are delayed past an SLA. Burns are kept in memory, so those sent before a
+// restart are not tracked.
+type Monitor struct {
+	attestations *Attestations
+	sla          time.Duration
+	now          func() time.Time
+
+	mu    sync.Mutex
+	burns []Burn
+}
+
+// NewMonitor creates a monitor counting attestations as delayed once a burn waited
+// longer than sla for its attestation
+func NewMonitor(attestations *Attestations, sla time.Duration) *Monitor {
+	return &Monitor{attestations: attestations, sla: sla, now: time.Now}
+}
+
+// SLA is how long a burn may wait for its attestation
+func (m *Monitor) SLA() time.Duration {
+	return m.sla
+}
+
+// Track records a burn to watch until it is attested
+func (m *Monitor) Track(burn Burn) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.burns = append(m.burns, burn)
+}
+
+// Delayed returns the oldest burn on sourceChainID that waited longer than the SLA and
+// is still not attested, nil when attestations of the chain are on time. Burns found
+// attested are forgotten.
+func (m *Monitor) Delayed(ctx context.Context, sourceChainID uint64) (*Burn, error) {
+	if m == nil {
+		return nil, nil
+	}
+	m.mu.Lock()
+	var overdue []Burn
+	for _, burn := range m.burns {
+		if burn.SourceChainID == sourceChainID && m.now().Sub(burn.SentAt) > m.sla {
+			overdue = append(overdue, burn)
+		}
+	}
+	m.mu.Unlock()
+
+	attested := make(map[common.Hash]bool)
+	var delayed *Burn
+	for i, burn := range overdue {
+		status, err := m.attestations.Status(ctx, burn.SourceChainID, burn.TxHash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the attestation of burn %s: %w", burn.TxHash.Hex(), err)
+		}
+		if status == AttestationComplete {
+			attested[burn.TxHash] = true
+			continue
+		}
+		if delayed == nil || burn.SentAt.Before(delayed.SentAt) {
+			delayed = &overdue[i]
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	kept := m.burns[:0]
+	for _, burn := range m.burns {
+		if !attested[burn.TxHash] {
+			kept = append(kept, burn)
+		}
+	}
+	m.burns = kept
+	return delayed, nil
+}
@@ -0,0 +1,110 @@
+package cctp
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func Test_MonitorDelayed(t *testing.T) {
+	attested := common.HexToHash("0x01")
+	stuck := common.HexToHash("0x02")
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path+"?"+r.URL.RawQuery)
+		switch r.URL.Query().Get("transactionHash") {
+		case attested.Hex():
+			w.Write([]byte(`{"messages":[{"status":"complete","message":"0x0102","attestation":"0xabcd"}]}`))
+		case stuck.Hex():
+			w.Write([]byte(`{"messages":[{"status":"pending_confirmations"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	m := NewMonitor(NewAttestations(server.URL, time.Second, nil), time.Hour)
+	m.now = func() time.Time { return now }
+	m.Track(Burn{SourceChainID: 8453, TxHash: attested, SentAt: now.Add(-3 * time.Hour)})
+	m.Track(Burn{SourceChainID: 8453, TxHash: stuck, SentAt: now.Add(-2 * time.Hour)})
+	m.Track(Burn{SourceChainID: 8453, TxHash: common.HexToHash("0x03"), SentAt: now.Add(-10 * time.Minute)})
+
+	ctx := context.Background()
+	delayed, err := m.Delayed(ctx, 8453)
+	if err != nil {
+		t.Fatalf("Delayed failed: %v", err)
+	}
+	if delayed == nil || delayed.TxHash != stuck {
+		t.Fatalf("Expected the unattested burn to be delayed, got %+v", delayed)
+	}
+	// only burns past the SLA are looked up, on the domain of their source chain
+	if len(requests) != 2 || requests[0] != "/v2/messages/6?transactionHash="+attested.Hex() {
+		t.Errorf("Unexpected requests %v", requests)
+	}
+
+	requests = nil
+	if _, err := m.Delayed(ctx, 8453); err != nil || len(requests) != 1 {
+		t.Errorf("Expected the attested burn to be forgotten, got requests %v, %v", requests, err)
+	}
+	if delayed, err := m.Delayed(ctx, 1); err != nil || delayed != nil {
+		t.Errorf("Expected attestations of another chain to be on time, got %+v, %v", delayed, err)
+	}
+
+	var disabled *Monitor
+	disabled.Track(Burn{SourceChainID: 1})
+	if delayed, err := disabled.Delayed(ctx, 1); err != nil || delayed != nil {
+		t.Errorf("Expected a nil monitor to report no delay, got %+v, %v", delayed, err)
+	}
+}
+
+func Test_AttestationStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	a := NewAttestations(server.URL, time.Second, nil)
+	if _, err := a.Status(context.Background(), 1, common.HexToHash("0x01")); err == nil {
+		t.Errorf("Expected an unavailable service to fail")
+	}
+	if _, err := a.Status(context.Background(), 137, common.HexToHash("0x01")); err == nil {
+		t.Errorf("Expected a chain without a CCTP domain to fail")
+	}
+}
+
+func Test_AttestationMessage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("transactionHash") {
+		case common.HexToHash("0x01").Hex():
+			w.Write([]byte(`{"messages":[{"status":"complete","message":"0x0102","attestation":"0xabcd"}]}`))
+		case common.HexToHash("0x02").Hex():
+			w.Write([]byte(`{"messages":[{"status":"pending_confirmations","message":"0x","attestation":"PENDING"}]}`))
+		default:
+			w.Write([]byte(`{"messages":[{"status":"complete","message":"0x0102","attestation":"PENDING"}]}`))
+		}
+	}))
+	defer server.Close()
+
+	a := NewAttestations(server.URL, time.Second, nil)
+	ctx := context.Background()
+	message, err := a.Message(ctx, 1, common.HexToHash("0x01"))
+	if err != nil {
+		t.Fatalf("Message failed: %v", err)
+	}
+	if message.Status != AttestationComplete || !bytes.Equal(message.Message, []byte{1, 2}) || !bytes.Equal(message.Attestation, []byte{0xab, 0xcd}) {
+		t.Errorf("Unexpected attested message %+v", message)
+	}
+	// pending messages carry no attestation yet
+	if message, err := a.Message(ctx, 1, common.HexToHash("0x02")); err != nil || message.Status != AttestationPending || message.Attestation != nil {
+		t.Errorf("Expected a pending message, got %+v, %v", message, err)
+	}
+	if _, err := a.Message(ctx, 1, common.HexToHash("0x03")); err == nil {
+		t.Errorf("Expected an invalid attestation to fail")
+	}
+}
@@ -0,0 +1,219 @@
+// Package cctp builds Circle Cross-Chain Transfer Protocol (CCTP v2) transactions and
+// estimates how long transfers wait for Circle's attestation.
+package cctp
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/addressbook"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+)
+
+// Minimum finality thresholds of a burn. Fast transfers are attested after soft finality
+// for a fee; standard transfers wait for hard finality and are free.
+const (
+	FinalityFast     uint32 = 1000
+	FinalityStandard uint32 = 2000
+)
+
+// domains maps chain ids to CCTP domain ids. Testnets share the domain of their mainnet.
+var domains = map[uint64]uint32{
+	1:        0, // Ethereum
+	42161:    3, // Arbitrum
+	8453:     6, // Base
+	11155111: 0, // Sepolia
+	421614:   3, // Arbitrum Sepolia
+	84532:    6, // Base Sepolia
+}
+
+// Speed selects how a transfer is attested: SpeedStandard after hard finality of the
+// burn, SpeedFast after soft finality for a fee
+type Speed string
+
+const (
+	SpeedStandard Speed = "standard"
+	SpeedFast     Speed = "fast"
+)
+
+// ParseSpeed returns the speed named s
+func ParseSpeed(s string) (Speed, error) {
+	switch speed := Speed(s); speed {
+	case SpeedStandard, SpeedFast:
+		return speed, nil
+	}
+	return "", fmt.Errorf("unknown transfer speed %q, must be %s or %s", s, SpeedStandard, SpeedFast)
+}
+
+// Threshold is the minimum finality threshold of burns of speed s
+func (s Speed) Threshold() uint32 {
+	if s == SpeedFast {
+		return FinalityFast
+	}
+	return FinalityStandard
+}
+
+// Finality is what a burn on a source chain waits for before Circle attests it
+type Finality struct {
+	// Requirement describes the finality the burn waits for
+	Requirement string
+
+	// Wait is Circle's published upper bound of the time between the burn and its
+	// attestation
+	Wait time.Duration
+}
+
+// Finality requirements of burns. Ethereum burns are final once their epoch is
+// finalized, two epochs later; rollup burns only once the batch carrying them is posted to
+// Ethereum and finalized there, so their standard transfers wait as long as Ethereum's.
+// Fast transfers are attested once the burn is in a block the source chain is unlikely to
+// reorganize: a few confirmations on Ethereum, and the sequencer's confirmation on rollups.
+var (
+	ethereumHardFinality = Finality{Requirement: "hard finality: two epochs finalized on Ethereum", Wait: 19 * time.Minute}
+	rollupHardFinality   = Finality{Requirement: "hard finality: batch finalized on Ethereum", Wait: 19 * time.Minute}
+	ethereumSoftFinality = Finality{Requirement: "soft finality: two block confirmations", Wait: 20 * time.Second}
+	rollupSoftFinality   = Finality{Requirement: "soft finality: sequencer confirmation", Wait: 8 * time.Second}
+)
+
+// finalities are the finality requirements of burns per threshold and source chain
+var finalities = map[uint32]map[uint64]Finality{
+	FinalityStandard: {
+		1:        ethereumHardFinality,
+		42161:    rollupHardFinality,
+		8453:     rollupHardFinality,
+		11155111: ethereumHardFinality,
+		421614:   rollupHardFinality,
+		84532:    rollupHardFinality,
+	},
+	FinalityFast: {
+		1:        ethereumSoftFinality,
+		42161:    rollupSoftFinality,
+		8453:     rollupSoftFinality,
+		11155111: ethereumSoftFinality,
+		421614:   rollupSoftFinality,
+		84532:    rollupSoftFinality,
+	},
+}
+
+const tokenMessengerABIJson = `[
+	{"name":"depositForBurn","type":"function","stateMutability":"nonpayable",
+	 "inputs":[
+		{"name":"amount","type":"uint256"},
+		{"name":"destinationDomain","type":"uint32"},
+		{"name":"mintRecipient","type":"bytes32"},
+		{"name":"burnToken","type":"address"},
+		{"name":"destinationCaller","type":"bytes32"},
+		{"name":"maxFee","type":"uint256"},
+		{"name":"minFinalityThreshold","type":"uint32"}
+	 ],
+	 "outputs":[]}
+]`
+
+const messageTransmitterABIJson = `[
+	{"name":"receiveMessage","type":"function","stateMutability":"nonpayable",
+	 "inputs":[{"name":"message","type":"bytes"},{"name":"attestation","type":"bytes"}],
+	 "outputs":[{"name":"success","type":"bool"}]}
+]`
+
+var (
+	tokenMessengerABI     = chain.MustParseABI(tokenMessengerABIJson)
+	messageTransmitterABI = chain.MustParseABI(messageTransmitterABIJson)
+)
+
+// Domain returns the CCTP domain of chainID
+func Domain(chainID uint64) (uint32, error) {
+	domain, ok := domains[chainID]
+	if !ok {
+		return 0, fmt.Errorf("chain %d is not supported by CCTP", chainID)
+	}
+	return domain, nil
+}
+
+// Transfer is a burn of USDC on a source chain, minted to Recipient on the destination chain
+type Transfer struct {
+	SourceChainID      uint64
+	DestinationChainID uint64
+	Amount             *big.Int
+	Recipient          common.Address
+	BurnToken          common.Address
+
+	// MaxFee is the most the sender pays for the transfer, in burn token base units
+	MaxFee *big.Int
+
+	// MinFinality is FinalityFast or FinalityStandard. Zero means standard.
+	MinFinality uint32
+}
+
+func (t *Transfer) finality() uint32 {
+	if t.MinFinality == 0 {
+		return FinalityStandard
+	}
+	return t.MinFinality
+}
+
+// DepositForBurnCall builds the call burning t through the TokenMessenger of its source
+// chain in book. Anyone may relay the mint on the destination chain.
+func DepositForBurnCall(book *addressbook.Book, t *Transfer) (*chain.Call, error) {
+	if _, err := Domain(t.SourceChainID); err != nil {
+		return nil, err
+	}
+	destination, err := Domain(t.DestinationChainID)
+	if err != nil {
+		return nil, err
+	}
+	maxFee := t.MaxFee
+	if maxFee == nil {
+		maxFee = new(big.Int)
+	}
+	data, err := tokenMessengerABI.Pack("depositForBurn",
+		t.Amount,
+		destination,
+		common.BytesToHash(t.Recipient.Bytes()),
+		t.BurnToken,
+		common.Hash{},
+		maxFee,
+		t.finality(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack depositForBurn: %w", err)
+	}
+	messenger, err := book.Require(t.SourceChainID, addressbook.ProtocolCCTP, addressbook.ContractTokenMessenger)
+	if err != nil {
+		return nil, err
+	}
+	return &chain.Call{To: messenger, Data: data}, nil
+}
+
+// ReceiveMessageCall builds the call minting an attested transfer through the
+// MessageTransmitter of its destination chain, destinationChainID, in book
+func ReceiveMessageCall(book *addressbook.Book, destinationChainID uint64, message, attestation []byte) (*chain.Call, error) {
+	transmitter, err := book.Require(destinationChainID, addressbook.ProtocolCCTP, addressbook.ContractMessageTransmitter)
+	if err != nil {
+		return nil, err
+	}
+	data, err := messageTransmitterABI.Pack("receiveMessage", message, attestation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack receiveMessage: %w", err)
+	}
+	return &chain.Call{To: transmitter, Data: data}, nil
+}
+
+// SourceFinality returns the finality t waits for on its source chain
+func SourceFinality(t *Transfer) (Finality, error) {
+	finality, ok := finalities[t.finality()][t.SourceChainID]
+	if !ok {
+		return Finality{}, fmt.Errorf("no attestation time known for chain %d at finality %d", t.SourceChainID, t.finality())
+	}
+	return finality, nil
+}
+
+// AttestationTime returns the expected upper bound of the wait for Circle to attest t
+func AttestationTime(t *Transfer) (time.Duration, error) {
+	finality, err := SourceFinality(t)
+	if err != nil {
+		return 0, err
+	}
+	return finality.Wait, nil
+}
@@ -0,0 +1,103 @@
+package cctp
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/addressbook"
+)
+
+func Test_DepositForBurnCall(t *testing.T) {
+	recipient := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	book := addressbook.Default()
+	call, err := DepositForBurnCall(book, &Transfer{
+		SourceChainID:      1,
+		DestinationChainID: 8453,
+		Amount:             big.NewInt(1_000_000),
+		Recipient:          recipient,
+		BurnToken:          common.HexToAddress("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48"),
+	})
+	if err != nil {
+		t.Fatalf("DepositForBurnCall failed: %v", err)
+	}
+	if call.To != common.HexToAddress("0x28b5a0e9C621a5BadaA536219b3a228C8168cf5d") {
+		t.Errorf("Expected the burn to go to the token messenger, got %s", call.To.Hex())
+	}
+
+	args, err := tokenMessengerABI.Methods["depositForBurn"].Inputs.Unpack(call.Data[4:])
+	if err != nil {
+		t.Fatalf("Failed to unpack call: %v", err)
+	}
+	if domain := args[1].(uint32); domain != 6 {
+		t.Errorf("Expected the Base domain, got %d", domain)
+	}
+	if mintRecipient := common.Hash(args[2].([32]byte)); mintRecipient != common.BytesToHash(recipient.Bytes()) {
+		t.Errorf("Expected the recipient left padded to 32 bytes, got %s", mintRecipient.Hex())
+	}
+	if finality := args[6].(uint32); finality != FinalityStandard {
+		t.Errorf("Expected a standard transfer by default, got finality %d", finality)
+	}
+
+	if _, err := DepositForBurnCall(book, &Transfer{SourceChainID: 1, DestinationChainID: 56, Amount: big.NewInt(1)}); err == nil {
+		t.Errorf("Expected a burn to an unsupported chain to be rejected")
+	}
+
+	// testnets burn through the testnet contracts, to the domain of their mainnet
+	testnetMessenger := common.HexToAddress("0x8FE6B999Dc680CcFDD5Bf7EB0974218be2542DAA")
+	call, err = DepositForBurnCall(book, &Transfer{SourceChainID: 11155111, DestinationChainID: 84532, Amount: big.NewInt(1), Recipient: recipient})
+	if err != nil || call.To != testnetMessenger {
+		t.Fatalf("Expected the testnet burn to go to the testnet token messenger, got %+v: %v", call, err)
+	}
+	if args, _ := tokenMessengerABI.Methods["depositForBurn"].Inputs.Unpack(call.Data[4:]); args[1].(uint32) != 6 {
+		t.Errorf("Expected the Base domain for Base Sepolia, got %d", args[1])
+	}
+	if mint, err := ReceiveMessageCall(book, 84532, []byte{1}, []byte{2}); err != nil || mint.To != common.HexToAddress("0xE737e5cEBEEBa77EFE34D4aa090756590b1CE275") {
+		t.Errorf("Expected the testnet mint to go to the testnet message transmitter, got %+v: %v", mint, err)
+	}
+
+	// overridden contracts are burned through
+	overridden, err := book.With([]addressbook.Entry{{ChainID: 11155111, Protocol: addressbook.ProtocolCCTP, Contract: addressbook.ContractTokenMessenger, Address: "0x00000000000000000000000000000000000000bb"}})
+	if err != nil {
+		t.Fatalf("With failed: %v", err)
+	}
+	call, err = DepositForBurnCall(overridden, &Transfer{SourceChainID: 11155111, DestinationChainID: 84532, Amount: big.NewInt(1), Recipient: recipient})
+	if err != nil || call.To != common.HexToAddress("0x00000000000000000000000000000000000000bb") {
+		t.Errorf("Expected the burn to go to the overridden token messenger, got %+v: %v", call, err)
+	}
+}
+
+func Test_AttestationTime(t *testing.T) {
+	standard, err := AttestationTime(&Transfer{SourceChainID: 1})
+	if err != nil || standard != 19*time.Minute {
+		t.Errorf("Unexpected standard attestation time %s: %v", standard, err)
+	}
+	fast, err := AttestationTime(&Transfer{SourceChainID: 8453, MinFinality: FinalityFast})
+	if err != nil || fast != 8*time.Second {
+		t.Errorf("Unexpected fast attestation time %s: %v", fast, err)
+	}
+}
+
+func Test_SourceFinality(t *testing.T) {
+	speed, err := ParseSpeed("fast")
+	if err != nil || speed.Threshold() != FinalityFast {
+		t.Fatalf("Expected fast transfers at the fast threshold, got %q: %v", speed, err)
+	}
+	if _, err := ParseSpeed("instant"); err == nil {
+		t.Errorf("Expected an unknown speed to be rejected")
+	}
+
+	// rollups reach hard finality with the Ethereum batch carrying the burn
+	standard, err := SourceFinality(&Transfer{SourceChainID: 8453, MinFinality: SpeedStandard.Threshold()})
+	if err != nil || standard.Wait != 19*time.Minute || standard != rollupHardFinality {
+		t.Errorf("Unexpected standard finality of Base %+v: %v", standard, err)
+	}
+	fast, err := SourceFinality(&Transfer{SourceChainID: 1, MinFinality: speed.Threshold()})
+	if err != nil || fast.Wait != 20*time.Second || fast.Requirement == "" {
+		t.Errorf("Unexpected fast finality of Ethereum %+v: %v", fast, err)
+	}
+	if _, err := SourceFinality(&Transfer{SourceChainID: 56}); err == nil {
+		t.Errorf("Expected no finality known for a chain CCTP does not support")
+	}
+}
@@ -0,0 +1,59 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Call is an unsigned transaction to a contract
+type Call struct {
+	To   common.Address
+	Data []byte
+}
+
+// MustParseABI parses a JSON ABI definition, panicking on malformed input. It is meant for
+// package level ABI constants.
+func MustParseABI(definition string) abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(definition))
+	if err != nil {
+		panic(fmt.Sprintf("invalid abi definition: %v", err))
+	}
+	return parsed
+}
+
+// CallView executes a view function at the block ctx is pinned to, or the latest block,
+// and returns its decoded outputs
+func CallView(ctx context.Context, client Client, contract common.Address, contractABI abi.ABI, method string, args ...interface{}) ([]interface{}, error) {
+	data, err := contractABI.Pack(method, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack %s: %w", method, err)
+	}
+	out, err := client.CallContract(ctx, ethereum.CallMsg{To: &contract, Data: data}, BlockOf(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("call to %s on %s failed: %w", method, contract.Hex(), historical(ctx, err))
+	}
+	values, err := contractABI.Unpack(method, out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack %s from %s: %w", method, contract.Hex(), err)
+	}
+	return values, nil
+}
+
+// CallUint calls a view function returning a single integer
+func CallUint(ctx context.Context, client Client, contract common.Address, contractABI abi.ABI, method string, args ...interface{}) (*big.Int, error) {
+	values, err := CallView(ctx, client, contract, contractABI, method, args...)
+	if err != nil {
+		return nil, err
+	}
+	value, ok := values[0].(*big.Int)
+	if !ok {
+		return nil, fmt.Errorf("unexpected %s output type %T", method, values[0])
+	}
+	return value, nil
+}
@@ -0,0 +1,140 @@
+package chain
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// TracedCall is an eth_call a call trace recorded: replaying Data against Address on ChainID
+// at Block returns the data hashing to ResultHash
+type TracedCall struct {
+	ChainID    uint64         `json:"chain_id"`
+	Address    common.Address `json:"address"`
+	Selector   hexutil.Bytes  `json:"selector"`
+	Data       hexutil.Bytes  `json:"data"`
+	Block      uint64         `json:"block"`
+	ResultHash common.Hash    `json:"result_hash"`
+}
+
+// CallTrace records the eth_calls made with a context it was attached to with
+// WithCallTrace, for results to be verified by replaying them. It is unrelated to the
+// spans of the tracing package.
+type CallTrace struct {
+	mu    sync.Mutex
+	heads map[uint64]uint64
+	calls map[tracedCallKey]common.Hash
+}
+
+// tracedCallKey identifies a call, which returns the same data whenever it is made
+type tracedCallKey struct {
+	chainID uint64
+	address common.Address
+	data    string
+	block   uint64
+}
+
+// NewCallTrace creates an empty call trace
+func NewCallTrace() *CallTrace {
+	return &CallTrace{heads: make(map[uint64]uint64), calls: make(map[tracedCallKey]common.Hash)}
+}
+
+type callTraceKey struct{}
+
+// WithCallTrace records the eth_calls made with the returned context in trace. Calls not
+// pinned to a block are pinned to the head of their chain as of the first call there,
+// so every call can be replayed at the block it read.
+func WithCallTrace(ctx context.Context, trace *CallTrace) context.Context {
+	return context.WithValue(ctx, callTraceKey{}, trace)
+}
+
+func callTraceOf(ctx context.Context) *CallTrace {
+	trace, _ := ctx.Value(callTraceKey{}).(*CallTrace)
+	return trace
+}
+
+// Calls returns the calls recorded, once each, ordered by chain, block, address and data
+// so concurrent reads trace the same
+func (t *CallTrace) Calls() []TracedCall {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	calls := make([]TracedCall, 0, len(t.calls))
+	for key, result := range t.calls {
+		call := TracedCall{ChainID: key.chainID, Address: key.address, Data: hexutil.Bytes(key.data), Block: key.block, ResultHash: result}
+		if len(key.data) >= 4 {
+			call.Selector = hexutil.Bytes(key.data[:4])
+		}
+		calls = append(calls, call)
+	}
+	sort.Slice(calls, func(i, j int) bool {
+		a, b := calls[i], calls[j]
+		if a.ChainID != b.ChainID {
+			return a.ChainID < b.ChainID
+		}
+		if a.Block != b.Block {
+			return a.Block < b.Block
+		}
+		if c := bytes.Compare(a.Address.Bytes(), b.Address.Bytes()); c != 0 {
+			return c < 0
+		}
+		return bytes.Compare(a.Data, b.Data) < 0
+	})
+	return calls
+}
+
+// head returns the block unpinned calls on chainID are pinned to
+func (t *CallTrace) head(ctx context.Context, chainID uint64, client Client) (uint64, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if head, ok := t.heads[chainID]; ok {
+		return head, nil
+	}
+	head, err := client.BlockNumber(ctx)
+	if err != nil {
+		return 0, err
+	}
+	t.heads[chainID] = head
+	return head, nil
+}
+
+func (t *CallTrace) record(chainID uint64, msg ethereum.CallMsg, block uint64, out []byte) {
+	key := tracedCallKey{chainID: chainID, data: string(msg.Data), block: block}
+	if msg.To != nil {
+		key.address = *msg.To
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.calls[key] = crypto.Keccak256Hash(out)
+}
+
+// callTracingClient records the calls of contexts carrying a call trace
+type callTracingClient struct {
+	Client
+	chainID uint64
+}
+
+func (c *callTracingClient) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	trace := callTraceOf(ctx)
+	if trace == nil {
+		return c.Client.CallContract(ctx, msg, blockNumber)
+	}
+	if blockNumber == nil {
+		head, err := trace.head(ctx, c.chainID, c.Client)
+		if err != nil {
+			return nil, err
+		}
+		blockNumber = new(big.Int).SetUint64(head)
+	}
+	out, err := c.Client.CallContract(ctx, msg, blockNumber)
+	if err == nil {
+		trace.record(c.chainID, msg, blockNumber.Uint64(), out)
+	}
+	return out, err
+}
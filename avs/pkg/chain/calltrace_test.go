@@ -0,0 +1,65 @@
+package chain
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func Test_CallTraceRecordsCalls(t *testing.T) {
+	contractABI := MustParseABI(`[
+		{"name":"totalSupply","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+		{"name":"balanceOf","type":"function","stateMutability":"view","inputs":[{"name":"account","type":"address"}],"outputs":[{"name":"","type":"uint256"}]}
+	]`)
+	fake := &fakeClient{chainID: 1}
+	m := NewManager()
+	m.Register(1, "ethereum", fake)
+	client, err := m.Client(1)
+	if err != nil {
+		t.Fatalf("Client failed: %v", err)
+	}
+
+	trace := NewCallTrace()
+	ctx := WithCallTrace(context.Background(), trace)
+	token, other := common.Address{2}, common.Address{1}
+	for _, call := range []struct {
+		ctx      context.Context
+		contract common.Address
+		method   string
+		args     []interface{}
+	}{
+		{ctx, token, "balanceOf", []interface{}{common.Address{9}}},
+		{ctx, token, "totalSupply", nil},
+		{ctx, token, "totalSupply", nil},
+		{WithBlock(ctx, 90), other, "totalSupply", nil},
+	} {
+		if _, err := CallUint(call.ctx, client, call.contract, contractABI, call.method, call.args...); err != nil {
+			t.Fatalf("CallUint failed: %v", err)
+		}
+	}
+	if fake.calledAt == nil || fake.calledAt.Uint64() != 90 {
+		t.Errorf("Expected the pinned read at block 90, got %v", fake.calledAt)
+	}
+
+	calls := trace.Calls()
+	if len(calls) != 3 {
+		t.Fatalf("Expected the repeated call recorded once, got %+v", calls)
+	}
+	selector := contractABI.Methods["totalSupply"].ID
+	result := crypto.Keccak256Hash(common.BigToHash(big.NewInt(1)).Bytes())
+	if calls[0].Block != 90 || calls[0].Address != other || calls[0].ResultHash != result {
+		t.Errorf("Expected the pinned call first, got %+v", calls[0])
+	}
+	// unpinned calls read the head, and are ordered by their data
+	if calls[1].Block != 100 || string(calls[1].Selector) != string(selector) || calls[2].Block != 100 || len(calls[2].Data) != 36 {
+		t.Errorf("Expected the unpinned calls at the head, got %+v", calls[1:])
+	}
+
+	// calls without a trace are not pinned
+	if _, err := CallUint(context.Background(), client, token, contractABI, "totalSupply"); err != nil || fake.calledAt != nil {
+		t.Errorf("Expected an untraced read of the latest block, got %v: %v", fake.calledAt, err)
+	}
+}
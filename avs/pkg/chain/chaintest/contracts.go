@@ -0,0 +1,563 @@
+// Package chaintest provides an in-memory chain.Client for tests of code reading contracts.
+package chaintest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ErrReverted is returned for calls without a stubbed result
+var ErrReverted = errors.New("execution reverted")
+
+// multicall3 is the address Contracts serves Multicall3.aggregate3 at, as every chain does
+var multicall3 = common.HexToAddress("0xcA11bde05977b3631167028862bE2a173976CA11")
+
+var multicall3ABI = func() abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(`[
+		{"name":"aggregate3","type":"function","stateMutability":"payable",
+		 "inputs":[{"name":"calls","type":"tuple[]","components":[{"name":"target","type":"address"},{"name":"allowFailure","type":"bool"},{"name":"callData","type":"bytes"}]}],
+		 "outputs":[{"name":"returnData","type":"tuple[]","components":[{"name":"success","type":"bool"},{"name":"returnData","type":"bytes"}]}]}
+	]`))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}()
+
+// Contracts is a chain.Client answering eth_call from stubbed results keyed by contract
+// address and method selector
+type Contracts struct {
+	mu        sync.RWMutex
+	chainID   uint64
+	block     uint64
+	blockTime uint64
+
+	// blockInterval is the seconds between blocks, 0 when every block has the head's time
+	blockInterval uint64
+
+	// prunedBefore is the oldest block whose state reads are served
+	prunedBefore uint64
+
+	// finalized is the finalized block, the head when it is 0
+	finalized uint64
+	results   map[string][]byte
+	code      map[common.Address][]byte
+	storage   map[common.Address]map[common.Hash]common.Hash
+	logs      []types.Log
+	gas       map[string]uint64
+	gasPrice  *big.Int
+	baseFee   *big.Int
+	tipCap    *big.Int
+	nonces    map[common.Address]uint64
+	sent      []*types.Transaction
+	sendErr   error
+	receipts  map[common.Hash]*types.Receipt
+	forks     map[uint64]byte
+	autoMine  bool
+
+	// blockBaseFees are the base fees of past blocks
+	blockBaseFees map[uint64]*big.Int
+
+	// calls counts the eth_calls answered
+	calls int
+}
+
+// NewContracts creates a client for chainID at block 100 with a gas price and base fee of
+// 1 gwei and a suggested tip of 0.1 gwei
+func NewContracts(chainID uint64) *Contracts {
+	return &Contracts{
+		chainID:  chainID,
+		block:    100,
+		results:  make(map[string][]byte),
+		code:     make(map[common.Address][]byte),
+		storage:  make(map[common.Address]map[common.Hash]common.Hash),
+		gas:      make(map[string]uint64),
+		gasPrice: big.NewInt(1_000_000_000),
+		baseFee:  big.NewInt(1_000_000_000),
+		tipCap:   big.NewInt(100_000_000),
+		nonces:   make(map[common.Address]uint64),
+		receipts: make(map[common.Hash]*types.Receipt),
+		forks:    make(map[uint64]byte),
+	}
+}
+
+// SetHead sets the number and timestamp of the latest block
+func (c *Contracts) SetHead(number, timestamp uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.block = number
+	c.blockTime = timestamp
+}
+
+// SetBlockInterval spaces the timestamps of past blocks seconds apart, ending at the
+// timestamp of the head
+func (c *Contracts) SetBlockInterval(seconds uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.blockInterval = seconds
+}
+
+// SetFinalized sets the number of the latest finalized block. Until it is set, the head
+// is final.
+func (c *Contracts) SetFinalized(number uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.finalized = number
+}
+
+// PruneBefore makes state reads of blocks before number fail the way they fail on full
+// nodes, which keep the state of recent blocks only
+func (c *Contracts) PruneBefore(number uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.prunedBefore = number
+}
+
+// stateLocked fails reads of the state of pruned blocks and blocks past the head
+func (c *Contracts) stateLocked(blockNumber *big.Int) error {
+	if blockNumber == nil {
+		return nil
+	}
+	if blockNumber.Uint64() > c.block {
+		return errors.New("header not found")
+	}
+	if blockNumber.Uint64() < c.prunedBefore {
+		return fmt.Errorf("missing trie node: state of block %s is not available", blockNumber)
+	}
+	return nil
+}
+
+// Stub makes calls to method on contract return outputs
+func (c *Contracts) Stub(t testing.TB, contract common.Address, contractABI abi.ABI, method string, outputs ...interface{}) {
+	t.Helper()
+	m, ok := contractABI.Methods[method]
+	if !ok {
+		t.Fatalf("Method %s is not part of the abi", method)
+	}
+	encoded, err := m.Outputs.Pack(outputs...)
+	if err != nil {
+		t.Fatalf("Failed to pack %s outputs: %v", method, err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.results[key(contract, m.ID)] = encoded
+}
+
+// StubCode deploys code at contract. Accounts with stubbed calls or storage have code
+// without it; any other account is an externally owned account.
+func (c *Contracts) StubCode(contract common.Address, code []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.code[contract] = code
+}
+
+// StubStorage sets the value of slot in the storage of contract
+func (c *Contracts) StubStorage(contract common.Address, slot, value common.Hash) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.storage[contract] == nil {
+		c.storage[contract] = make(map[common.Hash]common.Hash)
+	}
+	c.storage[contract][slot] = value
+}
+
+// AddLog adds log to the logs FilterLogs searches
+func (c *Contracts) AddLog(log types.Log) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.logs = append(c.logs, log)
+}
+
+// StubGas makes gas estimates of transactions calling method on contract return gas.
+// Estimates of transactions without stubbed gas revert.
+func (c *Contracts) StubGas(t testing.TB, contract common.Address, contractABI abi.ABI, method string, gas uint64) {
+	t.Helper()
+	m, ok := contractABI.Methods[method]
+	if !ok {
+		t.Fatalf("Method %s is not part of the abi", method)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gas[key(contract, m.ID)] = gas
+}
+
+// SetGasPrice sets the gas price suggested by the client
+func (c *Contracts) SetGasPrice(price *big.Int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gasPrice = price
+}
+
+// SetBaseFee sets the base fee of the latest block
+func (c *Contracts) SetBaseFee(fee *big.Int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.baseFee = fee
+}
+
+// SetBlockBaseFee sets the base fee of the past block number. Unlike that of the latest
+// block, it is part of the header and changes the hash of the block.
+func (c *Contracts) SetBlockBaseFee(number uint64, fee *big.Int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.blockBaseFees == nil {
+		c.blockBaseFees = make(map[uint64]*big.Int)
+	}
+	c.blockBaseFees[number] = fee
+}
+
+// SetGasTipCap sets the priority fee suggested by the client
+func (c *Contracts) SetGasTipCap(tip *big.Int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tipCap = tip
+}
+
+// SetNonce sets the pending nonce of account
+func (c *Contracts) SetNonce(account common.Address, nonce uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nonces[account] = nonce
+}
+
+// FailSends makes SendTransaction return err. A nil err accepts transactions again.
+func (c *Contracts) FailSends(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sendErr = err
+}
+
+// Sent returns the transactions accepted by SendTransaction in order
+func (c *Contracts) Sent() []*types.Transaction {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]*types.Transaction(nil), c.sent...)
+}
+
+// AutoMine makes every accepted transaction succeed in a block of its own
+func (c *Contracts) AutoMine() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.autoMine = true
+}
+
+// Mine includes the sent transaction hash in a new block with status and gas used. Mining
+// a transaction again moves it to the new block.
+func (c *Contracts) Mine(hash common.Hash, status, gasUsed uint64) *types.Receipt {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.mineLocked(hash, status, gasUsed)
+}
+
+func (c *Contracts) mineLocked(hash common.Hash, status, gasUsed uint64) *types.Receipt {
+	c.block++
+	price := new(big.Int).Set(c.baseFee)
+	for _, tx := range c.sent {
+		if tx.Hash() == hash {
+			price = tx.EffectiveGasTipValue(c.baseFee)
+			price.Add(price, c.baseFee)
+		}
+	}
+	receipt := &types.Receipt{
+		Status:            status,
+		TxHash:            hash,
+		GasUsed:           gasUsed,
+		EffectiveGasPrice: price,
+		BlockNumber:       new(big.Int).SetUint64(c.block),
+		BlockHash:         c.headerLocked(c.block).Hash(),
+	}
+	c.receipts[hash] = receipt
+	return receipt
+}
+
+// AdvanceBlocks adds n empty blocks
+func (c *Contracts) AdvanceBlocks(n uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.block += n
+}
+
+// Reorg replaces the blocks from number on. Receipts of transactions in them keep the old
+// block hash, as served by a node that has not caught up, until they are mined again.
+func (c *Contracts) Reorg(number uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for n := number; n <= c.block; n++ {
+		c.forks[n]++
+	}
+}
+
+// headerLocked returns the canonical header at number. The base fee of the latest block is
+// left out so changing it does not change block hashes.
+func (c *Contracts) headerLocked(number uint64) *types.Header {
+	timestamp := c.blockTime
+	if elapsed := (c.block - number) * c.blockInterval; number <= c.block && elapsed <= timestamp {
+		timestamp -= elapsed
+	}
+	header := &types.Header{Number: new(big.Int).SetUint64(number), Time: timestamp, Extra: []byte{c.forks[number]}}
+	if fee, ok := c.blockBaseFees[number]; ok {
+		header.BaseFee = new(big.Int).Set(fee)
+	}
+	return header
+}
+
+func key(contract common.Address, selector []byte) string {
+	return fmt.Sprintf("%s:%x", contract.Hex(), selector)
+}
+
+func (c *Contracts) ChainID(ctx context.Context) (*big.Int, error) {
+	return new(big.Int).SetUint64(c.chainID), nil
+}
+
+func (c *Contracts) BlockNumber(ctx context.Context) (uint64, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.block, nil
+}
+
+// HeaderByNumber returns the latest header with its base fee for a nil number, and the
+// canonical header without base fee otherwise. The finalized and safe block tags resolve
+// to the finalized block.
+func (c *Contracts) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if number != nil && (number.Int64() == int64(rpc.FinalizedBlockNumber) || number.Int64() == int64(rpc.SafeBlockNumber)) {
+		if c.finalized == 0 {
+			return c.headerLocked(c.block), nil
+		}
+		return c.headerLocked(c.finalized), nil
+	}
+	if number == nil || number.Sign() < 0 {
+		return &types.Header{Number: new(big.Int).SetUint64(c.block), Time: c.blockTime, BaseFee: new(big.Int).Set(c.baseFee)}, nil
+	}
+	if number.Uint64() > c.block {
+		return nil, ethereum.NotFound
+	}
+	return c.headerLocked(number.Uint64()), nil
+}
+
+// HeaderByHash returns the canonical header hashing to hash
+func (c *Contracts) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for number := c.block; ; number-- {
+		if header := c.headerLocked(number); header.Hash() == hash {
+			return header, nil
+		}
+		if number == 0 {
+			return nil, ethereum.NotFound
+		}
+	}
+}
+
+// Calls returns the number of eth_calls answered, a batch of calls to Multicall3
+// counting once
+func (c *Contracts) Calls() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.calls
+}
+
+func (c *Contracts) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	if msg.To == nil || len(msg.Data) < 4 {
+		return nil, ErrReverted
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls++
+	if err := c.stateLocked(blockNumber); err != nil {
+		return nil, err
+	}
+	if aggregate3 := multicall3ABI.Methods["aggregate3"]; *msg.To == multicall3 && bytes.Equal(msg.Data[:4], aggregate3.ID) {
+		return c.aggregateLocked(aggregate3, msg.Data[4:])
+	}
+	result, ok := c.results[key(*msg.To, msg.Data[:4])]
+	if !ok {
+		return nil, ErrReverted
+	}
+	return result, nil
+}
+
+// aggregateLocked answers the calls of a Multicall3.aggregate3 batch from stubbed results
+func (c *Contracts) aggregateLocked(aggregate3 abi.Method, input []byte) ([]byte, error) {
+	args, err := aggregate3.Inputs.Unpack(input)
+	if err != nil {
+		return nil, ErrReverted
+	}
+	var calls []struct {
+		Target       common.Address
+		AllowFailure bool
+		CallData     []byte
+	}
+	if err := aggregate3.Inputs.Copy(&calls, args); err != nil {
+		return nil, ErrReverted
+	}
+	type result struct {
+		Success    bool
+		ReturnData []byte
+	}
+	results := make([]result, len(calls))
+	for i, call := range calls {
+		if len(call.CallData) >= 4 {
+			results[i].ReturnData, results[i].Success = c.results[key(call.Target, call.CallData[:4])]
+		}
+		if !results[i].Success && !call.AllowFailure {
+			return nil, ErrReverted
+		}
+	}
+	return aggregate3.Outputs.Pack(results)
+}
+
+func (c *Contracts) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if err := c.stateLocked(blockNumber); err != nil {
+		return nil, err
+	}
+	if code, ok := c.code[account]; ok {
+		return code, nil
+	}
+	if _, ok := c.storage[account]; ok {
+		return []byte{0xfe}, nil
+	}
+	prefix := account.Hex() + ":"
+	for k := range c.results {
+		if strings.HasPrefix(k, prefix) {
+			return []byte{0xfe}, nil
+		}
+	}
+	return nil, nil
+}
+
+func (c *Contracts) StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if err := c.stateLocked(blockNumber); err != nil {
+		return nil, err
+	}
+	return c.storage[account][key].Bytes(), nil
+}
+
+// FilterLogs returns the added logs in the block range of q emitted by one of its
+// addresses with one of its first topics. Later topic positions are ignored.
+func (c *Contracts) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var logs []types.Log
+	for _, log := range c.logs {
+		if q.FromBlock != nil && log.BlockNumber < q.FromBlock.Uint64() {
+			continue
+		}
+		if q.ToBlock != nil && log.BlockNumber > q.ToBlock.Uint64() {
+			continue
+		}
+		if len(q.Addresses) > 0 && !contains(q.Addresses, log.Address) {
+			continue
+		}
+		if len(q.Topics) > 0 && len(q.Topics[0]) > 0 && (len(log.Topics) == 0 || !contains(q.Topics[0], log.Topics[0])) {
+			continue
+		}
+		logs = append(logs, log)
+	}
+	return logs, nil
+}
+
+func contains[T comparable](values []T, v T) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Contracts) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error) {
+	if msg.To == nil || len(msg.Data) < 4 {
+		return 0, ErrReverted
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	gas, ok := c.gas[key(*msg.To, msg.Data[:4])]
+	if !ok {
+		return 0, ErrReverted
+	}
+	return gas, nil
+}
+
+func (c *Contracts) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return new(big.Int).Set(c.gasPrice), nil
+}
+
+func (c *Contracts) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return new(big.Int).Set(c.tipCap), nil
+}
+
+func (c *Contracts) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.nonces[account], nil
+}
+
+// SendTransaction records tx and advances the pending nonce of its sender. Transactions
+// reusing the nonce of a sent transaction are accepted as replacements; no transaction
+// is ever mined.
+func (c *Contracts) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	signer := types.LatestSignerForChainID(new(big.Int).SetUint64(c.chainID))
+	from, err := types.Sender(signer, tx)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.sendErr != nil {
+		return c.sendErr
+	}
+	if tx.Nonce() < c.nonces[from] && !c.pendingLocked(signer, from, tx.Nonce()) {
+		return errors.New("nonce too low")
+	}
+	if tx.Nonce() >= c.nonces[from] {
+		c.nonces[from] = tx.Nonce() + 1
+	}
+	c.sent = append(c.sent, tx)
+	if c.autoMine {
+		c.mineLocked(tx.Hash(), types.ReceiptStatusSuccessful, tx.Gas())
+	}
+	return nil
+}
+
+func (c *Contracts) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	receipt, ok := c.receipts[txHash]
+	if !ok {
+		return nil, ethereum.NotFound
+	}
+	return receipt, nil
+}
+
+func (c *Contracts) pendingLocked(signer types.Signer, from common.Address, nonce uint64) bool {
+	for _, sent := range c.sent {
+		if sender, _ := types.Sender(signer, sent); sender == from && sent.Nonce() == nonce {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Contracts) Close() {}
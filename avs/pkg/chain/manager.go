@@ -0,0 +1,346 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/najnomics/crosscow-avs/pkg/resilience"
+)
+
+// Config describes a single chain the performer reads from
+type Config struct {
+	ChainID uint64 `yaml:"chainId"`
+	Name    string `yaml:"name"`
+	RpcUrl  string `yaml:"rpcUrl"`
+
+	// WsUrl is an optional WebSocket endpoint new heads and logs are subscribed to.
+	// Without it, consumers poll RpcUrl.
+	WsUrl string `yaml:"wsUrl"`
+
+	// ArchiveRpcUrl is an optional endpoint of an archive node. Once probed, reads pinned
+	// to a block go to it unless RpcUrl serves historical state itself.
+	ArchiveRpcUrl string `yaml:"archiveRpcUrl"`
+
+	// QuorumRpcUrls are optional endpoints of other providers, read only to cross validate
+	// supply rates against. They are named quorum-1, quorum-2 and so on in that order.
+	QuorumRpcUrls []string `yaml:"quorumRpcUrls"`
+}
+
+// Client is the subset of an Ethereum JSON-RPC client the performer depends on
+type Client interface {
+	ChainID(ctx context.Context) (*big.Int, error)
+	BlockNumber(ctx context.Context) (uint64, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error)
+	CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+	CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error)
+	StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error)
+	FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error)
+	EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error)
+	SuggestGasPrice(ctx context.Context) (*big.Int, error)
+	SuggestGasTipCap(ctx context.Context) (*big.Int, error)
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+	SendTransaction(ctx context.Context, tx *types.Transaction) error
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+	Close()
+}
+
+// Manager owns one RPC client per configured chain, and one per archive and quorum
+// endpoint
+type Manager struct {
+	mu       sync.RWMutex
+	clients  map[uint64]Client
+	archives map[uint64]Client
+	quorums  map[uint64][]Client
+	names    map[uint64]string
+	dialers  map[uint64]DialFunc
+
+	// probes are the capabilities of the endpoints of each chain, by endpoint name
+	probes map[uint64]map[string]Capabilities
+
+	policies *resilience.Policies
+
+	// batcher coalesces view calls into Multicall3 batches, nil when batching is disabled
+	batcher *batcher
+}
+
+// NewManager creates an empty manager. Use Dial or Register to add chains.
+func NewManager() *Manager {
+	return &Manager{
+		clients:  make(map[uint64]Client),
+		archives: make(map[uint64]Client),
+		quorums:  make(map[uint64][]Client),
+		names:    make(map[uint64]string),
+		dialers:  make(map[uint64]DialFunc),
+		probes:   make(map[uint64]map[string]Capabilities),
+	}
+}
+
+// NewManagerFromConfig dials every configured chain
+func NewManagerFromConfig(ctx context.Context, cfgs []Config) (*Manager, error) {
+	m := NewManager()
+	for _, cfg := range cfgs {
+		if err := m.Dial(ctx, cfg); err != nil {
+			m.Close()
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Dial connects to the RPC, archive and quorum endpoints of cfg and registers them. The
+// WebSocket endpoint is only dialed by subscriptions.
+func (m *Manager) Dial(ctx context.Context, cfg Config) error {
+	if cfg.ChainID == 0 {
+		return fmt.Errorf("chain id is required")
+	}
+	if cfg.RpcUrl == "" {
+		return fmt.Errorf("rpc url is required for chain %d", cfg.ChainID)
+	}
+
+	client, err := ethclient.DialContext(ctx, cfg.RpcUrl)
+	if err != nil {
+		return fmt.Errorf("failed to dial chain %d: %w", cfg.ChainID, err)
+	}
+	m.Register(cfg.ChainID, cfg.Name, client)
+	if err := m.dialArchive(ctx, cfg); err != nil {
+		return err
+	}
+	if err := m.dialQuorum(ctx, cfg); err != nil {
+		return err
+	}
+	if cfg.WsUrl != "" {
+		m.RegisterSubscriptions(cfg.ChainID, dialWebSocket(cfg.WsUrl))
+	}
+	return nil
+}
+
+// dialArchive connects to the archive endpoint of cfg, or drops the archive endpoint of
+// the chain when cfg has none
+func (m *Manager) dialArchive(ctx context.Context, cfg Config) error {
+	if cfg.ArchiveRpcUrl == "" {
+		m.RegisterArchive(cfg.ChainID, nil)
+		return nil
+	}
+	archive, err := ethclient.DialContext(ctx, cfg.ArchiveRpcUrl)
+	if err != nil {
+		return fmt.Errorf("failed to dial archive endpoint of chain %d: %w", cfg.ChainID, err)
+	}
+	m.RegisterArchive(cfg.ChainID, archive)
+	return nil
+}
+
+// Redial connects to new RPC and archive endpoints of a configured chain, such as ones
+// with a rotated API key, and swaps them in for the current clients. Calls already made
+// over HTTP finish on the previous endpoints. Subscriptions keep their WebSocket endpoint,
+// and cross validation its quorum endpoints.
+// The new endpoints serve the latest state only until probed.
+func (m *Manager) Redial(ctx context.Context, cfg Config) error {
+	m.mu.RLock()
+	_, ok := m.clients[cfg.ChainID]
+	m.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("chain %d is not configured", cfg.ChainID)
+	}
+	if cfg.RpcUrl == "" {
+		return fmt.Errorf("rpc url is required for chain %d", cfg.ChainID)
+	}
+
+	client, err := ethclient.DialContext(ctx, cfg.RpcUrl)
+	if err != nil {
+		return fmt.Errorf("failed to dial chain %d: %w", cfg.ChainID, err)
+	}
+	m.Register(cfg.ChainID, cfg.Name, client)
+	return m.dialArchive(ctx, cfg)
+}
+
+// Register adds an already constructed client for chainID, replacing any existing one
+func (m *Manager) Register(chainID uint64, name string, client Client) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if existing, ok := m.clients[chainID]; ok {
+		existing.Close()
+	}
+	m.clients[chainID] = client
+	m.names[chainID] = name
+	delete(m.probes[chainID], EndpointRPC)
+}
+
+// RegisterArchive sets the client of the archive endpoint of chainID, replacing any
+// existing one. A nil client removes it.
+func (m *Manager) RegisterArchive(chainID uint64, client Client) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if existing, ok := m.archives[chainID]; ok {
+		existing.Close()
+		delete(m.archives, chainID)
+	}
+	if client != nil {
+		m.archives[chainID] = client
+	}
+	delete(m.probes[chainID], EndpointArchive)
+}
+
+// SetPolicies makes clients retry transient errors and break circuits of failing chains.
+// Without policies, clients make every call once.
+func (m *Manager) SetPolicies(policies *resilience.Policies) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.policies = policies
+}
+
+// SetBatching makes the view calls clients make concurrently on a chain go to Multicall3
+// together, under cfg. Without batching, every call is its own eth_call.
+func (m *Manager) SetBatching(cfg BatchConfig) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.batcher = nil
+	if cfg.Enabled {
+		m.batcher = newBatcher(cfg)
+	}
+}
+
+// Client returns the client for chainID, retrying under the rpc policy
+func (m *Manager) Client(chainID uint64) (Client, error) {
+	return m.ClientFor(chainID, resilience.PolicyRPC)
+}
+
+// ClientFor returns the client for chainID, retrying under the policy of caller when one
+// is configured and the rpc policy otherwise. Adapters pass their protocol name so their
+// retries can be tuned separately. Reads pinned to a block go to the archive endpoint
+// when it was probed to serve historical state and the rpc endpoint was not, and reads
+// naming a quorum endpoint with WithEndpoint go to it. With batching, each retry of a
+// view call joins the next batch. Calls made with a context carrying a call trace are
+// recorded in it, see WithCallTrace.
+func (m *Manager) ClientFor(chainID uint64, caller string) (Client, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	client, ok := m.clients[chainID]
+	if !ok {
+		return nil, fmt.Errorf("chain %d is not configured", chainID)
+	}
+	if archive, ok := m.archives[chainID]; ok && !m.probes[chainID][EndpointRPC].Archive && m.probes[chainID][EndpointArchive].Archive {
+		client = &archiveClient{Client: client, archive: archive}
+	}
+	if quorum := m.quorums[chainID]; len(quorum) > 0 {
+		client = newEndpointClient(client, quorum)
+	}
+	if m.batcher != nil {
+		client = &batchingClient{Client: client, chainID: chainID, batcher: m.batcher}
+	}
+	client = &callTracingClient{Client: client, chainID: chainID}
+	return m.withPolicyLocked(chainID, client, caller), nil
+}
+
+// DebugClient returns a client of an endpoint of chainID that was probed to serve the
+// debug namespace, preferring the rpc endpoint, for simulations. It is the rpc endpoint
+// when neither was.
+func (m *Manager) DebugClient(chainID uint64) (Client, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	client, ok := m.clients[chainID]
+	if !ok {
+		return nil, fmt.Errorf("chain %d is not configured", chainID)
+	}
+	if archive, ok := m.archives[chainID]; ok && !m.probes[chainID][EndpointRPC].Debug && m.probes[chainID][EndpointArchive].Debug {
+		client = archive
+	}
+	return m.withPolicyLocked(chainID, client, resilience.PolicyRPC), nil
+}
+
+// withPolicyLocked wraps client to retry under the policy of caller, when policies are
+// configured
+func (m *Manager) withPolicyLocked(chainID uint64, client Client, caller string) Client {
+	if m.policies == nil {
+		return client
+	}
+	if !m.policies.Has(caller) {
+		caller = resilience.PolicyRPC
+	}
+	return &resilientClient{
+		Client:   client,
+		policy:   m.policies.For(caller),
+		endpoint: m.nameLocked(chainID),
+	}
+}
+
+// Name returns the configured human readable name of chainID
+func (m *Manager) Name(chainID uint64) string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.nameLocked(chainID)
+}
+
+func (m *Manager) nameLocked(chainID uint64) string {
+	if name := m.names[chainID]; name != "" {
+		return name
+	}
+	if network, ok := NetworkOf(chainID); ok {
+		name, _ := network.ChainName(chainID)
+		return name
+	}
+	return fmt.Sprintf("chain-%d", chainID)
+}
+
+// ChainIDs returns the configured chain ids in ascending order
+func (m *Manager) ChainIDs() []uint64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.chainIDsLocked()
+}
+
+func (m *Manager) chainIDsLocked() []uint64 {
+	ids := make([]uint64, 0, len(m.clients))
+	for id := range m.clients {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// CheckConnectivity verifies the endpoint for chainID answers and serves the expected chain
+func (m *Manager) CheckConnectivity(ctx context.Context, chainID uint64) error {
+	client, err := m.Client(chainID)
+	if err != nil {
+		return err
+	}
+
+	remoteID, err := client.ChainID(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to query chain id: %w", err)
+	}
+	if remoteID.Uint64() != chainID {
+		return fmt.Errorf("rpc endpoint serves chain %s, expected %d", remoteID.String(), chainID)
+	}
+	if _, err := client.BlockNumber(ctx); err != nil {
+		return fmt.Errorf("failed to query block number: %w", err)
+	}
+	return nil
+}
+
+// Close closes all clients
+func (m *Manager) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, client := range m.clients {
+		client.Close()
+		delete(m.clients, id)
+	}
+	for id, archive := range m.archives {
+		archive.Close()
+		delete(m.archives, id)
+	}
+	for id, quorum := range m.quorums {
+		for _, client := range quorum {
+			client.Close()
+		}
+		delete(m.quorums, id)
+	}
+}
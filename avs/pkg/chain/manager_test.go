@@ -0,0 +1,278 @@
+package chain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/najnomics/crosscow-avs/pkg/resilience"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type fakeClient struct {
+	chainID  uint64
+	blockErr error
+	codeErr  error
+	closed   bool
+
+	// calledAt is the block of the last eth_call, nil for the latest block
+	calledAt *big.Int
+}
+
+func (f *fakeClient) ChainID(ctx context.Context) (*big.Int, error) {
+	return new(big.Int).SetUint64(f.chainID), nil
+}
+
+func (f *fakeClient) BlockNumber(ctx context.Context) (uint64, error) {
+	return 100, f.blockErr
+}
+
+func (f *fakeClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return &types.Header{Number: big.NewInt(100)}, nil
+}
+
+func (f *fakeClient) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	return &types.Header{Number: big.NewInt(100)}, nil
+}
+
+func (f *fakeClient) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	f.calledAt = blockNumber
+	return common.BigToHash(big.NewInt(1)).Bytes(), nil
+}
+
+func (f *fakeClient) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	return nil, f.codeErr
+}
+
+func (f *fakeClient) StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error) {
+	return common.Hash{}.Bytes(), nil
+}
+
+func (f *fakeClient) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	return nil, nil
+}
+
+func (f *fakeClient) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error) {
+	return 21_000, nil
+}
+
+func (f *fakeClient) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(1_000_000_000), nil
+}
+
+func (f *fakeClient) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(1_000_000), nil
+}
+
+func (f *fakeClient) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	return 0, nil
+}
+
+func (f *fakeClient) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	return nil
+}
+
+func (f *fakeClient) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	return nil, ethereum.NotFound
+}
+
+func (f *fakeClient) Close() { f.closed = true }
+
+func Test_CallViewPinnedBlock(t *testing.T) {
+	contractABI := MustParseABI(`[{"name":"totalSupply","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]}]`)
+	client := &fakeClient{chainID: 1}
+	ctx := context.Background()
+
+	if _, err := CallUint(ctx, client, common.Address{}, contractABI, "totalSupply"); err != nil {
+		t.Fatalf("CallUint failed: %v", err)
+	}
+	if client.calledAt != nil {
+		t.Errorf("Expected an unpinned read of the latest block, got block %s", client.calledAt)
+	}
+
+	if _, err := CallUint(WithBlock(ctx, 90), client, common.Address{}, contractABI, "totalSupply"); err != nil {
+		t.Fatalf("CallUint failed: %v", err)
+	}
+	if client.calledAt == nil || client.calledAt.Uint64() != 90 {
+		t.Errorf("Expected a read of block 90, got %v", client.calledAt)
+	}
+}
+
+func Test_ManagerConnectivity(t *testing.T) {
+	m := NewManager()
+	m.Register(8453, "base", &fakeClient{chainID: 8453})
+	m.Register(1, "ethereum", &fakeClient{chainID: 1})
+	m.Register(42161, "arbitrum", &fakeClient{chainID: 10})
+	m.Register(10, "optimism", &fakeClient{chainID: 10, blockErr: errors.New("rate limited")})
+
+	if ids := m.ChainIDs(); len(ids) != 4 || ids[0] != 1 || ids[3] != 42161 {
+		t.Errorf("Unexpected chain ids: %v", ids)
+	}
+
+	ctx := context.Background()
+	if err := m.CheckConnectivity(ctx, 1); err != nil {
+		t.Errorf("Expected chain 1 to be healthy: %v", err)
+	}
+	if err := m.CheckConnectivity(ctx, 42161); err == nil || !strings.Contains(err.Error(), "expected 42161") {
+		t.Errorf("Expected chain id mismatch error, got %v", err)
+	}
+	if err := m.CheckConnectivity(ctx, 10); err == nil {
+		t.Errorf("Expected block number failure to be reported")
+	}
+	if err := m.CheckConnectivity(ctx, 137); err == nil {
+		t.Errorf("Expected unconfigured chain to fail")
+	}
+
+	if m.Name(8453) != "base" || m.Name(84532) != "base-sepolia" || m.Name(137) != "chain-137" {
+		t.Errorf("Unexpected chain names: %s %s %s", m.Name(8453), m.Name(84532), m.Name(137))
+	}
+}
+
+func Test_ManagerCloseClosesClients(t *testing.T) {
+	m := NewManager()
+	client := &fakeClient{chainID: 1}
+	m.Register(1, "ethereum", client)
+	m.Close()
+
+	if !client.closed {
+		t.Errorf("Expected client to be closed")
+	}
+	if _, err := m.Client(1); err == nil {
+		t.Errorf("Expected closed manager to have no clients")
+	}
+}
+
+// flakyClient fails the first calls to BlockNumber with a transient error
+type flakyClient struct {
+	fakeClient
+	failures int
+	calls    int
+}
+
+func (f *flakyClient) BlockNumber(ctx context.Context) (uint64, error) {
+	f.calls++
+	if f.calls <= f.failures {
+		return 0, context.DeadlineExceeded
+	}
+	return 100, nil
+}
+
+func Test_ManagerRedial(t *testing.T) {
+	var served []string
+	newEndpoint := func(name string) *httptest.Server {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var req struct {
+				ID json.RawMessage `json:"id"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			served = append(served, name)
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":"0x1"}`, req.ID)
+		}))
+		t.Cleanup(server.Close)
+		return server
+	}
+	old, rotated := newEndpoint("old"), newEndpoint("rotated")
+
+	m := NewManager()
+	defer m.Close()
+	if err := m.Redial(context.Background(), Config{ChainID: 1, RpcUrl: rotated.URL}); err == nil {
+		t.Errorf("Expected chains that are not configured to be rejected")
+	}
+	if err := m.Dial(context.Background(), Config{ChainID: 1, Name: "ethereum", RpcUrl: old.URL}); err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	if err := m.Redial(context.Background(), Config{ChainID: 1, Name: "ethereum", RpcUrl: rotated.URL}); err != nil {
+		t.Fatalf("Redial failed: %v", err)
+	}
+
+	client, err := m.Client(1)
+	if err != nil {
+		t.Fatalf("Client failed: %v", err)
+	}
+	if _, err := client.BlockNumber(context.Background()); err != nil {
+		t.Fatalf("BlockNumber failed: %v", err)
+	}
+	if len(served) != 1 || served[0] != "rotated" {
+		t.Errorf("Expected calls to go to the new endpoint, went to %v", served)
+	}
+}
+
+func Test_ManagerRetriesUnderPolicy(t *testing.T) {
+	settings := resilience.DefaultSettings()
+	settings.InitialBackoff = time.Millisecond
+	settings.MaxBackoff = time.Millisecond
+
+	flaky := &flakyClient{fakeClient: fakeClient{chainID: 1}, failures: 2}
+	m := NewManager()
+	m.Register(1, "ethereum", flaky)
+	m.SetPolicies(resilience.NewPolicies(resilience.Config{Default: settings}))
+
+	client, err := m.ClientFor(1, "aave_v3")
+	if err != nil {
+		t.Fatalf("ClientFor failed: %v", err)
+	}
+	if _, err := client.BlockNumber(context.Background()); err != nil {
+		t.Errorf("Expected transient errors to be retried: %v", err)
+	}
+	if flaky.calls != 3 {
+		t.Errorf("Expected 3 calls, got %d", flaky.calls)
+	}
+}
+
+func Test_ManagerTracesCalls(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	settings := resilience.DefaultSettings()
+	settings.InitialBackoff = time.Millisecond
+	settings.MaxBackoff = time.Millisecond
+
+	flaky := &flakyClient{fakeClient: fakeClient{chainID: 1}, failures: 2}
+	m := NewManager()
+	m.Register(1, "ethereum", flaky)
+	m.SetPolicies(resilience.NewPolicies(resilience.Config{Default: settings}))
+
+	client, err := m.ClientFor(1, "aave_v3")
+	if err != nil {
+		t.Fatalf("ClientFor failed: %v", err)
+	}
+	if _, err := client.BlockNumber(context.Background()); err != nil {
+		t.Fatalf("BlockNumber failed: %v", err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("Expected one span for the call and its retries, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "rpc BlockNumber" {
+		t.Errorf("Expected span rpc BlockNumber, got %s", span.Name())
+	}
+	var chainName string
+	for _, attr := range span.Attributes() {
+		if attr.Key == "chain" {
+			chainName = attr.Value.AsString()
+		}
+	}
+	if chainName != "ethereum" {
+		t.Errorf("Expected chain attribute ethereum, got %q", chainName)
+	}
+	if len(span.Events()) != 2 {
+		t.Errorf("Expected 2 retry events, got %d", len(span.Events()))
+	}
+}
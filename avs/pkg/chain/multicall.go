@@ -0,0 +1,389 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// Multicall3 is the address Multicall3 is deployed at on every chain the performer reads
+var Multicall3 = common.HexToAddress("0xcA11bde05977b3631167028862bE2a173976CA11")
+
+// Multicall3.aggregate3, which runs every call whether or not others revert when they
+// allow failure
+const multicall3ABIJson = `[
+	{"name":"aggregate3","type":"function","stateMutability":"payable",
+	 "inputs":[{"name":"calls","type":"tuple[]","components":[
+		{"name":"target","type":"address"},
+		{"name":"allowFailure","type":"bool"},
+		{"name":"callData","type":"bytes"}
+	 ]}],
+	 "outputs":[{"name":"returnData","type":"tuple[]","components":[
+		{"name":"success","type":"bool"},
+		{"name":"returnData","type":"bytes"}
+	 ]}]}
+]`
+
+var multicall3ABI = MustParseABI(multicall3ABIJson)
+
+// multicall3Call is a Multicall3 Call3
+type multicall3Call struct {
+	Target       common.Address
+	AllowFailure bool
+	CallData     []byte
+}
+
+// multicall3Result is a Multicall3 Result
+type multicall3Result struct {
+	Success    bool
+	ReturnData []byte
+}
+
+// batchTimeout bounds a coalesced batch, which outlives the contexts of the calls it
+// carries
+const batchTimeout = 30 * time.Second
+
+// errNoMulticall is returned by aggregate on chains Multicall3 is not deployed on
+var errNoMulticall = errors.New("multicall3 is not deployed")
+
+// RevertError is a call of a batch that reverted. It is reported the way nodes report a
+// reverted eth_call, so retries and callers treat both alike.
+type RevertError struct {
+	Data []byte
+}
+
+func (e *RevertError) Error() string { return "execution reverted" }
+
+// ErrorCode is the JSON-RPC error code of reverts
+func (e *RevertError) ErrorCode() int { return 3 }
+
+// ErrorData is the hex encoded revert data
+func (e *RevertError) ErrorData() interface{} { return hexutil.Encode(e.Data) }
+
+// callResult is the outcome of one call of a batch
+type callResult struct {
+	out []byte
+	err error
+}
+
+// aggregate runs msgs at blockNumber in one eth_call to Multicall3. Calls that revert
+// report a RevertError without failing the others.
+func aggregate(ctx context.Context, client Client, msgs []ethereum.CallMsg, blockNumber *big.Int) ([]callResult, error) {
+	calls := make([]multicall3Call, len(msgs))
+	for i, msg := range msgs {
+		calls[i] = multicall3Call{Target: *msg.To, AllowFailure: true, CallData: msg.Data}
+	}
+	data, err := multicall3ABI.Pack("aggregate3", calls)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack aggregate3: %w", err)
+	}
+	out, err := client.CallContract(ctx, ethereum.CallMsg{To: &Multicall3, Data: data}, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	// calls to accounts without code succeed without output
+	if len(out) == 0 {
+		return nil, errNoMulticall
+	}
+	values, err := multicall3ABI.Unpack("aggregate3", out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack aggregate3: %w", err)
+	}
+	returned := *abi.ConvertType(values[0], new([]multicall3Result)).(*[]multicall3Result)
+	if len(returned) != len(msgs) {
+		return nil, fmt.Errorf("aggregate3 returned %d results for %d calls", len(returned), len(msgs))
+	}
+	results := make([]callResult, len(msgs))
+	for i, r := range returned {
+		if r.Success {
+			results[i].out = r.ReturnData
+		} else {
+			results[i].err = &RevertError{Data: r.ReturnData}
+		}
+	}
+	return results, nil
+}
+
+// batchable reports whether msg is a plain view call that may run from Multicall3
+func batchable(msg ethereum.CallMsg) bool {
+	return msg.To != nil && *msg.To != Multicall3 && msg.From == (common.Address{}) && len(msg.Data) >= 4 &&
+		msg.Gas == 0 && (msg.Value == nil || msg.Value.Sign() == 0) &&
+		msg.GasPrice == nil && msg.GasFeeCap == nil && msg.GasTipCap == nil
+}
+
+// Batch collects view calls that do not depend on each other and sends them to Multicall3
+// in one eth_call. On chains without Multicall3 they are sent one by one.
+type Batch struct {
+	client Client
+	calls  []*BatchCall
+}
+
+// BatchCall is a view call of a batch, whose outputs are read once the batch was sent
+type BatchCall struct {
+	contract    common.Address
+	contractABI abi.ABI
+	method      string
+	data        []byte
+
+	values []interface{}
+	err    error
+}
+
+// NewBatch creates an empty batch of calls made through client
+func NewBatch(client Client) *Batch {
+	return &Batch{client: client}
+}
+
+// Add queues a call of method on contract
+func (b *Batch) Add(contract common.Address, contractABI abi.ABI, method string, args ...interface{}) *BatchCall {
+	call := &BatchCall{contract: contract, contractABI: contractABI, method: method}
+	call.data, call.err = contractABI.Pack(method, args...)
+	if call.err != nil {
+		call.err = fmt.Errorf("failed to pack %s: %w", method, call.err)
+	}
+	b.calls = append(b.calls, call)
+	return call
+}
+
+// Do sends the queued calls at the block ctx is pinned to, or the latest block. It fails
+// only when the batch could not be sent; calls that reverted report it from Values.
+func (b *Batch) Do(ctx context.Context) error {
+	var pending []*BatchCall
+	for _, call := range b.calls {
+		if call.err == nil && call.values == nil {
+			pending = append(pending, call)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	if len(pending) > 1 {
+		msgs := make([]ethereum.CallMsg, len(pending))
+		for i, call := range pending {
+			msgs[i] = ethereum.CallMsg{To: &call.contract, Data: call.data}
+		}
+		results, err := aggregate(ctx, b.client, msgs, BlockOf(ctx))
+		switch {
+		case err == nil:
+			for i, call := range pending {
+				call.unpack(ctx, results[i].out, results[i].err)
+			}
+			return nil
+		case !errors.Is(err, errNoMulticall):
+			return fmt.Errorf("batch of %d calls failed: %w", len(pending), historical(ctx, err))
+		}
+	}
+	for _, call := range pending {
+		out, err := b.client.CallContract(ctx, ethereum.CallMsg{To: &call.contract, Data: call.data}, BlockOf(ctx))
+		call.unpack(ctx, out, err)
+	}
+	return nil
+}
+
+// unpack decodes the output of the call, or records why it failed
+func (c *BatchCall) unpack(ctx context.Context, out []byte, err error) {
+	if err != nil {
+		c.err = fmt.Errorf("call to %s on %s failed: %w", c.method, c.contract.Hex(), historical(ctx, err))
+		return
+	}
+	if c.values, err = c.contractABI.Unpack(c.method, out); err != nil {
+		c.err = fmt.Errorf("failed to unpack %s from %s: %w", c.method, c.contract.Hex(), err)
+	}
+}
+
+// Values returns the decoded outputs of the call
+func (c *BatchCall) Values() ([]interface{}, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	if c.values == nil {
+		return nil, fmt.Errorf("call to %s on %s was not sent", c.method, c.contract.Hex())
+	}
+	return c.values, nil
+}
+
+// Uint returns the output of a call returning a single integer
+func (c *BatchCall) Uint() (*big.Int, error) {
+	values, err := c.Values()
+	if err != nil {
+		return nil, err
+	}
+	value, ok := values[0].(*big.Int)
+	if !ok {
+		return nil, fmt.Errorf("unexpected %s output type %T", c.method, values[0])
+	}
+	return value, nil
+}
+
+// BatchConfig coalesces the view calls made concurrently on a chain, such as those of
+// tasks reading many markets at once, into Multicall3 batches
+type BatchConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Window is how long a call waits for others to join its batch
+	Window time.Duration `yaml:"window"`
+
+	// MaxCalls sends a batch as soon as it holds this many calls
+	MaxCalls int `yaml:"maxCalls"`
+}
+
+// DefaultBatchConfig waits 2ms for up to 100 calls
+func DefaultBatchConfig() BatchConfig {
+	return BatchConfig{Enabled: true, Window: 2 * time.Millisecond, MaxCalls: 100}
+}
+
+// Validate checks the window and batch size of enabled batching
+func (c BatchConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Window <= 0 || c.Window > time.Second {
+		return fmt.Errorf("window must be between 0 and 1s")
+	}
+	if c.MaxCalls < 2 {
+		return fmt.Errorf("maxCalls must be at least 2")
+	}
+	return nil
+}
+
+// batcher coalesces calls by chain and block
+type batcher struct {
+	cfg BatchConfig
+
+	mu      sync.Mutex
+	pending map[batchKey]*pendingBatch
+}
+
+type batchKey struct {
+	chainID uint64
+
+	// endpoint is the quorum endpoint the calls go to, empty for the rpc endpoint
+	endpoint string
+
+	// block is the block the calls read, empty for the latest block
+	block string
+}
+
+// pendingBatch is a batch waiting for calls to join it. It is sent through the client and
+// with the context of the call that opened it.
+type pendingBatch struct {
+	ctx    context.Context
+	client Client
+	block  *big.Int
+	calls  []*pendingCall
+	timer  *time.Timer
+}
+
+type pendingCall struct {
+	msg  ethereum.CallMsg
+	done chan struct{}
+	callResult
+
+	// direct is set when the call is left to be sent on its own
+	direct bool
+}
+
+func newBatcher(cfg BatchConfig) *batcher {
+	return &batcher{cfg: cfg, pending: make(map[batchKey]*pendingBatch)}
+}
+
+// call adds msg to the batch of its chain and block and waits for its result
+func (b *batcher) call(ctx context.Context, chainID uint64, client Client, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	call := &pendingCall{msg: msg, done: make(chan struct{})}
+	key := batchKey{chainID: chainID, endpoint: EndpointOf(ctx)}
+	if blockNumber != nil {
+		key.block = blockNumber.String()
+	}
+
+	b.mu.Lock()
+	batch, ok := b.pending[key]
+	if !ok {
+		batch = &pendingBatch{ctx: ctx, client: client, block: blockNumber}
+		b.pending[key] = batch
+		batch.timer = time.AfterFunc(b.cfg.Window, func() { b.flush(key, batch) })
+	}
+	batch.calls = append(batch.calls, call)
+	if len(batch.calls) >= b.cfg.MaxCalls {
+		delete(b.pending, key)
+		batch.timer.Stop()
+		go b.send(batch)
+	}
+	b.mu.Unlock()
+
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if call.direct {
+		return client.CallContract(ctx, msg, blockNumber)
+	}
+	return call.out, call.err
+}
+
+// flush sends batch when its window elapsed, unless it filled up first
+func (b *batcher) flush(key batchKey, batch *pendingBatch) {
+	b.mu.Lock()
+	if b.pending[key] != batch {
+		b.mu.Unlock()
+		return
+	}
+	delete(b.pending, key)
+	b.mu.Unlock()
+	b.send(batch)
+}
+
+// send aggregates the calls of batch. A call without company, and every call on chains
+// without Multicall3, is left to its caller to send on its own.
+func (b *batcher) send(batch *pendingBatch) {
+	defer func() {
+		for _, call := range batch.calls {
+			close(call.done)
+		}
+	}()
+	if len(batch.calls) == 1 {
+		batch.calls[0].direct = true
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(batch.ctx), batchTimeout)
+	defer cancel()
+	msgs := make([]ethereum.CallMsg, len(batch.calls))
+	for i, call := range batch.calls {
+		msgs[i] = call.msg
+	}
+	results, err := aggregate(ctx, batch.client, msgs, batch.block)
+	for i, call := range batch.calls {
+		switch {
+		case errors.Is(err, errNoMulticall):
+			call.direct = true
+		case err != nil:
+			call.err = err
+		default:
+			call.callResult = results[i]
+		}
+	}
+}
+
+// batchingClient sends the view calls of a chain through its batcher
+type batchingClient struct {
+	Client
+	chainID uint64
+	batcher *batcher
+}
+
+func (c *batchingClient) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	if !batchable(msg) {
+		return c.Client.CallContract(ctx, msg, blockNumber)
+	}
+	return c.batcher.call(ctx, c.chainID, c.Client, msg, blockNumber)
+}
@@ -0,0 +1,122 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/chain/chaintest"
+)
+
+var erc20 = MustParseABI(`[
+	{"name":"totalSupply","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+	{"name":"balanceOf","type":"function","stateMutability":"view","inputs":[{"name":"account","type":"address"}],"outputs":[{"name":"","type":"uint256"}]}
+]`)
+
+// withoutMulticall is a chain Multicall3 is not deployed on
+type withoutMulticall struct {
+	*chaintest.Contracts
+}
+
+func (c withoutMulticall) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	if *msg.To == Multicall3 {
+		return nil, nil
+	}
+	return c.Contracts.CallContract(ctx, msg, blockNumber)
+}
+
+func Test_Batch(t *testing.T) {
+	token, other := common.HexToAddress("0x01"), common.HexToAddress("0x02")
+	contracts := chaintest.NewContracts(1)
+	contracts.Stub(t, token, erc20, "totalSupply", big.NewInt(1000))
+	contracts.Stub(t, other, erc20, "totalSupply", big.NewInt(2000))
+
+	for name, client := range map[string]Client{"multicall": contracts, "without multicall": withoutMulticall{contracts}} {
+		before := contracts.Calls()
+		batch := NewBatch(client)
+		supply := batch.Add(token, erc20, "totalSupply")
+		otherSupply := batch.Add(other, erc20, "totalSupply")
+		balance := batch.Add(token, erc20, "balanceOf", common.HexToAddress("0xaa"))
+		if err := batch.Do(context.Background()); err != nil {
+			t.Fatalf("%s: Do failed: %v", name, err)
+		}
+
+		if v, err := supply.Uint(); err != nil || v.Int64() != 1000 {
+			t.Errorf("%s: Expected a supply of 1000, got %v (%v)", name, v, err)
+		}
+		if v, err := otherSupply.Uint(); err != nil || v.Int64() != 2000 {
+			t.Errorf("%s: Expected a supply of 2000, got %v (%v)", name, v, err)
+		}
+		if _, err := balance.Uint(); err == nil {
+			t.Errorf("%s: Expected the unstubbed call to revert", name)
+		}
+		if name == "multicall" {
+			var revert *RevertError
+			if _, err := balance.Uint(); !errors.As(err, &revert) {
+				t.Errorf("Expected a revert error, got %v", err)
+			}
+			if calls := contracts.Calls() - before; calls != 1 {
+				t.Errorf("Expected one eth_call, got %d", calls)
+			}
+		}
+	}
+
+	contracts.PruneBefore(50)
+	batch := NewBatch(contracts)
+	batch.Add(token, erc20, "totalSupply")
+	batch.Add(other, erc20, "totalSupply")
+	if err := batch.Do(WithBlock(context.Background(), 10)); !errors.Is(err, ErrHistoricalState) {
+		t.Errorf("Expected reads of pruned state to fail with ErrHistoricalState, got %v", err)
+	}
+}
+
+func Test_ManagerBatchesConcurrentCalls(t *testing.T) {
+	const markets = 8
+	contracts := chaintest.NewContracts(1)
+	for i := 1; i <= markets; i++ {
+		contracts.Stub(t, common.BigToAddress(big.NewInt(int64(i))), erc20, "totalSupply", big.NewInt(int64(i)))
+	}
+	m := NewManager()
+	m.Register(1, "ethereum", contracts)
+	// batches are sent once every market joined, well within the window
+	m.SetBatching(BatchConfig{Enabled: true, Window: time.Minute, MaxCalls: markets})
+
+	client, err := m.Client(1)
+	if err != nil {
+		t.Fatalf("Client failed: %v", err)
+	}
+	var wg sync.WaitGroup
+	supplies := make([]*big.Int, markets)
+	errs := make([]error, markets)
+	for i := range supplies {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			supplies[i], errs[i] = CallUint(context.Background(), client, common.BigToAddress(big.NewInt(int64(i+1))), erc20, "totalSupply")
+		}()
+	}
+	wg.Wait()
+	for i := range supplies {
+		if errs[i] != nil || supplies[i].Int64() != int64(i+1) {
+			t.Errorf("Expected market %d to have a supply of %d, got %v (%v)", i+1, i+1, supplies[i], errs[i])
+		}
+	}
+	if calls := contracts.Calls(); calls != 1 {
+		t.Errorf("Expected the reads to share one eth_call, got %d", calls)
+	}
+
+	// a call without company is sent on its own once the window elapses
+	m.SetBatching(BatchConfig{Enabled: true, Window: time.Millisecond, MaxCalls: markets})
+	client, _ = m.Client(1)
+	if _, err := CallUint(context.Background(), client, common.BigToAddress(big.NewInt(9)), erc20, "totalSupply"); err == nil {
+		t.Errorf("Expected the unstubbed call to revert")
+	}
+	if calls := contracts.Calls(); calls != 2 {
+		t.Errorf("Expected one more eth_call, got %d", calls)
+	}
+}
@@ -0,0 +1,51 @@
+package chain
+
+import "fmt"
+
+// Network is the set of chains a deployment runs against. Testnet deployments rehearse
+// the mainnet flow on the testnet counterpart of every supported chain.
+type Network string
+
+const (
+	NetworkMainnet Network = "mainnet"
+	NetworkTestnet Network = "testnet"
+)
+
+// networkChains are the supported chains of each network, with their default names
+var networkChains = map[Network]map[uint64]string{
+	NetworkMainnet: {
+		1:     "ethereum",
+		8453:  "base",
+		42161: "arbitrum",
+	},
+	NetworkTestnet: {
+		11155111: "sepolia",
+		84532:    "base-sepolia",
+		421614:   "arbitrum-sepolia",
+	},
+}
+
+// Validate checks n is a known network
+func (n Network) Validate() error {
+	if _, ok := networkChains[n]; !ok {
+		return fmt.Errorf("unknown network %q, expected %s or %s", n, NetworkMainnet, NetworkTestnet)
+	}
+	return nil
+}
+
+// ChainName returns the default name of chainID on n, false when n does not support it
+func (n Network) ChainName(chainID uint64) (string, bool) {
+	name, ok := networkChains[n][chainID]
+	return name, ok
+}
+
+// NetworkOf returns the network chainID is a supported chain of. Local and unsupported
+// chains belong to no network.
+func NetworkOf(chainID uint64) (Network, bool) {
+	for network, chains := range networkChains {
+		if _, ok := chains[chainID]; ok {
+			return network, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,138 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ErrHistoricalState is returned for reads pinned to a block whose state the node no
+// longer keeps, as full nodes do for all but the latest blocks. Such reads need an
+// archive node.
+var ErrHistoricalState = errors.New("node cannot serve historical state")
+
+// historicalStateMessages are substrings of the errors nodes return for state they pruned
+var historicalStateMessages = []string{
+	"missing trie node",
+	"historical state",
+	"state not available",
+	"state is not available",
+	"state histories",
+	"pruned",
+}
+
+type blockKey struct{}
+
+// WithBlock pins the reads made with the returned context to block number. Reads through
+// CallView, Head, HeadHeader, StorageAt and CodeAt honour it.
+func WithBlock(ctx context.Context, number uint64) context.Context {
+	return context.WithValue(ctx, blockKey{}, number)
+}
+
+// BlockOf returns the block reads made with ctx are pinned to, nil for the latest block
+func BlockOf(ctx context.Context) *big.Int {
+	number, ok := ctx.Value(blockKey{}).(uint64)
+	if !ok {
+		return nil
+	}
+	return new(big.Int).SetUint64(number)
+}
+
+type blockTimeKey struct{}
+
+// WithBlockTime pins the reads made with the returned context to block number like
+// WithBlock, and makes at, the time the block was produced, the present of computations
+// measuring history up to now, such as rates derived from share price appreciation
+func WithBlockTime(ctx context.Context, number uint64, at time.Time) context.Context {
+	return context.WithValue(WithBlock(ctx, number), blockTimeKey{}, at)
+}
+
+// TimeOf returns the time of the block ctx is pinned to by WithBlockTime, or now
+func TimeOf(ctx context.Context, now func() time.Time) time.Time {
+	if at, ok := ctx.Value(blockTimeKey{}).(time.Time); ok {
+		return at
+	}
+	return now()
+}
+
+// Head returns the block ctx is pinned to, or the latest block number. Log queries end at
+// it, so they cover the same blocks as the pinned reads.
+func Head(ctx context.Context, client Client) (uint64, error) {
+	if block := BlockOf(ctx); block != nil {
+		return block.Uint64(), nil
+	}
+	return client.BlockNumber(ctx)
+}
+
+// HeadHeader returns the header of the block ctx is pinned to, or the latest header
+func HeadHeader(ctx context.Context, client Client) (*types.Header, error) {
+	header, err := client.HeaderByNumber(ctx, BlockOf(ctx))
+	if err != nil {
+		return nil, historical(ctx, err)
+	}
+	return header, nil
+}
+
+// StorageAt reads a storage slot of account at the block ctx is pinned to
+func StorageAt(ctx context.Context, client Client, account common.Address, slot common.Hash) ([]byte, error) {
+	value, err := client.StorageAt(ctx, account, slot, BlockOf(ctx))
+	if err != nil {
+		return nil, historical(ctx, err)
+	}
+	return value, nil
+}
+
+// CodeAt reads the code of account at the block ctx is pinned to
+func CodeAt(ctx context.Context, client Client, account common.Address) ([]byte, error) {
+	code, err := client.CodeAt(ctx, account, BlockOf(ctx))
+	if err != nil {
+		return nil, historical(ctx, err)
+	}
+	return code, nil
+}
+
+// historical wraps errors of pinned reads the node failed for lack of historical state
+// with ErrHistoricalState
+func historical(ctx context.Context, err error) error {
+	block := BlockOf(ctx)
+	if block == nil {
+		return err
+	}
+	if isHistoricalState(err) {
+		return fmt.Errorf("%w at block %s: %v", ErrHistoricalState, block, err)
+	}
+	return err
+}
+
+// archiveClient sends the state reads of a client pinned to a block to an archive node
+type archiveClient struct {
+	Client
+	archive Client
+}
+
+func (c *archiveClient) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	if blockNumber != nil {
+		return c.archive.CallContract(ctx, msg, blockNumber)
+	}
+	return c.Client.CallContract(ctx, msg, blockNumber)
+}
+
+func (c *archiveClient) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	if blockNumber != nil {
+		return c.archive.CodeAt(ctx, account, blockNumber)
+	}
+	return c.Client.CodeAt(ctx, account, blockNumber)
+}
+
+func (c *archiveClient) StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error) {
+	if blockNumber != nil {
+		return c.archive.StorageAt(ctx, account, key, blockNumber)
+	}
+	return c.Client.StorageAt(ctx, account, key, blockNumber)
+}
@@ -0,0 +1,205 @@
+package chain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Endpoints of a chain
+const (
+	// EndpointRPC is the rpcUrl endpoint every read goes to by default
+	EndpointRPC = "rpc"
+
+	// EndpointArchive is the optional archiveRpcUrl endpoint
+	EndpointArchive = "archive"
+)
+
+// archiveProbeBlock is the block whose state archive probes read. Only archive nodes keep
+// the state of blocks that old.
+var archiveProbeBlock = big.NewInt(1)
+
+// rpcCodeMethodNotFound is the JSON-RPC error code of methods a node does not serve
+const rpcCodeMethodNotFound = -32601
+
+// methodMissingMessages are substrings of the errors nodes return for methods they do not
+// serve, or serve only when enabled, without using the standard error code
+var methodMissingMessages = []string{
+	"method not found",
+	"does not exist",
+	"is not available",
+	"not supported",
+	"unsupported method",
+}
+
+// RPCCaller makes raw JSON-RPC calls, for methods Client has no wrapper for
+type RPCCaller interface {
+	CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error
+}
+
+// Capabilities are what an endpoint serves beyond the latest state, as last probed
+type Capabilities struct {
+	// Archive is set when the endpoint serves the state of old blocks
+	Archive bool `json:"archive"`
+
+	// Debug is set when the endpoint serves the debug namespace, such as debug_traceCall
+	Debug bool `json:"debug"`
+
+	// Trace is set when the endpoint serves the trace namespace of Erigon and Nethermind
+	Trace bool `json:"trace"`
+
+	ProbedAt time.Time `json:"probedAt"`
+
+	// Error is why the endpoint could not be probed, in which case it is assumed to serve
+	// nothing beyond the latest state
+	Error string `json:"error,omitempty"`
+}
+
+// EndpointCapabilities are the capabilities of one endpoint of a chain
+type EndpointCapabilities struct {
+	ChainID  uint64 `json:"chainId"`
+	Chain    string `json:"chain"`
+	Endpoint string `json:"endpoint"`
+	Capabilities
+}
+
+// endpoint is a client of a chain to probe
+type endpoint struct {
+	chainID uint64
+	name    string
+	client  Client
+}
+
+// Probe checks whether the endpoints of chainIDs, every chain when none are given, serve
+// historical state and the debug and trace namespaces, and routes reads by what they
+// serve from then on. It returns the capabilities of the probed endpoints.
+func (m *Manager) Probe(ctx context.Context, chainIDs ...uint64) []EndpointCapabilities {
+	if len(chainIDs) == 0 {
+		chainIDs = m.ChainIDs()
+	}
+	m.mu.RLock()
+	var endpoints []endpoint
+	for _, id := range chainIDs {
+		if client, ok := m.clients[id]; ok {
+			endpoints = append(endpoints, endpoint{chainID: id, name: EndpointRPC, client: client})
+		}
+		if archive, ok := m.archives[id]; ok {
+			endpoints = append(endpoints, endpoint{chainID: id, name: EndpointArchive, client: archive})
+		}
+	}
+	m.mu.RUnlock()
+
+	probed := make([]Capabilities, len(endpoints))
+	var wg sync.WaitGroup
+	for i, e := range endpoints {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			probed[i] = probe(ctx, e.client)
+		}()
+	}
+	wg.Wait()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]EndpointCapabilities, len(endpoints))
+	for i, e := range endpoints {
+		if m.probes[e.chainID] == nil {
+			m.probes[e.chainID] = make(map[string]Capabilities)
+		}
+		m.probes[e.chainID][e.name] = probed[i]
+		out[i] = EndpointCapabilities{ChainID: e.chainID, Chain: m.nameLocked(e.chainID), Endpoint: e.name, Capabilities: probed[i]}
+	}
+	return out
+}
+
+// Capabilities returns the capabilities of every probed endpoint, ordered by chain with
+// the rpc endpoint first
+func (m *Manager) Capabilities() []EndpointCapabilities {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := []EndpointCapabilities{}
+	for _, id := range m.chainIDsLocked() {
+		for _, name := range []string{EndpointRPC, EndpointArchive} {
+			if caps, ok := m.probes[id][name]; ok {
+				out = append(out, EndpointCapabilities{ChainID: id, Chain: m.nameLocked(id), Endpoint: name, Capabilities: caps})
+			}
+		}
+	}
+	return out
+}
+
+// probe reads the state of an old block and calls a debug and a trace method on client.
+// Clients without raw JSON-RPC access are only probed for historical state.
+func probe(ctx context.Context, client Client) Capabilities {
+	caps := Capabilities{ProbedAt: time.Now().UTC()}
+	_, err := client.CodeAt(ctx, common.Address{}, archiveProbeBlock)
+	switch {
+	case err == nil:
+		caps.Archive = true
+	case !isHistoricalState(err):
+		caps.Error = err.Error()
+		return caps
+	}
+
+	caller := rpcCaller(client)
+	if caller == nil {
+		return caps
+	}
+	// transactions that do not exist are looked up like any other, so nodes serving the
+	// method answer with a result or an error of their own
+	var result json.RawMessage
+	caps.Debug = servesMethod(caller.CallContext(ctx, &result, "debug_traceTransaction", common.Hash{}))
+	caps.Trace = servesMethod(caller.CallContext(ctx, &result, "trace_transaction", common.Hash{}))
+	return caps
+}
+
+// rpcCaller returns the raw JSON-RPC client of client, nil when it has none
+func rpcCaller(client Client) RPCCaller {
+	switch c := client.(type) {
+	case RPCCaller:
+		return c
+	case interface{ Client() *rpc.Client }:
+		return c.Client()
+	}
+	return nil
+}
+
+// servesMethod reports whether the outcome err of a call shows the node serves the method
+// called: it answered, or failed the call for a reason other than the method. Calls that
+// failed in transport or were refused over HTTP tell nothing, and count as not served.
+func servesMethod(err error) bool {
+	if err == nil {
+		return true
+	}
+	var rpcErr rpc.Error
+	if !errors.As(err, &rpcErr) || rpcErr.ErrorCode() == rpcCodeMethodNotFound {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, m := range methodMissingMessages {
+		if strings.Contains(msg, m) {
+			return false
+		}
+	}
+	return true
+}
+
+// isHistoricalState reports whether err is a node failing a read for lack of the state
+// of its block
+func isHistoricalState(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, m := range historicalStateMessages {
+		if strings.Contains(msg, m) {
+			return true
+		}
+	}
+	return false
+}
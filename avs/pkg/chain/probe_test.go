@@ -0,0 +1,142 @@
+package chain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// newNode serves JSON-RPC, answering the methods in errors with their error and every
+// other method with "0x"
+func newNode(t *testing.T, errors map[string]string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		w.Header().Set("Content-Type", "application/json")
+		if rpcErr, ok := errors[req.Method]; ok {
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"error":%s}`, req.ID, rpcErr)
+			return
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":"0x"}`, req.ID)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func Test_ManagerProbe(t *testing.T) {
+	// a pruned node with the debug namespace enabled but no trace namespace
+	full := newNode(t, map[string]string{
+		"eth_getCode":       `{"code":-32000,"message":"missing trie node 1b2c (path ) state 0x1b2c is not available"}`,
+		"trace_transaction": `{"code":-32601,"message":"the method trace_transaction does not exist/is not available"}`,
+	})
+	archive := newNode(t, map[string]string{
+		"debug_traceTransaction": `{"code":-32000,"message":"transaction 0x0000000000000000000000000000000000000000000000000000000000000000 not found"}`,
+	})
+
+	m := NewManager()
+	defer m.Close()
+	if err := m.Dial(context.Background(), Config{ChainID: 1, Name: "ethereum", RpcUrl: full.URL, ArchiveRpcUrl: archive.URL}); err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	if got := m.Capabilities(); len(got) != 0 {
+		t.Errorf("Expected no capabilities before probing, got %+v", got)
+	}
+
+	probed := m.Probe(context.Background())
+	if len(probed) != 2 {
+		t.Fatalf("Expected both endpoints to be probed, got %+v", probed)
+	}
+	for _, tc := range []struct {
+		endpoint string
+		want     Capabilities
+	}{
+		{endpoint: EndpointRPC, want: Capabilities{Debug: true}},
+		{endpoint: EndpointArchive, want: Capabilities{Archive: true, Debug: true, Trace: true}},
+	} {
+		var got *EndpointCapabilities
+		for i := range probed {
+			if probed[i].Endpoint == tc.endpoint {
+				got = &probed[i]
+			}
+		}
+		if got == nil || got.Chain != "ethereum" || got.Error != "" {
+			t.Errorf("Expected the %s endpoint of ethereum to be probed, got %+v", tc.endpoint, got)
+			continue
+		}
+		if got.Archive != tc.want.Archive || got.Debug != tc.want.Debug || got.Trace != tc.want.Trace {
+			t.Errorf("Expected the %s endpoint to serve %+v, got %+v", tc.endpoint, tc.want, got.Capabilities)
+		}
+	}
+	if got := m.Capabilities(); len(got) != 2 || got[0].Endpoint != EndpointRPC || got[1].Endpoint != EndpointArchive {
+		t.Errorf("Expected the rpc then archive capabilities, got %+v", got)
+	}
+
+	// the rpc endpoint serves debug itself, so simulations stay on it
+	debug, err := m.DebugClient(1)
+	if err != nil {
+		t.Fatalf("DebugClient failed: %v", err)
+	}
+	if _, ok := debug.(*archiveClient); ok {
+		t.Errorf("Expected simulations to stay on the rpc endpoint")
+	}
+
+	if err := m.Redial(context.Background(), Config{ChainID: 1, Name: "ethereum", RpcUrl: full.URL}); err != nil {
+		t.Fatalf("Redial failed: %v", err)
+	}
+	if got := m.Capabilities(); len(got) != 0 {
+		t.Errorf("Expected redialed endpoints to be probed again, got %+v", got)
+	}
+}
+
+func Test_ProbeUnreachableEndpoint(t *testing.T) {
+	caps := probe(context.Background(), &fakeClient{codeErr: fmt.Errorf("connection refused")})
+	if caps.Error == "" || caps.Archive || caps.Debug || caps.Trace {
+		t.Errorf("Expected an unreachable endpoint to serve nothing, got %+v", caps)
+	}
+}
+
+func Test_ManagerRoutesPinnedReadsToArchive(t *testing.T) {
+	rpc, archive := &fakeClient{chainID: 1}, &fakeClient{chainID: 1}
+	m := NewManager()
+	m.Register(1, "ethereum", rpc)
+	m.RegisterArchive(1, archive)
+	m.probes[1] = map[string]Capabilities{EndpointRPC: {}, EndpointArchive: {Archive: true}}
+
+	client, err := m.ClientFor(1, "aave_v3")
+	if err != nil {
+		t.Fatalf("ClientFor failed: %v", err)
+	}
+	ctx := context.Background()
+	if _, err := client.CallContract(ctx, ethereum.CallMsg{To: &common.Address{}}, nil); err != nil {
+		t.Fatalf("CallContract failed: %v", err)
+	}
+	if _, err := client.CallContract(ctx, ethereum.CallMsg{To: &common.Address{}}, big.NewInt(90)); err != nil {
+		t.Fatalf("CallContract failed: %v", err)
+	}
+	if rpc.calledAt != nil || archive.calledAt == nil || archive.calledAt.Uint64() != 90 {
+		t.Errorf("Expected latest reads on the rpc endpoint and pinned reads on the archive, got %v and %v", rpc.calledAt, archive.calledAt)
+	}
+
+	m.RegisterArchive(1, nil)
+	if !archive.closed {
+		t.Errorf("Expected the removed archive client to be closed")
+	}
+	client, _ = m.ClientFor(1, "aave_v3")
+	if _, err := client.CallContract(ctx, ethereum.CallMsg{To: &common.Address{}}, big.NewInt(80)); err != nil {
+		t.Fatalf("CallContract failed: %v", err)
+	}
+	if rpc.calledAt == nil || rpc.calledAt.Uint64() != 80 {
+		t.Errorf("Expected reads to go to the rpc endpoint without an archive, got %v", rpc.calledAt)
+	}
+}
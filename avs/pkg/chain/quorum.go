@@ -0,0 +1,133 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// EndpointQuorum prefixes the names of the quorumRpcUrls endpoints of a chain
+const EndpointQuorum = "quorum"
+
+// QuorumEndpoint names the i-th quorum endpoint of a chain, counting from 0, as
+// "quorum-1", "quorum-2" and so on
+func QuorumEndpoint(i int) string {
+	return fmt.Sprintf("%s-%d", EndpointQuorum, i+1)
+}
+
+type endpointKey struct{}
+
+// WithEndpoint sends the reads made with the returned context to the named quorum
+// endpoint of the chain instead of its rpc endpoint. Chains without the endpoint read
+// from their rpc endpoint as usual.
+func WithEndpoint(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, endpointKey{}, name)
+}
+
+// EndpointOf returns the quorum endpoint reads made with ctx go to, empty for the rpc
+// endpoint
+func EndpointOf(ctx context.Context) string {
+	name, _ := ctx.Value(endpointKey{}).(string)
+	return name
+}
+
+// dialQuorum connects to the quorum endpoints of cfg, replacing those of the chain
+func (m *Manager) dialQuorum(ctx context.Context, cfg Config) error {
+	clients := make([]Client, 0, len(cfg.QuorumRpcUrls))
+	for i, url := range cfg.QuorumRpcUrls {
+		client, err := ethclient.DialContext(ctx, url)
+		if err != nil {
+			for _, c := range clients {
+				c.Close()
+			}
+			return fmt.Errorf("failed to dial %s endpoint of chain %d: %w", QuorumEndpoint(i), cfg.ChainID, err)
+		}
+		clients = append(clients, client)
+	}
+	m.RegisterQuorum(cfg.ChainID, clients...)
+	return nil
+}
+
+// RegisterQuorum sets the clients of the quorum endpoints of chainID, in order, replacing
+// any existing ones. No clients removes them.
+func (m *Manager) RegisterQuorum(chainID uint64, clients ...Client) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, existing := range m.quorums[chainID] {
+		existing.Close()
+	}
+	delete(m.quorums, chainID)
+	if len(clients) > 0 {
+		m.quorums[chainID] = clients
+	}
+}
+
+// HasEndpoint reports whether chainID has the named quorum endpoint
+func (m *Manager) HasEndpoint(chainID uint64, name string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for i := range m.quorums[chainID] {
+		if QuorumEndpoint(i) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// endpointClient sends the reads of contexts naming a quorum endpoint to it
+type endpointClient struct {
+	Client
+	endpoints map[string]Client
+}
+
+func newEndpointClient(client Client, quorum []Client) *endpointClient {
+	endpoints := make(map[string]Client, len(quorum))
+	for i, c := range quorum {
+		endpoints[QuorumEndpoint(i)] = c
+	}
+	return &endpointClient{Client: client, endpoints: endpoints}
+}
+
+func (c *endpointClient) pick(ctx context.Context) Client {
+	if endpoint, ok := c.endpoints[EndpointOf(ctx)]; ok {
+		return endpoint
+	}
+	return c.Client
+}
+
+func (c *endpointClient) ChainID(ctx context.Context) (*big.Int, error) {
+	return c.pick(ctx).ChainID(ctx)
+}
+
+func (c *endpointClient) BlockNumber(ctx context.Context) (uint64, error) {
+	return c.pick(ctx).BlockNumber(ctx)
+}
+
+func (c *endpointClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return c.pick(ctx).HeaderByNumber(ctx, number)
+}
+
+func (c *endpointClient) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	return c.pick(ctx).HeaderByHash(ctx, hash)
+}
+
+func (c *endpointClient) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	return c.pick(ctx).CallContract(ctx, msg, blockNumber)
+}
+
+func (c *endpointClient) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	return c.pick(ctx).CodeAt(ctx, account, blockNumber)
+}
+
+func (c *endpointClient) StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error) {
+	return c.pick(ctx).StorageAt(ctx, account, key, blockNumber)
+}
+
+func (c *endpointClient) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	return c.pick(ctx).FilterLogs(ctx, q)
+}
@@ -0,0 +1,57 @@
+package chain
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/chain/chaintest"
+)
+
+func Test_ManagerRoutesReadsToQuorumEndpoints(t *testing.T) {
+	token := common.HexToAddress("0x01")
+	rpc, quorum := chaintest.NewContracts(1), chaintest.NewContracts(1)
+	rpc.Stub(t, token, erc20, "totalSupply", big.NewInt(1000))
+	quorum.Stub(t, token, erc20, "totalSupply", big.NewInt(999))
+
+	m := NewManager()
+	m.Register(1, "ethereum", rpc)
+	m.RegisterQuorum(1, quorum)
+	m.SetBatching(BatchConfig{Enabled: true, Window: 10 * time.Millisecond, MaxCalls: 2})
+	if !m.HasEndpoint(1, "quorum-1") || m.HasEndpoint(1, "quorum-2") || m.HasEndpoint(8453, "quorum-1") {
+		t.Fatalf("Expected chain 1 to have one quorum endpoint")
+	}
+
+	client, err := m.Client(1)
+	if err != nil {
+		t.Fatalf("Client failed: %v", err)
+	}
+	// concurrent reads of both endpoints are batched apart
+	var wg sync.WaitGroup
+	var latest, quorumRead *big.Int
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		latest, _ = CallUint(context.Background(), client, token, erc20, "totalSupply")
+	}()
+	go func() {
+		defer wg.Done()
+		quorumRead, _ = CallUint(WithEndpoint(context.Background(), QuorumEndpoint(0)), client, token, erc20, "totalSupply")
+	}()
+	wg.Wait()
+	if latest == nil || latest.Int64() != 1000 || quorumRead == nil || quorumRead.Int64() != 999 {
+		t.Errorf("Expected the rpc endpoint to read 1000 and the quorum endpoint 999, got %v and %v", latest, quorumRead)
+	}
+	if rpc.Calls() != 1 || quorum.Calls() != 1 {
+		t.Errorf("Expected one eth_call per endpoint, got %d and %d", rpc.Calls(), quorum.Calls())
+	}
+
+	m.RegisterQuorum(1)
+	client, _ = m.Client(1)
+	if v, _ := CallUint(WithEndpoint(context.Background(), QuorumEndpoint(0)), client, token, erc20, "totalSupply"); v == nil || v.Int64() != 1000 {
+		t.Errorf("Expected reads to go to the rpc endpoint once the quorum endpoint is removed, got %v", v)
+	}
+}
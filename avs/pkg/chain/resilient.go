@@ -0,0 +1,170 @@
+package chain
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/najnomics/crosscow-avs/pkg/resilience"
+	"github.com/najnomics/crosscow-avs/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// resilientClient retries the calls of a client under a policy, with one circuit breaker
+// per chain
+type resilientClient struct {
+	Client
+	policy   *resilience.Policy
+	endpoint string
+}
+
+// do runs one call of method under the policy, in a span covering all its attempts
+func (c *resilientClient) do(ctx context.Context, method string, fn func(ctx context.Context) error) error {
+	ctx, span := tracing.Start(ctx, "rpc "+method,
+		attribute.String("rpc.method", method),
+		attribute.String("chain", c.endpoint),
+	)
+	err := c.policy.Do(ctx, c.endpoint, fn)
+	tracing.End(span, err)
+	return err
+}
+
+func (c *resilientClient) ChainID(ctx context.Context) (*big.Int, error) {
+	var id *big.Int
+	err := c.do(ctx, "ChainID", func(ctx context.Context) error {
+		var err error
+		id, err = c.Client.ChainID(ctx)
+		return err
+	})
+	return id, err
+}
+
+func (c *resilientClient) BlockNumber(ctx context.Context) (uint64, error) {
+	var number uint64
+	err := c.do(ctx, "BlockNumber", func(ctx context.Context) error {
+		var err error
+		number, err = c.Client.BlockNumber(ctx)
+		return err
+	})
+	return number, err
+}
+
+func (c *resilientClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	var header *types.Header
+	err := c.do(ctx, "HeaderByNumber", func(ctx context.Context) error {
+		var err error
+		header, err = c.Client.HeaderByNumber(ctx, number)
+		return err
+	})
+	return header, err
+}
+
+func (c *resilientClient) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	var header *types.Header
+	err := c.do(ctx, "HeaderByHash", func(ctx context.Context) error {
+		var err error
+		header, err = c.Client.HeaderByHash(ctx, hash)
+		return err
+	})
+	return header, err
+}
+
+func (c *resilientClient) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	var out []byte
+	err := c.do(ctx, "CallContract", func(ctx context.Context) error {
+		var err error
+		out, err = c.Client.CallContract(ctx, msg, blockNumber)
+		return err
+	})
+	return out, err
+}
+
+func (c *resilientClient) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	var code []byte
+	err := c.do(ctx, "CodeAt", func(ctx context.Context) error {
+		var err error
+		code, err = c.Client.CodeAt(ctx, account, blockNumber)
+		return err
+	})
+	return code, err
+}
+
+func (c *resilientClient) StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error) {
+	var value []byte
+	err := c.do(ctx, "StorageAt", func(ctx context.Context) error {
+		var err error
+		value, err = c.Client.StorageAt(ctx, account, key, blockNumber)
+		return err
+	})
+	return value, err
+}
+
+func (c *resilientClient) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	var logs []types.Log
+	err := c.do(ctx, "FilterLogs", func(ctx context.Context) error {
+		var err error
+		logs, err = c.Client.FilterLogs(ctx, q)
+		return err
+	})
+	return logs, err
+}
+
+func (c *resilientClient) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error) {
+	var gas uint64
+	err := c.do(ctx, "EstimateGas", func(ctx context.Context) error {
+		var err error
+		gas, err = c.Client.EstimateGas(ctx, msg)
+		return err
+	})
+	return gas, err
+}
+
+func (c *resilientClient) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	var price *big.Int
+	err := c.do(ctx, "SuggestGasPrice", func(ctx context.Context) error {
+		var err error
+		price, err = c.Client.SuggestGasPrice(ctx)
+		return err
+	})
+	return price, err
+}
+
+func (c *resilientClient) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	var tip *big.Int
+	err := c.do(ctx, "SuggestGasTipCap", func(ctx context.Context) error {
+		var err error
+		tip, err = c.Client.SuggestGasTipCap(ctx)
+		return err
+	})
+	return tip, err
+}
+
+func (c *resilientClient) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	var nonce uint64
+	err := c.do(ctx, "PendingNonceAt", func(ctx context.Context) error {
+		var err error
+		nonce, err = c.Client.PendingNonceAt(ctx, account)
+		return err
+	})
+	return nonce, err
+}
+
+// SendTransaction retries broadcasting a signed transaction. Rebroadcasting is safe: the
+// same signed transaction can only be included once.
+func (c *resilientClient) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	return c.do(ctx, "SendTransaction", func(ctx context.Context) error {
+		return c.Client.SendTransaction(ctx, tx)
+	})
+}
+
+func (c *resilientClient) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	var receipt *types.Receipt
+	err := c.do(ctx, "TransactionReceipt", func(ctx context.Context) error {
+		var err error
+		receipt, err = c.Client.TransactionReceipt(ctx, txHash)
+		return err
+	})
+	return receipt, err
+}
@@ -0,0 +1,298 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// ErrNoSubscriptions is returned for chains configured without a WebSocket endpoint,
+// whose state has to be polled instead
+var ErrNoSubscriptions = errors.New("chain has no websocket endpoint")
+
+const (
+	// MaxHeadBackfill bounds the missed heads delivered after a reconnect. Older heads
+	// are skipped; consumers catch up from the latest one.
+	MaxHeadBackfill = 128
+
+	minReconnectDelay = time.Second
+	maxReconnectDelay = 30 * time.Second
+)
+
+// Subscriber is the subset of a WebSocket JSON-RPC client subscriptions are made with
+type Subscriber interface {
+	SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error)
+	SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error)
+	Close()
+}
+
+// DialFunc connects a new Subscriber. Every subscription holds its own connection.
+type DialFunc func(ctx context.Context) (Subscriber, error)
+
+// dialWebSocket dials url with ethclient
+func dialWebSocket(url string) DialFunc {
+	return func(ctx context.Context) (Subscriber, error) {
+		return ethclient.DialContext(ctx, url)
+	}
+}
+
+// RegisterSubscriptions makes subscriptions to chainID connect with dial. Blocks missed
+// while reconnecting are read from the client registered for chainID.
+func (m *Manager) RegisterSubscriptions(chainID uint64, dial DialFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dialers[chainID] = dial
+}
+
+// HasSubscriptions reports whether chainID has a WebSocket endpoint
+func (m *Manager) HasSubscriptions(chainID uint64) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.dialers[chainID]
+	return ok
+}
+
+func (m *Manager) stream(chainID uint64) (*stream, error) {
+	m.mu.RLock()
+	dial, ok := m.dialers[chainID]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: chain %d", ErrNoSubscriptions, chainID)
+	}
+	client, err := m.Client(chainID)
+	if err != nil {
+		return nil, err
+	}
+	return &stream{dial: dial, backfill: client, delay: minReconnectDelay}, nil
+}
+
+// SubscribeHeads delivers every new head of chainID until ctx is cancelled, when the
+// channel is closed. Dropped connections are redialed, and heads produced in the
+// meantime are read over RPC and delivered in order, up to MaxHeadBackfill of them.
+// Reorgs deliver heads at or below a height already delivered.
+func (m *Manager) SubscribeHeads(ctx context.Context, chainID uint64) (<-chan *types.Header, error) {
+	s, err := m.stream(chainID)
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan *types.Header, 16)
+	go s.heads(ctx, out)
+	return out, nil
+}
+
+// SubscribeLogs delivers the logs matching q from the latest block on until ctx is
+// cancelled, when the channel is closed. q must not set a block range. Dropped
+// connections are redialed, and logs emitted in the meantime are read over RPC, so every
+// log is delivered once, along with logs removed by reorgs.
+func (m *Manager) SubscribeLogs(ctx context.Context, chainID uint64, q ethereum.FilterQuery) (<-chan types.Log, error) {
+	if q.FromBlock != nil || q.ToBlock != nil || q.BlockHash != nil {
+		return nil, fmt.Errorf("log subscriptions follow the chain head and take no block range")
+	}
+	s, err := m.stream(chainID)
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan types.Log, 64)
+	go s.logs(ctx, q, out)
+	return out, nil
+}
+
+// stream redials a subscription with exponential backoff and fills the gaps reconnecting
+// leaves from backfill
+type stream struct {
+	dial     DialFunc
+	backfill Client
+	delay    time.Duration
+}
+
+// wait sleeps for the reconnect delay, doubling it up to the maximum
+func (s *stream) wait(ctx context.Context) bool {
+	timer := time.NewTimer(s.delay)
+	defer timer.Stop()
+	s.delay = min(2*s.delay, maxReconnectDelay)
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+func (s *stream) heads(ctx context.Context, out chan<- *types.Header) {
+	defer close(out)
+	var last uint64
+	for ctx.Err() == nil {
+		conn, err := s.dial(ctx)
+		if err != nil {
+			if !s.wait(ctx) {
+				return
+			}
+			continue
+		}
+		ch := make(chan *types.Header, 16)
+		sub, err := conn.SubscribeNewHead(ctx, ch)
+		if err != nil {
+			conn.Close()
+			if !s.wait(ctx) {
+				return
+			}
+			continue
+		}
+		s.delay = minReconnectDelay
+		last = s.followHeads(ctx, sub, ch, last, out)
+		sub.Unsubscribe()
+		conn.Close()
+	}
+}
+
+// followHeads delivers heads from ch, after the heads missed since last, until the
+// subscription fails. It returns the height of the latest head delivered.
+func (s *stream) followHeads(ctx context.Context, sub ethereum.Subscription, ch <-chan *types.Header, last uint64, out chan<- *types.Header) uint64 {
+	for {
+		select {
+		case <-ctx.Done():
+			return last
+		case <-sub.Err():
+			return last
+		case head := <-ch:
+			number := head.Number.Uint64()
+			if last > 0 && number > last+1 {
+				from := last + 1
+				if number-from > MaxHeadBackfill {
+					from = number - MaxHeadBackfill
+				}
+				for n := from; n < number; n++ {
+					missed, err := s.backfill.HeaderByNumber(ctx, new(big.Int).SetUint64(n))
+					if err != nil {
+						break
+					}
+					if !send(ctx, out, missed) {
+						return last
+					}
+				}
+			}
+			if !send(ctx, out, head) {
+				return last
+			}
+			last = number
+		}
+	}
+}
+
+// logCursor is the position of the latest log delivered, or the end of the block logs
+// were read up to. Logs at or before it were delivered already.
+type logCursor struct {
+	block uint64
+	index uint
+}
+
+func endOfBlock(block uint64) logCursor {
+	return logCursor{block: block, index: ^uint(0)}
+}
+
+// covers reports whether log was delivered already. Removals are always delivered.
+func (c logCursor) covers(log types.Log) bool {
+	return !log.Removed && (log.BlockNumber < c.block || log.BlockNumber == c.block && log.Index <= c.index)
+}
+
+func (s *stream) logs(ctx context.Context, q ethereum.FilterQuery, out chan<- types.Log) {
+	defer close(out)
+
+	var cursor logCursor
+	for {
+		head, err := s.backfill.BlockNumber(ctx)
+		if err == nil {
+			cursor = endOfBlock(head)
+			break
+		}
+		if !s.wait(ctx) {
+			return
+		}
+	}
+
+	for ctx.Err() == nil {
+		conn, err := s.dial(ctx)
+		if err != nil {
+			if !s.wait(ctx) {
+				return
+			}
+			continue
+		}
+		ch := make(chan types.Log, 64)
+		sub, err := conn.SubscribeFilterLogs(ctx, q, ch)
+		if err != nil {
+			conn.Close()
+			if !s.wait(ctx) {
+				return
+			}
+			continue
+		}
+		s.delay = minReconnectDelay
+
+		ok := s.backfillLogs(ctx, q, &cursor, out)
+		for ok {
+			select {
+			case <-ctx.Done():
+				ok = false
+			case <-sub.Err():
+				ok = false
+			case log := <-ch:
+				ok = deliverLog(ctx, &cursor, log, out)
+			}
+		}
+		sub.Unsubscribe()
+		conn.Close()
+	}
+}
+
+// backfillLogs delivers the logs after cursor up to the head. Blocks after the head are
+// delivered by the subscription, which was made before. The cursor is left alone when
+// the logs cannot be read, so the subscription still skips what was delivered.
+func (s *stream) backfillLogs(ctx context.Context, q ethereum.FilterQuery, cursor *logCursor, out chan<- types.Log) bool {
+	head, err := s.backfill.BlockNumber(ctx)
+	if err != nil || head <= cursor.block {
+		return true
+	}
+	q.FromBlock = new(big.Int).SetUint64(cursor.block)
+	q.ToBlock = new(big.Int).SetUint64(head)
+	missed, err := s.backfill.FilterLogs(ctx, q)
+	if err != nil {
+		return true
+	}
+	for _, log := range missed {
+		if !deliverLog(ctx, cursor, log, out) {
+			return false
+		}
+	}
+	*cursor = endOfBlock(head)
+	return true
+}
+
+// deliverLog sends log unless cursor covers it, and moves the cursor past it
+func deliverLog(ctx context.Context, cursor *logCursor, log types.Log, out chan<- types.Log) bool {
+	if cursor.covers(log) {
+		return true
+	}
+	if !send(ctx, out, log) {
+		return false
+	}
+	if !log.Removed {
+		*cursor = logCursor{block: log.BlockNumber, index: log.Index}
+	}
+	return true
+}
+
+func send[T any](ctx context.Context, out chan<- T, v T) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case out <- v:
+		return true
+	}
+}
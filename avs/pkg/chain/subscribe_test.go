@@ -0,0 +1,145 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/najnomics/crosscow-avs/pkg/chain/chaintest"
+)
+
+type fakeSubscription struct {
+	err chan error
+}
+
+func (s *fakeSubscription) Err() <-chan error { return s.err }
+func (s *fakeSubscription) Unsubscribe()      {}
+
+// fakeConn is a WebSocket connection whose subscription the test feeds and drops
+type fakeConn struct {
+	heads chan<- *types.Header
+	logs  chan<- types.Log
+	sub   *fakeSubscription
+}
+
+func (c *fakeConn) drop() { c.sub.err <- errors.New("connection reset") }
+
+// fakeSubscriber hands every connection to the test once it subscribed
+type fakeSubscriber struct {
+	conns chan *fakeConn
+}
+
+func (f *fakeSubscriber) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	conn := &fakeConn{heads: ch, sub: &fakeSubscription{err: make(chan error, 1)}}
+	f.conns <- conn
+	return conn.sub, nil
+}
+
+func (f *fakeSubscriber) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	conn := &fakeConn{logs: ch, sub: &fakeSubscription{err: make(chan error, 1)}}
+	f.conns <- conn
+	return conn.sub, nil
+}
+
+func (f *fakeSubscriber) Close() {}
+
+func subscriptions(t *testing.T) (*Manager, *chaintest.Contracts, chan *fakeConn, context.Context) {
+	contracts := chaintest.NewContracts(1)
+	m := NewManager()
+	m.Register(1, "ethereum", contracts)
+	conns := make(chan *fakeConn, 1)
+	subscriber := &fakeSubscriber{conns: conns}
+	m.RegisterSubscriptions(1, func(ctx context.Context) (Subscriber, error) { return subscriber, nil })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return m, contracts, conns, ctx
+}
+
+func receive[T any](t *testing.T, ctx context.Context, ch <-chan T) T {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-ctx.Done():
+		t.Fatalf("Timed out waiting for the subscription")
+		var zero T
+		return zero
+	}
+}
+
+func Test_SubscribeHeadsBackfillsAfterReconnect(t *testing.T) {
+	m, contracts, conns, ctx := subscriptions(t)
+	heads, err := m.SubscribeHeads(ctx, 1)
+	if err != nil {
+		t.Fatalf("SubscribeHeads failed: %v", err)
+	}
+
+	conn := receive(t, ctx, conns)
+	conn.heads <- &types.Header{Number: big.NewInt(101)}
+	if head := receive(t, ctx, heads); head.Number.Uint64() != 101 {
+		t.Errorf("Expected head 101, got %d", head.Number)
+	}
+
+	// heads produced while reconnecting are read over RPC
+	conn.drop()
+	contracts.AdvanceBlocks(4)
+	conn = receive(t, ctx, conns)
+	conn.heads <- &types.Header{Number: big.NewInt(105)}
+	for want := uint64(102); want <= 105; want++ {
+		if head := receive(t, ctx, heads); head.Number.Uint64() != want {
+			t.Fatalf("Expected head %d, got %d", want, head.Number)
+		}
+	}
+}
+
+func Test_SubscribeLogsDeliversEveryLogOnce(t *testing.T) {
+	m, contracts, conns, ctx := subscriptions(t)
+	logs, err := m.SubscribeLogs(ctx, 1, ethereum.FilterQuery{})
+	if err != nil {
+		t.Fatalf("SubscribeLogs failed: %v", err)
+	}
+
+	conn := receive(t, ctx, conns)
+	first := types.Log{BlockNumber: 101, Index: 0}
+	conn.logs <- first
+	conn.logs <- first
+	if log := receive(t, ctx, logs); log.BlockNumber != 101 {
+		t.Errorf("Expected the log of block 101, got %+v", log)
+	}
+
+	// logs emitted while reconnecting are read over RPC, without the ones delivered
+	conn.drop()
+	contracts.AddLog(first)
+	contracts.AddLog(types.Log{BlockNumber: 102, Index: 1})
+	contracts.AdvanceBlocks(3)
+	conn = receive(t, ctx, conns)
+	if log := receive(t, ctx, logs); log.BlockNumber != 102 || log.Index != 1 {
+		t.Errorf("Expected the missed log of block 102, got %+v", log)
+	}
+
+	conn.logs <- types.Log{BlockNumber: 102, Index: 1}
+	conn.logs <- types.Log{BlockNumber: 104, Index: 0}
+	conn.logs <- types.Log{BlockNumber: 104, Index: 0, Removed: true}
+	if log := receive(t, ctx, logs); log.BlockNumber != 104 || log.Removed {
+		t.Errorf("Expected the log of block 104, got %+v", log)
+	}
+	if log := receive(t, ctx, logs); !log.Removed {
+		t.Errorf("Expected the removal of the log of block 104, got %+v", log)
+	}
+}
+
+func Test_SubscribeWithoutWebSocket(t *testing.T) {
+	m := NewManager()
+	m.Register(1, "ethereum", &fakeClient{chainID: 1})
+	if _, err := m.SubscribeHeads(context.Background(), 1); !errors.Is(err, ErrNoSubscriptions) {
+		t.Errorf("Expected ErrNoSubscriptions, got %v", err)
+	}
+	if _, err := m.SubscribeLogs(context.Background(), 1, ethereum.FilterQuery{FromBlock: big.NewInt(1)}); err == nil {
+		t.Errorf("Expected block ranges to be rejected")
+	}
+}
@@ -0,0 +1,100 @@
+package circle
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/najnomics/crosscow-avs/pkg/resilience"
+)
+
+const DefaultApiBaseUrl = "https://api.circle.com"
+
+// Config configures access to Circle's APIs
+type Config struct {
+	ApiBaseUrl string `yaml:"apiBaseUrl"`
+
+	// ApiKey is typically a reference to the secrets provider, such as
+	// ${secret:circle-api-key}, so it can be rotated without a restart
+	ApiKey  string        `yaml:"apiKey"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// Client is a thin HTTP client for the Circle platform APIs
+type Client struct {
+	baseUrl    string
+	httpClient *http.Client
+	policy     *resilience.Policy
+
+	// mu guards apiKey, which rotations replace while requests run
+	mu     sync.RWMutex
+	apiKey string
+}
+
+// NewClient creates a Circle API client. Missing values fall back to defaults. Transient
+// failures are retried under policy, which may be nil.
+func NewClient(cfg *Config, policy *resilience.Policy) *Client {
+	baseUrl := DefaultApiBaseUrl
+	timeout := 10 * time.Second
+	apiKey := ""
+	if cfg != nil {
+		if cfg.ApiBaseUrl != "" {
+			baseUrl = cfg.ApiBaseUrl
+		}
+		if cfg.Timeout > 0 {
+			timeout = cfg.Timeout
+		}
+		apiKey = cfg.ApiKey
+	}
+
+	return &Client{
+		baseUrl:    strings.TrimRight(baseUrl, "/"),
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: timeout},
+		policy:     policy,
+	}
+}
+
+// SetApiKey replaces the API key requests are sent with, such as after a rotation
+func (c *Client) SetApiKey(apiKey string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.apiKey = apiKey
+}
+
+func (c *Client) key() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.apiKey
+}
+
+// Ping checks that the Circle API is reachable
+func (c *Client) Ping(ctx context.Context) error {
+	return c.policy.Do(ctx, c.baseUrl, c.ping)
+}
+
+func (c *Client) ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseUrl+"/ping", nil)
+	if err != nil {
+		return fmt.Errorf("failed to build ping request: %w", err)
+	}
+	if apiKey := c.key(); apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("circle api unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("circle api ping: %w", &resilience.StatusError{Endpoint: c.baseUrl, StatusCode: resp.StatusCode})
+	}
+	return nil
+}
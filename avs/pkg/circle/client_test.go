@@ -0,0 +1,46 @@
+package circle
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func Test_Ping(t *testing.T) {
+	var healthy atomic.Bool
+	healthy.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ping" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(`{"message":"pong"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(&Config{ApiBaseUrl: server.URL + "/", ApiKey: "test-key"}, nil)
+	if err := client.Ping(context.Background()); err != nil {
+		t.Errorf("Expected ping to succeed: %v", err)
+	}
+
+	healthy.Store(false)
+	if err := client.Ping(context.Background()); err == nil {
+		t.Errorf("Expected ping to fail when the API is unhealthy")
+	}
+
+	healthy.Store(true)
+	client.SetApiKey("rotated-key")
+	if err := client.Ping(context.Background()); err == nil {
+		t.Errorf("Expected ping to be sent with the rotated key")
+	}
+}
@@ -0,0 +1,78 @@
+// Package client is the Go SDK of the performer. It builds task payloads from typed
+// parameters, checking them against the schema of their task type before they are sent,
+// runs them against a performer over gRPC and decodes their results, so integrators do
+// not hand-write payload JSON.
+//
+// The schema checks are those a payload can fail anywhere; whether a protocol, chain or
+// dry run is served depends on the performer, which validates tasks again.
+//
+// USDC amounts are decimal strings of base units, "1500000" for 1.5 USDC, so amounts of
+// any size reach the performer exactly.
+package client
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"google.golang.org/grpc"
+)
+
+// Client runs tasks against a performer over gRPC
+type Client struct {
+	performer performerV1.PerformerServiceClient
+	key       *ecdsa.PrivateKey
+}
+
+// ClientOption configures a Client
+type ClientOption func(*Client)
+
+// WithSigningKey signs every payload sent with key
+func WithSigningKey(key *ecdsa.PrivateKey) ClientOption {
+	return func(c *Client) { c.key = key }
+}
+
+// New creates a client of the performer served on conn
+func New(conn grpc.ClientConnInterface, opts ...ClientOption) *Client {
+	c := &Client{performer: performerV1.NewPerformerServiceClient(conn)}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Execute sends payload as task taskID and returns its raw result, which is ABI encoded
+// when the payload asked for it
+func (c *Client) Execute(ctx context.Context, taskID string, payload *Payload) ([]byte, error) {
+	var (
+		encoded []byte
+		err     error
+	)
+	if c.key != nil {
+		encoded, err = payload.Sign(c.key)
+	} else {
+		encoded, err = payload.Encode()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s payload: %w", payload.Type, err)
+	}
+	resp, err := c.performer.ExecuteTask(ctx, &performerV1.TaskRequest{TaskId: []byte(taskID), Payload: encoded})
+	if err != nil {
+		return nil, fmt.Errorf("task %s failed: %w", taskID, err)
+	}
+	return resp.Result, nil
+}
+
+// Run builds the payload of task, sends it as task taskID and decodes its JSON result
+func (c *Client) Run(ctx context.Context, taskID string, task Task, opts ...Option) (*Result, error) {
+	payload, err := Build(task, opts...)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := c.Execute(ctx, taskID, payload)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeResult(raw)
+}
@@ -0,0 +1,200 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/najnomics/crosscow-avs/pkg/auth"
+	"github.com/najnomics/crosscow-avs/pkg/resultsize"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const account = "0x000000000000000000000000000000000000dEaD"
+
+func Test_BuildEncodesParameters(t *testing.T) {
+	zero := uint64(0)
+	payload, err := Build(RebalanceExecution{
+		UserAddress: account, Nonce: "7", Amount: "1000000000", TargetProtocol: "aave_v3", TargetChain: 8453, MaxSlippageBps: &zero,
+	}, WithResultFormat("abi"))
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	encoded, err := payload.Encode()
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	want := `{"type":"rebalance_execution","parameters":{"amount":"1000000000","max_slippage_bps":0,"nonce":"7","result_format":"abi","target_chain":8453,"target_protocol":"aave_v3","user_address":"` + account + `"}}`
+	if string(encoded) != want {
+		t.Errorf("Unexpected payload:\n got: %s\nwant: %s", encoded, want)
+	}
+
+	plan, err := Build(RebalancePlan{
+		RebalanceExecution: RebalanceExecution{UserAddress: account, Nonce: "8", Amount: "1000000000", TargetProtocol: "aave_v3"},
+		ExpiresAt:          time.Now().Add(time.Minute).Unix(),
+	})
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if plan.Type != TaskTypeRebalancePlan || plan.Parameters["expires_at"] == nil || plan.Parameters["user_address"] != account {
+		t.Errorf("Expected the plan to carry the rebalance parameters, got %+v", plan)
+	}
+
+	costs, err := Build(AllocationOptimization{Amount: "1000000000", Token: "USDC", TransferCosts: map[uint64]string{8453: "5000000"}})
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if encoded, _ := costs.Encode(); !strings.Contains(string(encoded), `"transfer_costs":{"8453":"5000000"}`) {
+		t.Errorf("Expected transfer costs keyed by chain id, got %s", encoded)
+	}
+}
+
+func Test_BuildRejectsInvalidParameters(t *testing.T) {
+	monitoring, _ := Build(YieldMonitoring{Protocol: "aave_v3", ChainID: 1, Token: "USDC"})
+	nested, _ := Build(Batch{Tasks: []*Payload{monitoring}})
+	attested, _ := Build(YieldMonitoring{Protocol: "aave_v3", ChainID: 1, Token: "USDC"}, WithAttestation())
+	rebalance, _ := Build(RebalanceExecution{UserAddress: account, Nonce: "1", Amount: "1000000", TargetProtocol: "aave_v3"})
+	for name, tc := range map[string]struct {
+		task Task
+		opts []Option
+	}{
+		"protocol":        {task: YieldMonitoring{ChainID: 1, Token: "USDC"}},
+		"chain":           {task: YieldMonitoring{Protocol: "aave_v3", Token: "USDC"}},
+		"bridged token":   {task: DepegMonitoring{Token: "0xFF970A61A04b1cA14834A43f5dE4533eBDDB5CC8", ChainIDs: []uint64{42161}}},
+		"user address":    {task: RebalanceExecution{UserAddress: "dead", Nonce: "1", Amount: "1000000", TargetProtocol: "aave_v3"}},
+		"nonce":           {task: RebalanceExecution{UserAddress: account, Amount: "1000000", TargetProtocol: "aave_v3"}},
+		"flash loan":      {task: RebalanceExecution{UserAddress: account, Nonce: "1", Amount: "1000000", TargetProtocol: "aave_v3", Strategy: StrategyFlashLoan}},
+		"execution":       {task: RebalanceExecution{UserAddress: account, Nonce: "1", Amount: "1000000", TargetProtocol: "aave_v3", Execution: "circle_wallet"}},
+		"expired plan":    {task: RebalancePlan{RebalanceExecution: RebalanceExecution{UserAddress: account, Nonce: "1", Amount: "1000000", TargetProtocol: "aave_v3"}, ExpiresAt: 1}},
+		"plan hash":       {task: RebalanceCommit{PlanHash: "0x1234"}},
+		"execution id":    {task: ResumeExecution{ExecutionID: "execution"}},
+		"recovery id":     {task: TransferRecovery{ExecutionID: "0x"}},
+		"horizon":         {task: APYForecast{Protocol: "aave_v3", ChainID: 1, Token: "USDC", HorizonsHours: []uint64{MaxForecastHorizonHours + 1}}},
+		"lookback":        {task: APYForecast{Protocol: "aave_v3", ChainID: 1, Token: "USDC", LookbackHours: MaxForecastLookbackHours + 1}},
+		"accrual period":  {task: AccrualVerification{MinPeriodHours: MaxAccrualPeriodHours + 1}},
+		"max share":       {task: AllocationOptimization{Amount: "1000000", Token: "USDC", MaxShare: 2}},
+		"check user":      {task: CrossChainYieldCheck{SourceChain: 1, TargetChain: 8453, Amount: "1000000", UserAddress: "dead"}},
+		"profile preset":  {task: CrossChainYieldCheck{SourceChain: 1, TargetChain: 8453, Amount: "1000000", Profile: &Profile{Preset: "degen"}}},
+		"transfer speed":  {task: CrossChainYieldCheck{SourceChain: 1, TargetChain: 8453, Amount: "1000000", TransferSpeed: "instant"}},
+		"empty batch":     {task: Batch{}},
+		"nested batch":    {task: Batch{Tasks: []*Payload{nested}}},
+		"batched option":  {task: Batch{Tasks: []*Payload{attested}}},
+		"batched funds":   {task: Batch{Tasks: []*Payload{monitoring, rebalance}}},
+		"result format":   {task: DepegMonitoring{Token: "USDC"}, opts: []Option{WithResultFormat("xml")}},
+		"no fields":       {task: DepegMonitoring{Token: "USDC"}, opts: []Option{WithFields()}},
+		"compressed abi":  {task: DepegMonitoring{Token: "USDC"}, opts: []Option{WithResultFormat("abi"), WithCompression()}},
+		"block hash":      {task: RiskAssessment{Protocol: "aave_v3", ChainID: 1, AssessmentType: "full"}, opts: []Option{AtBlockHash("0x01")}},
+		"incident window": {task: ProtocolIncidentCheck{Protocol: "aave_v3", ChainID: 1, LookbackBlocks: MaxIncidentLookback + 1}},
+	} {
+		if _, err := Build(tc.task, tc.opts...); err == nil {
+			t.Errorf("%s: expected the task to be rejected", name)
+		}
+	}
+}
+
+func Test_SignedPayload(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	payload, _ := Build(DepegMonitoring{Token: "USDC"})
+	signed, err := payload.Sign(key)
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	raw, signature, err := auth.Unwrap(signed)
+	if err != nil || signature == nil {
+		t.Fatalf("Expected a signed envelope, got %s (%v)", signed, err)
+	}
+	verifier := auth.NewVerifier(auth.Config{Enabled: true, Signers: []string{crypto.PubkeyToAddress(key.PublicKey).Hex()}})
+	if _, err := verifier.Verify(raw, signature); err != nil {
+		t.Errorf("Expected the signature to verify: %v", err)
+	}
+}
+
+func Test_DecodeResult(t *testing.T) {
+	result, err := DecodeResult([]byte(`{"result":{"token":"USDC","status":"ok"},"schema_version":4,"task_type":"depeg_monitoring"}`))
+	if err != nil {
+		t.Fatalf("DecodeResult failed: %v", err)
+	}
+	var depeg struct {
+		Token string `json:"token"`
+	}
+	if err := result.Decode(&depeg); err != nil || depeg.Token != "USDC" || result.SchemaVersion != 4 {
+		t.Errorf("Expected the depeg result, got %+v (%v)", result, err)
+	}
+
+	failed, err := DecodeResult([]byte(`{"result":{"error":{"code":"unprofitable","message":"gain below cost","retryable":false},"status":"failed"},"task_type":"rebalance_execution"}`))
+	if err != nil {
+		t.Fatalf("DecodeResult failed: %v", err)
+	}
+	var rebalance json.RawMessage
+	if err := failed.Decode(&rebalance); err == nil || failed.Err() == nil || failed.Err().Code != "unprofitable" {
+		t.Errorf("Expected the error answered in place of the result, got %v", err)
+	}
+
+	encoded, _ := resultsize.Compress([]byte(`{"token":"USDC","status":"ok"}`))
+	compressed, err := DecodeResult([]byte(`{"encoding":"gzip+base64","result":"` + encoded + `","task_type":"depeg_monitoring"}`))
+	if err != nil {
+		t.Fatalf("DecodeResult failed: %v", err)
+	}
+	depeg.Token = ""
+	if err := compressed.Decode(&depeg); err != nil || depeg.Token != "USDC" || compressed.Encoding != resultsize.EncodingGzipBase64 {
+		t.Errorf("Expected the compressed depeg result, got %+v (%v)", compressed, err)
+	}
+	if _, err := DecodeResult([]byte(`{"encoding":"zstd","result":"","task_type":"depeg_monitoring"}`)); err == nil {
+		t.Errorf("Expected an unknown encoding to be rejected")
+	}
+
+	if _, err := DecodeResult([]byte{0x01, 0x02}); err == nil {
+		t.Errorf("Expected an ABI encoded result to be rejected")
+	}
+}
+
+// performer answers tasks with a result naming the payload it received
+type performer struct {
+	performerV1.UnimplementedPerformerServiceServer
+}
+
+func (performer) ExecuteTask(ctx context.Context, req *performerV1.TaskRequest) (*performerV1.TaskResponse, error) {
+	raw, _, err := auth.Unwrap(req.Payload)
+	if err != nil {
+		return nil, err
+	}
+	var payload Payload
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return nil, err
+	}
+	result, _ := json.Marshal(map[string]interface{}{"task_type": payload.Type, "result": payload.Parameters})
+	return &performerV1.TaskResponse{TaskId: req.TaskId, Result: result}, nil
+}
+
+func Test_ClientRunsTasks(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := grpc.NewServer()
+	performerV1.RegisterPerformerServiceServer(server, performer{})
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	key, _ := crypto.GenerateKey()
+	result, err := New(conn, WithSigningKey(key)).Run(context.Background(), "task-1", DepegMonitoring{Token: "USDC", ChainIDs: []uint64{1}})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	var params DepegMonitoring
+	if err := result.Decode(&params); err != nil || result.TaskType != TaskTypeDepegMonitoring || params.Token != "USDC" || len(params.ChainIDs) != 1 {
+		t.Errorf("Unexpected result %+v (%v)", params, err)
+	}
+}
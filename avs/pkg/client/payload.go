@@ -0,0 +1,163 @@
+package client
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+
+	"github.com/najnomics/crosscow-avs/pkg/auth"
+	"github.com/najnomics/crosscow-avs/pkg/resultsize"
+)
+
+// TaskType names the work a task asks of the performer
+type TaskType string
+
+const (
+	TaskTypeYieldMonitoring        TaskType = "yield_monitoring"
+	TaskTypeCrossChainYieldCheck   TaskType = "cross_chain_yield_check"
+	TaskTypeRebalanceExecution     TaskType = "rebalance_execution"
+	TaskTypeRiskAssessment         TaskType = "risk_assessment"
+	TaskTypeLiquidityDepthAnalysis TaskType = "liquidity_depth_analysis"
+	TaskTypeDepegMonitoring        TaskType = "depeg_monitoring"
+	TaskTypeAPYForecast            TaskType = "apy_forecast"
+	TaskTypeBatch                  TaskType = "batch"
+	TaskTypeAllocationOptimization TaskType = "allocation_optimization"
+	TaskTypeProtocolIncidentCheck  TaskType = "protocol_incident_check"
+	TaskTypePositionReconciliation TaskType = "position_reconciliation"
+	TaskTypeYieldRanking           TaskType = "yield_ranking"
+	TaskTypeRebalancePlan          TaskType = "rebalance_plan"
+	TaskTypeRebalanceCommit        TaskType = "rebalance_commit"
+	TaskTypeResumeExecution        TaskType = "resume_execution"
+	TaskTypeCapabilities           TaskType = "capabilities"
+	TaskTypeFullMarketSnapshot     TaskType = "full_market_snapshot"
+	TaskTypePerformanceReport      TaskType = "performance_report"
+	TaskTypeAccrualVerification    TaskType = "accrual_verification"
+	TaskTypeSelfReport             TaskType = "self_report"
+	TaskTypeStressScenario         TaskType = "stress_scenario"
+	TaskTypeTransferRecovery       TaskType = "transfer_recovery"
+)
+
+// Task is the typed parameters of a task type. Its fields encode as the parameters of
+// the payload; optional fields left zero are omitted.
+type Task interface {
+	TaskType() TaskType
+	validate() error
+}
+
+// Payload is a task payload as the performer decodes it
+type Payload struct {
+	Type       TaskType               `json:"type"`
+	Parameters map[string]interface{} `json:"parameters"`
+}
+
+// Option sets a parameter every task type accepts
+type Option func(parameters map[string]interface{})
+
+// WithResultFormat asks for results in format, "json" or "abi"
+func WithResultFormat(format string) Option {
+	return func(parameters map[string]interface{}) { parameters["result_format"] = format }
+}
+
+// WithSchemaVersion asks for JSON results in the given schema version, the oldest every
+// operator of a task runs
+func WithSchemaVersion(version int) Option {
+	return func(parameters map[string]interface{}) { parameters["schema_version"] = version }
+}
+
+// WithAttestation asks for results attesting the inputs they were computed from
+func WithAttestation() Option {
+	return func(parameters map[string]interface{}) { parameters["attest"] = true }
+}
+
+// WithFields asks for results cut down to the named top level fields and their status
+func WithFields(fields ...string) Option {
+	return func(parameters map[string]interface{}) { parameters["fields"] = fields }
+}
+
+// WithCompression asks for JSON results compressed with gzip and carried as base64,
+// which DecodeResult decompresses
+func WithCompression() Option {
+	return func(parameters map[string]interface{}) { parameters["result_encoding"] = resultsize.EncodingGzipBase64 }
+}
+
+// AtBlock pins the reads of a single chain task to a block number
+func AtBlock(number uint64) Option {
+	return func(parameters map[string]interface{}) { parameters["block_number"] = number }
+}
+
+// AtBlockHash pins the reads of a single chain task to a block hash
+func AtBlockHash(hash string) Option {
+	return func(parameters map[string]interface{}) { parameters["block_hash"] = hash }
+}
+
+// Build returns the payload of task with opts applied, failing when it breaks the schema
+// of its task type
+func Build(task Task, opts ...Option) (*Payload, error) {
+	if err := task.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", task.TaskType(), err)
+	}
+	encoded, err := json.Marshal(task)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to encode parameters: %w", task.TaskType(), err)
+	}
+	// numbers are kept as they were encoded, so large integers survive
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	parameters := make(map[string]interface{})
+	if err := decoder.Decode(&parameters); err != nil {
+		return nil, fmt.Errorf("%s: failed to encode parameters: %w", task.TaskType(), err)
+	}
+	for _, opt := range opts {
+		opt(parameters)
+	}
+	if err := validateOptions(parameters); err != nil {
+		return nil, fmt.Errorf("%s: %w", task.TaskType(), err)
+	}
+	return &Payload{Type: task.TaskType(), Parameters: parameters}, nil
+}
+
+// Encode returns the payload as the JSON a task carries
+func (p *Payload) Encode() ([]byte, error) {
+	return json.Marshal(p)
+}
+
+// Sign returns the payload wrapped in an envelope signed with key, for performers
+// requiring signed tasks of its type
+func (p *Payload) Sign(key *ecdsa.PrivateKey) ([]byte, error) {
+	encoded, err := p.Encode()
+	if err != nil {
+		return nil, err
+	}
+	return auth.Sign(encoded, key)
+}
+
+// validateOptions checks the parameters options set
+func validateOptions(parameters map[string]interface{}) error {
+	if format, present := parameters["result_format"]; present && format != "json" && format != "abi" {
+		return fmt.Errorf("invalid result_format: must be json or abi")
+	}
+	if fields, present := parameters["fields"].([]string); present {
+		if len(fields) == 0 {
+			return fmt.Errorf("invalid fields: must name at least one field")
+		}
+		for _, field := range fields {
+			if field == "" {
+				return fmt.Errorf("invalid fields: field names must not be empty")
+			}
+		}
+	}
+	if encoding, present := parameters["result_encoding"]; present && (encoding != resultsize.EncodingGzipBase64 || parameters["result_format"] == "abi") {
+		return fmt.Errorf("invalid result_encoding: only JSON results are compressed, as %s", resultsize.EncodingGzipBase64)
+	}
+	if version, present := parameters["schema_version"].(int); present && version < 1 {
+		return fmt.Errorf("invalid schema_version: must be a positive integer")
+	}
+	if block, present := parameters["block_number"].(uint64); present && block == 0 {
+		return fmt.Errorf("invalid block_number: must be a positive integer")
+	}
+	if hash, present := parameters["block_hash"].(string); present && !isHash(hash) {
+		return fmt.Errorf("invalid block_hash: must be a 32 byte hex string")
+	}
+	return nil
+}
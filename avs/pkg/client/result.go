@@ -0,0 +1,84 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/najnomics/crosscow-avs/pkg/resultsize"
+)
+
+// Result is a JSON task result: the envelope every result is answered in, with the
+// result of its task type kept encoded for Decode. Compressed results are decompressed
+// into Result; Encoding tells how they were answered.
+type Result struct {
+	TaskType      TaskType        `json:"task_type"`
+	SchemaVersion int             `json:"schema_version,omitempty"`
+	Result        json.RawMessage `json:"result"`
+	Encoding      string          `json:"encoding,omitempty"`
+
+	// Attestation is set on results of tasks asking for one
+	Attestation json.RawMessage `json:"attestation,omitempty"`
+}
+
+// ErrorReport is the error of a task answered in place of its result, such as an
+// unprofitable or policy violating rebalance. Retryable reports whether sending the task
+// again unchanged may succeed.
+type ErrorReport struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Retryable bool   `json:"retryable"`
+}
+
+func (e *ErrorReport) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// DecodeResult decodes the JSON result of a task
+func DecodeResult(raw []byte) (*Result, error) {
+	var result Result
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode result: %w", err)
+	}
+	if result.TaskType == "" || len(result.Result) == 0 {
+		return nil, fmt.Errorf("failed to decode result: missing task_type or result")
+	}
+	switch result.Encoding {
+	case "":
+	case resultsize.EncodingGzipBase64:
+		var compressed string
+		if err := json.Unmarshal(result.Result, &compressed); err != nil {
+			return nil, fmt.Errorf("failed to decode result: %s result is not a string", result.Encoding)
+		}
+		decompressed, err := resultsize.Decompress(compressed)
+		if err != nil {
+			return nil, err
+		}
+		result.Result = decompressed
+	default:
+		return nil, fmt.Errorf("failed to decode result: unknown encoding %s", result.Encoding)
+	}
+	return &result, nil
+}
+
+// Err returns the error answered in place of the result, nil when the task succeeded
+func (r *Result) Err() *ErrorReport {
+	var failed struct {
+		Error *ErrorReport `json:"error"`
+	}
+	if err := json.Unmarshal(r.Result, &failed); err != nil || failed.Error == nil || failed.Error.Code == "" {
+		return nil
+	}
+	return failed.Error
+}
+
+// Decode decodes the result of the task into v, failing with the ErrorReport answered in
+// its place when the task failed
+func (r *Result) Decode(v interface{}) error {
+	if report := r.Err(); report != nil {
+		return report
+	}
+	if err := json.Unmarshal(r.Result, v); err != nil {
+		return fmt.Errorf("failed to decode %s result: %w", r.TaskType, err)
+	}
+	return nil
+}
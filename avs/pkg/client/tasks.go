@@ -0,0 +1,772 @@
+package client
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/najnomics/crosscow-avs/pkg/tokens"
+)
+
+// Limits of task parameters, as the performer enforces them
+const (
+	// ProtocolAll monitors every protocol of a yield_monitoring task
+	ProtocolAll = "all"
+
+	// MaxBps bounds parameters in basis points
+	MaxBps = 10_000
+
+	// MaxBatchSize is the largest number of tasks a batch may contain
+	MaxBatchSize = 32
+
+	// MaxForecastHorizonHours is the furthest an apy_forecast looks ahead
+	MaxForecastHorizonHours = 30 * 24
+
+	// MinForecastLookbackHours is the least history an apy_forecast is fitted to
+	MinForecastLookbackHours = 24
+
+	// MaxForecastLookbackHours is the most history an apy_forecast is fitted to
+	MaxForecastLookbackHours = 365 * 24
+
+	// MaxAllocationHorizonDays is the longest horizon of an allocation_optimization
+	MaxAllocationHorizonDays = 365
+
+	// MaxIncidentLookback is the most blocks a protocol_incident_check scans
+	MaxIncidentLookback = 10_000
+
+	// MaxRiskFreeRate bounds the annual percentage of a yield_ranking risk free rate
+	MaxRiskFreeRate = 100
+
+	// MaxPlanLifetime is the furthest ahead a rebalance plan may expire
+	MaxPlanLifetime = time.Hour
+
+	// MaxPerformanceWindowHours is the longest window a performance_report covers
+	MaxPerformanceWindowHours = 365 * 24
+
+	// MaxSelfReportWindowHours is the longest window a self_report covers
+	MaxSelfReportWindowHours = 365 * 24
+
+	// MaxAccrualPeriodHours is the longest holding period an accrual_verification may
+	// require of the positions it checks
+	MaxAccrualPeriodHours = 365 * 24
+
+	// MaxStressShocks is the most utilization shocks a stress_scenario models
+	MaxStressShocks = 10
+
+	// MaxRiskScore is the highest risk score a profile may accept
+	MaxRiskScore = 100
+)
+
+// Strategies of rebalance executions
+const (
+	// StrategySequential withdraws from the source market, then deposits into the target
+	StrategySequential = "sequential"
+
+	// StrategyFlashLoan moves funds between markets of one chain atomically through the
+	// flash loan helper of the performer
+	StrategyFlashLoan = "flash_loan"
+)
+
+// Backends rebalance executions are sent with
+const (
+	// ExecutionEOA sends every step as a transaction of the performer account
+	ExecutionEOA = "eoa"
+
+	// ExecutionUserOperation sends the steps of each chain as one ERC-4337 user operation
+	// of the smart account at the user address, which the performer account owns
+	ExecutionUserOperation = "user_operation"
+)
+
+// Speeds of CCTP transfers
+const (
+	// TransferSpeedStandard burns wait for hard finality on the source chain before Circle
+	// attests them, for free
+	TransferSpeedStandard = "standard"
+
+	// TransferSpeedFast burns are attested after soft finality, for Circle's fast
+	// transfer fee
+	TransferSpeedFast = "fast"
+)
+
+// Modes of yield rankings
+const (
+	// RankingModeStandard ranks variable rate venues
+	RankingModeStandard = "standard"
+
+	// RankingModeFixedVsVariable also ranks fixed rate markets such as Pendle principal
+	// tokens, and compares the rate each locks with the best variable one
+	RankingModeFixedVsVariable = "fixed_vs_variable"
+)
+
+// Presets of profiles
+const (
+	// ProfileConservative asks 50 bps of improvement, takes low risk markets only and
+	// waits 30 minutes at most for bridges
+	ProfileConservative = "conservative"
+
+	// ProfileBalanced asks 20 bps, takes risk scores up to 50 and waits a day at most
+	ProfileBalanced = "balanced"
+
+	// ProfileAggressive asks 5 bps, takes anything short of critical risk and waits out
+	// any bridge
+	ProfileAggressive = "aggressive"
+)
+
+// Profile is the appetite of the user a task is made for, which the performer applies on
+// top of the operator's policy. Limits left nil keep the ones of Preset, none for custom
+// profiles without one; a limit set to zero lifts it.
+type Profile struct {
+	Preset                  string  `json:"preset,omitempty"`
+	MinAPYImprovementBps    *uint64 `json:"min_apy_improvement_bps,omitempty"`
+	MaxRiskScore            *uint64 `json:"max_risk_score,omitempty"`
+	MaxBridgeLatencySeconds *uint64 `json:"max_bridge_latency_seconds,omitempty"`
+}
+
+func (p *Profile) validate() error {
+	if p == nil {
+		return nil
+	}
+	switch p.Preset {
+	case "", ProfileConservative, ProfileBalanced, ProfileAggressive:
+	default:
+		return fmt.Errorf("invalid profile: unknown preset %q, must be %s, %s or %s", p.Preset, ProfileConservative, ProfileBalanced, ProfileAggressive)
+	}
+	if p.MinAPYImprovementBps != nil && *p.MinAPYImprovementBps > MaxBps {
+		return fmt.Errorf("invalid profile: min_apy_improvement_bps must be at most %d", MaxBps)
+	}
+	if p.MaxRiskScore != nil && *p.MaxRiskScore > MaxRiskScore {
+		return fmt.Errorf("invalid profile: max_risk_score must be between 0 and %d", MaxRiskScore)
+	}
+	return nil
+}
+
+var hashPattern = regexp.MustCompile(`^0x[0-9a-fA-F]{64}$`)
+
+func isHash(s string) bool {
+	return hashPattern.MatchString(s)
+}
+
+// YieldMonitoring reads the supply rate of a market, or of every market of every
+// protocol on ChainID, every chain when zero, with Protocol set to ProtocolAll. With
+// DepositAmount set, results project the rate of each market after depositing it.
+type YieldMonitoring struct {
+	Protocol      string  `json:"protocol"`
+	ChainID       uint64  `json:"chain_id,omitempty"`
+	Token         string  `json:"token"`
+	AnomalySigma  float64 `json:"anomaly_sigma,omitempty"`
+	DepositAmount string  `json:"deposit_amount,omitempty"`
+}
+
+func (YieldMonitoring) TaskType() TaskType { return TaskTypeYieldMonitoring }
+
+func (t YieldMonitoring) validate() error {
+	if t.Protocol == "" {
+		return fmt.Errorf("missing or invalid protocol")
+	}
+	if t.Token == "" {
+		return fmt.Errorf("missing or invalid token")
+	}
+	if t.ChainID == 0 && t.Protocol != ProtocolAll {
+		return fmt.Errorf("missing or invalid chain_id")
+	}
+	if t.AnomalySigma < 0 {
+		return fmt.Errorf("invalid anomaly_sigma: must be a positive number")
+	}
+	if t.DepositAmount != "" {
+		if _, err := tokens.ParsePositiveAmount(t.DepositAmount); err != nil {
+			return fmt.Errorf("invalid deposit_amount: %w", err)
+		}
+	}
+	return nil
+}
+
+// CrossChainYieldCheck compares the yield of moving Amount USDC between two chains. With
+// UserAddress set, performers configured with hysteresis hold the move back while its
+// funds cool down from their last move.
+type CrossChainYieldCheck struct {
+	SourceChain   uint64   `json:"source_chain"`
+	TargetChain   uint64   `json:"target_chain"`
+	Amount        string   `json:"amount"`
+	UserAddress   string   `json:"user_address,omitempty"`
+	Profile       *Profile `json:"profile,omitempty"`
+	TransferSpeed string   `json:"transfer_speed,omitempty"`
+}
+
+func (CrossChainYieldCheck) TaskType() TaskType { return TaskTypeCrossChainYieldCheck }
+
+func (t CrossChainYieldCheck) validate() error {
+	if t.SourceChain == 0 {
+		return fmt.Errorf("missing or invalid source_chain")
+	}
+	if t.TargetChain == 0 {
+		return fmt.Errorf("missing or invalid target_chain")
+	}
+	if err := checkAmount(t.Amount); err != nil {
+		return err
+	}
+	if t.UserAddress != "" && !common.IsHexAddress(t.UserAddress) {
+		return fmt.Errorf("invalid user_address: must be a hex address")
+	}
+	if err := checkTransferSpeed(t.TransferSpeed); err != nil {
+		return err
+	}
+	return t.Profile.validate()
+}
+
+// RebalanceExecution moves Amount USDC of UserAddress into TargetProtocol. Nonce is a
+// decimal string that must be above the last one used for the address. MaxSlippageBps is the performer default when
+// nil. Rebalances that are not Urgent may wait for a low gas window when the performer
+// times them. Strategy is StrategySequential, Execution ExecutionEOA and TransferSpeed
+// TransferSpeedStandard when empty.
+type RebalanceExecution struct {
+	UserAddress    string   `json:"user_address"`
+	Nonce          string   `json:"nonce,omitempty"`
+	Amount         string   `json:"amount"`
+	Token          string   `json:"token,omitempty"`
+	SourceProtocol string   `json:"source_protocol,omitempty"`
+	SourceChain    uint64   `json:"source_chain,omitempty"`
+	TargetProtocol string   `json:"target_protocol"`
+	TargetChain    uint64   `json:"target_chain,omitempty"`
+	MaxSlippageBps *uint64  `json:"max_slippage_bps,omitempty"`
+	ResizeToCap    bool     `json:"resize_to_cap,omitempty"`
+	Strategy       string   `json:"strategy,omitempty"`
+	Execution      string   `json:"execution,omitempty"`
+	TransferSpeed  string   `json:"transfer_speed,omitempty"`
+	Profile        *Profile `json:"profile,omitempty"`
+	Urgent         bool     `json:"urgent,omitempty"`
+	DryRun         bool     `json:"dry_run,omitempty"`
+}
+
+func (RebalanceExecution) TaskType() TaskType { return TaskTypeRebalanceExecution }
+
+func (t RebalanceExecution) validate() error {
+	if !common.IsHexAddress(t.UserAddress) {
+		return fmt.Errorf("missing or invalid user_address")
+	}
+	if t.Nonce != "" || !t.DryRun {
+		if _, err := tokens.ParsePositiveAmount(t.Nonce); err != nil {
+			return fmt.Errorf("missing or invalid nonce: rebalances must carry a positive decimal string nonce")
+		}
+	}
+	if err := checkAmount(t.Amount); err != nil {
+		return err
+	}
+	if t.TargetProtocol == "" {
+		return fmt.Errorf("missing or invalid target_protocol")
+	}
+	if t.Token != "" {
+		var chainIDs []uint64
+		for _, chainID := range []uint64{t.SourceChain, t.TargetChain} {
+			if chainID != 0 {
+				chainIDs = append(chainIDs, chainID)
+			}
+		}
+		if err := tokens.CheckUSDC(t.Token, chainIDs...); err != nil {
+			return fmt.Errorf("invalid token: %w", err)
+		}
+	}
+	if t.MaxSlippageBps != nil && *t.MaxSlippageBps > MaxBps {
+		return fmt.Errorf("invalid max_slippage_bps: must be an integer from 0 to %d", MaxBps)
+	}
+	switch t.Strategy {
+	case "", StrategySequential:
+	case StrategyFlashLoan:
+		if t.SourceProtocol == "" || (t.SourceChain != 0 && t.SourceChain != t.TargetChain) {
+			return fmt.Errorf("invalid strategy: flash loans only move funds between markets of one chain")
+		}
+	default:
+		return fmt.Errorf("invalid strategy: must be %s or %s", StrategySequential, StrategyFlashLoan)
+	}
+	if t.Execution != "" && t.Execution != ExecutionEOA && t.Execution != ExecutionUserOperation {
+		return fmt.Errorf("invalid execution: must be %s or %s", ExecutionEOA, ExecutionUserOperation)
+	}
+	if err := checkTransferSpeed(t.TransferSpeed); err != nil {
+		return err
+	}
+	return t.Profile.validate()
+}
+
+// RebalancePlan computes and stores the plan of a rebalance without executing it, for a
+// rebalance_commit to execute before ExpiresAt, a unix timestamp
+type RebalancePlan struct {
+	RebalanceExecution
+	ExpiresAt int64 `json:"expires_at"`
+}
+
+func (RebalancePlan) TaskType() TaskType { return TaskTypeRebalancePlan }
+
+func (t RebalancePlan) validate() error {
+	if t.DryRun {
+		return fmt.Errorf("dry_run cannot be set on plans, which are never executed")
+	}
+	deadline := time.Unix(t.ExpiresAt, 0)
+	if now := time.Now(); !deadline.After(now) || deadline.After(now.Add(MaxPlanLifetime)) {
+		return fmt.Errorf("invalid expires_at: must be in the future and at most %s ahead", MaxPlanLifetime)
+	}
+	return t.RebalanceExecution.validate()
+}
+
+// RebalanceCommit executes the plan a rebalance_plan stored under PlanHash
+type RebalanceCommit struct {
+	PlanHash string `json:"plan_hash"`
+}
+
+func (RebalanceCommit) TaskType() TaskType { return TaskTypeRebalanceCommit }
+
+func (t RebalanceCommit) validate() error {
+	if !isHash(t.PlanHash) {
+		return fmt.Errorf("missing or invalid plan_hash: must be a 32 byte hex hash")
+	}
+	return nil
+}
+
+// ResumeExecution submits the legs of an interrupted rebalance that did not go through
+type ResumeExecution struct {
+	ExecutionID string `json:"execution_id"`
+}
+
+func (ResumeExecution) TaskType() TaskType { return TaskTypeResumeExecution }
+
+func (t ResumeExecution) validate() error {
+	if id, err := hexutil.Decode(t.ExecutionID); err != nil || len(id) == 0 {
+		return fmt.Errorf("missing or invalid execution_id: must be the hex id of a rebalance execution")
+	}
+	return nil
+}
+
+// TransferRecovery mints the CCTP transfer of a rebalance again when it is stuck past
+// the SLA of the performer, or escalates it when it needs manual intervention
+type TransferRecovery struct {
+	ExecutionID string `json:"execution_id"`
+}
+
+func (TransferRecovery) TaskType() TaskType { return TaskTypeTransferRecovery }
+
+func (t TransferRecovery) validate() error {
+	return ResumeExecution(t).validate()
+}
+
+// RiskAssessment assesses the risk of a market
+type RiskAssessment struct {
+	Protocol       string `json:"protocol"`
+	ChainID        uint64 `json:"chain_id"`
+	AssessmentType string `json:"assessment_type"`
+}
+
+func (RiskAssessment) TaskType() TaskType { return TaskTypeRiskAssessment }
+
+func (t RiskAssessment) validate() error {
+	if t.Protocol == "" {
+		return fmt.Errorf("missing or invalid protocol")
+	}
+	if t.ChainID == 0 {
+		return fmt.Errorf("missing or invalid chain_id")
+	}
+	if t.AssessmentType == "" {
+		return fmt.Errorf("missing or invalid assessment_type")
+	}
+	return nil
+}
+
+// LiquidityDepthAnalysis reads how much a market absorbs before its rate moves by
+// MaxRateImpactBps, the performer default when zero
+type LiquidityDepthAnalysis struct {
+	Protocol         string `json:"protocol"`
+	ChainID          uint64 `json:"chain_id"`
+	Token            string `json:"token"`
+	MaxRateImpactBps uint64 `json:"max_rate_impact_bps,omitempty"`
+}
+
+func (LiquidityDepthAnalysis) TaskType() TaskType { return TaskTypeLiquidityDepthAnalysis }
+
+func (t LiquidityDepthAnalysis) validate() error {
+	if t.Protocol == "" {
+		return fmt.Errorf("missing or invalid protocol")
+	}
+	if err := checkUSDC(t.Token, t.ChainID); err != nil {
+		return err
+	}
+	if t.ChainID == 0 {
+		return fmt.Errorf("missing or invalid chain_id")
+	}
+	if t.MaxRateImpactBps > MaxBps {
+		return fmt.Errorf("invalid max_rate_impact_bps: must be an integer between 1 and %d", MaxBps)
+	}
+	return nil
+}
+
+// DepegMonitoring reads the price of Token on ChainIDs, every chain with a price source
+// when empty
+type DepegMonitoring struct {
+	Token    string   `json:"token"`
+	ChainIDs []uint64 `json:"chain_ids,omitempty"`
+}
+
+func (DepegMonitoring) TaskType() TaskType { return TaskTypeDepegMonitoring }
+
+func (t DepegMonitoring) validate() error {
+	if err := checkUSDC(t.Token, t.ChainIDs...); err != nil {
+		return err
+	}
+	return checkChainIDs(t.ChainIDs)
+}
+
+// APYForecast forecasts the supply rate of a market over HorizonsHours
+type APYForecast struct {
+	Protocol      string   `json:"protocol"`
+	ChainID       uint64   `json:"chain_id"`
+	Token         string   `json:"token"`
+	HorizonsHours []uint64 `json:"horizons_hours,omitempty"`
+	LookbackHours uint64   `json:"lookback_hours,omitempty"`
+	DepositAmount string   `json:"deposit_amount,omitempty"`
+}
+
+func (APYForecast) TaskType() TaskType { return TaskTypeAPYForecast }
+
+func (t APYForecast) validate() error {
+	if t.Protocol == "" {
+		return fmt.Errorf("missing or invalid protocol")
+	}
+	if err := checkUSDC(t.Token, t.ChainID); err != nil {
+		return err
+	}
+	if t.ChainID == 0 {
+		return fmt.Errorf("missing or invalid chain_id")
+	}
+	for _, h := range t.HorizonsHours {
+		if h == 0 || h > MaxForecastHorizonHours {
+			return fmt.Errorf("invalid horizon %d: must be an integer between 1 and %d hours", h, MaxForecastHorizonHours)
+		}
+	}
+	if t.LookbackHours != 0 && (t.LookbackHours < MinForecastLookbackHours || t.LookbackHours > MaxForecastLookbackHours) {
+		return fmt.Errorf("invalid lookback_hours: must be an integer between %d and %d", MinForecastLookbackHours, MaxForecastLookbackHours)
+	}
+	if t.DepositAmount != "" {
+		if _, err := tokens.ParsePositiveAmount(t.DepositAmount); err != nil {
+			return fmt.Errorf("invalid deposit_amount: %w", err)
+		}
+	}
+	return nil
+}
+
+// AllocationOptimization splits Amount USDC across markets of Protocols on ChainIDs,
+// every one when empty. TransferCosts are keyed by chain id, RiskPenaltyBps by protocol.
+// With StressShockBps set, no market gets more than stays withdrawable if its
+// utilization jumps by the shock. ConcentrationPenaltyBps is the annual penalty of
+// putting every dollar behind one dependency markets share, none when zero.
+type AllocationOptimization struct {
+	Amount                  string             `json:"amount"`
+	Token                   string             `json:"token"`
+	ChainIDs                []uint64           `json:"chain_ids,omitempty"`
+	Protocols               []string           `json:"protocols,omitempty"`
+	HorizonDays             uint64             `json:"horizon_days,omitempty"`
+	TransferCosts           map[uint64]string  `json:"transfer_costs,omitempty"`
+	RiskPenaltyBps          map[string]float64 `json:"risk_penalty_bps,omitempty"`
+	MaxShare                float64            `json:"max_share,omitempty"`
+	StressShockBps          uint64             `json:"stress_shock_bps,omitempty"`
+	ConcentrationPenaltyBps uint64             `json:"concentration_penalty_bps,omitempty"`
+	Profile                 *Profile           `json:"profile,omitempty"`
+}
+
+func (AllocationOptimization) TaskType() TaskType { return TaskTypeAllocationOptimization }
+
+func (t AllocationOptimization) validate() error {
+	if err := checkUSDC(t.Token, t.ChainIDs...); err != nil {
+		return err
+	}
+	if err := checkAmount(t.Amount); err != nil {
+		return err
+	}
+	if t.HorizonDays > MaxAllocationHorizonDays {
+		return fmt.Errorf("invalid horizon_days: must be an integer between 1 and %d", MaxAllocationHorizonDays)
+	}
+	if err := checkChainIDs(t.ChainIDs); err != nil {
+		return err
+	}
+	for chainID, cost := range t.TransferCosts {
+		if _, err := tokens.ParseAmount(cost); err != nil {
+			return fmt.Errorf("invalid transfer cost for chain %d: %w", chainID, err)
+		}
+	}
+	for protocol, bps := range t.RiskPenaltyBps {
+		if bps < 0 || bps > MaxBps {
+			return fmt.Errorf("invalid risk penalty for %s: must be between 0 and %d bps", protocol, MaxBps)
+		}
+	}
+	if t.MaxShare < 0 || t.MaxShare > 1 {
+		return fmt.Errorf("invalid max_share: must be in (0, 1]")
+	}
+	if t.StressShockBps > MaxBps {
+		return fmt.Errorf("invalid stress_shock_bps: must be an integer between 1 and %d bps", MaxBps)
+	}
+	if t.ConcentrationPenaltyBps > MaxBps {
+		return fmt.Errorf("invalid concentration_penalty_bps: must be between 0 and %d bps", MaxBps)
+	}
+	return t.Profile.validate()
+}
+
+// ProtocolIncidentCheck scans the last LookbackBlocks blocks of a market for incidents
+type ProtocolIncidentCheck struct {
+	Protocol       string `json:"protocol"`
+	ChainID        uint64 `json:"chain_id"`
+	LookbackBlocks uint64 `json:"lookback_blocks,omitempty"`
+}
+
+func (ProtocolIncidentCheck) TaskType() TaskType { return TaskTypeProtocolIncidentCheck }
+
+func (t ProtocolIncidentCheck) validate() error {
+	if t.Protocol == "" {
+		return fmt.Errorf("missing or invalid protocol")
+	}
+	if t.ChainID == 0 {
+		return fmt.Errorf("missing or invalid chain_id")
+	}
+	if t.LookbackBlocks > MaxIncidentLookback {
+		return fmt.Errorf("invalid lookback_blocks: must be an integer from 1 to %d", MaxIncidentLookback)
+	}
+	return nil
+}
+
+// PositionReconciliation compares the positions of Address on ChainIDs, every chain when
+// empty, with those the performer tracks
+type PositionReconciliation struct {
+	Address  string   `json:"address"`
+	ChainIDs []uint64 `json:"chain_ids,omitempty"`
+}
+
+func (PositionReconciliation) TaskType() TaskType { return TaskTypePositionReconciliation }
+
+func (t PositionReconciliation) validate() error {
+	if !common.IsHexAddress(t.Address) {
+		return fmt.Errorf("missing or invalid address")
+	}
+	return checkChainIDs(t.ChainIDs)
+}
+
+// YieldRanking ranks the markets of Protocols on ChainIDs, every one when empty.
+// RiskFreeRate is the performer default when nil. Liquidity pools are ranked alongside
+// lending markets when IncludeLP is set, and fixed rate markets when Mode is
+// RankingModeFixedVsVariable.
+type YieldRanking struct {
+	Token        string             `json:"token"`
+	ChainIDs     []uint64           `json:"chain_ids,omitempty"`
+	Protocols    []string           `json:"protocols,omitempty"`
+	Weights      map[string]float64 `json:"weights,omitempty"`
+	RiskFreeRate *float64           `json:"risk_free_rate,omitempty"`
+	IncludeLP    bool               `json:"include_lp,omitempty"`
+	Mode         string             `json:"mode,omitempty"`
+}
+
+func (YieldRanking) TaskType() TaskType { return TaskTypeYieldRanking }
+
+func (t YieldRanking) validate() error {
+	if err := checkUSDC(t.Token, t.ChainIDs...); err != nil {
+		return err
+	}
+	if err := checkChainIDs(t.ChainIDs); err != nil {
+		return err
+	}
+	if t.RiskFreeRate != nil && (*t.RiskFreeRate < 0 || *t.RiskFreeRate >= MaxRiskFreeRate) {
+		return fmt.Errorf("invalid risk_free_rate: must be an annual percentage between 0 and %d", MaxRiskFreeRate)
+	}
+	if t.Mode != "" && t.Mode != RankingModeStandard && t.Mode != RankingModeFixedVsVariable {
+		return fmt.Errorf("invalid mode: must be %s or %s", RankingModeStandard, RankingModeFixedVsVariable)
+	}
+	return nil
+}
+
+// Capabilities asks a performer for its release and the task types, adapters and chains
+// it serves
+type Capabilities struct{}
+
+func (Capabilities) TaskType() TaskType { return TaskTypeCapabilities }
+
+func (Capabilities) validate() error { return nil }
+
+// FullMarketSnapshot reads every market a performer serves into one snapshot, which its
+// comparison and ranking tasks reuse while fresh
+type FullMarketSnapshot struct {
+	Token string `json:"token"`
+}
+
+func (FullMarketSnapshot) TaskType() TaskType { return TaskTypeFullMarketSnapshot }
+
+func (t FullMarketSnapshot) validate() error { return checkUSDC(t.Token) }
+
+// PerformanceReport sums the costs and the yield uplift of the rebalances a performer
+// executed over the last WindowHours, the performer default when zero, of UserAddress
+// alone when set
+type PerformanceReport struct {
+	WindowHours uint64 `json:"window_hours,omitempty"`
+	UserAddress string `json:"user_address,omitempty"`
+}
+
+func (PerformanceReport) TaskType() TaskType { return TaskTypePerformanceReport }
+
+func (t PerformanceReport) validate() error {
+	if t.WindowHours > MaxPerformanceWindowHours {
+		return fmt.Errorf("invalid window_hours: must be at most %d", MaxPerformanceWindowHours)
+	}
+	if t.UserAddress != "" && !common.IsHexAddress(t.UserAddress) {
+		return fmt.Errorf("invalid user_address")
+	}
+	return nil
+}
+
+// AccrualVerification checks that the positions rebalances left accrued the rates their
+// markets advertised at deposit time, of UserAddress alone when set. Markets realizing a
+// rate further than MaxDivergenceBps from it are flagged, the performer default when nil.
+// Positions held less than MinPeriodHours, the performer default when zero, are not
+// checked.
+type AccrualVerification struct {
+	UserAddress      string  `json:"user_address,omitempty"`
+	MaxDivergenceBps *uint64 `json:"max_divergence_bps,omitempty"`
+	MinPeriodHours   uint64  `json:"min_period_hours,omitempty"`
+}
+
+func (AccrualVerification) TaskType() TaskType { return TaskTypeAccrualVerification }
+
+func (t AccrualVerification) validate() error {
+	if t.UserAddress != "" && !common.IsHexAddress(t.UserAddress) {
+		return fmt.Errorf("invalid user_address")
+	}
+	if t.MaxDivergenceBps != nil && *t.MaxDivergenceBps > MaxBps {
+		return fmt.Errorf("invalid max_divergence_bps: must be at most %d", MaxBps)
+	}
+	if t.MinPeriodHours > MaxAccrualPeriodHours {
+		return fmt.Errorf("invalid min_period_hours: must be at most %d", MaxAccrualPeriodHours)
+	}
+	return nil
+}
+
+// SelfReport reports the success rate, response latencies and quorum agreement of the
+// tasks the performer answered over the last WindowHours, the performer default when zero
+type SelfReport struct {
+	WindowHours uint64 `json:"window_hours,omitempty"`
+}
+
+func (SelfReport) TaskType() TaskType { return TaskTypeSelfReport }
+
+func (t SelfReport) validate() error {
+	if t.WindowHours > MaxSelfReportWindowHours {
+		return fmt.Errorf("invalid window_hours: must be at most %d", MaxSelfReportWindowHours)
+	}
+	return nil
+}
+
+// StressScenario models the rate and withdrawability of the markets of Protocols on
+// ChainIDs, every one when empty, if their utilization jumped by each of ShocksBps, the
+// performer defaults when empty. Amount, when set, is a position in USDC base units
+// whose withdrawable part is reported for every market.
+type StressScenario struct {
+	Token     string   `json:"token"`
+	ChainIDs  []uint64 `json:"chain_ids,omitempty"`
+	Protocols []string `json:"protocols,omitempty"`
+	ShocksBps []uint64 `json:"shocks_bps,omitempty"`
+	Amount    string   `json:"amount,omitempty"`
+}
+
+func (StressScenario) TaskType() TaskType { return TaskTypeStressScenario }
+
+func (t StressScenario) validate() error {
+	if err := checkUSDC(t.Token, t.ChainIDs...); err != nil {
+		return err
+	}
+	if err := checkChainIDs(t.ChainIDs); err != nil {
+		return err
+	}
+	if len(t.ShocksBps) > MaxStressShocks {
+		return fmt.Errorf("invalid shocks_bps: must be an array of 1 to %d shocks", MaxStressShocks)
+	}
+	for _, bps := range t.ShocksBps {
+		if bps == 0 || bps > MaxBps {
+			return fmt.Errorf("invalid shock %d: must be an integer between 1 and %d bps", bps, MaxBps)
+		}
+	}
+	if t.Amount != "" {
+		if err := checkAmount(t.Amount); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Batch runs Tasks concurrently and answers their results together. Result options
+// apply to the batch alone, and batches cannot be nested or move funds.
+type Batch struct {
+	Tasks []*Payload `json:"tasks"`
+}
+
+func (Batch) TaskType() TaskType { return TaskTypeBatch }
+
+func (t Batch) validate() error {
+	if len(t.Tasks) == 0 {
+		return fmt.Errorf("missing or invalid tasks: must be a non-empty array")
+	}
+	if len(t.Tasks) > MaxBatchSize {
+		return fmt.Errorf("too many tasks: %d exceeds the limit of %d", len(t.Tasks), MaxBatchSize)
+	}
+	for i, sub := range t.Tasks {
+		if sub.Type == TaskTypeBatch {
+			return fmt.Errorf("tasks[%d]: batches cannot be nested", i)
+		}
+		switch sub.Type {
+		case TaskTypeRebalanceExecution:
+			if dryRun, _ := sub.Parameters["dry_run"].(bool); !dryRun {
+				return fmt.Errorf("tasks[%d]: %s cannot be batched, as it moves funds", i, sub.Type)
+			}
+		case TaskTypeRebalanceCommit, TaskTypeResumeExecution, TaskTypeTransferRecovery:
+			return fmt.Errorf("tasks[%d]: %s cannot be batched, as it moves funds", i, sub.Type)
+		}
+		for _, option := range []string{"result_format", "attest", "schema_version", "fields", "result_encoding"} {
+			if _, present := sub.Parameters[option]; present {
+				return fmt.Errorf("tasks[%d]: %s can only be set on the batch", i, option)
+			}
+		}
+	}
+	return nil
+}
+
+// checkAmount checks amount is a positive decimal string of USDC base units
+func checkAmount(amount string) error {
+	if _, err := tokens.ParsePositiveAmount(amount); err != nil {
+		return fmt.Errorf("missing or invalid amount: %w", err)
+	}
+	return nil
+}
+
+// checkUSDC checks token names native USDC on chainIDs
+func checkUSDC(token string, chainIDs ...uint64) error {
+	if token == "" {
+		return fmt.Errorf("missing or invalid token, must be USDC")
+	}
+	var known []uint64
+	for _, chainID := range chainIDs {
+		if chainID != 0 {
+			known = append(known, chainID)
+		}
+	}
+	if err := tokens.CheckUSDC(token, known...); err != nil {
+		return fmt.Errorf("invalid token: %w", err)
+	}
+	return nil
+}
+
+func checkChainIDs(chainIDs []uint64) error {
+	for _, id := range chainIDs {
+		if id == 0 {
+			return fmt.Errorf("invalid chain id %d", id)
+		}
+	}
+	return nil
+}
+
+// checkTransferSpeed validates an optional transfer_speed
+func checkTransferSpeed(speed string) error {
+	if speed != "" && speed != TransferSpeedStandard && speed != TransferSpeedFast {
+		return fmt.Errorf("invalid transfer_speed: must be %s or %s", TransferSpeedStandard, TransferSpeedFast)
+	}
+	return nil
+}
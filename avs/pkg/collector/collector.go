@@ -0,0 +1,312 @@
+// Package collector samples market state from every registered adapter on an interval and
+// keeps it in the time series store, so handlers can use history for forecasting,
+// volatility metrics and anomaly detection.
+package collector
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+	"go.uber.org/zap"
+)
+
+// Config configures sampling and retention of historical market data
+type Config struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Interval is the time between samples of every market
+	Interval time.Duration `yaml:"interval"`
+
+	// Retention is how long points are kept before being deleted
+	Retention time.Duration `yaml:"retention"`
+
+	// CompactAfter is the age after which points are downsampled to CompactionResolution
+	CompactAfter time.Duration `yaml:"compactAfter"`
+
+	// CompactionResolution is the bucket size old points are averaged into
+	CompactionResolution time.Duration `yaml:"compactionResolution"`
+}
+
+// DefaultConfig samples every 5 minutes, keeps a week at full resolution, hourly
+// averages after that and deletes data older than 90 days
+func DefaultConfig() Config {
+	return Config{
+		Enabled:              true,
+		Interval:             5 * time.Minute,
+		Retention:            90 * 24 * time.Hour,
+		CompactAfter:         7 * 24 * time.Hour,
+		CompactionResolution: time.Hour,
+	}
+}
+
+// Validate checks the config for values the collector cannot run with
+func (c *Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Interval <= 0 {
+		return fmt.Errorf("interval must be positive")
+	}
+	if c.CompactionResolution < c.Interval {
+		return fmt.Errorf("compactionResolution must not be shorter than interval")
+	}
+	if c.CompactAfter <= 0 || c.Retention <= c.CompactAfter {
+		return fmt.Errorf("retention must be longer than compactAfter")
+	}
+	return nil
+}
+
+// HeadSource delivers the new heads of a chain, as chain.Manager does for chains with a
+// WebSocket endpoint
+type HeadSource interface {
+	SubscribeHeads(ctx context.Context, chainID uint64) (<-chan *types.Header, error)
+}
+
+// Sample is the state of a market as a collection sampled it. Amounts are in USDC base
+// units.
+type Sample struct {
+	Protocol    string
+	ChainID     uint64
+	SupplyRate  float64
+	Utilization float64
+	TotalSupply *big.Int
+	TotalBorrow *big.Int
+	At          time.Time
+}
+
+// Observer is told of the markets every collection sampled, once their points are stored
+type Observer func(ctx context.Context, samples []Sample)
+
+// Collector periodically records the supply rate and utilization of every market, and the
+// share price of vaults
+type Collector struct {
+	cfg       Config
+	registry  *adapters.Registry
+	history   *store.SeriesStore
+	chainIDs  map[uint64]bool
+	heads     HeadSource
+	observers []Observer
+	logger    *zap.Logger
+	now       func() time.Time
+
+	mu              sync.Mutex
+	lastMaintenance time.Time
+
+	// running tracks the goroutines of Start, see Wait
+	running sync.WaitGroup
+}
+
+// New creates a collector sampling the markets of registry on chainIDs
+func New(cfg Config, registry *adapters.Registry, history *store.SeriesStore, chainIDs []uint64, logger *zap.Logger) *Collector {
+	allowed := make(map[uint64]bool, len(chainIDs))
+	for _, id := range chainIDs {
+		allowed[id] = true
+	}
+	return &Collector{
+		cfg:      cfg,
+		registry: registry,
+		history:  history,
+		chainIDs: allowed,
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// FollowHeads makes Start sample the chains heads delivers on the first new head after
+// every interval, so samples are taken right after a block instead of on a timer
+func (c *Collector) FollowHeads(heads HeadSource) {
+	c.heads = heads
+}
+
+// Observe makes every collection, of all chains or the chain of a new head, tell observer
+// of the markets it sampled, after the observers added before it. It must be called
+// before Start.
+func (c *Collector) Observe(observer Observer) {
+	c.observers = append(c.observers, observer)
+}
+
+// Start samples immediately and then every interval until ctx is cancelled
+func (c *Collector) Start(ctx context.Context) {
+	timed := make(map[uint64]bool, len(c.chainIDs))
+	for chainID := range c.chainIDs {
+		if c.heads == nil {
+			timed[chainID] = true
+			continue
+		}
+		heads, err := c.heads.SubscribeHeads(ctx, chainID)
+		if err != nil {
+			timed[chainID] = true
+			continue
+		}
+		c.running.Add(1)
+		go func() {
+			defer c.running.Done()
+			c.follow(ctx, chainID, heads)
+		}()
+	}
+
+	c.running.Add(1)
+	go func() {
+		defer c.running.Done()
+		ticker := time.NewTicker(c.cfg.Interval)
+		defer ticker.Stop()
+		for {
+			c.run(ctx, timed)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Wait blocks until the goroutines of Start returned after its context was cancelled, so
+// the store the collector writes to can be closed
+func (c *Collector) Wait() {
+	c.running.Wait()
+}
+
+// follow samples chainID on the first of heads at least an interval after the previous
+// sample
+func (c *Collector) follow(ctx context.Context, chainID uint64, heads <-chan *types.Header) {
+	var last time.Time
+	for range heads {
+		now := c.now()
+		if !last.IsZero() && now.Sub(last) < c.cfg.Interval {
+			continue
+		}
+		last = now
+		sampled, err := c.collect(ctx, map[uint64]bool{chainID: true})
+		if err != nil {
+			c.logger.Sugar().Warnw("Yield collection incomplete", "chainId", chainID, "sampled", sampled, "error", err)
+		}
+	}
+}
+
+func (c *Collector) run(ctx context.Context, chainIDs map[uint64]bool) {
+	if len(chainIDs) > 0 {
+		sampled, err := c.collect(ctx, chainIDs)
+		if err != nil {
+			c.logger.Sugar().Warnw("Yield collection incomplete", "sampled", sampled, "error", err)
+		} else {
+			c.logger.Sugar().Debugw("Collected yield samples", "sampled", sampled)
+		}
+	}
+	if err := c.Maintain(ctx); err != nil {
+		c.logger.Sugar().Errorw("Failed to compact yield history", "error", err)
+	}
+}
+
+// Collect samples every market once. It returns the number of markets sampled and the
+// last error encountered; a failing market does not stop the others from being sampled.
+func (c *Collector) Collect(ctx context.Context) (int, error) {
+	return c.collect(ctx, c.chainIDs)
+}
+
+func (c *Collector) collect(ctx context.Context, chainIDs map[uint64]bool) (int, error) {
+	at := c.now()
+	var samples []Sample
+	var lastErr error
+	for _, protocol := range c.registry.Protocols() {
+		adapter, err := c.registry.Get(protocol)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		for _, chainID := range adapter.ChainIDs() {
+			if !chainIDs[chainID] {
+				continue
+			}
+			sample, err := c.sample(ctx, adapter, chainID, at)
+			if err != nil {
+				lastErr = fmt.Errorf("%s on chain %d: %w", protocol, chainID, err)
+				continue
+			}
+			samples = append(samples, *sample)
+		}
+	}
+	if len(samples) > 0 {
+		for _, observe := range c.observers {
+			observe(ctx, samples)
+		}
+	}
+	return len(samples), lastErr
+}
+
+// sample records the state of the market of adapter on chainID and returns it
+func (c *Collector) sample(ctx context.Context, adapter adapters.YieldAdapter, chainID uint64, at time.Time) (*Sample, error) {
+	// vault rates are measured from the share prices recorded here, so the price is
+	// recorded before the rate is read
+	if pricer, ok := adapter.(adapters.SharePricer); ok {
+		price, err := pricer.SharePrice(ctx, chainID)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.history.Append(ctx, store.SharePriceSeries(adapter.Protocol(), chainID), store.SeriesPoint{
+			Time:  at,
+			Value: price,
+		}); err != nil {
+			return nil, err
+		}
+	}
+	state, err := adapter.MarketState(ctx, chainID)
+	if err != nil {
+		return nil, err
+	}
+	rate, utilization := state.Pool.SupplyRate(), state.Pool.Utilization()
+	if err := c.history.Append(ctx, store.SupplyRateSeries(state.Protocol, chainID), store.SeriesPoint{
+		Time:  at,
+		Value: rate,
+	}); err != nil {
+		return nil, err
+	}
+	if err := c.history.Append(ctx, store.UtilizationSeries(state.Protocol, chainID), store.SeriesPoint{
+		Time:  at,
+		Value: utilization,
+	}); err != nil {
+		return nil, err
+	}
+	return &Sample{
+		Protocol:    state.Protocol,
+		ChainID:     chainID,
+		SupplyRate:  rate,
+		Utilization: utilization,
+		TotalSupply: state.Pool.TotalSupply,
+		TotalBorrow: state.Pool.TotalBorrow,
+		At:          at,
+	}, nil
+}
+
+// Maintain applies retention and compaction to every series, at most once per
+// compaction resolution
+func (c *Collector) Maintain(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if !c.lastMaintenance.IsZero() && now.Sub(c.lastMaintenance) < c.cfg.CompactionResolution {
+		return nil
+	}
+
+	names, err := c.history.Series(ctx)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if _, err := c.history.Prune(ctx, name, now.Add(-c.cfg.Retention)); err != nil {
+			return fmt.Errorf("failed to prune %s: %w", name, err)
+		}
+		if _, err := c.history.Compact(ctx, name, now.Add(-c.cfg.CompactAfter), c.cfg.CompactionResolution); err != nil {
+			return fmt.Errorf("failed to compact %s: %w", name, err)
+		}
+	}
+	c.lastMaintenance = now
+	return nil
+}
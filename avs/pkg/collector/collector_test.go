@@ -0,0 +1,196 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/irm"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+	"go.uber.org/zap"
+)
+
+type fakeAdapter struct {
+	protocol string
+	chains   []uint64
+	failOn   uint64
+}
+
+func (f *fakeAdapter) Protocol() string   { return f.protocol }
+func (f *fakeAdapter) ChainIDs() []uint64 { return f.chains }
+
+func (f *fakeAdapter) MarketState(ctx context.Context, chainID uint64) (*adapters.MarketState, error) {
+	if chainID == f.failOn {
+		return nil, errors.New("rpc unavailable")
+	}
+	return &adapters.MarketState{
+		Protocol: f.protocol,
+		ChainID:  chainID,
+		Pool: irm.Pool{
+			TotalSupply: big.NewInt(1000),
+			TotalBorrow: big.NewInt(500),
+			Model:       &irm.CometModel{Kink: 0.9, SlopeLow: 0.1},
+		},
+	}, nil
+}
+
+func Test_CollectSamplesConfiguredMarkets(t *testing.T) {
+	registry := adapters.NewRegistry(
+		&fakeAdapter{protocol: "aave_v3", chains: []uint64{1, 8453, 42161}},
+		&fakeAdapter{protocol: "compound_v3", chains: []uint64{1, 8453}, failOn: 8453},
+	)
+	history := store.NewSeriesStore(store.NewMemoryKV())
+	c := New(DefaultConfig(), registry, history, []uint64{1, 8453}, zap.NewNop())
+
+	sampled, err := c.Collect(context.Background())
+	if err == nil {
+		t.Errorf("Expected the failing market to be reported")
+	}
+	if sampled != 3 {
+		t.Errorf("Expected 3 markets to be sampled, got %d", sampled)
+	}
+
+	names, err := history.Series(context.Background())
+	if err != nil {
+		t.Fatalf("Series failed: %v", err)
+	}
+	// supply rate and utilization for aave on 1 and 8453 and compound on 1
+	if len(names) != 6 {
+		t.Errorf("Unexpected series: %v", names)
+	}
+
+	points, err := history.Range(context.Background(), store.SupplyRateSeries("aave_v3", 1), time.Unix(0, 0), time.Now().Add(time.Minute))
+	if err != nil || len(points) != 1 || points[0].Value != 0.05 {
+		t.Errorf("Unexpected supply rate points %+v: %v", points, err)
+	}
+}
+
+func Test_CollectTellsObserver(t *testing.T) {
+	registry := adapters.NewRegistry(
+		&fakeAdapter{protocol: "aave_v3", chains: []uint64{1, 8453}},
+		&fakeAdapter{protocol: "compound_v3", chains: []uint64{1}, failOn: 1},
+	)
+	c := New(DefaultConfig(), registry, store.NewSeriesStore(store.NewMemoryKV()), []uint64{1, 8453}, zap.NewNop())
+	var observed []Sample
+	c.Observe(func(ctx context.Context, samples []Sample) {
+		observed = append(observed, samples...)
+	})
+	told := 0
+	c.Observe(func(ctx context.Context, samples []Sample) {
+		told++
+	})
+
+	if _, err := c.Collect(context.Background()); err == nil {
+		t.Errorf("Expected the failing market to be reported")
+	}
+	if len(observed) != 2 || observed[0].Protocol != "aave_v3" || observed[0].SupplyRate != 0.05 || observed[0].TotalSupply == nil || observed[0].At.IsZero() {
+		t.Errorf("Expected the observer to be told of the sampled markets, got %+v", observed)
+	}
+	if told != 1 {
+		t.Errorf("Expected every observer to be told of the collection, got %d", told)
+	}
+}
+
+// fakeVault has no rate until its share price was recorded
+type fakeVault struct {
+	fakeAdapter
+	history *store.SeriesStore
+}
+
+func (f *fakeVault) SharePrice(ctx context.Context, chainID uint64) (float64, error) {
+	return 1.05, nil
+}
+
+func (f *fakeVault) MarketState(ctx context.Context, chainID uint64) (*adapters.MarketState, error) {
+	points, err := f.history.Range(ctx, store.SharePriceSeries(f.protocol, chainID), time.Unix(0, 0), time.Now().Add(time.Minute))
+	if err != nil || len(points) == 0 {
+		return nil, adapters.ErrInsufficientHistory
+	}
+	return f.fakeAdapter.MarketState(ctx, chainID)
+}
+
+func Test_CollectRecordsSharePrices(t *testing.T) {
+	history := store.NewSeriesStore(store.NewMemoryKV())
+	vault := &fakeVault{fakeAdapter: fakeAdapter{protocol: "steakhouse_usdc", chains: []uint64{1}}, history: history}
+	c := New(DefaultConfig(), adapters.NewRegistry(vault), history, []uint64{1}, zap.NewNop())
+
+	if sampled, err := c.Collect(context.Background()); err != nil || sampled != 1 {
+		t.Fatalf("Expected the vault to be sampled, got %d, %v", sampled, err)
+	}
+	points, err := history.Range(context.Background(), store.SharePriceSeries("steakhouse_usdc", 1), time.Unix(0, 0), time.Now().Add(time.Minute))
+	if err != nil || len(points) != 1 || points[0].Value != 1.05 {
+		t.Errorf("Unexpected share price points %+v: %v", points, err)
+	}
+}
+
+func Test_MaintainAppliesRetention(t *testing.T) {
+	registry := adapters.NewRegistry(&fakeAdapter{protocol: "aave_v3", chains: []uint64{1}})
+	history := store.NewSeriesStore(store.NewMemoryKV())
+	cfg := DefaultConfig()
+	c := New(cfg, registry, history, []uint64{1}, zap.NewNop())
+
+	now := time.Unix(1_800_000_000, 0)
+	c.now = func() time.Time { return now }
+	series := store.SupplyRateSeries("aave_v3", 1)
+	for _, age := range []time.Duration{cfg.Retention + time.Hour, cfg.CompactAfter + time.Hour, time.Hour} {
+		if err := history.Append(context.Background(), series, store.SeriesPoint{Time: now.Add(-age), Value: 1}); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+
+	if err := c.Maintain(context.Background()); err != nil {
+		t.Fatalf("Maintain failed: %v", err)
+	}
+	points, err := history.Range(context.Background(), series, time.Unix(0, 0), now)
+	if err != nil {
+		t.Fatalf("Range failed: %v", err)
+	}
+	if len(points) != 2 {
+		t.Errorf("Expected the point beyond retention to be deleted, got %+v", points)
+	}
+}
+
+func Test_FollowSamplesOnHeadsAfterInterval(t *testing.T) {
+	registry := adapters.NewRegistry(&fakeAdapter{protocol: "aave_v3", chains: []uint64{1, 8453}})
+	history := store.NewSeriesStore(store.NewMemoryKV())
+	c := New(DefaultConfig(), registry, history, []uint64{1, 8453}, zap.NewNop())
+
+	// every read of the clock is two minutes later than the previous one
+	now := time.Unix(1_800_000_000, 0)
+	c.now = func() time.Time {
+		now = now.Add(2 * time.Minute)
+		return now
+	}
+	start := now
+
+	heads := make(chan *types.Header, 3)
+	for number := int64(101); number <= 103; number++ {
+		heads <- &types.Header{Number: big.NewInt(number)}
+	}
+	close(heads)
+	c.follow(context.Background(), 1, heads)
+
+	// the second head comes four minutes after the first sample, within the interval
+	points, err := history.Range(context.Background(), store.SupplyRateSeries("aave_v3", 1), start, now.Add(time.Minute))
+	if err != nil || len(points) != 2 {
+		t.Errorf("Expected samples on the first and third head, got %+v (%v)", points, err)
+	}
+	if points, _ := history.Range(context.Background(), store.SupplyRateSeries("aave_v3", 8453), start, now.Add(time.Minute)); len(points) != 0 {
+		t.Errorf("Expected other chains not to be sampled, got %+v", points)
+	}
+}
+
+func Test_ConfigValidation(t *testing.T) {
+	cfg := DefaultConfig()
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected defaults to be valid: %v", err)
+	}
+	cfg.Retention = cfg.CompactAfter
+	if err := cfg.Validate(); err == nil {
+		t.Errorf("Expected retention shorter than compactAfter to be rejected")
+	}
+}
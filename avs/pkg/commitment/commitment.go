@@ -0,0 +1,144 @@
+// Package commitment commits to task results in a form contracts can check without the
+// full result being posted on-chain.
+//
+// A result is committed to by the keccak256 hash of its canonical JSON, and the yields
+// it reports per venue by the root of a Merkle tree over one leaf per venue. A leaf is
+// keccak256(keccak256(abi.encode(bytes32 protocolId, uint256 chainId, uint256 yieldBps))),
+// hashed twice so it cannot be mistaken for an inner node, and inner nodes hash their
+// children in sorted order, as OpenZeppelin's MerkleProof.verify expects. The yield hook
+// verifies the yield of a single venue against an attested root with the venue's proof.
+package commitment
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+)
+
+var venueArgs = abi.Arguments{
+	{Type: mustType("bytes32")},
+	{Type: mustType("uint256")},
+	{Type: mustType("uint256")},
+}
+
+func mustType(name string) abi.Type {
+	t, err := abi.NewType(name, "", nil)
+	if err != nil {
+		panic(fmt.Sprintf("invalid abi type %s: %v", name, err))
+	}
+	return t
+}
+
+// ResultHash returns the keccak256 hash of the canonical JSON of result
+func ResultHash(result interface{}) (common.Hash, error) {
+	encoded, err := canonical.Marshal(result)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to encode result: %w", err)
+	}
+	return crypto.Keccak256Hash(encoded), nil
+}
+
+// Venue is the yield reported for a market, as the yield hook identifies it
+type Venue struct {
+	ProtocolID [32]byte
+	ChainID    uint64
+
+	// YieldBps is the supply rate in basis points
+	YieldBps uint64
+}
+
+// Leaf returns the leaf of the venue in a venue tree
+func Leaf(v Venue) common.Hash {
+	encoded, err := venueArgs.Pack(v.ProtocolID, new(big.Int).SetUint64(v.ChainID), new(big.Int).SetUint64(v.YieldBps))
+	if err != nil {
+		// the arguments always match their types
+		panic(fmt.Sprintf("failed to abi encode venue: %v", err))
+	}
+	return crypto.Keccak256Hash(crypto.Keccak256(encoded))
+}
+
+// Tree is a Merkle tree over venue leaves, sorted and without duplicates so operators
+// reporting the same venues build the same tree
+type Tree struct {
+	layers [][]common.Hash
+}
+
+// NewTree builds the tree over leaves. A tree without leaves has the zero root.
+func NewTree(leaves []common.Hash) *Tree {
+	sorted := append([]common.Hash(nil), leaves...)
+	sort.Slice(sorted, func(i, j int) bool { return bytes.Compare(sorted[i][:], sorted[j][:]) < 0 })
+	unique := sorted[:0]
+	for i, leaf := range sorted {
+		if i == 0 || leaf != sorted[i-1] {
+			unique = append(unique, leaf)
+		}
+	}
+
+	tree := &Tree{layers: [][]common.Hash{unique}}
+	for layer := unique; len(layer) > 1; {
+		next := make([]common.Hash, 0, (len(layer)+1)/2)
+		for i := 0; i < len(layer); i += 2 {
+			if i+1 == len(layer) {
+				// an odd node is carried up unhashed
+				next = append(next, layer[i])
+				continue
+			}
+			next = append(next, hashPair(layer[i], layer[i+1]))
+		}
+		tree.layers = append(tree.layers, next)
+		layer = next
+	}
+	return tree
+}
+
+// Root returns the root of the tree
+func (t *Tree) Root() common.Hash {
+	top := t.layers[len(t.layers)-1]
+	if len(top) == 0 {
+		return common.Hash{}
+	}
+	return top[0]
+}
+
+// Proof returns the sibling hashes proving leaf is in the tree, bottom up, and false when
+// it is not
+func (t *Tree) Proof(leaf common.Hash) ([]common.Hash, bool) {
+	leaves := t.layers[0]
+	index := sort.Search(len(leaves), func(i int) bool { return bytes.Compare(leaves[i][:], leaf[:]) >= 0 })
+	if index == len(leaves) || leaves[index] != leaf {
+		return nil, false
+	}
+	proof := []common.Hash{}
+	for _, layer := range t.layers[:len(t.layers)-1] {
+		if sibling := index ^ 1; sibling < len(layer) {
+			proof = append(proof, layer[sibling])
+		}
+		index /= 2
+	}
+	return proof, true
+}
+
+// Verify reports whether proof proves leaf is in the tree of root, as MerkleProof.verify
+// does on-chain
+func Verify(root, leaf common.Hash, proof []common.Hash) bool {
+	computed := leaf
+	for _, sibling := range proof {
+		computed = hashPair(computed, sibling)
+	}
+	return computed == root
+}
+
+// hashPair hashes two nodes in sorted order, so proofs need not say which side a
+// sibling is on
+func hashPair(a, b common.Hash) common.Hash {
+	if bytes.Compare(a[:], b[:]) > 0 {
+		a, b = b, a
+	}
+	return crypto.Keccak256Hash(a[:], b[:])
+}
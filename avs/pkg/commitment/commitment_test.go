@@ -0,0 +1,52 @@
+package commitment
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func Test_TreeProvesEveryVenue(t *testing.T) {
+	for n := 0; n <= 7; n++ {
+		var leaves []common.Hash
+		for i := 0; i < n; i++ {
+			leaves = append(leaves, Leaf(Venue{ProtocolID: crypto.Keccak256Hash([]byte{byte(i)}), ChainID: 1, YieldBps: uint64(400 + i)}))
+		}
+		tree := NewTree(append(leaves, leaves...))
+		if n == 0 && tree.Root() != (common.Hash{}) {
+			t.Errorf("Expected the zero root without leaves, got %s", tree.Root().Hex())
+		}
+		if n == 1 && tree.Root() != leaves[0] {
+			t.Errorf("Expected the root of a single leaf to be the leaf")
+		}
+		for i, leaf := range leaves {
+			proof, ok := tree.Proof(leaf)
+			if !ok || !Verify(tree.Root(), leaf, proof) {
+				t.Errorf("%d leaves: expected leaf %d proven, got %v", n, i, proof)
+			}
+		}
+		if reversed := NewTree(reverse(leaves)); reversed.Root() != tree.Root() {
+			t.Errorf("%d leaves: expected the root independent of the leaf order", n)
+		}
+	}
+
+	leaf := Leaf(Venue{ProtocolID: crypto.Keccak256Hash([]byte("AAVE_V3")), ChainID: 1, YieldBps: 412})
+	other := Leaf(Venue{ProtocolID: crypto.Keccak256Hash([]byte("AAVE_V3")), ChainID: 1, YieldBps: 413})
+	tree := NewTree([]common.Hash{leaf, other})
+	if _, ok := tree.Proof(Leaf(Venue{ChainID: 1})); ok {
+		t.Errorf("Expected no proof of a venue not in the tree")
+	}
+	proof, _ := tree.Proof(leaf)
+	if Verify(tree.Root(), Leaf(Venue{ProtocolID: crypto.Keccak256Hash([]byte("AAVE_V3")), ChainID: 1, YieldBps: 500}), proof) {
+		t.Errorf("Expected a different yield to fail verification")
+	}
+}
+
+func reverse(leaves []common.Hash) []common.Hash {
+	out := make([]common.Hash, len(leaves))
+	for i, leaf := range leaves {
+		out[len(leaves)-1-i] = leaf
+	}
+	return out
+}
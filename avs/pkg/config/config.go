@@ -0,0 +1,782 @@
+// Package config is the YAML config of the performer, with the defaults of every
+// section and the checks run before the performer starts.
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/addressbook"
+	"github.com/najnomics/crosscow-avs/pkg/admin"
+	"github.com/najnomics/crosscow-avs/pkg/anomaly"
+	"github.com/najnomics/crosscow-avs/pkg/attestation"
+	"github.com/najnomics/crosscow-avs/pkg/auth"
+	"github.com/najnomics/crosscow-avs/pkg/bridge"
+	"github.com/najnomics/crosscow-avs/pkg/cache"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/circle"
+	"github.com/najnomics/crosscow-avs/pkg/collector"
+	"github.com/najnomics/crosscow-avs/pkg/crossval"
+	"github.com/najnomics/crosscow-avs/pkg/evidence"
+	"github.com/najnomics/crosscow-avs/pkg/features"
+	"github.com/najnomics/crosscow-avs/pkg/fixture"
+	"github.com/najnomics/crosscow-avs/pkg/flashloan"
+	"github.com/najnomics/crosscow-avs/pkg/gaswindow"
+	"github.com/najnomics/crosscow-avs/pkg/grpcserver"
+	"github.com/najnomics/crosscow-avs/pkg/health"
+	"github.com/najnomics/crosscow-avs/pkg/hysteresis"
+	"github.com/najnomics/crosscow-avs/pkg/incentives"
+	"github.com/najnomics/crosscow-avs/pkg/indexer"
+	"github.com/najnomics/crosscow-avs/pkg/killswitch"
+	"github.com/najnomics/crosscow-avs/pkg/ledger"
+	"github.com/najnomics/crosscow-avs/pkg/logging"
+	"github.com/najnomics/crosscow-avs/pkg/notify"
+	"github.com/najnomics/crosscow-avs/pkg/operator"
+	"github.com/najnomics/crosscow-avs/pkg/opportunity"
+	"github.com/najnomics/crosscow-avs/pkg/performer"
+	"github.com/najnomics/crosscow-avs/pkg/permit"
+	"github.com/najnomics/crosscow-avs/pkg/policy"
+	"github.com/najnomics/crosscow-avs/pkg/positions"
+	"github.com/najnomics/crosscow-avs/pkg/quota"
+	"github.com/najnomics/crosscow-avs/pkg/recovery"
+	"github.com/najnomics/crosscow-avs/pkg/redis"
+	"github.com/najnomics/crosscow-avs/pkg/reload"
+	"github.com/najnomics/crosscow-avs/pkg/reorg"
+	"github.com/najnomics/crosscow-avs/pkg/reputation"
+	"github.com/najnomics/crosscow-avs/pkg/resilience"
+	"github.com/najnomics/crosscow-avs/pkg/resultsize"
+	"github.com/najnomics/crosscow-avs/pkg/scan"
+	"github.com/najnomics/crosscow-avs/pkg/secrets"
+	"github.com/najnomics/crosscow-avs/pkg/security"
+	"github.com/najnomics/crosscow-avs/pkg/sequencer"
+	"github.com/najnomics/crosscow-avs/pkg/servicemanager"
+	"github.com/najnomics/crosscow-avs/pkg/signer"
+	"github.com/najnomics/crosscow-avs/pkg/simulate"
+	"github.com/najnomics/crosscow-avs/pkg/snapshot"
+	"github.com/najnomics/crosscow-avs/pkg/stability"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+	"github.com/najnomics/crosscow-avs/pkg/subgraph"
+	"github.com/najnomics/crosscow-avs/pkg/taskapi"
+	"github.com/najnomics/crosscow-avs/pkg/tasklistener"
+	"github.com/najnomics/crosscow-avs/pkg/taskqueue"
+	"github.com/najnomics/crosscow-avs/pkg/taskschema"
+	"github.com/najnomics/crosscow-avs/pkg/tokens"
+	"github.com/najnomics/crosscow-avs/pkg/tracing"
+	"github.com/najnomics/crosscow-avs/pkg/txmgr"
+	"github.com/najnomics/crosscow-avs/pkg/userop"
+	"github.com/najnomics/crosscow-avs/pkg/workerpool"
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds the runtime configuration of the USDC Yield Intelligence performer
+type Config struct {
+	GrpcPort int           `yaml:"grpcPort"`
+	Timeout  time.Duration `yaml:"timeout"`
+
+	// Grpc secures the gRPC server on GrpcPort with TLS, client certificates and a bearer
+	// token, for performers whose port is reachable beyond the executor
+	Grpc grpcserver.Config `yaml:"grpc"`
+
+	Storage store.Config  `yaml:"storage"`
+	Health  health.Config `yaml:"health"`
+
+	// Admin serves the operator API, for inspecting tasks and adapters and switching
+	// adapters and the log level at runtime, on its own port. Disabled by default.
+	Admin admin.Config `yaml:"admin"`
+
+	// TaskAPI serves the task interface over HTTP, for integrators previewing yield
+	// intelligence against this operator alone. Tasks moving funds are refused. Disabled
+	// by default.
+	TaskAPI taskapi.Config `yaml:"taskApi"`
+
+	// Logging selects the log format and level, and which task parameters are redacted
+	Logging logging.Config `yaml:"logging"`
+
+	// Tracing exports spans of tasks and the RPC and API calls they make over OTLP
+	Tracing tracing.Config `yaml:"tracing"`
+
+	// Fixtures records the HTTP and RPC calls of the performer, or serves them from a
+	// recording in simulation mode
+	Fixtures fixture.Config `yaml:"fixtures"`
+
+	// ShutdownTimeout is how long tasks in flight are waited for on shutdown before they
+	// are cancelled
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout"`
+
+	// Concurrency is the number of markets a task reads at once
+	Concurrency int `yaml:"concurrency"`
+
+	// Network is mainnet or testnet. Testnet deployments read the testnet counterparts of
+	// the supported chains, and default to Circle's sandbox attestation service.
+	Network chain.Network `yaml:"network"`
+
+	// Chains lists the RPC endpoints of every chain the performer reads from
+	Chains []chain.Config `yaml:"chains"`
+
+	// Protocols lists the protocols tasks may read, every supported protocol when empty
+	Protocols []string `yaml:"protocols"`
+
+	// Features enables experimental task types and adapters, which ship disabled. Tasks of
+	// a disabled type fail with task_type_disabled, so the aggregator routes them to
+	// operators who enabled it. Reloadable, and switchable through the admin API.
+	Features features.Config `yaml:"features"`
+
+	// AddressBook overrides or adds to the embedded contract addresses of protocols, by
+	// chain
+	AddressBook []addressbook.Entry `yaml:"addressBook"`
+
+	// Dependencies replace the embedded dependencies venues share, by chain and protocol,
+	// which allocation plans penalize concentration in
+	Dependencies []addressbook.DependencyEntry `yaml:"dependencies"`
+
+	// Vaults are ERC-4626 USDC vaults read by their share price history, each registered
+	// as a protocol of its own
+	Vaults adapters.VaultConfig `yaml:"vaults"`
+
+	// LiquidityPools are Curve and Uniswap USDC pools whose yield is estimated from their
+	// fees and incentives, each registered as a protocol of its own. Tasks only compare
+	// them with lending venues when asked to.
+	LiquidityPools adapters.LPConfig `yaml:"liquidityPools"`
+
+	// Pendle are Pendle USDC principal token markets, each maturity registered as a
+	// protocol of its own. Tasks only compare their fixed rates with variable ones when
+	// asked to.
+	Pendle adapters.PendleConfig `yaml:"pendle"`
+
+	// RWA are tokenized Treasury bill funds read by the net asset value their issuer
+	// publishes, each registered as a protocol of its own and compared with money
+	// markets like any other
+	RWA adapters.RWAConfig `yaml:"rwa"`
+
+	// Reload reloads the config file on SIGHUP and, when watched, whenever it changes.
+	// RPC endpoints, quota limits, anomaly and cross validation thresholds, protocols and
+	// the log level are applied at once; other settings on the next restart.
+	Reload reload.Config `yaml:"reload"`
+
+	// Circle enables the Circle API client. Leave unset when Circle Wallets are not used.
+	Circle *circle.Config `yaml:"circle"`
+
+	// Collector samples yields of every adapter into the historical time series store
+	Collector collector.Config `yaml:"collector"`
+
+	// Indexer keeps the state of every market up to date from protocol events, so tasks
+	// do not read it from the chain each time
+	Indexer indexer.Config `yaml:"indexer"`
+
+	// MarketSnapshots keeps the markets read by the latest full_market_snapshot task, so
+	// comparison and ranking tasks within its freshness window reuse them
+	MarketSnapshots scan.Config `yaml:"marketSnapshots"`
+
+	// Anomaly sets the rolling baseline and sigma thresholds used to flag suspicious rates
+	Anomaly anomaly.Config `yaml:"anomaly"`
+
+	// Stability sets the window yield_monitoring measures rate volatility and drawdown over
+	Stability stability.Config `yaml:"stability"`
+
+	// Hysteresis holds back moves cross_chain_yield_check recommends until the target has
+	// led the source long enough, and while funds cool down from their last move
+	Hysteresis hysteresis.Config `yaml:"hysteresis"`
+
+	// Sequencers checks the uptime feeds and block cadence of L2 sequencers before
+	// recommending or executing moves to their chains
+	Sequencers sequencer.Config `yaml:"sequencers"`
+
+	// CrossValidation checks contract rates against independent sources before reporting them
+	CrossValidation crossval.Config `yaml:"crossValidation"`
+
+	// Incentives prices the reward emissions of markets, reported next to their supply
+	// rate in yield_monitoring results
+	Incentives incentives.Config `yaml:"incentives"`
+
+	// Positions tracks where the performer account's USDC sits, so rebalances withdraw
+	// what is held rather than what tasks claim
+	Positions positions.Config `yaml:"positions"`
+
+	// Ledger records the gas, fees and rates of every verified rebalance, which
+	// performance_report tasks sum into profit and loss
+	Ledger ledger.Config `yaml:"ledger"`
+
+	// Subgraphs lists GraphQL endpoints for market history. Configured markets are also
+	// used as a cross validation source.
+	Subgraphs []subgraph.EndpointConfig `yaml:"subgraphs"`
+
+	// Resilience sets retries and circuit breaking of RPC, subgraph and API calls
+	Resilience resilience.Config `yaml:"resilience"`
+
+	// Multicall batches the view calls tasks make concurrently on a chain, such as the
+	// reads of every market of a monitoring task, into Multicall3 calls, so they cost one
+	// RPC round trip. Enabled by default.
+	Multicall chain.BatchConfig `yaml:"multicall"`
+
+	// Cache keeps results of read-only tasks so repeated queries within a block interval
+	// do not reach RPC providers
+	Cache cache.Config `yaml:"cache"`
+
+	// Results bounds the size of encoded results by task type. Tasks over the bound fail
+	// with result_too_large, and keep within it by selecting the fields of their results
+	// or compressing them. Defaults to 4 MiB for every task type.
+	Results resultsize.Config `yaml:"results"`
+
+	// PayloadSchemas validates task payloads against a JSON Schema of their type before
+	// they are handled. The built-in schemas always apply; operators may tighten them with
+	// schemas of their own, and reject parameters the schemas do not declare.
+	PayloadSchemas taskschema.Config `yaml:"payloadSchemas"`
+
+	// Secrets selects the provider the ${secret:name} references of other settings, such
+	// as RPC URLs carrying API keys, the Circle API key and signer credentials, are read
+	// from. Secrets are never logged, and rotated ones are applied without a restart where
+	// the setting can be reloaded. Defaults to the environment.
+	Secrets secrets.Config `yaml:"secrets"`
+
+	// Redis is shared by the replicas of an operator's performer. Enabled, replicas
+	// deduplicate tasks delivered to several of them and lease the rebalance plans they
+	// execute, and share the result cache and count quotas together when their backend is
+	// redis. Disabled by default.
+	Redis redis.Config `yaml:"redis"`
+
+	// Simulation selects how rebalance dry runs are simulated: over RPC by default, or on
+	// Tenderly when configured
+	Simulation simulate.Config `yaml:"simulation"`
+
+	// Transactions lets rebalance_execution sign and submit transactions itself. Leave
+	// disabled when rebalances go through Circle Wallets.
+	Transactions txmgr.Config `yaml:"transactions"`
+
+	// Security is the signed feed of audits and incidents risk assessments factor in.
+	// Disabled by default.
+	Security security.Config `yaml:"security"`
+
+	// Authorization verifies that tasks were signed by an authorized submitter. When
+	// enabled, the tasks moving funds (rebalance_execution, rebalance_commit,
+	// resume_execution and transfer_recovery) are rejected unless signed.
+	Authorization auth.Config `yaml:"authorization"`
+
+	// Attestation signs the results of tasks setting the attest parameter with an
+	// operator key, so consumers can audit which operator produced them. Disabled by
+	// default.
+	Attestation attestation.Config `yaml:"attestation"`
+
+	// Snapshots read the markets and prices of monitoring and risk tasks at the latest
+	// finalized block, or the block_number or block_hash of the task, and snap supply
+	// rates to ratePlaces, so operators at different heads agree on the result. Blocks
+	// whose state full nodes pruned need archive nodes. Disabled by default.
+	Snapshots snapshot.Config `yaml:"snapshots"`
+
+	// Bridges are the routes cross_chain_yield_check ranks next to CCTP, and how fees,
+	// latency and trust are weighed. By default the canonical bridges of Polygon PoS,
+	// Optimism, Arbitrum and Base are compared. Its fallback bridges rebalances through
+	// Across while a burn waits past the attestation SLA; disabled by default.
+	Bridges bridge.Config `yaml:"bridges"`
+
+	// Stablecoins lets yield_monitoring tasks read USDT, DAI and USDe markets, to compare
+	// their yields with USDC's. Only protocols reading any asset, such as Aave v3, report
+	// them. Disabled by default.
+	Stablecoins tokens.Config `yaml:"stablecoins"`
+
+	// Policy sets hard limits on where funds go: protocols allowed or denied, the TVL and
+	// utilization of target markets, and how much a move or plan puts anywhere. Rebalances
+	// breaking a rule are refused and recommendations leave the markets out. Disabled by
+	// default.
+	Policy policy.Config `yaml:"policy"`
+
+	// KillSwitch halts rebalances while the pause flag of the AVS service manager is set,
+	// on top of the halts operators set through the admin API. Reading the flag is
+	// disabled by default.
+	KillSwitch killswitch.Config `yaml:"killSwitch"`
+
+	// Reputation compares the results of the performer with the ones their quorum settled
+	// on in the task mailbox, in self reports. Disabled by default.
+	Reputation reputation.Config `yaml:"reputation"`
+
+	// GasWindows delays rebalances not marked urgent while base fees are above a ceiling
+	// or falling, up to a maximum delay. Disabled by default.
+	GasWindows gaswindow.Config `yaml:"gasWindows"`
+
+	// FlashLoans are the helper contracts rebalances between markets of one chain move
+	// through atomically when their task selects the flash_loan strategy. Disabled by
+	// default.
+	FlashLoans flashloan.Config `yaml:"flashLoans"`
+
+	// Permits let rebalance deposits into markets taking EIP-2612 permits spend a permit
+	// signed by the performer account instead of an approval sent before them, and let
+	// rebalance_execution tasks deposit the USDC of users with EIP-2612 or Permit2 permits
+	// they signed. Disabled by default.
+	Permits permit.Config `yaml:"permits"`
+
+	// UserOperations send rebalances of ERC-4337 smart accounts owned by the performer
+	// account as user operations through the configured bundlers and paymasters. Needs
+	// Transactions, whose signer signs the operations. Disabled by default.
+	UserOperations userop.Config `yaml:"userOperations"`
+
+	// Notifications post signed events of completed tasks, executed rebalances, detected
+	// anomalies and failed executions to operator webhooks, and alert Slack and PagerDuty
+	// of depegs, failed executions and opened circuit breakers. Disabled by default.
+	Notifications notify.Config `yaml:"notifications"`
+
+	// Opportunities sign rebalance opportunities when the collector samples a market
+	// leading another by the threshold, post them as notifications and optionally create
+	// tasks for them in the TaskMailbox. Needs the Collector and Attestation, whose key
+	// signs them, and Transactions to create tasks. Disabled by default.
+	Opportunities opportunity.Config `yaml:"opportunities"`
+
+	// Submission submits yield attestations of the markets the collector samples to the
+	// YieldIntelligenceServiceManager, for operators who also run the aggregator role, and
+	// attests markets other operators attested so their consensus can be reached. Needs
+	// the Collector and Transactions, whose account submits and signs the attestations.
+	// Disabled by default.
+	Submission servicemanager.Config `yaml:"submission"`
+
+	// Evidence re-checks the yield attestations submitted to the service manager against
+	// the markets they attest, read at the block before theirs, and keeps an artifact of
+	// those disagreeing beyond the tolerance for challenges, served by the admin API. Reads
+	// of past blocks need archive nodes. Disabled by default.
+	Evidence evidence.Config `yaml:"evidence"`
+
+	// Reorg watches the snapshot reference blocks results were read at, and marks the
+	// stored results of blocks a reorg removed once confirmed as stale, drops them from
+	// the cache and raises result_invalidated alerts. Needs Snapshots. Disabled by default.
+	Reorg reorg.Config `yaml:"reorg"`
+
+	// Recovery is when the CCTP transfers of rebalances count as stuck, unminted past the
+	// SLA, and how many times transfer_recovery submits their mints again before raising
+	// transfer_stuck alerts. Its watchdog recovers them in the background and needs
+	// Transactions; disabled by default.
+	Recovery recovery.Config `yaml:"recovery"`
+
+	// TaskListener follows the tasks created for the AVS in the TaskMailbox and computes
+	// the results of the cached task types before Hourglass delivers them. Needs the
+	// Cache. Disabled by default.
+	TaskListener tasklistener.Config `yaml:"taskListener"`
+
+	// Operator locates the AVS and the EigenLayer contracts the operator commands register
+	// keys and operator sets with, and configures checking the operator is still in good
+	// standing with the AVS before executing rebalances. The check is disabled by default.
+	Operator operator.Config `yaml:"operator"`
+
+	// Quotas bound how many tasks of each type start per minute and run at once. By
+	// default rebalances are limited to 6 a minute, and the monitoring tasks reading many
+	// markets to a few at once.
+	Quotas quota.Config `yaml:"quotas"`
+
+	// TaskQueue bounds how many tasks of each type run at once, queueing or rejecting the
+	// tasks over the bound, and which queued tasks run first. By default up to 32 tasks
+	// run at once, tasks moving funds one at a time, and executions and depeg checks run
+	// ahead of the yield polling queued before them.
+	TaskQueue taskqueue.Config `yaml:"taskQueue"`
+}
+
+// Default returns the configuration used when no config file is supplied
+func Default() *Config {
+	return &Config{
+		GrpcPort:        8080,
+		Timeout:         5 * time.Second,
+		ShutdownTimeout: 30 * time.Second,
+		Logging:         logging.DefaultConfig(),
+		Tracing:         tracing.DefaultConfig(),
+		Storage: store.Config{
+			Type: store.StorageTypeMemory,
+		},
+		Health: health.Config{
+			Enabled:      true,
+			Port:         8090,
+			CheckTimeout: 3 * time.Second,
+		},
+		Admin:          admin.DefaultConfig(),
+		TaskAPI:        taskapi.DefaultConfig(),
+		Reload:         reload.DefaultConfig(),
+		Concurrency:    workerpool.DefaultLimit,
+		Network:        chain.NetworkMainnet,
+		Vaults:         adapters.DefaultVaultConfig(),
+		LiquidityPools: adapters.DefaultLPConfig(),
+		RWA:            adapters.DefaultRWAConfig(),
+		Collector:      collector.DefaultConfig(),
+		Indexer:        indexer.DefaultConfig(),
+		Anomaly:        anomaly.DefaultConfig(),
+		Stability:      stability.DefaultConfig(),
+
+		MarketSnapshots: scan.DefaultConfig(),
+		Hysteresis:      hysteresis.DefaultConfig(),
+		Sequencers:      sequencer.DefaultConfig(),
+		CrossValidation: crossval.DefaultConfig(),
+		Incentives:      incentives.DefaultConfig(),
+		Positions:       positions.DefaultConfig(),
+		Ledger:          ledger.DefaultConfig(),
+		Resilience:      resilience.DefaultConfig(),
+		Multicall:       chain.DefaultBatchConfig(),
+		Cache:           cache.DefaultConfig(),
+		Results:         resultsize.DefaultConfig(),
+		PayloadSchemas:  taskschema.DefaultConfig(),
+		Secrets:         secrets.DefaultConfig(),
+		Redis:           redis.DefaultConfig(),
+		Simulation:      simulate.DefaultConfig(),
+		Transactions:    txmgr.DefaultConfig(),
+		Security:        security.DefaultConfig(),
+		Authorization:   auth.Config{Required: []string{string(performer.TaskTypeRebalanceExecution), string(performer.TaskTypeRebalanceCommit), string(performer.TaskTypeResumeExecution), string(performer.TaskTypeTransferRecovery)}},
+		Attestation:     attestation.DefaultConfig(),
+		Snapshots:       snapshot.DefaultConfig(),
+		Bridges:         bridge.DefaultConfig(),
+		Stablecoins:     tokens.DefaultConfig(),
+		Policy:          policy.DefaultConfig(),
+		KillSwitch:      killswitch.DefaultConfig(),
+		Reputation:      reputation.DefaultConfig(),
+		GasWindows:      gaswindow.DefaultConfig(),
+		FlashLoans:      flashloan.DefaultConfig(),
+		Permits:         permit.DefaultConfig(),
+		UserOperations:  userop.DefaultConfig(),
+		Notifications:   notify.DefaultConfig(),
+		Opportunities:   opportunity.DefaultConfig(),
+		Submission:      servicemanager.DefaultConfig(),
+		Evidence:        evidence.DefaultConfig(),
+		Reorg:           reorg.DefaultConfig(),
+		Recovery:        recovery.DefaultConfig(),
+		TaskListener:    tasklistener.DefaultConfig(),
+		Operator:        operator.DefaultConfig(),
+		Quotas: quota.Config{
+			Enabled: true,
+			Limits: map[string]quota.Limit{
+				string(performer.TaskTypeRebalanceExecution):     {PerMinute: 6},
+				string(performer.TaskTypeRebalanceCommit):        {PerMinute: 6},
+				string(performer.TaskTypeResumeExecution):        {PerMinute: 6},
+				string(performer.TaskTypeTransferRecovery):       {PerMinute: 6},
+				string(performer.TaskTypeYieldMonitoring):        {MaxConcurrent: 8},
+				string(performer.TaskTypeCrossChainYieldCheck):   {MaxConcurrent: 4},
+				string(performer.TaskTypeAllocationOptimization): {MaxConcurrent: 4},
+				string(performer.TaskTypeYieldRanking):           {MaxConcurrent: 4},
+				string(performer.TaskTypeBatch):                  {MaxConcurrent: 4},
+				string(performer.TaskTypeFullMarketSnapshot):     {MaxConcurrent: 2},
+			},
+		},
+		TaskQueue: taskqueue.DefaultConfig(),
+	}
+}
+
+// Load reads a YAML config file on top of the defaults.
+// An empty path returns the defaults. References to secrets are left for a secrets.Store
+// to expand.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	if path == "" {
+		return cfg, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	if err := cfg.checkSecretReferences(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return cfg, nil
+}
+
+// checkSecretReferences refuses signer credentials written in the config file rather
+// than referring to the secrets provider. It runs on the file as loaded, before the
+// references are expanded.
+func (c *Config) checkSecretReferences() error {
+	if err := checkSignerSecrets(c.Transactions.Signer); err != nil {
+		return fmt.Errorf("transactions.signer: %w", err)
+	}
+	for chainID, chainSigner := range c.Transactions.ChainSigners {
+		if err := checkSignerSecrets(chainSigner); err != nil {
+			return fmt.Errorf("transactions.chainSigners[%d]: %w", chainID, err)
+		}
+	}
+	if err := checkSignerSecrets(c.Attestation.Signer); err != nil {
+		return fmt.Errorf("attestation.signer: %w", err)
+	}
+	return nil
+}
+
+func checkSignerSecrets(cfg signer.Config) error {
+	if cfg.PrivateKey != "" && !secrets.HasReference(cfg.PrivateKey) {
+		return fmt.Errorf("privateKey must refer to the secrets provider, such as ${secret:signer-key}")
+	}
+	if cfg.Keystore.Password != "" && !secrets.HasReference(cfg.Keystore.Password) {
+		return fmt.Errorf("keystore.password must refer to the secrets provider, such as ${secret:keystore-password}")
+	}
+	return nil
+}
+
+// Validate checks the config for values the performer cannot run with
+func (c *Config) Validate() error {
+	if c.GrpcPort <= 0 || c.GrpcPort > 65535 {
+		return fmt.Errorf("grpcPort must be between 1 and 65535")
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("timeout must be positive")
+	}
+	if err := c.Grpc.Validate(); err != nil {
+		return fmt.Errorf("grpc: %w", err)
+	}
+	if c.ShutdownTimeout <= 0 {
+		return fmt.Errorf("shutdownTimeout must be positive")
+	}
+	if err := c.Logging.Validate(); err != nil {
+		return fmt.Errorf("logging: %w", err)
+	}
+	if err := c.Tracing.Validate(); err != nil {
+		return fmt.Errorf("tracing: %w", err)
+	}
+	if err := c.Fixtures.Validate(); err != nil {
+		return fmt.Errorf("fixtures: %w", err)
+	}
+	if c.Concurrency < 1 {
+		return fmt.Errorf("concurrency must be at least 1")
+	}
+	if err := c.Reload.Validate(); err != nil {
+		return fmt.Errorf("reload: %w", err)
+	}
+
+	switch c.Storage.Type {
+	case "", store.StorageTypeMemory:
+	case store.StorageTypeBadger:
+		if c.Storage.Badger == nil || (c.Storage.Badger.Dir == "" && !c.Storage.Badger.InMemory) {
+			return fmt.Errorf("storage.badger.dir is required for badger storage")
+		}
+	default:
+		return fmt.Errorf("unknown storage type: %s", c.Storage.Type)
+	}
+
+	if c.Health.Enabled && (c.Health.Port <= 0 || c.Health.Port > 65535 || c.Health.Port == c.GrpcPort) {
+		return fmt.Errorf("health.port must be a valid port distinct from grpcPort")
+	}
+	if err := c.Features.Validate(); err != nil {
+		return fmt.Errorf("features: %w", err)
+	}
+	if err := c.Admin.Validate(); err != nil {
+		return fmt.Errorf("admin: %w", err)
+	}
+	if c.Admin.Enabled && (c.Admin.Port == c.GrpcPort || (c.Health.Enabled && c.Admin.Port == c.Health.Port)) {
+		return fmt.Errorf("admin.port must be distinct from grpcPort and health.port")
+	}
+	if err := c.TaskAPI.Validate(); err != nil {
+		return fmt.Errorf("taskApi: %w", err)
+	}
+	if c.TaskAPI.Enabled && (c.TaskAPI.Port == c.GrpcPort || (c.Health.Enabled && c.TaskAPI.Port == c.Health.Port) ||
+		(c.Admin.Enabled && c.TaskAPI.Port == c.Admin.Port)) {
+		return fmt.Errorf("taskApi.port must be distinct from grpcPort, health.port and admin.port")
+	}
+
+	if err := c.Network.Validate(); err != nil {
+		return fmt.Errorf("network: %w", err)
+	}
+	seen := make(map[uint64]bool, len(c.Chains))
+	for i, ch := range c.Chains {
+		if ch.ChainID == 0 {
+			return fmt.Errorf("chains[%d].chainId is required", i)
+		}
+		if ch.RpcUrl == "" {
+			return fmt.Errorf("chains[%d].rpcUrl is required", i)
+		}
+		if ch.WsUrl != "" && !strings.HasPrefix(ch.WsUrl, "ws://") && !strings.HasPrefix(ch.WsUrl, "wss://") {
+			return fmt.Errorf("chains[%d].wsUrl must be a ws:// or wss:// url", i)
+		}
+		for j, url := range ch.QuorumRpcUrls {
+			if url == "" || url == ch.RpcUrl {
+				return fmt.Errorf("chains[%d].quorumRpcUrls[%d] must be set and differ from rpcUrl", i, j)
+			}
+		}
+		if network, ok := chain.NetworkOf(ch.ChainID); ok && network != c.Network {
+			return fmt.Errorf("chains[%d]: chain %d is a %s chain, not a %s one", i, ch.ChainID, network, c.Network)
+		}
+		if seen[ch.ChainID] {
+			return fmt.Errorf("chain %d is configured more than once", ch.ChainID)
+		}
+		seen[ch.ChainID] = true
+	}
+
+	if err := addressbook.Validate(c.AddressBook); err != nil {
+		return fmt.Errorf("addressBook%w", err)
+	}
+	if err := addressbook.ValidateDependencies(c.Dependencies); err != nil {
+		return fmt.Errorf("dependencies%w", err)
+	}
+	if err := c.Vaults.Validate(); err != nil {
+		return fmt.Errorf("vaults: %w", err)
+	}
+	if err := c.LiquidityPools.Validate(); err != nil {
+		return fmt.Errorf("liquidityPools: %w", err)
+	}
+	if err := c.Pendle.Validate(); err != nil {
+		return fmt.Errorf("pendle: %w", err)
+	}
+	if err := c.RWA.Validate(); err != nil {
+		return fmt.Errorf("rwa: %w", err)
+	}
+	if err := c.Collector.Validate(); err != nil {
+		return fmt.Errorf("collector: %w", err)
+	}
+	if err := c.Indexer.Validate(); err != nil {
+		return fmt.Errorf("indexer: %w", err)
+	}
+	if err := c.MarketSnapshots.Validate(); err != nil {
+		return fmt.Errorf("marketSnapshots: %w", err)
+	}
+	if err := c.Anomaly.Validate(); err != nil {
+		return fmt.Errorf("anomaly: %w", err)
+	}
+	if err := c.Stability.Validate(); err != nil {
+		return fmt.Errorf("stability: %w", err)
+	}
+	if err := c.Hysteresis.Validate(); err != nil {
+		return fmt.Errorf("hysteresis: %w", err)
+	}
+	if err := c.Sequencers.Validate(); err != nil {
+		return fmt.Errorf("sequencers: %w", err)
+	}
+	if err := c.CrossValidation.Validate(); err != nil {
+		return fmt.Errorf("crossValidation: %w", err)
+	}
+	if err := c.Incentives.Validate(); err != nil {
+		return fmt.Errorf("incentives: %w", err)
+	}
+	if err := c.Positions.Validate(); err != nil {
+		return fmt.Errorf("positions: %w", err)
+	}
+	if err := c.Ledger.Validate(); err != nil {
+		return fmt.Errorf("ledger: %w", err)
+	}
+	for i, sg := range c.Subgraphs {
+		if err := sg.Validate(); err != nil {
+			return fmt.Errorf("subgraphs[%d]: %w", i, err)
+		}
+	}
+	if err := c.Resilience.Validate(); err != nil {
+		return fmt.Errorf("resilience: %w", err)
+	}
+	if err := c.Multicall.Validate(); err != nil {
+		return fmt.Errorf("multicall: %w", err)
+	}
+	if err := c.Cache.Validate(); err != nil {
+		return fmt.Errorf("cache: %w", err)
+	}
+	if err := c.Results.Validate(); err != nil {
+		return fmt.Errorf("results: %w", err)
+	}
+	if err := c.PayloadSchemas.Validate(); err != nil {
+		return fmt.Errorf("payloadSchemas: %w", err)
+	}
+	if err := c.Secrets.Validate(); err != nil {
+		return fmt.Errorf("secrets: %w", err)
+	}
+	if err := c.Redis.Validate(); err != nil {
+		return fmt.Errorf("redis: %w", err)
+	}
+	if c.Cache.Enabled && c.Cache.Backend == cache.BackendRedis && !c.Redis.Enabled {
+		return fmt.Errorf("cache: the redis backend requires redis to be enabled")
+	}
+	if err := c.Simulation.Validate(); err != nil {
+		return fmt.Errorf("simulation: %w", err)
+	}
+	if err := c.Transactions.Validate(); err != nil {
+		return fmt.Errorf("transactions: %w", err)
+	}
+	if err := c.Security.Validate(); err != nil {
+		return fmt.Errorf("security: %w", err)
+	}
+	if err := c.Authorization.Validate(); err != nil {
+		return fmt.Errorf("authorization: %w", err)
+	}
+	if err := c.Attestation.Validate(); err != nil {
+		return fmt.Errorf("attestation: %w", err)
+	}
+	if err := c.Snapshots.Validate(); err != nil {
+		return fmt.Errorf("snapshots: %w", err)
+	}
+	if err := c.Bridges.Validate(); err != nil {
+		return fmt.Errorf("bridges: %w", err)
+	}
+	if err := c.Stablecoins.Validate(); err != nil {
+		return fmt.Errorf("stablecoins: %w", err)
+	}
+	if err := c.Policy.Validate(); err != nil {
+		return fmt.Errorf("policy: %w", err)
+	}
+	if err := c.KillSwitch.Validate(); err != nil {
+		return fmt.Errorf("killSwitch: %w", err)
+	}
+	if err := c.Reputation.Validate(); err != nil {
+		return fmt.Errorf("reputation: %w", err)
+	}
+	if err := c.GasWindows.Validate(); err != nil {
+		return fmt.Errorf("gasWindows: %w", err)
+	}
+	if err := c.FlashLoans.Validate(); err != nil {
+		return fmt.Errorf("flashLoans: %w", err)
+	}
+	if err := c.Permits.Validate(); err != nil {
+		return fmt.Errorf("permits: %w", err)
+	}
+	if err := c.UserOperations.Validate(); err != nil {
+		return fmt.Errorf("userOperations: %w", err)
+	}
+	if c.UserOperations.Enabled && !c.Transactions.Enabled {
+		return fmt.Errorf("userOperations: transactions must be enabled to sign user operations")
+	}
+	if err := c.Notifications.Validate(); err != nil {
+		return fmt.Errorf("notifications: %w", err)
+	}
+	if err := c.Opportunities.Validate(); err != nil {
+		return fmt.Errorf("opportunities: %w", err)
+	}
+	if c.Opportunities.Enabled && (!c.Collector.Enabled || !c.Attestation.Enabled) {
+		return fmt.Errorf("opportunities: the collector and attestation must be enabled to sign opportunities")
+	}
+	if c.Opportunities.Mailbox.Enabled && !c.Transactions.Enabled {
+		return fmt.Errorf("opportunities: transactions must be enabled to create tasks")
+	}
+	if err := c.Submission.Validate(); err != nil {
+		return fmt.Errorf("submission: %w", err)
+	}
+	if c.Submission.Enabled && (!c.Collector.Enabled || !c.Transactions.Enabled) {
+		return fmt.Errorf("submission: the collector and transactions must be enabled to submit attestations")
+	}
+	if err := c.Evidence.Validate(); err != nil {
+		return fmt.Errorf("evidence: %w", err)
+	}
+	if err := c.Reorg.Validate(); err != nil {
+		return fmt.Errorf("reorg: %w", err)
+	}
+	if c.Reorg.Enabled && !c.Snapshots.Enabled {
+		return fmt.Errorf("reorg: snapshots must be enabled to watch reference blocks")
+	}
+	if err := c.Recovery.Validate(); err != nil {
+		return fmt.Errorf("recovery: %w", err)
+	}
+	if c.Recovery.Enabled && !c.Transactions.Enabled {
+		return fmt.Errorf("recovery: transactions must be enabled to submit mints")
+	}
+	if err := c.TaskListener.Validate(); err != nil {
+		return fmt.Errorf("taskListener: %w", err)
+	}
+	if c.TaskListener.Enabled && !c.Cache.Enabled {
+		return fmt.Errorf("taskListener: the cache must be enabled to keep warmed results")
+	}
+	if err := c.Operator.Validate(); err != nil {
+		return fmt.Errorf("operator: %w", err)
+	}
+	if err := c.Quotas.Validate(); err != nil {
+		return fmt.Errorf("quotas: %w", err)
+	}
+	if c.Quotas.Enabled && c.Quotas.Backend == quota.BackendRedis && !c.Redis.Enabled {
+		return fmt.Errorf("quotas: the redis backend requires redis to be enabled")
+	}
+	if lease := c.Redis.ExecutionLease; c.Redis.Enabled && lease > 0 {
+		if lease <= c.Transactions.ConfirmationTimeout || (c.GasWindows.Enabled && lease <= c.GasWindows.MaxDelay) {
+			return fmt.Errorf("redis: executionLease must outlast transactions.confirmationTimeout and gasWindows.maxDelay")
+		}
+	}
+	if err := c.TaskQueue.Validate(); err != nil {
+		return fmt.Errorf("taskQueue: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/najnomics/crosscow-avs/pkg/store"
+)
+
+func writeConfig(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "performer.yaml")
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	return path
+}
+
+func Test_Load(t *testing.T) {
+	path := writeConfig(t, `
+grpcPort: 9000
+timeout: 10s
+storage:
+  type: badger
+  badger:
+    dir: /var/lib/yield-avs
+health:
+  enabled: true
+  port: 9001
+chains:
+  - chainId: 1
+    name: ethereum
+    rpcUrl: http://localhost:8545
+`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.GrpcPort != 9000 || cfg.Timeout != 10*time.Second {
+		t.Errorf("Unexpected server settings: %+v", cfg)
+	}
+	if cfg.Storage.Type != store.StorageTypeBadger || cfg.Storage.Badger.Dir != "/var/lib/yield-avs" {
+		t.Errorf("Unexpected storage settings: %+v", cfg.Storage)
+	}
+	if len(cfg.Chains) != 1 || cfg.Chains[0].Name != "ethereum" {
+		t.Errorf("Unexpected chains: %+v", cfg.Chains)
+	}
+	if cfg.Health.CheckTimeout != 3*time.Second {
+		t.Errorf("Expected default health check timeout to be kept, got %s", cfg.Health.CheckTimeout)
+	}
+}
+
+func Test_LoadRejectsInvalidValues(t *testing.T) {
+	testCases := map[string]string{
+		"badger without dir":  "storage:\n  type: badger\n",
+		"unknown storage":     "storage:\n  type: sqlite\n",
+		"shutdown timeout":    "shutdownTimeout: 0s\n",
+		"log format":          "logging:\n  format: text\n",
+		"tracing endpoint":    "tracing:\n  enabled: true\n  endpoint: localhost:4318\n",
+		"fixtures path":       "fixtures:\n  mode: replay\n",
+		"health on grpc port": "grpcPort: 9000\nhealth:\n  enabled: true\n  port: 9000\n",
+		"admin on health":     "health:\n  port: 9000\nadmin:\n  enabled: true\n  port: 9000\n",
+		"exposed admin":       "admin:\n  enabled: true\n  host: 0.0.0.0\n",
+		"exposed task api":    "taskApi:\n  enabled: true\n  host: 0.0.0.0\n",
+		"task api on admin":   "admin:\n  enabled: true\n  port: 9000\ntaskApi:\n  enabled: true\n  port: 9000\n",
+		"reload debounce":     "reload:\n  watch: true\n  debounce: 0s\n",
+		"duplicate chain":     "chains:\n  - {chainId: 1, rpcUrl: a}\n  - {chainId: 1, rpcUrl: b}\n",
+		"chain without rpc":   "chains:\n  - {chainId: 1}\n",
+		"websocket scheme":    "chains:\n  - {chainId: 1, rpcUrl: a, wsUrl: https://a}\n",
+		"quorum endpoint":     "chains:\n  - {chainId: 1, rpcUrl: a, quorumRpcUrls: [a]}\n",
+		"crossval quorum":     "crossValidation:\n  quorum: 0.5\n",
+		"crossval weight":     "crossValidation:\n  weights: {subgraph: -1}\n",
+		"indexer refresh":     "indexer:\n  pollInterval: 1m\n  refreshInterval: 30s\n",
+		"collector retention": "collector:\n  retention: 1h\n  compactAfter: 2h\n",
+		"stability samples":   "stability:\n  minSamples: 1\n",
+		"hysteresis cooldown": "hysteresis:\n  enabled: true\n  cooldown: -1h\n",
+		"sequencer head age":  "sequencers:\n  maxHeadAge: 0s\n",
+		"vault address":       "vaults:\n  vaults:\n    - {protocol: steakhouse_usdc, addresses: {1: steakhouse}}\n",
+		"lp pool kind":        "liquidityPools:\n  pools:\n    - {protocol: curve_3pool, kind: balancer, deployments: {1: {pool: a, subgraph: b}}}\n",
+		"pendle market":       "pendle:\n  markets:\n    - {protocol: pendle_pt_ausdc, addresses: {1: market}}\n",
+		"rwa transferability": "rwa:\n  tokens:\n    - {protocol: ondo_usdy, issuer: Ondo, transferability: free, deployments: {1: {token: '0x96F6eF951840721AdBF46Ac996b59E0235CB985C', feed: '0x96F6eF951840721AdBF46Ac996b59E0235CB985C'}}}\n",
+		"incentive haircut":   "incentives:\n  haircuts:\n    aave_v3: 2\n",
+		"position max age":    "positions:\n  maxAge: 0s\n",
+		"ledger price feed":   "ledger:\n  nativePriceFeeds: {1: feed}\n",
+		"subgraph protocol":   "subgraphs:\n  - {protocol: euler, chainId: 1, url: a, market: b}\n",
+		"retry attempts":      "resilience:\n  overrides:\n    aave_v3: {maxAttempts: 0}\n",
+		"multicall size":      "multicall: {enabled: true, window: 2ms, maxCalls: 1}\n",
+		"snapshot freshness":  "marketSnapshots: {enabled: true, freshness: 0s}\n",
+		"cache backend":       "cache:\n  backend: memcached\n",
+		"result limit":        "results:\n  limits: {full_market_snapshot: 0}\n",
+		"tenderly account":    "simulation:\n  tenderly: {enabled: true, accessKey: k}\n",
+		"keystore password":   "transactions:\n  enabled: true\n  signer: {type: keystore, keystore: {path: key.json}}\n",
+		"fee bump":            "transactions:\n  enabled: true\n  bumpPercent: 5\n",
+		"chain signer":        "transactions:\n  enabled: true\n  chainSigners:\n    8453: {type: aws_kms}\n",
+		"confirmations":       "transactions:\n  enabled: true\n  chainConfirmations:\n    1: 0\n",
+		"slippage":            "transactions:\n  enabled: true\n  chainProtection:\n    1: {maxSlippageBps: 20000}\n",
+		"unsigned feed":       "security:\n  enabled: true\n  url: https://feed.example\n",
+		"task signers":        "authorization:\n  enabled: true\n  signers: [service-manager]\n",
+		"attestation signer":  "attestation:\n  enabled: true\n  signer: {type: remote}\n",
+		"rate places":         "snapshots:\n  enabled: true\n  ratePlaces: 6\n",
+		"fallback bridge":     "bridges:\n  fallback:\n    enabled: true\n    bridge: stargate\n",
+		"stablecoin":          "stablecoins:\n  enabled: true\n  symbols: [FRAX]\n",
+		"policy utilization":  "policy:\n  maxUtilization: 95\n",
+		"kill switch address": "killSwitch:\n  enabled: true\n  chainId: 1\n  serviceManager: manager\n",
+		"task mailbox":        "reputation:\n  enabled: true\n  chainId: 1\n  taskMailbox: mailbox\n",
+		"gas window delay":    "gasWindows:\n  enabled: true\n  maxDelay: 0s\n",
+		"flash loan helper":   "flashLoans:\n  enabled: true\n  helpers: {1: helper}\n",
+		"permit validity":     "permits:\n  enabled: true\n  validity: 0s\n",
+		"bundler":             "userOperations:\n  enabled: true\n",
+		"operation signer":    "userOperations:\n  enabled: true\n  bundlers: {1: https://bundler.example.com}\n",
+		"webhook secret":      "notifications:\n  enabled: true\n  webhooks:\n  - {url: https://hooks.example.com/avs}\n",
+		"alert severity":      "notifications:\n  enabled: true\n  slack: {enabled: true, minSeverity: page}\n",
+		"opportunity signer":  "opportunities:\n  enabled: true\n",
+		"opportunity mailbox": "opportunities:\n  enabled: true\n  mailbox: {enabled: true, chainId: 1, taskMailbox: mailbox}\n",
+		"submission":          "submission:\n  enabled: true\n  chainId: 1\n  serviceManager: 0x0000000000000000000000000000000000005e41\n",
+		"evidence tolerance":  "evidence:\n  enabled: true\n  chainId: 1\n  serviceManager: 0x0000000000000000000000000000000000005e41\n  toleranceBps: 0\n",
+		"reorg depth":         "snapshots:\n  enabled: true\nreorg:\n  enabled: true\n  confirmations: 64\n  depth: 64\n",
+		"reorg snapshots":     "reorg:\n  enabled: true\n",
+		"recovery sla":        "recovery:\n  sla: 0s\n",
+		"recovery account":    "recovery:\n  enabled: true\n",
+		"task listener":       "taskListener:\n  enabled: true\n  chainId: 1\n  taskMailbox: mailbox\n",
+		"operator":            "operator:\n  keyRegistrar: registrar\n",
+		"standing":            "operator:\n  standing:\n    enabled: true\n",
+		"unknown network":     "network: devnet\n",
+		"network chain":       "network: testnet\nchains:\n  - {chainId: 1, rpcUrl: a}\n",
+		"address book":        "addressBook:\n  - {chainId: 1, protocol: usdc, contract: token, address: 0x00000000000000000000000000000000000000aa}\n",
+		"dependencies":        "dependencies:\n  - {chainId: 1, protocol: aave_v3, dependsOn: [chainlink]}\n",
+		"bridge route":        "bridges:\n  routes:\n  - {bridge: cctp_v2_fast, sourceChainId: 1, destinationChainId: 10, latency: 1m, trust: issuer, token: native}\n",
+		"task queue overflow": "taskQueue:\n  types:\n    rebalance_execution: {maxConcurrent: 1, overflow: drop}\n",
+		"task queue running":  "taskQueue:\n  maxRunning: -1\n",
+		"redis address":       "redis:\n  enabled: true\n",
+		"redis namespace":     "redis:\n  enabled: true\n  addr: localhost:6379\n  namespace: a:b\n",
+		"redis cache":         "cache:\n  backend: redis\n",
+		"redis quotas":        "quotas:\n  backend: redis\n",
+		"redis lease":         "redis:\n  enabled: true\n  addr: localhost:6379\n  executionLease: 1m\n",
+		"grpc tls":            "grpc:\n  tls:\n    enabled: true\n",
+		"secrets provider":    "secrets:\n  provider: keychain\n",
+		"plaintext key":       "transactions:\n  signer:\n    type: secret\n    privateKey: abc\n",
+		"plaintext password":  "attestation:\n  signer:\n    type: keystore\n    keystore:\n      path: key.json\n      password: hunter2\n",
+	}
+
+	for name, contents := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Load(writeConfig(t, contents)); err == nil {
+				t.Errorf("Expected config to be rejected")
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+// Package crossval checks supply rates read from chain against independent sources, so a
+// single bad RPC endpoint or indexer cannot push a wrong rate into a task result. Sources
+// weigh in a quorum by their configured stake and the reliability they earned over time;
+// the weighted median of their rates is the rate reported, and sources too far from it are
+// flagged as outliers.
+package crossval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Status is the outcome of a cross validation
+type Status string
+
+const (
+	// StatusAgreed means enough sources reported rates within the tolerance
+	StatusAgreed Status = "agreed"
+	// StatusDisputed means sources disagreed or too few of them answered
+	StatusDisputed Status = "disputed"
+)
+
+// ErrNotCovered is returned by sources that do not cover a market. Their readings are left
+// out rather than counted as failed.
+var ErrNotCovered = errors.New("market is not covered by the source")
+
+// Source reports the current supply rate of a market, as an annual fraction (0.05 = 5%)
+type Source interface {
+	// Name identifies the source in results and logs
+	Name() string
+	SupplyRate(ctx context.Context, protocol string, chainID uint64) (float64, error)
+}
+
+// Config sets how closely sources must agree
+type Config struct {
+	Enabled bool `yaml:"enabled"`
+	// ToleranceBps is the largest distance from the median rate, in basis points of rate,
+	// that still counts as agreement
+	ToleranceBps float64 `yaml:"toleranceBps"`
+	// MinSources is the number of sources, including the contract read, that must agree
+	MinSources int `yaml:"minSources"`
+
+	// Weights are the stake of sources in the quorum by name, such as contract, defillama,
+	// subgraph or rpc:quorum-1. Sources not listed weigh 1, and sources weighing 0 are
+	// compared with the quorum without being part of it.
+	Weights map[string]float64 `yaml:"weights"`
+	// Quorum is the share of the weight of the answering sources that must agree with the
+	// median rate, over one half
+	Quorum float64 `yaml:"quorum"`
+
+	Reliability ReliabilityConfig `yaml:"reliability"`
+
+	DefiLlama DefiLlamaConfig `yaml:"defiLlama"`
+}
+
+// DefaultConfig requires the contract read and one independent source to agree within
+// 25 bps, and two thirds of the weight of the sources that answered to agree
+func DefaultConfig() Config {
+	return Config{
+		Enabled:      true,
+		ToleranceBps: 25,
+		MinSources:   2,
+		Quorum:       0.66,
+		Reliability:  DefaultReliabilityConfig(),
+		DefiLlama:    DefaultDefiLlamaConfig(),
+	}
+}
+
+// Validate checks the config for values cross validation cannot run with
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.ToleranceBps <= 0 {
+		return fmt.Errorf("toleranceBps must be positive")
+	}
+	if c.MinSources < 2 {
+		return fmt.Errorf("minSources must be at least 2")
+	}
+	for source, weight := range c.Weights {
+		if weight < 0 || math.IsNaN(weight) || math.IsInf(weight, 0) {
+			return fmt.Errorf("weights.%s must be a non-negative number", source)
+		}
+	}
+	if c.Quorum <= 0.5 || c.Quorum > 1 {
+		return fmt.Errorf("quorum must be over 0.5 and at most 1")
+	}
+	if err := c.Reliability.Validate(); err != nil {
+		return fmt.Errorf("reliability: %w", err)
+	}
+	return c.DefiLlama.Validate()
+}
+
+// weight returns the configured stake of source
+func (c Config) weight(source string) float64 {
+	if weight, ok := c.Weights[source]; ok {
+		return weight
+	}
+	return 1
+}
+
+// Reading is the rate a single source reported, or the error it failed with
+type Reading struct {
+	Source string
+	Rate   float64
+	Err    error
+
+	// Weight is the weight of the reading in the quorum, its stake scaled by the
+	// reliability of its source. Readings that failed or weigh 0 are not part of it.
+	Weight float64
+	// Outlier is set when the rate is further than the tolerance from the median
+	Outlier bool
+}
+
+// Report is the outcome of comparing readings
+type Report struct {
+	Status Status
+	// Readings are ordered by source name
+	Readings []Reading
+	// Agreeing is the number of readings in the quorum within the tolerance of the median
+	Agreeing int
+	// Median is the weighted median rate of the readings in the quorum
+	Median float64
+	// Outliers are the sources whose rate is further than the tolerance from the median
+	Outliers []string
+	// SpreadBps is the difference between the highest and lowest rate in basis points
+	SpreadBps float64
+	// Reason explains a dispute
+	Reason string
+}
+
+// Collect reads the supply rate of a market from every source covering it
+func Collect(ctx context.Context, sources []Source, protocol string, chainID uint64) []Reading {
+	readings := make([]Reading, 0, len(sources))
+	for _, s := range sources {
+		rate, err := s.SupplyRate(ctx, protocol, chainID)
+		if errors.Is(err, ErrNotCovered) {
+			continue
+		}
+		readings = append(readings, Reading{Source: s.Name(), Rate: rate, Err: err})
+	}
+	return readings
+}
+
+// Evaluate compares readings, weighing each source by its configured stake. Failed
+// readings do not count towards MinSources.
+func Evaluate(readings []Reading, cfg Config) *Report {
+	return evaluate(readings, cfg, func(string) float64 { return 1 })
+}
+
+// evaluate compares readings, weighing each source by its configured stake scaled by its
+// reliability
+func evaluate(readings []Reading, cfg Config, reliability func(source string) float64) *Report {
+	sorted := append([]Reading(nil), readings...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Source < sorted[j].Source })
+	report := &Report{Status: StatusDisputed, Readings: sorted}
+
+	answered := 0
+	var quorum []*Reading
+	low, high := math.Inf(1), math.Inf(-1)
+	for i := range sorted {
+		r := &sorted[i]
+		if r.Err != nil || math.IsNaN(r.Rate) || math.IsInf(r.Rate, 0) {
+			continue
+		}
+		answered++
+		low = math.Min(low, r.Rate)
+		high = math.Max(high, r.Rate)
+		if r.Weight = cfg.weight(r.Source) * reliability(r.Source); r.Weight > 0 {
+			quorum = append(quorum, r)
+		}
+	}
+	if answered < cfg.MinSources {
+		report.Reason = fmt.Sprintf("%d of %d required sources answered", answered, cfg.MinSources)
+		return report
+	}
+	report.SpreadBps = (high - low) * 10000
+	if len(quorum) < cfg.MinSources {
+		report.Reason = fmt.Sprintf("%d of %d required sources weigh in the quorum, the rest are unreliable or weigh 0", len(quorum), cfg.MinSources)
+		return report
+	}
+
+	report.Median = weightedMedian(quorum)
+	total, agreeing := 0.0, 0.0
+	for i := range sorted {
+		r := &sorted[i]
+		if r.Err != nil || math.IsNaN(r.Rate) || math.IsInf(r.Rate, 0) {
+			continue
+		}
+		total += r.Weight
+		if math.Abs(r.Rate-report.Median)*10000 > cfg.ToleranceBps {
+			r.Outlier = true
+			report.Outliers = append(report.Outliers, r.Source)
+			continue
+		}
+		if r.Weight > 0 {
+			report.Agreeing++
+			agreeing += r.Weight
+		}
+	}
+	if report.Agreeing < cfg.MinSources {
+		report.Reason = fmt.Sprintf("%d of %d required sources agree within %.2f bps of the median rate, spread is %.2f bps",
+			report.Agreeing, cfg.MinSources, cfg.ToleranceBps, report.SpreadBps)
+		return report
+	}
+	if agreeing < cfg.Quorum*total {
+		report.Reason = fmt.Sprintf("sources agreeing with the median rate weigh %.0f%%, quorum is %.0f%%", 100*agreeing/total, 100*cfg.Quorum)
+		return report
+	}
+	report.Status = StatusAgreed
+	return report
+}
+
+// weightedMedian returns the lowest rate of readings at which the weight of the readings
+// paying no more reaches half of the total
+func weightedMedian(readings []*Reading) float64 {
+	sorted := append([]*Reading(nil), readings...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Rate < sorted[j].Rate })
+	total := 0.0
+	for _, r := range sorted {
+		total += r.Weight
+	}
+	cumulative := 0.0
+	for _, r := range sorted {
+		cumulative += r.Weight
+		if cumulative >= total/2 {
+			return r.Rate
+		}
+	}
+	return sorted[len(sorted)-1].Rate
+}
@@ -0,0 +1,215 @@
+package crossval
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func Test_Evaluate(t *testing.T) {
+	cfg := DefaultConfig()
+	testCases := []struct {
+		name     string
+		readings []Reading
+		status   Status
+	}{
+		{
+			name:     "agree",
+			readings: []Reading{{Source: "contract", Rate: 0.0384}, {Source: "defillama", Rate: 0.0386}},
+			status:   StatusAgreed,
+		},
+		{
+			name:     "disagree",
+			readings: []Reading{{Source: "contract", Rate: 0.0384}, {Source: "defillama", Rate: 0.0450}},
+			status:   StatusDisputed,
+		},
+		{
+			name:     "source failed",
+			readings: []Reading{{Source: "contract", Rate: 0.0384}, {Source: "defillama", Err: errors.New("timeout")}},
+			status:   StatusDisputed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			report := Evaluate(tc.readings, cfg)
+			if report.Status != tc.status {
+				t.Errorf("Expected %s, got %s (%s)", tc.status, report.Status, report.Reason)
+			}
+			if report.Status == StatusDisputed && report.Reason == "" {
+				t.Errorf("Expected a reason for the dispute")
+			}
+		})
+	}
+}
+
+func Test_EvaluateQuorum(t *testing.T) {
+	readings := []Reading{
+		{Source: "contract", Rate: 0.0500},
+		{Source: "rpc:quorum-1", Rate: 0.0384},
+		{Source: "defillama", Rate: 0.0386},
+	}
+	weighted := DefaultConfig()
+	weighted.Weights = map[string]float64{"contract": 3}
+	split := DefaultConfig()
+	split.Weights = map[string]float64{"contract": 0}
+
+	testCases := []struct {
+		name     string
+		cfg      Config
+		readings []Reading
+		status   Status
+		median   float64
+		outliers int
+	}{
+		// the sources reading 3.84% and 3.86% outvote the contract read
+		{name: "outvoted", cfg: DefaultConfig(), readings: readings, status: StatusAgreed, median: 0.0386, outliers: 1},
+		// staked three times as much, the contract read sets the median alone
+		{name: "staked", cfg: weighted, readings: readings, status: StatusDisputed, median: 0.0500, outliers: 2},
+		// a source weighing 0 is compared with the others without being part of the quorum
+		{name: "unweighted", cfg: split, readings: readings, status: StatusAgreed, median: 0.0384, outliers: 1},
+		{
+			name: "no quorum",
+			cfg:  DefaultConfig(),
+			readings: []Reading{
+				{Source: "a", Rate: 0.0384}, {Source: "b", Rate: 0.0385},
+				{Source: "c", Rate: 0.0600}, {Source: "d", Rate: 0.0700},
+			},
+			status:   StatusDisputed,
+			median:   0.0385,
+			outliers: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			report := Evaluate(tc.readings, tc.cfg)
+			if report.Status != tc.status {
+				t.Errorf("Expected %s, got %s (%s)", tc.status, report.Status, report.Reason)
+			}
+			if report.Median != tc.median || len(report.Outliers) != tc.outliers {
+				t.Errorf("Expected a median of %f and %d outliers, got %f and %v", tc.median, tc.outliers, report.Median, report.Outliers)
+			}
+		})
+	}
+}
+
+func Test_TrackerExcludesUnreliableSources(t *testing.T) {
+	cfg := DefaultConfig()
+	tracker := NewTracker()
+	stats := func(source string) SourceStats {
+		for _, s := range tracker.Stats() {
+			if s.Source == source {
+				return s
+			}
+		}
+		t.Fatalf("Expected stats of %s", source)
+		return SourceStats{}
+	}
+
+	// the subgraph lags the other sources until it weighs in no more
+	lagging := []Reading{
+		{Source: "contract", Rate: 0.0384},
+		{Source: "defillama", Rate: 0.0385},
+		{Source: "subgraph", Rate: 0.0300},
+	}
+	for i := 0; i < 7; i++ {
+		if report := tracker.Evaluate(lagging, cfg); report.Status != StatusAgreed {
+			t.Fatalf("Expected the other sources to agree, got %s (%s)", report.Status, report.Reason)
+		}
+	}
+	if s := stats("subgraph"); !s.Excluded || s.Outliers != 7 || s.LastOutlierAt == nil {
+		t.Errorf("Expected the lagging subgraph to be excluded, got %+v", s)
+	}
+	if s := stats("contract"); s.Excluded || s.Agreed != 7 || s.Score != 1 {
+		t.Errorf("Expected the contract read to stay reliable, got %+v", s)
+	}
+
+	// excluded, it no longer counts against the quorum the remaining sources form
+	report := tracker.Evaluate([]Reading{
+		{Source: "contract", Rate: 0.0384},
+		{Source: "defillama", Rate: 0.0386},
+		{Source: "subgraph", Rate: 0.0300},
+		{Source: "rpc:quorum-1", Err: errors.New("timeout")},
+	}, cfg)
+	for _, r := range report.Readings {
+		if r.Source == "subgraph" && (r.Weight != 0 || !r.Outlier) {
+			t.Errorf("Expected the excluded subgraph to be an outlier without weight, got %+v", r)
+		}
+	}
+	if s := stats("rpc:quorum-1"); s.Failures != 1 || s.Score >= 1 {
+		t.Errorf("Expected the failure to count against the endpoint, got %+v", s)
+	}
+
+	// agreeing again, it earns its say back
+	for i := 0; i < 10 && stats("subgraph").Excluded; i++ {
+		tracker.Evaluate([]Reading{{Source: "contract", Rate: 0.0384}, {Source: "defillama", Rate: 0.0385}, {Source: "subgraph", Rate: 0.0385}}, cfg)
+	}
+	if s := stats("subgraph"); s.Excluded {
+		t.Errorf("Expected the subgraph to be included once it agrees again, got %+v", s)
+	}
+}
+
+type fixedSource struct {
+	name string
+	err  error
+}
+
+func (s fixedSource) Name() string { return s.name }
+
+func (s fixedSource) SupplyRate(ctx context.Context, protocol string, chainID uint64) (float64, error) {
+	return 0.0384, s.err
+}
+
+func Test_CollectSkipsUncoveredMarkets(t *testing.T) {
+	readings := Collect(context.Background(), []Source{
+		fixedSource{name: "defillama"},
+		fixedSource{name: "rpc:quorum-1", err: ErrNotCovered},
+		fixedSource{name: "subgraph", err: errors.New("timeout")},
+	}, "aave_v3", 1)
+	if len(readings) != 2 || readings[0].Source != "defillama" || readings[1].Err == nil {
+		t.Errorf("Expected the uncovered source to be left out and the failed one kept, got %+v", readings)
+	}
+}
+
+func Test_DefiLlamaSource(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/pools" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		requests.Add(1)
+		_, _ = w.Write([]byte(`{"status":"success","data":[
+			{"chain":"Ethereum","project":"aave-v3","symbol":"WETH","apyBase":1.9},
+			{"chain":"Ethereum","project":"aave-v3","symbol":"USDC","apyBase":3.9147},
+			{"chain":"Base","project":"compound-v3","symbol":"USDC","apyBase":null}
+		]}`))
+	}))
+	defer server.Close()
+
+	cfg := DefaultDefiLlamaConfig()
+	cfg.Url = server.URL
+	source := NewDefiLlamaSource(cfg, nil)
+
+	rate, err := source.SupplyRate(context.Background(), "aave_v3", 1)
+	if err != nil {
+		t.Fatalf("SupplyRate failed: %v", err)
+	}
+	if math.Abs(rate-0.0384) > 1e-4 {
+		t.Errorf("Expected the APY to convert to a 3.84%% rate, got %f", rate)
+	}
+	if _, err := source.SupplyRate(context.Background(), "compound_v3", 8453); err == nil {
+		t.Errorf("Expected pools without a base APY to be skipped")
+	}
+	if _, err := source.SupplyRate(context.Background(), "aave_v3", 10); err == nil {
+		t.Errorf("Expected an unknown chain to fail")
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("Expected the pool list to be cached, got %d requests", got)
+	}
+}
@@ -0,0 +1,175 @@
+package crossval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/najnomics/crosscow-avs/pkg/resilience"
+)
+
+// DefaultDefiLlamaUrl is the DefiLlama yields API
+const DefaultDefiLlamaUrl = "https://yields.llama.fi"
+
+// DefiLlamaConfig configures the DefiLlama yields API source
+type DefiLlamaConfig struct {
+	Enabled bool          `yaml:"enabled"`
+	Url     string        `yaml:"url"`
+	Timeout time.Duration `yaml:"timeout"`
+	// CacheTTL is how long the pool list is reused. DefiLlama refreshes it roughly hourly.
+	CacheTTL time.Duration `yaml:"cacheTTL"`
+}
+
+// DefaultDefiLlamaConfig returns the public API settings
+func DefaultDefiLlamaConfig() DefiLlamaConfig {
+	return DefiLlamaConfig{
+		Enabled:  true,
+		Url:      DefaultDefiLlamaUrl,
+		Timeout:  10 * time.Second,
+		CacheTTL: 5 * time.Minute,
+	}
+}
+
+// Validate checks the config for values the source cannot run with
+func (c DefiLlamaConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Url == "" {
+		return fmt.Errorf("defiLlama.url is required")
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("defiLlama.timeout must be positive")
+	}
+	return nil
+}
+
+// defiLlamaProjects maps task protocol names to DefiLlama project slugs
+var defiLlamaProjects = map[string]string{
+	"aave_v3":     "aave-v3",
+	"compound_v3": "compound-v3",
+}
+
+// defiLlamaChains maps chain ids to DefiLlama chain names
+var defiLlamaChains = map[uint64]string{
+	1:     "Ethereum",
+	8453:  "Base",
+	42161: "Arbitrum",
+}
+
+type defiLlamaPool struct {
+	Chain   string   `json:"chain"`
+	Project string   `json:"project"`
+	Symbol  string   `json:"symbol"`
+	ApyBase *float64 `json:"apyBase"`
+}
+
+// DefiLlamaSource reads base supply APYs from the DefiLlama yields API, an indexer that
+// is independent of the performer's RPC endpoints
+type DefiLlamaSource struct {
+	url        string
+	ttl        time.Duration
+	httpClient *http.Client
+	policy     *resilience.Policy
+
+	mu        sync.Mutex
+	pools     []defiLlamaPool
+	fetchedAt time.Time
+}
+
+// NewDefiLlamaSource creates a DefiLlama source. Missing values fall back to defaults.
+// Transient failures are retried under policy, which may be nil.
+func NewDefiLlamaSource(cfg DefiLlamaConfig, policy *resilience.Policy) *DefiLlamaSource {
+	defaults := DefaultDefiLlamaConfig()
+	if cfg.Url == "" {
+		cfg.Url = defaults.Url
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaults.Timeout
+	}
+	return &DefiLlamaSource{
+		url:        strings.TrimRight(cfg.Url, "/"),
+		ttl:        cfg.CacheTTL,
+		httpClient: &http.Client{Timeout: cfg.Timeout},
+		policy:     policy,
+	}
+}
+
+func (s *DefiLlamaSource) Name() string {
+	return "defillama"
+}
+
+// SupplyRate returns the USDC base APY of the market, converted to a continuously
+// compounded annual rate so it compares with rates read from chain
+func (s *DefiLlamaSource) SupplyRate(ctx context.Context, protocol string, chainID uint64) (float64, error) {
+	project, ok := defiLlamaProjects[strings.ToLower(protocol)]
+	if !ok {
+		return 0, fmt.Errorf("defillama: unknown protocol %s", protocol)
+	}
+	chainName, ok := defiLlamaChains[chainID]
+	if !ok {
+		return 0, fmt.Errorf("defillama: unknown chain %d", chainID)
+	}
+
+	pools, err := s.fetchPools(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, p := range pools {
+		if p.Project == project && p.Chain == chainName && p.Symbol == "USDC" && p.ApyBase != nil {
+			return math.Log1p(*p.ApyBase / 100), nil
+		}
+	}
+	return 0, fmt.Errorf("defillama: no USDC pool for %s on %s", project, chainName)
+}
+
+func (s *DefiLlamaSource) fetchPools(ctx context.Context) ([]defiLlamaPool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pools != nil && time.Since(s.fetchedAt) < s.ttl {
+		return s.pools, nil
+	}
+
+	var pools []defiLlamaPool
+	err := s.policy.Do(ctx, s.url, func(ctx context.Context) error {
+		var err error
+		pools, err = s.requestPools(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.pools = pools
+	s.fetchedAt = time.Now()
+	return s.pools, nil
+}
+
+func (s *DefiLlamaSource) requestPools(ctx context.Context) ([]defiLlamaPool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url+"/pools", nil)
+	if err != nil {
+		return nil, fmt.Errorf("defillama: failed to build request: %w", err)
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("defillama: api unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("defillama: %w", &resilience.StatusError{Endpoint: s.url + "/pools", StatusCode: resp.StatusCode})
+	}
+
+	var body struct {
+		Status string          `json:"status"`
+		Data   []defiLlamaPool `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("defillama: failed to decode pools: %w", err)
+	}
+	return body.Data, nil
+}
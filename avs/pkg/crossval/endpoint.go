@@ -0,0 +1,45 @@
+package crossval
+
+import (
+	"context"
+
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+)
+
+// EndpointSource reads supply rates from the protocol contracts like the performer does,
+// through a quorum endpoint of the chain instead of its rpc endpoint, so a node serving
+// stale or wrong state is outvoted by other providers
+type EndpointSource struct {
+	endpoint string
+	adapters *adapters.Registry
+	chains   *chain.Manager
+}
+
+// NewEndpointSource creates a source reading markets through the named quorum endpoint,
+// as chain.QuorumEndpoint names it
+func NewEndpointSource(endpoint string, registry *adapters.Registry, chains *chain.Manager) *EndpointSource {
+	return &EndpointSource{endpoint: endpoint, adapters: registry, chains: chains}
+}
+
+// Name is rpc: followed by the endpoint name, such as rpc:quorum-1
+func (s *EndpointSource) Name() string {
+	return "rpc:" + s.endpoint
+}
+
+// SupplyRate reads the market of protocol on chainID through the endpoint. Chains without
+// the endpoint are not covered.
+func (s *EndpointSource) SupplyRate(ctx context.Context, protocol string, chainID uint64) (float64, error) {
+	if !s.chains.HasEndpoint(chainID, s.endpoint) {
+		return 0, ErrNotCovered
+	}
+	adapter, err := s.adapters.Get(protocol)
+	if err != nil {
+		return 0, err
+	}
+	state, err := adapter.MarketState(chain.WithEndpoint(ctx, s.endpoint), chainID)
+	if err != nil {
+		return 0, err
+	}
+	return state.Pool.SupplyRate(), nil
+}
@@ -0,0 +1,136 @@
+package crossval
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ReliabilityConfig sets how sources earn reliability, and when they lose their say in
+// quorums
+type ReliabilityConfig struct {
+	// Decay is the weight of the latest outcome in the reliability score of a source.
+	// Scores move towards 1 each time a source agrees with the median, and towards 0 each
+	// time it fails or is an outlier.
+	Decay float64 `yaml:"decay"`
+
+	// MinScore is the score below which a source no longer weighs in quorums. It is still
+	// read and compared with the median, so it earns its say back once it agrees again.
+	MinScore float64 `yaml:"minScore"`
+}
+
+// DefaultReliabilityConfig takes a source out of quorums after about seven failed or
+// outlying readings in a row
+func DefaultReliabilityConfig() ReliabilityConfig {
+	return ReliabilityConfig{Decay: 0.1, MinScore: 0.5}
+}
+
+// Validate checks the decay and minimum score are fractions
+func (c ReliabilityConfig) Validate() error {
+	if c.Decay <= 0 || c.Decay > 1 {
+		return fmt.Errorf("decay must be over 0 and at most 1")
+	}
+	if c.MinScore < 0 || c.MinScore >= 1 {
+		return fmt.Errorf("minScore must be at least 0 and under 1")
+	}
+	return nil
+}
+
+// SourceStats are the outcomes of the readings of a source since the performer started
+type SourceStats struct {
+	Source   string `json:"source"`
+	Readings int    `json:"readings"`
+	Agreed   int    `json:"agreed"`
+	Outliers int    `json:"outliers"`
+	Failures int    `json:"failures"`
+
+	// Score is the reliability of the source, from 0 to 1
+	Score float64 `json:"score"`
+	// Excluded is set while the score is below the minimum, and the source does not weigh
+	// in quorums
+	Excluded bool `json:"excluded"`
+
+	LastOutlierAt *time.Time `json:"lastOutlierAt,omitempty"`
+	LastFailureAt *time.Time `json:"lastFailureAt,omitempty"`
+}
+
+// Tracker scores the reliability of sources across evaluations. Sources start out fully
+// reliable.
+type Tracker struct {
+	mu    sync.Mutex
+	stats map[string]*SourceStats
+	now   func() time.Time
+}
+
+// NewTracker creates a tracker without history
+func NewTracker() *Tracker {
+	return &Tracker{stats: make(map[string]*SourceStats), now: time.Now}
+}
+
+// Evaluate compares readings like the package Evaluate, scaling the stake of each source
+// by its reliability, and scores the sources by the outcome. A nil tracker compares them
+// by stake alone.
+func (t *Tracker) Evaluate(readings []Reading, cfg Config) *Report {
+	if t == nil {
+		return Evaluate(readings, cfg)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	report := evaluate(readings, cfg, func(source string) float64 {
+		if stats, ok := t.stats[source]; ok && stats.Excluded {
+			return 0
+		}
+		return 1
+	})
+	t.recordLocked(report, cfg.Reliability)
+	return report
+}
+
+// recordLocked scores the sources of report. Failures always count against a source;
+// rates only count once enough sources answered to take their median.
+func (t *Tracker) recordLocked(report *Report, cfg ReliabilityConfig) {
+	// a median was taken when at least the reading it came from agrees with it
+	judged := report.Agreeing > 0
+	now := t.now()
+	for _, r := range report.Readings {
+		if r.Err == nil && !judged {
+			continue
+		}
+		stats, ok := t.stats[r.Source]
+		if !ok {
+			stats = &SourceStats{Source: r.Source, Score: 1}
+			t.stats[r.Source] = stats
+		}
+		stats.Readings++
+		outcome := 0.0
+		switch {
+		case r.Err != nil:
+			stats.Failures++
+			stats.LastFailureAt = &now
+		case r.Outlier:
+			stats.Outliers++
+			stats.LastOutlierAt = &now
+		default:
+			stats.Agreed++
+			outcome = 1
+		}
+		stats.Score += cfg.Decay * (outcome - stats.Score)
+		stats.Excluded = stats.Score < cfg.MinScore
+	}
+}
+
+// Stats returns the reliability of every source read so far, ordered by name
+func (t *Tracker) Stats() []SourceStats {
+	if t == nil {
+		return []SourceStats{}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make([]SourceStats, 0, len(t.stats))
+	for _, stats := range t.stats {
+		out = append(out, *stats)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Source < out[j].Source })
+	return out
+}
@@ -0,0 +1,191 @@
+// Package depeg turns USDC price observations into a depeg severity and a recommended
+// action for the rebalancer.
+package depeg
+
+import (
+	"errors"
+	"math/big"
+	"sort"
+
+	"github.com/najnomics/crosscow-avs/pkg/pricefeed"
+)
+
+// NativeChainID is the chain USDC is natively issued and redeemed on. When a depeg is
+// confined to other chains, funds should exit to it.
+const NativeChainID uint64 = 1
+
+// ErrNoFreshQuotes is returned when every price source failed or is stale
+var ErrNoFreshQuotes = errors.New("no fresh price quotes")
+
+// Severity grades a price deviation
+type Severity string
+
+const (
+	SeverityNone     Severity = "none"
+	SeverityWarning  Severity = "warning"
+	SeverityHigh     Severity = "high"
+	SeverityCritical Severity = "critical"
+)
+
+var severityRank = map[Severity]int{
+	SeverityNone:     0,
+	SeverityWarning:  1,
+	SeverityHigh:     2,
+	SeverityCritical: 3,
+}
+
+// AtLeast reports whether s is as severe as other
+func (s Severity) AtLeast(other Severity) bool {
+	return severityRank[s] >= severityRank[other]
+}
+
+// Action is the recommended response to a depeg
+type Action string
+
+const (
+	ActionNone              Action = "none"
+	ActionMonitor           Action = "monitor"
+	ActionHaltRebalancing   Action = "halt_rebalancing"
+	ActionExitToNativeChain Action = "exit_to_native_chain"
+)
+
+// Thresholds are the absolute deviations from $1, in basis points, at which each severity starts
+type Thresholds struct {
+	WarningBps  int64
+	HighBps     int64
+	CriticalBps int64
+}
+
+// DefaultThresholds flag 0.25% as a warning, 1% as high and 3% as critical
+var DefaultThresholds = Thresholds{
+	WarningBps:  25,
+	HighBps:     100,
+	CriticalBps: 300,
+}
+
+// Classify returns the severity of a deviation in basis points
+func (t Thresholds) Classify(deviationBps *big.Rat) Severity {
+	switch {
+	case deviationBps.Cmp(big.NewRat(t.CriticalBps, 1)) >= 0:
+		return SeverityCritical
+	case deviationBps.Cmp(big.NewRat(t.HighBps, 1)) >= 0:
+		return SeverityHigh
+	case deviationBps.Cmp(big.NewRat(t.WarningBps, 1)) >= 0:
+		return SeverityWarning
+	default:
+		return SeverityNone
+	}
+}
+
+// Score maps a deviation onto 0-100, reaching 100 at the critical threshold
+func (t Thresholds) Score(deviationBps *big.Rat) *big.Rat {
+	score := new(big.Rat).Mul(deviationBps, big.NewRat(100, t.CriticalBps))
+	if score.Cmp(big.NewRat(100, 1)) > 0 {
+		return big.NewRat(100, 1)
+	}
+	return score
+}
+
+// ChainAssessment is the depeg state on a single chain
+type ChainAssessment struct {
+	ChainID      uint64
+	Price        *big.Rat
+	DeviationBps *big.Rat
+	Severity     Severity
+}
+
+// Assessment is the overall depeg state
+type Assessment struct {
+	// Price is the median of all fresh quotes
+	Price        *big.Rat
+	DeviationBps *big.Rat
+	Severity     Severity
+	Score        *big.Rat
+	Action       Action
+	// Chains holds a per chain assessment, ordered by chain id
+	Chains []ChainAssessment
+}
+
+// DeviationBps returns |price - 1| in basis points
+func DeviationBps(price *big.Rat) *big.Rat {
+	deviation := new(big.Rat).Sub(price, big.NewRat(1, 1))
+	deviation.Abs(deviation)
+	return deviation.Mul(deviation, big.NewRat(10000, 1))
+}
+
+// Assess grades quotes, ignoring stale ones
+func Assess(quotes []*pricefeed.Quote, thresholds Thresholds) (*Assessment, error) {
+	var all []*big.Rat
+	byChain := make(map[uint64][]*big.Rat)
+	for _, q := range quotes {
+		if q == nil || q.Stale {
+			continue
+		}
+		all = append(all, q.Price)
+		byChain[q.ChainID] = append(byChain[q.ChainID], q.Price)
+	}
+	if len(all) == 0 {
+		return nil, ErrNoFreshQuotes
+	}
+
+	price := pricefeed.Median(all)
+	deviation := DeviationBps(price)
+	assessment := &Assessment{
+		Price:        price,
+		DeviationBps: deviation,
+		Severity:     thresholds.Classify(deviation),
+		Score:        thresholds.Score(deviation),
+	}
+
+	chainIDs := make([]uint64, 0, len(byChain))
+	for id := range byChain {
+		chainIDs = append(chainIDs, id)
+	}
+	sort.Slice(chainIDs, func(i, j int) bool { return chainIDs[i] < chainIDs[j] })
+	for _, id := range chainIDs {
+		chainPrice := pricefeed.Median(byChain[id])
+		chainDeviation := DeviationBps(chainPrice)
+		assessment.Chains = append(assessment.Chains, ChainAssessment{
+			ChainID:      id,
+			Price:        chainPrice,
+			DeviationBps: chainDeviation,
+			Severity:     thresholds.Classify(chainDeviation),
+		})
+	}
+
+	assessment.Action = recommend(assessment)
+	return assessment, nil
+}
+
+// recommend halts rebalancing on a broad depeg, exits to the native chain when the depeg
+// is confined to other chains, and asks for monitoring on warnings
+func recommend(a *Assessment) Action {
+	if a.Severity.AtLeast(SeverityHigh) {
+		return ActionHaltRebalancing
+	}
+
+	nativeHealthy := true
+	remoteDepeg := false
+	anyWarning := a.Severity.AtLeast(SeverityWarning)
+	for _, c := range a.Chains {
+		if c.Severity.AtLeast(SeverityWarning) {
+			anyWarning = true
+		}
+		if c.ChainID == NativeChainID {
+			nativeHealthy = !c.Severity.AtLeast(SeverityHigh)
+		} else if c.Severity.AtLeast(SeverityHigh) {
+			remoteDepeg = true
+		}
+	}
+
+	switch {
+	case remoteDepeg && nativeHealthy:
+		return ActionExitToNativeChain
+	case remoteDepeg:
+		return ActionHaltRebalancing
+	case anyWarning:
+		return ActionMonitor
+	default:
+		return ActionNone
+	}
+}
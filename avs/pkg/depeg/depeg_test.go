@@ -0,0 +1,86 @@
+package depeg
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/najnomics/crosscow-avs/pkg/pricefeed"
+)
+
+func quote(chainID uint64, price string) *pricefeed.Quote {
+	p, _ := new(big.Rat).SetString(price)
+	return &pricefeed.Quote{Source: "test", ChainID: chainID, Price: p}
+}
+
+func Test_Assess(t *testing.T) {
+	testCases := []struct {
+		name     string
+		quotes   []*pricefeed.Quote
+		severity Severity
+		action   Action
+	}{
+		{
+			name:     "pegged",
+			quotes:   []*pricefeed.Quote{quote(1, "1.0001"), quote(1, "0.9999"), quote(8453, "1.0000")},
+			severity: SeverityNone,
+			action:   ActionNone,
+		},
+		{
+			name:     "minor drift",
+			quotes:   []*pricefeed.Quote{quote(1, "0.9970"), quote(1, "0.9972"), quote(8453, "0.9975")},
+			severity: SeverityWarning,
+			action:   ActionMonitor,
+		},
+		{
+			name:     "broad depeg",
+			quotes:   []*pricefeed.Quote{quote(1, "0.95"), quote(1, "0.96"), quote(8453, "0.94")},
+			severity: SeverityCritical,
+			action:   ActionHaltRebalancing,
+		},
+		{
+			name:     "depeg confined to an l2",
+			quotes:   []*pricefeed.Quote{quote(1, "1.0000"), quote(1, "0.9999"), quote(42161, "0.97")},
+			severity: SeverityNone,
+			action:   ActionExitToNativeChain,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a, err := Assess(tc.quotes, DefaultThresholds)
+			if err != nil {
+				t.Fatalf("Assess failed: %v", err)
+			}
+			if a.Severity != tc.severity || a.Action != tc.action {
+				t.Errorf("Expected %s/%s, got %s/%s", tc.severity, tc.action, a.Severity, a.Action)
+			}
+		})
+	}
+}
+
+func Test_AssessIgnoresStaleQuotes(t *testing.T) {
+	stale := quote(1, "0.50")
+	stale.Stale = true
+
+	a, err := Assess([]*pricefeed.Quote{stale, quote(1, "1.0000")}, DefaultThresholds)
+	if err != nil {
+		t.Fatalf("Assess failed: %v", err)
+	}
+	if a.Severity != SeverityNone {
+		t.Errorf("Expected stale quote to be ignored, got %s", a.Severity)
+	}
+
+	if _, err := Assess([]*pricefeed.Quote{stale}, DefaultThresholds); !errors.Is(err, ErrNoFreshQuotes) {
+		t.Errorf("Expected ErrNoFreshQuotes, got %v", err)
+	}
+}
+
+func Test_Score(t *testing.T) {
+	if got := DefaultThresholds.Score(big.NewRat(150, 1)); got.Cmp(big.NewRat(50, 1)) != 0 {
+		t.Errorf("Expected 150 bps to score 50, got %s", got.FloatString(2))
+	}
+	if got := DefaultThresholds.Score(big.NewRat(1000, 1)); got.Cmp(big.NewRat(100, 1)) != 0 {
+		t.Errorf("Expected score to be capped at 100, got %s", got.FloatString(2))
+	}
+}
@@ -0,0 +1,196 @@
+package devnet
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/client"
+	"github.com/najnomics/crosscow-avs/pkg/resultdiff"
+)
+
+// rebalanceAmount is the USDC the lifecycle checks moving and dry-runs rebalancing, in
+// base units: 1,000 USDC
+const rebalanceAmount = "1000000000"
+
+// Step is a task of the lifecycle the mock aggregator sends
+type Step struct {
+	// Name is unique within the lifecycle and suffixes the task id of the step
+	Name    string
+	Payload *client.Payload
+}
+
+// namedTask is a task of the lifecycle before its payload is built
+type namedTask struct {
+	name string
+	task client.Task
+}
+
+// Lifecycle returns the tasks of a full task lifecycle on chainIDs, for user: the
+// capabilities of the performer, the rates, risk and ranking of every market, the move
+// of funds between the first two chains, a dry run rebalancing on the first chain, and
+// a batch of reads. Dry runs simulate every step of the rebalance against the forks
+// without sending a transaction.
+func Lifecycle(chainIDs []uint64, user common.Address) ([]Step, error) {
+	if len(chainIDs) == 0 {
+		return nil, fmt.Errorf("at least one chain is needed")
+	}
+	home := chainIDs[0]
+	tasks := []namedTask{
+		{"capabilities", client.Capabilities{}},
+		{"yield-monitoring", client.YieldMonitoring{Protocol: client.ProtocolAll, Token: "USDC"}},
+		{"yield-ranking", client.YieldRanking{Token: "USDC", ChainIDs: chainIDs}},
+	}
+	for _, chainID := range chainIDs {
+		tasks = append(tasks, namedTask{fmt.Sprintf("risk-assessment-%d", chainID), client.RiskAssessment{Protocol: adapters.ProtocolAaveV3, ChainID: chainID, AssessmentType: "full"}})
+	}
+	if len(chainIDs) > 1 {
+		tasks = append(tasks, namedTask{"cross-chain-yield-check", client.CrossChainYieldCheck{SourceChain: home, TargetChain: chainIDs[1], Amount: rebalanceAmount, UserAddress: user.Hex()}})
+	}
+	tasks = append(tasks, namedTask{"rebalance-dry-run", client.RebalanceExecution{UserAddress: user.Hex(), Amount: rebalanceAmount, TargetProtocol: adapters.ProtocolAaveV3, TargetChain: home, DryRun: true}})
+
+	steps := make([]Step, 0, len(tasks)+1)
+	for _, t := range tasks {
+		payload, err := client.Build(t.task)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", t.name, err)
+		}
+		steps = append(steps, Step{Name: t.name, Payload: payload})
+	}
+	reads := make([]*client.Payload, 0, 2)
+	for _, protocol := range []string{adapters.ProtocolAaveV3, adapters.ProtocolCompoundV3} {
+		payload, err := client.Build(client.YieldMonitoring{Protocol: protocol, ChainID: home, Token: "USDC"})
+		if err != nil {
+			return nil, fmt.Errorf("batch: %w", err)
+		}
+		reads = append(reads, payload)
+	}
+	batch, err := client.Build(client.Batch{Tasks: reads})
+	if err != nil {
+		return nil, fmt.Errorf("batch: %w", err)
+	}
+	return append(steps, Step{Name: "batch", Payload: batch}), nil
+}
+
+// Performer is a performer the mock aggregator sends tasks to, as to the executor of an
+// operator
+type Performer struct {
+	Name   string
+	Client *client.Client
+}
+
+// Answer is how a performer answered the task of a step
+type Answer struct {
+	Performer string        `json:"performer"`
+	Latency   time.Duration `json:"latency"`
+
+	// Error is the error the performer answered in place of a result, or the reason no
+	// answer came
+	Error string `json:"error,omitempty"`
+
+	raw []byte
+}
+
+// StepReport is how the performers answered the task of a step
+type StepReport struct {
+	Step    string   `json:"step"`
+	TaskID  string   `json:"taskId"`
+	Answers []Answer `json:"answers"`
+
+	// Differences are the fields the results of the performers differ on. They are only
+	// compared when more than one performer answered a result.
+	Differences []resultdiff.Difference `json:"differences,omitempty"`
+}
+
+// Failed reports whether a performer did not answer a result, or the results differ
+func (r *StepReport) Failed() bool {
+	for _, answer := range r.Answers {
+		if answer.Error != "" {
+			return true
+		}
+	}
+	return len(r.Differences) > 0
+}
+
+// Round sends the task of every step to every performer, all performers at once and the
+// steps one after the other, as task <roundID>-<step name>. The results of the
+// performers are compared with opts, as the aggregator compares the results of its
+// operators before reaching a quorum.
+func Round(ctx context.Context, performers []Performer, steps []Step, roundID string, timeout time.Duration, opts resultdiff.Options) ([]StepReport, error) {
+	if len(performers) == 0 {
+		return nil, fmt.Errorf("at least one performer is needed")
+	}
+	reports := make([]StepReport, 0, len(steps))
+	for _, step := range steps {
+		if ctx.Err() != nil {
+			return reports, ctx.Err()
+		}
+		report := StepReport{Step: step.Name, TaskID: roundID + "-" + step.Name, Answers: make([]Answer, len(performers))}
+		var wg sync.WaitGroup
+		for i, p := range performers {
+			wg.Add(1)
+			go func(i int, p Performer) {
+				defer wg.Done()
+				report.Answers[i] = send(ctx, p, report.TaskID, step.Payload, timeout)
+			}(i, p)
+		}
+		wg.Wait()
+
+		results := make([]resultdiff.Result, 0, len(performers))
+		for _, answer := range report.Answers {
+			if answer.Error == "" {
+				results = append(results, resultdiff.Result{Operator: answer.Performer, Data: answer.raw})
+			}
+		}
+		if len(results) > 1 {
+			diff, err := resultdiff.Compare(results, opts)
+			if err != nil {
+				return reports, fmt.Errorf("%s: %w", step.Name, err)
+			}
+			report.Differences = diff.Differences
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// send sends payload to p as task taskID
+func send(ctx context.Context, p Performer, taskID string, payload *client.Payload, timeout time.Duration) Answer {
+	answer := Answer{Performer: p.Name}
+	taskCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	raw, err := p.Client.Execute(taskCtx, taskID, payload)
+	answer.Latency = time.Since(start)
+	if err != nil {
+		answer.Error = err.Error()
+		return answer
+	}
+	result, err := client.DecodeResult(raw)
+	if err != nil {
+		answer.Error = err.Error()
+		return answer
+	}
+	if report := result.Err(); report != nil {
+		answer.Error = report.Error()
+		return answer
+	}
+	answer.raw = raw
+	return answer
+}
+
+// Failures returns the names of the steps of reports that failed, sorted
+func Failures(reports []StepReport) []string {
+	var failed []string
+	for i := range reports {
+		if reports[i].Failed() {
+			failed = append(failed, reports[i].Step)
+		}
+	}
+	sort.Strings(failed)
+	return failed
+}
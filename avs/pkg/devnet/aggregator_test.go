@@ -0,0 +1,111 @@
+package devnet
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/client"
+	"github.com/najnomics/crosscow-avs/pkg/loadgen"
+	"github.com/najnomics/crosscow-avs/pkg/resultdiff"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// stubPerformer serves a performer reading stub adapters and returns a client of it
+func stubPerformer(t *testing.T, name string) Performer {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	target, err := loadgen.ServeStub(ctx, 0, zap.NewNop())
+	if err != nil {
+		t.Fatalf("ServeStub failed: %v", err)
+	}
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to dial %s: %v", target, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return Performer{Name: name, Client: client.New(conn)}
+}
+
+func Test_LifecycleCoversTheChains(t *testing.T) {
+	steps, err := Lifecycle([]uint64{adapters.ChainIDEthereum, adapters.ChainIDBase}, DefaultUser)
+	if err != nil {
+		t.Fatalf("Lifecycle failed: %v", err)
+	}
+	names := make([]string, len(steps))
+	for i, step := range steps {
+		names[i] = step.Name
+	}
+	want := "capabilities yield-monitoring yield-ranking risk-assessment-1 risk-assessment-8453 cross-chain-yield-check rebalance-dry-run batch"
+	if got := strings.Join(names, " "); got != want {
+		t.Errorf("Expected steps %q, got %q", want, got)
+	}
+	if dryRun := steps[6].Payload; dryRun.Type != client.TaskTypeRebalanceExecution || dryRun.Parameters["dry_run"] != true {
+		t.Errorf("Expected the rebalance to be a dry run, got %+v", dryRun)
+	}
+
+	single, err := Lifecycle([]uint64{adapters.ChainIDBase}, DefaultUser)
+	if err != nil {
+		t.Fatalf("Lifecycle failed: %v", err)
+	}
+	for _, step := range single {
+		if step.Name == "cross-chain-yield-check" {
+			t.Errorf("Expected no cross-chain check on a single chain")
+		}
+	}
+	if _, err := Lifecycle(nil, DefaultUser); err == nil {
+		t.Errorf("Expected a lifecycle without chains to be refused")
+	}
+}
+
+func Test_RoundComparesPerformers(t *testing.T) {
+	performers := []Performer{stubPerformer(t, "alice"), stubPerformer(t, "bob")}
+	steps, err := Lifecycle([]uint64{adapters.ChainIDEthereum, adapters.ChainIDBase}, DefaultUser)
+	if err != nil {
+		t.Fatalf("Lifecycle failed: %v", err)
+	}
+	reads := []Step{steps[0], steps[1], steps[len(steps)-1]}
+
+	reports, err := Round(context.Background(), performers, reads, "round-1", 10*time.Second, resultdiff.Options{Ignore: resultdiff.DefaultIgnore})
+	if err != nil {
+		t.Fatalf("Round failed: %v", err)
+	}
+	if len(reports) != len(reads) {
+		t.Fatalf("Expected a report per step, got %d", len(reports))
+	}
+	for _, report := range reports {
+		if report.TaskID != "round-1-"+report.Step || len(report.Answers) != 2 {
+			t.Errorf("Expected both performers to answer task round-1-%s, got %+v", report.Step, report)
+		}
+		if report.Failed() {
+			t.Errorf("Expected %s to succeed and agree, got %+v", report.Step, report)
+		}
+	}
+	if failed := Failures(reports); len(failed) != 0 {
+		t.Errorf("Expected no failed step, got %v", failed)
+	}
+
+	// the stubs serve no market on chain 10
+	payload, err := client.Build(client.RiskAssessment{Protocol: adapters.ProtocolAaveV3, ChainID: 10, AssessmentType: "full"})
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	reports, err = Round(context.Background(), performers[:1], []Step{{Name: "unserved", Payload: payload}}, "round-2", 10*time.Second, resultdiff.Options{})
+	if err != nil {
+		t.Fatalf("Round failed: %v", err)
+	}
+	if reports[0].Answers[0].Error == "" || !reports[0].Failed() {
+		t.Errorf("Expected the unserved chain to fail, got %+v", reports[0])
+	}
+	if failed := Failures(reports); len(failed) != 1 || failed[0] != "unserved" {
+		t.Errorf("Expected the unserved step to fail, got %v", failed)
+	}
+	if _, err := Round(context.Background(), nil, reads, "round-3", time.Second, resultdiff.Options{}); err == nil {
+		t.Errorf("Expected a round without performers to be refused")
+	}
+}
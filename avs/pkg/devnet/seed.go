@@ -0,0 +1,119 @@
+// Package devnet backs the local stack in the devnet directory: Anvil forks of the
+// chains the performer serves, seeded with a funded user, and a mock aggregator sending
+// the tasks of a full lifecycle to one or more performers, as the aggregator and
+// executors of Hourglass would, and checking that they agree on the results.
+//
+// Forks keep the live state of every protocol, so the performer reads real markets;
+// seeding only adds the ETH and USDC the user rebalances with.
+package devnet
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+)
+
+// USDCBalancesSlot is the storage slot of the balances mapping of USDC, the same on
+// every chain
+const USDCBalancesSlot = 9
+
+// pollInterval is how often a chain that does not answer yet is asked again
+const pollInterval = 500 * time.Millisecond
+
+// DefaultUser is the account the stack is seeded with and rebalances for. Nobody holds
+// its key: dry runs simulate its calls without signing them.
+var DefaultUser = common.HexToAddress("0x00000000000000000000000000000000000a11ce")
+
+// Seed is the state added to every chain
+type Seed struct {
+	User common.Address
+
+	// ETH is the native balance of User, in wei
+	ETH *big.Int
+
+	// USDC is the USDC balance of User, in base units
+	USDC *big.Int
+}
+
+// DefaultSeed funds DefaultUser with 10 ETH and 100,000 USDC
+func DefaultSeed() Seed {
+	return Seed{
+		User: DefaultUser,
+		ETH:  new(big.Int).Mul(big.NewInt(10), big.NewInt(1e18)),
+		USDC: big.NewInt(100_000_000_000),
+	}
+}
+
+// BalanceSlot returns the storage slot of the balance of holder in an ERC-20 token whose
+// balances mapping is at storage slot mappingSlot
+func BalanceSlot(holder common.Address, mappingSlot uint64) common.Hash {
+	return crypto.Keccak256Hash(
+		common.LeftPadBytes(holder.Bytes(), 32),
+		common.BigToHash(new(big.Int).SetUint64(mappingSlot)).Bytes(),
+	)
+}
+
+// SeedChain waits until the Anvil node of cfg answers, checks that it serves the chain
+// of cfg, and funds seed.User on it. It reads the USDC balance back, so a token keeping
+// its balances elsewhere fails seeding rather than the tasks run against the chain.
+func SeedChain(ctx context.Context, cfg chain.Config, seed Seed) error {
+	client, err := waitForChain(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	usdc, err := adapters.USDCAddress(cfg.ChainID)
+	if err != nil {
+		return err
+	}
+	if err := client.CallContext(ctx, nil, "anvil_setBalance", seed.User, hexutil.EncodeBig(seed.ETH)); err != nil {
+		return fmt.Errorf("failed to set the ETH balance on chain %d: %w", cfg.ChainID, err)
+	}
+	slot := BalanceSlot(seed.User, USDCBalancesSlot)
+	if err := client.CallContext(ctx, nil, "anvil_setStorageAt", usdc, slot, common.BigToHash(seed.USDC)); err != nil {
+		return fmt.Errorf("failed to set the USDC balance on chain %d: %w", cfg.ChainID, err)
+	}
+
+	balance, err := adapters.USDCBalance(ctx, ethclient.NewClient(client), cfg.ChainID, seed.User)
+	if err != nil {
+		return fmt.Errorf("failed to read the USDC balance on chain %d: %w", cfg.ChainID, err)
+	}
+	if balance.Cmp(seed.USDC) != 0 {
+		return fmt.Errorf("USDC balance on chain %d is %s after seeding %s: balances are not at slot %d", cfg.ChainID, balance, seed.USDC, USDCBalancesSlot)
+	}
+	return nil
+}
+
+// waitForChain dials the node of cfg until it answers or ctx is done, and checks its
+// chain id
+func waitForChain(ctx context.Context, cfg chain.Config) (*rpc.Client, error) {
+	for {
+		client, err := rpc.DialContext(ctx, cfg.RpcUrl)
+		if err == nil {
+			var id hexutil.Uint64
+			if err = client.CallContext(ctx, &id, "eth_chainId"); err == nil {
+				if uint64(id) != cfg.ChainID {
+					client.Close()
+					return nil, fmt.Errorf("%s serves chain %d, not %d", cfg.Name, uint64(id), cfg.ChainID)
+				}
+				return client, nil
+			}
+			client.Close()
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("chain %d at %s did not answer: %w", cfg.ChainID, cfg.RpcUrl, err)
+		case <-time.After(pollInterval):
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package devnet
+
+import (
+	"context"
+	"math/big"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+)
+
+// fakeAnvil answers the calls seeding makes, keeping the storage of one token
+type fakeAnvil struct {
+	mu       sync.Mutex
+	chainID  uint64
+	token    common.Address
+	storage  map[common.Hash]common.Hash
+	balances map[common.Address]*big.Int
+
+	// mappingSlot is where the token reads balances from
+	mappingSlot uint64
+}
+
+type fakeEth struct{ anvil *fakeAnvil }
+
+func (e fakeEth) ChainId() hexutil.Uint64 { return hexutil.Uint64(e.anvil.chainID) }
+
+func (e fakeEth) Call(args map[string]interface{}, block string) (hexutil.Bytes, error) {
+	input, _ := args["input"].(string)
+	if input == "" {
+		input, _ = args["data"].(string)
+	}
+	data, err := hexutil.Decode(input)
+	if err != nil || len(data) != 36 {
+		return nil, err
+	}
+	holder := common.BytesToAddress(data[4:])
+	e.anvil.mu.Lock()
+	defer e.anvil.mu.Unlock()
+	value := e.anvil.storage[BalanceSlot(holder, e.anvil.mappingSlot)]
+	return value.Bytes(), nil
+}
+
+type fakeAnvilAPI struct{ anvil *fakeAnvil }
+
+func (a fakeAnvilAPI) SetBalance(account common.Address, wei hexutil.Big) {
+	a.anvil.mu.Lock()
+	defer a.anvil.mu.Unlock()
+	a.anvil.balances[account] = wei.ToInt()
+}
+
+func (a fakeAnvilAPI) SetStorageAt(contract common.Address, slot, value common.Hash) {
+	a.anvil.mu.Lock()
+	defer a.anvil.mu.Unlock()
+	if contract == a.anvil.token {
+		a.anvil.storage[slot] = value
+	}
+}
+
+func serveFakeAnvil(t *testing.T, chainID, mappingSlot uint64) (*fakeAnvil, string) {
+	t.Helper()
+	token, err := adapters.USDCAddress(chainID)
+	if err != nil {
+		t.Fatalf("USDCAddress failed: %v", err)
+	}
+	anvil := &fakeAnvil{chainID: chainID, token: token, mappingSlot: mappingSlot, storage: map[common.Hash]common.Hash{}, balances: map[common.Address]*big.Int{}}
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", fakeEth{anvil}); err != nil {
+		t.Fatalf("RegisterName failed: %v", err)
+	}
+	if err := server.RegisterName("anvil", fakeAnvilAPI{anvil}); err != nil {
+		t.Fatalf("RegisterName failed: %v", err)
+	}
+	ts := httptest.NewServer(server)
+	t.Cleanup(func() {
+		ts.Close()
+		server.Stop()
+	})
+	return anvil, ts.URL
+}
+
+func Test_SeedChainFundsTheUser(t *testing.T) {
+	anvil, url := serveFakeAnvil(t, adapters.ChainIDBase, USDCBalancesSlot)
+	seed := DefaultSeed()
+
+	if err := SeedChain(context.Background(), chain.Config{ChainID: adapters.ChainIDBase, Name: "base", RpcUrl: url}, seed); err != nil {
+		t.Fatalf("SeedChain failed: %v", err)
+	}
+	if got := anvil.balances[DefaultUser]; got == nil || got.Cmp(seed.ETH) != 0 {
+		t.Errorf("Expected the user to hold %s wei, got %v", seed.ETH, got)
+	}
+	if got := anvil.storage[BalanceSlot(DefaultUser, USDCBalancesSlot)].Big(); got.Cmp(seed.USDC) != 0 {
+		t.Errorf("Expected the user to hold %s USDC, got %s", seed.USDC, got)
+	}
+}
+
+func Test_SeedChainChecksTheChain(t *testing.T) {
+	_, base := serveFakeAnvil(t, adapters.ChainIDBase, USDCBalancesSlot)
+	_, elsewhere := serveFakeAnvil(t, adapters.ChainIDEthereum, 51)
+
+	testCases := map[string]struct {
+		cfg  chain.Config
+		want string
+	}{
+		"wrong chain":   {chain.Config{ChainID: adapters.ChainIDEthereum, Name: "mainnet", RpcUrl: base}, "serves chain 8453, not 1"},
+		"wrong slot":    {chain.Config{ChainID: adapters.ChainIDEthereum, Name: "mainnet", RpcUrl: elsewhere}, "balances are not at slot 9"},
+		"not answering": {chain.Config{ChainID: adapters.ChainIDBase, Name: "base", RpcUrl: "http://127.0.0.1:1"}, "did not answer"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			if err := SeedChain(ctx, tc.cfg, DefaultSeed()); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("Expected %q, got %v", tc.want, err)
+			}
+		})
+	}
+}
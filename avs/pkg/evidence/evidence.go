@@ -0,0 +1,521 @@
+// Package evidence re-checks the yield attestations submitted to the service manager
+// against the markets they attest, read at the block they were submitted at, and keeps
+// an artifact of every attestation whose yield rate disagrees materially with the chain.
+// Artifacts hold the attestation, the block the market was read at and the computation
+// from the market state to the rate, so challenges and slashing requests can be made
+// from them and replayed by anyone with an archive node.
+package evidence
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/irm"
+	"github.com/najnomics/crosscow-avs/pkg/servicemanager"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+	"go.uber.org/zap"
+)
+
+const (
+	prefixArtifact = "evidence:artifact:"
+	keyCursor      = "evidence:cursor"
+
+	// tracePlaces is the number of fractional digits of the rates and utilization of
+	// traces, as many as float64 carries
+	tracePlaces = 15
+)
+
+// Config configures re-checking the attestations submitted to the service manager
+type Config struct {
+	Enabled bool `yaml:"enabled"`
+
+	// ChainID is the chain of the service manager and ServiceManager its address
+	ChainID        uint64 `yaml:"chainId"`
+	ServiceManager string `yaml:"serviceManager"`
+
+	// ToleranceBps is how far, in basis points, an attested yield rate may be from the
+	// one recomputed before an artifact is kept. Markets move between the sample an
+	// attestation is computed from and its inclusion, so it should not be zero.
+	ToleranceBps uint64 `yaml:"toleranceBps"`
+
+	// Confirmations is how many blocks attestations must be buried under before they are
+	// checked, so reorged attestations are not taken for evidence
+	Confirmations uint64 `yaml:"confirmations"`
+
+	// StartBlock is the first block scanned on the first start, the head when zero.
+	// Later starts resume from the last block scanned.
+	StartBlock uint64 `yaml:"startBlock"`
+
+	// PollInterval is the time between queries of attestation events, and MaxBlockRange
+	// bounds the blocks of a single query
+	PollInterval  time.Duration `yaml:"pollInterval"`
+	MaxBlockRange uint64        `yaml:"maxBlockRange"`
+}
+
+// DefaultConfig is disabled. Enabled, attestations more than 50 bps off are kept as
+// evidence once 12 blocks deep, polling every Ethereum block time.
+func DefaultConfig() Config {
+	return Config{
+		ToleranceBps:  50,
+		Confirmations: 12,
+		PollInterval:  12 * time.Second,
+		MaxBlockRange: 2_000,
+	}
+}
+
+// Validate checks the config for values attestations cannot be checked with
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.ChainID == 0 {
+		return fmt.Errorf("chainId is required")
+	}
+	if !common.IsHexAddress(c.ServiceManager) {
+		return fmt.Errorf("invalid serviceManager address %q", c.ServiceManager)
+	}
+	if c.ToleranceBps == 0 {
+		return fmt.Errorf("toleranceBps must be positive")
+	}
+	if c.PollInterval <= 0 {
+		return fmt.Errorf("pollInterval must be positive")
+	}
+	if c.MaxBlockRange == 0 {
+		return fmt.Errorf("maxBlockRange must be positive")
+	}
+	return nil
+}
+
+// Attestation is a yield attestation as submitted to the service manager
+type Attestation struct {
+	ID           common.Hash    `json:"id"`
+	Operator     common.Address `json:"operator"`
+	Protocol     string         `json:"protocol"`
+	ProtocolID   common.Hash    `json:"protocolId"`
+	ChainID      uint64         `json:"chainId"`
+	YieldRateBps uint64         `json:"yieldRateBps"`
+
+	// ServiceManager is the contract the attestation was submitted to on
+	// ServiceManagerChainID, in transaction TxHash of the block numbered BlockNumber
+	ServiceManager        common.Address `json:"serviceManager"`
+	ServiceManagerChainID uint64         `json:"serviceManagerChainId"`
+	BlockNumber           uint64         `json:"blockNumber"`
+	BlockHash             common.Hash    `json:"blockHash"`
+	BlockTimestamp        uint64         `json:"blockTimestamp"`
+	TxHash                common.Hash    `json:"txHash"`
+}
+
+// Block is the block of the attested market's chain its state was read at: the last
+// block before the attestation's
+type Block struct {
+	ChainID   uint64      `json:"chainId"`
+	Number    uint64      `json:"number"`
+	Hash      common.Hash `json:"hash"`
+	Timestamp uint64      `json:"timestamp"`
+}
+
+// Curve is the rate model of a market, as described by irm.Describe
+type Curve struct {
+	Kind          string            `json:"kind"`
+	Kink          canonical.Decimal `json:"kink"`
+	BaseRate      canonical.Decimal `json:"baseRate"`
+	Slope1        canonical.Decimal `json:"slope1"`
+	Slope2        canonical.Decimal `json:"slope2"`
+	ReserveFactor canonical.Decimal `json:"reserveFactor"`
+}
+
+// Trace is the computation of the yield rate from the market state: utilization is
+// total borrow over total supply, the supply rate that of the curve at the utilization,
+// and the rate in basis points the supply rate rounded the way attestations round it.
+// Curve is nil for models irm.Describe does not know.
+type Trace struct {
+	TotalSupply  string            `json:"totalSupply"`
+	TotalBorrow  string            `json:"totalBorrow"`
+	Utilization  canonical.Decimal `json:"utilization"`
+	Curve        *Curve            `json:"curve,omitempty"`
+	SupplyRate   canonical.Decimal `json:"supplyRate"`
+	YieldRateBps uint64            `json:"yieldRateBps"`
+	DeviationBps uint64            `json:"deviationBps"`
+	ToleranceBps uint64            `json:"toleranceBps"`
+}
+
+// Evidence is what an artifact proves: that the attestation's yield rate is further
+// than the tolerance from the one the trace recomputes at the block
+type Evidence struct {
+	Attestation Attestation `json:"attestation"`
+	Block       Block       `json:"block"`
+	Trace       Trace       `json:"trace"`
+}
+
+// Artifact is the evidence against an attestation. Digest is the keccak256 hash of the
+// canonical JSON of the evidence, which challenges can commit to.
+type Artifact struct {
+	Evidence
+	Digest     common.Hash `json:"digest"`
+	DetectedAt time.Time   `json:"detectedAt"`
+}
+
+// Digest returns the keccak256 hash of the canonical JSON of e
+func Digest(e *Evidence) (common.Hash, error) {
+	encoded, err := canonical.Marshal(e)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to encode evidence: %w", err)
+	}
+	return crypto.Keccak256Hash(encoded), nil
+}
+
+// Checker re-checks the attestations submitted to the service manager and keeps the
+// artifacts of those disagreeing with the chain
+type Checker struct {
+	cfg      Config
+	sm       *servicemanager.ServiceManager
+	chains   *chain.Manager
+	registry *adapters.Registry
+	kv       store.KV
+	logger   *zap.Logger
+	now      func() time.Time
+}
+
+// New creates a checker reading the markets attested through registry and keeping
+// artifacts in kv
+func New(cfg Config, chains *chain.Manager, registry *adapters.Registry, kv store.KV, logger *zap.Logger) *Checker {
+	return &Checker{
+		cfg:      cfg,
+		sm:       servicemanager.New(chains, cfg.ChainID, common.HexToAddress(cfg.ServiceManager)),
+		chains:   chains,
+		registry: registry,
+		kv:       kv,
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// NewFromConfig creates the checker of cfg, nil when disabled
+func NewFromConfig(cfg Config, chains *chain.Manager, registry *adapters.Registry, kv store.KV, logger *zap.Logger) *Checker {
+	if !cfg.Enabled {
+		return nil
+	}
+	return New(cfg, chains, registry, kv, logger)
+}
+
+// Start polls for attestations and checks them until ctx is cancelled
+func (c *Checker) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(c.cfg.PollInterval)
+		defer ticker.Stop()
+		for {
+			if found, err := c.Poll(ctx); err != nil {
+				c.logger.Sugar().Warnw("Attestation checks incomplete", "evidence", found, "error", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Poll checks the attestations submitted in the confirmed blocks produced since the
+// previous poll and returns the number of artifacts kept. Attestations whose market
+// cannot be read are checked again on the next poll, unless the node pruned the state
+// they need.
+func (c *Checker) Poll(ctx context.Context) (int, error) {
+	client, err := c.chains.Client(c.cfg.ChainID)
+	if err != nil {
+		return 0, err
+	}
+	head, err := client.BlockNumber(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if head < c.cfg.Confirmations {
+		return 0, nil
+	}
+	head -= c.cfg.Confirmations
+
+	cursor, found, err := c.cursor(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if !found {
+		if c.cfg.StartBlock == 0 || c.cfg.StartBlock > head {
+			return 0, c.advance(ctx, head)
+		}
+		cursor = c.cfg.StartBlock - 1
+	}
+	if head <= cursor {
+		return 0, nil
+	}
+	to := head
+	if to-cursor > c.cfg.MaxBlockRange {
+		to = cursor + c.cfg.MaxBlockRange
+	}
+	attested, err := c.sm.AttestationsSubmitted(ctx, cursor+1, to)
+	if err != nil {
+		return 0, err
+	}
+
+	protocols := make(map[common.Hash]string)
+	for _, protocol := range c.registry.Protocols() {
+		protocols[servicemanager.ProtocolID(protocol)] = protocol
+	}
+	kept := 0
+	for _, a := range attested {
+		protocol, known := protocols[a.ProtocolID]
+		if !known {
+			continue
+		}
+		if _, err := c.Artifact(ctx, a.AttestationID); err == nil {
+			continue
+		} else if !errors.Is(err, store.ErrNotFound) {
+			return kept, err
+		}
+		artifact, err := c.Check(ctx, a, protocol)
+		if errors.Is(err, chain.ErrHistoricalState) {
+			c.logger.Sugar().Warnw("Cannot check attestation without an archive node", "attestation", a.AttestationID.Hex(), "protocol", protocol, "chainId", a.ChainID, "error", err)
+			continue
+		}
+		if err != nil {
+			// the blocks from the attestation's on are scanned again on the next poll
+			if advanceErr := c.advance(ctx, a.BlockNumber-1); advanceErr != nil {
+				return kept, advanceErr
+			}
+			return kept, fmt.Errorf("attestation %s of %s on chain %d: %w", a.AttestationID.Hex(), protocol, a.ChainID, err)
+		}
+		if artifact == nil {
+			continue
+		}
+		if err := c.store(ctx, artifact); err != nil {
+			return kept, err
+		}
+		kept++
+		c.logger.Sugar().Warnw("Attestation disagrees with the chain", "attestation", a.AttestationID.Hex(), "operator", a.Operator.Hex(),
+			"protocol", protocol, "chainId", a.ChainID, "attestedBps", artifact.Attestation.YieldRateBps, "recomputedBps", artifact.Trace.YieldRateBps,
+			"block", artifact.Block.Number, "digest", artifact.Digest.Hex())
+	}
+	return kept, c.advance(ctx, to)
+}
+
+// Check re-reads the market of attestation a of protocol at the last block before the
+// attestation's and returns the artifact against it, nil when its yield rate is within
+// the tolerance
+func (c *Checker) Check(ctx context.Context, a servicemanager.AttestationSubmitted, protocol string) (*Artifact, error) {
+	adapter, err := c.registry.Get(protocol)
+	if err != nil {
+		return nil, err
+	}
+	smClient, err := c.chains.Client(c.cfg.ChainID)
+	if err != nil {
+		return nil, err
+	}
+	attestedIn, err := smClient.HeaderByNumber(ctx, new(big.Int).SetUint64(a.BlockNumber))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attestation block %d: %w", a.BlockNumber, err)
+	}
+	block, err := c.blockBefore(ctx, a.ChainID, a.BlockNumber, attestedIn.Time)
+	if err != nil {
+		return nil, err
+	}
+	state, err := adapter.MarketState(chain.WithBlock(ctx, block.Number), a.ChainID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read market at block %d: %w", block.Number, err)
+	}
+
+	trace := newTrace(&state.Pool)
+	attested := a.YieldRate.Uint64()
+	trace.DeviationBps = max(attested, trace.YieldRateBps) - min(attested, trace.YieldRateBps)
+	trace.ToleranceBps = c.cfg.ToleranceBps
+	if trace.DeviationBps <= c.cfg.ToleranceBps {
+		return nil, nil
+	}
+
+	artifact := &Artifact{
+		Evidence: Evidence{
+			Attestation: Attestation{
+				ID:                    a.AttestationID,
+				Operator:              a.Operator,
+				Protocol:              protocol,
+				ProtocolID:            a.ProtocolID,
+				ChainID:               a.ChainID,
+				YieldRateBps:          attested,
+				ServiceManager:        c.sm.Address(),
+				ServiceManagerChainID: c.cfg.ChainID,
+				BlockNumber:           a.BlockNumber,
+				BlockHash:             a.BlockHash,
+				BlockTimestamp:        attestedIn.Time,
+				TxHash:                a.TxHash,
+			},
+			Block: *block,
+			Trace: trace,
+		},
+		DetectedAt: c.now().UTC(),
+	}
+	if artifact.Digest, err = Digest(&artifact.Evidence); err != nil {
+		return nil, err
+	}
+	return artifact, nil
+}
+
+// blockBefore returns the last block of chainID before the attestation in block number
+// of the service manager's chain, produced at timestamp. On the service manager's chain
+// that is the block before; on others the last one produced earlier.
+func (c *Checker) blockBefore(ctx context.Context, chainID, number, timestamp uint64) (*Block, error) {
+	client, err := c.chains.Client(chainID)
+	if err != nil {
+		return nil, err
+	}
+	if chainID == c.cfg.ChainID {
+		return header(ctx, client, chainID, number-1)
+	}
+	latest, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if latest.Time < timestamp {
+		return header(ctx, client, chainID, latest.Number.Uint64())
+	}
+	// the last block produced before timestamp is in [low, high)
+	low, high := uint64(0), latest.Number.Uint64()
+	for high-low > 1 {
+		mid := low + (high-low)/2
+		h, err := client.HeaderByNumber(ctx, new(big.Int).SetUint64(mid))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read block %d of chain %d: %w", mid, chainID, err)
+		}
+		if h.Time < timestamp {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return header(ctx, client, chainID, low)
+}
+
+func header(ctx context.Context, client chain.Client, chainID, number uint64) (*Block, error) {
+	h, err := client.HeaderByNumber(ctx, new(big.Int).SetUint64(number))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read block %d of chain %d: %w", number, chainID, err)
+	}
+	return &Block{ChainID: chainID, Number: number, Hash: h.Hash(), Timestamp: h.Time}, nil
+}
+
+// newTrace traces the supply rate of pool
+func newTrace(pool *irm.Pool) Trace {
+	supplyRate := pool.SupplyRate()
+	trace := Trace{
+		TotalSupply:  amount(pool.TotalSupply),
+		TotalBorrow:  amount(pool.TotalBorrow),
+		Utilization:  canonical.NewDecimalFromFloat(pool.Utilization(), tracePlaces),
+		SupplyRate:   canonical.NewDecimalFromFloat(supplyRate, tracePlaces),
+		YieldRateBps: bps(supplyRate),
+	}
+	if curve, ok := irm.Describe(pool.Model); ok {
+		trace.Curve = &Curve{
+			Kind:          curve.Kind,
+			Kink:          canonical.NewDecimalFromFloat(curve.Kink, tracePlaces),
+			BaseRate:      canonical.NewDecimalFromFloat(curve.BaseRate, tracePlaces),
+			Slope1:        canonical.NewDecimalFromFloat(curve.Slope1, tracePlaces),
+			Slope2:        canonical.NewDecimalFromFloat(curve.Slope2, tracePlaces),
+			ReserveFactor: canonical.NewDecimalFromFloat(curve.ReserveFactor, tracePlaces),
+		}
+	}
+	return trace
+}
+
+func amount(a *big.Int) string {
+	if a == nil {
+		return "0"
+	}
+	return a.String()
+}
+
+// bps converts a fraction to rounded basis points, negative fractions to zero, the way
+// the submitter rounds the rates it attests
+func bps(fraction float64) uint64 {
+	if fraction <= 0 {
+		return 0
+	}
+	return uint64(fraction*10_000 + 0.5)
+}
+
+// Artifact returns the artifact against attestation id, store.ErrNotFound without one
+func (c *Checker) Artifact(ctx context.Context, id common.Hash) (*Artifact, error) {
+	encoded, err := c.kv.Get(ctx, []byte(prefixArtifact+id.Hex()))
+	if err != nil {
+		return nil, err
+	}
+	var artifact Artifact
+	if err := json.Unmarshal(encoded, &artifact); err != nil {
+		return nil, fmt.Errorf("failed to decode artifact: %w", err)
+	}
+	return &artifact, nil
+}
+
+// Artifacts returns the artifacts kept, the latest attestations first
+func (c *Checker) Artifacts(ctx context.Context) ([]*Artifact, error) {
+	artifacts := []*Artifact{}
+	err := c.kv.Iterate(ctx, []byte(prefixArtifact), func(key, value []byte) error {
+		var artifact Artifact
+		if err := json.Unmarshal(value, &artifact); err != nil {
+			return fmt.Errorf("failed to decode artifact %s: %w", key, err)
+		}
+		artifacts = append(artifacts, &artifact)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(artifacts, func(i, j int) bool {
+		return artifacts[i].Attestation.BlockNumber > artifacts[j].Attestation.BlockNumber
+	})
+	return artifacts, nil
+}
+
+func (c *Checker) store(ctx context.Context, artifact *Artifact) error {
+	encoded, err := json.Marshal(artifact)
+	if err != nil {
+		return err
+	}
+	if err := c.kv.Set(ctx, []byte(prefixArtifact+artifact.Attestation.ID.Hex()), encoded); err != nil {
+		return fmt.Errorf("failed to store artifact: %w", err)
+	}
+	return nil
+}
+
+// cursor returns the last block scanned, false before the first scan
+func (c *Checker) cursor(ctx context.Context) (uint64, bool, error) {
+	encoded, err := c.kv.Get(ctx, []byte(keyCursor))
+	if errors.Is(err, store.ErrNotFound) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to load evidence cursor: %w", err)
+	}
+	var block uint64
+	if err := json.Unmarshal(encoded, &block); err != nil {
+		return 0, false, fmt.Errorf("failed to decode evidence cursor: %w", err)
+	}
+	return block, true, nil
+}
+
+func (c *Checker) advance(ctx context.Context, block uint64) error {
+	encoded, err := json.Marshal(block)
+	if err != nil {
+		return err
+	}
+	if err := c.kv.Set(ctx, []byte(keyCursor), encoded); err != nil {
+		return fmt.Errorf("failed to store evidence cursor: %w", err)
+	}
+	return nil
+}
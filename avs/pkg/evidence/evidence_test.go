@@ -0,0 +1,155 @@
+package evidence
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/chain/chaintest"
+	"github.com/najnomics/crosscow-avs/pkg/irm"
+	"github.com/najnomics/crosscow-avs/pkg/servicemanager"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+	"go.uber.org/zap"
+)
+
+var serviceManagerAddress = common.HexToAddress("0x0000000000000000000000000000000000005e41")
+
+var eventABI = chain.MustParseABI(`[
+	{"name":"YieldAttestationSubmitted","type":"event","anonymous":false,"inputs":[
+		{"name":"attestationId","type":"bytes32","indexed":true},{"name":"operator","type":"address","indexed":true},
+		{"name":"protocolId","type":"bytes32","indexed":true},{"name":"chainId","type":"uint256","indexed":false},
+		{"name":"yieldRate","type":"uint256","indexed":false}]}
+]`)
+
+// fakeAdapter serves a market supplying at 8%, or fails with err, and records the blocks
+// it was read at
+type fakeAdapter struct {
+	mu     sync.Mutex
+	blocks []uint64
+	err    error
+}
+
+func (f *fakeAdapter) Protocol() string   { return "compound_v3" }
+func (f *fakeAdapter) ChainIDs() []uint64 { return []uint64{1} }
+
+func (f *fakeAdapter) MarketState(ctx context.Context, chainID uint64) (*adapters.MarketState, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	if block := chain.BlockOf(ctx); block != nil {
+		f.blocks = append(f.blocks, block.Uint64())
+	}
+	return &adapters.MarketState{
+		Protocol: f.Protocol(),
+		ChainID:  chainID,
+		Pool: irm.Pool{
+			TotalSupply: big.NewInt(50_000_000_000_000),
+			TotalBorrow: big.NewInt(40_000_000_000_000),
+			Model:       &irm.CometModel{Kink: 0.9, SlopeLow: 0.1},
+		},
+	}, nil
+}
+
+func attested(t *testing.T, id byte, block uint64, protocol string, rateBps int64) types.Log {
+	t.Helper()
+	event := eventABI.Events["YieldAttestationSubmitted"]
+	data, err := event.Inputs.NonIndexed().Pack(big.NewInt(1), big.NewInt(rateBps))
+	if err != nil {
+		t.Fatalf("Failed to pack event: %v", err)
+	}
+	return types.Log{
+		Address:     serviceManagerAddress,
+		Topics:      []common.Hash{event.ID, {id}, common.BytesToHash(common.Address{7}.Bytes()), servicemanager.ProtocolID(protocol)},
+		Data:        data,
+		BlockNumber: block,
+		TxHash:      common.Hash{id, 0xff},
+	}
+}
+
+func Test_CheckerKeepsEvidenceOfDisagreeingAttestations(t *testing.T) {
+	contracts := chaintest.NewContracts(1)
+	chains := chain.NewManager()
+	chains.Register(1, "ethereum", contracts)
+	adapter := &fakeAdapter{}
+	cfg := DefaultConfig()
+	cfg.Enabled, cfg.ChainID, cfg.ServiceManager, cfg.Confirmations = true, 1, serviceManagerAddress.Hex(), 2
+	c := New(cfg, chains, adapters.NewRegistry(adapter), store.NewMemoryKV(), zap.NewNop())
+	ctx := context.Background()
+
+	contracts.SetHead(12, 1_700_000_000)
+	if kept, err := c.Poll(ctx); err != nil || kept != 0 {
+		t.Fatalf("Expected the first poll to start from the head, got %d: %v", kept, err)
+	}
+
+	contracts.AddLog(attested(t, 1, 11, "compound_v3", 820))   // within the tolerance of 800
+	contracts.AddLog(attested(t, 2, 12, "compound_v3", 1_200)) // 400 bps off
+	contracts.AddLog(attested(t, 3, 12, "unknown", 1_200))
+	contracts.SetHead(13, 1_700_000_000)
+	if kept, err := c.Poll(ctx); err != nil || kept != 0 {
+		t.Fatalf("Expected attestations to wait for their confirmations, got %d: %v", kept, err)
+	}
+	contracts.SetHead(14, 1_700_000_000)
+	kept, err := c.Poll(ctx)
+	if err != nil || kept != 1 {
+		t.Fatalf("Expected one artifact, got %d: %v", kept, err)
+	}
+	if len(adapter.blocks) != 2 || adapter.blocks[0] != 10 || adapter.blocks[1] != 11 {
+		t.Errorf("Expected markets to be read at the blocks before the attestations, got %v", adapter.blocks)
+	}
+
+	artifact, err := c.Artifact(ctx, common.Hash{2})
+	if err != nil {
+		t.Fatalf("Expected the artifact against the attestation off, got %v", err)
+	}
+	if artifact.Attestation.YieldRateBps != 1_200 || artifact.Attestation.TxHash != (common.Hash{2, 0xff}) || artifact.Block.Number != 11 {
+		t.Errorf("Unexpected attestation or block %+v %+v", artifact.Attestation, artifact.Block)
+	}
+	trace := artifact.Trace
+	if trace.YieldRateBps != 800 || trace.DeviationBps != 400 || trace.Utilization.String() != "0.800000000000000" || trace.Curve == nil || trace.Curve.Kind != irm.KindComet {
+		t.Errorf("Unexpected trace %+v", trace)
+	}
+	if digest, err := Digest(&artifact.Evidence); err != nil || digest != artifact.Digest {
+		t.Errorf("Expected the digest of the evidence, got %s: %v", artifact.Digest.Hex(), err)
+	}
+	if _, err := c.Artifact(ctx, common.Hash{1}); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("Expected no artifact against the attestation within the tolerance, got %v", err)
+	}
+
+	// a restarted checker resumes from the stored cursor
+	c = New(cfg, chains, adapters.NewRegistry(adapter), c.kv, zap.NewNop())
+	if kept, err := c.Poll(ctx); err != nil || kept != 0 {
+		t.Errorf("Expected no attestation checked twice, got %d: %v", kept, err)
+	}
+	if artifacts, err := c.Artifacts(ctx); err != nil || len(artifacts) != 1 {
+		t.Errorf("Expected the artifact listed, got %d: %v", len(artifacts), err)
+	}
+}
+
+func Test_CheckerRetriesUnreadableAttestations(t *testing.T) {
+	contracts := chaintest.NewContracts(1)
+	chains := chain.NewManager()
+	chains.Register(1, "ethereum", contracts)
+	adapter := &fakeAdapter{err: errors.New("rpc unavailable")}
+	cfg := DefaultConfig()
+	cfg.Enabled, cfg.ChainID, cfg.ServiceManager, cfg.Confirmations, cfg.StartBlock = true, 1, serviceManagerAddress.Hex(), 0, 5
+	c := New(cfg, chains, adapters.NewRegistry(adapter), store.NewMemoryKV(), zap.NewNop())
+	ctx := context.Background()
+
+	contracts.AddLog(attested(t, 1, 8, "compound_v3", 1_200))
+	contracts.SetHead(10, 1_700_000_000)
+	if _, err := c.Poll(ctx); err == nil {
+		t.Fatalf("Expected the unreadable market to fail the poll")
+	}
+	adapter.err = nil
+	if kept, err := c.Poll(ctx); err != nil || kept != 1 {
+		t.Errorf("Expected the attestation checked again, got %d: %v", kept, err)
+	}
+}
@@ -0,0 +1,418 @@
+// Package export writes what a performer recorded, its yield history, the risk scores of
+// its assessments and its execution ledger, as CSV or Parquet, so analysts can work with
+// it without access to the store of the performer.
+package export
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/najnomics/crosscow-avs/pkg/ledger"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+	"github.com/parquet-go/parquet-go"
+)
+
+// Formats rows are written in
+const (
+	FormatCSV     = "csv"
+	FormatParquet = "parquet"
+)
+
+// Datasets that can be exported
+const (
+	// DatasetYieldHistory is the supply rate, utilization and share price history the
+	// collector records
+	DatasetYieldHistory = "yield_history"
+
+	// DatasetRiskScores is the overall score of every risk assessment of the latest state
+	// of a market
+	DatasetRiskScores = "risk_scores"
+
+	// DatasetExecutions is the ledger of executed rebalances
+	DatasetExecutions = "executions"
+)
+
+// Datasets lists the datasets in the order they are documented
+var Datasets = []string{DatasetYieldHistory, DatasetRiskScores, DatasetExecutions}
+
+// metrics are the series of the yield history, by the first segment of their name
+var metrics = map[string]bool{"supply_rate": true, "utilization": true, "share_price": true}
+
+// Row is a row of an export. Header names the columns of Record, which are the columns
+// of the Parquet schema of the row.
+type Row interface {
+	Header() []string
+	Record() []string
+}
+
+// CheckFormat fails for formats rows cannot be written in
+func CheckFormat(format string) error {
+	if format != FormatCSV && format != FormatParquet {
+		return fmt.Errorf("unknown format %s, must be %s or %s", format, FormatCSV, FormatParquet)
+	}
+	return nil
+}
+
+// ContentType is the media type of files in format
+func ContentType(format string) string {
+	if format == FormatParquet {
+		return "application/vnd.apache.parquet"
+	}
+	return "text/csv"
+}
+
+// Write writes rows to w in format
+func Write[T Row](w io.Writer, format string, rows []T) error {
+	if err := CheckFormat(format); err != nil {
+		return err
+	}
+	if format == FormatParquet {
+		return parquet.Write(w, rows)
+	}
+	var header T
+	records := [][]string{header.Header()}
+	for _, row := range rows {
+		records = append(records, row.Record())
+	}
+	return csv.NewWriter(w).WriteAll(records)
+}
+
+// Filter selects the rows of an export
+type Filter struct {
+	// From and To bound the time of rows, from <= time < to. Zero times leave the range
+	// open.
+	From time.Time
+	To   time.Time
+
+	// Protocols and ChainIDs select the venues of rows, every venue when empty
+	Protocols []string
+	ChainIDs  []uint64
+}
+
+// Validate checks the time range of f
+func (f Filter) Validate() error {
+	if !f.From.IsZero() && !f.To.IsZero() && !f.From.Before(f.To) {
+		return fmt.Errorf("from must be before to")
+	}
+	return nil
+}
+
+// ParseFilter parses the filter of query: from and to as RFC 3339 times or dates, and
+// protocol and chain_id as comma separated lists
+func ParseFilter(query url.Values) (Filter, error) {
+	var filter Filter
+	var err error
+	if filter.From, err = parseTime(query.Get("from")); err != nil {
+		return Filter{}, fmt.Errorf("invalid from: %w", err)
+	}
+	if filter.To, err = parseTime(query.Get("to")); err != nil {
+		return Filter{}, fmt.Errorf("invalid to: %w", err)
+	}
+	filter.Protocols = splitList(query.Get("protocol"))
+	for _, field := range splitList(query.Get("chain_id")) {
+		chainID, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return Filter{}, fmt.Errorf("invalid chain_id %q", field)
+		}
+		filter.ChainIDs = append(filter.ChainIDs, chainID)
+	}
+	return filter, filter.Validate()
+}
+
+// Query encodes f as the query ParseFilter parses
+func (f Filter) Query() url.Values {
+	query := url.Values{}
+	if !f.From.IsZero() {
+		query.Set("from", f.From.Format(time.RFC3339))
+	}
+	if !f.To.IsZero() {
+		query.Set("to", f.To.Format(time.RFC3339))
+	}
+	if len(f.Protocols) > 0 {
+		query.Set("protocol", strings.Join(f.Protocols, ","))
+	}
+	if len(f.ChainIDs) > 0 {
+		chainIDs := make([]string, len(f.ChainIDs))
+		for i, chainID := range f.ChainIDs {
+			chainIDs[i] = formatUint(chainID)
+		}
+		query.Set("chain_id", strings.Join(chainIDs, ","))
+	}
+	return query
+}
+
+// parseTime parses an RFC 3339 time or a date, the zero time for ""
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// splitList splits a comma separated list, dropping empty elements
+func splitList(s string) []string {
+	var out []string
+	for _, field := range strings.Split(s, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			out = append(out, field)
+		}
+	}
+	return out
+}
+
+// within reports whether t is in the time range of f
+func (f Filter) within(t time.Time) bool {
+	return (f.From.IsZero() || !t.Before(f.From)) && (f.To.IsZero() || t.Before(f.To))
+}
+
+// venue reports whether the market of protocol on chainID is selected by f
+func (f Filter) venue(protocol string, chainID uint64) bool {
+	protocolOK, chainOK := len(f.Protocols) == 0, len(f.ChainIDs) == 0
+	for _, p := range f.Protocols {
+		protocolOK = protocolOK || strings.EqualFold(p, protocol)
+	}
+	for _, c := range f.ChainIDs {
+		chainOK = chainOK || c == chainID
+	}
+	return protocolOK && chainOK
+}
+
+// YieldRow is a point of the yield history. Supply rates are annual percentages,
+// utilization a fraction and share prices in USDC.
+type YieldRow struct {
+	Time     int64   `parquet:"time"`
+	Protocol string  `parquet:"protocol"`
+	ChainID  uint64  `parquet:"chain_id"`
+	Metric   string  `parquet:"metric"`
+	Value    float64 `parquet:"value"`
+}
+
+func (YieldRow) Header() []string {
+	return []string{"time", "protocol", "chain_id", "metric", "value"}
+}
+
+func (r YieldRow) Record() []string {
+	return []string{formatInt(r.Time), r.Protocol, formatUint(r.ChainID), r.Metric, formatFloat(r.Value)}
+}
+
+// RiskScoreRow is the overall score of a risk assessment, from 0 to 100
+type RiskScoreRow struct {
+	Time     int64   `parquet:"time"`
+	Protocol string  `parquet:"protocol"`
+	ChainID  uint64  `parquet:"chain_id"`
+	Score    float64 `parquet:"score"`
+}
+
+func (RiskScoreRow) Header() []string {
+	return []string{"time", "protocol", "chain_id", "score"}
+}
+
+func (r RiskScoreRow) Record() []string {
+	return []string{formatInt(r.Time), r.Protocol, formatUint(r.ChainID), formatFloat(r.Score)}
+}
+
+// ExecutionRow is an entry of the execution ledger. Amounts are in USDC and rates annual
+// percentages. GasPriced is false when gas on a chain of the rebalance could not be
+// priced, and GasUSDC is then the priced part.
+type ExecutionRow struct {
+	ExecutionID    string  `parquet:"execution_id"`
+	ExecutedAt     int64   `parquet:"executed_at"`
+	Owner          string  `parquet:"owner"`
+	SourceProtocol string  `parquet:"source_protocol"`
+	SourceChain    uint64  `parquet:"source_chain"`
+	TargetProtocol string  `parquet:"target_protocol"`
+	TargetChain    uint64  `parquet:"target_chain"`
+	Amount         float64 `parquet:"amount"`
+	BridgeFee      float64 `parquet:"bridge_fee"`
+	GasUSDC        float64 `parquet:"gas_usdc"`
+	GasPriced      bool    `parquet:"gas_priced"`
+	RateBefore     float64 `parquet:"rate_before"`
+	RateAfter      float64 `parquet:"rate_after"`
+}
+
+func (ExecutionRow) Header() []string {
+	return []string{
+		"execution_id", "executed_at", "owner", "source_protocol", "source_chain", "target_protocol", "target_chain",
+		"amount", "bridge_fee", "gas_usdc", "gas_priced", "rate_before", "rate_after",
+	}
+}
+
+func (r ExecutionRow) Record() []string {
+	return []string{
+		r.ExecutionID, formatInt(r.ExecutedAt), r.Owner, r.SourceProtocol, formatUint(r.SourceChain), r.TargetProtocol, formatUint(r.TargetChain),
+		formatFloat(r.Amount), formatFloat(r.BridgeFee), formatFloat(r.GasUSDC), strconv.FormatBool(r.GasPriced), formatFloat(r.RateBefore), formatFloat(r.RateAfter),
+	}
+}
+
+// Exporter reads the datasets of a performer
+type Exporter struct {
+	history *store.SeriesStore
+	ledger  *ledger.Ledger
+}
+
+// New creates an exporter of the series of history and the entries of l, which is nil
+// when the ledger is disabled
+func New(history *store.SeriesStore, l *ledger.Ledger) *Exporter {
+	return &Exporter{history: history, ledger: l}
+}
+
+// Export writes the rows of dataset filter selects to w in format
+func (e *Exporter) Export(ctx context.Context, w io.Writer, dataset, format string, filter Filter) error {
+	if err := CheckFormat(format); err != nil {
+		return err
+	}
+	if err := filter.Validate(); err != nil {
+		return err
+	}
+	switch dataset {
+	case DatasetYieldHistory:
+		rows, err := e.YieldHistory(ctx, filter)
+		if err != nil {
+			return err
+		}
+		return Write(w, format, rows)
+	case DatasetRiskScores:
+		rows, err := e.RiskScores(ctx, filter)
+		if err != nil {
+			return err
+		}
+		return Write(w, format, rows)
+	case DatasetExecutions:
+		rows, err := e.Executions(ctx, filter)
+		if err != nil {
+			return err
+		}
+		return Write(w, format, rows)
+	}
+	return fmt.Errorf("unknown dataset %s, must be one of %s", dataset, strings.Join(Datasets, ", "))
+}
+
+// YieldHistory returns the points of the yield history filter selects, by series and
+// then time
+func (e *Exporter) YieldHistory(ctx context.Context, filter Filter) ([]YieldRow, error) {
+	rows := []YieldRow{}
+	err := e.series(ctx, filter, func(metric string) bool { return metrics[metric] }, func(metric, protocol string, chainID uint64, point store.SeriesPoint) {
+		value := point.Value
+		if metric == "supply_rate" {
+			value *= 100
+		}
+		rows = append(rows, YieldRow{Time: point.Time.Unix(), Protocol: protocol, ChainID: chainID, Metric: metric, Value: value})
+	})
+	return rows, err
+}
+
+// RiskScores returns the risk scores filter selects, by market and then time
+func (e *Exporter) RiskScores(ctx context.Context, filter Filter) ([]RiskScoreRow, error) {
+	rows := []RiskScoreRow{}
+	err := e.series(ctx, filter, func(metric string) bool { return metric == "risk_score" }, func(metric, protocol string, chainID uint64, point store.SeriesPoint) {
+		rows = append(rows, RiskScoreRow{Time: point.Time.Unix(), Protocol: protocol, ChainID: chainID, Score: point.Value})
+	})
+	return rows, err
+}
+
+// series calls add with the points of every series of a metric selected by metric whose
+// market and time filter selects
+func (e *Exporter) series(ctx context.Context, filter Filter, metric func(string) bool, add func(metric, protocol string, chainID uint64, point store.SeriesPoint)) error {
+	if e.history == nil {
+		return nil
+	}
+	names, err := e.history.Series(ctx)
+	if err != nil {
+		return err
+	}
+	from, to := filter.From, filter.To
+	if from.IsZero() {
+		from = time.Unix(0, 0)
+	}
+	if to.IsZero() {
+		to = time.Unix(math.MaxInt64/int64(time.Second), 0)
+	}
+	for _, name := range names {
+		parts := strings.Split(name, "/")
+		if len(parts) != 3 || !metric(parts[0]) {
+			continue
+		}
+		chainID, err := strconv.ParseUint(parts[2], 10, 64)
+		if err != nil || !filter.venue(parts[1], chainID) {
+			continue
+		}
+		points, err := e.history.Range(ctx, name, from, to)
+		if err != nil {
+			return err
+		}
+		for _, point := range points {
+			add(parts[0], parts[1], chainID, point)
+		}
+	}
+	return nil
+}
+
+// Executions returns the ledger entries filter selects in the order they were executed.
+// Entries are selected by their time of execution and by their source or target market.
+func (e *Exporter) Executions(ctx context.Context, filter Filter) ([]ExecutionRow, error) {
+	rows := []ExecutionRow{}
+	if e.ledger == nil {
+		return rows, nil
+	}
+	entries, err := e.ledger.Entries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if !filter.within(entry.ExecutedAt) {
+			continue
+		}
+		if !filter.venue(entry.TargetProtocol, entry.TargetChain) && (entry.SourceProtocol == "" || !filter.venue(entry.SourceProtocol, entry.SourceChain)) {
+			continue
+		}
+		gas, priced := entry.GasUSDC()
+		rows = append(rows, ExecutionRow{
+			ExecutionID:    entry.ExecutionID,
+			ExecutedAt:     entry.ExecutedAt.Unix(),
+			Owner:          entry.Owner.Hex(),
+			SourceProtocol: entry.SourceProtocol,
+			SourceChain:    entry.SourceChain,
+			TargetProtocol: entry.TargetProtocol,
+			TargetChain:    entry.TargetChain,
+			Amount:         usdc(entry.Amount),
+			BridgeFee:      usdc(entry.BridgeFee),
+			GasUSDC:        usdc(gas),
+			GasPriced:      priced,
+			RateBefore:     entry.RateBefore * 100,
+			RateAfter:      entry.RateAfter * 100,
+		})
+	}
+	return rows, nil
+}
+
+// usdc converts USDC base units into whole USDC
+func usdc(baseUnits *big.Int) float64 {
+	if baseUnits == nil {
+		return 0
+	}
+	amount, _ := new(big.Rat).SetFrac(baseUnits, big.NewInt(1_000_000)).Float64()
+	return amount
+}
+
+func formatInt(v int64) string {
+	return strconv.FormatInt(v, 10)
+}
+
+func formatUint(v uint64) string {
+	return strconv.FormatUint(v, 10)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,161 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"math/big"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/ledger"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+	"github.com/parquet-go/parquet-go"
+)
+
+var start = time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+func testExporter(t *testing.T) *Exporter {
+	t.Helper()
+	ctx := context.Background()
+	kv := store.NewMemoryKV()
+	history := store.NewSeriesStore(kv)
+	for day := 0; day < 3; day++ {
+		at := start.Add(time.Duration(day) * 24 * time.Hour)
+		for _, point := range []struct {
+			series string
+			value  float64
+		}{
+			{store.SupplyRateSeries("aave_v3", 1), 0.05},
+			{store.UtilizationSeries("aave_v3", 1), 0.8},
+			{store.SupplyRateSeries("compound_v3", 8453), 0.04},
+			{store.RiskScoreSeries("aave_v3", 1), 22.5},
+		} {
+			if err := history.Append(ctx, point.series, store.SeriesPoint{Time: at, Value: point.value}); err != nil {
+				t.Fatalf("Append failed: %v", err)
+			}
+		}
+	}
+
+	l := ledger.New(ledger.Config{}, kv, nil)
+	for i, entry := range []ledger.Entry{
+		{ExecutionID: "a", SourceProtocol: "aave_v3", SourceChain: 1, TargetProtocol: "compound_v3", TargetChain: 8453, ExecutedAt: start},
+		{ExecutionID: "b", TargetProtocol: "aave_v3", TargetChain: 1, ExecutedAt: start.Add(48 * time.Hour)},
+	} {
+		entry.Owner = common.HexToAddress("0x01")
+		entry.Amount = big.NewInt(int64(i+1) * 1_000_000_000)
+		entry.BridgeFee = big.NewInt(500_000)
+		entry.Gas = []ledger.GasCost{{ChainID: 1, CostWei: big.NewInt(1), CostUSDC: big.NewInt(2_000_000)}, {ChainID: 8453, CostWei: big.NewInt(1)}}
+		entry.RateBefore, entry.RateAfter = 0.04, 0.05
+		if err := l.Record(ctx, entry); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+	return New(history, l)
+}
+
+func Test_YieldHistoryFilters(t *testing.T) {
+	e := testExporter(t)
+	rows, err := e.YieldHistory(context.Background(), Filter{})
+	if err != nil || len(rows) != 9 {
+		t.Fatalf("Expected every yield point, got %d: %v", len(rows), err)
+	}
+
+	rows, err = e.YieldHistory(context.Background(), Filter{
+		From:      start.Add(24 * time.Hour),
+		To:        start.Add(48 * time.Hour),
+		Protocols: []string{"AAVE_V3"},
+		ChainIDs:  []uint64{1},
+	})
+	if err != nil || len(rows) != 2 {
+		t.Fatalf("Expected the aave points of the second day, got %+v: %v", rows, err)
+	}
+	for _, row := range rows {
+		if row.Time != start.Add(24*time.Hour).Unix() || row.Protocol != "aave_v3" {
+			t.Errorf("Unexpected row %+v", row)
+		}
+		if row.Metric == "supply_rate" && row.Value != 5 {
+			t.Errorf("Expected the supply rate as a percentage, got %v", row.Value)
+		}
+	}
+
+	scores, err := e.RiskScores(context.Background(), Filter{ChainIDs: []uint64{8453}})
+	if err != nil || len(scores) != 0 {
+		t.Errorf("Expected no risk score on base, got %+v: %v", scores, err)
+	}
+	if scores, err = e.RiskScores(context.Background(), Filter{}); err != nil || len(scores) != 3 || scores[0].Score != 22.5 {
+		t.Errorf("Unexpected risk scores %+v: %v", scores, err)
+	}
+}
+
+func Test_ExecutionsFilters(t *testing.T) {
+	e := testExporter(t)
+	// entries are selected by their source or target market
+	rows, err := e.Executions(context.Background(), Filter{Protocols: []string{"aave_v3"}})
+	if err != nil || len(rows) != 2 {
+		t.Fatalf("Expected both executions, got %+v: %v", rows, err)
+	}
+	first := rows[0]
+	if first.ExecutionID != "a" || first.Amount != 1000 || first.BridgeFee != 0.5 || first.GasUSDC != 2 || first.GasPriced || first.RateAfter != 5 {
+		t.Errorf("Unexpected row %+v", first)
+	}
+
+	rows, err = e.Executions(context.Background(), Filter{From: start.Add(time.Hour), ChainIDs: []uint64{8453}})
+	if err != nil || len(rows) != 0 {
+		t.Errorf("Expected no execution, got %+v: %v", rows, err)
+	}
+}
+
+func Test_Export(t *testing.T) {
+	e := testExporter(t)
+	var buf bytes.Buffer
+	if err := e.Export(context.Background(), &buf, DatasetExecutions, FormatCSV, Filter{}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil || len(records) != 3 || records[0][0] != "execution_id" || records[2][0] != "b" {
+		t.Errorf("Unexpected CSV %v: %v", records, err)
+	}
+
+	buf.Reset()
+	if err := e.Export(context.Background(), &buf, DatasetRiskScores, FormatParquet, Filter{}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	rows, err := parquet.Read[RiskScoreRow](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil || len(rows) != 3 || rows[2].Time != start.Add(48*time.Hour).Unix() {
+		t.Errorf("Unexpected Parquet rows %+v: %v", rows, err)
+	}
+
+	if err := e.Export(context.Background(), &buf, "balances", FormatCSV, Filter{}); err == nil {
+		t.Errorf("Expected an unknown dataset to be refused")
+	}
+	if err := e.Export(context.Background(), &buf, DatasetExecutions, "json", Filter{}); err == nil {
+		t.Errorf("Expected an unknown format to be refused")
+	}
+}
+
+func Test_ParseFilter(t *testing.T) {
+	filter, err := ParseFilter(url.Values{"from": {"2026-03-01"}, "to": {"2026-03-02T12:00:00Z"}, "protocol": {"aave_v3, morpho"}, "chain_id": {"1,8453"}})
+	if err != nil {
+		t.Fatalf("ParseFilter failed: %v", err)
+	}
+	if !filter.From.Equal(start) || len(filter.Protocols) != 2 || filter.Protocols[1] != "morpho" || len(filter.ChainIDs) != 2 {
+		t.Errorf("Unexpected filter %+v", filter)
+	}
+	parsed, err := ParseFilter(filter.Query())
+	if err != nil || !parsed.To.Equal(filter.To) || parsed.ChainIDs[1] != 8453 {
+		t.Errorf("Expected the filter to survive its query, got %+v: %v", parsed, err)
+	}
+
+	for name, query := range map[string]url.Values{
+		"bad from":  {"from": {"yesterday"}},
+		"bad chain": {"chain_id": {"base"}},
+		"reversed":  {"from": {"2026-03-02"}, "to": {"2026-03-01"}},
+	} {
+		if _, err := ParseFilter(query); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+// Package features switches experimental task types and adapters on and off. Experimental
+// features are declared with a flag and ship disabled; operators enable them in the
+// config or through the admin API. Tasks needing a disabled feature are refused with an
+// error the aggregator routes on, so they reach operators who enabled it.
+package features
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrUnknownFeature is a flag name no experimental feature declares
+var ErrUnknownFeature = errors.New("unknown feature")
+
+// TaskType returns the name of the flag of an experimental task type
+func TaskType(taskType string) string {
+	return "task_type." + taskType
+}
+
+// Adapter returns the name of the flag of an experimental protocol adapter
+func Adapter(protocol string) string {
+	return "adapter." + strings.ToLower(strings.TrimSpace(protocol))
+}
+
+// Flag declares an experimental feature
+type Flag struct {
+	// Name is the name operators enable the feature by, see TaskType and Adapter
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// Config configures which experimental features are enabled
+type Config struct {
+	// Enabled lists the flags of the experimental features to enable, such as
+	// task_type.stress_scenario. Every experimental feature is disabled by default.
+	Enabled []string `yaml:"enabled"`
+}
+
+// DefaultConfig enables no experimental feature
+func DefaultConfig() Config {
+	return Config{}
+}
+
+// Validate checks that every flag is named once
+func (c Config) Validate() error {
+	seen := make(map[string]bool, len(c.Enabled))
+	for _, name := range c.Enabled {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("enabled: flag names must not be empty")
+		}
+		if seen[name] {
+			return fmt.Errorf("enabled: %s is listed twice", name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// State is a flag and whether its feature is enabled
+type State struct {
+	Flag
+	Enabled bool `json:"enabled"`
+}
+
+// Set holds the experimental features and which of them are enabled. Features without a
+// flag are not experimental and always enabled. A nil set enables everything.
+type Set struct {
+	mu      sync.RWMutex
+	flags   map[string]Flag
+	enabled map[string]bool
+}
+
+// New declares flags, enabling those cfg lists. Listing a flag that is not declared is
+// an error, so a misspelled name does not leave a feature silently disabled.
+func New(flags []Flag, cfg Config) (*Set, error) {
+	s := &Set{flags: make(map[string]Flag, len(flags)), enabled: make(map[string]bool)}
+	for _, flag := range flags {
+		if _, ok := s.flags[flag.Name]; ok {
+			return nil, fmt.Errorf("feature %s is declared twice", flag.Name)
+		}
+		s.flags[flag.Name] = flag
+	}
+	if err := s.Apply(cfg); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Apply enables the features cfg lists and disables every other one, replacing what was
+// switched through SetEnabled. Nothing changes when cfg names an unknown flag.
+func (s *Set) Apply(cfg Config) error {
+	enabled := make(map[string]bool, len(cfg.Enabled))
+	for _, name := range cfg.Enabled {
+		if _, ok := s.flags[name]; !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownFeature, name)
+		}
+		enabled[name] = true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.enabled = enabled
+	return nil
+}
+
+// Enabled reports whether the feature of a flag is enabled. Names no feature declares are
+// enabled, since only experimental features have flags.
+func (s *Set) Enabled(name string) bool {
+	if s == nil {
+		return true
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, ok := s.flags[name]; !ok {
+		return true
+	}
+	return s.enabled[name]
+}
+
+// SetEnabled enables or disables the feature of a flag until the next Apply
+func (s *Set) SetEnabled(name string, enabled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.flags[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownFeature, name)
+	}
+	if enabled {
+		s.enabled[name] = true
+	} else {
+		delete(s.enabled, name)
+	}
+	return nil
+}
+
+// States returns every flag and whether its feature is enabled, by name
+func (s *Set) States() []State {
+	if s == nil {
+		return []State{}
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	states := make([]State, 0, len(s.flags))
+	for name, flag := range s.flags {
+		states = append(states, State{Flag: flag, Enabled: s.enabled[name]})
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Name < states[j].Name })
+	return states
+}
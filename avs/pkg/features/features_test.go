@@ -0,0 +1,74 @@
+package features
+
+import (
+	"errors"
+	"testing"
+)
+
+var testFlags = []Flag{
+	{Name: TaskType("stress_scenario"), Description: "utilization shocks"},
+	{Name: Adapter("Euler_V2"), Description: "Euler v2 vaults"},
+}
+
+func Test_FlagsShipDisabled(t *testing.T) {
+	set, err := New(testFlags, DefaultConfig())
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if set.Enabled("task_type.stress_scenario") || set.Enabled("adapter.euler_v2") {
+		t.Errorf("Expected experimental features to be disabled by default")
+	}
+	if !set.Enabled(TaskType("yield_monitoring")) {
+		t.Errorf("Expected features without a flag to be enabled")
+	}
+	var none *Set
+	if !none.Enabled(TaskType("stress_scenario")) || len(none.States()) != 0 {
+		t.Errorf("Expected a nil set to enable everything")
+	}
+}
+
+func Test_ConfigAndSwitchesEnableFlags(t *testing.T) {
+	set, err := New(testFlags, Config{Enabled: []string{"adapter.euler_v2"}})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if !set.Enabled("adapter.euler_v2") {
+		t.Errorf("Expected the configured flag to be enabled")
+	}
+
+	if err := set.SetEnabled("task_type.stress_scenario", true); err != nil {
+		t.Fatalf("SetEnabled failed: %v", err)
+	}
+	states := set.States()
+	if len(states) != 2 || states[0].Name != "adapter.euler_v2" || !states[0].Enabled || !states[1].Enabled {
+		t.Errorf("Expected both flags enabled by name, got %+v", states)
+	}
+	if err := set.SetEnabled("task_type.balances", true); !errors.Is(err, ErrUnknownFeature) {
+		t.Errorf("Expected an unknown flag to be refused, got %v", err)
+	}
+
+	// applying a config replaces the switches
+	if err := set.Apply(DefaultConfig()); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if set.Enabled("adapter.euler_v2") || set.Enabled("task_type.stress_scenario") {
+		t.Errorf("Expected the applied config to disable every flag")
+	}
+	if err := set.Apply(Config{Enabled: []string{"adapter.euler"}}); !errors.Is(err, ErrUnknownFeature) {
+		t.Errorf("Expected a misspelled flag to be refused, got %v", err)
+	}
+}
+
+func Test_ConfigValidates(t *testing.T) {
+	for name, cfg := range map[string]Config{
+		"empty name": {Enabled: []string{" "}},
+		"duplicate":  {Enabled: []string{"adapter.euler_v2", "adapter.euler_v2"}},
+	} {
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("%s: expected the config to be refused", name)
+		}
+	}
+	if _, err := New(append(testFlags, testFlags[0]), DefaultConfig()); err == nil {
+		t.Errorf("Expected a flag declared twice to be refused")
+	}
+}
@@ -0,0 +1,181 @@
+// Package fixture records the HTTP exchanges of a performer with its RPC providers,
+// Circle and the other APIs it calls, and serves them back in simulation mode. Protocol
+// adapters read chains over JSON-RPC, so replaying those exchanges runs every handler
+// end to end without RPC keys or testnet funds.
+//
+// Exchanges are matched by method, URL and body. JSON-RPC request ids are left out of
+// the match and answered with the id of the request, since clients number their requests
+// differently from run to run. Configured URLs are replaced by aliases, such as chain-1,
+// so fixtures neither hold the API keys of RPC URLs nor depend on the endpoints they
+// were recorded from.
+package fixture
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Fixture modes
+const (
+	// ModeReplay serves every HTTP request from the fixture file and makes no calls
+	ModeReplay = "replay"
+
+	// ModeRecord makes calls as usual and writes their exchanges to the fixture file
+	ModeRecord = "record"
+)
+
+// Config configures fixtures. Without a mode, calls are made as usual.
+type Config struct {
+	Mode string `yaml:"mode"`
+	Path string `yaml:"path"`
+}
+
+// Validate checks the config for values fixtures cannot be used with
+func (c Config) Validate() error {
+	switch c.Mode {
+	case "":
+		return nil
+	case ModeReplay, ModeRecord:
+	default:
+		return fmt.Errorf("mode must be %s or %s", ModeReplay, ModeRecord)
+	}
+	if c.Path == "" {
+		return fmt.Errorf("path is required")
+	}
+	return nil
+}
+
+// Exchange is a recorded request and the response it got. Bodies are kept as JSON when
+// they are JSON, so fixture files can be read and edited by hand.
+type Exchange struct {
+	Method      string          `json:"method"`
+	URL         string          `json:"url"`
+	Request     json.RawMessage `json:"request,omitempty"`
+	Status      int             `json:"status"`
+	ContentType string          `json:"contentType,omitempty"`
+	Response    json.RawMessage `json:"response"`
+}
+
+// Set is the content of a fixture file
+type Set struct {
+	Exchanges []Exchange `json:"exchanges"`
+}
+
+// Load reads the fixture file at path
+func Load(path string) (*Set, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixtures: %w", err)
+	}
+	var set Set
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse fixtures %s: %w", path, err)
+	}
+	return &set, nil
+}
+
+// Save writes s to the fixture file at path
+func (s *Set) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode fixtures: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write fixtures: %w", err)
+	}
+	return nil
+}
+
+// Aliases replace URL prefixes with names in fixtures. The longest matching prefix wins.
+type Aliases map[string]string
+
+// apply returns url with its longest aliased prefix replaced
+func (a Aliases) apply(url string) string {
+	prefixes := make([]string, 0, len(a))
+	for prefix := range a {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+	for _, prefix := range prefixes {
+		if prefix != "" && strings.HasPrefix(url, prefix) {
+			return a[prefix] + strings.TrimPrefix(url, prefix)
+		}
+	}
+	return url
+}
+
+// encodeBody returns body as JSON: as it is when it is JSON, as a JSON string otherwise.
+// A body that is itself a JSON string reads back as text, which no API the performer
+// calls answers with.
+func encodeBody(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+	if json.Valid(body) {
+		return json.RawMessage(body)
+	}
+	encoded, _ := json.Marshal(string(body))
+	return encoded
+}
+
+// decodeBody reverses encodeBody
+func decodeBody(body json.RawMessage) []byte {
+	var text string
+	if json.Unmarshal(body, &text) == nil {
+		return []byte(text)
+	}
+	return body
+}
+
+// matchKey is what requests are matched on: the method, the aliased URL and the body
+// with keys sorted and without a JSON-RPC id
+func matchKey(method, url string, body []byte) string {
+	return method + " " + url + " " + normalizeBody(body)
+}
+
+func normalizeBody(body []byte) string {
+	var value interface{}
+	if len(body) == 0 || json.Unmarshal(body, &value) != nil {
+		return string(body)
+	}
+	if request, ok := value.(map[string]interface{}); ok {
+		if _, rpc := request["jsonrpc"]; rpc {
+			delete(request, "id")
+		}
+	}
+	// maps are marshalled with sorted keys
+	normalized, _ := json.Marshal(value)
+	return string(normalized)
+}
+
+// rpcID returns the id of a JSON-RPC request, nil for other bodies
+func rpcID(body []byte) json.RawMessage {
+	var request struct {
+		JSONRPC string          `json:"jsonrpc"`
+		ID      json.RawMessage `json:"id"`
+	}
+	if json.Unmarshal(body, &request) != nil || request.JSONRPC == "" {
+		return nil
+	}
+	return request.ID
+}
+
+// withRPCID returns the JSON-RPC response body with its id replaced by id
+func withRPCID(body []byte, id json.RawMessage) []byte {
+	var response map[string]json.RawMessage
+	if id == nil || json.Unmarshal(body, &response) != nil {
+		return body
+	}
+	if _, ok := response["jsonrpc"]; !ok {
+		return body
+	}
+	response["id"] = id
+	replaced, err := json.Marshal(response)
+	if err != nil {
+		return body
+	}
+	return replaced
+}
@@ -0,0 +1,114 @@
+package fixture
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func post(t *testing.T, client *http.Client, url, body string) (*http.Response, string) {
+	t.Helper()
+	resp, err := client.Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST %s failed: %v", url, err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp, string(data)
+}
+
+func Test_RecordAndReplay(t *testing.T) {
+	var blocks atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		w.Header().Set("Content-Type", "application/json")
+		switch request.Method {
+		case "eth_blockNumber":
+			block := 100 + blocks.Add(1)
+			_, _ = fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":"0x%x"}`, request.ID, block)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, "not found")
+		}
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "fixtures.json")
+	recorder := NewRecorder(http.DefaultTransport, Aliases{server.URL + "/rpc/secret-key": "chain-1"})
+	recording := &http.Client{Transport: recorder}
+	for id := 1; id <= 2; id++ {
+		post(t, recording, server.URL+"/rpc/secret-key", fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"eth_blockNumber","params":[]}`, id))
+	}
+	post(t, recording, server.URL+"/rpc/secret-key", `{"jsonrpc":"2.0","id":3,"method":"eth_unknown","params":[]}`)
+	if err := recorder.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	set, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(set.Exchanges) != 3 {
+		t.Fatalf("Expected 3 recorded exchanges, got %d", len(set.Exchanges))
+	}
+	for _, exchange := range set.Exchanges {
+		if strings.Contains(exchange.URL, "secret-key") {
+			t.Errorf("Expected the aliased URL to be recorded, got %s", exchange.URL)
+		}
+	}
+
+	// the replaying performer is configured with another endpoint and numbers requests anew
+	replaying := &http.Client{Transport: NewReplayer(set, Aliases{"http://simulated.invalid": "chain-1"})}
+	want := []string{
+		`{"id":41,"jsonrpc":"2.0","result":"0x65"}`,
+		`{"id":42,"jsonrpc":"2.0","result":"0x66"}`,
+		`{"id":43,"jsonrpc":"2.0","result":"0x66"}`,
+	}
+	for i, id := range []string{"41", "42", "43"} {
+		_, body := post(t, replaying, "http://simulated.invalid", `{"params":[],"method":"eth_blockNumber","id":`+id+`,"jsonrpc":"2.0"}`)
+		if body != want[i] {
+			t.Errorf("Expected replay %d to be %s, got %s", i, want[i], body)
+		}
+	}
+
+	resp, body := post(t, replaying, "http://simulated.invalid", `{"jsonrpc":"2.0","id":7,"method":"eth_unknown","params":[]}`)
+	if resp.StatusCode != http.StatusNotFound || body != "not found" {
+		t.Errorf("Expected the recorded error response, got %d %q", resp.StatusCode, body)
+	}
+
+	_, err = replaying.Post("http://simulated.invalid", "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":8,"method":"eth_chainId"}`))
+	if !errors.Is(err, ErrNoFixture) {
+		t.Errorf("Expected unrecorded requests to fail, got %v", err)
+	}
+}
+
+func Test_ConfigValidate(t *testing.T) {
+	testCases := map[string]struct {
+		cfg   Config
+		valid bool
+	}{
+		"disabled":            {Config{}, true},
+		"replay":              {Config{Mode: ModeReplay, Path: "fixtures.json"}, true},
+		"record":              {Config{Mode: ModeRecord, Path: "fixtures.json"}, true},
+		"unknown mode":        {Config{Mode: "mock", Path: "fixtures.json"}, false},
+		"replay without path": {Config{Mode: ModeReplay}, false},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if err := tc.cfg.Validate(); (err == nil) != tc.valid {
+				t.Errorf("Expected valid=%v, got %v", tc.valid, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package fixture
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// ErrNoFixture is returned for requests the fixtures have no exchange for
+var ErrNoFixture = errors.New("no fixture for request")
+
+// Replayer is an http.RoundTripper answering requests with recorded exchanges. Requests
+// matching several exchanges get them in recorded order, then the last one again.
+type Replayer struct {
+	aliases Aliases
+
+	mu        sync.Mutex
+	exchanges map[string][]Exchange
+	served    map[string]int
+}
+
+// NewReplayer creates a replayer serving the exchanges of set
+func NewReplayer(set *Set, aliases Aliases) *Replayer {
+	r := &Replayer{
+		aliases:   aliases,
+		exchanges: make(map[string][]Exchange),
+		served:    make(map[string]int),
+	}
+	for _, exchange := range set.Exchanges {
+		key := matchKey(exchange.Method, exchange.URL, decodeBody(exchange.Request))
+		r.exchanges[key] = append(r.exchanges[key], exchange)
+	}
+	return r
+}
+
+// RoundTrip answers req with the next matching exchange
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	url := r.aliases.apply(req.URL.String())
+	key := matchKey(req.Method, url, body)
+
+	r.mu.Lock()
+	exchanges := r.exchanges[key]
+	if len(exchanges) == 0 {
+		r.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s %s %s", ErrNoFixture, req.Method, url, truncate(body))
+	}
+	i := r.served[key]
+	if i < len(exchanges)-1 {
+		r.served[key] = i + 1
+	}
+	exchange := exchanges[i]
+	r.mu.Unlock()
+
+	responseBody := withRPCID(decodeBody(exchange.Response), rpcID(body))
+	header := make(http.Header)
+	if exchange.ContentType != "" {
+		header.Set("Content-Type", exchange.ContentType)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", exchange.Status, http.StatusText(exchange.Status)),
+		StatusCode:    exchange.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(responseBody)),
+		ContentLength: int64(len(responseBody)),
+		Request:       req,
+	}, nil
+}
+
+// Recorder is an http.RoundTripper passing requests to the next one and recording the
+// exchanges. Requests that fail without a response are not recorded.
+type Recorder struct {
+	next    http.RoundTripper
+	aliases Aliases
+
+	mu  sync.Mutex
+	set Set
+}
+
+// NewRecorder creates a recorder of the exchanges of next
+func NewRecorder(next http.RoundTripper, aliases Aliases) *Recorder {
+	return &Recorder{next: next, aliases: aliases}
+}
+
+// RoundTrip sends req through the next round tripper and records the exchange
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	responseBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(responseBody))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.set.Exchanges = append(r.set.Exchanges, Exchange{
+		Method:      req.Method,
+		URL:         r.aliases.apply(req.URL.String()),
+		Request:     encodeBody(body),
+		Status:      resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Response:    encodeBody(responseBody),
+	})
+	return resp, nil
+}
+
+// Save writes the exchanges recorded so far to the fixture file at path
+func (r *Recorder) Save(path string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.set.Save(path)
+}
+
+// readBody reads the body of req and puts it back, so it can still be sent
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+func truncate(body []byte) string {
+	const max = 200
+	if len(body) > max {
+		return string(body[:max]) + "..."
+	}
+	return string(body)
+}
@@ -0,0 +1,128 @@
+// Package flashloan builds atomic same-chain migrations through a flash loan helper
+// contract. In one transaction the helper borrows the amount moved, supplies it to the
+// target market on behalf of the owner, withdraws the owner's position from the source
+// market to itself and repays the loan, so funds never sit idle between the markets. The
+// owner pays the loan premium from its wallet, through an allowance to the helper, and
+// has granted the helper the allowance over its source position it needs to withdraw it
+// when the helper was set up.
+package flashloan
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+)
+
+// Strategies rebalances execute with
+const (
+	// StrategySequential withdraws from the source market, then deposits into the target
+	StrategySequential = "sequential"
+
+	// StrategyFlashLoan deposits into the target before withdrawing from the source,
+	// atomically, through the helper of the chain
+	StrategyFlashLoan = "flash_loan"
+)
+
+const maxBps = 10_000
+
+// Config configures flash loan migrations
+type Config struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Helpers are the helper contracts migrations are sent to, per chain id
+	Helpers map[uint64]string `yaml:"helpers"`
+
+	// PremiumBps is the premium of the flash loan the helpers take, in basis points of
+	// the amount borrowed
+	PremiumBps uint64 `yaml:"premiumBps"`
+}
+
+// DefaultConfig pays the 5 bps premium of Aave v3 flash loans. Disabled by default.
+func DefaultConfig() Config {
+	return Config{PremiumBps: 5}
+}
+
+// Validate checks the config for values migrations cannot be built with
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if len(c.Helpers) == 0 {
+		return fmt.Errorf("helpers must not be empty")
+	}
+	for chainID, helper := range c.Helpers {
+		if !common.IsHexAddress(helper) {
+			return fmt.Errorf("invalid helper address %q of chain %d", helper, chainID)
+		}
+	}
+	if c.PremiumBps >= maxBps {
+		return fmt.Errorf("premiumBps must be below %d", maxBps)
+	}
+	return nil
+}
+
+// Helper returns the helper contract of chainID
+func (c Config) Helper(chainID uint64) (common.Address, error) {
+	helper, ok := c.Helpers[chainID]
+	if !c.Enabled || !ok {
+		return common.Address{}, fmt.Errorf("no flash loan helper on chain %d", chainID)
+	}
+	return common.HexToAddress(helper), nil
+}
+
+// Premium returns the premium of borrowing amount, rounded up
+func (c Config) Premium(amount *big.Int) *big.Int {
+	premium := new(big.Int).Mul(amount, new(big.Int).SetUint64(c.PremiumBps))
+	premium.Add(premium, big.NewInt(maxBps-1))
+	return premium.Div(premium, big.NewInt(maxBps))
+}
+
+const helperABIJson = `[
+	{"name":"migrate","type":"function","stateMutability":"nonpayable",
+	 "inputs":[
+		{"name":"asset","type":"address"},
+		{"name":"amount","type":"uint256"},
+		{"name":"owner","type":"address"},
+		{"name":"withdrawTarget","type":"address"},
+		{"name":"withdrawData","type":"bytes"},
+		{"name":"supplyTarget","type":"address"},
+		{"name":"supplyData","type":"bytes"},
+		{"name":"maxPremium","type":"uint256"}
+	 ],
+	 "outputs":[]}
+]`
+
+var helperABI = chain.MustParseABI(helperABIJson)
+
+// Migration moves Amount of Asset held by Owner from the market Withdraw takes it out of
+// to the one Supply puts it in. Withdraw must send the funds to the helper, and Supply
+// credit them to Owner. MaxPremium bounds the premium the helper pulls from Owner.
+type Migration struct {
+	Helper     common.Address
+	Asset      common.Address
+	Amount     *big.Int
+	Owner      common.Address
+	Withdraw   *chain.Call
+	Supply     *chain.Call
+	MaxPremium *big.Int
+}
+
+// MigrateCall builds the helper call executing m
+func MigrateCall(m *Migration) (*chain.Call, error) {
+	data, err := helperABI.Pack("migrate",
+		m.Asset,
+		m.Amount,
+		m.Owner,
+		m.Withdraw.To,
+		m.Withdraw.Data,
+		m.Supply.To,
+		m.Supply.Data,
+		m.MaxPremium,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack migrate: %w", err)
+	}
+	return &chain.Call{To: m.Helper, Data: data}, nil
+}
@@ -0,0 +1,63 @@
+package flashloan
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+)
+
+func Test_MigrateCall(t *testing.T) {
+	helper := common.HexToAddress("0x40")
+	migration := &Migration{
+		Helper:     helper,
+		Asset:      common.HexToAddress("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48"),
+		Amount:     big.NewInt(1_000_000),
+		Owner:      common.HexToAddress("0xaa"),
+		Withdraw:   &chain.Call{To: common.HexToAddress("0x10"), Data: []byte{0x01}},
+		Supply:     &chain.Call{To: common.HexToAddress("0x20"), Data: []byte{0x02}},
+		MaxPremium: big.NewInt(500),
+	}
+	call, err := MigrateCall(migration)
+	if err != nil {
+		t.Fatalf("MigrateCall failed: %v", err)
+	}
+	if call.To != helper {
+		t.Errorf("Expected the migration to go to the helper, got %s", call.To.Hex())
+	}
+	args, err := helperABI.Methods["migrate"].Inputs.Unpack(call.Data[4:])
+	if err != nil {
+		t.Fatalf("Failed to unpack call: %v", err)
+	}
+	if args[3].(common.Address) != migration.Withdraw.To || !bytes.Equal(args[6].([]byte), []byte{0x02}) || args[7].(*big.Int).Int64() != 500 {
+		t.Errorf("Unexpected migration arguments %v", args)
+	}
+}
+
+func Test_Config(t *testing.T) {
+	cfg := DefaultConfig()
+	if _, err := cfg.Helper(1); err == nil {
+		t.Errorf("Expected no helper while disabled")
+	}
+	cfg.Enabled, cfg.Helpers = true, map[uint64]string{1: "0x0000000000000000000000000000000000000040"}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected the config to be valid: %v", err)
+	}
+	if helper, err := cfg.Helper(1); err != nil || helper != common.HexToAddress("0x40") {
+		t.Errorf("Expected the helper of Ethereum, got %s (%v)", helper.Hex(), err)
+	}
+	if _, err := cfg.Helper(8453); err == nil {
+		t.Errorf("Expected no helper on Base")
+	}
+	// premiums are rounded up
+	if premium := cfg.Premium(big.NewInt(1_000_001)); premium.Int64() != 501 {
+		t.Errorf("Expected a premium of 501, got %s", premium)
+	}
+
+	cfg.Helpers[8453] = "helper"
+	if err := cfg.Validate(); err == nil {
+		t.Errorf("Expected an invalid helper address to be rejected")
+	}
+}
@@ -0,0 +1,137 @@
+// Package forecast produces short horizon supply rate forecasts. Rates are modelled as a
+// mean reverting AR(1) process fitted on evenly resampled history, which captures rates
+// drifting back towards their long run level after large deposits or withdrawals.
+package forecast
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/najnomics/crosscow-avs/pkg/store"
+)
+
+// MinSamples is the minimum number of resampled observations needed for a fit
+const MinSamples = 24
+
+// maxPhi keeps fitted models strictly mean reverting
+const maxPhi = 0.999
+
+// ErrInsufficientHistory is returned when there are not enough observations to fit a model
+var ErrInsufficientHistory = errors.New("insufficient rate history")
+
+// Resample converts irregular points into values at a fixed step, carrying the last seen
+// value forward across gaps. Points must be ordered by time.
+func Resample(points []store.SeriesPoint, step time.Duration) []float64 {
+	if len(points) == 0 || step <= 0 {
+		return nil
+	}
+	start := points[0].Time.Truncate(step)
+	end := points[len(points)-1].Time.Truncate(step)
+
+	var values []float64
+	i := 0
+	last := points[0].Value
+	for t := start; !t.After(end); t = t.Add(step) {
+		for i < len(points) && points[i].Time.Before(t.Add(step)) {
+			last = points[i].Value
+			i++
+		}
+		values = append(values, last)
+	}
+	return values
+}
+
+// Model is a fitted AR(1) process x[t+1] - Mean = Phi * (x[t] - Mean) + e, e ~ N(0, Sigma^2)
+type Model struct {
+	Mean    float64
+	Phi     float64
+	Sigma   float64
+	Step    time.Duration
+	Samples int
+}
+
+// Fit estimates an AR(1) model by least squares on values sampled every step
+func Fit(values []float64, step time.Duration) (*Model, error) {
+	if len(values) < MinSamples {
+		return nil, ErrInsufficientHistory
+	}
+
+	n := float64(len(values) - 1)
+	var sumX, sumY float64
+	for i := 0; i < len(values)-1; i++ {
+		sumX += values[i]
+		sumY += values[i+1]
+	}
+	meanX, meanY := sumX/n, sumY/n
+
+	var cov, varX float64
+	for i := 0; i < len(values)-1; i++ {
+		dx := values[i] - meanX
+		cov += dx * (values[i+1] - meanY)
+		varX += dx * dx
+	}
+
+	phi := 0.0
+	if varX > 0 {
+		phi = cov / varX
+	}
+	phi = math.Max(0, math.Min(maxPhi, phi))
+
+	// long run mean implied by the regression intercept
+	intercept := meanY - phi*meanX
+	mean := intercept / (1 - phi)
+
+	var sse float64
+	for i := 0; i < len(values)-1; i++ {
+		residual := values[i+1] - (intercept + phi*values[i])
+		sse += residual * residual
+	}
+
+	return &Model{
+		Mean:    mean,
+		Phi:     phi,
+		Sigma:   math.Sqrt(sse / n),
+		Step:    step,
+		Samples: len(values),
+	}, nil
+}
+
+// Prediction is the forecast distribution at one horizon
+type Prediction struct {
+	Horizon  time.Duration
+	Expected float64
+	Lower    float64
+	Upper    float64
+}
+
+// Predict forecasts the value horizon ahead of current, with a two sided interval of z
+// standard deviations (1.96 for 95%). Lower bounds are floored at zero since supply rates
+// cannot be negative.
+func (m *Model) Predict(current float64, horizon time.Duration, z float64) Prediction {
+	steps := float64(horizon) / float64(m.Step)
+	decay := math.Pow(m.Phi, steps)
+	expected := m.Mean + decay*(current-m.Mean)
+
+	var variance float64
+	if m.Phi < 1 {
+		variance = m.Sigma * m.Sigma * (1 - decay*decay) / (1 - m.Phi*m.Phi)
+	}
+	width := z * math.Sqrt(variance)
+
+	return Prediction{
+		Horizon:  horizon,
+		Expected: expected,
+		Lower:    math.Max(0, expected-width),
+		Upper:    expected + width,
+	}
+}
+
+// HalfLife returns the time for a deviation from the mean to halve, or 0 when the model
+// reverts immediately
+func (m *Model) HalfLife() time.Duration {
+	if m.Phi <= 0 {
+		return 0
+	}
+	return time.Duration(math.Log(0.5) / math.Log(m.Phi) * float64(m.Step))
+}
@@ -0,0 +1,69 @@
+package forecast
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/najnomics/crosscow-avs/pkg/store"
+)
+
+func Test_Resample(t *testing.T) {
+	base := time.Unix(1_700_000_000, 0).Truncate(time.Hour)
+	points := []store.SeriesPoint{
+		{Time: base, Value: 1},
+		{Time: base.Add(10 * time.Minute), Value: 2},
+		{Time: base.Add(3 * time.Hour), Value: 5},
+	}
+	got := Resample(points, time.Hour)
+	want := []float64{2, 2, 2, 5}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Expected %v, got %v", want, got)
+			break
+		}
+	}
+}
+
+func Test_FitRecoversMeanReversion(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	const mean, phi, sigma = 0.05, 0.9, 0.001
+
+	values := make([]float64, 2000)
+	values[0] = 0.08
+	for i := 1; i < len(values); i++ {
+		values[i] = mean + phi*(values[i-1]-mean) + sigma*rng.NormFloat64()
+	}
+
+	m, err := Fit(values, time.Hour)
+	if err != nil {
+		t.Fatalf("Fit failed: %v", err)
+	}
+	if math.Abs(m.Phi-phi) > 0.03 || math.Abs(m.Mean-mean) > 0.002 || math.Abs(m.Sigma-sigma) > 0.0002 {
+		t.Errorf("Fit did not recover parameters: %+v", m)
+	}
+
+	// After a deposit pushed the rate below its mean, the forecast reverts upwards
+	short := m.Predict(0.03, time.Hour, 1.96)
+	long := m.Predict(0.03, 7*24*time.Hour, 1.96)
+	if !(short.Expected < long.Expected && math.Abs(long.Expected-m.Mean) < 0.001) {
+		t.Errorf("Expected forecast to revert to the mean: short %+v long %+v", short, long)
+	}
+	if long.Upper-long.Lower <= short.Upper-short.Lower {
+		t.Errorf("Expected longer horizons to have wider intervals")
+	}
+	if hl := m.HalfLife(); hl < 5*time.Hour || hl > 8*time.Hour {
+		t.Errorf("Expected a half life of ~6.6h, got %s", hl)
+	}
+}
+
+func Test_FitRequiresHistory(t *testing.T) {
+	if _, err := Fit(make([]float64, MinSamples-1), time.Hour); !errors.Is(err, ErrInsufficientHistory) {
+		t.Errorf("Expected ErrInsufficientHistory, got %v", err)
+	}
+}
@@ -0,0 +1,257 @@
+// Package gaswindow times rebalances that are not urgent into low gas windows. Before
+// their first transaction is sent, they wait while the base fee of its chain is above a
+// ceiling, or while it is falling, since the next blocks are then likely cheaper, up to a
+// maximum delay. Trends compare the base fees of the newest half of the latest blocks
+// with those of the older half.
+package gaswindow
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+)
+
+// trendPercent is how far the newest blocks' base fees must move from the older ones'
+// before their trend counts as rising or falling
+const trendPercent = 2
+
+// Trend is the direction base fees move in
+type Trend string
+
+const (
+	TrendFalling Trend = "falling"
+	TrendFlat    Trend = "flat"
+	TrendRising  Trend = "rising"
+)
+
+// Config configures timing rebalances into low gas windows
+type Config struct {
+	Enabled bool `yaml:"enabled"`
+
+	// MaxBaseFeeGwei is the base fee below which rebalances are sent, unless it is
+	// falling. ChainMaxBaseFeeGwei overrides it per chain id. Zero sets no ceiling, so
+	// rebalances only wait out falling base fees.
+	MaxBaseFeeGwei      float64            `yaml:"maxBaseFeeGwei"`
+	ChainMaxBaseFeeGwei map[uint64]float64 `yaml:"chainMaxBaseFeeGwei"`
+
+	// MaxDelay bounds how long a rebalance waits. It is sent once the delay is over,
+	// whatever the base fee.
+	MaxDelay time.Duration `yaml:"maxDelay"`
+
+	// PollInterval is how often base fees are read while waiting
+	PollInterval time.Duration `yaml:"pollInterval"`
+
+	// TrendBlocks is the number of latest blocks whose base fees tell the trend
+	TrendBlocks int `yaml:"trendBlocks"`
+}
+
+// DefaultConfig waits out falling base fees for up to 10 minutes once enabled, reading
+// them every 12 seconds
+func DefaultConfig() Config {
+	return Config{MaxDelay: 10 * time.Minute, PollInterval: 12 * time.Second, TrendBlocks: 10}
+}
+
+// Validate checks the config for values rebalances cannot be timed with
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.MaxBaseFeeGwei < 0 {
+		return fmt.Errorf("maxBaseFeeGwei must not be negative")
+	}
+	for chainID, ceiling := range c.ChainMaxBaseFeeGwei {
+		if ceiling < 0 {
+			return fmt.Errorf("chainMaxBaseFeeGwei[%d] must not be negative", chainID)
+		}
+	}
+	if c.MaxDelay <= 0 || c.PollInterval <= 0 {
+		return fmt.Errorf("maxDelay and pollInterval must be positive")
+	}
+	if c.TrendBlocks < 2 {
+		return fmt.Errorf("trendBlocks must be at least 2")
+	}
+	return nil
+}
+
+// Ceiling returns the base fee ceiling of chainID in wei, nil without one
+func (c Config) Ceiling(chainID uint64) *big.Int {
+	ceiling := c.MaxBaseFeeGwei
+	if override, ok := c.ChainMaxBaseFeeGwei[chainID]; ok {
+		ceiling = override
+	}
+	if ceiling <= 0 {
+		return nil
+	}
+	wei, _ := new(big.Float).Mul(big.NewFloat(ceiling), big.NewFloat(params.GWei)).Int(nil)
+	return wei
+}
+
+// Sample is the base fee of the latest block of a chain and its trend. Change is the
+// ratio of the mean base fee of the newest blocks to that of the older ones.
+type Sample struct {
+	Block   uint64
+	BaseFee *big.Int
+	Trend   Trend
+	Change  float64
+}
+
+// Window is how a rebalance was timed on a chain
+type Window struct {
+	ChainID uint64
+
+	// Ceiling is the base fee ceiling of the chain in wei, nil without one
+	Ceiling *big.Int
+
+	// Requested is the base fee when the rebalance asked to be sent, Released when it was
+	Requested Sample
+	Released  Sample
+
+	// Expected is the base fee the rebalance waited for: the ceiling when above it, and
+	// the base fee moved on by its trend once more when falling
+	Expected *big.Int
+
+	Delayed bool
+	Waited  time.Duration
+
+	// Expired is set when the rebalance was sent at the end of its delay rather than in a
+	// low gas window
+	Expired bool
+}
+
+// Scheduler times rebalances
+type Scheduler struct {
+	cfg    Config
+	chains *chain.Manager
+}
+
+// New creates a scheduler reading base fees from chains
+func New(cfg Config, chains *chain.Manager) *Scheduler {
+	return &Scheduler{cfg: cfg, chains: chains}
+}
+
+// NewFromConfig creates a scheduler, nil when cfg disables timing
+func NewFromConfig(cfg Config, chains *chain.Manager) *Scheduler {
+	if !cfg.Enabled {
+		return nil
+	}
+	return New(cfg, chains)
+}
+
+// Wait returns once chainID is in a low gas window or the maximum delay is over,
+// whichever comes first. It fails when the base fee cannot be read at first or ctx is done
+// while waiting; other read failures while waiting end the wait.
+func (s *Scheduler) Wait(ctx context.Context, chainID uint64) (*Window, error) {
+	requested, err := s.Sample(ctx, chainID)
+	if err != nil {
+		return nil, err
+	}
+	window := &Window{ChainID: chainID, Ceiling: s.cfg.Ceiling(chainID), Requested: requested, Released: requested}
+	window.Expected = expected(requested, window.Ceiling)
+	if s.open(requested, window.Ceiling) {
+		return window, nil
+	}
+
+	window.Delayed = true
+	started := time.Now()
+	timer := time.NewTimer(s.cfg.MaxDelay)
+	defer timer.Stop()
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+			window.Expired = true
+		case <-ticker.C:
+			sample, err := s.Sample(ctx, chainID)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				window.Expired = true
+				break
+			}
+			window.Released = sample
+			if !s.open(sample, window.Ceiling) {
+				continue
+			}
+		}
+		window.Waited = time.Since(started)
+		return window, nil
+	}
+}
+
+// open reports whether sample is a low gas window: at most the ceiling, and not falling
+func (s *Scheduler) open(sample Sample, ceiling *big.Int) bool {
+	return (ceiling == nil || sample.BaseFee.Cmp(ceiling) <= 0) && sample.Trend != TrendFalling
+}
+
+// expected is the base fee a rebalance requested at sample waits for
+func expected(sample Sample, ceiling *big.Int) *big.Int {
+	if ceiling != nil && sample.BaseFee.Cmp(ceiling) > 0 {
+		return new(big.Int).Set(ceiling)
+	}
+	if sample.Trend == TrendFalling {
+		fee, _ := new(big.Float).Mul(new(big.Float).SetInt(sample.BaseFee), big.NewFloat(sample.Change)).Int(nil)
+		return fee
+	}
+	return new(big.Int).Set(sample.BaseFee)
+}
+
+// Sample reads the base fees of the latest blocks of chainID
+func (s *Scheduler) Sample(ctx context.Context, chainID uint64) (Sample, error) {
+	client, err := s.chains.Client(chainID)
+	if err != nil {
+		return Sample{}, err
+	}
+	head, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return Sample{}, fmt.Errorf("failed to read the base fee of chain %d: %w", chainID, err)
+	}
+	if head.BaseFee == nil {
+		return Sample{}, fmt.Errorf("chain %d does not support EIP-1559 fees", chainID)
+	}
+	sample := Sample{Block: head.Number.Uint64(), BaseFee: head.BaseFee, Trend: TrendFlat, Change: 1}
+
+	// base fees of the latest blocks, newest first
+	fees := []*big.Int{head.BaseFee}
+	for i := uint64(1); i < uint64(s.cfg.TrendBlocks) && i <= sample.Block; i++ {
+		header, err := client.HeaderByNumber(ctx, new(big.Int).SetUint64(sample.Block-i))
+		if err != nil {
+			return Sample{}, fmt.Errorf("failed to read block %d of chain %d: %w", sample.Block-i, chainID, err)
+		}
+		if header.BaseFee == nil {
+			break
+		}
+		fees = append(fees, header.BaseFee)
+	}
+	if len(fees) < 2 {
+		return sample, nil
+	}
+	half := len(fees) / 2
+	newer, older := mean(fees[:half]), mean(fees[len(fees)-half:])
+	if older.Sign() == 0 {
+		return sample, nil
+	}
+	sample.Change, _ = new(big.Rat).SetFrac(newer, older).Float64()
+	switch {
+	case sample.Change < 1-trendPercent/100.0:
+		sample.Trend = TrendFalling
+	case sample.Change > 1+trendPercent/100.0:
+		sample.Trend = TrendRising
+	}
+	return sample, nil
+}
+
+func mean(fees []*big.Int) *big.Int {
+	sum := new(big.Int)
+	for _, fee := range fees {
+		sum.Add(sum, fee)
+	}
+	return sum.Div(sum, big.NewInt(int64(len(fees))))
+}
@@ -0,0 +1,134 @@
+package gaswindow
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/chain/chaintest"
+)
+
+func gwei(n int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(n), big.NewInt(1e9))
+}
+
+// newChain serves blocks 91 to 100 with base fees, oldest first
+func newChain(fees ...int64) (*chain.Manager, *chaintest.Contracts) {
+	contracts := chaintest.NewContracts(1)
+	for i, fee := range fees[:len(fees)-1] {
+		contracts.SetBlockBaseFee(uint64(100-len(fees)+1+i), gwei(fee))
+	}
+	contracts.SetBaseFee(gwei(fees[len(fees)-1]))
+	chains := chain.NewManager()
+	chains.Register(1, "ethereum", contracts)
+	return chains, contracts
+}
+
+func testConfig() Config {
+	cfg := DefaultConfig()
+	cfg.Enabled, cfg.MaxBaseFeeGwei = true, 20
+	cfg.MaxDelay, cfg.PollInterval, cfg.TrendBlocks = 200*time.Millisecond, 5*time.Millisecond, 4
+	return cfg
+}
+
+func Test_Sample(t *testing.T) {
+	for name, tc := range map[string]struct {
+		fees  []int64
+		trend Trend
+	}{
+		"falling": {fees: []int64{40, 36, 30, 26}, trend: TrendFalling},
+		"rising":  {fees: []int64{10, 12, 14, 16}, trend: TrendRising},
+		"flat":    {fees: []int64{10, 10, 10, 10}, trend: TrendFlat},
+		// blocks before the London fork have no base fee
+		"pre-london": {fees: []int64{10}, trend: TrendFlat},
+	} {
+		chains, _ := newChain(tc.fees...)
+		sample, err := New(testConfig(), chains).Sample(context.Background(), 1)
+		if err != nil {
+			t.Fatalf("%s: Sample failed: %v", name, err)
+		}
+		if sample.Trend != tc.trend || sample.Block != 100 || sample.BaseFee.Cmp(gwei(tc.fees[len(tc.fees)-1])) != 0 {
+			t.Errorf("%s: expected a %s trend, got %+v", name, tc.trend, sample)
+		}
+	}
+}
+
+func Test_WaitSendsInLowGasWindows(t *testing.T) {
+	chains, _ := newChain(10, 10, 10, 10)
+	window, err := New(testConfig(), chains).Wait(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+	if window.Delayed || window.Expected.Cmp(gwei(10)) != 0 {
+		t.Errorf("Expected a cheap steady base fee not to delay, got %+v", window)
+	}
+}
+
+func Test_WaitForCeiling(t *testing.T) {
+	chains, contracts := newChain(30, 30, 30, 30)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		for block := uint64(97); block < 100; block++ {
+			contracts.SetBlockBaseFee(block, gwei(15))
+		}
+		contracts.SetBaseFee(gwei(15))
+	}()
+	window, err := New(testConfig(), chains).Wait(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+	if !window.Delayed || window.Expired || window.Released.BaseFee.Cmp(gwei(15)) != 0 || window.Expected.Cmp(gwei(20)) != 0 {
+		t.Errorf("Expected the rebalance to wait for the base fee to fall under the ceiling, got %+v", window)
+	}
+}
+
+func Test_WaitExpires(t *testing.T) {
+	chains, _ := newChain(30, 30, 30, 30)
+	started := time.Now()
+	window, err := New(testConfig(), chains).Wait(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+	if !window.Delayed || !window.Expired || time.Since(started) < 200*time.Millisecond {
+		t.Errorf("Expected the rebalance to be sent once the delay is over, got %+v", window)
+	}
+
+	if _, err := New(testConfig(), chains).Wait(context.Background(), 8453); err == nil {
+		t.Errorf("Expected an unknown chain to fail")
+	}
+}
+
+func Test_WaitAbortsWithItsContext(t *testing.T) {
+	chains, _ := newChain(30, 30, 30, 30)
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	started := time.Now()
+	window, err := New(testConfig(), chains).Wait(ctx, 1)
+	if !errors.Is(err, context.Canceled) || window != nil {
+		t.Errorf("Expected a cancelled wait to fail with its context, got %+v (%v)", window, err)
+	}
+	if time.Since(started) >= 200*time.Millisecond {
+		t.Errorf("Expected a cancelled task not to wait out the delay")
+	}
+}
+
+func Test_ConfigValidate(t *testing.T) {
+	cfg := testConfig()
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected the config to be valid: %v", err)
+	}
+	cfg.ChainMaxBaseFeeGwei = map[uint64]float64{8453: 0.1}
+	if ceiling := cfg.Ceiling(8453); ceiling.Cmp(big.NewInt(1e8)) != 0 {
+		t.Errorf("Expected the ceiling of Base to be overridden, got %s", ceiling)
+	}
+	cfg.TrendBlocks = 1
+	if err := cfg.Validate(); err == nil {
+		t.Errorf("Expected a single trend block to be rejected")
+	}
+}
@@ -0,0 +1,206 @@
+// Package grpcserver serves the Ponos performer gRPC API, as the server of the Ponos SDK
+// does, with TLS and a bearer token when configured, so a performer exposed beyond
+// localhost only accepts tasks from the executors it trusts.
+//
+// Callers present the token as "authorization: Bearer <token>" metadata. The health
+// service is exempt from it, so probes need no credentials, though with mutual TLS they
+// still need a client certificate.
+package grpcserver
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/Layr-Labs/hourglass-monorepo/ponos/pkg/performer/worker"
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	healthV1 "github.com/Layr-Labs/protocol-apis/gen/protos/grpc/health/v1"
+	grpc_zap "github.com/grpc-ecosystem/go-grpc-middleware/logging/zap"
+	grpc_ctxtags "github.com/grpc-ecosystem/go-grpc-middleware/tags"
+	"github.com/najnomics/crosscow-avs/pkg/transport"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+// Config secures the gRPC server
+type Config struct {
+	TLS transport.TLSConfig `yaml:"tls"`
+
+	// Token is the bearer token calls must carry, such as ${secret:grpc-token}. Empty
+	// accepts calls without one.
+	Token string `yaml:"token"`
+}
+
+// Validate checks the config for values the server cannot be secured with
+func (c Config) Validate() error {
+	if err := c.TLS.Validate(); err != nil {
+		return fmt.Errorf("tls: %w", err)
+	}
+	return nil
+}
+
+// replaceGrpcLogger routes the logs of gRPC itself to the logger of the first server. gRPC
+// reads its logger without locking, so it is only set once per process.
+var replaceGrpcLogger sync.Once
+
+// Server serves the performer and health services of the Ponos performer API
+type Server struct {
+	grpcServer *grpc.Server
+	listener   net.Listener
+	logger     *zap.Logger
+}
+
+// New creates a server handing the tasks it receives on port to w. It listens as it is
+// created, so the port is taken before Start serves it.
+func New(port int, cfg Config, w worker.IWorker, logger *zap.Logger) (*Server, error) {
+	tlsConfig, err := transport.ServerConfig(cfg.TLS)
+	if err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen: %w", err)
+	}
+	return newServer(listener, tlsConfig, cfg.Token, w, logger), nil
+}
+
+// NewWithListener creates a server handing the tasks it receives on listener to w, which
+// it closes once stopped
+func NewWithListener(listener net.Listener, cfg Config, w worker.IWorker, logger *zap.Logger) (*Server, error) {
+	tlsConfig, err := transport.ServerConfig(cfg.TLS)
+	if err != nil {
+		return nil, err
+	}
+	return newServer(listener, tlsConfig, cfg.Token, w, logger), nil
+}
+
+func newServer(listener net.Listener, tlsConfig *tls.Config, token string, w worker.IWorker, logger *zap.Logger) *Server {
+	replaceGrpcLogger.Do(func() {
+		grpc_zap.ReplaceGrpcLoggerV2(logger.WithOptions(zap.IncreaseLevel(zap.WarnLevel)))
+	})
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(
+			grpc_ctxtags.UnaryServerInterceptor(grpc_ctxtags.WithFieldExtractor(grpc_ctxtags.CodeGenRequestFieldExtractor)),
+			grpc_zap.UnaryServerInterceptor(logger),
+			unaryToken(token),
+		),
+		grpc.ChainStreamInterceptor(streamToken(token)),
+	}
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	grpcServer := grpc.NewServer(opts...)
+	performerV1.RegisterPerformerServiceServer(grpcServer, &performerService{worker: w, logger: logger})
+	healthV1.RegisterHealthServer(grpcServer, healthService{})
+	reflection.Register(grpcServer)
+	return &Server{grpcServer: grpcServer, listener: listener, logger: logger}
+}
+
+// Start serves the API until ctx is cancelled, then stops gracefully
+func (s *Server) Start(ctx context.Context) error {
+	errCh := make(chan error, 1)
+	go func() { errCh <- s.grpcServer.Serve(s.listener) }()
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("gRPC server stopped: %w", err)
+	case <-ctx.Done():
+	}
+	s.grpcServer.GracefulStop()
+	return nil
+}
+
+// Addr returns the address the server listens on
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// performerService hands tasks to the worker, as the Ponos server does
+type performerService struct {
+	performerV1.UnimplementedPerformerServiceServer
+	worker worker.IWorker
+	logger *zap.Logger
+}
+
+func (p *performerService) ExecuteTask(ctx context.Context, task *performerV1.TaskRequest) (*performerV1.TaskResponse, error) {
+	if err := p.worker.ValidateTask(task); err != nil {
+		p.logger.Sugar().Errorw("task is invalid", "taskId", string(task.TaskId), "error", err)
+		return nil, status.Errorf(codes.Internal, "task is invalid: %s", err.Error())
+	}
+	res, err := p.worker.HandleTask(task)
+	if err != nil {
+		p.logger.Sugar().Errorw("Failed to handle task", "taskId", string(task.TaskId), "error", err)
+		return nil, status.Errorf(codes.Internal, "Failed to handle task: %s", err.Error())
+	}
+	return &performerV1.TaskResponse{TaskId: task.TaskId, Result: res.Result}, nil
+}
+
+func (p *performerService) StartSync(ctx context.Context, request *performerV1.StartSyncRequest) (*performerV1.StartSyncResponse, error) {
+	return &performerV1.StartSyncResponse{}, nil
+}
+
+// healthService reports the server as serving while it runs
+type healthService struct {
+	healthV1.UnimplementedHealthServer
+}
+
+func (healthService) Check(ctx context.Context, request *healthV1.HealthCheckRequest) (*healthV1.HealthCheckResponse, error) {
+	return &healthV1.HealthCheckResponse{Status: healthV1.HealthCheckResponse_SERVING}, nil
+}
+
+var errUnauthenticated = status.Error(codes.Unauthenticated, "missing or invalid bearer token")
+
+// authorized reports whether a call of method with ctx may proceed under token
+func authorized(ctx context.Context, token, method string) bool {
+	if token == "" || strings.HasPrefix(method, "/"+healthV1.Health_ServiceDesc.ServiceName+"/") {
+		return true
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("authorization") {
+		if presented, ok := strings.CutPrefix(value, "Bearer "); ok && subtle.ConstantTimeCompare([]byte(presented), []byte(token)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+func unaryToken(token string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !authorized(ctx, token, info.FullMethod) {
+			return nil, errUnauthenticated
+		}
+		return handler(ctx, req)
+	}
+}
+
+func streamToken(token string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !authorized(ss.Context(), token, info.FullMethod) {
+			return errUnauthenticated
+		}
+		return handler(srv, ss)
+	}
+}
+
+// TokenCredentials sends token as the bearer token of every call of a client
+type TokenCredentials string
+
+func (t TokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+// RequireTransportSecurity allows tokens over plaintext connections, for performers
+// served on localhost
+func (t TokenCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+var _ credentials.PerRPCCredentials = TokenCredentials("")
@@ -0,0 +1,112 @@
+package grpcserver
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	healthV1 "github.com/Layr-Labs/protocol-apis/gen/protos/grpc/health/v1"
+	"github.com/najnomics/crosscow-avs/pkg/transport"
+	"github.com/najnomics/crosscow-avs/pkg/transport/transporttest"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// echoWorker answers every task with its payload
+type echoWorker struct{}
+
+func (echoWorker) ValidateTask(task *performerV1.TaskRequest) error { return nil }
+
+func (echoWorker) HandleTask(task *performerV1.TaskRequest) (*performerV1.TaskResponse, error) {
+	return &performerV1.TaskResponse{TaskId: task.TaskId, Result: task.Payload}, nil
+}
+
+// serve starts a server secured by cfg on a local port, and returns its address
+func serve(t *testing.T, cfg Config) string {
+	t.Helper()
+	tlsConfig, err := transport.ServerConfig(cfg.TLS)
+	if err != nil {
+		t.Fatalf("ServerConfig failed: %v", err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := newServer(listener, tlsConfig, cfg.Token, echoWorker{}, zap.NewNop())
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = server.Start(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return server.Addr().String()
+}
+
+// call sends a task and a health check over a connection dialed with opts, and returns
+// their status codes
+func call(t *testing.T, addr string, opts ...grpc.DialOption) (task, health codes.Code) {
+	t.Helper()
+	conn, err := grpc.NewClient(addr, opts...)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = performerV1.NewPerformerServiceClient(conn).ExecuteTask(ctx, &performerV1.TaskRequest{TaskId: []byte("task"), Payload: []byte("{}")})
+	task = status.Code(err)
+	_, err = healthV1.NewHealthClient(conn).Check(ctx, &healthV1.HealthCheckRequest{})
+	return task, status.Code(err)
+}
+
+func Test_ServerRequiresToken(t *testing.T) {
+	addr := serve(t, Config{Token: "secret"})
+	plaintext := grpc.WithTransportCredentials(insecure.NewCredentials())
+
+	for _, tc := range []struct {
+		name  string
+		opts  []grpc.DialOption
+		task  codes.Code
+		probe codes.Code
+	}{
+		{name: "no token", opts: []grpc.DialOption{plaintext}, task: codes.Unauthenticated, probe: codes.OK},
+		{name: "wrong token", opts: []grpc.DialOption{plaintext, grpc.WithPerRPCCredentials(TokenCredentials("other"))}, task: codes.Unauthenticated, probe: codes.OK},
+		{name: "token", opts: []grpc.DialOption{plaintext, grpc.WithPerRPCCredentials(TokenCredentials("secret"))}, task: codes.OK, probe: codes.OK},
+	} {
+		if task, probe := call(t, addr, tc.opts...); task != tc.task || probe != tc.probe {
+			t.Errorf("%s: expected task %v and health %v, got %v and %v", tc.name, tc.task, tc.probe, task, probe)
+		}
+	}
+}
+
+func Test_ServerRequiresClientCertificate(t *testing.T) {
+	ca := transporttest.NewCA(t)
+	certFile, keyFile := ca.Server("performer")
+	addr := serve(t, Config{TLS: transport.TLSConfig{Enabled: true, CertFile: certFile, KeyFile: keyFile, ClientCAFile: ca.CertFile}})
+
+	anonymous, err := transport.ClientConfig(ca.CertFile, "", "")
+	if err != nil {
+		t.Fatalf("ClientConfig failed: %v", err)
+	}
+	if task, probe := call(t, addr, grpc.WithTransportCredentials(credentials.NewTLS(anonymous))); task != codes.Unavailable || probe != codes.Unavailable {
+		t.Errorf("Expected a client without a certificate to be refused, got %v and %v", task, probe)
+	}
+	clientCert, clientKey := ca.Client("executor")
+	executor, err := transport.ClientConfig(ca.CertFile, clientCert, clientKey)
+	if err != nil {
+		t.Fatalf("ClientConfig failed: %v", err)
+	}
+	if task, probe := call(t, addr, grpc.WithTransportCredentials(credentials.NewTLS(executor))); task != codes.OK || probe != codes.OK {
+		t.Errorf("Expected the executor to be served, got %v and %v", task, probe)
+	}
+}
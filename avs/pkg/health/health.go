@@ -0,0 +1,176 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Checker reports whether a single dependency of the performer is usable
+type Checker interface {
+	Name() string
+	Check(ctx context.Context) error
+}
+
+type checkFunc struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+func (c *checkFunc) Name() string                    { return c.name }
+func (c *checkFunc) Check(ctx context.Context) error { return c.fn(ctx) }
+
+// NewChecker wraps fn as a named Checker
+func NewChecker(name string, fn func(ctx context.Context) error) Checker {
+	return &checkFunc{name: name, fn: fn}
+}
+
+// Status is the outcome of a check or of a whole report
+type Status string
+
+const (
+	StatusOK   Status = "ok"
+	StatusFail Status = "fail"
+)
+
+// CheckResult is the outcome of a single Checker
+type CheckResult struct {
+	Status     Status `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+// Report aggregates the results of a set of checks
+type Report struct {
+	Status    Status                 `json:"status"`
+	Checks    map[string]CheckResult `json:"checks"`
+	CheckedAt time.Time              `json:"checkedAt"`
+}
+
+// Registry holds the liveness and readiness checks of the performer.
+// Liveness answers "is the process working at all", readiness answers
+// "can this performer serve tasks right now".
+type Registry struct {
+	mu        sync.RWMutex
+	liveness  []Checker
+	readiness []Checker
+	timeout   time.Duration
+}
+
+// NewRegistry creates an empty registry. Each check is bounded by timeout.
+func NewRegistry(timeout time.Duration) *Registry {
+	if timeout <= 0 {
+		timeout = 3 * time.Second
+	}
+	return &Registry{timeout: timeout}
+}
+
+// AddLivenessCheck registers a check served on /healthz
+func (r *Registry) AddLivenessCheck(c Checker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.liveness = append(r.liveness, c)
+}
+
+// AddReadinessCheck registers a check served on /readyz
+func (r *Registry) AddReadinessCheck(c Checker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.readiness = append(r.readiness, c)
+}
+
+// Liveness runs all liveness checks
+func (r *Registry) Liveness(ctx context.Context) *Report {
+	r.mu.RLock()
+	checks := append([]Checker(nil), r.liveness...)
+	r.mu.RUnlock()
+	return r.run(ctx, checks)
+}
+
+// Readiness runs all readiness checks
+func (r *Registry) Readiness(ctx context.Context) *Report {
+	r.mu.RLock()
+	checks := append([]Checker(nil), r.readiness...)
+	r.mu.RUnlock()
+	return r.run(ctx, checks)
+}
+
+// CheckNames returns the names of all registered readiness checks, sorted
+func (r *Registry) CheckNames() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.readiness))
+	for _, c := range r.readiness {
+		names = append(names, c.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+// run executes checks concurrently so one slow dependency doesn't hide the others
+func (r *Registry) run(ctx context.Context, checks []Checker) *Report {
+	report := &Report{
+		Status:    StatusOK,
+		Checks:    make(map[string]CheckResult, len(checks)),
+		CheckedAt: time.Now().UTC(),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, c := range checks {
+		wg.Add(1)
+		go func(c Checker) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, r.timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := c.Check(checkCtx)
+			result := CheckResult{
+				Status:     StatusOK,
+				DurationMs: time.Since(start).Milliseconds(),
+			}
+			if err != nil {
+				result.Status = StatusFail
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			report.Checks[c.Name()] = result
+			if err != nil {
+				report.Status = StatusFail
+			}
+			mu.Unlock()
+		}(c)
+	}
+	wg.Wait()
+
+	return report
+}
+
+// Handler serves /healthz and /readyz. Failing reports are returned with 503
+// so orchestrators such as Kubernetes stop routing tasks to this performer.
+func (r *Registry) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, req *http.Request) {
+		writeReport(w, r.Liveness(req.Context()))
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, req *http.Request) {
+		writeReport(w, r.Readiness(req.Context()))
+	})
+	return mux
+}
+
+func writeReport(w http.ResponseWriter, report *Report) {
+	w.Header().Set("Content-Type", "application/json")
+	if report.Status != StatusOK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	_ = json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,75 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_ReadinessReportsFailingDependencies(t *testing.T) {
+	registry := NewRegistry(time.Second)
+	registry.AddLivenessCheck(NewChecker("process", func(ctx context.Context) error { return nil }))
+	registry.AddReadinessCheck(NewChecker("config", func(ctx context.Context) error { return nil }))
+	registry.AddReadinessCheck(NewChecker("chain:ethereum", func(ctx context.Context) error {
+		return errors.New("connection refused")
+	}))
+
+	server := httptest.NewServer(registry.Handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/healthz")
+	if err != nil {
+		t.Fatalf("GET /healthz failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected /healthz to return 200, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get(server.URL + "/readyz")
+	if err != nil {
+		t.Fatalf("GET /readyz failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected /readyz to return 503, got %d", resp.StatusCode)
+	}
+
+	var report Report
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		t.Fatalf("Failed to decode report: %v", err)
+	}
+	if report.Status != StatusFail {
+		t.Errorf("Expected overall status fail, got %s", report.Status)
+	}
+	if report.Checks["config"].Status != StatusOK {
+		t.Errorf("Expected config check to pass, got %+v", report.Checks["config"])
+	}
+	if report.Checks["chain:ethereum"].Error != "connection refused" {
+		t.Errorf("Expected chain check error to be reported, got %+v", report.Checks["chain:ethereum"])
+	}
+}
+
+func Test_ChecksAreBoundedByTimeout(t *testing.T) {
+	registry := NewRegistry(20 * time.Millisecond)
+	registry.AddReadinessCheck(NewChecker("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}))
+
+	start := time.Now()
+	report := registry.Readiness(context.Background())
+	if time.Since(start) > time.Second {
+		t.Errorf("Readiness did not respect the check timeout")
+	}
+	if report.Status != StatusFail {
+		t.Errorf("Expected timed out check to fail the report")
+	}
+	if names := registry.CheckNames(); len(names) != 1 || names[0] != "slow" {
+		t.Errorf("Unexpected check names: %v", names)
+	}
+}
@@ -0,0 +1,62 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Config configures the HTTP server exposing health endpoints
+type Config struct {
+	Enabled      bool          `yaml:"enabled"`
+	Port         int           `yaml:"port"`
+	CheckTimeout time.Duration `yaml:"checkTimeout"`
+}
+
+// Server exposes a Registry over HTTP
+type Server struct {
+	httpServer *http.Server
+	mux        *http.ServeMux
+	logger     *zap.Logger
+}
+
+// NewServer creates a health server listening on cfg.Port
+func NewServer(cfg *Config, registry *Registry, logger *zap.Logger) *Server {
+	mux := http.NewServeMux()
+	mux.Handle("/", registry.Handler())
+	return &Server{
+		httpServer: &http.Server{
+			Addr:              fmt.Sprintf(":%d", cfg.Port),
+			Handler:           mux,
+			ReadHeaderTimeout: 5 * time.Second,
+		},
+		mux:    mux,
+		logger: logger,
+	}
+}
+
+// Handle serves an additional endpoint, such as metrics, next to the health endpoints.
+// It must be called before Start.
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// Start serves health endpoints until ctx is cancelled
+func (s *Server) Start(ctx context.Context) {
+	go func() {
+		if err := s.httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Sugar().Errorw("Health server stopped unexpectedly", "error", err)
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = s.httpServer.Shutdown(shutdownCtx)
+	}()
+}
@@ -0,0 +1,155 @@
+// Package hysteresis keeps rebalance recommendations from chasing transient rate spikes.
+// A move is only recommended once the target market has out-earned the source for a
+// minimum duration, and not while the funds of its owner cool down from their last move,
+// so funds do not thrash between markets whose rates cross back and forth.
+package hysteresis
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/najnomics/crosscow-avs/pkg/store"
+)
+
+// Reason tells why a move is held back
+type Reason string
+
+const (
+	// ReasonNotPersisted holds back moves to a target that has not led the source for
+	// long enough
+	ReasonNotPersisted Reason = "advantage_not_persisted"
+
+	// ReasonCooldown holds back moves of funds that moved recently
+	ReasonCooldown Reason = "cooldown"
+)
+
+// Config configures hysteresis
+type Config struct {
+	Enabled bool `yaml:"enabled"`
+
+	// MinPersistence is how long the target must have earned MinAdvantageBps more than
+	// the source, at every rate recorded in between, for a move to be recommended
+	MinPersistence time.Duration `yaml:"minPersistence"`
+
+	// MinAdvantageBps is the lead over the source, in basis points of annual rate, that
+	// counts as out-earning it
+	MinAdvantageBps uint64 `yaml:"minAdvantageBps"`
+
+	// Cooldown is how long after funds of an owner moved no further move is recommended
+	Cooldown time.Duration `yaml:"cooldown"`
+}
+
+// DefaultConfig is disabled. Enabled, it asks a 5 bps lead held for six hours and leaves
+// funds in place for a day after they moved.
+func DefaultConfig() Config {
+	return Config{
+		MinPersistence:  6 * time.Hour,
+		MinAdvantageBps: 5,
+		Cooldown:        24 * time.Hour,
+	}
+}
+
+// Validate checks the config for values hysteresis cannot apply
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.MinPersistence < 0 {
+		return fmt.Errorf("minPersistence must not be negative")
+	}
+	if c.Cooldown < 0 {
+		return fmt.Errorf("cooldown must not be negative")
+	}
+	if c.MinAdvantageBps > 10_000 {
+		return fmt.Errorf("minAdvantageBps must be at most 10000")
+	}
+	return nil
+}
+
+// Lookback is how far back rate history is read to measure a lead, which starts before
+// the persistence window when the target already led then
+func (c Config) Lookback() time.Duration {
+	return 2 * c.MinPersistence
+}
+
+// Decision is whether a move is recommended. Reason is empty when it is.
+type Decision struct {
+	Reason Reason
+
+	// Lead is how long the target has out-earned the source
+	Lead time.Duration
+
+	// CooldownEnds is when the funds may move again, zero when they did not move within
+	// the cooldown
+	CooldownEnds time.Time
+}
+
+// Suppressed reports whether the move is held back
+func (d Decision) Suppressed() bool {
+	return d.Reason != ""
+}
+
+// Evaluate decides whether moving funds that last moved at lastMove, zero when never,
+// from a source to a target that has led it for lead is recommended at now
+func (c Config) Evaluate(lead time.Duration, lastMove, now time.Time) Decision {
+	d := Decision{Lead: lead}
+	if !lastMove.IsZero() && now.Before(lastMove.Add(c.Cooldown)) {
+		d.CooldownEnds = lastMove.Add(c.Cooldown)
+		d.Reason = ReasonCooldown
+		return d
+	}
+	if lead < c.MinPersistence {
+		d.Reason = ReasonNotPersisted
+	}
+	return d
+}
+
+// Lead returns how long target has earned at least advantage more than source up to now,
+// when their rates are sourceRate and targetRate. Histories are rates ordered by time,
+// each carried forward until its next point; the lead starts no earlier than the first
+// time both are known. Rates and advantage are annual rates (0.0005 is 5 bps).
+func Lead(source, target []store.SeriesPoint, sourceRate, targetRate, advantage float64, now time.Time) time.Duration {
+	if targetRate-sourceRate < advantage {
+		return 0
+	}
+	type sample struct {
+		time   time.Time
+		target bool
+		value  float64
+	}
+	samples := make([]sample, 0, len(source)+len(target))
+	for _, p := range source {
+		samples = append(samples, sample{time: p.Time, value: p.Value})
+	}
+	for _, p := range target {
+		samples = append(samples, sample{time: p.Time, target: true, value: p.Value})
+	}
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].time.Before(samples[j].time) })
+
+	var sourceValue, targetValue *float64
+	since := now
+	for i := range samples {
+		s := &samples[i]
+		if s.time.After(now) {
+			break
+		}
+		if s.target {
+			targetValue = &s.value
+		} else {
+			sourceValue = &s.value
+		}
+		if i+1 < len(samples) && samples[i+1].time.Equal(s.time) {
+			// both rates were recorded at once
+			continue
+		}
+		switch {
+		case sourceValue == nil || targetValue == nil:
+		case *targetValue-*sourceValue < advantage:
+			since = now
+		case since.Equal(now):
+			since = s.time
+		}
+	}
+	return now.Sub(since)
+}
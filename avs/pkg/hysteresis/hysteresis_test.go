@@ -0,0 +1,79 @@
+package hysteresis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/najnomics/crosscow-avs/pkg/store"
+)
+
+func Test_Lead(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(hoursAgo int) time.Time { return now.Add(-time.Duration(hoursAgo) * time.Hour) }
+	source := []store.SeriesPoint{{Time: at(10), Value: 0.04}, {Time: at(4), Value: 0.04}}
+
+	// the target fell behind 8 hours ago and has led by 100 bps since 5 hours ago
+	target := []store.SeriesPoint{{Time: at(10), Value: 0.05}, {Time: at(8), Value: 0.03}, {Time: at(5), Value: 0.05}}
+	if lead := Lead(source, target, 0.04, 0.05, 0.0005, now); lead != 5*time.Hour {
+		t.Errorf("Expected a 5 hour lead, got %s", lead)
+	}
+
+	// a lead below the advantage counts as none
+	if lead := Lead(source, target, 0.04, 0.05, 0.02, now); lead != 0 {
+		t.Errorf("Expected no lead below the advantage, got %s", lead)
+	}
+	if lead := Lead(source, target, 0.05, 0.04, 0, now); lead != 0 {
+		t.Errorf("Expected no lead once the target falls behind, got %s", lead)
+	}
+
+	// the lead starts when both rates are known
+	if lead := Lead(source[1:], target, 0.04, 0.05, 0.0005, now); lead != 4*time.Hour {
+		t.Errorf("Expected the lead to start with the source history, got %s", lead)
+	}
+	if lead := Lead(nil, nil, 0.04, 0.05, 0.0005, now); lead != 0 {
+		t.Errorf("Expected no lead without history, got %s", lead)
+	}
+
+	// rates recorded at once are compared together
+	crossed := []store.SeriesPoint{{Time: at(3), Value: 0.03}}
+	sourceCrossed := []store.SeriesPoint{{Time: at(3), Value: 0.02}}
+	if lead := Lead(sourceCrossed, crossed, 0.04, 0.05, 0.0005, now); lead != 3*time.Hour {
+		t.Errorf("Expected a 3 hour lead, got %s", lead)
+	}
+}
+
+func Test_Evaluate(t *testing.T) {
+	cfg := DefaultConfig()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	if d := cfg.Evaluate(7*time.Hour, time.Time{}, now); d.Suppressed() {
+		t.Errorf("Expected a persisted lead to be recommended, got %+v", d)
+	}
+	if d := cfg.Evaluate(time.Hour, time.Time{}, now); d.Reason != ReasonNotPersisted {
+		t.Errorf("Expected a fresh lead to be held back, got %+v", d)
+	}
+
+	moved := now.Add(-2 * time.Hour)
+	d := cfg.Evaluate(7*time.Hour, moved, now)
+	if d.Reason != ReasonCooldown || !d.CooldownEnds.Equal(moved.Add(24*time.Hour)) {
+		t.Errorf("Expected recently moved funds to cool down, got %+v", d)
+	}
+	if d := cfg.Evaluate(7*time.Hour, now.Add(-25*time.Hour), now); d.Suppressed() || !d.CooldownEnds.IsZero() {
+		t.Errorf("Expected the cooldown to have ended, got %+v", d)
+	}
+}
+
+func Test_Validate(t *testing.T) {
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Errorf("Expected the default config to be valid: %v", err)
+	}
+	for name, cfg := range map[string]Config{
+		"persistence": {Enabled: true, MinPersistence: -time.Hour},
+		"cooldown":    {Enabled: true, Cooldown: -time.Hour},
+		"advantage":   {Enabled: true, MinAdvantageBps: 10_001},
+	} {
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("%s: expected the config to be rejected", name)
+		}
+	}
+}
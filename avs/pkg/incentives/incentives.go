@@ -0,0 +1,223 @@
+// Package incentives converts the reward emissions protocols pay suppliers into an APR
+// on top of the supply rate, net of vesting haircuts and the cost of claiming.
+package incentives
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/pricefeed"
+	"github.com/najnomics/crosscow-avs/pkg/rates"
+)
+
+const usdcDecimals = 6
+
+// Confidence grades how much of an incentive APR can be relied on
+type Confidence string
+
+const (
+	// ConfidenceHigh is an APR of priced emissions that keep running
+	ConfidenceHigh Confidence = "high"
+
+	// ConfidenceMedium is an APR including emissions that end soon
+	ConfidenceMedium Confidence = "medium"
+
+	// ConfidenceLow is an APR leaving out emissions that could not be priced
+	ConfidenceLow Confidence = "low"
+)
+
+// Config configures the pricing of reward emissions
+type Config struct {
+	Enabled bool `yaml:"enabled"`
+
+	// PriceFeeds are Chainlink USD aggregators of reward tokens, by chain id and token
+	PriceFeeds map[uint64]map[string]string `yaml:"priceFeeds"`
+
+	// Heartbeat is the age after which a reward token price is stale and not used
+	Heartbeat time.Duration `yaml:"heartbeat"`
+
+	// Haircuts are the fractions of rewards lost to vesting or lockups, by protocol
+	Haircuts map[string]float64 `yaml:"haircuts"`
+
+	// ClaimCostUSD is the cost of claiming rewards once. It is amortized over
+	// ClaimInterval for a position of ReferencePosition USDC.
+	ClaimCostUSD      float64       `yaml:"claimCostUsd"`
+	ClaimInterval     time.Duration `yaml:"claimInterval"`
+	ReferencePosition float64       `yaml:"referencePosition"`
+
+	// EndingWithin lowers the confidence in emissions that end within it
+	EndingWithin time.Duration `yaml:"endingWithin"`
+}
+
+// DefaultConfig prices AAVE and COMP on Ethereum and ARB on Arbitrum, and amortizes a $5
+// claim a month over a position of 100k USDC
+func DefaultConfig() Config {
+	return Config{
+		Enabled: true,
+		PriceFeeds: map[uint64]map[string]string{
+			1: {
+				"0x7Fc66500c84A76Ad7e9c93437bFc5Ac33E2DDaE9": "0x547a514d5e3769680Ce22B2361c10Ea13619e8a9",
+				"0xc00e94Cb662C3520282E6f5717214004A7f26888": "0xdbd020CAeF83eFd542f4De03e3cF0C28A4428bd5",
+			},
+			42161: {
+				"0x912CE59144191C1204E64559FE8253a0e49E6548": "0xb2A824043730FE05F3DA2efaFa1CBbe83fa548D6",
+			},
+		},
+		Heartbeat:         25 * time.Hour,
+		ClaimCostUSD:      5,
+		ClaimInterval:     30 * 24 * time.Hour,
+		ReferencePosition: 100_000,
+		EndingWithin:      7 * 24 * time.Hour,
+	}
+}
+
+// Validate checks the config for values incentives cannot be priced with
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	for chainID, feeds := range c.PriceFeeds {
+		for token, feed := range feeds {
+			if !common.IsHexAddress(token) || !common.IsHexAddress(feed) {
+				return fmt.Errorf("priceFeeds[%d] maps %s to %s, which are not both addresses", chainID, token, feed)
+			}
+		}
+	}
+	if c.Heartbeat <= 0 {
+		return fmt.Errorf("heartbeat must be positive")
+	}
+	for protocol, haircut := range c.Haircuts {
+		if haircut < 0 || haircut > 1 {
+			return fmt.Errorf("haircuts[%s] must be between 0 and 1", protocol)
+		}
+	}
+	if c.ClaimCostUSD < 0 {
+		return fmt.Errorf("claimCostUsd must not be negative")
+	}
+	if c.ClaimCostUSD > 0 && (c.ClaimInterval <= 0 || c.ReferencePosition <= 0) {
+		return fmt.Errorf("claimInterval and referencePosition must be positive to amortize claims")
+	}
+	if c.EndingWithin < 0 {
+		return fmt.Errorf("endingWithin must not be negative")
+	}
+	return nil
+}
+
+// Reward is the APR a single emission adds before haircuts
+type Reward struct {
+	Token common.Address
+
+	// APR is the annual reward value over the market supply, 0 when unpriced
+	APR    float64
+	Priced bool
+	EndsAt uint64
+}
+
+// Estimate is the incentive APR of a market
+type Estimate struct {
+	// APR is the sum of reward APRs after the haircut of the protocol and the amortized
+	// claim cost, never negative
+	APR        float64
+	Confidence Confidence
+	Rewards    []Reward
+}
+
+// Estimator prices the emissions of markets. A nil Estimator estimates nothing.
+type Estimator struct {
+	cfg    Config
+	chains *chain.Manager
+	feeds  map[uint64]map[common.Address]common.Address
+}
+
+// New creates an estimator pricing reward tokens with the feeds of cfg on chains
+func New(cfg Config, chains *chain.Manager) *Estimator {
+	feeds := make(map[uint64]map[common.Address]common.Address, len(cfg.PriceFeeds))
+	for chainID, tokens := range cfg.PriceFeeds {
+		feeds[chainID] = make(map[common.Address]common.Address, len(tokens))
+		for token, feed := range tokens {
+			feeds[chainID][common.HexToAddress(token)] = common.HexToAddress(feed)
+		}
+	}
+	return &Estimator{cfg: cfg, chains: chains, feeds: feeds}
+}
+
+// NewFromConfig creates the estimator enabled in cfg, nil when it is disabled
+func NewFromConfig(cfg Config, chains *chain.Manager) *Estimator {
+	if !cfg.Enabled {
+		return nil
+	}
+	return New(cfg, chains)
+}
+
+// Estimate prices the emissions of the market of protocol on chainID, whose suppliers
+// hold totalSupply USDC base units. Rewards without a fresh price are left out and lower
+// the confidence.
+func (e *Estimator) Estimate(ctx context.Context, protocol string, chainID uint64, incentives *adapters.Incentives, totalSupply *big.Int) (*Estimate, error) {
+	if e == nil {
+		return nil, nil
+	}
+	out := &Estimate{Confidence: ConfidenceHigh, Rewards: make([]Reward, 0, len(incentives.Emissions))}
+	supply := scaled(totalSupply, usdcDecimals)
+
+	var gross float64
+	for _, emission := range incentives.Emissions {
+		reward := Reward{Token: emission.Token, EndsAt: emission.EndsAt}
+		price, err := e.price(ctx, chainID, emission.Token)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err == nil && supply > 0 {
+			reward.Priced = true
+			reward.APR = scaled(emission.PerSecond, int(emission.Decimals)) * rates.SecondsPerYear * price / supply
+			gross += reward.APR
+		} else {
+			out.Confidence = ConfidenceLow
+		}
+		if out.Confidence == ConfidenceHigh && emission.EndsAt != 0 &&
+			emission.EndsAt < incentives.BlockTime+uint64(e.cfg.EndingWithin/time.Second) {
+			out.Confidence = ConfidenceMedium
+		}
+		out.Rewards = append(out.Rewards, reward)
+	}
+
+	if gross > 0 {
+		net := gross * (1 - e.cfg.Haircuts[strings.ToLower(protocol)])
+		if e.cfg.ClaimCostUSD > 0 {
+			claimsPerYear := float64(rates.SecondsPerYear) / e.cfg.ClaimInterval.Seconds()
+			net -= e.cfg.ClaimCostUSD * claimsPerYear / e.cfg.ReferencePosition
+		}
+		if net > 0 {
+			out.APR = net
+		}
+	}
+	return out, nil
+}
+
+// price reads the USD price of token from its configured feed, failing for tokens without
+// a feed or with a stale one
+func (e *Estimator) price(ctx context.Context, chainID uint64, token common.Address) (float64, error) {
+	feed, ok := e.feeds[chainID][token]
+	if !ok {
+		return 0, fmt.Errorf("no price feed for %s on chain %d", token.Hex(), chainID)
+	}
+	quote, err := pricefeed.NewChainlinkSource(e.chains, chainID, feed, e.cfg.Heartbeat).Quote(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if quote.Stale {
+		return 0, fmt.Errorf("price of %s on chain %d is stale", token.Hex(), chainID)
+	}
+	price, _ := quote.Price.Float64()
+	return price, nil
+}
+
+func scaled(value *big.Int, decimals int) float64 {
+	f, _ := new(big.Rat).SetFrac(value, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)).Float64()
+	return f
+}
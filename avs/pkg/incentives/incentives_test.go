@@ -0,0 +1,126 @@
+package incentives
+
+import (
+	"context"
+	"math"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/chain/chaintest"
+	"github.com/najnomics/crosscow-avs/pkg/rates"
+)
+
+var aggregatorABI = chain.MustParseABI(`[
+	{"name":"decimals","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint8"}]},
+	{"name":"latestRoundData","type":"function","stateMutability":"view","inputs":[],
+	 "outputs":[
+		{"name":"roundId","type":"uint80"},
+		{"name":"answer","type":"int256"},
+		{"name":"startedAt","type":"uint256"},
+		{"name":"updatedAt","type":"uint256"},
+		{"name":"answeredInRound","type":"uint80"}
+	]}
+]`)
+
+const now = 1_800_000_000
+
+func Test_Estimate(t *testing.T) {
+	priced := common.HexToAddress("0x10")
+	unpriced := common.HexToAddress("0x11")
+	feed := common.HexToAddress("0x20")
+
+	contracts := chaintest.NewContracts(1)
+	contracts.SetHead(100, now)
+	contracts.Stub(t, feed, aggregatorABI, "decimals", uint8(8))
+	contracts.Stub(t, feed, aggregatorABI, "latestRoundData",
+		big.NewInt(1), big.NewInt(200_000_000), big.NewInt(0), big.NewInt(now-60), big.NewInt(1))
+	chains := chain.NewManager()
+	chains.Register(1, "ethereum", contracts)
+
+	cfg := DefaultConfig()
+	cfg.PriceFeeds = map[uint64]map[string]string{1: {priced.Hex(): feed.Hex()}}
+	cfg.Haircuts = map[string]float64{"aave_v3": 0.5}
+	cfg.ClaimCostUSD = 0
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Expected the config to be valid: %v", err)
+	}
+	estimator := NewFromConfig(cfg, chains)
+
+	// one token a second at $2 over a billion USDC of supply
+	oneTokenPerSecond := new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
+	supply := new(big.Int).Mul(big.NewInt(1_000_000_000), big.NewInt(1_000_000))
+	gross := float64(rates.SecondsPerYear) * 2 / 1e9
+
+	estimate, err := estimator.Estimate(context.Background(), "Aave_V3", 1, &adapters.Incentives{
+		BlockTime: now,
+		Emissions: []adapters.Emission{{Token: priced, Decimals: 18, PerSecond: oneTokenPerSecond}},
+	}, supply)
+	if err != nil {
+		t.Fatalf("Estimate failed: %v", err)
+	}
+	if math.Abs(estimate.APR-gross/2) > 1e-12 || estimate.Confidence != ConfidenceHigh {
+		t.Errorf("Expected half of %f with high confidence, got %+v", gross, estimate)
+	}
+	if len(estimate.Rewards) != 1 || math.Abs(estimate.Rewards[0].APR-gross) > 1e-12 {
+		t.Errorf("Expected rewards before the haircut, got %+v", estimate.Rewards)
+	}
+
+	estimate, err = estimator.Estimate(context.Background(), "compound_v3", 1, &adapters.Incentives{
+		BlockTime: now,
+		Emissions: []adapters.Emission{
+			{Token: priced, Decimals: 18, PerSecond: oneTokenPerSecond, EndsAt: now + 3600},
+			{Token: unpriced, Decimals: 18, PerSecond: oneTokenPerSecond},
+		},
+	}, supply)
+	if err != nil {
+		t.Fatalf("Estimate failed: %v", err)
+	}
+	if math.Abs(estimate.APR-gross) > 1e-12 || estimate.Confidence != ConfidenceLow || estimate.Rewards[1].Priced {
+		t.Errorf("Expected the unpriced reward to be left out with low confidence, got %+v", estimate)
+	}
+
+	estimate, err = estimator.Estimate(context.Background(), "compound_v3", 1, &adapters.Incentives{
+		BlockTime: now,
+		Emissions: []adapters.Emission{{Token: priced, Decimals: 18, PerSecond: oneTokenPerSecond, EndsAt: now + 3600}},
+	}, supply)
+	if err != nil || estimate.Confidence != ConfidenceMedium {
+		t.Errorf("Expected medium confidence in emissions ending soon, got %+v, %v", estimate, err)
+	}
+
+	// stale prices are not used
+	contracts.Stub(t, feed, aggregatorABI, "latestRoundData",
+		big.NewInt(1), big.NewInt(200_000_000), big.NewInt(0), big.NewInt(now-int64(26*time.Hour/time.Second)), big.NewInt(1))
+	estimate, err = estimator.Estimate(context.Background(), "compound_v3", 1, &adapters.Incentives{
+		BlockTime: now,
+		Emissions: []adapters.Emission{{Token: priced, Decimals: 18, PerSecond: oneTokenPerSecond}},
+	}, supply)
+	if err != nil || estimate.APR != 0 || estimate.Confidence != ConfidenceLow {
+		t.Errorf("Expected a stale price to be left out, got %+v, %v", estimate, err)
+	}
+}
+
+func Test_EstimateAmortizesClaims(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.PriceFeeds = nil
+	estimator := New(cfg, chain.NewManager())
+
+	// no emissions cost nothing to claim
+	estimate, err := estimator.Estimate(context.Background(), "aave_v3", 1, &adapters.Incentives{BlockTime: now}, big.NewInt(1_000_000))
+	if err != nil || estimate.APR != 0 || estimate.Confidence != ConfidenceHigh {
+		t.Errorf("Expected no incentives with high confidence, got %+v, %v", estimate, err)
+	}
+
+	var nilEstimator *Estimator
+	if estimate, err := nilEstimator.Estimate(context.Background(), "aave_v3", 1, &adapters.Incentives{}, big.NewInt(1)); estimate != nil || err != nil {
+		t.Errorf("Expected a nil estimator to estimate nothing")
+	}
+
+	cfg.ClaimInterval = 0
+	if err := cfg.Validate(); err == nil {
+		t.Errorf("Expected claim costs without an interval to be rejected")
+	}
+}
@@ -0,0 +1,434 @@
+// Package indexer follows the logs of lending protocol contracts on every configured chain
+// and keeps the state of their USDC markets, reading a market again only after an event
+// changed it, so handlers can use the state without reading the chain for every task.
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"go.uber.org/zap"
+)
+
+// Config configures log polling and how long indexed state is trusted
+type Config struct {
+	Enabled bool `yaml:"enabled"`
+
+	// PollInterval is the time between log queries of every chain, about a block time
+	PollInterval time.Duration `yaml:"pollInterval"`
+
+	// MaxBlockRange bounds the blocks of a single log query
+	MaxBlockRange uint64 `yaml:"maxBlockRange"`
+
+	// RefreshInterval is the age after which a market is read again without any event,
+	// since interest accrues and prices move in between
+	RefreshInterval time.Duration `yaml:"refreshInterval"`
+}
+
+// DefaultConfig polls every Ethereum block time, in ranges RPC providers serve at once,
+// and reads quiet markets again every 5 minutes
+func DefaultConfig() Config {
+	return Config{
+		Enabled:         true,
+		PollInterval:    12 * time.Second,
+		MaxBlockRange:   2_000,
+		RefreshInterval: 5 * time.Minute,
+	}
+}
+
+// Validate checks the config for values the indexer cannot run with
+func (c *Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.PollInterval <= 0 {
+		return fmt.Errorf("pollInterval must be positive")
+	}
+	if c.MaxBlockRange == 0 {
+		return fmt.Errorf("maxBlockRange must be positive")
+	}
+	if c.RefreshInterval < c.PollInterval {
+		return fmt.Errorf("refreshInterval must not be shorter than pollInterval")
+	}
+	return nil
+}
+
+// Entry is the indexed state of one market
+type Entry struct {
+	Market *adapters.MarketState
+
+	// Risk is nil when the adapter does not report risk data
+	Risk *adapters.RiskState
+
+	// Block is the chain head when the market was read and ReadAt the time it was read
+	Block  uint64
+	ReadAt time.Time
+}
+
+type marketKey struct {
+	protocol string
+	chainID  uint64
+}
+
+// tracked is a market followed by the indexer
+type tracked struct {
+	key     marketKey
+	watcher adapters.EventWatcher
+
+	// watches are resolved on the first poll of the market
+	watches []adapters.EventWatch
+	entry   *Entry
+
+	// dirty is set when an event changed the market after it was read, or reading it
+	// again failed
+	dirty bool
+}
+
+// Indexer keeps the state of the markets of every adapter implementing
+// adapters.EventWatcher up to date from their logs
+type Indexer struct {
+	cfg      Config
+	registry *adapters.Registry
+	chains   *chain.Manager
+	logger   *zap.Logger
+	now      func() time.Time
+
+	mu       sync.RWMutex
+	markets  map[marketKey]*tracked
+	cursors  map[uint64]uint64
+	polledAt map[uint64]time.Time
+}
+
+// New creates an indexer following the markets of registry on the chains of chains
+func New(cfg Config, registry *adapters.Registry, chains *chain.Manager, logger *zap.Logger) *Indexer {
+	return &Indexer{
+		cfg:      cfg,
+		registry: registry,
+		chains:   chains,
+		logger:   logger,
+		now:      time.Now,
+		markets:  make(map[marketKey]*tracked),
+		cursors:  make(map[uint64]uint64),
+		polledAt: make(map[uint64]time.Time),
+	}
+}
+
+// Start indexes until ctx is cancelled. Chains with a WebSocket endpoint are polled on
+// every new head, the others immediately and then every poll interval.
+func (ix *Indexer) Start(ctx context.Context) {
+	var polled []uint64
+	for _, chainID := range ix.chains.ChainIDs() {
+		heads, err := ix.chains.SubscribeHeads(ctx, chainID)
+		if err != nil {
+			polled = append(polled, chainID)
+			continue
+		}
+		go func() {
+			ix.report(ix.poll(ctx, chainID))
+			for range heads {
+				// heads queued while polling are covered by the next poll
+				for len(heads) > 0 {
+					<-heads
+				}
+				ix.report(ix.poll(ctx, chainID))
+			}
+		}()
+	}
+	if len(polled) == 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(ix.cfg.PollInterval)
+		defer ticker.Stop()
+		for {
+			ix.report(ix.poll(ctx, polled...))
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (ix *Indexer) report(read int, err error) {
+	if err != nil {
+		ix.logger.Sugar().Warnw("Market indexing incomplete", "read", read, "error", err)
+	} else if read > 0 {
+		ix.logger.Sugar().Debugw("Indexed market state", "read", read)
+	}
+}
+
+// Poll scans the blocks produced since the previous poll on every chain and reads the
+// markets changed in them again, along with markets older than the refresh interval. It
+// returns the number of markets read and the last error encountered; a failing chain or
+// market does not stop the others from being indexed.
+func (ix *Indexer) Poll(ctx context.Context) (int, error) {
+	return ix.poll(ctx, ix.chains.ChainIDs()...)
+}
+
+func (ix *Indexer) poll(ctx context.Context, chainIDs ...uint64) (int, error) {
+	read := 0
+	var lastErr error
+	for _, chainID := range chainIDs {
+		n, err := ix.pollChain(ctx, chainID)
+		read += n
+		if err != nil {
+			lastErr = fmt.Errorf("chain %d: %w", chainID, err)
+		}
+	}
+	return read, lastErr
+}
+
+func (ix *Indexer) pollChain(ctx context.Context, chainID uint64) (int, error) {
+	client, err := ix.chains.Client(chainID)
+	if err != nil {
+		return 0, err
+	}
+	head, err := client.BlockNumber(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var lastErr error
+	markets := ix.track(chainID)
+	for _, t := range markets {
+		if t.watches != nil {
+			continue
+		}
+		watches, err := t.watcher.WatchedEvents(ctx, chainID)
+		if err != nil {
+			lastErr = fmt.Errorf("%s: failed to resolve watched events: %w", t.key.protocol, err)
+			continue
+		}
+		ix.mu.Lock()
+		t.watches = watches
+		ix.mu.Unlock()
+	}
+
+	ix.mu.RLock()
+	cursor, scanned := ix.cursors[chainID]
+	ix.mu.RUnlock()
+	if scanned && head > cursor {
+		changed, err := ix.scan(ctx, client, markets, cursor+1, head)
+		if err != nil {
+			// the cursor stays, so the blocks are scanned again on the next poll
+			return 0, fmt.Errorf("failed to filter logs: %w", err)
+		}
+		ix.mu.Lock()
+		for _, t := range changed {
+			t.dirty = true
+		}
+		ix.mu.Unlock()
+	}
+
+	read := 0
+	for _, t := range markets {
+		if !ix.due(t) {
+			continue
+		}
+		entry, err := ix.read(ctx, t, head)
+		ix.mu.Lock()
+		if err != nil {
+			t.dirty = true
+			lastErr = fmt.Errorf("%s: %w", t.key.protocol, err)
+		} else {
+			t.entry, t.dirty = entry, false
+			read++
+		}
+		ix.mu.Unlock()
+	}
+
+	ix.mu.Lock()
+	if !scanned || head > cursor {
+		ix.cursors[chainID] = head
+	}
+	ix.polledAt[chainID] = ix.now()
+	ix.mu.Unlock()
+	return read, lastErr
+}
+
+// track returns the markets on chainID of every adapter watching events, adding
+// adapters registered since the previous poll
+func (ix *Indexer) track(chainID uint64) []*tracked {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	var markets []*tracked
+	for _, protocol := range ix.registry.Protocols() {
+		adapter, err := ix.registry.Get(protocol)
+		if err != nil {
+			continue
+		}
+		watcher, ok := adapter.(adapters.EventWatcher)
+		if !ok || !hasChain(adapter, chainID) {
+			continue
+		}
+		key := marketKey{protocol: protocol, chainID: chainID}
+		t, ok := ix.markets[key]
+		if !ok {
+			t = &tracked{key: key, watcher: watcher}
+			ix.markets[key] = t
+		}
+		markets = append(markets, t)
+	}
+	return markets
+}
+
+// scan filters the logs of every watched contract from fromBlock to toBlock and returns
+// the markets they changed
+func (ix *Indexer) scan(ctx context.Context, client chain.Client, markets []*tracked, fromBlock, toBlock uint64) ([]*tracked, error) {
+	ix.mu.RLock()
+	var addresses []common.Address
+	var topics []common.Hash
+	seenAddress := make(map[common.Address]bool)
+	seenTopic := make(map[common.Hash]bool)
+	for _, t := range markets {
+		for _, w := range t.watches {
+			if !seenAddress[w.Address] {
+				seenAddress[w.Address] = true
+				addresses = append(addresses, w.Address)
+			}
+			for _, topic := range w.Topics {
+				if !seenTopic[topic] {
+					seenTopic[topic] = true
+					topics = append(topics, topic)
+				}
+			}
+		}
+	}
+	ix.mu.RUnlock()
+	if len(addresses) == 0 {
+		return nil, nil
+	}
+
+	changed := make(map[*tracked]bool)
+	for start := fromBlock; start <= toBlock; start += ix.cfg.MaxBlockRange {
+		end := min(start+ix.cfg.MaxBlockRange-1, toBlock)
+		logs, err := client.FilterLogs(ctx, ethereum.FilterQuery{
+			FromBlock: new(big.Int).SetUint64(start),
+			ToBlock:   new(big.Int).SetUint64(end),
+			Addresses: addresses,
+			Topics:    [][]common.Hash{topics},
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, log := range logs {
+			if log.Removed {
+				continue
+			}
+			for _, t := range markets {
+				if changed[t] {
+					continue
+				}
+				for _, w := range t.watches {
+					if w.Matches(log) {
+						changed[t] = true
+						break
+					}
+				}
+			}
+		}
+	}
+
+	result := make([]*tracked, 0, len(changed))
+	for _, t := range markets {
+		if changed[t] {
+			result = append(result, t)
+		}
+	}
+	return result, nil
+}
+
+// due reports whether t has to be read: it was never read, changed since or is older
+// than the refresh interval. Markets whose events could not be resolved are not read,
+// since they could not be kept up to date.
+func (ix *Indexer) due(t *tracked) bool {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	if t.watches == nil {
+		return false
+	}
+	return t.entry == nil || t.dirty || ix.now().Sub(t.entry.ReadAt) >= ix.cfg.RefreshInterval
+}
+
+func (ix *Indexer) read(ctx context.Context, t *tracked, head uint64) (*Entry, error) {
+	state, err := t.watcher.MarketState(ctx, t.key.chainID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read market: %w", err)
+	}
+	entry := &Entry{Market: state, Block: head, ReadAt: ix.now()}
+	if reader, ok := t.watcher.(adapters.RiskReader); ok {
+		risk, err := reader.RiskState(ctx, t.key.chainID)
+		switch {
+		case errors.Is(err, adapters.ErrNoRiskData):
+		case err != nil:
+			return nil, fmt.Errorf("failed to read risk parameters: %w", err)
+		default:
+			entry.Risk = risk
+		}
+	}
+	return entry, nil
+}
+
+// Lookup returns the indexed state of the market of protocol on chainID. It reports false
+// when the market is not indexed, changed since it was read, could not be read again or
+// is older than the refresh interval, and when its chain was not polled in the last two
+// poll intervals. The returned state is shared and must not be modified.
+func (ix *Indexer) Lookup(protocol string, chainID uint64) (*Entry, bool) {
+	if ix == nil {
+		return nil, false
+	}
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	t, ok := ix.markets[marketKey{protocol: strings.ToLower(strings.TrimSpace(protocol)), chainID: chainID}]
+	if !ok || t.entry == nil || t.dirty {
+		return nil, false
+	}
+	now := ix.now()
+	if now.Sub(ix.polledAt[chainID]) > 2*ix.cfg.PollInterval || now.Sub(t.entry.ReadAt) > ix.cfg.RefreshInterval+ix.cfg.PollInterval {
+		return nil, false
+	}
+	entry := *t.entry
+	return &entry, true
+}
+
+// MarketState returns the indexed market state, see Lookup
+func (ix *Indexer) MarketState(protocol string, chainID uint64) (*adapters.MarketState, bool) {
+	entry, ok := ix.Lookup(protocol, chainID)
+	if !ok {
+		return nil, false
+	}
+	return entry.Market, true
+}
+
+// RiskState returns the indexed risk state, see Lookup. It reports false for markets
+// whose adapter does not report risk data.
+func (ix *Indexer) RiskState(protocol string, chainID uint64) (*adapters.RiskState, bool) {
+	entry, ok := ix.Lookup(protocol, chainID)
+	if !ok || entry.Risk == nil {
+		return nil, false
+	}
+	return entry.Risk, true
+}
+
+func hasChain(adapter adapters.YieldAdapter, chainID uint64) bool {
+	for _, id := range adapter.ChainIDs() {
+		if id == chainID {
+			return true
+		}
+	}
+	return false
+}
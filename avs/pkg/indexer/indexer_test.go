@@ -0,0 +1,180 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/chain/chaintest"
+	"github.com/najnomics/crosscow-avs/pkg/irm"
+	"go.uber.org/zap"
+)
+
+var (
+	pool     = common.HexToAddress("0x10")
+	usdc     = common.HexToAddress("0x1")
+	weth     = common.HexToAddress("0x2")
+	updated  = common.HexToHash("0xaa")
+	unwanted = common.HexToHash("0xbb")
+)
+
+// fakeWatcher watches updated events of usdc on pool and counts market reads
+type fakeWatcher struct {
+	protocol string
+	reads    int
+	supply   int64
+	fail     bool
+}
+
+func (f *fakeWatcher) Protocol() string   { return f.protocol }
+func (f *fakeWatcher) ChainIDs() []uint64 { return []uint64{1} }
+
+func (f *fakeWatcher) MarketState(ctx context.Context, chainID uint64) (*adapters.MarketState, error) {
+	if f.fail {
+		return nil, errors.New("rpc unavailable")
+	}
+	f.reads++
+	return &adapters.MarketState{
+		Protocol: f.protocol,
+		ChainID:  chainID,
+		Pool: irm.Pool{
+			TotalSupply: big.NewInt(f.supply),
+			TotalBorrow: big.NewInt(500),
+			Model:       &irm.CometModel{Kink: 0.9, SlopeLow: 0.1},
+		},
+	}, nil
+}
+
+func (f *fakeWatcher) RiskState(ctx context.Context, chainID uint64) (*adapters.RiskState, error) {
+	return &adapters.RiskState{Frozen: f.supply > 1000}, nil
+}
+
+func (f *fakeWatcher) WatchedEvents(ctx context.Context, chainID uint64) ([]adapters.EventWatch, error) {
+	return []adapters.EventWatch{{Address: pool, Topics: []common.Hash{updated}, Asset: &usdc}}, nil
+}
+
+// coldAdapter hides the watched events of the adapter it wraps
+type coldAdapter struct{ adapters.YieldAdapter }
+
+func event(topic common.Hash, asset common.Address, block uint64) types.Log {
+	return types.Log{Address: pool, Topics: []common.Hash{topic, common.BytesToHash(asset.Bytes())}, BlockNumber: block}
+}
+
+func setup(registered ...adapters.YieldAdapter) (*Indexer, *chaintest.Contracts, *time.Time) {
+	contracts := chaintest.NewContracts(1)
+	chains := chain.NewManager()
+	chains.Register(1, "ethereum", contracts)
+
+	ix := New(DefaultConfig(), adapters.NewRegistry(registered...), chains, zap.NewNop())
+	now := time.Unix(1_800_000_000, 0)
+	ix.now = func() time.Time { return now }
+	return ix, contracts, &now
+}
+
+func Test_IndexerReadsMarketsChangedByEvents(t *testing.T) {
+	watcher := &fakeWatcher{protocol: "aave_v3", supply: 1000}
+	ix, contracts, now := setup(watcher)
+	ctx := context.Background()
+
+	if _, ok := ix.MarketState("aave_v3", 1); ok {
+		t.Errorf("Expected no state before the first poll")
+	}
+	if read, err := ix.Poll(ctx); err != nil || read != 1 {
+		t.Fatalf("Expected the market to be read on the first poll, got %d (%v)", read, err)
+	}
+	state, ok := ix.MarketState("AAVE_V3", 1)
+	if !ok || state.Pool.TotalSupply.Int64() != 1000 {
+		t.Fatalf("Expected the indexed state, got %+v", state)
+	}
+
+	// events of other reserves and other events leave the market alone
+	contracts.AddLog(event(updated, weth, 101))
+	contracts.AddLog(event(unwanted, usdc, 102))
+	contracts.AdvanceBlocks(5)
+	*now = now.Add(12 * time.Second)
+	if read, err := ix.Poll(ctx); err != nil || read != 0 {
+		t.Errorf("Expected no reads without relevant events, got %d (%v)", read, err)
+	}
+
+	watcher.supply = 2000
+	contracts.AddLog(event(updated, usdc, 106))
+	contracts.AdvanceBlocks(1)
+	*now = now.Add(12 * time.Second)
+	if read, err := ix.Poll(ctx); err != nil || read != 1 {
+		t.Errorf("Expected the changed market to be read, got %d (%v)", read, err)
+	}
+	entry, ok := ix.Lookup("aave_v3", 1)
+	if !ok || entry.Market.Pool.TotalSupply.Int64() != 2000 || entry.Block != 106 || !entry.Risk.Frozen {
+		t.Errorf("Expected the state read at block 106, got %+v", entry)
+	}
+
+	// the event is not scanned twice, quiet markets are read after the refresh interval
+	*now = now.Add(12 * time.Second)
+	if read, _ := ix.Poll(ctx); read != 0 {
+		t.Errorf("Expected the scanned event to be skipped, got %d reads", read)
+	}
+	*now = now.Add(ix.cfg.RefreshInterval)
+	if read, _ := ix.Poll(ctx); read != 1 || watcher.reads != 3 {
+		t.Errorf("Expected the market to be refreshed, got %d reads", read)
+	}
+}
+
+func Test_IndexerDropsStateItCannotKeepUp(t *testing.T) {
+	watcher := &fakeWatcher{protocol: "aave_v3", supply: 1000}
+	ix, contracts, now := setup(watcher, coldAdapter{&fakeWatcher{protocol: "euler"}})
+	ctx := context.Background()
+
+	if _, err := ix.Poll(ctx); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	if _, ok := ix.MarketState("euler", 1); ok {
+		t.Errorf("Expected adapters without watched events not to be indexed")
+	}
+
+	// a market that changed but could not be read again is not served
+	watcher.fail = true
+	contracts.AddLog(event(updated, usdc, 101))
+	contracts.AdvanceBlocks(1)
+	*now = now.Add(12 * time.Second)
+	if _, err := ix.Poll(ctx); err == nil {
+		t.Errorf("Expected the failed read to be reported")
+	}
+	if _, ok := ix.MarketState("aave_v3", 1); ok {
+		t.Errorf("Expected the outdated state not to be served")
+	}
+
+	watcher.fail = false
+	if _, err := ix.Poll(ctx); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	if _, ok := ix.RiskState("aave_v3", 1); !ok {
+		t.Errorf("Expected the state to be served again")
+	}
+
+	// without polls the state is not trusted
+	*now = now.Add(3 * ix.cfg.PollInterval)
+	if _, ok := ix.MarketState("aave_v3", 1); ok {
+		t.Errorf("Expected the state not to be served once polling stopped")
+	}
+	var disabled *Indexer
+	if _, ok := disabled.MarketState("aave_v3", 1); ok {
+		t.Errorf("Expected a nil indexer to serve nothing")
+	}
+}
+
+func Test_ConfigValidate(t *testing.T) {
+	cfg := DefaultConfig()
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected the default config to be valid: %v", err)
+	}
+	cfg.RefreshInterval = time.Second
+	if err := cfg.Validate(); err == nil {
+		t.Errorf("Expected a refresh interval shorter than the poll interval to be rejected")
+	}
+}
@@ -0,0 +1,319 @@
+// Package irm models lending protocol interest rate curves and computes how deposits and
+// withdrawals move the supply rate of a pool.
+package irm
+
+import (
+	"math/big"
+)
+
+// maxSearchIterations bounds the binary searches over amounts. 256 halvings cover any uint256.
+const maxSearchIterations = 256
+
+// maxDepositMultiple caps the deposit search at this multiple of the current pool supply
+const maxDepositMultiple = 10
+
+// Model maps pool utilization (0-1) to the annual supply rate lenders earn, as a fraction
+// (0.05 is 5% APR)
+type Model interface {
+	SupplyRate(utilization float64) float64
+}
+
+// KinkModel is the two-slope variable borrow rate curve used by Aave v3 and its forks.
+// Lenders earn the borrow interest pro-rata to utilization, minus the reserve factor.
+type KinkModel struct {
+	OptimalUtilization float64
+	BaseBorrowRate     float64
+	Slope1             float64
+	Slope2             float64
+	ReserveFactor      float64
+}
+
+// BorrowRate returns the annual variable borrow rate at utilization
+func (m *KinkModel) BorrowRate(utilization float64) float64 {
+	u := clampUtilization(utilization)
+	if m.OptimalUtilization <= 0 {
+		return m.BaseBorrowRate + m.Slope1 + m.Slope2*u
+	}
+	if u <= m.OptimalUtilization {
+		return m.BaseBorrowRate + m.Slope1*u/m.OptimalUtilization
+	}
+	excess := (u - m.OptimalUtilization) / (1 - m.OptimalUtilization)
+	return m.BaseBorrowRate + m.Slope1 + m.Slope2*excess
+}
+
+// SupplyRate returns the annual supply rate at utilization
+func (m *KinkModel) SupplyRate(utilization float64) float64 {
+	u := clampUtilization(utilization)
+	return m.BorrowRate(u) * u * (1 - m.ReserveFactor)
+}
+
+// CometModel is the Compound v3 supply rate curve, which is defined directly rather than
+// derived from the borrow rate
+type CometModel struct {
+	Kink      float64
+	Base      float64
+	SlopeLow  float64
+	SlopeHigh float64
+}
+
+// SupplyRate returns the annual supply rate at utilization
+func (m *CometModel) SupplyRate(utilization float64) float64 {
+	u := clampUtilization(utilization)
+	if u <= m.Kink {
+		return m.Base + m.SlopeLow*u
+	}
+	return m.Base + m.SlopeLow*m.Kink + m.SlopeHigh*(u-m.Kink)
+}
+
+// JumpRateModel is the borrow rate curve of Compound v2 and its forks: the borrow rate
+// rises by Multiplier per unit of utilization up to Kink and by JumpMultiplier beyond it
+type JumpRateModel struct {
+	Kink           float64
+	BaseRate       float64
+	Multiplier     float64
+	JumpMultiplier float64
+	ReserveFactor  float64
+}
+
+// BorrowRate returns the annual borrow rate at utilization
+func (m *JumpRateModel) BorrowRate(utilization float64) float64 {
+	u := clampUtilization(utilization)
+	if u <= m.Kink {
+		return m.BaseRate + m.Multiplier*u
+	}
+	return m.BaseRate + m.Multiplier*m.Kink + m.JumpMultiplier*(u-m.Kink)
+}
+
+// SupplyRate returns the annual supply rate at utilization
+func (m *JumpRateModel) SupplyRate(utilization float64) float64 {
+	u := clampUtilization(utilization)
+	return m.BorrowRate(u) * u * (1 - m.ReserveFactor)
+}
+
+// FixedModel is a supply rate that does not depend on utilization, such as a savings
+// rate or the yield of a vault measured from its share price
+type FixedModel struct {
+	Rate float64
+}
+
+// SupplyRate returns the rate at any utilization
+func (m *FixedModel) SupplyRate(utilization float64) float64 {
+	return m.Rate
+}
+
+// Kinds of rate models
+const (
+	KindKink     = "kink"
+	KindComet    = "comet"
+	KindJumpRate = "jump_rate"
+	KindFixed    = "fixed"
+)
+
+// Curve is the parameters of a rate model. Rates and utilizations are fractions. Kink,
+// comet and jump rate curves rise by Slope1 per unit of utilization up to Kink and by
+// Slope2 beyond it; the comet curve is that of the supply rate, the others that of the
+// borrow rate lenders earn a share of. Fixed curves only have a BaseRate.
+type Curve struct {
+	Kind          string
+	Kink          float64
+	BaseRate      float64
+	Slope1        float64
+	Slope2        float64
+	ReserveFactor float64
+}
+
+// Describe returns the curve of m, and false for models it does not know
+func Describe(m Model) (Curve, bool) {
+	switch m := m.(type) {
+	case *KinkModel:
+		return Curve{Kind: KindKink, Kink: m.OptimalUtilization, BaseRate: m.BaseBorrowRate, Slope1: m.Slope1, Slope2: m.Slope2, ReserveFactor: m.ReserveFactor}, true
+	case *CometModel:
+		return Curve{Kind: KindComet, Kink: m.Kink, BaseRate: m.Base, Slope1: m.SlopeLow, Slope2: m.SlopeHigh}, true
+	case *JumpRateModel:
+		return Curve{Kind: KindJumpRate, Kink: m.Kink, BaseRate: m.BaseRate, Slope1: m.Multiplier, Slope2: m.JumpMultiplier, ReserveFactor: m.ReserveFactor}, true
+	case *FixedModel:
+		return Curve{Kind: KindFixed, BaseRate: m.Rate}, true
+	}
+	return Curve{}, false
+}
+
+func clampUtilization(u float64) float64 {
+	if u < 0 {
+		return 0
+	}
+	if u > 1 {
+		return 1
+	}
+	return u
+}
+
+// Pool is the state of a lending pool. Amounts are in token base units.
+type Pool struct {
+	TotalSupply *big.Int
+	TotalBorrow *big.Int
+	Model       Model
+}
+
+// Impact describes the pool after a hypothetical deposit or withdrawal
+type Impact struct {
+	Amount      *big.Int
+	Utilization float64
+	SupplyRate  float64
+	// RateImpactBps is the change of the supply rate in basis points (negative for deposits)
+	RateImpactBps float64
+}
+
+// Utilization returns borrows / supply
+func (p *Pool) Utilization() float64 {
+	return utilization(p.TotalSupply, p.TotalBorrow)
+}
+
+// SupplyRate returns the current annual supply rate
+func (p *Pool) SupplyRate() float64 {
+	return p.Model.SupplyRate(p.Utilization())
+}
+
+// AvailableLiquidity returns the amount that can currently be withdrawn
+func (p *Pool) AvailableLiquidity() *big.Int {
+	available := new(big.Int).Sub(p.TotalSupply, p.TotalBorrow)
+	if available.Sign() < 0 {
+		return new(big.Int)
+	}
+	return available
+}
+
+// DepositImpact returns the pool state after depositing amount
+func (p *Pool) DepositImpact(amount *big.Int) Impact {
+	return p.impactAt(amount, new(big.Int).Add(p.TotalSupply, amount))
+}
+
+// WithdrawalImpact returns the pool state after withdrawing amount. ok is false when the
+// pool does not hold enough idle liquidity.
+func (p *Pool) WithdrawalImpact(amount *big.Int) (impact Impact, ok bool) {
+	if amount.Cmp(p.AvailableLiquidity()) > 0 {
+		return Impact{}, false
+	}
+	return p.impactAt(amount, new(big.Int).Sub(p.TotalSupply, amount)), true
+}
+
+func (p *Pool) impactAt(amount, supply *big.Int) Impact {
+	u := utilization(supply, p.TotalBorrow)
+	rate := p.Model.SupplyRate(u)
+	return Impact{
+		Amount:        new(big.Int).Set(amount),
+		Utilization:   u,
+		SupplyRate:    rate,
+		RateImpactBps: (rate - p.SupplyRate()) * 10000,
+	}
+}
+
+// MaxDeposit returns the largest deposit that moves the supply rate by at most maxImpactBps,
+// capped at maxDepositMultiple times the current supply
+func (p *Pool) MaxDeposit(maxImpactBps float64) *big.Int {
+	upper := new(big.Int).Mul(p.TotalSupply, big.NewInt(maxDepositMultiple))
+	return search(upper, func(amount *big.Int) bool {
+		return abs(p.DepositImpact(amount).RateImpactBps) <= maxImpactBps
+	})
+}
+
+// MaxWithdrawal returns the largest withdrawal that moves the supply rate by at most
+// maxImpactBps and can be served from idle liquidity
+func (p *Pool) MaxWithdrawal(maxImpactBps float64) *big.Int {
+	return search(p.AvailableLiquidity(), func(amount *big.Int) bool {
+		impact, ok := p.WithdrawalImpact(amount)
+		return ok && abs(impact.RateImpactBps) <= maxImpactBps
+	})
+}
+
+// Stress describes the pool after borrowers draw down idle liquidity
+type Stress struct {
+	// Shock is the rise of utilization, as a fraction
+	Shock       float64
+	Utilization float64
+	SupplyRate  float64
+	// RateImpactBps is the change of the supply rate in basis points
+	RateImpactBps float64
+	// AvailableLiquidity is the amount that can still be withdrawn
+	AvailableLiquidity *big.Int
+}
+
+// Stress returns the pool state after utilization rises by shock, a fraction of the
+// supply, capped at full utilization
+func (p *Pool) Stress(shock float64) Stress {
+	u := clampUtilization(p.Utilization() + shock)
+	borrow := new(big.Float).Mul(new(big.Float).SetInt(p.TotalSupply), big.NewFloat(u))
+	borrowed, _ := borrow.Int(nil)
+	available := new(big.Int).Sub(p.TotalSupply, borrowed)
+	if available.Sign() < 0 {
+		available.SetInt64(0)
+	}
+	rate := p.Model.SupplyRate(u)
+	return Stress{
+		Shock:              shock,
+		Utilization:        u,
+		SupplyRate:         rate,
+		RateImpactBps:      (rate - p.SupplyRate()) * 10000,
+		AvailableLiquidity: available,
+	}
+}
+
+// MaxStressedDeposit returns the largest deposit that can still be withdrawn in full
+// after utilization of the pool it joins rises by shock, capped at maxDepositMultiple
+// times the current supply. A deposit adds idle liquidity, but the shock then draws on a
+// larger supply: with supply S and borrows B, the shock borrows shock(S+D) more, which
+// leaves D withdrawable while S + D - B - shock(S+D) >= D, that is D <= (S-B)/shock - S.
+func (p *Pool) MaxStressedDeposit(shock float64) *big.Int {
+	upper := new(big.Int).Mul(p.TotalSupply, big.NewInt(maxDepositMultiple))
+	if shock <= 0 {
+		return upper
+	}
+	limit := new(big.Rat).Quo(new(big.Rat).SetInt(p.AvailableLiquidity()), new(big.Rat).SetFloat64(shock))
+	limit.Sub(limit, new(big.Rat).SetInt(p.TotalSupply))
+	if limit.Sign() <= 0 {
+		return new(big.Int)
+	}
+	amount := new(big.Int).Quo(limit.Num(), limit.Denom())
+	if amount.Cmp(upper) > 0 {
+		return upper
+	}
+	return amount
+}
+
+// search returns the largest amount in [0, upper] for which within holds, assuming within
+// is monotonic (true up to some amount, false beyond)
+func search(upper *big.Int, within func(*big.Int) bool) *big.Int {
+	if upper.Sign() <= 0 {
+		return new(big.Int)
+	}
+	if within(upper) {
+		return new(big.Int).Set(upper)
+	}
+
+	lo, hi := new(big.Int), new(big.Int).Set(upper)
+	one := big.NewInt(1)
+	for i := 0; i < maxSearchIterations && new(big.Int).Sub(hi, lo).Cmp(one) > 0; i++ {
+		mid := new(big.Int).Add(lo, hi)
+		mid.Rsh(mid, 1)
+		if within(mid) {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return lo
+}
+
+func utilization(supply, borrow *big.Int) float64 {
+	if supply == nil || borrow == nil || supply.Sign() <= 0 {
+		return 0
+	}
+	u, _ := new(big.Rat).SetFrac(borrow, supply).Float64()
+	return clampUtilization(u)
+}
+
+func abs(f float64) float64 {
+	if f < 0 {
+		return -f
+	}
+	return f
+}
@@ -0,0 +1,165 @@
+package irm
+
+import (
+	"math"
+	"math/big"
+	"testing"
+)
+
+func usdc(amount int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(amount), big.NewInt(1_000_000))
+}
+
+func aaveLikePool() *Pool {
+	return &Pool{
+		TotalSupply: usdc(100_000_000),
+		TotalBorrow: usdc(80_000_000),
+		Model: &KinkModel{
+			OptimalUtilization: 0.9,
+			BaseBorrowRate:     0,
+			Slope1:             0.06,
+			Slope2:             0.6,
+			ReserveFactor:      0.1,
+		},
+	}
+}
+
+func Test_KinkModel(t *testing.T) {
+	m := aaveLikePool().Model.(*KinkModel)
+
+	if got := m.BorrowRate(0.45); math.Abs(got-0.03) > 1e-12 {
+		t.Errorf("Expected 3%% borrow rate at half the kink, got %f", got)
+	}
+	if got := m.BorrowRate(0.95); math.Abs(got-0.36) > 1e-12 {
+		t.Errorf("Expected 36%% borrow rate half way up slope 2, got %f", got)
+	}
+	// 0.8 utilization: borrow 0.06*0.8/0.9, supply = borrow*0.8*0.9
+	want := 0.06 * 0.8 / 0.9 * 0.8 * 0.9
+	if got := m.SupplyRate(0.8); math.Abs(got-want) > 1e-12 {
+		t.Errorf("Expected supply rate %f, got %f", want, got)
+	}
+}
+
+func Test_CometModel(t *testing.T) {
+	m := &CometModel{Kink: 0.9, Base: 0, SlopeLow: 0.05, SlopeHigh: 3}
+	if got := m.SupplyRate(0.5); math.Abs(got-0.025) > 1e-12 {
+		t.Errorf("Expected 2.5%% below the kink, got %f", got)
+	}
+	if got := m.SupplyRate(0.95); math.Abs(got-(0.045+0.15)) > 1e-12 {
+		t.Errorf("Expected 19.5%% above the kink, got %f", got)
+	}
+}
+
+func Test_JumpRateModel(t *testing.T) {
+	m := &JumpRateModel{Kink: 0.8, BaseRate: 0.01, Multiplier: 0.05, JumpMultiplier: 1, ReserveFactor: 0.2}
+	if got := m.BorrowRate(0.4); math.Abs(got-0.03) > 1e-12 {
+		t.Errorf("Expected 3%% borrow rate at half the kink, got %f", got)
+	}
+	if got := m.BorrowRate(0.9); math.Abs(got-(0.05+0.1)) > 1e-12 {
+		t.Errorf("Expected 15%% borrow rate past the kink, got %f", got)
+	}
+	if got := m.SupplyRate(0.9); math.Abs(got-0.15*0.9*0.8) > 1e-12 {
+		t.Errorf("Expected supply rate %f, got %f", 0.15*0.9*0.8, got)
+	}
+}
+
+func Test_FixedModel(t *testing.T) {
+	pool := &Pool{TotalSupply: usdc(50_000_000), TotalBorrow: new(big.Int), Model: &FixedModel{Rate: 0.045}}
+	if got := pool.SupplyRate(); got != 0.045 {
+		t.Errorf("Expected the fixed rate, got %f", got)
+	}
+	if impact := pool.DepositImpact(usdc(10_000_000)); impact.RateImpactBps != 0 {
+		t.Errorf("Expected deposits not to move a fixed rate, got %f bps", impact.RateImpactBps)
+	}
+	if _, ok := pool.WithdrawalImpact(usdc(50_000_000)); !ok {
+		t.Errorf("Expected the whole supply to be withdrawable without borrows")
+	}
+}
+
+func Test_PoolImpact(t *testing.T) {
+	pool := aaveLikePool()
+
+	deposit := pool.DepositImpact(usdc(10_000_000))
+	if deposit.RateImpactBps >= 0 {
+		t.Errorf("Expected a deposit to lower the supply rate, got %f bps", deposit.RateImpactBps)
+	}
+
+	withdrawal, ok := pool.WithdrawalImpact(usdc(10_000_000))
+	if !ok || withdrawal.RateImpactBps <= 0 {
+		t.Errorf("Expected a withdrawal to raise the supply rate, got %f bps", withdrawal.RateImpactBps)
+	}
+
+	if _, ok := pool.WithdrawalImpact(usdc(20_000_001)); ok {
+		t.Errorf("Expected a withdrawal beyond idle liquidity to be rejected")
+	}
+}
+
+func Test_MaxDepositAndWithdrawal(t *testing.T) {
+	pool := aaveLikePool()
+
+	maxDeposit := pool.MaxDeposit(10)
+	if maxDeposit.Sign() <= 0 || maxDeposit.Cmp(pool.TotalSupply) >= 0 {
+		t.Fatalf("Unexpected max deposit %s", maxDeposit)
+	}
+	if impact := pool.DepositImpact(maxDeposit); math.Abs(impact.RateImpactBps) > 10 {
+		t.Errorf("Max deposit exceeds the impact bound: %f bps", impact.RateImpactBps)
+	}
+	over := new(big.Int).Add(maxDeposit, big.NewInt(1))
+	if impact := pool.DepositImpact(over); math.Abs(impact.RateImpactBps) <= 10 {
+		t.Errorf("Expected max deposit to be the largest amount within bound")
+	}
+
+	maxWithdrawal := pool.MaxWithdrawal(10)
+	if impact, ok := pool.WithdrawalImpact(maxWithdrawal); !ok || math.Abs(impact.RateImpactBps) > 10 {
+		t.Errorf("Max withdrawal exceeds the impact bound: %+v", impact)
+	}
+
+	// With no impact bound, withdrawals are limited by idle liquidity
+	if got := pool.MaxWithdrawal(1e9); got.Cmp(pool.AvailableLiquidity()) != 0 {
+		t.Errorf("Expected max withdrawal to equal available liquidity, got %s", got)
+	}
+}
+
+func Test_Describe(t *testing.T) {
+	curve, ok := Describe(aaveLikePool().Model)
+	if !ok || curve.Kind != KindKink || curve.Kink != 0.9 || curve.Slope2 != 0.6 || curve.ReserveFactor != 0.1 {
+		t.Errorf("Unexpected kink curve: %+v", curve)
+	}
+	curve, ok = Describe(&CometModel{Kink: 0.9, SlopeLow: 0.05, SlopeHigh: 3})
+	if !ok || curve.Kind != KindComet || curve.Slope1 != 0.05 || curve.Slope2 != 3 {
+		t.Errorf("Unexpected comet curve: %+v", curve)
+	}
+	if curve, ok = Describe(&FixedModel{Rate: 0.04}); !ok || curve.Kind != KindFixed || curve.BaseRate != 0.04 {
+		t.Errorf("Unexpected fixed curve: %+v", curve)
+	}
+	if _, ok := Describe(nil); ok {
+		t.Errorf("Expected an unknown model not to be described")
+	}
+}
+
+func Test_PoolStress(t *testing.T) {
+	pool := aaveLikePool()
+
+	stress := pool.Stress(0.1)
+	if math.Abs(stress.Utilization-0.9) > 1e-9 || stress.AvailableLiquidity.Cmp(usdc(10_000_000)) != 0 {
+		t.Errorf("Expected a 10 point shock to leave 10M idle at 90%%, got %+v", stress)
+	}
+	if stress.RateImpactBps <= 0 {
+		t.Errorf("Expected the shock to raise the supply rate, got %f bps", stress.RateImpactBps)
+	}
+	if full := pool.Stress(0.3); full.Utilization != 1 || full.AvailableLiquidity.Sign() != 0 {
+		t.Errorf("Expected shocks beyond idle liquidity to lock the pool, got %+v", full)
+	}
+
+	// 20M idle over a 10% shock of the supply leaves 100M withdrawable after the deposit
+	if got := pool.MaxStressedDeposit(0.1); new(big.Int).Sub(usdc(100_000_000), got).CmpAbs(big.NewInt(1)) > 0 {
+		t.Errorf("Expected a stressed deposit cap of 100M, got %s", got)
+	}
+	deposited := &Pool{TotalSupply: usdc(200_000_000), TotalBorrow: pool.TotalBorrow, Model: pool.Model}
+	if available := deposited.Stress(0.1).AvailableLiquidity; available.Cmp(usdc(100_000_000)) != 0 {
+		t.Errorf("Expected the capped deposit to stay withdrawable, got %s", available)
+	}
+	if got := pool.MaxStressedDeposit(0.2); got.Sign() != 0 {
+		t.Errorf("Expected no deposit to be withdrawable when the shock takes all idle liquidity, got %s", got)
+	}
+}
@@ -0,0 +1,211 @@
+// Package killswitch halts the tasks that move funds while operators respond to an
+// incident, such as a depeg or a protocol exploit, without stopping the performer:
+// monitoring keeps being served. Funds are halted by an operator, a halt kept across
+// restarts until it is lifted, or by the pause flag of the AVS service manager, which
+// lets the AVS halt every operator at once.
+package killswitch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+)
+
+const keyHalt = "killswitch:halt"
+
+// pausedABI is the pause flag of EigenLayer's Pausable, which service managers inherit
+var pausedABI = chain.MustParseABI(`[
+	{"name":"paused","type":"function","stateMutability":"view","inputs":[{"name":"index","type":"uint8"}],"outputs":[{"name":"","type":"bool"}]}
+]`)
+
+// Source tells who halted funds
+type Source string
+
+const (
+	SourceOperator       Source = "operator"
+	SourceServiceManager Source = "service_manager"
+)
+
+// Halt is why funds are halted
+type Halt struct {
+	Source Source    `json:"source"`
+	Reason string    `json:"reason"`
+	Since  time.Time `json:"since"`
+}
+
+// Config configures reading the pause flag of the service manager
+type Config struct {
+	Enabled bool   `yaml:"enabled"`
+	ChainID uint64 `yaml:"chainId"`
+
+	// ServiceManager is the address of the AVS service manager
+	ServiceManager string `yaml:"serviceManager"`
+
+	// PauseIndex is the bit of the pause flag halting rebalances
+	PauseIndex uint8 `yaml:"pauseIndex"`
+
+	// CacheTTL is how long a read flag is trusted, bounding both the RPC calls tasks make
+	// and how late a pause takes effect
+	CacheTTL time.Duration `yaml:"cacheTtl"`
+}
+
+// DefaultConfig reads the first pause bit at most every 12 seconds once enabled
+func DefaultConfig() Config {
+	return Config{CacheTTL: 12 * time.Second}
+}
+
+// Validate checks the config for values the flag cannot be read with
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.ChainID == 0 {
+		return fmt.Errorf("chainId is required")
+	}
+	if !common.IsHexAddress(c.ServiceManager) {
+		return fmt.Errorf("invalid serviceManager address %q", c.ServiceManager)
+	}
+	if c.CacheTTL <= 0 {
+		return fmt.Errorf("cacheTtl must be positive")
+	}
+	return nil
+}
+
+// Switch tells whether funds are halted. Operator halts are kept in the store.
+type Switch struct {
+	kv     store.KV
+	chains *chain.Manager
+	cfg    Config
+
+	mu       sync.Mutex
+	loaded   bool
+	operator *Halt
+
+	// the flag of the service manager as last read, paused since pausedAt
+	readAt   time.Time
+	pausedAt time.Time
+	paused   bool
+}
+
+// New creates a switch halted only by operators
+func New(kv store.KV) *Switch {
+	return &Switch{kv: kv}
+}
+
+// NewFromConfig creates a switch also halted by the pause flag of the service manager,
+// when cfg enables reading it
+func NewFromConfig(cfg Config, kv store.KV, chains *chain.Manager) *Switch {
+	s := New(kv)
+	if cfg.Enabled {
+		s.cfg, s.chains = cfg, chains
+	}
+	return s
+}
+
+// Halt halts funds until Resume is called, whatever the service manager says
+func (s *Switch) Halt(ctx context.Context, reason string) (*Halt, error) {
+	halt := &Halt{Source: SourceOperator, Reason: reason, Since: time.Now().UTC()}
+	encoded, err := json.Marshal(halt)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.kv.Set(ctx, []byte(keyHalt), encoded); err != nil {
+		return nil, fmt.Errorf("failed to store halt: %w", err)
+	}
+	s.operator, s.loaded = halt, true
+	return halt, nil
+}
+
+// Resume lifts the halt of operators. Funds stay halted while the service manager is
+// paused.
+func (s *Switch) Resume(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.kv.Delete(ctx, []byte(keyHalt)); err != nil && !errors.Is(err, store.ErrNotFound) {
+		return fmt.Errorf("failed to clear halt: %w", err)
+	}
+	s.operator, s.loaded = nil, true
+	return nil
+}
+
+// Status returns why funds are halted, nil when they are not. It fails when the pause
+// flag cannot be read, since funds may then be halted without the performer knowing.
+func (s *Switch) Status(ctx context.Context) (*Halt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.loadLocked(ctx); err != nil {
+		return nil, err
+	}
+	if s.operator != nil {
+		halt := *s.operator
+		return &halt, nil
+	}
+	if s.chains == nil {
+		return nil, nil
+	}
+
+	if time.Since(s.readAt) >= s.cfg.CacheTTL {
+		paused, err := s.readFlag(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if paused && !s.paused {
+			s.pausedAt = time.Now().UTC()
+		}
+		s.paused, s.readAt = paused, time.Now()
+	}
+	if !s.paused {
+		return nil, nil
+	}
+	return &Halt{
+		Source: SourceServiceManager,
+		Reason: fmt.Sprintf("service manager %s on chain %d is paused", s.cfg.ServiceManager, s.cfg.ChainID),
+		Since:  s.pausedAt,
+	}, nil
+}
+
+func (s *Switch) loadLocked(ctx context.Context) error {
+	if s.loaded {
+		return nil
+	}
+	encoded, err := s.kv.Get(ctx, []byte(keyHalt))
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+	case err != nil:
+		return fmt.Errorf("failed to load halt: %w", err)
+	default:
+		var halt Halt
+		if err := json.Unmarshal(encoded, &halt); err != nil {
+			return fmt.Errorf("failed to decode halt: %w", err)
+		}
+		s.operator = &halt
+	}
+	s.loaded = true
+	return nil
+}
+
+func (s *Switch) readFlag(ctx context.Context) (bool, error) {
+	client, err := s.chains.Client(s.cfg.ChainID)
+	if err != nil {
+		return false, err
+	}
+	values, err := chain.CallView(ctx, client, common.HexToAddress(s.cfg.ServiceManager), pausedABI, "paused", s.cfg.PauseIndex)
+	if err != nil {
+		return false, fmt.Errorf("failed to read the pause flag: %w", err)
+	}
+	paused, ok := values[0].(bool)
+	if !ok {
+		return false, fmt.Errorf("unexpected paused output type %T", values[0])
+	}
+	return paused, nil
+}
@@ -0,0 +1,96 @@
+package killswitch
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/chain/chaintest"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+)
+
+const serviceManager = "0x0000000000000000000000000000000000000a75"
+
+func Test_ConfigValidate(t *testing.T) {
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Errorf("Expected the default config to be valid, got %v", err)
+	}
+	valid := Config{Enabled: true, ChainID: 1, ServiceManager: serviceManager, CacheTTL: time.Second}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected %+v to be valid, got %v", valid, err)
+	}
+	for name, mutate := range map[string]func(*Config){
+		"chain":   func(c *Config) { c.ChainID = 0 },
+		"address": func(c *Config) { c.ServiceManager = "manager" },
+		"ttl":     func(c *Config) { c.CacheTTL = 0 },
+	} {
+		cfg := valid
+		mutate(&cfg)
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("Expected an invalid %s to be rejected", name)
+		}
+	}
+}
+
+func Test_OperatorHaltSurvivesRestarts(t *testing.T) {
+	ctx := context.Background()
+	kv := store.NewMemoryKV()
+	s := New(kv)
+
+	if halt, err := s.Status(ctx); err != nil || halt != nil {
+		t.Fatalf("Expected funds not to be halted, got %+v, %v", halt, err)
+	}
+	if _, err := s.Halt(ctx, "usdc depeg"); err != nil {
+		t.Fatalf("Halt failed: %v", err)
+	}
+
+	restarted := New(kv)
+	halt, err := restarted.Status(ctx)
+	if err != nil || halt == nil || halt.Source != SourceOperator || halt.Reason != "usdc depeg" || halt.Since.IsZero() {
+		t.Fatalf("Expected the halt to be kept, got %+v, %v", halt, err)
+	}
+
+	if err := restarted.Resume(ctx); err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+	if halt, err := New(kv).Status(ctx); err != nil || halt != nil {
+		t.Errorf("Expected the halt to be lifted, got %+v, %v", halt, err)
+	}
+}
+
+func Test_ServiceManagerPause(t *testing.T) {
+	ctx := context.Background()
+	contracts := chaintest.NewContracts(1)
+	chains := chain.NewManager()
+	chains.Register(1, "ethereum", contracts)
+	manager := common.HexToAddress(serviceManager)
+	contracts.Stub(t, manager, pausedABI, "paused", true)
+
+	cfg := Config{Enabled: true, ChainID: 1, ServiceManager: serviceManager, CacheTTL: time.Nanosecond}
+	s := NewFromConfig(cfg, store.NewMemoryKV(), chains)
+	halt, err := s.Status(ctx)
+	if err != nil || halt == nil || halt.Source != SourceServiceManager || halt.Since.IsZero() {
+		t.Fatalf("Expected the pause to halt funds, got %+v, %v", halt, err)
+	}
+
+	// operators cannot lift a pause of the service manager
+	if err := s.Resume(ctx); err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+	if halt, _ := s.Status(ctx); halt == nil {
+		t.Errorf("Expected funds to stay halted while paused")
+	}
+
+	contracts.Stub(t, manager, pausedABI, "paused", false)
+	if halt, err := s.Status(ctx); err != nil || halt != nil {
+		t.Errorf("Expected the unpause to lift the halt, got %+v, %v", halt, err)
+	}
+
+	// an unreadable flag fails rather than letting funds move
+	s = NewFromConfig(Config{Enabled: true, ChainID: 10, ServiceManager: serviceManager, CacheTTL: time.Second}, store.NewMemoryKV(), chains)
+	if _, err := s.Status(ctx); err == nil {
+		t.Errorf("Expected an unreadable flag to fail")
+	}
+}
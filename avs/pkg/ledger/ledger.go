@@ -0,0 +1,370 @@
+// Package ledger records the realized outcome of every rebalance the performer executed:
+// the gas and bridge fees it paid, the supply rates of the funds before and after, and
+// the net benefit once the extra yield accrued, so operators and the DAO can show over any
+// window whether rebalancing created value.
+package ledger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/pricefeed"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+)
+
+const prefixEntry = "ledger:"
+
+// usdcDecimals and nativeDecimals are the decimals of USDC and of the gas token of every
+// supported chain
+const (
+	usdcDecimals   = 6
+	nativeDecimals = 18
+)
+
+// year is the period supply rates are quoted over
+const year = 365 * 24 * time.Hour
+
+// Config configures the ledger
+type Config struct {
+	Enabled bool `yaml:"enabled"`
+
+	// NativePriceFeeds are Chainlink USD aggregators of the gas token of each chain, gas
+	// costs are priced in USDC with. Gas on chains without one is left unpriced.
+	NativePriceFeeds map[uint64]string `yaml:"nativePriceFeeds"`
+
+	// Heartbeat is the age after which a gas token price is stale and not used
+	Heartbeat time.Duration `yaml:"heartbeat"`
+}
+
+// DefaultConfig prices ETH gas on Ethereum, Base and Arbitrum
+func DefaultConfig() Config {
+	return Config{
+		Enabled: true,
+		NativePriceFeeds: map[uint64]string{
+			1:     "0x5f4eC3Df9cbd43714FE2740f5E3616155c5b8419",
+			8453:  "0x71041dddad3595F9CEd3DcCFBe3D1F4b0a16Bb70",
+			42161: "0x639Fe6ab55C921f74e7fac1ee960C0B6293ba612",
+		},
+		Heartbeat: 25 * time.Hour,
+	}
+}
+
+// Validate checks the config for values the ledger cannot price gas with
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	for chainID, feed := range c.NativePriceFeeds {
+		if !common.IsHexAddress(feed) {
+			return fmt.Errorf("nativePriceFeeds[%d] must be an address", chainID)
+		}
+	}
+	if c.Heartbeat <= 0 {
+		return fmt.Errorf("heartbeat must be positive")
+	}
+	return nil
+}
+
+// GasCost is the gas a rebalance paid on one chain. CostUSDC is nil when the gas token
+// could not be priced.
+type GasCost struct {
+	ChainID  uint64   `json:"chainId"`
+	GasUsed  uint64   `json:"gasUsed"`
+	CostWei  *big.Int `json:"costWei"`
+	CostUSDC *big.Int `json:"costUsdc,omitempty"`
+}
+
+// Entry is the outcome of one executed rebalance, once its transactions were final and
+// its balance checks passed. Amounts are in USDC base units and rates are annual
+// fractions (0.05 = 5%).
+type Entry struct {
+	ExecutionID    string         `json:"executionId"`
+	Owner          common.Address `json:"owner"`
+	SourceProtocol string         `json:"sourceProtocol,omitempty"`
+	SourceChain    uint64         `json:"sourceChain"`
+	TargetProtocol string         `json:"targetProtocol"`
+	TargetChain    uint64         `json:"targetChain"`
+	Amount         *big.Int       `json:"amount"`
+	ExecutedAt     time.Time      `json:"executedAt"`
+
+	Gas []GasCost `json:"gas"`
+	// BridgeFee is the USDC the move lost, to bridge fees and slippage, as the balance
+	// checks measured it
+	BridgeFee *big.Int `json:"bridgeFee"`
+
+	// RateBefore is the supply rate of the source market before the withdrawal, 0 for
+	// funds that sat in the wallet, and RateAfter that of the target market once the
+	// deposit landed
+	RateBefore float64 `json:"rateBefore"`
+	RateAfter  float64 `json:"rateAfter"`
+
+	// Position is the balance of the target market once the deposit landed, which the
+	// interest it accrues since is measured from. Entries recorded without it are nil.
+	Position *big.Int `json:"position,omitempty"`
+}
+
+// Deposited is the amount that reached the target market
+func (e *Entry) Deposited() *big.Int {
+	return new(big.Int).Sub(e.Amount, e.BridgeFee)
+}
+
+// GasUSDC returns the gas cost of the rebalance in USDC, and false when gas on one of its
+// chains could not be priced, in which case the priced part is returned
+func (e *Entry) GasUSDC() (*big.Int, bool) {
+	total, priced := new(big.Int), true
+	for _, gas := range e.Gas {
+		if gas.CostUSDC == nil {
+			priced = false
+			continue
+		}
+		total.Add(total, gas.CostUSDC)
+	}
+	return total, priced
+}
+
+// Ledger records entries in a store.KV
+type Ledger struct {
+	cfg    Config
+	kv     store.KV
+	chains *chain.Manager
+	feeds  map[uint64]common.Address
+}
+
+// New creates a ledger recording in kv and pricing gas through chains
+func New(cfg Config, kv store.KV, chains *chain.Manager) *Ledger {
+	feeds := make(map[uint64]common.Address, len(cfg.NativePriceFeeds))
+	for chainID, feed := range cfg.NativePriceFeeds {
+		feeds[chainID] = common.HexToAddress(feed)
+	}
+	return &Ledger{cfg: cfg, kv: kv, chains: chains, feeds: feeds}
+}
+
+// NewFromConfig creates a ledger, or returns nil when it is disabled
+func NewFromConfig(cfg Config, kv store.KV, chains *chain.Manager) *Ledger {
+	if !cfg.Enabled {
+		return nil
+	}
+	return New(cfg, kv, chains)
+}
+
+func entryKey(executionID string) []byte {
+	return []byte(prefixEntry + executionID)
+}
+
+// PriceGas converts costWei of the gas token of chainID into USDC base units, failing on
+// chains without a feed or with a stale one
+func (l *Ledger) PriceGas(ctx context.Context, chainID uint64, costWei *big.Int) (*big.Int, error) {
+	feed, ok := l.feeds[chainID]
+	if !ok {
+		return nil, fmt.Errorf("no gas token price feed on chain %d", chainID)
+	}
+	quote, err := pricefeed.NewChainlinkSource(l.chains, chainID, feed, l.cfg.Heartbeat).Quote(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if quote.Stale {
+		return nil, fmt.Errorf("gas token price on chain %d is stale", chainID)
+	}
+	cost := new(big.Rat).Mul(new(big.Rat).SetInt(costWei), quote.Price)
+	cost.Mul(cost, new(big.Rat).SetFrac(big.NewInt(1), new(big.Int).Exp(big.NewInt(10), big.NewInt(nativeDecimals-usdcDecimals), nil)))
+	return new(big.Int).Quo(cost.Num(), cost.Denom()), nil
+}
+
+// Record adds entry unless its execution was already recorded, as resuming a finished
+// execution verifies it again
+func (l *Ledger) Record(ctx context.Context, entry Entry) error {
+	_, err := l.kv.Get(ctx, entryKey(entry.ExecutionID))
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, store.ErrNotFound) {
+		return err
+	}
+	encoded, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := l.kv.Set(ctx, entryKey(entry.ExecutionID), encoded); err != nil {
+		return fmt.Errorf("failed to record execution %s: %w", entry.ExecutionID, err)
+	}
+	return nil
+}
+
+// Entries returns every recorded entry in the order the rebalances were executed
+func (l *Ledger) Entries(ctx context.Context) ([]Entry, error) {
+	var entries []Entry
+	err := l.kv.Iterate(ctx, []byte(prefixEntry), func(key, value []byte) error {
+		var entry Entry
+		if err := json.Unmarshal(value, &entry); err != nil {
+			return fmt.Errorf("failed to decode ledger entry %s: %w", key, err)
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].ExecutedAt.Before(entries[j].ExecutedAt) })
+	return entries, nil
+}
+
+// Holding returns the entries, ordered by execution, whose funds still sit in their
+// target market: no later rebalance of their owner moved funds out of the market or
+// deposited into it again
+func Holding(entries []Entry) []Entry {
+	type market struct {
+		owner    common.Address
+		protocol string
+		chainID  uint64
+	}
+	latest := map[market]int{}
+	for i, entry := range entries {
+		if entry.SourceProtocol != "" {
+			delete(latest, market{entry.Owner, entry.SourceProtocol, entry.SourceChain})
+		}
+		latest[market{entry.Owner, entry.TargetProtocol, entry.TargetChain}] = i
+	}
+	indexes := make([]int, 0, len(latest))
+	for _, i := range latest {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	holding := make([]Entry, len(indexes))
+	for i, index := range indexes {
+		holding[i] = entries[index]
+	}
+	return holding
+}
+
+// Outcome is what one rebalance contributed to a report. Costs count in the window the
+// rebalance was executed in; its yield uplift accrues over the part of the window the
+// funds stayed in the target market.
+type Outcome struct {
+	Entry
+
+	// YieldUplift is the interest the deposited funds earned over what they would have
+	// at RateBefore, within the window
+	YieldUplift *big.Int
+	// NetBenefit is YieldUplift less the costs counted in the window
+	NetBenefit *big.Int
+	// GasPriced is false when part of the gas of the rebalance could not be priced
+	GasPriced bool
+}
+
+// Report is the profit and loss of rebalancing over a window, in USDC base units
+type Report struct {
+	From, To time.Time
+
+	// Rebalances were executed in the window; earlier ones still holding their funds only
+	// add their yield uplift
+	Rebalances  int
+	Volume      *big.Int
+	GasCost     *big.Int
+	BridgeFees  *big.Int
+	YieldUplift *big.Int
+	NetBenefit  *big.Int
+
+	// UnpricedGas is the number of rebalances whose gas was not all priced
+	UnpricedGas int
+
+	Outcomes []Outcome
+}
+
+// Report sums the outcomes of the rebalances over [from, to)
+func (l *Ledger) Report(ctx context.Context, from, to time.Time) (*Report, error) {
+	entries, err := l.Entries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return Summarize(entries, from, to), nil
+}
+
+// Summarize sums the outcomes of entries, ordered by execution, over [from, to). The
+// yield uplift of a rebalance accrues until the funds move out of its target market
+// again, or the window ends.
+func Summarize(entries []Entry, from, to time.Time) *Report {
+	report := &Report{
+		From:        from,
+		To:          to,
+		Volume:      new(big.Int),
+		GasCost:     new(big.Int),
+		BridgeFees:  new(big.Int),
+		YieldUplift: new(big.Int),
+		NetBenefit:  new(big.Int),
+		Outcomes:    []Outcome{},
+	}
+	for i, entry := range entries {
+		if !entry.ExecutedAt.Before(to) {
+			break
+		}
+		end := to
+		for _, later := range entries[i+1:] {
+			if later.Owner == entry.Owner && later.SourceProtocol == entry.TargetProtocol && later.SourceChain == entry.TargetChain {
+				end = minTime(end, later.ExecutedAt)
+				break
+			}
+		}
+		start := maxTime(entry.ExecutedAt, from)
+		executed := !entry.ExecutedAt.Before(from)
+		if !end.After(start) && !executed {
+			continue
+		}
+
+		outcome := Outcome{Entry: entry, YieldUplift: new(big.Int), NetBenefit: new(big.Int), GasPriced: true}
+		if end.After(start) {
+			outcome.YieldUplift = accrued(entry.Deposited(), entry.RateAfter-entry.RateBefore, end.Sub(start))
+		}
+		outcome.NetBenefit.Set(outcome.YieldUplift)
+		if executed {
+			gas, priced := entry.GasUSDC()
+			outcome.GasPriced = priced
+			outcome.NetBenefit.Sub(outcome.NetBenefit, gas)
+			outcome.NetBenefit.Sub(outcome.NetBenefit, entry.BridgeFee)
+
+			report.Rebalances++
+			report.Volume.Add(report.Volume, entry.Amount)
+			report.GasCost.Add(report.GasCost, gas)
+			report.BridgeFees.Add(report.BridgeFees, entry.BridgeFee)
+			if !priced {
+				report.UnpricedGas++
+			}
+		}
+		report.YieldUplift.Add(report.YieldUplift, outcome.YieldUplift)
+		report.NetBenefit.Add(report.NetBenefit, outcome.NetBenefit)
+		report.Outcomes = append(report.Outcomes, outcome)
+	}
+	return report
+}
+
+// accrued is the simple interest amount earns at rate over d, rounded towards zero
+func accrued(amount *big.Int, rate float64, d time.Duration) *big.Int {
+	if rate == 0 || math.IsNaN(rate) || math.IsInf(rate, 0) {
+		return new(big.Int)
+	}
+	interest := new(big.Float).SetInt(amount)
+	interest.Mul(interest, big.NewFloat(rate*d.Seconds()/year.Seconds()))
+	out, _ := interest.Int(nil)
+	return out
+}
+
+func minTime(a, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+	return a
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
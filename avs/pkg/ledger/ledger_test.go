@@ -0,0 +1,150 @@
+package ledger
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/chain/chaintest"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+)
+
+var aggregatorABI = chain.MustParseABI(`[
+	{"name":"decimals","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint8"}]},
+	{"name":"latestRoundData","type":"function","stateMutability":"view","inputs":[],
+	 "outputs":[
+		{"name":"roundId","type":"uint80"},
+		{"name":"answer","type":"int256"},
+		{"name":"startedAt","type":"uint256"},
+		{"name":"updatedAt","type":"uint256"},
+		{"name":"answeredInRound","type":"uint80"}
+	]}
+]`)
+
+const now = 1_800_000_000
+
+func usdc(amount int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(amount), big.NewInt(1_000_000))
+}
+
+func Test_LedgerRecordsAndPricesGas(t *testing.T) {
+	feed := common.HexToAddress("0x20")
+	contracts := chaintest.NewContracts(1)
+	contracts.SetHead(100, now)
+	contracts.Stub(t, feed, aggregatorABI, "decimals", uint8(8))
+	contracts.Stub(t, feed, aggregatorABI, "latestRoundData",
+		big.NewInt(1), big.NewInt(2000_00000000), big.NewInt(0), big.NewInt(now-60), big.NewInt(1))
+	chains := chain.NewManager()
+	chains.Register(1, "ethereum", contracts)
+
+	cfg := DefaultConfig()
+	cfg.NativePriceFeeds = map[uint64]string{1: feed.Hex()}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Expected the config to be valid: %v", err)
+	}
+	l := NewFromConfig(cfg, store.NewMemoryKV(), chains)
+
+	// 0.001 ETH at $2000
+	cost, err := l.PriceGas(context.Background(), 1, big.NewInt(1e15))
+	if err != nil || cost.Cmp(usdc(2)) != 0 {
+		t.Errorf("Expected $2 of gas, got %v (%v)", cost, err)
+	}
+	if _, err := l.PriceGas(context.Background(), 8453, big.NewInt(1e15)); err == nil {
+		t.Errorf("Expected gas on a chain without a feed not to be priced")
+	}
+
+	entry := Entry{ExecutionID: "task-1", Amount: usdc(1000), BridgeFee: new(big.Int), ExecutedAt: time.Unix(now, 0).UTC()}
+	if err := l.Record(context.Background(), entry); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	again := entry
+	again.Amount = usdc(5)
+	if err := l.Record(context.Background(), again); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	entries, err := l.Entries(context.Background())
+	if err != nil || len(entries) != 1 || entries[0].Amount.Cmp(usdc(1000)) != 0 {
+		t.Errorf("Expected the first record of the execution to be kept, got %+v (%v)", entries, err)
+	}
+
+	if NewFromConfig(Config{}, store.NewMemoryKV(), chains) != nil {
+		t.Errorf("Expected a disabled ledger to be nil")
+	}
+	cfg.NativePriceFeeds = map[uint64]string{1: "feed"}
+	if err := cfg.Validate(); err == nil {
+		t.Errorf("Expected a feed that is not an address to be rejected")
+	}
+}
+
+func Test_Holding(t *testing.T) {
+	owner, other := common.HexToAddress("0xaa"), common.HexToAddress("0xbb")
+	entries := []Entry{
+		{ExecutionID: "a", Owner: owner, TargetProtocol: "aave_v3", TargetChain: 1},
+		{ExecutionID: "b", Owner: owner, TargetProtocol: "compound_v3", TargetChain: 1},
+		{ExecutionID: "c", Owner: other, SourceProtocol: "compound_v3", SourceChain: 1, TargetProtocol: "aave_v3", TargetChain: 8453},
+		// moves the funds of a out
+		{ExecutionID: "d", Owner: owner, SourceProtocol: "aave_v3", SourceChain: 1, TargetProtocol: "aave_v3", TargetChain: 8453},
+		// deposits into the market of b again
+		{ExecutionID: "e", Owner: owner, TargetProtocol: "compound_v3", TargetChain: 1},
+	}
+	var ids []string
+	for _, entry := range Holding(entries) {
+		ids = append(ids, entry.ExecutionID)
+	}
+	if strings.Join(ids, ",") != "c,d,e" {
+		t.Errorf("Expected c, d and e to still hold their funds, got %v", ids)
+	}
+}
+
+func Test_Summarize(t *testing.T) {
+	owner := common.HexToAddress("0xaa")
+	start := time.Unix(now, 0)
+	day := 24 * time.Hour
+	entries := []Entry{
+		{
+			// earns 2% more on 9,990 USDC for 73 days, until it moves again
+			ExecutionID: "a", Owner: owner,
+			SourceProtocol: "aave_v3", SourceChain: 1, TargetProtocol: "compound_v3", TargetChain: 8453,
+			Amount: usdc(10_000), BridgeFee: usdc(10), ExecutedAt: start,
+			Gas:        []GasCost{{ChainID: 1, CostUSDC: usdc(5)}, {ChainID: 8453, CostUSDC: big.NewInt(500_000)}},
+			RateBefore: 0.03, RateAfter: 0.05,
+		},
+		{
+			ExecutionID: "b", Owner: owner,
+			SourceProtocol: "compound_v3", SourceChain: 8453, TargetProtocol: "aave_v3", TargetChain: 8453,
+			Amount: usdc(9_990), BridgeFee: new(big.Int), ExecutedAt: start.Add(73 * day),
+			Gas:        []GasCost{{ChainID: 8453}},
+			RateBefore: 0.05, RateAfter: 0.05,
+		},
+	}
+
+	report := Summarize(entries, start, start.Add(365*day))
+	// 9,990 * 2% * 73/365
+	uplift := big.NewInt(39_960_000)
+	if report.Rebalances != 2 || report.Volume.Cmp(usdc(19_990)) != 0 {
+		t.Errorf("Expected two rebalances moving 19,990 USDC, got %d moving %v", report.Rebalances, report.Volume)
+	}
+	if report.YieldUplift.Cmp(uplift) != 0 {
+		t.Errorf("Expected an uplift of %v, got %v", uplift, report.YieldUplift)
+	}
+	if report.GasCost.Cmp(big.NewInt(5_500_000)) != 0 || report.BridgeFees.Cmp(usdc(10)) != 0 || report.UnpricedGas != 1 {
+		t.Errorf("Expected $5.50 of gas with one rebalance unpriced and $10 of fees, got %+v", report)
+	}
+	net := new(big.Int).Sub(uplift, big.NewInt(15_500_000))
+	if report.NetBenefit.Cmp(net) != 0 || len(report.Outcomes) != 2 || report.Outcomes[0].NetBenefit.Cmp(net) != 0 {
+		t.Errorf("Expected a net benefit of %v, got %+v", net, report)
+	}
+
+	// a window after the first rebalance counts only the uplift it still earns
+	report = Summarize(entries, start.Add(36*day+12*time.Hour), start.Add(365*day))
+	if report.Rebalances != 1 || report.YieldUplift.Cmp(big.NewInt(19_980_000)) != 0 || report.GasCost.Sign() != 0 {
+		t.Errorf("Expected half of the uplift without the costs of the first rebalance, got %+v", report)
+	}
+	if report = Summarize(entries, start.Add(-day), start); report.Rebalances != 0 || len(report.Outcomes) != 0 {
+		t.Errorf("Expected nothing before the first rebalance, got %+v", report)
+	}
+}
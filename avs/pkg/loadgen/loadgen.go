@@ -0,0 +1,334 @@
+// Package loadgen fires concurrent tasks of mixed types at a performer over gRPC and
+// measures its throughput and latency, for capacity planning and for catching
+// performance regressions before they reach operators.
+package loadgen
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/najnomics/crosscow-avs/pkg/client"
+)
+
+// Task is a payload of the mix, sent Weight times for every task of weight one
+type Task struct {
+	Payload *client.Payload
+	Weight  int
+}
+
+// mixEntry is a Task as mix files hold it
+type mixEntry struct {
+	Type       client.TaskType        `json:"type"`
+	Parameters map[string]interface{} `json:"parameters"`
+	Weight     int                    `json:"weight"`
+}
+
+// ParseMix parses a mix file, a JSON array of payloads with an optional weight:
+//
+//	[{"type":"yield_monitoring","parameters":{"protocol":"all","token":"USDC"},"weight":3}]
+func ParseMix(data []byte) ([]Task, error) {
+	var entries []mixEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("mix must be a JSON array of payloads: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("mix has no tasks")
+	}
+	mix := make([]Task, len(entries))
+	for i, e := range entries {
+		if e.Type == "" {
+			return nil, fmt.Errorf("mix[%d]: missing type", i)
+		}
+		if e.Weight < 0 {
+			return nil, fmt.Errorf("mix[%d]: weight must not be negative", i)
+		}
+		if e.Weight == 0 {
+			e.Weight = 1
+		}
+		if e.Parameters == nil {
+			e.Parameters = map[string]interface{}{}
+		}
+		mix[i] = Task{Payload: &client.Payload{Type: e.Type, Parameters: e.Parameters}, Weight: e.Weight}
+	}
+	return mix, nil
+}
+
+// DefaultMix is a read-heavy mix of the task types a performer answers from market
+// state alone, weighted like the traffic of an aggregator polling yields
+func DefaultMix() []Task {
+	tasks := []struct {
+		task   client.Task
+		weight int
+	}{
+		{client.YieldMonitoring{Protocol: client.ProtocolAll, Token: "USDC"}, 4},
+		{client.YieldMonitoring{Protocol: "aave_v3", ChainID: 1, Token: "USDC"}, 4},
+		{client.CrossChainYieldCheck{SourceChain: 1, TargetChain: 8453, Amount: "10000000000"}, 3},
+		{client.YieldRanking{Token: "USDC"}, 2},
+		{client.LiquidityDepthAnalysis{Protocol: "aave_v3", ChainID: 1, Token: "USDC"}, 2},
+		{client.AllocationOptimization{Token: "USDC", Amount: "100000000000"}, 1},
+		{client.FullMarketSnapshot{Token: "USDC"}, 1},
+		{client.Capabilities{}, 1},
+	}
+	mix := make([]Task, len(tasks))
+	for i, t := range tasks {
+		payload, err := client.Build(t.task)
+		if err != nil {
+			panic(fmt.Sprintf("loadgen: invalid default task: %v", err))
+		}
+		mix[i] = Task{Payload: payload, Weight: t.weight}
+	}
+	return mix
+}
+
+// Config configures a load run. The run ends when Tasks were sent or Duration elapsed,
+// whichever comes first; at least one of them must be set.
+type Config struct {
+	// Concurrency is the number of tasks in flight at once
+	Concurrency int
+
+	// Tasks is the number of tasks to send, unlimited when zero
+	Tasks int
+
+	// Duration is how long to send tasks for, unlimited when zero
+	Duration time.Duration
+
+	// Timeout bounds each task
+	Timeout time.Duration
+
+	// RunID prefixes task ids, which performers deduplicate, so runs against one
+	// performer must differ
+	RunID string
+
+	Mix []Task
+}
+
+// Validate checks the config describes a run that ends
+func (c *Config) Validate() error {
+	if c.Concurrency < 1 {
+		return fmt.Errorf("concurrency must be at least 1")
+	}
+	if c.Tasks < 0 || c.Duration < 0 || c.Timeout < 0 {
+		return fmt.Errorf("tasks, duration and timeout must not be negative")
+	}
+	if c.Tasks == 0 && c.Duration == 0 {
+		return fmt.Errorf("tasks or duration is required")
+	}
+	if len(c.Mix) == 0 {
+		return fmt.Errorf("mix has no tasks")
+	}
+	for i, t := range c.Mix {
+		if t.Payload == nil || t.Weight < 1 {
+			return fmt.Errorf("mix[%d]: a payload and a positive weight are required", i)
+		}
+	}
+	return nil
+}
+
+// schedule returns the mix index of each slot of a cycle of the mix, which interleaves
+// task types in proportion to their weight
+func schedule(mix []Task) []int {
+	var slots []int
+	remaining := make([]int, len(mix))
+	for i, t := range mix {
+		remaining[i] = t.Weight
+	}
+	for left := true; left; {
+		left = false
+		for i := range mix {
+			if remaining[i] > 0 {
+				slots = append(slots, i)
+				remaining[i]--
+				left = true
+			}
+		}
+	}
+	return slots
+}
+
+// sample is the outcome of one task
+type sample struct {
+	taskType client.TaskType
+	latency  time.Duration
+	err      error
+}
+
+// Run sends the tasks of cfg through c and reports their outcome. Tasks fail when the
+// performer rejects them, errors or times out, or answers with an error result.
+// Cancelling ctx ends the run early, reporting the tasks sent so far.
+func Run(ctx context.Context, c *client.Client, cfg Config) (*Report, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Duration)
+		defer cancel()
+	}
+	slots := schedule(cfg.Mix)
+
+	var (
+		mu      sync.Mutex
+		next    int
+		samples []sample
+		wg      sync.WaitGroup
+	)
+	// claim returns the number of the next task to send, false when the run is over
+	claim := func() (int, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		if ctx.Err() != nil || (cfg.Tasks > 0 && next >= cfg.Tasks) {
+			return 0, false
+		}
+		next++
+		return next - 1, true
+	}
+
+	start := time.Now()
+	for w := 0; w < cfg.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				n, ok := claim()
+				if !ok {
+					return
+				}
+				task := cfg.Mix[slots[n%len(slots)]]
+				s := send(ctx, c, fmt.Sprintf("%s-%d", cfg.RunID, n), task.Payload, cfg.Timeout)
+				// tasks cut short by the end of the run are not counted
+				if s.err != nil && ctx.Err() != nil {
+					return
+				}
+				mu.Lock()
+				samples = append(samples, s)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return newReport(samples, time.Since(start)), nil
+}
+
+// send runs one task and times it
+func send(ctx context.Context, c *client.Client, taskID string, payload *client.Payload, timeout time.Duration) sample {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	started := time.Now()
+	raw, err := c.Execute(ctx, taskID, payload)
+	s := sample{taskType: payload.Type, latency: time.Since(started), err: err}
+	if err != nil {
+		return s
+	}
+	result, err := client.DecodeResult(raw)
+	if err != nil {
+		// ABI encoded results are not decoded, and succeeded when they arrived
+		if json.Valid(raw) {
+			s.err = err
+		}
+		return s
+	}
+	if report := result.Err(); report != nil {
+		s.err = report
+	}
+	return s
+}
+
+// Latency summarizes the latencies of a set of tasks
+type Latency struct {
+	P50 time.Duration `json:"p50"`
+	P90 time.Duration `json:"p90"`
+	P99 time.Duration `json:"p99"`
+	Max time.Duration `json:"max"`
+}
+
+func newLatency(latencies []time.Duration) Latency {
+	if len(latencies) == 0 {
+		return Latency{}
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	return Latency{
+		P50: percentile(latencies, 0.50),
+		P90: percentile(latencies, 0.90),
+		P99: percentile(latencies, 0.99),
+		Max: latencies[len(latencies)-1],
+	}
+}
+
+// percentile returns the nearest rank p percentile of sorted
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(p*float64(len(sorted))+0.999999) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
+
+// TypeReport is the outcome of the tasks of one type
+type TypeReport struct {
+	Tasks   int     `json:"tasks"`
+	Failed  int     `json:"failed"`
+	Latency Latency `json:"latency"`
+}
+
+// Report is the outcome of a load run
+type Report struct {
+	Tasks   int           `json:"tasks"`
+	Failed  int           `json:"failed"`
+	Elapsed time.Duration `json:"elapsed"`
+	// Throughput is the number of tasks answered per second, failed ones included
+	Throughput float64                         `json:"throughput"`
+	Latency    Latency                         `json:"latency"`
+	TaskTypes  map[client.TaskType]*TypeReport `json:"task_types"`
+	// Errors counts the failures by error message
+	Errors map[string]int `json:"errors,omitempty"`
+}
+
+func newReport(samples []sample, elapsed time.Duration) *Report {
+	r := &Report{Tasks: len(samples), Elapsed: elapsed, TaskTypes: make(map[client.TaskType]*TypeReport)}
+	if elapsed > 0 {
+		r.Throughput = float64(len(samples)) / elapsed.Seconds()
+	}
+	all := make([]time.Duration, 0, len(samples))
+	byType := make(map[client.TaskType][]time.Duration)
+	for _, s := range samples {
+		all = append(all, s.latency)
+		byType[s.taskType] = append(byType[s.taskType], s.latency)
+		tr := r.TaskTypes[s.taskType]
+		if tr == nil {
+			tr = &TypeReport{}
+			r.TaskTypes[s.taskType] = tr
+		}
+		tr.Tasks++
+		if s.err != nil {
+			r.Failed++
+			tr.Failed++
+			if r.Errors == nil {
+				r.Errors = make(map[string]int)
+			}
+			r.Errors[s.err.Error()]++
+		}
+	}
+	r.Latency = newLatency(all)
+	for taskType, latencies := range byType {
+		r.TaskTypes[taskType].Latency = newLatency(latencies)
+	}
+	return r
+}
+
+// FailureRate returns the share of tasks that failed
+func (r *Report) FailureRate() float64 {
+	if r.Tasks == 0 {
+		return 0
+	}
+	return float64(r.Failed) / float64(r.Tasks)
+}
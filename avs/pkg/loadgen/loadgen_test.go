@@ -0,0 +1,139 @@
+package loadgen
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/najnomics/crosscow-avs/pkg/client"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// newStubClient serves a performer reading stub adapters and returns a client of it
+func newStubClient(t *testing.T) *client.Client {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	addr, err := ServeStub(ctx, time.Millisecond, zap.NewNop())
+	if err != nil {
+		t.Fatalf("ServeStub failed: %v", err)
+	}
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	c := client.New(conn)
+
+	// the connection is warmed up, so the first tasks of a run are not held up dialing
+	warmup, cancelWarmup := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancelWarmup()
+	if _, err := c.Execute(warmup, "warmup", &client.Payload{Type: client.TaskTypeCapabilities, Parameters: map[string]interface{}{}}); err != nil {
+		t.Fatalf("Failed to warm up the connection: %v", err)
+	}
+	return c
+}
+
+func Test_RunSendsTheMix(t *testing.T) {
+	c := newStubClient(t)
+	mix := DefaultMix()
+	cycle := 0
+	weights := make(map[client.TaskType]int)
+	for _, task := range mix {
+		cycle += task.Weight
+		weights[task.Payload.Type] += task.Weight
+	}
+
+	// the timeout leaves room for slow runs, such as under the race detector
+	report, err := Run(context.Background(), c, Config{Concurrency: 8, Tasks: 2 * cycle, Timeout: time.Minute, RunID: "mix", Mix: mix})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if report.Tasks != 2*cycle {
+		t.Fatalf("Expected %d tasks, got %+v", 2*cycle, report)
+	}
+	for taskType, weight := range weights {
+		if got := report.TaskTypes[taskType]; got == nil || got.Tasks != 2*weight {
+			t.Errorf("Expected %d %s tasks, got %+v", 2*weight, taskType, got)
+		}
+	}
+	if report.Throughput <= 0 || report.Latency.P50 <= 0 || report.Latency.P50 > report.Latency.P99 || report.Latency.P99 > report.Latency.Max {
+		t.Errorf("Unexpected throughput or latencies %+v", report)
+	}
+}
+
+func Test_RunCountsFailures(t *testing.T) {
+	c := newStubClient(t)
+	mix := []Task{
+		{Payload: &client.Payload{Type: client.TaskTypeCapabilities, Parameters: map[string]interface{}{}}, Weight: 1},
+		// the stubs have no market of this protocol
+		{Payload: &client.Payload{Type: client.TaskTypeYieldMonitoring, Parameters: map[string]interface{}{"protocol": "euler", "token": "USDC", "chain_id": 1}}, Weight: 1},
+	}
+
+	report, err := Run(context.Background(), c, Config{Concurrency: 2, Tasks: 10, RunID: "failures", Mix: mix})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if report.Tasks != 10 || report.Failed != 5 || report.FailureRate() != 0.5 || report.TaskTypes[client.TaskTypeYieldMonitoring].Failed != 5 {
+		t.Errorf("Expected the monitoring tasks to fail, got %+v", report)
+	}
+	if len(report.Errors) == 0 {
+		t.Errorf("Expected the errors to be counted")
+	}
+}
+
+func Test_RunStopsAfterDuration(t *testing.T) {
+	c := newStubClient(t)
+	started := time.Now()
+	report, err := Run(context.Background(), c, Config{Concurrency: 4, Duration: 200 * time.Millisecond, RunID: "duration", Mix: DefaultMix()})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > 2*time.Second {
+		t.Errorf("Expected the run to stop after its duration, took %v", elapsed)
+	}
+	if report.Tasks == 0 {
+		t.Errorf("Expected tasks to be sent until the end of the run, got %+v", report)
+	}
+}
+
+func Test_ParseMix(t *testing.T) {
+	mix, err := ParseMix([]byte(`[{"type":"capabilities"},{"type":"yield_ranking","parameters":{"token":"USDC"},"weight":3}]`))
+	if err != nil {
+		t.Fatalf("ParseMix failed: %v", err)
+	}
+	if len(mix) != 2 || mix[0].Weight != 1 || mix[1].Weight != 3 || mix[1].Payload.Parameters["token"] != "USDC" {
+		t.Errorf("Unexpected mix %+v", mix)
+	}
+	if got := schedule(mix); len(got) != 4 || got[0] != 0 || got[1] != 1 || got[3] != 1 {
+		t.Errorf("Expected the tasks interleaved by weight, got %v", got)
+	}
+
+	for name, data := range map[string]string{
+		"object":  `{"type":"capabilities"}`,
+		"empty":   `[]`,
+		"type":    `[{"parameters":{}}]`,
+		"weight":  `[{"type":"capabilities","weight":-1}]`,
+		"invalid": `[`,
+	} {
+		if _, err := ParseMix([]byte(data)); err == nil {
+			t.Errorf("%s: expected the mix to be rejected", name)
+		}
+	}
+}
+
+func Test_ConfigValidate(t *testing.T) {
+	mix := []Task{{Payload: &client.Payload{Type: client.TaskTypeCapabilities}, Weight: 1}}
+	for name, cfg := range map[string]Config{
+		"concurrency": {Tasks: 1, Mix: mix},
+		"endless":     {Concurrency: 1, Mix: mix},
+		"mix":         {Concurrency: 1, Tasks: 1},
+		"weight":      {Concurrency: 1, Tasks: 1, Mix: []Task{{Payload: mix[0].Payload}}},
+	} {
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("%s: expected the config to be rejected", name)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package loadgen
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/najnomics/crosscow-avs/pkg/adapters/adapterstest"
+	"github.com/najnomics/crosscow-avs/pkg/grpcserver"
+	"github.com/najnomics/crosscow-avs/pkg/performer"
+	"go.uber.org/zap"
+)
+
+// ServeStub serves a performer reading stub adapters, whose reads take latency, with its
+// gRPC server on a free local port until ctx is cancelled. It returns the address of the
+// server, which accepts tasks once ServeStub returns. Runs against it measure the
+// performer and the gRPC server alone, without chains or APIs.
+func ServeStub(ctx context.Context, latency time.Duration, l *zap.Logger) (string, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", fmt.Errorf("failed to listen: %w", err)
+	}
+
+	// the server serves the listener it is given, so the port is never let go of
+	yip := performer.NewYieldIntelligencePerformer(l, performer.WithAdapters(adapterstest.NewRegistry(latency)))
+	server, err := grpcserver.NewWithListener(listener, grpcserver.Config{}, yip, l)
+	if err != nil {
+		listener.Close()
+		return "", err
+	}
+	go func() { _ = server.Start(ctx) }()
+	return listener.Addr().String(), nil
+}
@@ -0,0 +1,203 @@
+// Package logging builds the performer's logger and the per-task loggers handlers log
+// through, so every line of a task carries its identifiers and no secret or, when
+// configured, account address from its parameters.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+)
+
+// Redacted replaces the values of redacted parameters
+const Redacted = "[redacted]"
+
+// Config configures the performer's logs
+type Config struct {
+	// Format is json, for log collectors, or console, for humans
+	Format string `yaml:"format"`
+
+	// Level is the minimum level logged: debug, info, warn or error
+	Level string `yaml:"level"`
+
+	// RedactAddresses hides account addresses in logged task parameters. Secrets such as
+	// API keys are always hidden.
+	RedactAddresses bool `yaml:"redactAddresses"`
+}
+
+// DefaultConfig logs JSON at info level
+func DefaultConfig() Config {
+	return Config{Format: FormatJSON, Level: "info"}
+}
+
+// Validate checks the config for values a logger cannot be built with
+func (c Config) Validate() error {
+	if c.Format != FormatJSON && c.Format != FormatConsole {
+		return fmt.Errorf("format must be %s or %s", FormatJSON, FormatConsole)
+	}
+	if _, err := zapcore.ParseLevel(c.Level); err != nil {
+		return fmt.Errorf("unknown level %q", c.Level)
+	}
+	return nil
+}
+
+// New builds the logger described by cfg
+func New(cfg Config) (*zap.Logger, error) {
+	logger, _, err := NewWithLevel(cfg)
+	return logger, err
+}
+
+// NewWithLevel builds the logger described by cfg and returns the level it logs at,
+// which can be changed while the logger is in use. The level serves GET and PUT requests
+// reading and setting it as JSON, such as {"level":"debug"}.
+func NewWithLevel(cfg Config) (*zap.Logger, zap.AtomicLevel, error) {
+	level, err := zapcore.ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, zap.AtomicLevel{}, fmt.Errorf("unknown log level %q", cfg.Level)
+	}
+	zapCfg := zap.NewProductionConfig()
+	if cfg.Format == FormatConsole {
+		zapCfg = zap.NewDevelopmentConfig()
+	}
+	zapCfg.Level = zap.NewAtomicLevelAt(level)
+	logger, err := zapCfg.Build()
+	if err != nil {
+		return nil, zap.AtomicLevel{}, err
+	}
+	return logger, zapCfg.Level, nil
+}
+
+type contextKey struct{}
+
+// WithLogger returns a copy of ctx carrying logger
+func WithLogger(ctx context.Context, logger *zap.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the logger ctx carries, fallback when it carries none
+func FromContext(ctx context.Context, fallback *zap.Logger) *zap.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*zap.Logger); ok {
+		return logger
+	}
+	return fallback
+}
+
+// NewCorrelationID returns a random identifier for one delivery of a task
+func NewCorrelationID() string {
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(id[:])
+}
+
+var (
+	secretKey = regexp.MustCompile(`(?i)(api_?key|secret|password|private_?key|access_?key|auth|credential|signature)`)
+	address   = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+)
+
+// Redactor hides sensitive values of task parameters
+type Redactor struct {
+	addresses bool
+}
+
+// NewRedactor returns a redactor hiding secrets, and addresses when cfg says so
+func NewRedactor(cfg Config) *Redactor {
+	return &Redactor{addresses: cfg.RedactAddresses}
+}
+
+// Parameters returns a copy of params with sensitive values replaced by Redacted. Nested
+// objects and arrays, such as the tasks of a batch, are redacted too.
+func (r *Redactor) Parameters(params map[string]interface{}) map[string]interface{} {
+	redacted := make(map[string]interface{}, len(params))
+	for key, value := range params {
+		if secretKey.MatchString(key) {
+			redacted[key] = Redacted
+			continue
+		}
+		redacted[key] = r.value(value)
+	}
+	return redacted
+}
+
+func (r *Redactor) value(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return r.Parameters(v)
+	case []interface{}:
+		values := make([]interface{}, len(v))
+		for i, item := range v {
+			values[i] = r.value(item)
+		}
+		return values
+	case string:
+		if r.addresses && address.MatchString(strings.TrimSpace(v)) {
+			return Redacted
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// WithSecretsHidden returns logger passing the message and the string, error and
+// stringer fields of every line through hide, which replaces the secrets it knows of
+func WithSecretsHidden(logger *zap.Logger, hide func(string) string) *zap.Logger {
+	return logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return &hidingCore{Core: core, hide: hide}
+	}))
+}
+
+// hidingCore hides secrets in the lines written to the core it wraps
+type hidingCore struct {
+	zapcore.Core
+	hide func(string) string
+}
+
+func (c *hidingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &hidingCore{Core: c.Core.With(c.fields(fields)), hide: c.hide}
+}
+
+func (c *hidingCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	// the wrapped core decides, so sampling still applies, and the line is written here
+	if c.Core.Check(entry, nil) == nil {
+		return checked
+	}
+	return checked.AddCore(entry, c)
+}
+
+func (c *hidingCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	entry.Message = c.hide(entry.Message)
+	return c.Core.Write(entry, c.fields(fields))
+}
+
+func (c *hidingCore) fields(fields []zapcore.Field) []zapcore.Field {
+	hidden := make([]zapcore.Field, len(fields))
+	for i, field := range fields {
+		switch field.Type {
+		case zapcore.StringType:
+			field.String = c.hide(field.String)
+		case zapcore.ErrorType:
+			if err, ok := field.Interface.(error); ok && err != nil {
+				field = zap.String(field.Key, c.hide(err.Error()))
+			}
+		case zapcore.StringerType:
+			if s, ok := field.Interface.(fmt.Stringer); ok && s != nil {
+				field = zap.String(field.Key, c.hide(s.String()))
+			}
+		}
+		hidden[i] = field
+	}
+	return hidden
+}
@@ -0,0 +1,88 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func Test_RedactParameters(t *testing.T) {
+	params := map[string]interface{}{
+		"protocol":     "aave_v3",
+		"user_address": "0x00000000000000000000000000000000000000aa",
+		"api_key":      "sk-live",
+		"amount":       float64(1000),
+		"tasks": []interface{}{
+			map[string]interface{}{"ApiKey": "nested", "recipient": "0x00000000000000000000000000000000000000bb"},
+		},
+	}
+
+	secrets := NewRedactor(Config{}).Parameters(params)
+	want := map[string]interface{}{
+		"protocol":     "aave_v3",
+		"user_address": "0x00000000000000000000000000000000000000aa",
+		"api_key":      Redacted,
+		"amount":       float64(1000),
+		"tasks": []interface{}{
+			map[string]interface{}{"ApiKey": Redacted, "recipient": "0x00000000000000000000000000000000000000bb"},
+		},
+	}
+	if !reflect.DeepEqual(secrets, want) {
+		t.Errorf("Expected secrets to be redacted, got %v", secrets)
+	}
+
+	addresses := NewRedactor(Config{RedactAddresses: true}).Parameters(params)
+	nested := addresses["tasks"].([]interface{})[0].(map[string]interface{})
+	if addresses["user_address"] != Redacted || nested["recipient"] != Redacted || addresses["protocol"] != "aave_v3" {
+		t.Errorf("Expected addresses to be redacted, got %v", addresses)
+	}
+	if params["api_key"] != "sk-live" {
+		t.Errorf("Expected the parameters to be left unchanged")
+	}
+}
+
+func Test_FromContext(t *testing.T) {
+	fallback, task := zap.NewNop(), zap.NewExample()
+	if FromContext(context.Background(), fallback) != fallback {
+		t.Errorf("Expected the fallback outside tasks")
+	}
+	if FromContext(WithLogger(context.Background(), task), fallback) != task {
+		t.Errorf("Expected the logger of the task")
+	}
+}
+
+func Test_WithSecretsHidden(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	hide := func(s string) string { return strings.ReplaceAll(s, "sk-live-key", "[secret]") }
+	logger := WithSecretsHidden(zap.New(core), hide).With(zap.String("endpoint", "https://rpc/sk-live-key"))
+
+	logger.Info("dialing with sk-live-key", zap.Error(errors.New("401 for sk-live-key")), zap.Int("attempt", 1))
+	logger.Debug("below the level sk-live-key")
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("Expected one line, got %d", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	if entries[0].Message != "dialing with [secret]" || fields["endpoint"] != "https://rpc/[secret]" || fields["error"] != "401 for [secret]" || fields["attempt"] != int64(1) {
+		t.Errorf("Expected the secret to be hidden, got %q %v", entries[0].Message, fields)
+	}
+}
+
+func Test_ConfigValidate(t *testing.T) {
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Errorf("Expected the default config to be valid: %v", err)
+	}
+	for _, cfg := range []Config{{Format: "xml", Level: "info"}, {Format: FormatConsole, Level: "loud"}} {
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("Expected %+v to be rejected", cfg)
+		}
+	}
+	if _, err := New(Config{Format: FormatConsole, Level: "debug"}); err != nil {
+		t.Errorf("New failed: %v", err)
+	}
+}
@@ -0,0 +1,232 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/najnomics/crosscow-avs/pkg/resilience"
+)
+
+// DefaultPagerDutyURL is the PagerDuty Events API v2
+const DefaultPagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+
+// maxSummaryLength is the longest summary PagerDuty accepts
+const maxSummaryLength = 1024
+
+// Severity grades how urgently an event needs an operator. Events with a severity are
+// alerts, which Slack and PagerDuty receive from their minimum severity on.
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+var severityRanks = map[Severity]int{SeverityInfo: 1, SeverityWarning: 2, SeverityCritical: 3}
+
+// AtLeast reports whether s is as severe as min. Events without a severity are never.
+func (s Severity) AtLeast(min Severity) bool {
+	return severityRanks[s] > 0 && severityRanks[s] >= severityRanks[min]
+}
+
+func (s Severity) valid() bool {
+	return severityRanks[s] > 0
+}
+
+// SlackConfig configures alerts posted to a Slack incoming webhook
+type SlackConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// WebhookUrlEnv names the environment variable holding the webhook URL, which is a
+	// secret
+	WebhookUrlEnv string `yaml:"webhookUrlEnv"`
+
+	// MinSeverity is the least severe alert posted
+	MinSeverity Severity `yaml:"minSeverity"`
+}
+
+// PagerDutyConfig configures alerts triggering PagerDuty incidents
+type PagerDutyConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Url     string `yaml:"url"`
+
+	// RoutingKeyEnv names the environment variable holding the integration key of the
+	// PagerDuty service
+	RoutingKeyEnv string `yaml:"routingKeyEnv"`
+
+	// MinSeverity is the least severe alert triggering an incident
+	MinSeverity Severity `yaml:"minSeverity"`
+}
+
+// dedup holds back alerts repeating one sent within its window. Alerts are the same when
+// their keys are; an alert more severe than the last one sent of its key is never held
+// back. It is used by the delivery goroutine alone.
+type dedup struct {
+	window time.Duration
+	now    func() time.Time
+	sent   map[string]*sentAlert
+}
+
+// sentAlert is the last alert sent of a key, and how many were held back since
+type sentAlert struct {
+	at         time.Time
+	severity   Severity
+	suppressed int
+}
+
+func newDedup(window time.Duration) *dedup {
+	return &dedup{window: window, now: time.Now, sent: make(map[string]*sentAlert)}
+}
+
+// admit reports whether event is sent, and how many alerts of its key were held back
+// since the last one sent
+func (d *dedup) admit(event *Event) (bool, int) {
+	now := d.now()
+	key := event.Key
+	if key == "" {
+		key = string(event.Type)
+	}
+	for k, sent := range d.sent {
+		if now.Sub(sent.at) >= d.window {
+			delete(d.sent, k)
+		}
+	}
+	// held back alerts are forgotten with the entry, so they are only counted within the
+	// window
+	last, found := d.sent[key]
+	if found && severityRanks[event.Severity] <= severityRanks[last.severity] {
+		last.suppressed++
+		return false, 0
+	}
+	suppressed := 0
+	if found {
+		suppressed = last.suppressed
+	}
+	d.sent[key] = &sentAlert{at: now, severity: event.Severity}
+	return true, suppressed
+}
+
+// Slack posts alerts to a Slack incoming webhook
+type Slack struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NewSlack creates a sink posting to the incoming webhook at webhookURL
+func NewSlack(webhookURL string, timeout time.Duration) *Slack {
+	return &Slack{url: webhookURL, httpClient: &http.Client{Timeout: timeout}}
+}
+
+// Name is "slack"; webhook URLs are secrets
+func (s *Slack) Name() string {
+	return "slack"
+}
+
+// Deliver posts the summary of event as a message
+func (s *Slack) Deliver(ctx context.Context, event *Event, _ []byte) error {
+	text := fmt.Sprintf("[%s] %s", strings.ToUpper(string(event.Severity)), event.summary())
+	if event.TaskID != "" {
+		text += fmt.Sprintf("\nTask `%s` (%s)", event.TaskID, event.TaskType)
+	}
+	if event.Suppressed > 0 {
+		text += fmt.Sprintf("\n%d similar alerts were suppressed", event.Suppressed)
+	}
+	return post(ctx, s.httpClient, s.Name(), s.url, map[string]string{"text": text})
+}
+
+// PagerDuty triggers PagerDuty incidents for alerts. Incidents are deduplicated by the
+// key of their alert, so repeated alerts add to the open incident.
+type PagerDuty struct {
+	url        string
+	routingKey string
+	source     string
+	httpClient *http.Client
+}
+
+// NewPagerDuty creates a sink triggering incidents of the service routingKey integrates,
+// on the Events API at endpoint, DefaultPagerDutyURL when empty. source names the
+// performer in incidents.
+func NewPagerDuty(endpoint, routingKey, source string, timeout time.Duration) *PagerDuty {
+	if endpoint == "" {
+		endpoint = DefaultPagerDutyURL
+	}
+	return &PagerDuty{url: endpoint, routingKey: routingKey, source: source, httpClient: &http.Client{Timeout: timeout}}
+}
+
+// Name is "pagerduty"
+func (p *PagerDuty) Name() string {
+	return "pagerduty"
+}
+
+// Deliver triggers an incident for event
+func (p *PagerDuty) Deliver(ctx context.Context, event *Event, _ []byte) error {
+	summary := event.summary()
+	if len(summary) > maxSummaryLength {
+		summary = summary[:maxSummaryLength]
+	}
+	dedupKey := event.Key
+	if dedupKey == "" {
+		dedupKey = event.ID
+	}
+	return post(ctx, p.httpClient, p.Name(), p.url, map[string]any{
+		"routing_key":  p.routingKey,
+		"event_action": "trigger",
+		"dedup_key":    dedupKey,
+		"payload": map[string]any{
+			"summary":        summary,
+			"source":         p.source,
+			"severity":       string(event.Severity),
+			"timestamp":      event.Time.Format(time.RFC3339),
+			"component":      string(event.Type),
+			"custom_details": event,
+		},
+	})
+}
+
+// summary is the summary of event, its type when it has none
+func (e *Event) summary() string {
+	if e.Summary != "" {
+		return e.Summary
+	}
+	return string(e.Type)
+}
+
+// post sends message as JSON to endpoint, failing unless it is accepted
+func post(ctx context.Context, client *http.Client, name, endpoint string, message any) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("%s: failed to encode: %w", name, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%s: failed to build request: %w", name, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return send(client, name, req)
+}
+
+// send sends req to the endpoint name identifies, failing unless it is accepted. Errors
+// name the endpoint rather than its URL, which may carry secrets.
+func send(client *http.Client, name string, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("%s unreachable: %w", name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s: %w", name, &resilience.StatusError{Endpoint: name, StatusCode: resp.StatusCode})
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// alertEndpoint keeps the JSON messages posted to it
+type alertEndpoint struct {
+	mu       sync.Mutex
+	messages []map[string]any
+}
+
+func (a *alertEndpoint) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var message map[string]any
+	if err := json.NewDecoder(req.Body).Decode(&message); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.messages = append(a.messages, message)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func Test_AlertsRoutedBySeverity(t *testing.T) {
+	slack, pagerDuty := &alertEndpoint{}, &alertEndpoint{}
+	slackServer, pagerDutyServer := httptest.NewServer(slack), httptest.NewServer(pagerDuty)
+	defer slackServer.Close()
+	defer pagerDutyServer.Close()
+
+	n := New(8, nil, zap.NewNop())
+	n.SubscribeAlerts(NewSlack(slackServer.URL, time.Second), SeverityWarning, time.Hour)
+	n.SubscribeAlerts(NewPagerDuty(pagerDutyServer.URL, "routing-key", "performer-1", time.Second), SeverityCritical, time.Hour)
+	n.Notify(Event{Type: EventTaskCompleted, TaskID: "task-1"})
+	n.Notify(Event{Type: EventCircuitOpen, Severity: SeverityWarning, Key: "circuit_open:rpc:ethereum", Summary: "Circuit of ethereum opened"})
+	n.Notify(Event{Type: EventExecutionFailed, Severity: SeverityCritical, Key: "execution_failed:aave_v3:1", Summary: "Rebalance reverted", TaskID: "task-2"})
+	if err := n.Close(context.Background()); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	if len(slack.messages) != 2 {
+		t.Fatalf("Expected Slack to get the warning and the critical alert, got %+v", slack.messages)
+	}
+	if text := slack.messages[1]["text"].(string); !strings.Contains(text, "[CRITICAL] Rebalance reverted") || !strings.Contains(text, "task-2") {
+		t.Errorf("Unexpected Slack message %q", text)
+	}
+	if len(pagerDuty.messages) != 1 {
+		t.Fatalf("Expected PagerDuty to get the critical alert alone, got %+v", pagerDuty.messages)
+	}
+	incident := pagerDuty.messages[0]
+	payload := incident["payload"].(map[string]any)
+	if incident["routing_key"] != "routing-key" || incident["event_action"] != "trigger" || incident["dedup_key"] != "execution_failed:aave_v3:1" ||
+		payload["severity"] != "critical" || payload["summary"] != "Rebalance reverted" || payload["source"] != "performer-1" {
+		t.Errorf("Unexpected PagerDuty event %+v", incident)
+	}
+}
+
+func Test_DedupHoldsBackRepeatedAlerts(t *testing.T) {
+	now := time.Unix(0, 0)
+	d := newDedup(10 * time.Minute)
+	d.now = func() time.Time { return now }
+	warning := &Event{Type: EventDepegDetected, Severity: SeverityWarning, Key: "depeg_detected:USDC"}
+
+	if sent, _ := d.admit(warning); !sent {
+		t.Fatalf("Expected the first alert to be sent")
+	}
+	now = now.Add(time.Minute)
+	if sent, _ := d.admit(warning); sent {
+		t.Errorf("Expected a repeated alert to be held back")
+	}
+	if sent, _ := d.admit(&Event{Type: EventDepegDetected, Severity: SeverityWarning, Key: "depeg_detected:EURC"}); !sent {
+		t.Errorf("Expected alerts of other keys to be sent")
+	}
+
+	// alerts growing more severe are sent at once, with the count of those held back
+	sent, suppressed := d.admit(&Event{Type: EventDepegDetected, Severity: SeverityCritical, Key: "depeg_detected:USDC"})
+	if !sent || suppressed != 1 {
+		t.Errorf("Expected the escalation to be sent after 1 held back alert, got %v, %d", sent, suppressed)
+	}
+	now = now.Add(time.Minute)
+	if sent, _ := d.admit(warning); sent {
+		t.Errorf("Expected a less severe alert to be held back")
+	}
+
+	now = now.Add(10 * time.Minute)
+	if sent, _ := d.admit(warning); !sent {
+		t.Errorf("Expected the alert to be sent again once the window passed")
+	}
+}
@@ -0,0 +1,496 @@
+// Package notify posts events of the performer, such as completed tasks, executed
+// rebalances and detected anomalies, to the webhooks of its operator, so monitoring and
+// alerting systems are told about them rather than scraping logs.
+//
+// Events are JSON objects signed with HMAC-SHA256 under the secret of each webhook. The
+// signature covers the timestamp in TimestampHeader, a dot and the body, and is sent in
+// SignatureHeader as "sha256=" and the hex encoded MAC. Receivers should also reject
+// timestamps too far in the past, so captured events cannot be replayed.
+//
+// Events with a severity are alerts. Slack and PagerDuty receive the alerts from their
+// minimum severity on, and an alert is not repeated to them within the dedup window
+// unless it grows more severe.
+//
+// Delivery is asynchronous: tasks never wait for endpoints, and events raised while the
+// queue is full are dropped. Webhooks, Slack and PagerDuty are the built in destinations;
+// message queues plug in by implementing Sink.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/najnomics/crosscow-avs/pkg/resilience"
+	"go.uber.org/zap"
+)
+
+const (
+	// SignatureHeader carries the HMAC-SHA256 signature of an event
+	SignatureHeader = "X-Event-Signature"
+
+	// TimestampHeader carries the unix time an event was signed at
+	TimestampHeader = "X-Event-Timestamp"
+)
+
+// ErrInvalidSignature is returned for events not signed with the secret of a webhook
+var ErrInvalidSignature = errors.New("event signature is invalid")
+
+// EventType names what happened
+type EventType string
+
+const (
+	// EventTaskCompleted is raised when a task completed, whatever its result status
+	EventTaskCompleted EventType = "task_completed"
+
+	// EventRebalanceExecuted is raised when a rebalance submitted transactions
+	EventRebalanceExecuted EventType = "rebalance_executed"
+
+	// EventAnomalyDetected is raised when data failed anomaly checks, or a rebalance did
+	// not move the balances it should have
+	EventAnomalyDetected EventType = "anomaly_detected"
+
+	// EventExecutionFailed is raised when the transactions of a rebalance failed
+	EventExecutionFailed EventType = "execution_failed"
+
+	// EventDepegDetected is raised when USDC deviated from its peg
+	EventDepegDetected EventType = "depeg_detected"
+
+	// EventCircuitOpen is raised when the circuit breaker of an endpoint opened after it
+	// kept failing
+	EventCircuitOpen EventType = "circuit_open"
+
+	// EventRebalanceOpportunity is raised when the lead of a market over another crossed
+	// the threshold of signed opportunities
+	EventRebalanceOpportunity EventType = "rebalance_opportunity"
+
+	// EventResultInvalidated is raised when a reorg removed a block a task result was read
+	// at
+	EventResultInvalidated EventType = "result_invalidated"
+
+	// EventTransferStuck is raised when a CCTP transfer stayed unminted past its SLA and
+	// needs manual intervention
+	EventTransferStuck EventType = "transfer_stuck"
+)
+
+// EventTypes are the types of every event raised
+var EventTypes = []EventType{EventTaskCompleted, EventRebalanceExecuted, EventAnomalyDetected, EventExecutionFailed, EventDepegDetected, EventCircuitOpen, EventRebalanceOpportunity, EventResultInvalidated, EventTransferStuck}
+
+// Event is a notification as it is posted. ID is unique to the event, so receivers can
+// tell redeliveries apart from events that happened twice.
+type Event struct {
+	ID       string    `json:"id"`
+	Type     EventType `json:"type"`
+	Time     time.Time `json:"time"`
+	TaskID   string    `json:"task_id,omitempty"`
+	TaskType string    `json:"task_type,omitempty"`
+
+	// Severity is set on events that are alerts
+	Severity Severity `json:"severity,omitempty"`
+
+	// Key identifies what an alert is about, such as a token or an endpoint, so repeated
+	// alerts about it are deduplicated. Alerts without a key are keyed by their type.
+	Key string `json:"key,omitempty"`
+
+	// Summary describes the event in a line, as alerts show it
+	Summary string `json:"summary,omitempty"`
+
+	// Suppressed counts the alerts of the same key held back since the last one sent. It
+	// is only set on alerts.
+	Suppressed int `json:"suppressed,omitempty"`
+
+	Data any `json:"data,omitempty"`
+}
+
+// Sink delivers events to a destination. Delivery is retried while it fails with an
+// error resilience classifies as retryable.
+type Sink interface {
+	// Name identifies the destination in logs. It must not carry secrets.
+	Name() string
+	Deliver(ctx context.Context, event *Event, body []byte) error
+}
+
+// Config configures notifications
+type Config struct {
+	Enabled  bool            `yaml:"enabled"`
+	Webhooks []WebhookConfig `yaml:"webhooks"`
+	Timeout  time.Duration   `yaml:"timeout"`
+
+	// QueueSize bounds the events waiting for delivery. Events raised while it is full
+	// are dropped.
+	QueueSize int `yaml:"queueSize"`
+
+	// Slack and PagerDuty receive alerts, the events with a severity, from their minimum
+	// severity on
+	Slack     SlackConfig     `yaml:"slack"`
+	PagerDuty PagerDutyConfig `yaml:"pagerDuty"`
+
+	// DedupWindow is how long an alert is not repeated to Slack and PagerDuty, unless it
+	// grows more severe
+	DedupWindow time.Duration `yaml:"dedupWindow"`
+}
+
+// WebhookConfig configures an endpoint events are posted to
+type WebhookConfig struct {
+	Url string `yaml:"url"`
+
+	// SecretEnv names the environment variable holding the secret events are signed with
+	SecretEnv string `yaml:"secretEnv"`
+
+	// Events are the types of events posted, every type when empty
+	Events []EventType `yaml:"events"`
+}
+
+// DefaultConfig leaves notifications disabled. Once enabled, Slack gets warnings and
+// PagerDuty critical alerts, and alerts are repeated at most every 15 minutes.
+func DefaultConfig() Config {
+	return Config{
+		Timeout:   10 * time.Second,
+		QueueSize: 256,
+		Slack: SlackConfig{
+			WebhookUrlEnv: "YIELD_AVS_SLACK_WEBHOOK_URL",
+			MinSeverity:   SeverityWarning,
+		},
+		PagerDuty: PagerDutyConfig{
+			Url:           DefaultPagerDutyURL,
+			RoutingKeyEnv: "YIELD_AVS_PAGERDUTY_ROUTING_KEY",
+			MinSeverity:   SeverityCritical,
+		},
+		DedupWindow: 15 * time.Minute,
+	}
+}
+
+// Validate checks the config for values events cannot be delivered with
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if len(c.Webhooks) == 0 && !c.Slack.Enabled && !c.PagerDuty.Enabled {
+		return fmt.Errorf("webhooks, slack or pagerDuty are required")
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("timeout must be positive")
+	}
+	if c.QueueSize <= 0 {
+		return fmt.Errorf("queueSize must be positive")
+	}
+	for i, webhook := range c.Webhooks {
+		if u, err := url.Parse(webhook.Url); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("webhooks[%d]: url must be an http or https URL", i)
+		}
+		if webhook.SecretEnv == "" {
+			return fmt.Errorf("webhooks[%d]: secretEnv is required", i)
+		}
+		for _, event := range webhook.Events {
+			if !slices.Contains(EventTypes, event) {
+				return fmt.Errorf("webhooks[%d]: unknown event %q", i, event)
+			}
+		}
+	}
+	if c.Slack.Enabled {
+		if c.Slack.WebhookUrlEnv == "" {
+			return fmt.Errorf("slack: webhookUrlEnv is required")
+		}
+		if !c.Slack.MinSeverity.valid() {
+			return fmt.Errorf("slack: unknown minSeverity %q", c.Slack.MinSeverity)
+		}
+	}
+	if c.PagerDuty.Enabled {
+		if u, err := url.Parse(c.PagerDuty.Url); err != nil || u.Scheme != "https" || u.Host == "" {
+			return fmt.Errorf("pagerDuty: url must be an https URL")
+		}
+		if c.PagerDuty.RoutingKeyEnv == "" {
+			return fmt.Errorf("pagerDuty: routingKeyEnv is required")
+		}
+		if !c.PagerDuty.MinSeverity.valid() {
+			return fmt.Errorf("pagerDuty: unknown minSeverity %q", c.PagerDuty.MinSeverity)
+		}
+	}
+	if (c.Slack.Enabled || c.PagerDuty.Enabled) && c.DedupWindow < 0 {
+		return fmt.Errorf("dedupWindow must not be negative")
+	}
+	return nil
+}
+
+// Sign returns the signature of body sent at timestamp under secret
+func Sign(secret []byte, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks that signature is the signature of body sent at timestamp, as read from
+// TimestampHeader, under secret
+func Verify(secret []byte, timestamp, signature string, body []byte) error {
+	sent, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: malformed %s header", ErrInvalidSignature, TimestampHeader)
+	}
+	if !hmac.Equal([]byte(Sign(secret, sent, body)), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Webhook posts events to an HTTP endpoint
+type Webhook struct {
+	url        string
+	name       string
+	secret     []byte
+	httpClient *http.Client
+	now        func() time.Time
+}
+
+// NewWebhook creates a webhook posting to endpoint, signing events with secret
+func NewWebhook(endpoint string, secret []byte, timeout time.Duration) *Webhook {
+	name := endpoint
+	// webhook URLs often carry tokens, so only the host shows in logs
+	if u, err := url.Parse(endpoint); err == nil {
+		name = u.Scheme + "://" + u.Host
+	}
+	return &Webhook{
+		url:        endpoint,
+		name:       name,
+		secret:     secret,
+		httpClient: &http.Client{Timeout: timeout},
+		now:        time.Now,
+	}
+}
+
+// Name is the scheme and host of the endpoint
+func (w *Webhook) Name() string {
+	return w.name
+}
+
+// Deliver posts body, signed, to the endpoint
+func (w *Webhook) Deliver(ctx context.Context, event *Event, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("webhook %s: failed to build request: %w", w.name, err)
+	}
+	timestamp := w.now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(w.secret, timestamp, body))
+	return send(w.httpClient, "webhook "+w.name, req)
+}
+
+// subscription is a sink with the types of events it receives, every type when empty.
+// Alert subscriptions receive alerts from minSeverity on instead.
+type subscription struct {
+	sink   Sink
+	events []EventType
+
+	minSeverity Severity
+	dedup       *dedup
+}
+
+// wants reports whether the subscription receives event, returning the event as it is
+// delivered
+func (s subscription) wants(event *Event) (*Event, bool) {
+	if s.dedup == nil {
+		return event, len(s.events) == 0 || slices.Contains(s.events, event.Type)
+	}
+	if !event.Severity.AtLeast(s.minSeverity) {
+		return nil, false
+	}
+	admitted, suppressed := s.dedup.admit(event)
+	if !admitted {
+		return nil, false
+	}
+	alert := *event
+	alert.Suppressed = suppressed
+	return &alert, true
+}
+
+// Notifier queues events and delivers them to its sinks in the background. A nil
+// Notifier drops every event.
+type Notifier struct {
+	subscriptions []subscription
+	policy        *resilience.Policy
+	logger        *zap.Logger
+	now           func() time.Time
+
+	mu      sync.Mutex
+	closed  bool
+	queue   chan *queued
+	dropped atomic.Uint64
+	done    chan struct{}
+}
+
+// New creates a notifier queueing up to queueSize events. Failed deliveries are retried
+// under policy, which may be nil, and logged once given up on.
+func New(queueSize int, policy *resilience.Policy, logger *zap.Logger) *Notifier {
+	n := &Notifier{
+		policy: policy,
+		logger: logger,
+		now:    time.Now,
+		queue:  make(chan *queued, queueSize),
+		done:   make(chan struct{}),
+	}
+	go n.run()
+	return n
+}
+
+// NewFromConfig creates the notifier posting to the webhooks, Slack and PagerDuty of
+// cfg, nil when notifications are disabled. Secrets are read from the environment.
+func NewFromConfig(cfg Config, policy *resilience.Policy, logger *zap.Logger) (*Notifier, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	webhooks := make([]*Webhook, len(cfg.Webhooks))
+	for i, webhook := range cfg.Webhooks {
+		secret := os.Getenv(webhook.SecretEnv)
+		if secret == "" {
+			return nil, fmt.Errorf("webhooks[%d]: environment variable %s is not set", i, webhook.SecretEnv)
+		}
+		webhooks[i] = NewWebhook(webhook.Url, []byte(secret), cfg.Timeout)
+	}
+	var slack *Slack
+	if cfg.Slack.Enabled {
+		webhookURL := os.Getenv(cfg.Slack.WebhookUrlEnv)
+		if webhookURL == "" {
+			return nil, fmt.Errorf("slack: environment variable %s is not set", cfg.Slack.WebhookUrlEnv)
+		}
+		slack = NewSlack(webhookURL, cfg.Timeout)
+	}
+	var pagerDuty *PagerDuty
+	if cfg.PagerDuty.Enabled {
+		routingKey := os.Getenv(cfg.PagerDuty.RoutingKeyEnv)
+		if routingKey == "" {
+			return nil, fmt.Errorf("pagerDuty: environment variable %s is not set", cfg.PagerDuty.RoutingKeyEnv)
+		}
+		source, err := os.Hostname()
+		if err != nil {
+			source = "yield-intelligence-performer"
+		}
+		pagerDuty = NewPagerDuty(cfg.PagerDuty.Url, routingKey, source, cfg.Timeout)
+	}
+
+	n := New(cfg.QueueSize, policy, logger)
+	for i, webhook := range webhooks {
+		n.Subscribe(webhook, cfg.Webhooks[i].Events...)
+	}
+	if slack != nil {
+		n.SubscribeAlerts(slack, cfg.Slack.MinSeverity, cfg.DedupWindow)
+	}
+	if pagerDuty != nil {
+		n.SubscribeAlerts(pagerDuty, cfg.PagerDuty.MinSeverity, cfg.DedupWindow)
+	}
+	return n, nil
+}
+
+// Subscribe delivers the events of the given types to sink, every event when none are
+// given. Sinks are subscribed before events are raised.
+func (n *Notifier) Subscribe(sink Sink, events ...EventType) {
+	n.subscriptions = append(n.subscriptions, subscription{sink: sink, events: events})
+}
+
+// SubscribeAlerts delivers the alerts at least as severe as min to sink. An alert is not
+// delivered again within window of the last one of its key, unless it is more severe.
+func (n *Notifier) SubscribeAlerts(sink Sink, min Severity, window time.Duration) {
+	n.subscriptions = append(n.subscriptions, subscription{sink: sink, minSeverity: min, dedup: newDedup(window)})
+}
+
+// queued is an event waiting for delivery, encoded as it was raised
+type queued struct {
+	event *Event
+	body  []byte
+}
+
+// Notify queues event for delivery, setting its ID and time. Its data is encoded at once,
+// so callers may change it afterwards. Notify never blocks: the event is dropped when the
+// queue is full or the notifier closed.
+func (n *Notifier) Notify(event Event) {
+	if n == nil {
+		return
+	}
+	id := make([]byte, 16)
+	rand.Read(id)
+	event.ID = hex.EncodeToString(id)
+	event.Time = n.now().UTC()
+	body, err := json.Marshal(&event)
+	if err != nil {
+		n.logger.Sugar().Errorw("Failed to encode event", "type", event.Type, "error", err)
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closed {
+		n.dropped.Add(1)
+		return
+	}
+	select {
+	case n.queue <- &queued{event: &event, body: body}:
+	default:
+		n.dropped.Add(1)
+		n.logger.Sugar().Warnw("Notification queue is full, dropping event", "type", event.Type, "taskId", event.TaskID)
+	}
+}
+
+// Dropped returns the number of events dropped since the notifier was created
+func (n *Notifier) Dropped() uint64 {
+	if n == nil {
+		return 0
+	}
+	return n.dropped.Load()
+}
+
+// Close stops accepting events and waits until the queued ones were delivered or ctx is
+// done
+func (n *Notifier) Close(ctx context.Context) error {
+	if n == nil {
+		return nil
+	}
+	n.mu.Lock()
+	if !n.closed {
+		n.closed = true
+		close(n.queue)
+	}
+	n.mu.Unlock()
+	select {
+	case <-n.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (n *Notifier) run() {
+	defer close(n.done)
+	for q := range n.queue {
+		n.deliver(q.event, q.body)
+	}
+}
+
+func (n *Notifier) deliver(event *Event, body []byte) {
+	for _, s := range n.subscriptions {
+		delivered, ok := s.wants(event)
+		if !ok {
+			continue
+		}
+		err := n.policy.Do(context.Background(), s.sink.Name(), func(ctx context.Context) error {
+			return s.sink.Deliver(ctx, delivered, body)
+		})
+		if err != nil {
+			n.logger.Sugar().Errorw("Failed to deliver event", "sink", s.sink.Name(), "type", event.Type, "eventId", event.ID, "error", err)
+		}
+	}
+}
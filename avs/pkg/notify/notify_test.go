@@ -0,0 +1,179 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/najnomics/crosscow-avs/pkg/resilience"
+	"go.uber.org/zap"
+)
+
+// recorder is a webhook endpoint keeping the events posted to it
+type recorder struct {
+	mu     sync.Mutex
+	events []Event
+	status int
+}
+
+func (r *recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	if err := Verify([]byte("secret"), req.Header.Get(TimestampHeader), req.Header.Get(SignatureHeader), body); err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.status != 0 {
+		w.WriteHeader(r.status)
+		r.status = 0
+		return
+	}
+	var event Event
+	if err := json.Unmarshal(body, &event); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	r.events = append(r.events, event)
+}
+
+func (r *recorder) received() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Event(nil), r.events...)
+}
+
+func Test_ConfigValidate(t *testing.T) {
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Fatalf("Expected the default config to be valid: %v", err)
+	}
+	valid := DefaultConfig()
+	valid.Enabled = true
+	valid.Webhooks = []WebhookConfig{{Url: "https://hooks.example.com/avs", SecretEnv: "WEBHOOK_SECRET", Events: []EventType{EventExecutionFailed}}}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Expected a config with a webhook to be valid: %v", err)
+	}
+	alertsOnly := DefaultConfig()
+	alertsOnly.Enabled, alertsOnly.PagerDuty.Enabled = true, true
+	if err := alertsOnly.Validate(); err != nil {
+		t.Fatalf("Expected a config alerting PagerDuty alone to be valid: %v", err)
+	}
+	for name, mutate := range map[string]func(*Config){
+		"webhooks": func(c *Config) { c.Webhooks = nil },
+		"timeout":  func(c *Config) { c.Timeout = 0 },
+		"queue":    func(c *Config) { c.QueueSize = 0 },
+		"url":      func(c *Config) { c.Webhooks[0].Url = "hooks.example.com" },
+		"secret":   func(c *Config) { c.Webhooks[0].SecretEnv = "" },
+		"event":    func(c *Config) { c.Webhooks[0].Events = []EventType{"task_started"} },
+		"severity": func(c *Config) { c.Slack.Enabled, c.Slack.MinSeverity = true, "page" },
+		"pagerDuty": func(c *Config) {
+			c.PagerDuty.Enabled, c.PagerDuty.Url = true, "http://events.pagerduty.com/v2/enqueue"
+		},
+	} {
+		cfg := valid
+		cfg.Webhooks = append([]WebhookConfig(nil), valid.Webhooks...)
+		mutate(&cfg)
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("Expected an invalid %s to be rejected", name)
+		}
+	}
+}
+
+func Test_SignVerify(t *testing.T) {
+	body := []byte(`{"type":"task_completed"}`)
+	signature := Sign([]byte("secret"), 1700000000, body)
+	if err := Verify([]byte("secret"), "1700000000", signature, body); err != nil {
+		t.Fatalf("Expected the signature to verify: %v", err)
+	}
+	if err := Verify([]byte("secret"), "1700000001", signature, body); err == nil {
+		t.Errorf("Expected a changed timestamp to be rejected")
+	}
+	if err := Verify([]byte("other"), "1700000000", signature, body); err == nil {
+		t.Errorf("Expected another secret to be rejected")
+	}
+	if err := Verify([]byte("secret"), "1700000000", signature, []byte(`{"type":"execution_failed"}`)); err == nil {
+		t.Errorf("Expected a changed body to be rejected")
+	}
+}
+
+func Test_NotifierDeliversSubscribedEvents(t *testing.T) {
+	all, failures := &recorder{}, &recorder{}
+	allServer, failuresServer := httptest.NewServer(all), httptest.NewServer(failures)
+	defer allServer.Close()
+	defer failuresServer.Close()
+
+	n := New(8, nil, zap.NewNop())
+	n.Subscribe(NewWebhook(allServer.URL, []byte("secret"), time.Second))
+	n.Subscribe(NewWebhook(failuresServer.URL, []byte("secret"), time.Second), EventExecutionFailed)
+	n.Notify(Event{Type: EventTaskCompleted, TaskID: "task-1", TaskType: "yield_monitoring"})
+	n.Notify(Event{Type: EventExecutionFailed, TaskID: "task-2", Data: map[string]string{"code": "execution_failed"}})
+	if err := n.Close(context.Background()); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	received := all.received()
+	if len(received) != 2 || received[0].Type != EventTaskCompleted || received[0].TaskType != "yield_monitoring" || received[1].TaskID != "task-2" {
+		t.Fatalf("Expected every event to be posted in order, got %+v", received)
+	}
+	if received[0].ID == "" || received[0].ID == received[1].ID || received[0].Time.IsZero() {
+		t.Errorf("Expected events to get unique ids and a time, got %+v", received)
+	}
+	if failed := failures.received(); len(failed) != 1 || failed[0].Type != EventExecutionFailed {
+		t.Errorf("Expected only the failure to be posted to its subscriber, got %+v", failed)
+	}
+
+	// events raised after closing are dropped
+	n.Notify(Event{Type: EventTaskCompleted})
+	if n.Dropped() != 1 {
+		t.Errorf("Expected the event to be dropped, got %d dropped", n.Dropped())
+	}
+}
+
+func Test_NotifierRetriesFailedDeliveries(t *testing.T) {
+	endpoint := &recorder{status: http.StatusServiceUnavailable}
+	server := httptest.NewServer(endpoint)
+	defer server.Close()
+
+	settings := resilience.DefaultSettings()
+	settings.InitialBackoff, settings.MaxBackoff = time.Millisecond, time.Millisecond
+	n := New(1, resilience.NewPolicy("notify", settings), zap.NewNop())
+	n.Subscribe(NewWebhook(server.URL, []byte("secret"), time.Second))
+	n.Notify(Event{Type: EventAnomalyDetected})
+	if err := n.Close(context.Background()); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if received := endpoint.received(); len(received) != 1 {
+		t.Errorf("Expected the event to be delivered once the endpoint recovered, got %+v", received)
+	}
+}
+
+func Test_NilNotifier(t *testing.T) {
+	var n *Notifier
+	n.Notify(Event{Type: EventTaskCompleted})
+	if n.Dropped() != 0 || n.Close(context.Background()) != nil {
+		t.Errorf("Expected a nil notifier to do nothing")
+	}
+	if n, err := NewFromConfig(DefaultConfig(), nil, zap.NewNop()); n != nil || err != nil {
+		t.Errorf("Expected no notifier when disabled, got %v, %v", n, err)
+	}
+}
+
+func Test_NewFromConfigReadsSecrets(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Enabled = true
+	cfg.Webhooks = []WebhookConfig{{Url: "https://hooks.example.com/avs", SecretEnv: "NOTIFY_TEST_SECRET"}}
+	if _, err := NewFromConfig(cfg, nil, zap.NewNop()); err == nil {
+		t.Errorf("Expected an unset secret to be rejected")
+	}
+	t.Setenv("NOTIFY_TEST_SECRET", "secret")
+	n, err := NewFromConfig(cfg, nil, zap.NewNop())
+	if err != nil || n == nil {
+		t.Fatalf("NewFromConfig failed: %v", err)
+	}
+	n.Close(context.Background())
+}
@@ -0,0 +1,151 @@
+// Package operator onboards operators onto the AVS. It generates their BN254 (BLS) and
+// ECDSA keys into encrypted keystores, registers their keys with EigenLayer's
+// KeyRegistrar and their operator sets with the AllocationManager, sets their metadata
+// URI in the DelegationManager and reports how far their registration got. Once
+// registered, Standing tells whether the operator may still execute tasks moving funds.
+package operator
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fp"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// BLSKey is a BN254 key, which operator sets of the BN254 curve type sign task results
+// with
+type BLSKey struct {
+	secret *big.Int
+}
+
+// GenerateBLSKey returns a random BN254 key
+func GenerateBLSKey() (*BLSKey, error) {
+	for {
+		secret, err := rand.Int(rand.Reader, fr.Modulus())
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate BLS key: %w", err)
+		}
+		if secret.Sign() > 0 {
+			return &BLSKey{secret: secret}, nil
+		}
+	}
+}
+
+// ParseBLSKey parses the hex of a BN254 secret key, with or without 0x prefix
+func ParseBLSKey(hexKey string) (*BLSKey, error) {
+	secret, ok := new(big.Int).SetString(strings.TrimPrefix(strings.TrimSpace(hexKey), "0x"), 16)
+	if !ok {
+		return nil, errors.New("BLS key must be hex encoded")
+	}
+	return NewBLSKey(secret.Bytes())
+}
+
+// NewBLSKey returns the BN254 key of the big endian secret
+func NewBLSKey(secret []byte) (*BLSKey, error) {
+	s := new(big.Int).SetBytes(secret)
+	if s.Sign() == 0 || s.Cmp(fr.Modulus()) >= 0 {
+		return nil, errors.New("BLS key is out of range")
+	}
+	return &BLSKey{secret: s}, nil
+}
+
+// Secret returns the 32 byte big endian secret of the key
+func (k *BLSKey) Secret() []byte {
+	return common.LeftPadBytes(k.secret.Bytes(), 32)
+}
+
+// G1 returns the public key of the key in G1
+func (k *BLSKey) G1() bn254.G1Affine {
+	var p bn254.G1Affine
+	p.ScalarMultiplicationBase(k.secret)
+	return p
+}
+
+// G2 returns the public key of the key in G2
+func (k *BLSKey) G2() bn254.G2Affine {
+	var p bn254.G2Affine
+	p.ScalarMultiplicationBase(k.secret)
+	return p
+}
+
+// PublicKey returns the hex of the compressed G1 public key
+func (k *BLSKey) PublicKey() string {
+	g1 := k.G1()
+	compressed := g1.Bytes()
+	return hexutil.Encode(compressed[:])
+}
+
+// Sign signs messageHash, mapped to G1 the way EigenLayer's BN254 library maps it
+func (k *BLSKey) Sign(messageHash common.Hash) bn254.G1Affine {
+	h := HashToG1(messageHash)
+	var sig bn254.G1Affine
+	sig.ScalarMultiplication(&h, k.secret)
+	return sig
+}
+
+var (
+	// curveB is the constant of y² = x³ + 3
+	curveB = big.NewInt(3)
+	// sqrtExponent is (p + 1) / 4, which square roots modulo p ≡ 3 mod 4 are powers of
+	sqrtExponent = new(big.Int).Rsh(new(big.Int).Add(fp.Modulus(), big.NewInt(1)), 2)
+)
+
+// HashToG1 maps messageHash to G1 by try-and-increment, as BN254.hashToG1 of EigenLayer
+// does on chain: x starts at the hash modulo p and is incremented until x³ + 3 is a square.
+func HashToG1(messageHash common.Hash) bn254.G1Affine {
+	p := fp.Modulus()
+	x := new(big.Int).Mod(new(big.Int).SetBytes(messageHash.Bytes()), p)
+	for {
+		beta := new(big.Int).Exp(x, big.NewInt(3), p)
+		beta.Add(beta, curveB).Mod(beta, p)
+		y := new(big.Int).Exp(beta, sqrtExponent, p)
+		if new(big.Int).Exp(y, big.NewInt(2), p).Cmp(beta) == 0 {
+			var point bn254.G1Affine
+			point.X.SetBigInt(x)
+			point.Y.SetBigInt(y)
+			return point
+		}
+		x.Add(x, big.NewInt(1)).Mod(x, p)
+	}
+}
+
+var (
+	uint256Type, _  = abi.NewType("uint256", "", nil)
+	uint256x2, _    = abi.NewType("uint256[2]", "", nil)
+	g1Arguments     = abi.Arguments{{Type: uint256Type}, {Type: uint256Type}}
+	bn254KeyDataABI = abi.Arguments{{Type: uint256Type}, {Type: uint256Type}, {Type: uint256x2}, {Type: uint256x2}}
+)
+
+// KeyData returns the key data the KeyRegistrar takes for BN254 keys: the G1 and G2
+// public keys, ABI encoded as (uint256 x, uint256 y, uint256[2] X, uint256[2] Y). G2
+// coordinates are encoded imaginary part first, as the pairing precompile takes them.
+func (k *BLSKey) KeyData() []byte {
+	g1, g2 := k.G1(), k.G2()
+	data, err := bn254KeyDataABI.Pack(
+		g1.X.BigInt(new(big.Int)), g1.Y.BigInt(new(big.Int)),
+		[2]*big.Int{g2.X.A1.BigInt(new(big.Int)), g2.X.A0.BigInt(new(big.Int))},
+		[2]*big.Int{g2.Y.A1.BigInt(new(big.Int)), g2.Y.A0.BigInt(new(big.Int))},
+	)
+	if err != nil {
+		// static arguments of valid points always pack
+		panic(err)
+	}
+	return data
+}
+
+// EncodeSignature ABI encodes a BN254 signature as (uint256 x, uint256 y)
+func EncodeSignature(sig bn254.G1Affine) []byte {
+	data, err := g1Arguments.Pack(sig.X.BigInt(new(big.Int)), sig.Y.BigInt(new(big.Int)))
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
@@ -0,0 +1,222 @@
+package operator
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/text/unicode/norm"
+)
+
+// ErrWrongPassword is returned when a keystore does not decrypt with the password given
+var ErrWrongPassword = errors.New("wrong keystore password")
+
+// KDF sets the cost of deriving keystore encryption keys from passwords
+type KDF struct {
+	N, P int
+}
+
+var (
+	// StandardKDF is the scrypt cost of EIP-2335 and geth keystores
+	StandardKDF = KDF{N: keystore.StandardScryptN, P: keystore.StandardScryptP}
+
+	// LightKDF derives keys fast, for throwaway keys and tests
+	LightKDF = KDF{N: keystore.LightScryptN, P: keystore.LightScryptP}
+)
+
+// blsKeystore is an EIP-2335 keystore, with the curve of the key named as EigenLayer
+// tooling names it
+type blsKeystore struct {
+	Crypto    blsCrypto `json:"crypto"`
+	Pubkey    string    `json:"pubkey"`
+	Path      string    `json:"path"`
+	UUID      string    `json:"uuid"`
+	Version   int       `json:"version"`
+	CurveType string    `json:"curveType"`
+}
+
+type blsCrypto struct {
+	KDF      keystoreModule `json:"kdf"`
+	Checksum keystoreModule `json:"checksum"`
+	Cipher   keystoreModule `json:"cipher"`
+}
+
+type keystoreModule struct {
+	Function string                 `json:"function"`
+	Params   map[string]interface{} `json:"params"`
+	Message  string                 `json:"message"`
+}
+
+// SaveBLSKey writes key to path as an EIP-2335 keystore encrypted with password. The
+// file is created readable by its owner only and is never overwritten.
+func SaveBLSKey(path string, key *BLSKey, password string, kdf KDF) error {
+	salt := make([]byte, 32)
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	if _, err := rand.Read(iv); err != nil {
+		return err
+	}
+	derived, err := scrypt.Key(normalizePassword(password), salt, kdf.N, 8, kdf.P, 32)
+	if err != nil {
+		return fmt.Errorf("failed to derive keystore key: %w", err)
+	}
+	ciphertext, err := aesCTR(derived[:16], iv, key.Secret())
+	if err != nil {
+		return err
+	}
+	checksum := sha256.Sum256(append(append([]byte{}, derived[16:32]...), ciphertext...))
+
+	encoded, err := json.MarshalIndent(blsKeystore{
+		Crypto: blsCrypto{
+			KDF: keystoreModule{Function: "scrypt", Params: map[string]interface{}{
+				"dklen": 32, "n": kdf.N, "r": 8, "p": kdf.P, "salt": hex.EncodeToString(salt),
+			}},
+			Checksum: keystoreModule{Function: "sha256", Params: map[string]interface{}{}, Message: hex.EncodeToString(checksum[:])},
+			Cipher:   keystoreModule{Function: "aes-128-ctr", Params: map[string]interface{}{"iv": hex.EncodeToString(iv)}, Message: hex.EncodeToString(ciphertext)},
+		},
+		Pubkey:    strings.TrimPrefix(key.PublicKey(), "0x"),
+		UUID:      uuid.NewString(),
+		Version:   4,
+		CurveType: "bn254",
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeKeystore(path, encoded)
+}
+
+// LoadBLSKey decrypts the EIP-2335 keystore at path
+func LoadBLSKey(path, password string) (*BLSKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keystore: %w", err)
+	}
+	var ks blsKeystore
+	if err := json.Unmarshal(data, &ks); err != nil {
+		return nil, fmt.Errorf("failed to parse keystore %s: %w", path, err)
+	}
+	if ks.CurveType != "" && ks.CurveType != "bn254" {
+		return nil, fmt.Errorf("keystore %s holds a %s key, not a bn254 key", path, ks.CurveType)
+	}
+	if ks.Crypto.KDF.Function != "scrypt" || ks.Crypto.Checksum.Function != "sha256" || ks.Crypto.Cipher.Function != "aes-128-ctr" {
+		return nil, fmt.Errorf("keystore %s uses an unsupported kdf, checksum or cipher", path)
+	}
+
+	params := ks.Crypto.KDF.Params
+	n, r, p, dklen := intParam(params, "n"), intParam(params, "r"), intParam(params, "p"), intParam(params, "dklen")
+	salt, err := hexParam(params, "salt")
+	if err != nil || dklen < 32 {
+		return nil, fmt.Errorf("keystore %s has invalid kdf params", path)
+	}
+	derived, err := scrypt.Key(normalizePassword(password), salt, n, r, p, dklen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive keystore key: %w", err)
+	}
+	ciphertext, err := hex.DecodeString(ks.Crypto.Cipher.Message)
+	if err != nil {
+		return nil, fmt.Errorf("keystore %s has an invalid cipher message", path)
+	}
+	checksum := sha256.Sum256(append(append([]byte{}, derived[16:32]...), ciphertext...))
+	if hex.EncodeToString(checksum[:]) != strings.ToLower(ks.Crypto.Checksum.Message) {
+		return nil, ErrWrongPassword
+	}
+	iv, err := hexParam(ks.Crypto.Cipher.Params, "iv")
+	if err != nil {
+		return nil, fmt.Errorf("keystore %s has an invalid cipher iv", path)
+	}
+	secret, err := aesCTR(derived[:16], iv, ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	return NewBLSKey(secret)
+}
+
+// SaveECDSAKey writes key to path as a geth keystore encrypted with password. The file is
+// created readable by its owner only and is never overwritten.
+func SaveECDSAKey(path string, key *ecdsa.PrivateKey, password string, kdf KDF) error {
+	encoded, err := keystore.EncryptKey(&keystore.Key{
+		Id:         uuid.New(),
+		Address:    crypto.PubkeyToAddress(key.PublicKey),
+		PrivateKey: key,
+	}, password, kdf.N, kdf.P)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt key: %w", err)
+	}
+	return writeKeystore(path, encoded)
+}
+
+// LoadECDSAKey decrypts the geth keystore at path
+func LoadECDSAKey(path, password string) (*ecdsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keystore: %w", err)
+	}
+	key, err := keystore.DecryptKey(data, password)
+	if errors.Is(err, keystore.ErrDecrypt) {
+		return nil, ErrWrongPassword
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt keystore %s: %w", path, err)
+	}
+	return key.PrivateKey, nil
+}
+
+func writeKeystore(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create keystore: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write keystore: %w", err)
+	}
+	return f.Close()
+}
+
+// normalizePassword prepares password as EIP-2335 does: NFKD normalized, without control
+// codes
+func normalizePassword(password string) []byte {
+	var out bytes.Buffer
+	for _, r := range norm.NFKD.String(password) {
+		if !unicode.IsControl(r) {
+			out.WriteRune(r)
+		}
+	}
+	return out.Bytes()
+}
+
+func aesCTR(key, iv, in []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, len(in))
+	cipher.NewCTR(block, iv).XORKeyStream(out, in)
+	return out, nil
+}
+
+func intParam(params map[string]interface{}, name string) int {
+	v, _ := params[name].(float64)
+	return int(v)
+}
+
+func hexParam(params map[string]interface{}, name string) ([]byte, error) {
+	s, _ := params[name].(string)
+	return hex.DecodeString(s)
+}
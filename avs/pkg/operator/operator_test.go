@@ -0,0 +1,267 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/chain/chaintest"
+)
+
+var (
+	avsAddress               = common.HexToAddress("0x00000000000000000000000000000000000000a5")
+	allocationManagerAddress = common.HexToAddress("0x0000000000000000000000000000000000000a11")
+	delegationManagerAddress = common.HexToAddress("0x0000000000000000000000000000000000000de1")
+	keyRegistrarAddress      = common.HexToAddress("0x0000000000000000000000000000000000000ce7")
+)
+
+func Test_BLSSignatureVerifiesWithPairing(t *testing.T) {
+	key, err := GenerateBLSKey()
+	if err != nil {
+		t.Fatalf("GenerateBLSKey failed: %v", err)
+	}
+	hash := crypto.Keccak256Hash([]byte("register"))
+	point := HashToG1(hash)
+	if !point.IsOnCurve() {
+		t.Fatalf("Expected the hash to map onto the curve")
+	}
+
+	// e(sig, -G2) · e(H(m), pk) == 1 is what the KeyRegistrar checks
+	_, _, _, g2 := bn254.Generators()
+	var negG2 bn254.G2Affine
+	negG2.Neg(&g2)
+	sig := key.Sign(hash)
+	ok, err := bn254.PairingCheck([]bn254.G1Affine{sig, point}, []bn254.G2Affine{negG2, key.G2()})
+	if err != nil || !ok {
+		t.Errorf("Expected the signature to verify against the G2 public key: %v", err)
+	}
+
+	parsed, err := ParseBLSKey(common.Bytes2Hex(key.Secret()))
+	if err != nil || parsed.PublicKey() != key.PublicKey() {
+		t.Errorf("Expected the parsed key to match, got %v", err)
+	}
+	if _, err := ParseBLSKey("0x00"); err == nil {
+		t.Errorf("Expected a zero key to be rejected")
+	}
+}
+
+func Test_KeystoresRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	blsKey, err := GenerateBLSKey()
+	if err != nil {
+		t.Fatalf("GenerateBLSKey failed: %v", err)
+	}
+	blsPath := filepath.Join(dir, "bls.json")
+	if err := SaveBLSKey(blsPath, blsKey, "pässword", LightKDF); err != nil {
+		t.Fatalf("SaveBLSKey failed: %v", err)
+	}
+	loaded, err := LoadBLSKey(blsPath, "pässword")
+	if err != nil || loaded.PublicKey() != blsKey.PublicKey() {
+		t.Fatalf("Expected the BLS key back, got %v", err)
+	}
+	if _, err := LoadBLSKey(blsPath, "other"); !errors.Is(err, ErrWrongPassword) {
+		t.Errorf("Expected a wrong password to be reported, got %v", err)
+	}
+	if err := SaveBLSKey(blsPath, blsKey, "pässword", LightKDF); err == nil {
+		t.Errorf("Expected an existing keystore not to be overwritten")
+	}
+
+	ecdsaKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	ecdsaPath := filepath.Join(dir, "ecdsa.json")
+	if err := SaveECDSAKey(ecdsaPath, ecdsaKey, "password", LightKDF); err != nil {
+		t.Fatalf("SaveECDSAKey failed: %v", err)
+	}
+	loadedECDSA, err := LoadECDSAKey(ecdsaPath, "password")
+	if err != nil || !loadedECDSA.Equal(ecdsaKey) {
+		t.Fatalf("Expected the ECDSA key back, got %v", err)
+	}
+	if _, err := LoadECDSAKey(ecdsaPath, "other"); !errors.Is(err, ErrWrongPassword) {
+		t.Errorf("Expected a wrong password to be reported, got %v", err)
+	}
+}
+
+func newTestRegistrar(t *testing.T) (*Registrar, *chaintest.Contracts) {
+	t.Helper()
+	contracts := chaintest.NewContracts(1)
+	chains := chain.NewManager()
+	chains.Register(1, "ethereum", contracts)
+	cfg := DefaultConfig()
+	cfg.ChainID, cfg.AVS, cfg.OperatorSetIDs = 1, avsAddress.Hex(), []uint32{0, 1}
+	cfg.AllocationManager, cfg.DelegationManager, cfg.KeyRegistrar = allocationManagerAddress.Hex(), delegationManagerAddress.Hex(), keyRegistrarAddress.Hex()
+	cfg.Socket = "performer.example.com:8080"
+	r, err := NewRegistrar(cfg, chains)
+	if err != nil {
+		t.Fatalf("NewRegistrar failed: %v", err)
+	}
+	return r, contracts
+}
+
+func Test_RegistrationCalls(t *testing.T) {
+	r, contracts := newTestRegistrar(t)
+	operator := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	hash := crypto.Keccak256Hash([]byte("message"))
+	contracts.Stub(t, keyRegistrarAddress, keyRegistrarABI, "getBN254KeyRegistrationMessageHash", hash)
+	contracts.Stub(t, keyRegistrarAddress, keyRegistrarABI, "getECDSAKeyRegistrationMessageHash", hash)
+
+	blsKey, _ := GenerateBLSKey()
+	call, err := r.RegisterBN254Key(context.Background(), operator, 1, blsKey)
+	if err != nil {
+		t.Fatalf("RegisterBN254Key failed: %v", err)
+	}
+	args, err := keyRegistrarABI.Methods["registerKey"].Inputs.Unpack(call.Data[4:])
+	if err != nil || call.To != keyRegistrarAddress {
+		t.Fatalf("Expected a registerKey call, got %v", err)
+	}
+	if string(args[2].([]byte)) != string(blsKey.KeyData()) || string(args[3].([]byte)) != string(EncodeSignature(blsKey.Sign(hash))) {
+		t.Errorf("Unexpected BN254 key registration %v", args)
+	}
+
+	ecdsaKey, _ := crypto.GenerateKey()
+	call, err = r.RegisterECDSAKey(context.Background(), operator, 0, ecdsaKey)
+	if err != nil {
+		t.Fatalf("RegisterECDSAKey failed: %v", err)
+	}
+	args, _ = keyRegistrarABI.Methods["registerKey"].Inputs.Unpack(call.Data[4:])
+	signature := args[3].([]byte)
+	if v := signature[64]; v != 27 && v != 28 {
+		t.Errorf("Expected the recovery id to be 27 or 28, got %d", v)
+	}
+	signature[64] -= 27
+	signer, err := crypto.SigToPub(hash.Bytes(), signature)
+	if err != nil || common.BytesToAddress(args[2].([]byte)) != crypto.PubkeyToAddress(*signer) {
+		t.Errorf("Expected the signature to recover to the registered key, got %v", err)
+	}
+
+	call, err = r.RegisterForOperatorSets(operator, []uint32{0, 1})
+	if err != nil || call.To != allocationManagerAddress {
+		t.Fatalf("Expected a registerForOperatorSets call, got %v", err)
+	}
+	args, _ = allocationManagerABI.Methods["registerForOperatorSets"].Inputs.Unpack(call.Data[4:])
+	params := args[1].(struct {
+		Avs            common.Address `json:"avs"`
+		OperatorSetIds []uint32       `json:"operatorSetIds"`
+		Data           []byte         `json:"data"`
+	})
+	socket, _ := socketArguments.Unpack(params.Data)
+	if params.Avs != avsAddress || len(params.OperatorSetIds) != 2 || socket[0] != "performer.example.com:8080" {
+		t.Errorf("Unexpected registration params %+v", params)
+	}
+}
+
+func Test_Status(t *testing.T) {
+	r, contracts := newTestRegistrar(t)
+	operator := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	contracts.Stub(t, delegationManagerAddress, delegationManagerABI, "isOperator", true)
+	contracts.Stub(t, keyRegistrarAddress, keyRegistrarABI, "getOperatorSetCurveType", uint8(CurveTypeBN254))
+	contracts.Stub(t, keyRegistrarAddress, keyRegistrarABI, "isRegistered", true)
+	contracts.Stub(t, allocationManagerAddress, allocationManagerABI, "isMemberOfOperatorSet", false)
+
+	status, err := r.Status(context.Background(), operator)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if !status.IsOperator || len(status.Sets) != 2 || status.Sets[1].CurveType != CurveTypeBN254 || !status.Sets[1].KeyRegistered || status.Registered() {
+		t.Errorf("Unexpected status %+v", status)
+	}
+
+	contracts.Stub(t, allocationManagerAddress, allocationManagerABI, "isMemberOfOperatorSet", true)
+	if status, err := r.Status(context.Background(), operator); err != nil || !status.Registered() {
+		t.Errorf("Expected the operator to be registered, got %+v: %v", status, err)
+	}
+}
+
+func Test_ConfigRequiresContracts(t *testing.T) {
+	cfg := DefaultConfig()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Expected an unset config to be valid: %v", err)
+	}
+	if _, err := NewRegistrar(cfg, chain.NewManager()); err == nil {
+		t.Errorf("Expected registering without contracts to be rejected")
+	}
+	cfg.KeyRegistrar = "registrar"
+	if err := cfg.Validate(); err == nil {
+		t.Errorf("Expected an invalid address to be rejected")
+	}
+}
+
+func Test_StandingRefusesOperatorsOutOfSetsOrStake(t *testing.T) {
+	contracts := chaintest.NewContracts(1)
+	chains := chain.NewManager()
+	chains.Register(1, "ethereum", contracts)
+	serviceManager := common.HexToAddress("0x00000000000000000000000000000000000005e4")
+	cfg := DefaultConfig()
+	cfg.ChainID, cfg.AVS = 1, avsAddress.Hex()
+	cfg.AllocationManager, cfg.DelegationManager, cfg.KeyRegistrar = allocationManagerAddress.Hex(), delegationManagerAddress.Hex(), keyRegistrarAddress.Hex()
+	cfg.Standing = StandingConfig{
+		Enabled:        true,
+		Operator:       "0x0000000000000000000000000000000000000001",
+		Strategies:     []string{"0x0000000000000000000000000000000000005701", "0x0000000000000000000000000000000000005702"},
+		MinimumStake:   "100",
+		ServiceManager: serviceManager.Hex(),
+		CacheTTL:       time.Hour,
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Expected the config to be valid: %v", err)
+	}
+	qualifiedABI := chain.MustParseABI(`[{"name":"isYieldIntelligenceOperatorQualified","type":"function","stateMutability":"view","inputs":[{"name":"operator","type":"address"}],"outputs":[{"name":"","type":"bool"}]}]`)
+
+	testCases := map[string]struct {
+		member    bool
+		stake     []*big.Int
+		qualified bool
+		reason    string
+	}{
+		"in good standing": {member: true, stake: []*big.Int{big.NewInt(60), big.NewInt(40)}, qualified: true},
+		"ejected":          {member: false, stake: []*big.Int{big.NewInt(100), big.NewInt(0)}, qualified: true, reason: "not registered for operator set 0"},
+		"slashed":          {member: true, stake: []*big.Int{big.NewInt(60), big.NewInt(39)}, qualified: true, reason: "is 99, below the minimum of 100"},
+		"unqualified":      {member: true, stake: []*big.Int{big.NewInt(100), big.NewInt(0)}, qualified: false, reason: "not qualified by service manager"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			contracts.Stub(t, allocationManagerAddress, allocationManagerABI, "isMemberOfOperatorSet", tc.member)
+			contracts.Stub(t, allocationManagerAddress, stakeABI, "getAllocatedStake", [][]*big.Int{tc.stake})
+			contracts.Stub(t, serviceManager, qualifiedABI, "isYieldIntelligenceOperatorQualified", tc.qualified)
+
+			err := NewStandingFromConfig(cfg, chains).Check(context.Background())
+			if tc.reason == "" {
+				if err != nil {
+					t.Errorf("Expected the operator to be in good standing, got %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrNotInGoodStanding) || !strings.Contains(err.Error(), tc.reason) {
+				t.Errorf("Expected the operator to be refused with %q, got %v", tc.reason, err)
+			}
+		})
+	}
+
+	// standings are trusted for the cache TTL
+	contracts.Stub(t, allocationManagerAddress, allocationManagerABI, "isMemberOfOperatorSet", false)
+	standing := NewStandingFromConfig(cfg, chains)
+	if err := standing.Check(context.Background()); !errors.Is(err, ErrNotInGoodStanding) {
+		t.Fatalf("Expected the operator to be refused, got %v", err)
+	}
+	contracts.Stub(t, allocationManagerAddress, allocationManagerABI, "isMemberOfOperatorSet", true)
+	if err := standing.Check(context.Background()); !errors.Is(err, ErrNotInGoodStanding) {
+		t.Errorf("Expected the refusal to be cached, got %v", err)
+	}
+
+	cfg.Standing.MinimumStake = "-1"
+	if err := cfg.Validate(); err == nil {
+		t.Errorf("Expected a negative minimum stake to be rejected")
+	}
+	if NewStandingFromConfig(DefaultConfig(), chains) != nil {
+		t.Errorf("Expected no check when disabled")
+	}
+}
@@ -0,0 +1,384 @@
+package operator
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+)
+
+// Config locates the AVS and the EigenLayer core contracts operators register with. The
+// addresses are those of the deployment the AVS runs on; they are needed by the operator
+// commands and the standing check.
+type Config struct {
+	ChainID uint64 `yaml:"chainId"`
+
+	// AVS is the address of the AVS, the ServiceManager operator sets are created under,
+	// and OperatorSetIDs the sets operators register for
+	AVS            string   `yaml:"avs"`
+	OperatorSetIDs []uint32 `yaml:"operatorSetIds"`
+
+	AllocationManager string `yaml:"allocationManager"`
+	DelegationManager string `yaml:"delegationManager"`
+	KeyRegistrar      string `yaml:"keyRegistrar"`
+
+	// Socket is the endpoint the aggregator reaches the performer of the operator at,
+	// passed to the AVS registrar on registration
+	Socket string `yaml:"socket"`
+
+	// Standing refuses tasks moving funds while the operator is not registered for the
+	// operator sets, or lost its stake. Disabled by default.
+	Standing StandingConfig `yaml:"standing"`
+}
+
+// DefaultConfig registers for operator set 0, the executor set of Hourglass AVSs
+func DefaultConfig() Config {
+	return Config{OperatorSetIDs: []uint32{0}, Standing: DefaultStandingConfig()}
+}
+
+// Validate checks the addresses that are set. Missing ones are reported by the commands
+// needing them, but the standing check needs every contract.
+func (c Config) Validate() error {
+	if c.Standing.Enabled {
+		if err := c.require(); err != nil {
+			return err
+		}
+		if err := c.Standing.validate(); err != nil {
+			return err
+		}
+	}
+	for name, address := range map[string]string{
+		"avs":               c.AVS,
+		"allocationManager": c.AllocationManager,
+		"delegationManager": c.DelegationManager,
+		"keyRegistrar":      c.KeyRegistrar,
+	} {
+		if address != "" && !common.IsHexAddress(address) {
+			return fmt.Errorf("invalid %s address %q", name, address)
+		}
+	}
+	return nil
+}
+
+// require checks that cfg has what registering operators takes
+func (c Config) require() error {
+	if c.ChainID == 0 {
+		return fmt.Errorf("operator.chainId is required")
+	}
+	if len(c.OperatorSetIDs) == 0 {
+		return fmt.Errorf("operator.operatorSetIds is required")
+	}
+	for name, address := range map[string]string{
+		"operator.avs":               c.AVS,
+		"operator.allocationManager": c.AllocationManager,
+		"operator.delegationManager": c.DelegationManager,
+		"operator.keyRegistrar":      c.KeyRegistrar,
+	} {
+		if !common.IsHexAddress(address) {
+			return fmt.Errorf("%s must be an address", name)
+		}
+	}
+	return nil
+}
+
+var (
+	allocationManagerABI = chain.MustParseABI(`[
+		{"name":"registerForOperatorSets","type":"function","stateMutability":"nonpayable","inputs":[
+			{"name":"operator","type":"address"},
+			{"name":"params","type":"tuple","components":[{"name":"avs","type":"address"},{"name":"operatorSetIds","type":"uint32[]"},{"name":"data","type":"bytes"}]}],"outputs":[]},
+		{"name":"isMemberOfOperatorSet","type":"function","stateMutability":"view","inputs":[
+			{"name":"operator","type":"address"},
+			{"name":"operatorSet","type":"tuple","components":[{"name":"avs","type":"address"},{"name":"id","type":"uint32"}]}],"outputs":[{"name":"","type":"bool"}]}
+	]`)
+
+	delegationManagerABI = chain.MustParseABI(`[
+		{"name":"isOperator","type":"function","stateMutability":"view","inputs":[{"name":"operator","type":"address"}],"outputs":[{"name":"","type":"bool"}]},
+		{"name":"updateOperatorMetadataURI","type":"function","stateMutability":"nonpayable","inputs":[
+			{"name":"operator","type":"address"},{"name":"metadataURI","type":"string"}],"outputs":[]}
+	]`)
+
+	keyRegistrarABI = chain.MustParseABI(`[
+		{"name":"registerKey","type":"function","stateMutability":"nonpayable","inputs":[
+			{"name":"operator","type":"address"},
+			{"name":"operatorSet","type":"tuple","components":[{"name":"avs","type":"address"},{"name":"id","type":"uint32"}]},
+			{"name":"pubkey","type":"bytes"},{"name":"signature","type":"bytes"}],"outputs":[]},
+		{"name":"isRegistered","type":"function","stateMutability":"view","inputs":[
+			{"name":"operatorSet","type":"tuple","components":[{"name":"avs","type":"address"},{"name":"id","type":"uint32"}]},
+			{"name":"operator","type":"address"}],"outputs":[{"name":"","type":"bool"}]},
+		{"name":"getOperatorSetCurveType","type":"function","stateMutability":"view","inputs":[
+			{"name":"operatorSet","type":"tuple","components":[{"name":"avs","type":"address"},{"name":"id","type":"uint32"}]}],"outputs":[{"name":"","type":"uint8"}]},
+		{"name":"getBN254KeyRegistrationMessageHash","type":"function","stateMutability":"view","inputs":[
+			{"name":"operator","type":"address"},
+			{"name":"operatorSet","type":"tuple","components":[{"name":"avs","type":"address"},{"name":"id","type":"uint32"}]},
+			{"name":"keyData","type":"bytes"}],"outputs":[{"name":"","type":"bytes32"}]},
+		{"name":"getECDSAKeyRegistrationMessageHash","type":"function","stateMutability":"view","inputs":[
+			{"name":"operator","type":"address"},
+			{"name":"operatorSet","type":"tuple","components":[{"name":"avs","type":"address"},{"name":"id","type":"uint32"}]},
+			{"name":"keyAddress","type":"address"}],"outputs":[{"name":"","type":"bytes32"}]}
+	]`)
+
+	// socketArguments encode the registration data of the TaskAVSRegistrar
+	stringType, _   = abi.NewType("string", "", nil)
+	socketArguments = abi.Arguments{{Type: stringType}}
+)
+
+// operatorSet is the OperatorSet struct of EigenLayer
+type operatorSet struct {
+	Avs common.Address
+	Id  uint32
+}
+
+// registerParams is the RegisterParams struct of the AllocationManager
+type registerParams struct {
+	Avs            common.Address
+	OperatorSetIds []uint32
+	Data           []byte
+}
+
+// CurveType is the key type an operator set signs with, as the KeyRegistrar numbers it
+type CurveType uint8
+
+const (
+	CurveTypeNone CurveType = iota
+	CurveTypeECDSA
+	CurveTypeBN254
+)
+
+func (c CurveType) String() string {
+	switch c {
+	case CurveTypeECDSA:
+		return "ecdsa"
+	case CurveTypeBN254:
+		return "bn254"
+	default:
+		return "none"
+	}
+}
+
+// MarshalText names the curve type in status reports
+func (c CurveType) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// SetStatus is how far an operator got registering for an operator set
+type SetStatus struct {
+	ID            uint32    `json:"id"`
+	CurveType     CurveType `json:"curveType"`
+	KeyRegistered bool      `json:"keyRegistered"`
+	Member        bool      `json:"member"`
+}
+
+// Status is how far an operator got registering with the AVS
+type Status struct {
+	Operator   common.Address `json:"operator"`
+	IsOperator bool           `json:"isOperator"`
+	Sets       []SetStatus    `json:"operatorSets"`
+}
+
+// Registered reports whether the operator is a member of every set with its key
+// registered
+func (s *Status) Registered() bool {
+	if !s.IsOperator {
+		return false
+	}
+	for _, set := range s.Sets {
+		if !set.KeyRegistered || !set.Member {
+			return false
+		}
+	}
+	return true
+}
+
+// Registrar reads and builds the registration calls of operators. Calls are to be sent
+// from the operator account.
+type Registrar struct {
+	chains            *chain.Manager
+	cfg               Config
+	avs               common.Address
+	allocationManager common.Address
+	delegationManager common.Address
+	keyRegistrar      common.Address
+}
+
+// NewRegistrar binds the contracts of cfg
+func NewRegistrar(cfg Config, chains *chain.Manager) (*Registrar, error) {
+	if err := cfg.require(); err != nil {
+		return nil, err
+	}
+	return newRegistrar(cfg, chains), nil
+}
+
+func newRegistrar(cfg Config, chains *chain.Manager) *Registrar {
+	return &Registrar{
+		chains:            chains,
+		cfg:               cfg,
+		avs:               common.HexToAddress(cfg.AVS),
+		allocationManager: common.HexToAddress(cfg.AllocationManager),
+		delegationManager: common.HexToAddress(cfg.DelegationManager),
+		keyRegistrar:      common.HexToAddress(cfg.KeyRegistrar),
+	}
+}
+
+func (r *Registrar) set(id uint32) operatorSet {
+	return operatorSet{Avs: r.avs, Id: id}
+}
+
+func (r *Registrar) call(ctx context.Context, contract common.Address, contractABI abi.ABI, method string, args ...interface{}) (interface{}, error) {
+	client, err := r.chains.Client(r.cfg.ChainID)
+	if err != nil {
+		return nil, err
+	}
+	values, err := chain.CallView(ctx, client, contract, contractABI, method, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != 1 {
+		return nil, fmt.Errorf("unexpected %s output length %d", method, len(values))
+	}
+	return values[0], nil
+}
+
+func (r *Registrar) callBool(ctx context.Context, contract common.Address, contractABI abi.ABI, method string, args ...interface{}) (bool, error) {
+	value, err := r.call(ctx, contract, contractABI, method, args...)
+	if err != nil {
+		return false, err
+	}
+	b, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("unexpected %s output type %T", method, value)
+	}
+	return b, nil
+}
+
+func (r *Registrar) callHash(ctx context.Context, method string, args ...interface{}) (common.Hash, error) {
+	value, err := r.call(ctx, r.keyRegistrar, keyRegistrarABI, method, args...)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	hash, ok := value.([32]byte)
+	if !ok {
+		return common.Hash{}, fmt.Errorf("unexpected %s output type %T", method, value)
+	}
+	return hash, nil
+}
+
+// OperatorSetIDs are the sets operators register for
+func (r *Registrar) OperatorSetIDs() []uint32 {
+	return r.cfg.OperatorSetIDs
+}
+
+// IsOperator reports whether operator registered as an operator with the
+// DelegationManager, which registering for operator sets requires
+func (r *Registrar) IsOperator(ctx context.Context, operator common.Address) (bool, error) {
+	return r.callBool(ctx, r.delegationManager, delegationManagerABI, "isOperator", operator)
+}
+
+// CurveType returns the key type of operator set id
+func (r *Registrar) CurveType(ctx context.Context, id uint32) (CurveType, error) {
+	value, err := r.call(ctx, r.keyRegistrar, keyRegistrarABI, "getOperatorSetCurveType", r.set(id))
+	if err != nil {
+		return CurveTypeNone, err
+	}
+	curve, ok := value.(uint8)
+	if !ok {
+		return CurveTypeNone, fmt.Errorf("unexpected getOperatorSetCurveType output type %T", value)
+	}
+	return CurveType(curve), nil
+}
+
+// IsKeyRegistered reports whether operator registered a key for operator set id
+func (r *Registrar) IsKeyRegistered(ctx context.Context, operator common.Address, id uint32) (bool, error) {
+	return r.callBool(ctx, r.keyRegistrar, keyRegistrarABI, "isRegistered", r.set(id), operator)
+}
+
+// IsMember reports whether operator is registered for operator set id
+func (r *Registrar) IsMember(ctx context.Context, operator common.Address, id uint32) (bool, error) {
+	return r.callBool(ctx, r.allocationManager, allocationManagerABI, "isMemberOfOperatorSet", operator, r.set(id))
+}
+
+// RegisterBN254Key returns the call registering key as the key of operator in operator
+// set id, signed over the registration message the KeyRegistrar expects
+func (r *Registrar) RegisterBN254Key(ctx context.Context, operator common.Address, id uint32, key *BLSKey) (chain.Call, error) {
+	keyData := key.KeyData()
+	hash, err := r.callHash(ctx, "getBN254KeyRegistrationMessageHash", operator, r.set(id), keyData)
+	if err != nil {
+		return chain.Call{}, err
+	}
+	return r.registerKey(operator, id, keyData, EncodeSignature(key.Sign(hash)))
+}
+
+// RegisterECDSAKey returns the call registering the address of key as the key of operator
+// in operator set id, signed over the registration message the KeyRegistrar expects
+func (r *Registrar) RegisterECDSAKey(ctx context.Context, operator common.Address, id uint32, key *ecdsa.PrivateKey) (chain.Call, error) {
+	keyAddress := crypto.PubkeyToAddress(key.PublicKey)
+	hash, err := r.callHash(ctx, "getECDSAKeyRegistrationMessageHash", operator, r.set(id), keyAddress)
+	if err != nil {
+		return chain.Call{}, err
+	}
+	signature, err := crypto.Sign(hash.Bytes(), key)
+	if err != nil {
+		return chain.Call{}, fmt.Errorf("failed to sign key registration: %w", err)
+	}
+	signature[64] += 27
+	return r.registerKey(operator, id, keyAddress.Bytes(), signature)
+}
+
+func (r *Registrar) registerKey(operator common.Address, id uint32, pubkey, signature []byte) (chain.Call, error) {
+	data, err := keyRegistrarABI.Pack("registerKey", operator, r.set(id), pubkey, signature)
+	if err != nil {
+		return chain.Call{}, fmt.Errorf("failed to pack registerKey: %w", err)
+	}
+	return chain.Call{To: r.keyRegistrar, Data: data}, nil
+}
+
+// RegisterForOperatorSets returns the call registering operator for the operator sets
+// ids, with the socket of the config as registration data
+func (r *Registrar) RegisterForOperatorSets(operator common.Address, ids []uint32) (chain.Call, error) {
+	socket, err := socketArguments.Pack(r.cfg.Socket)
+	if err != nil {
+		return chain.Call{}, fmt.Errorf("failed to encode socket: %w", err)
+	}
+	data, err := allocationManagerABI.Pack("registerForOperatorSets", operator, registerParams{Avs: r.avs, OperatorSetIds: ids, Data: socket})
+	if err != nil {
+		return chain.Call{}, fmt.Errorf("failed to pack registerForOperatorSets: %w", err)
+	}
+	return chain.Call{To: r.allocationManager, Data: data}, nil
+}
+
+// UpdateMetadataURI returns the call pointing the metadata of operator at uri
+func (r *Registrar) UpdateMetadataURI(operator common.Address, uri string) (chain.Call, error) {
+	data, err := delegationManagerABI.Pack("updateOperatorMetadataURI", operator, uri)
+	if err != nil {
+		return chain.Call{}, fmt.Errorf("failed to pack updateOperatorMetadataURI: %w", err)
+	}
+	return chain.Call{To: r.delegationManager, Data: data}, nil
+}
+
+// Status reads how far operator got registering for the operator sets of the config
+func (r *Registrar) Status(ctx context.Context, operator common.Address) (*Status, error) {
+	isOperator, err := r.IsOperator(ctx, operator)
+	if err != nil {
+		return nil, err
+	}
+	status := &Status{Operator: operator, IsOperator: isOperator, Sets: make([]SetStatus, 0, len(r.cfg.OperatorSetIDs))}
+	for _, id := range r.cfg.OperatorSetIDs {
+		curve, err := r.CurveType(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		registered, err := r.IsKeyRegistered(ctx, operator, id)
+		if err != nil {
+			return nil, err
+		}
+		member, err := r.IsMember(ctx, operator, id)
+		if err != nil {
+			return nil, err
+		}
+		status.Sets = append(status.Sets, SetStatus{ID: id, CurveType: curve, KeyRegistered: registered, Member: member})
+	}
+	return status, nil
+}
@@ -0,0 +1,192 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/servicemanager"
+)
+
+// ErrNotInGoodStanding is wrapped by the errors of Standing.Check refusing the operator
+var ErrNotInGoodStanding = errors.New("operator is not in good standing with the AVS")
+
+// StandingConfig configures the checks of the operator's standing before it executes
+// tasks moving funds
+type StandingConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Operator is the address of the operator checked
+	Operator string `yaml:"operator"`
+
+	// Strategies are the strategies whose stake the operator allocates to the operator
+	// sets, and MinimumStake the least total, in wei of shares, each set must hold. Stake
+	// is only checked when strategies are set; any stake passes without a minimum.
+	Strategies   []string `yaml:"strategies"`
+	MinimumStake string   `yaml:"minimumStake"`
+
+	// ServiceManager is the address of the YieldIntelligenceServiceManager. When set, the
+	// operator must also be qualified by it.
+	ServiceManager string `yaml:"serviceManager"`
+
+	// CacheTTL is how long a checked standing is trusted, bounding both the RPC calls
+	// tasks make and how late an ejection takes effect
+	CacheTTL time.Duration `yaml:"cacheTtl"`
+}
+
+// DefaultStandingConfig is disabled. Enabled, it checks the standing at most once a
+// minute.
+func DefaultStandingConfig() StandingConfig {
+	return StandingConfig{CacheTTL: time.Minute}
+}
+
+func (c StandingConfig) validate() error {
+	if !common.IsHexAddress(c.Operator) {
+		return fmt.Errorf("invalid standing.operator address %q", c.Operator)
+	}
+	for _, strategy := range c.Strategies {
+		if !common.IsHexAddress(strategy) {
+			return fmt.Errorf("invalid standing.strategies address %q", strategy)
+		}
+	}
+	if c.MinimumStake != "" {
+		if minimum, ok := new(big.Int).SetString(c.MinimumStake, 10); !ok || minimum.Sign() < 0 {
+			return fmt.Errorf("standing.minimumStake must be a non-negative integer")
+		}
+	}
+	if c.ServiceManager != "" && !common.IsHexAddress(c.ServiceManager) {
+		return fmt.Errorf("invalid standing.serviceManager address %q", c.ServiceManager)
+	}
+	if c.CacheTTL <= 0 {
+		return fmt.Errorf("standing.cacheTtl must be positive")
+	}
+	return nil
+}
+
+// stakeABI reads the stake operators allocate to operator sets
+var stakeABI = chain.MustParseABI(`[
+	{"name":"getAllocatedStake","type":"function","stateMutability":"view","inputs":[
+		{"name":"operatorSet","type":"tuple","components":[{"name":"avs","type":"address"},{"name":"id","type":"uint32"}]},
+		{"name":"operators","type":"address[]"},{"name":"strategies","type":"address[]"}],"outputs":[{"name":"","type":"uint256[][]"}]}
+]`)
+
+// Standing tells whether the operator may execute tasks moving funds: it must be a
+// member of every operator set of the config, which operators ejected by the AVS are
+// not, keep the minimum stake allocated to each after slashing, and be qualified by the
+// service manager when one is configured.
+type Standing struct {
+	registrar      *Registrar
+	cfg            StandingConfig
+	operator       common.Address
+	strategies     []common.Address
+	minimum        *big.Int
+	serviceManager *servicemanager.ServiceManager
+
+	mu        sync.Mutex
+	checkedAt time.Time
+	refusal   error
+}
+
+// NewStandingFromConfig creates the standing check of cfg. It returns nil when the check
+// is disabled. cfg must be valid.
+func NewStandingFromConfig(cfg Config, chains *chain.Manager) *Standing {
+	if !cfg.Standing.Enabled {
+		return nil
+	}
+	s := &Standing{
+		registrar: newRegistrar(cfg, chains),
+		cfg:       cfg.Standing,
+		operator:  common.HexToAddress(cfg.Standing.Operator),
+		minimum:   new(big.Int),
+	}
+	for _, strategy := range cfg.Standing.Strategies {
+		s.strategies = append(s.strategies, common.HexToAddress(strategy))
+	}
+	if cfg.Standing.MinimumStake != "" {
+		s.minimum.SetString(cfg.Standing.MinimumStake, 10)
+	}
+	if cfg.Standing.ServiceManager != "" {
+		s.serviceManager = servicemanager.New(chains, cfg.ChainID, common.HexToAddress(cfg.Standing.ServiceManager))
+	}
+	return s
+}
+
+// Check returns an error wrapping ErrNotInGoodStanding when the operator may not execute
+// tasks moving funds, and any other error when its standing cannot be read. Standings
+// read are trusted for the cache TTL; failed reads are not kept.
+func (s *Standing) Check(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.checkedAt.IsZero() && time.Since(s.checkedAt) < s.cfg.CacheTTL {
+		return s.refusal
+	}
+	reasons, err := s.read(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check the standing of operator %s: %w", s.operator.Hex(), err)
+	}
+	s.refusal = nil
+	if len(reasons) > 0 {
+		s.refusal = fmt.Errorf("%w: %s", ErrNotInGoodStanding, strings.Join(reasons, "; "))
+	}
+	s.checkedAt = time.Now()
+	return s.refusal
+}
+
+// read returns why the operator is not in good standing, nothing when it is
+func (s *Standing) read(ctx context.Context) ([]string, error) {
+	var reasons []string
+	for _, id := range s.registrar.OperatorSetIDs() {
+		member, err := s.registrar.IsMember(ctx, s.operator, id)
+		if err != nil {
+			return nil, err
+		}
+		if !member {
+			reasons = append(reasons, fmt.Sprintf("%s is not registered for operator set %d", s.operator.Hex(), id))
+			continue
+		}
+		if len(s.strategies) == 0 {
+			continue
+		}
+		stake, err := s.allocatedStake(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if stake.Sign() == 0 || stake.Cmp(s.minimum) < 0 {
+			reasons = append(reasons, fmt.Sprintf("stake allocated to operator set %d is %s, below the minimum of %s", id, stake, s.minimum))
+		}
+	}
+	if s.serviceManager != nil {
+		qualified, err := s.serviceManager.IsOperatorQualified(ctx, s.operator)
+		if err != nil {
+			return nil, err
+		}
+		if !qualified {
+			reasons = append(reasons, fmt.Sprintf("%s is not qualified by service manager %s", s.operator.Hex(), s.serviceManager.Address().Hex()))
+		}
+	}
+	return reasons, nil
+}
+
+// allocatedStake is the total stake the operator allocates to operator set id over the
+// strategies of the config
+func (s *Standing) allocatedStake(ctx context.Context, id uint32) (*big.Int, error) {
+	value, err := s.registrar.call(ctx, s.registrar.allocationManager, stakeABI, "getAllocatedStake", s.registrar.set(id), []common.Address{s.operator}, s.strategies)
+	if err != nil {
+		return nil, err
+	}
+	stakes, ok := value.([][]*big.Int)
+	if !ok || len(stakes) != 1 {
+		return nil, fmt.Errorf("unexpected getAllocatedStake output %T", value)
+	}
+	total := new(big.Int)
+	for _, stake := range stakes[0] {
+		total.Add(total, stake)
+	}
+	return total, nil
+}
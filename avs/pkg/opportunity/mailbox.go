@@ -0,0 +1,96 @@
+package opportunity
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/client"
+	"github.com/najnomics/crosscow-avs/pkg/txmgr"
+)
+
+// mailboxABI creates tasks in Hourglass' TaskMailbox
+var mailboxABI = chain.MustParseABI(`[
+	{"name":"createTask","type":"function","stateMutability":"nonpayable","inputs":[{"name":"taskParams","type":"tuple","components":[
+		{"name":"refundCollector","type":"address"},
+		{"name":"avsFee","type":"uint96"},
+		{"name":"executorOperatorSet","type":"tuple","components":[{"name":"avs","type":"address"},{"name":"id","type":"uint32"}]},
+		{"name":"payload","type":"bytes"}]}],
+	 "outputs":[{"name":"taskHash","type":"bytes32"}]}
+]`)
+
+// operatorSet is the OperatorSet struct of createTask
+type operatorSet struct {
+	Avs common.Address
+	Id  uint32
+}
+
+// taskParams is the TaskParams struct of createTask
+type taskParams struct {
+	RefundCollector     common.Address
+	AvsFee              *big.Int
+	ExecutorOperatorSet operatorSet
+	Payload             []byte
+}
+
+// Mailbox creates tasks for opportunities in a TaskMailbox, sending the transactions
+// from the account of the performer on the chain of the mailbox
+type Mailbox struct {
+	cfg          MailboxConfig
+	transactions *txmgr.Set
+}
+
+// NewMailbox creates tasks in the mailbox of cfg with transactions, nil when creating
+// tasks is disabled
+func NewMailbox(cfg MailboxConfig, transactions *txmgr.Set) (*Mailbox, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	if transactions == nil {
+		return nil, fmt.Errorf("transactions must be enabled to create tasks")
+	}
+	return &Mailbox{cfg: cfg, transactions: transactions}, nil
+}
+
+// CreateTask creates a cross_chain_yield_check task comparing the markets of the chains
+// of signal, which it carries as its opportunity parameter, and returns the hash of the
+// transaction creating it. Refunds go to the account sending it.
+func (m *Mailbox) CreateTask(ctx context.Context, signal *Signal) (common.Hash, error) {
+	payload, err := TaskPayload(signal, m.cfg.Amount)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	manager, err := m.transactions.For(m.cfg.ChainID)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	data, err := mailboxABI.Pack("createTask", taskParams{
+		RefundCollector:     manager.Address(),
+		AvsFee:              new(big.Int),
+		ExecutorOperatorSet: operatorSet{Avs: common.HexToAddress(m.cfg.AVS), Id: m.cfg.OperatorSetID},
+		Payload:             payload,
+	})
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to pack createTask: %w", err)
+	}
+	tx, err := manager.Send(ctx, txmgr.Request{Call: chain.Call{To: common.HexToAddress(m.cfg.TaskMailbox), Data: data}})
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to send createTask: %w", err)
+	}
+	return tx.Hash(), nil
+}
+
+// TaskPayload returns the payload of the task checking signal, comparing the move of
+// amount, in USDC base units, between its chains. Results are asked to be attested, so the opportunity
+// and the answers of every operator can be audited together.
+func TaskPayload(signal *Signal, amount string) ([]byte, error) {
+	o := signal.Opportunity
+	payload, err := client.Build(client.CrossChainYieldCheck{SourceChain: o.SourceChain, TargetChain: o.TargetChain, Amount: amount}, client.WithAttestation())
+	if err != nil {
+		return nil, err
+	}
+	payload.Parameters["opportunity"] = signal
+	return payload.Encode()
+}
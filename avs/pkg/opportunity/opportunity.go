@@ -0,0 +1,289 @@
+// Package opportunity pushes rebalance opportunities rather than waiting for tasks to
+// ask for them. A Watcher follows the supply rates the collector samples and, when the
+// lead of one market over another crosses a threshold, signs the opportunity with the
+// attestation key of the operator. Signed opportunities are posted as notifications and,
+// when configured, created as tasks in the TaskMailbox, so the operators of the AVS check
+// them as they check pulled tasks.
+//
+// A Signal carries the attestation of its opportunity: its result hash is the keccak256
+// hash of the canonical JSON of the opportunity, and its task ID the ID of the
+// opportunity.
+package opportunity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/attestation"
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+	"github.com/najnomics/crosscow-avs/pkg/collector"
+	"github.com/najnomics/crosscow-avs/pkg/notify"
+	"github.com/najnomics/crosscow-avs/pkg/tokens"
+	"go.uber.org/zap"
+)
+
+// Config configures pushing rebalance opportunities
+type Config struct {
+	Enabled bool `yaml:"enabled"`
+
+	// ThresholdBps is the lead over a source market, in basis points of annual rate, at
+	// which a target market makes an opportunity
+	ThresholdBps uint64 `yaml:"thresholdBps"`
+
+	// Mailbox creates a task in the TaskMailbox for every opportunity
+	Mailbox MailboxConfig `yaml:"mailbox"`
+}
+
+// MailboxConfig configures creating tasks for opportunities
+type MailboxConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	ChainID uint64 `yaml:"chainId"`
+
+	// TaskMailbox is the address of the Hourglass TaskMailbox tasks are created in
+	TaskMailbox string `yaml:"taskMailbox"`
+
+	// AVS is the address of the AVS whose operator set executes the tasks, and
+	// OperatorSetID the ID of that set
+	AVS           string `yaml:"avs"`
+	OperatorSetID uint32 `yaml:"operatorSetId"`
+
+	// Amount is the USDC amount the created cross_chain_yield_check tasks compare moving,
+	// a decimal string of base units
+	Amount string `yaml:"amount"`
+}
+
+// DefaultConfig is disabled. Enabled, a market leading another by 50 bps makes an
+// opportunity, which is only posted as a notification.
+func DefaultConfig() Config {
+	return Config{
+		ThresholdBps: 50,
+		Mailbox:      MailboxConfig{Amount: "10000000000"},
+	}
+}
+
+// Validate checks the config for values opportunities cannot be pushed with
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.ThresholdBps == 0 || c.ThresholdBps > 10_000 {
+		return fmt.Errorf("thresholdBps must be between 1 and 10000")
+	}
+	if err := c.Mailbox.Validate(); err != nil {
+		return fmt.Errorf("mailbox: %w", err)
+	}
+	return nil
+}
+
+// Validate checks the config for values tasks cannot be created with
+func (c MailboxConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.ChainID == 0 {
+		return fmt.Errorf("chainId is required")
+	}
+	if !common.IsHexAddress(c.TaskMailbox) {
+		return fmt.Errorf("invalid taskMailbox address %q", c.TaskMailbox)
+	}
+	if !common.IsHexAddress(c.AVS) {
+		return fmt.Errorf("invalid avs address %q", c.AVS)
+	}
+	if _, err := tokens.ParsePositiveAmount(c.Amount); err != nil {
+		return fmt.Errorf("amount: %w", err)
+	}
+	return nil
+}
+
+// Opportunity is a target market earning at least the threshold more than a source
+// market. Rates are annual percentages.
+type Opportunity struct {
+	ID              string            `json:"id"`
+	SourceProtocol  string            `json:"source_protocol"`
+	SourceChain     uint64            `json:"source_chain"`
+	SourceRate      canonical.Decimal `json:"source_rate"`
+	TargetProtocol  string            `json:"target_protocol"`
+	TargetChain     uint64            `json:"target_chain"`
+	TargetRate      canonical.Decimal `json:"target_rate"`
+	DifferentialBps canonical.Decimal `json:"differential_bps"`
+	ThresholdBps    uint64            `json:"threshold_bps"`
+
+	// DetectedAt is the unix time of the sample the lead crossed the threshold at
+	DetectedAt int64 `json:"detected_at"`
+}
+
+// Signal is an opportunity signed by the operator that detected it
+type Signal struct {
+	Opportunity Opportunity              `json:"opportunity"`
+	Attestation *attestation.Attestation `json:"attestation"`
+}
+
+// Verify checks that the attestation of the signal covers its opportunity and was signed
+// by its operator
+func (s *Signal) Verify() error {
+	if s.Attestation == nil || s.Attestation.TaskID != s.Opportunity.ID {
+		return fmt.Errorf("%w: does not attest opportunity %s", attestation.ErrInvalidAttestation, s.Opportunity.ID)
+	}
+	encoded, err := canonical.Marshal(&s.Opportunity)
+	if err != nil {
+		return fmt.Errorf("failed to encode opportunity: %w", err)
+	}
+	return s.Attestation.Verify(encoded)
+}
+
+// market is a protocol on a chain
+type market struct {
+	protocol string
+	chainID  uint64
+}
+
+// pair is a move of funds from a source to a target market
+type pair struct {
+	source, target market
+}
+
+// Watcher detects opportunities in the samples of the collector. The lead of every pair
+// of markets is followed across collections: an opportunity is made when it reaches the
+// threshold, the first time the pair is seen included, and the pair is only signaled
+// again once its lead fell back below the threshold.
+type Watcher struct {
+	cfg      Config
+	attester *attestation.Attester
+	notifier *notify.Notifier
+	mailbox  *Mailbox
+	logger   *zap.Logger
+
+	mu     sync.Mutex
+	latest map[market]collector.Sample
+	above  map[pair]bool
+}
+
+// New creates a watcher signing opportunities with attester and posting them to
+// notifier, and creating their tasks in mailbox when it is not nil
+func New(cfg Config, attester *attestation.Attester, notifier *notify.Notifier, mailbox *Mailbox, logger *zap.Logger) *Watcher {
+	return &Watcher{
+		cfg:      cfg,
+		attester: attester,
+		notifier: notifier,
+		mailbox:  mailbox,
+		logger:   logger,
+		latest:   make(map[market]collector.Sample),
+		above:    make(map[pair]bool),
+	}
+}
+
+// Observe implements collector.Observer, signing and pushing the opportunities the
+// samples make
+func (w *Watcher) Observe(ctx context.Context, samples []collector.Sample) {
+	for _, o := range w.Detect(samples) {
+		signal, err := w.Sign(ctx, o)
+		if err != nil {
+			w.logger.Sugar().Errorw("Failed to sign rebalance opportunity", "id", o.ID, "error", err)
+			continue
+		}
+		w.publish(ctx, signal)
+	}
+}
+
+// Detect records samples as the latest rates of their markets and returns the
+// opportunities whose lead crossed the threshold. A source market makes one opportunity
+// at most, to the target leading it most.
+func (w *Watcher) Detect(samples []collector.Sample) []Opportunity {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, s := range samples {
+		w.latest[market{protocol: s.Protocol, chainID: s.ChainID}] = s
+	}
+
+	markets := make([]market, 0, len(w.latest))
+	for m := range w.latest {
+		markets = append(markets, m)
+	}
+	sort.Slice(markets, func(i, j int) bool {
+		if markets[i].chainID != markets[j].chainID {
+			return markets[i].chainID < markets[j].chainID
+		}
+		return markets[i].protocol < markets[j].protocol
+	})
+
+	threshold := float64(w.cfg.ThresholdBps) / 10_000
+	var opportunities []Opportunity
+	for _, source := range markets {
+		var best *market
+		for i, target := range markets {
+			if target == source {
+				continue
+			}
+			p := pair{source: source, target: target}
+			crossed := w.latest[target].SupplyRate-w.latest[source].SupplyRate >= threshold
+			if crossed && !w.above[p] && (best == nil || w.latest[target].SupplyRate > w.latest[*best].SupplyRate) {
+				best = &markets[i]
+			}
+			w.above[p] = crossed
+		}
+		if best != nil {
+			opportunities = append(opportunities, w.opportunity(w.latest[source], w.latest[*best]))
+		}
+	}
+	return opportunities
+}
+
+func (w *Watcher) opportunity(source, target collector.Sample) Opportunity {
+	at := source.At
+	if target.At.After(at) {
+		at = target.At
+	}
+	return Opportunity{
+		ID:              fmt.Sprintf("opportunity:%s:%d:%s:%d:%d", source.Protocol, source.ChainID, target.Protocol, target.ChainID, at.Unix()),
+		SourceProtocol:  source.Protocol,
+		SourceChain:     source.ChainID,
+		SourceRate:      canonical.APY(source.SupplyRate * 100),
+		TargetProtocol:  target.Protocol,
+		TargetChain:     target.ChainID,
+		TargetRate:      canonical.APY(target.SupplyRate * 100),
+		DifferentialBps: canonical.NewDecimalFromFloat((target.SupplyRate-source.SupplyRate)*10_000, canonical.ScorePlaces),
+		ThresholdBps:    w.cfg.ThresholdBps,
+		DetectedAt:      at.Unix(),
+	}
+}
+
+// Sign attests o with the key of the operator
+func (w *Watcher) Sign(ctx context.Context, o Opportunity) (*Signal, error) {
+	if w.attester == nil {
+		return nil, errors.New("opportunities are signed with the attestation key, which is not configured")
+	}
+	encoded, err := canonical.Marshal(&o)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode opportunity: %w", err)
+	}
+	a, err := w.attester.Attest(ctx, o.ID, encoded)
+	if err != nil {
+		return nil, err
+	}
+	return &Signal{Opportunity: o, Attestation: a}, nil
+}
+
+// publish posts signal as a notification and creates its task in the mailbox
+func (w *Watcher) publish(ctx context.Context, signal *Signal) {
+	o := signal.Opportunity
+	w.logger.Sugar().Infow("Detected rebalance opportunity", "id", o.ID, "differentialBps", o.DifferentialBps.String())
+	w.notifier.Notify(notify.Event{
+		Type: notify.EventRebalanceOpportunity,
+		Summary: fmt.Sprintf("%s on chain %d earns %s bps more than %s on chain %d",
+			o.TargetProtocol, o.TargetChain, o.DifferentialBps, o.SourceProtocol, o.SourceChain),
+		Data: signal,
+	})
+	if w.mailbox == nil {
+		return
+	}
+	tx, err := w.mailbox.CreateTask(ctx, signal)
+	if err != nil {
+		w.logger.Sugar().Errorw("Failed to create task for rebalance opportunity", "id", o.ID, "error", err)
+		return
+	}
+	w.logger.Sugar().Infow("Created task for rebalance opportunity", "id", o.ID, "tx", tx.Hex())
+}
@@ -0,0 +1,177 @@
+package opportunity
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/najnomics/crosscow-avs/pkg/attestation"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/chain/chaintest"
+	"github.com/najnomics/crosscow-avs/pkg/collector"
+	"github.com/najnomics/crosscow-avs/pkg/signer"
+	"github.com/najnomics/crosscow-avs/pkg/txmgr"
+	"go.uber.org/zap"
+)
+
+var mailboxAddress = common.HexToAddress("0x00000000000000000000000000000000000ba11")
+
+func newTestWatcher(t *testing.T, mailbox *Mailbox) *Watcher {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	cfg := DefaultConfig()
+	cfg.Enabled = true
+	return New(cfg, attestation.New(signer.NewKeySigner(key), "v1.2.3", nil), nil, mailbox, zap.NewNop())
+}
+
+func samples(at time.Time, rates map[string]float64) []collector.Sample {
+	var out []collector.Sample
+	for protocol, rate := range rates {
+		out = append(out, collector.Sample{Protocol: protocol, ChainID: 1, SupplyRate: rate, At: at})
+	}
+	return out
+}
+
+func Test_DetectCrossings(t *testing.T) {
+	w := newTestWatcher(t, nil)
+	at := time.Unix(1_700_000_000, 0)
+
+	if found := w.Detect(samples(at, map[string]float64{"aave_v3": 0.040, "compound_v3": 0.043})); len(found) != 0 {
+		t.Fatalf("Expected no opportunity below the threshold, got %+v", found)
+	}
+
+	// morpho leads both by at least 50 bps once it is sampled on Base
+	found := w.Detect([]collector.Sample{{Protocol: "morpho", ChainID: 8453, SupplyRate: 0.050, At: at.Add(time.Minute)}})
+	if len(found) != 2 {
+		t.Fatalf("Expected an opportunity from each market on Ethereum, got %+v", found)
+	}
+	o := found[0]
+	if o.SourceProtocol != "aave_v3" || o.TargetProtocol != "morpho" || o.TargetChain != 8453 || o.DifferentialBps.String() != "100.00" ||
+		o.SourceRate.String() != "4.0000" || o.DetectedAt != at.Add(time.Minute).Unix() || o.ID != "opportunity:aave_v3:1:morpho:8453:1700000060" {
+		t.Errorf("Unexpected opportunity %+v", o)
+	}
+
+	// signaled leads are not repeated until they fall back below the threshold
+	if found := w.Detect(samples(at.Add(2*time.Minute), map[string]float64{"aave_v3": 0.041})); len(found) != 0 {
+		t.Errorf("Expected a lead already signaled not to be repeated, got %+v", found)
+	}
+	w.Detect(samples(at.Add(3*time.Minute), map[string]float64{"aave_v3": 0.049}))
+	if found := w.Detect(samples(at.Add(4*time.Minute), map[string]float64{"aave_v3": 0.040})); len(found) != 1 || found[0].SourceProtocol != "aave_v3" {
+		t.Errorf("Expected the lead to be signaled again once it crossed anew, got %+v", found)
+	}
+}
+
+func Test_SignedOpportunity(t *testing.T) {
+	w := newTestWatcher(t, nil)
+	found := w.Detect(samples(time.Unix(1_700_000_000, 0), map[string]float64{"aave_v3": 0.04, "compound_v3": 0.05}))
+	if len(found) != 1 {
+		t.Fatalf("Expected one opportunity, got %+v", found)
+	}
+	signal, err := w.Sign(context.Background(), found[0])
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	if err := signal.Verify(); err != nil {
+		t.Fatalf("Expected the signal to verify: %v", err)
+	}
+
+	encoded, err := json.Marshal(signal)
+	if err != nil {
+		t.Fatalf("Failed to encode signal: %v", err)
+	}
+	var decoded Signal
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatalf("Failed to decode signal: %v", err)
+	}
+	if err := decoded.Verify(); err != nil {
+		t.Errorf("Expected the decoded signal to verify: %v", err)
+	}
+	decoded.Opportunity.ThresholdBps = 1
+	if err := decoded.Verify(); !errors.Is(err, attestation.ErrInvalidAttestation) {
+		t.Errorf("Expected a tampered opportunity to be rejected, got %v", err)
+	}
+}
+
+func Test_MailboxCreatesTask(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	account := signer.NewKeySigner(key)
+	ethereum := chaintest.NewContracts(1)
+	ethereum.StubGas(t, mailboxAddress, mailboxABI, "createTask", 300_000)
+	chains := chain.NewManager()
+	chains.Register(1, "ethereum", ethereum)
+
+	cfg := MailboxConfig{Enabled: true, ChainID: 1, TaskMailbox: mailboxAddress.Hex(), AVS: "0x00000000000000000000000000000000000000a5", OperatorSetID: 1, Amount: "5000000000"}
+	mailbox, err := NewMailbox(cfg, txmgr.NewSet(chains, account, txmgr.DefaultConfig()))
+	if err != nil {
+		t.Fatalf("NewMailbox failed: %v", err)
+	}
+	w := newTestWatcher(t, mailbox)
+	w.Observe(context.Background(), samples(time.Unix(1_700_000_000, 0), map[string]float64{"aave_v3": 0.04, "compound_v3": 0.05}))
+
+	sent := ethereum.Sent()
+	if len(sent) != 1 || *sent[0].To() != mailboxAddress {
+		t.Fatalf("Expected one createTask transaction to the mailbox, got %d", len(sent))
+	}
+	args, err := mailboxABI.Methods["createTask"].Inputs.Unpack(sent[0].Data()[4:])
+	if err != nil {
+		t.Fatalf("Failed to unpack createTask: %v", err)
+	}
+	params := args[0].(struct {
+		RefundCollector     common.Address `json:"refundCollector"`
+		AvsFee              *big.Int       `json:"avsFee"`
+		ExecutorOperatorSet struct {
+			Avs common.Address `json:"avs"`
+			Id  uint32         `json:"id"`
+		} `json:"executorOperatorSet"`
+		Payload []byte `json:"payload"`
+	})
+	if params.RefundCollector != account.Address() || params.ExecutorOperatorSet.Id != 1 || params.ExecutorOperatorSet.Avs != common.HexToAddress(cfg.AVS) {
+		t.Errorf("Unexpected task params %+v", params)
+	}
+
+	var payload struct {
+		Type       string `json:"type"`
+		Parameters struct {
+			SourceChain uint64 `json:"source_chain"`
+			Amount      string `json:"amount"`
+			Attest      bool   `json:"attest"`
+			Opportunity Signal `json:"opportunity"`
+		} `json:"parameters"`
+	}
+	if err := json.Unmarshal(params.Payload, &payload); err != nil {
+		t.Fatalf("Failed to decode task payload: %v", err)
+	}
+	if payload.Type != "cross_chain_yield_check" || payload.Parameters.Amount != "5000000000" || !payload.Parameters.Attest || payload.Parameters.Opportunity.Opportunity.TargetProtocol != "compound_v3" {
+		t.Errorf("Unexpected task payload %s", params.Payload)
+	}
+	if err := payload.Parameters.Opportunity.Verify(); err != nil {
+		t.Errorf("Expected the task to carry a valid signal: %v", err)
+	}
+
+	if _, err := NewMailbox(cfg, nil); err == nil {
+		t.Errorf("Expected a mailbox without transactions to be rejected")
+	}
+}
+
+func Test_Validate(t *testing.T) {
+	for name, cfg := range map[string]Config{
+		"threshold": {Enabled: true},
+		"mailbox":   {Enabled: true, ThresholdBps: 50, Mailbox: MailboxConfig{Enabled: true, ChainID: 1, TaskMailbox: mailboxAddress.Hex(), AVS: "avs", Amount: "1000000"}},
+		"amount":    {Enabled: true, ThresholdBps: 50, Mailbox: MailboxConfig{Enabled: true, ChainID: 1, TaskMailbox: mailboxAddress.Hex(), AVS: mailboxAddress.Hex()}},
+	} {
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("%s: expected the config to be rejected", name)
+		}
+	}
+}
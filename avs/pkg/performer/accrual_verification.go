@@ -0,0 +1,168 @@
+package performer
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+	"github.com/najnomics/crosscow-avs/pkg/ledger"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+	"github.com/najnomics/crosscow-avs/pkg/txmgr"
+	"github.com/najnomics/crosscow-avs/pkg/workerpool"
+)
+
+const (
+	defaultAccrualDivergenceBps = 50
+	defaultAccrualPeriodHours   = 24
+)
+
+// AccrualCheck compares the interest a position accrued since a rebalance deposited into
+// its market with the supply rate the market advertised then. Rates are annual
+// percentages, balances are in USDC. AverageRate is the mean of the rates recorded since,
+// omitted without history, and tells rates that moved from a market that does not pay
+// what it advertises. DivergenceBps is the realized rate less the advertised one.
+type AccrualCheck struct {
+	ExecutionID    string             `json:"execution_id"`
+	UserAddress    string             `json:"user_address"`
+	Protocol       string             `json:"protocol"`
+	ChainID        uint64             `json:"chain_id"`
+	DepositedAt    uint64             `json:"deposited_at"`
+	PeriodHours    canonical.Decimal  `json:"period_hours"`
+	StartBalance   canonical.Decimal  `json:"start_balance"`
+	CurrentBalance canonical.Decimal  `json:"current_balance"`
+	AdvertisedRate canonical.Decimal  `json:"advertised_rate"`
+	RealizedRate   canonical.Decimal  `json:"realized_rate"`
+	AverageRate    *canonical.Decimal `json:"average_rate,omitempty"`
+	DivergenceBps  int64              `json:"divergence_bps"`
+	Diverged       bool               `json:"diverged"`
+}
+
+// AccrualVerificationResult is the result of an accrual_verification task. Positions
+// deposited less than min_period_hours ago are counted as TooRecent and not checked.
+type AccrualVerificationResult struct {
+	MaxDivergenceBps uint64          `json:"max_divergence_bps"`
+	Checks           []AccrualCheck  `json:"checks"`
+	Diverged         int             `json:"diverged"`
+	TooRecent        int             `json:"too_recent"`
+	Failures         []MarketFailure `json:"failures"`
+	Status           ResultStatus    `json:"status"`
+}
+
+// handleAccrualVerification checks that the positions rebalances left accrued the yield
+// their markets advertised at deposit time. Each position the ledger holds is read again,
+// of user_address alone when set, and its balance growth annualized; markets realizing a
+// rate further than max_divergence_bps from the advertised one are flagged.
+func (yip *YieldIntelligencePerformer) handleAccrualVerification(ctx context.Context, t *performerV1.TaskRequest, payload *TaskPayload) (interface{}, error) {
+	yip.log(ctx).Sugar().Infow("Processing accrual verification task")
+
+	maxDivergence := uint64(defaultAccrualDivergenceBps)
+	if _, present := payload.Parameters["max_divergence_bps"]; present {
+		maxDivergence = paramUint64(payload, "max_divergence_bps")
+	}
+	minPeriod := time.Duration(defaultAccrualPeriodHours) * time.Hour
+	if hours := paramUint64(payload, "min_period_hours"); hours > 0 {
+		minPeriod = time.Duration(hours) * time.Hour
+	}
+	entries, err := yip.ledger.Entries(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the ledger: %w", err)
+	}
+	var owner *common.Address
+	if user := paramString(payload, "user_address"); user != "" {
+		address := common.HexToAddress(user)
+		owner = &address
+	}
+
+	result := &AccrualVerificationResult{
+		MaxDivergenceBps: maxDivergence,
+		Checks:           []AccrualCheck{},
+		Failures:         []MarketFailure{},
+		Status:           ResultStatusCompleted,
+	}
+	now := time.Now()
+	var held []ledger.Entry
+	for _, entry := range ledger.Holding(entries) {
+		if entry.Position == nil || entry.Position.Sign() <= 0 || (owner != nil && entry.Owner != *owner) {
+			continue
+		}
+		if now.Sub(entry.ExecutedAt) < minPeriod {
+			result.TooRecent++
+			continue
+		}
+		held = append(held, entry)
+	}
+
+	balances := workerpool.Map(ctx, yip.pool, held, func(ctx context.Context, entry ledger.Entry) (*big.Int, error) {
+		mover, err := yip.adapters.MoverFor(entry.TargetProtocol)
+		if err != nil {
+			return nil, err
+		}
+		return mover.PositionOf(ctx, entry.TargetChain, entry.Owner)
+	})
+	for i, entry := range held {
+		if balances[i].Err != nil {
+			result.Failures = append(result.Failures, marketFailure(market{protocol: entry.TargetProtocol, chainID: entry.TargetChain}, balances[i].Err))
+			continue
+		}
+		check := yip.checkAccrual(ctx, entry, balances[i].Value, now)
+		check.Diverged = uint64(math.Abs(float64(check.DivergenceBps))) > maxDivergence
+		if check.Diverged {
+			result.Diverged++
+			yip.log(ctx).Sugar().Warnw("Realized yield diverges from the advertised rate", "protocol", entry.TargetProtocol, "chainId", entry.TargetChain,
+				"advertised", check.AdvertisedRate.String(), "realized", check.RealizedRate.String())
+		}
+		result.Checks = append(result.Checks, check)
+	}
+	if len(result.Failures) > 0 {
+		result.Status = ResultStatusPartial
+	}
+	return result, nil
+}
+
+// checkAccrual annualizes the growth of the position of entry to balance at now, as
+// simple interest like supply rates are quoted
+func (yip *YieldIntelligencePerformer) checkAccrual(ctx context.Context, entry ledger.Entry, balance *big.Int, now time.Time) AccrualCheck {
+	period := now.Sub(entry.ExecutedAt)
+	growth, _ := new(big.Rat).SetFrac(new(big.Int).Sub(balance, entry.Position), entry.Position).Float64()
+	realized := growth * (365 * 24 * time.Hour).Seconds() / period.Seconds()
+
+	check := AccrualCheck{
+		ExecutionID:    entry.ExecutionID,
+		UserAddress:    entry.Owner.Hex(),
+		Protocol:       entry.TargetProtocol,
+		ChainID:        entry.TargetChain,
+		DepositedAt:    uint64(entry.ExecutedAt.Unix()),
+		PeriodHours:    canonical.Score(period.Hours()),
+		StartBalance:   usdcAmount(entry.Position),
+		CurrentBalance: usdcAmount(balance),
+		AdvertisedRate: ratePercent(entry.RateAfter),
+		RealizedRate:   ratePercent(realized),
+		DivergenceBps:  int64(math.Round((realized - entry.RateAfter) * txmgr.MaxBps)),
+	}
+
+	points, err := yip.history.Range(ctx, store.SupplyRateSeries(entry.TargetProtocol, entry.TargetChain), entry.ExecutedAt, now)
+	if err != nil {
+		yip.log(ctx).Sugar().Warnw("Failed to load rate history", "protocol", entry.TargetProtocol, "chainId", entry.TargetChain, "error", err)
+	}
+	if len(points) > 0 {
+		sum := 0.0
+		for _, p := range points {
+			sum += p.Value
+		}
+		average := ratePercent(sum / float64(len(points)))
+		check.AverageRate = &average
+	}
+	return check
+}
+
+func (yip *YieldIntelligencePerformer) validateAccrualVerificationTask(payload *TaskPayload) error {
+	if yip.ledger == nil {
+		return fmt.Errorf("accrual verification needs the ledger, which is not configured on this performer")
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package performer
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/ledger"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+	"go.uber.org/zap"
+)
+
+// chainPositionsAdapter is a movableAdapter holding a fixed position on each chain
+type chainPositionsAdapter struct {
+	*movableAdapter
+	held map[uint64]*big.Int
+}
+
+func (a *chainPositionsAdapter) PositionOf(ctx context.Context, chainID uint64, account common.Address) (*big.Int, error) {
+	return a.held[chainID], nil
+}
+
+func Test_AccrualVerification(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+	aave := &chainPositionsAdapter{movableAdapter: newMovableAave(), held: map[uint64]*big.Int{
+		1:                    usdcUnits(1010),
+		adapters.ChainIDBase: usdcUnits(1004),
+	}}
+	performer := NewYieldIntelligencePerformer(logger, WithAdapters(adapters.NewRegistry(aave)))
+
+	var result AccrualVerificationResult
+	if err := runYieldMonitoring(t, performer, "accrual-0", `{"type":"accrual_verification","parameters":{}}`, &result); err == nil {
+		t.Errorf("Expected accrual verification without a ledger to be rejected")
+	}
+
+	l := ledger.New(ledger.Config{Enabled: true}, store.NewMemoryKV(), nil)
+	WithLedger(l)(performer)
+	owner, other := common.HexToAddress("0xaa"), common.HexToAddress("0xbb")
+	deposited := time.Now().Add(-73 * 24 * time.Hour)
+	for _, entry := range []ledger.Entry{
+		// 1000 USDC earning 1% over 73 days realizes the advertised 5%
+		{ExecutionID: "ethereum", Owner: owner, TargetProtocol: "aave_v3", TargetChain: 1, RateAfter: 0.05, ExecutedAt: deposited},
+		// base advertised 8% and paid 2%
+		{ExecutionID: "base", Owner: owner, TargetProtocol: "aave_v3", TargetChain: adapters.ChainIDBase, RateAfter: 0.08, ExecutedAt: deposited.Add(time.Minute)},
+		{ExecutionID: "recent", Owner: other, TargetProtocol: "aave_v3", TargetChain: 1, RateAfter: 0.05, ExecutedAt: time.Now().Add(-time.Hour)},
+	} {
+		entry.Amount, entry.Position, entry.BridgeFee = usdcUnits(1000), usdcUnits(1000), new(big.Int)
+		if err := l.Record(context.Background(), entry); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+
+	if err := runYieldMonitoring(t, performer, "accrual-1", `{"type":"accrual_verification","parameters":{"max_divergence_bps":100}}`, &result); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	if len(result.Checks) != 2 || result.TooRecent != 1 || result.Diverged != 1 || result.Status != ResultStatusCompleted {
+		t.Fatalf("Expected two checked positions and one too recent, got %+v", result)
+	}
+	ethereum, base := result.Checks[0], result.Checks[1]
+	if ethereum.ChainID != 1 || ethereum.Diverged || ethereum.DivergenceBps != 0 || ethereum.AdvertisedRate.String() != "5.0000" {
+		t.Errorf("Expected ethereum to realize its advertised rate, got %+v", ethereum)
+	}
+	if base.ChainID != adapters.ChainIDBase || !base.Diverged || base.DivergenceBps != -600 || base.RealizedRate.String() != "2.0000" {
+		t.Errorf("Expected base to fall 6%% short of its advertised rate, got %+v", base)
+	}
+
+	if err := runYieldMonitoring(t, performer, "accrual-2", `{"type":"accrual_verification","parameters":{"user_address":"`+other.Hex()+`","min_period_hours":1}}`, &result); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	if len(result.Checks) != 1 || result.Checks[0].ExecutionID != "recent" || result.MaxDivergenceBps != defaultAccrualDivergenceBps {
+		t.Errorf("Expected the position of the other account alone, got %+v", result)
+	}
+
+	for name, params := range map[string]string{
+		"divergence":  `{"max_divergence_bps":20000}`,
+		"period":      `{"min_period_hours":0}`,
+		"long period": `{"min_period_hours":1e15}`,
+		"address":     `{"user_address":"abc"}`,
+	} {
+		if err := runYieldMonitoring(t, performer, name, `{"type":"accrual_verification","parameters":`+params+`}`, &result); err == nil {
+			t.Errorf("Expected an invalid %s to be rejected", name)
+		}
+	}
+}
@@ -0,0 +1,456 @@
+package performer
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/admin"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/evidence"
+	"github.com/najnomics/crosscow-avs/pkg/export"
+	"github.com/najnomics/crosscow-avs/pkg/features"
+	"github.com/najnomics/crosscow-avs/pkg/killswitch"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+	"github.com/najnomics/crosscow-avs/pkg/workerpool"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultRecentTasks is how many recent tasks are listed without a limit
+	defaultRecentTasks = 20
+
+	// maxRecentTasks bounds the limit of recent tasks
+	maxRecentTasks = 500
+)
+
+// adminAPI serves the operator endpoints of a performer
+type adminAPI struct {
+	performer *YieldIntelligencePerformer
+
+	// chains are the chains adapter health is probed on, every chain of an adapter when
+	// none are configured, and whose endpoints are reported
+	chains       *chain.Manager
+	probeTimeout time.Duration
+}
+
+// RegisterAdminRoutes serves the operator endpoints of performer on server:
+//
+//	GET       /tasks/inflight                 tasks being handled
+//	GET       /tasks/recent?limit=N           most recently updated tasks and their results
+//	GET       /adapters                       registered adapters and whether they are enabled
+//	GET       /adapters/{protocol}/health     reads the markets of an adapter and reports failures
+//	POST      /adapters/{protocol}/enable     lets tasks read the protocol again
+//	POST      /adapters/{protocol}/disable    fails tasks reading the protocol
+//	GET       /chains/endpoints               what the RPC endpoints of every chain serve, as last probed
+//	POST      /chains/endpoints/probe         probes the RPC endpoints again and reports what they serve
+//	GET       /crossval/sources               how reliably each rate source agreed with the quorum
+//	GET       /reputation?window_hours=N      success rate, latencies and quorum agreement of answered tasks
+//	GET       /evidence                       artifacts against attestations disagreeing with the chain
+//	GET       /evidence/{attestationId}       the artifact against an attestation
+//	GET       /export/{dataset}               yield_history, risk_scores or executions as ?format=csv or parquet,
+//	                                          filtered by from, to, protocol and chain_id
+//	GET       /features                       experimental task types and adapters, and whether they are enabled
+//	POST      /features/{flag}/enable         enables an experimental feature, such as task_type.stress_scenario
+//	POST      /features/{flag}/disable        disables an experimental feature
+//	GET       /schemas                        task types payloads are validated against a JSON Schema of
+//	GET       /schemas/{taskType}             the JSON Schema payloads of a task type must satisfy
+//	GET       /halt                           whether rebalances are halted, and why
+//	POST      /halt                           halts rebalances, as {"reason":"usdc depeg"}
+//	DELETE    /halt                           lifts the halt set through the API
+//	GET, PUT  /log/level                      reads or sets the log level, as {"level":"debug"}
+func RegisterAdminRoutes(server *admin.Server, performer *YieldIntelligencePerformer, level zap.AtomicLevel, chains *chain.Manager, probeTimeout time.Duration) {
+	api := &adminAPI{performer: performer, chains: chains, probeTimeout: probeTimeout}
+	server.Handle("GET /tasks/inflight", http.HandlerFunc(api.inflightTasks))
+	server.Handle("GET /tasks/recent", http.HandlerFunc(api.recentTasks))
+	server.Handle("GET /adapters", http.HandlerFunc(api.listAdapters))
+	server.Handle("GET /adapters/{protocol}/health", http.HandlerFunc(api.adapterHealth))
+	server.Handle("POST /adapters/{protocol}/enable", api.setAdapterEnabled(true))
+	server.Handle("POST /adapters/{protocol}/disable", api.setAdapterEnabled(false))
+	server.Handle("GET /chains/endpoints", http.HandlerFunc(api.endpoints))
+	server.Handle("POST /chains/endpoints/probe", http.HandlerFunc(api.probeEndpoints))
+	server.Handle("GET /crossval/sources", http.HandlerFunc(api.rateSources))
+	server.Handle("GET /reputation", http.HandlerFunc(api.reputation))
+	server.Handle("GET /evidence", http.HandlerFunc(api.listEvidence))
+	server.Handle("GET /evidence/{attestationId}", http.HandlerFunc(api.evidence))
+	server.Handle("GET /export/{dataset}", http.HandlerFunc(api.exportDataset))
+	server.Handle("GET /features", http.HandlerFunc(api.listFeatures))
+	server.Handle("POST /features/{flag}/enable", api.setFeatureEnabled(true))
+	server.Handle("POST /features/{flag}/disable", api.setFeatureEnabled(false))
+	server.Handle("GET /schemas", http.HandlerFunc(api.listSchemas))
+	server.Handle("GET /schemas/{taskType}", http.HandlerFunc(api.schema))
+	server.Handle("GET /halt", http.HandlerFunc(api.haltStatus))
+	server.Handle("POST /halt", http.HandlerFunc(api.halt))
+	server.Handle("DELETE /halt", http.HandlerFunc(api.resume))
+	server.Handle("/log/level", level)
+}
+
+func (a *adminAPI) inflightTasks(w http.ResponseWriter, r *http.Request) {
+	admin.WriteJSON(w, http.StatusOK, map[string]interface{}{"tasks": a.performer.InflightTasks()})
+}
+
+// taskSummary is a task record as listed by the admin API. Payloads are left out, since
+// their parameters may hold secrets.
+type taskSummary struct {
+	TaskID      string           `json:"taskId"`
+	TaskType    string           `json:"taskType"`
+	Status      store.TaskStatus `json:"status"`
+	Error       string           `json:"error,omitempty"`
+	Result      json.RawMessage  `json:"result,omitempty"`
+	ReceivedAt  time.Time        `json:"receivedAt"`
+	UpdatedAt   time.Time        `json:"updatedAt"`
+	CompletedAt *time.Time       `json:"completedAt,omitempty"`
+}
+
+func (a *adminAPI) recentTasks(w http.ResponseWriter, r *http.Request) {
+	limit := defaultRecentTasks
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxRecentTasks {
+			admin.WriteError(w, http.StatusBadRequest, fmt.Errorf("limit must be between 1 and %d", maxRecentTasks))
+			return
+		}
+		limit = parsed
+	}
+
+	records, err := a.performer.tasks.RecentTasks(r.Context(), limit)
+	if err != nil {
+		admin.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	tasks := make([]taskSummary, 0, len(records))
+	for _, record := range records {
+		tasks = append(tasks, taskSummary{
+			TaskID:      record.TaskID,
+			TaskType:    record.TaskType,
+			Status:      record.Status,
+			Error:       record.Error,
+			Result:      resultJSON(record.Result),
+			ReceivedAt:  record.ReceivedAt,
+			UpdatedAt:   record.UpdatedAt,
+			CompletedAt: record.CompletedAt,
+		})
+	}
+	admin.WriteJSON(w, http.StatusOK, map[string]interface{}{"tasks": tasks})
+}
+
+// resultJSON returns a task result as it is when it is JSON, and as a hex string when it
+// is ABI encoded
+func resultJSON(result []byte) json.RawMessage {
+	if len(result) == 0 {
+		return nil
+	}
+	if json.Valid(result) {
+		return result
+	}
+	encoded, _ := json.Marshal("0x" + hex.EncodeToString(result))
+	return encoded
+}
+
+// adapterStatus describes a registered adapter
+type adapterStatus struct {
+	Protocol string   `json:"protocol"`
+	Enabled  bool     `json:"enabled"`
+	ChainIDs []uint64 `json:"chainIds"`
+}
+
+func (a *adminAPI) listAdapters(w http.ResponseWriter, r *http.Request) {
+	var statuses []adapterStatus
+	for _, protocol := range a.performer.adapters.Registered() {
+		adapter, enabled, err := a.performer.adapters.Lookup(protocol)
+		if err != nil {
+			continue
+		}
+		statuses = append(statuses, adapterStatus{Protocol: protocol, Enabled: enabled, ChainIDs: a.probedChains(adapter)})
+	}
+	admin.WriteJSON(w, http.StatusOK, map[string]interface{}{"adapters": statuses})
+}
+
+// marketProbe is the outcome of reading one market of an adapter
+type marketProbe struct {
+	ChainID   uint64 `json:"chainId"`
+	Healthy   bool   `json:"healthy"`
+	Error     string `json:"error,omitempty"`
+	LatencyMs int64  `json:"latencyMs"`
+}
+
+// adapterHealth reads every market of an adapter from the chain, bypassing the indexer,
+// whether the adapter is enabled or not, so it can be checked before it is enabled again
+func (a *adminAPI) adapterHealth(w http.ResponseWriter, r *http.Request) {
+	protocol := r.PathValue("protocol")
+	adapter, enabled, err := a.performer.adapters.Lookup(protocol)
+	if err != nil {
+		admin.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), a.probeTimeout)
+	defer cancel()
+	chainIDs := a.probedChains(adapter)
+	results := workerpool.Map(ctx, a.performer.pool, chainIDs, func(ctx context.Context, chainID uint64) (marketProbe, error) {
+		started := time.Now()
+		_, err := adapter.MarketState(ctx, chainID)
+		probe := marketProbe{ChainID: chainID, Healthy: err == nil, LatencyMs: time.Since(started).Milliseconds()}
+		if err != nil {
+			probe.Error = err.Error()
+		}
+		return probe, nil
+	})
+
+	healthy := true
+	probes := make([]marketProbe, 0, len(results))
+	for i, result := range results {
+		probe := result.Value
+		if result.Err != nil {
+			probe = marketProbe{ChainID: chainIDs[i], Error: result.Err.Error()}
+		}
+		healthy = healthy && probe.Healthy
+		probes = append(probes, probe)
+	}
+	admin.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"protocol": adapter.Protocol(),
+		"enabled":  enabled,
+		"healthy":  healthy,
+		"markets":  probes,
+	})
+}
+
+// probedChains returns the chains of adapter the performer is configured for
+func (a *adminAPI) probedChains(adapter adapters.YieldAdapter) []uint64 {
+	ids := adapter.ChainIDs()
+	var chainIDs []uint64
+	if a.chains != nil {
+		chainIDs = a.chains.ChainIDs()
+	}
+	if len(chainIDs) == 0 {
+		return ids
+	}
+	configured := make(map[uint64]bool, len(chainIDs))
+	for _, id := range chainIDs {
+		configured[id] = true
+	}
+	out := make([]uint64, 0, len(ids))
+	for _, id := range ids {
+		if configured[id] {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+func (a *adminAPI) setAdapterEnabled(enabled bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		protocol := r.PathValue("protocol")
+		if err := a.performer.adapters.SetEnabled(protocol, enabled); err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, adapters.ErrUnsupportedProtocol) {
+				status = http.StatusNotFound
+			}
+			admin.WriteError(w, status, err)
+			return
+		}
+		a.performer.logger.Sugar().Warnw("Adapter switched through the admin API", "protocol", protocol, "enabled", enabled)
+		admin.WriteJSON(w, http.StatusOK, adapterStatus{Protocol: protocol, Enabled: enabled})
+	})
+}
+
+func (a *adminAPI) endpoints(w http.ResponseWriter, r *http.Request) {
+	endpoints := []chain.EndpointCapabilities{}
+	if a.chains != nil {
+		endpoints = a.chains.Capabilities()
+	}
+	admin.WriteJSON(w, http.StatusOK, map[string]interface{}{"endpoints": endpoints})
+}
+
+func (a *adminAPI) rateSources(w http.ResponseWriter, r *http.Request) {
+	admin.WriteJSON(w, http.StatusOK, map[string]interface{}{"sources": a.performer.sourceStats.Stats()})
+}
+
+// reputation reports on the tasks answered over the last window_hours, a week by default
+func (a *adminAPI) reputation(w http.ResponseWriter, r *http.Request) {
+	hours := defaultSelfReportWindowHours
+	if raw := r.URL.Query().Get("window_hours"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxSelfReportWindowHours {
+			admin.WriteError(w, http.StatusBadRequest, fmt.Errorf("window_hours must be between 1 and %d", maxSelfReportWindowHours))
+			return
+		}
+		hours = parsed
+	}
+	report, err := a.performer.selfReport(r.Context(), time.Duration(hours)*time.Hour)
+	if err != nil {
+		admin.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	admin.WriteJSON(w, http.StatusOK, report)
+}
+
+// listEvidence lists the evidence artifacts kept, none when attestations are not checked
+func (a *adminAPI) listEvidence(w http.ResponseWriter, r *http.Request) {
+	artifacts := []*evidence.Artifact{}
+	if a.performer.evidence != nil {
+		var err error
+		if artifacts, err = a.performer.evidence.Artifacts(r.Context()); err != nil {
+			admin.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	admin.WriteJSON(w, http.StatusOK, map[string]interface{}{"artifacts": artifacts})
+}
+
+func (a *adminAPI) evidence(w http.ResponseWriter, r *http.Request) {
+	raw := r.PathValue("attestationId")
+	id, err := hexutil.Decode(raw)
+	if err != nil || len(id) != common.HashLength {
+		admin.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid attestation id %q", raw))
+		return
+	}
+	if a.performer.evidence == nil {
+		admin.WriteError(w, http.StatusNotFound, errors.New("attestations are not checked"))
+		return
+	}
+	artifact, err := a.performer.evidence.Artifact(r.Context(), common.BytesToHash(id))
+	if errors.Is(err, store.ErrNotFound) {
+		admin.WriteError(w, http.StatusNotFound, fmt.Errorf("no evidence against attestation %s", raw))
+		return
+	}
+	if err != nil {
+		admin.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	admin.WriteJSON(w, http.StatusOK, artifact)
+}
+
+func (a *adminAPI) listFeatures(w http.ResponseWriter, r *http.Request) {
+	admin.WriteJSON(w, http.StatusOK, map[string]interface{}{"features": a.performer.features.States()})
+}
+
+func (a *adminAPI) setFeatureEnabled(enabled bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flag := r.PathValue("flag")
+		if err := a.performer.features.SetEnabled(flag, enabled); err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, features.ErrUnknownFeature) {
+				status = http.StatusNotFound
+			}
+			admin.WriteError(w, status, err)
+			return
+		}
+		a.performer.logger.Sugar().Warnw("Feature switched through the admin API", "flag", flag, "enabled", enabled)
+		admin.WriteJSON(w, http.StatusOK, map[string]interface{}{"name": flag, "enabled": enabled})
+	})
+}
+
+func (a *adminAPI) listSchemas(w http.ResponseWriter, r *http.Request) {
+	admin.WriteJSON(w, http.StatusOK, map[string]interface{}{"taskTypes": a.performer.schemas.TaskTypes()})
+}
+
+// schema answers with the schema of a task type as it is, so submitters can validate
+// payloads with any JSON Schema validator
+func (a *adminAPI) schema(w http.ResponseWriter, r *http.Request) {
+	taskType := r.PathValue("taskType")
+	document, ok := a.performer.schemas.Schema(taskType)
+	if !ok {
+		admin.WriteError(w, http.StatusNotFound, fmt.Errorf("no payload schema for task type %s", taskType))
+		return
+	}
+	w.Header().Set("Content-Type", "application/schema+json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(document)
+}
+
+// exportDataset answers with the rows of a dataset as a file. The file is written before
+// it is sent, so failures are answered with an error rather than a truncated file.
+func (a *adminAPI) exportDataset(w http.ResponseWriter, r *http.Request) {
+	dataset := r.PathValue("dataset")
+	if !slices.Contains(export.Datasets, dataset) {
+		admin.WriteError(w, http.StatusNotFound, fmt.Errorf("unknown dataset %s", dataset))
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = export.FormatCSV
+	}
+	if err := export.CheckFormat(format); err != nil {
+		admin.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	filter, err := export.ParseFilter(r.URL.Query())
+	if err != nil {
+		admin.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	var file bytes.Buffer
+	if err := export.New(a.performer.history, a.performer.ledger).Export(r.Context(), &file, dataset, format, filter); err != nil {
+		admin.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", export.ContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", dataset+"."+format))
+	_, _ = file.WriteTo(w)
+}
+
+// probeEndpoints probes every endpoint again, such as after an archive node caught up,
+// and routes reads by the outcome
+func (a *adminAPI) probeEndpoints(w http.ResponseWriter, r *http.Request) {
+	endpoints := []chain.EndpointCapabilities{}
+	if a.chains != nil {
+		ctx, cancel := context.WithTimeout(r.Context(), a.probeTimeout)
+		defer cancel()
+		endpoints = a.chains.Probe(ctx)
+	}
+	admin.WriteJSON(w, http.StatusOK, map[string]interface{}{"endpoints": endpoints})
+}
+
+// haltStatus tells whether rebalances are halted
+type haltStatus struct {
+	Halted bool             `json:"halted"`
+	Halt   *killswitch.Halt `json:"halt,omitempty"`
+}
+
+func (a *adminAPI) haltStatus(w http.ResponseWriter, r *http.Request) {
+	halt, err := a.performer.killswitch.Status(r.Context())
+	if err != nil {
+		admin.WriteError(w, http.StatusBadGateway, err)
+		return
+	}
+	admin.WriteJSON(w, http.StatusOK, haltStatus{Halted: halt != nil, Halt: halt})
+}
+
+func (a *adminAPI) halt(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Reason == "" {
+		admin.WriteError(w, http.StatusBadRequest, errors.New(`body must give a reason, as {"reason":"usdc depeg"}`))
+		return
+	}
+	halt, err := a.performer.killswitch.Halt(r.Context(), req.Reason)
+	if err != nil {
+		admin.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	a.performer.logger.Sugar().Warnw("Rebalances halted through the admin API", "reason", req.Reason)
+	admin.WriteJSON(w, http.StatusOK, haltStatus{Halted: true, Halt: halt})
+}
+
+// resume lifts the halt set through the API. Rebalances stay halted while the service
+// manager is paused, which the answer shows.
+func (a *adminAPI) resume(w http.ResponseWriter, r *http.Request) {
+	if err := a.performer.killswitch.Resume(r.Context()); err != nil {
+		admin.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	a.performer.logger.Sugar().Warnw("Rebalance halt lifted through the admin API")
+	a.haltStatus(w, r)
+}
@@ -0,0 +1,260 @@
+package performer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/admin"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/chain/chaintest"
+	"github.com/najnomics/crosscow-avs/pkg/evidence"
+	"github.com/najnomics/crosscow-avs/pkg/servicemanager"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func newAdminServer(t *testing.T, performer *YieldIntelligencePerformer, level zap.AtomicLevel) *httptest.Server {
+	t.Helper()
+	cfg := admin.DefaultConfig()
+	server, err := admin.NewServer(&cfg, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewServer failed: %v", err)
+	}
+	RegisterAdminRoutes(server, performer, level, nil, cfg.ProbeTimeout)
+	ts := httptest.NewServer(server.Handler())
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func adminRequest(t *testing.T, method, url, body string, out interface{}) int {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to build request: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, url, err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("Failed to decode response of %s %s: %v", method, url, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func Test_AdminListsTasks(t *testing.T) {
+	performer, adapter, handled := startBlockedTask(t)
+	ts := newAdminServer(t, performer, zap.NewAtomicLevel())
+
+	var inflight struct {
+		Tasks []InflightTask `json:"tasks"`
+	}
+	adminRequest(t, http.MethodGet, ts.URL+"/tasks/inflight", "", &inflight)
+	if len(inflight.Tasks) != 1 || inflight.Tasks[0].TaskID != "in-flight" || inflight.Tasks[0].TaskType != TaskTypeYieldMonitoring {
+		t.Errorf("Expected the blocked task in flight, got %+v", inflight.Tasks)
+	}
+
+	close(adapter.release)
+	if err := <-handled; err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+	adminRequest(t, http.MethodGet, ts.URL+"/tasks/inflight", "", &inflight)
+	if len(inflight.Tasks) != 0 {
+		t.Errorf("Expected no task in flight once handled, got %+v", inflight.Tasks)
+	}
+
+	var recent struct {
+		Tasks []taskSummary `json:"tasks"`
+	}
+	adminRequest(t, http.MethodGet, ts.URL+"/tasks/recent?limit=5", "", &recent)
+	if len(recent.Tasks) != 1 || recent.Tasks[0].TaskID != "in-flight" || recent.Tasks[0].Status != "completed" || !json.Valid(recent.Tasks[0].Result) {
+		t.Errorf("Expected the completed task and its result, got %+v", recent.Tasks)
+	}
+	if status := adminRequest(t, http.MethodGet, ts.URL+"/tasks/recent?limit=0", "", nil); status != http.StatusBadRequest {
+		t.Errorf("Expected an invalid limit to be rejected, got %d", status)
+	}
+}
+
+func Test_AdminSwitchesAdapters(t *testing.T) {
+	performer := NewYieldIntelligencePerformer(zap.NewNop(), WithAdapters(adapters.NewRegistry(
+		newFakeAaveAdapter(),
+		&brokenAdapter{protocol: adapters.ProtocolCompoundV3, chainIDs: []uint64{1, 8453}},
+	)))
+	ts := newAdminServer(t, performer, zap.NewAtomicLevel())
+
+	var health struct {
+		Healthy bool          `json:"healthy"`
+		Markets []marketProbe `json:"markets"`
+	}
+	adminRequest(t, http.MethodGet, ts.URL+"/adapters/compound_v3/health", "", &health)
+	if health.Healthy || len(health.Markets) != 2 || health.Markets[0].Error != "rpc unavailable" {
+		t.Errorf("Expected both compound markets to be reported failing, got %+v", health)
+	}
+	var aaveHealth struct {
+		Healthy bool          `json:"healthy"`
+		Markets []marketProbe `json:"markets"`
+	}
+	adminRequest(t, http.MethodGet, ts.URL+"/adapters/aave_v3/health", "", &aaveHealth)
+	if !aaveHealth.Healthy || len(aaveHealth.Markets) == 0 {
+		t.Errorf("Expected the aave markets to be healthy, got %+v", aaveHealth)
+	}
+
+	if status := adminRequest(t, http.MethodPost, ts.URL+"/adapters/compound_v3/disable", "", nil); status != http.StatusOK {
+		t.Fatalf("Expected the adapter to be disabled, got %d", status)
+	}
+	var listed struct {
+		Adapters []adapterStatus `json:"adapters"`
+	}
+	adminRequest(t, http.MethodGet, ts.URL+"/adapters", "", &listed)
+	if len(listed.Adapters) != 2 || !listed.Adapters[0].Enabled || listed.Adapters[1].Enabled {
+		t.Errorf("Expected compound_v3 to be listed as disabled, got %+v", listed.Adapters)
+	}
+
+	// tasks for a disabled protocol are refused
+	_, err := performer.HandleTask(&performerV1.TaskRequest{
+		TaskId:  []byte("disabled"),
+		Payload: []byte(`{"type":"yield_monitoring","parameters":{"protocol":"compound_v3","token":"USDC","chain_id":1}}`),
+	})
+	var taskErr *TaskError
+	if !errors.As(err, &taskErr) || taskErr.Code != ErrorCodeValidation || !errors.Is(err, adapters.ErrProtocolDisabled) {
+		t.Errorf("Expected the task to be refused as invalid, got %v", err)
+	}
+
+	if status := adminRequest(t, http.MethodPost, ts.URL+"/adapters/euler/enable", "", nil); status != http.StatusNotFound {
+		t.Errorf("Expected unknown adapters to be reported missing, got %d", status)
+	}
+}
+
+func Test_AdminSetsLogLevel(t *testing.T) {
+	level := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	ts := newAdminServer(t, NewYieldIntelligencePerformer(zap.NewNop()), level)
+
+	if status := adminRequest(t, http.MethodPut, ts.URL+"/log/level", `{"level":"debug"}`, nil); status != http.StatusOK {
+		t.Fatalf("Expected the level to be set, got %d", status)
+	}
+	if level.Level() != zapcore.DebugLevel {
+		t.Errorf("Expected debug level, got %s", level.Level())
+	}
+}
+
+func Test_AdminExportsDatasets(t *testing.T) {
+	history := store.NewSeriesStore(store.NewMemoryKV())
+	at := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	for _, series := range []string{store.SupplyRateSeries("aave_v3", 1), store.SupplyRateSeries("compound_v3", 1)} {
+		if err := history.Append(context.Background(), series, store.SeriesPoint{Time: at, Value: 0.05}); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+	ts := newAdminServer(t, NewYieldIntelligencePerformer(zap.NewNop(), WithRateHistory(history)), zap.NewAtomicLevel())
+
+	resp, err := http.Get(ts.URL + "/export/yield_history?protocol=aave_v3&from=2026-03-01")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/csv" {
+		t.Fatalf("Expected a CSV file, got %d %s: %s", resp.StatusCode, resp.Header.Get("Content-Type"), body)
+	}
+	if want := "time,protocol,chain_id,metric,value\n1772323200,aave_v3,1,supply_rate,5\n"; string(body) != want {
+		t.Errorf("Expected the aave history, got %q", body)
+	}
+
+	for url, status := range map[string]int{
+		"/export/balances":                  http.StatusNotFound,
+		"/export/yield_history?format=json": http.StatusBadRequest,
+		"/export/executions?chain_id=base":  http.StatusBadRequest,
+		"/export/executions?format=parquet": http.StatusOK,
+		"/export/risk_scores?to=2026-03-01": http.StatusOK,
+	} {
+		if got := adminRequest(t, http.MethodGet, ts.URL+url, "", nil); got != status {
+			t.Errorf("GET %s: expected %d, got %d", url, status, got)
+		}
+	}
+}
+
+var attestationEventABI = chain.MustParseABI(`[
+	{"name":"YieldAttestationSubmitted","type":"event","anonymous":false,"inputs":[
+		{"name":"attestationId","type":"bytes32","indexed":true},{"name":"operator","type":"address","indexed":true},
+		{"name":"protocolId","type":"bytes32","indexed":true},{"name":"chainId","type":"uint256","indexed":false},
+		{"name":"yieldRate","type":"uint256","indexed":false}]}
+]`)
+
+func Test_AdminServesEvidence(t *testing.T) {
+	serviceManager := common.HexToAddress("0x0000000000000000000000000000000000005e41")
+	contracts := chaintest.NewContracts(1)
+	chains := chain.NewManager()
+	chains.Register(1, "ethereum", contracts)
+	event := attestationEventABI.Events["YieldAttestationSubmitted"]
+	data, err := event.Inputs.NonIndexed().Pack(big.NewInt(1), big.NewInt(9_000))
+	if err != nil {
+		t.Fatalf("Failed to pack event: %v", err)
+	}
+	contracts.AddLog(types.Log{
+		Address:     serviceManager,
+		Topics:      []common.Hash{event.ID, {1}, common.BytesToHash(common.Address{7}.Bytes()), servicemanager.ProtocolID(adapters.ProtocolAaveV3)},
+		Data:        data,
+		BlockNumber: 50,
+	})
+
+	registry := adapters.NewRegistry(newFakeAaveAdapter())
+	cfg := evidence.DefaultConfig()
+	cfg.Enabled, cfg.ChainID, cfg.ServiceManager, cfg.StartBlock = true, 1, serviceManager.Hex(), 1
+	checker := evidence.New(cfg, chains, registry, store.NewMemoryKV(), zap.NewNop())
+	if kept, err := checker.Poll(context.Background()); err != nil || kept != 1 {
+		t.Fatalf("Expected evidence against the attestation, got %d: %v", kept, err)
+	}
+	ts := newAdminServer(t, NewYieldIntelligencePerformer(zap.NewNop(), WithAdapters(registry), WithEvidence(checker)), zap.NewAtomicLevel())
+
+	var listed struct {
+		Artifacts []evidence.Artifact `json:"artifacts"`
+	}
+	adminRequest(t, http.MethodGet, ts.URL+"/evidence", "", &listed)
+	if len(listed.Artifacts) != 1 || listed.Artifacts[0].Attestation.YieldRateBps != 9_000 {
+		t.Fatalf("Expected the artifact listed, got %+v", listed.Artifacts)
+	}
+	var artifact evidence.Artifact
+	if status := adminRequest(t, http.MethodGet, ts.URL+"/evidence/"+common.Hash{1}.Hex(), "", &artifact); status != http.StatusOK || artifact.Digest != listed.Artifacts[0].Digest {
+		t.Errorf("Expected the artifact of the attestation, got %d: %+v", status, artifact)
+	}
+	if status := adminRequest(t, http.MethodGet, ts.URL+"/evidence/"+common.Hash{2}.Hex(), "", nil); status != http.StatusNotFound {
+		t.Errorf("Expected no artifact against another attestation, got %d", status)
+	}
+	if status := adminRequest(t, http.MethodGet, ts.URL+"/evidence/0x12", "", nil); status != http.StatusBadRequest {
+		t.Errorf("Expected an invalid attestation id to be rejected, got %d", status)
+	}
+}
+
+func Test_AdminServesPayloadSchemas(t *testing.T) {
+	ts := newAdminServer(t, NewYieldIntelligencePerformer(zap.NewNop()), zap.NewAtomicLevel())
+
+	var listed struct {
+		TaskTypes []string `json:"taskTypes"`
+	}
+	if status := adminRequest(t, http.MethodGet, ts.URL+"/schemas", "", &listed); status != http.StatusOK || len(listed.TaskTypes) == 0 {
+		t.Fatalf("Expected the task types with a schema, got %d %v", status, listed.TaskTypes)
+	}
+	var schema map[string]interface{}
+	if status := adminRequest(t, http.MethodGet, ts.URL+"/schemas/yield_monitoring", "", &schema); status != http.StatusOK || schema["title"] != "yield_monitoring payload" {
+		t.Errorf("Expected the yield_monitoring schema, got %d %v", status, schema["title"])
+	}
+	if status := adminRequest(t, http.MethodGet, ts.URL+"/schemas/balances", "", nil); status != http.StatusNotFound {
+		t.Errorf("Expected an unknown task type to be not found, got %d", status)
+	}
+}
@@ -0,0 +1,369 @@
+package performer
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"time"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/najnomics/crosscow-avs/pkg/addressbook"
+	"github.com/najnomics/crosscow-avs/pkg/allocation"
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+	"github.com/najnomics/crosscow-avs/pkg/tokens"
+)
+
+const defaultAllocationHorizonDays = 30
+
+// MarketAllocation is the part of the amount an allocation plan puts into one market.
+// Rates are annual percentages, amounts are in USDC.
+type MarketAllocation struct {
+	Protocol       string            `json:"protocol"`
+	ChainID        uint64            `json:"chain_id"`
+	Amount         canonical.Decimal `json:"amount"`
+	Share          canonical.Decimal `json:"share"`
+	CurrentRate    canonical.Decimal `json:"current_rate"`
+	ExpectedRate   canonical.Decimal `json:"expected_rate"`
+	RiskPenaltyBps canonical.Decimal `json:"risk_penalty_bps"`
+	TransferCost   canonical.Decimal `json:"transfer_cost"`
+	NetYield       canonical.Decimal `json:"net_yield"`
+
+	// Dependencies are what the market shares with other markets and fails with, as
+	// kind:name
+	Dependencies []string `json:"dependencies,omitempty"`
+
+	// StressCap is the most the market can take and still be withdrawn in full after
+	// the stress_shock_bps utilization shock. Only reported when the task sets one.
+	StressCap *canonical.Decimal `json:"stress_cap,omitempty"`
+}
+
+// DependencyExposure is the part of a plan behind one dependency, which fails every
+// market sharing it at once. Amounts are in USDC.
+type DependencyExposure struct {
+	Dependency string            `json:"dependency"`
+	Kind       string            `json:"kind"`
+	Amount     canonical.Decimal `json:"amount"`
+	Share      canonical.Decimal `json:"share"`
+	Markets    int               `json:"markets"`
+}
+
+// AllocationOptimizationResult is the result of an allocation_optimization task. Net
+// yield is earned over the horizon after risk penalties, transfer costs and the
+// concentration cost of funds sharing dependencies.
+type AllocationOptimizationResult struct {
+	Token               string               `json:"token"`
+	Amount              canonical.Decimal    `json:"amount"`
+	HorizonDays         uint64               `json:"horizon_days"`
+	Allocations         []MarketAllocation   `json:"allocations"`
+	Unallocated         canonical.Decimal    `json:"unallocated"`
+	ExpectedNetYield    canonical.Decimal    `json:"expected_net_yield"`
+	NetRate             canonical.Decimal    `json:"net_rate"`
+	ConcentrationCost   canonical.Decimal    `json:"concentration_cost"`
+	DependencyExposures []DependencyExposure `json:"dependency_exposures"`
+	Failures            []MarketFailure      `json:"failures"`
+
+	// PolicyRejections are the rules markets break, which leaves them out of the plan.
+	// Only reported when the operator configures a policy or the task carries a profile.
+	PolicyRejections []PolicyViolation `json:"policy_rejections,omitempty"`
+	Status           ResultStatus      `json:"status"`
+}
+
+// handleAllocationOptimization computes how to split amount across markets to maximize
+// net risk-adjusted yield, given how each deposit dilutes its market's rate and what it
+// costs to move funds to each chain. Markets breaking the operator's policy are left out,
+// and no plan puts more into a market or protocol than the policy allows. Markets above
+// the risk of the user's profile are left out too; the improvement and bridge latency it
+// asks for depend on where funds come from, which rebalances check. With
+// stress_shock_bps set, no market gets more than could still be withdrawn if its
+// utilization jumped by the shock, the max_allocation of the stress_scenario table.
+// The exposure of the plan to every dependency markets share in the address book, such
+// as an oracle or the sequencer of a chain, is reported; with concentration_penalty_bps
+// set, funds concentrating behind one are penalized, which spreads them over
+// independent markets.
+func (yip *YieldIntelligencePerformer) handleAllocationOptimization(ctx context.Context, t *performerV1.TaskRequest, payload *TaskPayload) (interface{}, error) {
+	yip.log(ctx).Sugar().Infow("Processing allocation optimization task")
+
+	amount := paramAmount(payload, "amount")
+	horizonDays := paramUint64(payload, "horizon_days")
+	if horizonDays == 0 {
+		horizonDays = defaultAllocationHorizonDays
+	}
+	transferCosts, _ := payload.Parameters["transfer_costs"].(map[string]interface{})
+	riskPenalties, _ := payload.Parameters["risk_penalty_bps"].(map[string]interface{})
+	maxShare, _ := payload.Parameters["max_share"].(float64)
+	stressShock := float64(paramUint64(payload, "stress_shock_bps")) / 10000
+	concentrationBps, _ := payload.Parameters["concentration_penalty_bps"].(float64)
+	profile := paramProfile(payload)
+
+	markets := yip.selectedMarkets(payload)
+
+	result := &AllocationOptimizationResult{
+		Token:               paramString(payload, "token"),
+		Amount:              amount,
+		HorizonDays:         horizonDays,
+		Allocations:         []MarketAllocation{},
+		DependencyExposures: []DependencyExposure{},
+		Failures:            []MarketFailure{},
+		Status:              ResultStatusCompleted,
+	}
+
+	total := usdcBaseUnits(amount)
+	var candidates []allocation.Candidate
+	stressCaps := make(map[int]*big.Int)
+	for i, outcome := range yip.readMarkets(ctx, markets) {
+		if outcome.Err != nil {
+			result.Failures = append(result.Failures, marketFailure(markets[i], outcome.Err))
+			continue
+		}
+		if violations := yip.policy.CheckMarket(policyMarket(markets[i], outcome.Value)); len(violations) > 0 {
+			result.PolicyRejections = append(result.PolicyRejections, policyViolations(violations)...)
+			continue
+		}
+		violations, err := yip.checkProfileRisk(ctx, profile, markets[i], outcome.Value)
+		if err != nil {
+			result.Failures = append(result.Failures, marketFailure(markets[i], err))
+			continue
+		}
+		if len(violations) > 0 {
+			result.PolicyRejections = append(result.PolicyRejections, policyViolations(violations)...)
+			continue
+		}
+		penaltyBps, _ := riskPenalties[markets[i].protocol].(float64)
+		cost, _ := transferCosts[strconv.FormatUint(markets[i].chainID, 10)].(string)
+		transferCost, err := tokens.ParseAmount(cost)
+		if err != nil {
+			transferCost = new(big.Int)
+		}
+		candidate := allocation.Candidate{
+			Protocol:     markets[i].protocol,
+			ChainID:      markets[i].chainID,
+			Pool:         outcome.Value.Pool,
+			TransferCost: transferCost,
+			RiskPenalty:  penaltyBps / 10000,
+			Dependencies: yip.dependencies(markets[i]),
+		}
+		if maxShare > 0 {
+			limit := new(big.Rat).Mul(new(big.Rat).SetInt(total), new(big.Rat).SetFloat64(maxShare))
+			candidate.MaxAmount = new(big.Int).Quo(limit.Num(), limit.Denom())
+		}
+		// markets cannot take more than their supply cap headroom, queued cuts included
+		_, headroom, err := yip.supplyCapStatus(ctx, markets[i], outcome.Value.Pool.TotalSupply, true)
+		if err != nil {
+			yip.log(ctx).Sugar().Warnw("Failed to read supply cap",
+				"protocol", markets[i].protocol,
+				"chainId", markets[i].chainID,
+				"error", err,
+			)
+		} else if headroom != nil && (candidate.MaxAmount == nil || headroom.Cmp(candidate.MaxAmount) < 0) {
+			candidate.MaxAmount = headroom
+		}
+		if limit := yip.policy.MaxMoveSize(); limit != nil && (candidate.MaxAmount == nil || limit.Cmp(candidate.MaxAmount) < 0) {
+			candidate.MaxAmount = limit
+		}
+		if stressShock > 0 {
+			limit := outcome.Value.Pool.MaxStressedDeposit(stressShock)
+			stressCaps[len(candidates)] = limit
+			if candidate.MaxAmount == nil || limit.Cmp(candidate.MaxAmount) < 0 {
+				candidate.MaxAmount = limit
+			}
+		}
+		candidates = append(candidates, candidate)
+	}
+	if len(candidates) == 0 && len(result.PolicyRejections) == 0 {
+		return nil, fmt.Errorf("failed to read any of %d candidate markets", len(markets))
+	}
+	if len(result.Failures) > 0 {
+		result.Status = ResultStatusPartial
+	}
+
+	horizon := time.Duration(horizonDays) * 24 * time.Hour
+	opts := allocation.Options{Horizon: horizon, ConcentrationPenalty: concentrationBps / 10000}
+	if limit := yip.policy.MaxProtocolAllocation(); limit != nil {
+		opts.ProtocolCaps = make(map[string]*big.Int)
+		for _, c := range candidates {
+			opts.ProtocolCaps[c.Protocol] = limit
+		}
+	}
+	plan := allocation.Optimize(total, candidates, opts)
+
+	totalUnits, _ := new(big.Float).SetInt(total).Float64()
+	for _, a := range plan.Allocations {
+		c := &candidates[a.Candidate]
+		units, _ := new(big.Float).SetInt(a.Amount).Float64()
+		var stressCap *canonical.Decimal
+		if limit, ok := stressCaps[a.Candidate]; ok {
+			capped := usdcAmount(limit)
+			stressCap = &capped
+		}
+		result.Allocations = append(result.Allocations, MarketAllocation{
+			Protocol:       c.Protocol,
+			ChainID:        c.ChainID,
+			Amount:         usdcAmount(a.Amount),
+			Share:          canonical.Ratio(units / totalUnits),
+			CurrentRate:    ratePercent(c.Pool.SupplyRate()),
+			ExpectedRate:   ratePercent(a.Rate),
+			RiskPenaltyBps: canonical.Score(c.RiskPenalty * 10000),
+			TransferCost:   usdcAmount(c.TransferCost),
+			NetYield:       usdcFloat(a.NetYield),
+			Dependencies:   c.Dependencies,
+			StressCap:      stressCap,
+		})
+	}
+	result.DependencyExposures = dependencyExposures(candidates, plan, total)
+	result.Unallocated = usdcAmount(plan.Unallocated)
+	result.ExpectedNetYield = usdcFloat(plan.NetYield)
+	result.ConcentrationCost = usdcFloat(plan.ConcentrationCost)
+	result.NetRate = ratePercent(plan.NetYield / totalUnits * (365 / float64(horizonDays)))
+	return result, nil
+}
+
+// dependencies returns what m shares with other markets in the address book
+func (yip *YieldIntelligencePerformer) dependencies(m market) []string {
+	var out []string
+	for _, d := range yip.book.Dependencies(m.chainID, m.protocol) {
+		out = append(out, string(d))
+	}
+	return out
+}
+
+// dependencyExposures sums the allocations of plan behind each dependency, largest
+// exposure first
+func dependencyExposures(candidates []allocation.Candidate, plan *allocation.Plan, total *big.Int) []DependencyExposure {
+	amounts := make(map[string]*big.Int)
+	markets := make(map[string]int)
+	for _, a := range plan.Allocations {
+		for _, d := range candidates[a.Candidate].Dependencies {
+			if amounts[d] == nil {
+				amounts[d] = new(big.Int)
+			}
+			amounts[d].Add(amounts[d], a.Amount)
+			markets[d]++
+		}
+	}
+	exposures := make([]DependencyExposure, 0, len(amounts))
+	for d, amount := range amounts {
+		share, _ := new(big.Rat).SetFrac(amount, total).Float64()
+		exposures = append(exposures, DependencyExposure{
+			Dependency: d,
+			Kind:       addressbook.Dependency(d).Kind(),
+			Amount:     usdcAmount(amount),
+			Share:      canonical.Ratio(share),
+			Markets:    markets[d],
+		})
+	}
+	sort.Slice(exposures, func(i, j int) bool {
+		if c := exposures[i].Amount.Cmp(exposures[j].Amount); c != 0 {
+			return c > 0
+		}
+		return exposures[i].Dependency < exposures[j].Dependency
+	})
+	return exposures
+}
+
+// usdcFloat converts an amount of USDC base units computed in floating point into a
+// USDC decimal
+func usdcFloat(baseUnits float64) canonical.Decimal {
+	return canonical.NewDecimalFromFloat(baseUnits/1e6, USDCDecimals)
+}
+
+// selectedMarkets lists the registered markets of the protocols and chain_ids parameters,
+// every protocol and chain when omitted
+func (yip *YieldIntelligencePerformer) selectedMarkets(payload *TaskPayload) []market {
+	return selectProtocols(payload, yip.markets(paramUint64List(payload, "chain_ids")...))
+}
+
+// selectProtocols keeps the candidates of the protocols parameter, every one when omitted
+func selectProtocols(payload *TaskPayload, candidates []market) []market {
+	protocols := make(map[string]bool)
+	for _, p := range paramStringList(payload, "protocols") {
+		protocols[p] = true
+	}
+	var markets []market
+	for _, m := range candidates {
+		if len(protocols) == 0 || protocols[m.protocol] {
+			markets = append(markets, m)
+		}
+	}
+	return markets
+}
+
+// validateMarketSelection checks the optional protocols parameter selectedMarkets filters
+// by. Lending markets are accepted, and other venues when their kind is allowed.
+func (yip *YieldIntelligencePerformer) validateMarketSelection(payload *TaskPayload, allowed ...venueKind) error {
+	for _, protocol := range paramStringList(payload, "protocols") {
+		adapter, err := yip.adapters.Get(protocol)
+		if err != nil {
+			return err
+		}
+		if err := checkVenueKind(protocol, kindOf(adapter), allowed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkVenueKind(protocol string, kind venueKind, allowed []venueKind) error {
+	if kind == venueLending {
+		return nil
+	}
+	for _, k := range allowed {
+		if k == kind {
+			return nil
+		}
+	}
+	if kind == venueLiquidityPool {
+		return fmt.Errorf("invalid protocol %s: liquidity pools are only compared when include_lp is set", protocol)
+	}
+	return fmt.Errorf("invalid protocol %s: fixed rate markets are only compared in %s mode", protocol, RankingModeFixedVsVariable)
+}
+
+// paramStringList returns the non-empty strings of an array parameter in order
+func paramStringList(payload *TaskPayload, key string) []string {
+	values, _ := payload.Parameters[key].([]interface{})
+	list := make([]string, 0, len(values))
+	for _, v := range values {
+		if s, ok := v.(string); ok && s != "" {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+func (yip *YieldIntelligencePerformer) validateAllocationOptimizationTask(payload *TaskPayload) error {
+	if err := validateToken(payload, paramUint64List(payload, "chain_ids")...); err != nil {
+		return err
+	}
+
+	if err := checkAmount(payload.Parameters["amount"]); err != nil {
+		return fmt.Errorf("missing or invalid amount: %w", err)
+	}
+
+	if err := yip.validateMarketSelection(payload); err != nil {
+		return err
+	}
+
+	// the schema only checks that chain ids and costs are digits, not that they fit
+	raw, present := payload.Parameters["transfer_costs"]
+	if !present {
+		return nil
+	}
+	costs, ok := raw.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("invalid transfer_costs: must be an object of costs by chain id")
+	}
+	for chainID, v := range costs {
+		if _, err := strconv.ParseUint(chainID, 10, 64); err != nil {
+			return fmt.Errorf("invalid transfer_costs chain id %q", chainID)
+		}
+		cost, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("invalid transfer cost for chain %s: must be a decimal string", chainID)
+		}
+		if _, err := tokens.ParseAmount(cost); err != nil {
+			return fmt.Errorf("invalid transfer cost for chain %s: %w", chainID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package performer
+
+import (
+	"encoding/json"
+	"testing"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"go.uber.org/zap"
+)
+
+func newAllocationPerformer(t *testing.T) *YieldIntelligencePerformer {
+	t.Helper()
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+	aave := newFakeAaveAdapter()
+	base := *aave.markets[1]
+	base.ChainID = adapters.ChainIDBase
+	aave.markets[adapters.ChainIDBase] = &base
+
+	return NewYieldIntelligencePerformer(logger, WithAdapters(adapters.NewRegistry(
+		aave,
+		&brokenAdapter{protocol: adapters.ProtocolCompoundV3, chainIDs: []uint64{1}},
+	)), WithFeatures(allFeatures()))
+}
+
+func Test_AllocationOptimization(t *testing.T) {
+	performer := newAllocationPerformer(t)
+
+	testCases := []struct {
+		name        string
+		params      string
+		allocations int
+	}{
+		// both aave markets pay the same, so a large amount is split to limit dilution
+		{name: "split", params: `"amount":"20000000000000"`, allocations: 2},
+		// moving 100k to Base does not earn back a 5000 USDC transfer in 30 days
+		{name: "transfer cost", params: `"amount":"100000000000","transfer_costs":{"8453":"5000000000"}`, allocations: 1},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			task := &performerV1.TaskRequest{
+				TaskId:  []byte("allocate-" + tc.name),
+				Payload: []byte(`{"type":"allocation_optimization","parameters":{"token":"USDC",` + tc.params + `}}`),
+			}
+			if err := performer.ValidateTask(task); err != nil {
+				t.Fatalf("ValidateTask failed: %v", err)
+			}
+			resp, err := performer.HandleTask(task)
+			if err != nil {
+				t.Fatalf("HandleTask failed: %v", err)
+			}
+
+			var envelope struct {
+				Result AllocationOptimizationResult `json:"result"`
+			}
+			if err := json.Unmarshal(resp.Result, &envelope); err != nil {
+				t.Fatalf("Failed to decode result: %v", err)
+			}
+			result := envelope.Result
+			if len(result.Allocations) != tc.allocations {
+				t.Fatalf("Expected %d allocations, got %+v", tc.allocations, result.Allocations)
+			}
+			if result.Allocations[0].ChainID != 1 || result.Unallocated.Rat().Sign() != 0 {
+				t.Errorf("Unexpected allocation plan: %+v", result)
+			}
+			if result.Allocations[0].ExpectedRate.Cmp(result.Allocations[0].CurrentRate) >= 0 {
+				t.Errorf("Expected the deposit to dilute the rate, got %+v", result.Allocations[0])
+			}
+			if result.Status != ResultStatusPartial || len(result.Failures) != 1 {
+				t.Errorf("Expected the unreadable compound market to be reported, got %s %+v", result.Status, result.Failures)
+			}
+		})
+	}
+}
+
+func Test_AllocationOptimizationValidation(t *testing.T) {
+	performer := newAllocationPerformer(t)
+
+	testCases := map[string]string{
+		"missing amount":   `"token":"USDC"`,
+		"unknown protocol": `"token":"USDC","amount":"1000000000","protocols":["euler"]`,
+		"bad transfer key": `"token":"USDC","amount":"1000000000","transfer_costs":{"base":"5000000"}`,
+		"penalty range":    `"token":"USDC","amount":"1000000000","risk_penalty_bps":{"aave_v3":20000}`,
+		"max share":        `"token":"USDC","amount":"1000000000","max_share":1.5`,
+		"horizon":          `"token":"USDC","amount":"1000000000","horizon_days":0.5`,
+	}
+	for name, params := range testCases {
+		t.Run(name, func(t *testing.T) {
+			task := &performerV1.TaskRequest{
+				TaskId:  []byte(name),
+				Payload: []byte(`{"type":"allocation_optimization","parameters":{` + params + `}}`),
+			}
+			if err := performer.ValidateTask(task); err == nil {
+				t.Errorf("Expected the task to be rejected")
+			}
+		})
+	}
+}
+
+func Test_AllocationOptimizationDependencies(t *testing.T) {
+	performer := newAllocationPerformer(t)
+
+	allocate := func(params string) AllocationOptimizationResult {
+		task := &performerV1.TaskRequest{
+			TaskId:  []byte("allocate-" + params),
+			Payload: []byte(`{"type":"allocation_optimization","parameters":{"token":"USDC","amount":"10000000000000"` + params + `}}`),
+		}
+		if err := performer.ValidateTask(task); err != nil {
+			t.Fatalf("ValidateTask failed: %v", err)
+		}
+		resp, err := performer.HandleTask(task)
+		if err != nil {
+			t.Fatalf("HandleTask failed: %v", err)
+		}
+		var envelope struct {
+			Result AllocationOptimizationResult `json:"result"`
+		}
+		if err := json.Unmarshal(resp.Result, &envelope); err != nil {
+			t.Fatalf("Failed to decode result: %v", err)
+		}
+		return envelope.Result
+	}
+
+	// both aave markets, each with 10M of cap headroom, price collateral with chainlink,
+	// and the Base one halts with its sequencer
+	result := allocate("")
+	exposures := result.DependencyExposures
+	if len(exposures) != 2 || exposures[0].Dependency != "oracle:chainlink" || exposures[0].Share.String() != "1.000000" || exposures[0].Markets != 2 {
+		t.Fatalf("Expected the whole plan behind chainlink, got %+v", exposures)
+	}
+	if exposures[1].Kind != "sequencer" || exposures[1].Share.Cmp(exposures[0].Share) >= 0 {
+		t.Errorf("Expected the Base allocation behind its sequencer, got %+v", exposures[1])
+	}
+	if result.ConcentrationCost.Rat().Sign() != 0 {
+		t.Errorf("Expected no concentration cost without a penalty, got %s", result.ConcentrationCost)
+	}
+
+	penalized := allocate(`,"concentration_penalty_bps":50`)
+	if penalized.ConcentrationCost.Rat().Sign() <= 0 || penalized.ExpectedNetYield.Cmp(result.ExpectedNetYield) >= 0 {
+		t.Errorf("Expected the concentration to be charged, got %s of %s", penalized.ConcentrationCost, penalized.ExpectedNetYield)
+	}
+	if penalized.Allocations[1].Amount.Cmp(result.Allocations[1].Amount) >= 0 {
+		t.Errorf("Expected less behind the Base sequencer, got %+v", penalized.Allocations)
+	}
+
+	task := &performerV1.TaskRequest{
+		TaskId:  []byte("allocate-penalty-range"),
+		Payload: []byte(`{"type":"allocation_optimization","parameters":{"token":"USDC","amount":"1000000000","concentration_penalty_bps":20000}}`),
+	}
+	if err := performer.ValidateTask(task); err == nil {
+		t.Errorf("Expected a penalty above 10000 bps to be rejected")
+	}
+}
@@ -0,0 +1,153 @@
+package performer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+	"github.com/najnomics/crosscow-avs/pkg/forecast"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+)
+
+const (
+	// forecastStep is the resolution rate history is resampled to before fitting
+	forecastStep = time.Hour
+
+	// forecastZ is the z-score of the reported two sided 95% confidence interval
+	forecastZ = 1.96
+
+	defaultForecastLookbackHours = 30 * 24
+)
+
+var defaultForecastHorizonsHours = []uint64{1, 24, 7 * 24}
+
+// RateForecast is the forecast supply rate at one horizon, as annual percentages
+type RateForecast struct {
+	HorizonHours uint64            `json:"horizon_hours"`
+	Expected     canonical.Decimal `json:"expected_rate"`
+	Lower        canonical.Decimal `json:"lower_rate"`
+	Upper        canonical.Decimal `json:"upper_rate"`
+}
+
+// APYForecastResult is the result of an apy_forecast task
+type APYForecastResult struct {
+	Protocol        string            `json:"protocol"`
+	Token           string            `json:"token"`
+	ChainID         uint64            `json:"chain_id"`
+	CurrentRate     canonical.Decimal `json:"current_rate"`
+	DepositAmount   canonical.Decimal `json:"deposit_amount"`
+	StartingRate    canonical.Decimal `json:"starting_rate"`
+	LongRunRate     canonical.Decimal `json:"long_run_rate"`
+	HalfLifeHours   canonical.Decimal `json:"half_life_hours"`
+	ConfidenceLevel canonical.Decimal `json:"confidence_level"`
+	Samples         int               `json:"samples"`
+	Forecasts       []RateForecast    `json:"forecasts"`
+	Status          ResultStatus      `json:"status"`
+}
+
+// recordSupplyRate appends the current supply rate of a market to its history
+func (yip *YieldIntelligencePerformer) recordSupplyRate(ctx context.Context, state *adapters.MarketState, at time.Time) error {
+	return yip.history.Append(ctx, store.SupplyRateSeries(state.Protocol, state.ChainID), store.SeriesPoint{
+		Time:  at,
+		Value: state.Pool.SupplyRate(),
+	})
+}
+
+// handleAPYForecast forecasts the supply rate of a market over short horizons from its
+// history. With deposit_amount set, the forecast starts from the rate right after the
+// deposit and shows how it reverts.
+func (yip *YieldIntelligencePerformer) handleAPYForecast(ctx context.Context, t *performerV1.TaskRequest, payload *TaskPayload) (interface{}, error) {
+	yip.log(ctx).Sugar().Infow("Processing APY forecast task")
+
+	protocol := paramString(payload, "protocol")
+	chainID := paramUint64(payload, "chain_id")
+	lookbackHours := paramUint64(payload, "lookback_hours")
+	if lookbackHours == 0 {
+		lookbackHours = defaultForecastLookbackHours
+	}
+	horizons := paramUint64List(payload, "horizons_hours")
+	if len(horizons) == 0 {
+		horizons = defaultForecastHorizonsHours
+	}
+	deposit := paramAmount(payload, "deposit_amount")
+
+	state, err := yip.readMarket(ctx, market{protocol: protocol, chainID: chainID})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := yip.recordSupplyRate(ctx, state, now); err != nil {
+		return nil, fmt.Errorf("failed to record supply rate: %w", err)
+	}
+	points, err := yip.history.Range(ctx, store.SupplyRateSeries(protocol, chainID),
+		now.Add(-time.Duration(lookbackHours)*time.Hour), now.Add(time.Nanosecond))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load rate history: %w", err)
+	}
+	model, err := forecast.Fit(forecast.Resample(points, forecastStep), forecastStep)
+	if err != nil {
+		return nil, fmt.Errorf("cannot forecast %s on chain %d: %w", protocol, chainID, err)
+	}
+
+	current := state.Pool.SupplyRate()
+	starting := current
+	if deposit.Rat().Sign() > 0 {
+		starting = state.Pool.DepositImpact(usdcBaseUnits(deposit)).SupplyRate
+	}
+
+	result := &APYForecastResult{
+		Protocol:        protocol,
+		Token:           paramString(payload, "token"),
+		ChainID:         chainID,
+		CurrentRate:     ratePercent(current),
+		DepositAmount:   deposit,
+		StartingRate:    ratePercent(starting),
+		LongRunRate:     ratePercent(model.Mean),
+		HalfLifeHours:   canonical.Score(model.HalfLife().Hours()),
+		ConfidenceLevel: canonical.NewDecimalFromFloat(0.95, 2),
+		Samples:         model.Samples,
+		Forecasts:       make([]RateForecast, 0, len(horizons)),
+		Status:          ResultStatusCompleted,
+	}
+	for _, h := range horizons {
+		p := model.Predict(starting, time.Duration(h)*time.Hour, forecastZ)
+		result.Forecasts = append(result.Forecasts, RateForecast{
+			HorizonHours: h,
+			Expected:     ratePercent(p.Expected),
+			Lower:        ratePercent(p.Lower),
+			Upper:        ratePercent(p.Upper),
+		})
+	}
+	return result, nil
+}
+
+// paramUint64List returns the positive integers of an array parameter in order
+func paramUint64List(payload *TaskPayload, key string) []uint64 {
+	values, _ := payload.Parameters[key].([]interface{})
+	list := make([]uint64, 0, len(values))
+	for _, v := range values {
+		if f, ok := v.(float64); ok && f > 0 {
+			list = append(list, uint64(f))
+		}
+	}
+	return list
+}
+
+func (yip *YieldIntelligencePerformer) validateAPYForecastTask(payload *TaskPayload) error {
+	if _, err := yip.adapters.Get(paramString(payload, "protocol")); err != nil {
+		return err
+	}
+	if err := validateToken(payload, paramUint64(payload, "chain_id")); err != nil {
+		return err
+	}
+	if raw, present := payload.Parameters["deposit_amount"]; present {
+		if err := checkAmount(raw); err != nil {
+			return fmt.Errorf("invalid deposit_amount: %w", err)
+		}
+	}
+	return nil
+}
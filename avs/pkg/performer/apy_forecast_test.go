@@ -0,0 +1,112 @@
+package performer
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"testing"
+	"time"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+	"go.uber.org/zap"
+)
+
+// seedRateHistory writes hourly mean reverting supply rates around mean for the past days
+func seedRateHistory(t *testing.T, history *store.SeriesStore, series string, mean float64, days int) {
+	t.Helper()
+	rng := rand.New(rand.NewSource(7))
+	now := time.Now()
+	rate := mean
+	for h := days * 24; h > 0; h-- {
+		rate = mean + 0.85*(rate-mean) + 0.0005*rng.NormFloat64()
+		point := store.SeriesPoint{Time: now.Add(-time.Duration(h) * time.Hour), Value: rate}
+		if err := history.Append(context.Background(), series, point); err != nil {
+			t.Fatalf("Failed to seed history: %v", err)
+		}
+	}
+}
+
+func Test_APYForecast(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+	history := store.NewSeriesStore(store.NewMemoryKV())
+	seedRateHistory(t, history, store.SupplyRateSeries(adapters.ProtocolAaveV3, 1), 0.0384, 14)
+
+	performer := NewYieldIntelligencePerformer(logger,
+		WithAdapters(adapters.NewRegistry(newFakeAaveAdapter())),
+		WithRateHistory(history),
+	)
+
+	task := &performerV1.TaskRequest{
+		TaskId:  []byte("forecast-task"),
+		Payload: []byte(`{"type":"apy_forecast","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1,"deposit_amount":"10000000000000","horizons_hours":[1,24,168]}}`),
+	}
+	if err := performer.ValidateTask(task); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	resp, err := performer.HandleTask(task)
+	if err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+
+	var envelope struct {
+		Result APYForecastResult `json:"result"`
+	}
+	if err := json.Unmarshal(resp.Result, &envelope); err != nil {
+		t.Fatalf("Failed to decode result: %v", err)
+	}
+	result := envelope.Result
+
+	if result.StartingRate.Cmp(result.CurrentRate) >= 0 {
+		t.Errorf("Expected a deposit to start the forecast below the current rate: %s vs %s", result.StartingRate, result.CurrentRate)
+	}
+	if len(result.Forecasts) != 3 {
+		t.Fatalf("Expected 3 forecasts, got %d", len(result.Forecasts))
+	}
+	first, last := result.Forecasts[0], result.Forecasts[2]
+	if first.Expected.Cmp(last.Expected) >= 0 {
+		t.Errorf("Expected the rate to revert upwards after the deposit: %s -> %s", first.Expected, last.Expected)
+	}
+	for _, f := range result.Forecasts {
+		if f.Lower.Cmp(f.Expected) > 0 || f.Upper.Cmp(f.Expected) < 0 {
+			t.Errorf("Expected rate outside its interval: %+v", f)
+		}
+	}
+	if diff := last.Expected.Float64() - 3.84; diff > 0.05 || diff < -0.05 {
+		t.Errorf("Expected the long horizon forecast to approach the historical mean, got %s", last.Expected)
+	}
+}
+
+func Test_APYForecastRequiresHistory(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+	performer := NewYieldIntelligencePerformer(logger, WithAdapters(adapters.NewRegistry(newFakeAaveAdapter())))
+
+	task := &performerV1.TaskRequest{
+		TaskId:  []byte("forecast-task"),
+		Payload: []byte(`{"type":"apy_forecast","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1}}`),
+	}
+	if _, err := performer.HandleTask(task); err == nil {
+		t.Errorf("Expected forecast without history to fail")
+	}
+
+	for name, payload := range map[string]string{
+		"horizon too long":   `{"type":"apy_forecast","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1,"horizons_hours":[10000]}}`,
+		"lookback too short": `{"type":"apy_forecast","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1,"lookback_hours":2}}`,
+		"lookback too long":  `{"type":"apy_forecast","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1,"lookback_hours":1e15}}`,
+		"negative deposit":   `{"type":"apy_forecast","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1,"deposit_amount":"-5000000"}}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			task := &performerV1.TaskRequest{TaskId: []byte("forecast-task"), Payload: []byte(payload)}
+			if err := performer.ValidateTask(task); err == nil {
+				t.Errorf("Expected task to be rejected")
+			}
+		})
+	}
+}
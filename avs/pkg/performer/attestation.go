@@ -0,0 +1,71 @@
+package performer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+)
+
+// validateAttest checks the attest parameter. Attested results differ between operators,
+// so attest is meant for tasks sent to a single operator to audit, not for tasks whose
+// results are aggregated.
+func (yip *YieldIntelligencePerformer) validateAttest(payload *TaskPayload) error {
+	raw, present := payload.Parameters["attest"]
+	if !present {
+		return nil
+	}
+	attest, ok := raw.(bool)
+	if !ok {
+		return fmt.Errorf("invalid attest: must be a boolean")
+	}
+	if !attest {
+		return nil
+	}
+	if yip.attester == nil {
+		return fmt.Errorf("attestations are not configured on this performer")
+	}
+	if format, _ := resultFormat(payload); format != ResultFormatJSON {
+		return fmt.Errorf("attest is only supported for %s results", ResultFormatJSON)
+	}
+	return nil
+}
+
+// attestResult adds the operator's attestation to the encoded result of a task setting
+// the attest parameter. The attestation covers the result as encoded without it, which
+// consumers get back by dropping the attestation and encoding the envelope canonically.
+func (yip *YieldIntelligencePerformer) attestResult(ctx context.Context, t *performerV1.TaskRequest, payload *TaskPayload, encoded []byte) ([]byte, error) {
+	if !paramBool(payload, "attest") || yip.attester == nil {
+		return encoded, nil
+	}
+	var envelope struct {
+		TaskType      TaskType          `json:"task_type"`
+		SchemaVersion int               `json:"schema_version"`
+		Result        json.RawMessage   `json:"result"`
+		Encoding      string            `json:"encoding"`
+		Commitment    *ResultCommitment `json:"commitment"`
+		Trace         *ResultTrace      `json:"trace"`
+	}
+	if err := json.Unmarshal(encoded, &envelope); err != nil {
+		return nil, fmt.Errorf("failed to decode %s result: %w", payload.Type, err)
+	}
+	signed, err := yip.attester.Attest(ctx, string(t.TaskId), encoded)
+	if err != nil {
+		return nil, err
+	}
+	attested, err := canonical.Marshal(&TaskResult{
+		TaskType:      envelope.TaskType,
+		SchemaVersion: envelope.SchemaVersion,
+		Result:        envelope.Result,
+		Encoding:      envelope.Encoding,
+		Commitment:    envelope.Commitment,
+		Trace:         envelope.Trace,
+		Attestation:   signed,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s result: %w", payload.Type, err)
+	}
+	return attested, nil
+}
@@ -0,0 +1,94 @@
+package performer
+
+import (
+	"encoding/json"
+	"testing"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/najnomics/crosscow-avs/pkg/attestation"
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+	"github.com/najnomics/crosscow-avs/pkg/signer"
+)
+
+func Test_AttestedResult(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	operator := signer.NewKeySigner(key)
+	performer := newDryRunPerformer(t, &fakeSimulator{bundles: make(map[uint64]int)})
+	WithAttestation(attestation.New(operator, "v1.2.3", []attestation.Source{attestation.NewSource("rpc:1", "https://rpc.example")}))(performer)
+
+	task := &performerV1.TaskRequest{TaskId: []byte("attested"), Payload: []byte(`{"type":"yield_monitoring","parameters":{
+		"protocol":"aave_v3","token":"USDC","chain_id":1,"attest":true}}`)}
+	if err := performer.ValidateTask(task); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	resp, err := performer.HandleTask(task)
+	if err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+
+	var envelope struct {
+		TaskType      TaskType                 `json:"task_type"`
+		SchemaVersion int                      `json:"schema_version"`
+		Result        json.RawMessage          `json:"result"`
+		Commitment    *ResultCommitment        `json:"commitment"`
+		Attestation   *attestation.Attestation `json:"attestation"`
+	}
+	if err := json.Unmarshal(resp.Result, &envelope); err != nil {
+		t.Fatalf("Failed to decode result: %v", err)
+	}
+	signed := envelope.Attestation
+	if signed == nil || signed.Operator != operator.Address() || signed.TaskID != "attested" || signed.Version != "v1.2.3" || len(signed.Sources) != 1 {
+		t.Fatalf("Unexpected attestation: %+v", signed)
+	}
+
+	// the attestation covers the envelope encoded without it
+	unattested, err := canonical.Marshal(&TaskResult{TaskType: envelope.TaskType, SchemaVersion: envelope.SchemaVersion, Result: envelope.Result, Commitment: envelope.Commitment})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if err := signed.Verify(unattested); err != nil {
+		t.Errorf("Verify failed: %v", err)
+	}
+
+	// redeliveries return the stored attestation
+	again, err := performer.HandleTask(task)
+	if err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+	if string(again.Result) != string(resp.Result) {
+		t.Errorf("Expected the redelivered task to return the stored result")
+	}
+}
+
+func Test_AttestValidation(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	attesting := newDryRunPerformer(t, &fakeSimulator{bundles: make(map[uint64]int)})
+	WithAttestation(attestation.New(signer.NewKeySigner(key), "dev", nil))(attesting)
+	plain := newDryRunPerformer(t, &fakeSimulator{bundles: make(map[uint64]int)})
+
+	const monitoring = `{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1,`
+	testCases := []struct {
+		name      string
+		performer *YieldIntelligencePerformer
+		payload   string
+	}{
+		{name: "not a boolean", performer: attesting, payload: monitoring + `"attest":"yes"}}`},
+		{name: "not configured", performer: plain, payload: monitoring + `"attest":true}}`},
+		{name: "abi result", performer: attesting, payload: monitoring + `"attest":true,"result_format":"abi"}}`},
+		{name: "batch sub-task", performer: attesting, payload: `{"type":"batch","parameters":{"tasks":[
+			{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1,"attest":true}}]}}`},
+	}
+	for _, tc := range testCases {
+		task := &performerV1.TaskRequest{TaskId: []byte(tc.name), Payload: []byte(tc.payload)}
+		if err := tc.performer.ValidateTask(task); err == nil {
+			t.Errorf("%s: expected validation to fail", tc.name)
+		}
+	}
+}
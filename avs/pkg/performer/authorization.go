@@ -0,0 +1,40 @@
+package performer
+
+import (
+	"fmt"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/najnomics/crosscow-avs/pkg/auth"
+)
+
+// authorizeTask verifies the signature of a signed task, and rejects unsigned tasks of
+// types that must be signed. Batches must be signed when any of their tasks must be.
+func (yip *YieldIntelligencePerformer) authorizeTask(t *performerV1.TaskRequest, payload *TaskPayload) error {
+	raw, signature, err := auth.Unwrap(t.Payload)
+	if err != nil {
+		return newTaskError(ErrorCodeUnauthorized, err)
+	}
+
+	if signature == nil {
+		if yip.authorizer.Requires(string(payload.Type)) {
+			return newTaskError(ErrorCodeUnauthorized, fmt.Errorf("%w: %s tasks must be signed", auth.ErrUnsigned, payload.Type))
+		}
+		if payload.Type == TaskTypeBatch {
+			// malformed batches are rejected by validation
+			tasks, _ := batchTasks(payload)
+			for i, sub := range tasks {
+				if yip.authorizer.Requires(string(sub.Type)) {
+					return newTaskError(ErrorCodeUnauthorized, fmt.Errorf("%w: tasks[%d] is a %s task, which must be signed", auth.ErrUnsigned, i, sub.Type))
+				}
+			}
+		}
+		return nil
+	}
+
+	signer, err := yip.authorizer.Verify(raw, signature)
+	if err != nil {
+		return newTaskError(ErrorCodeUnauthorized, err)
+	}
+	yip.logger.Sugar().Debugw("Verified task signature", "task_id", string(t.TaskId), "signer", signer.Hex())
+	return nil
+}
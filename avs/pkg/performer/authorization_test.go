@@ -0,0 +1,79 @@
+package performer
+
+import (
+	"errors"
+	"testing"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/najnomics/crosscow-avs/pkg/auth"
+)
+
+func Test_TaskAuthorization(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	intruder, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	performer := newDryRunPerformer(t, &fakeSimulator{bundles: make(map[uint64]int)})
+	WithAuthorization(auth.NewVerifier(auth.Config{
+		Enabled:  true,
+		Signers:  []string{crypto.PubkeyToAddress(key.PublicKey).Hex()},
+		Required: []string{string(TaskTypeRebalanceExecution), string(TaskTypeRiskAssessment)},
+	}))(performer)
+
+	rebalance := []byte(`{"type":"rebalance_execution","parameters":{
+		"user_address":"0x00000000000000000000000000000000000000aa","amount":"1000000000","dry_run":true,"target_protocol":"aave_v3","target_chain":1}}`)
+	signed, err := auth.Sign(rebalance, key)
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	spoofed, err := auth.Sign(rebalance, intruder)
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	monitoring, err := auth.Sign([]byte(`{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1}}`), intruder)
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+
+	testCases := []struct {
+		name       string
+		payload    []byte
+		authorized bool
+	}{
+		{name: "signed rebalance", payload: signed, authorized: true},
+		{name: "unsigned rebalance", payload: rebalance},
+		{name: "unknown signer", payload: spoofed},
+		{name: "unsigned monitoring", payload: []byte(`{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1}}`), authorized: true},
+		{name: "signed monitoring of unknown signer", payload: monitoring},
+		{name: "unsigned batch", payload: []byte(`{"type":"batch","parameters":{"tasks":[
+			{"type":"risk_assessment","parameters":{"protocol":"aave_v3","chain_id":1}}]}}`)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			task := &performerV1.TaskRequest{TaskId: []byte(tc.name), Payload: tc.payload}
+			err := performer.ValidateTask(task)
+			if tc.authorized {
+				if err != nil {
+					t.Fatalf("Expected the task to be authorized: %v", err)
+				}
+				if _, err := performer.HandleTask(task); err != nil {
+					t.Errorf("HandleTask failed: %v", err)
+				}
+				return
+			}
+			var taskErr *TaskError
+			if !errors.As(err, &taskErr) || taskErr.Code != ErrorCodeUnauthorized {
+				t.Fatalf("Expected the task to be unauthorized, got %v", err)
+			}
+			if _, err := performer.HandleTask(task); err == nil {
+				t.Errorf("Expected HandleTask to refuse the task")
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package performer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/najnomics/crosscow-avs/pkg/logging"
+	"github.com/najnomics/crosscow-avs/pkg/workerpool"
+	"go.uber.org/zap"
+)
+
+// BatchItemStatus is the outcome of a single sub-task
+type BatchItemStatus string
+
+const (
+	BatchItemCompleted BatchItemStatus = "completed"
+	BatchItemFailed    BatchItemStatus = "failed"
+)
+
+// BatchItemResult is the result of one sub-task of a batch, in the same form as the
+// result of the task on its own
+type BatchItemResult struct {
+	Index    int             `json:"index"`
+	TaskType TaskType        `json:"task_type"`
+	Status   BatchItemStatus `json:"status"`
+	Result   interface{}     `json:"result"`
+	Error    string          `json:"error,omitempty"`
+
+	// ErrorCode classifies Error, see ErrorCode
+	ErrorCode ErrorCode `json:"error_code,omitempty"`
+}
+
+// BatchResult is the combined result of a batch task. The status is partial when any
+// sub-task failed.
+type BatchResult struct {
+	Results   []BatchItemResult `json:"results"`
+	Completed int               `json:"completed"`
+	Failed    int               `json:"failed"`
+	Status    ResultStatus      `json:"status"`
+}
+
+// batchTasks decodes the tasks parameter of a batch payload into sub-task payloads
+func batchTasks(payload *TaskPayload) ([]*TaskPayload, error) {
+	raw, ok := payload.Parameters["tasks"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("missing or invalid tasks: must be an array of payloads")
+	}
+
+	tasks := make([]*TaskPayload, len(raw))
+	for i, item := range raw {
+		encoded, err := json.Marshal(item)
+		if err != nil {
+			return nil, fmt.Errorf("tasks[%d]: %w", i, err)
+		}
+		var sub TaskPayload
+		if err := json.Unmarshal(encoded, &sub); err != nil {
+			return nil, fmt.Errorf("tasks[%d]: must be an object with type and parameters", i)
+		}
+		if sub.Parameters == nil {
+			sub.Parameters = map[string]interface{}{}
+		}
+		tasks[i] = &sub
+	}
+	return tasks, nil
+}
+
+// movesFunds reports whether payload submits transactions moving funds when run. Batches
+// cannot contain such tasks: sub-task ids are derived from the batch id, so a redelivered
+// batch would run them again past the guards against executing them twice.
+func movesFunds(payload *TaskPayload) bool {
+	switch payload.Type {
+	case TaskTypeRebalanceExecution:
+		return !paramBool(payload, "dry_run")
+	case TaskTypeRebalanceCommit, TaskTypeResumeExecution, TaskTypeTransferRecovery:
+		return true
+	}
+	return false
+}
+
+// validateBatchTask validates every sub-task. Batches cannot be nested or move funds, and
+// sub-tasks use the result format of the batch.
+func (yip *YieldIntelligencePerformer) validateBatchTask(payload *TaskPayload) error {
+	tasks, err := batchTasks(payload)
+	if err != nil {
+		return err
+	}
+	for i, sub := range tasks {
+		if sub.Type == TaskTypeBatch {
+			return fmt.Errorf("tasks[%d]: batches cannot be nested", i)
+		}
+		if movesFunds(sub) {
+			return fmt.Errorf("tasks[%d]: %s cannot be batched, as it moves funds", i, sub.Type)
+		}
+		if err := yip.checkEnabled(sub.Type); err != nil {
+			return fmt.Errorf("tasks[%d]: %w", i, err)
+		}
+		if _, present := sub.Parameters["result_format"]; present {
+			return fmt.Errorf("tasks[%d]: result_format can only be set on the batch", i)
+		}
+		if _, present := sub.Parameters["attest"]; present {
+			return fmt.Errorf("tasks[%d]: attest can only be set on the batch", i)
+		}
+		if _, present := sub.Parameters["schema_version"]; present {
+			return fmt.Errorf("tasks[%d]: schema_version can only be set on the batch", i)
+		}
+		if _, present := sub.Parameters["include_trace"]; present {
+			return fmt.Errorf("tasks[%d]: include_trace can only be set on the batch", i)
+		}
+		if _, present := sub.Parameters["fields"]; present {
+			return fmt.Errorf("tasks[%d]: fields can only be set on the batch", i)
+		}
+		if _, present := sub.Parameters["result_encoding"]; present {
+			return fmt.Errorf("tasks[%d]: result_encoding can only be set on the batch", i)
+		}
+		if err := yip.validateSchema(sub); err != nil {
+			return fmt.Errorf("tasks[%d]: %w", i, err)
+		}
+		if err := yip.validatePayload(sub); err != nil {
+			return fmt.Errorf("tasks[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// handleBatch runs every sub-task concurrently and combines their results in order.
+// A failing sub-task is reported in its slot; the batch only fails when all of them do.
+func (yip *YieldIntelligencePerformer) handleBatch(ctx context.Context, t *performerV1.TaskRequest, payload *TaskPayload) (interface{}, error) {
+	yip.log(ctx).Sugar().Infow("Processing batch task")
+
+	tasks, err := batchTasks(payload)
+	if err != nil {
+		return nil, err
+	}
+	for i, sub := range tasks {
+		if movesFunds(sub) {
+			return nil, newTaskError(ErrorCodeValidation, fmt.Errorf("tasks[%d]: %s cannot be batched, as it moves funds", i, sub.Type))
+		}
+	}
+
+	indexes := make([]int, len(tasks))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	outcomes := workerpool.Map(ctx, yip.pool, indexes, func(ctx context.Context, i int) (interface{}, error) {
+		// sub-tasks run in the lane and count against the quota of their own type
+		dequeue, err := yip.queue.Acquire(ctx, string(tasks[i].Type))
+		if err != nil {
+			return nil, err
+		}
+		defer dequeue()
+		release, err := yip.quotas.Admit(string(tasks[i].Type))
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		ctx = logging.WithLogger(ctx, yip.log(ctx).With(zap.Int("batch_index", i), zap.String("sub_task_type", string(tasks[i].Type))))
+		sub := &performerV1.TaskRequest{TaskId: []byte(fmt.Sprintf("%s/%d", t.TaskId, i))}
+		return yip.dispatch(ctx, sub, tasks[i])
+	})
+
+	result := &BatchResult{
+		Results: make([]BatchItemResult, len(tasks)),
+		Status:  ResultStatusCompleted,
+	}
+	for i, outcome := range outcomes {
+		item := BatchItemResult{Index: i, TaskType: tasks[i].Type, Status: BatchItemCompleted, Result: outcome.Value}
+		if outcome.Err != nil {
+			item.Status = BatchItemFailed
+			item.Result = nil
+			taskErr := classifyError(outcome.Err)
+			item.Error = taskErr.Err.Error()
+			item.ErrorCode = taskErr.Code
+			result.Failed++
+		} else {
+			result.Completed++
+		}
+		result.Results[i] = item
+	}
+
+	if result.Completed == 0 {
+		return nil, newTaskError(result.Results[0].ErrorCode, fmt.Errorf("all %d batch tasks failed, first error: %s", len(tasks), result.Results[0].Error))
+	}
+	if result.Failed > 0 {
+		result.Status = ResultStatusPartial
+	}
+	return result, nil
+}
@@ -0,0 +1,137 @@
+package performer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/client"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+	"go.uber.org/zap"
+)
+
+func Test_BatchTask(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+	performer := NewYieldIntelligencePerformer(logger, WithAdapters(adapters.NewRegistry(newFakeAaveAdapter())))
+
+	task := &performerV1.TaskRequest{
+		TaskId: []byte("batch-task"),
+		Payload: []byte(`{"type":"batch","parameters":{"tasks":[
+			{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1}},
+			{"type":"liquidity_depth_analysis","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1}},
+			{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":8453}}
+		]}}`),
+	}
+	if err := performer.ValidateTask(task); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	resp, err := performer.HandleTask(task)
+	if err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+
+	var envelope struct {
+		Result struct {
+			Results []struct {
+				Index    int             `json:"index"`
+				TaskType TaskType        `json:"task_type"`
+				Status   BatchItemStatus `json:"status"`
+				Result   json.RawMessage `json:"result"`
+				Error    string          `json:"error"`
+				Code     ErrorCode       `json:"error_code"`
+			} `json:"results"`
+			Completed int          `json:"completed"`
+			Failed    int          `json:"failed"`
+			Status    ResultStatus `json:"status"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(resp.Result, &envelope); err != nil {
+		t.Fatalf("Failed to decode result: %v", err)
+	}
+	result := envelope.Result
+	if result.Status != ResultStatusPartial || result.Completed != 2 || result.Failed != 1 {
+		t.Fatalf("Expected 2 completed and 1 failed sub-task, got %+v", result)
+	}
+	for i, item := range result.Results {
+		if item.Index != i {
+			t.Errorf("Expected results in task order, got index %d at %d", item.Index, i)
+		}
+	}
+	var depth LiquidityDepthResult
+	if err := json.Unmarshal(result.Results[1].Result, &depth); err != nil || depth.Protocol != adapters.ProtocolAaveV3 {
+		t.Errorf("Expected the liquidity depth result in slot 1, got %s", result.Results[1].Result)
+	}
+	if failed := result.Results[2]; failed.Status != BatchItemFailed || failed.Error == "" || failed.Code != ErrorCodeValidation {
+		t.Errorf("Expected the unsupported chain to fail, got %+v", result.Results[2])
+	}
+}
+
+func Test_BatchTaskValidation(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+	performer := NewYieldIntelligencePerformer(logger, WithAdapters(adapters.NewRegistry(newFakeAaveAdapter())))
+
+	monitoring := `{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1}}`
+	testCases := map[string]string{
+		"empty":         `[]`,
+		"nested":        `[{"type":"batch","parameters":{"tasks":[` + monitoring + `]}}]`,
+		"invalid task":  `[{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","token":"DAI","chain_id":1}}]`,
+		"result format": `[{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1,"result_format":"abi"}}]`,
+		"too large":     `[` + strings.Repeat(monitoring+",", client.MaxBatchSize) + monitoring + `]`,
+	}
+
+	for name, tasks := range testCases {
+		t.Run(name, func(t *testing.T) {
+			task := &performerV1.TaskRequest{
+				TaskId:  []byte(fmt.Sprintf("batch-%s", name)),
+				Payload: []byte(`{"type":"batch","parameters":{"tasks":` + tasks + `}}`),
+			}
+			if err := performer.ValidateTask(task); err == nil {
+				t.Errorf("Expected the batch to be rejected")
+			}
+		})
+	}
+}
+
+func Test_BatchedRebalancesRefusedOnRedelivery(t *testing.T) {
+	performer, account, ethereum := newSubmittingPerformer(t, big.NewInt(0), big.NewInt(1_000_000_000))
+	ethereum.AutoMine()
+	ctx := context.Background()
+	tasks := store.NewTaskStore(store.NewMemoryKV())
+	WithTaskStore(tasks)(performer)
+
+	task := &performerV1.TaskRequest{
+		TaskId: []byte("batch-rebalance"),
+		Payload: []byte(`{"type":"batch","parameters":{"tasks":[
+			{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1}},
+			{"type":"rebalance_execution","parameters":{"user_address":"` + account.Hex() + `","nonce":"1","amount":"1000000000","target_protocol":"aave_v3","target_chain":1}}
+		]}}`),
+	}
+	if err := performer.ValidateTask(task); err == nil || !strings.Contains(err.Error(), "tasks[1]") {
+		t.Errorf("Expected the batched rebalance to be rejected, got %v", err)
+	}
+
+	// a delivery bypassing validation, then its redelivery after a crash mid-execution
+	if _, err := performer.HandleTask(task); err == nil {
+		t.Errorf("Expected the batched rebalance to be refused")
+	}
+	if err := tasks.MarkProcessing(ctx, "batch-rebalance"); err != nil {
+		t.Fatalf("MarkProcessing failed: %v", err)
+	}
+	if _, err := performer.HandleTask(task); err == nil {
+		t.Errorf("Expected the redelivered batch to be refused")
+	}
+	if sent := ethereum.Sent(); len(sent) != 0 {
+		t.Errorf("Expected no transactions, got %d", len(sent))
+	}
+}
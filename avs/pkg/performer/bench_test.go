@@ -0,0 +1,74 @@
+package performer
+
+import (
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"testing"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/najnomics/crosscow-avs/pkg/adapters/adapterstest"
+	"go.uber.org/zap"
+)
+
+// benchmarkTasks are payloads of the task types answered from market state alone, which
+// the stub adapters serve without latency, so benchmarks measure the handlers
+var benchmarkTasks = map[string]string{
+	"yield_monitoring":         `{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1}}`,
+	"yield_monitoring_all":     `{"type":"yield_monitoring","parameters":{"protocol":"all","token":"USDC"}}`,
+	"cross_chain_yield_check":  `{"type":"cross_chain_yield_check","parameters":{"source_chain":1,"target_chain":8453,"amount":"10000000000"}}`,
+	"yield_ranking":            `{"type":"yield_ranking","parameters":{"token":"USDC"}}`,
+	"liquidity_depth_analysis": `{"type":"liquidity_depth_analysis","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1}}`,
+	"allocation_optimization":  `{"type":"allocation_optimization","parameters":{"token":"USDC","amount":"100000000000"}}`,
+	"full_market_snapshot":     `{"type":"full_market_snapshot","parameters":{"token":"USDC"}}`,
+	"capabilities":             `{"type":"capabilities","parameters":{}}`,
+}
+
+// runBenchmarkTask validates and handles payload as the Ponos server does
+func runBenchmarkTask(b *testing.B, performer *YieldIntelligencePerformer, taskID, payload string) {
+	task := &performerV1.TaskRequest{TaskId: []byte(taskID), Payload: []byte(payload)}
+	if err := performer.ValidateTask(task); err != nil {
+		b.Fatalf("ValidateTask failed: %v", err)
+	}
+	if _, err := performer.HandleTask(task); err != nil {
+		b.Fatalf("HandleTask failed: %v", err)
+	}
+}
+
+func BenchmarkHandleTask(b *testing.B) {
+	names := make([]string, 0, len(benchmarkTasks))
+	for name := range benchmarkTasks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		b.Run(name, func(b *testing.B) {
+			performer := NewYieldIntelligencePerformer(zap.NewNop(), WithAdapters(adapterstest.NewRegistry(0)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				runBenchmarkTask(b, performer, fmt.Sprintf("%s-%d", name, i), benchmarkTasks[name])
+			}
+		})
+	}
+}
+
+// BenchmarkHandleTaskParallel handles the benchmark tasks in turn from GOMAXPROCS
+// goroutines, measuring how handlers contend on the shared state of the performer
+func BenchmarkHandleTaskParallel(b *testing.B) {
+	payloads := make([]string, 0, len(benchmarkTasks))
+	for name, payload := range benchmarkTasks {
+		// the allocation optimizer takes most of the time of a mixed run
+		if name != "allocation_optimization" {
+			payloads = append(payloads, payload)
+		}
+	}
+	performer := NewYieldIntelligencePerformer(zap.NewNop(), WithAdapters(adapterstest.NewRegistry(0)))
+	var n atomic.Int64
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			i := n.Add(1)
+			runBenchmarkTask(b, performer, fmt.Sprintf("parallel-%d", i), payloads[int(i)%len(payloads)])
+		}
+	})
+}
@@ -0,0 +1,127 @@
+package performer
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/bridge"
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+	"github.com/najnomics/crosscow-avs/pkg/cctp"
+	"github.com/najnomics/crosscow-avs/pkg/tokens"
+	"github.com/najnomics/crosscow-avs/pkg/txmgr"
+)
+
+// acrossRisk is what a rebalance falling back to Across accepts
+const acrossRisk = "funds bridge through Across instead of CCTP: a relayer fills the transfer on the target chain and is repaid once UMA's optimistic oracle settles it, so delivery relies on relayers and the oracle rather than on Circle alone; deposits not filled by their deadline are refunded on the source chain"
+
+// BridgeFallback records that a rebalance bridged through another bridge than CCTP,
+// because a burn of the performer waited for Circle's attestation past the SLA.
+// RiskAcknowledgment states the risk the rebalance took on by doing so.
+type BridgeFallback struct {
+	Bridge             string            `json:"bridge"`
+	Trust              bridge.Trust      `json:"trust"`
+	DelayedBurn        string            `json:"delayed_burn"`
+	DelaySeconds       uint64            `json:"delay_seconds"`
+	SLASeconds         uint64            `json:"sla_seconds"`
+	FeeBps             uint64            `json:"fee_bps"`
+	Fee                canonical.Decimal `json:"fee"`
+	RiskAcknowledgment string            `json:"risk_acknowledgment"`
+}
+
+// checkAttestations moves route off CCTP while a burn sent on its source chain is
+// unattested past the SLA, so no more funds are burned that Circle is slow to mint. When
+// the fallback fee exceeds the max slippage of route, the rebalance is refused until
+// attestations catch up. Routes keep CCTP when the attestation service cannot be read.
+func (yip *YieldIntelligencePerformer) checkAttestations(ctx context.Context, route *rebalanceRoute) (*BridgeFallback, error) {
+	if !route.crossChain() || yip.burns == nil {
+		return nil, nil
+	}
+	burn, err := yip.burns.Delayed(ctx, route.sourceChain)
+	if err != nil {
+		yip.log(ctx).Sugar().Warnw("Failed to check CCTP attestations", "chainId", route.sourceChain, "error", err)
+		return nil, nil
+	}
+	if burn == nil {
+		return nil, nil
+	}
+
+	delay := time.Since(burn.SentAt).Truncate(time.Second)
+	delayed := fmt.Errorf("CCTP attestations on chain %d are delayed: burn %s is unattested after %s, over the %s SLA",
+		route.sourceChain, burn.TxHash.Hex(), delay, yip.burns.SLA())
+	for _, chainID := range route.chains() {
+		if _, err := bridge.AcrossSpokePool(chainID); err != nil {
+			return nil, newTaskError(ErrorCodeUpstreamUnavailable, fmt.Errorf("%w, and %w", delayed, err))
+		}
+	}
+	if yip.fallback.FeeBps > route.maxSlippageBps {
+		return nil, newTaskError(ErrorCodeUpstreamUnavailable, fmt.Errorf("%w, and the %d bps %s fee exceeds the %d bps max slippage",
+			delayed, yip.fallback.FeeBps, yip.fallback.Bridge, route.maxSlippageBps))
+	}
+
+	fee := new(big.Int).Mul(route.amount, new(big.Int).SetUint64(yip.fallback.FeeBps))
+	route.bridgeFee = fee.Div(fee, big.NewInt(txmgr.MaxBps))
+	route.fallback = &BridgeFallback{
+		Bridge:             yip.fallback.Bridge,
+		Trust:              bridge.TrustOracle,
+		DelayedBurn:        burn.TxHash.Hex(),
+		DelaySeconds:       uint64(delay / time.Second),
+		SLASeconds:         uint64(yip.burns.SLA() / time.Second),
+		FeeBps:             yip.fallback.FeeBps,
+		Fee:                usdcAmount(route.bridgeFee),
+		RiskAcknowledgment: acrossRisk,
+	}
+	yip.log(ctx).Sugar().Warnw("Falling back from CCTP", "bridge", yip.fallback.Bridge, "delayedBurn", burn.TxHash.Hex(), "delay", delay)
+	return route.fallback, nil
+}
+
+// acrossSteps plans the approval and Across deposit bridging route
+func (yip *YieldIntelligencePerformer) acrossSteps(route *rebalanceRoute) (approve, deposit *plannedStep, err error) {
+	input, err := tokens.NativeUSDC(route.sourceChain)
+	if err != nil {
+		return nil, nil, err
+	}
+	output, err := tokens.NativeUSDC(route.targetChain)
+	if err != nil {
+		return nil, nil, err
+	}
+	pool, err := bridge.AcrossSpokePool(route.sourceChain)
+	if err != nil {
+		return nil, nil, err
+	}
+	approveCall, err := adapters.ApproveCall(route.sourceChain, pool, route.amount)
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	depositCall, err := bridge.AcrossDepositCall(&bridge.AcrossDeposit{
+		SourceChainID:      route.sourceChain,
+		DestinationChainID: route.targetChain,
+		Depositor:          route.user,
+		Recipient:          route.user,
+		InputToken:         input.Address,
+		OutputToken:        output.Address,
+		InputAmount:        route.amount,
+		OutputAmount:       route.received(),
+		QuoteTimestamp:     uint32(now.Unix()),
+		FillDeadline:       uint32(now.Add(yip.fallback.FillDeadline).Unix()),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return newPlannedStep(ActionApprove, route.sourceChain, "", approveCall, false),
+		newPlannedStep(ActionBridge, route.sourceChain, "", depositCall, false), nil
+}
+
+// trackBurns hands the burns among transactions to the attestation monitor
+func (yip *YieldIntelligencePerformer) trackBurns(transactions []SubmittedTransaction) {
+	for _, tx := range transactions {
+		// burns sent in user operations have no hash before they are included
+		if tx.Action == ActionBurn && tx.Status != TransactionReverted && tx.Hash != "" {
+			yip.burns.Track(cctp.Burn{SourceChainID: tx.ChainID, TxHash: common.HexToHash(tx.Hash), SentAt: time.Now()})
+		}
+	}
+}
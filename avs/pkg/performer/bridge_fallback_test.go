@@ -0,0 +1,125 @@
+package performer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/bridge"
+	"github.com/najnomics/crosscow-avs/pkg/cctp"
+)
+
+// newAttestationService answers every burn as unattested and records the burns asked for
+func newAttestationService(t *testing.T) (*cctp.Attestations, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var asked []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		asked = append(asked, r.URL.Query().Get("transactionHash"))
+		mu.Unlock()
+		w.Write([]byte(`{"messages":[{"status":"pending_confirmations"}]}`))
+	}))
+	t.Cleanup(server.Close)
+	return cctp.NewAttestations(server.URL, time.Second, nil), func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), asked...)
+	}
+}
+
+func withDelayedBurn(t *testing.T, performer *YieldIntelligencePerformer) {
+	t.Helper()
+	attestations, _ := newAttestationService(t)
+	monitor := cctp.NewMonitor(attestations, time.Hour)
+	monitor.Track(cctp.Burn{SourceChainID: 1, TxHash: common.HexToHash("0xb0"), SentAt: time.Now().Add(-2 * time.Hour)})
+	cfg := bridge.DefaultFallbackConfig()
+	cfg.Enabled = true
+	WithBridgeFallback(cfg, monitor)(performer)
+}
+
+func Test_RebalanceFallsBackFromDelayedAttestations(t *testing.T) {
+	performer, account, ethereum := newSubmittingPerformer(t)
+	withDelayedBurn(t, performer)
+
+	result := runDryRun(t, performer, `{"type":"rebalance_execution","parameters":{
+		"user_address":"`+account.Hex()+`","nonce":"1","amount":"1000000000","source_chain":1,"target_protocol":"aave_v3","target_chain":8453}}`)
+	fallback := result.BridgeFallback
+	if fallback == nil || fallback.Bridge != bridge.Across || fallback.Trust != bridge.TrustOracle || fallback.Fee.String() != "0.500000" {
+		t.Fatalf("Expected the rebalance to fall back to Across, got %+v", fallback)
+	}
+	if fallback.DelayedBurn != common.HexToHash("0xb0").Hex() || fallback.DelaySeconds < 7200 || fallback.SLASeconds != 3600 || fallback.RiskAcknowledgment == "" {
+		t.Errorf("Unexpected fallback %+v", fallback)
+	}
+
+	execution := result.Execution
+	pool, _ := bridge.AcrossSpokePool(1)
+	sent := ethereum.Sent()
+	if len(execution.Transactions) != 2 || execution.Transactions[1].Action != ActionBridge || len(sent) != 2 || *sent[1].To() != pool {
+		t.Fatalf("Expected the approve and Across deposit to be submitted, got %+v", execution.Transactions)
+	}
+	if len(execution.PendingSteps) != 2 || execution.PendingSteps[1].Action != ActionDeposit || execution.PendingSteps[1].ChainID != adapters.ChainIDBase {
+		t.Errorf("Expected the deposit on Base to wait for the fill, got %+v", execution.PendingSteps)
+	}
+
+	// dry runs preview the fallback
+	performer, account, _ = newSubmittingPerformer(t)
+	withDelayedBurn(t, performer)
+	result = runDryRun(t, performer, `{"type":"rebalance_execution","parameters":{
+		"user_address":"`+account.Hex()+`","amount":"1000000000","source_chain":1,"target_protocol":"aave_v3","target_chain":8453,"dry_run":true}}`)
+	sim := result.Simulation
+	if result.BridgeFallback == nil || sim.BridgeFee.String() != "0.500000" || sim.AmountReceived.String() != "999.500000" || sim.SlippageBps.String() != "5.00" {
+		t.Fatalf("Expected the simulation to pay the Across fee, got %+v", sim)
+	}
+	if bridged := sim.Steps[1]; bridged.Action != ActionBridge || bridged.DurationSeconds != 12+120 {
+		t.Errorf("Unexpected bridge step %+v", bridged)
+	}
+}
+
+func Test_RebalanceRefusedWhenFallbackExceedsSlippage(t *testing.T) {
+	performer, account, ethereum := newSubmittingPerformer(t)
+	withDelayedBurn(t, performer)
+
+	task := &performerV1.TaskRequest{TaskId: []byte("slippage"), Payload: []byte(`{"type":"rebalance_execution","parameters":{
+		"user_address":"` + account.Hex() + `","nonce":"1","amount":"1000000000","source_chain":1,"target_protocol":"aave_v3","target_chain":8453,"max_slippage_bps":2}}`)}
+	if err := performer.ValidateTask(task); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	_, err := performer.HandleTask(task)
+	var taskErr *TaskError
+	if !errors.As(err, &taskErr) || taskErr.Code != ErrorCodeUpstreamUnavailable || !strings.Contains(err.Error(), "exceeds the 2 bps max slippage") {
+		t.Errorf("Expected the rebalance to wait for CCTP, got %v", err)
+	}
+	if len(ethereum.Sent()) != 0 {
+		t.Errorf("Expected no transaction to be sent")
+	}
+}
+
+func Test_RebalanceTracksBurns(t *testing.T) {
+	performer, account, ethereum := newSubmittingPerformer(t)
+	attestations, asked := newAttestationService(t)
+	monitor := cctp.NewMonitor(attestations, time.Nanosecond)
+	WithBridgeFallback(bridge.DefaultFallbackConfig(), monitor)(performer)
+
+	result := runDryRun(t, performer, `{"type":"rebalance_execution","parameters":{
+		"user_address":"`+account.Hex()+`","nonce":"1","amount":"1000000000","source_chain":1,"target_protocol":"aave_v3","target_chain":8453}}`)
+	if result.BridgeFallback != nil || len(ethereum.Sent()) != 2 {
+		t.Fatalf("Expected the rebalance to burn through CCTP, got %+v", result)
+	}
+
+	delayed, err := monitor.Delayed(context.Background(), 1)
+	if err != nil || delayed == nil || delayed.TxHash.Hex() != result.Execution.Transactions[1].Hash {
+		t.Fatalf("Expected the burn to be tracked, got %+v, %v", delayed, err)
+	}
+	if got := asked(); len(got) != 1 || got[0] != delayed.TxHash.Hex() {
+		t.Errorf("Expected the attestation of the burn to be read, got %v", got)
+	}
+}
@@ -0,0 +1,38 @@
+package performer
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/najnomics/crosscow-avs/pkg/bridge"
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+)
+
+// bridgeRoutes reports ranked routes, best first
+func bridgeRoutes(routes []bridge.Route) []BridgeRoute {
+	out := make([]BridgeRoute, 0, len(routes))
+	for _, route := range routes {
+		out = append(out, BridgeRoute{
+			Bridge:         route.Bridge,
+			Trust:          route.Trust,
+			Token:          route.Token,
+			FeeBps:         route.FeeBps,
+			Fee:            usdcAmount(route.Fee),
+			LatencySeconds: uint64(route.Latency.Seconds()),
+			FeeScore:       canonical.NewDecimalFromRat(route.Scores[bridge.DimensionFee], canonical.ScorePlaces),
+			LatencyScore:   canonical.NewDecimalFromRat(route.Scores[bridge.DimensionLatency], canonical.ScorePlaces),
+			TrustScore:     canonical.NewDecimalFromRat(route.Scores[bridge.DimensionTrust], canonical.ScorePlaces),
+			Score:          canonical.NewDecimalFromRat(route.Score, canonical.ScorePlaces),
+		})
+	}
+	return out
+}
+
+// setTransitYieldLost sets the yield amount, in USDC base units, misses at rate, an annual
+// percentage, while it is in transit on each of routes
+func setTransitYieldLost(routes []BridgeRoute, amount *big.Int, rate canonical.Decimal) {
+	for i := range routes {
+		lost, _ := transitYieldLost(amount, rate, time.Duration(routes[i].LatencySeconds)*time.Second)
+		routes[i].TransitYieldLost = &lost
+	}
+}
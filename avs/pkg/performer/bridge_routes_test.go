@@ -0,0 +1,60 @@
+package performer
+
+import (
+	"encoding/json"
+	"testing"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/bridge"
+	"github.com/najnomics/crosscow-avs/pkg/tokens"
+	"go.uber.org/zap"
+)
+
+func runCrossChainYieldCheck(t *testing.T, performer *YieldIntelligencePerformer, id, payload string) CrossChainYieldResult {
+	t.Helper()
+	task := &performerV1.TaskRequest{TaskId: []byte(id), Payload: []byte(payload)}
+	if err := performer.ValidateTask(task); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	resp, err := performer.HandleTask(task)
+	if err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+	var envelope struct {
+		Result CrossChainYieldResult `json:"result"`
+	}
+	if err := json.Unmarshal(resp.Result, &envelope); err != nil {
+		t.Fatalf("Failed to decode result: %v", err)
+	}
+	return envelope.Result
+}
+
+func Test_CrossChainYieldCheckRoutes(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+	performer := NewYieldIntelligencePerformer(logger, WithAdapters(adapters.NewRegistry(newFakeAaveAdapter())))
+
+	// CCTP does not reach Polygon PoS, whose canonical bridge mints bridged USDC
+	result := runCrossChainYieldCheck(t, performer, "polygon", `{"type":"cross_chain_yield_check","parameters":{"source_chain":1,"target_chain":137,"amount":"5000000000"}}`)
+	if len(result.Routes) != 1 || result.Route == nil || result.Route.Bridge != "polygon_pos" {
+		t.Fatalf("Expected the Polygon PoS bridge as the only route, got %+v", result.Routes)
+	}
+	if route := result.Route; route.Trust != bridge.TrustValidators || route.Token != tokens.KindBridged || route.LatencySeconds != 1800 || route.Score.String() != "24.06" {
+		t.Errorf("Unexpected route %+v", route)
+	}
+
+	// no known bridge connects Polygon PoS and Base
+	result = runCrossChainYieldCheck(t, performer, "unbridged", `{"type":"cross_chain_yield_check","parameters":{"source_chain":137,"target_chain":8453,"amount":"5000000000"}}`)
+	if result.Route != nil || result.Routes == nil || len(result.Routes) != 0 {
+		t.Errorf("Expected no route, got %+v", result.Routes)
+	}
+
+	WithBridgeRoutes(bridge.Config{})(performer)
+	result = runCrossChainYieldCheck(t, performer, "disabled", `{"type":"cross_chain_yield_check","parameters":{"source_chain":1,"target_chain":8453,"amount":"5000000000"}}`)
+	if result.Route != nil || len(result.Routes) != 0 {
+		t.Errorf("Expected no route with route comparison disabled, got %+v", result.Routes)
+	}
+}
@@ -0,0 +1,154 @@
+package performer
+
+import (
+	"context"
+	"sort"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/najnomics/crosscow-avs/pkg/features"
+	"github.com/najnomics/crosscow-avs/pkg/schema"
+)
+
+// BuildInfo identifies the release of the performer binary
+type BuildInfo struct {
+	Version string
+	Commit  string
+}
+
+// WithBuildInfo sets the release capabilities tasks report. Defaults to version "dev"
+// without a commit.
+func WithBuildInfo(info BuildInfo) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.build = info
+	}
+}
+
+// taskTypes lists every task type the performer knows, in the order they were added
+var taskTypes = []TaskType{
+	TaskTypeYieldMonitoring,
+	TaskTypeCrossChainYieldCheck,
+	TaskTypeRebalanceExecution,
+	TaskTypeRiskAssessment,
+	TaskTypeLiquidityDepthAnalysis,
+	TaskTypeDepegMonitoring,
+	TaskTypeAPYForecast,
+	TaskTypeBatch,
+	TaskTypeAllocationOptimization,
+	TaskTypeProtocolIncidentCheck,
+	TaskTypePositionReconciliation,
+	TaskTypeYieldRanking,
+	TaskTypeRebalancePlan,
+	TaskTypeRebalanceCommit,
+	TaskTypeResumeExecution,
+	TaskTypeCapabilities,
+	TaskTypeFullMarketSnapshot,
+	TaskTypePerformanceReport,
+	TaskTypeAccrualVerification,
+	TaskTypeSelfReport,
+	TaskTypeStressScenario,
+	TaskTypeTransferRecovery,
+}
+
+// AdapterCapability is an enabled protocol adapter and the chains it reads markets on
+type AdapterCapability struct {
+	Protocol string   `json:"protocol"`
+	ChainIDs []uint64 `json:"chain_ids"`
+}
+
+// CapabilityFeatures reports the optional parameters the performer honours
+type CapabilityFeatures struct {
+	// DryRuns is set when rebalances with dry_run are simulated
+	DryRuns bool `json:"dry_runs"`
+
+	// Attestation is set when tasks may set attest
+	Attestation bool `json:"attestation"`
+
+	// BlockPinning is set when single chain reads may set block_number or block_hash
+	BlockPinning bool `json:"block_pinning"`
+
+	// Submission is set when rebalances are signed and submitted from the performer's
+	// own account rather than through Circle Wallets
+	Submission bool `json:"submission"`
+
+	// UserOperations is set when rebalances may set execution to user_operation to be
+	// sent through the user's ERC-4337 account
+	UserOperations bool `json:"user_operations"`
+}
+
+// CapabilitiesResult is the result of a capabilities task: the release of the performer
+// and what it serves, so aggregators route tasks only to operators able to run them.
+// Task types missing a dependency on this performer, such as position tracking for
+// position_reconciliation, are left out.
+type CapabilitiesResult struct {
+	Version             string              `json:"version"`
+	Commit              string              `json:"commit"`
+	OldestSchemaVersion int                 `json:"oldest_schema_version"`
+	SchemaVersion       int                 `json:"schema_version"`
+	TaskTypes           []TaskType          `json:"task_types"`
+	Adapters            []AdapterCapability `json:"adapters"`
+	ChainIDs            []uint64            `json:"chain_ids"`
+	Features            CapabilityFeatures  `json:"features"`
+	Status              ResultStatus        `json:"status"`
+}
+
+// validateCapabilitiesTask accepts every capabilities task, which takes no parameters
+func (yip *YieldIntelligencePerformer) validateCapabilitiesTask(payload *TaskPayload) error {
+	return nil
+}
+
+// handleCapabilities reports the release of the performer, the task types it serves and
+// the adapters and chains it reads. Disabled adapters are left out.
+func (yip *YieldIntelligencePerformer) handleCapabilities(ctx context.Context, t *performerV1.TaskRequest, payload *TaskPayload) (interface{}, error) {
+	yip.log(ctx).Sugar().Infow("Processing capabilities task")
+
+	result := &CapabilitiesResult{
+		Version:             yip.build.Version,
+		Commit:              yip.build.Commit,
+		OldestSchemaVersion: schema.Oldest,
+		SchemaVersion:       schema.Current,
+		TaskTypes:           yip.supportedTaskTypes(),
+		Adapters:            []AdapterCapability{},
+		ChainIDs:            []uint64{},
+		Features: CapabilityFeatures{
+			DryRuns:        yip.simulator != nil,
+			Attestation:    yip.attester != nil,
+			BlockPinning:   yip.snapshots != nil,
+			Submission:     yip.transactions != nil,
+			UserOperations: yip.userOperations != nil,
+		},
+		Status: ResultStatusCompleted,
+	}
+	chains := make(map[uint64]bool)
+	for _, m := range yip.markets() {
+		if n := len(result.Adapters); n == 0 || result.Adapters[n-1].Protocol != m.protocol {
+			result.Adapters = append(result.Adapters, AdapterCapability{Protocol: m.protocol, ChainIDs: []uint64{}})
+		}
+		adapter := &result.Adapters[len(result.Adapters)-1]
+		adapter.ChainIDs = append(adapter.ChainIDs, m.chainID)
+		if !chains[m.chainID] {
+			chains[m.chainID] = true
+			result.ChainIDs = append(result.ChainIDs, m.chainID)
+		}
+	}
+	sort.Slice(result.ChainIDs, func(i, j int) bool { return result.ChainIDs[i] < result.ChainIDs[j] })
+	return result, nil
+}
+
+// supportedTaskTypes lists the task types the performer has the dependencies to serve,
+// and the operator enabled when they are experimental
+func (yip *YieldIntelligencePerformer) supportedTaskTypes() []TaskType {
+	supported := make([]TaskType, 0, len(taskTypes))
+	for _, taskType := range taskTypes {
+		switch {
+		case !yip.features.Enabled(features.TaskType(string(taskType))):
+			continue
+		case taskType == TaskTypeDepegMonitoring && len(yip.prices) == 0,
+			taskType == TaskTypePositionReconciliation && yip.positions == nil,
+			taskType == TaskTypeResumeExecution && yip.transactions == nil,
+			taskType == TaskTypeTransferRecovery && yip.transactions == nil:
+			continue
+		}
+		supported = append(supported, taskType)
+	}
+	return supported
+}
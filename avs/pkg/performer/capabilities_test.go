@@ -0,0 +1,83 @@
+package performer
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/schema"
+)
+
+func Test_Capabilities(t *testing.T) {
+	performer := newAllocationPerformer(t)
+	WithBuildInfo(BuildInfo{Version: "v1.4.0", Commit: "4111b30"})(performer)
+
+	raw, err := performer.RunTask(context.Background(), string(TaskTypeCapabilities), "capabilities-1", json.RawMessage(`{}`))
+	if err != nil {
+		t.Fatalf("RunTask failed: %v", err)
+	}
+	var envelope struct {
+		Result CapabilitiesResult `json:"result"`
+	}
+	if err := json.Unmarshal(raw, &envelope); err != nil {
+		t.Fatalf("Failed to decode result: %v", err)
+	}
+	result := envelope.Result
+
+	if result.Version != "v1.4.0" || result.Commit != "4111b30" {
+		t.Errorf("Expected the build info, got %s at %s", result.Version, result.Commit)
+	}
+	if result.OldestSchemaVersion != schema.Oldest || result.SchemaVersion != schema.Current {
+		t.Errorf("Expected schema versions %d to %d, got %d to %d", schema.Oldest, schema.Current, result.OldestSchemaVersion, result.SchemaVersion)
+	}
+	wantAdapters := []AdapterCapability{
+		{Protocol: adapters.ProtocolAaveV3, ChainIDs: []uint64{1, adapters.ChainIDBase}},
+		{Protocol: adapters.ProtocolCompoundV3, ChainIDs: []uint64{1}},
+	}
+	if !reflect.DeepEqual(result.Adapters, wantAdapters) {
+		t.Errorf("Expected adapters %+v, got %+v", wantAdapters, result.Adapters)
+	}
+	if !reflect.DeepEqual(result.ChainIDs, []uint64{1, adapters.ChainIDBase}) {
+		t.Errorf("Expected chains 1 and %d, got %v", adapters.ChainIDBase, result.ChainIDs)
+	}
+
+	served := make(map[TaskType]bool)
+	for _, taskType := range result.TaskTypes {
+		served[taskType] = true
+	}
+	if !served[TaskTypeYieldMonitoring] || !served[TaskTypeCapabilities] {
+		t.Errorf("Expected monitoring and capabilities to be served, got %v", result.TaskTypes)
+	}
+	// no price sources, position tracking or account are configured
+	for _, taskType := range []TaskType{TaskTypeDepegMonitoring, TaskTypePositionReconciliation, TaskTypeResumeExecution} {
+		if served[taskType] {
+			t.Errorf("Expected %s to be left out, got %v", taskType, result.TaskTypes)
+		}
+	}
+	if result.Features != (CapabilityFeatures{}) {
+		t.Errorf("Expected no optional features, got %+v", result.Features)
+	}
+
+	if err := performer.adapters.SetEnabled(adapters.ProtocolCompoundV3, false); err != nil {
+		t.Fatalf("SetEnabled failed: %v", err)
+	}
+	raw, _ = performer.RunTask(context.Background(), string(TaskTypeCapabilities), "capabilities-2", json.RawMessage(`{}`))
+	if strings.Contains(string(raw), adapters.ProtocolCompoundV3) {
+		t.Errorf("Expected the disabled adapter to be left out, got %s", raw)
+	}
+}
+
+// Test_CapabilitiesListEveryTaskType keeps the task types capabilities report in step
+// with those the performer validates
+func Test_CapabilitiesListEveryTaskType(t *testing.T) {
+	performer := newAllocationPerformer(t)
+	for _, taskType := range taskTypes {
+		err := performer.validatePayload(&TaskPayload{Type: taskType, Parameters: map[string]interface{}{}})
+		if err != nil && strings.Contains(err.Error(), "unknown task type") {
+			t.Errorf("Expected %s to be a known task type", taskType)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package performer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+	"github.com/najnomics/crosscow-avs/pkg/tracing"
+)
+
+// capChangeLookback is how many blocks timelocks are scanned for queued cap changes,
+// about a week on Ethereum and longer than the delay of the timelocks governing markets
+const capChangeLookback = 50_000
+
+// SupplyCapReport is the supply cap of a market and the USDC that can still be deposited
+// under it. Cap and headroom are null when the market is uncapped. With pending changes
+// read, the headroom is that under the lowest of the current and queued caps, as a
+// queued cut may execute before funds are withdrawn.
+type SupplyCapReport struct {
+	Cap            *canonical.Decimal `json:"cap"`
+	Headroom       *canonical.Decimal `json:"headroom"`
+	PendingChanges []PendingCapChange `json:"pending_changes,omitempty"`
+}
+
+// PendingCapChange is a supply cap change governance queued in a timelock. Cap is null
+// when the change lifts the cap; ETA is the earliest time it can execute.
+type PendingCapChange struct {
+	Cap      *canonical.Decimal `json:"cap"`
+	ETA      uint64             `json:"eta"`
+	Timelock string             `json:"timelock"`
+	TxHash   string             `json:"tx_hash"`
+}
+
+// supplyCapStatus reports the supply cap of m, where supply USDC base units are deposited,
+// and returns the headroom in base units, nil when m is uncapped. With withPending set,
+// cap changes queued by governance lower the headroom. Protocols that do not report risk
+// data are reported uncapped with no report.
+func (yip *YieldIntelligencePerformer) supplyCapStatus(ctx context.Context, m market, supply *big.Int, withPending bool) (*SupplyCapReport, *big.Int, error) {
+	risk, err := yip.readRiskState(ctx, m)
+	if errors.Is(err, adapters.ErrNoRiskData) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s caps on chain %d: %w", m.protocol, m.chainID, err)
+	}
+
+	limit := risk.SupplyCap
+	report := &SupplyCapReport{Cap: optionalUSDC(risk.SupplyCap)}
+	if withPending {
+		changes, err := yip.pendingCapChanges(ctx, m)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read %s queued cap changes on chain %d: %w", m.protocol, m.chainID, err)
+		}
+		for _, change := range changes {
+			report.PendingChanges = append(report.PendingChanges, PendingCapChange{
+				Cap:      optionalUSDC(change.SupplyCap),
+				ETA:      change.Call.ETA,
+				Timelock: change.Call.Timelock.Hex(),
+				TxHash:   change.Call.TxHash.Hex(),
+			})
+			// a queued cut may execute before funds arrive, a queued raise is not counted on
+			if change.SupplyCap != nil && (limit == nil || change.SupplyCap.Cmp(limit) < 0) {
+				limit = change.SupplyCap
+			}
+		}
+	}
+	if limit == nil {
+		return report, nil, nil
+	}
+
+	headroom := new(big.Int).Sub(limit, supply)
+	if headroom.Sign() < 0 {
+		headroom.SetInt64(0)
+	}
+	report.Headroom = optionalUSDC(headroom)
+	return report, headroom, nil
+}
+
+// pendingCapChanges returns the cap changes queued for m, none for protocols that do not
+// report them
+func (yip *YieldIntelligencePerformer) pendingCapChanges(ctx context.Context, m market) ([]adapters.CapChange, error) {
+	reader, err := yip.adapters.CapChangeReaderFor(m.protocol)
+	if errors.Is(err, adapters.ErrNoCapChanges) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	ctx, span := startAdapterSpan(ctx, "PendingCapChanges", m)
+	changes, err := reader.PendingCapChanges(ctx, m.chainID, capChangeLookback)
+	tracing.End(span, err)
+	return changes, err
+}
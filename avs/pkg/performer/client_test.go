@@ -0,0 +1,127 @@
+package performer
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/najnomics/crosscow-avs/pkg/client"
+	"github.com/najnomics/crosscow-avs/pkg/forecast"
+	"github.com/najnomics/crosscow-avs/pkg/ledger"
+	"github.com/najnomics/crosscow-avs/pkg/pricefeed"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+	"github.com/najnomics/crosscow-avs/pkg/taskschema"
+	"github.com/najnomics/crosscow-avs/pkg/txmgr"
+)
+
+// Test_ClientPayloadsValidate keeps the SDK in step with the performer: the payloads it
+// builds validate, and its limits are the performer's
+func Test_ClientPayloadsValidate(t *testing.T) {
+	performer := newAllocationPerformer(t)
+	WithPriceSources([]pricefeed.Source{
+		&fakePriceSource{name: "chainlink:1", kind: pricefeed.SourceKindOracle, chainID: 1, price: "1.00000000"},
+	})(performer)
+	WithLedger(ledger.New(ledger.Config{Enabled: true}, store.NewMemoryKV(), nil))(performer)
+
+	zero, rate := uint64(0), 4.5
+	rebalance := client.RebalanceExecution{
+		UserAddress: "0x000000000000000000000000000000000000dEaD", Nonce: "1", Amount: "1000000000",
+		TargetProtocol: "aave_v3", TargetChain: 1, MaxSlippageBps: &zero,
+	}
+	monitoring, _ := client.Build(client.YieldMonitoring{Protocol: "aave_v3", ChainID: 1, Token: "USDC"})
+	for _, task := range []client.Task{
+		client.YieldMonitoring{Protocol: client.ProtocolAll, Token: "USDC", AnomalySigma: 3, DepositAmount: "500000000000"},
+		client.CrossChainYieldCheck{SourceChain: 1, TargetChain: 8453, Amount: "1000000000"},
+		rebalance,
+		client.RebalancePlan{RebalanceExecution: rebalance, ExpiresAt: time.Now().Add(time.Minute).Unix()},
+		client.RebalanceCommit{PlanHash: "0x" + strings.Repeat("ab", 32)},
+		client.RiskAssessment{Protocol: "aave_v3", ChainID: 1, AssessmentType: "comprehensive"},
+		client.LiquidityDepthAnalysis{Protocol: "aave_v3", ChainID: 1, Token: "USDC", MaxRateImpactBps: 50},
+		client.DepegMonitoring{Token: "USDC", ChainIDs: []uint64{1}},
+		client.APYForecast{Protocol: "aave_v3", ChainID: 1, Token: "USDC", HorizonsHours: []uint64{24, client.MaxForecastHorizonHours}, LookbackHours: client.MinForecastLookbackHours},
+		client.AllocationOptimization{Amount: "1000000000", Token: "USDC", ChainIDs: []uint64{1}, Protocols: []string{"aave_v3"}, HorizonDays: 30,
+			TransferCosts: map[uint64]string{8453: "5000000"}, RiskPenaltyBps: map[string]float64{"aave_v3": 10}, MaxShare: 0.5, StressShockBps: 2000, ConcentrationPenaltyBps: 100},
+		client.ProtocolIncidentCheck{Protocol: "aave_v3", ChainID: 1, LookbackBlocks: client.MaxIncidentLookback},
+		client.YieldRanking{Token: "USDC", ChainIDs: []uint64{1}, RiskFreeRate: &rate},
+		client.Capabilities{},
+		client.FullMarketSnapshot{Token: "USDC"},
+		client.AccrualVerification{MaxDivergenceBps: &zero, MinPeriodHours: 1},
+		client.PerformanceReport{WindowHours: client.MaxPerformanceWindowHours, UserAddress: "0x00000000000000000000000000000000000000aa"},
+		client.SelfReport{WindowHours: client.MaxSelfReportWindowHours},
+		client.StressScenario{Token: "USDC", ChainIDs: []uint64{1}, ShocksBps: []uint64{1000, client.MaxBps}, Amount: "1000000000"},
+		client.Batch{Tasks: []*client.Payload{monitoring}},
+	} {
+		payload, err := client.Build(task, client.WithSchemaVersion(1))
+		if err != nil {
+			t.Fatalf("%s: Build failed: %v", task.TaskType(), err)
+		}
+		encoded, _ := payload.Encode()
+		if err := performer.ValidateTask(&performerV1.TaskRequest{TaskId: []byte("sdk"), Payload: encoded}); err != nil {
+			t.Errorf("%s: expected %s to validate: %v", task.TaskType(), encoded, err)
+		}
+	}
+
+	// the limits the performer checks itself
+	for name, limits := range map[string][2]int{
+		"bps":             {client.MaxBps, txmgr.MaxBps},
+		"forecast sample": {client.MinForecastLookbackHours, forecast.MinSamples},
+		"plan lifetime":   {int(client.MaxPlanLifetime), int(maxPlanLifetime)},
+		"self report":     {client.MaxSelfReportWindowHours, maxSelfReportWindowHours},
+	} {
+		if limits[0] != limits[1] {
+			t.Errorf("Expected the %s limit of the client to be the performer's, got %d and %d", name, limits[0], limits[1])
+		}
+	}
+	// and those of the payload schemas
+	for name, limits := range map[string][2]int{
+		"bps":              {client.MaxBps, schemaBound(t, "rebalance_execution", "max_slippage_bps", "maximum")},
+		"batch size":       {client.MaxBatchSize, schemaBound(t, "batch", "tasks", "maxItems")},
+		"forecast horizon": {client.MaxForecastHorizonHours, schemaBound(t, "apy_forecast", "horizons_hours", "items", "maximum")},
+		"lookback":         {client.MinForecastLookbackHours, schemaBound(t, "apy_forecast", "lookback_hours", "minimum")},
+		"allocation":       {client.MaxAllocationHorizonDays, schemaBound(t, "allocation_optimization", "horizon_days", "maximum")},
+		"incident":         {client.MaxIncidentLookback, schemaBound(t, "protocol_incident_check", "lookback_blocks", "maximum")},
+		"risk free rate":   {client.MaxRiskFreeRate, schemaBound(t, "yield_ranking", "risk_free_rate", "exclusiveMaximum")},
+		"report window":    {client.MaxPerformanceWindowHours, schemaBound(t, "performance_report", "window_hours", "maximum")},
+		"self report":      {client.MaxSelfReportWindowHours, schemaBound(t, "self_report", "window_hours", "maximum")},
+		"stress shocks":    {client.MaxStressShocks, schemaBound(t, "stress_scenario", "shocks_bps", "maxItems")},
+	} {
+		if limits[0] != limits[1] {
+			t.Errorf("Expected the %s limit of the client to be the payload schema's, got %d and %d", name, limits[0], limits[1])
+		}
+	}
+	if client.ProtocolAll != ProtocolAll {
+		t.Errorf("Expected the client to monitor every protocol as the performer does")
+	}
+}
+
+// schemaBound returns the bound at path in the schema of the parameter of taskType, such
+// as its maximum
+func schemaBound(t *testing.T, taskType, parameter string, path ...string) int {
+	t.Helper()
+	document, ok := taskschema.Default().Schema(taskType)
+	if !ok {
+		t.Fatalf("No payload schema for %s", taskType)
+	}
+	var schema struct {
+		Properties struct {
+			Parameters struct {
+				Properties map[string]interface{} `json:"properties"`
+			} `json:"parameters"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(document, &schema); err != nil {
+		t.Fatalf("Failed to decode the schema of %s: %v", taskType, err)
+	}
+	value := schema.Properties.Parameters.Properties[parameter]
+	for _, key := range path {
+		node, _ := value.(map[string]interface{})
+		value = node[key]
+	}
+	bound, ok := value.(float64)
+	if !ok {
+		t.Fatalf("No %s.%s bound in the schema of %s", parameter, strings.Join(path, "."), taskType)
+	}
+	return int(bound)
+}
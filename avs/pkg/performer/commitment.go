@@ -0,0 +1,112 @@
+package performer
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/abicodec"
+	"github.com/najnomics/crosscow-avs/pkg/commitment"
+)
+
+// ResultCommitment commits to a JSON result, see package commitment. The yield hook
+// checks a single venue of the result against VenueRoot with the venue's proof, without
+// the full result posted on-chain. VenueRoot and Venues are omitted for results that do
+// not report supply rates.
+type ResultCommitment struct {
+	ResultHash common.Hash       `json:"result_hash"`
+	VenueRoot  *common.Hash      `json:"venue_root,omitempty"`
+	Venues     []VenueCommitment `json:"venues,omitempty"`
+}
+
+// VenueCommitment is the leaf of a venue in the venue tree of a result and its proof
+type VenueCommitment struct {
+	Protocol string        `json:"protocol"`
+	ChainID  uint64        `json:"chain_id"`
+	YieldBps uint64        `json:"yield_bps"`
+	Leaf     common.Hash   `json:"leaf"`
+	Proof    []common.Hash `json:"proof"`
+}
+
+// venueResult is implemented by results reporting the supply rates of venues
+type venueResult interface {
+	venueRates() []ChainMarketRate
+}
+
+func (r *YieldMonitoringResult) venueRates() []ChainMarketRate {
+	if r.SupplyRate == nil {
+		return nil
+	}
+	return []ChainMarketRate{{Protocol: r.Protocol, ChainID: r.ChainID, SupplyRate: *r.SupplyRate}}
+}
+
+func (r *MultiYieldMonitoringResult) venueRates() []ChainMarketRate {
+	var rates []ChainMarketRate
+	for _, market := range r.Markets {
+		rates = append(rates, market.venueRates()...)
+	}
+	return rates
+}
+
+func (r *CrossChainYieldResult) venueRates() []ChainMarketRate {
+	return r.Markets
+}
+
+func (r *YieldRankingResult) venueRates() []ChainMarketRate {
+	rates := make([]ChainMarketRate, 0, len(r.Venues))
+	for _, venue := range r.Venues {
+		rates = append(rates, ChainMarketRate{Protocol: venue.Protocol, ChainID: venue.ChainID, SupplyRate: venue.SupplyRate})
+	}
+	return rates
+}
+
+func (r *FullMarketSnapshotResult) venueRates() []ChainMarketRate {
+	rates := make([]ChainMarketRate, 0, len(r.Markets))
+	for _, market := range r.Markets {
+		rates = append(rates, ChainMarketRate{Protocol: market.Protocol, ChainID: market.ChainID, SupplyRate: market.SupplyRate})
+	}
+	return rates
+}
+
+func (r *BatchResult) venueRates() []ChainMarketRate {
+	var rates []ChainMarketRate
+	for _, item := range r.Results {
+		if venues, ok := item.Result.(venueResult); ok {
+			rates = append(rates, venues.venueRates()...)
+		}
+	}
+	return rates
+}
+
+// commitResult builds the commitment to result as it is encoded in schema.Current
+func commitResult(result interface{}) (*ResultCommitment, error) {
+	hash, err := commitment.ResultHash(result)
+	if err != nil {
+		return nil, err
+	}
+	committed := &ResultCommitment{ResultHash: hash}
+	venues, ok := result.(venueResult)
+	if !ok {
+		return committed, nil
+	}
+	rates := venues.venueRates()
+	if len(rates) == 0 {
+		return committed, nil
+	}
+
+	leaves := make([]common.Hash, len(rates))
+	committed.Venues = make([]VenueCommitment, len(rates))
+	for i, rate := range rates {
+		yieldBps := abicodec.PercentToBasisPoints(rate.SupplyRate).Uint64()
+		leaves[i] = commitment.Leaf(commitment.Venue{
+			ProtocolID: abicodec.ProtocolId(rate.Protocol),
+			ChainID:    rate.ChainID,
+			YieldBps:   yieldBps,
+		})
+		committed.Venues[i] = VenueCommitment{Protocol: rate.Protocol, ChainID: rate.ChainID, YieldBps: yieldBps, Leaf: leaves[i]}
+	}
+	tree := commitment.NewTree(leaves)
+	root := tree.Root()
+	committed.VenueRoot = &root
+	for i := range committed.Venues {
+		committed.Venues[i].Proof, _ = tree.Proof(committed.Venues[i].Leaf)
+	}
+	return committed, nil
+}
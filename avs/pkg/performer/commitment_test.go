@@ -0,0 +1,65 @@
+package performer
+
+import (
+	"encoding/json"
+	"testing"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/najnomics/crosscow-avs/pkg/abicodec"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+	"github.com/najnomics/crosscow-avs/pkg/commitment"
+	"go.uber.org/zap"
+)
+
+func Test_ResultCommitment(t *testing.T) {
+	performer := NewYieldIntelligencePerformer(zap.NewNop(), WithAdapters(adapters.NewRegistry(newFakeAaveAdapter())))
+	resp, err := performer.HandleTask(&performerV1.TaskRequest{
+		TaskId:  []byte("committed"),
+		Payload: []byte(`{"type":"yield_ranking","parameters":{"token":"USDC"}}`),
+	})
+	if err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+	var envelope struct {
+		Result     json.RawMessage   `json:"result"`
+		Commitment *ResultCommitment `json:"commitment"`
+	}
+	if err := json.Unmarshal(resp.Result, &envelope); err != nil {
+		t.Fatalf("Failed to decode result: %v", err)
+	}
+	committed := envelope.Commitment
+	if committed == nil || committed.VenueRoot == nil || len(committed.Venues) == 0 {
+		t.Fatalf("Expected a commitment to the ranked venues, got %+v", committed)
+	}
+
+	result, err := canonical.Marshal(envelope.Result)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if committed.ResultHash != crypto.Keccak256Hash(result) {
+		t.Errorf("Expected the result hash to cover the canonical result")
+	}
+	for _, venue := range committed.Venues {
+		leaf := commitment.Leaf(commitment.Venue{ProtocolID: abicodec.ProtocolId(venue.Protocol), ChainID: venue.ChainID, YieldBps: venue.YieldBps})
+		if leaf != venue.Leaf || !commitment.Verify(*committed.VenueRoot, leaf, venue.Proof) {
+			t.Errorf("Expected venue %s on chain %d proven against the root", venue.Protocol, venue.ChainID)
+		}
+	}
+
+	resp, err = performer.HandleTask(&performerV1.TaskRequest{
+		TaskId:  []byte("uncommitted"),
+		Payload: []byte(`{"type":"risk_assessment","parameters":{"protocol":"aave_v3","chain_id":1,"assessment_type":"full"}}`),
+	})
+	if err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+	envelope.Commitment = nil
+	if err := json.Unmarshal(resp.Result, &envelope); err != nil {
+		t.Fatalf("Failed to decode result: %v", err)
+	}
+	if envelope.Commitment == nil || envelope.Commitment.VenueRoot != nil {
+		t.Errorf("Expected a result hash without venues for results without supply rates, got %+v", envelope.Commitment)
+	}
+}
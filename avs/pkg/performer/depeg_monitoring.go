@@ -0,0 +1,176 @@
+package performer
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+	"github.com/najnomics/crosscow-avs/pkg/depeg"
+	"github.com/najnomics/crosscow-avs/pkg/pricefeed"
+	"github.com/najnomics/crosscow-avs/pkg/snapshot"
+)
+
+// usdPricePlaces is the number of fractional digits USD prices are rounded to
+const usdPricePlaces = 6
+
+// PriceObservation is a single USDC price quote
+type PriceObservation struct {
+	Source       string            `json:"source"`
+	Kind         string            `json:"kind"`
+	ChainID      uint64            `json:"chain_id"`
+	Price        canonical.Decimal `json:"price"`
+	DeviationBps canonical.Decimal `json:"deviation_bps"`
+	Stale        bool              `json:"stale"`
+}
+
+// ChainDepegStatus is the depeg state of USDC on one chain
+type ChainDepegStatus struct {
+	ChainID      uint64            `json:"chain_id"`
+	Price        canonical.Decimal `json:"price"`
+	DeviationBps canonical.Decimal `json:"deviation_bps"`
+	Severity     depeg.Severity    `json:"severity"`
+
+	// BlockNumber is the reference block prices on the chain were read at, omitted when
+	// snapshots are disabled and prices were read at the latest block
+	BlockNumber uint64 `json:"block_number,omitempty"`
+}
+
+// DepegMonitoringResult is the result of a depeg_monitoring task
+type DepegMonitoringResult struct {
+	Token              string             `json:"token"`
+	Price              canonical.Decimal  `json:"price"`
+	DeviationBps       canonical.Decimal  `json:"deviation_bps"`
+	Severity           depeg.Severity     `json:"severity"`
+	SeverityScore      canonical.Decimal  `json:"severity_score"`
+	RecommendedAction  depeg.Action       `json:"recommended_action"`
+	Chains             []ChainDepegStatus `json:"chains"`
+	Observations       []PriceObservation `json:"observations"`
+	UnavailableSources []string           `json:"unavailable_sources"`
+	Status             ResultStatus       `json:"status"`
+}
+
+// handleDepegMonitoring checks the USDC price across oracles and DEX pools and grades any
+// deviation from the peg. With snapshots, the prices on each chain are read at the
+// requested block, or the latest finalized block of the chain; the sources of chains
+// whose finalized block cannot be read are unavailable.
+func (yip *YieldIntelligencePerformer) handleDepegMonitoring(ctx context.Context, t *performerV1.TaskRequest, payload *TaskPayload) (interface{}, error) {
+	yip.log(ctx).Sugar().Infow("Processing depeg monitoring task")
+
+	chainFilter := paramUint64Set(payload, "chain_ids")
+	block := blockRequest(payload)
+
+	var quotes []*pricefeed.Quote
+	unavailable := []string{}
+	pinned := make(map[uint64]context.Context)
+	blocks := make(map[uint64]uint64)
+	for _, source := range yip.prices {
+		chainID := source.ChainID()
+		if len(chainFilter) > 0 && !chainFilter[chainID] {
+			continue
+		}
+		sourceCtx, ok := pinned[chainID]
+		if !ok {
+			var err error
+			sourceCtx, blocks[chainID], err = yip.pin(ctx, chainID, block)
+			if err != nil && block != (snapshot.Request{}) {
+				return nil, err
+			}
+			if err != nil {
+				yip.log(ctx).Sugar().Warnw("Failed to resolve reference block", "chainId", chainID, "error", err)
+				sourceCtx = nil
+			}
+			pinned[chainID] = sourceCtx
+		}
+		if sourceCtx == nil {
+			unavailable = append(unavailable, source.Name())
+			continue
+		}
+		q, err := source.Quote(sourceCtx)
+		if err != nil {
+			yip.log(ctx).Sugar().Warnw("Price source unavailable", "source", source.Name(), "error", err)
+			unavailable = append(unavailable, source.Name())
+			continue
+		}
+		quotes = append(quotes, q)
+	}
+	sort.Strings(unavailable)
+
+	assessment, err := depeg.Assess(quotes, depeg.DefaultThresholds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to assess USDC peg: %w", err)
+	}
+
+	result := &DepegMonitoringResult{
+		Token:              paramString(payload, "token"),
+		Price:              usdPrice(assessment.Price),
+		DeviationBps:       bps(assessment.DeviationBps),
+		Severity:           assessment.Severity,
+		SeverityScore:      canonical.NewDecimalFromRat(assessment.Score, canonical.ScorePlaces),
+		RecommendedAction:  assessment.Action,
+		Chains:             make([]ChainDepegStatus, 0, len(assessment.Chains)),
+		Observations:       make([]PriceObservation, 0, len(quotes)),
+		UnavailableSources: unavailable,
+		Status:             ResultStatusCompleted,
+	}
+	for _, c := range assessment.Chains {
+		result.Chains = append(result.Chains, ChainDepegStatus{
+			ChainID:      c.ChainID,
+			Price:        usdPrice(c.Price),
+			DeviationBps: bps(c.DeviationBps),
+			Severity:     c.Severity,
+			BlockNumber:  blocks[c.ChainID],
+		})
+	}
+	for _, q := range quotes {
+		result.Observations = append(result.Observations, PriceObservation{
+			Source:       q.Source,
+			Kind:         string(q.Kind),
+			ChainID:      q.ChainID,
+			Price:        usdPrice(q.Price),
+			DeviationBps: bps(depeg.DeviationBps(q.Price)),
+			Stale:        q.Stale,
+		})
+	}
+	sort.Slice(result.Observations, func(i, j int) bool {
+		return result.Observations[i].Source < result.Observations[j].Source
+	})
+	return result, nil
+}
+
+func usdPrice(price *big.Rat) canonical.Decimal {
+	return canonical.NewDecimalFromRat(price, usdPricePlaces)
+}
+
+func bps(value *big.Rat) canonical.Decimal {
+	return canonical.NewDecimalFromRat(value, canonical.ScorePlaces)
+}
+
+// paramUint64Set returns the set of positive integers in an array parameter
+func paramUint64Set(payload *TaskPayload, key string) map[uint64]bool {
+	values, _ := payload.Parameters[key].([]interface{})
+	set := make(map[uint64]bool, len(values))
+	for _, v := range values {
+		if f, ok := v.(float64); ok && f > 0 {
+			set[uint64(f)] = true
+		}
+	}
+	return set
+}
+
+func (yip *YieldIntelligencePerformer) validateDepegMonitoringTask(payload *TaskPayload) error {
+	if err := validateToken(payload, paramUint64List(payload, "chain_ids")...); err != nil {
+		return err
+	}
+
+	if err := yip.validateBlockRequest(payload, len(paramUint64List(payload, "chain_ids")) == 1); err != nil {
+		return err
+	}
+
+	if len(yip.prices) == 0 {
+		return fmt.Errorf("no price sources configured")
+	}
+	return nil
+}
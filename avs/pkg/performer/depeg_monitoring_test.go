@@ -0,0 +1,195 @@
+package performer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/chain/chaintest"
+	"github.com/najnomics/crosscow-avs/pkg/depeg"
+	"github.com/najnomics/crosscow-avs/pkg/pricefeed"
+	"github.com/najnomics/crosscow-avs/pkg/snapshot"
+	"go.uber.org/zap"
+)
+
+// fakePriceSource returns a fixed price or error
+type fakePriceSource struct {
+	name    string
+	kind    pricefeed.SourceKind
+	chainID uint64
+	price   string
+	err     error
+}
+
+func (f *fakePriceSource) Name() string               { return f.name }
+func (f *fakePriceSource) Kind() pricefeed.SourceKind { return f.kind }
+func (f *fakePriceSource) ChainID() uint64            { return f.chainID }
+
+func (f *fakePriceSource) Quote(ctx context.Context) (*pricefeed.Quote, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	price, _ := new(big.Rat).SetString(f.price)
+	return &pricefeed.Quote{Source: f.name, Kind: f.kind, ChainID: f.chainID, Price: price}, nil
+}
+
+func Test_DepegMonitoring(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+	sources := []pricefeed.Source{
+		&fakePriceSource{name: "chainlink:1", kind: pricefeed.SourceKindOracle, chainID: 1, price: "0.99990000"},
+		&fakePriceSource{name: "curve_3pool:1", kind: pricefeed.SourceKindDEX, chainID: 1, price: "1.000100"},
+		&fakePriceSource{name: "uniswap_v3_usdc_usdt:1", kind: pricefeed.SourceKindDEX, chainID: 1, err: errors.New("rpc timeout")},
+		&fakePriceSource{name: "chainlink:42161", kind: pricefeed.SourceKindOracle, chainID: 42161, price: "0.97500000"},
+	}
+	performer := NewYieldIntelligencePerformer(logger, WithPriceSources(sources))
+
+	task := &performerV1.TaskRequest{
+		TaskId:  []byte("depeg-task"),
+		Payload: []byte(`{"type":"depeg_monitoring","parameters":{"token":"USDC"}}`),
+	}
+	if err := performer.ValidateTask(task); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	resp, err := performer.HandleTask(task)
+	if err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+	checkGoldenResult(t, "depeg_monitoring", resp.Result)
+
+	var envelope struct {
+		Result DepegMonitoringResult `json:"result"`
+	}
+	if err := json.Unmarshal(resp.Result, &envelope); err != nil {
+		t.Fatalf("Failed to decode result: %v", err)
+	}
+	if envelope.Result.RecommendedAction != depeg.ActionExitToNativeChain {
+		t.Errorf("Expected an arbitrum-only depeg to recommend exiting to the native chain, got %s", envelope.Result.RecommendedAction)
+	}
+	if len(envelope.Result.UnavailableSources) != 1 || envelope.Result.UnavailableSources[0] != "uniswap_v3_usdc_usdt:1" {
+		t.Errorf("Unexpected unavailable sources: %v", envelope.Result.UnavailableSources)
+	}
+
+	// Restricting to Ethereum hides the arbitrum depeg
+	task = &performerV1.TaskRequest{
+		TaskId:  []byte("depeg-task-mainnet"),
+		Payload: []byte(`{"type":"depeg_monitoring","parameters":{"token":"USDC","chain_ids":[1]}}`),
+	}
+	resp, err = performer.HandleTask(task)
+	if err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+	if err := json.Unmarshal(resp.Result, &envelope); err != nil {
+		t.Fatalf("Failed to decode result: %v", err)
+	}
+	if envelope.Result.RecommendedAction != depeg.ActionNone {
+		t.Errorf("Expected no action on a pegged mainnet, got %s", envelope.Result.RecommendedAction)
+	}
+}
+
+func Test_DepegMonitoringValidation(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+
+	unconfigured := NewYieldIntelligencePerformer(logger)
+	task := &performerV1.TaskRequest{
+		TaskId:  []byte("depeg-task"),
+		Payload: []byte(`{"type":"depeg_monitoring","parameters":{"token":"USDC"}}`),
+	}
+	if err := unconfigured.ValidateTask(task); err == nil {
+		t.Errorf("Expected task to be rejected without price sources")
+	}
+
+	performer := NewYieldIntelligencePerformer(logger, WithPriceSources([]pricefeed.Source{
+		&fakePriceSource{name: "chainlink:1", chainID: 1, price: "1"},
+	}))
+	for name, payload := range map[string]string{
+		"wrong token":      `{"type":"depeg_monitoring","parameters":{"token":"USDT"}}`,
+		"empty chain_ids":  `{"type":"depeg_monitoring","parameters":{"token":"USDC","chain_ids":[]}}`,
+		"invalid chain id": `{"type":"depeg_monitoring","parameters":{"token":"USDC","chain_ids":["base"]}}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			task := &performerV1.TaskRequest{TaskId: []byte("depeg-task"), Payload: []byte(payload)}
+			if err := performer.ValidateTask(task); err == nil {
+				t.Errorf("Expected task to be rejected")
+			}
+		})
+	}
+}
+
+func Test_DepegMonitoringSnapshots(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+	// arbitrum is not configured, so its finalized block cannot be read
+	contracts := chaintest.NewContracts(1)
+	contracts.SetHead(120, 1_700_000_000)
+	contracts.SetFinalized(100)
+	chains := chain.NewManager()
+	chains.Register(1, "ethereum", contracts)
+	cfg := snapshot.DefaultConfig()
+	cfg.Enabled = true
+	performer := NewYieldIntelligencePerformer(logger,
+		WithPriceSources([]pricefeed.Source{
+			&fakePriceSource{name: "chainlink:1", kind: pricefeed.SourceKindOracle, chainID: 1, price: "0.99990000"},
+			&fakePriceSource{name: "chainlink:42161", kind: pricefeed.SourceKindOracle, chainID: 42161, price: "0.97500000"},
+		}),
+		WithSnapshots(snapshot.New(cfg, chains)),
+	)
+
+	run := func(taskID, params string) (DepegMonitoringResult, error) {
+		task := &performerV1.TaskRequest{TaskId: []byte(taskID), Payload: []byte(`{"type":"depeg_monitoring","parameters":` + params + `}`)}
+		if err := performer.ValidateTask(task); err != nil {
+			t.Fatalf("ValidateTask failed: %v", err)
+		}
+		var envelope struct {
+			Result DepegMonitoringResult `json:"result"`
+		}
+		resp, err := performer.HandleTask(task)
+		if err != nil {
+			return envelope.Result, err
+		}
+		if err := json.Unmarshal(resp.Result, &envelope); err != nil {
+			t.Fatalf("Failed to decode result: %v", err)
+		}
+		return envelope.Result, nil
+	}
+
+	result, err := run("depeg-finalized", `{"token":"USDC"}`)
+	if err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+	if len(result.Chains) != 1 || result.Chains[0].BlockNumber != 100 {
+		t.Errorf("Expected Ethereum prices read at the finalized block 100, got %+v", result.Chains)
+	}
+	if len(result.UnavailableSources) != 1 || result.UnavailableSources[0] != "chainlink:42161" {
+		t.Errorf("Expected the arbitrum source to be unavailable, got %v", result.UnavailableSources)
+	}
+
+	result, err = run("depeg-block", `{"token":"USDC","chain_ids":[1],"block_number":90}`)
+	if err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+	if len(result.Chains) != 1 || result.Chains[0].BlockNumber != 90 {
+		t.Errorf("Expected Ethereum prices read at block 90, got %+v", result.Chains)
+	}
+
+	// a requested block that cannot be read fails the task rather than every source
+	if _, err := run("depeg-unfinalized", `{"token":"USDC","chain_ids":[1],"block_number":110}`); !errors.Is(err, snapshot.ErrNotFinalized) {
+		t.Errorf("Expected a block past the finalized head to be refused, got %v", err)
+	}
+
+	task := &performerV1.TaskRequest{TaskId: []byte("depeg-chains"), Payload: []byte(`{"type":"depeg_monitoring","parameters":{"token":"USDC","block_number":90}}`)}
+	if err := performer.ValidateTask(task); err == nil {
+		t.Errorf("Expected a block_number without a single chain to be rejected")
+	}
+}
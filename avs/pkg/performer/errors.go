@@ -0,0 +1,187 @@
+package performer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/permit"
+	"github.com/najnomics/crosscow-avs/pkg/quota"
+	"github.com/najnomics/crosscow-avs/pkg/resilience"
+	"github.com/najnomics/crosscow-avs/pkg/snapshot"
+	"github.com/najnomics/crosscow-avs/pkg/taskqueue"
+)
+
+// ErrorCode is a stable, machine-readable class of task failure. Codes are part of the
+// result schema and are never renamed.
+type ErrorCode string
+
+const (
+	// ErrorCodeValidation is a malformed task or parameters. Retrying cannot succeed.
+	ErrorCodeValidation ErrorCode = "validation_error"
+
+	// ErrorCodeUnauthorized is a task that is not signed by an authorized submitter, or
+	// whose signature is invalid
+	ErrorCodeUnauthorized ErrorCode = "unauthorized"
+
+	// ErrorCodeRateLimited is a task over the quota of its type, or arriving while the
+	// task queue of its type is full. It may succeed later.
+	ErrorCodeRateLimited ErrorCode = "rate_limited"
+
+	// ErrorCodeShuttingDown is a task received while the performer drains before it
+	// stops. Another operator or a restarted performer may run it.
+	ErrorCodeShuttingDown ErrorCode = "shutting_down"
+
+	// ErrorCodeHalted is a rebalance received while an operator or the service manager
+	// halts fund movements. It may succeed once the halt is lifted.
+	ErrorCodeHalted ErrorCode = "halted"
+
+	// ErrorCodeNotInGoodStanding is an execution task received while the operator is not
+	// registered for the operator sets of the AVS, was ejected or lost its stake. Other
+	// operators of the AVS may run it.
+	ErrorCodeNotInGoodStanding ErrorCode = "not_in_good_standing"
+
+	// ErrorCodeTaskTypeDisabled is a task of an experimental type the operator did not
+	// enable. Other operators of the AVS may run it.
+	ErrorCodeTaskTypeDisabled ErrorCode = "task_type_disabled"
+
+	// ErrorCodeUpstreamUnavailable is an RPC provider, subgraph or API that failed or
+	// timed out. The task may succeed later.
+	ErrorCodeUpstreamUnavailable ErrorCode = "upstream_unavailable"
+
+	// ErrorCodeUnprofitable is a rebalance that would not improve the yield. It is not
+	// retried until market conditions change.
+	ErrorCodeUnprofitable ErrorCode = "unprofitable"
+
+	// ErrorCodeRiskBlocked is an action refused because the market it touches is paused,
+	// frozen or otherwise unsafe
+	ErrorCodeRiskBlocked ErrorCode = "risk_blocked"
+
+	// ErrorCodePolicyViolation is a rebalance breaking a rule of the operator's policy,
+	// such as a denied protocol or a move over the maximum size. The result lists every
+	// rule broken.
+	ErrorCodePolicyViolation ErrorCode = "policy_violation"
+
+	// ErrorCodeInsufficientLiquidity is a rebalance withdrawing more than the source
+	// market holds idle. It is not retried until borrowers repay; the message recommends
+	// the largest amount that can be withdrawn.
+	ErrorCodeInsufficientLiquidity ErrorCode = "insufficient_liquidity"
+
+	// ErrorCodeExecutionFailed is a transaction that could not be submitted. It is not
+	// retried blindly, since part of the rebalance may have gone through.
+	ErrorCodeExecutionFailed ErrorCode = "execution_failed"
+
+	// ErrorCodeExecutionInProgress is a rebalance whose plan or execution another task of
+	// the operator is executing, on this replica of the performer or another. It is not
+	// retried, since that task answers it.
+	ErrorCodeExecutionInProgress ErrorCode = "execution_in_progress"
+
+	// ErrorCodeResultTooLarge is a result encoded larger than the operator bounds results
+	// of its task type to. Retrying unchanged cannot succeed; selecting fields or
+	// compressing the result may.
+	ErrorCodeResultTooLarge ErrorCode = "result_too_large"
+
+	// ErrorCodeInternal is any other failure of the performer
+	ErrorCodeInternal ErrorCode = "internal_error"
+)
+
+// Retryable reports whether a task failing with the code may succeed when sent again
+// unchanged
+func (c ErrorCode) Retryable() bool {
+	switch c {
+	case ErrorCodeUpstreamUnavailable, ErrorCodeRateLimited, ErrorCodeShuttingDown, ErrorCodeHalted, ErrorCodeInternal:
+		return true
+	}
+	return false
+}
+
+// TaskError is a task failure with the code telling callers whether to retry. Its message
+// starts with the code, so callers that only see the error text can still tell.
+type TaskError struct {
+	Code ErrorCode
+	Err  error
+}
+
+func (e *TaskError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Code, e.Err)
+}
+
+func (e *TaskError) Unwrap() error {
+	return e.Err
+}
+
+// newTaskError wraps err with code. Errors that already carry a code keep it.
+func newTaskError(code ErrorCode, err error) error {
+	var taskErr *TaskError
+	if errors.As(err, &taskErr) {
+		return err
+	}
+	return &TaskError{Code: code, Err: err}
+}
+
+// classifyError returns the TaskError of err, classifying errors without a code by their
+// cause: transient RPC and HTTP failures, open circuits and nodes that cannot serve the
+// block a task pins its reads to are upstream unavailability, unknown chains and unknown or disabled protocols are validation errors, exceeded quotas
+// are rate limits
+func classifyError(err error) *TaskError {
+	var taskErr *TaskError
+	if errors.As(err, &taskErr) {
+		return taskErr
+	}
+	switch {
+	case errors.Is(err, adapters.ErrUnsupportedProtocol), errors.Is(err, adapters.ErrUnsupportedChain),
+		errors.Is(err, adapters.ErrProtocolDisabled), errors.Is(err, permit.ErrInvalid):
+		return &TaskError{Code: ErrorCodeValidation, Err: err}
+	case errors.Is(err, quota.ErrQuotaExceeded),
+		errors.Is(err, taskqueue.ErrQueueFull), errors.Is(err, taskqueue.ErrQueueTimeout):
+		return &TaskError{Code: ErrorCodeRateLimited, Err: err}
+	case errors.Is(err, resilience.ErrCircuitOpen),
+		errors.Is(err, chain.ErrHistoricalState), errors.Is(err, snapshot.ErrNotFinalized),
+		resilience.Classify(context.Background(), err) == resilience.ClassRetryable:
+		return &TaskError{Code: ErrorCodeUpstreamUnavailable, Err: err}
+	default:
+		return &TaskError{Code: ErrorCodeInternal, Err: err}
+	}
+}
+
+// ErrorReport describes why a task failed
+type ErrorReport struct {
+	Code      ErrorCode `json:"code"`
+	Message   string    `json:"message"`
+	Retryable bool      `json:"retryable"`
+}
+
+// ErrorResult is answered in place of a result when a task ran and its outcome is a
+// refusal or failure retrying cannot change: an unprofitable, risk blocked, illiquid or
+// policy violating rebalance, or one whose transactions failed. It is stored like any
+// other result, so redeliveries get the same answer and never submit again. Violations
+// lists the policy rules a rebalance broke.
+type ErrorResult struct {
+	Error      ErrorReport       `json:"error"`
+	Violations []PolicyViolation `json:"violations,omitempty"`
+	Status     ResultStatus      `json:"status"`
+}
+
+// errorResult returns the error result of a failed task, nil when the failure is returned
+// as an error instead. ABI encoded results have no error form.
+func errorResult(payload *TaskPayload, err *TaskError) *ErrorResult {
+	switch err.Code {
+	case ErrorCodeUnprofitable, ErrorCodeRiskBlocked, ErrorCodePolicyViolation, ErrorCodeInsufficientLiquidity, ErrorCodeExecutionFailed:
+	default:
+		return nil
+	}
+	if format, _ := resultFormat(payload); format == ResultFormatABI {
+		return nil
+	}
+	result := &ErrorResult{
+		Error:  ErrorReport{Code: err.Code, Message: err.Err.Error(), Retryable: err.Code.Retryable()},
+		Status: ResultStatusFailed,
+	}
+	var violation *policyError
+	if errors.As(err.Err, &violation) {
+		result.Violations = violation.violations
+	}
+	return result
+}
@@ -0,0 +1,116 @@
+package performer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/quota"
+	"github.com/najnomics/crosscow-avs/pkg/resilience"
+	"github.com/najnomics/crosscow-avs/pkg/snapshot"
+)
+
+func Test_ClassifyError(t *testing.T) {
+	testCases := []struct {
+		name      string
+		err       error
+		code      ErrorCode
+		retryable bool
+	}{
+		{name: "coded", err: fmt.Errorf("wrapped: %w", newTaskError(ErrorCodeRiskBlocked, errors.New("paused"))), code: ErrorCodeRiskBlocked},
+		{name: "unsupported chain", err: fmt.Errorf("failed to read market: %w", adapters.ErrUnsupportedChain), code: ErrorCodeValidation},
+		{name: "open circuit", err: fmt.Errorf("rpc: %w", resilience.ErrCircuitOpen), code: ErrorCodeUpstreamUnavailable, retryable: true},
+		{name: "pruned state", err: fmt.Errorf("call failed: %w", chain.ErrHistoricalState), code: ErrorCodeUpstreamUnavailable, retryable: true},
+		{name: "unfinalized block", err: fmt.Errorf("%w: block 110", snapshot.ErrNotFinalized), code: ErrorCodeUpstreamUnavailable, retryable: true},
+		{name: "quota", err: fmt.Errorf("tasks[0]: %w", quota.ErrQuotaExceeded), code: ErrorCodeRateLimited, retryable: true},
+		{name: "timeout", err: context.DeadlineExceeded, code: ErrorCodeUpstreamUnavailable, retryable: true},
+		{name: "other", err: errors.New("unexpected"), code: ErrorCodeInternal, retryable: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			taskErr := classifyError(tc.err)
+			if taskErr.Code != tc.code || taskErr.Code.Retryable() != tc.retryable {
+				t.Errorf("Expected %s (retryable %v), got %s", tc.code, tc.retryable, taskErr.Code)
+			}
+			if !strings.HasPrefix(taskErr.Error(), string(tc.code)+": ") {
+				t.Errorf("Expected the message to start with the code, got %q", taskErr.Error())
+			}
+		})
+	}
+}
+
+func runFailedRebalance(t *testing.T, performer *YieldIntelligencePerformer, id, payload string) ErrorResult {
+	t.Helper()
+	task := &performerV1.TaskRequest{TaskId: []byte(id), Payload: []byte(payload)}
+	if err := performer.ValidateTask(task); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	resp, err := performer.HandleTask(task)
+	if err != nil {
+		t.Fatalf("Expected an error result, got %v", err)
+	}
+	var envelope struct {
+		Result ErrorResult `json:"result"`
+	}
+	if err := json.Unmarshal(resp.Result, &envelope); err != nil {
+		t.Fatalf("Failed to decode result: %v", err)
+	}
+	return envelope.Result
+}
+
+func Test_RebalanceRefusalsAreErrorResults(t *testing.T) {
+	performer, account, ethereum := newSubmittingPerformer(t)
+	aave := newMovableAave()
+	aave.risk.Frozen = true
+	WithAdapters(adapters.NewRegistry(aave))(performer)
+
+	result := runFailedRebalance(t, performer, "frozen", `{"type":"rebalance_execution","parameters":{
+		"user_address":"`+account.Hex()+`","nonce":"1","amount":"1000000000","target_protocol":"aave_v3","target_chain":1}}`)
+	if result.Status != ResultStatusFailed || result.Error.Code != ErrorCodeRiskBlocked || result.Error.Retryable {
+		t.Errorf("Expected the frozen market to block the rebalance, got %+v", result)
+	}
+
+	// the market has 10M USDC of supply cap headroom
+	aave.risk.Frozen = false
+	result = runFailedRebalance(t, performer, "capped", `{"type":"rebalance_execution","parameters":{
+		"user_address":"`+account.Hex()+`","nonce":"2","amount":"12000000000000","target_protocol":"aave_v3","target_chain":1}}`)
+	if result.Error.Code != ErrorCodeRiskBlocked || !strings.Contains(result.Error.Message, "supply cap") {
+		t.Errorf("Expected the supply cap to block the rebalance, got %+v", result)
+	}
+
+	// the uncapped source market has 20M USDC of available liquidity
+	aave.risk.SupplyCap = nil
+	result = runFailedRebalance(t, performer, "illiquid", `{"type":"rebalance_execution","parameters":{
+		"user_address":"`+account.Hex()+`","nonce":"3","amount":"25000000000000","source_protocol":"aave_v3","source_chain":8453,"target_protocol":"aave_v3","target_chain":1}}`)
+	if result.Error.Code != ErrorCodeInsufficientLiquidity || !strings.Contains(result.Error.Message, "at most 19800000.000000 USDC") {
+		t.Errorf("Expected the withdrawal to be refused with a recommended amount, got %+v", result)
+	}
+
+	// moving between markets earning the same rate only lowers it
+	result = runFailedRebalance(t, performer, "unprofitable", `{"type":"rebalance_execution","parameters":{
+		"user_address":"`+account.Hex()+`","nonce":"4","amount":"1000000000","source_protocol":"aave_v3","source_chain":8453,"target_protocol":"aave_v3","target_chain":1}}`)
+	if result.Error.Code != ErrorCodeUnprofitable || result.Error.Message == "" {
+		t.Errorf("Expected the rebalance to be unprofitable, got %+v", result)
+	}
+	if len(ethereum.Sent()) != 0 {
+		t.Errorf("Expected no transaction to be sent")
+	}
+}
+
+func Test_ExecutionFailureIsErrorResult(t *testing.T) {
+	performer, account, ethereum := newSubmittingPerformer(t)
+	ethereum.FailSends(errors.New("insufficient funds for gas"))
+
+	result := runFailedRebalance(t, performer, "unsent", `{"type":"rebalance_execution","parameters":{
+		"user_address":"`+account.Hex()+`","nonce":"5","amount":"1000000000","target_protocol":"aave_v3","target_chain":1}}`)
+	if result.Error.Code != ErrorCodeExecutionFailed || result.Error.Retryable {
+		t.Errorf("Expected the failed submission to be reported, got %+v", result)
+	}
+}
@@ -0,0 +1,103 @@
+package performer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/najnomics/crosscow-avs/pkg/redis"
+)
+
+// executionLocks keeps two tasks from executing the same rebalance plan at once. Plans
+// are locked within the replica, and leased through the Redis its replicas share when
+// there is one. Redis being down refuses executions rather than locking them locally,
+// since another replica may be executing the same plan.
+type executionLocks struct {
+	client *redis.Client
+
+	mu   sync.Mutex
+	held map[string]bool
+}
+
+func newExecutionLocks(client *redis.Client) *executionLocks {
+	return &executionLocks{client: client, held: make(map[string]bool)}
+}
+
+// executionLock is a plan locked by a task until it is released
+type executionLock struct {
+	name  string
+	lease *redis.Lease
+}
+
+type executionLockKey struct{}
+
+// lockExecution locks the plan or execution name for the task of ctx. The returned
+// context carries the lock, so the steps of the execution renew it as they progress, and
+// the returned func releases it.
+func (yip *YieldIntelligencePerformer) lockExecution(ctx context.Context, name string) (context.Context, func(), error) {
+	l := yip.locks
+	l.mu.Lock()
+	if l.held[name] {
+		l.mu.Unlock()
+		return nil, nil, newTaskError(ErrorCodeExecutionInProgress, fmt.Errorf("%s is being executed by another task", name))
+	}
+	l.held[name] = true
+	l.mu.Unlock()
+
+	lock := &executionLock{name: name}
+	release := func() {
+		if lock.lease != nil {
+			if err := lock.lease.Release(context.WithoutCancel(ctx)); err != nil {
+				yip.log(ctx).Sugar().Warnw("Failed to release execution lease", "execution", name, "error", err)
+			}
+		}
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		delete(l.held, name)
+	}
+	if ttl := l.client.ExecutionLease(); ttl > 0 {
+		lease, err := l.client.Acquire(ctx, name, ttl)
+		switch {
+		case errors.Is(err, redis.ErrLeaseHeld):
+			release()
+			return nil, nil, newTaskError(ErrorCodeExecutionInProgress, fmt.Errorf("%s is being executed by another replica", name))
+		case err != nil:
+			release()
+			return nil, nil, newTaskError(ErrorCodeUpstreamUnavailable, fmt.Errorf("failed to lease %s from the shared Redis: %w", name, err))
+		}
+		lock.lease = lease
+	}
+	return context.WithValue(ctx, executionLockKey{}, lock), release, nil
+}
+
+// renewExecutionLock renews the lease of the execution of ctx as it progresses. Losing it
+// to another replica, once the lease expired without progress, is returned as an error:
+// the execution must stop submitting. Other failures leave the lock local.
+func (yip *YieldIntelligencePerformer) renewExecutionLock(ctx context.Context) error {
+	lock, ok := ctx.Value(executionLockKey{}).(*executionLock)
+	if !ok || lock.lease == nil {
+		return nil
+	}
+	// renewals after a confirmation timed out still count
+	err := lock.lease.Renew(context.WithoutCancel(ctx))
+	switch {
+	case errors.Is(err, redis.ErrLeaseLost):
+		return newTaskError(ErrorCodeExecutionInProgress, fmt.Errorf("%s was taken over by another replica after its lease expired", lock.name))
+	case err != nil:
+		yip.log(ctx).Sugar().Warnw("Failed to renew execution lease", "execution", lock.name, "error", err)
+	}
+	return nil
+}
+
+// planLockName is the name rebalances of plan are locked under. Plans are locked without
+// their expiry, so a commit of the plan and an execution of the same rebalance exclude
+// each other.
+func planLockName(plan RebalancePlan) (string, error) {
+	plan.ExpiresAt = 0
+	hash, err := plan.Hash()
+	if err != nil {
+		return "", err
+	}
+	return "plan:" + hash.Hex(), nil
+}
@@ -0,0 +1,164 @@
+package performer
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/najnomics/crosscow-avs/pkg/redis"
+	"go.uber.org/zap"
+)
+
+// newReplicaRedis returns a client of server, as a replica of the performer sharing it
+// would use
+func newReplicaRedis(t *testing.T, server *miniredis.Miniredis) *redis.Client {
+	t.Helper()
+	cfg := redis.DefaultConfig()
+	cfg.Enabled, cfg.Addr = true, server.Addr()
+	client, err := redis.New(cfg, nil, nil)
+	if err != nil {
+		t.Fatalf("redis.New failed: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// newReplicaLocks returns execution locks leased through server
+func newReplicaLocks(t *testing.T, server *miniredis.Miniredis) *executionLocks {
+	t.Helper()
+	return newExecutionLocks(newReplicaRedis(t, server))
+}
+
+func Test_ExecutionLocksAcrossReplicas(t *testing.T) {
+	server := miniredis.RunT(t)
+	a := NewYieldIntelligencePerformer(zap.NewNop())
+	a.locks = newReplicaLocks(t, server)
+	b := NewYieldIntelligencePerformer(zap.NewNop())
+	b.locks = newReplicaLocks(t, server)
+	ctx := context.Background()
+
+	lockedCtx, unlock, err := a.lockExecution(ctx, "plan:ab")
+	if err != nil {
+		t.Fatalf("lockExecution failed: %v", err)
+	}
+	var taskErr *TaskError
+	for name, replica := range map[string]*YieldIntelligencePerformer{"same replica": a, "other replica": b} {
+		if _, _, err := replica.lockExecution(ctx, "plan:ab"); !errors.As(err, &taskErr) || taskErr.Code != ErrorCodeExecutionInProgress || taskErr.Code.Retryable() {
+			t.Errorf("%s: expected the locked plan to be refused, got %v", name, err)
+		}
+	}
+
+	// progress renews the lease, and a lease left to expire is taken over
+	server.FastForward(10 * time.Minute)
+	if err := a.renewExecutionLock(lockedCtx); err != nil {
+		t.Fatalf("renewExecutionLock failed: %v", err)
+	}
+	server.FastForward(10 * time.Minute)
+	if _, _, err := b.lockExecution(ctx, "plan:ab"); !errors.As(err, &taskErr) || taskErr.Code != ErrorCodeExecutionInProgress {
+		t.Fatalf("Expected the renewed lease to still be held, got %v", err)
+	}
+	server.FastForward(10 * time.Minute)
+	_, unlockB, err := b.lockExecution(ctx, "plan:ab")
+	if err != nil {
+		t.Fatalf("Expected the expired lease to be taken over: %v", err)
+	}
+	if err := a.renewExecutionLock(lockedCtx); !errors.As(err, &taskErr) || taskErr.Code != ErrorCodeExecutionInProgress {
+		t.Errorf("Expected the replica that lost its lease to stop, got %v", err)
+	}
+	unlock()
+	if !server.Exists("yieldavs:lease:plan:ab") {
+		t.Errorf("Expected unlocking a lost lease to leave the new holder's")
+	}
+	unlockB()
+	if server.Exists("yieldavs:lease:plan:ab") {
+		t.Errorf("Expected the lease to be released")
+	}
+
+	// while Redis is down, another replica may be executing the plan
+	server.Close()
+	for i := 0; i < 2; i++ {
+		if _, _, err := a.lockExecution(ctx, "plan:cd"); !errors.As(err, &taskErr) || taskErr.Code != ErrorCodeUpstreamUnavailable || !taskErr.Code.Retryable() {
+			t.Errorf("Expected the plan to be refused while Redis is down, got %v", err)
+		}
+	}
+	if len(a.locks.held) != 0 {
+		t.Errorf("Expected refused plans not to stay locked, got %v", a.locks.held)
+	}
+}
+
+func Test_ExecutionsRefusedWhileRedisIsDown(t *testing.T) {
+	server := miniredis.RunT(t)
+	performer, account, ethereum := newSubmittingPerformer(t)
+	performer.locks = newReplicaLocks(t, server)
+
+	var plan RebalancePlanResult
+	if err := runRebalanceTask(t, performer, "plan", planPayload(account, time.Now().Add(10*time.Minute)), &plan); err != nil {
+		t.Fatalf("Planning failed: %v", err)
+	}
+	server.Close()
+
+	var taskErr *TaskError
+	executionPayload := `{"type":"rebalance_execution","parameters":{"user_address":"` + account.Hex() + `","amount":"1000000000",
+		"source_chain":1,"target_protocol":"aave_v3","target_chain":8453,"nonce":"1"}}`
+	for name, payload := range map[string]string{
+		"commit":    commitPayload(plan.PlanHash),
+		"execution": executionPayload,
+		"resume":    `{"type":"resume_execution","parameters":{"execution_id":"0x` + strings.Repeat("ab", 32) + `"}}`,
+		"recovery":  `{"type":"transfer_recovery","parameters":{"execution_id":"0x` + strings.Repeat("ab", 32) + `"}}`,
+	} {
+		if err := runRebalanceTask(t, performer, name, payload, nil); !errors.As(err, &taskErr) || taskErr.Code != ErrorCodeUpstreamUnavailable {
+			t.Errorf("%s: expected the task to be refused while Redis is down, got %v", name, err)
+		}
+	}
+	if len(ethereum.Sent()) != 0 {
+		t.Errorf("Expected nothing to be submitted while Redis is down")
+	}
+}
+
+func Test_CommitRefusedWhileAnotherReplicaExecutes(t *testing.T) {
+	server := miniredis.RunT(t)
+	performer, account, ethereum := newSubmittingPerformer(t)
+	performer.locks = newReplicaLocks(t, server)
+	other := NewYieldIntelligencePerformer(zap.NewNop())
+	other.locks = newReplicaLocks(t, server)
+
+	var plan RebalancePlanResult
+	if err := runRebalanceTask(t, performer, "plan", planPayload(account, time.Now().Add(10*time.Minute)), &plan); err != nil {
+		t.Fatalf("Planning failed: %v", err)
+	}
+	name, err := planLockName(plan.Plan)
+	if err != nil {
+		t.Fatalf("planLockName failed: %v", err)
+	}
+	_, unlock, err := other.lockExecution(context.Background(), name)
+	if err != nil {
+		t.Fatalf("lockExecution failed: %v", err)
+	}
+
+	var taskErr *TaskError
+	err = runRebalanceTask(t, performer, "commit", commitPayload(plan.PlanHash), nil)
+	if !errors.As(err, &taskErr) || taskErr.Code != ErrorCodeExecutionInProgress || len(ethereum.Sent()) != 0 {
+		t.Fatalf("Expected the commit to be refused before submitting, got %v", err)
+	}
+	executionPayload := `{"type":"rebalance_execution","parameters":{"user_address":"` + plan.Plan.UserAddress + `","amount":"1000000000",
+		"source_chain":1,"target_protocol":"aave_v3","target_chain":8453,"nonce":"1"}}`
+	if err := runRebalanceTask(t, performer, "execution", executionPayload, nil); !errors.As(err, &taskErr) || taskErr.Code != ErrorCodeExecutionInProgress {
+		t.Errorf("Expected an execution of the same rebalance to be refused, got %v", err)
+	}
+
+	// the refused commit left the plan to be committed once the other replica is done
+	unlock()
+	var executed RebalanceExecutionResult
+	if err := runRebalanceTask(t, performer, "commit-again", commitPayload(plan.PlanHash), &executed); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if executed.PlanHash != plan.PlanHash || len(ethereum.Sent()) != 2 {
+		t.Errorf("Expected the plan to be executed, got %+v", executed)
+	}
+	if server.Exists("yieldavs:lease:" + name) {
+		t.Errorf("Expected the lease to be released once the plan executed")
+	}
+}
@@ -0,0 +1,42 @@
+package performer
+
+import (
+	"fmt"
+
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/features"
+)
+
+// experimentalTaskTypes are the task types that ship disabled, until operators enable
+// their features.TaskType flag, by what they do
+var experimentalTaskTypes = map[TaskType]string{
+	TaskTypeStressScenario: "Models how utilization shocks move the rates and withdrawability of markets",
+}
+
+// ExperimentalFeatures declares the flags of the experimental task types and adapters
+func ExperimentalFeatures() []features.Flag {
+	flags := make([]features.Flag, 0, len(experimentalTaskTypes)+len(adapters.ExperimentalProtocols))
+	for taskType, description := range experimentalTaskTypes {
+		flags = append(flags, features.Flag{Name: features.TaskType(string(taskType)), Description: description})
+	}
+	for protocol, description := range adapters.ExperimentalProtocols {
+		flags = append(flags, features.Flag{Name: features.Adapter(protocol), Description: description})
+	}
+	return flags
+}
+
+// WithFeatures sets which experimental task types are enabled. Defaults to none.
+func WithFeatures(set *features.Set) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.features = set
+	}
+}
+
+// checkEnabled refuses tasks of experimental types the operator did not enable, with an
+// error the aggregator routes to other operators on
+func (yip *YieldIntelligencePerformer) checkEnabled(taskType TaskType) error {
+	if !yip.features.Enabled(features.TaskType(string(taskType))) {
+		return newTaskError(ErrorCodeTaskTypeDisabled, fmt.Errorf("task type %s not enabled by this operator", taskType))
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package performer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/najnomics/crosscow-avs/pkg/features"
+	"go.uber.org/zap"
+)
+
+// allFeatures returns a set enabling every experimental feature
+func allFeatures() *features.Set {
+	var cfg features.Config
+	for _, flag := range ExperimentalFeatures() {
+		cfg.Enabled = append(cfg.Enabled, flag.Name)
+	}
+	// every name is declared, so enabling them cannot fail
+	set, _ := features.New(ExperimentalFeatures(), cfg)
+	return set
+}
+
+const stressScenarioTask = `{"type":"stress_scenario","parameters":{"token":"USDC","chain_ids":[1],"shocks_bps":[1000],"amount":"1000000000"}}`
+
+func Test_ExperimentalTaskTypesShipDisabled(t *testing.T) {
+	performer := newAllocationPerformer(t)
+	set, err := features.New(ExperimentalFeatures(), features.DefaultConfig())
+	if err != nil {
+		t.Fatalf("features.New failed: %v", err)
+	}
+	WithFeatures(set)(performer)
+
+	err = performer.ValidateTask(&performerV1.TaskRequest{TaskId: []byte("stress-1"), Payload: []byte(stressScenarioTask)})
+	var taskErr *TaskError
+	if !errors.As(err, &taskErr) || taskErr.Code != ErrorCodeTaskTypeDisabled || taskErr.Code.Retryable() {
+		t.Fatalf("Expected the experimental task type to be refused, got %v", err)
+	}
+	batch := `{"type":"batch","parameters":{"tasks":[` + stressScenarioTask + `]}}`
+	if err := performer.ValidateTask(&performerV1.TaskRequest{TaskId: []byte("stress-2"), Payload: []byte(batch)}); !errors.As(err, &taskErr) || taskErr.Code != ErrorCodeTaskTypeDisabled {
+		t.Errorf("Expected a batch of the experimental task type to be refused, got %v", err)
+	}
+
+	raw, err := performer.RunTask(context.Background(), string(TaskTypeCapabilities), "capabilities-1", json.RawMessage(`{}`))
+	if err != nil {
+		t.Fatalf("RunTask failed: %v", err)
+	}
+	var envelope struct {
+		Result CapabilitiesResult `json:"result"`
+	}
+	if err := json.Unmarshal(raw, &envelope); err != nil {
+		t.Fatalf("Failed to decode result: %v", err)
+	}
+	for _, taskType := range envelope.Result.TaskTypes {
+		if taskType == TaskTypeStressScenario {
+			t.Errorf("Expected the disabled task type to be left out, got %v", envelope.Result.TaskTypes)
+		}
+	}
+
+	if err := set.SetEnabled(features.TaskType(string(TaskTypeStressScenario)), true); err != nil {
+		t.Fatalf("SetEnabled failed: %v", err)
+	}
+	if err := performer.ValidateTask(&performerV1.TaskRequest{TaskId: []byte("stress-3"), Payload: []byte(stressScenarioTask)}); err != nil {
+		t.Errorf("Expected the enabled task type to be accepted: %v", err)
+	}
+}
+
+func Test_AdminSwitchesFeatures(t *testing.T) {
+	performer := newAllocationPerformer(t)
+	set, err := features.New(ExperimentalFeatures(), features.DefaultConfig())
+	if err != nil {
+		t.Fatalf("features.New failed: %v", err)
+	}
+	WithFeatures(set)(performer)
+	ts := newAdminServer(t, performer, zap.NewAtomicLevel())
+	flag := features.TaskType(string(TaskTypeStressScenario))
+
+	var listed struct {
+		Features []features.State `json:"features"`
+	}
+	adminRequest(t, http.MethodGet, ts.URL+"/features", "", &listed)
+	if len(listed.Features) != len(ExperimentalFeatures()) || listed.Features[0].Name != flag || listed.Features[0].Enabled {
+		t.Fatalf("Expected the disabled experimental features, got %+v", listed.Features)
+	}
+
+	if code := adminRequest(t, http.MethodPost, ts.URL+"/features/"+flag+"/enable", "", nil); code != http.StatusOK {
+		t.Fatalf("Expected the feature to be enabled, got %d", code)
+	}
+	if !set.Enabled(flag) {
+		t.Errorf("Expected %s to be enabled", flag)
+	}
+	adminRequest(t, http.MethodPost, ts.URL+"/features/"+flag+"/disable", "", nil)
+	if set.Enabled(flag) {
+		t.Errorf("Expected %s to be disabled", flag)
+	}
+	if code := adminRequest(t, http.MethodPost, ts.URL+"/features/task_type.euler/enable", "", nil); code != http.StatusNotFound {
+		t.Errorf("Expected an unknown flag to be not found, got %d", code)
+	}
+}
@@ -0,0 +1,72 @@
+package performer
+
+import (
+	"fmt"
+
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/flashloan"
+)
+
+// checkFlashLoan prices the flash loan of routes with the flash_loan strategy. Its
+// premium is lost to the route, so routes whose max slippage is below it are refused.
+func (yip *YieldIntelligencePerformer) checkFlashLoan(route *rebalanceRoute) error {
+	if route.strategy != flashloan.StrategyFlashLoan {
+		return nil
+	}
+	if yip.flashLoans.PremiumBps > route.maxSlippageBps {
+		return newTaskError(ErrorCodeValidation, fmt.Errorf("invalid strategy: the %d bps flash loan premium exceeds the %d bps max slippage",
+			yip.flashLoans.PremiumBps, route.maxSlippageBps))
+	}
+	route.premium = yip.flashLoans.Premium(route.amount)
+	return nil
+}
+
+// flashLoanSteps lists the transactions moving route through the flash loan helper of its
+// chain: the allowance of the premium, then the migration depositing into the target
+// before withdrawing from the source
+func (yip *YieldIntelligencePerformer) flashLoanSteps(route *rebalanceRoute) ([]*plannedStep, error) {
+	helper, err := yip.flashLoans.Helper(route.sourceChain)
+	if err != nil {
+		return nil, err
+	}
+	usdc, err := adapters.USDCAddress(route.sourceChain)
+	if err != nil {
+		return nil, err
+	}
+	source, err := yip.adapters.MoverFor(route.sourceProtocol)
+	if err != nil {
+		return nil, err
+	}
+	target, err := yip.adapters.MoverFor(route.targetProtocol)
+	if err != nil {
+		return nil, err
+	}
+	withdraw, err := source.WithdrawCall(route.sourceChain, helper, route.amount)
+	if err != nil {
+		return nil, err
+	}
+	supply, err := target.SupplyCall(route.targetChain, route.user, route.amount)
+	if err != nil {
+		return nil, err
+	}
+	migrate, err := flashloan.MigrateCall(&flashloan.Migration{
+		Helper:     helper,
+		Asset:      usdc,
+		Amount:     route.amount,
+		Owner:      route.user,
+		Withdraw:   withdraw,
+		Supply:     supply,
+		MaxPremium: route.premium,
+	})
+	if err != nil {
+		return nil, err
+	}
+	approve, err := adapters.ApproveCall(route.sourceChain, helper, route.premium)
+	if err != nil {
+		return nil, err
+	}
+	return []*plannedStep{
+		newPlannedStep(ActionApprove, route.sourceChain, "", approve, false),
+		newPlannedStep(ActionMigrate, route.targetChain, route.targetProtocol, migrate, false),
+	}, nil
+}
@@ -0,0 +1,90 @@
+package performer
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/flashloan"
+)
+
+// newMovableCompound returns a compound adapter on Ethereum paying more than aave
+func newMovableCompound(positions ...*big.Int) *movableAdapter {
+	compound := newMovableAave(positions...)
+	compound.protocol, compound.contract = adapters.ProtocolCompoundV3, common.HexToAddress("0x02")
+	market := compound.markets[1]
+	market.Protocol = adapters.ProtocolCompoundV3
+	market.Pool.TotalBorrow = usdcUnits(88_000_000)
+	return compound
+}
+
+func flashLoanConfig() flashloan.Config {
+	cfg := flashloan.DefaultConfig()
+	cfg.Enabled, cfg.Helpers = true, map[uint64]string{1: "0x0000000000000000000000000000000000000040"}
+	return cfg
+}
+
+func Test_RebalanceThroughFlashLoan(t *testing.T) {
+	performer, account, ethereum := newSubmittingPerformer(t)
+	ethereum.AutoMine()
+	WithAdapters(adapters.NewRegistry(
+		newMovableAave(usdcUnits(1000), usdcUnits(1000), new(big.Int)),
+		newMovableCompound(new(big.Int), usdcUnits(1000)),
+	))(performer)
+	WithFlashLoans(flashLoanConfig())(performer)
+
+	payload := `{"type":"rebalance_execution","parameters":{"user_address":"` + account.Hex() + `","nonce":"1","amount":"1000000000",
+		"source_protocol":"aave_v3","target_protocol":"compound_v3","target_chain":1,"strategy":"flash_loan"%s}}`
+	dryRun := runDryRun(t, performer, fmt.Sprintf(payload, `,"dry_run":true`))
+	sim := dryRun.Simulation
+	if sim == nil || sim.Strategy != flashloan.StrategyFlashLoan || len(sim.Steps) != 2 || sim.Steps[1].Action != ActionMigrate ||
+		sim.FlashLoanPremium == nil || sim.FlashLoanPremium.String() != "0.500000" || sim.SlippageBps.String() != "5.00" {
+		t.Fatalf("Expected the premium allowance and migration to be simulated, got %+v", sim)
+	}
+
+	var result RebalanceExecutionResult
+	if err := runYieldMonitoring(t, performer, "flash-loan", fmt.Sprintf(payload, ""), &result); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	execution := result.Execution
+	if result.Status != ResultStatusCompleted || execution == nil || execution.Strategy != flashloan.StrategyFlashLoan {
+		t.Fatalf("Expected a completed flash loan rebalance, got %+v", result)
+	}
+	if len(execution.Transactions) != 2 || execution.Transactions[1].Action != ActionMigrate || execution.Transactions[1].GasLimit != fallbackGas[ActionMigrate] {
+		t.Errorf("Expected the allowance and migration to be submitted, got %+v", execution.Transactions)
+	}
+	// the wallet only pays the premium, the positions move in full
+	if !execution.Verified || len(execution.BalanceChecks) != 3 || execution.BalanceChecks[1].MinChange.String() != "-1.500000" ||
+		execution.BalanceChecks[2].Change.String() != "1000.000000" {
+		t.Errorf("Expected the positions and wallet to be verified, got %+v", execution.BalanceChecks)
+	}
+}
+
+func Test_FlashLoanStrategyValidation(t *testing.T) {
+	performer := newDryRunPerformer(t, &fakeSimulator{bundles: make(map[uint64]int)})
+	WithAdapters(adapters.NewRegistry(newMovableAave(), newMovableCompound()))(performer)
+	WithFlashLoans(flashLoanConfig())(performer)
+	unconfigured := newDryRunPerformer(t, &fakeSimulator{bundles: make(map[uint64]int)})
+	WithAdapters(adapters.NewRegistry(newMovableAave(), newMovableCompound()))(unconfigured)
+
+	for name, tc := range map[string]struct {
+		performer *YieldIntelligencePerformer
+		params    string
+	}{
+		"unknown strategy": {performer: performer, params: `"source_protocol":"aave_v3","target_chain":1,"strategy":"bridge"`},
+		"cross-chain":      {performer: performer, params: `"source_protocol":"aave_v3","source_chain":1,"target_chain":8453,"strategy":"flash_loan"`},
+		"no source market": {performer: performer, params: `"target_chain":1,"strategy":"flash_loan"`},
+		"no helper":        {performer: unconfigured, params: `"source_protocol":"aave_v3","target_chain":1,"strategy":"flash_loan"`},
+	} {
+		task := &performerV1.TaskRequest{
+			TaskId:  []byte(name),
+			Payload: []byte(`{"type":"rebalance_execution","parameters":{"user_address":"0x00000000000000000000000000000000000000aa","amount":"1000000000","target_protocol":"compound_v3","dry_run":true,` + tc.params + `}}`),
+		}
+		if err := tc.performer.ValidateTask(task); err == nil {
+			t.Errorf("%s: expected the task to be rejected", name)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package performer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+	"github.com/najnomics/crosscow-avs/pkg/scan"
+	"github.com/najnomics/crosscow-avs/pkg/workerpool"
+)
+
+// SnapshotMarket is the state of one market in a full market snapshot. Rates are annual
+// percentages, amounts are in USDC.
+type SnapshotMarket struct {
+	Protocol           string            `json:"protocol"`
+	ChainID            uint64            `json:"chain_id"`
+	SupplyRate         canonical.Decimal `json:"supply_rate"`
+	Utilization        canonical.Decimal `json:"utilization"`
+	TotalSupply        canonical.Decimal `json:"total_supply"`
+	TotalBorrow        canonical.Decimal `json:"total_borrow"`
+	AvailableLiquidity canonical.Decimal `json:"available_liquidity"`
+}
+
+// FullMarketSnapshotResult is the result of a full_market_snapshot task. Comparison and
+// ranking tasks reuse the states it read until FreshUntil, which is omitted when the
+// performer does not reuse snapshots.
+type FullMarketSnapshotResult struct {
+	SnapshotID string           `json:"snapshot_id"`
+	Token      string           `json:"token"`
+	TakenAt    uint64           `json:"taken_at"`
+	FreshUntil uint64           `json:"fresh_until,omitempty"`
+	Markets    []SnapshotMarket `json:"markets"`
+	Failures   []MarketFailure  `json:"failures"`
+	Status     ResultStatus     `json:"status"`
+}
+
+// handleFullMarketSnapshot reads every registered market on every chain concurrently
+// into one snapshot, named after the task. Markets that cannot be read are reported as
+// failures and left for later tasks to read themselves.
+func (yip *YieldIntelligencePerformer) handleFullMarketSnapshot(ctx context.Context, t *performerV1.TaskRequest, payload *TaskPayload) (interface{}, error) {
+	yip.log(ctx).Sugar().Infow("Processing full market snapshot task")
+
+	markets := yip.markets()
+	if len(markets) == 0 {
+		return nil, fmt.Errorf("no markets are registered")
+	}
+
+	snapshot := &scan.Scan{
+		ID:      string(t.TaskId),
+		TakenAt: time.Now(),
+		States:  make(map[scan.Market]*adapters.MarketState, len(markets)),
+	}
+	result := &FullMarketSnapshotResult{
+		SnapshotID: snapshot.ID,
+		Token:      paramString(payload, "token"),
+		TakenAt:    uint64(snapshot.TakenAt.Unix()),
+		Markets:    []SnapshotMarket{},
+		Failures:   []MarketFailure{},
+		Status:     ResultStatusCompleted,
+	}
+	// reads bypass the previous snapshot, which is what this one replaces
+	for i, outcome := range workerpool.Map(ctx, yip.pool, markets, yip.readMarket) {
+		m := markets[i]
+		if outcome.Err != nil {
+			result.Failures = append(result.Failures, marketFailure(m, outcome.Err))
+			continue
+		}
+		state := outcome.Value
+		snapshot.States[scan.Market{Protocol: m.protocol, ChainID: m.chainID}] = state
+		result.Markets = append(result.Markets, SnapshotMarket{
+			Protocol:           m.protocol,
+			ChainID:            m.chainID,
+			SupplyRate:         ratePercent(state.Pool.SupplyRate()),
+			Utilization:        canonical.Ratio(state.Pool.Utilization()),
+			TotalSupply:        usdcAmount(state.Pool.TotalSupply),
+			TotalBorrow:        usdcAmount(state.Pool.TotalBorrow),
+			AvailableLiquidity: usdcAmount(state.Pool.AvailableLiquidity()),
+		})
+	}
+	if len(result.Markets) == 0 {
+		return nil, fmt.Errorf("failed to read any of %d markets: %s", len(markets), result.Failures[0].Error)
+	}
+	if len(result.Failures) > 0 {
+		result.Status = ResultStatusPartial
+	}
+
+	yip.scans.Put(snapshot)
+	if freshUntil := yip.scans.FreshUntil(snapshot); !freshUntil.IsZero() {
+		result.FreshUntil = uint64(freshUntil.Unix())
+	}
+	return result, nil
+}
+
+func (yip *YieldIntelligencePerformer) validateFullMarketSnapshotTask(payload *TaskPayload) error {
+	return validateToken(payload)
+}
@@ -0,0 +1,59 @@
+package performer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/scan"
+	"go.uber.org/zap"
+)
+
+func Test_FullMarketSnapshot(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+	aave := newFakeAaveAdapter()
+	base := *aave.markets[1]
+	base.ChainID = adapters.ChainIDBase
+	aave.markets[adapters.ChainIDBase] = &base
+	performer := NewYieldIntelligencePerformer(logger,
+		WithAdapters(adapters.NewRegistry(aave, &brokenAdapter{protocol: adapters.ProtocolCompoundV3, chainIDs: []uint64{1}})),
+		WithMarketScans(scan.NewStore(time.Minute)),
+	)
+
+	var snapshot FullMarketSnapshotResult
+	if err := runYieldMonitoring(t, performer, "snapshot-1", `{"type":"full_market_snapshot","parameters":{"token":"USDC"}}`, &snapshot); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	if snapshot.SnapshotID != "snapshot-1" || len(snapshot.Markets) != 2 || len(snapshot.Failures) != 1 || snapshot.Status != ResultStatusPartial {
+		t.Fatalf("Expected both aave markets and the compound failure, got %+v", snapshot)
+	}
+	if snapshot.FreshUntil != snapshot.TakenAt+60 {
+		t.Errorf("Expected the snapshot to be fresh for a minute, got %d after %d", snapshot.FreshUntil, snapshot.TakenAt)
+	}
+
+	// base pays more once the snapshot was taken, which ranking does not see until it is stale
+	busier := base
+	busier.Pool.TotalBorrow = usdcUnits(85_000_000)
+	aave.markets[adapters.ChainIDBase] = &busier
+	var ranking YieldRankingResult
+	if err := runYieldMonitoring(t, performer, "rank", `{"type":"yield_ranking","parameters":{"token":"USDC"}}`, &ranking); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	if len(ranking.Venues) != 2 {
+		t.Fatalf("Expected both aave venues, got %+v", ranking)
+	}
+	for _, venue := range ranking.Venues {
+		for _, market := range snapshot.Markets {
+			if market.ChainID == venue.ChainID && market.SupplyRate.Cmp(venue.SupplyRate) != 0 {
+				t.Errorf("Expected chain %d to be ranked at its snapshot rate %v, got %v", venue.ChainID, market.SupplyRate, venue.SupplyRate)
+			}
+		}
+	}
+
+	if err := runYieldMonitoring(t, performer, "bad", `{"type":"full_market_snapshot","parameters":{"token":"DAI"}}`, &snapshot); err == nil {
+		t.Errorf("Expected a snapshot of another token to be rejected")
+	}
+}
@@ -0,0 +1,185 @@
+package performer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/attestation"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/chain/chaintest"
+	"github.com/najnomics/crosscow-avs/pkg/ledger"
+	"github.com/najnomics/crosscow-avs/pkg/positions"
+	"github.com/najnomics/crosscow-avs/pkg/pricefeed"
+	"github.com/najnomics/crosscow-avs/pkg/signer"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+	"github.com/najnomics/crosscow-avs/pkg/txmgr"
+	"go.uber.org/zap"
+)
+
+// fuzzKey is the account of the fuzzed performer, which plans rebalance its own funds
+var fuzzKey, _ = crypto.HexToECDSA("4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318")
+
+// fuzzSeeds are valid payloads of every task type, which the fuzz targets mutate
+var fuzzSeeds = []string{
+	`{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1,"deposit_amount":"1000000000","anomaly_sigma":3}}`,
+	`{"type":"yield_monitoring","parameters":{"protocol":"all","token":"USDC","block":{"number":100}}}`,
+	`{"type":"cross_chain_yield_check","parameters":{"source_chain":1,"target_chain":8453,"amount":"5000000000","user_address":"0x00000000000000000000000000000000000000aa","profile":{"preset":"conservative"}}}`,
+	`{"type":"rebalance_execution","parameters":{"user_address":"0x00000000000000000000000000000000000000aa","amount":"1000000000","target_protocol":"aave_v3","target_chain":1,"source_chain":8453,"max_slippage_bps":50,"dry_run":true}}`,
+	`{"type":"risk_assessment","parameters":{"protocol":"aave_v3","chain_id":1,"assessment_type":"full"}}`,
+	`{"type":"liquidity_depth_analysis","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1,"max_rate_impact_bps":25}}`,
+	`{"type":"depeg_monitoring","parameters":{"token":"USDC","chain_ids":[1]}}`,
+	`{"type":"apy_forecast","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1,"deposit_amount":"10000000000","horizons_hours":[1,24,168],"lookback_hours":48}}`,
+	`{"type":"batch","parameters":{"tasks":[{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1}},{"type":"risk_assessment","parameters":{"protocol":"aave_v3","chain_id":1,"assessment_type":"full"}}]}}`,
+	`{"type":"allocation_optimization","parameters":{"token":"USDC","amount":"100000000000","horizon_days":30,"transfer_costs":{"8453":"5000000"},"risk_penalty_bps":{"aave_v3":10},"max_share":0.5}}`,
+	`{"type":"protocol_incident_check","parameters":{"protocol":"aave_v3","chain_id":1,"lookback_blocks":50}}`,
+	`{"type":"position_reconciliation","parameters":{"address":"0x00000000000000000000000000000000000000aa"}}`,
+	`{"type":"yield_ranking","parameters":{"token":"USDC","weights":{"rate":1,"sharpe":1},"risk_free_rate":4}}`,
+	`{"type":"rebalance_plan","parameters":{"user_address":"` + crypto.PubkeyToAddress(fuzzKey.PublicKey).Hex() + `","amount":"1000000000","target_protocol":"aave_v3","target_chain":1,"expires_at":` + strconv.FormatInt(time.Now().Add(30*time.Minute).Unix(), 10) + `}}`,
+	`{"type":"rebalance_commit","parameters":{"plan_hash":"0x0000000000000000000000000000000000000000000000000000000000000001"}}`,
+	`{"type":"resume_execution","parameters":{"execution_id":"0x01"}}`,
+	`{"type":"capabilities","parameters":{}}`,
+	`{"type":"full_market_snapshot","parameters":{"token":"USDC"}}`,
+	`{"type":"performance_report","parameters":{"window_hours":24}}`,
+	`{"type":"accrual_verification","parameters":{"max_divergence_bps":100,"min_period_hours":24,"user_address":"0x00000000000000000000000000000000000000aa"}}`,
+	`{"type":"self_report","parameters":{"window_hours":1}}`,
+	`{"type":"stress_scenario","parameters":{"token":"USDC","shocks_bps":[1000,5000],"amount":"1000000000"}}`,
+	`{"type":"transfer_recovery","parameters":{"execution_id":"0x01"}}`,
+	`{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1,"result_format":"abi","schema_version":5}}`,
+	`{"type":"yield_ranking","parameters":{"token":"USDC","attest":true,"include_trace":true}}`,
+}
+
+// adversarialValues are JSON values of the wrong type or range for most parameters
+var adversarialValues = []string{`null`, `true`, `-1`, `0`, `0.5`, `1e308`, `18446744073709551616`, `"x"`, `""`, `"-1"`, `[]`, `[null]`, `[-1]`, `{}`, `{"1":null}`}
+
+// newFuzzPerformer returns a performer configured for every task type, reading a fake
+// market and sending to fake chains
+func newFuzzPerformer() *YieldIntelligencePerformer {
+	chains := chain.NewManager()
+	chains.Register(1, "ethereum", chaintest.NewContracts(1))
+	chains.Register(adapters.ChainIDBase, "base", chaintest.NewContracts(adapters.ChainIDBase))
+	cfg := txmgr.DefaultConfig()
+	cfg.ConfirmationTimeout = 10 * time.Millisecond
+	cfg.PollInterval = time.Millisecond
+
+	return NewYieldIntelligencePerformer(zap.NewNop(),
+		WithAdapters(adapters.NewRegistry(newMovableAave())),
+		WithSimulator(&fakeSimulator{bundles: make(map[uint64]int)}),
+		WithPriceSources([]pricefeed.Source{&fakePriceSource{name: "chainlink:1", chainID: 1, price: "1"}}),
+		WithPositions(positions.New(positions.DefaultConfig(), store.NewMemoryKV())),
+		WithLedger(ledger.New(ledger.Config{Enabled: true}, store.NewMemoryKV(), nil)),
+		WithTransactions(txmgr.NewSet(chains, signer.NewKeySigner(fuzzKey), cfg)),
+		WithAttestation(attestation.New(signer.NewKeySigner(fuzzKey), "fuzz", nil)),
+		WithFeatures(allFeatures()),
+	)
+}
+
+// handleIfValid handles payload when the performer validates it. Errors are answers to
+// the task; panics fail the fuzz target.
+func handleIfValid(performer *YieldIntelligencePerformer, id string, payload []byte) {
+	task := &performerV1.TaskRequest{TaskId: []byte(id), Payload: payload}
+	if err := performer.ValidateTask(task); err != nil {
+		return
+	}
+	performer.HandleTask(task) //nolint:errcheck
+}
+
+func FuzzParseTaskPayload(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		payload, err := parseTaskPayload(&performerV1.TaskRequest{TaskId: []byte("fuzz"), Payload: data})
+		if err == nil && payload == nil {
+			t.Fatalf("Expected a payload or an error for %q", data)
+		}
+	})
+}
+
+// FuzzValidatedTasksAreHandled checks any payload ValidateTask accepts is handled
+// without panicking
+func FuzzValidatedTasksAreHandled(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add([]byte(seed))
+	}
+	performer := newFuzzPerformer()
+	var n atomic.Int64
+	f.Fuzz(func(t *testing.T, data []byte) {
+		handleIfValid(performer, fmt.Sprintf("fuzz-%d", n.Add(1)), data)
+	})
+}
+
+// FuzzTaskParameters sets one parameter of a valid payload to an arbitrary JSON value,
+// reaching the type assertions of the validators and handlers that byte mutations of
+// whole payloads rarely get past the parser to
+func FuzzTaskParameters(f *testing.F) {
+	for i, seed := range fuzzSeeds {
+		var payload TaskPayload
+		if err := json.Unmarshal([]byte(seed), &payload); err != nil {
+			f.Fatalf("Invalid seed %s: %v", seed, err)
+		}
+		for key := range payload.Parameters {
+			f.Add(uint8(i), key, []byte(adversarialValues[len(key)%len(adversarialValues)]))
+		}
+	}
+	performer := newFuzzPerformer()
+	var n atomic.Int64
+	f.Fuzz(func(t *testing.T, seed uint8, key string, value []byte) {
+		data, ok := withParameter(fuzzSeeds[int(seed)%len(fuzzSeeds)], key, value)
+		if !ok {
+			return
+		}
+		handleIfValid(performer, fmt.Sprintf("fuzz-%d", n.Add(1)), data)
+	})
+}
+
+// withParameter returns seed with parameter key set to the JSON value, false when value
+// is not JSON
+func withParameter(seed, key string, value []byte) ([]byte, bool) {
+	var payload TaskPayload
+	if err := json.Unmarshal([]byte(seed), &payload); err != nil {
+		return nil, false
+	}
+	var v interface{}
+	if err := json.Unmarshal(value, &v); err != nil {
+		return nil, false
+	}
+	payload.Parameters[key] = v
+	data, err := json.Marshal(&payload)
+	return data, err == nil
+}
+
+// Test_AdversarialParametersAreHandled sets every parameter of every seed to each
+// adversarial value in turn: payloads the performer validates must be handled without
+// panicking, whatever type their parameters have
+func Test_AdversarialParametersAreHandled(t *testing.T) {
+	performer := newFuzzPerformer()
+	n := 0
+	for _, seed := range fuzzSeeds {
+		var payload TaskPayload
+		if err := json.Unmarshal([]byte(seed), &payload); err != nil {
+			t.Fatalf("Invalid seed %s: %v", seed, err)
+		}
+		n++
+		task := &performerV1.TaskRequest{TaskId: []byte(fmt.Sprintf("adversarial-%d", n)), Payload: []byte(seed)}
+		if err := performer.ValidateTask(task); err != nil {
+			t.Errorf("Expected seed %s to validate, got %v", seed, err)
+		}
+		for key := range payload.Parameters {
+			for _, value := range adversarialValues {
+				data, ok := withParameter(seed, key, []byte(value))
+				if !ok {
+					t.Fatalf("Failed to set %s to %s", key, value)
+				}
+				n++
+				handleIfValid(performer, fmt.Sprintf("adversarial-%d", n), data)
+			}
+		}
+	}
+}
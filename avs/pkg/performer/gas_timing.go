@@ -0,0 +1,123 @@
+package performer
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+)
+
+// GasTiming is how a rebalance was timed into a low gas window on the chain of its first
+// transaction. Base fees are in gwei; MaxBaseFee is null when the chain has no ceiling.
+// Savings are in the native token, for the gas of the steps sent on that chain: expected
+// ones at the base fee waited for, actual ones at the base fees of the blocks the steps
+// were included in. ActualSavings is null until every such step is included.
+type GasTiming struct {
+	ChainID          uint64             `json:"chain_id"`
+	Delayed          bool               `json:"delayed"`
+	Expired          bool               `json:"expired"`
+	WaitedSeconds    uint64             `json:"waited_seconds"`
+	Trend            string             `json:"trend"`
+	MaxBaseFee       *canonical.Decimal `json:"max_base_fee"`
+	RequestedBaseFee canonical.Decimal  `json:"requested_base_fee"`
+	ExpectedBaseFee  canonical.Decimal  `json:"expected_base_fee"`
+	ReleasedBaseFee  canonical.Decimal  `json:"released_base_fee"`
+	ExpectedSavings  canonical.Decimal  `json:"expected_savings"`
+	ActualSavings    *canonical.Decimal `json:"actual_savings"`
+
+	// requested is the base fee savings are measured against, in wei
+	requested *big.Int
+}
+
+// awaitGasWindow delays route until the chain of its first step is in a low gas window.
+// Rebalances are sent at once when base fees cannot be read, and abort when ctx is done
+// while waiting. Conditions are checked again
+// after a delay, since funds may have been halted or rates moved meanwhile.
+func (yip *YieldIntelligencePerformer) awaitGasWindow(ctx context.Context, route *rebalanceRoute, steps []*plannedStep) (*GasTiming, error) {
+	if yip.gasWindows == nil || len(steps) == 0 {
+		return nil, nil
+	}
+	chainID := steps[0].ChainID
+	window, err := yip.gasWindows.Wait(ctx, chainID)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("rebalance was cancelled waiting for a low gas window: %w", err)
+		}
+		yip.log(ctx).Sugar().Warnw("Failed to time rebalance into a low gas window", "chainId", chainID, "error", err)
+		return nil, nil
+	}
+
+	var gas uint64
+	for _, step := range steps {
+		if step.ChainID == chainID {
+			gas += fallbackGas[step.Action]
+		}
+	}
+	savings := new(big.Int).Sub(window.Requested.BaseFee, window.Expected)
+	timing := &GasTiming{
+		ChainID:          chainID,
+		Delayed:          window.Delayed,
+		Expired:          window.Expired,
+		WaitedSeconds:    uint64(window.Waited.Seconds()),
+		Trend:            string(window.Requested.Trend),
+		RequestedBaseFee: gweiAmount(window.Requested.BaseFee),
+		ExpectedBaseFee:  gweiAmount(window.Expected),
+		ReleasedBaseFee:  gweiAmount(window.Released.BaseFee),
+		ExpectedSavings:  nativeAmount(savings.Mul(savings, new(big.Int).SetUint64(gas))),
+		requested:        window.Requested.BaseFee,
+	}
+	if window.Ceiling != nil {
+		ceiling := gweiAmount(window.Ceiling)
+		timing.MaxBaseFee = &ceiling
+	}
+	yip.log(ctx).Sugar().Infow("Timed rebalance into a gas window", "chainId", chainID, "delayed", timing.Delayed, "expired", timing.Expired, "waitedSeconds", timing.WaitedSeconds)
+
+	if window.Delayed {
+		if err := yip.checkHalt(ctx); err != nil {
+			return nil, err
+		}
+		if err := yip.checkRebalance(ctx, route); err != nil {
+			return nil, err
+		}
+	}
+	return timing, nil
+}
+
+// gasSavings sets the savings timing made on the transactions of execution, once every
+// one sent on its chain is included
+func (yip *YieldIntelligencePerformer) gasSavings(ctx context.Context, timing *GasTiming, execution *RebalanceExecution) {
+	if timing == nil {
+		return
+	}
+	client, err := yip.transactions.Client(timing.ChainID)
+	if err != nil {
+		return
+	}
+	savings := new(big.Int)
+	for _, tx := range execution.Transactions {
+		if tx.ChainID != timing.ChainID {
+			continue
+		}
+		if tx.BlockNumber == 0 {
+			return
+		}
+		header, err := client.HeaderByNumber(ctx, new(big.Int).SetUint64(tx.BlockNumber))
+		if err != nil || header.BaseFee == nil {
+			yip.log(ctx).Sugar().Warnw("Failed to read the base fee a rebalance paid", "chainId", tx.ChainID, "block", tx.BlockNumber, "error", err)
+			return
+		}
+		saved := new(big.Int).Sub(timing.requested, header.BaseFee)
+		savings.Add(savings, saved.Mul(saved, new(big.Int).SetUint64(tx.GasUsed)))
+	}
+	actual := nativeAmount(savings)
+	timing.ActualSavings = &actual
+}
+
+func gweiAmount(wei *big.Int) canonical.Decimal {
+	return canonical.NewDecimalFromInt(wei, 9, 9)
+}
+
+func nativeAmount(wei *big.Int) canonical.Decimal {
+	return canonical.NewDecimalFromInt(wei, 18, 18)
+}
@@ -0,0 +1,72 @@
+package performer
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/gaswindow"
+)
+
+func gwei(n int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(n), big.NewInt(1e9))
+}
+
+func Test_RebalanceTimedIntoGasWindow(t *testing.T) {
+	performer, account, ethereum := newSubmittingPerformer(t, big.NewInt(0), big.NewInt(1_000_000_000))
+	ethereum.AutoMine()
+	for block := uint64(97); block < 100; block++ {
+		ethereum.SetBlockBaseFee(block, gwei(30))
+	}
+	ethereum.SetBaseFee(gwei(30))
+	chains := chain.NewManager()
+	chains.Register(1, "ethereum", ethereum)
+	cfg := gaswindow.DefaultConfig()
+	cfg.Enabled, cfg.MaxBaseFeeGwei = true, 20
+	cfg.MaxDelay, cfg.PollInterval, cfg.TrendBlocks = time.Second, 5*time.Millisecond, 4
+	WithGasWindows(gaswindow.New(cfg, chains))(performer)
+
+	// base fees fall under the ceiling, and stay there for the blocks the steps land in
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		for block := uint64(97); block <= 102; block++ {
+			ethereum.SetBlockBaseFee(block, gwei(15))
+		}
+		ethereum.SetBaseFee(gwei(15))
+	}()
+	result := runDryRun(t, performer, `{"type":"rebalance_execution","parameters":{
+		"user_address":"`+account.Hex()+`","nonce":"1","amount":"1000000000","target_protocol":"aave_v3","target_chain":1}}`)
+
+	timing := result.GasTiming
+	if result.Status != ResultStatusCompleted || timing == nil {
+		t.Fatalf("Expected a completed rebalance timed into a gas window, got %+v", result)
+	}
+	if !timing.Delayed || timing.Expired || timing.MaxBaseFee.String() != "20.000000000" || timing.RequestedBaseFee.String() != "30.000000000" ||
+		timing.ExpectedBaseFee.String() != "20.000000000" || timing.ReleasedBaseFee.String() != "15.000000000" {
+		t.Errorf("Expected the rebalance to wait for the ceiling, got %+v", timing)
+	}
+	// 10 gwei under the requested base fee for the fallback gas of the approve and deposit
+	if timing.ExpectedSavings.String() != "0.003100000000000000" {
+		t.Errorf("Unexpected expected savings %s", timing.ExpectedSavings)
+	}
+	var gasUsed int64
+	for _, tx := range result.Execution.Transactions {
+		gasUsed += int64(tx.GasUsed)
+	}
+	actual := nativeAmount(new(big.Int).Mul(gwei(15), big.NewInt(gasUsed)))
+	if timing.ActualSavings == nil || timing.ActualSavings.String() != actual.String() {
+		t.Errorf("Expected %s saved at 15 gwei under the requested base fee, got %v", actual, timing.ActualSavings)
+	}
+
+	// urgent rebalances are sent at once
+	ethereum.SetBaseFee(gwei(30))
+	var urgent RebalanceExecutionResult
+	if err := runYieldMonitoring(t, performer, "urgent", `{"type":"rebalance_execution","parameters":{
+		"user_address":"`+account.Hex()+`","nonce":"2","amount":"1000000000","target_protocol":"aave_v3","target_chain":1,"urgent":true}}`, &urgent); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	if urgent.GasTiming != nil || urgent.Execution == nil {
+		t.Errorf("Expected an urgent rebalance to be sent without timing, got %+v", urgent)
+	}
+}
@@ -0,0 +1,67 @@
+package performer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/hysteresis"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+)
+
+// HysteresisReport tells whether a recommended move is held back until the target has
+// out-earned the source long enough, or the funds of the user cooled down from their
+// last move
+type HysteresisReport struct {
+	Suppressed bool              `json:"suppressed"`
+	Reason     hysteresis.Reason `json:"reason,omitempty"`
+
+	// LeadSeconds is how long the target has earned the minimum advantage over the
+	// source, and MinLeadSeconds how long it must before the move is recommended
+	LeadSeconds    uint64 `json:"lead_seconds"`
+	MinLeadSeconds uint64 `json:"min_lead_seconds"`
+
+	// CooldownEndsAt is the unix time the funds of the user may move again, set while
+	// they cool down
+	CooldownEndsAt *uint64 `json:"cooldown_ends_at,omitempty"`
+}
+
+// checkHysteresis decides whether moving the funds of user, zero when the task names
+// none, from source to target is recommended at now. The lead of target is measured on
+// the supply rate history of both markets, ending with their current rates.
+func (yip *YieldIntelligencePerformer) checkHysteresis(ctx context.Context, user common.Address, source, target *ChainMarketRate, now time.Time) (*HysteresisReport, error) {
+	cfg := yip.hysteresis
+	from := now.Add(-cfg.Lookback())
+	sourceHistory, err := yip.history.Range(ctx, store.SupplyRateSeries(source.Protocol, source.ChainID), from, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load rate history: %w", err)
+	}
+	targetHistory, err := yip.history.Range(ctx, store.SupplyRateSeries(target.Protocol, target.ChainID), from, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load rate history: %w", err)
+	}
+	// reported rates are percentages, history holds fractions
+	sourceRate, _ := source.SupplyRate.Rat().Float64()
+	targetRate, _ := target.SupplyRate.Rat().Float64()
+	lead := hysteresis.Lead(sourceHistory, targetHistory, sourceRate/100, targetRate/100, float64(cfg.MinAdvantageBps)/10_000, now)
+
+	var lastMove time.Time
+	if user != (common.Address{}) {
+		if lastMove, err = yip.positions.LastMove(ctx, user); err != nil {
+			return nil, fmt.Errorf("failed to load last move: %w", err)
+		}
+	}
+	decision := cfg.Evaluate(lead, lastMove, now)
+	report := &HysteresisReport{
+		Suppressed:     decision.Suppressed(),
+		Reason:         decision.Reason,
+		LeadSeconds:    uint64(decision.Lead / time.Second),
+		MinLeadSeconds: uint64(cfg.MinPersistence / time.Second),
+	}
+	if !decision.CooldownEnds.IsZero() {
+		ends := uint64(decision.CooldownEnds.Unix())
+		report.CooldownEndsAt = &ends
+	}
+	return report, nil
+}
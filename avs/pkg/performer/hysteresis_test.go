@@ -0,0 +1,56 @@
+package performer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/hysteresis"
+	"github.com/najnomics/crosscow-avs/pkg/positions"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+)
+
+func Test_CrossChainYieldCheckHysteresis(t *testing.T) {
+	ctx := context.Background()
+	performer := newAllocationPerformer(t)
+	tracker := positions.New(positions.DefaultConfig(), store.NewMemoryKV())
+	WithPositions(tracker)(performer)
+	// both aave markets pay the same, which leads without a minimum advantage
+	cfg := hysteresis.DefaultConfig()
+	cfg.Enabled, cfg.MinAdvantageBps = true, 0
+	WithHysteresis(cfg)(performer)
+	user := common.HexToAddress("0xaa")
+	check := func(id string) CrossChainYieldResult {
+		return runCrossChainYieldCheck(t, performer, id, `{"type":"cross_chain_yield_check","parameters":{"source_chain":1,"target_chain":8453,"amount":"5000000000","user_address":"`+user.Hex()+`"}}`)
+	}
+
+	result := check("no history")
+	if report := result.Hysteresis; report == nil || report.Reason != hysteresis.ReasonNotPersisted || report.LeadSeconds != 0 || report.MinLeadSeconds != 6*60*60 {
+		t.Fatalf("Expected a lead without history to be held back, got %+v", report)
+	}
+
+	recorded := time.Now().Add(-10 * time.Hour)
+	for _, chainID := range []uint64{1, adapters.ChainIDBase} {
+		if err := performer.history.Append(ctx, store.SupplyRateSeries(adapters.ProtocolAaveV3, chainID), store.SeriesPoint{Time: recorded, Value: 0.03}); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+	result = check("persisted")
+	if report := result.Hysteresis; report == nil || report.Suppressed || report.LeadSeconds < 10*60*60 {
+		t.Fatalf("Expected a lead held for 10 hours to be recommended, got %+v", report)
+	}
+
+	if err := tracker.RecordMove(ctx, user); err != nil {
+		t.Fatalf("RecordMove failed: %v", err)
+	}
+	result = check("cooldown")
+	if report := result.Hysteresis; report == nil || report.Reason != hysteresis.ReasonCooldown || report.CooldownEndsAt == nil || *report.CooldownEndsAt < uint64(time.Now().Add(23*time.Hour).Unix()) {
+		t.Errorf("Expected the funds to cool down, got %+v", report)
+	}
+
+	if result := runCrossChainYieldCheck(t, newAllocationPerformer(t), "disabled", `{"type":"cross_chain_yield_check","parameters":{"source_chain":1,"target_chain":8453,"amount":"5000000000"}}`); result.Hysteresis != nil {
+		t.Errorf("Expected no hysteresis report unless configured, got %+v", result.Hysteresis)
+	}
+}
@@ -0,0 +1,209 @@
+package performer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/najnomics/crosscow-avs/pkg/logging"
+	"github.com/najnomics/crosscow-avs/pkg/redis"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+var (
+	// ErrTaskIdConflict is returned when a TaskId is redelivered with a different payload
+	ErrTaskIdConflict = errors.New("task id was already used with a different payload")
+
+	// ErrTaskInterrupted is returned when a task that moves funds was interrupted mid-execution
+	// by a previous performer run. Re-executing it blindly could move funds twice.
+	ErrTaskInterrupted = errors.New("task was interrupted during a previous execution and requires manual reconciliation")
+)
+
+// nonIdempotentTaskTypes are task types whose re-execution after an interruption could
+// have external side effects such as moving funds twice
+var nonIdempotentTaskTypes = map[TaskType]bool{
+	TaskTypeRebalanceExecution: true,
+}
+
+// inflightTask is a task execution that concurrent duplicate deliveries wait on
+type inflightTask struct {
+	done    chan struct{}
+	waiters int
+	result  []byte
+	err     error
+}
+
+// taskDeduplicator collapses concurrent deliveries of the same task into one execution
+type taskDeduplicator struct {
+	mu       sync.Mutex
+	inflight map[string]*inflightTask
+}
+
+func newTaskDeduplicator() *taskDeduplicator {
+	return &taskDeduplicator{
+		inflight: make(map[string]*inflightTask),
+	}
+}
+
+// do runs fn once per key at a time. Callers arriving while fn runs for the same key
+// wait for it and receive the same result.
+func (d *taskDeduplicator) do(key string, fn func() ([]byte, error)) ([]byte, error) {
+	d.mu.Lock()
+	if call, ok := d.inflight[key]; ok {
+		call.waiters++
+		d.mu.Unlock()
+		<-call.done
+		return call.result, call.err
+	}
+
+	call := &inflightTask{done: make(chan struct{})}
+	d.inflight[key] = call
+	d.mu.Unlock()
+
+	call.result, call.err = fn()
+
+	d.mu.Lock()
+	delete(d.inflight, key)
+	d.mu.Unlock()
+	close(call.done)
+
+	return call.result, call.err
+}
+
+// waiting returns how many duplicate callers are waiting on the execution for key
+func (d *taskDeduplicator) waiting(key string) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if call, ok := d.inflight[key]; ok {
+		return call.waiters
+	}
+	return 0
+}
+
+// sharedResultPollInterval is how often a replica waiting on a task another replica
+// runs checks for its result
+const sharedResultPollInterval = 100 * time.Millisecond
+
+// sharedDeduplicator collapses deliveries of the same task to different replicas into
+// one execution, through the Redis they share. The replica claiming a task runs it and
+// stores its result for the idempotency TTL; the others wait for the result. A nil
+// sharedDeduplicator runs every task.
+type sharedDeduplicator struct {
+	client *redis.Client
+	owner  string
+}
+
+func newSharedDeduplicator(client *redis.Client) *sharedDeduplicator {
+	if client == nil {
+		return nil
+	}
+	return &sharedDeduplicator{client: client, owner: logging.NewCorrelationID()}
+}
+
+// do runs fn unless another replica ran the task of key or runs it now, in which case
+// it returns that replica's result once stored. Failed tasks are not stored, so a
+// redelivery runs them again. While Redis is unavailable fn runs on this replica.
+//
+// Claims expire after the idempotency TTL, so a replica stopping mid-task holds back
+// redeliveries of it for that long rather than risk running a rebalance twice.
+func (d *sharedDeduplicator) do(ctx context.Context, key string, fn func() ([]byte, error)) ([]byte, error) {
+	if d == nil || d.client.IdempotencyTTL() <= 0 {
+		return fn()
+	}
+	ttl := d.client.IdempotencyTTL()
+	resultKey, claimKey := d.client.Key("idem", "result", key), d.client.Key("idem", "claim", key)
+	waited := false
+	for {
+		var result []byte
+		found, claimed := false, false
+		err := d.client.Do(ctx, func(ctx context.Context, rdb goredis.Cmdable) error {
+			stored, err := rdb.Get(ctx, resultKey).Bytes()
+			if err == nil {
+				result, found = stored, true
+				return nil
+			}
+			if !errors.Is(err, redis.Nil) {
+				return err
+			}
+			claimed, err = rdb.SetNX(ctx, claimKey, d.owner, ttl).Result()
+			return err
+		})
+		switch {
+		case err != nil && waited:
+			// the replica running the task may still be running it
+			return nil, newTaskError(ErrorCodeUpstreamUnavailable, fmt.Errorf("task runs on another replica, whose result cannot be read: %w", err))
+		case err != nil:
+			return fn()
+		case found:
+			return result, nil
+		case claimed:
+			return d.run(ctx, resultKey, claimKey, ttl, fn)
+		}
+
+		waited = true
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(sharedResultPollInterval):
+		}
+	}
+}
+
+// run runs the task claimed under claimKey, stores its result under resultKey unless it
+// failed, and releases the claim
+func (d *sharedDeduplicator) run(ctx context.Context, resultKey, claimKey string, ttl time.Duration, fn func() ([]byte, error)) ([]byte, error) {
+	result, err := fn()
+	// the result is stored even when the task outlived its submitter
+	storeCtx := context.WithoutCancel(ctx)
+	_ = d.client.Do(storeCtx, func(ctx context.Context, rdb goredis.Cmdable) error {
+		pipe := rdb.TxPipeline()
+		if err == nil {
+			pipe.Set(ctx, resultKey, result, ttl)
+		}
+		pipe.Del(ctx, claimKey)
+		_, execErr := pipe.Exec(ctx)
+		return execErr
+	})
+	return result, err
+}
+
+// priorResult checks the task store for an earlier delivery of this task. It returns the
+// stored result when the identical task already completed, and an error when the TaskId
+// was reused with a different payload or a fund-moving task was interrupted.
+func (yip *YieldIntelligencePerformer) priorResult(ctx context.Context, t *performerV1.TaskRequest, payload *TaskPayload) ([]byte, bool, error) {
+	taskID := string(t.TaskId)
+
+	record, err := yip.tasks.GetTask(ctx, taskID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to look up task %s: %w", taskID, err)
+	}
+
+	if record.IdempotencyKey != store.IdempotencyKey(taskID, t.Payload) {
+		return nil, false, fmt.Errorf("%w: %s", ErrTaskIdConflict, taskID)
+	}
+
+	switch record.Status {
+	case store.TaskStatusCompleted:
+		yip.log(ctx).Sugar().Infow("Returning stored result for redelivered task")
+		return record.Result, true, nil
+	case store.TaskStatusProcessing:
+		// Live duplicates are collapsed by the deduplicator, so a processing record here
+		// was left behind by a previous run that stopped mid-execution
+		if nonIdempotentTaskTypes[payload.Type] {
+			return nil, false, fmt.Errorf("%w: %s", ErrTaskInterrupted, taskID)
+		}
+		yip.log(ctx).Sugar().Warnw("Re-executing task interrupted by a previous run")
+	case store.TaskStatusStale:
+		yip.log(ctx).Sugar().Infow("Re-executing task whose result was invalidated", "reason", record.StaleReason)
+	}
+
+	// Received, failed or stale tasks are safe to (re-)execute
+	return nil, false, nil
+}
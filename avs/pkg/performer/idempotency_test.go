@@ -0,0 +1,224 @@
+package performer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/najnomics/crosscow-avs/pkg/redis"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+	"go.uber.org/zap"
+)
+
+func Test_RedeliveredTaskReturnsStoredResult(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+
+	tasks := store.NewTaskStore(store.NewMemoryKV())
+	performer := NewYieldIntelligencePerformer(logger, WithTaskStore(tasks))
+
+	taskRequest := &performerV1.TaskRequest{
+		TaskId:  []byte("rebalance-1"),
+		Payload: []byte(`{"type":"rebalance_execution","parameters":{"user_address":"0xabc","amount":"1000000000","target_protocol":"aave_v3"}}`),
+	}
+
+	first, err := performer.HandleTask(taskRequest)
+	if err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+
+	// Overwrite the stored result so we can tell a replay from a re-execution
+	ctx := context.Background()
+	if err := tasks.MarkCompleted(ctx, "rebalance-1", []byte("stored-result")); err != nil {
+		t.Fatalf("MarkCompleted failed: %v", err)
+	}
+
+	second, err := performer.HandleTask(taskRequest)
+	if err != nil {
+		t.Fatalf("HandleTask on redelivery failed: %v", err)
+	}
+	if string(second.Result) != "stored-result" {
+		t.Errorf("Expected stored result on redelivery, got %q (first result %q)", string(second.Result), string(first.Result))
+	}
+}
+
+func Test_TaskIdReusedWithDifferentPayloadIsRejected(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+
+	performer := NewYieldIntelligencePerformer(logger)
+
+	original := &performerV1.TaskRequest{
+		TaskId:  []byte("rebalance-2"),
+		Payload: []byte(`{"type":"rebalance_execution","parameters":{"user_address":"0xabc","amount":"1000000000","target_protocol":"aave_v3"}}`),
+	}
+	if _, err := performer.HandleTask(original); err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+
+	tampered := &performerV1.TaskRequest{
+		TaskId:  []byte("rebalance-2"),
+		Payload: []byte(`{"type":"rebalance_execution","parameters":{"user_address":"0xabc","amount":"9000000000","target_protocol":"aave_v3"}}`),
+	}
+	if _, err := performer.HandleTask(tampered); !errors.Is(err, ErrTaskIdConflict) {
+		t.Errorf("Expected ErrTaskIdConflict, got %v", err)
+	}
+}
+
+func Test_InterruptedRebalanceIsNotReExecuted(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+
+	ctx := context.Background()
+	tasks := store.NewTaskStore(store.NewMemoryKV())
+	payload := []byte(`{"type":"rebalance_execution","parameters":{"user_address":"0xabc","amount":"1000000000","target_protocol":"aave_v3"}}`)
+
+	// Simulate a crash after the task started processing
+	if _, err := tasks.RecordReceived(ctx, "rebalance-3", string(TaskTypeRebalanceExecution), payload); err != nil {
+		t.Fatalf("RecordReceived failed: %v", err)
+	}
+	if err := tasks.MarkProcessing(ctx, "rebalance-3"); err != nil {
+		t.Fatalf("MarkProcessing failed: %v", err)
+	}
+
+	performer := NewYieldIntelligencePerformer(logger, WithTaskStore(tasks))
+	_, err = performer.HandleTask(&performerV1.TaskRequest{TaskId: []byte("rebalance-3"), Payload: payload})
+	if !errors.Is(err, ErrTaskInterrupted) {
+		t.Errorf("Expected ErrTaskInterrupted, got %v", err)
+	}
+}
+
+func Test_TaskDeduplicatorCollapsesConcurrentCalls(t *testing.T) {
+	dedup := newTaskDeduplicator()
+
+	var executions atomic.Int32
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	var wg sync.WaitGroup
+	results := make([]string, 5)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		res, _ := dedup.do("key", func() ([]byte, error) {
+			executions.Add(1)
+			close(started)
+			<-release
+			return []byte("done"), nil
+		})
+		results[0] = string(res)
+	}()
+	<-started
+
+	for i := 1; i < len(results); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			res, _ := dedup.do("key", func() ([]byte, error) {
+				executions.Add(1)
+				return []byte("duplicate"), nil
+			})
+			results[i] = string(res)
+		}(i)
+	}
+
+	// Only release the first execution once every duplicate is waiting on it
+	deadline := time.Now().Add(5 * time.Second)
+	for dedup.waiting("key") < len(results)-1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for duplicate callers")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	for i, res := range results {
+		if res != "done" {
+			t.Errorf("Expected caller %d to share the first result, got %q", i, res)
+		}
+	}
+	if executions.Load() != 1 {
+		t.Errorf("Expected exactly one execution, got %d", executions.Load())
+	}
+}
+
+func Test_SharedDeduplicatorCollapsesDeliveriesToReplicas(t *testing.T) {
+	server := miniredis.RunT(t)
+	cfg := redis.DefaultConfig()
+	cfg.Enabled, cfg.Addr = true, server.Addr()
+	newReplica := func() *sharedDeduplicator {
+		client, err := redis.New(cfg, nil, nil)
+		if err != nil {
+			t.Fatalf("redis.New failed: %v", err)
+		}
+		t.Cleanup(func() { client.Close() })
+		return newSharedDeduplicator(client)
+	}
+	a, b := newReplica(), newReplica()
+	ctx := context.Background()
+
+	var executions atomic.Int32
+	release := make(chan struct{})
+	started := make(chan struct{})
+	first := make(chan string, 1)
+	go func() {
+		res, _ := a.do(ctx, "key", func() ([]byte, error) {
+			executions.Add(1)
+			close(started)
+			<-release
+			return []byte("done"), nil
+		})
+		first <- string(res)
+	}()
+	<-started
+
+	// the task runs on replica a, so replica b waits for its result
+	second := make(chan string, 1)
+	go func() {
+		res, _ := b.do(ctx, "key", func() ([]byte, error) {
+			executions.Add(1)
+			return []byte("duplicate"), nil
+		})
+		second <- string(res)
+	}()
+	time.Sleep(3 * sharedResultPollInterval)
+	close(release)
+	if got, other := <-first, <-second; got != "done" || other != "done" || executions.Load() != 1 {
+		t.Errorf("Expected one execution shared by both replicas, got %q and %q from %d executions", got, other, executions.Load())
+	}
+	if !server.Exists("yieldavs:idem:result:key") || server.Exists("yieldavs:idem:claim:key") {
+		t.Errorf("Expected the result stored and the claim released, got %v", server.Keys())
+	}
+
+	// failed tasks are not stored, so a redelivery runs them again
+	if _, err := a.do(ctx, "failing", func() ([]byte, error) { return nil, errors.New("rpc down") }); err == nil {
+		t.Fatalf("Expected the task to fail")
+	}
+	if res, err := b.do(ctx, "failing", func() ([]byte, error) { return []byte("retried"), nil }); err != nil || string(res) != "retried" {
+		t.Errorf("Expected the failed task to run again, got %q (%v)", res, err)
+	}
+
+	// while Redis is down, each replica runs the tasks it receives
+	server.Close()
+	if res, err := b.do(ctx, "offline", func() ([]byte, error) { return []byte("local"), nil }); err != nil || string(res) != "local" {
+		t.Errorf("Expected the task to run locally, got %q (%v)", res, err)
+	}
+
+	var disabled *sharedDeduplicator
+	if res, err := disabled.do(ctx, "key", func() ([]byte, error) { return []byte("run"), nil }); err != nil || string(res) != "run" {
+		t.Errorf("Expected a nil deduplicator to run the task, got %q (%v)", res, err)
+	}
+}
@@ -0,0 +1,21 @@
+package performer
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// checkHalt refuses to move funds while an operator or the service manager halts them.
+// Rebalances are refused too when the pause flag cannot be read.
+func (yip *YieldIntelligencePerformer) checkHalt(ctx context.Context) error {
+	halt, err := yip.killswitch.Status(ctx)
+	if err != nil {
+		return newTaskError(ErrorCodeUpstreamUnavailable, fmt.Errorf("failed to check whether funds are halted: %w", err))
+	}
+	if halt != nil {
+		return newTaskError(ErrorCodeHalted, fmt.Errorf("funds are halted by the %s since %s: %s",
+			halt.Source, halt.Since.Format(time.RFC3339), halt.Reason))
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package performer
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/najnomics/crosscow-avs/pkg/killswitch"
+	"go.uber.org/zap"
+)
+
+func Test_KillSwitchHaltsRebalances(t *testing.T) {
+	performer, account, ethereum := newSubmittingPerformer(t)
+	ts := newAdminServer(t, performer, zap.NewAtomicLevel())
+
+	var status haltStatus
+	if code := adminRequest(t, http.MethodPost, ts.URL+"/halt", `{}`, nil); code != http.StatusBadRequest {
+		t.Errorf("Expected a halt without reason to be rejected, got %d", code)
+	}
+	adminRequest(t, http.MethodPost, ts.URL+"/halt", `{"reason":"usdc depeg"}`, &status)
+	if !status.Halted || status.Halt.Source != killswitch.SourceOperator || status.Halt.Reason != "usdc depeg" {
+		t.Fatalf("Expected rebalances to be halted, got %+v", status)
+	}
+
+	rebalance := `{"type":"rebalance_execution","parameters":{"user_address":"` + account.Hex() + `","nonce":"1","amount":"1000000000","target_protocol":"aave_v3","target_chain":1}}`
+	task := &performerV1.TaskRequest{TaskId: []byte("halted"), Payload: []byte(rebalance)}
+	if err := performer.ValidateTask(task); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	_, err := performer.HandleTask(task)
+	var taskErr *TaskError
+	if !errors.As(err, &taskErr) || taskErr.Code != ErrorCodeHalted || !taskErr.Code.Retryable() || !strings.Contains(err.Error(), "usdc depeg") {
+		t.Fatalf("Expected the rebalance to be halted, got %v", err)
+	}
+	if len(ethereum.Sent()) != 0 {
+		t.Errorf("Expected no transaction to be sent")
+	}
+
+	// monitoring and dry runs are still served
+	runYieldMonitoring(t, performer, "halted-monitoring", `{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","chain_id":1,"token":"USDC"}}`, &YieldMonitoringResult{})
+	runDryRun(t, performer, `{"type":"rebalance_execution","parameters":{"user_address":"`+account.Hex()+`","amount":"1000000000","target_protocol":"aave_v3","target_chain":1,"dry_run":true}}`)
+
+	adminRequest(t, http.MethodDelete, ts.URL+"/halt", "", &status)
+	if status.Halted {
+		t.Fatalf("Expected the halt to be lifted, got %+v", status)
+	}
+	// the refused task was not recorded as answered, so its redelivery runs
+	if _, err := performer.HandleTask(task); err != nil {
+		t.Fatalf("Expected the rebalance to run once resumed, got %v", err)
+	}
+	if len(ethereum.Sent()) == 0 {
+		t.Errorf("Expected the rebalance to be submitted")
+	}
+}
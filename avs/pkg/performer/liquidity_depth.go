@@ -0,0 +1,132 @@
+package performer
+
+import (
+	"context"
+	"math/big"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+	"github.com/najnomics/crosscow-avs/pkg/irm"
+)
+
+// defaultMaxRateImpactBps is the supply rate move considered immaterial when sizing
+// deposits and withdrawals, unless the task sets max_rate_impact_bps
+const defaultMaxRateImpactBps = 10
+
+// rateImpactCurveSteps are the points of the rate impact curve, as fractions of the
+// current pool supply
+var rateImpactCurveSteps = []*big.Rat{
+	big.NewRat(1, 1000),
+	big.NewRat(5, 1000),
+	big.NewRat(1, 100),
+	big.NewRat(2, 100),
+	big.NewRat(5, 100),
+	big.NewRat(10, 100),
+	big.NewRat(25, 100),
+}
+
+// RateImpactPoint is the pool state after a hypothetical deposit or withdrawal
+type RateImpactPoint struct {
+	Amount        canonical.Decimal `json:"amount"`
+	Utilization   canonical.Decimal `json:"utilization"`
+	SupplyRate    canonical.Decimal `json:"supply_rate"`
+	RateImpactBps canonical.Decimal `json:"rate_impact_bps"`
+}
+
+// LiquidityDepthResult is the result of a liquidity_depth_analysis task. Rates are annual
+// percentages, amounts are in USDC.
+type LiquidityDepthResult struct {
+	Protocol           string            `json:"protocol"`
+	Token              string            `json:"token"`
+	ChainID            uint64            `json:"chain_id"`
+	TotalSupply        canonical.Decimal `json:"total_supply"`
+	TotalBorrow        canonical.Decimal `json:"total_borrow"`
+	AvailableLiquidity canonical.Decimal `json:"available_liquidity"`
+	Utilization        canonical.Decimal `json:"utilization"`
+	SupplyRate         canonical.Decimal `json:"supply_rate"`
+	MaxRateImpactBps   uint64            `json:"max_rate_impact_bps"`
+	MaxDeposit         canonical.Decimal `json:"max_deposit"`
+	MaxWithdrawal      canonical.Decimal `json:"max_withdrawal"`
+	DepositCurve       []RateImpactPoint `json:"deposit_curve"`
+	WithdrawalCurve    []RateImpactPoint `json:"withdrawal_curve"`
+	Status             ResultStatus      `json:"status"`
+}
+
+// handleLiquidityDepthAnalysis measures how much USDC can move into or out of a protocol
+// before its supply rate moves by more than max_rate_impact_bps
+func (yip *YieldIntelligencePerformer) handleLiquidityDepthAnalysis(ctx context.Context, t *performerV1.TaskRequest, payload *TaskPayload) (interface{}, error) {
+	yip.log(ctx).Sugar().Infow("Processing liquidity depth analysis task")
+
+	protocol := paramString(payload, "protocol")
+	chainID := paramUint64(payload, "chain_id")
+	maxImpactBps := paramUint64(payload, "max_rate_impact_bps")
+	if maxImpactBps == 0 {
+		maxImpactBps = defaultMaxRateImpactBps
+	}
+
+	state, err := yip.readMarket(ctx, market{protocol: protocol, chainID: chainID})
+	if err != nil {
+		return nil, err
+	}
+	pool := &state.Pool
+
+	result := &LiquidityDepthResult{
+		Protocol:           protocol,
+		Token:              paramString(payload, "token"),
+		ChainID:            chainID,
+		TotalSupply:        usdcAmount(pool.TotalSupply),
+		TotalBorrow:        usdcAmount(pool.TotalBorrow),
+		AvailableLiquidity: usdcAmount(pool.AvailableLiquidity()),
+		Utilization:        canonical.Ratio(pool.Utilization()),
+		SupplyRate:         ratePercent(pool.SupplyRate()),
+		MaxRateImpactBps:   maxImpactBps,
+		MaxDeposit:         usdcAmount(pool.MaxDeposit(float64(maxImpactBps))),
+		MaxWithdrawal:      usdcAmount(pool.MaxWithdrawal(float64(maxImpactBps))),
+		DepositCurve:       []RateImpactPoint{},
+		WithdrawalCurve:    []RateImpactPoint{},
+		Status:             ResultStatusCompleted,
+	}
+
+	for _, step := range rateImpactCurveSteps {
+		amount := new(big.Int).Mul(pool.TotalSupply, step.Num())
+		amount.Quo(amount, step.Denom())
+
+		result.DepositCurve = append(result.DepositCurve, rateImpactPoint(pool.DepositImpact(amount)))
+		if impact, ok := pool.WithdrawalImpact(amount); ok {
+			result.WithdrawalCurve = append(result.WithdrawalCurve, rateImpactPoint(impact))
+		}
+	}
+	return result, nil
+}
+
+func rateImpactPoint(impact irm.Impact) RateImpactPoint {
+	return RateImpactPoint{
+		Amount:        usdcAmount(impact.Amount),
+		Utilization:   canonical.Ratio(impact.Utilization),
+		SupplyRate:    ratePercent(impact.SupplyRate),
+		RateImpactBps: canonical.Score(impact.RateImpactBps),
+	}
+}
+
+// usdcAmount converts USDC base units into a USDC decimal
+func usdcAmount(baseUnits *big.Int) canonical.Decimal {
+	return canonical.NewDecimalFromInt(baseUnits, USDCDecimals, USDCDecimals)
+}
+
+// usdcBaseUnits converts a USDC decimal into base units, truncating sub-unit precision
+func usdcBaseUnits(amount canonical.Decimal) *big.Int {
+	scaled := new(big.Rat).Mul(amount.Rat(), new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(USDCDecimals), nil)))
+	return new(big.Int).Quo(scaled.Num(), scaled.Denom())
+}
+
+// ratePercent converts an annual rate fraction (0.05) into a percentage (5.0000)
+func ratePercent(rate float64) canonical.Decimal {
+	return canonical.APY(rate * 100)
+}
+
+func (yip *YieldIntelligencePerformer) validateLiquidityDepthAnalysisTask(payload *TaskPayload) error {
+	if _, err := yip.adapters.Get(paramString(payload, "protocol")); err != nil {
+		return err
+	}
+	return validateToken(payload, paramUint64(payload, "chain_id"))
+}
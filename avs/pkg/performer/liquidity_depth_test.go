@@ -0,0 +1,209 @@
+package performer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/irm"
+	"go.uber.org/zap"
+)
+
+// fakeAdapter serves fixed market states per chain, and risk, governance and emergency
+// events when set
+type fakeAdapter struct {
+	protocol   string
+	markets    map[uint64]*adapters.MarketState
+	risk       *adapters.RiskState
+	governance *adapters.GovernanceState
+	emergency  *adapters.EmergencyLog
+	capCuts    []adapters.CapChange
+}
+
+func (f *fakeAdapter) Protocol() string { return f.protocol }
+
+func (f *fakeAdapter) ChainIDs() []uint64 {
+	ids := make([]uint64, 0, len(f.markets))
+	for id := range f.markets {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func (f *fakeAdapter) MarketState(ctx context.Context, chainID uint64) (*adapters.MarketState, error) {
+	state, ok := f.markets[chainID]
+	if !ok {
+		return nil, adapters.ErrUnsupportedChain
+	}
+	return state, nil
+}
+
+func (f *fakeAdapter) RiskState(ctx context.Context, chainID uint64) (*adapters.RiskState, error) {
+	if _, ok := f.markets[chainID]; !ok {
+		return nil, adapters.ErrUnsupportedChain
+	}
+	if f.risk == nil {
+		return nil, fmt.Errorf("%w: %s", adapters.ErrNoRiskData, f.protocol)
+	}
+	return f.risk, nil
+}
+
+func (f *fakeAdapter) Governance(ctx context.Context, chainID uint64) (*adapters.GovernanceState, error) {
+	if _, ok := f.markets[chainID]; !ok {
+		return nil, adapters.ErrUnsupportedChain
+	}
+	if f.governance == nil {
+		return nil, fmt.Errorf("%w: %s", adapters.ErrNoGovernanceData, f.protocol)
+	}
+	return f.governance, nil
+}
+
+func (f *fakeAdapter) EmergencyEvents(ctx context.Context, chainID uint64, lookback uint64) (*adapters.EmergencyLog, error) {
+	if _, ok := f.markets[chainID]; !ok {
+		return nil, adapters.ErrUnsupportedChain
+	}
+	if f.emergency == nil {
+		return nil, fmt.Errorf("%w: %s", adapters.ErrNoEmergencyData, f.protocol)
+	}
+	return f.emergency, nil
+}
+
+func (f *fakeAdapter) PendingCapChanges(ctx context.Context, chainID uint64, lookback uint64) ([]adapters.CapChange, error) {
+	if _, ok := f.markets[chainID]; !ok {
+		return nil, adapters.ErrUnsupportedChain
+	}
+	return f.capCuts, nil
+}
+
+func usdcUnits(amount int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(amount), big.NewInt(1_000_000))
+}
+
+func newFakeAaveAdapter() *fakeAdapter {
+	// a governor acting through a one day timelock, with a 5 of 9 safe as guardian
+	timelock := &adapters.Controller{
+		Address: common.HexToAddress("0xEE56e2B3D491590B5b31738cC34d5232F378a8D5"),
+		Kind:    adapters.ControllerTimelock,
+		Delay:   24 * 60 * 60,
+		Admin:   &adapters.Controller{Address: common.HexToAddress("0x9AEE0B04504CeF83A65AC3f0e838D0593BCb2BC7"), Kind: adapters.ControllerContract},
+	}
+	return &fakeAdapter{
+		protocol: adapters.ProtocolAaveV3,
+		risk: &adapters.RiskState{
+			SupplyCap: usdcUnits(110_000_000),
+			BorrowCap: usdcUnits(90_000_000),
+			BadDebt:   new(big.Int),
+			Oracle: &adapters.Oracle{
+				Feed:      common.HexToAddress("0x8fFfFfd4AfB6115b954Bd326cbe7B4BA576818f6"),
+				Price:     big.NewInt(99_990_000),
+				Decimals:  8,
+				UpdatedAt: 1_700_000_000,
+			},
+			Timestamp: 1_700_003_600,
+		},
+		governance: &adapters.GovernanceState{
+			ProxyAdmin: &adapters.Controller{
+				Address: common.HexToAddress("0x2f39d218133AFaB8F2B819B1066c7E434Ad94E9e"),
+				Kind:    adapters.ControllerContract,
+				Admin:   timelock,
+			},
+			Governor: timelock,
+			PauseGuardian: &adapters.Controller{
+				Address:   common.HexToAddress("0xCA76Ebd8617a03126B6FB84F9b1c1A0fB71C2633"),
+				Kind:      adapters.ControllerSafe,
+				Threshold: 5,
+				Owners:    9,
+			},
+		},
+		emergency: &adapters.EmergencyLog{FromBlock: 19_999_701, ToBlock: 20_000_000},
+		markets: map[uint64]*adapters.MarketState{
+			1: {
+				Protocol: adapters.ProtocolAaveV3,
+				ChainID:  1,
+				Pool: irm.Pool{
+					TotalSupply: usdcUnits(100_000_000),
+					TotalBorrow: usdcUnits(80_000_000),
+					Model: &irm.KinkModel{
+						OptimalUtilization: 0.9,
+						Slope1:             0.06,
+						Slope2:             0.6,
+						ReserveFactor:      0.1,
+					},
+				},
+			},
+		},
+	}
+}
+
+func Test_LiquidityDepthAnalysis(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+	performer := NewYieldIntelligencePerformer(logger, WithAdapters(adapters.NewRegistry(newFakeAaveAdapter())))
+
+	task := &performerV1.TaskRequest{
+		TaskId:  []byte("depth-task"),
+		Payload: []byte(`{"type":"liquidity_depth_analysis","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1,"max_rate_impact_bps":25}}`),
+	}
+	if err := performer.ValidateTask(task); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	resp, err := performer.HandleTask(task)
+	if err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+	checkGoldenResult(t, "liquidity_depth_analysis", resp.Result)
+
+	var envelope struct {
+		Result LiquidityDepthResult `json:"result"`
+	}
+	if err := json.Unmarshal(resp.Result, &envelope); err != nil {
+		t.Fatalf("Failed to decode result: %v", err)
+	}
+	result := envelope.Result
+	if result.AvailableLiquidity.String() != "20000000.000000" {
+		t.Errorf("Unexpected available liquidity %s", result.AvailableLiquidity)
+	}
+	if result.MaxDeposit.Rat().Sign() <= 0 || result.MaxDeposit.Cmp(result.TotalSupply) >= 0 {
+		t.Errorf("Unexpected max deposit %s", result.MaxDeposit)
+	}
+	if result.MaxWithdrawal.Cmp(result.AvailableLiquidity) > 0 {
+		t.Errorf("Max withdrawal %s exceeds available liquidity", result.MaxWithdrawal)
+	}
+	if len(result.DepositCurve) != len(rateImpactCurveSteps) {
+		t.Errorf("Expected a deposit point per curve step, got %d", len(result.DepositCurve))
+	}
+	// 25% of supply exceeds the 20% idle liquidity
+	if len(result.WithdrawalCurve) != len(rateImpactCurveSteps)-1 {
+		t.Errorf("Expected withdrawals beyond idle liquidity to be omitted, got %d points", len(result.WithdrawalCurve))
+	}
+}
+
+func Test_LiquidityDepthAnalysisValidation(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+	performer := NewYieldIntelligencePerformer(logger, WithAdapters(adapters.NewRegistry(newFakeAaveAdapter())))
+
+	testCases := map[string]string{
+		"unsupported protocol": `{"type":"liquidity_depth_analysis","parameters":{"protocol":"euler","token":"USDC","chain_id":1}}`,
+		"missing chain":        `{"type":"liquidity_depth_analysis","parameters":{"protocol":"aave_v3","token":"USDC"}}`,
+		"fractional impact":    `{"type":"liquidity_depth_analysis","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1,"max_rate_impact_bps":2.5}}`,
+		"impact out of range":  `{"type":"liquidity_depth_analysis","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1,"max_rate_impact_bps":20000}}`,
+	}
+	for name, payload := range testCases {
+		t.Run(name, func(t *testing.T) {
+			task := &performerV1.TaskRequest{TaskId: []byte("depth-task"), Payload: []byte(payload)}
+			if err := performer.ValidateTask(task); err == nil {
+				t.Errorf("Expected task to be rejected")
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package performer
+
+import (
+	"context"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/najnomics/crosscow-avs/pkg/logging"
+	"go.uber.org/zap"
+)
+
+// taskLogger returns the logger of one delivery of a task. Every line carries the task ID
+// and type, the chain it targets, and the correlation ID of the delivery: the one set in
+// the payload, so the submitter can trace the task, or a new one, so redeliveries of the
+// same task can be told apart.
+func (yip *YieldIntelligencePerformer) taskLogger(t *performerV1.TaskRequest, payload *TaskPayload) *zap.Logger {
+	correlationID := payload.CorrelationID
+	if correlationID == "" {
+		correlationID = logging.NewCorrelationID()
+	}
+	fields := []zap.Field{
+		zap.String("task_id", string(t.TaskId)),
+		zap.String("task_type", string(payload.Type)),
+		zap.String("correlation_id", correlationID),
+	}
+	if chainID := taskChainID(payload); chainID != 0 {
+		fields = append(fields, zap.Uint64("chain_id", chainID))
+	}
+	return yip.logger.With(fields...)
+}
+
+// taskChainID returns the chain a task reads or moves funds to, zero when it spans chains
+func taskChainID(payload *TaskPayload) uint64 {
+	if chainID := paramUint64(payload, "chain_id"); chainID != 0 {
+		return chainID
+	}
+	return paramUint64(payload, "target_chain")
+}
+
+// log returns the logger of the task ctx runs, the performer's logger outside tasks
+func (yip *YieldIntelligencePerformer) log(ctx context.Context) *zap.Logger {
+	return logging.FromContext(ctx, yip.logger)
+}
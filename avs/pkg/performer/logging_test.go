@@ -0,0 +1,46 @@
+package performer
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/logging"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func Test_TaskLogsCarryIdentifiers(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	performer := NewYieldIntelligencePerformer(zap.New(core),
+		WithAdapters(adapters.NewRegistry(newFakeAaveAdapter())),
+		WithLogRedaction(logging.Config{RedactAddresses: true}),
+	)
+
+	task := &performerV1.TaskRequest{TaskId: []byte("logged"), Payload: []byte(`{"type":"yield_monitoring","correlation_id":"hook-42",
+		"parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1,"api_key":"sk-live","user_address":"0x00000000000000000000000000000000000000aa"}}`)}
+	if err := performer.ValidateTask(task); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	if _, err := performer.HandleTask(task); err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+
+	processing := logs.FilterMessage("Processing yield monitoring task").All()
+	if len(processing) != 1 {
+		t.Fatalf("Expected the handler to log, got %d lines", len(processing))
+	}
+	fields := processing[0].ContextMap()
+	if fields["task_id"] != "logged" || fields["task_type"] != "yield_monitoring" || fields["chain_id"] != uint64(1) || fields["correlation_id"] != "hook-42" {
+		t.Errorf("Expected the task identifiers on handler logs, got %v", fields)
+	}
+
+	for _, entry := range logs.All() {
+		if text := fmt.Sprint(entry.ContextMap()); strings.Contains(text, "sk-live") || strings.Contains(text, "00000000aa") {
+			t.Errorf("Expected sensitive parameters to be redacted, %q logged %s", entry.Message, text)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package performer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/tracing"
+	"github.com/najnomics/crosscow-avs/pkg/workerpool"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// market identifies the USDC market of a protocol on a chain
+type market struct {
+	protocol string
+	chainID  uint64
+}
+
+// MarketFailure reports a market that could not be read
+type MarketFailure struct {
+	Protocol string `json:"protocol"`
+	ChainID  uint64 `json:"chain_id"`
+	Error    string `json:"error"`
+}
+
+// venueKind tells lending markets from the venues tasks only read when asked to
+type venueKind int
+
+const (
+	venueLending venueKind = iota
+	venueLiquidityPool
+	venueFixedRate
+)
+
+func kindOf(adapter adapters.YieldAdapter) venueKind {
+	switch {
+	case adapters.IsLiquidityPool(adapter):
+		return venueLiquidityPool
+	case adapters.IsFixedRate(adapter):
+		return venueFixedRate
+	default:
+		return venueLending
+	}
+}
+
+// markets lists every registered lending market on chainIDs, or on every chain when none
+// are given, ordered by protocol and chain. Liquidity pools and fixed rate markets are
+// left out.
+func (yip *YieldIntelligencePerformer) markets(chainIDs ...uint64) []market {
+	return yip.registeredMarkets(venueLending, chainIDs...)
+}
+
+// lpMarkets lists every registered liquidity pool on chainIDs as markets does
+func (yip *YieldIntelligencePerformer) lpMarkets(chainIDs ...uint64) []market {
+	return yip.registeredMarkets(venueLiquidityPool, chainIDs...)
+}
+
+// fixedMarkets lists every registered fixed rate market on chainIDs as markets does
+func (yip *YieldIntelligencePerformer) fixedMarkets(chainIDs ...uint64) []market {
+	return yip.registeredMarkets(venueFixedRate, chainIDs...)
+}
+
+func (yip *YieldIntelligencePerformer) registeredMarkets(kind venueKind, chainIDs ...uint64) []market {
+	wanted := make(map[uint64]bool, len(chainIDs))
+	for _, id := range chainIDs {
+		wanted[id] = true
+	}
+
+	var out []market
+	for _, protocol := range yip.adapters.Protocols() {
+		adapter, err := yip.adapters.Get(protocol)
+		if err != nil || kindOf(adapter) != kind {
+			continue
+		}
+		ids := adapter.ChainIDs()
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		for _, id := range ids {
+			if len(wanted) == 0 || wanted[id] {
+				out = append(out, market{protocol: protocol, chainID: id})
+			}
+		}
+	}
+	return out
+}
+
+// readMarkets reads the state of every market concurrently, reusing the latest full
+// market snapshot while it is fresh. Results are in the order of markets; failed markets
+// carry their error.
+func (yip *YieldIntelligencePerformer) readMarkets(ctx context.Context, markets []market) []workerpool.Result[*adapters.MarketState] {
+	return workerpool.Map(ctx, yip.pool, markets, func(ctx context.Context, m market) (*adapters.MarketState, error) {
+		if chain.BlockOf(ctx) == nil {
+			if state, ok := yip.scans.MarketState(m.protocol, m.chainID); ok {
+				return state, nil
+			}
+		}
+		return yip.readMarket(ctx, m)
+	})
+}
+
+// readMarket returns the indexed state of m while it is up to date and reads it from the
+// chain otherwise. Reads pinned to a block always go to the chain, since the index
+// follows the head.
+func (yip *YieldIntelligencePerformer) readMarket(ctx context.Context, m market) (*adapters.MarketState, error) {
+	if chain.BlockOf(ctx) == nil {
+		if state, ok := yip.indexer.MarketState(m.protocol, m.chainID); ok {
+			return state, nil
+		}
+	}
+	adapter, err := yip.adapters.Get(m.protocol)
+	if err != nil {
+		return nil, err
+	}
+	ctx, span := startAdapterSpan(ctx, "MarketState", m)
+	state, err := adapter.MarketState(ctx, m.chainID)
+	tracing.End(span, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s market on chain %d: %w", m.protocol, m.chainID, err)
+	}
+	return state, nil
+}
+
+// readRiskState returns the indexed risk state of m while it is up to date and reads it
+// from the chain otherwise. It fails with adapters.ErrNoRiskData for protocols that do
+// not report risk data.
+func (yip *YieldIntelligencePerformer) readRiskState(ctx context.Context, m market) (*adapters.RiskState, error) {
+	if chain.BlockOf(ctx) == nil {
+		if state, ok := yip.indexer.RiskState(m.protocol, m.chainID); ok {
+			return state, nil
+		}
+	}
+	reader, err := yip.adapters.RiskReaderFor(m.protocol)
+	if err != nil {
+		return nil, err
+	}
+	ctx, span := startAdapterSpan(ctx, "RiskState", m)
+	state, err := reader.RiskState(ctx, m.chainID)
+	tracing.End(span, err)
+	return state, err
+}
+
+// startAdapterSpan starts the span of a read of m by its protocol adapter. Indexed reads
+// make no calls and get no span.
+func startAdapterSpan(ctx context.Context, method string, m market) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "adapter "+m.protocol+" "+method,
+		attribute.String("protocol", m.protocol),
+		attribute.Int64("chain_id", int64(m.chainID)),
+	)
+}
+
+func marketFailure(m market, err error) MarketFailure {
+	return MarketFailure{Protocol: m.protocol, ChainID: m.chainID, Error: err.Error()}
+}
@@ -0,0 +1,166 @@
+package performer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/redis"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+	"github.com/najnomics/crosscow-avs/pkg/tokens"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+const prefixNonce = "nonce:"
+
+// requiresNonce reports whether payload is a rebalance submitted from the performer's
+// account, which must carry a nonce. Dry runs move nothing, and rebalance_commit tasks
+// are bound to a plan that expires instead.
+func (yip *YieldIntelligencePerformer) requiresNonce(payload *TaskPayload) bool {
+	return yip.transactions != nil && !paramBool(payload, "dry_run")
+}
+
+// validateNonce checks the nonce parameter of rebalances that must carry one. Replays are
+// refused when the task is handled, since validating a redelivered task must succeed.
+func (yip *YieldIntelligencePerformer) validateNonce(payload *TaskPayload) error {
+	if !yip.requiresNonce(payload) {
+		return nil
+	}
+	_, err := taskNonce(payload)
+	return err
+}
+
+// taskNonce returns the nonce parameter of payload. Nonces are decimal strings, like
+// amounts, so nonces beyond the integers a JSON number holds exactly stay distinct.
+func taskNonce(payload *TaskPayload) (*big.Int, error) {
+	value, _ := payload.Parameters["nonce"].(string)
+	nonce, err := tokens.ParsePositiveAmount(value)
+	if err != nil {
+		return nil, fmt.Errorf("missing or invalid nonce: rebalances must carry a positive decimal string nonce, above the last one used for their user_address")
+	}
+	return nonce, nil
+}
+
+// consumeNonce records the nonce of a rebalance as used, refusing nonces that are not
+// above the last one used for its user_address
+func (yip *YieldIntelligencePerformer) consumeNonce(ctx context.Context, t *performerV1.TaskRequest, payload *TaskPayload) error {
+	if !yip.requiresNonce(payload) {
+		return nil
+	}
+	nonce, err := taskNonce(payload)
+	if err != nil {
+		return newTaskError(ErrorCodeValidation, err)
+	}
+	account := common.HexToAddress(paramString(payload, "user_address"))
+	return yip.nonces.consume(ctx, account, nonce, string(t.TaskId))
+}
+
+// nonceRecord is the last nonce used for an account and the task that used it. Nonces
+// are kept as JSON numbers of any size.
+type nonceRecord struct {
+	Nonce  json.Number `json:"nonce"`
+	TaskID string      `json:"task_id"`
+}
+
+// consumeSharedNonce sets the nonce ARGV[1] of KEYS[1] used by task ARGV[2] when it is
+// above the last one, or the last one used by the same task. Nonces are compared as
+// decimal strings without leading zeros, since Lua numbers lose precision beyond 2^53.
+// It returns whether the nonce was consumed, and the last nonce and task.
+var consumeSharedNonce = goredis.NewScript(`
+local last = redis.call("HMGET", KEYS[1], "nonce", "task_id")
+if last[1] then
+	if last[1] == ARGV[1] and last[2] == ARGV[2] then
+		return {1, last[1], last[2]}
+	end
+	if #ARGV[1] < #last[1] or (#ARGV[1] == #last[1] and ARGV[1] <= last[1]) then
+		return {0, last[1], last[2]}
+	end
+end
+redis.call("HSET", KEYS[1], "nonce", ARGV[1], "task_id", ARGV[2])
+return {1, ARGV[1], ARGV[2]}
+`)
+
+// nonceStore keeps the last nonce each account's rebalances used, in the Redis the
+// replicas share when there is one, so a rebalance replayed to another replica is
+// refused too
+type nonceStore struct {
+	kv     store.KV
+	shared *redis.Client
+
+	// mu makes consuming a nonce atomic, so concurrent replays use it once
+	mu sync.Mutex
+}
+
+func newNonceStore(kv store.KV, shared *redis.Client) *nonceStore {
+	return &nonceStore{kv: kv, shared: shared}
+}
+
+// consume records nonce as used by taskID for account. Nonces must be above the last one
+// used, except for redeliveries of the task that used it, which handle it again when it
+// failed before completing.
+func (s *nonceStore) consume(ctx context.Context, account common.Address, nonce *big.Int, taskID string) error {
+	if s.shared != nil {
+		return s.consumeShared(ctx, account, nonce, taskID)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := []byte(prefixNonce + account.Hex())
+	encoded, err := s.kv.Get(ctx, key)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+	case err != nil:
+		return fmt.Errorf("failed to read the nonce of %s: %w", account.Hex(), err)
+	default:
+		var last nonceRecord
+		if err := json.Unmarshal(encoded, &last); err != nil {
+			return fmt.Errorf("failed to decode the nonce of %s: %w", account.Hex(), err)
+		}
+		lastNonce, ok := new(big.Int).SetString(last.Nonce.String(), 10)
+		if !ok {
+			return fmt.Errorf("failed to decode the nonce of %s: %q is not an integer", account.Hex(), last.Nonce)
+		}
+		if nonce.Cmp(lastNonce) == 0 && taskID == last.TaskID {
+			return nil
+		}
+		if nonce.Cmp(lastNonce) <= 0 {
+			return newTaskError(ErrorCodeValidation, fmt.Errorf("replayed rebalance: nonce %s of %s is not above %s, used by task %s",
+				nonce, account.Hex(), lastNonce, last.TaskID))
+		}
+	}
+
+	encoded, err = json.Marshal(&nonceRecord{Nonce: json.Number(nonce.String()), TaskID: taskID})
+	if err != nil {
+		return err
+	}
+	if err := s.kv.Set(ctx, key, encoded); err != nil {
+		return fmt.Errorf("failed to store the nonce of %s: %w", account.Hex(), err)
+	}
+	return nil
+}
+
+// consumeShared consumes nonce in the shared Redis. Rebalances are refused while Redis is
+// down, since another replica may have used the nonce.
+func (s *nonceStore) consumeShared(ctx context.Context, account common.Address, nonce *big.Int, taskID string) error {
+	var reply []interface{}
+	err := s.shared.Do(ctx, func(ctx context.Context, rdb goredis.Cmdable) (err error) {
+		reply, err = consumeSharedNonce.Run(ctx, rdb, []string{s.shared.Key("nonce", account.Hex())}, nonce.String(), taskID).Slice()
+		return err
+	})
+	if err != nil {
+		return newTaskError(ErrorCodeUpstreamUnavailable, fmt.Errorf("failed to consume the nonce of %s in the shared Redis: %w", account.Hex(), err))
+	}
+	if len(reply) != 3 {
+		return fmt.Errorf("failed to consume the nonce of %s: unexpected reply %v", account.Hex(), reply)
+	}
+	if consumed, _ := reply[0].(int64); consumed == 0 {
+		return newTaskError(ErrorCodeValidation, fmt.Errorf("replayed rebalance: nonce %s of %s is not above %v, used by task %v",
+			nonce, account.Hex(), reply[1], reply[2]))
+	}
+	return nil
+}
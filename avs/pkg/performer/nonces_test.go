@@ -0,0 +1,159 @@
+package performer
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+)
+
+func Test_RebalanceRequiresNonce(t *testing.T) {
+	performer, account, ethereum := newSubmittingPerformer(t)
+
+	for name, nonce := range map[string]string{"missing": ``, "zero": `"nonce":"0",`, "fractional": `"nonce":"1.5",`, "number": `"nonce":1,`} {
+		payload := `{"type":"rebalance_execution","parameters":{"user_address":"` + account.Hex() + `",` + nonce + `"amount":"1000000000","target_protocol":"aave_v3","target_chain":1}}`
+		if err := runRebalanceTask(t, performer, name, payload, nil); err == nil {
+			t.Errorf("Expected the %s nonce to be rejected", name)
+		}
+	}
+	// dry runs move nothing and need none
+	runDryRun(t, performer, `{"type":"rebalance_execution","parameters":{"user_address":"`+account.Hex()+`","amount":"1000000000","target_protocol":"aave_v3","target_chain":1,"dry_run":true}}`)
+	if len(ethereum.Sent()) != 0 {
+		t.Errorf("Expected no transaction to be sent")
+	}
+}
+
+func Test_RebalanceReplaysRefused(t *testing.T) {
+	performer, account, ethereum := newSubmittingPerformer(t)
+	rebalance := func(nonce string) string {
+		return `{"type":"rebalance_execution","parameters":{"user_address":"` + account.Hex() + `","nonce":"` + nonce + `","amount":"1000000000","target_protocol":"aave_v3","target_chain":1}}`
+	}
+
+	var result RebalanceExecutionResult
+	if err := runRebalanceTask(t, performer, "first", rebalance("2"), &result); err != nil || result.Execution == nil {
+		t.Fatalf("Expected the rebalance to be submitted, got %+v, %v", result, err)
+	}
+	for id, nonce := range map[string]string{"replay": "2", "lower": "1"} {
+		err := runRebalanceTask(t, performer, id, rebalance(nonce), nil)
+		var taskErr *TaskError
+		if !errors.As(err, &taskErr) || taskErr.Code != ErrorCodeValidation || taskErr.Code.Retryable() {
+			t.Errorf("Expected the %s nonce to be refused, got %v", id, err)
+		}
+	}
+	if len(ethereum.Sent()) != 2 {
+		t.Fatalf("Expected the replays to send nothing, got %d transactions", len(ethereum.Sent()))
+	}
+
+	if err := runRebalanceTask(t, performer, "next", rebalance("3"), &result); err != nil || result.Execution == nil {
+		t.Errorf("Expected a higher nonce to be accepted, got %+v, %v", result, err)
+	}
+}
+
+func Test_RebalanceRedeliveryKeepsNonce(t *testing.T) {
+	performer, account, ethereum := newSubmittingPerformer(t)
+	if _, err := performer.killswitch.Halt(context.Background(), "maintenance"); err != nil {
+		t.Fatalf("Halt failed: %v", err)
+	}
+
+	rebalance := `{"type":"rebalance_execution","parameters":{"user_address":"` + account.Hex() + `","nonce":"1","amount":"1000000000","target_protocol":"aave_v3","target_chain":1}}`
+	err := runRebalanceTask(t, performer, "halted", rebalance, nil)
+	var taskErr *TaskError
+	if !errors.As(err, &taskErr) || taskErr.Code != ErrorCodeHalted {
+		t.Fatalf("Expected the rebalance to be halted, got %v", err)
+	}
+
+	// the retried task may use its nonce again
+	if err := performer.killswitch.Resume(context.Background()); err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+	var result RebalanceExecutionResult
+	if err := runRebalanceTask(t, performer, "halted", rebalance, &result); err != nil || result.Execution == nil || len(ethereum.Sent()) != 2 {
+		t.Errorf("Expected the redelivered rebalance to be submitted, got %+v, %v", result, err)
+	}
+}
+
+func Test_NonceStorePersists(t *testing.T) {
+	ctx := context.Background()
+	kv := store.NewMemoryKV()
+	account := common.HexToAddress("0xaa")
+
+	if err := newNonceStore(kv, nil).consume(ctx, account, big.NewInt(7), "first"); err != nil {
+		t.Fatalf("consume failed: %v", err)
+	}
+	// a restarted performer reads the nonces used before
+	nonces := newNonceStore(kv, nil)
+	if err := nonces.consume(ctx, account, big.NewInt(7), "replay"); err == nil {
+		t.Errorf("Expected the used nonce to be refused after a restart")
+	}
+	if err := nonces.consume(ctx, common.HexToAddress("0xbb"), big.NewInt(1), "other"); err != nil {
+		t.Errorf("Expected nonces to be kept per account, got %v", err)
+	}
+}
+
+func Test_NoncesBeyondFloatPrecisionStayDistinct(t *testing.T) {
+	performer, account, _ := newSubmittingPerformer(t)
+	rebalance := func(nonce string) string {
+		return `{"type":"rebalance_execution","parameters":{"user_address":"` + account.Hex() + `","nonce":"` + nonce + `","amount":"1000000000","target_protocol":"aave_v3","target_chain":1}}`
+	}
+
+	// 2^53 and 2^53+1 are the same float64
+	var result RebalanceExecutionResult
+	if err := runRebalanceTask(t, performer, "2^53", rebalance("9007199254740992"), &result); err != nil || result.Execution == nil {
+		t.Fatalf("Expected the rebalance to be submitted, got %+v, %v", result, err)
+	}
+	if err := runRebalanceTask(t, performer, "2^53+1", rebalance("9007199254740993"), &result); err != nil || result.Execution == nil {
+		t.Errorf("Expected nonce 2^53+1 to be above 2^53, got %+v, %v", result, err)
+	}
+	err := runRebalanceTask(t, performer, "replayed 2^53+1", rebalance("9007199254740993"), nil)
+	var taskErr *TaskError
+	if !errors.As(err, &taskErr) || taskErr.Code != ErrorCodeValidation {
+		t.Errorf("Expected the replayed nonce to be refused, got %v", err)
+	}
+
+	// nonces beyond uint64 are kept exactly
+	nonces := newNonceStore(store.NewMemoryKV(), nil)
+	huge, _ := new(big.Int).SetString("340282366920938463463374607431768211456", 10)
+	if err := nonces.consume(context.Background(), account, huge, "huge"); err != nil {
+		t.Fatalf("consume failed: %v", err)
+	}
+	if err := nonces.consume(context.Background(), account, new(big.Int).Sub(huge, big.NewInt(1)), "lower"); err == nil {
+		t.Errorf("Expected a nonce below 2^128 to be refused")
+	}
+}
+
+func Test_NoncesSharedAcrossReplicas(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	account := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	a := newNonceStore(store.NewMemoryKV(), newReplicaRedis(t, server))
+	b := newNonceStore(store.NewMemoryKV(), newReplicaRedis(t, server))
+
+	if err := a.consume(ctx, account, big.NewInt(9007199254740992), "first"); err != nil {
+		t.Fatalf("consume failed: %v", err)
+	}
+	var taskErr *TaskError
+	for name, nonce := range map[string]int64{"replayed": 9007199254740992, "lower": 7} {
+		if err := b.consume(ctx, account, big.NewInt(nonce), "replay"); !errors.As(err, &taskErr) || taskErr.Code != ErrorCodeValidation {
+			t.Errorf("%s: expected the nonce to be refused by the other replica, got %v", name, err)
+		}
+	}
+	// redeliveries of the task that used the nonce handle it again, on any replica
+	if err := b.consume(ctx, account, big.NewInt(9007199254740992), "first"); err != nil {
+		t.Errorf("Expected the redelivered task to keep its nonce: %v", err)
+	}
+	// 2^53+1 is above 2^53, and 10^16 above both despite sorting first as a string
+	for _, nonce := range []int64{9007199254740993, 10000000000000000} {
+		if err := b.consume(ctx, account, big.NewInt(nonce), "next"); err != nil {
+			t.Errorf("Expected nonce %d to be above the last one: %v", nonce, err)
+		}
+	}
+
+	server.Close()
+	if err := a.consume(ctx, account, big.NewInt(10000000000000001), "down"); !errors.As(err, &taskErr) || taskErr.Code != ErrorCodeUpstreamUnavailable {
+		t.Errorf("Expected nonces to be refused while Redis is down, got %v", err)
+	}
+}
@@ -0,0 +1,189 @@
+package performer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/najnomics/crosscow-avs/pkg/depeg"
+	"github.com/najnomics/crosscow-avs/pkg/notify"
+)
+
+// TaskCompletedEvent is the data of task_completed events: the result as it is answered,
+// as JSON, or hex encoded when the task asked for ABI encoded results
+type TaskCompletedEvent struct {
+	Result    json.RawMessage `json:"result,omitempty"`
+	ResultABI hexutil.Bytes   `json:"result_abi,omitempty"`
+}
+
+func newTaskCompletedEvent(result []byte) *TaskCompletedEvent {
+	if json.Valid(result) {
+		return &TaskCompletedEvent{Result: result}
+	}
+	return &TaskCompletedEvent{ResultABI: result}
+}
+
+// ExecutionFailedEvent is the data of execution_failed events. Error is set when the
+// transactions of a rebalance could not be submitted, Rebalance when they reverted.
+type ExecutionFailedEvent struct {
+	Error     *ErrorReport              `json:"error,omitempty"`
+	Rebalance *RebalanceExecutionResult `json:"rebalance,omitempty"`
+}
+
+// AnomalyEvent is the data of anomaly_detected events. Market is set for a supply rate
+// failing anomaly checks, Rebalance for a rebalance whose balances did not move as
+// planned.
+type AnomalyEvent struct {
+	Market    *YieldMonitoringResult    `json:"market,omitempty"`
+	Rebalance *RebalanceExecutionResult `json:"rebalance,omitempty"`
+}
+
+// DepegEvent is the data of depeg_detected events
+type DepegEvent struct {
+	Depeg *DepegMonitoringResult `json:"depeg"`
+}
+
+// CircuitOpenEvent is the data of circuit_open events. Endpoint is the chain name of RPC
+// endpoints, and the scheme and host of others.
+type CircuitOpenEvent struct {
+	Policy   string `json:"policy"`
+	Endpoint string `json:"endpoint"`
+}
+
+// ResultInvalidatedEvent is the data of result_invalidated events: the block a reorg
+// removed, the hash of the block now at its height, and whether the task was executed
+// again
+type ResultInvalidatedEvent struct {
+	ChainID       uint64      `json:"chain_id"`
+	BlockNumber   uint64      `json:"block_number"`
+	BlockHash     common.Hash `json:"block_hash"`
+	CanonicalHash common.Hash `json:"canonical_hash"`
+	Depth         uint64      `json:"depth"`
+	Reexecuted    bool        `json:"reexecuted"`
+}
+
+// TransferStuckEvent is the data of transfer_stuck events: the recovery that escalated
+// the transfer
+type TransferStuckEvent struct {
+	Recovery *TransferRecoveryResult `json:"recovery"`
+}
+
+// notify raises event as an event of task t
+func (yip *YieldIntelligencePerformer) notify(t *performerV1.TaskRequest, payload *TaskPayload, event notify.Event) {
+	event.TaskID, event.TaskType = string(t.TaskId), string(payload.Type)
+	yip.notifier.Notify(event)
+}
+
+// notifyOutcome raises the events of the outcome of a task or batched task: rebalances
+// that moved funds, anomalous rates and rebalances, depegs, failed executions and stuck
+// transfers. Failed executions, depegs and stuck transfers are alerts.
+func (yip *YieldIntelligencePerformer) notifyOutcome(t *performerV1.TaskRequest, payload *TaskPayload, result interface{}, err error) {
+	if err != nil {
+		if taskErr := classifyError(err); taskErr.Code == ErrorCodeExecutionFailed {
+			report := ErrorReport{Code: taskErr.Code, Message: taskErr.Err.Error(), Retryable: taskErr.Code.Retryable()}
+			yip.notify(t, payload, notify.Event{
+				Type:     notify.EventExecutionFailed,
+				Severity: notify.SeverityCritical,
+				Key:      executionFailedKey(paramString(payload, "target_protocol"), paramUint64(payload, "target_chain")),
+				Summary:  fmt.Sprintf("Rebalance %s could not be submitted: %s", string(t.TaskId), report.Message),
+				Data:     &ExecutionFailedEvent{Error: &report},
+			})
+		}
+		return
+	}
+
+	switch result := result.(type) {
+	case *YieldMonitoringResult:
+		yip.notifyMarket(t, payload, result)
+	case *MultiYieldMonitoringResult:
+		for _, market := range result.Markets {
+			yip.notifyMarket(t, payload, market)
+		}
+	case *DepegMonitoringResult:
+		yip.notifyDepeg(t, payload, result)
+	case *TransferRecoveryResult:
+		if result.Action == RecoveryEscalated {
+			yip.notify(t, payload, transferStuckEvent(result))
+		}
+	case *RebalanceExecutionResult:
+		if result.DryRun {
+			return
+		}
+		yip.notify(t, payload, notify.Event{Type: notify.EventRebalanceExecuted, Data: result})
+		switch result.Status {
+		case ResultStatusReverted:
+			var targetChain uint64
+			if result.Execution != nil {
+				targetChain = result.Execution.TargetChain
+			}
+			yip.notify(t, payload, notify.Event{
+				Type:     notify.EventExecutionFailed,
+				Severity: notify.SeverityCritical,
+				Key:      executionFailedKey(result.TargetProtocol, targetChain),
+				Summary:  fmt.Sprintf("Rebalance of %s USDC to %s on chain %d reverted", result.Amount.String(), result.TargetProtocol, targetChain),
+				Data:     &ExecutionFailedEvent{Rebalance: result},
+			})
+		case ResultStatusAnomalous:
+			yip.notify(t, payload, notify.Event{Type: notify.EventAnomalyDetected, Data: &AnomalyEvent{Rebalance: result}})
+		}
+	}
+}
+
+func (yip *YieldIntelligencePerformer) notifyMarket(t *performerV1.TaskRequest, payload *TaskPayload, market *YieldMonitoringResult) {
+	if market.Status == ResultStatusAnomalous {
+		yip.notify(t, payload, notify.Event{Type: notify.EventAnomalyDetected, Data: &AnomalyEvent{Market: market}})
+	}
+}
+
+// notifyDepeg raises a depeg_detected alert when the price of the token, on average or
+// on any chain, deviated from the peg by at least the warning threshold. High and
+// critical deviations are critical alerts.
+func (yip *YieldIntelligencePerformer) notifyDepeg(t *performerV1.TaskRequest, payload *TaskPayload, result *DepegMonitoringResult) {
+	severity, price, deviation, where := result.Severity, result.Price, result.DeviationBps, "across chains"
+	for _, c := range result.Chains {
+		if c.Severity.AtLeast(severity) && !severity.AtLeast(c.Severity) {
+			severity, price, deviation, where = c.Severity, c.Price, c.DeviationBps, fmt.Sprintf("on chain %d", c.ChainID)
+		}
+	}
+	if !severity.AtLeast(depeg.SeverityWarning) {
+		return
+	}
+	alert := notify.SeverityWarning
+	if severity.AtLeast(depeg.SeverityHigh) {
+		alert = notify.SeverityCritical
+	}
+	yip.notify(t, payload, notify.Event{
+		Type:     notify.EventDepegDetected,
+		Severity: alert,
+		Key:      "depeg_detected:" + result.Token,
+		Summary: fmt.Sprintf("%s depeg %s %s: price %s, %s bps off peg, recommended action %s",
+			result.Token, severity, where, price.String(), deviation.String(), result.RecommendedAction),
+		Data: &DepegEvent{Depeg: result},
+	})
+}
+
+// executionFailedKey keys the alerts of failed executions by their target market, so a
+// market failing every rebalance alerts once
+func executionFailedKey(protocol string, chainID uint64) string {
+	return fmt.Sprintf("execution_failed:%s:%d", protocol, chainID)
+}
+
+// NotifyCircuitOpen returns the hook raising circuit_open alerts on n as circuits open
+func NotifyCircuitOpen(n *notify.Notifier) func(policy, endpoint string) {
+	return func(policy, endpoint string) {
+		// endpoints of HTTP APIs are their URLs, which may carry API keys
+		if u, err := url.Parse(endpoint); err == nil && u.Host != "" {
+			endpoint = u.Scheme + "://" + u.Host
+		}
+		n.Notify(notify.Event{
+			Type:     notify.EventCircuitOpen,
+			Severity: notify.SeverityWarning,
+			Key:      "circuit_open:" + policy + ":" + endpoint,
+			Summary:  fmt.Sprintf("Circuit breaker of %s endpoint %s opened after repeated failures; calls to it are rejected until it recovers", policy, endpoint),
+			Data:     &CircuitOpenEvent{Policy: policy, Endpoint: endpoint},
+		})
+	}
+}
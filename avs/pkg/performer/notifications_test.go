@@ -0,0 +1,168 @@
+package performer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/najnomics/crosscow-avs/pkg/notify"
+	"github.com/najnomics/crosscow-avs/pkg/pricefeed"
+	"go.uber.org/zap"
+)
+
+// eventSink keeps the events delivered to it
+type eventSink struct {
+	mu     sync.Mutex
+	events []notify.Event
+	bodies [][]byte
+}
+
+func (s *eventSink) Name() string { return "test" }
+
+func (s *eventSink) Deliver(ctx context.Context, event *notify.Event, body []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, *event)
+	s.bodies = append(s.bodies, body)
+	return nil
+}
+
+// withEventSink raises the events of performer on a sink, returning a function closing
+// the notifier and returning the events delivered
+func withEventSink(t *testing.T, performer *YieldIntelligencePerformer) func() ([]notify.Event, [][]byte) {
+	t.Helper()
+	sink := &eventSink{}
+	notifier := notify.New(16, nil, zap.NewNop())
+	notifier.Subscribe(sink)
+	WithNotifier(notifier)(performer)
+	return func() ([]notify.Event, [][]byte) {
+		if err := notifier.Close(context.Background()); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+		return sink.events, sink.bodies
+	}
+}
+
+func eventTypes(events []notify.Event) []notify.EventType {
+	types := make([]notify.EventType, len(events))
+	for i, event := range events {
+		types[i] = event.Type
+	}
+	return types
+}
+
+func Test_RebalanceRaisesEvents(t *testing.T) {
+	performer, account, ethereum := newSubmittingPerformer(t)
+	ethereum.AutoMine()
+	events := withEventSink(t, performer)
+
+	var result RebalanceExecutionResult
+	if err := runRebalanceTask(t, performer, "notified", `{"type":"rebalance_execution","parameters":{
+		"user_address":"`+account.Hex()+`","nonce":"1","amount":"1000000000","target_protocol":"aave_v3","target_chain":1}}`, &result); err != nil {
+		t.Fatalf("rebalance_execution failed: %v", err)
+	}
+	// answering a redelivery from the store raises nothing
+	if err := runRebalanceTask(t, performer, "notified", `{"type":"rebalance_execution","parameters":{
+		"user_address":"`+account.Hex()+`","nonce":"1","amount":"1000000000","target_protocol":"aave_v3","target_chain":1}}`, nil); err != nil {
+		t.Fatalf("rebalance_execution redelivery failed: %v", err)
+	}
+
+	raised, bodies := events()
+	types := eventTypes(raised)
+	if len(raised) < 2 || types[0] != notify.EventRebalanceExecuted || types[len(types)-1] != notify.EventTaskCompleted {
+		t.Fatalf("Expected the rebalance to be raised before the task completed, got %v", types)
+	}
+	for _, event := range raised {
+		if event.TaskID != "notified" || event.TaskType != string(TaskTypeRebalanceExecution) {
+			t.Errorf("Expected events to name the task, got %+v", event)
+		}
+	}
+	var executed struct {
+		Data RebalanceExecutionResult `json:"data"`
+	}
+	if err := json.Unmarshal(bodies[0], &executed); err != nil {
+		t.Fatalf("Failed to decode event: %v", err)
+	}
+	if executed.Data.Execution == nil || executed.Data.Execution.ID != result.Execution.ID {
+		t.Errorf("Expected the event to carry the execution, got %s", bodies[0])
+	}
+}
+
+func Test_FailedExecutionRaisesEvent(t *testing.T) {
+	performer, account, ethereum := newSubmittingPerformer(t)
+	ethereum.FailSends(errors.New("insufficient funds for gas"))
+	events := withEventSink(t, performer)
+
+	runFailedRebalance(t, performer, "unsent", `{"type":"rebalance_execution","parameters":{
+		"user_address":"`+account.Hex()+`","nonce":"1","amount":"1000000000","target_protocol":"aave_v3","target_chain":1}}`)
+
+	raised, bodies := events()
+	if types := eventTypes(raised); len(types) != 2 || types[0] != notify.EventExecutionFailed || types[1] != notify.EventTaskCompleted {
+		t.Fatalf("Expected the failed execution to be raised, got %v", types)
+	}
+	var failed struct {
+		Data ExecutionFailedEvent `json:"data"`
+	}
+	if err := json.Unmarshal(bodies[0], &failed); err != nil {
+		t.Fatalf("Failed to decode event: %v", err)
+	}
+	if failed.Data.Error == nil || failed.Data.Error.Code != ErrorCodeExecutionFailed {
+		t.Errorf("Expected the event to carry the error, got %s", bodies[0])
+	}
+}
+
+func Test_DepegRaisesAlert(t *testing.T) {
+	sources := []pricefeed.Source{
+		&fakePriceSource{name: "chainlink:1", kind: pricefeed.SourceKindOracle, chainID: 1, price: "0.99990000"},
+		&fakePriceSource{name: "chainlink:42161", kind: pricefeed.SourceKindOracle, chainID: 42161, price: "0.97500000"},
+	}
+	performer := NewYieldIntelligencePerformer(zap.NewNop(), WithPriceSources(sources))
+	events := withEventSink(t, performer)
+
+	for _, task := range []*performerV1.TaskRequest{
+		{TaskId: []byte("depegged"), Payload: []byte(`{"type":"depeg_monitoring","parameters":{"token":"USDC"}}`)},
+		{TaskId: []byte("pegged"), Payload: []byte(`{"type":"depeg_monitoring","parameters":{"token":"USDC","chain_ids":[1]}}`)},
+	} {
+		if _, err := performer.HandleTask(task); err != nil {
+			t.Fatalf("HandleTask failed: %v", err)
+		}
+	}
+
+	var alerts []notify.Event
+	raised, _ := events()
+	for _, event := range raised {
+		if event.Type == notify.EventDepegDetected {
+			alerts = append(alerts, event)
+		}
+	}
+	if len(alerts) != 1 {
+		t.Fatalf("Expected the arbitrum depeg alone to be raised, got %v", eventTypes(raised))
+	}
+	alert := alerts[0]
+	if alert.TaskID != "depegged" || alert.Severity != notify.SeverityCritical || alert.Key != "depeg_detected:USDC" || !strings.Contains(alert.Summary, "USDC depeg high") {
+		t.Errorf("Unexpected depeg alert %+v", alert)
+	}
+}
+
+func Test_CircuitOpenAlertHidesURLs(t *testing.T) {
+	sink := &eventSink{}
+	notifier := notify.New(1, nil, zap.NewNop())
+	notifier.Subscribe(sink)
+	NotifyCircuitOpen(notifier)("subgraph", "https://gateway.thegraph.com/api/secret-key/subgraphs/id/aave")
+	notifier.Close(context.Background())
+
+	if len(sink.events) != 1 {
+		t.Fatalf("Expected an alert, got %+v", sink.events)
+	}
+	alert := sink.events[0]
+	if alert.Type != notify.EventCircuitOpen || alert.Severity != notify.SeverityWarning || alert.Key != "circuit_open:subgraph:https://gateway.thegraph.com" {
+		t.Errorf("Unexpected circuit alert %+v", alert)
+	}
+	if strings.Contains(string(sink.bodies[0]), "secret-key") {
+		t.Errorf("Expected the URL path to be left out, got %s", sink.bodies[0])
+	}
+}
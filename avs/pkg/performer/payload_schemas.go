@@ -0,0 +1,32 @@
+package performer
+
+import (
+	"fmt"
+
+	"github.com/najnomics/crosscow-avs/pkg/taskschema"
+)
+
+// WithPayloadSchemas sets the JSON Schemas payloads are validated against before their
+// handlers see them. Defaults to the built-in schemas.
+func WithPayloadSchemas(set *taskschema.Set) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.schemas = set
+	}
+}
+
+// validateSchema checks payload against the schema of its type, which covers the types,
+// ranges and shapes of its parameters. Handlers check what needs the state of the
+// performer.
+func (yip *YieldIntelligencePerformer) validateSchema(payload *TaskPayload) error {
+	if _, ok := yip.schemas.Schema(string(payload.Type)); !ok {
+		return fmt.Errorf("unknown task type: %s", payload.Type)
+	}
+	decoded := map[string]interface{}{"type": string(payload.Type)}
+	if payload.Parameters != nil {
+		decoded["parameters"] = payload.Parameters
+	}
+	if payload.CorrelationID != "" {
+		decoded["correlation_id"] = payload.CorrelationID
+	}
+	return yip.schemas.Validate(string(payload.Type), decoded)
+}
@@ -0,0 +1,75 @@
+package performer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/taskschema"
+	"go.uber.org/zap"
+)
+
+func Test_EveryTaskTypeHasASchema(t *testing.T) {
+	set := taskschema.Default()
+	for _, taskType := range taskTypes {
+		if _, ok := set.Schema(string(taskType)); !ok {
+			t.Errorf("Expected a payload schema for %s", taskType)
+		}
+	}
+}
+
+func Test_PayloadSchemasValidateBeforeHandlers(t *testing.T) {
+	dir := t.TempDir()
+	tightened := `{"properties":{"parameters":{"properties":{"anomaly_sigma":{"maximum":5}}}}}`
+	if err := os.WriteFile(filepath.Join(dir, "yield_monitoring.json"), []byte(tightened), 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	set, err := taskschema.NewFromConfig(taskschema.Config{Dir: dir})
+	if err != nil {
+		t.Fatalf("NewFromConfig failed: %v", err)
+	}
+	performer := NewYieldIntelligencePerformer(zap.NewNop(), WithAdapters(adapters.NewRegistry(newFakeAaveAdapter())), WithPayloadSchemas(set))
+
+	testCases := map[string]struct {
+		payload string
+		valid   bool
+	}{
+		"valid":            {payload: `{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","chain_id":1,"token":"USDC","anomaly_sigma":3}}`, valid: true},
+		"wrong type":       {payload: `{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","chain_id":"1","token":"USDC"}}`},
+		"tightened":        {payload: `{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","chain_id":1,"token":"USDC","anomaly_sigma":6}}`},
+		"batch sub-task":   {payload: `{"type":"batch","parameters":{"tasks":[{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","chain_id":0,"token":"USDC"}}]}}`},
+		"unknown type":     {payload: `{"type":"balances","parameters":{}}`},
+		"missing argument": {payload: `{"type":"cross_chain_yield_check","parameters":{"source_chain":1,"amount":"1"}}`},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			err := performer.ValidateTask(&performerV1.TaskRequest{TaskId: []byte(name), Payload: []byte(tc.payload)})
+			if tc.valid {
+				if err != nil {
+					t.Fatalf("Expected the payload to validate: %v", err)
+				}
+				return
+			}
+			var taskErr *TaskError
+			if !errors.As(err, &taskErr) || taskErr.Code != ErrorCodeValidation {
+				t.Errorf("Expected a validation error, got %v", err)
+			}
+		})
+	}
+}
+
+func Test_ValidatorsRejectPayloadsSkippingTheSchema(t *testing.T) {
+	performer := NewYieldIntelligencePerformer(zap.NewNop(), WithAdapters(adapters.NewRegistry(newFakeAaveAdapter())))
+
+	for name, parameters := range map[TaskType]map[string]interface{}{
+		TaskTypeBatch:                  {"tasks": map[string]interface{}{}},
+		TaskTypeAllocationOptimization: {"amount": "1000000000", "token": "USDC", "transfer_costs": map[string]interface{}{"8453": 5.0}},
+	} {
+		if err := performer.validatePayload(&TaskPayload{Type: name, Parameters: parameters}); err == nil {
+			t.Errorf("%s: expected parameters of the wrong type to be rejected", name)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package performer
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+	"github.com/najnomics/crosscow-avs/pkg/ledger"
+)
+
+const defaultPerformanceWindowHours = 30 * 24
+
+// RebalanceOutcome is what one rebalance contributed to a performance report. Rates are
+// annual percentages, amounts are in USDC. GasCost leaves out the gas that could not be
+// priced, in which case GasPriced is false.
+type RebalanceOutcome struct {
+	ExecutionID    string            `json:"execution_id"`
+	UserAddress    string            `json:"user_address"`
+	SourceProtocol string            `json:"source_protocol,omitempty"`
+	SourceChain    uint64            `json:"source_chain"`
+	TargetProtocol string            `json:"target_protocol"`
+	TargetChain    uint64            `json:"target_chain"`
+	Amount         canonical.Decimal `json:"amount"`
+	ExecutedAt     uint64            `json:"executed_at"`
+	GasCost        canonical.Decimal `json:"gas_cost"`
+	GasPriced      bool              `json:"gas_priced"`
+	BridgeFee      canonical.Decimal `json:"bridge_fee"`
+	RateBefore     canonical.Decimal `json:"rate_before"`
+	RateAfter      canonical.Decimal `json:"rate_after"`
+	YieldUplift    canonical.Decimal `json:"yield_uplift"`
+	NetBenefit     canonical.Decimal `json:"net_benefit"`
+}
+
+// PerformanceReportResult is the result of a performance_report task: the profit and
+// loss of the rebalances the performer executed, over the window from From to To. Costs
+// count in the window a rebalance was executed in, while its yield uplift accrues for as
+// long as the funds stay in its target market. The report is partial when the gas of
+// some rebalances could not be priced.
+type PerformanceReportResult struct {
+	From        uint64             `json:"from"`
+	To          uint64             `json:"to"`
+	Rebalances  int                `json:"rebalances"`
+	Volume      canonical.Decimal  `json:"volume"`
+	GasCost     canonical.Decimal  `json:"gas_cost"`
+	BridgeFees  canonical.Decimal  `json:"bridge_fees"`
+	YieldUplift canonical.Decimal  `json:"yield_uplift"`
+	NetBenefit  canonical.Decimal  `json:"net_benefit"`
+	UnpricedGas int                `json:"unpriced_gas"`
+	Outcomes    []RebalanceOutcome `json:"outcomes"`
+	Status      ResultStatus       `json:"status"`
+}
+
+// handlePerformanceReport sums the outcomes the ledger recorded over the last
+// window_hours, of the rebalances of user_address when set
+func (yip *YieldIntelligencePerformer) handlePerformanceReport(ctx context.Context, t *performerV1.TaskRequest, payload *TaskPayload) (interface{}, error) {
+	yip.log(ctx).Sugar().Infow("Processing performance report task")
+
+	windowHours := paramUint64(payload, "window_hours")
+	if windowHours == 0 {
+		windowHours = defaultPerformanceWindowHours
+	}
+	entries, err := yip.ledger.Entries(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the ledger: %w", err)
+	}
+	if user := paramString(payload, "user_address"); user != "" {
+		owner := common.HexToAddress(user)
+		kept := entries[:0]
+		for _, entry := range entries {
+			if entry.Owner == owner {
+				kept = append(kept, entry)
+			}
+		}
+		entries = kept
+	}
+
+	to := time.Now()
+	report := ledger.Summarize(entries, to.Add(-time.Duration(windowHours)*time.Hour), to)
+	result := &PerformanceReportResult{
+		From:        uint64(report.From.Unix()),
+		To:          uint64(report.To.Unix()),
+		Rebalances:  report.Rebalances,
+		Volume:      usdcAmount(report.Volume),
+		GasCost:     usdcAmount(report.GasCost),
+		BridgeFees:  usdcAmount(report.BridgeFees),
+		YieldUplift: usdcAmount(report.YieldUplift),
+		NetBenefit:  usdcAmount(report.NetBenefit),
+		UnpricedGas: report.UnpricedGas,
+		Outcomes:    make([]RebalanceOutcome, 0, len(report.Outcomes)),
+		Status:      ResultStatusCompleted,
+	}
+	for _, outcome := range report.Outcomes {
+		gas, _ := outcome.GasUSDC()
+		result.Outcomes = append(result.Outcomes, RebalanceOutcome{
+			ExecutionID:    outcome.ExecutionID,
+			UserAddress:    outcome.Owner.Hex(),
+			SourceProtocol: outcome.SourceProtocol,
+			SourceChain:    outcome.SourceChain,
+			TargetProtocol: outcome.TargetProtocol,
+			TargetChain:    outcome.TargetChain,
+			Amount:         usdcAmount(outcome.Amount),
+			ExecutedAt:     uint64(outcome.ExecutedAt.Unix()),
+			GasCost:        usdcAmount(gas),
+			GasPriced:      outcome.GasPriced,
+			BridgeFee:      usdcAmount(outcome.BridgeFee),
+			RateBefore:     ratePercent(outcome.RateBefore),
+			RateAfter:      ratePercent(outcome.RateAfter),
+			YieldUplift:    usdcAmount(outcome.YieldUplift),
+			NetBenefit:     usdcAmount(outcome.NetBenefit),
+		})
+	}
+	if report.UnpricedGas > 0 {
+		result.Status = ResultStatusPartial
+	}
+	return result, nil
+}
+
+// sourceRate reads the supply rate of the market route withdraws from, for the ledger to
+// compare with that of its target. It is nil without a ledger, for routes starting from
+// the wallet, which earns nothing, and when the market cannot be read.
+func (yip *YieldIntelligencePerformer) sourceRate(ctx context.Context, route *rebalanceRoute) *float64 {
+	if yip.ledger == nil {
+		return nil
+	}
+	rate := 0.0
+	if route.sourceProtocol != "" {
+		state, err := yip.readMarket(ctx, market{protocol: route.sourceProtocol, chainID: route.sourceChain})
+		if err != nil {
+			yip.log(ctx).Sugar().Warnw("Failed to read the source rate of the rebalance", "error", err)
+			return nil
+		}
+		rate = state.Pool.SupplyRate()
+	}
+	return &rate
+}
+
+// recordOutcome records the gas, the bridge fee, the rates and the target position of a
+// verified rebalance in the ledger. Gas is priced in USDC at the current price of each
+// chain's gas token. When the rate before the rebalance is unknown it is taken to be the
+// rate after, so the rebalance only counts its costs.
+func (yip *YieldIntelligencePerformer) recordOutcome(ctx context.Context, route *rebalanceRoute, record *executionRecord) {
+	if yip.ledger == nil {
+		return
+	}
+	execution := record.Execution
+	entry := ledger.Entry{
+		ExecutionID:    execution.ID,
+		Owner:          route.user,
+		SourceProtocol: route.sourceProtocol,
+		SourceChain:    route.sourceChain,
+		TargetProtocol: route.targetProtocol,
+		TargetChain:    route.targetChain,
+		Amount:         route.amount,
+		ExecutedAt:     time.Now().UTC(),
+		Gas:            []ledger.GasCost{},
+		BridgeFee:      new(big.Int),
+	}
+
+	target, err := yip.readMarket(ctx, market{protocol: route.targetProtocol, chainID: route.targetChain})
+	if err != nil {
+		yip.log(ctx).Sugar().Warnw("Failed to read the target rate of the rebalance", "error", err)
+	} else {
+		entry.RateAfter = target.Pool.SupplyRate()
+	}
+	entry.RateBefore = entry.RateAfter
+	if record.RateBefore != nil && err == nil {
+		entry.RateBefore = *record.RateBefore
+	}
+
+	// the target position grew by what reached it, the rest was lost on the way
+	for _, check := range execution.BalanceChecks {
+		if check.ChainID == route.targetChain && check.Holding == route.targetProtocol {
+			entry.Position = usdcBaseUnits(check.After)
+			if lost := new(big.Int).Sub(route.amount, usdcBaseUnits(check.Change)); lost.Sign() > 0 {
+				entry.BridgeFee = lost
+			}
+		}
+	}
+
+	gas := map[uint64]*ledger.GasCost{}
+	for _, tx := range execution.Transactions {
+		if tx.EffectiveGasPrice == nil {
+			continue
+		}
+		cost, ok := gas[tx.ChainID]
+		if !ok {
+			cost = &ledger.GasCost{ChainID: tx.ChainID, CostWei: new(big.Int)}
+			gas[tx.ChainID] = cost
+		}
+		price := new(big.Rat).Mul(tx.EffectiveGasPrice.Rat(), new(big.Rat).SetInt64(1e9))
+		cost.GasUsed += tx.GasUsed
+		cost.CostWei.Add(cost.CostWei, new(big.Int).Mul(new(big.Int).Quo(price.Num(), price.Denom()), new(big.Int).SetUint64(tx.GasUsed)))
+	}
+	for _, cost := range gas {
+		priced, err := yip.ledger.PriceGas(ctx, cost.ChainID, cost.CostWei)
+		if err != nil {
+			yip.log(ctx).Sugar().Warnw("Failed to price rebalance gas", "chainId", cost.ChainID, "error", err)
+		}
+		cost.CostUSDC = priced
+		entry.Gas = append(entry.Gas, *cost)
+	}
+	sort.Slice(entry.Gas, func(i, j int) bool { return entry.Gas[i].ChainID < entry.Gas[j].ChainID })
+
+	if err := yip.ledger.Record(ctx, entry); err != nil {
+		yip.log(ctx).Sugar().Errorw("Failed to record rebalance outcome", "executionId", execution.ID, "error", err)
+	}
+}
+
+func (yip *YieldIntelligencePerformer) validatePerformanceReportTask(payload *TaskPayload) error {
+	if yip.ledger == nil {
+		return fmt.Errorf("performance reports are not configured on this performer")
+	}
+	return nil
+}
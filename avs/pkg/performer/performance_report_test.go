@@ -0,0 +1,58 @@
+package performer
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/najnomics/crosscow-avs/pkg/ledger"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+)
+
+func Test_PerformanceReport(t *testing.T) {
+	// the deposit credits 998 USDC of 1000
+	performer, account, ethereum := newSubmittingPerformer(t, big.NewInt(0), big.NewInt(998_000_000))
+	ethereum.AutoMine()
+
+	var report PerformanceReportResult
+	if err := runYieldMonitoring(t, performer, "report-0", `{"type":"performance_report","parameters":{}}`, &report); err == nil {
+		t.Errorf("Expected a performance report without a ledger to be rejected")
+	}
+
+	// no gas token is priced
+	WithLedger(ledger.New(ledger.Config{Enabled: true}, store.NewMemoryKV(), nil))(performer)
+	result := runDryRun(t, performer, `{"type":"rebalance_execution","parameters":{
+		"user_address":"`+account.Hex()+`","nonce":"1","amount":"1000000000","target_protocol":"aave_v3","target_chain":1,"max_slippage_bps":50}}`)
+	if !result.Execution.Verified {
+		t.Fatalf("Expected the rebalance to be verified, got %+v", result.Execution)
+	}
+
+	if err := runYieldMonitoring(t, performer, "report-1", `{"type":"performance_report","parameters":{"window_hours":24}}`, &report); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	if report.Rebalances != 1 || report.Volume.String() != "1000.000000" || report.BridgeFees.String() != "2.000000" {
+		t.Errorf("Expected one rebalance of 1000 USDC losing 2 USDC, got %+v", report)
+	}
+	if report.UnpricedGas != 1 || report.GasCost.String() != "0.000000" || report.Status != ResultStatusPartial {
+		t.Errorf("Expected the unpriced gas to leave the report partial, got %+v", report)
+	}
+	if len(report.Outcomes) != 1 || report.Outcomes[0].UserAddress != account.Hex() || report.Outcomes[0].NetBenefit.String() != "-2.000000" {
+		t.Errorf("Expected the rebalance to cost its bridge fee so far, got %+v", report.Outcomes)
+	}
+
+	if err := runYieldMonitoring(t, performer, "report-2", `{"type":"performance_report","parameters":{"user_address":"0x00000000000000000000000000000000000000aa"}}`, &report); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	if report.Rebalances != 0 || len(report.Outcomes) != 0 || report.Status != ResultStatusCompleted {
+		t.Errorf("Expected no rebalance of another account, got %+v", report)
+	}
+
+	for name, params := range map[string]string{
+		"window":  `{"window_hours":0}`,
+		"long":    `{"window_hours":9000}`,
+		"address": `{"user_address":"abc"}`,
+	} {
+		if err := runYieldMonitoring(t, performer, name, `{"type":"performance_report","parameters":`+params+`}`, &report); err == nil {
+			t.Errorf("Expected an invalid %s to be rejected", name)
+		}
+	}
+}
@@ -0,0 +1,1343 @@
+// Package performer implements the USDC Yield Intelligence performer: it validates the
+// tasks Ponos hands it and runs their handlers against the adapters, stores and services
+// it is built with. The binary in cmd wires those from the config and serves it.
+package performer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/addressbook"
+	"github.com/najnomics/crosscow-avs/pkg/anomaly"
+	"github.com/najnomics/crosscow-avs/pkg/attestation"
+	"github.com/najnomics/crosscow-avs/pkg/auth"
+	"github.com/najnomics/crosscow-avs/pkg/bridge"
+	"github.com/najnomics/crosscow-avs/pkg/cache"
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+	"github.com/najnomics/crosscow-avs/pkg/cctp"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/crossval"
+	"github.com/najnomics/crosscow-avs/pkg/evidence"
+	"github.com/najnomics/crosscow-avs/pkg/features"
+	"github.com/najnomics/crosscow-avs/pkg/flashloan"
+	"github.com/najnomics/crosscow-avs/pkg/gaswindow"
+	"github.com/najnomics/crosscow-avs/pkg/hysteresis"
+	"github.com/najnomics/crosscow-avs/pkg/incentives"
+	"github.com/najnomics/crosscow-avs/pkg/indexer"
+	"github.com/najnomics/crosscow-avs/pkg/killswitch"
+	"github.com/najnomics/crosscow-avs/pkg/ledger"
+	"github.com/najnomics/crosscow-avs/pkg/logging"
+	"github.com/najnomics/crosscow-avs/pkg/notify"
+	"github.com/najnomics/crosscow-avs/pkg/operator"
+	"github.com/najnomics/crosscow-avs/pkg/permit"
+	"github.com/najnomics/crosscow-avs/pkg/policy"
+	"github.com/najnomics/crosscow-avs/pkg/positions"
+	"github.com/najnomics/crosscow-avs/pkg/pricefeed"
+	"github.com/najnomics/crosscow-avs/pkg/quota"
+	"github.com/najnomics/crosscow-avs/pkg/recovery"
+	"github.com/najnomics/crosscow-avs/pkg/redis"
+	"github.com/najnomics/crosscow-avs/pkg/reorg"
+	"github.com/najnomics/crosscow-avs/pkg/reputation"
+	"github.com/najnomics/crosscow-avs/pkg/resultsize"
+	"github.com/najnomics/crosscow-avs/pkg/scan"
+	"github.com/najnomics/crosscow-avs/pkg/security"
+	"github.com/najnomics/crosscow-avs/pkg/sequencer"
+	"github.com/najnomics/crosscow-avs/pkg/simulate"
+	"github.com/najnomics/crosscow-avs/pkg/snapshot"
+	"github.com/najnomics/crosscow-avs/pkg/stability"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+	"github.com/najnomics/crosscow-avs/pkg/taskqueue"
+	"github.com/najnomics/crosscow-avs/pkg/taskschema"
+	"github.com/najnomics/crosscow-avs/pkg/tokens"
+	"github.com/najnomics/crosscow-avs/pkg/tracing"
+	"github.com/najnomics/crosscow-avs/pkg/txmgr"
+	"github.com/najnomics/crosscow-avs/pkg/userop"
+	"github.com/najnomics/crosscow-avs/pkg/workerpool"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+)
+
+// TaskType represents the different types of USDC Yield Intelligence tasks
+type TaskType string
+
+const (
+	TaskTypeYieldMonitoring        TaskType = "yield_monitoring"
+	TaskTypeCrossChainYieldCheck   TaskType = "cross_chain_yield_check"
+	TaskTypeRebalanceExecution     TaskType = "rebalance_execution"
+	TaskTypeRiskAssessment         TaskType = "risk_assessment"
+	TaskTypeLiquidityDepthAnalysis TaskType = "liquidity_depth_analysis"
+	TaskTypeDepegMonitoring        TaskType = "depeg_monitoring"
+	TaskTypeAPYForecast            TaskType = "apy_forecast"
+	TaskTypeBatch                  TaskType = "batch"
+	TaskTypeAllocationOptimization TaskType = "allocation_optimization"
+	TaskTypeProtocolIncidentCheck  TaskType = "protocol_incident_check"
+	TaskTypePositionReconciliation TaskType = "position_reconciliation"
+	TaskTypeYieldRanking           TaskType = "yield_ranking"
+	TaskTypeRebalancePlan          TaskType = "rebalance_plan"
+	TaskTypeRebalanceCommit        TaskType = "rebalance_commit"
+	TaskTypeResumeExecution        TaskType = "resume_execution"
+	TaskTypeCapabilities           TaskType = "capabilities"
+	TaskTypeFullMarketSnapshot     TaskType = "full_market_snapshot"
+	TaskTypePerformanceReport      TaskType = "performance_report"
+	TaskTypeAccrualVerification    TaskType = "accrual_verification"
+	TaskTypeSelfReport             TaskType = "self_report"
+	TaskTypeStressScenario         TaskType = "stress_scenario"
+	TaskTypeTransferRecovery       TaskType = "transfer_recovery"
+)
+
+// TaskPayload represents the structure of task payload data
+type TaskPayload struct {
+	Type       TaskType               `json:"type"`
+	Parameters map[string]interface{} `json:"parameters"`
+
+	// CorrelationID is logged with every line of the task, so the submitter can trace it
+	CorrelationID string `json:"correlation_id,omitempty"`
+}
+
+// parseTaskPayload extracts and parses the task payload from TaskRequest. Signed payloads
+// are unwrapped without verifying them, see authorizeTask.
+func parseTaskPayload(t *performerV1.TaskRequest) (*TaskPayload, error) {
+	raw, _, err := auth.Unwrap(t.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse task payload: %w", err)
+	}
+	var payload TaskPayload
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return nil, fmt.Errorf("failed to parse task payload: %w", err)
+	}
+	return &payload, nil
+}
+
+// YieldIntelligencePerformer implements the Hourglass Performer interface for USDC Yield tasks.
+// This offchain binary is run by Operators running the Hourglass Executor. It contains
+// the business logic of the USDC Yield Intelligence AVS and performs work based on tasks sent to it.
+//
+// The Hourglass Aggregator ingests tasks from the TaskMailbox and distributes work
+// to Executors configured to run the Yield Intelligence Performer. Performers execute the work and
+// return the result to the Executor where the result is signed and returned to the
+// Aggregator to place in the outbox once the signing threshold is met.
+type YieldIntelligencePerformer struct {
+	logger   *zap.Logger
+	tasks    *store.TaskStore
+	dedup    *taskDeduplicator
+	shared   *sharedDeduplicator
+	adapters *adapters.Registry
+	prices   []pricefeed.Source
+	history  *store.SeriesStore
+
+	// thresholdsMu guards anomaly and crossval, which config reloads replace while tasks run
+	thresholdsMu sync.RWMutex
+	anomaly      anomaly.Config
+	crossval     crossval.Config
+	rateSources  []crossval.Source
+
+	// sourceStats scores how reliably each rate source agrees with the quorum
+	sourceStats *crossval.Tracker
+
+	// stability is the window supply rate volatility and drawdown are measured over
+	stability stability.Config
+
+	// hysteresis holds back cross-chain recommendations chasing transient rates
+	hysteresis hysteresis.Config
+
+	// incentives prices the reward emissions reported next to supply rates
+	incentives *incentives.Estimator
+
+	// positions tracks the performer account's positions rebalances withdraw from
+	positions *positions.Tracker
+
+	// attester signs the results of tasks asking for an attestation
+	attester *attestation.Attester
+
+	// snapshots pin monitoring reads to a reference block and snap the rates read
+	snapshots *snapshot.Snapper
+
+	// bridges ranks the routes cross-chain yield checks report between chains
+	bridges *bridge.Engine
+
+	// cctpFastFeeBps is the fee Circle keeps of fast transfers
+	cctpFastFeeBps uint64
+
+	// burns watches CCTP burns for delayed attestations, during which rebalances bridge
+	// as fallback configures
+	burns    *cctp.Monitor
+	fallback bridge.FallbackConfig
+
+	// attestations are read to mint the CCTP transfers of resumed executions
+	attestations *cctp.Attestations
+
+	// book locates the CCTP contracts rebalances burn and mint through
+	book *addressbook.Book
+
+	// stablecoins are the stablecoins other than USDC yield monitoring accepts
+	stablecoins tokens.Config
+
+	// policy blocks rebalances and recommendations breaking the operator's hard limits
+	policy *policy.Engine
+
+	// killswitch halts rebalances during incidents while monitoring is still served
+	killswitch *killswitch.Switch
+
+	// standing refuses execution tasks while the operator is not in good standing with
+	// the AVS. Nil when not checked.
+	standing *operator.Standing
+
+	// sequencers refuses moves to L2s whose sequencer is down or stalled. Nil when not
+	// checked.
+	sequencers *sequencer.Monitor
+
+	// plans keeps the rebalance plans produced until rebalance_commit tasks execute them
+	plans *planStore
+
+	// nonces keeps the last nonce each account's rebalances used, refusing replays
+	nonces *nonceStore
+
+	// executions keeps the rebalances submitted, so resume_execution tasks carry them on
+	// and stuck transfers are recovered
+	executions *executionStore
+
+	// recovery is when transfers of executions are stuck and how often their mints are
+	// submitted again
+	recovery recovery.Config
+
+	// locks keeps tasks, and replicas sharing a Redis, from executing a plan at once
+	locks *executionLocks
+
+	// notifier posts completed tasks, rebalances, anomalies and failed executions to the
+	// operator's webhooks
+	notifier *notify.Notifier
+
+	// pool bounds concurrent market reads of tasks spanning many protocols or chains
+	pool *workerpool.Pool
+
+	// cache answers repeated read-only tasks within their TTL without new RPC calls
+	cache *cache.Cache
+
+	// resultLimits bounds the size of encoded results, which tasks keep within by
+	// selecting fields or compressing their results
+	resultLimits resultsize.Config
+
+	// simulator previews rebalances requested as dry runs
+	simulator simulate.Simulator
+
+	// transactions submits rebalances directly when Circle Wallets are not used
+	transactions *txmgr.Set
+
+	// userOperations sends rebalances of smart accounts the performer account owns as
+	// ERC-4337 user operations
+	userOperations *userop.Backend
+
+	// security is the feed of audits and incidents risk assessments factor in
+	security *security.Feed
+
+	// indexer serves market state kept up to date from protocol events
+	indexer *indexer.Indexer
+
+	// scans keeps the latest full market snapshot for comparison and ranking tasks
+	scans *scan.Store
+
+	// ledger records the outcome of executed rebalances for performance reports
+	ledger *ledger.Ledger
+
+	// reputation compares results with the ones their quorum settled on in self reports
+	reputation *reputation.Reporter
+
+	// evidence keeps the artifacts against attestations disagreeing with the chain
+	evidence *evidence.Checker
+
+	// reorgs watches the blocks results were read at for reorgs invalidating them
+	reorgs *reorg.Watcher
+
+	// gasWindows delays rebalances that are not urgent to low gas windows
+	gasWindows *gaswindow.Scheduler
+
+	// flashLoans configures the helpers of rebalances with the flash_loan strategy
+	flashLoans flashloan.Config
+
+	// permits lets deposits spend EIP-2612 permits instead of approved allowances
+	permits permit.Config
+
+	// redactor hides secrets and, when configured, addresses in logged task parameters
+	redactor *logging.Redactor
+
+	// authorizer verifies the signatures of tasks and requires them for sensitive types
+	authorizer *auth.Verifier
+
+	// quotas bound how many tasks of each type start per minute and run at once
+	quotas *quota.Limiter
+
+	// queue bounds how many tasks of each type run at once, queueing the others
+	queue *taskqueue.Queue
+
+	// schemas check payloads declaratively before their handlers do
+	schemas *taskschema.Set
+
+	// features enables the experimental task types the operator opted into
+	features *features.Set
+
+	// lifecycle tracks tasks in flight so they are drained on shutdown
+	lifecycle *lifecycle
+
+	// build is the release capabilities tasks report
+	build BuildInfo
+}
+
+// PerformerOption configures optional dependencies of the performer
+type PerformerOption func(*YieldIntelligencePerformer)
+
+// WithTaskStore sets the store every received task, its state and result is recorded in.
+// Defaults to an in-memory store.
+func WithTaskStore(tasks *store.TaskStore) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.tasks = tasks
+	}
+}
+
+// WithAdapters sets the protocol adapters market data is read through. Defaults to an
+// empty registry, in which case tasks needing protocol data fail.
+func WithAdapters(registry *adapters.Registry) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.adapters = registry
+	}
+}
+
+// WithPriceSources sets the USDC price sources used for depeg monitoring
+func WithPriceSources(sources []pricefeed.Source) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.prices = sources
+	}
+}
+
+// WithRateHistory sets the store historical supply rates are kept in. Defaults to an
+// in-memory store.
+func WithRateHistory(history *store.SeriesStore) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.history = history
+	}
+}
+
+// WithAnomalyDetection sets the baseline and sigma thresholds fresh rates are checked
+// against. Defaults to anomaly.DefaultConfig.
+func WithAnomalyDetection(cfg anomaly.Config) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.anomaly = cfg
+	}
+}
+
+// WithStability sets the window the stability of supply rates is measured over. Defaults
+// to stability.DefaultConfig.
+func WithStability(cfg stability.Config) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.stability = cfg
+	}
+}
+
+// WithHysteresis holds back the moves cross-chain yield checks recommend until the
+// target has led the source long enough, and while the user's funds cool down from their
+// last move. Disabled by default.
+func WithHysteresis(cfg hysteresis.Config) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.hysteresis = cfg
+	}
+}
+
+// WithCrossValidation sets independent rate sources contract reads must agree with before
+// a rate is reported. Without sources, rates are reported unchecked.
+func WithCrossValidation(cfg crossval.Config, sources ...crossval.Source) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.crossval = cfg
+		yip.rateSources = sources
+	}
+}
+
+// WithIncentives reports the reward emissions of markets, priced by e, next to their
+// supply rate. Without an estimator no incentives are reported.
+func WithIncentives(e *incentives.Estimator) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.incentives = e
+	}
+}
+
+// WithPositions checks rebalance withdrawals against the positions t tracks, and
+// records the positions rebalances leave. Without a tracker withdrawals are not checked.
+func WithPositions(t *positions.Tracker) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.positions = t
+	}
+}
+
+// WithLedger records the gas, fees, rates and positions of every verified rebalance in l,
+// which performance_report tasks sum over a window and accrual_verification tasks check
+// the positions of. Without a ledger such tasks are rejected.
+func WithLedger(l *ledger.Ledger) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.ledger = l
+	}
+}
+
+// WithReputation compares the results of the performer with the ones their quorum
+// settled on, in self_report tasks and the admin API. Without a reporter self reports
+// leave agreement out.
+func WithReputation(r *reputation.Reporter) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.reputation = r
+	}
+}
+
+// WithEvidence serves the artifacts c keeps against attestations disagreeing with the
+// chain through the admin API. Without a checker there are none.
+func WithEvidence(c *evidence.Checker) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.evidence = c
+	}
+}
+
+// WithReorgs watches the snapshot reference blocks results were read at with w, which
+// hands the results reorgs invalidate back to Invalidate. Without a watcher results are
+// not watched.
+func WithReorgs(w *reorg.Watcher) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.reorgs = w
+	}
+}
+
+// WithGasWindows delays rebalances not marked urgent until s finds the chain of their
+// first transaction in a low gas window. Without a scheduler rebalances are sent at once.
+func WithGasWindows(s *gaswindow.Scheduler) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.gasWindows = s
+	}
+}
+
+// WithFlashLoans lets rebalances between markets of one chain move atomically through
+// the flash loan helpers of cfg when their task selects the flash_loan strategy
+func WithFlashLoans(cfg flashloan.Config) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.flashLoans = cfg
+	}
+}
+
+// WithPermits lets rebalances deposit into markets taking EIP-2612 permits with a permit
+// of the performer account signed as the deposit is sent, sparing the approval
+// transaction before it
+func WithPermits(cfg permit.Config) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.permits = cfg
+	}
+}
+
+// WithAttestation signs the results of tasks setting the attest parameter with the
+// operator key of a. Without an attester such tasks are rejected.
+func WithAttestation(a *attestation.Attester) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.attester = a
+	}
+}
+
+// WithSnapshots pins the reads of monitoring and risk tasks to the reference block s
+// resolves and snaps the supply rates read. Without it markets are read at the latest
+// block and block_number and block_hash are rejected.
+func WithSnapshots(s *snapshot.Snapper) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.snapshots = s
+	}
+}
+
+// WithBridgeRoutes sets the routes cross_chain_yield_check compares and how they are
+// scored, and the fee of fast CCTP transfers. Defaults to bridge.DefaultConfig; when
+// disabled no routes are reported.
+func WithBridgeRoutes(cfg bridge.Config) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.bridges = bridge.NewFromConfig(cfg)
+		yip.cctpFastFeeBps = cfg.CCTPFastFeeBps
+	}
+}
+
+// WithPolicy sets the rules rebalances and recommendations must keep to. Without an
+// engine, nothing is blocked.
+func WithPolicy(engine *policy.Engine) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.policy = engine
+	}
+}
+
+// WithKillSwitch sets the switch halting rebalances. Defaults to a switch halted only
+// through the admin API, whose halts are lost on restart.
+func WithKillSwitch(s *killswitch.Switch) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.killswitch = s
+	}
+}
+
+// WithStanding sets the check of the operator's standing with the AVS execution tasks
+// must pass. Without it, the standing is not checked.
+func WithStanding(s *operator.Standing) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.standing = s
+	}
+}
+
+// WithSequencerChecks sets the check of L2 sequencer health recommendations and
+// rebalances to L2s must pass. Without it, sequencers are not checked.
+func WithSequencerChecks(m *sequencer.Monitor) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.sequencers = m
+	}
+}
+
+// WithPlanStore sets the store rebalance plans are kept in until committed, and the
+// Redis the replicas of the performer claim commits through, if any. Defaults to an
+// in-memory store, claimed within the replica.
+func WithPlanStore(kv store.KV, shared *redis.Client) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.plans = newPlanStore(kv, shared)
+	}
+}
+
+// WithExecutionStore sets the store submitted rebalance executions are kept in. Defaults
+// to an in-memory store, which forgets them on restart.
+func WithExecutionStore(kv store.KV) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.executions = newExecutionStore(kv)
+	}
+}
+
+// WithTransferRecovery sets when the CCTP transfers of executions count as stuck and how
+// many times their mints are submitted again before they are escalated. Defaults to
+// recovery.DefaultConfig.
+func WithTransferRecovery(cfg recovery.Config) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.recovery = cfg
+	}
+}
+
+// WithNonceStore sets the store the nonces of rebalances are kept in, and the Redis the
+// replicas of the performer share them through instead, if any. Defaults to an in-memory
+// store, which forgets them on restart.
+func WithNonceStore(kv store.KV, shared *redis.Client) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.nonces = newNonceStore(kv, shared)
+	}
+}
+
+// WithNotifier sets the notifier events of tasks are raised on. Without it no events
+// are raised.
+func WithNotifier(n *notify.Notifier) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.notifier = n
+	}
+}
+
+// WithStablecoins lets yield_monitoring tasks name the stablecoins of cfg besides USDC,
+// when enabled
+func WithStablecoins(cfg tokens.Config) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.stablecoins = cfg
+	}
+}
+
+// WithBridgeFallback bridges rebalances through the fallback bridge of cfg while a burn
+// monitor tracks is unattested past its SLA. Without a monitor rebalances always use CCTP.
+func WithBridgeFallback(cfg bridge.FallbackConfig, monitor *cctp.Monitor) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.fallback = cfg
+		yip.burns = monitor
+	}
+}
+
+// WithAddressBook sets the contract addresses rebalances are built with, the embedded
+// address book by default
+func WithAddressBook(book *addressbook.Book) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.book = book
+	}
+}
+
+// WithAttestations sets Circle's attestation service, which resumed executions read to
+// mint their CCTP transfers. Without it mints stay pending.
+func WithAttestations(attestations *cctp.Attestations) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.attestations = attestations
+	}
+}
+
+// WithConcurrency sets how many markets a task reads at once. Defaults to
+// workerpool.DefaultLimit.
+func WithConcurrency(limit int) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.pool = workerpool.New(limit)
+	}
+}
+
+// WithResultCache sets the cache results of read-only tasks are kept in. Without a
+// cache every task is executed.
+func WithResultCache(c *cache.Cache) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.cache = c
+	}
+}
+
+// WithResultLimits sets the size encoded results are bounded to by task type. Defaults to
+// resultsize.DefaultConfig.
+func WithResultLimits(cfg resultsize.Config) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.resultLimits = cfg
+	}
+}
+
+// WithSimulator sets how rebalance dry runs are simulated. Without a simulator dry runs
+// are rejected.
+func WithSimulator(s simulate.Simulator) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.simulator = s
+	}
+}
+
+// WithTransactions makes rebalance_execution sign and submit its transactions with the
+// account of set instead of going through Circle Wallets
+func WithTransactions(set *txmgr.Set) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.transactions = set
+	}
+}
+
+// WithUserOperations lets rebalance_execution tasks with execution user_operation move
+// the funds of smart accounts owned by the performer account through b. It needs
+// WithTransactions, whose account signs the operations.
+func WithUserOperations(b *userop.Backend) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.userOperations = b
+	}
+}
+
+// WithSecurityFeed sets the feed of audits and incidents risk assessments factor in.
+// Without a feed the security record of protocols is not scored.
+func WithSecurityFeed(feed *security.Feed) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.security = feed
+	}
+}
+
+// WithIndexer serves market and risk state from ix while it is up to date. Without an
+// indexer every task reads the chain.
+func WithIndexer(ix *indexer.Indexer) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.indexer = ix
+	}
+}
+
+// WithMarketScans keeps the markets full_market_snapshot tasks read in store, for
+// cross_chain_yield_check, allocation_optimization and yield_ranking tasks to reuse
+// while fresh. Without a store every task reads its markets.
+func WithMarketScans(store *scan.Store) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.scans = store
+	}
+}
+
+// WithAuthorization rejects tasks not signed by a signer v accepts when their type
+// requires it. Without a verifier any task is run.
+func WithAuthorization(v *auth.Verifier) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.authorizer = v
+	}
+}
+
+// WithQuotas rejects tasks over the rate or concurrency limits of their type. Without a
+// limiter every task runs.
+func WithQuotas(l *quota.Limiter) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.quotas = l
+	}
+}
+
+// WithTaskQueue runs the tasks of each type within the bounds of its lane, queueing or
+// rejecting the tasks over them. Without a queue tasks run as soon as they arrive.
+func WithTaskQueue(q *taskqueue.Queue) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.queue = q
+	}
+}
+
+// WithSharedIdempotency deduplicates tasks delivered to several replicas of the
+// performer through the Redis they share. Without it, each replica deduplicates the
+// tasks it received.
+func WithSharedIdempotency(client *redis.Client) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.shared = newSharedDeduplicator(client)
+	}
+}
+
+// WithExecutionLocks leases the rebalance plans executed through the Redis the replicas
+// of the performer share, so no two replicas execute a plan at once, and refuses
+// executions while Redis is down. Without it, plans are only locked within the replica.
+func WithExecutionLocks(client *redis.Client) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.locks = newExecutionLocks(client)
+	}
+}
+
+// WithLogRedaction sets which task parameters are hidden in logs. Secrets always are.
+func WithLogRedaction(cfg logging.Config) PerformerOption {
+	return func(yip *YieldIntelligencePerformer) {
+		yip.redactor = logging.NewRedactor(cfg)
+	}
+}
+
+func NewYieldIntelligencePerformer(logger *zap.Logger, opts ...PerformerOption) *YieldIntelligencePerformer {
+	yip := &YieldIntelligencePerformer{
+		logger:      logger,
+		dedup:       newTaskDeduplicator(),
+		lifecycle:   newLifecycle(),
+		anomaly:     anomaly.DefaultConfig(),
+		crossval:    crossval.DefaultConfig(),
+		sourceStats: crossval.NewTracker(),
+		stability:   stability.DefaultConfig(),
+		bridges:     bridge.New(bridge.DefaultConfig()),
+		recovery:    recovery.DefaultConfig(),
+		build:       BuildInfo{Version: "dev"},
+
+		cctpFastFeeBps: bridge.DefaultConfig().CCTPFastFeeBps,
+		resultLimits:   resultsize.DefaultConfig(),
+	}
+	for _, opt := range opts {
+		opt(yip)
+	}
+	if yip.tasks == nil {
+		yip.tasks = store.NewTaskStore(store.NewMemoryKV())
+	}
+	if yip.book == nil {
+		yip.book = addressbook.Default()
+	}
+	if yip.history == nil {
+		yip.history = store.NewSeriesStore(store.NewMemoryKV())
+	}
+	if yip.nonces == nil {
+		yip.nonces = newNonceStore(store.NewMemoryKV(), nil)
+	}
+	if yip.executions == nil {
+		yip.executions = newExecutionStore(store.NewMemoryKV())
+	}
+	if yip.plans == nil {
+		yip.plans = newPlanStore(store.NewMemoryKV(), nil)
+	}
+	if yip.locks == nil {
+		yip.locks = newExecutionLocks(nil)
+	}
+	if yip.killswitch == nil {
+		yip.killswitch = killswitch.New(store.NewMemoryKV())
+	}
+	if yip.adapters == nil {
+		yip.adapters = adapters.NewRegistry()
+	}
+	if yip.pool == nil {
+		yip.pool = workerpool.New(workerpool.DefaultLimit)
+	}
+	if yip.redactor == nil {
+		yip.redactor = logging.NewRedactor(logging.DefaultConfig())
+	}
+	if yip.schemas == nil {
+		yip.schemas = taskschema.Default()
+	}
+	if yip.features == nil {
+		// flags are declared once each, so declaring them cannot fail
+		yip.features, _ = features.New(ExperimentalFeatures(), features.DefaultConfig())
+	}
+	return yip
+}
+
+func (yip *YieldIntelligencePerformer) ValidateTask(t *performerV1.TaskRequest) (err error) {
+	_, span := tracing.Start(context.Background(), "ValidateTask", attribute.String("task.id", string(t.TaskId)))
+	defer func() { tracing.End(span, err) }()
+
+	yip.logger.Sugar().Infow("Validating USDC Yield Intelligence task",
+		"task_id", string(t.TaskId),
+		"payload_size", len(t.Payload),
+	)
+
+	// ------------------------------------------------------------------------
+	// USDC Yield Intelligence Task Validation Logic
+	// ------------------------------------------------------------------------
+	// Validate that the task request data is well-formed for yield optimization operations
+
+	if err := yip.lifecycle.accepting(); err != nil {
+		return newTaskError(ErrorCodeShuttingDown, err)
+	}
+
+	if len(t.TaskId) == 0 {
+		return newTaskError(ErrorCodeValidation, fmt.Errorf("task ID cannot be empty"))
+	}
+
+	if len(t.Payload) == 0 {
+		return newTaskError(ErrorCodeValidation, fmt.Errorf("task payload cannot be empty"))
+	}
+
+	// Parse and validate task payload
+	payload, err := parseTaskPayload(t)
+	if err != nil {
+		return newTaskError(ErrorCodeValidation, fmt.Errorf("failed to parse task payload: %w", err))
+	}
+
+	span.SetAttributes(attribute.String("task.type", string(payload.Type)))
+
+	logger := yip.taskLogger(t, payload).Sugar()
+	logger.Debugw("Task parameters", "parameters", yip.redactor.Parameters(payload.Parameters))
+
+	if err := yip.checkEnabled(payload.Type); err != nil {
+		return err
+	}
+
+	if err := yip.authorizeTask(t, payload); err != nil {
+		return err
+	}
+
+	if err := yip.quotas.Check(string(payload.Type)); err != nil {
+		return newTaskError(ErrorCodeRateLimited, err)
+	}
+
+	if err := yip.queue.Check(string(payload.Type)); err != nil {
+		return newTaskError(ErrorCodeRateLimited, err)
+	}
+
+	if err := yip.validateSchema(payload); err != nil {
+		return newTaskError(ErrorCodeValidation, err)
+	}
+
+	if _, err := resultFormat(payload); err != nil {
+		return newTaskError(ErrorCodeValidation, err)
+	}
+
+	if _, err := resultSchemaVersion(payload); err != nil {
+		return newTaskError(ErrorCodeValidation, err)
+	}
+
+	if err := yip.validateAttest(payload); err != nil {
+		return newTaskError(ErrorCodeValidation, err)
+	}
+
+	if err := validateTrace(payload); err != nil {
+		return newTaskError(ErrorCodeValidation, err)
+	}
+
+	if err := validateResultShape(payload); err != nil {
+		return newTaskError(ErrorCodeValidation, err)
+	}
+
+	if err := yip.validatePayload(payload); err != nil {
+		return newTaskError(ErrorCodeValidation, err)
+	}
+
+	logger.Infow("Task validation successful")
+	return nil
+}
+
+// validatePayload checks the parameters of a payload against the requirements of its type
+// that need the state of the performer. It runs after validateSchema, so the validators of
+// each type leave the types and ranges of the parameters to the payload schema.
+func (yip *YieldIntelligencePerformer) validatePayload(payload *TaskPayload) error {
+	switch payload.Type {
+	case TaskTypeYieldMonitoring:
+		if err := yip.validateYieldMonitoringTask(payload); err != nil {
+			return fmt.Errorf("yield monitoring validation failed: %w", err)
+		}
+	case TaskTypeCrossChainYieldCheck:
+		if err := yip.validateCrossChainYieldCheckTask(payload); err != nil {
+			return fmt.Errorf("cross-chain yield check validation failed: %w", err)
+		}
+	case TaskTypeRebalanceExecution:
+		if err := yip.validateRebalanceExecutionTask(payload); err != nil {
+			return fmt.Errorf("rebalance execution validation failed: %w", err)
+		}
+		if err := yip.validateNonce(payload); err != nil {
+			return fmt.Errorf("rebalance execution validation failed: %w", err)
+		}
+	case TaskTypeRiskAssessment:
+		if err := yip.validateRiskAssessmentTask(payload); err != nil {
+			return fmt.Errorf("risk assessment validation failed: %w", err)
+		}
+	case TaskTypeLiquidityDepthAnalysis:
+		if err := yip.validateLiquidityDepthAnalysisTask(payload); err != nil {
+			return fmt.Errorf("liquidity depth analysis validation failed: %w", err)
+		}
+	case TaskTypeDepegMonitoring:
+		if err := yip.validateDepegMonitoringTask(payload); err != nil {
+			return fmt.Errorf("depeg monitoring validation failed: %w", err)
+		}
+	case TaskTypeAPYForecast:
+		if err := yip.validateAPYForecastTask(payload); err != nil {
+			return fmt.Errorf("apy forecast validation failed: %w", err)
+		}
+	case TaskTypeBatch:
+		if err := yip.validateBatchTask(payload); err != nil {
+			return fmt.Errorf("batch validation failed: %w", err)
+		}
+	case TaskTypeAllocationOptimization:
+		if err := yip.validateAllocationOptimizationTask(payload); err != nil {
+			return fmt.Errorf("allocation optimization validation failed: %w", err)
+		}
+	case TaskTypeProtocolIncidentCheck:
+		if err := yip.validateProtocolIncidentCheckTask(payload); err != nil {
+			return fmt.Errorf("protocol incident check validation failed: %w", err)
+		}
+	case TaskTypePositionReconciliation:
+		if err := yip.validatePositionReconciliationTask(payload); err != nil {
+			return fmt.Errorf("position reconciliation validation failed: %w", err)
+		}
+	case TaskTypeYieldRanking:
+		if err := yip.validateYieldRankingTask(payload); err != nil {
+			return fmt.Errorf("yield ranking validation failed: %w", err)
+		}
+	case TaskTypeRebalancePlan:
+		if err := yip.validateRebalancePlanTask(payload); err != nil {
+			return fmt.Errorf("rebalance plan validation failed: %w", err)
+		}
+	case TaskTypeRebalanceCommit:
+		if err := yip.validateRebalanceCommitTask(payload); err != nil {
+			return fmt.Errorf("rebalance commit validation failed: %w", err)
+		}
+	case TaskTypeResumeExecution:
+		if err := yip.validateResumeExecutionTask(payload); err != nil {
+			return fmt.Errorf("resume execution validation failed: %w", err)
+		}
+	case TaskTypeCapabilities:
+		if err := yip.validateCapabilitiesTask(payload); err != nil {
+			return fmt.Errorf("capabilities validation failed: %w", err)
+		}
+	case TaskTypeFullMarketSnapshot:
+		if err := yip.validateFullMarketSnapshotTask(payload); err != nil {
+			return fmt.Errorf("full market snapshot validation failed: %w", err)
+		}
+	case TaskTypePerformanceReport:
+		if err := yip.validatePerformanceReportTask(payload); err != nil {
+			return fmt.Errorf("performance report validation failed: %w", err)
+		}
+	case TaskTypeAccrualVerification:
+		if err := yip.validateAccrualVerificationTask(payload); err != nil {
+			return fmt.Errorf("accrual verification validation failed: %w", err)
+		}
+	case TaskTypeSelfReport:
+		if err := yip.validateSelfReportTask(payload); err != nil {
+			return fmt.Errorf("self report validation failed: %w", err)
+		}
+	case TaskTypeStressScenario:
+		if err := yip.validateStressScenarioTask(payload); err != nil {
+			return fmt.Errorf("stress scenario validation failed: %w", err)
+		}
+	case TaskTypeTransferRecovery:
+		if err := yip.validateTransferRecoveryTask(payload); err != nil {
+			return fmt.Errorf("transfer recovery validation failed: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown task type: %s", payload.Type)
+	}
+	return nil
+}
+
+func (yip *YieldIntelligencePerformer) HandleTask(t *performerV1.TaskRequest) (_ *performerV1.TaskResponse, err error) {
+	yip.logger.Sugar().Infow("Handling USDC Yield Intelligence task",
+		"task_id", string(t.TaskId),
+		"payload_size", len(t.Payload),
+	)
+
+	// ------------------------------------------------------------------------
+	// USDC Yield Intelligence Task Processing Logic
+	// ------------------------------------------------------------------------
+	// This is where the Performer will execute yield optimization work
+
+	ctx, done, err := yip.lifecycle.begin()
+	if err != nil {
+		return nil, newTaskError(ErrorCodeShuttingDown, err)
+	}
+	defer done()
+	ctx, span := tracing.Start(ctx, "HandleTask", attribute.String("task.id", string(t.TaskId)))
+	defer func() { tracing.End(span, err) }()
+
+	// Parse task payload to determine task type
+	payload, err := parseTaskPayload(t)
+	if err != nil {
+		return nil, newTaskError(ErrorCodeValidation, fmt.Errorf("failed to parse task payload: %w", err))
+	}
+	span.SetAttributes(attribute.String("task.type", string(payload.Type)))
+	defer yip.lifecycle.track(string(t.TaskId), payload.Type)()
+	logger := yip.taskLogger(t, payload)
+	ctx = logging.WithLogger(ctx, logger)
+	logger.Sugar().Debugw("Task parameters", "parameters", yip.redactor.Parameters(payload.Parameters))
+	if err := yip.authorizeTask(t, payload); err != nil {
+		return nil, err
+	}
+
+	// Concurrent redeliveries of the same task share a single execution, also across
+	// replicas sharing a Redis
+	key := store.IdempotencyKey(string(t.TaskId), t.Payload)
+	resultBytes, err := yip.dedup.do(key, func() ([]byte, error) {
+		return yip.shared.do(ctx, key, func() ([]byte, error) {
+			return yip.executeTask(ctx, t, payload)
+		})
+	})
+	if err != nil {
+		logger.Sugar().Errorw("Task processing failed", "error", err)
+		return nil, err
+	}
+
+	logger.Sugar().Infow("Task processing completed successfully", "resultSize", len(resultBytes))
+
+	return &performerV1.TaskResponse{
+		TaskId: t.TaskId,
+		Result: resultBytes,
+	}, nil
+}
+
+// executeTask records the task and routes it to the handler for its type.
+// Tasks that already completed are answered from the store instead of being re-executed.
+func (yip *YieldIntelligencePerformer) executeTask(ctx context.Context, t *performerV1.TaskRequest, payload *TaskPayload) ([]byte, error) {
+	taskID := string(t.TaskId)
+
+	prior, found, err := yip.priorResult(ctx, t, payload)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		return prior, nil
+	}
+
+	// Record the task before doing any work so it is auditable even if we crash mid-way
+	if _, err := yip.tasks.RecordReceived(ctx, taskID, string(payload.Type), t.Payload); err != nil {
+		return nil, fmt.Errorf("failed to record task %s: %w", taskID, err)
+	}
+	if err := yip.tasks.MarkProcessing(ctx, taskID); err != nil {
+		return nil, fmt.Errorf("failed to update task %s: %w", taskID, err)
+	}
+
+	ctx, pins := yip.withPins(ctx)
+	resultBytes, err := yip.cachedResult(ctx, t, payload)
+	if err == nil {
+		// attestations name the task, so they are added after results are cached
+		resultBytes, err = yip.attestResult(ctx, t, payload, resultBytes)
+	}
+	if err != nil {
+		if storeErr := yip.tasks.MarkFailed(ctx, taskID, err); storeErr != nil {
+			yip.log(ctx).Sugar().Errorw("Failed to record task failure", "error", storeErr)
+		}
+		return nil, err
+	}
+
+	if err := yip.tasks.MarkCompleted(ctx, taskID, resultBytes); err != nil {
+		return nil, fmt.Errorf("failed to record result of task %s: %w", taskID, err)
+	}
+	yip.trackPins(ctx, taskID, pins)
+	yip.notify(t, payload, notify.Event{Type: notify.EventTaskCompleted, Data: newTaskCompletedEvent(resultBytes)})
+	return resultBytes, nil
+}
+
+// cachedResult returns the encoded result of a task, answering it from the result cache
+// when an identical task ran within the TTL of its type
+func (yip *YieldIntelligencePerformer) cachedResult(ctx context.Context, t *performerV1.TaskRequest, payload *TaskPayload) ([]byte, error) {
+	taskType := string(payload.Type)
+	if cached, ok := yip.cache.Get(ctx, taskType, payload.Parameters); ok {
+		yip.log(ctx).Sugar().Debugw("Serving task from result cache")
+		return cached, nil
+	}
+
+	// tasks wait for a slot before using their quota, which counts the tasks started
+	dequeue, err := yip.queue.Acquire(ctx, taskType)
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	release, err := yip.quotas.Admit(taskType)
+	if err != nil {
+		dequeue()
+		return nil, newTaskError(ErrorCodeRateLimited, err)
+	}
+	var trace *chain.CallTrace
+	if paramBool(payload, "include_trace") {
+		trace = chain.NewCallTrace()
+		ctx = chain.WithCallTrace(ctx, trace)
+	}
+	result, err := yip.dispatch(ctx, t, payload)
+	release()
+	dequeue()
+	if err != nil {
+		taskErr := classifyError(err)
+		failure := errorResult(payload, taskErr)
+		if failure == nil {
+			return nil, taskErr
+		}
+		// refusals and failed executions are answered like results, but never cached
+		yip.log(ctx).Sugar().Warnw("Task failed", "code", taskErr.Code, "error", taskErr.Err)
+		return yip.encodeResult(payload, failure, trace)
+	}
+
+	// Every handler result goes through the canonical encoder so operators agree byte-for-byte
+	resultBytes, err := yip.encodeResult(payload, result, trace)
+	if err != nil {
+		return nil, err
+	}
+	if err := yip.cache.Put(ctx, taskType, payload.Parameters, resultBytes); err != nil {
+		yip.log(ctx).Sugar().Warnw("Failed to cache task result", "error", err)
+	}
+	return resultBytes, nil
+}
+
+// dispatch routes a payload to the handler for its type. Handlers read parameters without
+// checking their types, which ValidateTask checked against the payload schema of the type.
+func (yip *YieldIntelligencePerformer) dispatch(ctx context.Context, t *performerV1.TaskRequest, payload *TaskPayload) (result interface{}, err error) {
+	ctx, span := tracing.Start(ctx, "handle "+string(payload.Type), attribute.String("task.type", string(payload.Type)))
+	defer func() { tracing.End(span, err) }()
+	defer func() { yip.notifyOutcome(t, payload, result, err) }()
+
+	switch payload.Type {
+	case TaskTypeYieldMonitoring:
+		return yip.handleYieldMonitoring(ctx, t, payload)
+	case TaskTypeCrossChainYieldCheck:
+		return yip.handleCrossChainYieldCheck(ctx, t, payload)
+	case TaskTypeRebalanceExecution:
+		return yip.handleRebalanceExecution(ctx, t, payload)
+	case TaskTypeRiskAssessment:
+		return yip.handleRiskAssessment(ctx, t, payload)
+	case TaskTypeLiquidityDepthAnalysis:
+		return yip.handleLiquidityDepthAnalysis(ctx, t, payload)
+	case TaskTypeDepegMonitoring:
+		return yip.handleDepegMonitoring(ctx, t, payload)
+	case TaskTypeAPYForecast:
+		return yip.handleAPYForecast(ctx, t, payload)
+	case TaskTypeBatch:
+		return yip.handleBatch(ctx, t, payload)
+	case TaskTypeAllocationOptimization:
+		return yip.handleAllocationOptimization(ctx, t, payload)
+	case TaskTypeProtocolIncidentCheck:
+		return yip.handleProtocolIncidentCheck(ctx, t, payload)
+	case TaskTypePositionReconciliation:
+		return yip.handlePositionReconciliation(ctx, t, payload)
+	case TaskTypeYieldRanking:
+		return yip.handleYieldRanking(ctx, t, payload)
+	case TaskTypeRebalancePlan:
+		return yip.handleRebalancePlan(ctx, t, payload)
+	case TaskTypeRebalanceCommit:
+		return yip.handleRebalanceCommit(ctx, t, payload)
+	case TaskTypeResumeExecution:
+		return yip.handleResumeExecution(ctx, t, payload)
+	case TaskTypeCapabilities:
+		return yip.handleCapabilities(ctx, t, payload)
+	case TaskTypeFullMarketSnapshot:
+		return yip.handleFullMarketSnapshot(ctx, t, payload)
+	case TaskTypePerformanceReport:
+		return yip.handlePerformanceReport(ctx, t, payload)
+	case TaskTypeAccrualVerification:
+		return yip.handleAccrualVerification(ctx, t, payload)
+	case TaskTypeSelfReport:
+		return yip.handleSelfReport(ctx, t, payload)
+	case TaskTypeStressScenario:
+		return yip.handleStressScenario(ctx, t, payload)
+	case TaskTypeTransferRecovery:
+		return yip.handleTransferRecovery(ctx, t, payload)
+	default:
+		return nil, newTaskError(ErrorCodeValidation, fmt.Errorf("unknown task type '%s' for task %s", payload.Type, string(t.TaskId)))
+	}
+}
+
+// handleCrossChainYieldCheck processes cross-chain yield comparison tasks. Every
+// registered market on both chains is read concurrently; markets that fail are reported
+// without failing the task. The bridges between the chains are ranked next to the rates:
+// CCTP where Circle supports both chains and canonical bridges where it does not. Target
+// markets breaking the operator's policy are reported and never recommended, as are
+// those above the risk of the user's profile and bridges slower than it waits. Targets
+// improving less on the source than the profile asks are reported as rejected. With
+// hysteresis configured, moves to targets that have not led the source long enough, or of
+// funds of user_address that moved recently, are reported as held back. With
+// transfer_speed set, the route taken is the CCTP route of that speed. Routes report the
+// yield the amount misses while in transit once a target is recommended.
+func (yip *YieldIntelligencePerformer) handleCrossChainYieldCheck(ctx context.Context, t *performerV1.TaskRequest, payload *TaskPayload) (interface{}, error) {
+	yip.log(ctx).Sugar().Infow("Processing cross-chain yield check task")
+
+	// TODO: net bridge fees and gas out of the improvement
+
+	result := &CrossChainYieldResult{
+		SourceChain: paramUint64(payload, "source_chain"),
+		TargetChain: paramUint64(payload, "target_chain"),
+		Amount:      paramAmount(payload, "amount"),
+		Markets:     []ChainMarketRate{},
+		Failures:    []MarketFailure{},
+		Status:      ResultStatusCompleted,
+	}
+	profile := paramProfile(payload)
+	routes := yip.bridges.Routes(result.SourceChain, result.TargetChain, usdcBaseUnits(result.Amount))
+	result.Routes = bridgeRoutes(routes)
+	// with transfer_speed set, only the CCTP route of that speed is taken
+	_, speedSet := payload.Parameters["transfer_speed"]
+	speedRoute := bridge.CCTPBridge(paramTransferSpeed(payload))
+	var fastest time.Duration
+	for i, route := range routes {
+		if speedSet && route.Bridge != speedRoute {
+			continue
+		}
+		if max := profile.MaxBridgeLatency(); max == 0 || route.Latency <= max {
+			result.Route = &result.Routes[i]
+			break
+		}
+		if fastest == 0 || route.Latency < fastest {
+			fastest = route.Latency
+		}
+	}
+	if result.Route == nil && len(routes) > 0 {
+		result.PolicyRejections = append(result.PolicyRejections, policyViolations(profile.CheckBridgeLatency(policy.Market{ChainID: result.TargetChain}, fastest))...)
+	}
+
+	reports, failed := yip.checkSequencers(ctx, result.SourceChain, result.TargetChain)
+	result.Sequencers = reports
+	for chainID, err := range failed {
+		yip.log(ctx).Sugar().Warnw("Failed to check sequencer", "chainId", chainID, "error", err)
+		result.Status = ResultStatusPartial
+	}
+	// moving to an L2 whose sequencer is down, stalled or unknown is never recommended
+	targetBlocked := failed[result.TargetChain] != nil
+	for _, report := range reports {
+		if report.ChainID == result.TargetChain && report.Blocked {
+			targetBlocked = true
+		}
+	}
+
+	markets := yip.markets(result.SourceChain, result.TargetChain)
+	var sourceBest, targetBest *ChainMarketRate
+	for i, outcome := range yip.readMarkets(ctx, markets) {
+		if outcome.Err != nil {
+			result.Failures = append(result.Failures, marketFailure(markets[i], outcome.Err))
+			continue
+		}
+		rate := ChainMarketRate{
+			Protocol:   markets[i].protocol,
+			ChainID:    markets[i].chainID,
+			SupplyRate: ratePercent(outcome.Value.Pool.SupplyRate()),
+		}
+		if rate.ChainID == result.TargetChain {
+			projected := ratePercent(outcome.Value.Pool.DepositImpact(usdcBaseUnits(result.Amount)).SupplyRate)
+			rate.ProjectedRate = &projected
+		}
+		result.Markets = append(result.Markets, rate)
+		allowed := !(targetBlocked && rate.ChainID == result.TargetChain)
+		if rate.ChainID == result.TargetChain {
+			if violations := yip.policy.CheckMove(policyMarket(markets[i], outcome.Value), usdcBaseUnits(result.Amount)); len(violations) > 0 {
+				result.PolicyRejections = append(result.PolicyRejections, policyViolations(violations)...)
+				allowed = false
+			}
+			violations, err := yip.checkProfileRisk(ctx, profile, markets[i], outcome.Value)
+			switch {
+			case err != nil:
+				result.Failures = append(result.Failures, marketFailure(markets[i], err))
+				allowed = false
+			case len(violations) > 0:
+				result.PolicyRejections = append(result.PolicyRejections, policyViolations(violations)...)
+				allowed = false
+			}
+		}
+		if rate.ChainID == result.SourceChain && (sourceBest == nil || rate.SupplyRate.Cmp(sourceBest.SupplyRate) > 0) {
+			sourceBest = &result.Markets[len(result.Markets)-1]
+		}
+		if allowed && rate.ChainID == result.TargetChain && (targetBest == nil || rate.ProjectedRate.Cmp(*targetBest.ProjectedRate) > 0) {
+			targetBest = &result.Markets[len(result.Markets)-1]
+		}
+	}
+	if len(result.Failures) > 0 {
+		result.Status = ResultStatusPartial
+	}
+
+	if sourceBest != nil {
+		result.SourceRate = &sourceBest.SupplyRate
+	}
+	if targetBest != nil {
+		result.TargetRate = &targetBest.SupplyRate
+		result.TargetProjectedRate = targetBest.ProjectedRate
+		result.TargetProtocol = targetBest.Protocol
+		setTransitYieldLost(result.Routes, usdcBaseUnits(result.Amount), *targetBest.ProjectedRate)
+	}
+	if sourceBest != nil && targetBest != nil {
+		result.ImprovementBps = improvementBps(sourceBest.SupplyRate, targetBest.SupplyRate)
+		result.ProjectedImprovementBps = improvementBps(sourceBest.SupplyRate, *targetBest.ProjectedRate)
+		target := policy.Market{Protocol: targetBest.Protocol, ChainID: targetBest.ChainID}
+		result.PolicyRejections = append(result.PolicyRejections, policyViolations(profile.CheckImprovement(target, result.ProjectedImprovementBps.Rat()))...)
+
+		if yip.hysteresis.Enabled {
+			report, err := yip.checkHysteresis(ctx, common.HexToAddress(paramString(payload, "user_address")), sourceBest, targetBest, time.Now())
+			if err != nil {
+				yip.log(ctx).Sugar().Warnw("Failed to check hysteresis", "error", err)
+				result.Status = ResultStatusPartial
+			}
+			result.Hysteresis = report
+		}
+	}
+	return result, nil
+}
+
+// improvementBps is how many basis points target earns above source
+func improvementBps(source, target canonical.Decimal) *canonical.Decimal {
+	// rates are percentages, so one point is 100 bps
+	improvement := new(big.Rat).Sub(target.Rat(), source.Rat())
+	bps := canonical.NewDecimalFromRat(improvement.Mul(improvement, big.NewRat(100, 1)), canonical.ScorePlaces)
+	return &bps
+}
+
+// validateToken checks that the token parameter names native USDC on every chain of
+// chainIDs, which are ignored when zero. Tasks name it by symbol or by its address.
+func validateToken(payload *TaskPayload, chainIDs ...uint64) error {
+	token, ok := payload.Parameters["token"].(string)
+	if !ok || token == "" {
+		return fmt.Errorf("missing or invalid token, must be USDC")
+	}
+	known := make([]uint64, 0, len(chainIDs))
+	for _, chainID := range chainIDs {
+		if chainID != 0 {
+			known = append(known, chainID)
+		}
+	}
+	if err := tokens.CheckUSDC(token, known...); err != nil {
+		return fmt.Errorf("invalid token: %w", err)
+	}
+	return nil
+}
+
+// USDC Yield Intelligence task validation functions
+func (yip *YieldIntelligencePerformer) validateYieldMonitoringTask(payload *TaskPayload) error {
+	protocol := paramString(payload, "protocol")
+	all := strings.EqualFold(protocol, ProtocolAll)
+	if !all {
+		if _, err := yip.adapters.Get(protocol); err != nil {
+			return err
+		}
+	}
+
+	if err := yip.validateMonitoredToken(payload, paramUint64(payload, "chain_id")); err != nil {
+		return err
+	}
+
+	// chain_id is optional when monitoring all protocols, which then covers every chain
+	_, present := payload.Parameters["chain_id"]
+	if !present && !all {
+		return fmt.Errorf("missing chain_id")
+	}
+
+	if raw, present := payload.Parameters["deposit_amount"]; present {
+		if err := checkAmount(raw); err != nil {
+			return fmt.Errorf("invalid deposit_amount: %w", err)
+		}
+	}
+
+	if err := yip.validateBlockRequest(payload, present); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (yip *YieldIntelligencePerformer) validateCrossChainYieldCheckTask(payload *TaskPayload) error {
+	if err := checkAmount(payload.Parameters["amount"]); err != nil {
+		return fmt.Errorf("missing or invalid amount: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,399 @@
+package performer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/quota"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+	"github.com/najnomics/crosscow-avs/pkg/taskqueue"
+	"go.uber.org/zap"
+)
+
+func Test_YieldIntelligenceTaskRequestPayload(t *testing.T) {
+	// ------------------------------------------------------------------------
+	// USDC Yield Intelligence Task Tests
+	// ------------------------------------------------------------------------
+
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+
+	performer := NewYieldIntelligencePerformer(logger, WithAdapters(adapters.NewRegistry(newFakeAaveAdapter())))
+
+	// Test basic task validation
+	taskRequest := &performerV1.TaskRequest{
+		TaskId:  []byte("test-yield-task-id"),
+		Payload: []byte(`{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1}}`),
+	}
+
+	err = performer.ValidateTask(taskRequest)
+	if err != nil {
+		t.Errorf("ValidateTask failed: %v", err)
+	}
+
+	resp, err := performer.HandleTask(taskRequest)
+	if err != nil {
+		t.Errorf("HandleTask failed: %v", err)
+	}
+
+	t.Logf("Response: %v", resp)
+}
+
+func Test_YieldIntelligenceTaskTypes(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+
+	performer := NewYieldIntelligencePerformer(logger, WithAdapters(adapters.NewRegistry(newFakeAaveAdapter())))
+
+	testCases := []struct {
+		name     string
+		taskType TaskType
+		params   map[string]interface{}
+	}{
+		{
+			name:     "Yield Monitoring Task",
+			taskType: TaskTypeYieldMonitoring,
+			params: map[string]interface{}{
+				"protocol": "aave_v3",
+				"token":    "USDC",
+				"chain_id": 1,
+			},
+		},
+		{
+			name:     "Cross-Chain Yield Check Task",
+			taskType: TaskTypeCrossChainYieldCheck,
+			params: map[string]interface{}{
+				"source_chain": 1,
+				"target_chain": 8453,
+				"amount":       "1000000000",
+			},
+		},
+		{
+			name:     "Rebalance Execution Task",
+			taskType: TaskTypeRebalanceExecution,
+			params: map[string]interface{}{
+				"user_address":    "0x1234567890abcdef1234567890abcdef12345678",
+				"amount":          "500000000",
+				"target_protocol": "compound_v3",
+			},
+		},
+		{
+			name:     "Risk Assessment Task",
+			taskType: TaskTypeRiskAssessment,
+			params: map[string]interface{}{
+				"protocol":        "aave_v3",
+				"chain_id":        1,
+				"assessment_type": "full",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Create task payload
+			payload := TaskPayload{
+				Type:       tc.taskType,
+				Parameters: tc.params,
+			}
+
+			payloadBytes, err := json.Marshal(payload)
+			if err != nil {
+				t.Errorf("Failed to marshal payload: %v", err)
+				return
+			}
+
+			taskRequest := &performerV1.TaskRequest{
+				TaskId:  []byte("test-task-" + string(tc.taskType)),
+				Payload: payloadBytes,
+			}
+
+			// Test validation
+			err = performer.ValidateTask(taskRequest)
+			if err != nil {
+				t.Errorf("ValidateTask failed for %s: %v", tc.name, err)
+				return
+			}
+
+			// Test handling
+			resp, err := performer.HandleTask(taskRequest)
+			if err != nil {
+				t.Errorf("HandleTask failed for %s: %v", tc.name, err)
+				return
+			}
+
+			if resp == nil {
+				t.Errorf("HandleTask returned nil response for %s", tc.name)
+				return
+			}
+
+			if len(resp.Result) == 0 {
+				t.Errorf("HandleTask returned empty result for %s", tc.name)
+				return
+			}
+
+			t.Logf("%s completed successfully with result: %s", tc.name, string(resp.Result))
+		})
+	}
+}
+
+func Test_TaskPayloadParsing(t *testing.T) {
+	// Test payload parsing functionality
+	testPayload := TaskPayload{
+		Type: TaskTypeYieldMonitoring,
+		Parameters: map[string]interface{}{
+			"protocol": "aave_v3",
+			"token":    "USDC",
+		},
+	}
+
+	payloadBytes, err := json.Marshal(testPayload)
+	if err != nil {
+		t.Errorf("Failed to marshal test payload: %v", err)
+		return
+	}
+
+	taskRequest := &performerV1.TaskRequest{
+		TaskId:  []byte("parse-test"),
+		Payload: payloadBytes,
+	}
+
+	parsedPayload, err := parseTaskPayload(taskRequest)
+	if err != nil {
+		t.Errorf("Failed to parse task payload: %v", err)
+		return
+	}
+
+	if parsedPayload.Type != TaskTypeYieldMonitoring {
+		t.Errorf("Expected task type %s, got %s", TaskTypeYieldMonitoring, parsedPayload.Type)
+	}
+
+	if parsedPayload.Parameters["protocol"] != "aave_v3" {
+		t.Errorf("Expected protocol aave_v3, got %v", parsedPayload.Parameters["protocol"])
+	}
+
+	t.Logf("Payload parsing test successful: %+v", parsedPayload)
+}
+func Test_TaskTokenValidation(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+	performer := NewYieldIntelligencePerformer(logger, WithAdapters(adapters.NewRegistry(newFakeAaveAdapter())))
+
+	testCases := []struct {
+		name   string
+		params string
+		valid  bool
+	}{
+		{name: "symbol", params: `"token":"USDC","chain_id":1`, valid: true},
+		{name: "native address", params: `"token":"0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48","chain_id":1`, valid: true},
+		{name: "native address of another chain", params: `"token":"0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913","chain_id":1`},
+		{name: "bridged symbol", params: `"token":"USDbC","chain_id":8453`},
+		{name: "bridged address", params: `"token":"0xFF970A61A04b1cA14834A43f5dE4533eBDDB5CC8","chain_id":42161`},
+		{name: "other token", params: `"token":"DAI","chain_id":1`},
+		{name: "missing", params: `"chain_id":1`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			task := &performerV1.TaskRequest{
+				TaskId:  []byte(tc.name),
+				Payload: []byte(`{"type":"yield_monitoring","parameters":{"protocol":"aave_v3",` + tc.params + `}}`),
+			}
+			if err := performer.ValidateTask(task); (err == nil) != tc.valid {
+				t.Errorf("Expected valid %t, got %v", tc.valid, err)
+			}
+		})
+	}
+}
+
+func Test_TaskAmountValidation(t *testing.T) {
+	performer := NewYieldIntelligencePerformer(zap.NewNop(), WithAdapters(adapters.NewRegistry(newFakeAaveAdapter())))
+	task := func(amount string) *performerV1.TaskRequest {
+		return &performerV1.TaskRequest{
+			TaskId:  []byte("amount-" + amount),
+			Payload: []byte(`{"type":"cross_chain_yield_check","parameters":{"source_chain":1,"target_chain":8453,"amount":` + amount + `}}`),
+		}
+	}
+
+	testCases := []struct {
+		name   string
+		amount string
+		valid  bool
+	}{
+		{name: "base units", amount: `"1000500000"`, valid: true},
+		{name: "max uint256", amount: `"115792089237316195423570985008687907853269984665640564039457584007913129639935"`, valid: true},
+		{name: "number", amount: `1000.5`},
+		{name: "decimal string", amount: `"1000.5"`},
+		{name: "negative", amount: `"-1"`},
+		{name: "zero", amount: `"0"`},
+		{name: "above uint256", amount: `"115792089237316195423570985008687907853269984665640564039457584007913129639936"`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := performer.ValidateTask(task(tc.amount)); (err == nil) != tc.valid {
+				t.Errorf("Expected valid %t, got %v", tc.valid, err)
+			}
+		})
+	}
+
+	// amounts past float64 precision are answered to the base unit
+	result := runCrossChainYieldCheck(t, performer, "large", `{"type":"cross_chain_yield_check","parameters":{"source_chain":1,"target_chain":8453,"amount":"123456789012345678901"}}`)
+	if result.Amount.String() != "123456789012345.678901" {
+		t.Errorf("Expected the amount kept to the base unit, got %s", result.Amount)
+	}
+}
+
+func Test_TaskStateIsPersisted(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+
+	tasks := store.NewTaskStore(store.NewMemoryKV())
+	performer := NewYieldIntelligencePerformer(logger, WithTaskStore(tasks), WithAdapters(adapters.NewRegistry(newFakeAaveAdapter())))
+
+	taskRequest := &performerV1.TaskRequest{
+		TaskId:  []byte("persisted-task"),
+		Payload: []byte(`{"type":"risk_assessment","parameters":{"protocol":"aave_v3","chain_id":1,"assessment_type":"full"}}`),
+	}
+
+	resp, err := performer.HandleTask(taskRequest)
+	if err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+
+	record, err := tasks.GetTask(context.Background(), "persisted-task")
+	if err != nil {
+		t.Fatalf("Expected task to be recorded: %v", err)
+	}
+	if record.Status != store.TaskStatusCompleted {
+		t.Errorf("Expected status %s, got %s", store.TaskStatusCompleted, record.Status)
+	}
+	if record.TaskType != string(TaskTypeRiskAssessment) {
+		t.Errorf("Expected task type %s, got %s", TaskTypeRiskAssessment, record.TaskType)
+	}
+	if string(record.Result) != string(resp.Result) {
+		t.Errorf("Expected stored result %q, got %q", string(resp.Result), string(record.Result))
+	}
+
+	unknown := &performerV1.TaskRequest{
+		TaskId:  []byte("unknown-task"),
+		Payload: []byte(`{"type":"not_a_task","parameters":{}}`),
+	}
+	if _, err := performer.HandleTask(unknown); err == nil {
+		t.Fatalf("Expected HandleTask to fail for unknown task type")
+	}
+
+	record, err = tasks.GetTask(context.Background(), "unknown-task")
+	if err != nil {
+		t.Fatalf("Expected failed task to be recorded: %v", err)
+	}
+	if record.Status != store.TaskStatusFailed || record.Error == "" {
+		t.Errorf("Expected failed record with error, got %+v", record)
+	}
+}
+
+func Test_TaskQuotas(t *testing.T) {
+	performer := newDryRunPerformer(t, &fakeSimulator{bundles: make(map[uint64]int)})
+	WithQuotas(quota.New(quota.Config{Enabled: true, Limits: map[string]quota.Limit{
+		string(TaskTypeRebalanceExecution): {PerMinute: 1},
+	}}))(performer)
+
+	rebalance := func(amount int) *performerV1.TaskRequest {
+		return &performerV1.TaskRequest{TaskId: []byte(fmt.Sprintf("rebalance-%d", amount)), Payload: []byte(fmt.Sprintf(`{"type":"rebalance_execution","parameters":{
+			"user_address":"0x00000000000000000000000000000000000000aa","amount":"%d000000","dry_run":true,"target_protocol":"aave_v3","target_chain":1}}`, amount))}
+	}
+	if _, err := performer.HandleTask(rebalance(1000)); err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+
+	var taskErr *TaskError
+	if err := performer.ValidateTask(rebalance(2000)); !errors.As(err, &taskErr) || taskErr.Code != ErrorCodeRateLimited {
+		t.Errorf("Expected the second rebalance to be rate limited when validated, got %v", err)
+	}
+	if _, err := performer.HandleTask(rebalance(2000)); !errors.As(err, &taskErr) || !taskErr.Code.Retryable() {
+		t.Errorf("Expected the second rebalance to be rejected as retryable, got %v", err)
+	}
+
+	// batched rebalances count against the rebalance quota
+	resp, err := performer.HandleTask(&performerV1.TaskRequest{TaskId: []byte("batch"), Payload: []byte(`{"type":"batch","parameters":{"tasks":[
+		{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1}},
+		{"type":"rebalance_execution","parameters":{"user_address":"0x00000000000000000000000000000000000000aa","amount":"3000000000","dry_run":true,"target_protocol":"aave_v3","target_chain":1}}
+	]}}`)})
+	if err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+	var envelope struct {
+		Result BatchResult `json:"result"`
+	}
+	if err := json.Unmarshal(resp.Result, &envelope); err != nil {
+		t.Fatalf("Failed to decode result: %v", err)
+	}
+	if item := envelope.Result.Results[1]; item.Status != BatchItemFailed || item.ErrorCode != ErrorCodeRateLimited {
+		t.Errorf("Expected the batched rebalance to be rate limited, got %+v", item)
+	}
+}
+
+func Test_TaskQueue(t *testing.T) {
+	performer := newDryRunPerformer(t, &fakeSimulator{bundles: make(map[uint64]int)})
+	queue, err := taskqueue.New(taskqueue.Config{Enabled: true, Default: taskqueue.Lane{MaxConcurrent: 4}, Types: map[string]taskqueue.Lane{
+		string(TaskTypeRebalanceExecution): {MaxConcurrent: 1, MaxQueued: 1},
+	}}, nil)
+	if err != nil {
+		t.Fatalf("taskqueue.New failed: %v", err)
+	}
+	WithTaskQueue(queue)(performer)
+
+	rebalance := func(amount int) *performerV1.TaskRequest {
+		return &performerV1.TaskRequest{TaskId: []byte(fmt.Sprintf("rebalance-%d", amount)), Payload: []byte(fmt.Sprintf(`{"type":"rebalance_execution","parameters":{
+			"user_address":"0x00000000000000000000000000000000000000aa","amount":"%d000000","dry_run":true,"target_protocol":"aave_v3","target_chain":1}}`, amount))}
+	}
+	// a rebalance runs, so the next one waits for it
+	running, err := queue.Acquire(context.Background(), string(TaskTypeRebalanceExecution))
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	queued := make(chan error, 1)
+	go func() {
+		_, err := performer.HandleTask(rebalance(1000))
+		queued <- err
+	}()
+	deadline := time.Now().Add(time.Second)
+	for queue.Check(string(TaskTypeRebalanceExecution)) == nil {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the rebalance to be queued")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	var taskErr *TaskError
+	if err := performer.ValidateTask(rebalance(2000)); !errors.As(err, &taskErr) || taskErr.Code != ErrorCodeRateLimited {
+		t.Errorf("Expected a rebalance arriving at the full queue to be rejected when validated, got %v", err)
+	}
+	if _, err := performer.HandleTask(rebalance(2000)); !errors.As(err, &taskErr) || taskErr.Code != ErrorCodeRateLimited || !taskErr.Code.Retryable() {
+		t.Errorf("Expected a rebalance arriving at the full queue to be rejected as retryable, got %v", err)
+	}
+	if err := performer.ValidateTask(&performerV1.TaskRequest{TaskId: []byte("monitoring"), Payload: []byte(`{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1}}`)}); err != nil {
+		t.Errorf("Expected other task types to run meanwhile: %v", err)
+	}
+
+	running()
+	select {
+	case err := <-queued:
+		if err != nil {
+			t.Errorf("Expected the queued rebalance to run once the slot was freed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the queued rebalance to run once the slot was freed")
+	}
+}
@@ -0,0 +1,197 @@
+package performer
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/flashloan"
+	"github.com/najnomics/crosscow-avs/pkg/permit"
+)
+
+// paramPermit returns the permit parameter, signed by user_address, or nil when missing or
+// invalid
+func paramPermit(payload *TaskPayload) *permit.Signed {
+	raw, ok := payload.Parameters["permit"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	signed := &permit.Signed{Owner: common.HexToAddress(paramString(payload, "user_address"))}
+	signed.Standard, _ = raw["standard"].(string)
+	deadline, _ := raw["deadline"].(float64)
+	signed.Deadline = uint64(deadline)
+	value, _ := raw["value"].(string)
+	if signed.Value, ok = new(big.Int).SetString(value, 10); !ok {
+		return nil
+	}
+	if nonce, present := raw["nonce"].(string); present {
+		if signed.Nonce, ok = new(big.Int).SetString(nonce, 10); !ok {
+			return nil
+		}
+	}
+	signature, _ := raw["signature"].(string)
+	var err error
+	if signed.Signature, err = hexutil.Decode(signature); err != nil {
+		return nil
+	}
+	return signed
+}
+
+// validatePermit checks the permit a rebalance_execution task carries. Permits fund the
+// deposit of wallet funds on one chain: the performer account pulls the USDC of
+// user_address with the permit and deposits it on their behalf, so it must send the steps
+// itself.
+func (yip *YieldIntelligencePerformer) validatePermit(payload *TaskPayload) error {
+	if _, present := payload.Parameters["permit"]; !present {
+		return nil
+	}
+	signed := paramPermit(payload)
+	switch {
+	case signed == nil || signed.Deadline == 0 || (signed.Standard != permit.StandardEIP2612 && signed.Standard != permit.StandardPermit2):
+		return fmt.Errorf("invalid permit: must carry a standard, value, deadline and signature")
+	case !yip.permits.Enabled || yip.transactions == nil:
+		return fmt.Errorf("invalid permit: permits are not enabled on this performer")
+	case signed.Standard == permit.StandardPermit2 && signed.Nonce == nil:
+		return fmt.Errorf("invalid permit: Permit2 permits need a nonce")
+	case signed.Standard == permit.StandardEIP2612 && signed.Nonce != nil:
+		return fmt.Errorf("invalid permit: EIP-2612 permits are signed over the nonce of the token, which cannot be set")
+	case paramString(payload, "source_protocol") != "":
+		return fmt.Errorf("invalid permit: permits fund deposits of wallet funds, which have no source_protocol")
+	case paramUint64(payload, "source_chain") != 0 && paramUint64(payload, "source_chain") != paramUint64(payload, "target_chain"):
+		return fmt.Errorf("invalid permit: permits fund deposits on target_chain, which source_chain must match")
+	case paramString(payload, "execution") == ExecutionUserOperation:
+		return fmt.Errorf("invalid permit: permits are spent by the performer account, which user operations are not sent from")
+	case paramString(payload, "strategy") == flashloan.StrategyFlashLoan:
+		return fmt.Errorf("invalid permit: flash loans migrate positions, which permits do not fund")
+	}
+	return nil
+}
+
+// refusePermit refuses permits on tasks that do not spend them, rather than ignoring them
+func refusePermit(payload *TaskPayload) error {
+	if _, present := payload.Parameters["permit"]; present {
+		return fmt.Errorf("invalid permit: permits are only spent by rebalance_execution tasks")
+	}
+	return nil
+}
+
+// checkPermit verifies the permit funding route against its chain, so permits that are
+// expired, spent, not signed by the user or larger than their balance are refused before
+// anything is sent
+func (yip *YieldIntelligencePerformer) checkPermit(ctx context.Context, route *rebalanceRoute) error {
+	if route.userPermit == nil {
+		return nil
+	}
+	if yip.transactions == nil {
+		return newTaskError(ErrorCodeValidation, fmt.Errorf("invalid permit: permits are not enabled on this performer"))
+	}
+	client, err := yip.transactions.Client(route.targetChain)
+	if err != nil {
+		return err
+	}
+	pull, err := yip.permitPull(route)
+	if err != nil {
+		return err
+	}
+	if err := route.userPermit.Verify(ctx, client, pull, time.Now()); err != nil {
+		return fmt.Errorf("failed to verify permit: %w", err)
+	}
+	return nil
+}
+
+// permitPull is the transfer of the amount of route from the user to the performer
+// account their permit funds
+func (yip *YieldIntelligencePerformer) permitPull(route *rebalanceRoute) (permit.Pull, error) {
+	usdc, err := adapters.USDCAddress(route.targetChain)
+	if err != nil {
+		return permit.Pull{}, err
+	}
+	return permit.Pull{
+		ChainID: route.targetChain,
+		Token:   usdc,
+		Spender: yip.transactions.Address(route.targetChain),
+		Amount:  route.amount,
+	}, nil
+}
+
+// permitSteps lists the steps pulling the funds of route with the permit of the user:
+// the EIP-2612 permit followed by the transfer, or the single Permit2 transfer
+func (yip *YieldIntelligencePerformer) permitSteps(route *rebalanceRoute) ([]*plannedStep, error) {
+	pull, err := yip.permitPull(route)
+	if err != nil {
+		return nil, err
+	}
+	calls, err := route.userPermit.Calls(pull)
+	if err != nil {
+		return nil, err
+	}
+	steps := make([]*plannedStep, len(calls))
+	for i := range calls {
+		action := ActionPermit
+		if i == len(calls)-1 {
+			action = ActionPull
+		}
+		steps[i] = newPlannedStep(action, route.targetChain, "", &calls[i], false)
+	}
+	return steps, nil
+}
+
+// permitsDeposit reports whether the deposit of route can spend a permit instead of an
+// allowance: permits are enabled and the target market takes them. Flash loan migrations
+// deposit through their helper and never do, and user operations batch the approval
+// with the deposit instead, as permits are signed for the performer account.
+func (yip *YieldIntelligencePerformer) permitsDeposit(route *rebalanceRoute) bool {
+	if !yip.permits.Enabled || yip.transactions == nil || route.strategy == flashloan.StrategyFlashLoan || route.execution == ExecutionUserOperation {
+		return false
+	}
+	mover, err := yip.adapters.MoverFor(route.targetProtocol)
+	if err != nil {
+		return false
+	}
+	_, ok := mover.(adapters.PermitMover)
+	return ok
+}
+
+// signPermit builds the call of a deposit step spending a permit, signed now so its nonce
+// is current and its deadline runs from when it is sent. Other steps are left as planned.
+func (yip *YieldIntelligencePerformer) signPermit(ctx context.Context, route *rebalanceRoute, step *plannedStep) error {
+	if !step.permitted {
+		return nil
+	}
+	mover, err := yip.adapters.MoverFor(route.targetProtocol)
+	if err != nil {
+		return err
+	}
+	permitMover, ok := mover.(adapters.PermitMover)
+	if !ok {
+		return fmt.Errorf("%s deposits do not take permits", route.targetProtocol)
+	}
+	usdc, err := adapters.USDCAddress(route.targetChain)
+	if err != nil {
+		return err
+	}
+	client, err := yip.transactions.Client(route.targetChain)
+	if err != nil {
+		return err
+	}
+	signed, err := permit.Sign(ctx, client, yip.transactions.Signer(route.targetChain), permit.Request{
+		ChainID:  route.targetChain,
+		Token:    usdc,
+		Spender:  step.call.To,
+		Value:    route.received(),
+		Deadline: time.Now().Add(yip.permits.Validity),
+	})
+	if err != nil {
+		return err
+	}
+	call, err := permitMover.SupplyWithPermitCall(route.targetChain, route.user, route.received(), signed)
+	if err != nil {
+		return err
+	}
+	step.call = call
+	return nil
+}
@@ -0,0 +1,243 @@
+package performer
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/permit"
+)
+
+// permitAdapter is a movableAdapter whose market takes permits
+type permitAdapter struct {
+	*movableAdapter
+	permits []*adapters.Permit
+}
+
+func (p *permitAdapter) SupplyWithPermitCall(chainID uint64, onBehalfOf common.Address, amount *big.Int, signed *adapters.Permit) (*chain.Call, error) {
+	p.permits = append(p.permits, signed)
+	return &chain.Call{To: p.contract, Data: supplyWithPermitABI.Methods["supplyWithPermit"].ID}, nil
+}
+
+var supplyWithPermitABI = chain.MustParseABI(`[{"name":"supplyWithPermit","type":"function","stateMutability":"nonpayable","inputs":[],"outputs":[]}]`)
+
+var permitTokenABI = chain.MustParseABI(`[
+	{"name":"name","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"string"}]},
+	{"name":"version","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"string"}]},
+	{"name":"DOMAIN_SEPARATOR","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"bytes32"}]},
+	{"name":"nonces","type":"function","stateMutability":"view","inputs":[{"name":"owner","type":"address"}],"outputs":[{"name":"","type":"uint256"}]}
+]`)
+
+func Test_RebalanceDepositsWithPermit(t *testing.T) {
+	performer, account, ethereum := newSubmittingPerformer(t)
+	ethereum.AutoMine()
+	aave := &permitAdapter{movableAdapter: newMovableAave(big.NewInt(0), big.NewInt(1_000_000_000))}
+	WithAdapters(adapters.NewRegistry(aave))(performer)
+	cfg := permit.DefaultConfig()
+	cfg.Enabled = true
+	WithPermits(cfg)(performer)
+
+	usdc, err := adapters.USDCAddress(1)
+	if err != nil {
+		t.Fatalf("USDCAddress failed: %v", err)
+	}
+	data := permit.TypedData(permit.Request{ChainID: 1, Token: usdc}, "USD Coin", "2", account, new(big.Int))
+	separator, err := data.HashStruct("EIP712Domain", data.Domain.Map())
+	if err != nil {
+		t.Fatalf("Failed to hash domain: %v", err)
+	}
+	ethereum.Stub(t, usdc, permitTokenABI, "name", "USD Coin")
+	ethereum.Stub(t, usdc, permitTokenABI, "version", "2")
+	ethereum.Stub(t, usdc, permitTokenABI, "nonces", new(big.Int))
+	ethereum.Stub(t, usdc, permitTokenABI, "DOMAIN_SEPARATOR", [32]byte(separator))
+	ethereum.StubGas(t, aave.contract, supplyWithPermitABI, "supplyWithPermit", 250_000)
+
+	payload := `{"type":"rebalance_execution","parameters":{"user_address":"` + account.Hex() + `","nonce":"1","amount":"1000000000","target_protocol":"aave_v3","target_chain":1%s}}`
+	// dry runs plan the approval, as they cannot sign
+	dryRun := runDryRun(t, performer, fmt.Sprintf(payload, `,"dry_run":true`))
+	if dryRun.Simulation == nil || len(dryRun.Simulation.Steps) != 2 || dryRun.Simulation.Steps[0].Action != ActionApprove {
+		t.Fatalf("Expected the approval and deposit to be simulated, got %+v", dryRun.Simulation)
+	}
+
+	var result RebalanceExecutionResult
+	if err := runYieldMonitoring(t, performer, "permit", fmt.Sprintf(payload, ""), &result); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	execution := result.Execution
+	if result.Status != ResultStatusCompleted || execution == nil || !execution.Verified {
+		t.Fatalf("Expected a completed rebalance, got %+v", result)
+	}
+	sent := ethereum.Sent()
+	if len(execution.Transactions) != 1 || execution.Transactions[0].Action != ActionDeposit || len(sent) != 1 ||
+		!bytes.Equal(sent[0].Data(), supplyWithPermitABI.Methods["supplyWithPermit"].ID) {
+		t.Fatalf("Expected a single deposit spending the permit, got %+v", execution.Transactions)
+	}
+	if len(aave.permits) != 1 || aave.permits[0].Deadline.Int64() <= time.Now().Unix() {
+		t.Errorf("Expected a permit valid for the deposit, got %+v", aave.permits)
+	}
+}
+
+// userPermitABI has the methods spending and verifying permits of users
+var userPermitABI = chain.MustParseABI(`[
+	{"name":"permit","type":"function","stateMutability":"nonpayable","inputs":[{"name":"owner","type":"address"},{"name":"spender","type":"address"},{"name":"value","type":"uint256"},{"name":"deadline","type":"uint256"},{"name":"v","type":"uint8"},{"name":"r","type":"bytes32"},{"name":"s","type":"bytes32"}],"outputs":[]},
+	{"name":"transferFrom","type":"function","stateMutability":"nonpayable","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"value","type":"uint256"}],"outputs":[{"name":"","type":"bool"}]},
+	{"name":"allowance","type":"function","stateMutability":"view","inputs":[{"name":"owner","type":"address"},{"name":"spender","type":"address"}],"outputs":[{"name":"","type":"uint256"}]},
+	{"name":"permitTransferFrom","type":"function","stateMutability":"nonpayable","inputs":[
+		{"name":"permit","type":"tuple","components":[
+			{"name":"permitted","type":"tuple","components":[{"name":"token","type":"address"},{"name":"amount","type":"uint256"}]},
+			{"name":"nonce","type":"uint256"},
+			{"name":"deadline","type":"uint256"}]},
+		{"name":"transferDetails","type":"tuple","components":[{"name":"to","type":"address"},{"name":"requestedAmount","type":"uint256"}]},
+		{"name":"owner","type":"address"},
+		{"name":"signature","type":"bytes"}],"outputs":[]},
+	{"name":"nonceBitmap","type":"function","stateMutability":"view","inputs":[{"name":"owner","type":"address"},{"name":"wordPos","type":"uint256"}],"outputs":[{"name":"","type":"uint256"}]}
+]`)
+
+func Test_RebalanceDepositsWithUserPermit(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	user := crypto.PubkeyToAddress(key.PublicKey)
+	usdc, err := adapters.USDCAddress(1)
+	if err != nil {
+		t.Fatalf("USDCAddress failed: %v", err)
+	}
+	sign := func(data apitypes.TypedData) string {
+		hash, _, err := apitypes.TypedDataAndHash(data)
+		if err != nil {
+			t.Fatalf("Failed to hash permit: %v", err)
+		}
+		sig, err := crypto.Sign(hash, key)
+		if err != nil {
+			t.Fatalf("Failed to sign permit: %v", err)
+		}
+		return hexutil.Encode(sig)
+	}
+	deadline := time.Now().Add(time.Hour).Unix()
+
+	for name, tc := range map[string]struct {
+		permit  func(account common.Address) string
+		actions []string
+	}{
+		"eip-2612": {
+			permit: func(account common.Address) string {
+				data := permit.TypedData(permit.Request{ChainID: 1, Token: usdc, Spender: account, Value: big.NewInt(1_000_000_000), Deadline: time.Unix(deadline, 0)},
+					"USD Coin", "2", user, big.NewInt(7))
+				return fmt.Sprintf(`{"standard":"eip2612","value":"1000000000","deadline":%d,"signature":"%s"}`, deadline, sign(data))
+			},
+			actions: []string{ActionPermit, ActionPull, ActionApprove, ActionDeposit},
+		},
+		"permit2": {
+			permit: func(account common.Address) string {
+				signed := &permit.Signed{Standard: permit.StandardPermit2, Owner: user, Value: big.NewInt(1_000_000_000), Nonce: big.NewInt(9), Deadline: uint64(deadline)}
+				data := permit.Permit2TypedData(signed, permit.Pull{ChainID: 1, Token: usdc, Spender: account, Amount: big.NewInt(1_000_000_000)})
+				return fmt.Sprintf(`{"standard":"permit2","value":"1000000000","nonce":"9","deadline":%d,"signature":"%s"}`, deadline, sign(data))
+			},
+			actions: []string{ActionPull, ActionApprove, ActionDeposit},
+		},
+	} {
+		performer, account, ethereum := newSubmittingPerformer(t)
+		ethereum.AutoMine()
+		WithAdapters(adapters.NewRegistry(newMovableAave(big.NewInt(0), big.NewInt(1_000_000_000))))(performer)
+		cfg := permit.DefaultConfig()
+		cfg.Enabled = true
+		WithPermits(cfg)(performer)
+
+		domain := permit.TypedData(permit.Request{ChainID: 1, Token: usdc}, "USD Coin", "2", user, new(big.Int))
+		separator, err := domain.HashStruct("EIP712Domain", domain.Domain.Map())
+		if err != nil {
+			t.Fatalf("Failed to hash domain: %v", err)
+		}
+		ethereum.Stub(t, usdc, permitTokenABI, "name", "USD Coin")
+		ethereum.Stub(t, usdc, permitTokenABI, "version", "2")
+		ethereum.Stub(t, usdc, permitTokenABI, "nonces", big.NewInt(7))
+		ethereum.Stub(t, usdc, permitTokenABI, "DOMAIN_SEPARATOR", [32]byte(separator))
+		ethereum.Stub(t, usdc, userPermitABI, "allowance", big.NewInt(1_000_000_000))
+		ethereum.Stub(t, permit.Permit2Address, userPermitABI, "nonceBitmap", new(big.Int))
+		ethereum.StubGas(t, usdc, userPermitABI, "permit", 80_000)
+		ethereum.StubGas(t, permit.Permit2Address, userPermitABI, "permitTransferFrom", 90_000)
+
+		payload := `{"type":"rebalance_execution","parameters":{"user_address":"` + user.Hex() + `","nonce":"1","amount":"1000000000","target_protocol":"aave_v3","target_chain":1,"permit":` + tc.permit(account) + `}}`
+		var result RebalanceExecutionResult
+		if err := runYieldMonitoring(t, performer, name, payload, &result); err != nil {
+			t.Fatalf("%s: ValidateTask failed: %v", name, err)
+		}
+		execution := result.Execution
+		if result.Status != ResultStatusCompleted || execution == nil || !execution.Verified || execution.From != account.Hex() {
+			t.Fatalf("%s: expected a completed deposit sent from the performer account, got %+v", name, result)
+		}
+		var actions []string
+		for _, tx := range execution.Transactions {
+			actions = append(actions, tx.Action)
+		}
+		if strings.Join(actions, ",") != strings.Join(tc.actions, ",") || len(ethereum.Sent()) != len(tc.actions) {
+			t.Errorf("%s: expected %v to be sent, got %v", name, tc.actions, actions)
+		}
+		if len(execution.Legs) != 2 || execution.Legs[0].Leg != ActionPull {
+			t.Errorf("%s: expected the pull and the deposit legs, got %+v", name, execution.Legs)
+		}
+
+		// more than the permit allows
+		over := strings.Replace(strings.Replace(payload, `"nonce":"1"`, `"nonce":"2"`, 1), `"amount":"1000000000"`, `"amount":"2000000000"`, 1)
+		task := &performerV1.TaskRequest{TaskId: []byte(name + " over value"), Payload: []byte(over)}
+		if err := performer.ValidateTask(task); err != nil {
+			t.Fatalf("%s: ValidateTask failed: %v", name, err)
+		}
+		if _, err := performer.HandleTask(task); err == nil || !strings.Contains(err.Error(), "invalid permit") {
+			t.Errorf("%s: expected a permit allowing less than the amount to be refused, got %v", name, err)
+		}
+	}
+}
+
+func Test_PermitsValidated(t *testing.T) {
+	performer, account, _ := newSubmittingPerformer(t)
+	cfg := permit.DefaultConfig()
+	cfg.Enabled = true
+	WithPermits(cfg)(performer)
+	signature := "0x" + strings.Repeat("ab", 65)
+	eip2612 := `"permit":{"standard":"eip2612","value":"1000000000","deadline":1800000000,"signature":"` + signature + `"}`
+	rebalance := `"user_address":"0x00000000000000000000000000000000000000aa","nonce":"1","amount":"1000000000","target_protocol":"aave_v3","target_chain":1,`
+
+	for name, tc := range map[string]struct {
+		taskType string
+		params   string
+		valid    bool
+	}{
+		"deposit":               {taskType: "rebalance_execution", params: rebalance + eip2612, valid: true},
+		"without permit":        {taskType: "rebalance_execution", params: rebalance + `"dry_run":false`},
+		"permit2 without nonce": {taskType: "rebalance_execution", params: rebalance + strings.Replace(eip2612, "eip2612", "permit2", 1)},
+		"eip-2612 with nonce":   {taskType: "rebalance_execution", params: rebalance + strings.Replace(eip2612, `"value"`, `"nonce":"1","value"`, 1)},
+		"permit2 fields":        {taskType: "rebalance_execution", params: rebalance + `"permit":{"permitted":{"token":"0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48","amount":"1000000000"},"nonce":"0","deadline":1800000000}`},
+		"withdrawal":            {taskType: "rebalance_execution", params: rebalance + `"source_protocol":"compound_v3",` + eip2612},
+		"cross-chain":           {taskType: "rebalance_execution", params: rebalance + `"source_chain":8453,` + eip2612},
+		"user operation":        {taskType: "rebalance_execution", params: rebalance + `"execution":"user_operation",` + eip2612},
+		"plan":                  {taskType: "rebalance_plan", params: rebalance + `"expires_at":` + fmt.Sprint(time.Now().Add(time.Minute).Unix()) + `,` + eip2612},
+		"commit":                {taskType: "rebalance_commit", params: `"plan_hash":"0x` + strings.Repeat("ab", 32) + `",` + eip2612},
+		"performer funds":       {taskType: "rebalance_execution", params: strings.Replace(rebalance, "0x00000000000000000000000000000000000000aa", account.Hex(), 1) + `"dry_run":false`, valid: true},
+	} {
+		task := &performerV1.TaskRequest{
+			TaskId:  []byte(name),
+			Payload: []byte(`{"type":"` + tc.taskType + `","parameters":{` + tc.params + `}}`),
+		}
+		if err := performer.ValidateTask(task); (err == nil) != tc.valid {
+			t.Errorf("%s: unexpected validation error %v", name, err)
+		}
+	}
+
+	WithPermits(permit.DefaultConfig())(performer)
+	task := &performerV1.TaskRequest{TaskId: []byte("disabled"), Payload: []byte(`{"type":"rebalance_execution","parameters":{` + rebalance + eip2612 + `}}`)}
+	if err := performer.ValidateTask(task); err == nil || !strings.Contains(err.Error(), "not enabled") {
+		t.Errorf("Expected permits to be refused while disabled, got %v", err)
+	}
+}
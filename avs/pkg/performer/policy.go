@@ -0,0 +1,70 @@
+package performer
+
+import (
+	"context"
+	"strings"
+
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+	"github.com/najnomics/crosscow-avs/pkg/policy"
+)
+
+// PolicyViolation is a rule of the operator's policy, or of the user's profile, a market
+// or move breaks. Limit and Actual are in USDC for amounts, a ratio for utilization,
+// basis points for improvements and seconds for latencies, and null for protocol lists.
+type PolicyViolation struct {
+	Rule     policy.Rule        `json:"rule"`
+	Protocol string             `json:"protocol"`
+	ChainID  uint64             `json:"chain_id"`
+	Limit    *canonical.Decimal `json:"limit"`
+	Actual   *canonical.Decimal `json:"actual"`
+	Reason   string             `json:"reason"`
+}
+
+func policyViolations(violations []policy.Violation) []PolicyViolation {
+	out := make([]PolicyViolation, len(violations))
+	for i, v := range violations {
+		out[i] = PolicyViolation{Rule: v.Rule, Protocol: v.Protocol, ChainID: v.ChainID, Limit: v.Limit, Actual: v.Actual, Reason: v.Reason}
+	}
+	return out
+}
+
+// policyError refuses a move over the rules it breaks, which error results list
+type policyError struct {
+	violations []PolicyViolation
+}
+
+func (e *policyError) Error() string {
+	reasons := make([]string, len(e.violations))
+	for i, v := range e.violations {
+		reasons[i] = v.Reason
+	}
+	return strings.Join(reasons, "; ")
+}
+
+// policyMarket describes m, read as state, to the policy engine
+func policyMarket(m market, state *adapters.MarketState) policy.Market {
+	return policy.Market{
+		Protocol:    m.protocol,
+		ChainID:     m.chainID,
+		TotalSupply: state.Pool.TotalSupply,
+		Utilization: state.Pool.Utilization(),
+	}
+}
+
+// checkPolicy refuses route, dry runs included, when moving its amount into the target
+// market breaks a rule of the policy
+func (yip *YieldIntelligencePerformer) checkPolicy(ctx context.Context, route *rebalanceRoute) error {
+	if yip.policy == nil {
+		return nil
+	}
+	target := market{protocol: route.targetProtocol, chainID: route.targetChain}
+	state, err := yip.readMarket(ctx, target)
+	if err != nil {
+		return err
+	}
+	if violations := yip.policy.CheckMove(policyMarket(target, state), route.amount); len(violations) > 0 {
+		return newTaskError(ErrorCodePolicyViolation, &policyError{violations: policyViolations(violations)})
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package performer
+
+import (
+	"encoding/json"
+	"testing"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/najnomics/crosscow-avs/pkg/policy"
+)
+
+func Test_RebalanceRefusedByPolicy(t *testing.T) {
+	performer, account, ethereum := newSubmittingPerformer(t)
+	WithPolicy(policy.New(policy.Config{MaxMoveSize: 500, MaxUtilization: 0.75}))(performer)
+
+	result := runFailedRebalance(t, performer, "policy", `{"type":"rebalance_execution","parameters":{
+		"user_address":"`+account.Hex()+`","nonce":"1","amount":"1000000000","target_protocol":"aave_v3","target_chain":1}}`)
+	if result.Status != ResultStatusFailed || result.Error.Code != ErrorCodePolicyViolation || result.Error.Retryable {
+		t.Fatalf("Expected the policy to refuse the rebalance, got %+v", result)
+	}
+	if len(result.Violations) != 2 {
+		t.Fatalf("Expected both broken rules to be reported, got %+v", result.Violations)
+	}
+	utilization, size := result.Violations[0], result.Violations[1]
+	if utilization.Rule != policy.RuleMaxUtilization || utilization.Actual.String() != "0.800000" || utilization.Protocol != "aave_v3" || utilization.ChainID != 1 {
+		t.Errorf("Unexpected utilization violation %+v", utilization)
+	}
+	if size.Rule != policy.RuleMaxMoveSize || size.Limit.String() != "500.000000" || size.Actual.String() != "1000.000000" || size.Reason == "" {
+		t.Errorf("Unexpected move size violation %+v", size)
+	}
+
+	// dry runs are refused too
+	result = runFailedRebalance(t, performer, "policy-dry-run", `{"type":"rebalance_execution","parameters":{
+		"user_address":"`+account.Hex()+`","amount":"100000000","target_protocol":"aave_v3","target_chain":1,"dry_run":true}}`)
+	if result.Error.Code != ErrorCodePolicyViolation || len(result.Violations) != 1 {
+		t.Errorf("Expected the dry run to be refused, got %+v", result)
+	}
+	if len(ethereum.Sent()) != 0 {
+		t.Errorf("Expected no transaction to be sent")
+	}
+}
+
+func Test_CrossChainYieldCheckPolicyRejections(t *testing.T) {
+	performer := newAllocationPerformer(t)
+
+	result := runCrossChainYieldCheck(t, performer, "no-policy", `{"type":"cross_chain_yield_check","parameters":{"source_chain":1,"target_chain":8453,"amount":"5000000000"}}`)
+	if result.TargetProtocol != "aave_v3" || result.PolicyRejections != nil {
+		t.Fatalf("Expected aave to be recommended without a policy, got %+v", result)
+	}
+
+	WithPolicy(policy.New(policy.Config{DeniedProtocols: []string{"aave_v3"}}))(performer)
+	result = runCrossChainYieldCheck(t, performer, "denied", `{"type":"cross_chain_yield_check","parameters":{"source_chain":1,"target_chain":8453,"amount":"5000000000"}}`)
+	if result.TargetProtocol != "" || result.TargetRate != nil || result.ImprovementBps != nil {
+		t.Errorf("Expected no market to be recommended, got %+v", result)
+	}
+	if result.SourceRate == nil || len(result.Markets) != 2 {
+		t.Errorf("Expected the rates to still be reported, got %+v", result.Markets)
+	}
+	if len(result.PolicyRejections) != 1 || result.PolicyRejections[0].Rule != policy.RuleProtocolDenylist || result.PolicyRejections[0].ChainID != 8453 {
+		t.Errorf("Expected the denied target market to be reported, got %+v", result.PolicyRejections)
+	}
+}
+
+func Test_AllocationOptimizationPolicy(t *testing.T) {
+	performer := newAllocationPerformer(t)
+	WithPolicy(policy.New(policy.Config{MaxMoveSize: 4_000_000, MaxProtocolAllocation: 6_000_000}))(performer)
+
+	task := &performerV1.TaskRequest{TaskId: []byte("allocate-policy"), Payload: []byte(`{"type":"allocation_optimization","parameters":{"token":"USDC","amount":"10000000000000"}}`)}
+	if err := performer.ValidateTask(task); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	resp, err := performer.HandleTask(task)
+	if err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+	var envelope struct {
+		Result AllocationOptimizationResult `json:"result"`
+	}
+	if err := json.Unmarshal(resp.Result, &envelope); err != nil {
+		t.Fatalf("Failed to decode result: %v", err)
+	}
+	result := envelope.Result
+
+	// both aave markets together stop at the protocol cap, neither over the move size
+	if len(result.Allocations) != 2 || result.Unallocated.String() != "4000000.000000" {
+		t.Fatalf("Expected 6M split across the aave markets, got %+v", result)
+	}
+	for _, a := range result.Allocations {
+		if a.Amount.String() != "3000000.000000" {
+			t.Errorf("Expected an even split within the caps, got %+v", a)
+		}
+	}
+
+	WithPolicy(policy.New(policy.Config{AllowedProtocols: []string{"morpho_blue"}}))(performer)
+	task = &performerV1.TaskRequest{TaskId: []byte("allocate-denied"), Payload: []byte(`{"type":"allocation_optimization","parameters":{"token":"USDC","amount":"1000000000"}}`)}
+	resp, err = performer.HandleTask(task)
+	if err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+	envelope.Result = AllocationOptimizationResult{}
+	if err := json.Unmarshal(resp.Result, &envelope); err != nil {
+		t.Fatalf("Failed to decode result: %v", err)
+	}
+	if result := envelope.Result; len(result.Allocations) != 0 || result.Unallocated.String() != "1000.000000" || len(result.PolicyRejections) != 2 {
+		t.Errorf("Expected every market to be rejected, got %+v", result)
+	}
+}
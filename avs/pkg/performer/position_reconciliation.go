@@ -0,0 +1,159 @@
+package performer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+	"github.com/najnomics/crosscow-avs/pkg/positions"
+	"github.com/najnomics/crosscow-avs/pkg/tracing"
+	"github.com/najnomics/crosscow-avs/pkg/workerpool"
+)
+
+// ObservedPosition is the USDC an address holds in a market, read from its aToken,
+// Comet or vault share balance. Tracked is the position the performer tracked before the
+// read, null when it tracked none.
+type ObservedPosition struct {
+	Protocol string             `json:"protocol"`
+	ChainID  uint64             `json:"chain_id"`
+	Amount   canonical.Decimal  `json:"amount"`
+	Tracked  *canonical.Decimal `json:"tracked"`
+}
+
+// PositionDiscrepancy is a market where the chain disagrees with the tracked position
+// beyond the configured tolerance. Tracked is null for funds that were not tracked.
+type PositionDiscrepancy struct {
+	Protocol   string             `json:"protocol"`
+	ChainID    uint64             `json:"chain_id"`
+	Tracked    *canonical.Decimal `json:"tracked"`
+	Observed   canonical.Decimal  `json:"observed"`
+	Difference canonical.Decimal  `json:"difference"`
+}
+
+// PositionReconciliationResult is the result of a position_reconciliation task. Markets
+// of protocols the performer cannot move funds through have no position to read and are
+// listed as unsupported.
+type PositionReconciliationResult struct {
+	Address              string                `json:"address"`
+	Positions            []ObservedPosition    `json:"positions"`
+	Total                canonical.Decimal     `json:"total"`
+	Discrepancies        []PositionDiscrepancy `json:"discrepancies"`
+	Reconciled           bool                  `json:"reconciled"`
+	UnsupportedProtocols []string              `json:"unsupported_protocols"`
+	Failures             []MarketFailure       `json:"failures"`
+	Status               ResultStatus          `json:"status"`
+}
+
+// handlePositionReconciliation reads the positions of an address in every market it can
+// hold USDC in, compares them with the tracked ones and tracks what was read. Markets that
+// cannot be read keep their tracked position and make the result partial.
+func (yip *YieldIntelligencePerformer) handlePositionReconciliation(ctx context.Context, t *performerV1.TaskRequest, payload *TaskPayload) (interface{}, error) {
+	yip.log(ctx).Sugar().Infow("Processing position reconciliation task")
+
+	owner := common.HexToAddress(paramString(payload, "address"))
+	result := &PositionReconciliationResult{
+		Address:              owner.Hex(),
+		Positions:            []ObservedPosition{},
+		Discrepancies:        []PositionDiscrepancy{},
+		UnsupportedProtocols: []string{},
+		Failures:             []MarketFailure{},
+		Status:               ResultStatusCompleted,
+	}
+
+	var markets []market
+	unsupported := make(map[string]bool)
+	for _, m := range yip.markets(paramUint64List(payload, "chain_ids")...) {
+		if _, err := yip.adapters.MoverFor(m.protocol); errors.Is(err, adapters.ErrNotMovable) {
+			if !unsupported[m.protocol] {
+				unsupported[m.protocol] = true
+				result.UnsupportedProtocols = append(result.UnsupportedProtocols, m.protocol)
+			}
+			continue
+		}
+		markets = append(markets, m)
+	}
+
+	tracked, err := yip.positions.Positions(ctx, owner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tracked positions: %w", err)
+	}
+	trackedAmounts := make(map[market]*big.Int, len(tracked))
+	for _, p := range tracked {
+		trackedAmounts[market{protocol: p.Protocol, chainID: p.ChainID}] = p.Amount
+	}
+
+	var observed []positions.Position
+	total := new(big.Int)
+	for i, outcome := range yip.readPositions(ctx, markets, owner) {
+		m := markets[i]
+		if outcome.Err != nil {
+			result.Failures = append(result.Failures, marketFailure(m, outcome.Err))
+			continue
+		}
+		observed = append(observed, positions.Position{Protocol: m.protocol, ChainID: m.chainID, Amount: outcome.Value})
+		total.Add(total, outcome.Value)
+		result.Positions = append(result.Positions, ObservedPosition{
+			Protocol: m.protocol,
+			ChainID:  m.chainID,
+			Amount:   usdcAmount(outcome.Value),
+			Tracked:  optionalUSDC(trackedAmounts[m]),
+		})
+	}
+	if len(markets) > 0 && len(observed) == 0 {
+		return nil, fmt.Errorf("failed to read any of %d positions", len(markets))
+	}
+	result.Total = usdcAmount(total)
+
+	discrepancies, err := yip.positions.Reconcile(ctx, owner, observed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reconcile positions: %w", err)
+	}
+	for _, d := range discrepancies {
+		difference := new(big.Int).Set(d.Observed)
+		if d.Tracked != nil {
+			difference.Sub(difference, d.Tracked)
+		}
+		result.Discrepancies = append(result.Discrepancies, PositionDiscrepancy{
+			Protocol:   d.Protocol,
+			ChainID:    d.ChainID,
+			Tracked:    optionalUSDC(d.Tracked),
+			Observed:   usdcAmount(d.Observed),
+			Difference: usdcAmount(difference),
+		})
+	}
+
+	if len(result.Failures) > 0 {
+		result.Status = ResultStatusPartial
+	}
+	result.Reconciled = len(result.Discrepancies) == 0 && len(result.Failures) == 0
+	return result, nil
+}
+
+// readPositions reads the position of owner in each of markets concurrently
+func (yip *YieldIntelligencePerformer) readPositions(ctx context.Context, markets []market, owner common.Address) []workerpool.Result[*big.Int] {
+	return workerpool.Map(ctx, yip.pool, markets, func(ctx context.Context, m market) (*big.Int, error) {
+		mover, err := yip.adapters.MoverFor(m.protocol)
+		if err != nil {
+			return nil, err
+		}
+		ctx, span := startAdapterSpan(ctx, "PositionOf", m)
+		amount, err := mover.PositionOf(ctx, m.chainID, owner)
+		tracing.End(span, err)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s position on chain %d: %w", m.protocol, m.chainID, err)
+		}
+		return amount, nil
+	})
+}
+
+func (yip *YieldIntelligencePerformer) validatePositionReconciliationTask(payload *TaskPayload) error {
+	if yip.positions == nil {
+		return fmt.Errorf("position tracking is not configured on this performer")
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package performer
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/positions"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+	"go.uber.org/zap"
+)
+
+func runReconciliation(t *testing.T, performer *YieldIntelligencePerformer, taskID, payload string) PositionReconciliationResult {
+	t.Helper()
+	task := &performerV1.TaskRequest{TaskId: []byte(taskID), Payload: []byte(payload)}
+	if err := performer.ValidateTask(task); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	resp, err := performer.HandleTask(task)
+	if err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+	var envelope struct {
+		Result PositionReconciliationResult `json:"result"`
+	}
+	if err := json.Unmarshal(resp.Result, &envelope); err != nil {
+		t.Fatalf("Failed to decode result: %v", err)
+	}
+	return envelope.Result
+}
+
+func Test_PositionReconciliation(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+	owner := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	// venus has no mover, so no position to read
+	venus := &fakeAdapter{protocol: adapters.ProtocolVenus, markets: newFakeAaveAdapter().markets}
+	tracker := positions.New(positions.DefaultConfig(), store.NewMemoryKV())
+	performer := NewYieldIntelligencePerformer(logger,
+		WithAdapters(adapters.NewRegistry(newMovableAave(big.NewInt(1_000_000_000)), venus)),
+		WithPositions(tracker),
+	)
+
+	ctx := context.Background()
+	for chainID, amount := range map[uint64]int64{1: 999_000_000, adapters.ChainIDBase: 500_000_000} {
+		if err := tracker.Record(ctx, positions.Position{Owner: owner, Protocol: adapters.ProtocolAaveV3, ChainID: chainID, Amount: big.NewInt(amount)}); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+
+	const payload = `{"type":"position_reconciliation","parameters":{"address":"0x00000000000000000000000000000000000000aa"}}`
+	result := runReconciliation(t, performer, "reconcile", payload)
+	if result.Status != ResultStatusCompleted || len(result.Positions) != 2 || result.Total.String() != "2000.000000" {
+		t.Fatalf("Expected both aave positions to be read, got %+v", result)
+	}
+	if tracked := result.Positions[0].Tracked; tracked == nil || tracked.String() != "999.000000" {
+		t.Errorf("Expected the tracked position to be reported, got %v", tracked)
+	}
+	if len(result.UnsupportedProtocols) != 1 || result.UnsupportedProtocols[0] != adapters.ProtocolVenus {
+		t.Errorf("Expected venus to be unsupported, got %v", result.UnsupportedProtocols)
+	}
+
+	// 1 USDC of interest on Ethereum is within the tolerance, 500 USDC on Base is not
+	if result.Reconciled || len(result.Discrepancies) != 1 {
+		t.Fatalf("Expected one discrepancy, got %+v", result.Discrepancies)
+	}
+	d := result.Discrepancies[0]
+	if d.ChainID != adapters.ChainIDBase || d.Tracked.String() != "500.000000" || d.Observed.String() != "1000.000000" || d.Difference.String() != "500.000000" {
+		t.Errorf("Unexpected discrepancy: %+v", d)
+	}
+
+	// the observed positions are tracked now
+	result = runReconciliation(t, performer, "reconcile-again", payload)
+	if !result.Reconciled || len(result.Discrepancies) != 0 {
+		t.Errorf("Expected the positions to reconcile once tracked, got %+v", result.Discrepancies)
+	}
+}
+
+func Test_PositionReconciliationValidation(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+	tracking := NewYieldIntelligencePerformer(logger,
+		WithAdapters(adapters.NewRegistry(newMovableAave())),
+		WithPositions(positions.New(positions.DefaultConfig(), store.NewMemoryKV())),
+	)
+	untracked := NewYieldIntelligencePerformer(logger, WithAdapters(adapters.NewRegistry(newMovableAave())))
+
+	for name, tc := range map[string]struct {
+		performer *YieldIntelligencePerformer
+		params    string
+	}{
+		"missing address":  {performer: tracking, params: `{}`},
+		"invalid address":  {performer: tracking, params: `{"address":"0x1234"}`},
+		"empty chain ids":  {performer: tracking, params: `{"address":"0x00000000000000000000000000000000000000aa","chain_ids":[]}`},
+		"invalid chain id": {performer: tracking, params: `{"address":"0x00000000000000000000000000000000000000aa","chain_ids":[1.5]}`},
+		"no tracking":      {performer: untracked, params: `{"address":"0x00000000000000000000000000000000000000aa"}`},
+	} {
+		task := &performerV1.TaskRequest{TaskId: []byte(name), Payload: []byte(`{"type":"position_reconciliation","parameters":` + tc.params + `}`)}
+		if err := tc.performer.ValidateTask(task); err == nil {
+			t.Errorf("%s: expected validation to fail", name)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package performer
+
+import (
+	"context"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/najnomics/crosscow-avs/pkg/logging"
+)
+
+// Prewarm computes the result of a task seen in the TaskMailbox before Hourglass delivers
+// it, so the delivered task is answered from the result cache. Only tasks that validate
+// and whose type is cached are warmed; it reports whether the task was. As for tasks
+// answered from the cache, nothing is recorded and attestations are made on delivery.
+func (yip *YieldIntelligencePerformer) Prewarm(ctx context.Context, taskID, payload []byte) (bool, error) {
+	t := &performerV1.TaskRequest{TaskId: taskID, Payload: payload}
+	parsed, err := parseTaskPayload(t)
+	if err != nil {
+		return false, newTaskError(ErrorCodeValidation, err)
+	}
+	if yip.cache.TTL(string(parsed.Type)) <= 0 {
+		return false, nil
+	}
+	if err := yip.ValidateTask(t); err != nil {
+		return false, err
+	}
+
+	runCtx, done, err := yip.lifecycle.begin()
+	if err != nil {
+		return false, newTaskError(ErrorCodeShuttingDown, err)
+	}
+	defer done()
+	// the task runs until either the caller or the performer is done with it
+	runCtx, cancel := context.WithCancel(runCtx)
+	defer cancel()
+	stop := context.AfterFunc(ctx, cancel)
+	defer stop()
+
+	runCtx = logging.WithLogger(runCtx, yip.taskLogger(t, parsed))
+	if _, err := yip.cachedResult(runCtx, t, parsed); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,47 @@
+package performer
+
+import (
+	"context"
+	"testing"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/cache"
+	"go.uber.org/zap"
+)
+
+func Test_PrewarmedTaskServedFromCache(t *testing.T) {
+	resultCache, err := cache.New(cache.DefaultConfig(), cache.NewMemoryBackend(10), nil)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	adapter := &countingAdapter{fakeAdapter: newFakeAaveAdapter()}
+	performer := NewYieldIntelligencePerformer(zap.NewNop(),
+		WithAdapters(adapters.NewRegistry(adapter)),
+		WithResultCache(resultCache),
+	)
+
+	payload := []byte(`{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1}}`)
+	warmed, err := performer.Prewarm(context.Background(), []byte("0xwarm"), payload)
+	if err != nil || !warmed {
+		t.Fatalf("Expected the task to be warmed, got %v: %v", warmed, err)
+	}
+	if _, err := performer.HandleTask(&performerV1.TaskRequest{TaskId: []byte("0xwarm"), Payload: payload}); err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+	if reads := adapter.reads.Load(); reads != 1 {
+		t.Errorf("Expected the delivered task to be answered from the warmed result, got %d reads", reads)
+	}
+
+	for name, payload := range map[string]string{
+		"uncached": `{"type":"rebalance_execution","parameters":{}}`,
+		"invalid":  `{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","token":"DAI","chain_id":1}}`,
+	} {
+		if warmed, _ := performer.Prewarm(context.Background(), []byte("0x"+name), []byte(payload)); warmed {
+			t.Errorf("%s: expected the task not to be warmed", name)
+		}
+	}
+	if reads := adapter.reads.Load(); reads != 1 {
+		t.Errorf("Expected tasks that are not warmed not to read markets, got %d reads", reads)
+	}
+}
@@ -0,0 +1,113 @@
+package performer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/cctp"
+	"github.com/najnomics/crosscow-avs/pkg/policy"
+	"github.com/najnomics/crosscow-avs/pkg/risk"
+)
+
+// profileLimits are the parameters of a profile overriding the limits of its preset
+var profileLimits = []string{"min_apy_improvement_bps", "max_risk_score", "max_bridge_latency_seconds"}
+
+// paramProfile returns the profile parameter of payload, nil when absent: the limits of
+// its preset, with the ones set beside it taking their place. A limit set to zero lifts
+// the limit of the preset.
+func paramProfile(payload *TaskPayload) *policy.Profile {
+	params, ok := payload.Parameters["profile"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	var profile policy.Profile
+	if preset, _ := params["preset"].(string); preset != "" {
+		profile, _ = policy.ProfilePreset(preset)
+	}
+	limits := []*uint64{&profile.MinAPYImprovementBps, &profile.MaxRiskScore, &profile.MaxBridgeLatencySeconds}
+	for i, key := range profileLimits {
+		if v, ok := params[key].(float64); ok {
+			*limits[i] = uint64(v)
+		}
+	}
+	return &profile
+}
+
+// profileParam encodes profile as the profile parameter paramProfile reads back
+func profileParam(profile *policy.Profile) map[string]interface{} {
+	param := map[string]interface{}{
+		"min_apy_improvement_bps":    float64(profile.MinAPYImprovementBps),
+		"max_risk_score":             float64(profile.MaxRiskScore),
+		"max_bridge_latency_seconds": float64(profile.MaxBridgeLatencySeconds),
+	}
+	if profile.Preset != "" {
+		param["preset"] = profile.Preset
+	}
+	return param
+}
+
+// riskScore is the overall risk score of m, read as state, from its pool and the risk
+// parameters its protocol reports. Admin keys and the security record, which
+// risk_assessment tasks also score, are left out so profiles cost one read per market.
+func (yip *YieldIntelligencePerformer) riskScore(ctx context.Context, m market, state *adapters.MarketState) (*big.Rat, error) {
+	riskState, err := yip.readRiskState(ctx, m)
+	if err != nil && !errors.Is(err, adapters.ErrNoRiskData) {
+		return nil, fmt.Errorf("failed to read %s risk parameters on chain %d: %w", m.protocol, m.chainID, err)
+	}
+	return risk.Assess(riskMetrics(state, riskState), risk.DefaultThresholds, risk.DefaultWeights).Overall, nil
+}
+
+// checkProfileRisk returns the violations of profile funds going to m, read as state,
+// would make for its risk score. Markets are only scored when the profile limits risk.
+func (yip *YieldIntelligencePerformer) checkProfileRisk(ctx context.Context, profile *policy.Profile, m market, state *adapters.MarketState) ([]policy.Violation, error) {
+	if profile == nil || profile.MaxRiskScore == 0 {
+		return nil, nil
+	}
+	score, err := yip.riskScore(ctx, m, state)
+	if err != nil {
+		return nil, err
+	}
+	return profile.CheckRisk(policyMarket(m, state), score), nil
+}
+
+// checkProfile refuses route, dry runs included, when it breaks a rule of the user's
+// profile: the target market scores above the risk it takes, CCTP takes longer to
+// attest the burn than it waits, or the target market would not earn enough more than
+// the source once the amount is deposited into it
+func (yip *YieldIntelligencePerformer) checkProfile(ctx context.Context, route *rebalanceRoute) error {
+	profile := route.profile
+	if profile == nil {
+		return nil
+	}
+	target := market{protocol: route.targetProtocol, chainID: route.targetChain}
+	state, err := yip.readMarket(ctx, target)
+	if err != nil {
+		return err
+	}
+	violations, err := yip.checkProfileRisk(ctx, profile, target, state)
+	if err != nil {
+		return err
+	}
+	if route.crossChain() {
+		latency, err := cctp.AttestationTime(&cctp.Transfer{SourceChainID: route.sourceChain, DestinationChainID: route.targetChain, MinFinality: route.speed.Threshold()})
+		if err != nil {
+			return fmt.Errorf("invalid route: %w", err)
+		}
+		violations = append(violations, profile.CheckBridgeLatency(policyMarket(target, state), latency)...)
+	}
+	if route.sourceProtocol != "" && profile.MinAPYImprovementBps > 0 {
+		source, err := yip.readMarket(ctx, market{protocol: route.sourceProtocol, chainID: route.sourceChain})
+		if err != nil {
+			return err
+		}
+		improvement := improvementBps(ratePercent(source.Pool.SupplyRate()), ratePercent(state.Pool.DepositImpact(route.amount).SupplyRate))
+		violations = append(violations, profile.CheckImprovement(policyMarket(target, state), improvement.Rat())...)
+	}
+	if len(violations) > 0 {
+		return newTaskError(ErrorCodePolicyViolation, &policyError{violations: policyViolations(violations)})
+	}
+	return nil
+}
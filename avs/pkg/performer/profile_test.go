@@ -0,0 +1,116 @@
+package performer
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/najnomics/crosscow-avs/pkg/policy"
+)
+
+func Test_RebalanceRefusedByProfile(t *testing.T) {
+	performer, account, ethereum := newSubmittingPerformer(t)
+
+	// each refusal consumes its nonce
+	for i, tc := range []struct {
+		route   string
+		profile string
+		rule    policy.Rule
+	}{
+		{route: `"source_chain":1,"target_chain":8453`, profile: `{"max_bridge_latency_seconds":600}`, rule: policy.RuleMaxBridgeLatency},
+		{route: `"target_chain":1`, profile: `{"max_risk_score":1}`, rule: policy.RuleMaxRiskScore},
+		{route: `"source_protocol":"aave_v3","source_chain":1,"target_chain":8453`, profile: `{"min_apy_improvement_bps":10000}`, rule: policy.RuleMinAPYImprovement},
+	} {
+		result := runFailedRebalance(t, performer, string(tc.rule), `{"type":"rebalance_execution","parameters":{
+			"user_address":"`+account.Hex()+`","nonce":"`+strconv.Itoa(i+1)+`","amount":"1000000000","target_protocol":"aave_v3",`+tc.route+`,"profile":`+tc.profile+`}}`)
+		if result.Error.Code != ErrorCodePolicyViolation || len(result.Violations) != 1 || result.Violations[0].Rule != tc.rule {
+			t.Errorf("%s: expected the profile to refuse the rebalance, got %+v", tc.rule, result)
+		}
+	}
+	if len(ethereum.Sent()) != 0 {
+		t.Errorf("Expected no transaction to be sent")
+	}
+}
+
+func Test_CrossChainYieldCheckProfile(t *testing.T) {
+	performer := newAllocationPerformer(t)
+	check := func(id, profile string) CrossChainYieldResult {
+		return runCrossChainYieldCheck(t, performer, id, `{"type":"cross_chain_yield_check","parameters":{"source_chain":1,"target_chain":8453,"amount":"5000000000","profile":`+profile+`}}`)
+	}
+
+	// only fast CCTP attests within a minute
+	result := check("minute", `{"max_bridge_latency_seconds":60}`)
+	if result.Route == nil || result.Route.LatencySeconds > 60 || len(result.Routes) != 3 || result.PolicyRejections != nil {
+		t.Errorf("Expected the fastest bridges to be recommended, got %+v", result)
+	}
+	result = check("instant", `{"max_bridge_latency_seconds":1}`)
+	if result.Route != nil || len(result.PolicyRejections) != 1 || result.PolicyRejections[0].Rule != policy.RuleMaxBridgeLatency {
+		t.Errorf("Expected no bridge to be fast enough, got %+v", result)
+	}
+
+	result = check("risky", `{"max_risk_score":1}`)
+	if result.TargetProtocol != "" || len(result.PolicyRejections) != 1 || result.PolicyRejections[0].Rule != policy.RuleMaxRiskScore {
+		t.Errorf("Expected the target market to be too risky, got %+v", result)
+	}
+
+	result = check("improvement", `{"min_apy_improvement_bps":10000}`)
+	if len(result.PolicyRejections) != 1 || result.PolicyRejections[0].Rule != policy.RuleMinAPYImprovement || result.PolicyRejections[0].Limit.String() != "10000.00" {
+		t.Errorf("Expected the improvement to be rejected, got %+v", result.PolicyRejections)
+	}
+}
+
+func Test_AllocationOptimizationProfile(t *testing.T) {
+	performer := newAllocationPerformer(t)
+	task := &performerV1.TaskRequest{TaskId: []byte("allocate-profile"), Payload: []byte(`{"type":"allocation_optimization","parameters":{
+		"token":"USDC","amount":"1000000000","profile":{"preset":"aggressive","max_risk_score":1}}}`)}
+	resp, err := performer.HandleTask(task)
+	if err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+	var envelope struct {
+		Result AllocationOptimizationResult `json:"result"`
+	}
+	if err := json.Unmarshal(resp.Result, &envelope); err != nil {
+		t.Fatalf("Failed to decode result: %v", err)
+	}
+	if result := envelope.Result; len(result.Allocations) != 0 || len(result.PolicyRejections) != 2 || result.PolicyRejections[0].Rule != policy.RuleMaxRiskScore {
+		t.Errorf("Expected every market to be too risky, got %+v", result)
+	}
+}
+
+func Test_ProfileValidation(t *testing.T) {
+	performer := newAllocationPerformer(t)
+	for name, profile := range map[string]string{
+		"not an object":  `"conservative"`,
+		"unknown preset": `{"preset":"degen"}`,
+		"unknown limit":  `{"max_tvl":1}`,
+		"negative limit": `{"min_apy_improvement_bps":-1}`,
+		"risk above 100": `{"max_risk_score":101}`,
+	} {
+		task := &performerV1.TaskRequest{TaskId: []byte(name), Payload: []byte(`{"type":"cross_chain_yield_check","parameters":{"source_chain":1,"target_chain":8453,"amount":"1000000","profile":` + profile + `}}`)}
+		if err := performer.ValidateTask(task); err == nil {
+			t.Errorf("%s: expected the task to be rejected", name)
+		}
+	}
+}
+
+func Test_PlanCarriesProfile(t *testing.T) {
+	payload := &TaskPayload{Type: TaskTypeRebalancePlan, Parameters: map[string]interface{}{
+		"user_address": "0x0000000000000000000000000000000000000001", "amount": "100000000", "target_protocol": "aave_v3", "target_chain": 1.0,
+		"profile": map[string]interface{}{"preset": "conservative", "max_bridge_latency_seconds": 0.0},
+	}}
+	plan := rebalancePlan(payload)
+	want := policy.Profile{Preset: policy.PresetConservative, MinAPYImprovementBps: 50, MaxRiskScore: 25}
+	if plan.Profile == nil || *plan.Profile != want {
+		t.Fatalf("Expected the conservative preset without latency limit, got %+v", plan.Profile)
+	}
+	if executed := paramProfile(plan.execution()); executed == nil || *executed != want {
+		t.Errorf("Expected the execution to carry the profile of the plan, got %+v", executed)
+	}
+
+	delete(payload.Parameters, "profile")
+	if plan := rebalancePlan(payload); plan.Profile != nil {
+		t.Errorf("Expected plans without profile to carry none, got %+v", plan.Profile)
+	}
+}
@@ -0,0 +1,137 @@
+package performer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+)
+
+// DefaultIncidentLookback is about an hour of Ethereum blocks
+const DefaultIncidentLookback = 300
+
+// IncidentReason is why a market is not safe to deposit into
+type IncidentReason string
+
+const (
+	IncidentReasonPaused         IncidentReason = "paused"
+	IncidentReasonFrozen         IncidentReason = "frozen"
+	IncidentReasonEmergencyEvent IncidentReason = "emergency_event"
+	IncidentReasonActiveIncident IncidentReason = "active_incident"
+
+	// IncidentReasonStatusUnknown and IncidentReasonEventsUnknown are given when the
+	// protocol does not report its status or events, which is never taken as safe
+	IncidentReasonStatusUnknown IncidentReason = "status_unknown"
+	IncidentReasonEventsUnknown IncidentReason = "events_unknown"
+)
+
+// EmergencyEventReport is an emergency event found in the scanned blocks
+type EmergencyEventReport struct {
+	Name        string `json:"name"`
+	Contract    string `json:"contract"`
+	BlockNumber uint64 `json:"block_number"`
+	TxHash      string `json:"tx_hash"`
+	LogIndex    uint64 `json:"log_index"`
+}
+
+// ProtocolIncidentCheckResult is the result of a protocol_incident_check task. Active
+// incidents are only checked when a security feed is configured.
+type ProtocolIncidentCheckResult struct {
+	Protocol        string                 `json:"protocol"`
+	ChainID         uint64                 `json:"chain_id"`
+	FromBlock       uint64                 `json:"from_block"`
+	ToBlock         uint64                 `json:"to_block"`
+	Paused          bool                   `json:"paused"`
+	Frozen          bool                   `json:"frozen"`
+	EmergencyEvents []EmergencyEventReport `json:"emergency_events"`
+	ActiveIncidents []IncidentReport       `json:"active_incidents"`
+	SafeToDeposit   bool                   `json:"safe_to_deposit"`
+	Reasons         []IncidentReason       `json:"reasons"`
+	Status          ResultStatus           `json:"status"`
+}
+
+// handleProtocolIncidentCheck decides whether the USDC market of a protocol is safe to
+// deposit into: not paused or frozen, no emergency events in the latest blocks and no
+// active incident in the security feed. Checks that cannot be made count against the
+// market and make the result partial.
+func (yip *YieldIntelligencePerformer) handleProtocolIncidentCheck(ctx context.Context, t *performerV1.TaskRequest, payload *TaskPayload) (interface{}, error) {
+	yip.log(ctx).Sugar().Infow("Processing protocol incident check task")
+
+	result := &ProtocolIncidentCheckResult{
+		Protocol:        paramString(payload, "protocol"),
+		ChainID:         paramUint64(payload, "chain_id"),
+		EmergencyEvents: []EmergencyEventReport{},
+		ActiveIncidents: []IncidentReport{},
+		Reasons:         []IncidentReason{},
+		Status:          ResultStatusCompleted,
+	}
+	lookback := paramUint64(payload, "lookback_blocks")
+	if lookback == 0 {
+		lookback = DefaultIncidentLookback
+	}
+
+	state, err := yip.readRiskState(ctx, market{protocol: result.Protocol, chainID: result.ChainID})
+	switch {
+	case errors.Is(err, adapters.ErrNoRiskData):
+		result.Reasons = append(result.Reasons, IncidentReasonStatusUnknown)
+		result.Status = ResultStatusPartial
+	case err != nil:
+		return nil, fmt.Errorf("failed to read %s status on chain %d: %w", result.Protocol, result.ChainID, err)
+	default:
+		result.Paused, result.Frozen = state.Paused, state.Frozen
+		if state.Paused {
+			result.Reasons = append(result.Reasons, IncidentReasonPaused)
+		}
+		if state.Frozen {
+			result.Reasons = append(result.Reasons, IncidentReasonFrozen)
+		}
+	}
+
+	var log *adapters.EmergencyLog
+	events, err := yip.adapters.EmergencyReaderFor(result.Protocol)
+	if err == nil {
+		log, err = events.EmergencyEvents(ctx, result.ChainID, lookback)
+	}
+	switch {
+	case errors.Is(err, adapters.ErrNoEmergencyData):
+		result.Reasons = append(result.Reasons, IncidentReasonEventsUnknown)
+		result.Status = ResultStatusPartial
+	case err != nil:
+		return nil, fmt.Errorf("failed to read %s emergency events on chain %d: %w", result.Protocol, result.ChainID, err)
+	default:
+		result.FromBlock, result.ToBlock = log.FromBlock, log.ToBlock
+		for _, e := range log.Events {
+			result.EmergencyEvents = append(result.EmergencyEvents, EmergencyEventReport{
+				Name:        e.Name,
+				Contract:    e.Contract.Hex(),
+				BlockNumber: e.BlockNumber,
+				TxHash:      e.TxHash.Hex(),
+				LogIndex:    uint64(e.LogIndex),
+			})
+		}
+		if len(log.Events) > 0 {
+			result.Reasons = append(result.Reasons, IncidentReasonEmergencyEvent)
+		}
+	}
+
+	if yip.security != nil {
+		record, err := yip.securityRecord(ctx, result.Protocol, result.ChainID)
+		if err != nil {
+			yip.log(ctx).Sugar().Warnw("Failed to read security feed", "protocol", result.Protocol, "error", err)
+			result.Status = ResultStatusPartial
+		} else if len(record.ActiveIncidents) > 0 {
+			result.ActiveIncidents = record.ActiveIncidents
+			result.Reasons = append(result.Reasons, IncidentReasonActiveIncident)
+		}
+	}
+
+	result.SafeToDeposit = len(result.Reasons) == 0
+	return result, nil
+}
+
+func (yip *YieldIntelligencePerformer) validateProtocolIncidentCheckTask(payload *TaskPayload) error {
+	_, err := yip.adapters.Get(paramString(payload, "protocol"))
+	return err
+}
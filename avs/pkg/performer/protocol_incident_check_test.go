@@ -0,0 +1,103 @@
+package performer
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/security"
+	"go.uber.org/zap"
+)
+
+func runIncidentCheck(t *testing.T, performer *YieldIntelligencePerformer, payload string) ProtocolIncidentCheckResult {
+	t.Helper()
+	task := &performerV1.TaskRequest{TaskId: []byte("incident-task"), Payload: []byte(payload)}
+	if err := performer.ValidateTask(task); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	resp, err := performer.HandleTask(task)
+	if err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+	var envelope struct {
+		Result ProtocolIncidentCheckResult `json:"result"`
+	}
+	if err := json.Unmarshal(resp.Result, &envelope); err != nil {
+		t.Fatalf("Failed to decode result: %v", err)
+	}
+	return envelope.Result
+}
+
+func Test_ProtocolIncidentCheck(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+	const payload = `{"type":"protocol_incident_check","parameters":{"protocol":"aave_v3","chain_id":1}}`
+
+	performer := NewYieldIntelligencePerformer(logger, WithAdapters(adapters.NewRegistry(newFakeAaveAdapter())))
+	result := runIncidentCheck(t, performer, payload)
+	if !result.SafeToDeposit || len(result.Reasons) != 0 || result.Status != ResultStatusCompleted {
+		t.Errorf("Expected a healthy market to be safe, got %+v", result)
+	}
+	if result.FromBlock != 19_999_701 || result.ToBlock != 20_000_000 {
+		t.Errorf("Expected the scanned range to be reported, got %d to %d", result.FromBlock, result.ToBlock)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"updated_at":1700000000,"protocols":{"aave_v3":{"audits":[],"incidents":[
+			{"id":"oracle","title":"Oracle misconfiguration","severity":"medium","active":true,"chain_ids":[],"loss_usd":"","timestamp":1699990000,"url":""}
+		]}}}`))
+	}))
+	defer server.Close()
+	aave := newFakeAaveAdapter()
+	aave.risk.Frozen = true
+	aave.emergency.Events = []adapters.EmergencyEvent{{Name: "ReserveFrozen", Contract: common.HexToAddress("0x64b761D848206f447Fe2dd461b0c635Ec39EbB27"), BlockNumber: 19_999_950}}
+	performer = NewYieldIntelligencePerformer(logger,
+		WithAdapters(adapters.NewRegistry(aave)),
+		WithSecurityFeed(security.NewFeed(security.Config{Url: server.URL, AllowUnsigned: true}, nil)),
+	)
+	result = runIncidentCheck(t, performer, payload)
+	want := []IncidentReason{IncidentReasonFrozen, IncidentReasonEmergencyEvent, IncidentReasonActiveIncident}
+	if result.SafeToDeposit || !reflect.DeepEqual(result.Reasons, want) {
+		t.Errorf("Expected reasons %v, got %v", want, result.Reasons)
+	}
+	if len(result.EmergencyEvents) != 1 || result.EmergencyEvents[0].BlockNumber != 19_999_950 || len(result.ActiveIncidents) != 1 {
+		t.Errorf("Expected the freeze and the incident to be reported, got %+v", result)
+	}
+}
+
+func Test_ProtocolIncidentCheckWithoutData(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+	aave := newFakeAaveAdapter()
+	aave.risk, aave.emergency = nil, nil
+	performer := NewYieldIntelligencePerformer(logger, WithAdapters(adapters.NewRegistry(aave)))
+
+	// a market that cannot be checked is never safe
+	result := runIncidentCheck(t, performer, `{"type":"protocol_incident_check","parameters":{"protocol":"aave_v3","chain_id":1,"lookback_blocks":50}}`)
+	want := []IncidentReason{IncidentReasonStatusUnknown, IncidentReasonEventsUnknown}
+	if result.SafeToDeposit || result.Status != ResultStatusPartial || !reflect.DeepEqual(result.Reasons, want) {
+		t.Errorf("Expected an unsafe partial result for %v, got %+v", want, result)
+	}
+
+	for _, params := range []string{
+		`{"protocol":"euler","chain_id":1}`,
+		`{"protocol":"aave_v3"}`,
+		`{"protocol":"aave_v3","chain_id":1,"lookback_blocks":0}`,
+		`{"protocol":"aave_v3","chain_id":1,"lookback_blocks":10.5}`,
+		`{"protocol":"aave_v3","chain_id":1,"lookback_blocks":20000}`,
+	} {
+		task := &performerV1.TaskRequest{TaskId: []byte("incident-task"), Payload: []byte(`{"type":"protocol_incident_check","parameters":` + params + `}`)}
+		if err := performer.ValidateTask(task); err == nil {
+			t.Errorf("Expected %s to be rejected", params)
+		}
+	}
+}
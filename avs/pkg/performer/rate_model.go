@@ -0,0 +1,46 @@
+package performer
+
+import (
+	"math/big"
+
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+	"github.com/najnomics/crosscow-avs/pkg/irm"
+)
+
+// RateModelReport is the interest rate curve of a market, as its adapter read it. Rates
+// are annual percentages and the kink a utilization ratio; see irm.Curve for the kinds.
+// Kink, slopes and reserve factor are omitted for fixed rates. Projected is the market
+// once the deposit amount of the task is added, omitted without one; large deposits
+// lower utilization and earn less than the current rate.
+type RateModelReport struct {
+	Kind          string             `json:"kind"`
+	Kink          *canonical.Decimal `json:"kink,omitempty"`
+	BaseRate      canonical.Decimal  `json:"base_rate"`
+	Slope1        *canonical.Decimal `json:"slope1,omitempty"`
+	Slope2        *canonical.Decimal `json:"slope2,omitempty"`
+	ReserveFactor *canonical.Decimal `json:"reserve_factor,omitempty"`
+	Projected     *RateImpactPoint   `json:"projected,omitempty"`
+}
+
+// rateModelReport describes the rate model of pool and projects deposit into it when
+// positive. Pools of unknown models are not reported.
+func rateModelReport(pool irm.Pool, deposit *big.Int) *RateModelReport {
+	curve, ok := irm.Describe(pool.Model)
+	if !ok {
+		return nil
+	}
+	report := &RateModelReport{Kind: curve.Kind, BaseRate: ratePercent(curve.BaseRate)}
+	if curve.Kind != irm.KindFixed {
+		kink, slope1, slope2 := canonical.Ratio(curve.Kink), ratePercent(curve.Slope1), ratePercent(curve.Slope2)
+		report.Kink, report.Slope1, report.Slope2 = &kink, &slope1, &slope2
+		if curve.Kind != irm.KindComet {
+			reserveFactor := canonical.Ratio(curve.ReserveFactor)
+			report.ReserveFactor = &reserveFactor
+		}
+	}
+	if deposit != nil && deposit.Sign() > 0 {
+		projected := rateImpactPoint(pool.DepositImpact(deposit))
+		report.Projected = &projected
+	}
+	return report
+}
@@ -0,0 +1,42 @@
+package performer
+
+import (
+	"testing"
+
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/irm"
+	"go.uber.org/zap"
+)
+
+func Test_RateModelReport(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+	performer := NewYieldIntelligencePerformer(logger, WithAdapters(adapters.NewRegistry(newFakeAaveAdapter())))
+
+	var result YieldMonitoringResult
+	if err := runYieldMonitoring(t, performer, "model", `{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1,"deposit_amount":"100000000000"}}`, &result); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	model := result.RateModel
+	if model == nil || model.Kind != irm.KindKink || model.Kink.String() != "0.900000" || model.Slope2.String() != "60.0000" {
+		t.Fatalf("Expected the kinked curve of the market, got %+v", model)
+	}
+	if p := model.Projected; p == nil || p.SupplyRate.String() != "3.8323" || p.Utilization.String() != "0.799201" {
+		t.Errorf("Expected the deposit to lower the supply rate, got %+v", p)
+	}
+
+	if err := runYieldMonitoring(t, performer, "negative", `{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1,"deposit_amount":"-1000000"}}`, &result); err == nil {
+		t.Errorf("Expected a negative deposit_amount to be rejected")
+	}
+
+	// moving 100,000 USDC to ethereum earns less there than its current rate
+	cross := runCrossChainYieldCheck(t, performer, "projected", `{"type":"cross_chain_yield_check","parameters":{"source_chain":8453,"target_chain":1,"amount":"100000000000"}}`)
+	if cross.TargetProjectedRate == nil || cross.TargetProjectedRate.String() != "3.8323" || cross.TargetRate == nil || cross.TargetRate.String() != "3.8400" {
+		t.Errorf("Expected the projected rate of the target market, got %+v", cross)
+	}
+	if len(cross.Markets) != 1 || cross.Markets[0].ProjectedRate == nil || cross.Markets[0].ProjectedRate.Cmp(cross.Markets[0].SupplyRate) >= 0 {
+		t.Errorf("Expected target markets to project the deposit, got %+v", cross.Markets)
+	}
+}
@@ -0,0 +1,1315 @@
+package performer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/addressbook"
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+	"github.com/najnomics/crosscow-avs/pkg/cctp"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/flashloan"
+	"github.com/najnomics/crosscow-avs/pkg/permit"
+	"github.com/najnomics/crosscow-avs/pkg/policy"
+	"github.com/najnomics/crosscow-avs/pkg/positions"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+	"github.com/najnomics/crosscow-avs/pkg/tokens"
+	"github.com/najnomics/crosscow-avs/pkg/txmgr"
+)
+
+// Actions of a simulated rebalance, in execution order
+const (
+	ActionWithdraw = "withdraw"
+	ActionApprove  = "approve"
+	ActionBurn     = "burn"
+	ActionBridge   = "bridge"
+	ActionMint     = "mint"
+	ActionDeposit  = "deposit"
+
+	// ActionMigrate moves a same-chain position atomically through a flash loan
+	ActionMigrate = "migrate"
+
+	// ActionPermit and ActionPull move the funds of a user into the performer account with
+	// a permit they signed. Permit2 permits are pulled without a permit step.
+	ActionPermit = "permit"
+	ActionPull   = "pull"
+)
+
+// fallbackGas is the conservative gas used by steps that cannot be simulated, such as
+// the mint that needs Circle's attestation first
+var fallbackGas = map[string]uint64{
+	ActionWithdraw: 250_000,
+	ActionApprove:  60_000,
+	ActionBurn:     180_000,
+	ActionBridge:   150_000,
+	ActionMint:     200_000,
+	ActionDeposit:  250_000,
+	ActionMigrate:  700_000,
+	ActionPermit:   90_000,
+	ActionPull:     100_000,
+}
+
+// blockTimes is the expected time for a transaction to be included, per chain
+var blockTimes = map[uint64]time.Duration{
+	adapters.ChainIDEthereum: 12 * time.Second,
+	adapters.ChainIDBase:     2 * time.Second,
+	adapters.ChainIDArbitrum: time.Second,
+
+	adapters.ChainIDSepolia:         12 * time.Second,
+	adapters.ChainIDBaseSepolia:     2 * time.Second,
+	adapters.ChainIDArbitrumSepolia: time.Second,
+}
+
+const defaultBlockTime = 12 * time.Second
+
+// SimulatedStep is one transaction of a simulated rebalance
+type SimulatedStep struct {
+	Action   string `json:"action"`
+	ChainID  uint64 `json:"chain_id"`
+	Protocol string `json:"protocol,omitempty"`
+	Contract string `json:"contract"`
+	GasUsed  uint64 `json:"gas_used"`
+
+	// Simulated is false when gas is a fallback estimate, because the step depends on
+	// funds that only exist after earlier steps settle
+	Simulated    bool   `json:"simulated"`
+	Reverted     bool   `json:"reverted"`
+	RevertReason string `json:"revert_reason,omitempty"`
+
+	DurationSeconds uint64 `json:"duration_seconds"`
+}
+
+// ChainGasCost is the gas a simulated rebalance spends on one chain. Prices are in gwei
+// and costs in the native token; both are null when the gas price could not be read.
+type ChainGasCost struct {
+	ChainID  uint64             `json:"chain_id"`
+	GasUsed  uint64             `json:"gas_used"`
+	GasPrice *canonical.Decimal `json:"gas_price"`
+	Cost     *canonical.Decimal `json:"cost"`
+}
+
+// RebalanceSimulation previews a rebalance without moving funds. Rates are annual
+// percentages and amounts are in USDC.
+type RebalanceSimulation struct {
+	Strategy       string          `json:"strategy"`
+	SourceProtocol string          `json:"source_protocol,omitempty"`
+	SourceChain    uint64          `json:"source_chain"`
+	TargetChain    uint64          `json:"target_chain"`
+	Steps          []SimulatedStep `json:"steps"`
+	Gas            []ChainGasCost  `json:"gas"`
+
+	// Lending deposits and withdrawals are 1:1, so slippage is the bridge fee
+	BridgeFee       canonical.Decimal `json:"bridge_fee"`
+	AmountReceived  canonical.Decimal `json:"amount_received"`
+	SlippageBps     canonical.Decimal `json:"slippage_bps"`
+	DurationSeconds uint64            `json:"duration_seconds"`
+
+	// Transit is set for rebalances bridging through CCTP
+	Transit *TransitReport `json:"transit,omitempty"`
+
+	// FlashLoanPremium is the most the flash loan of the flash_loan strategy costs
+	FlashLoanPremium *canonical.Decimal `json:"flash_loan_premium,omitempty"`
+
+	SourceRate          *canonical.Decimal `json:"source_rate,omitempty"`
+	SourceRateImpactBps *canonical.Decimal `json:"source_rate_impact_bps,omitempty"`
+	TargetRate          canonical.Decimal  `json:"target_rate"`
+	FinalRate           canonical.Decimal  `json:"final_rate"`
+	TargetRateImpactBps canonical.Decimal  `json:"target_rate_impact_bps"`
+
+	// Feasible is false when a step reverted or the source market lacks the liquidity
+	// to withdraw amount
+	Feasible bool `json:"feasible"`
+}
+
+// Statuses of submitted transactions
+const (
+	TransactionPending   = "pending"
+	TransactionConfirmed = "confirmed"
+	TransactionReverted  = "reverted"
+)
+
+// SubmittedTransaction is a transaction broadcast for a rebalance step. Receipt fields
+// are set once the transaction is in a canonical block; the effective gas price is in
+// gwei. Hash is that of the mined replacement when fees were bumped.
+//
+// Steps sent in a user operation share its UserOperationHash and nonce, and Hash is the
+// bundle transaction including it, empty until it is. Gas fields are those of the whole
+// operation and only set on its first step.
+type SubmittedTransaction struct {
+	Action            string `json:"action"`
+	ChainID           uint64 `json:"chain_id"`
+	Hash              string `json:"hash"`
+	UserOperationHash string `json:"user_operation_hash,omitempty"`
+	Nonce             uint64 `json:"nonce"`
+	GasLimit          uint64 `json:"gas_limit"`
+
+	Status            string             `json:"status"`
+	BlockNumber       uint64             `json:"block_number,omitempty"`
+	GasUsed           uint64             `json:"gas_used,omitempty"`
+	EffectiveGasPrice *canonical.Decimal `json:"effective_gas_price,omitempty"`
+	Confirmations     uint64             `json:"confirmations"`
+	Reorgs            int                `json:"reorgs,omitempty"`
+}
+
+// PendingStep is a rebalance step that was not submitted
+type PendingStep struct {
+	Action  string `json:"action"`
+	ChainID uint64 `json:"chain_id"`
+}
+
+// Holdings of the performer account compared by balance checks
+const HoldingWallet = "wallet"
+
+// BalanceCheck compares a USDC holding of the performer account before and after its
+// transactions were final. Holding is HoldingWallet or the protocol of a position. The
+// check passes when Change is at least MinChange, the change the transactions make less
+// the slippage allowed.
+type BalanceCheck struct {
+	ChainID   uint64            `json:"chain_id"`
+	Holding   string            `json:"holding"`
+	Before    canonical.Decimal `json:"before"`
+	After     canonical.Decimal `json:"after"`
+	Change    canonical.Decimal `json:"change"`
+	MinChange canonical.Decimal `json:"min_change"`
+	Passed    bool              `json:"passed"`
+}
+
+// RebalanceExecution lists the transactions a rebalance submitted from the performer's
+// account. Steps spending bridged funds wait for the bridge to deliver them and are left
+// pending, as are the steps after a failed submission. ID names the execution to
+// resume_execution tasks, which submit the pending steps once they can be.
+//
+// Balance checks run once every submitted transaction is confirmed; Verified is set when
+// they ran and all passed.
+type RebalanceExecution struct {
+	ID             string                 `json:"id"`
+	From           string                 `json:"from"`
+	Strategy       string                 `json:"strategy"`
+	Execution      string                 `json:"execution"`
+	SourceChain    uint64                 `json:"source_chain"`
+	TargetChain    uint64                 `json:"target_chain"`
+	MaxSlippageBps uint64                 `json:"max_slippage_bps"`
+	Transactions   []SubmittedTransaction `json:"transactions"`
+	PendingSteps   []PendingStep          `json:"pending_steps"`
+	Legs           []ExecutionLeg         `json:"legs"`
+	BalanceChecks  []BalanceCheck         `json:"balance_checks"`
+	Verified       bool                   `json:"verified"`
+}
+
+// rebalanceRoute is the path a rebalance moves funds along
+type rebalanceRoute struct {
+	user           common.Address
+	amount         *big.Int
+	sourceProtocol string
+	sourceChain    uint64
+	targetProtocol string
+	targetChain    uint64
+
+	// strategy is flashloan.StrategyFlashLoan when the route moves atomically through a
+	// flash loan, and premium the most the loan costs
+	strategy string
+	premium  *big.Int
+
+	// execution is the backend the steps are sent with, ExecutionEOA or
+	// ExecutionUserOperation
+	execution string
+
+	// permit is set when the deposit spends a permit of the performer account instead of
+	// an allowance approved before it
+	permit bool
+
+	// userPermit is set when the deposit is funded by USDC of user, pulled into the
+	// performer account with a permit they signed
+	userPermit *permit.Signed
+
+	// profile is the user's profile, nil when the task carries none
+	profile *policy.Profile
+
+	// maxSlippageBps bounds the bridge fee and the funds balance checks allow to be lost
+	maxSlippageBps uint64
+
+	// overCap is set when amount exceeds the supply cap headroom of the target market,
+	// illiquid when it exceeds the available liquidity of the source market
+	overCap  bool
+	illiquid bool
+
+	// speed is how CCTP attests the burn of cross-chain routes, standard when empty
+	speed cctp.Speed
+
+	// fallback is set when the route bridges through the fallback bridge instead of CCTP.
+	// The bridge, or CCTP for fast transfers, keeps bridgeFee of amount.
+	fallback  *BridgeFallback
+	bridgeFee *big.Int
+}
+
+// received is the amount that reaches the target chain
+func (r *rebalanceRoute) received() *big.Int {
+	if r.bridgeFee == nil {
+		return r.amount
+	}
+	return new(big.Int).Sub(r.amount, r.bridgeFee)
+}
+
+// tolerance is the most of amount the route may lose, in USDC base units
+func (r *rebalanceRoute) tolerance() *big.Int {
+	tolerance := new(big.Int).Mul(r.amount, new(big.Int).SetUint64(r.maxSlippageBps))
+	return tolerance.Div(tolerance, big.NewInt(txmgr.MaxBps))
+}
+
+func (r *rebalanceRoute) crossChain() bool {
+	return r.sourceChain != r.targetChain
+}
+
+// chains returns the chains the route sends transactions on, source first
+func (r *rebalanceRoute) chains() []uint64 {
+	if r.crossChain() {
+		return []uint64{r.sourceChain, r.targetChain}
+	}
+	return []uint64{r.sourceChain}
+}
+
+// plannedStep is a step of a route with the call it makes. Steps spending bridged funds
+// cannot be simulated before the bridge settles, and deposits spending a permit are only
+// built once it is signed, when they are sent.
+type plannedStep struct {
+	SimulatedStep
+	call          *chain.Call
+	needsBridging bool
+	permitted     bool
+}
+
+func newPlannedStep(action string, chainID uint64, protocol string, call *chain.Call, needsBridging bool) *plannedStep {
+	return &plannedStep{
+		SimulatedStep: SimulatedStep{
+			Action:   action,
+			ChainID:  chainID,
+			Protocol: protocol,
+			Contract: call.To.Hex(),
+		},
+		call:          call,
+		needsBridging: needsBridging,
+	}
+}
+
+// handleRebalanceExecution processes USDC rebalancing execution tasks, consuming the
+// nonce of rebalances submitted from the performer's account so replays are refused.
+// Tasks are refused before any work while the operator is not in good standing, or while
+// another task executes the same rebalance.
+func (yip *YieldIntelligencePerformer) handleRebalanceExecution(ctx context.Context, t *performerV1.TaskRequest, payload *TaskPayload) (interface{}, error) {
+	yip.log(ctx).Sugar().Infow("Processing rebalance execution task")
+
+	if err := yip.checkStanding(ctx); err != nil {
+		return nil, err
+	}
+	if !paramBool(payload, "dry_run") {
+		name, err := planLockName(rebalancePlan(payload))
+		if err != nil {
+			return nil, err
+		}
+		var unlock func()
+		if ctx, unlock, err = yip.lockExecution(ctx, name); err != nil {
+			return nil, err
+		}
+		defer unlock()
+	}
+	if err := yip.consumeNonce(ctx, t, payload); err != nil {
+		return nil, err
+	}
+	return yip.rebalance(ctx, t, payload)
+}
+
+// rebalance moves funds as payload describes. With dry_run set, the full path is
+// simulated and nothing is executed. Otherwise, when the performer has an account of its
+// own, the transactions are signed and submitted from it. Rebalances other than dry runs
+// are refused while funds are halted.
+func (yip *YieldIntelligencePerformer) rebalance(ctx context.Context, t *performerV1.TaskRequest, payload *TaskPayload) (interface{}, error) {
+	result := &RebalanceExecutionResult{
+		UserAddress:    paramString(payload, "user_address"),
+		SourceProtocol: paramString(payload, "source_protocol"),
+		SourceChain:    paramUint64(payload, "source_chain"),
+		TargetProtocol: paramString(payload, "target_protocol"),
+		TargetChain:    paramUint64(payload, "target_chain"),
+		Amount:         paramAmount(payload, "amount"),
+		DryRun:         paramBool(payload, "dry_run"),
+		Status:         ResultStatusCompleted,
+	}
+	if result.SourceChain == 0 {
+		result.SourceChain = result.TargetChain
+	}
+	if !result.DryRun {
+		if err := yip.checkHalt(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if !result.DryRun && yip.transactions == nil {
+		// TODO: Implement rebalance execution logic
+		// - Validate rebalancing opportunity from yield signals
+		// - Calculate optimal allocation across protocols/chains
+		// - Execute via Circle Wallets and CCTP v2
+		// - Monitor execution success and gas costs
+		// - Return execution result with performance metrics
+		result.Status = ResultStatusNotExecuted
+		return result, nil
+	}
+
+	route := &rebalanceRoute{
+		user:           common.HexToAddress(result.UserAddress),
+		amount:         usdcBaseUnits(result.Amount),
+		sourceProtocol: result.SourceProtocol,
+		sourceChain:    result.SourceChain,
+		targetProtocol: result.TargetProtocol,
+		targetChain:    result.TargetChain,
+		strategy:       paramString(payload, "strategy"),
+		execution:      paramString(payload, "execution"),
+		profile:        paramProfile(payload),
+		speed:          paramTransferSpeed(payload),
+		userPermit:     paramPermit(payload),
+	}
+	if route.strategy == "" {
+		route.strategy = flashloan.StrategySequential
+	}
+	if route.execution == "" {
+		route.execution = ExecutionEOA
+	}
+	if _, present := payload.Parameters["max_slippage_bps"]; present {
+		route.maxSlippageBps = paramUint64(payload, "max_slippage_bps")
+	} else if yip.transactions != nil {
+		route.maxSlippageBps = yip.transactions.Protection(route.sourceChain).MaxSlippageBps
+	}
+
+	if err := yip.checkPolicy(ctx, route); err != nil {
+		return nil, err
+	}
+	if err := yip.checkProfile(ctx, route); err != nil {
+		return nil, err
+	}
+	requested := route.amount
+	capReport, err := yip.fitSupplyCap(ctx, route, paramBool(payload, "resize_to_cap"))
+	if err != nil {
+		return nil, err
+	}
+	result.SupplyCap = capReport
+	if route.amount.Cmp(requested) != 0 {
+		requestedAmount := result.Amount
+		result.RequestedAmount = &requestedAmount
+		result.Amount = usdcAmount(route.amount)
+	}
+	if result.WithdrawalLiquidity, err = yip.checkWithdrawal(ctx, route); err != nil {
+		return nil, err
+	}
+	if err := yip.fitTransferSpeed(route); err != nil {
+		return nil, err
+	}
+	if result.BridgeFallback, err = yip.checkAttestations(ctx, route); err != nil {
+		return nil, err
+	}
+	if err := yip.checkFlashLoan(route); err != nil {
+		return nil, err
+	}
+	if err := yip.checkPermit(ctx, route); err != nil {
+		return nil, err
+	}
+	if !result.DryRun {
+		route.permit = yip.permitsDeposit(route)
+	}
+
+	if !result.DryRun {
+		if err := yip.checkPosition(ctx, route); err != nil {
+			return nil, err
+		}
+		if route.overCap {
+			return nil, newTaskError(ErrorCodeRiskBlocked, fmt.Errorf("%s USDC exceeds the %s USDC supply cap headroom of %s on chain %d",
+				usdcAmount(requested), result.SupplyCap.Headroom, route.targetProtocol, route.targetChain))
+		}
+		if liquidity := result.WithdrawalLiquidity; liquidity != nil && !liquidity.Sufficient {
+			return nil, newTaskError(ErrorCodeInsufficientLiquidity, fmt.Errorf("%s on chain %d holds %s USDC of available liquidity, below the %s USDC to withdraw; at most %s USDC can be withdrawn",
+				route.sourceProtocol, route.sourceChain, liquidity.Available, result.Amount, liquidity.MaxWithdrawable))
+		}
+		if err := yip.checkRebalance(ctx, route); err != nil {
+			return nil, err
+		}
+		steps, err := yip.planRebalance(route)
+		if err != nil {
+			return nil, err
+		}
+		if !paramBool(payload, "urgent") {
+			if result.GasTiming, err = yip.awaitGasWindow(ctx, route, steps); err != nil {
+				return nil, err
+			}
+			if err := yip.renewExecutionLock(ctx); err != nil {
+				return nil, err
+			}
+		}
+		before, err := yip.readHoldings(ctx, route.user, yip.rebalanceHoldings(route))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read balances before rebalancing: %w", err)
+		}
+		record := &executionRecord{
+			Route:      newExecutionRoute(route),
+			Before:     before,
+			RateBefore: yip.sourceRate(ctx, route),
+			Execution:  yip.newRebalanceExecution(executionID(t), route),
+		}
+		if result.Status, err = yip.executeRebalance(ctx, route, steps, record, nil, 0); err != nil {
+			return nil, err
+		}
+		result.Execution = record.Execution
+		yip.gasSavings(ctx, result.GasTiming, record.Execution)
+		return result, nil
+	}
+
+	simulation, complete, err := yip.simulateRebalance(ctx, route)
+	if err != nil {
+		return nil, err
+	}
+	result.Simulation = simulation
+	if !complete {
+		result.Status = ResultStatusPartial
+	}
+	return result, nil
+}
+
+// checkRebalance refuses to submit route when the sequencer of the target chain is down or
+// stalled, when the target market is paused or frozen, or when moving out of the source
+// market would not earn a higher rate once deposited.
+// Markets that do not report risk data are not blocked.
+func (yip *YieldIntelligencePerformer) checkRebalance(ctx context.Context, route *rebalanceRoute) error {
+	if err := yip.checkSequencer(ctx, route.targetChain); err != nil {
+		return err
+	}
+	targetMarket := market{protocol: route.targetProtocol, chainID: route.targetChain}
+	risk, err := yip.readRiskState(ctx, targetMarket)
+	switch {
+	case errors.Is(err, adapters.ErrNoRiskData):
+	case err != nil:
+		return fmt.Errorf("failed to read %s status on chain %d: %w", route.targetProtocol, route.targetChain, err)
+	case risk.Paused:
+		return newTaskError(ErrorCodeRiskBlocked, fmt.Errorf("%s on chain %d is paused", route.targetProtocol, route.targetChain))
+	case risk.Frozen:
+		return newTaskError(ErrorCodeRiskBlocked, fmt.Errorf("%s on chain %d is frozen", route.targetProtocol, route.targetChain))
+	}
+
+	if route.sourceProtocol == "" {
+		return nil
+	}
+	target, err := yip.readMarket(ctx, targetMarket)
+	if err != nil {
+		return err
+	}
+	source, err := yip.readMarket(ctx, market{protocol: route.sourceProtocol, chainID: route.sourceChain})
+	if err != nil {
+		return err
+	}
+	sourceRate, finalRate := source.Pool.SupplyRate(), target.Pool.DepositImpact(route.amount).SupplyRate
+	if finalRate <= sourceRate {
+		return newTaskError(ErrorCodeUnprofitable, fmt.Errorf("%s on chain %d would earn %s%%, not more than the %s%% of %s on chain %d",
+			route.targetProtocol, route.targetChain, ratePercent(finalRate), ratePercent(sourceRate), route.sourceProtocol, route.sourceChain))
+	}
+	return nil
+}
+
+// fitSupplyCap checks route against the supply cap headroom of its target market, queued
+// cap cuts included. With resize set, routes over the headroom are lowered to it; routes
+// left over it are marked overCap.
+func (yip *YieldIntelligencePerformer) fitSupplyCap(ctx context.Context, route *rebalanceRoute, resize bool) (*SupplyCapReport, error) {
+	targetMarket := market{protocol: route.targetProtocol, chainID: route.targetChain}
+	target, err := yip.readMarket(ctx, targetMarket)
+	if err != nil {
+		return nil, err
+	}
+	report, headroom, err := yip.supplyCapStatus(ctx, targetMarket, target.Pool.TotalSupply, true)
+	if err != nil || headroom == nil || route.amount.Cmp(headroom) <= 0 {
+		return report, err
+	}
+	if resize && headroom.Sign() > 0 {
+		route.amount = new(big.Int).Set(headroom)
+		return report, nil
+	}
+	route.overCap = true
+	return report, nil
+}
+
+// checkPosition refuses to withdraw more than the performer account holds in the source
+// market of route. The tracked position is used while fresh; otherwise it is read from the
+// chain and tracked.
+func (yip *YieldIntelligencePerformer) checkPosition(ctx context.Context, route *rebalanceRoute) error {
+	if yip.positions == nil || route.sourceProtocol == "" {
+		return nil
+	}
+	position, err := yip.positions.Get(ctx, route.user, route.sourceProtocol, route.sourceChain)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		return fmt.Errorf("failed to read tracked position: %w", err)
+	}
+	if !yip.positions.Fresh(position) {
+		mover, err := yip.adapters.MoverFor(route.sourceProtocol)
+		if err != nil {
+			return err
+		}
+		amount, err := mover.PositionOf(ctx, route.sourceChain, route.user)
+		if err != nil {
+			return fmt.Errorf("failed to read %s position on chain %d: %w", route.sourceProtocol, route.sourceChain, err)
+		}
+		position = &positions.Position{
+			Owner:    route.user,
+			Protocol: route.sourceProtocol,
+			ChainID:  route.sourceChain,
+			Amount:   amount,
+			Source:   positions.SourceChain,
+		}
+		if err := yip.positions.Record(ctx, *position); err != nil {
+			return err
+		}
+	}
+	if route.amount.Cmp(position.Amount) > 0 {
+		return newTaskError(ErrorCodeValidation, fmt.Errorf("invalid amount: %s holds %s USDC of %s on chain %d, less than the %s USDC to withdraw",
+			route.user.Hex(), usdcAmount(position.Amount), route.sourceProtocol, route.sourceChain, usdcAmount(route.amount)))
+	}
+	return nil
+}
+
+// trackRebalance records the positions execution left once its balance checks read them.
+// While transactions are outstanding the positions of route are forgotten instead, so
+// they are read from the chain when next needed. Either way the funds of the user are
+// stamped as moved, which hysteresis cooldowns count from.
+func (yip *YieldIntelligencePerformer) trackRebalance(ctx context.Context, route *rebalanceRoute, execution *RebalanceExecution) {
+	if yip.positions == nil {
+		return
+	}
+	err := yip.positions.RecordMove(ctx, route.user)
+	if len(execution.BalanceChecks) == 0 {
+		for _, h := range yip.rebalanceHoldings(route) {
+			if h.protocol != "" {
+				err = errors.Join(err, yip.positions.Forget(ctx, route.user, h.protocol, h.chainID))
+			}
+		}
+	}
+	for _, check := range execution.BalanceChecks {
+		if check.Holding == HoldingWallet {
+			continue
+		}
+		err = errors.Join(err, yip.positions.Record(ctx, positions.Position{
+			Owner:    route.user,
+			Protocol: check.Holding,
+			ChainID:  check.ChainID,
+			Amount:   usdcBaseUnits(check.After),
+			Source:   positions.SourceExecution,
+		}))
+	}
+	if err != nil {
+		yip.log(ctx).Sugar().Warnw("Failed to track rebalanced positions", "error", err)
+	}
+}
+
+// withdrawalBufferBps is the share of available liquidity left aside when recommending a
+// withdrawal, as borrows between the check and the withdrawal lower it
+const withdrawalBufferBps = 100
+
+// WithdrawalLiquidity compares a withdrawal with the USDC the source market holds idle,
+// which is all it can pay out. MaxWithdrawable recommends the largest withdrawal to retry
+// with when the requested one is not Sufficient.
+type WithdrawalLiquidity struct {
+	Available       canonical.Decimal  `json:"available"`
+	Sufficient      bool               `json:"sufficient"`
+	MaxWithdrawable *canonical.Decimal `json:"max_withdrawable,omitempty"`
+}
+
+// checkWithdrawal checks that the source market of route holds the liquidity to withdraw
+// its amount, and marks route illiquid otherwise. Routes without a source market withdraw
+// nothing and are not checked.
+func (yip *YieldIntelligencePerformer) checkWithdrawal(ctx context.Context, route *rebalanceRoute) (*WithdrawalLiquidity, error) {
+	if route.sourceProtocol == "" {
+		return nil, nil
+	}
+	source, err := yip.readMarket(ctx, market{protocol: route.sourceProtocol, chainID: route.sourceChain})
+	if err != nil {
+		return nil, err
+	}
+	available := source.Pool.AvailableLiquidity()
+	liquidity := &WithdrawalLiquidity{Available: usdcAmount(available), Sufficient: route.amount.Cmp(available) <= 0}
+	if !liquidity.Sufficient {
+		route.illiquid = true
+		recommended := new(big.Int).Mul(available, big.NewInt(txmgr.MaxBps-withdrawalBufferBps))
+		liquidity.MaxWithdrawable = optionalUSDC(recommended.Div(recommended, big.NewInt(txmgr.MaxBps)))
+	}
+	return liquidity, nil
+}
+
+// simulateRebalance previews route against current chain state. It reports false when
+// part of the simulation could not run and fallback estimates were used instead.
+func (yip *YieldIntelligencePerformer) simulateRebalance(ctx context.Context, route *rebalanceRoute) (*RebalanceSimulation, bool, error) {
+	sim := &RebalanceSimulation{
+		Strategy:       route.strategy,
+		SourceProtocol: route.sourceProtocol,
+		SourceChain:    route.sourceChain,
+		TargetChain:    route.targetChain,
+		Steps:          []SimulatedStep{},
+		Gas:            []ChainGasCost{},
+		BridgeFee:      usdcAmount(new(big.Int)),
+		AmountReceived: usdcAmount(route.amount),
+		SlippageBps:    canonical.Score(0),
+		Feasible:       true,
+	}
+
+	target, err := yip.readMarket(ctx, market{protocol: route.targetProtocol, chainID: route.targetChain})
+	if err != nil {
+		return nil, false, err
+	}
+	deposit := target.Pool.DepositImpact(route.amount)
+	sim.TargetRate = ratePercent(target.Pool.SupplyRate())
+	sim.FinalRate = ratePercent(deposit.SupplyRate)
+	sim.TargetRateImpactBps = canonical.Score(deposit.RateImpactBps)
+
+	if route.sourceProtocol != "" {
+		source, err := yip.readMarket(ctx, market{protocol: route.sourceProtocol, chainID: route.sourceChain})
+		if err != nil {
+			return nil, false, err
+		}
+		rate := ratePercent(source.Pool.SupplyRate())
+		sim.SourceRate = &rate
+		if withdrawal, ok := source.Pool.WithdrawalImpact(route.amount); ok {
+			impact := canonical.Score(withdrawal.RateImpactBps)
+			sim.SourceRateImpactBps = &impact
+		}
+	}
+
+	steps, err := yip.planRebalance(route)
+	if err != nil {
+		return nil, false, err
+	}
+	if route.illiquid {
+		// the flash loan strategy withdraws in its migration, after the premium allowance
+		withdrawal := steps[0]
+		if route.strategy == flashloan.StrategyFlashLoan {
+			withdrawal = steps[len(steps)-1]
+		}
+		withdrawal.Reverted = true
+		withdrawal.RevertReason = "available liquidity is below the withdrawal amount"
+	}
+	if route.overCap {
+		deposit := steps[len(steps)-1]
+		deposit.Reverted = true
+		deposit.RevertReason = "amount exceeds the supply cap headroom"
+	}
+
+	if route.bridgeFee != nil {
+		sim.BridgeFee = usdcAmount(route.bridgeFee)
+		sim.AmountReceived = usdcAmount(route.received())
+		feeBps := yip.cctpFastFeeBps
+		if route.fallback != nil {
+			feeBps = route.fallback.FeeBps
+		}
+		sim.SlippageBps = canonical.Score(float64(feeBps))
+	}
+	if sim.Transit, err = transitReport(route, sim.FinalRate); err != nil {
+		return nil, false, err
+	}
+	if route.premium != nil {
+		premium := usdcAmount(route.premium)
+		sim.FlashLoanPremium = &premium
+		sim.SlippageBps = canonical.Score(float64(yip.flashLoans.PremiumBps))
+	}
+
+	complete := yip.simulateSteps(ctx, route, steps)
+	gasByChain := make(map[uint64]uint64)
+	for _, step := range steps {
+		if step.Reverted {
+			sim.Feasible = false
+		}
+		gasByChain[step.ChainID] += step.GasUsed
+		sim.DurationSeconds += step.DurationSeconds
+		sim.Steps = append(sim.Steps, step.SimulatedStep)
+	}
+
+	for _, chainID := range route.chains() {
+		cost := ChainGasCost{ChainID: chainID, GasUsed: gasByChain[chainID]}
+		price, err := yip.simulator.GasPrice(ctx, chainID)
+		if err != nil {
+			yip.log(ctx).Sugar().Warnw("Failed to read gas price for rebalance simulation", "chainId", chainID, "error", err)
+			complete = false
+		} else {
+			gwei := canonical.NewDecimalFromInt(price, 9, 9)
+			native := canonical.NewDecimalFromInt(new(big.Int).Mul(price, new(big.Int).SetUint64(cost.GasUsed)), 18, 18)
+			cost.GasPrice = &gwei
+			cost.Cost = &native
+		}
+		sim.Gas = append(sim.Gas, cost)
+	}
+	return sim, complete, nil
+}
+
+// planRebalance lists the transactions of route in execution order
+func (yip *YieldIntelligencePerformer) planRebalance(route *rebalanceRoute) ([]*plannedStep, error) {
+	if route.strategy == flashloan.StrategyFlashLoan {
+		return yip.flashLoanSteps(route)
+	}
+	var steps []*plannedStep
+	add := func(action string, chainID uint64, protocol string, call *chain.Call, needsBridging bool) {
+		steps = append(steps, newPlannedStep(action, chainID, protocol, call, needsBridging))
+	}
+
+	if route.userPermit != nil {
+		pull, err := yip.permitSteps(route)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, pull...)
+	}
+	if route.sourceProtocol != "" {
+		mover, err := yip.adapters.MoverFor(route.sourceProtocol)
+		if err != nil {
+			return nil, err
+		}
+		call, err := mover.WithdrawCall(route.sourceChain, route.user, route.amount)
+		if err != nil {
+			return nil, err
+		}
+		add(ActionWithdraw, route.sourceChain, route.sourceProtocol, call, false)
+	}
+
+	if route.fallback != nil {
+		approve, deposit, err := yip.acrossSteps(route)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, approve, deposit)
+	} else if route.crossChain() {
+		usdc, err := tokens.CCTPUSDC(route.sourceChain)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tokens.CCTPUSDC(route.targetChain); err != nil {
+			return nil, err
+		}
+		burn, err := cctp.DepositForBurnCall(yip.book, &cctp.Transfer{
+			SourceChainID:      route.sourceChain,
+			DestinationChainID: route.targetChain,
+			Amount:             route.amount,
+			Recipient:          route.user,
+			BurnToken:          usdc.Address,
+			MaxFee:             route.tolerance(),
+			MinFinality:        route.speed.Threshold(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		approve, err := adapters.ApproveCall(route.sourceChain, burn.To, route.amount)
+		if err != nil {
+			return nil, err
+		}
+		transmitter, err := yip.book.Require(route.targetChain, addressbook.ProtocolCCTP, addressbook.ContractMessageTransmitter)
+		if err != nil {
+			return nil, err
+		}
+		add(ActionApprove, route.sourceChain, "", approve, false)
+		add(ActionBurn, route.sourceChain, "", burn, false)
+		// the mint call carries Circle's attestation, which only exists after the burn
+		add(ActionMint, route.targetChain, "", &chain.Call{To: transmitter}, true)
+	}
+
+	mover, err := yip.adapters.MoverFor(route.targetProtocol)
+	if err != nil {
+		return nil, err
+	}
+	deposit, err := mover.SupplyCall(route.targetChain, route.user, route.received())
+	if err != nil {
+		return nil, err
+	}
+	if route.permit {
+		step := newPlannedStep(ActionDeposit, route.targetChain, route.targetProtocol, deposit, route.crossChain())
+		step.permitted = true
+		return append(steps, step), nil
+	}
+	approve, err := adapters.ApproveCall(route.targetChain, deposit.To, route.received())
+	if err != nil {
+		return nil, err
+	}
+	// relayers fill Across deposits without a step of the performer, so its routes wait
+	// for the bridged funds from the approval on
+	add(ActionApprove, route.targetChain, "", approve, route.fallback != nil)
+	add(ActionDeposit, route.targetChain, route.targetProtocol, deposit, route.crossChain())
+	return steps, nil
+}
+
+// simulateSteps simulates the steps of each chain as one bundle and fills in gas and
+// durations. It reports false when a bundle could not be simulated.
+func (yip *YieldIntelligencePerformer) simulateSteps(ctx context.Context, route *rebalanceRoute, steps []*plannedStep) bool {
+	complete := true
+	for _, chainID := range route.chains() {
+		var bundle []*plannedStep
+		for _, step := range steps {
+			if step.ChainID == chainID && !step.needsBridging && !step.Reverted {
+				bundle = append(bundle, step)
+			}
+		}
+		if len(bundle) == 0 {
+			continue
+		}
+		calls := make([]chain.Call, len(bundle))
+		for i, step := range bundle {
+			calls[i] = *step.call
+		}
+
+		// steps pulling funds with a permit are sent by the performer account for the user
+		from := route.user
+		if route.userPermit != nil {
+			from = yip.transactions.Address(chainID)
+		}
+		outcomes, err := yip.simulator.SimulateBundle(ctx, chainID, from, calls)
+		if err != nil {
+			yip.log(ctx).Sugar().Warnw("Failed to simulate rebalance steps", "chainId", chainID, "error", err)
+			complete = false
+			continue
+		}
+		for i, outcome := range outcomes {
+			bundle[i].Simulated = outcome.Simulated
+			bundle[i].GasUsed = outcome.GasUsed
+			bundle[i].Reverted = outcome.Reverted
+			bundle[i].RevertReason = outcome.RevertReason
+		}
+	}
+
+	for _, step := range steps {
+		if !step.Simulated {
+			step.GasUsed = fallbackGas[step.Action]
+		}
+		wait := blockTime(step.ChainID)
+		if step.Action == ActionMint {
+			attestation, err := cctp.AttestationTime(&cctp.Transfer{SourceChainID: route.sourceChain, MinFinality: route.speed.Threshold()})
+			if err != nil {
+				complete = false
+			}
+			wait += attestation
+		}
+		if step.Action == ActionBridge {
+			wait += yip.fallback.FillTime
+		}
+		step.DurationSeconds = uint64(wait / time.Second)
+	}
+	return complete
+}
+
+// newRebalanceExecution starts the execution id of route, before any step was sent
+func (yip *YieldIntelligencePerformer) newRebalanceExecution(id string, route *rebalanceRoute) *RebalanceExecution {
+	from := yip.transactions.Address(route.sourceChain)
+	if route.execution == ExecutionUserOperation {
+		from = route.user
+	}
+	return &RebalanceExecution{
+		ID:             id,
+		From:           from.Hex(),
+		Strategy:       route.strategy,
+		Execution:      route.execution,
+		SourceChain:    route.sourceChain,
+		TargetChain:    route.targetChain,
+		MaxSlippageBps: route.maxSlippageBps,
+		Transactions:   []SubmittedTransaction{},
+		PendingSteps:   []PendingStep{},
+		Legs:           []ExecutionLeg{},
+		BalanceChecks:  []BalanceCheck{},
+	}
+}
+
+// executeRebalance submits the steps of route from the one at from on, after the
+// transactions of record sent before it, then waits for every transaction and verifies
+// the rebalance. The execution is recorded so resume_execution tasks can carry it on.
+func (yip *YieldIntelligencePerformer) executeRebalance(ctx context.Context, route *rebalanceRoute, steps []*plannedStep, record *executionRecord, sent []*types.Transaction, from int) (ResultStatus, error) {
+	execution := record.Execution
+	submitted, complete, err := yip.submitRebalance(ctx, route, steps, record, from)
+	if err != nil {
+		return "", err
+	}
+	sent = append(sent, submitted...)
+	status := yip.confirmRebalance(ctx, route, execution, sent)
+	yip.trackBurns(execution.Transactions[from:])
+	for _, tx := range execution.Transactions[from:] {
+		if tx.Action == ActionBurn {
+			record.BurnedAt = time.Now().Unix()
+		}
+	}
+	if !complete && status != ResultStatusReverted {
+		status = ResultStatusPartial
+	}
+	execution.BalanceChecks, execution.Verified = []BalanceCheck{}, false
+	if yip.verifyRebalance(ctx, route, execution, yip.rebalanceHoldings(route), record.Before) == verificationFailed {
+		status = ResultStatusAnomalous
+	}
+	yip.trackRebalance(ctx, route, execution)
+	if execution.Verified {
+		yip.recordOutcome(ctx, route, record)
+	}
+	execution.Legs, _ = executionLegs(execution)
+
+	if err := record.setSent(sent); err != nil {
+		return "", err
+	}
+	if err := yip.executions.put(ctx, record); err != nil {
+		yip.log(ctx).Sugar().Errorw("Failed to record rebalance execution", "executionId", execution.ID, "error", err)
+	}
+	return status, nil
+}
+
+// submitRebalance sends the steps of route from the one at from on, in order, until one
+// needs bridged funds that have not arrived, and returns the sent transactions in the
+// order of the execution's. The first step sent must be accepted; a later failure leaves
+// it and the steps after it pending and is reported as false.
+func (yip *YieldIntelligencePerformer) submitRebalance(ctx context.Context, route *rebalanceRoute, steps []*plannedStep, record *executionRecord, from int) ([]*types.Transaction, bool, error) {
+	if route.execution == ExecutionUserOperation {
+		complete, err := yip.submitUserOperations(ctx, route, steps, record, from)
+		return nil, complete, err
+	}
+	execution := record.Execution
+	var sent []*types.Transaction
+	complete, halted, bridged := true, false, false
+	for i, step := range steps[from:] {
+		i += from
+		if !halted && step.needsBridging && !bridged {
+			mint, arrived, err := yip.bridgedFunds(ctx, route, record)
+			if err != nil {
+				yip.log(ctx).Sugar().Warnw("Failed to check bridged funds", "chainId", route.targetChain, "error", err)
+			}
+			halted, bridged = !arrived, arrived
+			if mint != nil {
+				step.call = mint
+			}
+		}
+		if halted {
+			execution.PendingSteps = append(execution.PendingSteps, PendingStep{Action: step.Action, ChainID: step.ChainID})
+			continue
+		}
+
+		var tx *types.Transaction
+		err := yip.renewExecutionLock(ctx)
+		if err == nil {
+			err = yip.signPermit(ctx, route, step)
+		}
+		if err == nil {
+			req := txmgr.Request{Call: *step.call}
+			if i > 0 {
+				// estimates revert until the transactions before this one are mined
+				req.FallbackGas = fallbackGas[step.Action]
+			}
+			tx, err = yip.sendStep(ctx, step.ChainID, req)
+		}
+		if err != nil {
+			if i == from {
+				return nil, false, newTaskError(ErrorCodeExecutionFailed, fmt.Errorf("failed to submit %s: %w", step.Action, err))
+			}
+			yip.log(ctx).Sugar().Warnw("Failed to submit rebalance step", "action", step.Action, "chainId", step.ChainID, "error", err)
+			complete, halted = false, true
+			execution.PendingSteps = append(execution.PendingSteps, PendingStep{Action: step.Action, ChainID: step.ChainID})
+			continue
+		}
+		execution.Transactions = append(execution.Transactions, SubmittedTransaction{
+			Action:   step.Action,
+			ChainID:  step.ChainID,
+			Hash:     tx.Hash().Hex(),
+			Nonce:    tx.Nonce(),
+			GasLimit: tx.Gas(),
+			Status:   TransactionPending,
+		})
+		sent = append(sent, tx)
+	}
+	return sent, complete, nil
+}
+
+// confirmRebalance waits for the sent transactions to be final, up to the confirmation
+// timeout, and records their receipts. Rebalances are completed once every step is
+// final, and reverted when any transaction reverted. Replacements with bumped fees take
+// the place of the transactions in sent.
+func (yip *YieldIntelligencePerformer) confirmRebalance(ctx context.Context, route *rebalanceRoute, execution *RebalanceExecution, sent []*types.Transaction) ResultStatus {
+	ctx, cancel := context.WithTimeout(ctx, yip.transactions.ConfirmationTimeout())
+	defer cancel()
+	if route.execution == ExecutionUserOperation {
+		return yip.confirmUserOperations(ctx, execution)
+	}
+
+	status := ResultStatusCompleted
+	if len(execution.PendingSteps) > 0 {
+		status = ResultStatusSubmitted
+	}
+	for i, tx := range sent {
+		submitted := &execution.Transactions[i]
+		manager, err := yip.transactions.For(submitted.ChainID)
+		if err != nil {
+			status = ResultStatusSubmitted
+			continue
+		}
+		confirmation, err := manager.Confirm(ctx, tx)
+		if err != nil {
+			yip.log(ctx).Sugar().Warnw("Rebalance transaction not confirmed", "action", submitted.Action, "hash", confirmation.Tx.Hash().Hex(), "error", err)
+		}
+		// nothing is left to submit when the lease was lost
+		_ = yip.renewExecutionLock(ctx)
+		sent[i] = confirmation.Tx
+		submitted.Hash = confirmation.Tx.Hash().Hex()
+		submitted.Confirmations = confirmation.Confirmations
+		submitted.Reorgs = confirmation.Reorgs
+		if receipt := confirmation.Receipt; receipt != nil {
+			price := canonical.NewDecimalFromInt(receipt.EffectiveGasPrice, 9, 9)
+			submitted.BlockNumber = receipt.BlockNumber.Uint64()
+			submitted.GasUsed = receipt.GasUsed
+			submitted.EffectiveGasPrice = &price
+		}
+
+		switch {
+		case confirmation.Reverted():
+			submitted.Status = TransactionReverted
+			status = ResultStatusReverted
+		case confirmation.Final:
+			submitted.Status = TransactionConfirmed
+		default:
+			if status != ResultStatusReverted {
+				status = ResultStatusSubmitted
+			}
+		}
+	}
+	return status
+}
+
+// holding is a USDC balance of the performer account a rebalance changes
+type holding struct {
+	chainID  uint64
+	protocol string
+}
+
+func (h holding) name() string {
+	if h.protocol == "" {
+		return HoldingWallet
+	}
+	return h.protocol
+}
+
+// rebalanceHoldings lists the positions and wallets route moves funds between
+func (yip *YieldIntelligencePerformer) rebalanceHoldings(route *rebalanceRoute) []holding {
+	var holdings []holding
+	if route.sourceProtocol != "" {
+		holdings = append(holdings, holding{chainID: route.sourceChain, protocol: route.sourceProtocol})
+	}
+	for _, chainID := range route.chains() {
+		holdings = append(holdings, holding{chainID: chainID})
+	}
+	return append(holdings, holding{chainID: route.targetChain, protocol: route.targetProtocol})
+}
+
+// readHoldings reads the balance of every holding of account, the performer account or
+// the smart account user operations are sent from
+func (yip *YieldIntelligencePerformer) readHoldings(ctx context.Context, account common.Address, holdings []holding) ([]*big.Int, error) {
+	balances := make([]*big.Int, len(holdings))
+	for i, h := range holdings {
+		var err error
+		if h.protocol == "" {
+			var client chain.Client
+			if client, err = yip.transactions.Client(h.chainID); err == nil {
+				balances[i], err = adapters.USDCBalance(ctx, client, h.chainID, account)
+			}
+		} else {
+			var mover adapters.Mover
+			if mover, err = yip.adapters.MoverFor(h.protocol); err == nil {
+				balances[i], err = mover.PositionOf(ctx, h.chainID, account)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s on chain %d: %w", h.name(), h.chainID, err)
+		}
+	}
+	return balances, nil
+}
+
+// Outcomes of verifyRebalance
+type verification int
+
+const (
+	verificationSkipped verification = iota
+	verificationPassed
+	verificationFailed
+)
+
+// verifyRebalance compares holdings with their balances before the rebalance once every
+// submitted transaction is confirmed. Each holding must have changed by what the
+// confirmed steps move, less the slippage allowed; interest accrued in between only
+// raises balances.
+func (yip *YieldIntelligencePerformer) verifyRebalance(ctx context.Context, route *rebalanceRoute, execution *RebalanceExecution, holdings []holding, before []*big.Int) verification {
+	expected := make(map[holding]*big.Int, len(holdings))
+	for _, h := range holdings {
+		expected[h] = new(big.Int)
+	}
+	move := func(h holding, amount *big.Int) {
+		if change, ok := expected[h]; ok {
+			change.Add(change, amount)
+		}
+	}
+	spent := new(big.Int).Neg(route.amount)
+	for _, tx := range execution.Transactions {
+		if tx.Status != TransactionConfirmed {
+			return verificationSkipped
+		}
+		switch tx.Action {
+		case ActionWithdraw:
+			move(holding{chainID: tx.ChainID, protocol: route.sourceProtocol}, spent)
+			move(holding{chainID: tx.ChainID}, route.amount)
+		case ActionBurn, ActionBridge:
+			move(holding{chainID: tx.ChainID}, spent)
+		case ActionDeposit:
+			move(holding{chainID: tx.ChainID}, new(big.Int).Neg(route.received()))
+			move(holding{chainID: tx.ChainID, protocol: route.targetProtocol}, route.received())
+		case ActionMigrate:
+			move(holding{chainID: tx.ChainID, protocol: route.sourceProtocol}, spent)
+			move(holding{chainID: tx.ChainID}, new(big.Int).Neg(route.premium))
+			move(holding{chainID: tx.ChainID, protocol: route.targetProtocol}, route.amount)
+		}
+	}
+
+	after, err := yip.readHoldings(ctx, route.user, holdings)
+	if err != nil {
+		yip.log(ctx).Sugar().Warnw("Failed to read balances after rebalancing", "error", err)
+		return verificationSkipped
+	}
+	outcome := verificationPassed
+	for i, h := range holdings {
+		change := new(big.Int).Sub(after[i], before[i])
+		minChange := new(big.Int).Sub(expected[h], route.tolerance())
+		check := BalanceCheck{
+			ChainID:   h.chainID,
+			Holding:   h.name(),
+			Before:    usdcAmount(before[i]),
+			After:     usdcAmount(after[i]),
+			Change:    usdcAmount(change),
+			MinChange: usdcAmount(minChange),
+			Passed:    change.Cmp(minChange) >= 0,
+		}
+		if !check.Passed {
+			yip.log(ctx).Sugar().Warnw("Rebalance balance check failed", "chainId", h.chainID, "holding", check.Holding,
+				"change", check.Change.String(), "minChange", check.MinChange.String())
+			outcome = verificationFailed
+		}
+		execution.BalanceChecks = append(execution.BalanceChecks, check)
+	}
+	execution.Verified = outcome == verificationPassed
+	return outcome
+}
+
+func (yip *YieldIntelligencePerformer) sendStep(ctx context.Context, chainID uint64, req txmgr.Request) (*types.Transaction, error) {
+	manager, err := yip.transactions.For(chainID)
+	if err != nil {
+		return nil, err
+	}
+	return manager.Send(ctx, req)
+}
+
+func blockTime(chainID uint64) time.Duration {
+	if d, ok := blockTimes[chainID]; ok {
+		return d
+	}
+	return defaultBlockTime
+}
+
+// paramBool returns a boolean parameter or false when missing
+func paramBool(payload *TaskPayload, key string) bool {
+	value, _ := payload.Parameters[key].(bool)
+	return value
+}
+
+func (yip *YieldIntelligencePerformer) validateRebalanceExecutionTask(payload *TaskPayload) error {
+	if err := yip.validatePermit(payload); err != nil {
+		return err
+	}
+	if err := checkAmount(payload.Parameters["amount"]); err != nil {
+		return fmt.Errorf("missing or invalid amount: %w", err)
+	}
+
+	// the token is optional, as rebalances only ever move native USDC
+	if _, present := payload.Parameters["token"]; present {
+		if err := validateToken(payload, paramUint64(payload, "source_chain"), paramUint64(payload, "target_chain")); err != nil {
+			return err
+		}
+	}
+
+	execution := ExecutionEOA
+	if raw, present := payload.Parameters["execution"]; present {
+		execution, _ = raw.(string)
+	}
+
+	dryRun := paramBool(payload, "dry_run")
+	if dryRun {
+		if yip.simulator == nil {
+			return fmt.Errorf("dry runs are not configured on this performer")
+		}
+		return yip.validateRebalanceRoute(payload)
+	}
+	if yip.transactions == nil {
+		return nil
+	}
+	if err := yip.validateRebalanceRoute(payload); err != nil {
+		return err
+	}
+	user := common.HexToAddress(paramString(payload, "user_address"))
+	_, permitted := payload.Parameters["permit"]
+	for _, key := range []string{"source_chain", "target_chain"} {
+		chainID := paramUint64(payload, key)
+		if chainID == 0 {
+			continue
+		}
+		if execution == ExecutionUserOperation {
+			if yip.userOperations == nil {
+				return fmt.Errorf("invalid execution: user operations are not configured on this performer")
+			}
+			if !yip.userOperations.Supports(chainID) {
+				return fmt.Errorf("invalid execution: no bundler for user operations on chain %d", chainID)
+			}
+			continue
+		}
+		// deposits funded by a permit pull the funds of the user who signed it
+		if account := yip.transactions.Address(chainID); user != account && !permitted {
+			return fmt.Errorf("invalid user_address: only funds of the performer account %s can be rebalanced on chain %d", account.Hex(), chainID)
+		}
+	}
+	return nil
+}
+
+// validateRebalanceRoute checks the route parameters simulations and submissions need
+func (yip *YieldIntelligencePerformer) validateRebalanceRoute(payload *TaskPayload) error {
+	if _, present := payload.Parameters["target_chain"]; !present {
+		return fmt.Errorf("missing target_chain")
+	}
+
+	if _, err := yip.adapters.MoverFor(paramString(payload, "target_protocol")); err != nil {
+		return err
+	}
+	if source := paramString(payload, "source_protocol"); source != "" {
+		if _, err := yip.adapters.MoverFor(source); err != nil {
+			return err
+		}
+	}
+
+	targetChain := paramUint64(payload, "target_chain")
+	sourceChain := paramUint64(payload, "source_chain")
+	if sourceChain == 0 {
+		sourceChain = targetChain
+	}
+	if sourceChain == targetChain && paramString(payload, "source_protocol") == paramString(payload, "target_protocol") {
+		return fmt.Errorf("source and target market are the same")
+	}
+	if sourceChain != targetChain {
+		for _, chainID := range []uint64{sourceChain, targetChain} {
+			if _, err := tokens.CCTPUSDC(chainID); err != nil {
+				return fmt.Errorf("invalid route: %w", err)
+			}
+		}
+	}
+	if paramString(payload, "strategy") == flashloan.StrategyFlashLoan {
+		if sourceChain != targetChain || paramString(payload, "source_protocol") == "" {
+			return fmt.Errorf("invalid strategy: flash loans only move funds between markets of one chain")
+		}
+		if _, err := yip.flashLoans.Helper(sourceChain); err != nil {
+			return fmt.Errorf("invalid strategy: %w", err)
+		}
+	}
+	return nil
+}
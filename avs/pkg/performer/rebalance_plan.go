@@ -0,0 +1,362 @@
+package performer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+	"github.com/najnomics/crosscow-avs/pkg/cctp"
+	"github.com/najnomics/crosscow-avs/pkg/policy"
+	"github.com/najnomics/crosscow-avs/pkg/redis"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+const (
+	// maxPlanLifetime bounds how far ahead plans may expire
+	maxPlanLifetime = time.Hour
+
+	prefixPlan = "plan:"
+)
+
+// RebalancePlan is a rebalance agreed on before funds move. Operators produce the same
+// plan, and so the same hash, for the same task, since its expiry is a task parameter
+// rather than a time each operator picks. Amounts are in USDC and ExpiresAt is a unix
+// timestamp. Profile and TransferSpeed are left out of the encoding, and so of the hash,
+// when the task carries none.
+type RebalancePlan struct {
+	UserAddress    string            `json:"user_address"`
+	Amount         canonical.Decimal `json:"amount"`
+	SourceProtocol string            `json:"source_protocol"`
+	SourceChain    uint64            `json:"source_chain"`
+	TargetProtocol string            `json:"target_protocol"`
+	TargetChain    uint64            `json:"target_chain"`
+	MaxSlippageBps *uint64           `json:"max_slippage_bps"`
+	ResizeToCap    bool              `json:"resize_to_cap"`
+	Profile        *policy.Profile   `json:"profile,omitempty"`
+	TransferSpeed  cctp.Speed        `json:"transfer_speed,omitempty"`
+	ExpiresAt      uint64            `json:"expires_at"`
+}
+
+// rebalancePlan returns the plan of a rebalance_plan payload
+func rebalancePlan(payload *TaskPayload) RebalancePlan {
+	plan := RebalancePlan{
+		UserAddress:    common.HexToAddress(paramString(payload, "user_address")).Hex(),
+		Amount:         paramAmount(payload, "amount"),
+		SourceProtocol: paramString(payload, "source_protocol"),
+		SourceChain:    paramUint64(payload, "source_chain"),
+		TargetProtocol: paramString(payload, "target_protocol"),
+		TargetChain:    paramUint64(payload, "target_chain"),
+		ResizeToCap:    paramBool(payload, "resize_to_cap"),
+		Profile:        paramProfile(payload),
+		ExpiresAt:      paramUint64(payload, "expires_at"),
+	}
+	if _, present := payload.Parameters["transfer_speed"]; present {
+		plan.TransferSpeed = paramTransferSpeed(payload)
+	}
+	if plan.SourceChain == 0 {
+		plan.SourceChain = plan.TargetChain
+	}
+	if _, present := payload.Parameters["max_slippage_bps"]; present {
+		bps := paramUint64(payload, "max_slippage_bps")
+		plan.MaxSlippageBps = &bps
+	}
+	return plan
+}
+
+// Hash is the keccak256 hash of the canonical JSON encoding of the plan
+func (p *RebalancePlan) Hash() (common.Hash, error) {
+	encoded, err := canonical.Marshal(p)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to encode plan: %w", err)
+	}
+	return crypto.Keccak256Hash(encoded), nil
+}
+
+// execution returns the rebalance_execution payload carrying out the plan
+func (p *RebalancePlan) execution() *TaskPayload {
+	params := map[string]interface{}{
+		"user_address":    p.UserAddress,
+		"amount":          usdcBaseUnits(p.Amount).String(),
+		"source_chain":    float64(p.SourceChain),
+		"target_protocol": p.TargetProtocol,
+		"target_chain":    float64(p.TargetChain),
+	}
+	if p.SourceProtocol != "" {
+		params["source_protocol"] = p.SourceProtocol
+	}
+	if p.MaxSlippageBps != nil {
+		params["max_slippage_bps"] = float64(*p.MaxSlippageBps)
+	}
+	if p.ResizeToCap {
+		params["resize_to_cap"] = true
+	}
+	if p.Profile != nil {
+		params["profile"] = profileParam(p.Profile)
+	}
+	if p.TransferSpeed != "" {
+		params["transfer_speed"] = string(p.TransferSpeed)
+	}
+	return &TaskPayload{Type: TaskTypeRebalanceExecution, Parameters: params}
+}
+
+// RebalancePlanResult is the result of a rebalance_plan task. Operators sign it like any
+// result, so a quorum on it is a quorum on the plan hash that rebalance_commit executes.
+type RebalancePlanResult struct {
+	PlanHash string        `json:"plan_hash"`
+	Plan     RebalancePlan `json:"plan"`
+	Status   ResultStatus  `json:"status"`
+}
+
+// handleRebalancePlan checks a rebalance could proceed and keeps its plan until it is
+// committed or expires. Nothing is submitted: funds only move once a rebalance_commit
+// task names the plan hash.
+func (yip *YieldIntelligencePerformer) handleRebalancePlan(ctx context.Context, t *performerV1.TaskRequest, payload *TaskPayload) (interface{}, error) {
+	yip.log(ctx).Sugar().Infow("Processing rebalance plan task")
+
+	plan := rebalancePlan(payload)
+	if err := yip.checkHalt(ctx); err != nil {
+		return nil, err
+	}
+	route := &rebalanceRoute{
+		amount:         usdcBaseUnits(plan.Amount),
+		sourceProtocol: plan.SourceProtocol,
+		sourceChain:    plan.SourceChain,
+		targetProtocol: plan.TargetProtocol,
+		targetChain:    plan.TargetChain,
+		profile:        plan.Profile,
+		speed:          plan.TransferSpeed,
+	}
+	if err := yip.checkPolicy(ctx, route); err != nil {
+		return nil, err
+	}
+	if err := yip.checkProfile(ctx, route); err != nil {
+		return nil, err
+	}
+
+	hash, err := plan.Hash()
+	if err != nil {
+		return nil, err
+	}
+	if err := yip.plans.save(ctx, hash, plan); err != nil {
+		return nil, err
+	}
+	return &RebalancePlanResult{PlanHash: hash.Hex(), Plan: plan, Status: ResultStatusCompleted}, nil
+}
+
+// handleRebalanceCommit executes the plan named by plan_hash, once. Every check of a
+// rebalance runs again, since markets may have moved since the plan. Commits refused
+// before anything is submitted leave the plan to be committed again until it expires.
+// The plan is locked while it executes, so other replicas refuse to commit it meanwhile.
+func (yip *YieldIntelligencePerformer) handleRebalanceCommit(ctx context.Context, t *performerV1.TaskRequest, payload *TaskPayload) (interface{}, error) {
+	yip.log(ctx).Sugar().Infow("Processing rebalance commit task")
+
+	if err := yip.checkStanding(ctx); err != nil {
+		return nil, err
+	}
+	hash := common.HexToHash(paramString(payload, "plan_hash"))
+	if record, err := yip.plans.get(ctx, hash); err == nil {
+		name, err := planLockName(record.Plan)
+		if err != nil {
+			return nil, err
+		}
+		var unlock func()
+		if ctx, unlock, err = yip.lockExecution(ctx, name); err != nil {
+			return nil, err
+		}
+		defer unlock()
+	}
+	plan, err := yip.plans.claim(ctx, hash, string(t.TaskId), time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	execution := plan.execution()
+	result, err := func() (interface{}, error) {
+		if err := yip.validateRebalanceExecutionTask(execution); err != nil {
+			return nil, newTaskError(ErrorCodeValidation, fmt.Errorf("plan %s can no longer be executed: %w", hash.Hex(), err))
+		}
+		return yip.rebalance(ctx, t, execution)
+	}()
+	if err != nil {
+		if classifyError(err).Code != ErrorCodeExecutionFailed {
+			if releaseErr := yip.plans.release(ctx, hash); releaseErr != nil {
+				yip.log(ctx).Sugar().Errorw("Failed to release rebalance plan", "planHash", hash.Hex(), "error", releaseErr)
+			}
+		}
+		return nil, err
+	}
+	executed := result.(*RebalanceExecutionResult)
+	executed.PlanHash = hash.Hex()
+	return executed, nil
+}
+
+func (yip *YieldIntelligencePerformer) validateRebalancePlanTask(payload *TaskPayload) error {
+	if _, present := payload.Parameters["dry_run"]; present {
+		return fmt.Errorf("dry_run cannot be set on plans, which are never executed")
+	}
+	if err := refusePermit(payload); err != nil {
+		return err
+	}
+	now := time.Now()
+	if deadline := time.Unix(int64(paramUint64(payload, "expires_at")), 0); !deadline.After(now) || deadline.After(now.Add(maxPlanLifetime)) {
+		return fmt.Errorf("invalid expires_at: must be in the future and at most %s ahead", maxPlanLifetime)
+	}
+	return yip.validateRebalanceExecutionTask(payload)
+}
+
+func (yip *YieldIntelligencePerformer) validateRebalanceCommitTask(payload *TaskPayload) error {
+	return refusePermit(payload)
+}
+
+// planRecord is a plan as kept by the performer. CommittedBy is the task committing it.
+type planRecord struct {
+	Plan        RebalancePlan `json:"plan"`
+	CommittedBy string        `json:"committed_by,omitempty"`
+}
+
+// planStore keeps the plans the performer produced, so commits only ever execute them.
+// Commits are claimed through the Redis the replicas share when there is one, so replicas
+// keeping the same plan execute it once.
+type planStore struct {
+	kv     store.KV
+	shared *redis.Client
+
+	// mu makes claims atomic, so concurrent commits of a plan execute it once
+	mu sync.Mutex
+}
+
+func newPlanStore(kv store.KV, shared *redis.Client) *planStore {
+	return &planStore{kv: kv, shared: shared}
+}
+
+func planKey(hash common.Hash) []byte {
+	return []byte(prefixPlan + hash.Hex())
+}
+
+// save keeps plan under hash. Planning it again leaves the kept plan as it is.
+func (s *planStore) save(ctx context.Context, hash common.Hash, plan RebalancePlan) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.get(ctx, hash); !errors.Is(err, store.ErrNotFound) {
+		return err
+	}
+	return s.put(ctx, hash, &planRecord{Plan: plan})
+}
+
+// claim marks the plan under hash committed by taskID, refusing plans that are unknown,
+// expired or already committed
+func (s *planStore) claim(ctx context.Context, hash common.Hash, taskID string, now time.Time) (*RebalancePlan, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, err := s.get(ctx, hash)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		return nil, newTaskError(ErrorCodeValidation, fmt.Errorf("unknown plan %s: plans are only committed by the performers that produced them", hash.Hex()))
+	case err != nil:
+		return nil, err
+	case record.CommittedBy != "":
+		return nil, newTaskError(ErrorCodeValidation, fmt.Errorf("plan %s was already committed by task %s", hash.Hex(), record.CommittedBy))
+	case uint64(now.Unix()) >= record.Plan.ExpiresAt:
+		return nil, newTaskError(ErrorCodeValidation, fmt.Errorf("plan %s expired at %s", hash.Hex(), time.Unix(int64(record.Plan.ExpiresAt), 0).UTC().Format(time.RFC3339)))
+	}
+	// plans are never committed once expired, so neither are claims kept beyond
+	if err := s.claimShared(ctx, hash, taskID, time.Unix(int64(record.Plan.ExpiresAt), 0).Sub(now)); err != nil {
+		return nil, err
+	}
+	record.CommittedBy = taskID
+	if err := s.put(ctx, hash, record); err != nil {
+		if releaseErr := s.releaseShared(ctx, hash); releaseErr != nil {
+			return nil, errors.Join(err, releaseErr)
+		}
+		return nil, err
+	}
+	return &record.Plan, nil
+}
+
+// claimShared claims the commit of the plan under hash for taskID through the shared
+// Redis, for ttl. Commits are refused while Redis is down, since another replica may
+// be committing the plan.
+func (s *planStore) claimShared(ctx context.Context, hash common.Hash, taskID string, ttl time.Duration) error {
+	if s.shared == nil {
+		return nil
+	}
+	key := s.shared.Key("plan", "claim", hash.Hex())
+	claimed, holder := false, ""
+	err := s.shared.Do(ctx, func(ctx context.Context, rdb goredis.Cmdable) (err error) {
+		if claimed, err = rdb.SetNX(ctx, key, taskID, ttl).Result(); err != nil || claimed {
+			return err
+		}
+		holder, err = rdb.Get(ctx, key).Result()
+		if errors.Is(err, redis.Nil) {
+			return nil
+		}
+		return err
+	})
+	switch {
+	case err != nil:
+		return newTaskError(ErrorCodeUpstreamUnavailable, fmt.Errorf("failed to claim plan %s in the shared Redis: %w", hash.Hex(), err))
+	case !claimed:
+		return newTaskError(ErrorCodeValidation, fmt.Errorf("plan %s was already committed by task %s", hash.Hex(), holder))
+	}
+	return nil
+}
+
+// release lets the plan under hash be committed again
+func (s *planStore) release(ctx context.Context, hash common.Hash) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, err := s.get(ctx, hash)
+	if err != nil {
+		return err
+	}
+	record.CommittedBy = ""
+	if err := s.put(ctx, hash, record); err != nil {
+		return err
+	}
+	return s.releaseShared(ctx, hash)
+}
+
+// releaseShared drops the claim on the plan under hash from the shared Redis
+func (s *planStore) releaseShared(ctx context.Context, hash common.Hash) error {
+	if s.shared == nil {
+		return nil
+	}
+	err := s.shared.Do(context.WithoutCancel(ctx), func(ctx context.Context, rdb goredis.Cmdable) error {
+		return rdb.Del(ctx, s.shared.Key("plan", "claim", hash.Hex())).Err()
+	})
+	if err != nil {
+		return fmt.Errorf("failed to release plan %s in the shared Redis: %w", hash.Hex(), err)
+	}
+	return nil
+}
+
+func (s *planStore) get(ctx context.Context, hash common.Hash) (*planRecord, error) {
+	encoded, err := s.kv.Get(ctx, planKey(hash))
+	if err != nil {
+		return nil, err
+	}
+	var record planRecord
+	if err := json.Unmarshal(encoded, &record); err != nil {
+		return nil, fmt.Errorf("failed to decode plan %s: %w", hash.Hex(), err)
+	}
+	return &record, nil
+}
+
+func (s *planStore) put(ctx context.Context, hash common.Hash, record *planRecord) error {
+	encoded, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if err := s.kv.Set(ctx, planKey(hash), encoded); err != nil {
+		return fmt.Errorf("failed to store plan %s: %w", hash.Hex(), err)
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package performer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+)
+
+// runRebalanceTask validates and handles a task, decoding its result into result
+func runRebalanceTask(t *testing.T, performer *YieldIntelligencePerformer, id, payload string, result interface{}) error {
+	t.Helper()
+	task := &performerV1.TaskRequest{TaskId: []byte(id), Payload: []byte(payload)}
+	if err := performer.ValidateTask(task); err != nil {
+		return err
+	}
+	resp, err := performer.HandleTask(task)
+	if err != nil {
+		return err
+	}
+	envelope := struct {
+		Result interface{} `json:"result"`
+	}{Result: result}
+	if err := json.Unmarshal(resp.Result, &envelope); err != nil {
+		t.Fatalf("Failed to decode result: %v", err)
+	}
+	return nil
+}
+
+func planPayload(account common.Address, expiresAt time.Time) string {
+	return `{"type":"rebalance_plan","parameters":{"user_address":"` + account.Hex() + `","amount":"1000000000",
+		"source_chain":1,"target_protocol":"aave_v3","target_chain":8453,"expires_at":` + strconv.FormatInt(expiresAt.Unix(), 10) + `}}`
+}
+
+func commitPayload(hash string) string {
+	return `{"type":"rebalance_commit","parameters":{"plan_hash":"` + hash + `"}}`
+}
+
+func Test_RebalancePlanAndCommit(t *testing.T) {
+	performer, account, ethereum := newSubmittingPerformer(t)
+	expiresAt := time.Now().Add(10 * time.Minute)
+
+	var plan RebalancePlanResult
+	if err := runRebalanceTask(t, performer, "plan", planPayload(account, expiresAt), &plan); err != nil {
+		t.Fatalf("Planning failed: %v", err)
+	}
+	if plan.Status != ResultStatusCompleted || plan.Plan.Amount.String() != "1000.000000" || plan.Plan.ExpiresAt != uint64(expiresAt.Unix()) || plan.Plan.MaxSlippageBps != nil {
+		t.Errorf("Unexpected plan %+v", plan)
+	}
+	if len(ethereum.Sent()) != 0 {
+		t.Fatalf("Expected planning to submit nothing")
+	}
+
+	// another operator planning the same task gets the same hash
+	other, otherAccount, _ := newSubmittingPerformer(t)
+	var otherPlan RebalancePlanResult
+	if err := runRebalanceTask(t, other, "plan", planPayload(otherAccount, expiresAt), &otherPlan); err != nil {
+		t.Fatalf("Planning failed: %v", err)
+	}
+	otherPlan.Plan.UserAddress = plan.Plan.UserAddress
+	if hash, _ := otherPlan.Plan.Hash(); hash.Hex() != plan.PlanHash {
+		t.Errorf("Expected the plan hash to depend on the plan only")
+	}
+
+	var executed RebalanceExecutionResult
+	if err := runRebalanceTask(t, performer, "commit", commitPayload(plan.PlanHash), &executed); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if executed.PlanHash != plan.PlanHash || executed.Status != ResultStatusSubmitted || len(ethereum.Sent()) != 2 {
+		t.Fatalf("Expected the plan to be executed, got %+v", executed)
+	}
+
+	// a plan is executed once, whatever task commits it
+	err := runRebalanceTask(t, performer, "commit-again", commitPayload(plan.PlanHash), &executed)
+	var taskErr *TaskError
+	if !errors.As(err, &taskErr) || taskErr.Code != ErrorCodeValidation || !strings.Contains(err.Error(), "already committed by task commit") {
+		t.Errorf("Expected the second commit to be refused, got %v", err)
+	}
+	if len(ethereum.Sent()) != 2 {
+		t.Errorf("Expected nothing more to be submitted")
+	}
+}
+
+func Test_RebalanceCommitRefusals(t *testing.T) {
+	performer, account, ethereum := newSubmittingPerformer(t)
+	ctx := context.Background()
+
+	err := runRebalanceTask(t, performer, "unknown", commitPayload(common.HexToHash("0x01").Hex()), nil)
+	if err == nil || !strings.Contains(err.Error(), "unknown plan") {
+		t.Errorf("Expected an unknown plan to be refused, got %v", err)
+	}
+
+	expired := RebalancePlan{UserAddress: account.Hex(), Amount: usdcAmount(usdcUnits(1000)), SourceChain: 1, TargetProtocol: "aave_v3", TargetChain: 1, ExpiresAt: uint64(time.Now().Add(-time.Minute).Unix())}
+	hash, _ := expired.Hash()
+	if err := performer.plans.save(ctx, hash, expired); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	if err := runRebalanceTask(t, performer, "expired", commitPayload(hash.Hex()), nil); err == nil || !strings.Contains(err.Error(), "expired at") {
+		t.Errorf("Expected an expired plan to be refused, got %v", err)
+	}
+
+	// commits refused before anything is submitted leave the plan to commit later
+	var plan RebalancePlanResult
+	if err := runRebalanceTask(t, performer, "plan", planPayload(account, time.Now().Add(time.Minute)), &plan); err != nil {
+		t.Fatalf("Planning failed: %v", err)
+	}
+	if _, err := performer.killswitch.Halt(ctx, "exploit"); err != nil {
+		t.Fatalf("Halt failed: %v", err)
+	}
+	var taskErr *TaskError
+	if err := runRebalanceTask(t, performer, "halted", commitPayload(plan.PlanHash), nil); !errors.As(err, &taskErr) || taskErr.Code != ErrorCodeHalted {
+		t.Fatalf("Expected the commit to be halted, got %v", err)
+	}
+	if err := performer.killswitch.Resume(ctx); err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+	var executed RebalanceExecutionResult
+	if err := runRebalanceTask(t, performer, "resumed", commitPayload(plan.PlanHash), &executed); err != nil || executed.PlanHash != plan.PlanHash {
+		t.Fatalf("Expected the plan to be committed once resumed, got %+v, %v", executed, err)
+	}
+	if len(ethereum.Sent()) != 2 {
+		t.Errorf("Expected the plan to be executed once, got %d transactions", len(ethereum.Sent()))
+	}
+}
+
+func Test_RebalancePlanValidation(t *testing.T) {
+	performer, account, _ := newSubmittingPerformer(t)
+	plan := func(extra string) string {
+		return `{"type":"rebalance_plan","parameters":{"user_address":"` + account.Hex() + `","amount":"1000000000","target_protocol":"aave_v3","target_chain":1` + extra + `}}`
+	}
+	soon := strconv.FormatInt(time.Now().Add(time.Minute).Unix(), 10)
+
+	testCases := map[string]string{
+		"missing expiry": plan(``),
+		"past expiry":    plan(`,"expires_at":1000`),
+		"far expiry":     plan(`,"expires_at":` + strconv.FormatInt(time.Now().Add(2*time.Hour).Unix(), 10)),
+		"dry run":        plan(`,"expires_at":` + soon + `,"dry_run":true`),
+		"missing amount": `{"type":"rebalance_plan","parameters":{"user_address":"` + account.Hex() + `","target_protocol":"aave_v3","target_chain":1,"expires_at":` + soon + `}}`,
+		"plan hash":      commitPayload("0x1234"),
+	}
+	for name, payload := range testCases {
+		task := &performerV1.TaskRequest{TaskId: []byte(name), Payload: []byte(payload)}
+		if err := performer.ValidateTask(task); err == nil {
+			t.Errorf("Expected %s to be rejected", name)
+		}
+	}
+	task := &performerV1.TaskRequest{TaskId: []byte("valid"), Payload: []byte(plan(`,"expires_at":` + soon))}
+	if err := performer.ValidateTask(task); err != nil {
+		t.Errorf("Expected a valid plan to be accepted, got %v", err)
+	}
+}
+
+func Test_PlanClaimsSharedAcrossReplicas(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	now := time.Now()
+	plan := RebalancePlan{UserAddress: common.HexToAddress("0xaa").Hex(), TargetProtocol: "aave_v3", TargetChain: 1, ExpiresAt: uint64(now.Add(10 * time.Minute).Unix())}
+	hash, err := plan.Hash()
+	if err != nil {
+		t.Fatalf("Hash failed: %v", err)
+	}
+	// both replicas keep the plan, as when its task was delivered to each
+	a := newPlanStore(store.NewMemoryKV(), newReplicaRedis(t, server))
+	b := newPlanStore(store.NewMemoryKV(), newReplicaRedis(t, server))
+	for _, plans := range []*planStore{a, b} {
+		if err := plans.save(ctx, hash, plan); err != nil {
+			t.Fatalf("save failed: %v", err)
+		}
+	}
+
+	if _, err := a.claim(ctx, hash, "commit", now); err != nil {
+		t.Fatalf("claim failed: %v", err)
+	}
+	var taskErr *TaskError
+	if _, err := b.claim(ctx, hash, "other", now); !errors.As(err, &taskErr) || taskErr.Code != ErrorCodeValidation || !strings.Contains(err.Error(), "committed by task commit") {
+		t.Errorf("Expected the other replica to refuse the claimed plan, got %v", err)
+	}
+	if ttl := server.TTL("yieldavs:plan:claim:" + hash.Hex()); ttl <= 0 || ttl > 10*time.Minute {
+		t.Errorf("Expected the claim to expire with the plan, got %v", ttl)
+	}
+	// released plans can be committed again, on any replica
+	if err := a.release(ctx, hash); err != nil {
+		t.Fatalf("release failed: %v", err)
+	}
+	if _, err := b.claim(ctx, hash, "other", now); err != nil {
+		t.Errorf("Expected the released plan to be claimed: %v", err)
+	}
+
+	server.Close()
+	if err := a.release(ctx, hash); err == nil {
+		t.Errorf("Expected releasing the claim to fail while Redis is down")
+	}
+	if _, err := a.claim(ctx, hash, "down", now); !errors.As(err, &taskErr) || taskErr.Code != ErrorCodeUpstreamUnavailable {
+		t.Errorf("Expected plans to be refused while Redis is down, got %v", err)
+	}
+}
@@ -0,0 +1,504 @@
+package performer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/chain/chaintest"
+	"github.com/najnomics/crosscow-avs/pkg/positions"
+	"github.com/najnomics/crosscow-avs/pkg/signer"
+	"github.com/najnomics/crosscow-avs/pkg/simulate"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+	"github.com/najnomics/crosscow-avs/pkg/txmgr"
+	"go.uber.org/zap"
+)
+
+// movableAdapter is a fakeAdapter whose markets live at a fixed contract. Positions are
+// read from positions in turn, the last one repeating.
+type movableAdapter struct {
+	*fakeAdapter
+	contract  common.Address
+	positions []*big.Int
+}
+
+func (m *movableAdapter) SupplyCall(chainID uint64, onBehalfOf common.Address, amount *big.Int) (*chain.Call, error) {
+	return &chain.Call{To: m.contract, Data: []byte("supply")}, nil
+}
+
+func (m *movableAdapter) WithdrawCall(chainID uint64, to common.Address, amount *big.Int) (*chain.Call, error) {
+	return &chain.Call{To: m.contract, Data: []byte("withdraw")}, nil
+}
+
+func (m *movableAdapter) PositionOf(ctx context.Context, chainID uint64, account common.Address) (*big.Int, error) {
+	if len(m.positions) == 0 {
+		return new(big.Int), nil
+	}
+	position := m.positions[0]
+	if len(m.positions) > 1 {
+		m.positions = m.positions[1:]
+	}
+	return position, nil
+}
+
+// fakeSimulator simulates every call of a bundle at a fixed gas cost
+type fakeSimulator struct {
+	bundles map[uint64]int
+	err     error
+}
+
+func (s *fakeSimulator) SimulateBundle(ctx context.Context, chainID uint64, from common.Address, calls []chain.Call) ([]simulate.Outcome, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	s.bundles[chainID] = len(calls)
+	outcomes := make([]simulate.Outcome, len(calls))
+	for i := range outcomes {
+		outcomes[i] = simulate.Outcome{GasUsed: 100_000, Simulated: true}
+	}
+	return outcomes, nil
+}
+
+func (s *fakeSimulator) GasPrice(ctx context.Context, chainID uint64) (*big.Int, error) {
+	return big.NewInt(2_000_000_000), nil
+}
+
+func newDryRunPerformer(t *testing.T, simulator simulate.Simulator) *YieldIntelligencePerformer {
+	t.Helper()
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+	return NewYieldIntelligencePerformer(logger,
+		WithAdapters(adapters.NewRegistry(newMovableAave())),
+		WithSimulator(simulator),
+	)
+}
+
+// newMovableAave returns an aave adapter with markets on Ethereum and Base
+func newMovableAave(positions ...*big.Int) *movableAdapter {
+	aave := newFakeAaveAdapter()
+	base := *aave.markets[1]
+	base.ChainID = adapters.ChainIDBase
+	aave.markets[adapters.ChainIDBase] = &base
+	return &movableAdapter{fakeAdapter: aave, contract: common.HexToAddress("0x01"), positions: positions}
+}
+
+func runDryRun(t *testing.T, performer *YieldIntelligencePerformer, payload string) RebalanceExecutionResult {
+	t.Helper()
+	task := &performerV1.TaskRequest{TaskId: []byte("dry-run"), Payload: []byte(payload)}
+	if err := performer.ValidateTask(task); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	resp, err := performer.HandleTask(task)
+	if err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+	var envelope struct {
+		Result RebalanceExecutionResult `json:"result"`
+	}
+	if err := json.Unmarshal(resp.Result, &envelope); err != nil {
+		t.Fatalf("Failed to decode result: %v", err)
+	}
+	return envelope.Result
+}
+
+func Test_RebalanceDryRunAcrossChains(t *testing.T) {
+	simulator := &fakeSimulator{bundles: make(map[uint64]int)}
+	performer := newDryRunPerformer(t, simulator)
+
+	result := runDryRun(t, performer, `{"type":"rebalance_execution","parameters":{
+		"user_address":"0x00000000000000000000000000000000000000aa","amount":"1000000000000","dry_run":true,
+		"source_protocol":"aave_v3","source_chain":1,"target_protocol":"aave_v3","target_chain":8453}}`)
+
+	sim := result.Simulation
+	if !result.DryRun || sim == nil || result.Status != ResultStatusCompleted || !sim.Feasible {
+		t.Fatalf("Expected a feasible dry run, got %+v", result)
+	}
+
+	actions := []string{ActionWithdraw, ActionApprove, ActionBurn, ActionMint, ActionApprove, ActionDeposit}
+	if len(sim.Steps) != len(actions) {
+		t.Fatalf("Expected %d steps, got %+v", len(actions), sim.Steps)
+	}
+	for i, step := range sim.Steps {
+		if step.Action != actions[i] {
+			t.Errorf("Expected step %d to be %s, got %s", i, actions[i], step.Action)
+		}
+	}
+
+	// the mint and the deposit of minted funds cannot run before the attestation
+	if simulator.bundles[1] != 3 || simulator.bundles[adapters.ChainIDBase] != 1 {
+		t.Errorf("Unexpected simulated bundles: %v", simulator.bundles)
+	}
+	if mint := sim.Steps[3]; mint.Simulated || mint.GasUsed != fallbackGas[ActionMint] || mint.DurationSeconds < 19*60 {
+		t.Errorf("Expected the mint to be estimated and wait for attestation, got %+v", mint)
+	}
+	if sim.DurationSeconds < 19*60 {
+		t.Errorf("Expected the transfer to take at least the attestation time, got %ds", sim.DurationSeconds)
+	}
+
+	if len(sim.Gas) != 2 || sim.Gas[0].GasUsed != 300_000 || sim.Gas[0].Cost == nil || sim.Gas[0].Cost.String() != "0.000600000000000000" {
+		t.Errorf("Unexpected gas costs: %+v", sim.Gas)
+	}
+	if sim.FinalRate.Cmp(sim.TargetRate) >= 0 {
+		t.Errorf("Expected the deposit to dilute the target rate, got %s -> %s", sim.TargetRate, sim.FinalRate)
+	}
+	if sim.SourceRate == nil || sim.SourceRateImpactBps == nil || sim.SlippageBps.String() != "0.00" {
+		t.Errorf("Unexpected source side of the simulation: %+v", sim)
+	}
+}
+
+func Test_RebalanceDryRunInfeasibleWithdrawal(t *testing.T) {
+	simulator := &fakeSimulator{bundles: make(map[uint64]int)}
+	performer := newDryRunPerformer(t, simulator)
+
+	// the fake market has 20M USDC of available liquidity
+	result := runDryRun(t, performer, `{"type":"rebalance_execution","parameters":{
+		"user_address":"0x00000000000000000000000000000000000000aa","amount":"30000000000000","dry_run":true,
+		"source_protocol":"aave_v3","source_chain":1,"target_protocol":"aave_v3","target_chain":8453}}`)
+
+	sim := result.Simulation
+	if sim.Feasible || !sim.Steps[0].Reverted || sim.Steps[0].Simulated {
+		t.Errorf("Expected the withdrawal to be reported infeasible, got %+v", sim.Steps[0])
+	}
+	liquidity := result.WithdrawalLiquidity
+	if liquidity == nil || liquidity.Sufficient || liquidity.Available.String() != "20000000.000000" {
+		t.Fatalf("Expected the available liquidity to be reported short, got %+v", liquidity)
+	}
+	if liquidity.MaxWithdrawable == nil || liquidity.MaxWithdrawable.String() != "19800000.000000" {
+		t.Errorf("Expected a withdrawal leaving a 1%% buffer to be recommended, got %v", liquidity.MaxWithdrawable)
+	}
+}
+
+func Test_RebalanceDryRunSupplyCap(t *testing.T) {
+	simulator := &fakeSimulator{bundles: make(map[uint64]int)}
+
+	// the fake market has 10M USDC of headroom under its 110M cap
+	payload := `{"type":"rebalance_execution","parameters":{
+		"user_address":"0x00000000000000000000000000000000000000aa","amount":"15000000000000","dry_run":true,
+		"target_protocol":"aave_v3","target_chain":8453%s}}`
+	result := runDryRun(t, newDryRunPerformer(t, simulator), fmt.Sprintf(payload, ""))
+	sim := result.Simulation
+	if deposit := sim.Steps[len(sim.Steps)-1]; sim.Feasible || !deposit.Reverted || deposit.Action != ActionDeposit {
+		t.Errorf("Expected the deposit over the cap to be infeasible, got %+v", sim.Steps)
+	}
+	if c := result.SupplyCap; c == nil || c.Headroom == nil || c.Headroom.String() != "10000000.000000" {
+		t.Errorf("Expected the cap headroom to be reported, got %+v", c)
+	}
+
+	result = runDryRun(t, newDryRunPerformer(t, simulator), fmt.Sprintf(payload, `,"resize_to_cap":true`))
+	if !result.Simulation.Feasible || result.Amount.String() != "10000000.000000" || result.RequestedAmount == nil || result.RequestedAmount.String() != "15000000.000000" {
+		t.Errorf("Expected the rebalance to be resized to the headroom, got %+v", result)
+	}
+
+	// a queued cut to 104M leaves 4M
+	aave := newMovableAave()
+	aave.capCuts = []adapters.CapChange{
+		{SupplyCap: usdcUnits(104_000_000), Call: adapters.QueuedCall{ETA: 1_700_086_400}},
+		{Call: adapters.QueuedCall{ETA: 1_700_090_000}},
+	}
+	performer := newDryRunPerformer(t, simulator)
+	WithAdapters(adapters.NewRegistry(aave))(performer)
+	result = runDryRun(t, performer, fmt.Sprintf(payload, `,"resize_to_cap":true`))
+	if result.Amount.String() != "4000000.000000" || len(result.SupplyCap.PendingChanges) != 2 || result.SupplyCap.PendingChanges[1].Cap != nil {
+		t.Errorf("Expected the queued cut to lower the headroom, got %+v", result.SupplyCap)
+	}
+}
+
+func Test_RebalanceDryRunSimulatorDown(t *testing.T) {
+	performer := newDryRunPerformer(t, &fakeSimulator{err: errors.New("tenderly unavailable")})
+
+	result := runDryRun(t, performer, `{"type":"rebalance_execution","parameters":{
+		"user_address":"0x00000000000000000000000000000000000000aa","amount":"1000000000","dry_run":true,
+		"target_protocol":"aave_v3","target_chain":8453}}`)
+
+	if result.Status != ResultStatusPartial || len(result.Simulation.Steps) != 2 {
+		t.Fatalf("Expected a partial simulation of approve and deposit, got %+v", result)
+	}
+	for _, step := range result.Simulation.Steps {
+		if step.Simulated || step.GasUsed != fallbackGas[step.Action] {
+			t.Errorf("Expected fallback gas for %s, got %+v", step.Action, step)
+		}
+	}
+}
+
+func Test_RebalanceDryRunValidation(t *testing.T) {
+	performer := newDryRunPerformer(t, &fakeSimulator{bundles: make(map[uint64]int)})
+	logger, _ := zap.NewDevelopment()
+	unconfigured := NewYieldIntelligencePerformer(logger)
+
+	testCases := []struct {
+		name      string
+		performer *YieldIntelligencePerformer
+		params    string
+	}{
+		{name: "no simulator", performer: unconfigured, params: `"dry_run":true,"target_chain":1`},
+		{name: "dry_run not a boolean", performer: performer, params: `"dry_run":"yes","target_chain":1`},
+		{name: "missing target_chain", performer: performer, params: `"dry_run":true`},
+		{name: "same market", performer: performer, params: `"dry_run":true,"target_chain":1,"source_protocol":"aave_v3"`},
+		{name: "unknown source", performer: performer, params: `"dry_run":true,"target_chain":1,"source_protocol":"euler"`},
+		{name: "slippage above 100%", performer: performer, params: `"dry_run":true,"target_chain":1,"max_slippage_bps":10001`},
+		{name: "fractional slippage", performer: performer, params: `"dry_run":true,"target_chain":1,"max_slippage_bps":2.5`},
+		{name: "bridged token", performer: performer, params: `"dry_run":true,"source_chain":42161,"target_chain":1,"token":"0xFF970A61A04b1cA14834A43f5dE4533eBDDB5CC8"`},
+		{name: "route without cctp", performer: performer, params: `"dry_run":true,"source_chain":10,"target_chain":1`},
+		{name: "resize_to_cap not a boolean", performer: performer, params: `"dry_run":true,"target_chain":1,"resize_to_cap":1`},
+		{name: "urgent not a boolean", performer: performer, params: `"dry_run":true,"target_chain":1,"urgent":"yes"`},
+		{name: "unknown transfer_speed", performer: performer, params: `"dry_run":true,"source_chain":1,"target_chain":8453,"transfer_speed":"instant"`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			task := &performerV1.TaskRequest{
+				TaskId:  []byte(tc.name),
+				Payload: []byte(`{"type":"rebalance_execution","parameters":{"user_address":"0x00000000000000000000000000000000000000aa","amount":"1000000000","target_protocol":"aave_v3",` + tc.params + `}}`),
+			}
+			if err := tc.performer.ValidateTask(task); err == nil {
+				t.Errorf("Expected the dry run to be rejected")
+			}
+		})
+	}
+}
+
+var approveABI = chain.MustParseABI(`[
+	{"type":"function","name":"approve","inputs":[{"name":"spender","type":"address"},{"name":"amount","type":"uint256"}],"outputs":[{"name":"","type":"bool"}]},
+	{"type":"function","name":"balanceOf","inputs":[{"name":"account","type":"address"}],"outputs":[{"name":"","type":"uint256"}]}
+]`)
+
+// newSubmittingPerformer returns a performer sending from its own account on Ethereum
+// and Base. Its aave positions read as positions in turn.
+func newSubmittingPerformer(t *testing.T, positions ...*big.Int) (*YieldIntelligencePerformer, common.Address, *chaintest.Contracts) {
+	t.Helper()
+	performer, account, ethereum, _ := newSubmittingPerformerOnBase(t, positions...)
+	return performer, account, ethereum
+}
+
+// newSubmittingPerformerOnBase is newSubmittingPerformer also returning the Base contracts
+func newSubmittingPerformerOnBase(t *testing.T, positions ...*big.Int) (*YieldIntelligencePerformer, common.Address, *chaintest.Contracts, *chaintest.Contracts) {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	account := signer.NewKeySigner(key)
+
+	ethereum := chaintest.NewContracts(1)
+	usdc, err := adapters.USDCAddress(1)
+	if err != nil {
+		t.Fatalf("USDCAddress failed: %v", err)
+	}
+	ethereum.StubGas(t, usdc, approveABI, "approve", 50_000)
+	ethereum.Stub(t, usdc, approveABI, "balanceOf", big.NewInt(5_000_000_000))
+	ethereum.SetNonce(account.Address(), 5)
+	base := chaintest.NewContracts(adapters.ChainIDBase)
+	baseUSDC, err := adapters.USDCAddress(adapters.ChainIDBase)
+	if err != nil {
+		t.Fatalf("USDCAddress failed: %v", err)
+	}
+	base.Stub(t, baseUSDC, approveABI, "balanceOf", big.NewInt(0))
+	chains := chain.NewManager()
+	chains.Register(1, "ethereum", ethereum)
+	chains.Register(adapters.ChainIDBase, "base", base)
+
+	cfg := txmgr.DefaultConfig()
+	cfg.ChainConfirmations = map[uint64]uint64{1: 1, adapters.ChainIDBase: 1}
+	cfg.ConfirmationTimeout = 50 * time.Millisecond
+	cfg.PollInterval = time.Millisecond
+
+	performer := newDryRunPerformer(t, &fakeSimulator{bundles: make(map[uint64]int)})
+	WithTransactions(txmgr.NewSet(chains, account, cfg))(performer)
+	WithAdapters(adapters.NewRegistry(newMovableAave(positions...)))(performer)
+	return performer, account.Address(), ethereum, base
+}
+
+func Test_RebalanceSubmittedFromPerformerAccount(t *testing.T) {
+	performer, account, ethereum := newSubmittingPerformer(t)
+
+	result := runDryRun(t, performer, `{"type":"rebalance_execution","parameters":{
+		"user_address":"`+account.Hex()+`","nonce":"1","amount":"1000000000","source_chain":1,"target_protocol":"aave_v3","target_chain":8453}}`)
+
+	execution := result.Execution
+	if result.DryRun || result.Status != ResultStatusSubmitted || execution == nil {
+		t.Fatalf("Expected a submitted rebalance, got %+v", result)
+	}
+	if execution.From != account.Hex() || execution.SourceChain != 1 || execution.TargetChain != adapters.ChainIDBase {
+		t.Errorf("Unexpected execution: %+v", execution)
+	}
+
+	sent := ethereum.Sent()
+	if len(execution.Transactions) != 2 || len(sent) != 2 {
+		t.Fatalf("Expected the approve and burn to be submitted, got %+v", execution.Transactions)
+	}
+	approve, burn := execution.Transactions[0], execution.Transactions[1]
+	if approve.Status != TransactionPending || approve.BlockNumber != 0 {
+		t.Errorf("Expected the unmined approve to stay pending, got %+v", approve)
+	}
+	if approve.Action != ActionApprove || approve.Nonce != 5 || approve.GasLimit != 60_000 || approve.Hash != sent[0].Hash().Hex() {
+		t.Errorf("Unexpected approve: %+v", approve)
+	}
+	// the burn cannot be estimated before the approval is mined
+	if burn.Action != ActionBurn || burn.Nonce != 6 || burn.GasLimit != fallbackGas[ActionBurn] {
+		t.Errorf("Unexpected burn: %+v", burn)
+	}
+
+	pending := []string{ActionMint, ActionApprove, ActionDeposit}
+	if len(execution.PendingSteps) != len(pending) {
+		t.Fatalf("Expected %v to wait for the attestation, got %+v", pending, execution.PendingSteps)
+	}
+	for i, step := range execution.PendingSteps {
+		if step.Action != pending[i] || step.ChainID != adapters.ChainIDBase {
+			t.Errorf("Unexpected pending step %d: %+v", i, step)
+		}
+	}
+}
+
+func Test_RebalanceConfirmedOnChain(t *testing.T) {
+	performer, account, ethereum := newSubmittingPerformer(t, big.NewInt(0), big.NewInt(1_000_000_000))
+	ethereum.AutoMine()
+
+	result := runDryRun(t, performer, `{"type":"rebalance_execution","parameters":{
+		"user_address":"`+account.Hex()+`","nonce":"1","amount":"1000000000","target_protocol":"aave_v3","target_chain":1}}`)
+
+	execution := result.Execution
+	if result.Status != ResultStatusCompleted || len(execution.Transactions) != 2 || len(execution.PendingSteps) != 0 {
+		t.Fatalf("Expected the approve and deposit to be confirmed, got %+v", result)
+	}
+	for _, tx := range execution.Transactions {
+		// base fee of 1 gwei plus the 0.1 gwei tip
+		if tx.Status != TransactionConfirmed || tx.BlockNumber == 0 || tx.GasUsed != tx.GasLimit || tx.Confirmations < 1 ||
+			tx.EffectiveGasPrice == nil || tx.EffectiveGasPrice.String() != "1.100000000" {
+			t.Errorf("Unexpected receipt of %s: %+v", tx.Action, tx)
+		}
+	}
+
+	// the wallet balance is stubbed, so it reads unchanged, which is above what the
+	// deposit may take
+	if !execution.Verified || execution.MaxSlippageBps != 10 || len(execution.BalanceChecks) != 2 {
+		t.Fatalf("Expected the wallet and aave position to be verified, got %+v", execution)
+	}
+	wallet, position := execution.BalanceChecks[0], execution.BalanceChecks[1]
+	if wallet.Holding != HoldingWallet || wallet.MinChange.String() != "-1001.000000" || !wallet.Passed {
+		t.Errorf("Unexpected wallet check: %+v", wallet)
+	}
+	if position.Holding != "aave_v3" || position.Change.String() != "1000.000000" || position.MinChange.String() != "999.000000" || !position.Passed {
+		t.Errorf("Unexpected position check: %+v", position)
+	}
+
+	encoded, err := result.EncodeABI()
+	if err != nil {
+		t.Fatalf("EncodeABI failed: %v", err)
+	}
+	if encoded[len(encoded)-1] != 1 {
+		t.Errorf("Expected the confirmed rebalance to be encoded as executed")
+	}
+}
+
+func Test_RebalanceLosingFundsIsAnomalous(t *testing.T) {
+	// the deposit credits 998 USDC of 1000
+	performer, account, ethereum := newSubmittingPerformer(t, big.NewInt(0), big.NewInt(998_000_000))
+	ethereum.AutoMine()
+
+	result := runDryRun(t, performer, `{"type":"rebalance_execution","parameters":{
+		"user_address":"`+account.Hex()+`","nonce":"1","amount":"1000000000","target_protocol":"aave_v3","target_chain":1}}`)
+	if result.Status != ResultStatusAnomalous || result.Execution.Verified {
+		t.Fatalf("Expected the loss beyond 10 bps to be anomalous, got %+v", result.Execution)
+	}
+	if position := result.Execution.BalanceChecks[1]; position.Passed || position.Change.String() != "998.000000" {
+		t.Errorf("Unexpected position check: %+v", position)
+	}
+
+	// a looser bound in the task accepts the loss
+	performer, account, ethereum = newSubmittingPerformer(t, big.NewInt(0), big.NewInt(998_000_000))
+	ethereum.AutoMine()
+	result = runDryRun(t, performer, `{"type":"rebalance_execution","parameters":{
+		"user_address":"`+account.Hex()+`","nonce":"1","amount":"1000000000","target_protocol":"aave_v3","target_chain":1,"max_slippage_bps":50}}`)
+	if result.Status != ResultStatusCompleted || !result.Execution.Verified || result.Execution.MaxSlippageBps != 50 {
+		t.Errorf("Expected the loss within 50 bps to pass, got %+v", result.Execution)
+	}
+}
+
+func Test_RebalanceSlippageBoundsBridgeFee(t *testing.T) {
+	performer, account, ethereum := newSubmittingPerformer(t)
+
+	result := runDryRun(t, performer, `{"type":"rebalance_execution","parameters":{
+		"user_address":"`+account.Hex()+`","nonce":"1","amount":"1000000000","source_chain":1,"target_protocol":"aave_v3","target_chain":8453,"max_slippage_bps":25}}`)
+	if result.Execution == nil || len(ethereum.Sent()) != 2 {
+		t.Fatalf("Expected the approve and burn to be submitted, got %+v", result)
+	}
+	// unconfirmed transactions leave balances unchecked
+	if result.Execution.Verified || len(result.Execution.BalanceChecks) != 0 {
+		t.Errorf("Expected no balance checks before confirmation, got %+v", result.Execution.BalanceChecks)
+	}
+
+	// maxFee is the sixth word of the depositForBurn arguments
+	data := ethereum.Sent()[1].Data()
+	if maxFee := new(big.Int).SetBytes(data[4+5*32 : 4+6*32]); maxFee.Cmp(big.NewInt(2_500_000)) != 0 {
+		t.Errorf("Expected a max fee of 25 bps of the amount, got %s", maxFee)
+	}
+}
+
+func Test_RebalanceSubmissionOnlyMovesPerformerFunds(t *testing.T) {
+	performer, _, ethereum := newSubmittingPerformer(t)
+
+	task := &performerV1.TaskRequest{TaskId: []byte("other-user"), Payload: []byte(`{"type":"rebalance_execution","parameters":{
+		"user_address":"0x00000000000000000000000000000000000000aa","amount":"1000000000","source_chain":1,"target_protocol":"aave_v3","target_chain":8453}}`)}
+	if err := performer.ValidateTask(task); err == nil {
+		t.Errorf("Expected a rebalance of another account to be rejected")
+	}
+	if len(ethereum.Sent()) != 0 {
+		t.Errorf("Expected no transaction to be sent")
+	}
+}
+
+func Test_RebalanceTracksPositions(t *testing.T) {
+	performer, account, ethereum := newSubmittingPerformer(t, big.NewInt(0), big.NewInt(1_000_000_000))
+	ethereum.AutoMine()
+	tracker := positions.New(positions.DefaultConfig(), store.NewMemoryKV())
+	WithPositions(tracker)(performer)
+
+	result := runDryRun(t, performer, `{"type":"rebalance_execution","parameters":{
+		"user_address":"`+account.Hex()+`","nonce":"1","amount":"1000000000","target_protocol":"aave_v3","target_chain":1}}`)
+	if result.Status != ResultStatusCompleted {
+		t.Fatalf("Expected the deposit to be confirmed, got %+v", result)
+	}
+	position, err := tracker.Get(context.Background(), account, "aave_v3", 1)
+	if err != nil {
+		t.Fatalf("Expected the aave position to be tracked: %v", err)
+	}
+	if position.Amount.Cmp(big.NewInt(1_000_000_000)) != 0 || position.Source != positions.SourceExecution {
+		t.Errorf("Unexpected tracked position: %+v", position)
+	}
+}
+
+func Test_RebalanceRefusesWithdrawingMoreThanTracked(t *testing.T) {
+	performer, account, ethereum := newSubmittingPerformer(t)
+	tracker := positions.New(positions.DefaultConfig(), store.NewMemoryKV())
+	WithPositions(tracker)(performer)
+	if err := tracker.Record(context.Background(), positions.Position{
+		Owner: account, Protocol: "aave_v3", ChainID: 1, Amount: big.NewInt(500_000_000), Source: positions.SourceChain,
+	}); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+
+	task := &performerV1.TaskRequest{TaskId: []byte("over-position"), Payload: []byte(`{"type":"rebalance_execution","parameters":{
+		"user_address":"` + account.Hex() + `","nonce":"1","amount":"1000000000","source_protocol":"aave_v3","source_chain":1,"target_protocol":"aave_v3","target_chain":8453}}`)}
+	if err := performer.ValidateTask(task); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	_, err := performer.HandleTask(task)
+	var taskErr *TaskError
+	if !errors.As(err, &taskErr) || taskErr.Code != ErrorCodeValidation || !strings.Contains(err.Error(), "500.000000 USDC") {
+		t.Errorf("Expected the withdrawal beyond the tracked position to be refused, got %v", err)
+	}
+	if len(ethereum.Sent()) != 0 {
+		t.Errorf("Expected no transaction to be sent")
+	}
+}
@@ -0,0 +1,34 @@
+package performer
+
+import (
+	"github.com/najnomics/crosscow-avs/pkg/anomaly"
+	"github.com/najnomics/crosscow-avs/pkg/crossval"
+	"github.com/najnomics/crosscow-avs/pkg/quota"
+	"github.com/najnomics/crosscow-avs/pkg/taskqueue"
+)
+
+// Thresholds returns the anomaly and cross validation thresholds tasks run with
+func (yip *YieldIntelligencePerformer) Thresholds() (anomaly.Config, crossval.Config) {
+	yip.thresholdsMu.RLock()
+	defer yip.thresholdsMu.RUnlock()
+	return yip.anomaly, yip.crossval
+}
+
+// SetThresholds replaces the anomaly and cross validation thresholds of tasks started
+// from now on. Cross validation sources cannot be changed.
+func (yip *YieldIntelligencePerformer) SetThresholds(anomalyCfg anomaly.Config, crossvalCfg crossval.Config) {
+	yip.thresholdsMu.Lock()
+	defer yip.thresholdsMu.Unlock()
+	yip.anomaly = anomalyCfg
+	yip.crossval = crossvalCfg
+}
+
+// SetQuotaLimits replaces the quota limits of task types, as quota.Limiter.SetLimits does
+func (yip *YieldIntelligencePerformer) SetQuotaLimits(limits map[string]quota.Limit) {
+	yip.quotas.SetLimits(limits)
+}
+
+// SetTaskQueue replaces the lanes of the task queue, as taskqueue.Queue.SetConfig does
+func (yip *YieldIntelligencePerformer) SetTaskQueue(cfg taskqueue.Config) {
+	yip.queue.SetConfig(cfg)
+}
@@ -0,0 +1,123 @@
+package performer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/najnomics/crosscow-avs/pkg/notify"
+	"github.com/najnomics/crosscow-avs/pkg/reorg"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+)
+
+// monitoringTaskTypes are the task types re-executed when a reorg invalidates their
+// results
+var monitoringTaskTypes = map[TaskType]bool{
+	TaskTypeYieldMonitoring: true,
+	TaskTypeDepegMonitoring: true,
+}
+
+// pinnedBlocks collects the reference blocks the reads of a task were pinned to
+type pinnedBlocks struct {
+	mu     sync.Mutex
+	blocks map[uint64]map[uint64]bool
+}
+
+type pinnedBlocksKey struct{}
+
+// withPins collects the reference blocks pinned with the returned context, when reorgs
+// are watched
+func (yip *YieldIntelligencePerformer) withPins(ctx context.Context) (context.Context, *pinnedBlocks) {
+	if yip.reorgs == nil {
+		return ctx, nil
+	}
+	pins := &pinnedBlocks{blocks: make(map[uint64]map[uint64]bool)}
+	return context.WithValue(ctx, pinnedBlocksKey{}, pins), pins
+}
+
+// recordPin adds block of chainID to the blocks collected in ctx
+func recordPin(ctx context.Context, chainID, block uint64) {
+	pins, ok := ctx.Value(pinnedBlocksKey{}).(*pinnedBlocks)
+	if !ok || block == 0 {
+		return
+	}
+	pins.mu.Lock()
+	defer pins.mu.Unlock()
+	if pins.blocks[chainID] == nil {
+		pins.blocks[chainID] = make(map[uint64]bool)
+	}
+	pins.blocks[chainID][block] = true
+}
+
+// trackPins watches the blocks the result of taskID was read at. Results whose blocks
+// cannot be watched are still answered.
+func (yip *YieldIntelligencePerformer) trackPins(ctx context.Context, taskID string, pins *pinnedBlocks) {
+	if pins == nil {
+		return
+	}
+	pins.mu.Lock()
+	defer pins.mu.Unlock()
+	for chainID, blocks := range pins.blocks {
+		for block := range blocks {
+			if err := yip.reorgs.Track(ctx, taskID, chainID, block); err != nil {
+				yip.log(ctx).Sugar().Warnw("Failed to watch reference block for reorgs", "chainId", chainID, "block", block, "error", err)
+			}
+		}
+	}
+}
+
+// Invalidate marks the stored result of a task read at a block a reorg removed as stale,
+// drops it from the result cache and raises a result_invalidated alert. Monitoring tasks
+// are executed again when reexecute is set, so their stored results describe the chain
+// again; redeliveries of other stale tasks are executed again.
+func (yip *YieldIntelligencePerformer) Invalidate(ctx context.Context, invalidation *reorg.Invalidation, reexecute bool) error {
+	record, err := yip.tasks.GetTask(ctx, invalidation.TaskID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up task %s: %w", invalidation.TaskID, err)
+	}
+	if record.Status != store.TaskStatusCompleted {
+		return nil
+	}
+
+	reason := fmt.Sprintf("block %d (%s) of chain %d was reorganized %d blocks deep", invalidation.Number, invalidation.Hash.Hex(), invalidation.ChainID, invalidation.Depth)
+	if err := yip.tasks.MarkStale(ctx, record.TaskID, reason); err != nil {
+		return fmt.Errorf("failed to mark task %s stale: %w", record.TaskID, err)
+	}
+	t := &performerV1.TaskRequest{TaskId: []byte(record.TaskID), Payload: record.Payload}
+	payload, err := parseTaskPayload(t)
+	if err != nil {
+		return fmt.Errorf("failed to parse payload of task %s: %w", record.TaskID, err)
+	}
+	if err := yip.cache.Invalidate(ctx, string(payload.Type), payload.Parameters); err != nil {
+		yip.logger.Sugar().Warnw("Failed to drop invalidated result from the result cache", "taskId", record.TaskID, "error", err)
+	}
+
+	reexecuted := false
+	if reexecute && monitoringTaskTypes[payload.Type] {
+		if _, err := yip.HandleTask(t); err != nil {
+			yip.logger.Sugar().Warnw("Failed to re-execute task invalidated by a reorg", "taskId", record.TaskID, "error", err)
+		} else {
+			reexecuted = true
+		}
+	}
+	yip.notify(t, payload, notify.Event{
+		Type:     notify.EventResultInvalidated,
+		Severity: notify.SeverityWarning,
+		Key:      fmt.Sprintf("result_invalidated:%d:%d", invalidation.ChainID, invalidation.Number),
+		Summary:  fmt.Sprintf("Result of task %s is stale: %s", record.TaskID, reason),
+		Data: &ResultInvalidatedEvent{
+			ChainID:       invalidation.ChainID,
+			BlockNumber:   invalidation.Number,
+			BlockHash:     invalidation.Hash,
+			CanonicalHash: invalidation.CanonicalHash,
+			Depth:         invalidation.Depth,
+			Reexecuted:    reexecuted,
+		},
+	})
+	return nil
+}
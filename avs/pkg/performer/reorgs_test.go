@@ -0,0 +1,104 @@
+package performer
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/cache"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/chain/chaintest"
+	"github.com/najnomics/crosscow-avs/pkg/notify"
+	"github.com/najnomics/crosscow-avs/pkg/reorg"
+	"github.com/najnomics/crosscow-avs/pkg/snapshot"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+	"go.uber.org/zap"
+)
+
+func Test_ReorgInvalidatesResults(t *testing.T) {
+	contracts := chaintest.NewContracts(1)
+	contracts.SetHead(120, 1_700_000_000)
+	contracts.SetFinalized(100)
+	chains := chain.NewManager()
+	chains.Register(1, "ethereum", contracts)
+	snapshots := snapshot.DefaultConfig()
+	snapshots.Enabled = true
+	cfg := reorg.DefaultConfig()
+	cfg.Enabled = true
+	watcher := reorg.New(cfg, chains, store.NewMemoryKV(), zap.NewNop())
+	resultCache, err := cache.New(cache.DefaultConfig(), cache.NewMemoryBackend(10), nil)
+	if err != nil {
+		t.Fatalf("cache.New failed: %v", err)
+	}
+	performer := NewYieldIntelligencePerformer(zap.NewNop(),
+		WithResultCache(resultCache),
+		WithAdapters(adapters.NewRegistry(&pinnedAdapter{fakeAdapter: newFakeAaveAdapter()})),
+		WithSnapshots(snapshot.New(snapshots, chains)),
+		WithReorgs(watcher),
+	)
+	events := withEventSink(t, performer)
+	ctx := context.Background()
+
+	task := &performerV1.TaskRequest{TaskId: []byte("watched"), Payload: []byte(`{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1}}`)}
+	if _, err := performer.HandleTask(task); err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+	params := map[string]interface{}{"protocol": "aave_v3", "token": "USDC", "chain_id": float64(1)}
+	if _, ok := resultCache.Get(ctx, string(TaskTypeYieldMonitoring), params); !ok {
+		t.Fatalf("Expected the result cached")
+	}
+	invalidate := func(reexecute bool) {
+		t.Helper()
+		contracts.Reorg(95)
+		invalidations, err := watcher.Poll(ctx)
+		if err != nil || len(invalidations) != 1 || invalidations[0].TaskID != "watched" || invalidations[0].Number != 100 {
+			t.Fatalf("Expected the reference block of the result invalidated, got %+v: %v", invalidations, err)
+		}
+		if err := performer.Invalidate(ctx, invalidations[0], reexecute); err != nil {
+			t.Fatalf("Invalidate failed: %v", err)
+		}
+	}
+
+	invalidate(false)
+	record, err := performer.tasks.GetTask(ctx, "watched")
+	if err != nil || record.Status != store.TaskStatusStale || record.StaleReason == "" {
+		t.Fatalf("Expected the result marked stale, got %+v: %v", record, err)
+	}
+	if _, ok := resultCache.Get(ctx, string(TaskTypeYieldMonitoring), params); ok {
+		t.Errorf("Expected the stale result dropped from the result cache")
+	}
+	// redeliveries of stale tasks are executed again, and their new block watched
+	if _, err := performer.HandleTask(task); err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+	if record, _ := performer.tasks.GetTask(ctx, "watched"); record.Status != store.TaskStatusCompleted {
+		t.Errorf("Expected the redelivered task completed again, got %s", record.Status)
+	}
+
+	invalidate(true)
+	if record, _ := performer.tasks.GetTask(ctx, "watched"); record.Status != store.TaskStatusCompleted {
+		t.Errorf("Expected the monitoring task re-executed, got %s", record.Status)
+	}
+
+	raised, _ := events()
+	var invalidated []ResultInvalidatedEvent
+	for _, event := range raised {
+		if event.Type != notify.EventResultInvalidated {
+			continue
+		}
+		var data ResultInvalidatedEvent
+		encoded, _ := json.Marshal(event.Data)
+		if err := json.Unmarshal(encoded, &data); err != nil {
+			t.Fatalf("Failed to decode event: %v", err)
+		}
+		if event.Severity != notify.SeverityWarning || data.ChainID != 1 || data.BlockNumber != 100 || data.BlockHash == data.CanonicalHash {
+			t.Errorf("Unexpected result_invalidated event %+v %+v", event, data)
+		}
+		invalidated = append(invalidated, data)
+	}
+	if len(invalidated) != 2 || invalidated[0].Reexecuted || !invalidated[1].Reexecuted {
+		t.Errorf("Expected an event per invalidation, the second re-executed, got %+v", invalidated)
+	}
+}
@@ -0,0 +1,88 @@
+package performer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+	"github.com/najnomics/crosscow-avs/pkg/commitment"
+	"github.com/najnomics/crosscow-avs/pkg/resultsize"
+)
+
+// validateResultShape checks the fields and result_encoding parameters, which keep large
+// results within the limits of their consumers. fields names the top level fields of the
+// result to answer with, and result_encoding set to gzip+base64 compresses the result.
+// Both only apply to JSON results; the payload schema checks their values.
+func validateResultShape(payload *TaskPayload) error {
+	format, _ := resultFormat(payload)
+	for _, key := range []string{"fields", "result_encoding"} {
+		if _, present := payload.Parameters[key]; present && format != ResultFormatJSON {
+			return fmt.Errorf("%s is only supported for %s results", key, ResultFormatJSON)
+		}
+	}
+	return nil
+}
+
+// checkResultSize refuses encoded results over the limit of their task type
+func (yip *YieldIntelligencePerformer) checkResultSize(payload *TaskPayload, encoded []byte) error {
+	if err := yip.resultLimits.Check(string(payload.Type), len(encoded)); err != nil {
+		return newTaskError(ErrorCodeResultTooLarge, err)
+	}
+	return nil
+}
+
+// selectFields returns the fields of result the task selects, with its status so
+// consumers still tell results they must not act on, and commits to them in place of the
+// whole result. Fields the result does not have are left out.
+func selectFields(payload *TaskPayload, result interface{}, committed *ResultCommitment) (interface{}, error) {
+	fields := paramStringList(payload, "fields")
+	if len(fields) == 0 {
+		return result, nil
+	}
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	// numbers are kept as they were encoded, so the result stays canonical
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var whole map[string]interface{}
+	if err := decoder.Decode(&whole); err != nil {
+		return nil, err
+	}
+	selected := make(map[string]interface{}, len(fields)+1)
+	for _, field := range append(fields, "status") {
+		if value, ok := whole[field]; ok {
+			selected[field] = value
+		}
+	}
+	if committed.ResultHash, err = commitment.ResultHash(selected); err != nil {
+		return nil, err
+	}
+	return selected, nil
+}
+
+// compressResult replaces the result of an encoded envelope with its canonical JSON
+// compressed as the task's result_encoding asks, declaring the encoding in the envelope.
+// The commitment still covers the result as it decompresses.
+func compressResult(payload *TaskPayload, encoded []byte) ([]byte, error) {
+	if paramString(payload, "result_encoding") != resultsize.EncodingGzipBase64 {
+		return encoded, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var envelope map[string]interface{}
+	if err := decoder.Decode(&envelope); err != nil {
+		return nil, fmt.Errorf("failed to decode %s result: %w", payload.Type, err)
+	}
+	result, err := canonical.Marshal(envelope["result"])
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s result: %w", payload.Type, err)
+	}
+	if envelope["result"], err = resultsize.Compress(result); err != nil {
+		return nil, fmt.Errorf("failed to encode %s result: %w", payload.Type, err)
+	}
+	envelope["encoding"] = resultsize.EncodingGzipBase64
+	return canonical.Marshal(envelope)
+}
@@ -0,0 +1,102 @@
+package performer
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/resultsize"
+	"go.uber.org/zap"
+)
+
+const sizedMonitoring = `{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1`
+
+func handleSized(t *testing.T, performer *YieldIntelligencePerformer, id, parameters string) ([]byte, error) {
+	t.Helper()
+	task := &performerV1.TaskRequest{TaskId: []byte(id), Payload: []byte(sizedMonitoring + parameters + `}}`)}
+	if err := performer.ValidateTask(task); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	resp, err := performer.HandleTask(task)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Result, nil
+}
+
+func Test_ResultFieldsAndCompression(t *testing.T) {
+	performer := NewYieldIntelligencePerformer(zap.NewNop(), WithAdapters(adapters.NewRegistry(newFakeAaveAdapter())))
+
+	var envelope struct {
+		Result     json.RawMessage   `json:"result"`
+		Encoding   string            `json:"encoding"`
+		Commitment *ResultCommitment `json:"commitment"`
+	}
+	whole, err := handleSized(t, performer, "whole", "")
+	if err != nil || json.Unmarshal(whole, &envelope) != nil || envelope.Encoding != "" {
+		t.Fatalf("Expected an uncompressed result, got %s: %v", whole, err)
+	}
+
+	selected, err := handleSized(t, performer, "selected", `,"fields":["supply_rate","block_number","unknown"]`)
+	if err != nil || json.Unmarshal(selected, &envelope) != nil {
+		t.Fatalf("Expected a result, got %s: %v", selected, err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(envelope.Result, &fields); err != nil || len(fields) != 2 || fields["supply_rate"] == nil || fields["status"] == nil {
+		t.Errorf("Expected the supply rate and status only, got %s", envelope.Result)
+	}
+	if envelope.Commitment.ResultHash != crypto.Keccak256Hash(envelope.Result) {
+		t.Errorf("Expected the commitment to cover the selected fields")
+	}
+
+	compressed, err := handleSized(t, performer, "compressed", `,"result_encoding":"gzip+base64"`)
+	if err != nil || json.Unmarshal(compressed, &envelope) != nil || envelope.Encoding != resultsize.EncodingGzipBase64 {
+		t.Fatalf("Expected a compressed result, got %s: %v", compressed, err)
+	}
+	var encoded string
+	if err := json.Unmarshal(envelope.Result, &encoded); err != nil {
+		t.Fatalf("Expected the result carried as a string, got %s", envelope.Result)
+	}
+	decompressed, err := resultsize.Decompress(encoded)
+	if err != nil || envelope.Commitment.ResultHash != crypto.Keccak256Hash(decompressed) {
+		t.Errorf("Expected the result and its commitment back, got %s: %v", decompressed, err)
+	}
+}
+
+func Test_ResultSizeLimit(t *testing.T) {
+	performer := NewYieldIntelligencePerformer(zap.NewNop(), WithAdapters(adapters.NewRegistry(newFakeAaveAdapter())),
+		WithResultLimits(resultsize.Config{MaxBytes: 1 << 20, Limits: map[string]int{"yield_monitoring": 600}}))
+
+	_, err := handleSized(t, performer, "too large", "")
+	var taskErr *TaskError
+	if !errors.As(err, &taskErr) || taskErr.Code != ErrorCodeResultTooLarge || taskErr.Code.Retryable() {
+		t.Fatalf("Expected the result rejected as too large, got %v", err)
+	}
+	if result, err := handleSized(t, performer, "selected", `,"fields":["supply_rate"]`); err != nil || len(result) > 600 {
+		t.Errorf("Expected selected fields within the limit, got %d bytes: %v", len(result), err)
+	}
+}
+
+func Test_ResultShapeValidation(t *testing.T) {
+	performer := NewYieldIntelligencePerformer(zap.NewNop(), WithAdapters(adapters.NewRegistry(newFakeAaveAdapter())))
+
+	testCases := map[string]string{
+		"fields not an array": sizedMonitoring + `,"fields":"supply_rate"}}`,
+		"no fields":           sizedMonitoring + `,"fields":[]}}`,
+		"empty field":         sizedMonitoring + `,"fields":[""]}}`,
+		"duplicate field":     sizedMonitoring + `,"fields":["status","status"]}}`,
+		"abi fields":          sizedMonitoring + `,"fields":["supply_rate"],"result_format":"abi"}}`,
+		"unknown encoding":    sizedMonitoring + `,"result_encoding":"brotli"}}`,
+		"abi encoding":        sizedMonitoring + `,"result_encoding":"gzip+base64","result_format":"abi"}}`,
+		"batch sub-task":      `{"type":"batch","parameters":{"tasks":[` + sizedMonitoring + `,"result_encoding":"gzip+base64"}}]}}`,
+	}
+	for name, payload := range testCases {
+		task := &performerV1.TaskRequest{TaskId: []byte(name), Payload: []byte(payload)}
+		if err := performer.ValidateTask(task); err == nil {
+			t.Errorf("%s: expected validation to fail", name)
+		}
+	}
+}
@@ -0,0 +1,478 @@
+package performer
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/abicodec"
+	"github.com/najnomics/crosscow-avs/pkg/attestation"
+	"github.com/najnomics/crosscow-avs/pkg/bridge"
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/schema"
+	"github.com/najnomics/crosscow-avs/pkg/tokens"
+)
+
+// USDCDecimals is the number of decimals of the USDC token
+const USDCDecimals = 6
+
+// ResultStatus describes the outcome reported inside a task result
+type ResultStatus string
+
+const (
+	ResultStatusCompleted ResultStatus = "completed"
+
+	// ResultStatusAnomalous marks results built on data that failed anomaly checks.
+	// Consumers must not act on them.
+	ResultStatusAnomalous ResultStatus = "anomalous"
+
+	// ResultStatusDisputed marks results whose data sources disagreed. No value is reported.
+	ResultStatusDisputed ResultStatus = "disputed"
+
+	// ResultStatusPartial marks results that cover only some of the requested markets
+	ResultStatusPartial ResultStatus = "partial"
+
+	// ResultStatusSubmitted marks rebalances whose transactions were broadcast but are not
+	// all final yet
+	ResultStatusSubmitted ResultStatus = "submitted"
+
+	// ResultStatusReverted marks rebalances with a transaction that was mined and reverted
+	ResultStatusReverted ResultStatus = "reverted"
+
+	// ResultStatusNotExecuted marks rebalances that were accepted but not executed, as the
+	// performer has no account to submit them from
+	ResultStatusNotExecuted ResultStatus = "not_executed"
+
+	// ResultStatusFailed marks error results, see ErrorResult
+	ResultStatusFailed ResultStatus = "failed"
+)
+
+// ResultFormat selects how a task result is encoded, via the result_format task parameter
+type ResultFormat string
+
+const (
+	// ResultFormatJSON encodes results as canonical JSON wrapped in a TaskResult envelope (default)
+	ResultFormatJSON ResultFormat = "json"
+
+	// ResultFormatABI encodes results as the ABI-encoded Solidity struct the Service Manager
+	// and the yield hook decode on-chain for the task type
+	ResultFormatABI ResultFormat = "abi"
+)
+
+// abiEncodable is implemented by results that have an on-chain representation
+type abiEncodable interface {
+	EncodeABI() ([]byte, error)
+}
+
+// resultFormat returns the requested result format, defaulting to JSON
+func resultFormat(payload *TaskPayload) (ResultFormat, error) {
+	raw, present := payload.Parameters["result_format"]
+	if !present {
+		return ResultFormatJSON, nil
+	}
+	format, ok := raw.(string)
+	if !ok {
+		return "", fmt.Errorf("invalid result_format: must be a string")
+	}
+	switch ResultFormat(format) {
+	case ResultFormatJSON, ResultFormatABI:
+		return ResultFormat(format), nil
+	default:
+		return "", fmt.Errorf("unsupported result_format %q", format)
+	}
+}
+
+// resultSchemaVersion returns the schema version JSON results are encoded in, via the
+// schema_version task parameter. Tasks sent to operators on different releases name the
+// oldest version they all run, defaulting to schema.Current.
+func resultSchemaVersion(payload *TaskPayload) (int, error) {
+	raw, present := payload.Parameters["schema_version"]
+	if !present {
+		return schema.Current, nil
+	}
+	version, ok := raw.(float64)
+	if !ok || version != float64(int(version)) || !schema.Supported(int(version)) {
+		return 0, fmt.Errorf("invalid schema_version: must be an integer from %d to %d", schema.Oldest, schema.Current)
+	}
+	return int(version), nil
+}
+
+// TaskResult is the envelope every task result is encoded in. SchemaVersion is set by
+// the schema codec, and is absent from results of schema version 1.
+type TaskResult struct {
+	TaskType      TaskType    `json:"task_type"`
+	SchemaVersion int         `json:"schema_version,omitempty"`
+	Result        interface{} `json:"result"`
+
+	// Encoding is how Result is encoded when it is not JSON, set on results of tasks
+	// setting the result_encoding parameter
+	Encoding string `json:"encoding,omitempty"`
+
+	// Commitment commits to Result, absent from results before schema version 6
+	Commitment *ResultCommitment `json:"commitment,omitempty"`
+
+	// Trace is set on results of tasks setting the include_trace parameter
+	Trace *ResultTrace `json:"trace,omitempty"`
+
+	// Attestation is set on results of tasks setting the attest parameter
+	Attestation *attestation.Attestation `json:"attestation,omitempty"`
+}
+
+// YieldMonitoringResult is the result of a yield_monitoring task. The supply rate is an
+// annual percentage and the total supply is in USDC. The supply rate is null when the
+// rate sources disputed each other.
+type YieldMonitoringResult struct {
+	Protocol        string                 `json:"protocol"`
+	Token           string                 `json:"token"`
+	ChainID         uint64                 `json:"chain_id"`
+	SupplyRate      *canonical.Decimal     `json:"supply_rate"`
+	Utilization     canonical.Decimal      `json:"utilization"`
+	TotalSupply     canonical.Decimal      `json:"total_supply"`
+	CrossValidation *CrossValidationReport `json:"cross_validation,omitempty"`
+	Anomaly         *AnomalyReport         `json:"anomaly"`
+
+	// Stability measures the rate history of the market, omitted when the rate is not
+	// reported
+	Stability *StabilityReport `json:"stability,omitempty"`
+
+	// BlockNumber is the reference block the market was read at, omitted when snapshots
+	// are disabled and the market was read at the latest block
+	BlockNumber uint64 `json:"block_number,omitempty"`
+
+	// Incentives are the reward emissions paid on top of the supply rate, omitted for
+	// protocols without them or when they could not be read
+	Incentives *IncentiveReport `json:"incentives,omitempty"`
+
+	// SupplyCap is omitted for protocols that do not report risk data or when it could
+	// not be read
+	SupplyCap *SupplyCapReport `json:"supply_cap,omitempty"`
+
+	// RateModel projects the supply rate after deposit_amount is added, omitted for
+	// markets of unknown rate models
+	RateModel *RateModelReport `json:"rate_model,omitempty"`
+	Status    ResultStatus     `json:"status"`
+}
+
+// CrossChainYieldResult is the result of a cross_chain_yield_check task. Rates are the
+// best supply rates on each chain as annual percentages, and are null when no market on
+// the chain could be read. The target market is the one earning the most once the amount
+// is deposited into it, which TargetProjectedRate is, and ProjectedImprovementBps
+// compares that with the source rate.
+type CrossChainYieldResult struct {
+	SourceChain             uint64             `json:"source_chain"`
+	TargetChain             uint64             `json:"target_chain"`
+	Amount                  canonical.Decimal  `json:"amount"`
+	SourceRate              *canonical.Decimal `json:"source_rate"`
+	TargetRate              *canonical.Decimal `json:"target_rate"`
+	TargetProjectedRate     *canonical.Decimal `json:"target_projected_rate"`
+	TargetProtocol          string             `json:"target_protocol"`
+	ImprovementBps          *canonical.Decimal `json:"improvement_bps"`
+	ProjectedImprovementBps *canonical.Decimal `json:"projected_improvement_bps"`
+	Markets                 []ChainMarketRate  `json:"markets"`
+	Failures                []MarketFailure    `json:"failures"`
+
+	// PolicyRejections are the rules target markets break, which keeps them from being
+	// recommended. Only reported when the operator configures a policy or the task
+	// carries a profile.
+	PolicyRejections []PolicyViolation `json:"policy_rejections,omitempty"`
+
+	// Routes are the bridges moving the amount from the source to the target chain, best
+	// first, and Route is the best of them the profile waits for. Route is null when no
+	// known bridge connects the chains, or none is fast enough.
+	Route  *BridgeRoute  `json:"route"`
+	Routes []BridgeRoute `json:"routes"`
+
+	// Hysteresis tells whether moving to the target is held back, only reported when the
+	// operator configures hysteresis
+	Hysteresis *HysteresisReport `json:"hysteresis,omitempty"`
+
+	// Sequencers is the health of the sequencers of the source and target chains that
+	// are L2s. No target market is recommended while the target sequencer is blocked or
+	// cannot be read. Only reported when the operator checks sequencers.
+	Sequencers []SequencerReport `json:"sequencers,omitempty"`
+	Status     ResultStatus      `json:"status"`
+}
+
+// BridgeRoute is a bridge moving USDC between two chains. Scores run from 0 (no concern)
+// to 100; Score is the weighted mean of the fee, latency and trust scores.
+type BridgeRoute struct {
+	Bridge string       `json:"bridge"`
+	Trust  bridge.Trust `json:"trust"`
+
+	// Token is the USDC received on the target chain, native or a bridged variant
+	Token          tokens.Kind       `json:"token"`
+	FeeBps         uint64            `json:"fee_bps"`
+	Fee            canonical.Decimal `json:"fee"`
+	LatencySeconds uint64            `json:"latency_seconds"`
+	FeeScore       canonical.Decimal `json:"fee_score"`
+	LatencyScore   canonical.Decimal `json:"latency_score"`
+	TrustScore     canonical.Decimal `json:"trust_score"`
+	Score          canonical.Decimal `json:"score"`
+
+	// TransitYieldLost is the yield the amount would earn at the projected rate of the
+	// target meanwhile, in USDC, only set when a target is recommended
+	TransitYieldLost *canonical.Decimal `json:"transit_yield_lost,omitempty"`
+}
+
+// ChainMarketRate is the supply rate of a protocol on a chain, as an annual percentage
+type ChainMarketRate struct {
+	Protocol   string            `json:"protocol"`
+	ChainID    uint64            `json:"chain_id"`
+	SupplyRate canonical.Decimal `json:"supply_rate"`
+
+	// ProjectedRate is the supply rate of a target chain market once the amount is
+	// deposited
+	ProjectedRate *canonical.Decimal `json:"projected_rate,omitempty"`
+}
+
+// RebalanceExecutionResult is the result of a rebalance_execution task
+type RebalanceExecutionResult struct {
+	UserAddress    string            `json:"user_address"`
+	SourceProtocol string            `json:"source_protocol,omitempty"`
+	SourceChain    uint64            `json:"source_chain,omitempty"`
+	TargetProtocol string            `json:"target_protocol"`
+	TargetChain    uint64            `json:"target_chain,omitempty"`
+	Amount         canonical.Decimal `json:"amount"`
+
+	// RequestedAmount is the amount asked for when resize_to_cap lowered it to the supply
+	// cap headroom of the target market
+	RequestedAmount *canonical.Decimal `json:"requested_amount,omitempty"`
+	SupplyCap       *SupplyCapReport   `json:"supply_cap,omitempty"`
+
+	// WithdrawalLiquidity is set for rebalances out of a source market
+	WithdrawalLiquidity *WithdrawalLiquidity `json:"withdrawal_liquidity,omitempty"`
+
+	// BridgeFallback is set when the rebalance bridges through the fallback bridge
+	// because CCTP attestations are delayed
+	BridgeFallback *BridgeFallback `json:"bridge_fallback,omitempty"`
+
+	// GasTiming is set when a rebalance that is not urgent was timed into a low gas window
+	GasTiming *GasTiming `json:"gas_timing,omitempty"`
+
+	// DryRun results only carry a simulation; no funds were moved
+	DryRun     bool                 `json:"dry_run"`
+	Simulation *RebalanceSimulation `json:"simulation,omitempty"`
+
+	// Execution is set when the transactions were submitted from the performer's account
+	Execution *RebalanceExecution `json:"execution,omitempty"`
+
+	// PlanHash is the plan a rebalance_commit task executed
+	PlanHash string `json:"plan_hash,omitempty"`
+
+	Status ResultStatus `json:"status"`
+}
+
+// RiskAssessmentResult is the result of a risk_assessment task
+type RiskAssessmentResult struct {
+	Protocol       string       `json:"protocol"`
+	ChainID        uint64       `json:"chain_id"`
+	AssessmentType string       `json:"assessment_type"`
+	Report         *RiskReport  `json:"report,omitempty"`
+	Status         ResultStatus `json:"status"`
+
+	// BlockNumber is the reference block the market was read at, omitted when snapshots
+	// are disabled and the market was read at the latest block
+	BlockNumber uint64 `json:"block_number,omitempty"`
+}
+
+// percentBasisPoints converts an optional percentage into basis points, zero when unset
+func percentBasisPoints(percent *canonical.Decimal) *big.Int {
+	if percent == nil {
+		return new(big.Int)
+	}
+	return abicodec.PercentToBasisPoints(*percent)
+}
+
+// EncodeABI encodes the result as IYieldIntelligenceAVS.YieldData
+func (r *YieldMonitoringResult) EncodeABI() ([]byte, error) {
+	return abicodec.EncodeYieldData(&abicodec.YieldData{
+		ProtocolId:   abicodec.ProtocolId(r.Protocol),
+		ChainId:      new(big.Int).SetUint64(r.ChainID),
+		CurrentYield: percentBasisPoints(r.SupplyRate),
+		Tvl:          abicodec.AmountToBaseUnits(r.TotalSupply, USDCDecimals),
+		Utilization:  abicodec.RatioToBasisPoints(r.Utilization),
+		RiskScore:    new(big.Int),
+		Confidence:   new(big.Int),
+		Timestamp:    new(big.Int),
+		IsValid:      r.Status == ResultStatusCompleted,
+	})
+}
+
+// EncodeABI encodes the result as IYieldIntelligenceAVS.YieldOpportunity on the target chain
+func (r *CrossChainYieldResult) EncodeABI() ([]byte, error) {
+	var protocolID [32]byte
+	if r.TargetProtocol != "" {
+		protocolID = abicodec.ProtocolId(r.TargetProtocol)
+	}
+	projected, current := percentBasisPoints(r.TargetRate), percentBasisPoints(r.SourceRate)
+	improvement := new(big.Int).Sub(projected, current)
+	if improvement.Sign() < 0 {
+		improvement.SetInt64(0)
+	}
+	return abicodec.EncodeYieldOpportunity(&abicodec.YieldOpportunity{
+		ProtocolId:     protocolID,
+		ChainId:        new(big.Int).SetUint64(r.TargetChain),
+		ProjectedYield: projected,
+		CurrentYield:   current,
+		Improvement:    improvement,
+		Confidence:     new(big.Int),
+		MaxAmount:      abicodec.AmountToBaseUnits(r.Amount, USDCDecimals),
+		ExpiresAt:      new(big.Int),
+		AdditionalData: []byte{},
+	})
+}
+
+// EncodeABI encodes the result as IYieldIntelligenceAVS.RebalanceInstruction
+func (r *RebalanceExecutionResult) EncodeABI() ([]byte, error) {
+	if !common.IsHexAddress(r.UserAddress) {
+		return nil, fmt.Errorf("user_address %q is not a valid address", r.UserAddress)
+	}
+	instruction := &abicodec.RebalanceInstruction{
+		User:             common.HexToAddress(r.UserAddress),
+		TargetProtocolId: abicodec.ProtocolId(r.TargetProtocol),
+		SourceChainId:    new(big.Int).SetUint64(r.SourceChain),
+		TargetChainId:    new(big.Int).SetUint64(r.TargetChain),
+		Amount:           abicodec.AmountToBaseUnits(r.Amount, USDCDecimals),
+		Executed:         r.executed(),
+	}
+	if r.SourceProtocol != "" {
+		instruction.SourceProtocolId = abicodec.ProtocolId(r.SourceProtocol)
+	}
+	return abicodec.EncodeRebalanceInstruction(instruction)
+}
+
+// executed reports whether the rebalance completed with every transaction it submitted
+// confirmed
+func (r *RebalanceExecutionResult) executed() bool {
+	if r.DryRun || r.Status != ResultStatusCompleted || r.Execution == nil || len(r.Execution.Transactions) == 0 {
+		return false
+	}
+	for _, tx := range r.Execution.Transactions {
+		if tx.Status != TransactionConfirmed {
+			return false
+		}
+	}
+	return true
+}
+
+// EncodeABI encodes the result as IYieldIntelligenceAVS.RiskMetrics
+func (r *RiskAssessmentResult) EncodeABI() ([]byte, error) {
+	metrics := &abicodec.RiskMetrics{
+		ProtocolRisk:      new(big.Int),
+		LiquidityRisk:     new(big.Int),
+		SmartContractRisk: new(big.Int),
+		GovernanceRisk:    new(big.Int),
+		OverallRisk:       new(big.Int),
+		RiskCategory:      r.AssessmentType,
+	}
+	if r.Report != nil {
+		// scores are 0-100, the contract expects 0-10000
+		metrics.ProtocolRisk = percentBasisPoints(r.Report.SolvencyScore)
+		metrics.LiquidityRisk = percentBasisPoints(r.Report.LiquidityScore)
+		metrics.GovernanceRisk = percentBasisPoints(r.Report.AdminScore)
+		metrics.SmartContractRisk = percentBasisPoints(r.Report.SecurityScore)
+		metrics.OverallRisk = abicodec.PercentToBasisPoints(r.Report.OverallScore)
+	}
+	return abicodec.EncodeRiskMetrics(metrics)
+}
+
+// encodeResult encodes a handler result in the format requested by the task: canonical JSON
+// wrapped in the result envelope of the requested schema version with its commitment and
+// the calls of trace when one was kept, or the ABI-encoded struct for on-chain consumption.
+// JSON results other than error results are cut down to the fields the task selects and
+// compressed when it asks for it, and encoded results must fit the limit of their task
+// type.
+func (yip *YieldIntelligencePerformer) encodeResult(payload *TaskPayload, result interface{}, trace *chain.CallTrace) ([]byte, error) {
+	format, err := resultFormat(payload)
+	if err != nil {
+		return nil, err
+	}
+	if format == ResultFormatABI {
+		encodable, ok := result.(abiEncodable)
+		if !ok {
+			return nil, fmt.Errorf("%s results cannot be ABI encoded", payload.Type)
+		}
+		encoded, err := encodable.EncodeABI()
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s result: %w", payload.Type, err)
+		}
+		return encoded, yip.checkResultSize(payload, encoded)
+	}
+
+	version, err := resultSchemaVersion(payload)
+	if err != nil {
+		return nil, err
+	}
+	committed, err := commitResult(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit to %s result: %w", payload.Type, err)
+	}
+	// error results are small, and answered whole so every consumer tells them apart
+	_, failed := result.(*ErrorResult)
+	if !failed {
+		if result, err = selectFields(payload, result, committed); err != nil {
+			return nil, fmt.Errorf("failed to select fields of %s result: %w", payload.Type, err)
+		}
+	}
+	encoded, err := schema.Encode(&TaskResult{
+		TaskType:   payload.Type,
+		Result:     result,
+		Commitment: committed,
+		Trace:      resultTrace(trace),
+	}, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s result: %w", payload.Type, err)
+	}
+	if !failed {
+		if encoded, err = compressResult(payload, encoded); err != nil {
+			return nil, err
+		}
+	}
+	return encoded, yip.checkResultSize(payload, encoded)
+}
+
+// paramString returns a string parameter or "" when missing
+func paramString(payload *TaskPayload, key string) string {
+	value, _ := payload.Parameters[key].(string)
+	return value
+}
+
+// paramUint64 returns a positive integral parameter or 0 when missing
+func paramUint64(payload *TaskPayload, key string) uint64 {
+	value, ok := payload.Parameters[key].(float64)
+	if !ok || value <= 0 {
+		return 0
+	}
+	return uint64(value)
+}
+
+// paramBaseUnits returns a USDC amount parameter in base units, nil when missing or
+// invalid
+func paramBaseUnits(payload *TaskPayload, key string) *big.Int {
+	value, _ := payload.Parameters[key].(string)
+	amount, err := tokens.ParseAmount(value)
+	if err != nil {
+		return nil
+	}
+	return amount
+}
+
+// paramAmount returns a USDC amount parameter as a decimal of USDC, zero when missing
+func paramAmount(payload *TaskPayload, key string) canonical.Decimal {
+	amount := paramBaseUnits(payload, key)
+	if amount == nil {
+		amount = new(big.Int)
+	}
+	return usdcAmount(amount)
+}
+
+// checkAmount validates a USDC amount parameter, a positive decimal string of base units
+func checkAmount(raw interface{}) error {
+	value, ok := raw.(string)
+	if !ok {
+		return fmt.Errorf("must be a decimal string of USDC base units")
+	}
+	_, err := tokens.ParsePositiveAmount(value)
+	return err
+}
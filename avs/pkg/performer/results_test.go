@@ -0,0 +1,256 @@
+package performer
+
+import (
+	"bytes"
+	"flag"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/najnomics/crosscow-avs/pkg/abicodec"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/schema"
+	"go.uber.org/zap"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden result files")
+
+func checkGoldenResult(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if *updateGolden {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("Failed to update golden file: %v", err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read golden file: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("Result does not match %s\n got: %s\nwant: %s", path, got, want)
+	}
+}
+
+func Test_HandlerResultsAreCanonical(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		payloads []string
+	}{
+		{
+			name: "yield_monitoring",
+			payloads: []string{
+				`{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1}}`,
+				`{"parameters":{"chain_id":1.0,"token":"USDC","protocol":"aave_v3"},"type":"yield_monitoring"}`,
+			},
+		},
+		{
+			name: "cross_chain_yield_check",
+			payloads: []string{
+				`{"type":"cross_chain_yield_check","parameters":{"source_chain":1,"target_chain":8453,"amount":"1000500000"}}`,
+				`{"type":"cross_chain_yield_check","parameters":{"amount":"1000500000","target_chain":8453,"source_chain":1}}`,
+			},
+		},
+		{
+			name: "rebalance_execution",
+			payloads: []string{
+				`{"type":"rebalance_execution","parameters":{"user_address":"0xabc","amount":"250000000000","target_protocol":"compound_v3"}}`,
+			},
+		},
+		{
+			name: "risk_assessment",
+			payloads: []string{
+				`{"type":"risk_assessment","parameters":{"protocol":"aave_v3","chain_id":1,"assessment_type":"full"}}`,
+			},
+		},
+		{
+			name: "yield_ranking",
+			payloads: []string{
+				`{"type":"yield_ranking","parameters":{"token":"USDC","risk_free_rate":4}}`,
+				`{"type":"yield_ranking","parameters":{"risk_free_rate":4.0,"token":"USDC"}}`,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for i, payload := range tc.payloads {
+				// A fresh performer per payload so results are not served from the task store
+				performer := NewYieldIntelligencePerformer(logger, WithAdapters(adapters.NewRegistry(newFakeAaveAdapter())))
+				resp, err := performer.HandleTask(&performerV1.TaskRequest{
+					TaskId:  []byte("golden-" + tc.name),
+					Payload: []byte(payload),
+				})
+				if err != nil {
+					t.Fatalf("HandleTask failed for payload %d: %v", i, err)
+				}
+				checkGoldenResult(t, tc.name, resp.Result)
+			}
+		})
+	}
+}
+
+func Test_ABIResultFormat(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+	performer := NewYieldIntelligencePerformer(logger)
+
+	task := &performerV1.TaskRequest{
+		TaskId:  []byte("abi-task"),
+		Payload: []byte(`{"type":"cross_chain_yield_check","parameters":{"source_chain":1,"target_chain":8453,"amount":"1000500000","result_format":"abi"}}`),
+	}
+	if err := performer.ValidateTask(task); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	resp, err := performer.HandleTask(task)
+	if err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+
+	opportunity, err := abicodec.DecodeYieldOpportunity(resp.Result)
+	if err != nil {
+		t.Fatalf("Result is not an ABI-encoded YieldOpportunity: %v", err)
+	}
+	if opportunity.ChainId.Uint64() != 8453 || opportunity.MaxAmount.Int64() != 1_000_500_000 {
+		t.Errorf("Unexpected opportunity: %+v", opportunity)
+	}
+}
+
+func Test_RebalanceInstructionABI(t *testing.T) {
+	performer := NewYieldIntelligencePerformer(zap.NewNop())
+
+	// without an account of its own the performer sends nothing
+	resp, err := performer.HandleTask(&performerV1.TaskRequest{
+		TaskId: []byte("abi-rebalance"),
+		Payload: []byte(`{"type":"rebalance_execution","parameters":{"user_address":"0x00000000000000000000000000000000000000aa","amount":"1000000000",
+			"source_protocol":"compound_v3","source_chain":1,"target_protocol":"aave_v3","target_chain":8453,"result_format":"abi"}}`),
+	})
+	if err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+	instruction, err := abicodec.DecodeRebalanceInstruction(resp.Result)
+	if err != nil {
+		t.Fatalf("Result is not an ABI-encoded RebalanceInstruction: %v", err)
+	}
+	if instruction.Executed || instruction.SourceProtocolId != abicodec.ProtocolId("compound_v3") || instruction.TargetProtocolId != abicodec.ProtocolId("aave_v3") ||
+		instruction.SourceChainId.Uint64() != 1 || instruction.TargetChainId.Uint64() != 8453 {
+		t.Errorf("Expected the route of the payload and nothing executed, got %+v", instruction)
+	}
+
+	confirmed := func() *RebalanceExecutionResult {
+		return &RebalanceExecutionResult{
+			UserAddress:    "0x00000000000000000000000000000000000000aa",
+			TargetProtocol: "aave_v3",
+			TargetChain:    1,
+			SourceChain:    1,
+			Amount:         usdcAmount(big.NewInt(1_000_000_000)),
+			Status:         ResultStatusCompleted,
+			Execution: &RebalanceExecution{Transactions: []SubmittedTransaction{
+				{Action: ActionApprove, Status: TransactionConfirmed},
+				{Action: ActionDeposit, Status: TransactionConfirmed},
+			}},
+		}
+	}
+	for name, tc := range map[string]struct {
+		update   func(*RebalanceExecutionResult)
+		executed bool
+	}{
+		"confirmed":       {update: func(*RebalanceExecutionResult) {}, executed: true},
+		"pending":         {update: func(r *RebalanceExecutionResult) { r.Execution.Transactions[1].Status = TransactionPending }},
+		"no transactions": {update: func(r *RebalanceExecutionResult) { r.Execution.Transactions = nil }},
+		"dry run":         {update: func(r *RebalanceExecutionResult) { r.DryRun = true }},
+	} {
+		result := confirmed()
+		tc.update(result)
+		encoded, err := result.EncodeABI()
+		if err != nil {
+			t.Fatalf("%s: EncodeABI failed: %v", name, err)
+		}
+		instruction, err := abicodec.DecodeRebalanceInstruction(encoded)
+		if err != nil || instruction.Executed != tc.executed {
+			t.Errorf("%s: expected executed %v, got %+v (%v)", name, tc.executed, instruction, err)
+		}
+	}
+}
+
+func Test_UnsupportedResultFormatIsRejected(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+	performer := NewYieldIntelligencePerformer(logger)
+
+	task := &performerV1.TaskRequest{
+		TaskId:  []byte("xml-task"),
+		Payload: []byte(`{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1,"result_format":"xml"}}`),
+	}
+	if err := performer.ValidateTask(task); err == nil {
+		t.Errorf("Expected result_format xml to be rejected")
+	}
+}
+
+func Test_PreviousSchemaVersion(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+	performer := NewYieldIntelligencePerformer(logger, WithAdapters(adapters.NewRegistry(newFakeAaveAdapter())))
+
+	// operators that predate schema versions encode results as schema version 1
+	task := &performerV1.TaskRequest{
+		TaskId:  []byte("schema-v1"),
+		Payload: []byte(`{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1,"schema_version":1}}`),
+	}
+	if err := performer.ValidateTask(task); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	resp, err := performer.HandleTask(task)
+	if err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+	checkGoldenResult(t, "yield_monitoring.v1", resp.Result)
+	if version, err := schema.VersionOf(resp.Result); err != nil || version != schema.Version1 {
+		t.Errorf("Expected schema version 1, got %d, %v", version, err)
+	}
+
+	// schema version 2 predates bridge routes
+	task = &performerV1.TaskRequest{
+		TaskId:  []byte("schema-v2"),
+		Payload: []byte(`{"type":"cross_chain_yield_check","parameters":{"source_chain":1,"target_chain":8453,"amount":"1000500000","schema_version":2}}`),
+	}
+	resp, err = performer.HandleTask(task)
+	if err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+	checkGoldenResult(t, "cross_chain_yield_check.v2", resp.Result)
+
+	// schema version 3 predates the stability of supply rates
+	task = &performerV1.TaskRequest{
+		TaskId:  []byte("schema-v3"),
+		Payload: []byte(`{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1,"schema_version":3}}`),
+	}
+	resp, err = performer.HandleTask(task)
+	if err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+	checkGoldenResult(t, "yield_monitoring.v3", resp.Result)
+
+	for _, version := range []string{"0", "7", "1.5", `"1"`} {
+		task := &performerV1.TaskRequest{
+			TaskId:  []byte("schema-" + version),
+			Payload: []byte(`{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1,"schema_version":` + version + `}}`),
+		}
+		if err := performer.ValidateTask(task); err == nil {
+			t.Errorf("Expected schema_version %s to be rejected", version)
+		}
+	}
+}
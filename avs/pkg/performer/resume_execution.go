@@ -0,0 +1,350 @@
+package performer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"sync"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/najnomics/crosscow-avs/pkg/cctp"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/permit"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+)
+
+const prefixExecution = "execution:"
+
+// Statuses of the legs of a rebalance
+const (
+	LegCompleted = "completed"
+	LegSubmitted = "submitted"
+	LegPending   = "pending"
+	LegFailed    = "failed"
+)
+
+// legStatusRanks orders leg statuses, a leg taking the highest status of its steps
+var legStatusRanks = map[string]int{LegCompleted: 0, LegSubmitted: 1, LegPending: 2, LegFailed: 3}
+
+// transactionLegStatuses maps the status of a transaction to that of its leg
+var transactionLegStatuses = map[string]string{
+	TransactionConfirmed: LegCompleted,
+	TransactionPending:   LegSubmitted,
+	TransactionReverted:  LegFailed,
+}
+
+// ExecutionLeg is a leg of a rebalance: the transaction moving funds one hop along the
+// route, such as the withdrawal, the burn, the mint or the deposit, with the approval it
+// needs. Legs are completed once final, submitted while waiting to be, pending until
+// every step was sent and failed when a transaction reverted.
+type ExecutionLeg struct {
+	Leg     string `json:"leg"`
+	ChainID uint64 `json:"chain_id"`
+	Status  string `json:"status"`
+}
+
+// executionLegs groups the steps of execution into legs, and returns them with the index
+// of the first step of each
+func executionLegs(execution *RebalanceExecution) ([]ExecutionLeg, []int) {
+	legs, starts := []ExecutionLeg{}, []int{}
+	open := true
+	add := func(i int, action string, chainID uint64, status string) {
+		if open {
+			legs = append(legs, ExecutionLeg{Status: LegCompleted})
+			starts = append(starts, i)
+		}
+		leg := &legs[len(legs)-1]
+		leg.Leg, leg.ChainID = action, chainID
+		if legStatusRanks[status] > legStatusRanks[leg.Status] {
+			leg.Status = status
+		}
+		// approvals and permits belong to the leg of the step spending them
+		open = action != ActionApprove && action != ActionPermit
+	}
+	for i, tx := range execution.Transactions {
+		add(i, tx.Action, tx.ChainID, transactionLegStatuses[tx.Status])
+	}
+	for i, step := range execution.PendingSteps {
+		add(len(execution.Transactions)+i, step.Action, step.ChainID, LegPending)
+	}
+	return legs, starts
+}
+
+// resumePoint returns the step resuming execution submits first: the first step of the
+// first leg that failed, or else the first step not sent
+func resumePoint(execution *RebalanceExecution) int {
+	legs, starts := executionLegs(execution)
+	for i, leg := range legs {
+		if leg.Status == LegFailed {
+			return starts[i]
+		}
+	}
+	return len(execution.Transactions)
+}
+
+// executionID is the id of the execution of task t
+func executionID(t *performerV1.TaskRequest) string {
+	return hexutil.Encode(t.TaskId)
+}
+
+// handleResumeExecution carries on the rebalance execution_id names. Transactions not yet
+// final are waited for again, legs that failed are submitted again from their first
+// step, and steps waiting for bridged funds are submitted once they arrived: CCTP mints
+// once Circle attested the burn, and Across deposits once the relayer filled them.
+// Completed legs are never submitted again.
+func (yip *YieldIntelligencePerformer) handleResumeExecution(ctx context.Context, t *performerV1.TaskRequest, payload *TaskPayload) (interface{}, error) {
+	yip.log(ctx).Sugar().Infow("Processing resume execution task")
+
+	if err := yip.checkStanding(ctx); err != nil {
+		return nil, err
+	}
+	if err := yip.checkHalt(ctx); err != nil {
+		return nil, err
+	}
+	id := paramString(payload, "execution_id")
+	ctx, unlock, err := yip.lockExecution(ctx, prefixExecution+id)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	yip.executions.mu.Lock()
+	defer yip.executions.mu.Unlock()
+
+	record, err := yip.executions.get(ctx, id)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, newTaskError(ErrorCodeValidation, fmt.Errorf("unknown execution %s: executions are only resumed by the performers that started them", id))
+	}
+	if err != nil {
+		return nil, err
+	}
+	route, status, err := yip.resume(ctx, record)
+	if err != nil {
+		return nil, err
+	}
+	return &RebalanceExecutionResult{
+		UserAddress:    route.user.Hex(),
+		SourceProtocol: route.sourceProtocol,
+		SourceChain:    route.sourceChain,
+		TargetProtocol: route.targetProtocol,
+		TargetChain:    route.targetChain,
+		Amount:         usdcAmount(route.amount),
+		BridgeFallback: route.fallback,
+		Execution:      record.Execution,
+		Status:         status,
+	}, nil
+}
+
+// resume carries on the execution of record from the first leg that failed, or else the
+// first step not sent, and returns its route and status. The caller holds the lock of
+// the execution.
+func (yip *YieldIntelligencePerformer) resume(ctx context.Context, record *executionRecord) (*rebalanceRoute, ResultStatus, error) {
+	route := record.Route.route()
+	steps, err := yip.planRebalance(route)
+	if err != nil {
+		return nil, "", err
+	}
+	sent, err := record.sent()
+	if err != nil {
+		return nil, "", err
+	}
+
+	execution := record.Execution
+	yip.confirmRebalance(ctx, route, execution, sent)
+	from := resumePoint(execution)
+	execution.Transactions, execution.PendingSteps = execution.Transactions[:from], []PendingStep{}
+	// user operations leave no transactions of their own in sent
+	status, err := yip.executeRebalance(ctx, route, steps, record, sent[:min(from, len(sent))], from)
+	if err != nil {
+		return nil, "", err
+	}
+	return route, status, nil
+}
+
+// bridgedFunds reports whether the funds route bridges arrived on the target chain, so
+// the steps spending them can be submitted. CCTP transfers arrive once Circle attested
+// the burn, returning the mint call carrying the attestation; Across deposits once the
+// relayer credited the wallet.
+func (yip *YieldIntelligencePerformer) bridgedFunds(ctx context.Context, route *rebalanceRoute, record *executionRecord) (*chain.Call, bool, error) {
+	var bridged *SubmittedTransaction
+	for i, tx := range record.Execution.Transactions {
+		if tx.Action == ActionBurn || tx.Action == ActionBridge {
+			bridged = &record.Execution.Transactions[i]
+		}
+	}
+	if bridged == nil || bridged.Status != TransactionConfirmed {
+		return nil, false, nil
+	}
+
+	if route.fallback != nil {
+		wallet := holding{chainID: route.targetChain}
+		balances, err := yip.readHoldings(ctx, route.user, []holding{wallet})
+		if err != nil {
+			return nil, false, err
+		}
+		filled := new(big.Int).Add(record.Before[slices.Index(yip.rebalanceHoldings(route), wallet)], route.received())
+		return nil, balances[0].Cmp(filled) >= 0, nil
+	}
+
+	if yip.attestations == nil {
+		return nil, false, nil
+	}
+	message, err := yip.attestations.Message(ctx, route.sourceChain, common.HexToHash(bridged.Hash))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read the attestation of burn %s: %w", bridged.Hash, err)
+	}
+	if message.Status != cctp.AttestationComplete {
+		return nil, false, nil
+	}
+	mint, err := cctp.ReceiveMessageCall(yip.book, route.targetChain, message.Message, message.Attestation)
+	if err != nil {
+		return nil, false, err
+	}
+	return mint, true, nil
+}
+
+func (yip *YieldIntelligencePerformer) validateResumeExecutionTask(payload *TaskPayload) error {
+	if yip.transactions == nil {
+		return fmt.Errorf("executions are not submitted by this performer, which has no account")
+	}
+	return nil
+}
+
+// executionRoute is a rebalanceRoute as recorded with its execution
+type executionRoute struct {
+	User           common.Address  `json:"user"`
+	Amount         *big.Int        `json:"amount"`
+	SourceProtocol string          `json:"source_protocol,omitempty"`
+	SourceChain    uint64          `json:"source_chain"`
+	TargetProtocol string          `json:"target_protocol"`
+	TargetChain    uint64          `json:"target_chain"`
+	Strategy       string          `json:"strategy,omitempty"`
+	Premium        *big.Int        `json:"premium,omitempty"`
+	Permit         bool            `json:"permit,omitempty"`
+	UserPermit     *permit.Signed  `json:"user_permit,omitempty"`
+	Execution      string          `json:"execution,omitempty"`
+	MaxSlippageBps uint64          `json:"max_slippage_bps"`
+	Speed          cctp.Speed      `json:"speed,omitempty"`
+	Fallback       *BridgeFallback `json:"fallback,omitempty"`
+	BridgeFee      *big.Int        `json:"bridge_fee,omitempty"`
+}
+
+func newExecutionRoute(route *rebalanceRoute) executionRoute {
+	return executionRoute{
+		User:           route.user,
+		Amount:         route.amount,
+		SourceProtocol: route.sourceProtocol,
+		SourceChain:    route.sourceChain,
+		TargetProtocol: route.targetProtocol,
+		TargetChain:    route.targetChain,
+		Strategy:       route.strategy,
+		Premium:        route.premium,
+		Permit:         route.permit,
+		UserPermit:     route.userPermit,
+		Execution:      route.execution,
+		MaxSlippageBps: route.maxSlippageBps,
+		Speed:          route.speed,
+		Fallback:       route.fallback,
+		BridgeFee:      route.bridgeFee,
+	}
+}
+
+func (r executionRoute) route() *rebalanceRoute {
+	return &rebalanceRoute{
+		user:           r.User,
+		amount:         r.Amount,
+		sourceProtocol: r.SourceProtocol,
+		sourceChain:    r.SourceChain,
+		targetProtocol: r.TargetProtocol,
+		targetChain:    r.TargetChain,
+		strategy:       r.Strategy,
+		premium:        r.Premium,
+		permit:         r.Permit,
+		userPermit:     r.UserPermit,
+		execution:      r.Execution,
+		maxSlippageBps: r.MaxSlippageBps,
+		speed:          r.Speed,
+		fallback:       r.Fallback,
+		bridgeFee:      r.BridgeFee,
+	}
+}
+
+// executionRecord is a rebalance execution as kept by the performer. Before are the
+// balances of the holdings of the route before anything was submitted, RateBefore the
+// supply rate its funds earned then when the ledger needs it, and Sent the signed
+// transactions of the execution in its order. BurnedAt is when its CCTP burn was last
+// submitted, a unix timestamp, and Recoveries how many times transfer recovery
+// submitted its mint again.
+type executionRecord struct {
+	Route      executionRoute      `json:"route"`
+	Before     []*big.Int          `json:"before"`
+	RateBefore *float64            `json:"rate_before,omitempty"`
+	Sent       []hexutil.Bytes     `json:"sent"`
+	Execution  *RebalanceExecution `json:"execution"`
+	BurnedAt   int64               `json:"burned_at,omitempty"`
+	Recoveries int                 `json:"recoveries,omitempty"`
+}
+
+func (r *executionRecord) setSent(sent []*types.Transaction) error {
+	r.Sent = make([]hexutil.Bytes, len(sent))
+	for i, tx := range sent {
+		encoded, err := tx.MarshalBinary()
+		if err != nil {
+			return fmt.Errorf("failed to encode transaction %s: %w", tx.Hash().Hex(), err)
+		}
+		r.Sent[i] = encoded
+	}
+	return nil
+}
+
+func (r *executionRecord) sent() ([]*types.Transaction, error) {
+	sent := make([]*types.Transaction, len(r.Sent))
+	for i, encoded := range r.Sent {
+		sent[i] = new(types.Transaction)
+		if err := sent[i].UnmarshalBinary(encoded); err != nil {
+			return nil, fmt.Errorf("failed to decode transaction %d of execution %s: %w", i, r.Execution.ID, err)
+		}
+	}
+	return sent, nil
+}
+
+// executionStore keeps the rebalance executions the performer submitted, so they can be
+// resumed
+type executionStore struct {
+	kv store.KV
+
+	// mu serializes resumes, so no leg is submitted twice at once
+	mu sync.Mutex
+}
+
+func newExecutionStore(kv store.KV) *executionStore {
+	return &executionStore{kv: kv}
+}
+
+func (s *executionStore) get(ctx context.Context, id string) (*executionRecord, error) {
+	encoded, err := s.kv.Get(ctx, []byte(prefixExecution+id))
+	if err != nil {
+		return nil, err
+	}
+	var record executionRecord
+	if err := json.Unmarshal(encoded, &record); err != nil {
+		return nil, fmt.Errorf("failed to decode execution %s: %w", id, err)
+	}
+	return &record, nil
+}
+
+func (s *executionStore) put(ctx context.Context, record *executionRecord) error {
+	encoded, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if err := s.kv.Set(ctx, []byte(prefixExecution+record.Execution.ID), encoded); err != nil {
+		return fmt.Errorf("failed to store execution %s: %w", record.Execution.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package performer
+
+import (
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/najnomics/crosscow-avs/pkg/addressbook"
+	"github.com/najnomics/crosscow-avs/pkg/cctp"
+)
+
+// withAttestedBurns lets performer read burn messages, which Circle attests once attested
+// is set
+func withAttestedBurns(t *testing.T, performer *YieldIntelligencePerformer) *atomic.Bool {
+	t.Helper()
+	var attested atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !attested.Load() {
+			w.Write([]byte(`{"messages":[{"status":"pending_confirmations","message":"0x","attestation":"PENDING"}]}`))
+			return
+		}
+		w.Write([]byte(`{"messages":[{"status":"complete","message":"0x0102","attestation":"0xabcd"}]}`))
+	}))
+	t.Cleanup(server.Close)
+	WithAttestations(cctp.NewAttestations(server.URL, time.Second, nil))(performer)
+	return &attested
+}
+
+func checkLegs(t *testing.T, execution *RebalanceExecution, want ...string) {
+	t.Helper()
+	if len(execution.Legs) != len(want)/2 {
+		t.Fatalf("Expected legs %v, got %+v", want, execution.Legs)
+	}
+	for i, leg := range execution.Legs {
+		if leg.Leg != want[2*i] || leg.Status != want[2*i+1] {
+			t.Errorf("Expected leg %d to be %s %s, got %+v", i, want[2*i], want[2*i+1], leg)
+		}
+	}
+}
+
+func resumeExecution(t *testing.T, performer *YieldIntelligencePerformer, id, executionID string) RebalanceExecutionResult {
+	t.Helper()
+	var result RebalanceExecutionResult
+	if err := runRebalanceTask(t, performer, id, `{"type":"resume_execution","parameters":{"execution_id":"`+executionID+`"}}`, &result); err != nil {
+		t.Fatalf("resume_execution failed: %v", err)
+	}
+	return result
+}
+
+func Test_ResumeExecutionMintsAttestedBurn(t *testing.T) {
+	// the aave position on Base is credited by the deposit
+	performer, account, ethereum, base := newSubmittingPerformerOnBase(t, big.NewInt(0), big.NewInt(0), big.NewInt(1_000_000_000))
+	ethereum.AutoMine()
+	base.AutoMine()
+	attested := withAttestedBurns(t, performer)
+
+	var result RebalanceExecutionResult
+	if err := runRebalanceTask(t, performer, "bridged", `{"type":"rebalance_execution","parameters":{
+		"user_address":"`+account.Hex()+`","nonce":"1","amount":"1000000000","source_chain":1,"target_protocol":"aave_v3","target_chain":8453}}`, &result); err != nil {
+		t.Fatalf("rebalance_execution failed: %v", err)
+	}
+	execution := result.Execution
+	if result.Status != ResultStatusSubmitted || execution.ID != hexutil.Encode([]byte("bridged")) {
+		t.Fatalf("Expected the rebalance to wait for the attestation, got %+v", result)
+	}
+	checkLegs(t, execution, ActionBurn, LegCompleted, ActionMint, LegPending, ActionDeposit, LegPending)
+
+	// nothing is minted before Circle attests the burn
+	result = resumeExecution(t, performer, "unattested", execution.ID)
+	if result.Status != ResultStatusSubmitted || len(base.Sent()) != 0 {
+		t.Fatalf("Expected the mint to wait for the attestation, got %+v", result)
+	}
+	checkLegs(t, result.Execution, ActionBurn, LegCompleted, ActionMint, LegPending, ActionDeposit, LegPending)
+
+	attested.Store(true)
+	result = resumeExecution(t, performer, "attested", execution.ID)
+	if result.Status != ResultStatusCompleted || !result.Execution.Verified {
+		t.Fatalf("Expected the rebalance to complete, got %+v", result)
+	}
+	checkLegs(t, result.Execution, ActionBurn, LegCompleted, ActionMint, LegCompleted, ActionDeposit, LegCompleted)
+	sent := base.Sent()
+	if len(sent) != 3 || len(ethereum.Sent()) != 2 {
+		t.Fatalf("Expected the mint, approve and deposit to be submitted on Base alone, got %d", len(sent))
+	}
+	if mint := result.Execution.Transactions[2]; mint.Action != ActionMint || mint.Hash != sent[0].Hash().Hex() || *sent[0].To() != addressbook.Default().Address(8453, addressbook.ProtocolCCTP, addressbook.ContractMessageTransmitter) {
+		t.Errorf("Unexpected mint %+v", mint)
+	}
+
+	// completed legs are never submitted again
+	resumeExecution(t, performer, "completed", execution.ID)
+	if len(base.Sent()) != 3 || len(ethereum.Sent()) != 2 {
+		t.Errorf("Expected nothing to be submitted again")
+	}
+}
+
+func Test_ResumeExecutionRetriesFailedLeg(t *testing.T) {
+	performer, account, ethereum := newSubmittingPerformer(t)
+	withAttestedBurns(t, performer)
+
+	var result RebalanceExecutionResult
+	if err := runRebalanceTask(t, performer, "reverted", `{"type":"rebalance_execution","parameters":{
+		"user_address":"`+account.Hex()+`","nonce":"1","amount":"1000000000","source_chain":1,"target_protocol":"aave_v3","target_chain":8453}}`, &result); err != nil {
+		t.Fatalf("rebalance_execution failed: %v", err)
+	}
+	checkLegs(t, result.Execution, ActionBurn, LegSubmitted, ActionMint, LegPending, ActionDeposit, LegPending)
+
+	// the approval is mined and the burn reverts
+	sent := ethereum.Sent()
+	ethereum.Mine(sent[0].Hash(), types.ReceiptStatusSuccessful, 50_000)
+	ethereum.Mine(sent[1].Hash(), types.ReceiptStatusFailed, 50_000)
+	ethereum.AutoMine()
+
+	result = resumeExecution(t, performer, "retry", result.Execution.ID)
+	sent = ethereum.Sent()
+	if len(sent) != 4 || sent[2].Nonce() != 7 {
+		t.Fatalf("Expected the approve and burn to be submitted again, got %d transactions", len(sent))
+	}
+	if burn := result.Execution.Transactions[1]; burn.Action != ActionBurn || burn.Status != TransactionConfirmed || burn.Hash != sent[3].Hash().Hex() {
+		t.Errorf("Expected the burn to be replaced, got %+v", burn)
+	}
+	checkLegs(t, result.Execution, ActionBurn, LegCompleted, ActionMint, LegPending, ActionDeposit, LegPending)
+}
+
+func Test_ResumeExecutionRejections(t *testing.T) {
+	performer, _, _ := newSubmittingPerformer(t)
+
+	err := runRebalanceTask(t, performer, "unknown", `{"type":"resume_execution","parameters":{"execution_id":"0x01"}}`, nil)
+	var taskErr *TaskError
+	if !errors.As(err, &taskErr) || taskErr.Code != ErrorCodeValidation {
+		t.Errorf("Expected an unknown execution to be rejected, got %v", err)
+	}
+	if err := runRebalanceTask(t, performer, "invalid", `{"type":"resume_execution","parameters":{"execution_id":"bridged"}}`, nil); err == nil {
+		t.Errorf("Expected an invalid execution_id to be rejected")
+	}
+	if err := runRebalanceTask(t, newDryRunPerformer(t, &fakeSimulator{bundles: make(map[uint64]int)}), "no-account", `{"type":"resume_execution","parameters":{"execution_id":"0x01"}}`, nil); err == nil {
+		t.Errorf("Expected a performer without an account to reject resumes")
+	}
+}
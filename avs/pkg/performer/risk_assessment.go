@@ -0,0 +1,484 @@
+package performer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+	"github.com/najnomics/crosscow-avs/pkg/risk"
+	"github.com/najnomics/crosscow-avs/pkg/security"
+	"github.com/najnomics/crosscow-avs/pkg/snapshot"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+	"github.com/najnomics/crosscow-avs/pkg/workerpool"
+)
+
+// OracleReport is the price source a protocol values USDC with. Age is measured against
+// the block the market was read at and is null when the feed does not expose rounds.
+type OracleReport struct {
+	Feed       string            `json:"feed"`
+	Price      canonical.Decimal `json:"price"`
+	UpdatedAt  uint64            `json:"updated_at,omitempty"`
+	AgeSeconds *uint64           `json:"age_seconds"`
+	Stale      bool              `json:"stale"`
+}
+
+// ControllerReport is an account with privileges over a market and the account
+// controlling it, if any
+type ControllerReport struct {
+	Address      string                  `json:"address"`
+	Kind         adapters.ControllerKind `json:"kind"`
+	Threshold    uint64                  `json:"threshold,omitempty"`
+	Owners       uint64                  `json:"owners,omitempty"`
+	DelaySeconds uint64                  `json:"delay_seconds,omitempty"`
+	Admin        *ControllerReport       `json:"admin,omitempty"`
+}
+
+// GovernanceReport is who can upgrade, configure and pause a market. Proxy admin and pause
+// guardian are null when the market is not upgradeable or the guardian is unknown.
+type GovernanceReport struct {
+	ProxyAdmin    *ControllerReport `json:"proxy_admin"`
+	Governor      *ControllerReport `json:"governor"`
+	PauseGuardian *ControllerReport `json:"pause_guardian"`
+}
+
+// IncidentReport is a security incident of the protocol affecting the assessed chain
+type IncidentReport struct {
+	ID        string            `json:"id"`
+	Title     string            `json:"title"`
+	Severity  security.Severity `json:"severity"`
+	Active    bool              `json:"active"`
+	LossUsd   string            `json:"loss_usd,omitempty"`
+	Timestamp uint64            `json:"timestamp"`
+	Url       string            `json:"url,omitempty"`
+}
+
+// AuditReport is a completed audit of the protocol
+type AuditReport struct {
+	Auditor   string `json:"auditor"`
+	Scope     string `json:"scope"`
+	Timestamp uint64 `json:"timestamp"`
+	Url       string `json:"url,omitempty"`
+}
+
+// SecurityReport is the record of the protocol in the security feed as of FeedUpdatedAt.
+// Recent exploits are resolved incidents that still count against the protocol.
+type SecurityReport struct {
+	FeedUpdatedAt   uint64           `json:"feed_updated_at"`
+	Stale           bool             `json:"stale"`
+	ActiveIncidents []IncidentReport `json:"active_incidents"`
+	RecentExploits  []IncidentReport `json:"recent_exploits"`
+	LatestAudit     *AuditReport     `json:"latest_audit"`
+	AuditCount      uint64           `json:"audit_count"`
+}
+
+// RWAReport is the backing of a yield source backed by real world assets and who may
+// hold it. Jurisdiction is empty for protocols governed on chain; transferability is
+// permissionless, allowlisted or restricted.
+type RWAReport struct {
+	Issuer          string `json:"issuer"`
+	Jurisdiction    string `json:"jurisdiction"`
+	Backing         string `json:"backing"`
+	Transferability string `json:"transferability"`
+	KYC             bool   `json:"kyc"`
+}
+
+// RiskFactorScore is the 0-100 score of one risk factor, null when it could not be
+// measured
+type RiskFactorScore struct {
+	Factor risk.Factor        `json:"factor"`
+	Score  *canonical.Decimal `json:"score"`
+	Weight uint64             `json:"weight"`
+}
+
+// RiskReport holds the measurements of a USDC market and the scores derived from them.
+// Amounts are in USDC; caps, headroom and bad debt are null when the protocol has none or
+// does not expose them.
+type RiskReport struct {
+	// TVL is the USDC supplied to the market and ProtocolTVL that supplied to the
+	// protocol's USDC markets on every known chain
+	TVL         canonical.Decimal  `json:"tvl"`
+	ProtocolTVL *canonical.Decimal `json:"protocol_tvl"`
+	TotalBorrow canonical.Decimal  `json:"total_borrow"`
+	Utilization canonical.Decimal  `json:"utilization"`
+
+	SupplyCap         *canonical.Decimal `json:"supply_cap"`
+	SupplyCapHeadroom *canonical.Decimal `json:"supply_cap_headroom"`
+	BorrowCap         *canonical.Decimal `json:"borrow_cap"`
+	BorrowCapHeadroom *canonical.Decimal `json:"borrow_cap_headroom"`
+
+	BadDebt *canonical.Decimal `json:"bad_debt"`
+	Paused  bool               `json:"paused"`
+	Frozen  bool               `json:"frozen"`
+	Oracle  *OracleReport      `json:"oracle"`
+
+	Governance *GovernanceReport `json:"governance"`
+	Security   *SecurityReport   `json:"security"`
+
+	// RWA is set for yield sources backed by real world assets
+	RWA *RWAReport `json:"rwa,omitempty"`
+
+	Factors []RiskFactorScore `json:"factors"`
+
+	// LiquidityScore combines size, utilization and caps, SolvencyScore bad debt, the
+	// oracle and the market status. AdminScore and SecurityScore are the scores of the
+	// admin keys and the security record.
+	LiquidityScore *canonical.Decimal `json:"liquidity_score"`
+	SolvencyScore  *canonical.Decimal `json:"solvency_score"`
+	AdminScore     *canonical.Decimal `json:"admin_score"`
+	SecurityScore  *canonical.Decimal `json:"security_score"`
+	OverallScore   canonical.Decimal  `json:"overall_score"`
+	RiskLevel      risk.Level         `json:"risk_level"`
+}
+
+// handleRiskAssessment measures the USDC market of a protocol on one chain and scores
+// its risk. Protocols whose adapter cannot read risk parameters or admin keys, or that the
+// security feed does not cover, are scored from what could be read and reported as
+// partial. Without a security feed the security record is not scored. With snapshots, the
+// market is read at the requested block, or the latest finalized block.
+func (yip *YieldIntelligencePerformer) handleRiskAssessment(ctx context.Context, t *performerV1.TaskRequest, payload *TaskPayload) (interface{}, error) {
+	yip.log(ctx).Sugar().Infow("Processing risk assessment task")
+
+	result := &RiskAssessmentResult{
+		Protocol:       paramString(payload, "protocol"),
+		ChainID:        paramUint64(payload, "chain_id"),
+		AssessmentType: paramString(payload, "assessment_type"),
+		Status:         ResultStatusCompleted,
+	}
+
+	target := market{protocol: result.Protocol, chainID: result.ChainID}
+	ctx, block, err := yip.pin(ctx, target.chainID, blockRequest(payload))
+	if err != nil {
+		return nil, err
+	}
+	result.BlockNumber = block
+	state, err := yip.readMarket(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	riskState, err := yip.readRiskState(ctx, target)
+	switch {
+	case errors.Is(err, adapters.ErrNoRiskData):
+		result.Status = ResultStatusPartial
+	case err != nil:
+		return nil, fmt.Errorf("failed to read %s risk parameters on chain %d: %w", result.Protocol, result.ChainID, err)
+	}
+	metrics := riskMetrics(state, riskState)
+
+	report := &RiskReport{
+		TVL:         usdcAmount(state.Pool.TotalSupply),
+		TotalBorrow: usdcAmount(state.Pool.TotalBorrow),
+		Utilization: canonical.Ratio(state.Pool.Utilization()),
+		RWA:         yip.rwaReport(result.Protocol),
+	}
+	if riskState != nil {
+		report.SupplyCap, report.SupplyCapHeadroom = capReport(riskState.SupplyCap, state.Pool.TotalSupply)
+		report.BorrowCap, report.BorrowCapHeadroom = capReport(riskState.BorrowCap, state.Pool.TotalBorrow)
+		report.BadDebt = optionalUSDC(riskState.BadDebt)
+		report.Paused = riskState.Paused
+		report.Frozen = riskState.Frozen
+		if oracle := riskState.Oracle; oracle != nil {
+			report.Oracle = oracleReport(oracle, riskState.Timestamp)
+			report.Oracle.Stale = metrics.OracleAge != nil && *metrics.OracleAge > risk.DefaultThresholds.OracleHeartbeat
+		}
+	}
+
+	var governance *adapters.GovernanceState
+	governor, err := yip.adapters.GovernanceReaderFor(result.Protocol)
+	if err == nil {
+		governance, err = governor.Governance(ctx, result.ChainID)
+	}
+	switch {
+	case errors.Is(err, adapters.ErrNoGovernanceData):
+		result.Status = ResultStatusPartial
+	case err != nil:
+		return nil, fmt.Errorf("failed to read %s governance on chain %d: %w", result.Protocol, result.ChainID, err)
+	}
+	if governance != nil {
+		report.Governance = &GovernanceReport{
+			ProxyAdmin:    controllerReport(governance.ProxyAdmin),
+			Governor:      controllerReport(governance.Governor),
+			PauseGuardian: controllerReport(governance.PauseGuardian),
+		}
+		metrics.Governance = &risk.Governance{
+			Upgrade: control(governance.ProxyAdmin),
+			Admin:   control(governance.Governor),
+			Pause:   control(governance.PauseGuardian),
+		}
+	}
+
+	if yip.security != nil {
+		if record, err := yip.securityRecord(ctx, result.Protocol, result.ChainID); err != nil {
+			yip.log(ctx).Sugar().Warnw("Failed to read security feed", "protocol", result.Protocol, "error", err)
+			result.Status = ResultStatusPartial
+		} else {
+			report.Security = record
+			metrics.Security = securityMetrics(record)
+		}
+	}
+
+	if protocolTVL, ok := yip.protocolTVL(ctx, target, state); ok {
+		report.ProtocolTVL = &protocolTVL
+	} else {
+		result.Status = ResultStatusPartial
+	}
+
+	assessment := risk.Assess(metrics, risk.DefaultThresholds, risk.DefaultWeights)
+	for _, f := range assessment.Factors {
+		report.Factors = append(report.Factors, RiskFactorScore{Factor: f.Factor, Score: optionalScore(f.Score), Weight: uint64(f.Weight)})
+	}
+	report.LiquidityScore = optionalScore(assessment.Mean(risk.FactorTVL, risk.FactorUtilization, risk.FactorCapHeadroom))
+	report.SolvencyScore = optionalScore(assessment.Mean(risk.FactorBadDebt, risk.FactorOracle, risk.FactorMarketStatus))
+	report.AdminScore = optionalScore(assessment.Mean(risk.FactorAdmin))
+	report.SecurityScore = optionalScore(assessment.Mean(risk.FactorSecurity))
+	report.OverallScore = canonical.NewDecimalFromRat(assessment.Overall, canonical.ScorePlaces)
+	report.RiskLevel = assessment.Level
+
+	// scores of the latest state are kept for the risk score history exports read
+	if blockRequest(payload) == (snapshot.Request{}) {
+		score, _ := assessment.Overall.Float64()
+		if err := yip.history.Append(ctx, store.RiskScoreSeries(result.Protocol, result.ChainID), store.SeriesPoint{Time: time.Now(), Value: score}); err != nil {
+			yip.log(ctx).Sugar().Warnw("Failed to record risk score", "protocol", result.Protocol, "chainId", result.ChainID, "error", err)
+		}
+	}
+
+	result.Report = report
+	return result, nil
+}
+
+// protocolTVL sums the supply of the protocol's USDC markets on every chain, reusing the
+// state already read for m. It reports false when a market could not be read. With
+// snapshots, the markets on other chains are read at their latest finalized block.
+func (yip *YieldIntelligencePerformer) protocolTVL(ctx context.Context, m market, state *adapters.MarketState) (canonical.Decimal, bool) {
+	var others []market
+	for _, other := range yip.markets() {
+		if strings.EqualFold(other.protocol, m.protocol) && other.chainID != m.chainID {
+			others = append(others, other)
+		}
+	}
+	total := new(big.Int).Set(state.Pool.TotalSupply)
+	outcomes := workerpool.Map(ctx, yip.pool, others, func(ctx context.Context, other market) (*adapters.MarketState, error) {
+		ctx, _, err := yip.pin(ctx, other.chainID, snapshot.Request{})
+		if err != nil {
+			return nil, err
+		}
+		return yip.readMarket(ctx, other)
+	})
+	for i, outcome := range outcomes {
+		if outcome.Err != nil {
+			yip.log(ctx).Sugar().Warnw("Failed to read market for protocol TVL", "protocol", others[i].protocol, "chainId", others[i].chainID, "error", outcome.Err)
+			return canonical.Decimal{}, false
+		}
+		total.Add(total, outcome.Value.Pool.TotalSupply)
+	}
+	return usdcAmount(total), true
+}
+
+// securityRecord reports the incidents affecting protocol on chainID and its audits
+func (yip *YieldIntelligencePerformer) securityRecord(ctx context.Context, protocol string, chainID uint64) (*SecurityReport, error) {
+	feed, err := yip.security.Snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := feed.Protocol(protocol)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &SecurityReport{
+		FeedUpdatedAt:   feed.UpdatedAt,
+		Stale:           feed.Stale,
+		ActiveIncidents: []IncidentReport{},
+		RecentExploits:  []IncidentReport{},
+		AuditCount:      uint64(len(entry.Audits)),
+	}
+	for _, incident := range entry.Incidents {
+		if !incident.Affects(chainID) {
+			continue
+		}
+		ir := IncidentReport{
+			ID:        incident.ID,
+			Title:     incident.Title,
+			Severity:  incident.Severity,
+			Active:    incident.Active,
+			LossUsd:   incident.LossUsd,
+			Timestamp: incident.Timestamp,
+			Url:       incident.Url,
+		}
+		switch {
+		case incident.Active:
+			report.ActiveIncidents = append(report.ActiveIncidents, ir)
+		case age(feed.UpdatedAt, incident.Timestamp) < risk.DefaultThresholds.ExploitWindow:
+			report.RecentExploits = append(report.RecentExploits, ir)
+		}
+	}
+	if audit := entry.LatestAudit(); audit != nil {
+		report.LatestAudit = &AuditReport{Auditor: audit.Auditor, Scope: audit.Scope, Timestamp: audit.Timestamp, Url: audit.Url}
+	}
+	return report, nil
+}
+
+// securityMetrics measures ages against the time the feed was updated, so every operator
+// reading the same feed scores alike
+func securityMetrics(report *SecurityReport) *risk.Security {
+	metrics := &risk.Security{}
+	for _, incident := range report.ActiveIncidents {
+		if severityRank(incident.Severity) > severityRank(security.Severity(metrics.ActiveSeverity)) {
+			metrics.ActiveSeverity = risk.Severity(incident.Severity)
+		}
+	}
+	for _, exploit := range report.RecentExploits {
+		if a := age(report.FeedUpdatedAt, exploit.Timestamp); metrics.ExploitAge == nil || a < *metrics.ExploitAge {
+			metrics.ExploitAge = &a
+		}
+	}
+	if report.LatestAudit != nil {
+		a := age(report.FeedUpdatedAt, report.LatestAudit.Timestamp)
+		metrics.AuditAge = &a
+	}
+	return metrics
+}
+
+func severityRank(severity security.Severity) int {
+	switch severity {
+	case security.SeverityLow:
+		return 1
+	case security.SeverityMedium:
+		return 2
+	case security.SeverityHigh:
+		return 3
+	case security.SeverityCritical:
+		return 4
+	default:
+		return 0
+	}
+}
+
+// age is the seconds from then to now, zero for times after now
+func age(now, then uint64) uint64 {
+	if then >= now {
+		return 0
+	}
+	return now - then
+}
+
+// rwaReport returns the RWA profile of protocol, nil when it is not backed by real world
+// assets
+func (yip *YieldIntelligencePerformer) rwaReport(protocol string) *RWAReport {
+	source, err := yip.adapters.RWASourceFor(protocol)
+	if err != nil {
+		return nil
+	}
+	profile := source.RWAProfile()
+	return &RWAReport{
+		Issuer:          profile.Issuer,
+		Jurisdiction:    profile.Jurisdiction,
+		Backing:         profile.Backing,
+		Transferability: profile.Transferability,
+		KYC:             profile.KYC,
+	}
+}
+
+// capReport returns a cap and the amount left below it, both null when uncapped
+// riskMetrics measures a market read as state from its pool and, unless nil, the risk
+// parameters its protocol reports
+func riskMetrics(state *adapters.MarketState, riskState *adapters.RiskState) *risk.Metrics {
+	metrics := &risk.Metrics{
+		TotalSupply: state.Pool.TotalSupply,
+		TotalBorrow: state.Pool.TotalBorrow,
+	}
+	if riskState == nil {
+		return metrics
+	}
+	metrics.SupplyCap = riskState.SupplyCap
+	metrics.BadDebt = riskState.BadDebt
+	metrics.Status = &risk.Status{Paused: riskState.Paused, Frozen: riskState.Frozen}
+	if oracle := riskState.Oracle; oracle != nil {
+		metrics.OraclePrice = oracle.USD()
+		metrics.OracleAge = oracleReport(oracle, riskState.Timestamp).AgeSeconds
+	}
+	return metrics
+}
+
+func capReport(limit, used *big.Int) (*canonical.Decimal, *canonical.Decimal) {
+	if limit == nil {
+		return nil, nil
+	}
+	headroom := usdcAmount(new(big.Int).Sub(limit, used))
+	return optionalUSDC(limit), &headroom
+}
+
+func oracleReport(oracle *adapters.Oracle, now uint64) *OracleReport {
+	report := &OracleReport{
+		Feed:      oracle.Feed.Hex(),
+		Price:     usdPrice(oracle.USD()),
+		UpdatedAt: oracle.UpdatedAt,
+	}
+	if oracle.UpdatedAt > 0 && oracle.UpdatedAt <= now {
+		age := now - oracle.UpdatedAt
+		report.AgeSeconds = &age
+	}
+	return report
+}
+
+func controllerReport(c *adapters.Controller) *ControllerReport {
+	if c == nil {
+		return nil
+	}
+	return &ControllerReport{
+		Address:      c.Address.Hex(),
+		Kind:         c.Kind,
+		Threshold:    c.Threshold,
+		Owners:       c.Owners,
+		DelaySeconds: c.Delay,
+		Admin:        controllerReport(c.Admin),
+	}
+}
+
+// control follows c through timelocks and owning contracts to the account at the end,
+// adding up the delays on the way
+func control(c *adapters.Controller) *risk.Control {
+	if c == nil {
+		return nil
+	}
+	result := &risk.Control{}
+	for ; c != nil; c = c.Admin {
+		switch c.Kind {
+		case adapters.ControllerEOA:
+			result.Threshold = 1
+		case adapters.ControllerSafe:
+			result.Threshold = c.Threshold
+		case adapters.ControllerTimelock:
+			result.Delay += c.Delay
+		}
+	}
+	return result
+}
+
+func optionalUSDC(baseUnits *big.Int) *canonical.Decimal {
+	if baseUnits == nil {
+		return nil
+	}
+	amount := usdcAmount(baseUnits)
+	return &amount
+}
+
+func optionalScore(score *big.Rat) *canonical.Decimal {
+	if score == nil {
+		return nil
+	}
+	decimal := canonical.NewDecimalFromRat(score, canonical.ScorePlaces)
+	return &decimal
+}
+
+func (yip *YieldIntelligencePerformer) validateRiskAssessmentTask(payload *TaskPayload) error {
+	if _, err := yip.adapters.Get(paramString(payload, "protocol")); err != nil {
+		return err
+	}
+	return yip.validateBlockRequest(payload, true)
+}
@@ -0,0 +1,236 @@
+package performer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/najnomics/crosscow-avs/pkg/abicodec"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/chain/chaintest"
+	"github.com/najnomics/crosscow-avs/pkg/risk"
+	"github.com/najnomics/crosscow-avs/pkg/security"
+	"github.com/najnomics/crosscow-avs/pkg/snapshot"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+	"go.uber.org/zap"
+)
+
+func runRiskAssessment(t *testing.T, performer *YieldIntelligencePerformer, payload string) RiskAssessmentResult {
+	t.Helper()
+	task := &performerV1.TaskRequest{TaskId: []byte("risk-task"), Payload: []byte(payload)}
+	if err := performer.ValidateTask(task); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	resp, err := performer.HandleTask(task)
+	if err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+	var envelope struct {
+		Result RiskAssessmentResult `json:"result"`
+	}
+	if err := json.Unmarshal(resp.Result, &envelope); err != nil {
+		t.Fatalf("Failed to decode result: %v", err)
+	}
+	return envelope.Result
+}
+
+func factorScore(t *testing.T, report *RiskReport, factor risk.Factor) *RiskFactorScore {
+	t.Helper()
+	for i := range report.Factors {
+		if report.Factors[i].Factor == factor {
+			return &report.Factors[i]
+		}
+	}
+	t.Fatalf("Factor %s missing", factor)
+	return nil
+}
+
+func Test_RiskAssessmentReport(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+	aave := newFakeAaveAdapter()
+	base := *aave.markets[1]
+	base.ChainID = adapters.ChainIDBase
+	base.Pool.TotalSupply = usdcUnits(25_000_000)
+	aave.markets[adapters.ChainIDBase] = &base
+	aave.risk.Paused = true
+	performer := NewYieldIntelligencePerformer(logger, WithAdapters(adapters.NewRegistry(aave)))
+
+	result := runRiskAssessment(t, performer, `{"type":"risk_assessment","parameters":{"protocol":"aave_v3","chain_id":1,"assessment_type":"full"}}`)
+	report := result.Report
+	if result.Status != ResultStatusCompleted || report == nil {
+		t.Fatalf("Expected a completed report, got %+v", result)
+	}
+	if report.TVL.String() != "100000000.000000" || report.ProtocolTVL == nil || report.ProtocolTVL.String() != "125000000.000000" {
+		t.Errorf("Unexpected TVL %s of protocol TVL %v", report.TVL, report.ProtocolTVL)
+	}
+	if len(report.Factors) != len(risk.Factors) {
+		t.Fatalf("Expected a score per factor, got %+v", report.Factors)
+	}
+	if status := factorScore(t, report, risk.FactorMarketStatus); status.Score == nil || status.Score.String() != "100.00" {
+		t.Errorf("Expected the paused market to score 100, got %+v", status)
+	}
+	// a paused market is at least high risk whatever the other factors
+	if !report.Paused || report.RiskLevel != risk.LevelHigh {
+		t.Errorf("Expected a paused market to be high risk, got %s at %s", report.RiskLevel, report.OverallScore)
+	}
+	// upgrades and parameters wait a day in the timelock of a governor
+	if g := report.Governance; g == nil || g.ProxyAdmin == nil || g.ProxyAdmin.Admin == nil || g.ProxyAdmin.Admin.DelaySeconds != 86_400 {
+		t.Errorf("Expected the proxy admin to be owned by the timelock, got %+v", g)
+	}
+	if report.AdminScore == nil || report.AdminScore.String() != "31.25" {
+		t.Errorf("Expected an admin score of 31.25, got %v", report.AdminScore)
+	}
+	// the score of the latest state is kept for exports
+	points, err := performer.history.Range(context.Background(), store.RiskScoreSeries("aave_v3", 1), time.Unix(0, 0), time.Now().Add(time.Minute))
+	if score, _ := report.OverallScore.Rat().Float64(); err != nil || len(points) != 1 || points[0].Value != score {
+		t.Errorf("Expected the overall score %s to be recorded, got %+v: %v", report.OverallScore, points, err)
+	}
+
+	encoded, err := result.EncodeABI()
+	if err != nil {
+		t.Fatalf("EncodeABI failed: %v", err)
+	}
+	metrics, err := abicodec.DecodeRiskMetrics(encoded)
+	if err != nil {
+		t.Fatalf("DecodeRiskMetrics failed: %v", err)
+	}
+	if metrics.OverallRisk.Int64() != 5000 || metrics.GovernanceRisk.Int64() != 3125 || metrics.ProtocolRisk.Sign() <= 0 || metrics.LiquidityRisk.Sign() <= 0 {
+		t.Errorf("Unexpected risk metrics: %+v", metrics)
+	}
+}
+
+func Test_RiskAssessmentWithoutRiskData(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+	aave := newFakeAaveAdapter()
+	aave.risk = nil
+	aave.governance = nil
+	performer := NewYieldIntelligencePerformer(logger, WithAdapters(adapters.NewRegistry(aave)))
+
+	result := runRiskAssessment(t, performer, `{"type":"risk_assessment","parameters":{"protocol":"aave_v3","chain_id":1,"assessment_type":"full"}}`)
+	report := result.Report
+	if result.Status != ResultStatusPartial || report == nil {
+		t.Fatalf("Expected a partial report, got %+v", result)
+	}
+	if report.SolvencyScore != nil || report.Oracle != nil || report.BadDebt != nil || report.AdminScore != nil || report.Governance != nil {
+		t.Errorf("Expected solvency and admin keys to be unmeasured, got %+v", report)
+	}
+	if report.LiquidityScore == nil || report.Utilization.String() != "0.800000" {
+		t.Errorf("Expected market size and utilization to be scored, got %+v", report)
+	}
+
+	task := &performerV1.TaskRequest{TaskId: []byte("risk-task"), Payload: []byte(`{"type":"risk_assessment","parameters":{"protocol":"euler","chain_id":1,"assessment_type":"full"}}`)}
+	if err := performer.ValidateTask(task); err == nil {
+		t.Errorf("Expected an unsupported protocol to be rejected")
+	}
+}
+
+func Test_RiskAssessmentSecurityRecord(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"updated_at":1700000000,"protocols":{"aave_v3":{
+			"audits":[{"auditor":"Certora","scope":"v3.2","timestamp":1690000000,"url":""}],
+			"incidents":[
+				{"id":"oracle","title":"Oracle misconfiguration","severity":"critical","active":true,"chain_ids":[1],"loss_usd":"","timestamp":1699990000,"url":""},
+				{"id":"base","title":"Frontend incident","severity":"high","active":true,"chain_ids":[8453],"loss_usd":"","timestamp":1699990000,"url":""},
+				{"id":"2023","title":"Rounding exploit","severity":"high","active":false,"chain_ids":[],"loss_usd":"1200000.00","timestamp":1690000000,"url":""}
+			]}}}`))
+	}))
+	defer server.Close()
+	feed := security.NewFeed(security.Config{Url: server.URL, AllowUnsigned: true}, nil)
+	performer := NewYieldIntelligencePerformer(logger,
+		WithAdapters(adapters.NewRegistry(newFakeAaveAdapter())),
+		WithSecurityFeed(feed),
+	)
+
+	result := runRiskAssessment(t, performer, `{"type":"risk_assessment","parameters":{"protocol":"aave_v3","chain_id":1,"assessment_type":"full"}}`)
+	report := result.Report
+	if result.Status != ResultStatusCompleted || report == nil || report.Security == nil {
+		t.Fatalf("Expected a completed report with a security record, got %+v", result)
+	}
+	record := report.Security
+	if len(record.ActiveIncidents) != 1 || record.ActiveIncidents[0].ID != "oracle" {
+		t.Errorf("Expected only the incident on ethereum to be active, got %+v", record.ActiveIncidents)
+	}
+	if len(record.RecentExploits) != 1 || record.RecentExploits[0].LossUsd != "1200000.00" {
+		t.Errorf("Expected the exploit to be recent, got %+v", record.RecentExploits)
+	}
+	if record.LatestAudit == nil || record.AuditCount != 1 {
+		t.Errorf("Expected one audit, got %+v", record)
+	}
+	// an active critical incident alone makes the market high risk
+	if report.SecurityScore == nil || report.SecurityScore.String() != "100.00" || report.RiskLevel != risk.LevelHigh {
+		t.Errorf("Expected security to score 100 and the market to be high risk, got %v (%s)", report.SecurityScore, report.RiskLevel)
+	}
+
+	encoded, err := result.EncodeABI()
+	if err != nil {
+		t.Fatalf("EncodeABI failed: %v", err)
+	}
+	metrics, err := abicodec.DecodeRiskMetrics(encoded)
+	if err != nil {
+		t.Fatalf("DecodeRiskMetrics failed: %v", err)
+	}
+	if metrics.SmartContractRisk.Int64() != 10000 {
+		t.Errorf("Expected smart contract risk of 10000, got %s", metrics.SmartContractRisk)
+	}
+
+	// protocols the feed does not cover are not scored
+	compound := newFakeAaveAdapter()
+	compound.protocol = adapters.ProtocolCompoundV3
+	performer = NewYieldIntelligencePerformer(logger, WithAdapters(adapters.NewRegistry(compound)), WithSecurityFeed(feed))
+	result = runRiskAssessment(t, performer, `{"type":"risk_assessment","parameters":{"protocol":"compound_v3","chain_id":1,"assessment_type":"full"}}`)
+	if result.Status != ResultStatusPartial || result.Report.Security != nil || result.Report.SecurityScore != nil {
+		t.Errorf("Expected an uncovered protocol to be partial, got %+v", result.Report)
+	}
+}
+
+func Test_RiskAssessmentAtBlock(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+	chains := chain.NewManager()
+	for chainID, finalized := range map[uint64]uint64{1: 100, adapters.ChainIDBase: 500} {
+		contracts := chaintest.NewContracts(chainID)
+		contracts.SetHead(finalized+20, 1_700_000_000)
+		contracts.SetFinalized(finalized)
+		chains.Register(chainID, "", contracts)
+	}
+	aave := newFakeAaveAdapter()
+	base := *aave.markets[1]
+	base.ChainID = adapters.ChainIDBase
+	aave.markets[adapters.ChainIDBase] = &base
+	adapter := &pinnedAdapter{fakeAdapter: aave}
+	cfg := snapshot.DefaultConfig()
+	cfg.Enabled = true
+	performer := NewYieldIntelligencePerformer(logger,
+		WithAdapters(adapters.NewRegistry(adapter)),
+		WithSnapshots(snapshot.New(cfg, chains)),
+	)
+
+	result := runRiskAssessment(t, performer, `{"type":"risk_assessment","parameters":{"protocol":"aave_v3","chain_id":1,"assessment_type":"full","block_number":90}}`)
+	if result.BlockNumber != 90 || adapter.block(1) != 90 {
+		t.Errorf("Expected the market to be read at block 90, got %d and reads at %d", result.BlockNumber, adapter.block(1))
+	}
+	// the protocol TVL reads Base at its own finalized block, not Ethereum's block 90
+	if block := adapter.block(adapters.ChainIDBase); block != 500 {
+		t.Errorf("Expected the Base market to be read at its finalized block 500, got %d", block)
+	}
+	if result.Report == nil || result.Report.ProtocolTVL == nil {
+		t.Errorf("Expected the protocol TVL to be reported, got %+v", result.Report)
+	}
+}
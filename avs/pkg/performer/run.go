@@ -0,0 +1,55 @@
+package performer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+)
+
+// ErrTaskFailed is returned by RunTask for tasks that ran and answered with a failed
+// result
+var ErrTaskFailed = errors.New("task failed")
+
+// RunTask validates and handles a task of taskType with params, as the Ponos server
+// would, and returns its result. Tasks answered with an error result return it along
+// with ErrTaskFailed. Cancelling ctx drains the performer, which cancels the task.
+func (yip *YieldIntelligencePerformer) RunTask(ctx context.Context, taskType, taskID string, params json.RawMessage) ([]byte, error) {
+	payload, err := json.Marshal(struct {
+		Type       string          `json:"type"`
+		Parameters json.RawMessage `json:"parameters"`
+	}{taskType, params})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode task: %w", err)
+	}
+	task := &performerV1.TaskRequest{TaskId: []byte(taskID), Payload: payload}
+
+	if err := yip.ValidateTask(task); err != nil {
+		return nil, fmt.Errorf("invalid task: %w", err)
+	}
+
+	// an interrupt drains the performer at once, which cancels the task
+	stop := context.AfterFunc(ctx, func() {
+		cancelled, cancel := context.WithCancel(context.Background())
+		cancel()
+		_ = yip.Drain(cancelled)
+	})
+	defer stop()
+
+	resp, err := yip.HandleTask(task)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Result struct {
+			Status ResultStatus `json:"status"`
+		} `json:"result"`
+	}
+	if json.Unmarshal(resp.Result, &result) == nil && result.Result.Status == ResultStatusFailed {
+		return resp.Result, ErrTaskFailed
+	}
+	return resp.Result, nil
+}
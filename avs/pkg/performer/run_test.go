@@ -0,0 +1,61 @@
+package performer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"go.uber.org/zap"
+)
+
+func Test_RunTaskReturnsResult(t *testing.T) {
+	yip := NewYieldIntelligencePerformer(zap.NewNop(), WithAdapters(adapters.NewRegistry(newFakeAaveAdapter())))
+
+	params := json.RawMessage(`{"protocol":"aave_v3","token":"USDC","chain_id":1}`)
+	raw, err := yip.RunTask(context.Background(), "yield_monitoring", "local-1", params)
+	if err != nil {
+		t.Fatalf("RunTask failed: %v", err)
+	}
+
+	var result struct {
+		TaskType string                 `json:"task_type"`
+		Result   map[string]interface{} `json:"result"`
+	}
+	if err := json.Unmarshal(raw, &result); err != nil {
+		t.Fatalf("Expected a JSON result, got %q: %v", raw, err)
+	}
+	if result.TaskType != "yield_monitoring" || result.Result["protocol"] != "aave_v3" {
+		t.Errorf("Expected the yield monitoring result, got %v", result)
+	}
+}
+
+func Test_RunTaskRejectsInvalidTasks(t *testing.T) {
+	yip := NewYieldIntelligencePerformer(zap.NewNop(), WithAdapters(adapters.NewRegistry(newFakeAaveAdapter())))
+
+	raw, err := yip.RunTask(context.Background(), "yield_monitoring", "local-1", json.RawMessage(`{"token":"USDC"}`))
+	if err == nil || !strings.Contains(err.Error(), string(ErrorCodeValidation)) {
+		t.Errorf("Expected a validation error, got %v", err)
+	}
+	if raw != nil {
+		t.Errorf("Expected no result for an invalid task, got %q", raw)
+	}
+}
+
+func Test_RunTaskReportsFailedResults(t *testing.T) {
+	yip, account, _ := newSubmittingPerformer(t)
+	aave := newMovableAave()
+	aave.risk.Frozen = true
+	WithAdapters(adapters.NewRegistry(aave))(yip)
+
+	params := json.RawMessage(`{"user_address":"` + account.Hex() + `","nonce":"1","amount":"1000000000","target_protocol":"aave_v3","target_chain":1}`)
+	raw, err := yip.RunTask(context.Background(), "rebalance_execution", "local-1", params)
+	if !errors.Is(err, ErrTaskFailed) {
+		t.Errorf("Expected the refused rebalance to fail, got %v", err)
+	}
+	if !strings.Contains(string(raw), string(ErrorCodeRiskBlocked)) {
+		t.Errorf("Expected the error result to be returned, got %q", raw)
+	}
+}
@@ -0,0 +1,122 @@
+package performer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+	"github.com/najnomics/crosscow-avs/pkg/reputation"
+)
+
+const (
+	defaultSelfReportWindowHours = 7 * 24
+	maxSelfReportWindowHours     = 365 * 24
+)
+
+// TaskTally counts the tasks of a type the performer answered. Latencies are
+// percentiles of the milliseconds completed tasks took from being received to being
+// answered.
+type TaskTally struct {
+	TaskType     string            `json:"task_type,omitempty"`
+	Tasks        int               `json:"tasks"`
+	Completed    int               `json:"completed"`
+	Failed       int               `json:"failed"`
+	SuccessRate  canonical.Decimal `json:"success_rate"`
+	LatencyP50Ms int64             `json:"latency_p50_ms"`
+	LatencyP90Ms int64             `json:"latency_p90_ms"`
+	LatencyP99Ms int64             `json:"latency_p99_ms"`
+	LatencyMaxMs int64             `json:"latency_max_ms"`
+}
+
+// QuorumAgreement compares the completed tasks of a self report with the results their
+// quorum settled on in the task mailbox; see reputation.Agreement
+type QuorumAgreement struct {
+	Compared         int               `json:"compared"`
+	Disagreed        int               `json:"disagreed"`
+	DisagreementRate canonical.Decimal `json:"disagreement_rate"`
+	Pending          int               `json:"pending"`
+	Expired          int               `json:"expired"`
+	Unchecked        int               `json:"unchecked"`
+}
+
+// SelfReportResult is the result of a self_report task: the reputation of this operator
+// over the tasks it answered from From to To. Agreement is omitted when the task mailbox
+// is not read, and the report is partial when some of its reads failed.
+type SelfReportResult struct {
+	From      uint64           `json:"from"`
+	To        uint64           `json:"to"`
+	Total     TaskTally        `json:"total"`
+	TaskTypes []TaskTally      `json:"task_types"`
+	Agreement *QuorumAgreement `json:"agreement,omitempty"`
+	Status    ResultStatus     `json:"status"`
+}
+
+// handleSelfReport reports the success rate, latencies and quorum agreement of the tasks
+// answered over the last window_hours
+func (yip *YieldIntelligencePerformer) handleSelfReport(ctx context.Context, t *performerV1.TaskRequest, payload *TaskPayload) (interface{}, error) {
+	yip.log(ctx).Sugar().Infow("Processing self report task")
+
+	windowHours := paramUint64(payload, "window_hours")
+	if windowHours == 0 {
+		windowHours = defaultSelfReportWindowHours
+	}
+	report, err := yip.selfReport(ctx, time.Duration(windowHours)*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SelfReportResult{
+		From:      uint64(report.From.Unix()),
+		To:        uint64(report.To.Unix()),
+		Total:     taskTally(report.Tally),
+		TaskTypes: make([]TaskTally, 0, len(report.TaskTypes)),
+		Status:    ResultStatusCompleted,
+	}
+	for _, tally := range report.TaskTypes {
+		result.TaskTypes = append(result.TaskTypes, taskTally(tally))
+	}
+	if a := report.Agreement; a != nil {
+		result.Agreement = &QuorumAgreement{
+			Compared:         a.Compared,
+			Disagreed:        a.Disagreed,
+			DisagreementRate: canonical.Ratio(a.DisagreementRate),
+			Pending:          a.Pending,
+			Expired:          a.Expired,
+			Unchecked:        a.Unchecked,
+		}
+		if a.ReadErrors > 0 {
+			result.Status = ResultStatusPartial
+		}
+	}
+	return result, nil
+}
+
+// selfReport reports on the tasks the store recorded as answered over the last window
+func (yip *YieldIntelligencePerformer) selfReport(ctx context.Context, window time.Duration) (*reputation.Report, error) {
+	records, err := yip.tasks.ListTasks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load task records: %w", err)
+	}
+	to := time.Now().UTC()
+	return yip.reputation.Report(ctx, records, to.Add(-window), to)
+}
+
+func taskTally(t reputation.Tally) TaskTally {
+	return TaskTally{
+		TaskType:     t.TaskType,
+		Tasks:        t.Tasks,
+		Completed:    t.Completed,
+		Failed:       t.Failed,
+		SuccessRate:  canonical.Ratio(t.SuccessRate),
+		LatencyP50Ms: t.Latency.P50Ms,
+		LatencyP90Ms: t.Latency.P90Ms,
+		LatencyP99Ms: t.Latency.P99Ms,
+		LatencyMaxMs: t.Latency.MaxMs,
+	}
+}
+
+func (yip *YieldIntelligencePerformer) validateSelfReportTask(payload *TaskPayload) error {
+	return nil
+}
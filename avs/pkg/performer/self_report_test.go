@@ -0,0 +1,80 @@
+package performer
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/reputation"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+	"go.uber.org/zap"
+)
+
+// settledMailbox settles every task on result
+type settledMailbox struct {
+	result []byte
+}
+
+func (m *settledMailbox) Settled(ctx context.Context, taskHash common.Hash) (reputation.Status, []byte, error) {
+	return reputation.StatusVerified, m.result, nil
+}
+
+func Test_SelfReport(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+	performer := NewYieldIntelligencePerformer(logger, WithAdapters(adapters.NewRegistry(newFakeAaveAdapter())))
+
+	var monitoring YieldMonitoringResult
+	taskHash := string(common.HexToHash("0x01").Bytes())
+	for _, id := range []string{taskHash, "monitoring-2"} {
+		if err := runYieldMonitoring(t, performer, id, `{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1}}`, &monitoring); err != nil {
+			t.Fatalf("ValidateTask failed: %v", err)
+		}
+	}
+	// the adapter does not read markets on Base
+	failing := &performerV1.TaskRequest{TaskId: []byte("failing"), Payload: []byte(`{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":8453}}`)}
+	if _, err := performer.HandleTask(failing); err == nil {
+		t.Fatalf("Expected the task on Base to fail")
+	}
+
+	var result SelfReportResult
+	if err := runYieldMonitoring(t, performer, "report-1", `{"type":"self_report","parameters":{"window_hours":1}}`, &result); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	if result.Total.Tasks != 3 || result.Total.Failed != 1 || result.Total.SuccessRate.String() != "0.666667" || result.Agreement != nil {
+		t.Errorf("Expected two of three tasks to succeed, got %+v", result.Total)
+	}
+	if len(result.TaskTypes) != 1 || result.TaskTypes[0].TaskType != string(TaskTypeYieldMonitoring) || result.Status != ResultStatusCompleted {
+		t.Errorf("Expected the tasks tallied as yield monitoring, got %+v", result.TaskTypes)
+	}
+
+	// the quorum settled on another rate than this performer's
+	WithReputation(reputation.New(store.NewMemoryKV(), &settledMailbox{result: []byte(`{"result":{"supply_rate":"4.0000"}}`)}, 10))(performer)
+	if err := runYieldMonitoring(t, performer, "report-2", `{"type":"self_report","parameters":{}}`, &result); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	if a := result.Agreement; a == nil || a.Compared != 1 || a.Disagreed != 1 || a.DisagreementRate.String() != "1.000000" {
+		t.Errorf("Expected the mailbox task to disagree, got %+v", a)
+	}
+
+	ts := newAdminServer(t, performer, zap.NewAtomicLevel())
+	var report reputation.Report
+	if status := adminRequest(t, http.MethodGet, ts.URL+"/reputation?window_hours=24", "", &report); status != http.StatusOK {
+		t.Fatalf("Expected the reputation to be served, got %d", status)
+	}
+	if report.Tasks != 5 || len(report.TaskTypes) != 2 || report.Agreement == nil || report.Agreement.Disagreed != 1 {
+		t.Errorf("Expected the tasks and self reports answered so far, got %+v", report)
+	}
+	if status := adminRequest(t, http.MethodGet, ts.URL+"/reputation?window_hours=0", "", nil); status != http.StatusBadRequest {
+		t.Errorf("Expected an invalid window to be rejected, got %d", status)
+	}
+
+	if err := runYieldMonitoring(t, performer, "invalid", `{"type":"self_report","parameters":{"window_hours":9000}}`, &result); err == nil {
+		t.Errorf("Expected an invalid window_hours to be rejected")
+	}
+}
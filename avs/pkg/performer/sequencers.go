@@ -0,0 +1,76 @@
+package performer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+	"github.com/najnomics/crosscow-avs/pkg/sequencer"
+)
+
+// SequencerReport is the health of the sequencer of an L2. Feed and Since are absent for
+// chains whose sequencer is only checked through its blocks. Blocked sequencers keep
+// funds from moving to their chain.
+type SequencerReport struct {
+	ChainID          uint64            `json:"chain_id"`
+	Status           sequencer.Status  `json:"status"`
+	Feed             string            `json:"feed,omitempty"`
+	Since            uint64            `json:"since,omitempty"`
+	BlockTimeSeconds canonical.Decimal `json:"block_time_seconds"`
+	Blocked          bool              `json:"blocked"`
+	Reason           string            `json:"reason,omitempty"`
+}
+
+// sequencerReport returns the report of health
+func sequencerReport(health *sequencer.Health) SequencerReport {
+	report := SequencerReport{
+		ChainID:          health.ChainID,
+		Status:           health.Status,
+		Since:            health.Since,
+		BlockTimeSeconds: canonical.Score(health.BlockTime.Seconds()),
+		Blocked:          health.Status.Blocked(),
+		Reason:           health.Reason,
+	}
+	if health.Feed != (common.Address{}) {
+		report.Feed = health.Feed.Hex()
+	}
+	return report
+}
+
+// checkSequencers reads the sequencers of chainIDs, skipping chains without one. Chains
+// whose sequencer cannot be read are returned in failed.
+func (yip *YieldIntelligencePerformer) checkSequencers(ctx context.Context, chainIDs ...uint64) (reports []SequencerReport, failed map[uint64]error) {
+	if yip.sequencers == nil {
+		return nil, nil
+	}
+	for _, chainID := range chainIDs {
+		health, err := yip.sequencers.Check(ctx, chainID)
+		switch {
+		case err != nil:
+			if failed == nil {
+				failed = map[uint64]error{}
+			}
+			failed[chainID] = err
+		case health != nil:
+			reports = append(reports, sequencerReport(health))
+		}
+	}
+	return reports, failed
+}
+
+// checkSequencer refuses to move funds to chainID while its sequencer is down or stalled.
+// Moves are refused too when the sequencer cannot be read.
+func (yip *YieldIntelligencePerformer) checkSequencer(ctx context.Context, chainID uint64) error {
+	if yip.sequencers == nil {
+		return nil
+	}
+	health, err := yip.sequencers.Check(ctx, chainID)
+	switch {
+	case err != nil:
+		return newTaskError(ErrorCodeUpstreamUnavailable, fmt.Errorf("failed to check the sequencer of chain %d: %w", chainID, err))
+	case health != nil && health.Status.Blocked():
+		return newTaskError(ErrorCodeRiskBlocked, fmt.Errorf("sequencer of chain %d is %s: %s", chainID, health.Status, health.Reason))
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package performer
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/addressbook"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/chain/chaintest"
+	"github.com/najnomics/crosscow-avs/pkg/sequencer"
+)
+
+var uptimeFeedABI = chain.MustParseABI(`[{"name":"latestRoundData","type":"function","stateMutability":"view","inputs":[],"outputs":[
+	{"name":"roundId","type":"uint80"},{"name":"answer","type":"int256"},{"name":"startedAt","type":"uint256"},
+	{"name":"updatedAt","type":"uint256"},{"name":"answeredInRound","type":"uint80"}]}]`)
+
+// withBaseSequencer checks the sequencer of Base through the uptime feed of base, reporting
+// it down or up since since. The head of base is the current time.
+func withBaseSequencer(t *testing.T, performer *YieldIntelligencePerformer, base *chaintest.Contracts, down bool, since time.Time) {
+	t.Helper()
+	book := addressbook.Default()
+	feed := book.Address(adapters.ChainIDBase, addressbook.ProtocolChainlink, addressbook.ContractSequencerUptimeFeed)
+	answer := big.NewInt(0)
+	if down {
+		answer = big.NewInt(1)
+	}
+	base.SetHead(1_000, uint64(time.Now().Unix()))
+	base.Stub(t, feed, uptimeFeedABI, "latestRoundData", big.NewInt(1), answer, big.NewInt(since.Unix()), big.NewInt(since.Unix()), big.NewInt(1))
+	chains := chain.NewManager()
+	chains.Register(adapters.ChainIDBase, "base", base)
+	WithSequencerChecks(sequencer.NewFromConfig(sequencer.DefaultConfig(), chains, book))(performer)
+}
+
+func Test_CrossChainYieldCheckSequencers(t *testing.T) {
+	performer := newAllocationPerformer(t)
+	base := chaintest.NewContracts(adapters.ChainIDBase)
+	check := `{"type":"cross_chain_yield_check","parameters":{"source_chain":1,"target_chain":8453,"amount":"5000000000"}}`
+
+	withBaseSequencer(t, performer, base, true, time.Now().Add(-10*time.Minute))
+	result := runCrossChainYieldCheck(t, performer, "sequencer down", check)
+	if len(result.Sequencers) != 1 || result.Sequencers[0].ChainID != adapters.ChainIDBase || !result.Sequencers[0].Blocked || result.Sequencers[0].Status != sequencer.StatusDown {
+		t.Fatalf("Expected the Base sequencer reported down, got %+v", result.Sequencers)
+	}
+	if result.TargetProtocol != "" || result.TargetRate != nil {
+		t.Errorf("Expected no target recommended while its sequencer is down, got %s at %v", result.TargetProtocol, result.TargetRate)
+	}
+
+	withBaseSequencer(t, performer, base, false, time.Now().Add(-48*time.Hour))
+	result = runCrossChainYieldCheck(t, performer, "sequencer up", check)
+	if len(result.Sequencers) != 1 || result.Sequencers[0].Blocked || result.Sequencers[0].Feed == "" {
+		t.Fatalf("Expected the Base sequencer reported up, got %+v", result.Sequencers)
+	}
+	if result.TargetProtocol != adapters.ProtocolAaveV3 {
+		t.Errorf("Expected the target recommended once its sequencer is up, got %q", result.TargetProtocol)
+	}
+
+	if result := runCrossChainYieldCheck(t, newAllocationPerformer(t), "unchecked", check); result.Sequencers != nil {
+		t.Errorf("Expected no sequencer report unless checked, got %+v", result.Sequencers)
+	}
+}
+
+func Test_RebalanceRefusedWhileSequencerDown(t *testing.T) {
+	performer, account, ethereum, base := newSubmittingPerformerOnBase(t)
+	withBaseSequencer(t, performer, base, true, time.Now().Add(-10*time.Minute))
+
+	result := runFailedRebalance(t, performer, "sequencer down", `{"type":"rebalance_execution","parameters":{
+		"user_address":"`+account.Hex()+`","nonce":"1","amount":"1000000000","source_chain":1,"target_protocol":"aave_v3","target_chain":8453}}`)
+	if result.Error.Code != ErrorCodeRiskBlocked || !strings.Contains(result.Error.Message, "down") {
+		t.Errorf("Expected the down sequencer to block the rebalance, got %+v", result)
+	}
+	if len(ethereum.Sent()) != 0 {
+		t.Errorf("Expected no transaction to be sent")
+	}
+}
@@ -0,0 +1,111 @@
+package performer
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrShuttingDown is returned for tasks received after the performer started draining
+var ErrShuttingDown = errors.New("performer is shutting down")
+
+// lifecycle tracks the tasks in flight so shutdown can wait for them
+type lifecycle struct {
+	mu       sync.Mutex
+	draining bool
+	inflight sync.WaitGroup
+
+	// running describes the tasks in flight by a sequence number, since redeliveries of
+	// a task can be in flight at once
+	running map[uint64]InflightTask
+	seq     uint64
+
+	// ctx is the context tasks run in. It is cancelled when draining times out.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newLifecycle() *lifecycle {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &lifecycle{ctx: ctx, cancel: cancel, running: make(map[uint64]InflightTask)}
+}
+
+// InflightTask describes a task being handled
+type InflightTask struct {
+	TaskID    string    `json:"taskId"`
+	TaskType  TaskType  `json:"taskType"`
+	StartedAt time.Time `json:"startedAt"`
+}
+
+// accepting reports ErrShuttingDown once draining started
+func (l *lifecycle) accepting() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.draining {
+		return ErrShuttingDown
+	}
+	return nil
+}
+
+// begin registers a task in flight and returns the context it runs in, and the function
+// to call when it is done
+func (l *lifecycle) begin() (context.Context, func(), error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.draining {
+		return nil, nil, ErrShuttingDown
+	}
+	l.inflight.Add(1)
+	return l.ctx, l.inflight.Done, nil
+}
+
+// track lists a task as in flight until the returned function is called
+func (l *lifecycle) track(taskID string, taskType TaskType) func() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.seq++
+	seq := l.seq
+	l.running[seq] = InflightTask{TaskID: taskID, TaskType: taskType, StartedAt: time.Now().UTC()}
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		delete(l.running, seq)
+	}
+}
+
+// InflightTasks returns the tasks being handled, oldest first
+func (yip *YieldIntelligencePerformer) InflightTasks() []InflightTask {
+	yip.lifecycle.mu.Lock()
+	defer yip.lifecycle.mu.Unlock()
+	tasks := make([]InflightTask, 0, len(yip.lifecycle.running))
+	for _, task := range yip.lifecycle.running {
+		tasks = append(tasks, task)
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].StartedAt.Before(tasks[j].StartedAt) })
+	return tasks
+}
+
+// Drain stops accepting tasks and waits for the tasks in flight to finish. When ctx is
+// done first, the remaining tasks are cancelled and ctx's error is returned; they are
+// recorded as failed and may be redelivered.
+func (yip *YieldIntelligencePerformer) Drain(ctx context.Context) error {
+	yip.lifecycle.mu.Lock()
+	yip.lifecycle.draining = true
+	yip.lifecycle.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		yip.lifecycle.inflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		yip.lifecycle.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,98 @@
+package performer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+	"go.uber.org/zap"
+)
+
+// blockingAdapter holds market reads until release is closed or the task is cancelled
+type blockingAdapter struct {
+	*fakeAdapter
+	reading chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingAdapter) MarketState(ctx context.Context, chainID uint64) (*adapters.MarketState, error) {
+	b.reading <- struct{}{}
+	select {
+	case <-b.release:
+		return b.fakeAdapter.MarketState(ctx, chainID)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func startBlockedTask(t *testing.T) (*YieldIntelligencePerformer, *blockingAdapter, chan error) {
+	t.Helper()
+	adapter := &blockingAdapter{fakeAdapter: newFakeAaveAdapter(), reading: make(chan struct{}, 1), release: make(chan struct{})}
+	performer := NewYieldIntelligencePerformer(zap.NewNop(), WithAdapters(adapters.NewRegistry(adapter)))
+
+	handled := make(chan error, 1)
+	go func() {
+		_, err := performer.HandleTask(&performerV1.TaskRequest{
+			TaskId:  []byte("in-flight"),
+			Payload: []byte(`{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1}}`),
+		})
+		handled <- err
+	}()
+	<-adapter.reading
+	return performer, adapter, handled
+}
+
+func Test_DrainWaitsForTasksInFlight(t *testing.T) {
+	performer, adapter, handled := startBlockedTask(t)
+
+	drained := make(chan error, 1)
+	go func() { drained <- performer.Drain(context.Background()) }()
+
+	// new tasks are refused as soon as draining starts
+	task := &performerV1.TaskRequest{TaskId: []byte("late"), Payload: []byte(`{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1}}`)}
+	deadline := time.Now().Add(time.Second)
+	var taskErr *TaskError
+	for err := performer.ValidateTask(task); !errors.As(err, &taskErr) || taskErr.Code != ErrorCodeShuttingDown; err = performer.ValidateTask(task) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected new tasks to be refused while draining, got %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := performer.HandleTask(task); !errors.As(err, &taskErr) || !taskErr.Code.Retryable() {
+		t.Errorf("Expected the task to be refused as retryable, got %v", err)
+	}
+
+	select {
+	case err := <-drained:
+		t.Fatalf("Expected draining to wait for the task in flight, returned %v", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(adapter.release)
+	if err := <-handled; err != nil {
+		t.Errorf("Expected the task in flight to complete: %v", err)
+	}
+	if err := <-drained; err != nil {
+		t.Errorf("Drain failed: %v", err)
+	}
+}
+
+func Test_DrainCancelsTasksAfterTimeout(t *testing.T) {
+	performer, _, handled := startBlockedTask(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := performer.Drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected draining to time out, got %v", err)
+	}
+	if err := <-handled; err == nil {
+		t.Errorf("Expected the cancelled task to fail")
+	}
+	record, err := performer.tasks.GetTask(context.Background(), "in-flight")
+	if err != nil || record.Status == store.TaskStatusProcessing {
+		t.Errorf("Expected the cancelled task not to be left processing, got %+v (%v)", record, err)
+	}
+}
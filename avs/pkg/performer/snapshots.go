@@ -0,0 +1,52 @@
+package performer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/snapshot"
+)
+
+// blockRequest reads the block_number and block_hash parameters of a task
+func blockRequest(payload *TaskPayload) snapshot.Request {
+	req := snapshot.Request{Number: paramUint64(payload, "block_number")}
+	if hash := paramString(payload, "block_hash"); hash != "" {
+		req.Hash = common.HexToHash(hash)
+	}
+	return req
+}
+
+// pin pins the reads made with the returned context on chainID to the reference block of
+// req and returns the block, which is watched for reorgs with the result of the task.
+// Without snapshots reads stay at the latest block and the returned block is 0.
+func (yip *YieldIntelligencePerformer) pin(ctx context.Context, chainID uint64, req snapshot.Request) (context.Context, uint64, error) {
+	if yip.snapshots == nil {
+		return ctx, 0, nil
+	}
+	ctx, block, err := yip.snapshots.Pin(ctx, chainID, req)
+	if err != nil {
+		return ctx, 0, err
+	}
+	recordPin(ctx, chainID, block)
+	return ctx, block, nil
+}
+
+// validateBlockRequest checks the block_number and block_hash parameters the payload schema
+// accepted can be served. Block numbers
+// and hashes differ between chains, so they are only accepted by tasks reading a single
+// chain.
+func (yip *YieldIntelligencePerformer) validateBlockRequest(payload *TaskPayload, singleChain bool) error {
+	_, hasNumber := payload.Parameters["block_number"]
+	_, hasHash := payload.Parameters["block_hash"]
+	if !hasNumber && !hasHash {
+		return nil
+	}
+	if yip.snapshots == nil {
+		return fmt.Errorf("block_number and block_hash require snapshots, which are not configured on this performer")
+	}
+	if !singleChain {
+		return fmt.Errorf("block_number and block_hash require a single chain")
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package performer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+	"github.com/najnomics/crosscow-avs/pkg/snapshot"
+	"github.com/najnomics/crosscow-avs/pkg/tokens"
+	"github.com/najnomics/crosscow-avs/pkg/tracing"
+)
+
+// validateMonitoredToken checks the token of a yield_monitoring task, which is USDC or a
+// stablecoin enabled for comparison listed on chainID, ignored when zero
+func (yip *YieldIntelligencePerformer) validateMonitoredToken(payload *TaskPayload, chainID uint64) error {
+	symbol := paramString(payload, "token")
+	if !yip.stablecoins.Accepts(symbol) {
+		return validateToken(payload, chainID)
+	}
+	if chainID != 0 {
+		if _, err := tokens.Stablecoin(symbol, chainID); err != nil {
+			return fmt.Errorf("invalid token: %w", err)
+		}
+	}
+	return nil
+}
+
+// stablecoinMarkets keeps the markets of protocols reading other assets than USDC, on
+// the chains symbol is listed on
+func (yip *YieldIntelligencePerformer) stablecoinMarkets(symbol string, markets []market) []market {
+	var out []market
+	for _, m := range markets {
+		if _, err := yip.adapters.AssetReaderFor(m.protocol); err != nil {
+			continue
+		}
+		if _, err := tokens.Stablecoin(symbol, m.chainID); err != nil {
+			continue
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+// monitorStablecoin reads the supply rate of the symbol market of protocol m, to compare
+// it with USDC's. The total supply is in units of symbol like USDC amounts. Only the rate
+// is compared: it is neither cross validated, checked against a baseline nor kept in the
+// rate history, and incentives and supply caps are left out.
+func (yip *YieldIntelligencePerformer) monitorStablecoin(ctx context.Context, m market, symbol string, block snapshot.Request) (*YieldMonitoringResult, error) {
+	token, err := tokens.Stablecoin(symbol, m.chainID)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := yip.adapters.AssetReaderFor(m.protocol)
+	if err != nil {
+		return nil, err
+	}
+	ctx, reference, err := yip.pin(ctx, m.chainID, block)
+	if err != nil {
+		return nil, err
+	}
+
+	spanCtx, span := startAdapterSpan(ctx, "AssetMarketState", m)
+	state, err := reader.AssetMarketState(spanCtx, m.chainID, token.Address)
+	tracing.End(span, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s %s market on chain %d: %w", m.protocol, symbol, m.chainID, err)
+	}
+
+	supplyRate := ratePercent(yip.snapshots.Rate(state.Pool.SupplyRate()))
+	return &YieldMonitoringResult{
+		Protocol:    m.protocol,
+		Token:       symbol,
+		ChainID:     m.chainID,
+		SupplyRate:  &supplyRate,
+		Utilization: canonical.Ratio(state.Pool.Utilization()),
+		TotalSupply: canonical.NewDecimalFromInt(state.Pool.TotalSupply, int(token.Decimals), USDCDecimals),
+		BlockNumber: reference,
+		Status:      ResultStatusCompleted,
+	}, nil
+}
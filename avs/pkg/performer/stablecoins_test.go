@@ -0,0 +1,99 @@
+package performer
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/irm"
+	"github.com/najnomics/crosscow-avs/pkg/tokens"
+	"go.uber.org/zap"
+)
+
+// assetAdapter lends 50M of every other asset at 50% utilization next to the USDC markets
+// of the adapter it wraps
+type assetAdapter struct {
+	*fakeAdapter
+	read []common.Address
+}
+
+func (a *assetAdapter) AssetMarketState(ctx context.Context, chainID uint64, asset common.Address) (*adapters.MarketState, error) {
+	a.read = append(a.read, asset)
+	units := new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
+	return &adapters.MarketState{
+		Protocol: a.protocol,
+		ChainID:  chainID,
+		Pool: irm.Pool{
+			TotalSupply: new(big.Int).Mul(big.NewInt(50_000_000), units),
+			TotalBorrow: new(big.Int).Mul(big.NewInt(25_000_000), units),
+			Model:       &irm.KinkModel{OptimalUtilization: 0.9, Slope1: 0.09, Slope2: 0.6},
+		},
+	}, nil
+}
+
+func runYieldMonitoring(t *testing.T, performer *YieldIntelligencePerformer, id, payload string, result interface{}) error {
+	t.Helper()
+	task := &performerV1.TaskRequest{TaskId: []byte(id), Payload: []byte(payload)}
+	if err := performer.ValidateTask(task); err != nil {
+		return err
+	}
+	resp, err := performer.HandleTask(task)
+	if err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+	envelope := struct {
+		Result interface{} `json:"result"`
+	}{Result: result}
+	if err := json.Unmarshal(resp.Result, &envelope); err != nil {
+		t.Fatalf("Failed to decode result: %v", err)
+	}
+	return nil
+}
+
+func Test_YieldMonitoringStablecoins(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+	aave := &assetAdapter{fakeAdapter: newFakeAaveAdapter()}
+	aave.markets[8453] = aave.markets[1]
+	registry := adapters.NewRegistry(aave, &brokenAdapter{protocol: adapters.ProtocolCompoundV3, chainIDs: []uint64{1}})
+
+	performer := NewYieldIntelligencePerformer(logger, WithAdapters(registry))
+	dai := `{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","token":"DAI","chain_id":1}}`
+	if err := runYieldMonitoring(t, performer, "dai-disabled", dai, nil); err == nil {
+		t.Fatalf("Expected DAI to be rejected until stablecoins are enabled")
+	}
+
+	cfg := tokens.DefaultConfig()
+	cfg.Enabled = true
+	performer = NewYieldIntelligencePerformer(logger, WithAdapters(registry), WithStablecoins(cfg))
+	var result YieldMonitoringResult
+	if err := runYieldMonitoring(t, performer, "dai", dai, &result); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	// 50% utilization borrows at 5% and pays suppliers 2.5%
+	if result.Token != tokens.SymbolDAI || result.SupplyRate.String() != "2.5000" || result.TotalSupply.String() != "50000000.000000" || result.Status != ResultStatusCompleted {
+		t.Errorf("Expected the DAI market in DAI units, got %+v", result)
+	}
+	daiAddress, _ := tokens.Stablecoin(tokens.SymbolDAI, 1)
+	if len(aave.read) != 1 || aave.read[0] != daiAddress.Address {
+		t.Errorf("Expected the DAI reserve to be read, got %v", aave.read)
+	}
+
+	// USDT is not listed on Base, and compound only reads USDC
+	var multi MultiYieldMonitoringResult
+	if err := runYieldMonitoring(t, performer, "usdt-all", `{"type":"yield_monitoring","parameters":{"protocol":"all","token":"USDT"}}`, &multi); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	if len(multi.Markets) != 1 || multi.Markets[0].ChainID != 1 || len(multi.Failures) != 0 || multi.Status != ResultStatusCompleted {
+		t.Errorf("Expected the aave USDT market on Ethereum alone, got %+v", multi)
+	}
+	if err := runYieldMonitoring(t, performer, "usdt-base", `{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","token":"USDT","chain_id":8453}}`, nil); err == nil {
+		t.Errorf("Expected USDT on Base to be rejected")
+	}
+}
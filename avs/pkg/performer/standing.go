@@ -0,0 +1,26 @@
+package performer
+
+import (
+	"context"
+	"errors"
+
+	"github.com/najnomics/crosscow-avs/pkg/operator"
+)
+
+// checkStanding refuses execution tasks while the operator is not in good standing with
+// the AVS, so funds are never moved by an operator the AVS no longer counts on. They are
+// refused too when the standing cannot be read.
+func (yip *YieldIntelligencePerformer) checkStanding(ctx context.Context) error {
+	if yip.standing == nil {
+		return nil
+	}
+	err := yip.standing.Check(ctx)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, operator.ErrNotInGoodStanding):
+		return newTaskError(ErrorCodeNotInGoodStanding, err)
+	default:
+		return newTaskError(ErrorCodeUpstreamUnavailable, err)
+	}
+}
@@ -0,0 +1,47 @@
+package performer
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/chain/chaintest"
+	"github.com/najnomics/crosscow-avs/pkg/operator"
+)
+
+func Test_ExecutionRefusedOutOfGoodStanding(t *testing.T) {
+	performer, account, ethereum := newSubmittingPerformer(t)
+
+	allocationManager := common.HexToAddress("0x0000000000000000000000000000000000000a11")
+	memberABI := chain.MustParseABI(`[{"name":"isMemberOfOperatorSet","type":"function","stateMutability":"view","inputs":[
+		{"name":"operator","type":"address"},
+		{"name":"operatorSet","type":"tuple","components":[{"name":"avs","type":"address"},{"name":"id","type":"uint32"}]}],"outputs":[{"name":"","type":"bool"}]}]`)
+	registry := chaintest.NewContracts(1)
+	registry.Stub(t, allocationManager, memberABI, "isMemberOfOperatorSet", false)
+	chains := chain.NewManager()
+	chains.Register(1, "ethereum", registry)
+	cfg := operator.DefaultConfig()
+	cfg.ChainID, cfg.AVS, cfg.AllocationManager = 1, "0x00000000000000000000000000000000000000a5", allocationManager.Hex()
+	cfg.DelegationManager, cfg.KeyRegistrar = "0x0000000000000000000000000000000000000de1", "0x0000000000000000000000000000000000000ce7"
+	cfg.Standing.Enabled, cfg.Standing.Operator, cfg.Standing.CacheTTL = true, account.Hex(), time.Nanosecond
+	WithStanding(operator.NewStandingFromConfig(cfg, chains))(performer)
+
+	rebalance := `{"type":"rebalance_execution","parameters":{"user_address":"` + account.Hex() + `","nonce":"1","amount":"1000000000","target_protocol":"aave_v3","target_chain":1}}`
+	err := runRebalanceTask(t, performer, "ejected", rebalance, nil)
+	var taskErr *TaskError
+	if !errors.As(err, &taskErr) || taskErr.Code != ErrorCodeNotInGoodStanding || taskErr.Code.Retryable() {
+		t.Fatalf("Expected the rebalance to be refused, got %v", err)
+	}
+	if len(ethereum.Sent()) != 0 {
+		t.Errorf("Expected no transaction to be sent")
+	}
+
+	// the refused task neither consumed its nonce nor was recorded as answered
+	registry.Stub(t, allocationManager, memberABI, "isMemberOfOperatorSet", true)
+	var result RebalanceExecutionResult
+	if err := runRebalanceTask(t, performer, "ejected", rebalance, &result); err != nil || result.Execution == nil {
+		t.Errorf("Expected the rebalance to run once registered, got %+v, %v", result, err)
+	}
+}
@@ -0,0 +1,148 @@
+package performer
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+	"github.com/najnomics/crosscow-avs/pkg/irm"
+)
+
+// defaultStressShocksBps are the utilization shocks modeled unless the task sets
+// shocks_bps: 10, 20 and 30 points
+var defaultStressShocksBps = []uint64{1000, 2000, 3000}
+
+// StressScenario is a venue after borrowers draw utilization up by ShockBps. Rates are
+// annual percentages, amounts are in USDC. MaxAllocation is the largest deposit that
+// can still be withdrawn in full under the shock, which allocation_optimization caps
+// markets at when given stress_shock_bps. Withdrawable is the part of the task amount
+// held in the venue that can still be withdrawn.
+type StressScenario struct {
+	ShockBps           uint64             `json:"shock_bps"`
+	Utilization        canonical.Decimal  `json:"utilization"`
+	SupplyRate         canonical.Decimal  `json:"supply_rate"`
+	RateImpactBps      canonical.Decimal  `json:"rate_impact_bps"`
+	AvailableLiquidity canonical.Decimal  `json:"available_liquidity"`
+	MaxAllocation      canonical.Decimal  `json:"max_allocation"`
+	Withdrawable       *canonical.Decimal `json:"withdrawable,omitempty"`
+	WithdrawableShare  *canonical.Decimal `json:"withdrawable_share,omitempty"`
+}
+
+// VenueStress is the current state of a venue and its scenario table
+type VenueStress struct {
+	Protocol           string            `json:"protocol"`
+	ChainID            uint64            `json:"chain_id"`
+	Utilization        canonical.Decimal `json:"utilization"`
+	SupplyRate         canonical.Decimal `json:"supply_rate"`
+	AvailableLiquidity canonical.Decimal `json:"available_liquidity"`
+	Scenarios          []StressScenario  `json:"scenarios"`
+}
+
+// StressScenarioResult is the result of a stress_scenario task
+type StressScenarioResult struct {
+	Token     string             `json:"token"`
+	Amount    *canonical.Decimal `json:"amount,omitempty"`
+	ShocksBps []uint64           `json:"shocks_bps"`
+	Venues    []VenueStress      `json:"venues"`
+	Failures  []MarketFailure    `json:"failures"`
+	Status    ResultStatus       `json:"status"`
+}
+
+// handleStressScenario models what happens to the rate and withdrawability of every
+// selected lending market if utilization jumps by each of shocks_bps. Utilization is
+// raised by the shock and clamped at full; the rate follows the market's own curve, and
+// what can be withdrawn is the idle liquidity left. Markets that fail are reported
+// without failing the task.
+func (yip *YieldIntelligencePerformer) handleStressScenario(ctx context.Context, t *performerV1.TaskRequest, payload *TaskPayload) (interface{}, error) {
+	yip.log(ctx).Sugar().Infow("Processing stress scenario task")
+
+	shocks := paramUint64List(payload, "shocks_bps")
+	if len(shocks) == 0 {
+		shocks = defaultStressShocksBps
+	}
+	position := paramBaseUnits(payload, "amount")
+	markets := yip.selectedMarkets(payload)
+
+	result := &StressScenarioResult{
+		Token:     paramString(payload, "token"),
+		ShocksBps: shocks,
+		Venues:    []VenueStress{},
+		Failures:  []MarketFailure{},
+		Status:    ResultStatusCompleted,
+	}
+	if position != nil {
+		amount := usdcAmount(position)
+		result.Amount = &amount
+	}
+
+	for i, outcome := range yip.readMarkets(ctx, markets) {
+		if outcome.Err != nil {
+			result.Failures = append(result.Failures, marketFailure(markets[i], outcome.Err))
+			continue
+		}
+		pool := &outcome.Value.Pool
+		venue := VenueStress{
+			Protocol:           markets[i].protocol,
+			ChainID:            markets[i].chainID,
+			Utilization:        canonical.Ratio(pool.Utilization()),
+			SupplyRate:         ratePercent(pool.SupplyRate()),
+			AvailableLiquidity: usdcAmount(pool.AvailableLiquidity()),
+			Scenarios:          make([]StressScenario, 0, len(shocks)),
+		}
+		for _, bps := range shocks {
+			venue.Scenarios = append(venue.Scenarios, stressScenario(pool, bps, position))
+		}
+		result.Venues = append(result.Venues, venue)
+	}
+	if len(result.Venues) == 0 && len(markets) > 0 {
+		return nil, fmt.Errorf("failed to read any of %d markets", len(markets))
+	}
+	if len(result.Failures) > 0 {
+		result.Status = ResultStatusPartial
+	}
+	return result, nil
+}
+
+// stressScenario models pool under a utilization shock of bps, and how much of position,
+// when set, can be withdrawn under it
+func stressScenario(pool *irm.Pool, bps uint64, position *big.Int) StressScenario {
+	stress := pool.Stress(float64(bps) / 10000)
+	scenario := StressScenario{
+		ShockBps:           bps,
+		Utilization:        canonical.Ratio(stress.Utilization),
+		SupplyRate:         ratePercent(stress.SupplyRate),
+		RateImpactBps:      canonical.Score(stress.RateImpactBps),
+		AvailableLiquidity: usdcAmount(stress.AvailableLiquidity),
+		MaxAllocation:      usdcAmount(pool.MaxStressedDeposit(stress.Shock)),
+	}
+	if position != nil {
+		withdrawable := position
+		if stress.AvailableLiquidity.Cmp(withdrawable) < 0 {
+			withdrawable = stress.AvailableLiquidity
+		}
+		amount := usdcAmount(withdrawable)
+		share, _ := new(big.Rat).SetFrac(withdrawable, position).Float64()
+		ratio := canonical.Ratio(share)
+		scenario.Withdrawable, scenario.WithdrawableShare = &amount, &ratio
+	}
+	return scenario
+}
+
+func (yip *YieldIntelligencePerformer) validateStressScenarioTask(payload *TaskPayload) error {
+	if err := validateToken(payload, paramUint64List(payload, "chain_ids")...); err != nil {
+		return err
+	}
+
+	if err := yip.validateMarketSelection(payload); err != nil {
+		return err
+	}
+
+	if raw, present := payload.Parameters["amount"]; present {
+		if err := checkAmount(raw); err != nil {
+			return fmt.Errorf("invalid amount: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package performer
+
+import (
+	"encoding/json"
+	"testing"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+)
+
+func Test_StressScenario(t *testing.T) {
+	performer := newAllocationPerformer(t)
+
+	task := &performerV1.TaskRequest{
+		TaskId:  []byte("stress-task"),
+		Payload: []byte(`{"type":"stress_scenario","parameters":{"token":"USDC","chain_ids":[1],"shocks_bps":[1000,3000],"amount":"15000000000000"}}`),
+	}
+	if err := performer.ValidateTask(task); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	resp, err := performer.HandleTask(task)
+	if err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+	var envelope struct {
+		Result StressScenarioResult `json:"result"`
+	}
+	if err := json.Unmarshal(resp.Result, &envelope); err != nil {
+		t.Fatalf("Failed to decode result: %v", err)
+	}
+	result := envelope.Result
+	if len(result.Venues) != 1 || result.Status != ResultStatusPartial || len(result.Failures) != 1 {
+		t.Fatalf("Expected the aave market and the unreadable compound market, got %+v", result)
+	}
+
+	// the aave market is 80% utilized with 20M idle
+	moderate, severe := result.Venues[0].Scenarios[0], result.Venues[0].Scenarios[1]
+	if moderate.Utilization.String() != "0.900000" || moderate.AvailableLiquidity.String() != "10000000.000000" {
+		t.Errorf("Expected a 10 point shock to leave 10M idle at 90%%, got %+v", moderate)
+	}
+	if moderate.SupplyRate.Cmp(result.Venues[0].SupplyRate) <= 0 {
+		t.Errorf("Expected the shock to raise the supply rate, got %+v", moderate)
+	}
+	if moderate.Withdrawable.String() != "10000000.000000" || moderate.WithdrawableShare.String() != "0.666667" {
+		t.Errorf("Expected two thirds of the 15M position to stay withdrawable, got %+v", moderate)
+	}
+	if severe.AvailableLiquidity.Rat().Sign() != 0 || severe.MaxAllocation.Rat().Sign() != 0 || severe.WithdrawableShare.Rat().Sign() != 0 {
+		t.Errorf("Expected a 30 point shock to lock the market, got %+v", severe)
+	}
+
+	for name, params := range map[string]string{
+		"zero shock":       `"token":"USDC","shocks_bps":[0]`,
+		"shock range":      `"token":"USDC","shocks_bps":[10001]`,
+		"no shocks":        `"token":"USDC","shocks_bps":[]`,
+		"amount":           `"token":"USDC","amount":"-1"`,
+		"unknown protocol": `"token":"USDC","protocols":["euler"]`,
+		"optimizer cap":    `"token":"USDC","amount":"1000000000","stress_shock_bps":0.5`,
+	} {
+		taskType := "stress_scenario"
+		if name == "optimizer cap" {
+			taskType = "allocation_optimization"
+		}
+		task := &performerV1.TaskRequest{TaskId: []byte(name), Payload: []byte(`{"type":"` + taskType + `","parameters":{` + params + `}}`)}
+		if err := performer.ValidateTask(task); err == nil {
+			t.Errorf("%s: expected the task to be rejected", name)
+		}
+	}
+}
+
+func Test_AllocationOptimizationStressCap(t *testing.T) {
+	performer := newAllocationPerformer(t)
+
+	task := &performerV1.TaskRequest{
+		TaskId:  []byte("allocate-stressed"),
+		Payload: []byte(`{"type":"allocation_optimization","parameters":{"token":"USDC","amount":"300000000000000","stress_shock_bps":1000}}`),
+	}
+	if err := performer.ValidateTask(task); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	resp, err := performer.HandleTask(task)
+	if err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+	var envelope struct {
+		Result AllocationOptimizationResult `json:"result"`
+	}
+	if err := json.Unmarshal(resp.Result, &envelope); err != nil {
+		t.Fatalf("Failed to decode result: %v", err)
+	}
+	// each market keeps a deposit of up to 100M withdrawable through a 10 point shock
+	result := envelope.Result
+	if len(result.Allocations) != 2 {
+		t.Fatalf("Expected both aave markets, got %+v", result.Allocations)
+	}
+	for _, a := range result.Allocations {
+		if a.StressCap == nil || a.Amount.Cmp(*a.StressCap) > 0 {
+			t.Errorf("Expected the allocation to stay under its stress cap, got %+v", a)
+		}
+	}
+	if result.Unallocated.Rat().Sign() <= 0 {
+		t.Errorf("Expected what no market can take under the shock to stay unallocated, got %s", result.Unallocated)
+	}
+}
@@ -0,0 +1,134 @@
+package performer
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/najnomics/crosscow-avs/pkg/admin"
+)
+
+// TaskAPIResponse is the answer of the task API: the id the task ran under and its
+// result as the AVS would get it, as JSON, or hex encoded when the task asked for ABI
+// encoded results
+type TaskAPIResponse struct {
+	TaskID    string          `json:"task_id"`
+	Result    json.RawMessage `json:"result,omitempty"`
+	ResultABI hexutil.Bytes   `json:"result_abi,omitempty"`
+}
+
+// TaskAPIError is the answer of the task API to a task that failed
+type TaskAPIError struct {
+	TaskID string      `json:"task_id,omitempty"`
+	Error  ErrorReport `json:"error"`
+}
+
+// TaskAPIHandler serves the task interface of performer:
+//
+//	POST  /v1/tasks  runs a task payload, signed or not, as the AVS would send it
+//
+// Tasks run under a fresh id through ValidateTask and HandleTask, so authorization,
+// quotas and caching apply as they do to AVS tasks. The API serves previews: tasks that
+// move funds or plan to are refused, and rebalance_execution only runs as a dry run.
+func TaskAPIHandler(performer *YieldIntelligencePerformer) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/tasks", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeTaskAPIError(w, "", newTaskError(ErrorCodeValidation, fmt.Errorf("payload exceeds %d bytes", tooLarge.Limit)))
+				return
+			}
+			writeTaskAPIError(w, "", newTaskError(ErrorCodeValidation, fmt.Errorf("failed to read payload: %w", err)))
+			return
+		}
+		id := make([]byte, 16)
+		if _, err := rand.Read(id); err != nil {
+			writeTaskAPIError(w, "", newTaskError(ErrorCodeInternal, err))
+			return
+		}
+		t := &performerV1.TaskRequest{TaskId: []byte("api-" + hex.EncodeToString(id)), Payload: body}
+
+		payload, err := parseTaskPayload(t)
+		if err != nil {
+			writeTaskAPIError(w, string(t.TaskId), newTaskError(ErrorCodeValidation, err))
+			return
+		}
+		if err := previewOnly(payload); err != nil {
+			writeTaskAPIError(w, string(t.TaskId), err)
+			return
+		}
+		if err := performer.ValidateTask(t); err != nil {
+			writeTaskAPIError(w, string(t.TaskId), err)
+			return
+		}
+		resp, err := performer.HandleTask(t)
+		if err != nil {
+			writeTaskAPIError(w, string(t.TaskId), err)
+			return
+		}
+		answer := TaskAPIResponse{TaskID: string(t.TaskId)}
+		if json.Valid(resp.Result) {
+			answer.Result = resp.Result
+		} else {
+			answer.ResultABI = resp.Result
+		}
+		admin.WriteJSON(w, http.StatusOK, answer)
+	})
+	return mux
+}
+
+// previewOnly refuses tasks the task API does not run: those submitting transactions or
+// storing plans a commit could execute, alone or in a batch
+func previewOnly(payload *TaskPayload) error {
+	switch payload.Type {
+	case TaskTypeRebalanceExecution:
+		if !paramBool(payload, "dry_run") {
+			return newTaskError(ErrorCodeUnauthorized, fmt.Errorf("rebalance_execution is only served as a dry run over the task API"))
+		}
+	case TaskTypeRebalancePlan, TaskTypeRebalanceCommit, TaskTypeResumeExecution, TaskTypeTransferRecovery:
+		return newTaskError(ErrorCodeUnauthorized, fmt.Errorf("%s is not served over the task API", payload.Type))
+	case TaskTypeBatch:
+		tasks, err := batchTasks(payload)
+		if err != nil {
+			return newTaskError(ErrorCodeValidation, err)
+		}
+		for i, sub := range tasks {
+			if err := previewOnly(sub); err != nil {
+				return fmt.Errorf("tasks[%d]: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// writeTaskAPIError answers err with the status of its code
+func writeTaskAPIError(w http.ResponseWriter, taskID string, err error) {
+	taskErr := classifyError(err)
+	status := http.StatusInternalServerError
+	switch taskErr.Code {
+	case ErrorCodeValidation:
+		status = http.StatusBadRequest
+	case ErrorCodeUnauthorized, ErrorCodeNotInGoodStanding:
+		status = http.StatusForbidden
+	case ErrorCodeRateLimited:
+		status = http.StatusTooManyRequests
+	case ErrorCodeShuttingDown, ErrorCodeHalted:
+		status = http.StatusServiceUnavailable
+	case ErrorCodeUpstreamUnavailable:
+		status = http.StatusBadGateway
+	case ErrorCodeUnprofitable, ErrorCodeRiskBlocked, ErrorCodePolicyViolation, ErrorCodeInsufficientLiquidity:
+		status = http.StatusUnprocessableEntity
+	}
+	admin.WriteJSON(w, status, TaskAPIError{
+		TaskID: taskID,
+		Error:  ErrorReport{Code: taskErr.Code, Message: taskErr.Err.Error(), Retryable: taskErr.Code.Retryable()},
+	})
+}
@@ -0,0 +1,69 @@
+package performer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/najnomics/crosscow-avs/pkg/pricefeed"
+	"github.com/najnomics/crosscow-avs/pkg/taskapi"
+	"go.uber.org/zap"
+)
+
+func newTaskAPIServer(t *testing.T, performer *YieldIntelligencePerformer) *httptest.Server {
+	t.Helper()
+	cfg := taskapi.DefaultConfig()
+	server, err := taskapi.NewServer(&cfg, TaskAPIHandler(performer), zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewServer failed: %v", err)
+	}
+	ts := httptest.NewServer(server.Handler())
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func Test_TaskAPIRunsTasks(t *testing.T) {
+	sources := []pricefeed.Source{
+		&fakePriceSource{name: "chainlink:1", kind: pricefeed.SourceKindOracle, chainID: 1, price: "0.99990000"},
+	}
+	ts := newTaskAPIServer(t, NewYieldIntelligencePerformer(zap.NewNop(), WithPriceSources(sources)))
+
+	var answer struct {
+		TaskID string `json:"task_id"`
+		Result struct {
+			TaskType TaskType              `json:"task_type"`
+			Result   DepegMonitoringResult `json:"result"`
+		} `json:"result"`
+		Error *ErrorReport `json:"error"`
+	}
+	status := adminRequest(t, http.MethodPost, ts.URL+"/v1/tasks", `{"type":"depeg_monitoring","parameters":{"token":"USDC"}}`, &answer)
+	if status != http.StatusOK || !strings.HasPrefix(answer.TaskID, "api-") || answer.Error != nil {
+		t.Fatalf("Expected the task to run, got %d %+v", status, answer)
+	}
+	if answer.Result.TaskType != TaskTypeDepegMonitoring || answer.Result.Result.Token != "USDC" {
+		t.Errorf("Expected the depeg result, got %+v", answer.Result)
+	}
+}
+
+func Test_TaskAPIRefusesFundMovements(t *testing.T) {
+	ts := newTaskAPIServer(t, NewYieldIntelligencePerformer(zap.NewNop()))
+
+	for name, tc := range map[string]struct {
+		payload string
+		status  int
+		code    ErrorCode
+	}{
+		"malformed": {payload: `{"type":`, status: http.StatusBadRequest, code: ErrorCodeValidation},
+		"unknown":   {payload: `{"type":"mint","parameters":{}}`, status: http.StatusBadRequest, code: ErrorCodeValidation},
+		"rebalance": {payload: `{"type":"rebalance_execution","parameters":{"amount":"1000000000","target_protocol":"aave_v3","target_chain":1}}`, status: http.StatusForbidden, code: ErrorCodeUnauthorized},
+		"plan":      {payload: `{"type":"rebalance_plan","parameters":{}}`, status: http.StatusForbidden, code: ErrorCodeUnauthorized},
+		"batched":   {payload: `{"type":"batch","parameters":{"tasks":[{"type":"resume_execution","parameters":{}}]}}`, status: http.StatusForbidden, code: ErrorCodeUnauthorized},
+	} {
+		var answer TaskAPIError
+		status := adminRequest(t, http.MethodPost, ts.URL+"/v1/tasks", tc.payload, &answer)
+		if status != tc.status || answer.Error.Code != tc.code {
+			t.Errorf("%s: expected %d %s, got %d %+v", name, tc.status, tc.code, status, answer)
+		}
+	}
+}
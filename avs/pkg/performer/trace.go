@@ -0,0 +1,39 @@
+package performer
+
+import (
+	"fmt"
+
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+)
+
+// ResultTrace lists the contract calls a result was computed from, for challengers to
+// replay at the blocks they read and check the result byte for byte. Markets served from
+// the indexer or a recent market snapshot made no calls, and are not in it.
+type ResultTrace struct {
+	Calls []chain.TracedCall `json:"calls"`
+}
+
+// validateTrace checks the include_trace parameter. Traces are part of the JSON envelope,
+// which ABI encoded results do not have.
+func validateTrace(payload *TaskPayload) error {
+	raw, present := payload.Parameters["include_trace"]
+	if !present {
+		return nil
+	}
+	include, ok := raw.(bool)
+	if !ok {
+		return fmt.Errorf("invalid include_trace: must be a boolean")
+	}
+	if format, _ := resultFormat(payload); include && format != ResultFormatJSON {
+		return fmt.Errorf("include_trace is only supported for %s results", ResultFormatJSON)
+	}
+	return nil
+}
+
+// resultTrace returns the trace of the calls recorded in trace, nil without one
+func resultTrace(trace *chain.CallTrace) *ResultTrace {
+	if trace == nil {
+		return nil
+	}
+	return &ResultTrace{Calls: trace.Calls()}
+}
@@ -0,0 +1,101 @@
+package performer
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/chain/chaintest"
+	"go.uber.org/zap"
+)
+
+var totalSupplyABI = chain.MustParseABI(`[{"name":"totalSupply","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]}]`)
+
+// readingAdapter reads the total supply of a token through chains before serving the
+// state of the fake aave market
+type readingAdapter struct {
+	*fakeAdapter
+	chains *chain.Manager
+	token  common.Address
+}
+
+func (r *readingAdapter) MarketState(ctx context.Context, chainID uint64) (*adapters.MarketState, error) {
+	client, err := r.chains.Client(chainID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := chain.CallUint(ctx, client, r.token, totalSupplyABI, "totalSupply"); err != nil {
+		return nil, err
+	}
+	return r.fakeAdapter.MarketState(ctx, chainID)
+}
+
+func Test_ResultTrace(t *testing.T) {
+	token := common.HexToAddress("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48")
+	contracts := chaintest.NewContracts(1)
+	contracts.Stub(t, token, totalSupplyABI, "totalSupply", big.NewInt(42))
+	chains := chain.NewManager()
+	chains.Register(1, "ethereum", contracts)
+	adapter := &readingAdapter{fakeAdapter: newFakeAaveAdapter(), chains: chains, token: token}
+	performer := NewYieldIntelligencePerformer(zap.NewNop(), WithAdapters(adapters.NewRegistry(adapter)))
+
+	handle := func(id, parameters string) []byte {
+		t.Helper()
+		task := &performerV1.TaskRequest{TaskId: []byte(id), Payload: []byte(`{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1` + parameters + `}}`)}
+		if err := performer.ValidateTask(task); err != nil {
+			t.Fatalf("ValidateTask failed: %v", err)
+		}
+		resp, err := performer.HandleTask(task)
+		if err != nil {
+			t.Fatalf("HandleTask failed: %v", err)
+		}
+		return resp.Result
+	}
+
+	var envelope struct {
+		Trace *ResultTrace `json:"trace"`
+	}
+	if err := json.Unmarshal(handle("untraced", ""), &envelope); err != nil || envelope.Trace != nil {
+		t.Fatalf("Expected no trace without include_trace, got %+v: %v", envelope.Trace, err)
+	}
+
+	if err := json.Unmarshal(handle("traced", `,"include_trace":true`), &envelope); err != nil || envelope.Trace == nil {
+		t.Fatalf("Expected a trace, got %v", err)
+	}
+	output, err := totalSupplyABI.Methods["totalSupply"].Outputs.Pack(big.NewInt(42))
+	if err != nil {
+		t.Fatalf("Failed to pack output: %v", err)
+	}
+	calls := envelope.Trace.Calls
+	if len(calls) != 1 {
+		t.Fatalf("Expected the totalSupply call, got %+v", calls)
+	}
+	call := calls[0]
+	if call.ChainID != 1 || call.Address != token || call.Block != 100 || string(call.Selector) != string(totalSupplyABI.Methods["totalSupply"].ID) ||
+		call.ResultHash != crypto.Keccak256Hash(output) {
+		t.Errorf("Unexpected traced call %+v", call)
+	}
+}
+
+func Test_ResultTraceValidation(t *testing.T) {
+	performer := NewYieldIntelligencePerformer(zap.NewNop(), WithAdapters(adapters.NewRegistry(newFakeAaveAdapter())))
+
+	const monitoring = `{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1,`
+	testCases := map[string]string{
+		"not a boolean":  monitoring + `"include_trace":"yes"}}`,
+		"abi result":     monitoring + `"include_trace":true,"result_format":"abi"}}`,
+		"batch sub-task": `{"type":"batch","parameters":{"tasks":[` + monitoring + `"include_trace":true}}]}}`,
+	}
+	for name, payload := range testCases {
+		task := &performerV1.TaskRequest{TaskId: []byte(name), Payload: []byte(payload)}
+		if err := performer.ValidateTask(task); err == nil {
+			t.Errorf("%s: expected validation to fail", name)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package performer
+
+import (
+	"testing"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+)
+
+func Test_HandleTaskIsTraced(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	performer := NewYieldIntelligencePerformer(zap.NewNop(), WithAdapters(adapters.NewRegistry(newFakeAaveAdapter())))
+	task := &performerV1.TaskRequest{TaskId: []byte("traced"), Payload: []byte(`{"type":"yield_monitoring",
+		"parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1}}`)}
+	if err := performer.ValidateTask(task); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	if _, err := performer.HandleTask(task); err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	for _, name := range []string{"ValidateTask", "HandleTask", "handle yield_monitoring", "adapter aave_v3 MarketState"} {
+		if _, ok := spans[name]; !ok {
+			t.Fatalf("Expected a %q span, got %v", name, spans)
+		}
+	}
+
+	// the adapter read nests under the handler, which nests under the task
+	parents := map[string]string{
+		"handle yield_monitoring":     "HandleTask",
+		"adapter aave_v3 MarketState": "handle yield_monitoring",
+	}
+	for child, parent := range parents {
+		if spans[child].Parent().SpanID() != spans[parent].SpanContext().SpanID() {
+			t.Errorf("Expected %q to be a child of %q", child, parent)
+		}
+	}
+	if spans["HandleTask"].SpanContext().TraceID() != spans["adapter aave_v3 MarketState"].SpanContext().TraceID() {
+		t.Error("Expected the task to be a single trace")
+	}
+}
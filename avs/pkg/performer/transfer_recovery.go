@@ -0,0 +1,247 @@
+package performer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/cctp"
+	"github.com/najnomics/crosscow-avs/pkg/notify"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+)
+
+// Actions transfer recovery takes on a transfer
+const (
+	// RecoveryNone leaves transfers that are not stuck: minted, or within the SLA
+	RecoveryNone = "none"
+
+	// RecoveryResubmitted submits the mint of a stuck transfer again, with the
+	// attestation Circle serves now
+	RecoveryResubmitted = "resubmitted"
+
+	// RecoveryEscalated alerts operators to a stuck transfer that needs manual
+	// intervention
+	RecoveryEscalated = "escalated"
+)
+
+// TransferRecoveryResult is the result of a transfer_recovery task: the CCTP transfer of
+// an execution, how long its burn has waited to be minted and what was done about it.
+// Attestation is the status of Circle's attestation of the burn, only read once the
+// transfer is stuck, and Attempts how many times its mint was submitted again so far.
+type TransferRecoveryResult struct {
+	ExecutionID   string              `json:"execution_id"`
+	SourceChain   uint64              `json:"source_chain"`
+	TargetChain   uint64              `json:"target_chain"`
+	Burn          string              `json:"burn"`
+	WaitedSeconds uint64              `json:"waited_seconds"`
+	SLASeconds    uint64              `json:"sla_seconds"`
+	Stuck         bool                `json:"stuck"`
+	Attestation   string              `json:"attestation,omitempty"`
+	Attempts      int                 `json:"attempts"`
+	Action        string              `json:"action"`
+	Reason        string              `json:"reason,omitempty"`
+	Execution     *RebalanceExecution `json:"execution"`
+	Status        ResultStatus        `json:"status"`
+}
+
+// handleTransferRecovery recovers the CCTP transfer of the rebalance execution_id names
+// when it is stuck: its burn is final, but its mint did not land on the target chain
+// within the SLA. The mint is submitted again with the attestation Circle serves now, up
+// to the configured attempts; transfers Circle has not attested, or whose mint keeps
+// failing, are escalated with a transfer_stuck alert.
+func (yip *YieldIntelligencePerformer) handleTransferRecovery(ctx context.Context, t *performerV1.TaskRequest, payload *TaskPayload) (interface{}, error) {
+	yip.log(ctx).Sugar().Infow("Processing transfer recovery task")
+
+	if err := yip.checkStanding(ctx); err != nil {
+		return nil, err
+	}
+	if err := yip.checkHalt(ctx); err != nil {
+		return nil, err
+	}
+	return yip.recoverExecution(ctx, paramString(payload, "execution_id"))
+}
+
+// RecoverTransfers recovers the stuck transfers of every execution waiting for its mint,
+// as transfer_recovery tasks do, and raises transfer_stuck alerts for those escalated.
+// Executions a task is carrying on are left to it. Nothing is recovered while funds are
+// halted or the operator is not in good standing.
+func (yip *YieldIntelligencePerformer) RecoverTransfers(ctx context.Context) (int, error) {
+	if yip.transactions == nil {
+		return 0, nil
+	}
+	if err := yip.checkStanding(ctx); err != nil {
+		return 0, err
+	}
+	if err := yip.checkHalt(ctx); err != nil {
+		return 0, err
+	}
+	ids, err := yip.executions.unminted(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	stuck := 0
+	var firstErr error
+	for _, id := range ids {
+		result, err := yip.recoverExecution(ctx, id)
+		if classifyError(err).Code == ErrorCodeExecutionInProgress {
+			continue
+		}
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("execution %s: %w", id, err)
+			}
+			continue
+		}
+		if !result.Stuck {
+			continue
+		}
+		stuck++
+		yip.log(ctx).Sugar().Warnw("Recovered stuck transfer", "executionId", id, "action", result.Action, "attempts", result.Attempts, "reason", result.Reason)
+		if result.Action == RecoveryEscalated {
+			yip.notifier.Notify(transferStuckEvent(result))
+		}
+	}
+	return stuck, firstErr
+}
+
+// recoverExecution recovers the transfer of execution id under its lock
+func (yip *YieldIntelligencePerformer) recoverExecution(ctx context.Context, id string) (*TransferRecoveryResult, error) {
+	ctx, unlock, err := yip.lockExecution(ctx, prefixExecution+id)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	yip.executions.mu.Lock()
+	defer yip.executions.mu.Unlock()
+
+	record, err := yip.executions.get(ctx, id)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, newTaskError(ErrorCodeValidation, fmt.Errorf("unknown execution %s: executions are only recovered by the performers that started them", id))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return yip.recoverTransfer(ctx, record, time.Now())
+}
+
+// recoverTransfer recovers the transfer of record when it is stuck at now
+func (yip *YieldIntelligencePerformer) recoverTransfer(ctx context.Context, record *executionRecord, now time.Time) (*TransferRecoveryResult, error) {
+	route, burn := record.Route.route(), record.burn()
+	if burn == nil || route.fallback != nil {
+		return nil, newTaskError(ErrorCodeValidation, fmt.Errorf("execution %s burned nothing through CCTP", record.Execution.ID))
+	}
+	sla := yip.recovery.SLA
+	waited := now.Sub(time.Unix(record.BurnedAt, 0))
+	result := &TransferRecoveryResult{
+		ExecutionID:   record.Execution.ID,
+		SourceChain:   route.sourceChain,
+		TargetChain:   route.targetChain,
+		Burn:          burn.Hash,
+		WaitedSeconds: uint64(max(waited, 0) / time.Second),
+		SLASeconds:    uint64(sla / time.Second),
+		Attempts:      record.Recoveries,
+		Action:        RecoveryNone,
+		Execution:     record.Execution,
+		Status:        ResultStatusCompleted,
+	}
+	if !record.awaitingMint() || waited <= sla {
+		return result, nil
+	}
+
+	result.Stuck = true
+	escalate := func(reason string, args ...interface{}) (*TransferRecoveryResult, error) {
+		result.Action, result.Reason = RecoveryEscalated, fmt.Sprintf(reason, args...)
+		return result, nil
+	}
+	if yip.attestations == nil {
+		return escalate("the attestation of the burn cannot be read by this performer")
+	}
+	message, err := yip.attestations.Message(ctx, route.sourceChain, common.HexToHash(burn.Hash))
+	if err != nil {
+		return escalate("the attestation service could not be read: %v", err)
+	}
+	result.Attestation = message.Status
+	if message.Status != cctp.AttestationComplete {
+		return escalate("Circle has not attested the burn after %s", waited.Truncate(time.Second))
+	}
+	if record.Recoveries >= yip.recovery.MaxAttempts {
+		return escalate("the mint did not land after being submitted again %d times", record.Recoveries)
+	}
+
+	record.Recoveries++
+	result.Attempts = record.Recoveries
+	_, status, err := yip.resume(ctx, record)
+	if err != nil {
+		// the attempt counts even though nothing was recorded by the execution
+		if putErr := yip.executions.put(ctx, record); putErr != nil {
+			yip.log(ctx).Sugar().Errorw("Failed to record transfer recovery", "executionId", record.Execution.ID, "error", putErr)
+		}
+		return nil, err
+	}
+	result.Action, result.Status = RecoveryResubmitted, status
+	return result, nil
+}
+
+// burn returns the last CCTP burn the execution of r submitted, nil when none
+func (r *executionRecord) burn() *SubmittedTransaction {
+	var burn *SubmittedTransaction
+	for i, tx := range r.Execution.Transactions {
+		if tx.Action == ActionBurn {
+			burn = &r.Execution.Transactions[i]
+		}
+	}
+	return burn
+}
+
+// awaitingMint reports whether the CCTP burn of r is final while its mint did not land
+// yet. Executions whose burn time is unknown are never awaiting.
+func (r *executionRecord) awaitingMint() bool {
+	burn := r.burn()
+	if burn == nil || burn.Status != TransactionConfirmed || r.BurnedAt == 0 || r.Route.Fallback != nil {
+		return false
+	}
+	legs, _ := executionLegs(r.Execution)
+	for _, leg := range legs {
+		if leg.Leg == ActionMint {
+			return leg.Status != LegCompleted
+		}
+	}
+	return false
+}
+
+// unminted returns the ids of the executions awaiting their mint
+func (s *executionStore) unminted(ctx context.Context) ([]string, error) {
+	var ids []string
+	err := s.kv.Iterate(ctx, []byte(prefixExecution), func(key, value []byte) error {
+		var record executionRecord
+		if err := json.Unmarshal(value, &record); err != nil {
+			return fmt.Errorf("failed to decode execution %s: %w", key, err)
+		}
+		if record.awaitingMint() {
+			ids = append(ids, record.Execution.ID)
+		}
+		return nil
+	})
+	return ids, err
+}
+
+// transferStuckEvent is the transfer_stuck alert of an escalated recovery
+func transferStuckEvent(result *TransferRecoveryResult) notify.Event {
+	return notify.Event{
+		Type:     notify.EventTransferStuck,
+		Severity: notify.SeverityCritical,
+		Key:      "transfer_stuck:" + result.ExecutionID,
+		Summary: fmt.Sprintf("CCTP transfer of execution %s needs manual intervention: burn %s on chain %d is unminted on chain %d after %ds, %s",
+			result.ExecutionID, result.Burn, result.SourceChain, result.TargetChain, result.WaitedSeconds, result.Reason),
+		Data: &TransferStuckEvent{Recovery: result},
+	}
+}
+
+func (yip *YieldIntelligencePerformer) validateTransferRecoveryTask(payload *TaskPayload) error {
+	return yip.validateResumeExecutionTask(payload)
+}
@@ -0,0 +1,140 @@
+package performer
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/najnomics/crosscow-avs/pkg/notify"
+)
+
+func recoverTransfer(t *testing.T, performer *YieldIntelligencePerformer, id, executionID string) TransferRecoveryResult {
+	t.Helper()
+	var result TransferRecoveryResult
+	if err := runRebalanceTask(t, performer, id, `{"type":"transfer_recovery","parameters":{"execution_id":"`+executionID+`"}}`, &result); err != nil {
+		t.Fatalf("transfer_recovery failed: %v", err)
+	}
+	return result
+}
+
+func Test_TransferRecoveryMintsStuckTransfer(t *testing.T) {
+	performer, account, ethereum, base := newSubmittingPerformerOnBase(t, big.NewInt(0), big.NewInt(0), big.NewInt(1_000_000_000))
+	ethereum.AutoMine()
+	base.AutoMine()
+	attested := withAttestedBurns(t, performer)
+	events := withEventSink(t, performer)
+
+	var rebalance RebalanceExecutionResult
+	if err := runRebalanceTask(t, performer, "bridged", `{"type":"rebalance_execution","parameters":{
+		"user_address":"`+account.Hex()+`","nonce":"1","amount":"1000000000","source_chain":1,"target_protocol":"aave_v3","target_chain":8453}}`, &rebalance); err != nil {
+		t.Fatalf("rebalance_execution failed: %v", err)
+	}
+	id := rebalance.Execution.ID
+
+	// the burn is within the SLA
+	result := recoverTransfer(t, performer, "within sla", id)
+	if result.Stuck || result.Action != RecoveryNone || result.Burn != rebalance.Execution.Transactions[1].Hash || result.SLASeconds != 3600 {
+		t.Fatalf("Expected a transfer within the SLA left alone, got %+v", result)
+	}
+
+	performer.recovery.SLA = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	result = recoverTransfer(t, performer, "unattested", id)
+	if !result.Stuck || result.Action != RecoveryEscalated || result.Attestation != "pending_confirmations" || !strings.Contains(result.Reason, "not attested") {
+		t.Fatalf("Expected an unattested stuck transfer escalated, got %+v", result)
+	}
+	if len(base.Sent()) != 0 {
+		t.Errorf("Expected nothing minted before Circle attests the burn")
+	}
+
+	attested.Store(true)
+	result = recoverTransfer(t, performer, "attested", id)
+	if result.Action != RecoveryResubmitted || result.Attempts != 1 || result.Status != ResultStatusCompleted {
+		t.Fatalf("Expected the mint submitted again, got %+v", result)
+	}
+	checkLegs(t, result.Execution, ActionBurn, LegCompleted, ActionMint, LegCompleted, ActionDeposit, LegCompleted)
+
+	result = recoverTransfer(t, performer, "minted", id)
+	if result.Stuck || result.Action != RecoveryNone || len(base.Sent()) != 3 {
+		t.Errorf("Expected a minted transfer left alone, got %+v", result)
+	}
+
+	raised, _ := events()
+	var stuck []notify.Event
+	for _, event := range raised {
+		if event.Type == notify.EventTransferStuck {
+			stuck = append(stuck, event)
+		}
+	}
+	if len(stuck) != 1 || stuck[0].Severity != notify.SeverityCritical || stuck[0].Key != "transfer_stuck:"+id || stuck[0].TaskID != "unattested" {
+		t.Errorf("Expected one transfer_stuck alert, got %+v", stuck)
+	}
+}
+
+func Test_RecoverTransfersEscalatesExhaustedMints(t *testing.T) {
+	performer, account, ethereum, base := newSubmittingPerformerOnBase(t, big.NewInt(0), big.NewInt(0), big.NewInt(1_000_000_000))
+	ethereum.AutoMine()
+	base.AutoMine()
+	attested := withAttestedBurns(t, performer)
+	events := withEventSink(t, performer)
+	performer.recovery.SLA, performer.recovery.MaxAttempts = time.Nanosecond, 1
+
+	var rebalance RebalanceExecutionResult
+	if err := runRebalanceTask(t, performer, "bridged", `{"type":"rebalance_execution","parameters":{
+		"user_address":"`+account.Hex()+`","nonce":"1","amount":"1000000000","source_chain":1,"target_protocol":"aave_v3","target_chain":8453}}`, &rebalance); err != nil {
+		t.Fatalf("rebalance_execution failed: %v", err)
+	}
+	// the mint was submitted again once, and did not land
+	record, err := performer.executions.get(context.Background(), rebalance.Execution.ID)
+	if err != nil {
+		t.Fatalf("Failed to read execution: %v", err)
+	}
+	record.Recoveries = 1
+	if err := performer.executions.put(context.Background(), record); err != nil {
+		t.Fatalf("Failed to store execution: %v", err)
+	}
+	attested.Store(true)
+	time.Sleep(time.Millisecond)
+
+	stuck, err := performer.RecoverTransfers(context.Background())
+	if err != nil || stuck != 1 {
+		t.Fatalf("Expected one stuck transfer, got %d, %v", stuck, err)
+	}
+	if len(base.Sent()) != 0 {
+		t.Errorf("Expected no mint submitted past the attempts")
+	}
+	raised, _ := events()
+	types := eventTypes(raised)
+	if len(types) == 0 || types[len(types)-1] != notify.EventTransferStuck {
+		t.Fatalf("Expected a transfer_stuck alert after the rebalance, got %v", types)
+	}
+	if event := raised[len(raised)-1]; event.TaskID != "" || !strings.Contains(event.Summary, "submitted again 1 times") {
+		t.Errorf("Unexpected transfer_stuck alert %+v", event)
+	}
+}
+
+func Test_TransferRecoveryRejections(t *testing.T) {
+	performer, account, _ := newSubmittingPerformer(t)
+
+	err := runRebalanceTask(t, performer, "unknown", `{"type":"transfer_recovery","parameters":{"execution_id":"0x01"}}`, nil)
+	var taskErr *TaskError
+	if !errors.As(err, &taskErr) || taskErr.Code != ErrorCodeValidation {
+		t.Errorf("Expected an unknown execution to be rejected, got %v", err)
+	}
+
+	var rebalance RebalanceExecutionResult
+	if err := runRebalanceTask(t, performer, "same chain", `{"type":"rebalance_execution","parameters":{
+		"user_address":"`+account.Hex()+`","nonce":"1","amount":"1000000000","target_protocol":"aave_v3","target_chain":1}}`, &rebalance); err != nil {
+		t.Fatalf("rebalance_execution failed: %v", err)
+	}
+	err = runRebalanceTask(t, performer, "no transfer", `{"type":"transfer_recovery","parameters":{"execution_id":"`+rebalance.Execution.ID+`"}}`, nil)
+	if !errors.As(err, &taskErr) || taskErr.Code != ErrorCodeValidation || !strings.Contains(err.Error(), "burned nothing") {
+		t.Errorf("Expected an execution without a transfer to be rejected, got %v", err)
+	}
+	if err := runRebalanceTask(t, newDryRunPerformer(t, &fakeSimulator{bundles: make(map[uint64]int)}), "no-account", `{"type":"transfer_recovery","parameters":{"execution_id":"0x01"}}`, nil); err == nil {
+		t.Errorf("Expected a performer without an account to reject recoveries")
+	}
+}
@@ -0,0 +1,95 @@
+package performer
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+	"github.com/najnomics/crosscow-avs/pkg/cctp"
+	"github.com/najnomics/crosscow-avs/pkg/txmgr"
+)
+
+// year is the period annual rates accrue over
+const year = 365 * 24 * time.Hour
+
+// TransitReport is how long the funds of a cross-chain rebalance are in flight through
+// CCTP and what that costs. The transit runs from the burn on the source chain, through
+// the finality the burn waits for and Circle's attestation, to the mint on the target
+// chain. YieldLost is the yield the amount would have earned at the final rate of the
+// target market meanwhile, in USDC and in basis points of the amount; Fee is Circle's fee
+// for fast transfers.
+type TransitReport struct {
+	Speed           cctp.Speed        `json:"speed"`
+	SourceFinality  string            `json:"source_finality"`
+	FinalitySeconds uint64            `json:"finality_seconds"`
+	DurationSeconds uint64            `json:"duration_seconds"`
+	Fee             canonical.Decimal `json:"fee"`
+	YieldLost       canonical.Decimal `json:"yield_lost"`
+	YieldLostBps    canonical.Decimal `json:"yield_lost_bps"`
+}
+
+// transitReport reports the transit of route, whose funds earn rate, an annual
+// percentage, once deposited. It is nil for routes that do not bridge through CCTP.
+func transitReport(route *rebalanceRoute, rate canonical.Decimal) (*TransitReport, error) {
+	if !route.crossChain() || route.fallback != nil {
+		return nil, nil
+	}
+	finality, err := cctp.SourceFinality(&cctp.Transfer{SourceChainID: route.sourceChain, MinFinality: route.speed.Threshold()})
+	if err != nil {
+		return nil, err
+	}
+	duration := blockTime(route.sourceChain) + finality.Wait + blockTime(route.targetChain)
+	speed, fee := route.speed, new(big.Int)
+	if speed == "" {
+		speed = cctp.SpeedStandard
+	}
+	if route.bridgeFee != nil {
+		fee = route.bridgeFee
+	}
+	lost, lostBps := transitYieldLost(route.amount, rate, duration)
+	return &TransitReport{
+		Speed:           speed,
+		SourceFinality:  finality.Requirement,
+		FinalitySeconds: uint64(finality.Wait / time.Second),
+		DurationSeconds: uint64(duration / time.Second),
+		Fee:             usdcAmount(fee),
+		YieldLost:       lost,
+		YieldLostBps:    lostBps,
+	}, nil
+}
+
+// transitYieldLost is the yield amount, in USDC base units, would earn at rate, an
+// annual percentage, over duration, in USDC and in basis points of amount
+func transitYieldLost(amount *big.Int, rate canonical.Decimal, duration time.Duration) (canonical.Decimal, canonical.Decimal) {
+	// rates are percentages, so the fraction of a year they earn is rate/100
+	share := new(big.Rat).Mul(rate.Rat(), big.NewRat(int64(duration/time.Second), 100*int64(year/time.Second)))
+	lost := new(big.Rat).Mul(new(big.Rat).SetInt(amount), share)
+	bps := new(big.Rat).Mul(share, big.NewRat(txmgr.MaxBps, 1))
+	return usdcAmount(new(big.Int).Quo(lost.Num(), lost.Denom())), canonical.NewDecimalFromRat(bps, canonical.ScorePlaces)
+}
+
+// fitTransferSpeed charges route Circle's fee for fast transfers, refusing it when the
+// fee exceeds the max slippage of route
+func (yip *YieldIntelligencePerformer) fitTransferSpeed(route *rebalanceRoute) error {
+	if !route.crossChain() || route.speed != cctp.SpeedFast {
+		return nil
+	}
+	if yip.cctpFastFeeBps > route.maxSlippageBps {
+		return newTaskError(ErrorCodeValidation, fmt.Errorf("invalid transfer_speed: the %d bps fast transfer fee exceeds the %d bps max slippage",
+			yip.cctpFastFeeBps, route.maxSlippageBps))
+	}
+	fee := new(big.Int).Mul(route.amount, new(big.Int).SetUint64(yip.cctpFastFeeBps))
+	route.bridgeFee = fee.Div(fee, big.NewInt(txmgr.MaxBps))
+	return nil
+}
+
+// paramTransferSpeed returns the transfer_speed parameter, cctp.SpeedStandard when
+// missing
+func paramTransferSpeed(payload *TaskPayload) cctp.Speed {
+	speed, err := cctp.ParseSpeed(paramString(payload, "transfer_speed"))
+	if err != nil {
+		return cctp.SpeedStandard
+	}
+	return speed
+}
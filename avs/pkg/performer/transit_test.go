@@ -0,0 +1,99 @@
+package performer
+
+import (
+	"strings"
+	"testing"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/bridge"
+	"github.com/najnomics/crosscow-avs/pkg/cctp"
+)
+
+func Test_RebalanceDryRunTransit(t *testing.T) {
+	performer := newDryRunPerformer(t, &fakeSimulator{bundles: make(map[uint64]int)})
+	payload := `{"type":"rebalance_execution","parameters":{
+		"user_address":"0x00000000000000000000000000000000000000aa","amount":"1000000000000","dry_run":true,"max_slippage_bps":10,
+		"source_protocol":"aave_v3","source_chain":1,"target_protocol":"aave_v3","target_chain":8453%s}}`
+	blocks := uint64((blockTime(1) + blockTime(adapters.ChainIDBase)).Seconds())
+
+	standard := runDryRun(t, performer, strings.Replace(payload, "%s", "", 1)).Simulation
+	transit := standard.Transit
+	if transit == nil || transit.Speed != cctp.SpeedStandard || transit.FinalitySeconds != 19*60 || transit.DurationSeconds != 19*60+blocks {
+		t.Fatalf("Unexpected standard transit: %+v", transit)
+	}
+	if transit.Fee.String() != "0.000000" || !strings.HasPrefix(transit.SourceFinality, "hard finality") {
+		t.Errorf("Expected a free transfer waiting for hard finality, got %+v", transit)
+	}
+	if transit.YieldLost.Rat().Sign() <= 0 || transit.YieldLostBps.Rat().Sign() <= 0 {
+		t.Errorf("Expected yield lost while in transit, got %s (%s bps)", transit.YieldLost, transit.YieldLostBps)
+	}
+
+	fast := runDryRun(t, newDryRunPerformer(t, &fakeSimulator{bundles: make(map[uint64]int)}), strings.Replace(payload, "%s", `,"transfer_speed":"fast"`, 1)).Simulation
+	if fast.Transit == nil || fast.Transit.Speed != cctp.SpeedFast || fast.Transit.FinalitySeconds != 20 || fast.Transit.DurationSeconds != 20+blocks {
+		t.Fatalf("Unexpected fast transit: %+v", fast.Transit)
+	}
+	// Circle keeps 1 bps of the 1,000,000 USDC moved
+	if fast.Transit.Fee.String() != "100.000000" || fast.BridgeFee.String() != "100.000000" || fast.SlippageBps.String() != "1.00" {
+		t.Errorf("Expected the fast transfer fee charged, got %+v", fast)
+	}
+	if fast.Transit.YieldLost.Cmp(transit.YieldLost) >= 0 {
+		t.Errorf("Expected less yield lost by a fast transfer, got %s, standard %s", fast.Transit.YieldLost, transit.YieldLost)
+	}
+	if mint := fast.Steps[3]; mint.Action != ActionMint || mint.DurationSeconds >= 19*60 {
+		t.Errorf("Expected the mint to wait for soft finality only, got %+v", mint)
+	}
+	if fast.DurationSeconds >= standard.DurationSeconds {
+		t.Errorf("Expected a fast transfer to take less time, got %ds, standard %ds", fast.DurationSeconds, standard.DurationSeconds)
+	}
+
+	sameChain := runDryRun(t, newDryRunPerformer(t, &fakeSimulator{bundles: make(map[uint64]int)}), `{"type":"rebalance_execution","parameters":{
+		"user_address":"0x00000000000000000000000000000000000000aa","amount":"1000000000","dry_run":true,"target_protocol":"aave_v3","target_chain":1}}`)
+	if sameChain.Simulation.Transit != nil {
+		t.Errorf("Expected no transit within a chain, got %+v", sameChain.Simulation.Transit)
+	}
+}
+
+func Test_RebalanceFastTransferBeyondSlippage(t *testing.T) {
+	performer, account, ethereum, _ := newSubmittingPerformerOnBase(t)
+
+	task := &performerV1.TaskRequest{TaskId: []byte("fast transfer"), Payload: []byte(`{"type":"rebalance_execution","parameters":{
+		"user_address":"` + account.Hex() + `","nonce":"1","amount":"1000000000","source_chain":1,"target_protocol":"aave_v3","target_chain":8453,
+		"transfer_speed":"fast","max_slippage_bps":0}}`)}
+	if err := performer.ValidateTask(task); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	if _, err := performer.HandleTask(task); err == nil || !strings.Contains(err.Error(), "fast transfer fee exceeds") {
+		t.Errorf("Expected the fast transfer fee to exceed the max slippage, got %v", err)
+	}
+	if len(ethereum.Sent()) != 0 {
+		t.Errorf("Expected no transaction to be sent")
+	}
+}
+
+func Test_CrossChainYieldCheckTransferSpeed(t *testing.T) {
+	performer := newAllocationPerformer(t)
+	check := `{"type":"cross_chain_yield_check","parameters":{"source_chain":1,"target_chain":8453,"amount":"5000000000"%s}}`
+
+	for speed, want := range map[string]string{"standard": bridge.CCTPStandard, "fast": bridge.CCTPFast} {
+		result := runCrossChainYieldCheck(t, performer, speed, strings.Replace(check, "%s", `,"transfer_speed":"`+speed+`"`, 1))
+		if result.Route == nil || result.Route.Bridge != want {
+			t.Errorf("Expected the %s route taken, got %+v", want, result.Route)
+		}
+	}
+
+	result := runCrossChainYieldCheck(t, performer, "any speed", strings.Replace(check, "%s", "", 1))
+	if result.TargetProtocol == "" {
+		t.Fatalf("Expected a target recommended, got %+v", result)
+	}
+	lost := map[string]string{}
+	for _, route := range result.Routes {
+		if route.TransitYieldLost == nil {
+			t.Fatalf("Expected the yield lost in transit on route %s", route.Bridge)
+		}
+		lost[route.Bridge] = route.TransitYieldLost.String()
+	}
+	if lost[bridge.CCTPFast] == lost[bridge.CCTPStandard] {
+		t.Errorf("Expected fast and standard transfers to lose different yield, got %v", lost)
+	}
+}
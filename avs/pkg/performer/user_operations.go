@@ -0,0 +1,140 @@
+package performer
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/userop"
+)
+
+// Backends rebalances are executed with
+const (
+	// ExecutionEOA sends every step as a transaction of the performer account
+	ExecutionEOA = "eoa"
+
+	// ExecutionUserOperation sends the steps of each chain as one ERC-4337 user operation
+	// of the smart account at the user address, which the performer account owns
+	ExecutionUserOperation = "user_operation"
+)
+
+// submitUserOperations sends the steps of route from the one at from on as user
+// operations of the user's account, one per run of steps on a chain, until one needs
+// bridged funds that have not arrived. The steps of an operation execute atomically. The
+// first operation must be accepted; a later failure leaves its steps and the ones after
+// pending and is reported as false.
+func (yip *YieldIntelligencePerformer) submitUserOperations(ctx context.Context, route *rebalanceRoute, steps []*plannedStep, record *executionRecord, from int) (bool, error) {
+	execution := record.Execution
+	complete, halted, bridged := true, false, false
+	for i := from; i < len(steps); {
+		step := steps[i]
+		if !halted && step.needsBridging && !bridged {
+			mint, arrived, err := yip.bridgedFunds(ctx, route, record)
+			if err != nil {
+				yip.log(ctx).Sugar().Warnw("Failed to check bridged funds", "chainId", route.targetChain, "error", err)
+			}
+			halted, bridged = !arrived, arrived
+			if mint != nil {
+				step.call = mint
+			}
+		}
+		if halted {
+			execution.PendingSteps = append(execution.PendingSteps, PendingStep{Action: step.Action, ChainID: step.ChainID})
+			i++
+			continue
+		}
+
+		end := i + 1
+		for end < len(steps) && steps[end].ChainID == step.ChainID && (!steps[end].needsBridging || bridged) {
+			end++
+		}
+		batch := steps[i:end]
+		calls := make([]chain.Call, len(batch))
+		for j, s := range batch {
+			calls[j] = *s.call
+		}
+		err := yip.renewExecutionLock(ctx)
+		var sent *userop.Sent
+		if err == nil {
+			sent, err = yip.userOperations.Send(ctx, step.ChainID, route.user, calls)
+		}
+		if err != nil {
+			if i == from {
+				return false, newTaskError(ErrorCodeExecutionFailed, fmt.Errorf("failed to submit user operation from %s: %w", step.Action, err))
+			}
+			yip.log(ctx).Sugar().Warnw("Failed to submit rebalance user operation", "action", step.Action, "chainId", step.ChainID, "error", err)
+			complete, halted = false, true
+			for _, s := range batch {
+				execution.PendingSteps = append(execution.PendingSteps, PendingStep{Action: s.Action, ChainID: s.ChainID})
+			}
+			i = end
+			continue
+		}
+		for j, s := range batch {
+			submitted := SubmittedTransaction{
+				Action:            s.Action,
+				ChainID:           s.ChainID,
+				UserOperationHash: sent.Hash.Hex(),
+				Nonce:             sent.Nonce.Uint64(),
+				Status:            TransactionPending,
+			}
+			if j == 0 {
+				submitted.GasLimit = sent.GasLimit
+			}
+			execution.Transactions = append(execution.Transactions, submitted)
+		}
+		i = end
+	}
+	return complete, nil
+}
+
+// confirmUserOperations waits for the user operations of execution to be final, until
+// ctx is done, and records their receipts, as confirmRebalance does for transactions
+func (yip *YieldIntelligencePerformer) confirmUserOperations(ctx context.Context, execution *RebalanceExecution) ResultStatus {
+	status := ResultStatusCompleted
+	if len(execution.PendingSteps) > 0 {
+		status = ResultStatusSubmitted
+	}
+	confirmations := make(map[string]*userop.Confirmation)
+	for i := range execution.Transactions {
+		submitted := &execution.Transactions[i]
+		confirmation, seen := confirmations[submitted.UserOperationHash]
+		if !seen {
+			var err error
+			confirmation, err = yip.userOperations.Confirm(ctx, submitted.ChainID, common.HexToHash(submitted.UserOperationHash))
+			if err != nil {
+				yip.log(ctx).Sugar().Warnw("Rebalance user operation not confirmed", "action", submitted.Action, "userOperationHash", submitted.UserOperationHash, "error", err)
+			}
+			_ = yip.renewExecutionLock(ctx)
+			confirmations[submitted.UserOperationHash] = confirmation
+		}
+		submitted.Hash, submitted.BlockNumber = "", 0
+		submitted.Confirmations = confirmation.Confirmations
+		if receipt := confirmation.Receipt; receipt != nil {
+			submitted.Hash = receipt.TransactionHash.Hex()
+			submitted.BlockNumber = receipt.BlockNumber
+			if !seen && receipt.ActualGasUsed.Sign() > 0 {
+				price := canonical.NewDecimalFromInt(new(big.Int).Div(receipt.ActualGasCost, receipt.ActualGasUsed), 9, 9)
+				submitted.GasUsed = receipt.ActualGasUsed.Uint64()
+				submitted.EffectiveGasPrice = &price
+			}
+		}
+
+		switch {
+		case confirmation.Reverted():
+			submitted.Status = TransactionReverted
+			status = ResultStatusReverted
+		case confirmation.Final:
+			submitted.Status = TransactionConfirmed
+		default:
+			submitted.Status = TransactionPending
+			if status != ResultStatusReverted {
+				status = ResultStatusSubmitted
+			}
+		}
+	}
+	return status
+}
@@ -0,0 +1,119 @@
+package performer
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/chain/chaintest"
+	"github.com/najnomics/crosscow-avs/pkg/userop"
+	"github.com/najnomics/crosscow-avs/pkg/userop/bundlertest"
+)
+
+// smartAccount is the ERC-4337 account of the user, owned by the performer account
+var smartAccount = common.HexToAddress("0x0000000000000000000000000000000000a11ce5")
+
+var entryPointABI = chain.MustParseABI(`[{"name":"getNonce","type":"function","stateMutability":"view",
+	"inputs":[{"name":"sender","type":"address"},{"name":"key","type":"uint192"}],"outputs":[{"name":"nonce","type":"uint256"}]}]`)
+
+// withUserOperations sends the user operations of performer to bundlers of Ethereum and
+// Base, the one of Ethereum including them in the latest block of ethereum
+func withUserOperations(t *testing.T, performer *YieldIntelligencePerformer, ethereum *chaintest.Contracts) *bundlertest.Bundler {
+	t.Helper()
+	ethereum.Stub(t, common.HexToAddress(userop.EntryPointV07), entryPointABI, "getNonce", big.NewInt(3))
+	ethereum.SetHead(100, 1_700_000_000)
+	head, err := ethereum.HeaderByNumber(context.Background(), big.NewInt(100))
+	if err != nil {
+		t.Fatalf("HeaderByNumber failed: %v", err)
+	}
+	bundler := bundlertest.New(t, 1)
+	bundler.IncludeIn(head, true)
+
+	chains := chain.NewManager()
+	chains.Register(1, "ethereum", ethereum)
+	cfg := userop.DefaultConfig()
+	cfg.Enabled, cfg.Confirmations, cfg.PollInterval = true, 1, time.Millisecond
+	cfg.Bundlers = map[uint64]string{1: bundler.URL, adapters.ChainIDBase: bundlertest.New(t, adapters.ChainIDBase).URL}
+	WithUserOperations(userop.New(cfg, chains, performer.transactions, nil))(performer)
+	return bundler
+}
+
+func Test_RebalanceThroughUserOperation(t *testing.T) {
+	performer, _, ethereum := newSubmittingPerformer(t, big.NewInt(0), big.NewInt(1_000_000_000))
+	bundler := withUserOperations(t, performer, ethereum)
+
+	result := runDryRun(t, performer, `{"type":"rebalance_execution","parameters":{
+		"user_address":"`+smartAccount.Hex()+`","nonce":"1","amount":"1000000000","target_protocol":"aave_v3","target_chain":1,"execution":"user_operation"}}`)
+
+	execution := result.Execution
+	if result.Status != ResultStatusCompleted || execution == nil || execution.Execution != ExecutionUserOperation || execution.From != smartAccount.Hex() {
+		t.Fatalf("Expected a completed user operation rebalance, got %+v", result)
+	}
+	if len(ethereum.Sent()) != 0 || len(bundler.Sent()) != 1 || bundler.Sent()[0].Sender != smartAccount {
+		t.Fatalf("Expected one user operation of the smart account and no transaction, got %d operations", len(bundler.Sent()))
+	}
+	if len(execution.Transactions) != 2 {
+		t.Fatalf("Expected the approve and deposit to be recorded, got %+v", execution.Transactions)
+	}
+	approve, deposit := execution.Transactions[0], execution.Transactions[1]
+	if approve.UserOperationHash == "" || approve.UserOperationHash != deposit.UserOperationHash || approve.Nonce != 3 || approve.Hash == "" {
+		t.Errorf("Expected both steps in one included operation, got %+v and %+v", approve, deposit)
+	}
+	// 0.001 ETH for 200k gas
+	if approve.GasUsed != 200_000 || approve.EffectiveGasPrice == nil || approve.EffectiveGasPrice.String() != "5.000000000" || deposit.GasUsed != 0 {
+		t.Errorf("Expected the gas of the operation on its first step, got %+v and %+v", approve, deposit)
+	}
+	for _, tx := range execution.Transactions {
+		if tx.Status != TransactionConfirmed || tx.BlockNumber != 100 {
+			t.Errorf("Unexpected receipt of %s: %+v", tx.Action, tx)
+		}
+	}
+	if !execution.Verified || len(execution.BalanceChecks) != 2 {
+		t.Errorf("Expected the balances of the smart account to be verified, got %+v", execution.BalanceChecks)
+	}
+}
+
+func Test_UserOperationLeavesBridgedStepsPending(t *testing.T) {
+	performer, _, ethereum := newSubmittingPerformer(t)
+	bundler := withUserOperations(t, performer, ethereum)
+
+	result := runDryRun(t, performer, `{"type":"rebalance_execution","parameters":{
+		"user_address":"`+smartAccount.Hex()+`","nonce":"1","amount":"1000000000","source_chain":1,"target_protocol":"aave_v3","target_chain":8453,"execution":"user_operation"}}`)
+
+	execution := result.Execution
+	if execution == nil || len(bundler.Sent()) != 1 || len(execution.Transactions) != 2 || execution.Transactions[1].Action != ActionBurn {
+		t.Fatalf("Expected the approve and burn in one operation, got %+v", result)
+	}
+	if len(execution.PendingSteps) != 3 || result.Status != ResultStatusSubmitted {
+		t.Errorf("Expected the steps on Base to wait for the attestation, got %+v", execution.PendingSteps)
+	}
+}
+
+func Test_UserOperationValidation(t *testing.T) {
+	performer, _, ethereum := newSubmittingPerformer(t)
+	withUserOperations(t, performer, ethereum)
+	unconfigured, _, _ := newSubmittingPerformer(t)
+
+	for name, tc := range map[string]struct {
+		performer *YieldIntelligencePerformer
+		params    string
+	}{
+		"unknown execution":   {performer: performer, params: `"target_chain":1,"execution":"circle_wallet"`},
+		"no bundler":          {performer: performer, params: `"source_chain":1,"target_chain":42161,"execution":"user_operation"`},
+		"not configured":      {performer: unconfigured, params: `"target_chain":1,"execution":"user_operation"`},
+		"not performer funds": {performer: performer, params: `"target_chain":1`},
+	} {
+		task := &performerV1.TaskRequest{
+			TaskId:  []byte(name),
+			Payload: []byte(`{"type":"rebalance_execution","parameters":{"user_address":"` + smartAccount.Hex() + `","nonce":"1","amount":"1000000000","target_protocol":"aave_v3",` + tc.params + `}}`),
+		}
+		if err := tc.performer.ValidateTask(task); err == nil {
+			t.Errorf("%s: expected the task to be rejected", name)
+		}
+	}
+}
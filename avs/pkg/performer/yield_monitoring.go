@@ -0,0 +1,435 @@
+package performer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/anomaly"
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+	"github.com/najnomics/crosscow-avs/pkg/crossval"
+	"github.com/najnomics/crosscow-avs/pkg/incentives"
+	"github.com/najnomics/crosscow-avs/pkg/snapshot"
+	"github.com/najnomics/crosscow-avs/pkg/stability"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+	"github.com/najnomics/crosscow-avs/pkg/tracing"
+	"github.com/najnomics/crosscow-avs/pkg/workerpool"
+)
+
+// AnomalyReport compares a freshly read supply rate to its rolling baseline
+type AnomalyReport struct {
+	// Evaluated is false when there was not enough history to form a baseline
+	Evaluated      bool              `json:"evaluated"`
+	Detected       bool              `json:"detected"`
+	Direction      anomaly.Direction `json:"direction"`
+	Severity       anomaly.Severity  `json:"severity"`
+	ZScore         canonical.Decimal `json:"z_score"`
+	BaselineRate   canonical.Decimal `json:"baseline_rate"`
+	BaselineStdDev canonical.Decimal `json:"baseline_stddev"`
+	Samples        int               `json:"samples"`
+}
+
+// StabilityReport describes how the supply rate of a market moved over the stability
+// window, the rate just read included. Volatility is the standard deviation of the rate
+// in percentage points, and the max drawdown is the deepest fall from a peak as a
+// fraction of the peak. Steady rates score close to 1.
+type StabilityReport struct {
+	// Evaluated is false when the window held too few readings
+	Evaluated     bool              `json:"evaluated"`
+	Samples       int               `json:"samples"`
+	WindowSeconds uint64            `json:"window_seconds"`
+	MeanRate      canonical.Decimal `json:"mean_rate"`
+	Volatility    canonical.Decimal `json:"volatility"`
+	MaxDrawdown   canonical.Decimal `json:"max_drawdown"`
+	Score         canonical.Decimal `json:"score"`
+}
+
+// CrossValidationReport compares the contract read supply rate with independent sources.
+// Once a quorum of them agrees, their weighted median rate is the rate reported.
+type CrossValidationReport struct {
+	Status     crossval.Status    `json:"status"`
+	MedianRate *canonical.Decimal `json:"median_rate,omitempty"`
+	SpreadBps  canonical.Decimal  `json:"spread_bps"`
+	Reason     string             `json:"reason,omitempty"`
+	Sources    []SourceRate       `json:"sources"`
+}
+
+// SourceRate is the supply rate a single source reported, as an annual percentage, and
+// its weight in the quorum
+type SourceRate struct {
+	Source     string             `json:"source"`
+	SupplyRate *canonical.Decimal `json:"supply_rate"`
+	Weight     canonical.Decimal  `json:"weight"`
+	Outlier    bool               `json:"outlier,omitempty"`
+	Error      string             `json:"error,omitempty"`
+}
+
+// IncentiveReport is the APR reward emissions add to the supply rate of a market, as
+// annual percentages. The incentive APR is net of vesting haircuts and claim costs, and
+// leaves out rewards that could not be priced.
+type IncentiveReport struct {
+	IncentiveAPR canonical.Decimal     `json:"incentive_apr"`
+	TotalAPR     canonical.Decimal     `json:"total_apr"`
+	Confidence   incentives.Confidence `json:"confidence"`
+	Rewards      []RewardAPR           `json:"rewards"`
+}
+
+// RewardAPR is the APR a single reward token adds before haircuts, null when the token
+// could not be priced
+type RewardAPR struct {
+	Token  string             `json:"token"`
+	APR    *canonical.Decimal `json:"apr"`
+	EndsAt uint64             `json:"ends_at,omitempty"`
+}
+
+// ProtocolAll selects every registered protocol in yield_monitoring tasks
+const ProtocolAll = "all"
+
+// MultiYieldMonitoringResult is the result of a yield_monitoring task for protocol "all".
+// Markets that could not be read are listed in failures, and the status is partial when
+// there are any.
+type MultiYieldMonitoringResult struct {
+	Token    string                   `json:"token"`
+	Markets  []*YieldMonitoringResult `json:"markets"`
+	Failures []MarketFailure          `json:"failures"`
+	Status   ResultStatus             `json:"status"`
+}
+
+// handleYieldMonitoring reads the current supply rate of a market, checks it against
+// independent sources and its rolling baseline and reports its volatility. Disputed rates
+// are not reported at all, anomalous ones are reported with status anomalous. Neither is
+// kept in the rate history, so they never skew forecasts or rebalance decisions.
+//
+// With protocol "all", every registered market on chain_id, or on every chain when
+// chain_id is omitted, is monitored concurrently.
+//
+// Stablecoins other than USDC enabled for comparison are read by monitorStablecoin.
+func (yip *YieldIntelligencePerformer) handleYieldMonitoring(ctx context.Context, t *performerV1.TaskRequest, payload *TaskPayload) (interface{}, error) {
+	yip.log(ctx).Sugar().Infow("Processing yield monitoring task")
+
+	protocol := paramString(payload, "protocol")
+	chainID := paramUint64(payload, "chain_id")
+	token := paramString(payload, "token")
+	block := blockRequest(payload)
+	deposit := usdcBaseUnits(paramAmount(payload, "deposit_amount"))
+
+	cfg, _ := yip.Thresholds()
+	if sigma, ok := payload.Parameters["anomaly_sigma"].(float64); ok && sigma > 0 {
+		cfg.WarningSigma = sigma
+		cfg.CriticalSigma = math.Max(cfg.CriticalSigma, sigma)
+	}
+
+	stablecoin := yip.stablecoins.Accepts(token)
+	monitor := func(ctx context.Context, m market) (*YieldMonitoringResult, error) {
+		if stablecoin {
+			return yip.monitorStablecoin(ctx, m, token, block)
+		}
+		return yip.monitorMarket(ctx, m, token, block, cfg, deposit)
+	}
+
+	if !strings.EqualFold(protocol, ProtocolAll) {
+		return monitor(ctx, market{protocol: protocol, chainID: chainID})
+	}
+
+	var markets []market
+	if chainID != 0 {
+		markets = yip.markets(chainID)
+	} else {
+		markets = yip.markets()
+	}
+	if stablecoin {
+		markets = yip.stablecoinMarkets(token, markets)
+	}
+	if len(markets) == 0 {
+		return nil, fmt.Errorf("no %s markets are registered for chain %d", token, chainID)
+	}
+
+	outcomes := workerpool.Map(ctx, yip.pool, markets, monitor)
+	result := &MultiYieldMonitoringResult{
+		Token:    token,
+		Markets:  []*YieldMonitoringResult{},
+		Failures: []MarketFailure{},
+		Status:   ResultStatusCompleted,
+	}
+	for i, outcome := range outcomes {
+		if outcome.Err != nil {
+			result.Failures = append(result.Failures, marketFailure(markets[i], outcome.Err))
+			continue
+		}
+		result.Markets = append(result.Markets, outcome.Value)
+	}
+	if len(result.Markets) == 0 {
+		return nil, fmt.Errorf("failed to read any of %d markets: %s", len(markets), result.Failures[0].Error)
+	}
+	if len(result.Failures) > 0 {
+		result.Status = ResultStatusPartial
+	}
+	return result, nil
+}
+
+// monitorMarket cross validates and checks the current supply rate of a single market,
+// and reports its rate model with the rate it projects once deposit is added. With
+// snapshots, the market is read at the requested block, or the latest finalized block,
+// and its supply rate is snapped.
+func (yip *YieldIntelligencePerformer) monitorMarket(ctx context.Context, m market, token string, block snapshot.Request, cfg anomaly.Config, deposit *big.Int) (*YieldMonitoringResult, error) {
+	ctx, reference, err := yip.pin(ctx, m.chainID, block)
+	if err != nil {
+		return nil, err
+	}
+	state, err := yip.readMarket(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	rate := yip.snapshots.Rate(state.Pool.SupplyRate())
+
+	result := &YieldMonitoringResult{
+		Protocol:    m.protocol,
+		Token:       token,
+		ChainID:     m.chainID,
+		Utilization: canonical.Ratio(state.Pool.Utilization()),
+		TotalSupply: usdcAmount(state.Pool.TotalSupply),
+		BlockNumber: reference,
+		RateModel:   rateModelReport(state.Pool, deposit),
+	}
+
+	// contractOutlier is set when the sources outvoted the contract read, whose rate is
+	// then kept out of the history
+	contractOutlier := false
+	if len(yip.rateSources) > 0 {
+		readings := append([]crossval.Reading{{Source: contractRateSource, Rate: rate}},
+			crossval.Collect(ctx, yip.rateSources, m.protocol, m.chainID)...)
+		_, crossvalCfg := yip.Thresholds()
+		report := yip.sourceStats.Evaluate(readings, crossvalCfg)
+		result.CrossValidation = crossValidationReport(report)
+		if report.Status == crossval.StatusDisputed {
+			yip.log(ctx).Sugar().Warnw("Disputed supply rate",
+				"protocol", m.protocol,
+				"chainId", m.chainID,
+				"reason", report.Reason,
+			)
+			result.Status = ResultStatusDisputed
+			return result, nil
+		}
+		if len(report.Outliers) > 0 {
+			yip.log(ctx).Sugar().Warnw("Outlying rate sources",
+				"protocol", m.protocol,
+				"chainId", m.chainID,
+				"sources", report.Outliers,
+			)
+		}
+		contractOutlier = slices.Contains(report.Outliers, contractRateSource)
+		rate = yip.snapshots.Rate(report.Median)
+	}
+
+	now := time.Now()
+	report, err := yip.detectRateAnomaly(ctx, m.protocol, m.chainID, rate, now, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	stabilityReport, err := yip.measureStability(ctx, m, rate, now)
+	if err != nil {
+		return nil, err
+	}
+
+	supplyRate := ratePercent(rate)
+	result.SupplyRate = &supplyRate
+	result.Anomaly = report
+	result.Stability = stabilityReport
+	result.Incentives = yip.incentiveReport(ctx, m, state, rate)
+	result.SupplyCap = yip.supplyCapReport(ctx, m, state)
+	result.Status = ResultStatusCompleted
+	if report.Detected {
+		result.Status = ResultStatusAnomalous
+		yip.log(ctx).Sugar().Warnw("Anomalous supply rate",
+			"protocol", m.protocol,
+			"chainId", m.chainID,
+			"direction", report.Direction,
+			"severity", report.Severity,
+			"zScore", report.ZScore.String(),
+		)
+	} else if block == (snapshot.Request{}) && !contractOutlier {
+		// the rate of a requested past block is not the current rate the history tracks
+		if err := yip.recordSupplyRate(ctx, state, now); err != nil {
+			return nil, fmt.Errorf("failed to record supply rate: %w", err)
+		}
+	}
+
+	return result, nil
+}
+
+// incentiveReport prices the reward emissions of m on top of its supply rate. Incentives
+// are best effort: protocols without them, and markets whose emissions cannot be read, are
+// reported without.
+func (yip *YieldIntelligencePerformer) incentiveReport(ctx context.Context, m market, state *adapters.MarketState, rate float64) *IncentiveReport {
+	if yip.incentives == nil {
+		return nil
+	}
+	reader, err := yip.adapters.IncentiveReaderFor(m.protocol)
+	if err != nil {
+		return nil
+	}
+	spanCtx, span := startAdapterSpan(ctx, "Incentives", m)
+	emissions, err := reader.Incentives(spanCtx, m.chainID)
+	tracing.End(span, err)
+	var estimate *incentives.Estimate
+	if err == nil {
+		estimate, err = yip.incentives.Estimate(ctx, m.protocol, m.chainID, emissions, state.Pool.TotalSupply)
+	}
+	if err != nil {
+		if !errors.Is(err, adapters.ErrNoIncentives) {
+			yip.log(ctx).Sugar().Warnw("Failed to read incentives",
+				"protocol", m.protocol,
+				"chainId", m.chainID,
+				"error", err,
+			)
+		}
+		return nil
+	}
+
+	out := &IncentiveReport{
+		IncentiveAPR: ratePercent(estimate.APR),
+		TotalAPR:     ratePercent(rate + estimate.APR),
+		Confidence:   estimate.Confidence,
+		Rewards:      make([]RewardAPR, 0, len(estimate.Rewards)),
+	}
+	for _, reward := range estimate.Rewards {
+		r := RewardAPR{Token: reward.Token.Hex(), EndsAt: reward.EndsAt}
+		if reward.Priced {
+			apr := ratePercent(reward.APR)
+			r.APR = &apr
+		}
+		out.Rewards = append(out.Rewards, r)
+	}
+	return out
+}
+
+// supplyCapReport reports the current supply cap headroom of m. Caps are best effort like
+// incentives; queued cap changes are left to rebalances.
+func (yip *YieldIntelligencePerformer) supplyCapReport(ctx context.Context, m market, state *adapters.MarketState) *SupplyCapReport {
+	report, _, err := yip.supplyCapStatus(ctx, m, state.Pool.TotalSupply, false)
+	if err != nil {
+		yip.log(ctx).Sugar().Warnw("Failed to read supply cap",
+			"protocol", m.protocol,
+			"chainId", m.chainID,
+			"error", err,
+		)
+		return nil
+	}
+	return report
+}
+
+// contractRateSource names the direct contract read in cross validation reports
+const contractRateSource = "contract"
+
+func crossValidationReport(report *crossval.Report) *CrossValidationReport {
+	out := &CrossValidationReport{
+		Status:    report.Status,
+		SpreadBps: canonical.Score(report.SpreadBps),
+		Reason:    report.Reason,
+		Sources:   make([]SourceRate, 0, len(report.Readings)),
+	}
+	if report.Status == crossval.StatusAgreed {
+		median := ratePercent(report.Median)
+		out.MedianRate = &median
+	}
+	for _, r := range report.Readings {
+		source := SourceRate{Source: r.Source, Weight: canonical.Score(r.Weight), Outlier: r.Outlier}
+		if r.Err != nil {
+			source.Error = r.Err.Error()
+		} else {
+			rate := ratePercent(r.Rate)
+			source.SupplyRate = &rate
+		}
+		out.Sources = append(out.Sources, source)
+	}
+	return out
+}
+
+// measureStability measures the supply rate history of m over the stability window,
+// ending with rate read at now
+func (yip *YieldIntelligencePerformer) measureStability(ctx context.Context, m market, rate float64, now time.Time) (*StabilityReport, error) {
+	stats, samples, err := yip.rateStats(ctx, m, rate, now)
+	if err != nil {
+		return nil, err
+	}
+	return stabilityReport(stats, samples, yip.stability.Window), nil
+}
+
+// rateStats measures the supply rate history of m over the stability window, ending with
+// rate read at now. The stats are nil when the window holds too few samples.
+func (yip *YieldIntelligencePerformer) rateStats(ctx context.Context, m market, rate float64, now time.Time) (*stability.Stats, int, error) {
+	points, err := yip.history.Range(ctx, store.SupplyRateSeries(m.protocol, m.chainID), now.Add(-yip.stability.Window), now)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to load rate history: %w", err)
+	}
+	rates := make([]float64, 0, len(points)+1)
+	for _, p := range points {
+		rates = append(rates, p.Value)
+	}
+	rates = append(rates, rate)
+
+	stats, err := stability.Measure(rates, yip.stability)
+	if errors.Is(err, stability.ErrInsufficientHistory) {
+		return nil, len(rates), nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	return stats, len(rates), nil
+}
+
+func stabilityReport(stats *stability.Stats, samples int, window time.Duration) *StabilityReport {
+	out := &StabilityReport{Samples: samples, WindowSeconds: uint64(window / time.Second)}
+	if stats == nil {
+		return out
+	}
+	out.Evaluated = true
+	out.MeanRate = ratePercent(stats.Mean)
+	out.Volatility = ratePercent(stats.Volatility)
+	out.MaxDrawdown = canonical.Ratio(stats.MaxDrawdown)
+	out.Score = canonical.Ratio(stats.Score)
+	return out
+}
+
+// detectRateAnomaly compares rate to the supply rate history of the market
+func (yip *YieldIntelligencePerformer) detectRateAnomaly(ctx context.Context, protocol string, chainID uint64, rate float64, now time.Time, cfg anomaly.Config) (*AnomalyReport, error) {
+	points, err := yip.history.Range(ctx, store.SupplyRateSeries(protocol, chainID), now.Add(-cfg.Lookback), now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load rate history: %w", err)
+	}
+	baseline := make([]float64, len(points))
+	for i, p := range points {
+		baseline[i] = p.Value
+	}
+
+	detection, err := anomaly.Detect(rate, baseline, cfg)
+	if errors.Is(err, anomaly.ErrInsufficientBaseline) {
+		return &AnomalyReport{
+			Direction: anomaly.DirectionNone,
+			Severity:  anomaly.SeverityNone,
+			Samples:   len(baseline),
+		}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &AnomalyReport{
+		Evaluated:      true,
+		Detected:       detection.Detected,
+		Direction:      detection.Direction,
+		Severity:       detection.Severity,
+		ZScore:         canonical.Score(detection.ZScore),
+		BaselineRate:   ratePercent(detection.BaselineMean),
+		BaselineStdDev: ratePercent(detection.BaselineStdDev),
+		Samples:        detection.Samples,
+	}, nil
+}
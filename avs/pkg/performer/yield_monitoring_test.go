@@ -0,0 +1,664 @@
+package performer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/anomaly"
+	"github.com/najnomics/crosscow-avs/pkg/cache"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/chain/chaintest"
+	"github.com/najnomics/crosscow-avs/pkg/crossval"
+	"github.com/najnomics/crosscow-avs/pkg/incentives"
+	"github.com/najnomics/crosscow-avs/pkg/snapshot"
+	"github.com/najnomics/crosscow-avs/pkg/stability"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+	"go.uber.org/zap"
+)
+
+func Test_YieldMonitoringAnomalyDetection(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+
+	// the fake market currently pays 3.84%
+	testCases := []struct {
+		name         string
+		baselineRate float64
+		status       ResultStatus
+		direction    anomaly.Direction
+		severity     anomaly.Severity
+	}{
+		{name: "in line with history", baselineRate: 0.0384, status: ResultStatusCompleted, direction: anomaly.DirectionNone, severity: anomaly.SeverityNone},
+		{name: "spike", baselineRate: 0.02, status: ResultStatusAnomalous, direction: anomaly.DirectionSpike, severity: anomaly.SeverityCritical},
+		{name: "crash", baselineRate: 0.06, status: ResultStatusAnomalous, direction: anomaly.DirectionCrash, severity: anomaly.SeverityCritical},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			series := store.SupplyRateSeries(adapters.ProtocolAaveV3, 1)
+			history := store.NewSeriesStore(store.NewMemoryKV())
+			seedRateHistory(t, history, series, tc.baselineRate, 3)
+
+			performer := NewYieldIntelligencePerformer(logger,
+				WithAdapters(adapters.NewRegistry(newFakeAaveAdapter())),
+				WithRateHistory(history),
+			)
+			task := &performerV1.TaskRequest{
+				TaskId:  []byte("anomaly-" + tc.name),
+				Payload: []byte(`{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1}}`),
+			}
+			if err := performer.ValidateTask(task); err != nil {
+				t.Fatalf("ValidateTask failed: %v", err)
+			}
+			resp, err := performer.HandleTask(task)
+			if err != nil {
+				t.Fatalf("HandleTask failed: %v", err)
+			}
+
+			var envelope struct {
+				Result YieldMonitoringResult `json:"result"`
+			}
+			if err := json.Unmarshal(resp.Result, &envelope); err != nil {
+				t.Fatalf("Failed to decode result: %v", err)
+			}
+			result := envelope.Result
+			if result.Status != tc.status {
+				t.Errorf("Expected status %s, got %s", tc.status, result.Status)
+			}
+			if !result.Anomaly.Evaluated || result.Anomaly.Direction != tc.direction || result.Anomaly.Severity != tc.severity {
+				t.Errorf("Unexpected anomaly report: %+v", result.Anomaly)
+			}
+
+			// anomalous rates must stay out of the history they are judged against
+			points, err := history.Range(context.Background(), series, time.Now().Add(-time.Minute), time.Now().Add(time.Minute))
+			if err != nil {
+				t.Fatalf("Range failed: %v", err)
+			}
+			if recorded := len(points) == 1; recorded != (tc.status == ResultStatusCompleted) {
+				t.Errorf("Expected the rate to be recorded only when not anomalous, got %d new points", len(points))
+			}
+		})
+	}
+}
+
+func Test_YieldMonitoringWithoutHistory(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+	performer := NewYieldIntelligencePerformer(logger, WithAdapters(adapters.NewRegistry(newFakeAaveAdapter())))
+
+	task := &performerV1.TaskRequest{
+		TaskId:  []byte("anomaly-no-history"),
+		Payload: []byte(`{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1,"anomaly_sigma":0}}`),
+	}
+	if err := performer.ValidateTask(task); err == nil {
+		t.Errorf("Expected a non-positive anomaly_sigma to be rejected")
+	}
+
+	task.Payload = []byte(`{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1}}`)
+	resp, err := performer.HandleTask(task)
+	if err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+	var envelope struct {
+		Result YieldMonitoringResult `json:"result"`
+	}
+	if err := json.Unmarshal(resp.Result, &envelope); err != nil {
+		t.Fatalf("Failed to decode result: %v", err)
+	}
+	if envelope.Result.Status != ResultStatusCompleted || envelope.Result.Anomaly.Evaluated {
+		t.Errorf("Expected an unevaluated, completed result without history: %+v", envelope.Result)
+	}
+}
+
+type fakeRateSource struct {
+	name string
+	rate float64
+	err  error
+}
+
+func (s *fakeRateSource) Name() string {
+	if s.name != "" {
+		return s.name
+	}
+	return "fake"
+}
+
+func (s *fakeRateSource) SupplyRate(ctx context.Context, protocol string, chainID uint64) (float64, error) {
+	return s.rate, s.err
+}
+
+func Test_YieldMonitoringCrossValidation(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+
+	// the fake market currently pays 3.84%
+	testCases := []struct {
+		name   string
+		source *fakeRateSource
+		status ResultStatus
+	}{
+		{name: "agreed", source: &fakeRateSource{rate: 0.0385}, status: ResultStatusCompleted},
+		{name: "disputed", source: &fakeRateSource{rate: 0.0500}, status: ResultStatusDisputed},
+		{name: "source down", source: &fakeRateSource{err: errors.New("unreachable")}, status: ResultStatusDisputed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			series := store.SupplyRateSeries(adapters.ProtocolAaveV3, 1)
+			history := store.NewSeriesStore(store.NewMemoryKV())
+			performer := NewYieldIntelligencePerformer(logger,
+				WithAdapters(adapters.NewRegistry(newFakeAaveAdapter())),
+				WithRateHistory(history),
+				WithCrossValidation(crossval.DefaultConfig(), tc.source),
+			)
+			task := &performerV1.TaskRequest{
+				TaskId:  []byte("crossval-" + tc.name),
+				Payload: []byte(`{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1}}`),
+			}
+			resp, err := performer.HandleTask(task)
+			if err != nil {
+				t.Fatalf("HandleTask failed: %v", err)
+			}
+
+			var envelope struct {
+				Result YieldMonitoringResult `json:"result"`
+			}
+			if err := json.Unmarshal(resp.Result, &envelope); err != nil {
+				t.Fatalf("Failed to decode result: %v", err)
+			}
+			result := envelope.Result
+			if result.Status != tc.status {
+				t.Errorf("Expected status %s, got %s", tc.status, result.Status)
+			}
+			if result.CrossValidation == nil || len(result.CrossValidation.Sources) != 2 {
+				t.Fatalf("Expected a cross validation report over 2 sources, got %+v", result.CrossValidation)
+			}
+			if disputed := tc.status == ResultStatusDisputed; disputed != (result.SupplyRate == nil) {
+				t.Errorf("Expected a supply rate only when sources agree, got %v", result.SupplyRate)
+			}
+
+			points, err := history.Range(context.Background(), series, time.Now().Add(-time.Minute), time.Now().Add(time.Minute))
+			if err != nil {
+				t.Fatalf("Range failed: %v", err)
+			}
+			if recorded := len(points) == 1; recorded != (tc.status == ResultStatusCompleted) {
+				t.Errorf("Expected the rate to be recorded only when sources agree, got %d points", len(points))
+			}
+		})
+	}
+}
+
+func Test_YieldMonitoringQuorumOutvotesContract(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+	history := store.NewSeriesStore(store.NewMemoryKV())
+	performer := NewYieldIntelligencePerformer(logger,
+		WithAdapters(adapters.NewRegistry(newFakeAaveAdapter())),
+		WithRateHistory(history),
+		WithCrossValidation(crossval.DefaultConfig(),
+			&fakeRateSource{name: "rpc:quorum-1", rate: 0.0500},
+			&fakeRateSource{name: "defillama", rate: 0.0501},
+		),
+	)
+
+	// the fake market pays 3.84% through the rpc endpoint, while both sources read 5%
+	var result YieldMonitoringResult
+	if err := runYieldMonitoring(t, performer, "quorum", `{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1}}`, &result); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	report := result.CrossValidation
+	if result.Status != ResultStatusCompleted || report == nil || report.MedianRate == nil || report.MedianRate.String() != "5.0000" {
+		t.Fatalf("Expected the sources to agree on 5%%, got %+v", result)
+	}
+	if result.SupplyRate == nil || result.SupplyRate.String() != "5.0000" {
+		t.Errorf("Expected the median rate to be reported, got %v", result.SupplyRate)
+	}
+	for _, source := range report.Sources {
+		if outlier := source.Source == contractRateSource; source.Outlier != outlier {
+			t.Errorf("Expected only the contract read to be an outlier, got %+v", source)
+		}
+	}
+
+	points, err := history.Range(context.Background(), store.SupplyRateSeries(adapters.ProtocolAaveV3, 1), time.Now().Add(-time.Minute), time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Range failed: %v", err)
+	}
+	if len(points) != 0 {
+		t.Errorf("Expected the outvoted contract rate to be kept out of the history, got %d points", len(points))
+	}
+	stats := performer.sourceStats.Stats()
+	if len(stats) != 3 || stats[0].Source != contractRateSource || stats[0].Outliers != 1 {
+		t.Errorf("Expected the contract read to be scored as an outlier, got %+v", stats)
+	}
+}
+
+// brokenAdapter lists markets it cannot read
+type brokenAdapter struct {
+	protocol string
+	chainIDs []uint64
+}
+
+func (b *brokenAdapter) Protocol() string   { return b.protocol }
+func (b *brokenAdapter) ChainIDs() []uint64 { return b.chainIDs }
+
+func (b *brokenAdapter) MarketState(ctx context.Context, chainID uint64) (*adapters.MarketState, error) {
+	return nil, errors.New("rpc unavailable")
+}
+
+func Test_YieldMonitoringAllProtocols(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+	performer := NewYieldIntelligencePerformer(logger,
+		WithAdapters(adapters.NewRegistry(
+			newFakeAaveAdapter(),
+			&brokenAdapter{protocol: adapters.ProtocolCompoundV3, chainIDs: []uint64{1, 8453}},
+		)),
+		WithConcurrency(2),
+	)
+
+	task := &performerV1.TaskRequest{
+		TaskId:  []byte("monitor-all"),
+		Payload: []byte(`{"type":"yield_monitoring","parameters":{"protocol":"all","token":"USDC"}}`),
+	}
+	if err := performer.ValidateTask(task); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	resp, err := performer.HandleTask(task)
+	if err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+
+	var envelope struct {
+		Result MultiYieldMonitoringResult `json:"result"`
+	}
+	if err := json.Unmarshal(resp.Result, &envelope); err != nil {
+		t.Fatalf("Failed to decode result: %v", err)
+	}
+	result := envelope.Result
+	if result.Status != ResultStatusPartial {
+		t.Errorf("Expected a partial result, got %s", result.Status)
+	}
+	if len(result.Markets) != 1 || result.Markets[0].Protocol != adapters.ProtocolAaveV3 {
+		t.Errorf("Expected the aave market to be reported, got %+v", result.Markets)
+	}
+	if len(result.Failures) != 2 || result.Failures[0].ChainID != 1 || result.Failures[1].ChainID != 8453 {
+		t.Errorf("Expected both compound markets to be reported as failures in order, got %+v", result.Failures)
+	}
+
+	task.TaskId = []byte("monitor-all-base")
+	task.Payload = []byte(`{"type":"yield_monitoring","parameters":{"protocol":"all","token":"USDC","chain_id":8453}}`)
+	if _, err := performer.HandleTask(task); err == nil {
+		t.Errorf("Expected the task to fail when no market could be read")
+	}
+}
+
+// incentivizedAdapter pays suppliers of the markets of the adapter it wraps rewards
+type incentivizedAdapter struct {
+	*fakeAdapter
+	emissions []adapters.Emission
+}
+
+func (a *incentivizedAdapter) Incentives(ctx context.Context, chainID uint64) (*adapters.Incentives, error) {
+	return &adapters.Incentives{BlockTime: 1_700_000_000, Emissions: a.emissions}, nil
+}
+
+var aggregatorABI = chain.MustParseABI(`[
+	{"name":"decimals","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint8"}]},
+	{"name":"latestRoundData","type":"function","stateMutability":"view","inputs":[],
+	 "outputs":[
+		{"name":"roundId","type":"uint80"},
+		{"name":"answer","type":"int256"},
+		{"name":"startedAt","type":"uint256"},
+		{"name":"updatedAt","type":"uint256"},
+		{"name":"answeredInRound","type":"uint80"}
+	]}
+]`)
+
+func Test_YieldMonitoringIncentives(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+	priced := common.HexToAddress("0x7Fc66500c84A76Ad7e9c93437bFc5Ac33E2DDaE9")
+	unpriced := common.HexToAddress("0x4200000000000000000000000000000000000042")
+	feed := common.HexToAddress("0x547a514d5e3769680Ce22B2361c10Ea13619e8a9")
+
+	// the reward token trades at $2 an hour before the latest block
+	contracts := chaintest.NewContracts(1)
+	contracts.SetHead(100, 1_700_000_000)
+	contracts.Stub(t, feed, aggregatorABI, "decimals", uint8(8))
+	contracts.Stub(t, feed, aggregatorABI, "latestRoundData",
+		big.NewInt(1), big.NewInt(200_000_000), big.NewInt(0), big.NewInt(1_699_996_400), big.NewInt(1))
+	chains := chain.NewManager()
+	chains.Register(1, "ethereum", contracts)
+
+	cfg := incentives.DefaultConfig()
+	cfg.PriceFeeds = map[uint64]map[string]string{1: {priced.Hex(): feed.Hex()}}
+	cfg.ClaimCostUSD = 0
+
+	// 0.1 tokens a second over the 100M USDC of the fake market
+	adapter := &incentivizedAdapter{fakeAdapter: newFakeAaveAdapter(), emissions: []adapters.Emission{
+		{Token: priced, Decimals: 18, PerSecond: big.NewInt(100_000_000_000_000_000)},
+		{Token: unpriced, Decimals: 18, PerSecond: big.NewInt(1)},
+	}}
+	performer := NewYieldIntelligencePerformer(logger,
+		WithAdapters(adapters.NewRegistry(adapter)),
+		WithIncentives(incentives.New(cfg, chains)),
+	)
+
+	resp, err := performer.HandleTask(&performerV1.TaskRequest{
+		TaskId:  []byte("incentives"),
+		Payload: []byte(`{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1}}`),
+	})
+	if err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+	var envelope struct {
+		Result YieldMonitoringResult `json:"result"`
+	}
+	if err := json.Unmarshal(resp.Result, &envelope); err != nil {
+		t.Fatalf("Failed to decode result: %v", err)
+	}
+	report := envelope.Result.Incentives
+	if report == nil {
+		t.Fatalf("Expected incentives to be reported")
+	}
+
+	incentiveAPR := 0.1 * 2 * 365 * 24 * 60 * 60 / 100_000_000
+	supplyRate := envelope.Result.SupplyRate.Float64() / 100
+	if report.IncentiveAPR.Cmp(ratePercent(incentiveAPR)) != 0 || report.TotalAPR.Cmp(ratePercent(supplyRate+incentiveAPR)) != 0 {
+		t.Errorf("Expected a %f incentive APR on top of %f, got %s and %s", incentiveAPR, supplyRate, report.IncentiveAPR, report.TotalAPR)
+	}
+	if report.Confidence != incentives.ConfidenceLow || len(report.Rewards) != 2 || report.Rewards[0].APR == nil || report.Rewards[1].APR != nil {
+		t.Errorf("Expected the unpriced reward to lower the confidence, got %+v", report)
+	}
+
+	// protocols without incentives report none
+	performer = NewYieldIntelligencePerformer(logger,
+		WithAdapters(adapters.NewRegistry(newFakeAaveAdapter())),
+		WithIncentives(incentives.New(cfg, chains)),
+	)
+	resp, err = performer.HandleTask(&performerV1.TaskRequest{
+		TaskId:  []byte("no-incentives"),
+		Payload: []byte(`{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1}}`),
+	})
+	if err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+	if strings.Contains(string(resp.Result), "incentives") {
+		t.Errorf("Expected no incentives to be reported, got %s", resp.Result)
+	}
+}
+
+// countingAdapter counts market reads of the adapter it wraps
+type countingAdapter struct {
+	*fakeAdapter
+	reads atomic.Int32
+}
+
+func (c *countingAdapter) MarketState(ctx context.Context, chainID uint64) (*adapters.MarketState, error) {
+	c.reads.Add(1)
+	return c.fakeAdapter.MarketState(ctx, chainID)
+}
+
+func Test_YieldMonitoringServedFromCache(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+	resultCache, err := cache.New(cache.DefaultConfig(), cache.NewMemoryBackend(10), nil)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	adapter := &countingAdapter{fakeAdapter: newFakeAaveAdapter()}
+	performer := NewYieldIntelligencePerformer(logger,
+		WithAdapters(adapters.NewRegistry(adapter)),
+		WithResultCache(resultCache),
+	)
+
+	payloads := []string{
+		`{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1}}`,
+		`{"type":"yield_monitoring","parameters":{"chain_id":1,"token":"USDC","protocol":"aave_v3"}}`,
+	}
+	var results [][]byte
+	for i, payload := range payloads {
+		resp, err := performer.HandleTask(&performerV1.TaskRequest{
+			TaskId:  []byte(fmt.Sprintf("cached-%d", i)),
+			Payload: []byte(payload),
+		})
+		if err != nil {
+			t.Fatalf("HandleTask failed: %v", err)
+		}
+		results = append(results, resp.Result)
+	}
+
+	if reads := adapter.reads.Load(); reads != 1 {
+		t.Errorf("Expected the market to be read once, got %d reads", reads)
+	}
+	if string(results[0]) != string(results[1]) {
+		t.Errorf("Expected the cached result to be returned unchanged")
+	}
+
+	// results of tasks with other parameters are not shared
+	if _, err := performer.HandleTask(&performerV1.TaskRequest{
+		TaskId:  []byte("cached-sigma"),
+		Payload: []byte(`{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1,"anomaly_sigma":2}}`),
+	}); err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+	if reads := adapter.reads.Load(); reads != 2 {
+		t.Errorf("Expected a task with different parameters to read the market, got %d reads", reads)
+	}
+}
+
+// pinnedAdapter records the block market reads on each chain are pinned to
+type pinnedAdapter struct {
+	*fakeAdapter
+	mu     sync.Mutex
+	blocks map[uint64]uint64
+}
+
+func (p *pinnedAdapter) MarketState(ctx context.Context, chainID uint64) (*adapters.MarketState, error) {
+	if block := chain.BlockOf(ctx); block != nil {
+		p.mu.Lock()
+		if p.blocks == nil {
+			p.blocks = make(map[uint64]uint64)
+		}
+		p.blocks[chainID] = block.Uint64()
+		p.mu.Unlock()
+	}
+	return p.fakeAdapter.MarketState(ctx, chainID)
+}
+
+func (p *pinnedAdapter) block(chainID uint64) uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.blocks[chainID]
+}
+
+func Test_YieldMonitoringSnapshots(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+	contracts := chaintest.NewContracts(1)
+	contracts.SetHead(120, 1_700_000_000)
+	contracts.SetFinalized(100)
+	chains := chain.NewManager()
+	chains.Register(1, "ethereum", contracts)
+
+	// snapping to a tenth of a percent turns the 3.84% of the fake market into 3.8%
+	cfg := snapshot.DefaultConfig()
+	cfg.Enabled = true
+	cfg.RatePlaces = 1
+	history := store.NewSeriesStore(store.NewMemoryKV())
+	adapter := &pinnedAdapter{fakeAdapter: newFakeAaveAdapter()}
+	performer := NewYieldIntelligencePerformer(logger,
+		WithAdapters(adapters.NewRegistry(adapter)),
+		WithRateHistory(history),
+		WithSnapshots(snapshot.New(cfg, chains)),
+	)
+
+	run := func(taskID, params string) (YieldMonitoringResult, error) {
+		task := &performerV1.TaskRequest{TaskId: []byte(taskID), Payload: []byte(`{"type":"yield_monitoring","parameters":` + params + `}`)}
+		if err := performer.ValidateTask(task); err != nil {
+			t.Fatalf("ValidateTask failed: %v", err)
+		}
+		var envelope struct {
+			Result YieldMonitoringResult `json:"result"`
+		}
+		resp, err := performer.HandleTask(task)
+		if err != nil {
+			return envelope.Result, err
+		}
+		if err := json.Unmarshal(resp.Result, &envelope); err != nil {
+			t.Fatalf("Failed to decode result: %v", err)
+		}
+		return envelope.Result, nil
+	}
+
+	result, err := run("finalized", `{"protocol":"aave_v3","token":"USDC","chain_id":1}`)
+	if err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+	if result.BlockNumber != 100 || adapter.block(1) != 100 {
+		t.Errorf("Expected the market to be read at the finalized block 100, got %d and reads at %d", result.BlockNumber, adapter.block(1))
+	}
+	if result.SupplyRate == nil || result.SupplyRate.String() != "3.8000" {
+		t.Errorf("Expected the supply rate to be snapped to 3.8000, got %v", result.SupplyRate)
+	}
+
+	// a requested past block is reported but not kept in the rate history
+	series := store.SupplyRateSeries(adapters.ProtocolAaveV3, 1)
+	before, err := history.Range(context.Background(), series, time.Now().Add(-time.Minute), time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Range failed: %v", err)
+	}
+	result, err = run("requested", `{"protocol":"aave_v3","token":"USDC","chain_id":1,"block_number":90}`)
+	if err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+	if result.BlockNumber != 90 || adapter.block(1) != 90 {
+		t.Errorf("Expected the market to be read at block 90, got %d and reads at %d", result.BlockNumber, adapter.block(1))
+	}
+	after, err := history.Range(context.Background(), series, time.Now().Add(-time.Minute), time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Range failed: %v", err)
+	}
+	if len(after) != len(before) {
+		t.Errorf("Expected the rate of a past block not to be recorded, got %d new points", len(after)-len(before))
+	}
+
+	header, err := contracts.HeaderByNumber(context.Background(), big.NewInt(95))
+	if err != nil {
+		t.Fatalf("HeaderByNumber failed: %v", err)
+	}
+	result, err = run("by-hash", fmt.Sprintf(`{"protocol":"aave_v3","token":"USDC","chain_id":1,"block_hash":"%s"}`, header.Hash().Hex()))
+	if err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+	if result.BlockNumber != 95 || adapter.block(1) != 95 {
+		t.Errorf("Expected the market to be read at block 95, got %d and reads at %d", result.BlockNumber, adapter.block(1))
+	}
+
+	if _, err := run("unfinalized", `{"protocol":"aave_v3","token":"USDC","chain_id":1,"block_number":110}`); !errors.Is(err, snapshot.ErrNotFinalized) {
+		t.Errorf("Expected a block past the finalized head to be refused, got %v", err)
+	}
+}
+
+func Test_YieldMonitoringBlockNumberValidation(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+	cfg := snapshot.DefaultConfig()
+	cfg.Enabled = true
+	snapshotting := NewYieldIntelligencePerformer(logger,
+		WithAdapters(adapters.NewRegistry(newFakeAaveAdapter())),
+		WithSnapshots(snapshot.New(cfg, chain.NewManager())),
+	)
+	latest := NewYieldIntelligencePerformer(logger, WithAdapters(adapters.NewRegistry(newFakeAaveAdapter())))
+
+	for name, tc := range map[string]struct {
+		performer *YieldIntelligencePerformer
+		params    string
+	}{
+		"zero block":        {performer: snapshotting, params: `{"protocol":"aave_v3","token":"USDC","chain_id":1,"block_number":0}`},
+		"fractional block":  {performer: snapshotting, params: `{"protocol":"aave_v3","token":"USDC","chain_id":1,"block_number":1.5}`},
+		"all chains":        {performer: snapshotting, params: `{"protocol":"all","token":"USDC","block_number":100}`},
+		"short hash":        {performer: snapshotting, params: `{"protocol":"aave_v3","token":"USDC","chain_id":1,"block_hash":"0x1234"}`},
+		"without snapshots": {performer: latest, params: `{"protocol":"aave_v3","token":"USDC","chain_id":1,"block_number":100}`},
+	} {
+		task := &performerV1.TaskRequest{TaskId: []byte(name), Payload: []byte(`{"type":"yield_monitoring","parameters":` + tc.params + `}`)}
+		if err := tc.performer.ValidateTask(task); err == nil {
+			t.Errorf("%s: expected validation to fail", name)
+		}
+	}
+}
+
+func Test_YieldMonitoringStability(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+	series := store.SupplyRateSeries(adapters.ProtocolAaveV3, 1)
+	payload := `{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","token":"USDC","chain_id":1}}`
+
+	steadyHistory := store.NewSeriesStore(store.NewMemoryKV())
+	seedRateHistory(t, steadyHistory, series, 0.0384, 7)
+	performer := NewYieldIntelligencePerformer(logger,
+		WithAdapters(adapters.NewRegistry(newFakeAaveAdapter())),
+		WithRateHistory(steadyHistory),
+	)
+	var steady YieldMonitoringResult
+	if err := runYieldMonitoring(t, performer, "steady", payload, &steady); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	if s := steady.Stability; s == nil || !s.Evaluated || s.WindowSeconds != 7*24*60*60 || s.Score.Float64() < 0.9 {
+		t.Fatalf("Expected a stable rate, got %+v", s)
+	}
+
+	// the rate spiked to 8% and fell to 2% within the last day
+	spikyHistory := store.NewSeriesStore(store.NewMemoryKV())
+	now := time.Now()
+	for i, rate := range []float64{0.04, 0.08, 0.02, 0.04} {
+		point := store.SeriesPoint{Time: now.Add(-time.Duration(4-i) * time.Hour), Value: rate}
+		if err := spikyHistory.Append(context.Background(), series, point); err != nil {
+			t.Fatalf("Failed to seed history: %v", err)
+		}
+	}
+	performer = NewYieldIntelligencePerformer(logger,
+		WithAdapters(adapters.NewRegistry(newFakeAaveAdapter())),
+		WithRateHistory(spikyHistory),
+		WithStability(stability.Config{Window: 24 * time.Hour, MinSamples: 5}),
+	)
+	var spiky YieldMonitoringResult
+	if err := runYieldMonitoring(t, performer, "spiky", payload, &spiky); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	s := spiky.Stability
+	if s == nil || !s.Evaluated || s.Samples != 5 || s.MaxDrawdown.String() != "0.750000" || s.Score.Cmp(steady.Stability.Score) >= 0 {
+		t.Errorf("Expected the spiking rate to score below the steady one, got %+v", s)
+	}
+}
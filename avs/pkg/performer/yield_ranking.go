@@ -0,0 +1,319 @@
+package performer
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+	"github.com/najnomics/crosscow-avs/pkg/ranking"
+)
+
+// Yield ranking modes
+const (
+	// RankingModeStandard ranks variable rate venues
+	RankingModeStandard = "standard"
+
+	// RankingModeFixedVsVariable also ranks fixed rate markets, and compares the rate
+	// each locks to maturity with the rate the best variable venue is expected to pay
+	RankingModeFixedVsVariable = "fixed_vs_variable"
+)
+
+// RankedVenue is a market in a yield ranking. Scores range from 0 to 1 and are null for
+// dimensions that could not be measured, which the score then leaves out. Sharpe is the
+// mean rate in excess of the risk free rate divided by the volatility of the rate.
+// Liquidity pools carry the estimate their supply rate is derived from, and sources
+// backed by real world assets who may hold them.
+type RankedVenue struct {
+	Rank           int                `json:"rank"`
+	Protocol       string             `json:"protocol"`
+	ChainID        uint64             `json:"chain_id"`
+	SupplyRate     canonical.Decimal  `json:"supply_rate"`
+	Stability      *StabilityReport   `json:"stability"`
+	Sharpe         *canonical.Decimal `json:"sharpe"`
+	RateScore      *canonical.Decimal `json:"rate_score"`
+	SharpeScore    *canonical.Decimal `json:"sharpe_score"`
+	StabilityScore *canonical.Decimal `json:"stability_score"`
+	DrawdownScore  *canonical.Decimal `json:"drawdown_score"`
+	Score          canonical.Decimal  `json:"score"`
+	LP             *LPEstimate        `json:"lp,omitempty"`
+	Fixed          *FixedTerm         `json:"fixed,omitempty"`
+	RWA            *RWAReport         `json:"rwa,omitempty"`
+}
+
+// FixedTerm is the rate a fixed rate market locks until its maturity, a unix time. The
+// implied APY is an annual percentage.
+type FixedTerm struct {
+	Maturity   uint64            `json:"maturity"`
+	ImpliedAPY canonical.Decimal `json:"implied_apy"`
+}
+
+// FixedComparison compares the rate a fixed rate market locks with the rate the best
+// variable venue is expected to pay, its mean rate over the stability window or its
+// current rate when its history is too short. Rates are annual percentages and the
+// spread is the fixed rate less the variable one, in percentage points.
+type FixedComparison struct {
+	Protocol         string            `json:"protocol"`
+	ChainID          uint64            `json:"chain_id"`
+	Maturity         uint64            `json:"maturity"`
+	DaysToMaturity   canonical.Decimal `json:"days_to_maturity"`
+	FixedRate        canonical.Decimal `json:"fixed_rate"`
+	VariableProtocol string            `json:"variable_protocol"`
+	VariableChainID  uint64            `json:"variable_chain_id"`
+	VariableRate     canonical.Decimal `json:"variable_rate"`
+	Spread           canonical.Decimal `json:"spread"`
+
+	// Preferred is fixed when locking pays at least the expected variable rate, and
+	// variable otherwise
+	Preferred string `json:"preferred"`
+}
+
+// LPEstimate is the yield estimate of a liquidity pool. Rates are annual percentages,
+// the peg deviation a percentage and TVL in USD. The supply rate of the pool is the sum
+// of its rates scaled by the risk modifier, which falls from 1 at the peg to 0 at the
+// largest tolerated deviation from it.
+type LPEstimate struct {
+	Kind          string            `json:"kind"`
+	FeeRate       canonical.Decimal `json:"fee_rate"`
+	IncentiveRate canonical.Decimal `json:"incentive_rate"`
+	PegDeviation  canonical.Decimal `json:"peg_deviation"`
+	RiskModifier  canonical.Decimal `json:"risk_modifier"`
+	TVL           canonical.Decimal `json:"tvl"`
+}
+
+// YieldRankingResult is the result of a yield_ranking task, venues best first. The risk
+// free rate is an annual percentage.
+type YieldRankingResult struct {
+	Token        string                                  `json:"token"`
+	RiskFreeRate canonical.Decimal                       `json:"risk_free_rate"`
+	Weights      map[ranking.Dimension]canonical.Decimal `json:"weights"`
+	Venues       []RankedVenue                           `json:"venues"`
+	Failures     []MarketFailure                         `json:"failures"`
+	Status       ResultStatus                            `json:"status"`
+
+	// FixedVsVariable compares every fixed rate market with variable venues, in
+	// fixed_vs_variable mode only
+	FixedVsVariable []FixedComparison `json:"fixed_vs_variable,omitempty"`
+}
+
+// handleYieldRanking ranks every selected market by risk-adjusted return, weighing its
+// current rate against how stable its rate history has been, so consumers need not
+// combine yield_monitoring results themselves. Markets that cannot be read are reported
+// as failures. Liquidity pools are ranked alongside lending markets when include_lp is
+// set, at their risk adjusted fee and incentive rate, and fixed rate markets in
+// fixed_vs_variable mode, which compares them with the best variable venue.
+func (yip *YieldIntelligencePerformer) handleYieldRanking(ctx context.Context, t *performerV1.TaskRequest, payload *TaskPayload) (interface{}, error) {
+	yip.log(ctx).Sugar().Infow("Processing yield ranking task")
+
+	weights := rankingWeights(payload)
+	riskFree, _ := payload.Parameters["risk_free_rate"].(float64)
+	mode := rankingMode(payload)
+	chainIDs := paramUint64List(payload, "chain_ids")
+	markets := yip.selectedMarkets(payload)
+	if paramBool(payload, "include_lp") {
+		markets = append(markets, selectProtocols(payload, yip.lpMarkets(chainIDs...))...)
+	}
+	if mode == RankingModeFixedVsVariable {
+		markets = append(markets, selectProtocols(payload, yip.fixedMarkets(chainIDs...))...)
+	}
+	if len(markets) == 0 {
+		return nil, fmt.Errorf("no markets are registered for the selected protocols and chains")
+	}
+
+	result := &YieldRankingResult{
+		Token:        paramString(payload, "token"),
+		RiskFreeRate: canonical.APY(riskFree),
+		Weights:      make(map[ranking.Dimension]canonical.Decimal, len(weights)),
+		Venues:       []RankedVenue{},
+		Failures:     []MarketFailure{},
+		Status:       ResultStatusCompleted,
+	}
+	for dimension, weight := range weights {
+		result.Weights[dimension] = canonical.Score(weight)
+	}
+
+	now := time.Now()
+	var venues []ranking.Venue
+	samples := make(map[market]int)
+	pools := make(map[market]*adapters.LPState)
+	terms := make(map[market]*adapters.FixedState)
+	var variable, fixed []ranking.Venue
+	for i, outcome := range yip.readMarkets(ctx, markets) {
+		if outcome.Err != nil {
+			result.Failures = append(result.Failures, marketFailure(markets[i], outcome.Err))
+			continue
+		}
+		rate := outcome.Value.Pool.SupplyRate()
+		stats, n, err := yip.rateStats(ctx, markets[i], rate, now)
+		if err != nil {
+			return nil, err
+		}
+		samples[markets[i]] = n
+		pools[markets[i]] = outcome.Value.LP
+		terms[markets[i]] = outcome.Value.Fixed
+		venue := ranking.Venue{Protocol: markets[i].protocol, ChainID: markets[i].chainID, Rate: rate, Stats: stats}
+		switch {
+		case outcome.Value.Fixed != nil:
+			fixed = append(fixed, venue)
+		case outcome.Value.LP == nil:
+			variable = append(variable, venue)
+		}
+		venues = append(venues, venue)
+	}
+	if len(venues) == 0 {
+		return nil, fmt.Errorf("failed to read any of %d markets: %s", len(markets), result.Failures[0].Error)
+	}
+	if len(result.Failures) > 0 {
+		result.Status = ResultStatusPartial
+	}
+
+	for i, r := range ranking.Rank(venues, weights, riskFree/100) {
+		m := market{protocol: r.Protocol, chainID: r.ChainID}
+		venue := RankedVenue{
+			Rank:           i + 1,
+			Protocol:       r.Protocol,
+			ChainID:        r.ChainID,
+			SupplyRate:     ratePercent(r.Rate),
+			Stability:      stabilityReport(r.Stats, samples[m], yip.stability.Window),
+			RateScore:      dimensionScore(r, ranking.DimensionRate),
+			SharpeScore:    dimensionScore(r, ranking.DimensionSharpe),
+			StabilityScore: dimensionScore(r, ranking.DimensionStability),
+			DrawdownScore:  dimensionScore(r, ranking.DimensionDrawdown),
+			Score:          canonical.Ratio(r.Score),
+			LP:             lpEstimate(pools[m]),
+			Fixed:          fixedTerm(terms[m]),
+			RWA:            yip.rwaReport(r.Protocol),
+		}
+		if r.Sharpe != nil {
+			sharpe := canonical.Score(*r.Sharpe)
+			venue.Sharpe = &sharpe
+		}
+		result.Venues = append(result.Venues, venue)
+	}
+
+	if mode == RankingModeFixedVsVariable && len(fixed) > 0 {
+		if len(variable) == 0 {
+			return nil, fmt.Errorf("failed to read any variable rate market to compare fixed rates with")
+		}
+		result.FixedVsVariable = compareFixedRates(fixed, variable, terms, now)
+	}
+	return result, nil
+}
+
+// compareFixedRates compares every fixed venue with the variable venue expected to pay
+// the most
+func compareFixedRates(fixed, variable []ranking.Venue, terms map[market]*adapters.FixedState, now time.Time) []FixedComparison {
+	expected := func(v ranking.Venue) float64 {
+		if v.Stats != nil {
+			return v.Stats.Mean
+		}
+		return v.Rate
+	}
+	best := variable[0]
+	for _, v := range variable[1:] {
+		if expected(v) > expected(best) {
+			best = v
+		}
+	}
+
+	out := make([]FixedComparison, 0, len(fixed))
+	for _, v := range fixed {
+		term := terms[market{protocol: v.Protocol, chainID: v.ChainID}]
+		days := time.Unix(int64(term.Maturity), 0).Sub(now).Hours() / 24
+		comparison := FixedComparison{
+			Protocol:         v.Protocol,
+			ChainID:          v.ChainID,
+			Maturity:         term.Maturity,
+			DaysToMaturity:   canonical.NewDecimalFromFloat(math.Max(0, days), 2),
+			FixedRate:        ratePercent(v.Rate),
+			VariableProtocol: best.Protocol,
+			VariableChainID:  best.ChainID,
+			VariableRate:     ratePercent(expected(best)),
+			Spread:           ratePercent(v.Rate - expected(best)),
+			Preferred:        "fixed",
+		}
+		if v.Rate < expected(best) {
+			comparison.Preferred = "variable"
+		}
+		out = append(out, comparison)
+	}
+	return out
+}
+
+func fixedTerm(state *adapters.FixedState) *FixedTerm {
+	if state == nil {
+		return nil
+	}
+	return &FixedTerm{Maturity: state.Maturity, ImpliedAPY: ratePercent(state.ImpliedAPY)}
+}
+
+// rankingMode returns the mode parameter, standard when omitted
+func rankingMode(payload *TaskPayload) string {
+	if mode, ok := payload.Parameters["mode"].(string); ok && mode != "" {
+		return mode
+	}
+	return RankingModeStandard
+}
+
+func lpEstimate(state *adapters.LPState) *LPEstimate {
+	if state == nil {
+		return nil
+	}
+	return &LPEstimate{
+		Kind:          state.Kind,
+		FeeRate:       ratePercent(state.FeeRate),
+		IncentiveRate: ratePercent(state.IncentiveRate),
+		PegDeviation:  ratePercent(state.PegDeviation),
+		RiskModifier:  canonical.Ratio(state.RiskModifier),
+		TVL:           canonical.NewDecimalFromFloat(state.TVLUSD, USDCDecimals),
+	}
+}
+
+func dimensionScore(r ranking.Ranked, dimension ranking.Dimension) *canonical.Decimal {
+	score, ok := r.Scores[dimension]
+	if !ok {
+		return nil
+	}
+	out := canonical.Ratio(score)
+	return &out
+}
+
+// rankingWeights returns the weights parameter, the default weights when omitted
+func rankingWeights(payload *TaskPayload) map[ranking.Dimension]float64 {
+	raw, ok := payload.Parameters["weights"].(map[string]interface{})
+	if !ok {
+		return ranking.DefaultWeights()
+	}
+	weights := make(map[ranking.Dimension]float64, len(raw))
+	for dimension, v := range raw {
+		weight, _ := v.(float64)
+		weights[ranking.Dimension(dimension)] = weight
+	}
+	return weights
+}
+
+func (yip *YieldIntelligencePerformer) validateYieldRankingTask(payload *TaskPayload) error {
+	if err := validateToken(payload, paramUint64List(payload, "chain_ids")...); err != nil {
+		return err
+	}
+	var allowed []venueKind
+	if paramBool(payload, "include_lp") {
+		allowed = append(allowed, venueLiquidityPool)
+	}
+	if paramString(payload, "mode") == RankingModeFixedVsVariable {
+		allowed = append(allowed, venueFixedRate)
+	}
+	if err := yip.validateMarketSelection(payload, allowed...); err != nil {
+		return err
+	}
+
+	if _, present := payload.Parameters["weights"]; present {
+		if err := ranking.ValidateWeights(rankingWeights(payload)); err != nil {
+			return fmt.Errorf("invalid weights: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+package performer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/irm"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+	"go.uber.org/zap"
+)
+
+// newRankingPerformer serves a steady aave market on Ethereum, and one on Base paying
+// more right now after its rate swung between 2% and 8%
+func newRankingPerformer(t *testing.T) *YieldIntelligencePerformer {
+	t.Helper()
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+	aave := newFakeAaveAdapter()
+	base := *aave.markets[1]
+	base.ChainID = adapters.ChainIDBase
+	base.Pool.TotalBorrow = usdcUnits(85_000_000)
+	aave.markets[adapters.ChainIDBase] = &base
+
+	history := store.NewSeriesStore(store.NewMemoryKV())
+	seedRateHistory(t, history, store.SupplyRateSeries(adapters.ProtocolAaveV3, 1), 0.0384, 7)
+	now := time.Now()
+	for h := 7 * 24; h > 0; h-- {
+		rate := 0.02
+		if h%2 == 0 {
+			rate = 0.08
+		}
+		point := store.SeriesPoint{Time: now.Add(-time.Duration(h) * time.Hour), Value: rate}
+		if err := history.Append(context.Background(), store.SupplyRateSeries(adapters.ProtocolAaveV3, adapters.ChainIDBase), point); err != nil {
+			t.Fatalf("Failed to seed history: %v", err)
+		}
+	}
+
+	return NewYieldIntelligencePerformer(logger,
+		WithAdapters(adapters.NewRegistry(aave, &brokenAdapter{protocol: adapters.ProtocolCompoundV3, chainIDs: []uint64{1}})),
+		WithRateHistory(history),
+	)
+}
+
+func Test_YieldRanking(t *testing.T) {
+	performer := newRankingPerformer(t)
+
+	var result YieldRankingResult
+	if err := runYieldMonitoring(t, performer, "rank", `{"type":"yield_ranking","parameters":{"token":"USDC"}}`, &result); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	if len(result.Venues) != 2 || result.Status != ResultStatusPartial || len(result.Failures) != 1 {
+		t.Fatalf("Expected both aave venues and the compound failure, got %+v", result)
+	}
+	steady, spiky := result.Venues[0], result.Venues[1]
+	if steady.ChainID != 1 || steady.Rank != 1 || spiky.Rank != 2 {
+		t.Fatalf("Expected the steady venue first, got %+v", result.Venues)
+	}
+	if spiky.SupplyRate.Cmp(steady.SupplyRate) <= 0 || spiky.RateScore.String() != "1.000000" || steady.SharpeScore.String() != "1.000000" {
+		t.Errorf("Expected the spiky venue to pay more now and the steady one to be more efficient, got %+v and %+v", steady, spiky)
+	}
+	if !spiky.Stability.Evaluated || spiky.Stability.MaxDrawdown.String() != "0.750000" || spiky.Sharpe == nil {
+		t.Errorf("Expected the history of the spiky venue to be measured, got %+v", spiky.Stability)
+	}
+	if weight := result.Weights["rate"]; weight.String() != "40.00" {
+		t.Errorf("Expected the default weights to be reported, got %v", result.Weights)
+	}
+
+	// weighing the current rate alone ranks the spike first
+	result = YieldRankingResult{}
+	if err := runYieldMonitoring(t, performer, "rank-rate", `{"type":"yield_ranking","parameters":{"token":"USDC","weights":{"rate":1}}}`, &result); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	if result.Venues[0].ChainID != adapters.ChainIDBase || result.Venues[0].Score.String() != "1.000000" {
+		t.Errorf("Expected the highest rate first, got %+v", result.Venues)
+	}
+}
+
+// fakeLPAdapter is a stable pool on Ethereum paying 12% in fees and incentives, halved
+// for a 1% deviation from the peg
+type fakeLPAdapter struct{}
+
+func (fakeLPAdapter) Protocol() string   { return "curve_3pool" }
+func (fakeLPAdapter) ChainIDs() []uint64 { return []uint64{1} }
+func (fakeLPAdapter) PoolKind() string   { return adapters.PoolKindCurve }
+
+func (fakeLPAdapter) MarketState(ctx context.Context, chainID uint64) (*adapters.MarketState, error) {
+	return &adapters.MarketState{
+		Protocol: "curve_3pool",
+		ChainID:  chainID,
+		Pool:     irm.Pool{TotalSupply: usdcUnits(50_000_000), TotalBorrow: usdcUnits(0), Model: &irm.FixedModel{Rate: 0.06}},
+		LP:       &adapters.LPState{Kind: adapters.PoolKindCurve, FeeRate: 0.1, IncentiveRate: 0.02, TVLUSD: 50_000_000, PegDeviation: 0.01, RiskModifier: 0.5},
+	}, nil
+}
+
+func Test_YieldRankingIncludesLP(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+	performer := NewYieldIntelligencePerformer(logger,
+		WithAdapters(adapters.NewRegistry(newFakeAaveAdapter(), fakeLPAdapter{})),
+		WithRateHistory(store.NewSeriesStore(store.NewMemoryKV())),
+	)
+
+	var result YieldRankingResult
+	if err := runYieldMonitoring(t, performer, "rank", `{"type":"yield_ranking","parameters":{"token":"USDC"}}`, &result); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	if len(result.Venues) != 1 || result.Venues[0].Protocol != adapters.ProtocolAaveV3 || result.Venues[0].LP != nil {
+		t.Fatalf("Expected liquidity pools to be left out unless asked for, got %+v", result.Venues)
+	}
+
+	result = YieldRankingResult{}
+	if err := runYieldMonitoring(t, performer, "rank-lp", `{"type":"yield_ranking","parameters":{"token":"USDC","include_lp":true,"weights":{"rate":1}}}`, &result); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	if len(result.Venues) != 2 || result.Venues[0].Protocol != "curve_3pool" {
+		t.Fatalf("Expected the pool to rank first on its risk adjusted rate, got %+v", result.Venues)
+	}
+	lp := result.Venues[0].LP
+	if lp == nil || lp.Kind != adapters.PoolKindCurve || lp.FeeRate.Float64() != 10 || lp.RiskModifier.Float64() != 0.5 || lp.TVL.Float64() != 50_000_000 {
+		t.Errorf("Expected the estimate of the pool to be reported, got %+v", lp)
+	}
+	if result.Venues[0].SupplyRate.Float64() != 6 {
+		t.Errorf("Expected the risk adjusted rate as the supply rate, got %s", result.Venues[0].SupplyRate)
+	}
+
+	for name, params := range map[string]string{
+		"pool without include_lp": `"token":"USDC","protocols":["curve_3pool"]`,
+		"include_lp type":         `"token":"USDC","include_lp":"yes"`,
+	} {
+		task := &performerV1.TaskRequest{TaskId: []byte(name), Payload: []byte(`{"type":"yield_ranking","parameters":{` + params + `}}`)}
+		if err := performer.ValidateTask(task); err == nil {
+			t.Errorf("%s: expected the task to be rejected", name)
+		}
+	}
+}
+
+// fakeFixedAdapter is a principal token market on Ethereum locking 6% for 90 days
+type fakeFixedAdapter struct {
+	maturity uint64
+}
+
+func (fakeFixedAdapter) Protocol() string   { return "pendle_pt_ausdc" }
+func (fakeFixedAdapter) ChainIDs() []uint64 { return []uint64{1} }
+
+func (f fakeFixedAdapter) Maturity(ctx context.Context, chainID uint64) (uint64, error) {
+	return f.maturity, nil
+}
+
+func (f fakeFixedAdapter) MarketState(ctx context.Context, chainID uint64) (*adapters.MarketState, error) {
+	return &adapters.MarketState{
+		Protocol: "pendle_pt_ausdc",
+		ChainID:  chainID,
+		Pool:     irm.Pool{TotalSupply: usdcUnits(20_000_000), TotalBorrow: usdcUnits(0), Model: &irm.FixedModel{Rate: 0.06}},
+		Fixed:    &adapters.FixedState{Maturity: f.maturity, ImpliedAPY: 0.0618},
+	}, nil
+}
+
+func Test_YieldRankingFixedVsVariable(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+	maturity := uint64(time.Now().Add(90 * 24 * time.Hour).Unix())
+	performer := NewYieldIntelligencePerformer(logger,
+		WithAdapters(adapters.NewRegistry(newFakeAaveAdapter(), fakeFixedAdapter{maturity: maturity})),
+		WithRateHistory(store.NewSeriesStore(store.NewMemoryKV())),
+	)
+
+	var result YieldRankingResult
+	if err := runYieldMonitoring(t, performer, "rank", `{"type":"yield_ranking","parameters":{"token":"USDC"}}`, &result); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	if len(result.Venues) != 1 || result.FixedVsVariable != nil {
+		t.Fatalf("Expected fixed rate markets to be left out of standard rankings, got %+v", result)
+	}
+
+	result = YieldRankingResult{}
+	if err := runYieldMonitoring(t, performer, "fixed", `{"type":"yield_ranking","parameters":{"token":"USDC","mode":"fixed_vs_variable"}}`, &result); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	if len(result.Venues) != 2 || len(result.FixedVsVariable) != 1 {
+		t.Fatalf("Expected both venues and one comparison, got %+v", result)
+	}
+	for _, venue := range result.Venues {
+		if (venue.Protocol == "pendle_pt_ausdc") != (venue.Fixed != nil) {
+			t.Errorf("Expected only the fixed rate market to carry its term, got %+v", venue)
+		}
+	}
+	comparison := result.FixedVsVariable[0]
+	if comparison.Protocol != "pendle_pt_ausdc" || comparison.VariableProtocol != adapters.ProtocolAaveV3 || comparison.Maturity != maturity {
+		t.Errorf("Expected the market to be compared with aave, got %+v", comparison)
+	}
+	if comparison.Preferred != "fixed" || comparison.Spread.Float64() <= 0 || comparison.FixedRate.Float64() != 6 {
+		t.Errorf("Expected locking 6%% to beat the aave rate, got %+v", comparison)
+	}
+	if days := comparison.DaysToMaturity.Float64(); days < 89.9 || days > 90 {
+		t.Errorf("Expected 90 days to maturity, got %f", days)
+	}
+
+	for name, params := range map[string]string{
+		"fixed market in standard mode": `"token":"USDC","protocols":["pendle_pt_ausdc"]`,
+		"unknown mode":                  `"token":"USDC","mode":"locked"`,
+	} {
+		task := &performerV1.TaskRequest{TaskId: []byte(name), Payload: []byte(`{"type":"yield_ranking","parameters":{` + params + `}}`)}
+		if err := performer.ValidateTask(task); err == nil {
+			t.Errorf("%s: expected the task to be rejected", name)
+		}
+	}
+}
+
+func Test_YieldRankingValidation(t *testing.T) {
+	performer := newRankingPerformer(t)
+
+	testCases := map[string]string{
+		"missing token":     `"chain_ids":[1]`,
+		"unknown protocol":  `"token":"USDC","protocols":["euler"]`,
+		"unknown dimension": `"token":"USDC","weights":{"tvl":1}`,
+		"negative weight":   `"token":"USDC","weights":{"rate":-1,"sharpe":2}`,
+		"zero weights":      `"token":"USDC","weights":{"rate":0}`,
+		"weight type":       `"token":"USDC","weights":{"rate":"high"}`,
+		"risk free rate":    `"token":"USDC","risk_free_rate":-1`,
+	}
+	for name, params := range testCases {
+		t.Run(name, func(t *testing.T) {
+			task := &performerV1.TaskRequest{
+				TaskId:  []byte(name),
+				Payload: []byte(`{"type":"yield_ranking","parameters":{` + params + `}}`),
+			}
+			if err := performer.ValidateTask(task); err == nil {
+				t.Errorf("Expected the task to be rejected")
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+// Package permit signs EIP-2612 permits, which let deposits spend the USDC of the
+// performer account without an approval transaction before them, and verifies permits
+// users sign for the performer account to pull their USDC with, EIP-2612 or Permit2.
+// Native USDC implements EIP-2612 on every chain the performer rebalances on. The domain
+// of the permit is read from the token and checked against its DOMAIN_SEPARATOR, so tokens
+// whose name or version differ from what they report are refused rather than signed for.
+package permit
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/signer"
+)
+
+// Config configures permit deposits
+type Config struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Validity is how long after signing permits can be spent
+	Validity time.Duration `yaml:"validity"`
+}
+
+// DefaultConfig signs permits valid for 30 minutes once enabled
+func DefaultConfig() Config {
+	return Config{Validity: 30 * time.Minute}
+}
+
+// Validate checks the config for values permits cannot be signed with
+func (c Config) Validate() error {
+	if c.Enabled && c.Validity <= 0 {
+		return fmt.Errorf("validity must be positive")
+	}
+	return nil
+}
+
+// FiatToken getters of the EIP-712 domain and permit nonces
+const tokenABIJson = `[
+	{"name":"name","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"string"}]},
+	{"name":"version","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"string"}]},
+	{"name":"DOMAIN_SEPARATOR","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"bytes32"}]},
+	{"name":"nonces","type":"function","stateMutability":"view","inputs":[{"name":"owner","type":"address"}],"outputs":[{"name":"","type":"uint256"}]}
+]`
+
+var tokenABI = chain.MustParseABI(tokenABIJson)
+
+// Request is a permit of Value of Token, owned by the account of the signer, for Spender
+type Request struct {
+	ChainID  uint64
+	Token    common.Address
+	Spender  common.Address
+	Value    *big.Int
+	Deadline time.Time
+}
+
+// Sign reads the domain and nonce of the token of req with client, a client of its chain,
+// and signs the permit with s
+func Sign(ctx context.Context, client chain.Client, s signer.Signer, req Request) (*adapters.Permit, error) {
+	name, version, err := readDomain(ctx, client, req.ChainID, req.Token)
+	if err != nil {
+		return nil, err
+	}
+	owner := s.Address()
+	nonce, err := chain.CallUint(ctx, client, req.Token, tokenABI, "nonces", owner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the permit nonce of %s: %w", owner.Hex(), err)
+	}
+
+	deadline := big.NewInt(req.Deadline.Unix())
+	data := TypedData(req, name, version, owner, nonce)
+	sig, err := s.SignTypedData(ctx, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign permit: %w", err)
+	}
+	permit := &adapters.Permit{Deadline: deadline, V: sig[64] + 27}
+	copy(permit.R[:], sig[:32])
+	copy(permit.S[:], sig[32:64])
+	return permit, nil
+}
+
+// TypedData is the EIP-712 Permit message of req signed by owner with nonce, in the
+// domain of the token called name at version
+func TypedData(req Request, name, version string, owner common.Address, nonce *big.Int) apitypes.TypedData {
+	return apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": {
+				{Name: "name", Type: "string"},
+				{Name: "version", Type: "string"},
+				{Name: "chainId", Type: "uint256"},
+				{Name: "verifyingContract", Type: "address"},
+			},
+			"Permit": {
+				{Name: "owner", Type: "address"},
+				{Name: "spender", Type: "address"},
+				{Name: "value", Type: "uint256"},
+				{Name: "nonce", Type: "uint256"},
+				{Name: "deadline", Type: "uint256"},
+			},
+		},
+		PrimaryType: "Permit",
+		Domain: apitypes.TypedDataDomain{
+			Name:              name,
+			Version:           version,
+			ChainId:           math.NewHexOrDecimal256(int64(req.ChainID)),
+			VerifyingContract: req.Token.Hex(),
+		},
+		Message: apitypes.TypedDataMessage{
+			"owner":    owner.Hex(),
+			"spender":  req.Spender.Hex(),
+			"value":    (*math.HexOrDecimal256)(req.Value),
+			"nonce":    (*math.HexOrDecimal256)(nonce),
+			"deadline": math.NewHexOrDecimal256(req.Deadline.Unix()),
+		},
+	}
+}
+
+// readDomain reads the name and version of token on chainID, checked against its
+// DOMAIN_SEPARATOR
+func readDomain(ctx context.Context, client chain.Client, chainID uint64, token common.Address) (string, string, error) {
+	name, err := callString(ctx, client, token, "name")
+	if err != nil {
+		return "", "", err
+	}
+	version, err := callString(ctx, client, token, "version")
+	if err != nil {
+		return "", "", err
+	}
+	separator, err := chain.CallView(ctx, client, token, tokenABI, "DOMAIN_SEPARATOR")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read the domain separator of %s: %w", token.Hex(), err)
+	}
+	data := TypedData(Request{ChainID: chainID, Token: token}, name, version, common.Address{}, new(big.Int))
+	domain, err := data.HashStruct("EIP712Domain", data.Domain.Map())
+	if err != nil {
+		return "", "", fmt.Errorf("failed to hash the permit domain: %w", err)
+	}
+	if common.BytesToHash(domain) != common.Hash(separator[0].([32]byte)) {
+		return "", "", fmt.Errorf("the domain of %s on chain %d does not match its DOMAIN_SEPARATOR", token.Hex(), chainID)
+	}
+	return name, version, nil
+}
+
+func callString(ctx context.Context, client chain.Client, token common.Address, method string) (string, error) {
+	out, err := chain.CallView(ctx, client, token, tokenABI, method)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the %s of %s: %w", method, token.Hex(), err)
+	}
+	return out[0].(string), nil
+}
@@ -0,0 +1,167 @@
+package permit
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/najnomics/crosscow-avs/pkg/chain/chaintest"
+	"github.com/najnomics/crosscow-avs/pkg/signer"
+)
+
+func Test_Sign(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	account := signer.NewKeySigner(key)
+	req := Request{
+		ChainID:  1,
+		Token:    common.HexToAddress("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48"),
+		Spender:  common.HexToAddress("0x87870Bca3F3fD6335C3F4ce8392D69350B4fA4E2"),
+		Value:    big.NewInt(1_000_000_000),
+		Deadline: time.Unix(1_800_000_000, 0),
+	}
+	data := TypedData(req, "USD Coin", "2", account.Address(), big.NewInt(4))
+	separator, err := data.HashStruct("EIP712Domain", data.Domain.Map())
+	if err != nil {
+		t.Fatalf("Failed to hash domain: %v", err)
+	}
+
+	contracts := chaintest.NewContracts(1)
+	contracts.Stub(t, req.Token, tokenABI, "name", "USD Coin")
+	contracts.Stub(t, req.Token, tokenABI, "version", "2")
+	contracts.Stub(t, req.Token, tokenABI, "nonces", big.NewInt(4))
+	contracts.Stub(t, req.Token, tokenABI, "DOMAIN_SEPARATOR", [32]byte(separator))
+
+	permit, err := Sign(context.Background(), contracts, account, req)
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	if permit.Deadline.Int64() != 1_800_000_000 || (permit.V != 27 && permit.V != 28) {
+		t.Errorf("Unexpected permit %+v", permit)
+	}
+	hash, _, _ := apitypes.TypedDataAndHash(data)
+	sig := append(append(permit.R[:], permit.S[:]...), permit.V-27)
+	pub, err := crypto.SigToPub(hash, sig)
+	if err != nil || crypto.PubkeyToAddress(*pub) != account.Address() {
+		t.Errorf("Expected the permit to be signed by the account (%v)", err)
+	}
+
+	// a token reporting another domain than it verifies permits in
+	contracts.Stub(t, req.Token, tokenABI, "version", "1")
+	if _, err := Sign(context.Background(), contracts, account, req); err == nil {
+		t.Errorf("Expected a mismatched domain to be refused")
+	}
+}
+
+func Test_ConfigValidate(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Enabled = true
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected the config to be valid: %v", err)
+	}
+	cfg.Validity = 0
+	if err := cfg.Validate(); err == nil {
+		t.Errorf("Expected a zero validity to be rejected")
+	}
+}
+
+func Test_VerifySignedPermits(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	owner := crypto.PubkeyToAddress(key.PublicKey)
+	pull := Pull{
+		ChainID: 1,
+		Token:   common.HexToAddress("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48"),
+		Spender: common.HexToAddress("0x00000000000000000000000000000000000000bb"),
+		Amount:  big.NewInt(1_000_000_000),
+	}
+	now := time.Unix(1_700_000_000, 0)
+	sign := func(data apitypes.TypedData) []byte {
+		hash, _, err := apitypes.TypedDataAndHash(data)
+		if err != nil {
+			t.Fatalf("Failed to hash permit: %v", err)
+		}
+		sig, err := crypto.Sign(hash, key)
+		if err != nil {
+			t.Fatalf("Failed to sign permit: %v", err)
+		}
+		sig[64] += 27
+		return sig
+	}
+
+	contracts := chaintest.NewContracts(1)
+	data := TypedData(Request{ChainID: 1, Token: pull.Token}, "USD Coin", "2", common.Address{}, new(big.Int))
+	separator, err := data.HashStruct("EIP712Domain", data.Domain.Map())
+	if err != nil {
+		t.Fatalf("Failed to hash domain: %v", err)
+	}
+	contracts.Stub(t, pull.Token, tokenABI, "name", "USD Coin")
+	contracts.Stub(t, pull.Token, tokenABI, "version", "2")
+	contracts.Stub(t, pull.Token, tokenABI, "nonces", big.NewInt(3))
+	contracts.Stub(t, pull.Token, tokenABI, "DOMAIN_SEPARATOR", [32]byte(separator))
+	contracts.Stub(t, pull.Token, userTokenABI, "balanceOf", big.NewInt(5_000_000_000))
+	contracts.Stub(t, pull.Token, userTokenABI, "allowance", big.NewInt(5_000_000_000))
+	contracts.Stub(t, Permit2Address, permit2ABI, "nonceBitmap", big.NewInt(0b10))
+
+	eip2612 := &Signed{Standard: StandardEIP2612, Owner: owner, Value: pull.Amount, Deadline: 1_800_000_000}
+	eip2612.Signature = sign(TypedData(Request{
+		ChainID:  1,
+		Token:    pull.Token,
+		Spender:  pull.Spender,
+		Value:    eip2612.Value,
+		Deadline: time.Unix(int64(eip2612.Deadline), 0),
+	}, "USD Coin", "2", owner, big.NewInt(3)))
+	permit2 := &Signed{Standard: StandardPermit2, Owner: owner, Value: big.NewInt(2_000_000_000), Nonce: big.NewInt(0), Deadline: 1_800_000_000}
+	permit2.Signature = sign(Permit2TypedData(permit2, pull))
+
+	for name, signed := range map[string]*Signed{"eip-2612": eip2612, "permit2": permit2} {
+		if err := signed.Verify(context.Background(), contracts, pull, now); err != nil {
+			t.Errorf("%s: expected the permit to be verified: %v", name, err)
+		}
+		calls, err := signed.Calls(pull)
+		if err != nil {
+			t.Fatalf("%s: Calls failed: %v", name, err)
+		}
+		if signed.Standard == StandardEIP2612 && (len(calls) != 2 || calls[0].To != pull.Token || calls[1].To != pull.Token) {
+			t.Errorf("%s: expected permit and transferFrom on the token, got %+v", name, calls)
+		}
+		if signed.Standard == StandardPermit2 && (len(calls) != 1 || calls[0].To != Permit2Address) {
+			t.Errorf("%s: expected one transfer through Permit2, got %+v", name, calls)
+		}
+	}
+
+	// the spender the permit was not signed for
+	other := pull
+	other.Spender = common.HexToAddress("0x00000000000000000000000000000000000000cc")
+	// a nonce the bitmap marks spent
+	spent := *permit2
+	spent.Nonce = big.NewInt(1)
+	for name, tc := range map[string]struct {
+		signed *Signed
+		pull   Pull
+		now    time.Time
+	}{
+		"other spender": {signed: eip2612, pull: other, now: now},
+		"expired":       {signed: eip2612, pull: pull, now: time.Unix(1_800_000_000, 0)},
+		"over value":    {signed: eip2612, pull: Pull{ChainID: 1, Token: pull.Token, Spender: pull.Spender, Amount: big.NewInt(1_000_000_001)}, now: now},
+		"spent nonce":   {signed: &spent, pull: pull, now: now},
+		"over balance":  {signed: permit2, pull: Pull{ChainID: 1, Token: pull.Token, Spender: pull.Spender, Amount: big.NewInt(2_000_000_000)}, now: now},
+	} {
+		if name == "over balance" {
+			contracts.Stub(t, pull.Token, userTokenABI, "balanceOf", big.NewInt(1_500_000_000))
+		}
+		if err := tc.signed.Verify(context.Background(), contracts, tc.pull, tc.now); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: expected the permit to be refused, got %v", name, err)
+		}
+		contracts.Stub(t, pull.Token, userTokenABI, "balanceOf", big.NewInt(5_000_000_000))
+	}
+}
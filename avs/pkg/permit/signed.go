@@ -0,0 +1,261 @@
+package permit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+)
+
+// Standards of permits users sign
+const (
+	// StandardEIP2612 is a Permit of the token itself, spent with permit and transferFrom
+	StandardEIP2612 = "eip2612"
+
+	// StandardPermit2 is a PermitTransferFrom of the Permit2 signature transfer, spent with
+	// one permitTransferFrom. The owner must have approved Permit2 on the token.
+	StandardPermit2 = "permit2"
+)
+
+// Permit2Address is the canonical Permit2 contract, deployed at the same address on every
+// chain
+var Permit2Address = common.HexToAddress("0x000000000022D473030F116dDEE9F6B43aC78BA3")
+
+// ErrInvalid is returned for signed permits that cannot fund a pull
+var ErrInvalid = errors.New("invalid permit")
+
+const userTokenABIJson = `[
+	{"name":"permit","type":"function","stateMutability":"nonpayable","inputs":[{"name":"owner","type":"address"},{"name":"spender","type":"address"},{"name":"value","type":"uint256"},{"name":"deadline","type":"uint256"},{"name":"v","type":"uint8"},{"name":"r","type":"bytes32"},{"name":"s","type":"bytes32"}],"outputs":[]},
+	{"name":"transferFrom","type":"function","stateMutability":"nonpayable","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"value","type":"uint256"}],"outputs":[{"name":"","type":"bool"}]},
+	{"name":"balanceOf","type":"function","stateMutability":"view","inputs":[{"name":"account","type":"address"}],"outputs":[{"name":"","type":"uint256"}]},
+	{"name":"allowance","type":"function","stateMutability":"view","inputs":[{"name":"owner","type":"address"},{"name":"spender","type":"address"}],"outputs":[{"name":"","type":"uint256"}]}
+]`
+
+// Permit2 signature transfers and the bitmap of their spent nonces
+const permit2ABIJson = `[
+	{"name":"permitTransferFrom","type":"function","stateMutability":"nonpayable","inputs":[
+		{"name":"permit","type":"tuple","components":[
+			{"name":"permitted","type":"tuple","components":[{"name":"token","type":"address"},{"name":"amount","type":"uint256"}]},
+			{"name":"nonce","type":"uint256"},
+			{"name":"deadline","type":"uint256"}]},
+		{"name":"transferDetails","type":"tuple","components":[{"name":"to","type":"address"},{"name":"requestedAmount","type":"uint256"}]},
+		{"name":"owner","type":"address"},
+		{"name":"signature","type":"bytes"}],"outputs":[]},
+	{"name":"nonceBitmap","type":"function","stateMutability":"view","inputs":[{"name":"owner","type":"address"},{"name":"wordPos","type":"uint256"}],"outputs":[{"name":"","type":"uint256"}]}
+]`
+
+var (
+	userTokenABI = chain.MustParseABI(userTokenABIJson)
+	permit2ABI   = chain.MustParseABI(permit2ABIJson)
+)
+
+// Signed is a permit Owner signed allowing the performer account to pull up to Value of
+// their tokens until Deadline, a unix timestamp. Nonce is the Permit2 nonce the owner
+// picked; EIP-2612 permits are signed over the current nonce of the token.
+type Signed struct {
+	Standard  string         `json:"standard"`
+	Owner     common.Address `json:"owner"`
+	Value     *big.Int       `json:"value"`
+	Nonce     *big.Int       `json:"nonce,omitempty"`
+	Deadline  uint64         `json:"deadline"`
+	Signature hexutil.Bytes  `json:"signature"`
+}
+
+// Pull is a transfer of Amount of Token on chain ChainID from the owner of a permit to
+// Spender, the account sending it
+type Pull struct {
+	ChainID uint64
+	Token   common.Address
+	Spender common.Address
+	Amount  *big.Int
+}
+
+// Verify checks with client, a client of the chain of pull, that the permit funds pull at
+// now: it is signed by its owner for the spender, allows the amount, has neither expired
+// nor been spent, and the owner holds the amount. Permits that do not are refused with
+// ErrInvalid.
+func (s *Signed) Verify(ctx context.Context, client chain.Client, pull Pull, now time.Time) error {
+	if len(s.Signature) != crypto.SignatureLength {
+		return fmt.Errorf("%w: signatures are %d bytes, not %d", ErrInvalid, crypto.SignatureLength, len(s.Signature))
+	}
+	if !time.Unix(int64(s.Deadline), 0).After(now) {
+		return fmt.Errorf("%w: expired at %d", ErrInvalid, s.Deadline)
+	}
+	if s.Value.Cmp(pull.Amount) < 0 {
+		return fmt.Errorf("%w: allows %s, less than the %s to pull", ErrInvalid, s.Value, pull.Amount)
+	}
+
+	var data apitypes.TypedData
+	switch s.Standard {
+	case StandardEIP2612:
+		name, version, err := readDomain(ctx, client, pull.ChainID, pull.Token)
+		if err != nil {
+			return err
+		}
+		nonce, err := chain.CallUint(ctx, client, pull.Token, tokenABI, "nonces", s.Owner)
+		if err != nil {
+			return fmt.Errorf("failed to read the permit nonce of %s: %w", s.Owner.Hex(), err)
+		}
+		data = TypedData(Request{
+			ChainID:  pull.ChainID,
+			Token:    pull.Token,
+			Spender:  pull.Spender,
+			Value:    s.Value,
+			Deadline: time.Unix(int64(s.Deadline), 0),
+		}, name, version, s.Owner, nonce)
+	case StandardPermit2:
+		if s.Nonce == nil {
+			return fmt.Errorf("%w: Permit2 permits need a nonce", ErrInvalid)
+		}
+		bitmap, err := chain.CallUint(ctx, client, Permit2Address, permit2ABI, "nonceBitmap", s.Owner, new(big.Int).Rsh(s.Nonce, 8))
+		if err != nil {
+			return fmt.Errorf("failed to read the Permit2 nonces of %s: %w", s.Owner.Hex(), err)
+		}
+		if bitmap.Bit(int(s.Nonce.Uint64()&0xff)) == 1 {
+			return fmt.Errorf("%w: Permit2 nonce %s was already spent", ErrInvalid, s.Nonce)
+		}
+		allowance, err := chain.CallUint(ctx, client, pull.Token, userTokenABI, "allowance", s.Owner, Permit2Address)
+		if err != nil {
+			return fmt.Errorf("failed to read the Permit2 allowance of %s: %w", s.Owner.Hex(), err)
+		}
+		if allowance.Cmp(pull.Amount) < 0 {
+			return fmt.Errorf("%w: %s approved Permit2 for %s, less than the %s to pull", ErrInvalid, s.Owner.Hex(), allowance, pull.Amount)
+		}
+		data = Permit2TypedData(s, pull)
+	default:
+		return fmt.Errorf("%w: unknown standard %q", ErrInvalid, s.Standard)
+	}
+
+	hash, _, err := apitypes.TypedDataAndHash(data)
+	if err != nil {
+		return fmt.Errorf("failed to hash the permit: %w", err)
+	}
+	signer, err := recoverSigner(hash, s.Signature)
+	if err != nil || signer != s.Owner {
+		return fmt.Errorf("%w: not signed by %s for %s", ErrInvalid, s.Owner.Hex(), pull.Spender.Hex())
+	}
+
+	balance, err := chain.CallUint(ctx, client, pull.Token, userTokenABI, "balanceOf", s.Owner)
+	if err != nil {
+		return fmt.Errorf("failed to read the balance of %s: %w", s.Owner.Hex(), err)
+	}
+	if balance.Cmp(pull.Amount) < 0 {
+		return fmt.Errorf("%w: %s holds %s, less than the %s to pull", ErrInvalid, s.Owner.Hex(), balance, pull.Amount)
+	}
+	return nil
+}
+
+// Calls returns the calls the spender of pull sends, in order, to pull the amount with the
+// permit: permit and transferFrom for EIP-2612 permits, and permitTransferFrom for Permit2
+func (s *Signed) Calls(pull Pull) ([]chain.Call, error) {
+	if len(s.Signature) != crypto.SignatureLength {
+		return nil, fmt.Errorf("%w: signatures are %d bytes, not %d", ErrInvalid, crypto.SignatureLength, len(s.Signature))
+	}
+	signature := common.CopyBytes(s.Signature)
+	if signature[64] < 27 {
+		signature[64] += 27
+	}
+	deadline := new(big.Int).SetUint64(s.Deadline)
+	switch s.Standard {
+	case StandardEIP2612:
+		var r, sig [32]byte
+		copy(r[:], signature[:32])
+		copy(sig[:], signature[32:64])
+		permit, err := userTokenABI.Pack("permit", s.Owner, pull.Spender, s.Value, deadline, signature[64], r, sig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to pack permit: %w", err)
+		}
+		transfer, err := userTokenABI.Pack("transferFrom", s.Owner, pull.Spender, pull.Amount)
+		if err != nil {
+			return nil, fmt.Errorf("failed to pack transferFrom: %w", err)
+		}
+		return []chain.Call{{To: pull.Token, Data: permit}, {To: pull.Token, Data: transfer}}, nil
+	case StandardPermit2:
+		type tokenPermissions struct {
+			Token  common.Address
+			Amount *big.Int
+		}
+		transfer, err := permit2ABI.Pack("permitTransferFrom",
+			struct {
+				Permitted tokenPermissions
+				Nonce     *big.Int
+				Deadline  *big.Int
+			}{tokenPermissions{pull.Token, s.Value}, s.Nonce, deadline},
+			struct {
+				To              common.Address
+				RequestedAmount *big.Int
+			}{pull.Spender, pull.Amount},
+			s.Owner, signature)
+		if err != nil {
+			return nil, fmt.Errorf("failed to pack permitTransferFrom: %w", err)
+		}
+		return []chain.Call{{To: Permit2Address, Data: transfer}}, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown standard %q", ErrInvalid, s.Standard)
+	}
+}
+
+// Permit2TypedData is the EIP-712 PermitTransferFrom message of s allowing the spender of
+// pull to transfer its token
+func Permit2TypedData(s *Signed, pull Pull) apitypes.TypedData {
+	return apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": {
+				{Name: "name", Type: "string"},
+				{Name: "chainId", Type: "uint256"},
+				{Name: "verifyingContract", Type: "address"},
+			},
+			"PermitTransferFrom": {
+				{Name: "permitted", Type: "TokenPermissions"},
+				{Name: "spender", Type: "address"},
+				{Name: "nonce", Type: "uint256"},
+				{Name: "deadline", Type: "uint256"},
+			},
+			"TokenPermissions": {
+				{Name: "token", Type: "address"},
+				{Name: "amount", Type: "uint256"},
+			},
+		},
+		PrimaryType: "PermitTransferFrom",
+		Domain: apitypes.TypedDataDomain{
+			Name:              "Permit2",
+			ChainId:           math.NewHexOrDecimal256(int64(pull.ChainID)),
+			VerifyingContract: Permit2Address.Hex(),
+		},
+		Message: apitypes.TypedDataMessage{
+			"permitted": map[string]interface{}{
+				"token":  pull.Token.Hex(),
+				"amount": (*math.HexOrDecimal256)(s.Value),
+			},
+			"spender":  pull.Spender.Hex(),
+			"nonce":    (*math.HexOrDecimal256)(s.Nonce),
+			"deadline": (*math.HexOrDecimal256)(new(big.Int).SetUint64(s.Deadline)),
+		},
+	}
+}
+
+// recoverSigner returns the account that signed hash, refusing the malleable signatures
+// tokens and Permit2 refuse
+func recoverSigner(hash []byte, signature []byte) (common.Address, error) {
+	sig := common.CopyBytes(signature)
+	if sig[64] >= 27 {
+		sig[64] -= 27
+	}
+	r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:64])
+	if !crypto.ValidateSignatureValues(sig[64], r, s, true) {
+		return common.Address{}, fmt.Errorf("malformed signature")
+	}
+	pub, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
@@ -0,0 +1,185 @@
+// Package policy holds the hard limits operators put on where funds may go: protocols
+// allowed or denied, markets too small or too utilized, and how much a single move or
+// plan may put anywhere. Unlike risk scores, which weigh concerns against each other, a
+// violated rule blocks the move outright. Profiles add the rules of one user on top.
+package policy
+
+import (
+	"fmt"
+	"math/big"
+	"slices"
+
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+)
+
+// usdcDecimals scales the whole USDC amounts of the config to base units
+const usdcDecimals = 6
+
+// Rule names a policy rule
+type Rule string
+
+const (
+	RuleProtocolAllowlist     Rule = "protocol_allowlist"
+	RuleProtocolDenylist      Rule = "protocol_denylist"
+	RuleMinTVL                Rule = "min_tvl"
+	RuleMaxUtilization        Rule = "max_utilization"
+	RuleMaxProtocolAllocation Rule = "max_protocol_allocation"
+	RuleMaxMoveSize           Rule = "max_move_size"
+)
+
+// Config defines the rules. Amounts are whole USDC, and zero values leave a rule out.
+type Config struct {
+	Enabled bool `yaml:"enabled"`
+
+	// AllowedProtocols are the only protocols funds may go to, any when empty.
+	// DeniedProtocols are never used, even when allowed.
+	AllowedProtocols []string `yaml:"allowedProtocols"`
+	DeniedProtocols  []string `yaml:"deniedProtocols"`
+
+	// MinTVL is the total supply a market needs to receive funds
+	MinTVL uint64 `yaml:"minTvl"`
+
+	// MaxUtilization is the utilization, from 0 to 1, above which a market receives no
+	// funds, since what it holds could not be withdrawn
+	MaxUtilization float64 `yaml:"maxUtilization"`
+
+	// MaxProtocolAllocation is the most a single move or allocation plan puts into one
+	// protocol, all chains together
+	MaxProtocolAllocation uint64 `yaml:"maxProtocolAllocation"`
+
+	// MaxMoveSize is the most a single move takes from one market to another
+	MaxMoveSize uint64 `yaml:"maxMoveSize"`
+}
+
+// DefaultConfig returns the disabled config without rules
+func DefaultConfig() Config {
+	return Config{}
+}
+
+// Validate checks the config for rules that contradict each other or cannot hold
+func (c Config) Validate() error {
+	if c.MaxUtilization < 0 || c.MaxUtilization > 1 {
+		return fmt.Errorf("maxUtilization must be between 0 and 1")
+	}
+	for _, protocol := range c.DeniedProtocols {
+		if slices.Contains(c.AllowedProtocols, protocol) {
+			return fmt.Errorf("protocol %s is both allowed and denied", protocol)
+		}
+	}
+	return nil
+}
+
+// Market is the state of a market funds may go to. Amounts are in USDC base units.
+type Market struct {
+	Protocol    string
+	ChainID     uint64
+	TotalSupply *big.Int
+	Utilization float64
+}
+
+// Violation is a rule a move or market breaks. Limit and Actual are in the unit of the
+// rule, USDC for amounts, a ratio for utilization, basis points for improvements and
+// seconds for latencies, and are nil for protocol lists.
+type Violation struct {
+	Rule     Rule
+	Protocol string
+	ChainID  uint64
+	Limit    *canonical.Decimal
+	Actual   *canonical.Decimal
+	Reason   string
+}
+
+// Engine evaluates moves against the rules of a config. A nil engine allows everything.
+type Engine struct {
+	cfg Config
+}
+
+// New creates an engine evaluating the rules of cfg
+func New(cfg Config) *Engine {
+	return &Engine{cfg: cfg}
+}
+
+// NewFromConfig creates an engine, nil when policies are disabled
+func NewFromConfig(cfg Config) *Engine {
+	if !cfg.Enabled {
+		return nil
+	}
+	return New(cfg)
+}
+
+// CheckMarket returns the rules funds going to m would break, whatever their amount
+func (e *Engine) CheckMarket(m Market) []Violation {
+	if e == nil {
+		return nil
+	}
+	var out []Violation
+	violate := func(rule Rule, limit, actual *canonical.Decimal, reason string, args ...interface{}) {
+		out = append(out, Violation{Rule: rule, Protocol: m.Protocol, ChainID: m.ChainID, Limit: limit, Actual: actual, Reason: fmt.Sprintf(reason, args...)})
+	}
+
+	if len(e.cfg.AllowedProtocols) > 0 && !slices.Contains(e.cfg.AllowedProtocols, m.Protocol) {
+		violate(RuleProtocolAllowlist, nil, nil, "%s is not an allowed protocol", m.Protocol)
+	}
+	if slices.Contains(e.cfg.DeniedProtocols, m.Protocol) {
+		violate(RuleProtocolDenylist, nil, nil, "%s is a denied protocol", m.Protocol)
+	}
+	if minTVL := usdcUnits(e.cfg.MinTVL); minTVL != nil && m.TotalSupply != nil && m.TotalSupply.Cmp(minTVL) < 0 {
+		violate(RuleMinTVL, decimal(usdc(minTVL)), decimal(usdc(m.TotalSupply)), "%s on chain %d holds %s USDC, below the %s USDC minimum",
+			m.Protocol, m.ChainID, usdc(m.TotalSupply), usdc(minTVL))
+	}
+	if max := e.cfg.MaxUtilization; max > 0 && m.Utilization > max {
+		violate(RuleMaxUtilization, decimal(canonical.Ratio(max)), decimal(canonical.Ratio(m.Utilization)), "%s on chain %d is %.2f%% utilized, above the %.2f%% maximum",
+			m.Protocol, m.ChainID, m.Utilization*100, max*100)
+	}
+	return out
+}
+
+// CheckMove returns the rules moving amount into m would break
+func (e *Engine) CheckMove(m Market, amount *big.Int) []Violation {
+	if e == nil {
+		return nil
+	}
+	out := e.CheckMarket(m)
+	if max := usdcUnits(e.cfg.MaxMoveSize); max != nil && amount.Cmp(max) > 0 {
+		out = append(out, Violation{Rule: RuleMaxMoveSize, Protocol: m.Protocol, ChainID: m.ChainID, Limit: decimal(usdc(max)), Actual: decimal(usdc(amount)),
+			Reason: fmt.Sprintf("moving %s USDC exceeds the %s USDC maximum move size", usdc(amount), usdc(max))})
+	}
+	if max := usdcUnits(e.cfg.MaxProtocolAllocation); max != nil && amount.Cmp(max) > 0 {
+		out = append(out, Violation{Rule: RuleMaxProtocolAllocation, Protocol: m.Protocol, ChainID: m.ChainID, Limit: decimal(usdc(max)), Actual: decimal(usdc(amount)),
+			Reason: fmt.Sprintf("allocating %s USDC to %s exceeds the %s USDC maximum per protocol", usdc(amount), m.Protocol, usdc(max))})
+	}
+	return out
+}
+
+// MaxMoveSize returns the most a move may take in USDC base units, nil when unlimited
+func (e *Engine) MaxMoveSize() *big.Int {
+	if e == nil {
+		return nil
+	}
+	return usdcUnits(e.cfg.MaxMoveSize)
+}
+
+// MaxProtocolAllocation returns the most a plan may put into one protocol in USDC base
+// units, nil when unlimited
+func (e *Engine) MaxProtocolAllocation() *big.Int {
+	if e == nil {
+		return nil
+	}
+	return usdcUnits(e.cfg.MaxProtocolAllocation)
+}
+
+// usdcUnits converts whole USDC into base units, nil for zero
+func usdcUnits(amount uint64) *big.Int {
+	if amount == 0 {
+		return nil
+	}
+	return new(big.Int).Mul(new(big.Int).SetUint64(amount), big.NewInt(1_000_000))
+}
+
+func usdc(baseUnits *big.Int) canonical.Decimal {
+	return canonical.NewDecimalFromInt(baseUnits, usdcDecimals, usdcDecimals)
+}
+
+func decimal(d canonical.Decimal) *canonical.Decimal {
+	return &d
+}
@@ -0,0 +1,132 @@
+package policy
+
+import (
+	"math/big"
+	"testing"
+	"time"
+)
+
+func units(amount int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(amount), big.NewInt(1_000_000))
+}
+
+func rules(violations []Violation) []Rule {
+	out := make([]Rule, len(violations))
+	for i, v := range violations {
+		out[i] = v.Rule
+	}
+	return out
+}
+
+func Test_ConfigValidate(t *testing.T) {
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Errorf("Expected the default config to be valid, got %v", err)
+	}
+	for name, cfg := range map[string]Config{
+		"utilization above 1":  {MaxUtilization: 1.5},
+		"negative utilization": {MaxUtilization: -0.1},
+		"allowed and denied":   {AllowedProtocols: []string{"aave_v3"}, DeniedProtocols: []string{"aave_v3"}},
+	} {
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("Expected %s to be rejected", name)
+		}
+	}
+}
+
+func Test_DisabledEngineAllowsEverything(t *testing.T) {
+	engine := NewFromConfig(Config{DeniedProtocols: []string{"aave_v3"}})
+	if engine != nil {
+		t.Fatalf("Expected no engine while disabled")
+	}
+	if v := engine.CheckMove(Market{Protocol: "aave_v3"}, units(1)); v != nil {
+		t.Errorf("Expected a nil engine to allow everything, got %+v", v)
+	}
+	if engine.MaxMoveSize() != nil || engine.MaxProtocolAllocation() != nil {
+		t.Errorf("Expected a nil engine to have no caps")
+	}
+}
+
+func Test_CheckMarket(t *testing.T) {
+	engine := New(Config{
+		AllowedProtocols: []string{"aave_v3", "compound_v3"},
+		DeniedProtocols:  []string{"morpho"},
+		MinTVL:           10_000_000,
+		MaxUtilization:   0.9,
+	})
+	healthy := Market{Protocol: "aave_v3", ChainID: 1, TotalSupply: units(100_000_000), Utilization: 0.8}
+	if v := engine.CheckMarket(healthy); len(v) != 0 {
+		t.Errorf("Expected a healthy market to pass, got %+v", v)
+	}
+
+	v := engine.CheckMarket(Market{Protocol: "morpho", ChainID: 8453, TotalSupply: units(5_000_000), Utilization: 0.95})
+	got := rules(v)
+	want := []Rule{RuleProtocolAllowlist, RuleProtocolDenylist, RuleMinTVL, RuleMaxUtilization}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Expected %v, got %v", want, got)
+		}
+	}
+	if tvl := v[2]; tvl.Limit.String() != "10000000.000000" || tvl.Actual.String() != "5000000.000000" || tvl.ChainID != 8453 || tvl.Reason == "" {
+		t.Errorf("Unexpected min TVL violation %+v", tvl)
+	}
+	if utilization := v[3]; utilization.Limit.String() != "0.900000" || utilization.Actual.String() != "0.950000" {
+		t.Errorf("Unexpected utilization violation %+v", utilization)
+	}
+}
+
+func Test_CheckMove(t *testing.T) {
+	engine := New(Config{MaxMoveSize: 1_000_000, MaxProtocolAllocation: 2_000_000})
+	m := Market{Protocol: "aave_v3", ChainID: 1, TotalSupply: units(100_000_000)}
+
+	if v := engine.CheckMove(m, units(1_000_000)); len(v) != 0 {
+		t.Errorf("Expected a move at the limit to pass, got %+v", v)
+	}
+	if got := rules(engine.CheckMove(m, units(1_500_000))); len(got) != 1 || got[0] != RuleMaxMoveSize {
+		t.Errorf("Expected the move size to be exceeded, got %v", got)
+	}
+	if got := rules(engine.CheckMove(m, units(3_000_000))); len(got) != 2 || got[1] != RuleMaxProtocolAllocation {
+		t.Errorf("Expected both caps to be exceeded, got %v", got)
+	}
+	if engine.MaxMoveSize().Cmp(units(1_000_000)) != 0 || engine.MaxProtocolAllocation().Cmp(units(2_000_000)) != 0 {
+		t.Errorf("Unexpected caps %s and %s", engine.MaxMoveSize(), engine.MaxProtocolAllocation())
+	}
+}
+
+func Test_ProfileChecks(t *testing.T) {
+	profile, err := ProfilePreset(PresetConservative)
+	if err != nil {
+		t.Fatalf("ProfilePreset failed: %v", err)
+	}
+	m := Market{Protocol: "aave_v3", ChainID: 8453}
+
+	if v := profile.CheckRisk(m, big.NewRat(25, 1)); len(v) != 0 {
+		t.Errorf("Expected a score at the limit to pass, got %+v", v)
+	}
+	if v := profile.CheckRisk(m, big.NewRat(51, 2)); len(v) != 1 || v[0].Rule != RuleMaxRiskScore || v[0].Actual.String() != "25.50" {
+		t.Errorf("Expected the risk score to be exceeded, got %+v", v)
+	}
+	if v := profile.CheckImprovement(m, big.NewRat(49, 1)); len(v) != 1 || v[0].Rule != RuleMinAPYImprovement || v[0].Limit.String() != "50.00" {
+		t.Errorf("Expected the improvement to fall short, got %+v", v)
+	}
+	if v := profile.CheckBridgeLatency(m, time.Hour); len(v) != 1 || v[0].Rule != RuleMaxBridgeLatency || v[0].Actual.String() != "3600" {
+		t.Errorf("Expected the latency to be exceeded, got %+v", v)
+	}
+	if v := profile.CheckBridgeLatency(m, 19*time.Minute); len(v) != 0 {
+		t.Errorf("Expected a CCTP attestation to be fast enough, got %+v", v)
+	}
+
+	// unknown scores and nil profiles pass
+	var none *Profile
+	if profile.CheckRisk(m, nil) != nil || none.CheckRisk(m, big.NewRat(100, 1)) != nil || none.CheckBridgeLatency(m, time.Hour) != nil {
+		t.Errorf("Expected nothing to be checked")
+	}
+	if _, err := ProfilePreset("degen"); err == nil {
+		t.Errorf("Expected unknown presets to be rejected")
+	}
+	if err := (&Profile{MaxRiskScore: 101}).Validate(); err == nil {
+		t.Errorf("Expected risk scores above 100 to be rejected")
+	}
+}
@@ -0,0 +1,125 @@
+package policy
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+)
+
+const (
+	RuleMinAPYImprovement Rule = "min_apy_improvement"
+	RuleMaxRiskScore      Rule = "max_risk_score"
+	RuleMaxBridgeLatency  Rule = "max_bridge_latency"
+)
+
+// Presets of profiles
+const (
+	PresetConservative = "conservative"
+	PresetBalanced     = "balanced"
+	PresetAggressive   = "aggressive"
+)
+
+// MaxRiskScore is the highest risk score, which a market scoring every concern has
+const MaxRiskScore = 100
+
+// Profile is the appetite of one user for yield against risk, carried by the tasks made
+// on their behalf, where the operator's Config applies to every user. Zero values leave
+// a rule out.
+type Profile struct {
+	// Preset names the preset the profile starts from, empty for custom profiles
+	Preset string `json:"preset,omitempty"`
+
+	// MinAPYImprovementBps is how much more a target market must earn than the source
+	// market, once the moved funds dilute its rate
+	MinAPYImprovementBps uint64 `json:"min_apy_improvement_bps"`
+
+	// MaxRiskScore is the overall risk score, from 0 to 100, above which a market receives
+	// no funds
+	MaxRiskScore uint64 `json:"max_risk_score"`
+
+	// MaxBridgeLatencySeconds is the longest funds may spend bridging between chains
+	MaxBridgeLatencySeconds uint64 `json:"max_bridge_latency_seconds"`
+}
+
+// presets are what hook users pick from. Conservative users only take low risk markets
+// reached within a CCTP attestation; aggressive ones take anything short of critical risk
+// and wait out optimistic rollup withdrawals.
+var presets = map[string]Profile{
+	PresetConservative: {Preset: PresetConservative, MinAPYImprovementBps: 50, MaxRiskScore: 25, MaxBridgeLatencySeconds: 30 * 60},
+	PresetBalanced:     {Preset: PresetBalanced, MinAPYImprovementBps: 20, MaxRiskScore: 50, MaxBridgeLatencySeconds: 24 * 60 * 60},
+	PresetAggressive:   {Preset: PresetAggressive, MinAPYImprovementBps: 5, MaxRiskScore: 75},
+}
+
+// ProfilePreset returns the profile of preset
+func ProfilePreset(preset string) (Profile, error) {
+	p, ok := presets[preset]
+	if !ok {
+		return Profile{}, fmt.Errorf("unknown preset %q, must be %s, %s or %s", preset, PresetConservative, PresetBalanced, PresetAggressive)
+	}
+	return p, nil
+}
+
+// Validate checks the limits of the profile are in range
+func (p *Profile) Validate() error {
+	if p.MinAPYImprovementBps > 10_000 {
+		return fmt.Errorf("min_apy_improvement_bps must be at most 10000")
+	}
+	if p.MaxRiskScore > MaxRiskScore {
+		return fmt.Errorf("max_risk_score must be between 0 and %d", MaxRiskScore)
+	}
+	return nil
+}
+
+// CheckRisk returns the rules of the profile funds going to m, of overall risk score
+// score, would break. Markets whose score is unknown are not checked. A nil profile
+// allows everything, as do the other checks.
+func (p *Profile) CheckRisk(m Market, score *big.Rat) []Violation {
+	if p == nil || p.MaxRiskScore == 0 || score == nil {
+		return nil
+	}
+	limit := new(big.Rat).SetInt64(int64(p.MaxRiskScore))
+	if score.Cmp(limit) <= 0 {
+		return nil
+	}
+	actual := canonical.NewDecimalFromRat(score, canonical.ScorePlaces)
+	return []Violation{{Rule: RuleMaxRiskScore, Protocol: m.Protocol, ChainID: m.ChainID, Limit: decimal(canonical.NewDecimalFromRat(limit, canonical.ScorePlaces)), Actual: &actual,
+		Reason: fmt.Sprintf("%s on chain %d scores %s for risk, above the %d maximum of the profile", m.Protocol, m.ChainID, actual, p.MaxRiskScore)}}
+}
+
+// CheckImprovement returns the rules of the profile moving funds into m would break,
+// when m earns improvementBps more than where they come from
+func (p *Profile) CheckImprovement(m Market, improvementBps *big.Rat) []Violation {
+	if p == nil || p.MinAPYImprovementBps == 0 || improvementBps == nil {
+		return nil
+	}
+	limit := new(big.Rat).SetInt64(int64(p.MinAPYImprovementBps))
+	if improvementBps.Cmp(limit) >= 0 {
+		return nil
+	}
+	actual := canonical.NewDecimalFromRat(improvementBps, canonical.ScorePlaces)
+	return []Violation{{Rule: RuleMinAPYImprovement, Protocol: m.Protocol, ChainID: m.ChainID, Limit: decimal(canonical.NewDecimalFromRat(limit, canonical.ScorePlaces)), Actual: &actual,
+		Reason: fmt.Sprintf("%s on chain %d earns %s bps more, below the %d bps minimum improvement of the profile", m.Protocol, m.ChainID, actual, p.MinAPYImprovementBps)}}
+}
+
+// CheckBridgeLatency returns the rules of the profile bridging funds to m in latency
+// would break
+func (p *Profile) CheckBridgeLatency(m Market, latency time.Duration) []Violation {
+	if p == nil || p.MaxBridgeLatencySeconds == 0 || latency <= p.MaxBridgeLatency() {
+		return nil
+	}
+	seconds := uint64(latency / time.Second)
+	return []Violation{{Rule: RuleMaxBridgeLatency, Protocol: m.Protocol, ChainID: m.ChainID,
+		Limit:  decimal(canonical.NewDecimalFromInt(new(big.Int).SetUint64(p.MaxBridgeLatencySeconds), 0, 0)),
+		Actual: decimal(canonical.NewDecimalFromInt(new(big.Int).SetUint64(seconds), 0, 0)),
+		Reason: fmt.Sprintf("bridging to chain %d takes %s, longer than the %s maximum of the profile", m.ChainID, latency, p.MaxBridgeLatency())}}
+}
+
+// MaxBridgeLatency returns the longest funds may spend bridging, zero when unlimited
+func (p *Profile) MaxBridgeLatency() time.Duration {
+	if p == nil {
+		return 0
+	}
+	return time.Duration(p.MaxBridgeLatencySeconds) * time.Second
+}
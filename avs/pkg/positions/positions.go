@@ -0,0 +1,289 @@
+// Package positions tracks where the USDC of each owner, a user or the yield hook's
+// pooled funds, sits across protocols and chains. Positions are recorded from the
+// balances rebalances leave and from chain reads, and are reconciled against the chain
+// so rebalances withdraw what is actually held rather than what a task claims.
+package positions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+)
+
+const (
+	prefixPosition = "position:"
+	prefixMove     = "moved:"
+)
+
+// maxBps is the basis points of a whole amount
+const maxBps = 10_000
+
+// Source tells where a tracked position was last measured
+type Source string
+
+const (
+	// SourceExecution is a balance read by the checks of a rebalance
+	SourceExecution Source = "execution"
+
+	// SourceChain is a balance read from the chain on its own, when a position was
+	// untracked or stale, or on reconciliation
+	SourceChain Source = "chain"
+)
+
+// Config configures position tracking
+type Config struct {
+	Enabled bool `yaml:"enabled"`
+
+	// MaxAge is how long a tracked position is trusted before it is read from the
+	// chain again
+	MaxAge time.Duration `yaml:"maxAge"`
+
+	// ToleranceBps is the difference between a tracked and an observed position,
+	// relative to the tracked one, that reconciliation accepts as accrued interest
+	ToleranceBps uint64 `yaml:"toleranceBps"`
+}
+
+// DefaultConfig trusts positions for an hour and accepts 0.5% of interest between reads
+func DefaultConfig() Config {
+	return Config{
+		Enabled:      true,
+		MaxAge:       time.Hour,
+		ToleranceBps: 50,
+	}
+}
+
+// Validate checks the config for values positions cannot be tracked with
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.MaxAge <= 0 {
+		return fmt.Errorf("maxAge must be positive")
+	}
+	if c.ToleranceBps > maxBps {
+		return fmt.Errorf("toleranceBps must be at most %d", maxBps)
+	}
+	return nil
+}
+
+// Position is the USDC an owner holds in the market of a protocol on a chain, in USDC
+// base units
+type Position struct {
+	Owner     common.Address `json:"owner"`
+	Protocol  string         `json:"protocol"`
+	ChainID   uint64         `json:"chainId"`
+	Amount    *big.Int       `json:"amount"`
+	Source    Source         `json:"source"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+}
+
+// Discrepancy is a position the chain reports differently than tracked. Tracked is nil
+// for funds that were not tracked at all.
+type Discrepancy struct {
+	Protocol string
+	ChainID  uint64
+	Tracked  *big.Int
+	Observed *big.Int
+}
+
+// Tracker records positions in a store.KV
+type Tracker struct {
+	cfg Config
+	kv  store.KV
+	now func() time.Time
+
+	// mu serializes reconciliations with the records they compare against
+	mu sync.Mutex
+}
+
+// New creates a tracker recording positions in kv
+func New(cfg Config, kv store.KV) *Tracker {
+	return &Tracker{cfg: cfg, kv: kv, now: time.Now}
+}
+
+// NewFromConfig creates a tracker, or returns nil when tracking is disabled
+func NewFromConfig(cfg Config, kv store.KV) *Tracker {
+	if !cfg.Enabled {
+		return nil
+	}
+	return New(cfg, kv)
+}
+
+func ownerPrefix(owner common.Address) []byte {
+	return []byte(prefixPosition + strings.ToLower(owner.Hex()) + ":")
+}
+
+func positionKey(owner common.Address, protocol string, chainID uint64) []byte {
+	// zero padded chain ids sort numerically
+	return []byte(fmt.Sprintf("%s%s:%020d", ownerPrefix(owner), strings.ToLower(protocol), chainID))
+}
+
+// Record stores p, stamped with the current time when it has none
+func (t *Tracker) Record(ctx context.Context, p Position) error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.put(ctx, p)
+}
+
+// Forget drops the tracked position, so it is read from the chain when next needed
+func (t *Tracker) Forget(ctx context.Context, owner common.Address, protocol string, chainID uint64) error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.kv.Delete(ctx, positionKey(owner, protocol, chainID)); err != nil {
+		return fmt.Errorf("failed to forget position: %w", err)
+	}
+	return nil
+}
+
+// Get returns the tracked position or store.ErrNotFound
+func (t *Tracker) Get(ctx context.Context, owner common.Address, protocol string, chainID uint64) (*Position, error) {
+	if t == nil {
+		return nil, store.ErrNotFound
+	}
+	value, err := t.kv.Get(ctx, positionKey(owner, protocol, chainID))
+	if err != nil {
+		return nil, err
+	}
+	var p Position
+	if err := json.Unmarshal(value, &p); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal position: %w", err)
+	}
+	return &p, nil
+}
+
+// Fresh reports whether p was measured within the configured max age
+func (t *Tracker) Fresh(p *Position) bool {
+	return t != nil && p != nil && t.now().Sub(p.UpdatedAt) <= t.cfg.MaxAge
+}
+
+// Positions returns the tracked positions of owner in protocol and chain id order
+func (t *Tracker) Positions(ctx context.Context, owner common.Address) ([]Position, error) {
+	if t == nil {
+		return nil, nil
+	}
+	var out []Position
+	err := t.kv.Iterate(ctx, ownerPrefix(owner), func(key []byte, value []byte) error {
+		var p Position
+		if err := json.Unmarshal(value, &p); err != nil {
+			return fmt.Errorf("failed to unmarshal position %s: %w", string(key), err)
+		}
+		out = append(out, p)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Reconcile compares the positions of owner observed on the chain with the tracked ones,
+// records the observed ones and returns the discrepancies beyond the tolerance. Tracked
+// positions that were not observed are kept.
+func (t *Tracker) Reconcile(ctx context.Context, owner common.Address, observed []Position) ([]Discrepancy, error) {
+	if t == nil {
+		return nil, nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	discrepancies := []Discrepancy{}
+	for _, p := range observed {
+		tracked, err := t.Get(ctx, owner, p.Protocol, p.ChainID)
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			if p.Amount.Sign() == 0 {
+				// nothing held and nothing tracked
+				continue
+			}
+			discrepancies = append(discrepancies, Discrepancy{Protocol: p.Protocol, ChainID: p.ChainID, Observed: p.Amount})
+		case err != nil:
+			return nil, err
+		case !t.within(tracked.Amount, p.Amount):
+			discrepancies = append(discrepancies, Discrepancy{Protocol: p.Protocol, ChainID: p.ChainID, Tracked: tracked.Amount, Observed: p.Amount})
+		}
+
+		p.Owner = owner
+		p.Source = SourceChain
+		p.UpdatedAt = time.Time{}
+		if err := t.put(ctx, p); err != nil {
+			return nil, err
+		}
+	}
+	return discrepancies, nil
+}
+
+// within reports whether observed differs from tracked by at most the tolerance
+func (t *Tracker) within(tracked, observed *big.Int) bool {
+	diff := new(big.Int).Sub(observed, tracked)
+	diff.Abs(diff).Mul(diff, big.NewInt(maxBps))
+	return diff.Cmp(new(big.Int).Mul(tracked, new(big.Int).SetUint64(t.cfg.ToleranceBps))) <= 0
+}
+
+func moveKey(owner common.Address) []byte {
+	return []byte(prefixMove + strings.ToLower(owner.Hex()))
+}
+
+// RecordMove stamps the funds of owner as moved at the current time. Moves are kept
+// apart from positions, which are forgotten while rebalances settle, so how long ago
+// funds moved survives until they move again.
+func (t *Tracker) RecordMove(ctx context.Context, owner common.Address) error {
+	if t == nil {
+		return nil
+	}
+	value, err := t.now().UTC().MarshalText()
+	if err != nil {
+		return fmt.Errorf("failed to marshal move time: %w", err)
+	}
+	if err := t.kv.Set(ctx, moveKey(owner), value); err != nil {
+		return fmt.Errorf("failed to save move: %w", err)
+	}
+	return nil
+}
+
+// LastMove returns when the funds of owner last moved, zero when they never did as far
+// as the tracker knows
+func (t *Tracker) LastMove(ctx context.Context, owner common.Address) (time.Time, error) {
+	if t == nil {
+		return time.Time{}, nil
+	}
+	value, err := t.kv.Get(ctx, moveKey(owner))
+	if errors.Is(err, store.ErrNotFound) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	var at time.Time
+	if err := at.UnmarshalText(value); err != nil {
+		return time.Time{}, fmt.Errorf("failed to unmarshal move time: %w", err)
+	}
+	return at, nil
+}
+
+func (t *Tracker) put(ctx context.Context, p Position) error {
+	if p.UpdatedAt.IsZero() {
+		p.UpdatedAt = t.now().UTC()
+	}
+	value, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("failed to marshal position: %w", err)
+	}
+	if err := t.kv.Set(ctx, positionKey(p.Owner, p.Protocol, p.ChainID), value); err != nil {
+		return fmt.Errorf("failed to save position: %w", err)
+	}
+	return nil
+}
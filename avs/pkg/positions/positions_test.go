@@ -0,0 +1,143 @@
+package positions
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+)
+
+func usdc(amount int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(amount), big.NewInt(1_000_000))
+}
+
+func Test_TrackerRecordsPositions(t *testing.T) {
+	ctx := context.Background()
+	owner := common.HexToAddress("0xaa")
+	tracker := New(DefaultConfig(), store.NewMemoryKV())
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tracker.now = func() time.Time { return now }
+
+	for _, p := range []Position{
+		{Owner: owner, Protocol: "compound_v3", ChainID: 8453, Amount: usdc(300), Source: SourceExecution},
+		{Owner: owner, Protocol: "aave_v3", ChainID: 42161, Amount: usdc(200), Source: SourceExecution},
+		{Owner: owner, Protocol: "aave_v3", ChainID: 1, Amount: usdc(100), Source: SourceChain},
+		{Owner: common.HexToAddress("0xbb"), Protocol: "aave_v3", ChainID: 1, Amount: usdc(1)},
+	} {
+		if err := tracker.Record(ctx, p); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+
+	positions, err := tracker.Positions(ctx, owner)
+	if err != nil {
+		t.Fatalf("Positions failed: %v", err)
+	}
+	if len(positions) != 3 || positions[0].ChainID != 1 || positions[1].ChainID != 42161 || positions[2].Protocol != "compound_v3" {
+		t.Fatalf("Expected the owner's positions in protocol and chain order, got %+v", positions)
+	}
+
+	p, err := tracker.Get(ctx, owner, "AAVE_V3", 1)
+	if err != nil || p.Amount.Cmp(usdc(100)) != 0 || !p.UpdatedAt.Equal(now) {
+		t.Fatalf("Expected the stamped position, got %+v, %v", p, err)
+	}
+	if !tracker.Fresh(p) {
+		t.Errorf("Expected a position recorded now to be fresh")
+	}
+	now = now.Add(2 * time.Hour)
+	if tracker.Fresh(p) {
+		t.Errorf("Expected a position older than the max age to be stale")
+	}
+
+	if err := tracker.Forget(ctx, owner, "aave_v3", 1); err != nil {
+		t.Fatalf("Forget failed: %v", err)
+	}
+	if _, err := tracker.Get(ctx, owner, "aave_v3", 1); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("Expected the forgotten position to be gone, got %v", err)
+	}
+
+	var disabled *Tracker
+	if err := disabled.Record(ctx, Position{}); err != nil || NewFromConfig(Config{}, store.NewMemoryKV()) != nil {
+		t.Errorf("Expected a disabled tracker to record nothing")
+	}
+	if _, err := disabled.Get(ctx, owner, "aave_v3", 1); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("Expected a disabled tracker to track nothing, got %v", err)
+	}
+}
+
+func Test_TrackerReconcile(t *testing.T) {
+	ctx := context.Background()
+	owner := common.HexToAddress("0xaa")
+	tracker := New(DefaultConfig(), store.NewMemoryKV())
+	for _, p := range []Position{
+		{Owner: owner, Protocol: "aave_v3", ChainID: 1, Amount: usdc(1_000)},
+		{Owner: owner, Protocol: "compound_v3", ChainID: 1, Amount: usdc(1_000)},
+		{Owner: owner, Protocol: "morpho", ChainID: 8453, Amount: usdc(50)},
+	} {
+		if err := tracker.Record(ctx, p); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+
+	discrepancies, err := tracker.Reconcile(ctx, owner, []Position{
+		// interest within 50 bps
+		{Protocol: "aave_v3", ChainID: 1, Amount: usdc(1_004)},
+		{Protocol: "compound_v3", ChainID: 1, Amount: usdc(900)},
+		{Protocol: "spark_savings", ChainID: 1, Amount: usdc(20)},
+		{Protocol: "aave_v3", ChainID: 8453, Amount: new(big.Int)},
+	})
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if len(discrepancies) != 2 {
+		t.Fatalf("Expected the compound shortfall and the untracked spark funds, got %+v", discrepancies)
+	}
+	if d := discrepancies[0]; d.Protocol != "compound_v3" || d.Tracked.Cmp(usdc(1_000)) != 0 || d.Observed.Cmp(usdc(900)) != 0 {
+		t.Errorf("Unexpected compound discrepancy: %+v", d)
+	}
+	if d := discrepancies[1]; d.Protocol != "spark_savings" || d.Tracked != nil {
+		t.Errorf("Unexpected spark discrepancy: %+v", d)
+	}
+
+	positions, err := tracker.Positions(ctx, owner)
+	if err != nil {
+		t.Fatalf("Positions failed: %v", err)
+	}
+	if len(positions) != 4 || positions[2].Protocol != "morpho" || positions[1].Amount.Cmp(usdc(900)) != 0 || positions[1].Source != SourceChain {
+		t.Errorf("Expected observed positions to be recorded and unobserved ones kept, got %+v", positions)
+	}
+}
+
+func Test_TrackerRecordsMoves(t *testing.T) {
+	ctx := context.Background()
+	owner := common.HexToAddress("0xaa")
+	tracker := New(DefaultConfig(), store.NewMemoryKV())
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tracker.now = func() time.Time { return now }
+
+	if moved, err := tracker.LastMove(ctx, owner); err != nil || !moved.IsZero() {
+		t.Fatalf("Expected funds that never moved to have no last move, got %s, %v", moved, err)
+	}
+	if err := tracker.RecordMove(ctx, owner); err != nil {
+		t.Fatalf("RecordMove failed: %v", err)
+	}
+	// forgetting positions keeps the move
+	if err := tracker.Forget(ctx, owner, "aave_v3", 1); err != nil {
+		t.Fatalf("Forget failed: %v", err)
+	}
+	if moved, err := tracker.LastMove(ctx, owner); err != nil || !moved.Equal(now) {
+		t.Errorf("Expected the move to be stamped, got %s, %v", moved, err)
+	}
+	if moved, _ := tracker.LastMove(ctx, common.HexToAddress("0xbb")); !moved.IsZero() {
+		t.Errorf("Expected moves to be kept per owner, got %s", moved)
+	}
+
+	var disabled *Tracker
+	if moved, err := disabled.LastMove(ctx, owner); err != nil || !moved.IsZero() || disabled.RecordMove(ctx, owner) != nil {
+		t.Errorf("Expected a disabled tracker to track no moves")
+	}
+}
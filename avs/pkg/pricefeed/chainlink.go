@@ -0,0 +1,94 @@
+package pricefeed
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+)
+
+const chainlinkAggregatorABIJson = `[
+	{"name":"decimals","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint8"}]},
+	{"name":"latestRoundData","type":"function","stateMutability":"view","inputs":[],
+	 "outputs":[
+		{"name":"roundId","type":"uint80"},
+		{"name":"answer","type":"int256"},
+		{"name":"startedAt","type":"uint256"},
+		{"name":"updatedAt","type":"uint256"},
+		{"name":"answeredInRound","type":"uint80"}
+	]}
+]`
+
+var chainlinkAggregatorABI = chain.MustParseABI(chainlinkAggregatorABIJson)
+
+// ChainlinkSource reads a Chainlink USDC/USD aggregator
+type ChainlinkSource struct {
+	chains    *chain.Manager
+	chainID   uint64
+	feed      common.Address
+	heartbeat time.Duration
+}
+
+// NewChainlinkSource creates a source for the aggregator at feed. Answers older than
+// heartbeat relative to the latest block are reported as stale.
+func NewChainlinkSource(chains *chain.Manager, chainID uint64, feed common.Address, heartbeat time.Duration) *ChainlinkSource {
+	return &ChainlinkSource{chains: chains, chainID: chainID, feed: feed, heartbeat: heartbeat}
+}
+
+func (s *ChainlinkSource) Name() string {
+	return fmt.Sprintf("chainlink:%d", s.chainID)
+}
+
+func (s *ChainlinkSource) Kind() SourceKind {
+	return SourceKindOracle
+}
+
+func (s *ChainlinkSource) ChainID() uint64 {
+	return s.chainID
+}
+
+func (s *ChainlinkSource) Quote(ctx context.Context) (*Quote, error) {
+	client, err := s.chains.Client(s.chainID)
+	if err != nil {
+		return nil, err
+	}
+
+	decimalsOut, err := chain.CallView(ctx, client, s.feed, chainlinkAggregatorABI, "decimals")
+	if err != nil {
+		return nil, err
+	}
+	decimals, ok := decimalsOut[0].(uint8)
+	if !ok {
+		return nil, fmt.Errorf("unexpected decimals output type %T", decimalsOut[0])
+	}
+
+	round, err := chain.CallView(ctx, client, s.feed, chainlinkAggregatorABI, "latestRoundData")
+	if err != nil {
+		return nil, err
+	}
+	answer := round[1].(*big.Int)
+	updatedAt := round[3].(*big.Int)
+	if answer.Sign() <= 0 {
+		return nil, fmt.Errorf("%s reported non-positive answer %s", s.Name(), answer)
+	}
+
+	// Staleness is judged against chain time so every operator reaches the same verdict
+	head, err := chain.HeadHeader(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch latest header: %w", err)
+	}
+	age := int64(head.Time) - updatedAt.Int64()
+
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	return &Quote{
+		Source:    s.Name(),
+		Kind:      SourceKindOracle,
+		ChainID:   s.chainID,
+		Price:     new(big.Rat).SetFrac(answer, scale),
+		UpdatedAt: updatedAt.Uint64(),
+		Stale:     age > int64(s.heartbeat/time.Second),
+	}, nil
+}
@@ -0,0 +1,171 @@
+package pricefeed
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+)
+
+const curvePoolABIJson = `[
+	{"name":"get_dy","type":"function","stateMutability":"view",
+	 "inputs":[{"name":"i","type":"int128"},{"name":"j","type":"int128"},{"name":"dx","type":"uint256"}],
+	 "outputs":[{"name":"","type":"uint256"}]}
+]`
+
+const uniswapV3PoolABIJson = `[
+	{"name":"slot0","type":"function","stateMutability":"view","inputs":[],
+	 "outputs":[
+		{"name":"sqrtPriceX96","type":"uint160"},
+		{"name":"tick","type":"int24"},
+		{"name":"observationIndex","type":"uint16"},
+		{"name":"observationCardinality","type":"uint16"},
+		{"name":"observationCardinalityNext","type":"uint16"},
+		{"name":"feeProtocol","type":"uint8"},
+		{"name":"unlocked","type":"bool"}
+	]}
+]`
+
+var (
+	curvePoolABI     = chain.MustParseABI(curvePoolABIJson)
+	uniswapV3PoolABI = chain.MustParseABI(uniswapV3PoolABIJson)
+)
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// CurveSource quotes selling one USDC into a Curve stable pool. The output coin is
+// treated as worth one dollar.
+type CurveSource struct {
+	chains        *chain.Manager
+	chainID       uint64
+	name          string
+	pool          common.Address
+	usdcIndex     int64
+	quoteIndex    int64
+	quoteDecimals int
+}
+
+// NewCurveSource creates a source for pool, where usdcIndex and quoteIndex are the coin
+// indexes of USDC and the quote stablecoin and quoteDecimals the quote coin's decimals
+func NewCurveSource(chains *chain.Manager, chainID uint64, name string, pool common.Address, usdcIndex, quoteIndex int64, quoteDecimals int) *CurveSource {
+	return &CurveSource{
+		chains:        chains,
+		chainID:       chainID,
+		name:          name,
+		pool:          pool,
+		usdcIndex:     usdcIndex,
+		quoteIndex:    quoteIndex,
+		quoteDecimals: quoteDecimals,
+	}
+}
+
+func (s *CurveSource) Name() string {
+	return s.name
+}
+
+func (s *CurveSource) Kind() SourceKind {
+	return SourceKindDEX
+}
+
+func (s *CurveSource) ChainID() uint64 {
+	return s.chainID
+}
+
+func (s *CurveSource) Quote(ctx context.Context) (*Quote, error) {
+	client, err := s.chains.Client(s.chainID)
+	if err != nil {
+		return nil, err
+	}
+	dx := pow10(usdcDecimals)
+	dy, err := chain.CallUint(ctx, client, s.pool, curvePoolABI, "get_dy", big.NewInt(s.usdcIndex), big.NewInt(s.quoteIndex), dx)
+	if err != nil {
+		return nil, err
+	}
+	if dy.Sign() <= 0 {
+		return nil, fmt.Errorf("%s quoted zero output", s.name)
+	}
+	return &Quote{
+		Source:  s.name,
+		Kind:    SourceKindDEX,
+		ChainID: s.chainID,
+		Price:   new(big.Rat).SetFrac(dy, pow10(s.quoteDecimals)),
+	}, nil
+}
+
+// UniswapV3Source reads the spot price of a USDC/stablecoin Uniswap v3 pool. The other
+// token is treated as worth one dollar.
+type UniswapV3Source struct {
+	chains        *chain.Manager
+	chainID       uint64
+	name          string
+	pool          common.Address
+	usdcIsToken0  bool
+	otherDecimals int
+}
+
+// NewUniswapV3Source creates a source for pool. usdcIsToken0 tells which side of the pool
+// USDC is on and otherDecimals the decimals of the other token.
+func NewUniswapV3Source(chains *chain.Manager, chainID uint64, name string, pool common.Address, usdcIsToken0 bool, otherDecimals int) *UniswapV3Source {
+	return &UniswapV3Source{
+		chains:        chains,
+		chainID:       chainID,
+		name:          name,
+		pool:          pool,
+		usdcIsToken0:  usdcIsToken0,
+		otherDecimals: otherDecimals,
+	}
+}
+
+func (s *UniswapV3Source) Name() string {
+	return s.name
+}
+
+func (s *UniswapV3Source) Kind() SourceKind {
+	return SourceKindDEX
+}
+
+func (s *UniswapV3Source) ChainID() uint64 {
+	return s.chainID
+}
+
+func (s *UniswapV3Source) Quote(ctx context.Context) (*Quote, error) {
+	client, err := s.chains.Client(s.chainID)
+	if err != nil {
+		return nil, err
+	}
+	slot0, err := chain.CallView(ctx, client, s.pool, uniswapV3PoolABI, "slot0")
+	if err != nil {
+		return nil, err
+	}
+	sqrtPriceX96 := slot0[0].(*big.Int)
+	if sqrtPriceX96.Sign() <= 0 {
+		return nil, fmt.Errorf("%s is not initialized", s.name)
+	}
+
+	// raw price of token0 in token1 base units = sqrtPriceX96^2 / 2^192
+	raw := new(big.Rat).SetFrac(
+		new(big.Int).Mul(sqrtPriceX96, sqrtPriceX96),
+		new(big.Int).Lsh(big.NewInt(1), 192),
+	)
+
+	var price *big.Rat
+	if s.usdcIsToken0 {
+		// other per USDC, adjusted from base units
+		price = raw.Mul(raw, new(big.Rat).SetFrac(pow10(usdcDecimals), pow10(s.otherDecimals)))
+	} else {
+		// USDC per other, inverted into other per USDC
+		adjusted := raw.Mul(raw, new(big.Rat).SetFrac(pow10(s.otherDecimals), pow10(usdcDecimals)))
+		price = adjusted.Inv(adjusted)
+	}
+
+	return &Quote{
+		Source:  s.name,
+		Kind:    SourceKindDEX,
+		ChainID: s.chainID,
+		Price:   price,
+	}, nil
+}
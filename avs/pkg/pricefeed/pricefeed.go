@@ -0,0 +1,59 @@
+// Package pricefeed reads the USD price of USDC from on-chain sources: oracles such as
+// Chainlink and stable pools on DEXes. Prices are exact rationals so every operator
+// derives identical values from the same chain state.
+package pricefeed
+
+import (
+	"context"
+	"math/big"
+	"sort"
+)
+
+// SourceKind classifies a price source
+type SourceKind string
+
+const (
+	// SourceKindOracle is a push oracle reporting a USD price
+	SourceKindOracle SourceKind = "oracle"
+
+	// SourceKindDEX is a pool quoting USDC against another stablecoin, used as a USD proxy
+	SourceKindDEX SourceKind = "dex"
+)
+
+// Quote is a single price observation
+type Quote struct {
+	Source  string
+	Kind    SourceKind
+	ChainID uint64
+	Price   *big.Rat
+	// UpdatedAt is the unix time the source last updated, 0 if the source is always current
+	UpdatedAt uint64
+	// Stale is set when the source has not updated within its heartbeat
+	Stale bool
+}
+
+// Source reads the price of USDC from one venue on one chain
+type Source interface {
+	Name() string
+	Kind() SourceKind
+	ChainID() uint64
+	Quote(ctx context.Context) (*Quote, error)
+}
+
+// Median returns the median of prices, averaging the two middle values for even counts.
+// It returns nil for an empty slice.
+func Median(prices []*big.Rat) *big.Rat {
+	if len(prices) == 0 {
+		return nil
+	}
+	sorted := make([]*big.Rat, len(prices))
+	copy(sorted, prices)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Cmp(sorted[j]) < 0 })
+
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return new(big.Rat).Set(sorted[mid])
+	}
+	sum := new(big.Rat).Add(sorted[mid-1], sorted[mid])
+	return sum.Quo(sum, big.NewRat(2, 1))
+}
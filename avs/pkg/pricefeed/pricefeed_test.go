@@ -0,0 +1,87 @@
+package pricefeed
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/chain/chaintest"
+)
+
+func Test_Median(t *testing.T) {
+	if Median(nil) != nil {
+		t.Errorf("Expected nil median of no prices")
+	}
+	odd := Median([]*big.Rat{big.NewRat(3, 1), big.NewRat(1, 1), big.NewRat(2, 1)})
+	if odd.Cmp(big.NewRat(2, 1)) != 0 {
+		t.Errorf("Expected 2, got %s", odd)
+	}
+	even := Median([]*big.Rat{big.NewRat(4, 1), big.NewRat(1, 1), big.NewRat(2, 1), big.NewRat(3, 1)})
+	if even.Cmp(big.NewRat(5, 2)) != 0 {
+		t.Errorf("Expected 5/2, got %s", even)
+	}
+}
+
+func Test_ChainlinkSource(t *testing.T) {
+	feed := common.HexToAddress("0x20")
+	contracts := chaintest.NewContracts(1)
+	contracts.SetHead(100, 1_700_000_000)
+	contracts.Stub(t, feed, chainlinkAggregatorABI, "decimals", uint8(8))
+	contracts.Stub(t, feed, chainlinkAggregatorABI, "latestRoundData",
+		big.NewInt(1), big.NewInt(99_980_000), big.NewInt(0), big.NewInt(1_700_000_000-3600), big.NewInt(1))
+
+	chains := chain.NewManager()
+	chains.Register(1, "ethereum", contracts)
+
+	q, err := NewChainlinkSource(chains, 1, feed, 2*time.Hour).Quote(context.Background())
+	if err != nil {
+		t.Fatalf("Quote failed: %v", err)
+	}
+	if q.Price.Cmp(big.NewRat(9998, 10000)) != 0 || q.Stale {
+		t.Errorf("Unexpected quote: price %s stale %v", q.Price.FloatString(6), q.Stale)
+	}
+
+	q, err = NewChainlinkSource(chains, 1, feed, 30*time.Minute).Quote(context.Background())
+	if err != nil {
+		t.Fatalf("Quote failed: %v", err)
+	}
+	if !q.Stale {
+		t.Errorf("Expected an answer older than the heartbeat to be stale")
+	}
+}
+
+func Test_DEXSources(t *testing.T) {
+	curvePool := common.HexToAddress("0x30")
+	uniPool := common.HexToAddress("0x31")
+
+	contracts := chaintest.NewContracts(1)
+	contracts.Stub(t, curvePool, curvePoolABI, "get_dy", big.NewInt(999_500))
+	// sqrtPriceX96 for a 1:1 price between two 6 decimal tokens is 2^96
+	contracts.Stub(t, uniPool, uniswapV3PoolABI, "slot0",
+		new(big.Int).Lsh(big.NewInt(1), 96), big.NewInt(0), uint16(0), uint16(0), uint16(0), uint8(0), true)
+
+	chains := chain.NewManager()
+	chains.Register(1, "ethereum", contracts)
+	ctx := context.Background()
+
+	q, err := NewCurveSource(chains, 1, "curve", curvePool, 1, 2, 6).Quote(ctx)
+	if err != nil {
+		t.Fatalf("Curve quote failed: %v", err)
+	}
+	if q.Price.Cmp(big.NewRat(9995, 10000)) != 0 {
+		t.Errorf("Expected 0.9995, got %s", q.Price.FloatString(6))
+	}
+
+	for _, usdcIsToken0 := range []bool{true, false} {
+		q, err = NewUniswapV3Source(chains, 1, "uni", uniPool, usdcIsToken0, 6).Quote(ctx)
+		if err != nil {
+			t.Fatalf("Uniswap quote failed: %v", err)
+		}
+		if q.Price.Cmp(big.NewRat(1, 1)) != 0 {
+			t.Errorf("Expected parity, got %s", q.Price.FloatString(6))
+		}
+	}
+}
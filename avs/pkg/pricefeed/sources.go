@@ -0,0 +1,50 @@
+package pricefeed
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+)
+
+const usdcDecimals = 6
+
+// chainlinkUSDCHeartbeat is the heartbeat of the USDC/USD feeds plus an hour of slack
+const chainlinkUSDCHeartbeat = 25 * time.Hour
+
+// Chainlink USDC/USD aggregators
+var chainlinkUSDCFeeds = map[uint64]common.Address{
+	1:     common.HexToAddress("0x8fFfFfd4AfB6115b954Bd326cbe7B4BA576818f6"),
+	8453:  common.HexToAddress("0x7e860098F58bBFC8648a4311b374B1D669a2bc6B"),
+	42161: common.HexToAddress("0x50834F3163758fcC1Df9973b6e91f0F0F0434aD3"),
+}
+
+var (
+	// Curve 3pool: coins DAI (0), USDC (1), USDT (2)
+	curve3Pool = common.HexToAddress("0xbEbc44782C7dB0a1A60Cb6fe97d0b483032FF1C7")
+
+	// Uniswap v3 USDC/USDT 0.01% pool, USDC is token0
+	uniswapV3USDCUSDT = common.HexToAddress("0x3416cF6C708Da44DB2624D63ea0AAef7113527C6")
+)
+
+// DefaultUSDCSources returns the built-in USDC price sources on the chains configured in chains
+func DefaultUSDCSources(chains *chain.Manager) []Source {
+	configured := make(map[uint64]bool)
+	for _, id := range chains.ChainIDs() {
+		configured[id] = true
+	}
+
+	var sources []Source
+	for _, id := range chains.ChainIDs() {
+		if feed, ok := chainlinkUSDCFeeds[id]; ok {
+			sources = append(sources, NewChainlinkSource(chains, id, feed, chainlinkUSDCHeartbeat))
+		}
+	}
+	if configured[1] {
+		sources = append(sources,
+			NewCurveSource(chains, 1, "curve_3pool:1", curve3Pool, 1, 2, 6),
+			NewUniswapV3Source(chains, 1, "uniswap_v3_usdc_usdt:1", uniswapV3USDCUSDT, true, 6),
+		)
+	}
+	return sources
+}
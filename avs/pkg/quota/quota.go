@@ -0,0 +1,252 @@
+// Package quota limits how many tasks of each type the performer starts per minute and
+// runs at once, so a flood of tasks cannot exhaust the operator's RPC budget or queue up
+// rebalances faster than they can be reviewed.
+//
+// With the redis backend, the replicas of a performer count the tasks started per
+// minute together, in fixed one minute windows, and fall back to counting alone while
+// Redis is unavailable. Concurrency limits are always counted by each replica.
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/najnomics/crosscow-avs/pkg/redis"
+	goredis "github.com/redis/go-redis/v9"
+	"golang.org/x/time/rate"
+)
+
+// BackendRedis counts the tasks started per minute in the Redis shared by the replicas
+// of a performer
+const BackendRedis = "redis"
+
+// ErrQuotaExceeded is returned for tasks over the rate or concurrency limit of their type
+var ErrQuotaExceeded = errors.New("task quota exceeded")
+
+// Limit is the quota of one task type. Zero values leave that dimension unlimited.
+type Limit struct {
+	// PerMinute is the number of tasks started per minute, and Burst how many of them may
+	// start at once. Burst defaults to PerMinute, and is not enforced across replicas.
+	PerMinute int `yaml:"perMinute"`
+	Burst     int `yaml:"burst"`
+
+	// MaxConcurrent is the number of tasks running at the same time
+	MaxConcurrent int `yaml:"maxConcurrent"`
+}
+
+// Config configures task quotas, keyed by task type. Types without a limit are
+// unlimited.
+type Config struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Backend is memory, the default, or redis to count tasks started per minute across
+	// replicas
+	Backend string `yaml:"backend"`
+
+	Limits map[string]Limit `yaml:"limits"`
+}
+
+// Validate checks the config for values the limiter cannot run with
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	switch c.Backend {
+	case "", "memory", BackendRedis:
+	default:
+		return fmt.Errorf("unknown backend: %s", c.Backend)
+	}
+	for taskType, limit := range c.Limits {
+		if limit.PerMinute < 0 || limit.Burst < 0 || limit.MaxConcurrent < 0 {
+			return fmt.Errorf("limits.%s: values must not be negative", taskType)
+		}
+		if limit.Burst > 0 && limit.PerMinute == 0 {
+			return fmt.Errorf("limits.%s: burst requires perMinute", taskType)
+		}
+	}
+	return nil
+}
+
+type limiter struct {
+	limit Limit
+	rate  *rate.Limiter
+	slots chan struct{}
+}
+
+// Limiter enforces the quotas of every task type. A nil Limiter admits every task.
+type Limiter struct {
+	mu     sync.RWMutex
+	limits map[string]*limiter
+
+	// shared counts the tasks started per minute across replicas when it is available
+	shared *redis.Client
+
+	// now is replaced in tests
+	now func() time.Time
+}
+
+// New creates a limiter enforcing the limits in cfg
+func New(cfg Config) *Limiter {
+	l := &Limiter{now: time.Now}
+	l.SetLimits(cfg.Limits)
+	return l
+}
+
+// SetLimits replaces the limits of every task type. Types whose limit did not change keep
+// their quota. Tasks running under a changed limit are not counted against the new one.
+func (l *Limiter) SetLimits(limits map[string]Limit) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entries := make(map[string]*limiter, len(limits))
+	for taskType, limit := range limits {
+		if existing, ok := l.limits[taskType]; ok && existing.limit == limit {
+			entries[taskType] = existing
+			continue
+		}
+		entries[taskType] = newLimiter(limit)
+	}
+	l.limits = entries
+}
+
+func newLimiter(limit Limit) *limiter {
+	entry := &limiter{limit: limit}
+	if limit.PerMinute > 0 {
+		burst := limit.Burst
+		if burst == 0 {
+			burst = limit.PerMinute
+		}
+		entry.rate = rate.NewLimiter(rate.Every(time.Minute/time.Duration(limit.PerMinute)), burst)
+	}
+	if limit.MaxConcurrent > 0 {
+		entry.slots = make(chan struct{}, limit.MaxConcurrent)
+	}
+	return entry
+}
+
+// entry returns the limiter of taskType, if it has a limit
+func (l *Limiter) entry(taskType string) (*limiter, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	entry, ok := l.limits[taskType]
+	return entry, ok
+}
+
+// NewShared creates a limiter counting the tasks started per minute in shared, and
+// alone while shared is unavailable
+func NewShared(cfg Config, shared *redis.Client) *Limiter {
+	l := New(cfg)
+	l.shared = shared
+	return l
+}
+
+// NewFromConfig creates the limiter enabled in cfg, nil when it is disabled. The redis
+// backend counts in shared, and is ignored when shared is nil.
+func NewFromConfig(cfg Config, shared *redis.Client) *Limiter {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.Backend == BackendRedis {
+		return NewShared(cfg, shared)
+	}
+	return New(cfg)
+}
+
+// Check reports whether a task of taskType would be admitted now, without using its
+// quota. Tasks are checked when validated and admitted when handled.
+func (l *Limiter) Check(taskType string) error {
+	if l == nil {
+		return nil
+	}
+	entry, ok := l.entry(taskType)
+	if !ok {
+		return nil
+	}
+	if entry.rate != nil && !l.available(taskType, entry, false) {
+		return fmt.Errorf("%w: more than %s %s tasks per minute", ErrQuotaExceeded, perMinute(entry.rate), taskType)
+	}
+	if entry.slots != nil && len(entry.slots) == cap(entry.slots) {
+		return fmt.Errorf("%w: %d %s tasks are running already", ErrQuotaExceeded, cap(entry.slots), taskType)
+	}
+	return nil
+}
+
+// Admit uses the quota of a task of taskType, and returns the function that ends it once
+// the task finished. Tasks over the quota are rejected rather than queued, so their
+// submitter can retry later.
+func (l *Limiter) Admit(taskType string) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	entry, ok := l.entry(taskType)
+	if !ok {
+		return func() {}, nil
+	}
+
+	release := func() {}
+	if entry.slots != nil {
+		select {
+		case entry.slots <- struct{}{}:
+			release = func() { <-entry.slots }
+		default:
+			return nil, fmt.Errorf("%w: %d %s tasks are running already", ErrQuotaExceeded, cap(entry.slots), taskType)
+		}
+	}
+	if entry.rate != nil && !l.available(taskType, entry, true) {
+		release()
+		return nil, fmt.Errorf("%w: more than %s %s tasks per minute", ErrQuotaExceeded, perMinute(entry.rate), taskType)
+	}
+	return release, nil
+}
+
+// available reports whether a task of taskType may start under its rate limit, and
+// counts it as started when take is set. Tasks are counted in the shared window of the
+// current minute while Redis is available, and by the local rate limiter otherwise.
+func (l *Limiter) available(taskType string, entry *limiter, take bool) bool {
+	now := l.now()
+	if !l.shared.Available() {
+		return l.availableLocally(entry, now, take)
+	}
+	key := l.shared.Key("quota", taskType, strconv.FormatInt(now.Unix()/60, 10))
+	var started int64
+	err := l.shared.Do(context.Background(), func(ctx context.Context, rdb goredis.Cmdable) error {
+		if !take {
+			n, err := rdb.Get(ctx, key).Int64()
+			if errors.Is(err, redis.Nil) {
+				return nil
+			}
+			started = n + 1
+			return err
+		}
+		pipe := rdb.TxPipeline()
+		incr := pipe.Incr(ctx, key)
+		pipe.Expire(ctx, key, 2*time.Minute)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return err
+		}
+		started = incr.Val()
+		return nil
+	})
+	if err != nil {
+		return l.availableLocally(entry, now, take)
+	}
+	return started <= int64(entry.limit.PerMinute)
+}
+
+// availableLocally is available counting with the rate limiter of the replica
+func (l *Limiter) availableLocally(entry *limiter, now time.Time, take bool) bool {
+	if take {
+		return entry.rate.AllowN(now, 1)
+	}
+	return entry.rate.TokensAt(now) >= 1
+}
+
+func perMinute(r *rate.Limiter) string {
+	return fmt.Sprintf("%g", float64(r.Limit())*60)
+}
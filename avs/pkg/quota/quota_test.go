@@ -0,0 +1,140 @@
+package quota
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/najnomics/crosscow-avs/pkg/redis"
+)
+
+func Test_RateLimit(t *testing.T) {
+	l := New(Config{Enabled: true, Limits: map[string]Limit{"rebalance_execution": {PerMinute: 2}}})
+	now := time.Unix(1_800_000_000, 0)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		release, err := l.Admit("rebalance_execution")
+		if err != nil {
+			t.Fatalf("Expected task %d to be admitted: %v", i, err)
+		}
+		release()
+	}
+	if err := l.Check("rebalance_execution"); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Expected the exhausted quota to be reported, got %v", err)
+	}
+	if _, err := l.Admit("rebalance_execution"); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Expected the third task to be rejected, got %v", err)
+	}
+	if _, err := l.Admit("yield_monitoring"); err != nil {
+		t.Errorf("Expected types without a limit to be admitted: %v", err)
+	}
+
+	now = now.Add(30 * time.Second)
+	if _, err := l.Admit("rebalance_execution"); err != nil {
+		t.Errorf("Expected the quota to refill: %v", err)
+	}
+}
+
+func Test_ConcurrencyLimit(t *testing.T) {
+	l := New(Config{Enabled: true, Limits: map[string]Limit{"yield_monitoring": {MaxConcurrent: 1}}})
+
+	release, err := l.Admit("yield_monitoring")
+	if err != nil {
+		t.Fatalf("Admit failed: %v", err)
+	}
+	if _, err := l.Admit("yield_monitoring"); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Expected a second running task to be rejected, got %v", err)
+	}
+	release()
+	if err := l.Check("yield_monitoring"); err != nil {
+		t.Errorf("Expected the slot to be free again: %v", err)
+	}
+
+	var disabled *Limiter
+	if _, err := disabled.Admit("yield_monitoring"); err != nil {
+		t.Errorf("Expected a nil limiter to admit every task: %v", err)
+	}
+}
+
+func Test_SetLimits(t *testing.T) {
+	l := New(Config{Enabled: true, Limits: map[string]Limit{
+		"yield_monitoring":    {MaxConcurrent: 1},
+		"rebalance_execution": {MaxConcurrent: 1},
+	}})
+	if _, err := l.Admit("yield_monitoring"); err != nil {
+		t.Fatalf("Admit failed: %v", err)
+	}
+	if _, err := l.Admit("rebalance_execution"); err != nil {
+		t.Fatalf("Admit failed: %v", err)
+	}
+
+	l.SetLimits(map[string]Limit{
+		"yield_monitoring":    {MaxConcurrent: 1},
+		"rebalance_execution": {MaxConcurrent: 2},
+	})
+	if _, err := l.Admit("yield_monitoring"); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Expected an unchanged limit to keep counting running tasks, got %v", err)
+	}
+	if _, err := l.Admit("rebalance_execution"); err != nil {
+		t.Errorf("Expected the raised limit to admit the task: %v", err)
+	}
+
+	l.SetLimits(nil)
+	if _, err := l.Admit("yield_monitoring"); err != nil {
+		t.Errorf("Expected removed limits to admit every task: %v", err)
+	}
+}
+
+func Test_ConfigValidate(t *testing.T) {
+	if err := (Config{Enabled: true, Limits: map[string]Limit{"batch": {Burst: 2}}}).Validate(); err == nil {
+		t.Errorf("Expected a burst without a rate to be rejected")
+	}
+	if err := (Config{Enabled: true, Limits: map[string]Limit{"batch": {MaxConcurrent: -1}}}).Validate(); err == nil {
+		t.Errorf("Expected negative limits to be rejected")
+	}
+}
+
+func Test_SharedRateLimit(t *testing.T) {
+	server := miniredis.RunT(t)
+	redisCfg := redis.DefaultConfig()
+	redisCfg.Enabled, redisCfg.Addr = true, server.Addr()
+	cfg := Config{Enabled: true, Backend: BackendRedis, Limits: map[string]Limit{"rebalance_execution": {PerMinute: 2}}}
+	now := time.Unix(1_800_000_000, 0)
+	newReplica := func() *Limiter {
+		client, err := redis.New(redisCfg, nil, nil)
+		if err != nil {
+			t.Fatalf("redis.New failed: %v", err)
+		}
+		t.Cleanup(func() { client.Close() })
+		l := NewFromConfig(cfg, client)
+		l.now = func() time.Time { return now }
+		return l
+	}
+	a, b := newReplica(), newReplica()
+
+	if _, err := a.Admit("rebalance_execution"); err != nil {
+		t.Fatalf("Admit failed: %v", err)
+	}
+	if _, err := b.Admit("rebalance_execution"); err != nil {
+		t.Fatalf("Admit failed: %v", err)
+	}
+	if err := a.Check("rebalance_execution"); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Expected the tasks of both replicas to be counted, got %v", err)
+	}
+	if _, err := b.Admit("rebalance_execution"); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Expected the third task across replicas to be rejected, got %v", err)
+	}
+
+	now = now.Add(time.Minute)
+	if _, err := a.Admit("rebalance_execution"); err != nil {
+		t.Errorf("Expected the next window to admit tasks: %v", err)
+	}
+
+	// while Redis is down, each replica counts alone
+	server.Close()
+	if _, err := b.Admit("rebalance_execution"); err != nil {
+		t.Errorf("Expected the replica to fall back to its own quota: %v", err)
+	}
+}
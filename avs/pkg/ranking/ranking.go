@@ -0,0 +1,163 @@
+// Package ranking orders lending venues by risk-adjusted return. Each venue is scored from
+// 0 to 1 on its current rate, its Sharpe-style ratio of excess mean rate to volatility,
+// how stable its rate has been and how deep its rate fell from a peak. The overall score
+// weighs the dimensions that could be measured, so venues without enough rate history
+// are ranked on their current rate alone.
+package ranking
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/najnomics/crosscow-avs/pkg/stability"
+)
+
+// Dimension names an aspect venues are scored on
+type Dimension string
+
+const (
+	// DimensionRate scores the current supply rate against the best venue's
+	DimensionRate Dimension = "rate"
+	// DimensionSharpe scores the excess mean rate per unit of volatility against the
+	// best venue's
+	DimensionSharpe Dimension = "sharpe"
+	// DimensionStability scores how little the rate varied around its mean
+	DimensionStability Dimension = "stability"
+	// DimensionDrawdown scores how little the rate fell from its peaks
+	DimensionDrawdown Dimension = "drawdown"
+)
+
+// Dimensions in the order they are reported
+var Dimensions = []Dimension{DimensionRate, DimensionSharpe, DimensionStability, DimensionDrawdown}
+
+// MinVolatility floors the volatility Sharpe ratios divide by, so flat rates do not rank
+// infinitely well. It is 1 basis point of an annual rate.
+const MinVolatility = 0.0001
+
+// DefaultWeights favour return, then its ratio to volatility
+func DefaultWeights() map[Dimension]float64 {
+	return map[Dimension]float64{
+		DimensionRate:      40,
+		DimensionSharpe:    30,
+		DimensionStability: 15,
+		DimensionDrawdown:  15,
+	}
+}
+
+// ValidateWeights checks that weights name known dimensions, are not negative, and that
+// at least one is positive
+func ValidateWeights(weights map[Dimension]float64) error {
+	var total float64
+	for dimension, weight := range weights {
+		if !known(dimension) {
+			return fmt.Errorf("unknown dimension %q, must be one of %v", dimension, Dimensions)
+		}
+		if weight < 0 || math.IsNaN(weight) || math.IsInf(weight, 0) {
+			return fmt.Errorf("weight of %s must be a non-negative number", dimension)
+		}
+		total += weight
+	}
+	if total <= 0 {
+		return fmt.Errorf("at least one weight must be positive")
+	}
+	return nil
+}
+
+func known(dimension Dimension) bool {
+	for _, d := range Dimensions {
+		if d == dimension {
+			return true
+		}
+	}
+	return false
+}
+
+// Venue is a market to rank. Rates are annual fractions (0.05 is 5%).
+type Venue struct {
+	Protocol string
+	ChainID  uint64
+	Rate     float64
+
+	// Stats describe the rate history of the venue, nil when it is too short
+	Stats *stability.Stats
+}
+
+// Ranked is a venue with its scores
+type Ranked struct {
+	Venue
+
+	// Sharpe is the excess mean rate per unit of volatility, nil without Stats
+	Sharpe *float64
+
+	// Scores holds the score of each measured dimension
+	Scores map[Dimension]float64
+	Score  float64
+}
+
+// Rank scores venues under weights, DefaultWeights when nil, and orders them best first.
+// Ties are broken by rate, then by protocol and chain. Mean rates are measured in excess
+// of riskFree.
+func Rank(venues []Venue, weights map[Dimension]float64, riskFree float64) []Ranked {
+	if weights == nil {
+		weights = DefaultWeights()
+	}
+
+	var bestRate, bestSharpe float64
+	ranked := make([]Ranked, len(venues))
+	for i, v := range venues {
+		ranked[i] = Ranked{Venue: v, Scores: make(map[Dimension]float64)}
+		bestRate = math.Max(bestRate, v.Rate)
+		if v.Stats != nil {
+			sharpe := (v.Stats.Mean - riskFree) / math.Max(v.Stats.Volatility, MinVolatility)
+			ranked[i].Sharpe = &sharpe
+			bestSharpe = math.Max(bestSharpe, sharpe)
+		}
+	}
+
+	for i := range ranked {
+		r := &ranked[i]
+		r.Scores[DimensionRate] = ratio(r.Rate, bestRate)
+		if r.Stats != nil {
+			r.Scores[DimensionSharpe] = ratio(*r.Sharpe, bestSharpe)
+			r.Scores[DimensionStability] = r.Stats.Score
+			r.Scores[DimensionDrawdown] = 1 - r.Stats.MaxDrawdown
+		}
+
+		var sum, total float64
+		for _, dimension := range Dimensions {
+			score, measured := r.Scores[dimension]
+			if !measured || weights[dimension] == 0 {
+				continue
+			}
+			sum += score * weights[dimension]
+			total += weights[dimension]
+		}
+		if total > 0 {
+			r.Score = sum / total
+		}
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Rate != b.Rate {
+			return a.Rate > b.Rate
+		}
+		if a.Protocol != b.Protocol {
+			return a.Protocol < b.Protocol
+		}
+		return a.ChainID < b.ChainID
+	})
+	return ranked
+}
+
+// ratio scores value against best, 0 for values that are not positive
+func ratio(value, best float64) float64 {
+	if value <= 0 || best <= 0 {
+		return 0
+	}
+	return math.Min(value/best, 1)
+}
@@ -0,0 +1,62 @@
+package ranking
+
+import (
+	"math"
+	"testing"
+
+	"github.com/najnomics/crosscow-avs/pkg/stability"
+)
+
+func Test_RankPrefersStableVenues(t *testing.T) {
+	venues := []Venue{
+		// spiking: the best rate right now, but it swung wildly
+		{Protocol: "spiky", ChainID: 1, Rate: 0.08, Stats: &stability.Stats{Mean: 0.05, Volatility: 0.02, MaxDrawdown: 0.6, Score: 0.6}},
+		{Protocol: "steady", ChainID: 1, Rate: 0.06, Stats: &stability.Stats{Mean: 0.06, Volatility: 0.001, MaxDrawdown: 0.05, Score: 0.98}},
+		{Protocol: "new", ChainID: 8453, Rate: 0.04},
+	}
+
+	ranked := Rank(venues, nil, 0)
+	if ranked[0].Protocol != "steady" || ranked[1].Protocol != "spiky" || ranked[2].Protocol != "new" {
+		t.Fatalf("Expected the steady venue first, got %s, %s, %s", ranked[0].Protocol, ranked[1].Protocol, ranked[2].Protocol)
+	}
+	steady := ranked[0]
+	if steady.Scores[DimensionSharpe] != 1 || math.Abs(steady.Scores[DimensionRate]-0.75) > 1e-12 || *steady.Sharpe != 60 {
+		t.Errorf("Unexpected scores %+v, sharpe %v", steady.Scores, *steady.Sharpe)
+	}
+	fresh := ranked[2]
+	if fresh.Sharpe != nil || len(fresh.Scores) != 1 || fresh.Score != 0.5 {
+		t.Errorf("Expected a venue without history to be scored on its rate alone, got %+v", fresh)
+	}
+
+	// ranking on the current rate alone puts the spike first
+	ranked = Rank(venues, map[Dimension]float64{DimensionRate: 1}, 0)
+	if ranked[0].Protocol != "spiky" || ranked[0].Score != 1 {
+		t.Errorf("Expected the highest rate first, got %+v", ranked[0])
+	}
+}
+
+func Test_RankRiskFreeRateAndTies(t *testing.T) {
+	venues := []Venue{
+		{Protocol: "b", ChainID: 1, Rate: 0.03, Stats: &stability.Stats{Mean: 0.03, Volatility: 0, Score: 1}},
+		{Protocol: "a", ChainID: 1, Rate: 0.03, Stats: &stability.Stats{Mean: 0.03, Volatility: 0, Score: 1}},
+	}
+	ranked := Rank(venues, map[Dimension]float64{DimensionSharpe: 1}, 0.04)
+	if ranked[0].Protocol != "a" || ranked[0].Score != 0 || *ranked[0].Sharpe >= 0 {
+		t.Errorf("Expected venues below the risk free rate to score 0 and tie by name, got %+v", ranked[0])
+	}
+}
+
+func Test_ValidateWeights(t *testing.T) {
+	if err := ValidateWeights(DefaultWeights()); err != nil {
+		t.Errorf("Expected the default weights to be valid, got %v", err)
+	}
+	for name, weights := range map[string]map[Dimension]float64{
+		"unknown":  {"tvl": 1},
+		"negative": {DimensionRate: -1, DimensionSharpe: 2},
+		"zero":     {DimensionRate: 0},
+	} {
+		if err := ValidateWeights(weights); err == nil {
+			t.Errorf("%s: expected the weights to be rejected", name)
+		}
+	}
+}
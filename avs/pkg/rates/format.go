@@ -0,0 +1,130 @@
+package rates
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+)
+
+// Percent returns a rate as a percentage rounded to canonical.APYPlaces, as results
+// report it
+func Percent(rate float64) canonical.Decimal {
+	return canonical.APY(rate * 100)
+}
+
+// Locale is how a language writes decimals for people. Results never use it: their
+// decimals are canonical so every operator encodes them the same way.
+type Locale struct {
+	// Decimal separates the integer digits from the fraction
+	Decimal string
+
+	// Group separates groups of three integer digits. Empty for no grouping.
+	Group string
+
+	// Percent follows percentages, with the space the language puts before it
+	Percent string
+}
+
+var (
+	// English writes 1,234.5678%
+	English = Locale{Decimal: ".", Group: ",", Percent: "%"}
+
+	// German writes 1.234,5678 %, with a no-break space
+	German = Locale{Decimal: ",", Group: ".", Percent: "\u00a0%"}
+
+	// French writes 1 234,5678 %, with narrow no-break spaces
+	French = Locale{Decimal: ",", Group: "\u202f", Percent: "\u202f%"}
+
+	// SwissGerman writes 1’234.5678%
+	SwissGerman = Locale{Decimal: ".", Group: "\u2019", Percent: "%"}
+)
+
+// locales are the locales by language tag, lower case
+var locales = map[string]Locale{
+	"en":    English,
+	"de":    German,
+	"de-ch": SwissGerman,
+	"fr":    French,
+}
+
+// LocaleOf returns the locale of a language tag, such as de-CH, or of its language when
+// the tag has no locale of its own, such as de for de-AT
+func LocaleOf(tag string) (Locale, error) {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+	if locale, ok := locales[normalized]; ok {
+		return locale, nil
+	}
+	language, _, _ := strings.Cut(normalized, "-")
+	if locale, ok := locales[language]; ok {
+		return locale, nil
+	}
+	known := make([]string, 0, len(locales))
+	for language := range locales {
+		known = append(known, language)
+	}
+	sort.Strings(known)
+	return Locale{}, fmt.Errorf("unknown locale %q, must be one of %s", tag, strings.Join(known, ", "))
+}
+
+// Format writes d as l does, keeping every fractional digit of d
+func (l Locale) Format(d canonical.Decimal) string {
+	s := d.String()
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	integer, fraction, hasFraction := strings.Cut(s, ".")
+	if l.Group != "" && len(integer) > 3 {
+		var grouped strings.Builder
+		head := len(integer) % 3
+		if head > 0 {
+			grouped.WriteString(integer[:head])
+		}
+		for i := head; i < len(integer); i += 3 {
+			if grouped.Len() > 0 {
+				grouped.WriteString(l.Group)
+			}
+			grouped.WriteString(integer[i : i+3])
+		}
+		integer = grouped.String()
+	}
+	if hasFraction {
+		return sign + integer + l.Decimal + fraction
+	}
+	return sign + integer
+}
+
+// FormatPercent writes a percentage, such as an APY of a result, as l does
+func (l Locale) FormatPercent(percent canonical.Decimal) string {
+	return l.Format(percent) + l.Percent
+}
+
+// FormatRate writes a rate as a percentage rounded to canonical.APYPlaces, as l does
+func (l Locale) FormatRate(rate float64) string {
+	return l.FormatPercent(Percent(rate))
+}
+
+// Parse reads a decimal written as l does, group separators optional, keeping its
+// precision. A trailing percent sign is dropped.
+func (l Locale) Parse(s string) (canonical.Decimal, error) {
+	normalized := strings.TrimSpace(s)
+	if l.Percent != "" {
+		normalized = strings.TrimSpace(strings.TrimSuffix(normalized, strings.TrimSpace(l.Percent)))
+	}
+	if l.Group != "" {
+		normalized = strings.ReplaceAll(normalized, l.Group, "")
+	}
+	if l.Decimal != "." {
+		if strings.Contains(normalized, ".") {
+			return canonical.Decimal{}, fmt.Errorf("invalid decimal %q", s)
+		}
+		normalized = strings.ReplaceAll(normalized, l.Decimal, ".")
+	}
+	d, err := canonical.ParseDecimal(normalized)
+	if err != nil {
+		return canonical.Decimal{}, fmt.Errorf("invalid decimal %q", s)
+	}
+	return d, nil
+}
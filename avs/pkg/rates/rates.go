@@ -0,0 +1,139 @@
+// Package rates converts the rates protocols expose into the annual rates results report,
+// with the compounding convention of each conversion explicit. Aave reports annual rates
+// in ray, Comet and Compound v2 forks per-second or per-block rates in wad, and savings
+// rates such as the Sky Savings Rate per-second compounding factors in ray. Adapters
+// converting through this package agree on the conventions, so operators reading the
+// same market report the same rate.
+//
+// Rates are fractions: 0.05 is 5%. Percent and the Format functions turn them into the
+// percentages results and people read.
+package rates
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+)
+
+const (
+	// SecondsPerYear is the length of the year protocols annualize per-second rates with:
+	// 365 days, leap days ignored
+	SecondsPerYear = 365 * 24 * 60 * 60
+
+	// EthereumBlocksPerYear is the number of 12 second slots in a year, which per-block
+	// rates of Ethereum markets annualize with
+	EthereumBlocksPerYear = SecondsPerYear / 12
+
+	// RayDecimals is the precision of ray values, such as Aave rates
+	RayDecimals = 27
+
+	// WadDecimals is the precision of wad values, such as Comet and cToken rates
+	WadDecimals = 18
+)
+
+// Compounding is how the interest of a period earns interest in later periods
+type Compounding string
+
+const (
+	// Simple interest earns nothing on interest, so an annual rate is the sum of its
+	// periods: an APR
+	Simple Compounding = "simple"
+
+	// Periodic interest is added to the principal at the end of every period: an APY
+	Periodic Compounding = "periodic"
+
+	// Continuous interest is added to the principal as it accrues, the limit of periodic
+	// compounding over ever shorter periods
+	Continuous Compounding = "continuous"
+)
+
+// Validate checks that c is a known convention
+func (c Compounding) Validate() error {
+	switch c {
+	case Simple, Periodic, Continuous:
+		return nil
+	}
+	return fmt.Errorf("unknown compounding %q", c)
+}
+
+// Scaled returns value / 10^decimals as a float64
+func Scaled(value *big.Int, decimals int) float64 {
+	if value == nil {
+		return 0
+	}
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	f, _ := new(big.Rat).SetFrac(value, scale).Float64()
+	return f
+}
+
+// Ray returns a ray value as a float64
+func Ray(value *big.Int) float64 {
+	return Scaled(value, RayDecimals)
+}
+
+// Wad returns a wad value as a float64
+func Wad(value *big.Int) float64 {
+	return Scaled(value, WadDecimals)
+}
+
+// RayFactor returns the per-period rate of a per-period compounding factor in ray, such
+// as the Sky Savings Rate: 1e27 earns nothing
+func RayFactor(factor *big.Int) float64 {
+	if factor == nil {
+		return 0
+	}
+	one := new(big.Int).Exp(big.NewInt(10), big.NewInt(RayDecimals), nil)
+	return Ray(new(big.Int).Sub(factor, one))
+}
+
+// Annualize returns the annual rate of a per-period rate over periodsPerYear periods,
+// compounded as c. Simple rates add up, periodic rates compound every period and
+// continuous rates compound as they accrue.
+func Annualize(perPeriod, periodsPerYear float64, c Compounding) float64 {
+	switch c {
+	case Periodic:
+		// (1 + r)^n - 1, without losing the digits of small rates to the 1
+		return math.Expm1(periodsPerYear * math.Log1p(perPeriod))
+	case Continuous:
+		return math.Expm1(perPeriod * periodsPerYear)
+	default:
+		return perPeriod * periodsPerYear
+	}
+}
+
+// Deannualize returns the per-period rate Annualize annualizes to annual
+func Deannualize(annual, periodsPerYear float64, c Compounding) float64 {
+	if periodsPerYear == 0 {
+		return 0
+	}
+	switch c {
+	case Periodic:
+		return math.Expm1(math.Log1p(annual) / periodsPerYear)
+	case Continuous:
+		return math.Log1p(annual) / periodsPerYear
+	default:
+		return annual / periodsPerYear
+	}
+}
+
+// APRToAPY returns the yield of an APR accruing over periodsPerYear periods, each
+// compounding into the next. Aave supply rates compound every second.
+func APRToAPY(apr, periodsPerYear float64) float64 {
+	return Annualize(apr/periodsPerYear, periodsPerYear, Periodic)
+}
+
+// APYToAPR returns the APR whose periods, compounding, yield apy
+func APYToAPR(apy, periodsPerYear float64) float64 {
+	return Deannualize(apy, periodsPerYear, Periodic) * periodsPerYear
+}
+
+// ContinuousToAPY returns the yield of a continuously compounded annual rate, such as the
+// log return of a share price
+func ContinuousToAPY(rate float64) float64 {
+	return math.Expm1(rate)
+}
+
+// APYToContinuous returns the continuously compounded annual rate yielding apy
+func APYToContinuous(apy float64) float64 {
+	return math.Log1p(apy)
+}
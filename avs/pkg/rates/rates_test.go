@@ -0,0 +1,199 @@
+package rates
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+)
+
+func near(a, b, tolerance float64) bool {
+	return math.Abs(a-b) <= tolerance
+}
+
+func bigInt(t *testing.T, s string) *big.Int {
+	t.Helper()
+	v, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		t.Fatalf("Invalid integer %s", s)
+	}
+	return v
+}
+
+func Test_ScalesFixedPointValues(t *testing.T) {
+	testCases := map[string]struct {
+		got, want float64
+	}{
+		"ray":          {Ray(bigInt(t, "45000000000000000000000000")), 0.045},
+		"wad":          {Wad(bigInt(t, "1585489599")), 1.585489599e-9},
+		"usdc":         {Scaled(big.NewInt(1_500_000), 6), 1.5},
+		"negative":     {Scaled(big.NewInt(-25), 2), -0.25},
+		"nil":          {Ray(nil), 0},
+		"ray factor":   {RayFactor(bigInt(t, "1000000001547125957863212448")), 1.547125957863212448e-9},
+		"unit factor":  {RayFactor(bigInt(t, "1000000000000000000000000000")), 0},
+		"shrinking":    {RayFactor(bigInt(t, "999999999000000000000000000")), -1e-9},
+		"nil factor":   {RayFactor(nil), 0},
+		"zero decimal": {Scaled(big.NewInt(7), 0), 7},
+	}
+	for name, tc := range testCases {
+		if tc.got != tc.want {
+			t.Errorf("%s: expected %v, got %v", name, tc.want, tc.got)
+		}
+	}
+}
+
+func Test_AnnualizesByCompounding(t *testing.T) {
+	testCases := []struct {
+		name        string
+		perPeriod   float64
+		periods     float64
+		compounding Compounding
+		want        float64
+	}{
+		// Comet supply rate of 5% APR per second
+		{"simple per second", 0.05 / SecondsPerYear, SecondsPerYear, Simple, 0.05},
+		{"periodic per second", 0.05 / SecondsPerYear, SecondsPerYear, Periodic, 0.051271096},
+		{"continuous per second", 0.05 / SecondsPerYear, SecondsPerYear, Continuous, 0.051271096},
+		// per-block rate of a Compound v2 market on Ethereum
+		{"simple per block", 0.03 / EthereumBlocksPerYear, EthereumBlocksPerYear, Simple, 0.03},
+		{"periodic per block", 0.03 / EthereumBlocksPerYear, EthereumBlocksPerYear, Periodic, 0.030454533},
+		{"monthly", 0.01, 12, Periodic, 0.126825030},
+		{"monthly simple", 0.01, 12, Simple, 0.12},
+		{"daily continuous", 0.0001, 365, Continuous, 0.037174304},
+		{"zero", 0, SecondsPerYear, Periodic, 0},
+		{"negative", -0.01, 12, Periodic, -0.113615128},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Annualize(tc.perPeriod, tc.periods, tc.compounding)
+			if !near(got, tc.want, 1e-9) {
+				t.Errorf("Expected %.9f, got %.9f", tc.want, got)
+			}
+			if back := Deannualize(got, tc.periods, tc.compounding); !near(back, tc.perPeriod, 1e-15) {
+				t.Errorf("Expected deannualizing to return %g, got %g", tc.perPeriod, back)
+			}
+		})
+	}
+
+	if Deannualize(0.05, 0, Simple) != 0 {
+		t.Errorf("Expected no periods to have no per-period rate")
+	}
+	// digits of tiny per-second rates survive compounding
+	if got := Annualize(1e-18, SecondsPerYear, Periodic); !near(got, 3.1536e-11, 1e-20) {
+		t.Errorf("Expected a tiny rate to compound without losing precision, got %g", got)
+	}
+}
+
+func Test_ConvertsBetweenConventions(t *testing.T) {
+	for _, apr := range []float64{0, 0.0001, 0.035, 0.05, 0.25, 1.5} {
+		apy := APRToAPY(apr, SecondsPerYear)
+		if apr > 0 && apy <= apr {
+			t.Errorf("%v: expected compounding to yield more than the APR, got %v", apr, apy)
+		}
+		if !near(APYToAPR(apy, SecondsPerYear), apr, 1e-12) {
+			t.Errorf("%v: expected APYToAPR to invert APRToAPY, got %v", apr, APYToAPR(apy, SecondsPerYear))
+		}
+		continuous := APYToContinuous(apy)
+		if !near(ContinuousToAPY(continuous), apy, 1e-15) {
+			t.Errorf("%v: expected ContinuousToAPY to invert APYToContinuous", apr)
+		}
+		// compounding every second is continuous compounding to 7 digits
+		if !near(continuous, apr, 1e-7) {
+			t.Errorf("%v: expected per-second compounding to match continuous compounding, got %v", apr, continuous)
+		}
+	}
+	if got := APRToAPY(0.12, 12); !near(got, 0.126825030, 1e-9) {
+		t.Errorf("Expected 12%% APR compounded monthly to yield 12.6825%%, got %v", got)
+	}
+	// an Aave liquidity rate of 4.5% in ray
+	if got := Percent(APRToAPY(Ray(bigInt(t, "45000000000000000000000000")), SecondsPerYear)); got.String() != "4.6028" {
+		t.Errorf("Expected the Aave supply APY to be 4.6028%%, got %s", got)
+	}
+	// the Sky Savings Rate of 6.5% APY as a per-second factor in ray
+	ssr := bigInt(t, "1000000001996917783620820123")
+	if got := Percent(Annualize(RayFactor(ssr), SecondsPerYear, Periodic)); got.String() != "6.5000" {
+		t.Errorf("Expected the savings rate to be 6.5000%%, got %s", got)
+	}
+}
+
+func Test_ValidatesCompounding(t *testing.T) {
+	for _, c := range []Compounding{Simple, Periodic, Continuous} {
+		if err := c.Validate(); err != nil {
+			t.Errorf("%s: expected a known compounding, got %v", c, err)
+		}
+	}
+	if err := Compounding("daily").Validate(); err == nil {
+		t.Errorf("Expected an unknown compounding to be refused")
+	}
+}
+
+func Test_FormatsForLocales(t *testing.T) {
+	d, err := canonical.ParseDecimal("-1234567.0450")
+	if err != nil {
+		t.Fatalf("ParseDecimal failed: %v", err)
+	}
+	testCases := []struct {
+		locale Locale
+		want   string
+	}{
+		{English, "-1,234,567.0450"},
+		{German, "-1.234.567,0450"},
+		{French, "-1\u202f234\u202f567,0450"},
+		{SwissGerman, "-1\u2019234\u2019567.0450"},
+		{Locale{Decimal: "."}, "-1234567.0450"},
+	}
+	for _, tc := range testCases {
+		got := tc.locale.Format(d)
+		if got != tc.want {
+			t.Errorf("Expected %q, got %q", tc.want, got)
+		}
+		parsed, err := tc.locale.Parse(got)
+		if err != nil || parsed.String() != d.String() {
+			t.Errorf("Expected %q to parse back to %s, got %s: %v", got, d, parsed, err)
+		}
+	}
+
+	for value, want := range map[string]string{"0": "0", "999": "999", "1000": "1,000", "100000.5": "100,000.5", "0.0001": "0.0001"} {
+		d, _ := canonical.ParseDecimal(value)
+		if got := English.Format(d); got != want {
+			t.Errorf("%s: expected %q, got %q", value, want, got)
+		}
+	}
+
+	if got := German.FormatRate(0.0412346); got != "4,1235\u00a0%" {
+		t.Errorf("Expected the German percentage, got %q", got)
+	}
+	if got := English.FormatRate(0.05); got != "5.0000%" {
+		t.Errorf("Expected the English percentage, got %q", got)
+	}
+	if got, err := French.Parse("4,5\u202f%"); err != nil || got.String() != "4.5" {
+		t.Errorf("Expected a French percentage to parse, got %s: %v", got, err)
+	}
+	for _, invalid := range []string{"", "abc", "1.2.3", "1e5"} {
+		if _, err := English.Parse(invalid); err == nil {
+			t.Errorf("%q: expected an invalid English decimal to be refused", invalid)
+		}
+	}
+	if _, err := French.Parse("1.5"); err == nil {
+		t.Errorf("Expected a point in a French decimal to be refused")
+	}
+}
+
+func Test_LooksUpLocales(t *testing.T) {
+	testCases := map[string]Locale{
+		"en":    English,
+		"en-US": English,
+		"de_AT": German,
+		"de-CH": SwissGerman,
+		"FR-fr": French,
+	}
+	for tag, want := range testCases {
+		if got, err := LocaleOf(tag); err != nil || got != want {
+			t.Errorf("%s: expected %+v, got %+v: %v", tag, want, got, err)
+		}
+	}
+	if _, err := LocaleOf("ja-JP"); err == nil {
+		t.Errorf("Expected an unknown locale to be refused")
+	}
+}
@@ -0,0 +1,105 @@
+// Package recovery watches the CCTP transfers of rebalances for mints that never landed.
+// A transfer whose burn is final but whose mint is still not on the target chain past an
+// SLA is stuck: its mint is submitted again with a fresh attestation when Circle attested
+// the burn, and operators are alerted when it needs manual intervention.
+package recovery
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Config configures the recovery of stuck transfers
+type Config struct {
+	// Enabled runs the watchdog recovering stuck transfers in the background.
+	// transfer_recovery tasks recover them on demand either way.
+	Enabled bool `yaml:"enabled"`
+
+	// SLA is how long after its burn a transfer may go unminted before it is stuck
+	SLA time.Duration `yaml:"sla"`
+
+	// MaxAttempts is how many times the mint of a stuck transfer is submitted again
+	// before it is escalated for manual intervention
+	MaxAttempts int `yaml:"maxAttempts"`
+
+	// PollInterval is the time between checks of the watchdog
+	PollInterval time.Duration `yaml:"pollInterval"`
+}
+
+// DefaultConfig counts transfers as stuck once their burn waited an hour, three times
+// the upper bound of standard attestations, and mints them again up to three times. The
+// watchdog is disabled; enabled, it checks every five minutes.
+func DefaultConfig() Config {
+	return Config{
+		SLA:          time.Hour,
+		MaxAttempts:  3,
+		PollInterval: 5 * time.Minute,
+	}
+}
+
+// Validate checks the config for values transfers cannot be recovered with. The SLA and
+// attempts are checked with the watchdog disabled too, since tasks recover with them.
+func (c Config) Validate() error {
+	if c.SLA <= 0 {
+		return fmt.Errorf("sla must be positive")
+	}
+	if c.MaxAttempts <= 0 {
+		return fmt.Errorf("maxAttempts must be positive")
+	}
+	if c.Enabled && c.PollInterval <= 0 {
+		return fmt.Errorf("pollInterval must be positive")
+	}
+	return nil
+}
+
+// Recoverer recovers the stuck transfers of the performer
+type Recoverer interface {
+	// RecoverTransfers mints the stuck transfers again and escalates those needing manual
+	// intervention. It returns how many transfers were stuck.
+	RecoverTransfers(ctx context.Context) (int, error)
+}
+
+// Watchdog recovers stuck transfers periodically, so no transfer waits on an operator
+// noticing it
+type Watchdog struct {
+	cfg    Config
+	logger *zap.Logger
+}
+
+// New creates a watchdog checking transfers every cfg.PollInterval
+func New(cfg Config, logger *zap.Logger) *Watchdog {
+	return &Watchdog{cfg: cfg, logger: logger}
+}
+
+// NewFromConfig creates the watchdog of cfg, nil when disabled
+func NewFromConfig(cfg Config, logger *zap.Logger) *Watchdog {
+	if !cfg.Enabled {
+		return nil
+	}
+	return New(cfg, logger)
+}
+
+// Start hands the stuck transfers to recoverer until ctx is cancelled
+func (w *Watchdog) Start(ctx context.Context, recoverer Recoverer) {
+	go func() {
+		ticker := time.NewTicker(w.cfg.PollInterval)
+		defer ticker.Stop()
+		for {
+			stuck, err := recoverer.RecoverTransfers(ctx)
+			if err != nil {
+				w.logger.Sugar().Warnw("Transfer recovery incomplete", "error", err)
+			}
+			if stuck > 0 {
+				w.logger.Sugar().Warnw("Recovered stuck transfers", "stuck", stuck)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
@@ -0,0 +1,59 @@
+package recovery
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type countingRecoverer chan struct{}
+
+func (r countingRecoverer) RecoverTransfers(ctx context.Context) (int, error) {
+	select {
+	case r <- struct{}{}:
+	case <-ctx.Done():
+	}
+	return 1, nil
+}
+
+func Test_WatchdogRecoversPeriodically(t *testing.T) {
+	if NewFromConfig(DefaultConfig(), zap.NewNop()) != nil {
+		t.Fatalf("Expected no watchdog by default")
+	}
+	cfg := DefaultConfig()
+	cfg.Enabled, cfg.PollInterval = true, time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	recoverer := make(countingRecoverer)
+	NewFromConfig(cfg, zap.NewNop()).Start(ctx, recoverer)
+	for i := 0; i < 2; i++ {
+		select {
+		case <-recoverer:
+		case <-time.After(time.Second):
+			t.Fatalf("Expected check %d to recover transfers", i+1)
+		}
+	}
+}
+
+func Test_ConfigValidate(t *testing.T) {
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Fatalf("Expected the default config valid, got %v", err)
+	}
+	cfg := DefaultConfig()
+	cfg.PollInterval = 0
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected the poll interval ignored while disabled, got %v", err)
+	}
+	cfg.Enabled = true
+	if err := cfg.Validate(); err == nil {
+		t.Errorf("Expected a zero poll interval rejected")
+	}
+	cfg = DefaultConfig()
+	cfg.MaxAttempts = 0
+	if err := cfg.Validate(); err == nil {
+		t.Errorf("Expected zero attempts rejected")
+	}
+}
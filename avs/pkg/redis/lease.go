@@ -0,0 +1,98 @@
+package redis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+var (
+	// ErrLeaseHeld is returned when acquiring a lease another owner holds
+	ErrLeaseHeld = errors.New("lease held by another owner")
+
+	// ErrLeaseLost is returned when renewing a lease that expired and was taken by
+	// another owner
+	ErrLeaseLost = errors.New("lease lost to another owner")
+)
+
+// renewLease extends the lease of KEYS[1] when ARGV[1] holds it, taking it again when it
+// expired and nobody took it since
+var renewLease = goredis.NewScript(`
+local owner = redis.call("GET", KEYS[1])
+if owner == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+elseif not owner then
+	redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+	return 1
+end
+return 0
+`)
+
+// releaseLease deletes the lease of KEYS[1] when ARGV[1] holds it
+var releaseLease = goredis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// Lease is a lock on a name shared by the replicas, held until it is released or its
+// TTL passes without a renewal. Holders renew it as their work progresses, so the lease
+// of a replica that stopped or hangs is taken over once it expires.
+type Lease struct {
+	client *Client
+	key    string
+	owner  string
+	ttl    time.Duration
+}
+
+// Acquire takes the lease on name for ttl, returning ErrLeaseHeld when another owner
+// holds it, and ErrUnavailable while Redis is down
+func (c *Client) Acquire(ctx context.Context, name string, ttl time.Duration) (*Lease, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, fmt.Errorf("failed to generate lease owner: %w", err)
+	}
+	l := &Lease{client: c, key: c.Key("lease", name), owner: hex.EncodeToString(token), ttl: ttl}
+	var acquired bool
+	err := c.Do(ctx, func(ctx context.Context, rdb goredis.Cmdable) (err error) {
+		acquired, err = rdb.SetNX(ctx, l.key, l.owner, ttl).Result()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !acquired {
+		return nil, ErrLeaseHeld
+	}
+	return l, nil
+}
+
+// Renew extends the lease for its TTL from now, returning ErrLeaseLost when it expired
+// and another owner took it
+func (l *Lease) Renew(ctx context.Context) error {
+	var renewed int64
+	err := l.client.Do(ctx, func(ctx context.Context, rdb goredis.Cmdable) (err error) {
+		renewed, err = renewLease.Run(ctx, rdb, []string{l.key}, l.owner, l.ttl.Milliseconds()).Int64()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if renewed == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// Release gives up the lease, unless it was already lost to another owner
+func (l *Lease) Release(ctx context.Context) error {
+	return l.client.Do(ctx, func(ctx context.Context, rdb goredis.Cmdable) error {
+		return releaseLease.Run(ctx, rdb, []string{l.key}, l.owner).Err()
+	})
+}
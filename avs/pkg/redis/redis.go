@@ -0,0 +1,255 @@
+// Package redis connects replicas of one operator's performer to a shared Redis, so they
+// share the result cache, deduplicate tasks delivered to more than one of them, count
+// task quotas together and lease the rebalance plans they execute.
+//
+// Every key is prefixed with the namespace of the config, which must differ between
+// operators sharing a Redis. Redis is an optimization, never a dependency: when a call
+// fails the client reports Redis unavailable for the failover cooldown, and the
+// components fall back to the state local to their replica until it passes.
+package redis
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	goredis "github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// ErrUnavailable is returned while Redis is considered down after a failed call
+var ErrUnavailable = errors.New("redis unavailable")
+
+// Nil is returned by reads of missing keys
+var Nil = goredis.Nil
+
+// Config locates the Redis shared by the replicas of a performer
+type Config struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Addr is the host:port of the Redis server
+	Addr     string `yaml:"addr"`
+	Username string `yaml:"username"`
+
+	// PasswordEnv names the environment variable holding the password of Username
+	PasswordEnv string `yaml:"passwordEnv"`
+
+	DB  int  `yaml:"db"`
+	TLS bool `yaml:"tls"`
+
+	// Namespace prefixes every key. Replicas of one operator share it, and operators
+	// sharing a Redis must not.
+	Namespace string `yaml:"namespace"`
+
+	// DialTimeout bounds connecting, and Timeout every call
+	DialTimeout time.Duration `yaml:"dialTimeout"`
+	Timeout     time.Duration `yaml:"timeout"`
+
+	// FailoverCooldown is how long replicas use their local state after a failed call
+	// before trying Redis again. Rebalances, whose plans, nonces and leases must be
+	// shared, are refused meanwhile.
+	FailoverCooldown time.Duration `yaml:"failoverCooldown"`
+
+	// IdempotencyTTL is how long the results of tasks are kept for replicas receiving a
+	// task another replica ran. Zero leaves deduplication local to each replica.
+	IdempotencyTTL time.Duration `yaml:"idempotencyTtl"`
+
+	// ExecutionLease is how long a replica holds a rebalance plan it executes without
+	// making progress before other replicas may execute it. It must outlast the longest
+	// wait of an execution, for a gas window or a confirmation. Zero leaves execution
+	// locking local to each replica.
+	ExecutionLease time.Duration `yaml:"executionLease"`
+}
+
+// DefaultConfig leaves Redis disabled. Enabled, it answers within 200ms or is skipped
+// for 30 seconds, task results are shared for 10 minutes and rebalance plans are leased
+// for 15.
+func DefaultConfig() Config {
+	return Config{
+		Namespace:        "yieldavs",
+		DialTimeout:      time.Second,
+		Timeout:          200 * time.Millisecond,
+		FailoverCooldown: 30 * time.Second,
+		IdempotencyTTL:   10 * time.Minute,
+		ExecutionLease:   15 * time.Minute,
+	}
+}
+
+// Validate checks the config for values the client cannot run with
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Addr == "" {
+		return fmt.Errorf("addr is required")
+	}
+	if c.Namespace == "" || strings.ContainsAny(c.Namespace, " :") {
+		return fmt.Errorf("namespace must be set and contain no spaces or colons")
+	}
+	if c.DialTimeout <= 0 || c.Timeout <= 0 {
+		return fmt.Errorf("dialTimeout and timeout must be positive")
+	}
+	if c.FailoverCooldown < 0 || c.IdempotencyTTL < 0 || c.ExecutionLease < 0 {
+		return fmt.Errorf("failoverCooldown, idempotencyTtl and executionLease must not be negative")
+	}
+	return nil
+}
+
+// Client calls the shared Redis, tracking whether it is available. A nil Client is
+// never available, so components built on it keep their state local.
+type Client struct {
+	cfg    Config
+	rdb    goredis.UniversalClient
+	logger *zap.Logger
+
+	mu        sync.Mutex
+	downUntil time.Time
+
+	available prometheus.Gauge
+	failovers prometheus.Counter
+
+	// now is replaced in tests
+	now func() time.Time
+}
+
+// New creates a client of the Redis in cfg. Connections are opened on first use, so a
+// Redis that is down does not keep the performer from starting. Whether Redis is
+// available and how often replicas failed over to local state are exported as the
+// yieldavs_redis_available and yieldavs_redis_failovers_total metrics, registered with
+// registerer when it is not nil.
+func New(cfg Config, registerer prometheus.Registerer, logger *zap.Logger) (*Client, error) {
+	opts := &goredis.Options{
+		Addr:         cfg.Addr,
+		Username:     cfg.Username,
+		DB:           cfg.DB,
+		DialTimeout:  cfg.DialTimeout,
+		ReadTimeout:  cfg.Timeout,
+		WriteTimeout: cfg.Timeout,
+		// calls fail over rather than retry, which would hold tasks for longer
+		MaxRetries: -1,
+	}
+	if cfg.PasswordEnv != "" {
+		if opts.Password = os.Getenv(cfg.PasswordEnv); opts.Password == "" {
+			return nil, fmt.Errorf("%s is not set", cfg.PasswordEnv)
+		}
+	}
+	if cfg.TLS {
+		opts.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	return newClient(cfg, goredis.NewClient(opts), registerer, logger)
+}
+
+func newClient(cfg Config, rdb goredis.UniversalClient, registerer prometheus.Registerer, logger *zap.Logger) (*Client, error) {
+	c := &Client{
+		cfg:    cfg,
+		rdb:    rdb,
+		logger: logger,
+		available: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "yieldavs_redis_available",
+			Help: "Whether the shared Redis is used (1) or replicas fell back to local state (0).",
+		}),
+		failovers: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "yieldavs_redis_failovers_total",
+			Help: "Times a failed Redis call made replicas fall back to local state.",
+		}),
+		now: time.Now,
+	}
+	c.available.Set(1)
+	if registerer != nil {
+		for _, collector := range []prometheus.Collector{c.available, c.failovers} {
+			if err := registerer.Register(collector); err != nil {
+				return nil, fmt.Errorf("failed to register redis metrics: %w", err)
+			}
+		}
+	}
+	return c, nil
+}
+
+// NewFromConfig creates the client enabled in cfg, nil when it is disabled
+func NewFromConfig(cfg Config, registerer prometheus.Registerer, logger *zap.Logger) (*Client, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	return New(cfg, registerer, logger)
+}
+
+// Key joins parts into a key in the namespace of the client
+func (c *Client) Key(parts ...string) string {
+	return c.cfg.Namespace + ":" + strings.Join(parts, ":")
+}
+
+// IdempotencyTTL returns how long task results are shared, zero when they are not
+func (c *Client) IdempotencyTTL() time.Duration {
+	if c == nil {
+		return 0
+	}
+	return c.cfg.IdempotencyTTL
+}
+
+// ExecutionLease returns how long rebalance plans are leased, zero when they are not
+func (c *Client) ExecutionLease() time.Duration {
+	if c == nil {
+		return 0
+	}
+	return c.cfg.ExecutionLease
+}
+
+// Available reports whether Redis is used, false during the cooldown after a failure
+func (c *Client) Available() bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.downUntil.IsZero() && c.now().After(c.downUntil) {
+		c.downUntil = time.Time{}
+		c.available.Set(1)
+	}
+	return c.downUntil.IsZero()
+}
+
+// Do calls fn with the Redis client, returning ErrUnavailable without calling it while
+// Redis is down. Failures other than missing keys and the end of ctx mark Redis down
+// for the failover cooldown.
+func (c *Client) Do(ctx context.Context, fn func(ctx context.Context, rdb goredis.Cmdable) error) error {
+	if !c.Available() {
+		return ErrUnavailable
+	}
+	callCtx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+	err := fn(callCtx, c.rdb)
+	// calls ended by the caller's context say nothing of Redis
+	if err != nil && !errors.Is(err, goredis.Nil) && ctx.Err() == nil {
+		c.fail(err)
+	}
+	return err
+}
+
+// fail marks Redis down for the failover cooldown
+func (c *Client) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.downUntil.IsZero() {
+		return
+	}
+	c.downUntil = c.now().Add(c.cfg.FailoverCooldown)
+	c.available.Set(0)
+	c.failovers.Inc()
+	if c.logger != nil {
+		c.logger.Sugar().Warnw("Redis failed, using local state", "cooldown", c.cfg.FailoverCooldown, "error", err)
+	}
+}
+
+// Close closes the connections to Redis
+func (c *Client) Close() error {
+	if c == nil {
+		return nil
+	}
+	return c.rdb.Close()
+}
@@ -0,0 +1,152 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func newTestClient(t *testing.T) (*Client, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	cfg := DefaultConfig()
+	cfg.Enabled, cfg.Addr, cfg.Namespace = true, server.Addr(), "operator-a"
+	client, err := New(cfg, nil, nil)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client, server
+}
+
+func Test_KeysAreNamespaced(t *testing.T) {
+	client, server := newTestClient(t)
+	err := client.Do(context.Background(), func(ctx context.Context, rdb goredis.Cmdable) error {
+		return rdb.Set(ctx, client.Key("cache", "result:yield_monitoring:ab"), "1", time.Minute).Err()
+	})
+	if err != nil {
+		t.Fatalf("Do failed: %v", err)
+	}
+	if !server.Exists("operator-a:cache:result:yield_monitoring:ab") {
+		t.Errorf("Expected the key in the namespace, got %v", server.Keys())
+	}
+}
+
+func Test_FailoverToLocalState(t *testing.T) {
+	client, server := newTestClient(t)
+	now := time.Unix(1_800_000_000, 0)
+	client.now = func() time.Time { return now }
+	get := func() error {
+		return client.Do(context.Background(), func(ctx context.Context, rdb goredis.Cmdable) error {
+			return rdb.Get(ctx, client.Key("missing")).Err()
+		})
+	}
+
+	if err := get(); !errors.Is(err, Nil) || !client.Available() {
+		t.Fatalf("Expected a missing key to leave Redis available, got %v", err)
+	}
+
+	server.SetError("LOADING")
+	if err := get(); err == nil || errors.Is(err, Nil) {
+		t.Fatalf("Expected the call to fail, got %v", err)
+	}
+	server.SetError("")
+	if err := get(); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Expected Redis to be skipped during the cooldown, got %v", err)
+	}
+	if testutil.ToFloat64(client.available) != 0 || testutil.ToFloat64(client.failovers) != 1 {
+		t.Errorf("Expected the failover to be exported")
+	}
+
+	now = now.Add(client.cfg.FailoverCooldown + time.Second)
+	if err := get(); !errors.Is(err, Nil) {
+		t.Errorf("Expected Redis to be used again after the cooldown, got %v", err)
+	}
+	if testutil.ToFloat64(client.available) != 1 {
+		t.Errorf("Expected Redis to be exported as available")
+	}
+
+	var disabled *Client
+	if disabled.Available() {
+		t.Errorf("Expected a nil client to be unavailable")
+	}
+}
+
+func Test_ConfigValidate(t *testing.T) {
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Fatalf("Expected the default config to be valid: %v", err)
+	}
+	valid := DefaultConfig()
+	valid.Enabled, valid.Addr = true, "localhost:6379"
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Expected the config to be valid: %v", err)
+	}
+	for name, mutate := range map[string]func(*Config){
+		"addr":      func(c *Config) { c.Addr = "" },
+		"namespace": func(c *Config) { c.Namespace = "" },
+		"colon":     func(c *Config) { c.Namespace = "a:b" },
+		"timeout":   func(c *Config) { c.Timeout = 0 },
+		"cooldown":  func(c *Config) { c.FailoverCooldown = -time.Second },
+	} {
+		cfg := valid
+		mutate(&cfg)
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("%s: expected the config to be rejected", name)
+		}
+	}
+
+	missing := valid
+	missing.PasswordEnv = "YIELDAVS_TEST_REDIS_PASSWORD_UNSET"
+	if _, err := New(missing, nil, nil); err == nil {
+		t.Errorf("Expected an unset password variable to be rejected")
+	}
+}
+
+func Test_LeaseIsHeldByOneOwner(t *testing.T) {
+	client, server := newTestClient(t)
+	ctx := context.Background()
+
+	lease, err := client.Acquire(ctx, "plan:ab", time.Minute)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	if _, err := client.Acquire(ctx, "plan:ab", time.Minute); !errors.Is(err, ErrLeaseHeld) {
+		t.Errorf("Expected a held lease to be refused, got %v", err)
+	}
+
+	server.FastForward(50 * time.Second)
+	if err := lease.Renew(ctx); err != nil {
+		t.Fatalf("Renew failed: %v", err)
+	}
+	server.FastForward(50 * time.Second)
+	if !server.Exists("operator-a:lease:plan:ab") {
+		t.Fatalf("Expected the renewed lease to outlast its first TTL")
+	}
+
+	// a lease that expired is taken over, and its holder told when renewing
+	server.FastForward(time.Minute)
+	other, err := client.Acquire(ctx, "plan:ab", time.Minute)
+	if err != nil {
+		t.Fatalf("Expected the expired lease to be taken over: %v", err)
+	}
+	if err := lease.Renew(ctx); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("Expected the lost lease to be reported, got %v", err)
+	}
+	if err := lease.Release(ctx); err != nil || !server.Exists("operator-a:lease:plan:ab") {
+		t.Errorf("Expected releasing a lost lease to leave the new holder's (%v)", err)
+	}
+	if err := other.Release(ctx); err != nil || server.Exists("operator-a:lease:plan:ab") {
+		t.Errorf("Expected the lease to be released (%v)", err)
+	}
+
+	// an expired lease nobody took is taken again by its holder
+	server.FastForward(time.Minute)
+	if err := other.Renew(ctx); err != nil || !server.Exists("operator-a:lease:plan:ab") {
+		t.Errorf("Expected the expired lease to be taken again, got %v", err)
+	}
+}
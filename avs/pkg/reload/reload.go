@@ -0,0 +1,137 @@
+// Package reload tells a running performer when to reload its config file: on SIGHUP,
+// and when configured, whenever the file changes. Which settings can change without a
+// restart is up to the caller.
+package reload
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+)
+
+// Config configures config reloads. SIGHUP always reloads the config.
+type Config struct {
+	// Watch reloads the config whenever its file changes
+	Watch bool `yaml:"watch"`
+
+	// Debounce is how long changes to the file must settle before it is reloaded, so an
+	// editor writing it in several steps causes a single reload
+	Debounce time.Duration `yaml:"debounce"`
+}
+
+// DefaultConfig reloads on SIGHUP only
+func DefaultConfig() Config {
+	return Config{Debounce: 500 * time.Millisecond}
+}
+
+// Validate checks the config for values reloads cannot be watched with
+func (c Config) Validate() error {
+	if c.Watch && c.Debounce <= 0 {
+		return fmt.Errorf("debounce must be positive")
+	}
+	return nil
+}
+
+// Watcher calls a function when the config file at a path is to be reloaded
+type Watcher struct {
+	cfg    Config
+	path   string
+	logger *zap.Logger
+}
+
+// New creates a watcher of the config file at path
+func New(cfg Config, path string, logger *zap.Logger) *Watcher {
+	return &Watcher{cfg: cfg, path: path, logger: logger}
+}
+
+// Start calls reload on SIGHUP and, when watching, on changes to the file, until ctx is
+// cancelled. Reloads are made one at a time. SIGHUP no longer stops the process once
+// Start returned.
+func (w *Watcher) Start(ctx context.Context, reload func()) error {
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+
+	var changes <-chan struct{}
+	if w.cfg.Watch {
+		var err error
+		if changes, err = w.watch(ctx); err != nil {
+			signal.Stop(hangups)
+			return err
+		}
+	}
+
+	go func() {
+		defer signal.Stop(hangups)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hangups:
+				w.logger.Sugar().Infow("Reloading config on SIGHUP", "path", w.path)
+			case <-changes:
+				w.logger.Sugar().Infow("Reloading config after it changed", "path", w.path)
+			}
+			reload()
+		}
+	}()
+	return nil
+}
+
+// watch reports changes to the config file once they settled. The directory is watched
+// rather than the file, since editors and Kubernetes config maps replace files instead of
+// writing them in place.
+func (w *Watcher) watch(ctx context.Context) (<-chan struct{}, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch config: %w", err)
+	}
+	path, err := filepath.Abs(w.path)
+	if err != nil {
+		watcher.Close()
+		return nil, fmt.Errorf("failed to watch config: %w", err)
+	}
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		return nil, fmt.Errorf("failed to watch config: %w", err)
+	}
+
+	// the file itself, and the ..data link config maps swap when they are updated
+	relevant := map[string]bool{path: true, filepath.Join(filepath.Dir(path), "..data"): true}
+	changes := make(chan struct{}, 1)
+	go func() {
+		defer watcher.Close()
+		var settle <-chan time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if relevant[filepath.Clean(event.Name)] && !event.Has(fsnotify.Chmod) {
+					settle = time.After(w.cfg.Debounce)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				w.logger.Sugar().Warnw("Failed to watch config", "error", err)
+			case <-settle:
+				settle = nil
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return changes, nil
+}
@@ -0,0 +1,92 @@
+package reload
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func startWatcher(t *testing.T, cfg Config, path string) <-chan struct{} {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	reloads := make(chan struct{}, 10)
+	if err := New(cfg, path, zap.NewNop()).Start(ctx, func() { reloads <- struct{}{} }); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	return reloads
+}
+
+func expectReload(t *testing.T, reloads <-chan struct{}, reason string) {
+	t.Helper()
+	select {
+	case <-reloads:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected a reload %s", reason)
+	}
+}
+
+func Test_ReloadsOnFileChanges(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "performer.yaml")
+	if err := os.WriteFile(path, []byte("grpcPort: 8080\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	reloads := startWatcher(t, Config{Watch: true, Debounce: 20 * time.Millisecond}, path)
+
+	// other files in the directory are ignored
+	if err := os.WriteFile(filepath.Join(dir, "other.yaml"), []byte("a: 1\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	select {
+	case <-reloads:
+		t.Fatalf("Expected changes to other files to be ignored")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// several writes in a row cause one reload
+	for i := 0; i < 3; i++ {
+		if err := os.WriteFile(path, []byte("grpcPort: 9000\n"), 0o644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+	}
+	expectReload(t, reloads, "after the config changed")
+	select {
+	case <-reloads:
+		t.Errorf("Expected writes in quick succession to be debounced")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// editors replace the file
+	replacement := filepath.Join(dir, "performer.yaml.tmp")
+	if err := os.WriteFile(replacement, []byte("grpcPort: 9001\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if err := os.Rename(replacement, path); err != nil {
+		t.Fatalf("Failed to replace config: %v", err)
+	}
+	expectReload(t, reloads, "after the config was replaced")
+}
+
+func Test_ReloadsOnSIGHUP(t *testing.T) {
+	reloads := startWatcher(t, DefaultConfig(), filepath.Join(t.TempDir(), "performer.yaml"))
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatalf("Failed to send SIGHUP: %v", err)
+	}
+	expectReload(t, reloads, "on SIGHUP")
+}
+
+func Test_ConfigValidate(t *testing.T) {
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Errorf("Expected the default config to be valid: %v", err)
+	}
+	if err := (Config{Watch: true}).Validate(); err == nil {
+		t.Errorf("Expected watching without a debounce to be rejected")
+	}
+}
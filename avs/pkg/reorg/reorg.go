@@ -0,0 +1,239 @@
+// Package reorg watches the blocks task results were read at and reports the results a
+// reorganization of the chain removed a block of. Snapshot reference blocks are meant
+// to be final, so a block that changes once it is buried under the confirmation
+// threshold means the chain reorganized deeper than operators assumed it could, and the
+// results read at it no longer describe the chain.
+package reorg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+	"go.uber.org/zap"
+)
+
+const prefixBlock = "reorg:block:"
+
+// Config configures watching the blocks of results
+type Config struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Confirmations is how many blocks a block must be buried under before it is checked.
+	// Blocks changing before that are within the reorgs the chain is expected to have.
+	Confirmations uint64 `yaml:"confirmations"`
+
+	// Depth is how many blocks deep blocks are watched for. Deeper blocks are dropped.
+	Depth uint64 `yaml:"depth"`
+
+	// PollInterval is the time between checks of the watched blocks
+	PollInterval time.Duration `yaml:"pollInterval"`
+
+	// Reexecute re-executes the monitoring tasks whose results were invalidated, so
+	// their stored results describe the chain again
+	Reexecute bool `yaml:"reexecute"`
+}
+
+// DefaultConfig is disabled. Enabled, blocks are checked once 12 blocks deep, every
+// minute, for about a day of Ethereum blocks.
+func DefaultConfig() Config {
+	return Config{
+		Confirmations: 12,
+		Depth:         7_200,
+		PollInterval:  time.Minute,
+	}
+}
+
+// Validate checks the config for values blocks cannot be watched with
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Depth <= c.Confirmations {
+		return fmt.Errorf("depth must be greater than confirmations")
+	}
+	if c.PollInterval <= 0 {
+		return fmt.Errorf("pollInterval must be positive")
+	}
+	return nil
+}
+
+// Block is a block a task result was read at
+type Block struct {
+	TaskID  string      `json:"taskId"`
+	ChainID uint64      `json:"chainId"`
+	Number  uint64      `json:"number"`
+	Hash    common.Hash `json:"hash"`
+}
+
+// Invalidation is a watched block a reorg removed from its chain. Depth is the number of
+// blocks from it to the head the reorg was seen at.
+type Invalidation struct {
+	Block
+	CanonicalHash common.Hash `json:"canonicalHash"`
+	Depth         uint64      `json:"depth"`
+}
+
+// Invalidator handles the results invalidated by reorgs
+type Invalidator interface {
+	// Invalidate marks the result of the task of invalidation as stale, and re-executes
+	// it when reexecute is set and it is a monitoring task
+	Invalidate(ctx context.Context, invalidation *Invalidation, reexecute bool) error
+}
+
+// Watcher watches the blocks of results
+type Watcher struct {
+	cfg    Config
+	chains *chain.Manager
+	kv     store.KV
+	logger *zap.Logger
+}
+
+// New creates a watcher keeping the watched blocks in kv
+func New(cfg Config, chains *chain.Manager, kv store.KV, logger *zap.Logger) *Watcher {
+	return &Watcher{cfg: cfg, chains: chains, kv: kv, logger: logger}
+}
+
+// NewFromConfig creates the watcher of cfg, nil when disabled
+func NewFromConfig(cfg Config, chains *chain.Manager, kv store.KV, logger *zap.Logger) *Watcher {
+	if !cfg.Enabled {
+		return nil
+	}
+	return New(cfg, chains, kv, logger)
+}
+
+func blockKey(b *Block) []byte {
+	return []byte(fmt.Sprintf("%s%d:%020d:%s", prefixBlock, b.ChainID, b.Number, b.TaskID))
+}
+
+// Track watches block number of chainID, which the result of taskID was read at. The
+// hash of the block is read now.
+func (w *Watcher) Track(ctx context.Context, taskID string, chainID, number uint64) error {
+	client, err := w.chains.Client(chainID)
+	if err != nil {
+		return err
+	}
+	header, err := client.HeaderByNumber(ctx, new(big.Int).SetUint64(number))
+	if err != nil {
+		return fmt.Errorf("failed to read block %d of chain %d: %w", number, chainID, err)
+	}
+	block := &Block{TaskID: taskID, ChainID: chainID, Number: number, Hash: header.Hash()}
+	value, err := json.Marshal(block)
+	if err != nil {
+		return fmt.Errorf("failed to encode block: %w", err)
+	}
+	if err := w.kv.Set(ctx, blockKey(block), value); err != nil {
+		return fmt.Errorf("failed to watch block %d of chain %d: %w", number, chainID, err)
+	}
+	return nil
+}
+
+// Start checks the watched blocks and hands the invalidated results to invalidator until
+// ctx is cancelled
+func (w *Watcher) Start(ctx context.Context, invalidator Invalidator) {
+	go func() {
+		ticker := time.NewTicker(w.cfg.PollInterval)
+		defer ticker.Stop()
+		for {
+			invalidations, err := w.Poll(ctx)
+			if err != nil {
+				w.logger.Sugar().Warnw("Reorg checks incomplete", "error", err)
+			}
+			for _, invalidation := range invalidations {
+				w.logger.Sugar().Warnw("Result invalidated by a reorg", "taskId", invalidation.TaskID, "chainId", invalidation.ChainID,
+					"block", invalidation.Number, "hash", invalidation.Hash.Hex(), "canonicalHash", invalidation.CanonicalHash.Hex(), "depth", invalidation.Depth)
+				if err := invalidator.Invalidate(ctx, invalidation, w.cfg.Reexecute); err != nil {
+					w.logger.Sugar().Errorw("Failed to invalidate result", "taskId", invalidation.TaskID, "error", err)
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Poll checks the watched blocks buried under the confirmation threshold against the
+// chain and returns those a reorg removed. Invalidated blocks and blocks deeper than
+// the watched depth stop being watched. Chains whose head cannot be read are checked
+// again on the next poll.
+func (w *Watcher) Poll(ctx context.Context) ([]*Invalidation, error) {
+	byChain := make(map[uint64][]*Block)
+	var chainIDs []uint64
+	err := w.kv.Iterate(ctx, []byte(prefixBlock), func(key, value []byte) error {
+		var block Block
+		if err := json.Unmarshal(value, &block); err != nil {
+			return fmt.Errorf("failed to decode watched block %s: %w", key, err)
+		}
+		if _, ok := byChain[block.ChainID]; !ok {
+			chainIDs = append(chainIDs, block.ChainID)
+		}
+		byChain[block.ChainID] = append(byChain[block.ChainID], &block)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var invalidations []*Invalidation
+	var firstErr error
+	for _, chainID := range chainIDs {
+		found, err := w.pollChain(ctx, chainID, byChain[chainID])
+		invalidations = append(invalidations, found...)
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("chain %d: %w", chainID, err)
+		}
+	}
+	return invalidations, firstErr
+}
+
+// pollChain checks the watched blocks of chainID, in ascending order
+func (w *Watcher) pollChain(ctx context.Context, chainID uint64, blocks []*Block) ([]*Invalidation, error) {
+	client, err := w.chains.Client(chainID)
+	if err != nil {
+		return nil, err
+	}
+	head, err := client.BlockNumber(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	canonical := make(map[uint64]common.Hash)
+	var invalidations []*Invalidation
+	for _, block := range blocks {
+		if block.Number+w.cfg.Confirmations > head {
+			// blocks are in ascending order, so the rest are shallower still
+			break
+		}
+		if head-block.Number > w.cfg.Depth {
+			if err := w.kv.Delete(ctx, blockKey(block)); err != nil {
+				return invalidations, err
+			}
+			continue
+		}
+		hash, ok := canonical[block.Number]
+		if !ok {
+			header, err := client.HeaderByNumber(ctx, new(big.Int).SetUint64(block.Number))
+			if err != nil {
+				return invalidations, fmt.Errorf("failed to read block %d: %w", block.Number, err)
+			}
+			hash = header.Hash()
+			canonical[block.Number] = hash
+		}
+		if hash == block.Hash {
+			continue
+		}
+		if err := w.kv.Delete(ctx, blockKey(block)); err != nil {
+			return invalidations, err
+		}
+		invalidations = append(invalidations, &Invalidation{Block: *block, CanonicalHash: hash, Depth: head - block.Number + 1})
+	}
+	return invalidations, nil
+}
@@ -0,0 +1,58 @@
+package reorg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/chain/chaintest"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+	"go.uber.org/zap"
+)
+
+func Test_WatcherReportsReorgedBlocks(t *testing.T) {
+	contracts := chaintest.NewContracts(1)
+	chains := chain.NewManager()
+	chains.Register(1, "ethereum", contracts)
+	cfg := DefaultConfig()
+	cfg.Enabled, cfg.Confirmations, cfg.Depth = true, 5, 50
+	w := New(cfg, chains, store.NewMemoryKV(), zap.NewNop())
+	ctx := context.Background()
+
+	// the head is at block 100
+	for taskID, block := range map[string]uint64{"deep": 90, "shallow": 98, "kept": 80} {
+		if err := w.Track(ctx, taskID, 1, block); err != nil {
+			t.Fatalf("Track failed: %v", err)
+		}
+	}
+	if err := w.Track(ctx, "unknown", 10, 1); err == nil {
+		t.Errorf("Expected blocks of unknown chains to be rejected")
+	}
+
+	contracts.Reorg(85)
+	invalidations, err := w.Poll(ctx)
+	if err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	if len(invalidations) != 1 || invalidations[0].TaskID != "deep" || invalidations[0].Number != 90 || invalidations[0].Depth != 11 ||
+		invalidations[0].CanonicalHash == invalidations[0].Hash {
+		t.Fatalf("Expected the result read at the reorged confirmed block invalidated, got %+v", invalidations)
+	}
+
+	// the shallow block is checked once confirmed, and invalidated blocks are not reported again
+	contracts.AdvanceBlocks(3)
+	invalidations, err = w.Poll(ctx)
+	if err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	if len(invalidations) != 1 || invalidations[0].TaskID != "shallow" {
+		t.Errorf("Expected the confirmed shallow block invalidated, got %+v", invalidations)
+	}
+
+	// blocks past the watched depth are dropped
+	contracts.AdvanceBlocks(30)
+	contracts.Reorg(70)
+	if invalidations, err := w.Poll(ctx); err != nil || len(invalidations) != 0 {
+		t.Errorf("Expected blocks past the watched depth dropped, got %+v: %v", invalidations, err)
+	}
+}
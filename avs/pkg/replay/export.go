@@ -0,0 +1,95 @@
+package replay
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/najnomics/crosscow-avs/pkg/export"
+)
+
+// Files reports are exported to, suffixed with the format
+const (
+	fileMarkets    = "markets"
+	fileCrossChain = "cross_chain"
+)
+
+// Export writes the rows of the report to dir in format, one file per kind of row:
+// markets, and cross_chain when the replay compared chains. It returns the files written.
+func (r *Report) Export(dir, format string) ([]string, error) {
+	if err := export.CheckFormat(format); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create export directory: %w", err)
+	}
+	var written []string
+	write := func(name string, rows func(io.Writer) error) error {
+		path := filepath.Join(dir, name+"."+format)
+		if err := writeFile(path, rows); err != nil {
+			return fmt.Errorf("failed to export %s: %w", path, err)
+		}
+		written = append(written, path)
+		return nil
+	}
+	if err := write(fileMarkets, func(w io.Writer) error { return export.Write(w, format, r.Markets) }); err != nil {
+		return written, err
+	}
+	if len(r.CrossChain) > 0 {
+		if err := write(fileCrossChain, func(w io.Writer) error { return export.Write(w, format, r.CrossChain) }); err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+func (MarketRow) Header() []string {
+	return []string{"block", "time", "chain_id", "chain_block", "protocol", "supply_rate", "utilization", "total_supply", "total_borrow"}
+}
+
+func (r MarketRow) Record() []string {
+	return []string{
+		formatUint(r.Block), strconv.FormatInt(r.Time, 10), formatUint(r.ChainID), formatUint(r.ChainBlock), r.Protocol,
+		formatFloat(r.SupplyRate), formatFloat(r.Utilization), formatFloat(r.TotalSupply), formatFloat(r.TotalBorrow),
+	}
+}
+
+func (CrossChainRow) Header() []string {
+	return []string{
+		"block", "time", "source_chain", "source_block", "source_protocol", "source_rate",
+		"target_chain", "target_block", "target_protocol", "target_rate", "target_projected_rate",
+		"improvement_bps", "projected_improvement_bps",
+	}
+}
+
+func (r CrossChainRow) Record() []string {
+	return []string{
+		formatUint(r.Block), strconv.FormatInt(r.Time, 10),
+		formatUint(r.SourceChain), formatUint(r.SourceBlock), r.SourceProtocol, formatFloat(r.SourceRate),
+		formatUint(r.TargetChain), formatUint(r.TargetBlock), r.TargetProtocol, formatFloat(r.TargetRate), formatFloat(r.TargetProjectedRate),
+		formatFloat(r.ImprovementBps), formatFloat(r.ProjectedImprovementBps),
+	}
+}
+
+// writeFile creates path and writes it with write
+func writeFile(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func formatUint(v uint64) string {
+	return strconv.FormatUint(v, 10)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
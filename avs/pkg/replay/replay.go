@@ -0,0 +1,384 @@
+// Package replay recomputes the market reads of yield_monitoring and
+// cross_chain_yield_check tasks over a range of past blocks. Replays backfill the yield
+// history the collector records, and export what was read for strategy research and
+// adapter validation. Past state is only served by archive nodes, so chains replayed
+// beyond the blocks their nodes keep need an archiveRpcUrl.
+package replay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+	"go.uber.org/zap"
+)
+
+// MaxSteps bounds the blocks a single replay reads markets at
+const MaxSteps = 100_000
+
+// Config is the range of blocks a replay steps through and the markets it reads
+type Config struct {
+	// ChainID is the chain whose blocks From to To are stepped through, Step blocks apart.
+	// The other chains are read at their last block produced by the time of each step.
+	ChainID uint64
+	From    uint64
+	To      uint64
+	Step    uint64
+
+	// ChainIDs are the chains whose markets are read, ChainID alone when empty
+	ChainIDs []uint64
+
+	// CrossChain compares moving an amount between two chains at every step, as
+	// cross_chain_yield_check tasks do. Nil to skip the comparison.
+	CrossChain *CrossChain
+}
+
+// CrossChain is the move a replay compares the rates of
+type CrossChain struct {
+	SourceChain uint64
+	TargetChain uint64
+
+	// Amount is the USDC moved, in base units
+	Amount *big.Int
+}
+
+// Validate checks the range and the comparison of c
+func (c *Config) Validate() error {
+	if c.ChainID == 0 {
+		return fmt.Errorf("chain must be set")
+	}
+	if c.Step == 0 {
+		return fmt.Errorf("step must be positive")
+	}
+	if c.From > c.To {
+		return fmt.Errorf("from block %d is after to block %d", c.From, c.To)
+	}
+	if steps := (c.To-c.From)/c.Step + 1; steps > MaxSteps {
+		return fmt.Errorf("%d steps exceed the maximum of %d, use a larger step", steps, MaxSteps)
+	}
+	if cross := c.CrossChain; cross != nil {
+		if cross.SourceChain == 0 || cross.TargetChain == 0 || cross.SourceChain == cross.TargetChain {
+			return fmt.Errorf("cross-chain comparison needs distinct source and target chains")
+		}
+		if cross.Amount == nil || cross.Amount.Sign() <= 0 {
+			return fmt.Errorf("cross-chain comparison needs a positive amount")
+		}
+	}
+	return nil
+}
+
+// chains returns the chains whose markets are read: ChainIDs or ChainID, and the chains
+// compared
+func (c *Config) chains() []uint64 {
+	chainIDs := c.ChainIDs
+	if len(chainIDs) == 0 {
+		chainIDs = []uint64{c.ChainID}
+	}
+	seen := make(map[uint64]bool)
+	var out []uint64
+	add := func(chainID uint64) {
+		if !seen[chainID] {
+			seen[chainID] = true
+			out = append(out, chainID)
+		}
+	}
+	for _, chainID := range chainIDs {
+		add(chainID)
+	}
+	if c.CrossChain != nil {
+		add(c.CrossChain.SourceChain)
+		add(c.CrossChain.TargetChain)
+	}
+	return out
+}
+
+// MarketRow is a market read at a step, as yield_monitoring reports it. Rates are annual
+// percentages and amounts whole USDC. Block is the block of the stepped chain and
+// ChainBlock the block the market was read at.
+type MarketRow struct {
+	Block       uint64  `parquet:"block"`
+	Time        int64   `parquet:"time"`
+	ChainID     uint64  `parquet:"chain_id"`
+	ChainBlock  uint64  `parquet:"chain_block"`
+	Protocol    string  `parquet:"protocol"`
+	SupplyRate  float64 `parquet:"supply_rate"`
+	Utilization float64 `parquet:"utilization"`
+	TotalSupply float64 `parquet:"total_supply"`
+	TotalBorrow float64 `parquet:"total_borrow"`
+}
+
+// CrossChainRow is the comparison of a step, as cross_chain_yield_check reports it
+// before the operator's policy, bridge and sequencer checks: the best source rate against
+// the target market with the best rate once the amount is deposited
+type CrossChainRow struct {
+	Block                   uint64  `parquet:"block"`
+	Time                    int64   `parquet:"time"`
+	SourceChain             uint64  `parquet:"source_chain"`
+	SourceBlock             uint64  `parquet:"source_block"`
+	SourceProtocol          string  `parquet:"source_protocol"`
+	SourceRate              float64 `parquet:"source_rate"`
+	TargetChain             uint64  `parquet:"target_chain"`
+	TargetBlock             uint64  `parquet:"target_block"`
+	TargetProtocol          string  `parquet:"target_protocol"`
+	TargetRate              float64 `parquet:"target_rate"`
+	TargetProjectedRate     float64 `parquet:"target_projected_rate"`
+	ImprovementBps          float64 `parquet:"improvement_bps"`
+	ProjectedImprovementBps float64 `parquet:"projected_improvement_bps"`
+}
+
+// Failure is a market that could not be read at a step
+type Failure struct {
+	Block    uint64
+	ChainID  uint64
+	Protocol string
+	Err      error
+}
+
+// Report is what a replay read
+type Report struct {
+	Steps      int
+	Markets    []MarketRow
+	CrossChain []CrossChainRow
+	Failures   []Failure
+}
+
+// Replayer reads markets at past blocks and records them in the yield history
+type Replayer struct {
+	chains   *chain.Manager
+	registry *adapters.Registry
+	history  *store.SeriesStore
+	logger   *zap.Logger
+}
+
+// New creates a replayer reading the markets of registry through chains and recording
+// them in history
+func New(chains *chain.Manager, registry *adapters.Registry, history *store.SeriesStore, logger *zap.Logger) *Replayer {
+	return &Replayer{
+		chains:   chains,
+		registry: registry,
+		history:  history,
+		logger:   logger,
+	}
+}
+
+// cursor finds the blocks of a chain, searching forward from the block of the last step
+type cursor struct {
+	client chain.Client
+	head   uint64
+	last   uint64
+}
+
+// Run steps through the blocks of cfg, reading every market of its chains at each step
+// and recording their supply rate and utilization at the time of the step, as the
+// collector does. Markets failing to read are reported and skipped, except for state the
+// node no longer keeps, which fails the replay.
+func (r *Replayer) Run(ctx context.Context, cfg Config) (*Report, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	cursors := make(map[uint64]*cursor)
+	for _, chainID := range append([]uint64{cfg.ChainID}, cfg.chains()...) {
+		if cursors[chainID] != nil {
+			continue
+		}
+		client, err := r.chains.Client(chainID)
+		if err != nil {
+			return nil, err
+		}
+		head, err := client.BlockNumber(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the head of chain %d: %w", chainID, err)
+		}
+		cursors[chainID] = &cursor{client: client, head: head}
+	}
+	if head := cursors[cfg.ChainID].head; cfg.To > head {
+		return nil, fmt.Errorf("to block %d is past the head of chain %d at %d", cfg.To, cfg.ChainID, head)
+	}
+
+	report := &Report{Markets: []MarketRow{}, CrossChain: []CrossChainRow{}}
+	for block := cfg.From; block <= cfg.To; block += cfg.Step {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		header, err := cursors[cfg.ChainID].client.HeaderByNumber(ctx, new(big.Int).SetUint64(block))
+		if err != nil {
+			return report, fmt.Errorf("failed to read block %d of chain %d: %w", block, cfg.ChainID, err)
+		}
+		at := time.Unix(int64(header.Time), 0).UTC()
+		if err := r.step(ctx, cfg, block, at, cursors, report); err != nil {
+			return report, err
+		}
+		report.Steps++
+		if cfg.To-block < cfg.Step {
+			break
+		}
+	}
+	return report, nil
+}
+
+// step reads every market at the block of each chain produced by at
+func (r *Replayer) step(ctx context.Context, cfg Config, block uint64, at time.Time, cursors map[uint64]*cursor, report *Report) error {
+	blocks := make(map[uint64]uint64)
+	for _, chainID := range cfg.chains() {
+		number := block
+		if chainID != cfg.ChainID {
+			found, err := blockAt(ctx, cursors[chainID], at)
+			if err != nil {
+				return fmt.Errorf("chain %d: %w", chainID, err)
+			}
+			number = found
+		}
+		blocks[chainID] = number
+	}
+
+	states := make(map[uint64][]*adapters.MarketState)
+	for _, protocol := range r.registry.Protocols() {
+		adapter, err := r.registry.Get(protocol)
+		if err != nil {
+			return err
+		}
+		for _, chainID := range adapter.ChainIDs() {
+			number, ok := blocks[chainID]
+			if !ok {
+				continue
+			}
+			state, err := r.sample(chain.WithBlockTime(ctx, number, at), adapter, chainID, at)
+			if errors.Is(err, chain.ErrHistoricalState) {
+				return fmt.Errorf("%s on chain %d at block %d: %w", protocol, chainID, number, err)
+			}
+			if err != nil {
+				r.logger.Sugar().Warnw("Failed to replay market", "protocol", protocol, "chainId", chainID, "block", number, "error", err)
+				report.Failures = append(report.Failures, Failure{Block: block, ChainID: chainID, Protocol: protocol, Err: err})
+				continue
+			}
+			states[chainID] = append(states[chainID], state)
+			report.Markets = append(report.Markets, MarketRow{
+				Block:       block,
+				Time:        at.Unix(),
+				ChainID:     chainID,
+				ChainBlock:  number,
+				Protocol:    state.Protocol,
+				SupplyRate:  state.Pool.SupplyRate() * 100,
+				Utilization: state.Pool.Utilization(),
+				TotalSupply: usdc(state.Pool.TotalSupply),
+				TotalBorrow: usdc(state.Pool.TotalBorrow),
+			})
+		}
+	}
+
+	if cross := cfg.CrossChain; cross != nil {
+		if row := compare(cross, states); row != nil {
+			row.Block, row.Time = block, at.Unix()
+			row.SourceBlock, row.TargetBlock = blocks[cross.SourceChain], blocks[cross.TargetChain]
+			report.CrossChain = append(report.CrossChain, *row)
+		}
+	}
+	return nil
+}
+
+// sample reads the market of adapter on chainID at the block ctx is pinned to and records
+// it in the history at the time of the block. Share prices are recorded first, as vault
+// rates are measured from them.
+func (r *Replayer) sample(ctx context.Context, adapter adapters.YieldAdapter, chainID uint64, at time.Time) (*adapters.MarketState, error) {
+	if pricer, ok := adapter.(adapters.SharePricer); ok {
+		price, err := pricer.SharePrice(ctx, chainID)
+		if err != nil {
+			return nil, err
+		}
+		if err := r.history.Append(ctx, store.SharePriceSeries(adapter.Protocol(), chainID), store.SeriesPoint{Time: at, Value: price}); err != nil {
+			return nil, err
+		}
+	}
+	state, err := adapter.MarketState(ctx, chainID)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.history.Append(ctx, store.SupplyRateSeries(state.Protocol, chainID), store.SeriesPoint{Time: at, Value: state.Pool.SupplyRate()}); err != nil {
+		return nil, err
+	}
+	if err := r.history.Append(ctx, store.UtilizationSeries(state.Protocol, chainID), store.SeriesPoint{Time: at, Value: state.Pool.Utilization()}); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// compare returns the comparison of the markets read on the source and target chains of
+// cross, nil when either has none
+func compare(cross *CrossChain, states map[uint64][]*adapters.MarketState) *CrossChainRow {
+	var source, target *adapters.MarketState
+	var sourceRate, targetProjected float64
+	for _, state := range states[cross.SourceChain] {
+		if rate := state.Pool.SupplyRate(); source == nil || rate > sourceRate {
+			source, sourceRate = state, rate
+		}
+	}
+	for _, state := range states[cross.TargetChain] {
+		if projected := state.Pool.DepositImpact(cross.Amount).SupplyRate; target == nil || projected > targetProjected {
+			target, targetProjected = state, projected
+		}
+	}
+	if source == nil || target == nil {
+		return nil
+	}
+	// rates are fractions here, so a whole rate is 10000 bps
+	targetRate := target.Pool.SupplyRate()
+	return &CrossChainRow{
+		SourceChain:             cross.SourceChain,
+		SourceProtocol:          source.Protocol,
+		SourceRate:              sourceRate * 100,
+		TargetChain:             cross.TargetChain,
+		TargetProtocol:          target.Protocol,
+		TargetRate:              targetRate * 100,
+		TargetProjectedRate:     targetProjected * 100,
+		ImprovementBps:          (targetRate - sourceRate) * 10_000,
+		ProjectedImprovementBps: (targetProjected - sourceRate) * 10_000,
+	}
+}
+
+// blockAt returns the last block of c produced by at, searching from the block found for
+// the previous step
+func blockAt(ctx context.Context, c *cursor, at time.Time) (uint64, error) {
+	produced := func(number uint64) (bool, error) {
+		header, err := c.client.HeaderByNumber(ctx, new(big.Int).SetUint64(number))
+		if err != nil {
+			return false, fmt.Errorf("failed to read block %d: %w", number, err)
+		}
+		return int64(header.Time) <= at.Unix(), nil
+	}
+	lo, hi := c.last, c.head
+	ok, err := produced(lo)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, fmt.Errorf("no block was produced by %s", at.Format(time.RFC3339))
+	}
+	for lo < hi {
+		mid := lo + (hi-lo+1)/2
+		ok, err := produced(mid)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	c.last = lo
+	return lo, nil
+}
+
+// usdc converts USDC base units into whole USDC
+func usdc(baseUnits *big.Int) float64 {
+	if baseUnits == nil {
+		return 0
+	}
+	amount, _ := new(big.Rat).SetFrac(baseUnits, big.NewInt(1_000_000)).Float64()
+	return amount
+}
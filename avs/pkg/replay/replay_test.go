@@ -0,0 +1,199 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"math"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/chain/chaintest"
+	"github.com/najnomics/crosscow-avs/pkg/export"
+	"github.com/najnomics/crosscow-avs/pkg/irm"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+	"github.com/parquet-go/parquet-go"
+	"go.uber.org/zap"
+)
+
+// headTime is the timestamp of the head of every test chain
+const headTime = 1_700_000_000
+
+// blockAdapter supplies at a rate of one basis point per block number it is read at, and
+// reads a storage slot so reads of pruned blocks fail
+type blockAdapter struct {
+	protocol string
+	chains   *chain.Manager
+	chainIDs []uint64
+}
+
+func (a *blockAdapter) Protocol() string   { return a.protocol }
+func (a *blockAdapter) ChainIDs() []uint64 { return a.chainIDs }
+
+func (a *blockAdapter) MarketState(ctx context.Context, chainID uint64) (*adapters.MarketState, error) {
+	client, err := a.chains.Client(chainID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := chain.StorageAt(ctx, client, common.Address{}, common.Hash{}); err != nil {
+		return nil, err
+	}
+	block := chain.BlockOf(ctx)
+	if block == nil {
+		return nil, errors.New("read not pinned to a block")
+	}
+	return &adapters.MarketState{
+		Protocol: a.protocol,
+		ChainID:  chainID,
+		Pool: irm.Pool{
+			TotalSupply: big.NewInt(1_000_000_000_000),
+			TotalBorrow: new(big.Int),
+			Model:       &irm.FixedModel{Rate: float64(block.Uint64()) / 10_000},
+		},
+	}, nil
+}
+
+// testChains returns a manager of mainnet, producing a block every 12s up to block 200,
+// and Base, producing one every 2s up to block 1000
+func testChains() (*chain.Manager, *chaintest.Contracts) {
+	mainnet := chaintest.NewContracts(1)
+	mainnet.SetHead(200, headTime)
+	mainnet.SetBlockInterval(12)
+	base := chaintest.NewContracts(8453)
+	base.SetHead(1000, headTime)
+	base.SetBlockInterval(2)
+
+	chains := chain.NewManager()
+	chains.Register(1, "ethereum", mainnet)
+	chains.Register(8453, "base", base)
+	return chains, mainnet
+}
+
+func Test_RunReadsMarketsAtTheBlocksOfEachStep(t *testing.T) {
+	chains, _ := testChains()
+	registry := adapters.NewRegistry(&blockAdapter{protocol: "aave_v3", chains: chains, chainIDs: []uint64{1, 8453}})
+	history := store.NewSeriesStore(store.NewMemoryKV())
+	replayer := New(chains, registry, history, zap.NewNop())
+
+	report, err := replayer.Run(context.Background(), Config{
+		ChainID: 1,
+		From:    100,
+		To:      200,
+		Step:    50,
+		CrossChain: &CrossChain{
+			SourceChain: 1,
+			TargetChain: 8453,
+			Amount:      big.NewInt(1_000_000_000),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if report.Steps != 3 || len(report.Markets) != 6 || len(report.Failures) != 0 {
+		t.Fatalf("Unexpected report: %+v", report)
+	}
+
+	// Base is read at its last block produced by the time of each mainnet block
+	want := map[uint64]uint64{100: 400, 150: 700, 200: 1000}
+	for _, row := range report.Markets {
+		if row.ChainID == 8453 && row.ChainBlock != want[row.Block] {
+			t.Errorf("Expected block %d of base at mainnet block %d, got %d", want[row.Block], row.Block, row.ChainBlock)
+		}
+		if math.Abs(row.SupplyRate-float64(row.ChainBlock)/100) > 1e-9 {
+			t.Errorf("Expected the rate of block %d, got %v", row.ChainBlock, row.SupplyRate)
+		}
+	}
+
+	if len(report.CrossChain) != 3 {
+		t.Fatalf("Expected a comparison per step, got %+v", report.CrossChain)
+	}
+	last := report.CrossChain[2]
+	if last.SourceBlock != 200 || last.TargetBlock != 1000 || math.Abs(last.ImprovementBps-800) > 1e-6 {
+		t.Errorf("Unexpected comparison %+v", last)
+	}
+
+	points, err := history.Range(context.Background(), store.SupplyRateSeries("aave_v3", 8453), time.Unix(0, 0), time.Unix(headTime+1, 0))
+	if err != nil || len(points) != 3 {
+		t.Fatalf("Expected a point per step, got %+v: %v", points, err)
+	}
+	if !points[0].Time.Equal(time.Unix(headTime-1200, 0)) || points[0].Value != 0.04 {
+		t.Errorf("Expected the first point at the time of block 100, got %+v", points[0])
+	}
+}
+
+func Test_RunFailsWithoutHistoricalState(t *testing.T) {
+	chains, mainnet := testChains()
+	mainnet.PruneBefore(150)
+	registry := adapters.NewRegistry(&blockAdapter{protocol: "aave_v3", chains: chains, chainIDs: []uint64{1}})
+	replayer := New(chains, registry, store.NewSeriesStore(store.NewMemoryKV()), zap.NewNop())
+
+	_, err := replayer.Run(context.Background(), Config{ChainID: 1, From: 100, To: 200, Step: 50})
+	if !errors.Is(err, chain.ErrHistoricalState) {
+		t.Errorf("Expected the pruned state to fail the replay, got %v", err)
+	}
+}
+
+func Test_ConfigValidate(t *testing.T) {
+	invalid := map[string]Config{
+		"no chain":    {From: 1, To: 2, Step: 1},
+		"no step":     {ChainID: 1, From: 1, To: 2},
+		"reversed":    {ChainID: 1, From: 3, To: 2, Step: 1},
+		"too long":    {ChainID: 1, From: 0, To: MaxSteps, Step: 1},
+		"same chains": {ChainID: 1, From: 1, To: 2, Step: 1, CrossChain: &CrossChain{SourceChain: 1, TargetChain: 1, Amount: big.NewInt(1)}},
+		"no amount":   {ChainID: 1, From: 1, To: 2, Step: 1, CrossChain: &CrossChain{SourceChain: 1, TargetChain: 8453}},
+		"zero amount": {ChainID: 1, From: 1, To: 2, Step: 1, CrossChain: &CrossChain{SourceChain: 1, TargetChain: 8453, Amount: new(big.Int)}},
+		"no target":   {ChainID: 1, From: 1, To: 2, Step: 1, CrossChain: &CrossChain{SourceChain: 1, Amount: big.NewInt(1)}},
+	}
+	for name, cfg := range invalid {
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	valid := Config{ChainID: 1, From: 5, To: 5, Step: 10}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func Test_Export(t *testing.T) {
+	report := &Report{
+		Markets:    []MarketRow{{Block: 100, Time: headTime, ChainID: 1, ChainBlock: 100, Protocol: "aave_v3", SupplyRate: 4.5, Utilization: 0.8, TotalSupply: 1000}},
+		CrossChain: []CrossChainRow{{Block: 100, SourceChain: 1, TargetChain: 8453, ImprovementBps: 12.5}},
+	}
+	dir := t.TempDir()
+
+	written, err := report.Export(dir, export.FormatCSV)
+	if err != nil || len(written) != 2 {
+		t.Fatalf("Export failed: %v %v", written, err)
+	}
+	var buf bytes.Buffer
+	if err := export.Write(&buf, export.FormatCSV, report.Markets); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil || len(records) != 2 || records[1][4] != "aave_v3" || records[1][5] != "4.5" {
+		t.Errorf("Unexpected CSV %v: %v", records, err)
+	}
+
+	if _, err := report.Export(dir, export.FormatParquet); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	markets, err := parquet.ReadFile[MarketRow](filepath.Join(dir, "markets.parquet"))
+	if err != nil || len(markets) != 1 || markets[0] != report.Markets[0] {
+		t.Errorf("Unexpected Parquet rows %+v: %v", markets, err)
+	}
+	crossChain, err := parquet.ReadFile[CrossChainRow](filepath.Join(dir, "cross_chain.parquet"))
+	if err != nil || len(crossChain) != 1 || crossChain[0].ImprovementBps != 12.5 {
+		t.Errorf("Unexpected Parquet rows %+v: %v", crossChain, err)
+	}
+
+	if _, err := report.Export(dir, "xlsx"); err == nil {
+		t.Errorf("Expected an unknown format to be refused")
+	}
+}
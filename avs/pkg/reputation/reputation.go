@@ -0,0 +1,370 @@
+// Package reputation reports how well this operator serves its tasks, for transparency
+// tooling: how many succeed, how long they take to answer and, when the task mailbox the
+// AVS service manager settles results in is configured, how often its results disagree
+// with the ones the quorum settled on. Reports are computed from the task store, so they
+// cover every task the performer recorded whether it restarted or not.
+package reputation
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+)
+
+const prefixVerdict = "reputation:verdict:"
+
+// mailboxABI reads the status and settled result of a task from Hourglass' TaskMailbox
+var mailboxABI = chain.MustParseABI(`[
+	{"name":"getTaskStatus","type":"function","stateMutability":"view","inputs":[{"name":"taskHash","type":"bytes32"}],"outputs":[{"name":"","type":"uint8"}]},
+	{"name":"getTaskResult","type":"function","stateMutability":"view","inputs":[{"name":"taskHash","type":"bytes32"}],"outputs":[{"name":"","type":"bytes"}]}
+]`)
+
+// Status is the status of a task in the mailbox
+type Status uint8
+
+const (
+	StatusNone Status = iota
+	StatusCreated
+	StatusVerified
+	StatusExpired
+)
+
+// Mailbox reads the results tasks settled on
+type Mailbox interface {
+	// Settled returns the status of the task and, once verified, the result the quorum
+	// settled on
+	Settled(ctx context.Context, taskHash common.Hash) (Status, []byte, error)
+}
+
+// Config configures comparing results with the ones settled in the task mailbox
+type Config struct {
+	Enabled bool   `yaml:"enabled"`
+	ChainID uint64 `yaml:"chainId"`
+
+	// TaskMailbox is the address of the mailbox the service manager's tasks are settled in
+	TaskMailbox string `yaml:"taskMailbox"`
+
+	// MaxReads bounds the mailbox reads of a report. Settled verdicts are kept, so later
+	// reports read the tasks left out.
+	MaxReads int `yaml:"maxReads"`
+}
+
+// DefaultConfig reads at most 100 tasks a report once enabled
+func DefaultConfig() Config {
+	return Config{MaxReads: 100}
+}
+
+// Validate checks the config for values the mailbox cannot be read with
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.ChainID == 0 {
+		return fmt.Errorf("chainId is required")
+	}
+	if !common.IsHexAddress(c.TaskMailbox) {
+		return fmt.Errorf("invalid taskMailbox address %q", c.TaskMailbox)
+	}
+	if c.MaxReads <= 0 {
+		return fmt.Errorf("maxReads must be positive")
+	}
+	return nil
+}
+
+// Latency are percentiles of how long tasks took from being received to being answered,
+// in milliseconds
+type Latency struct {
+	P50Ms int64 `json:"p50Ms"`
+	P90Ms int64 `json:"p90Ms"`
+	P99Ms int64 `json:"p99Ms"`
+	MaxMs int64 `json:"maxMs"`
+}
+
+// Tally counts the tasks of a kind answered in a report's window
+type Tally struct {
+	TaskType  string `json:"taskType,omitempty"`
+	Tasks     int    `json:"tasks"`
+	Completed int    `json:"completed"`
+	Failed    int    `json:"failed"`
+
+	// SuccessRate is the share of tasks completed, from 0 to 1, and 1 without tasks
+	SuccessRate float64 `json:"successRate"`
+	Latency     Latency `json:"latency"`
+}
+
+// Agreement compares the completed tasks of a report with the results their quorum
+// settled on. Pending tasks are not settled yet, expired ones never will be, and
+// unchecked ones were not read, past the reads of a report or failing them.
+type Agreement struct {
+	Compared  int `json:"compared"`
+	Disagreed int `json:"disagreed"`
+	Pending   int `json:"pending"`
+	Expired   int `json:"expired"`
+	Unchecked int `json:"unchecked"`
+
+	// DisagreementRate is the share of compared tasks that disagreed, from 0 to 1
+	DisagreementRate float64 `json:"disagreementRate"`
+	// ReadErrors counts the tasks whose mailbox reads failed
+	ReadErrors int `json:"readErrors"`
+}
+
+// Report is the reputation of the operator over the tasks answered from From to To.
+// Agreement is nil without a mailbox.
+type Report struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+	Tally
+	TaskTypes []Tally    `json:"taskTypes"`
+	Agreement *Agreement `json:"agreement,omitempty"`
+}
+
+// Reporter reports the reputation of the operator
+type Reporter struct {
+	kv       store.KV
+	mailbox  Mailbox
+	maxReads int
+}
+
+// New creates a reporter comparing results with the ones settled in mailbox, keeping
+// verdicts in kv. A nil reporter reports without comparing results.
+func New(kv store.KV, mailbox Mailbox, maxReads int) *Reporter {
+	return &Reporter{kv: kv, mailbox: mailbox, maxReads: maxReads}
+}
+
+// NewFromConfig creates a reporter reading the mailbox of cfg, nil when disabled
+func NewFromConfig(cfg Config, kv store.KV, chains *chain.Manager) *Reporter {
+	if !cfg.Enabled {
+		return nil
+	}
+	return New(kv, NewChainMailbox(chains, cfg.ChainID, common.HexToAddress(cfg.TaskMailbox)), cfg.MaxReads)
+}
+
+// Report reports on the records answered from from to to. Tasks still being handled
+// are left out.
+func (r *Reporter) Report(ctx context.Context, records []*store.TaskRecord, from, to time.Time) (*Report, error) {
+	var answered []*store.TaskRecord
+	for _, record := range records {
+		if record.CompletedAt == nil || record.CompletedAt.Before(from) || record.CompletedAt.After(to) {
+			continue
+		}
+		answered = append(answered, record)
+	}
+	sort.Slice(answered, func(i, j int) bool { return answered[i].CompletedAt.Before(*answered[j].CompletedAt) })
+
+	byType := map[string][]*store.TaskRecord{}
+	for _, record := range answered {
+		byType[record.TaskType] = append(byType[record.TaskType], record)
+	}
+	report := &Report{From: from, To: to, Tally: tally(answered), TaskTypes: make([]Tally, 0, len(byType))}
+	for taskType, records := range byType {
+		t := tally(records)
+		t.TaskType = taskType
+		report.TaskTypes = append(report.TaskTypes, t)
+	}
+	sort.Slice(report.TaskTypes, func(i, j int) bool { return report.TaskTypes[i].TaskType < report.TaskTypes[j].TaskType })
+
+	if r != nil {
+		agreement, err := r.compare(ctx, answered)
+		if err != nil {
+			return nil, err
+		}
+		report.Agreement = agreement
+	}
+	return report, nil
+}
+
+func tally(records []*store.TaskRecord) Tally {
+	t := Tally{Tasks: len(records), SuccessRate: 1}
+	var latencies []time.Duration
+	for _, record := range records {
+		if record.Status != store.TaskStatusCompleted {
+			t.Failed++
+			continue
+		}
+		t.Completed++
+		latencies = append(latencies, record.CompletedAt.Sub(record.ReceivedAt))
+	}
+	if t.Tasks > 0 {
+		t.SuccessRate = float64(t.Completed) / float64(t.Tasks)
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	t.Latency = Latency{
+		P50Ms: percentile(latencies, 0.5).Milliseconds(),
+		P90Ms: percentile(latencies, 0.9).Milliseconds(),
+		P99Ms: percentile(latencies, 0.99).Milliseconds(),
+		MaxMs: percentile(latencies, 1).Milliseconds(),
+	}
+	return t
+}
+
+// percentile is the nearest rank percentile p of sorted, zero when empty
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p * float64(len(sorted))))
+	return sorted[max(rank, 1)-1]
+}
+
+// verdict is what the mailbox settled for a task, kept once final
+type verdict struct {
+	Status   Status `json:"status"`
+	Disagree bool   `json:"disagree,omitempty"`
+}
+
+// compare compares the completed records that went through the mailbox with the results
+// settled there, newest first so the reads of a report go to the freshest tasks
+func (r *Reporter) compare(ctx context.Context, records []*store.TaskRecord) (*Agreement, error) {
+	agreement := &Agreement{}
+	reads := 0
+	for i := len(records) - 1; i >= 0; i-- {
+		record := records[i]
+		taskHash, ok := TaskHash(record.TaskID)
+		if !ok || record.Status != store.TaskStatusCompleted {
+			continue
+		}
+		v, found, err := r.loadVerdict(ctx, taskHash)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			if reads >= r.maxReads {
+				agreement.Unchecked++
+				continue
+			}
+			reads++
+			status, settled, err := r.mailbox.Settled(ctx, taskHash)
+			if err != nil {
+				agreement.Unchecked++
+				agreement.ReadErrors++
+				continue
+			}
+			v = verdict{Status: status, Disagree: status == StatusVerified && !Matches(record.Result, settled)}
+			if status == StatusVerified || status == StatusExpired {
+				if err := r.storeVerdict(ctx, taskHash, v); err != nil {
+					return nil, err
+				}
+			}
+		}
+		switch v.Status {
+		case StatusVerified:
+			agreement.Compared++
+			if v.Disagree {
+				agreement.Disagreed++
+			}
+		case StatusExpired:
+			agreement.Expired++
+		default:
+			agreement.Pending++
+		}
+	}
+	if agreement.Compared > 0 {
+		agreement.DisagreementRate = float64(agreement.Disagreed) / float64(agreement.Compared)
+	}
+	return agreement, nil
+}
+
+func (r *Reporter) loadVerdict(ctx context.Context, taskHash common.Hash) (verdict, bool, error) {
+	var v verdict
+	encoded, err := r.kv.Get(ctx, []byte(prefixVerdict+taskHash.Hex()))
+	if errors.Is(err, store.ErrNotFound) {
+		return v, false, nil
+	}
+	if err != nil {
+		return v, false, fmt.Errorf("failed to load verdict: %w", err)
+	}
+	if err := json.Unmarshal(encoded, &v); err != nil {
+		return v, false, fmt.Errorf("failed to decode verdict: %w", err)
+	}
+	return v, true, nil
+}
+
+func (r *Reporter) storeVerdict(ctx context.Context, taskHash common.Hash, v verdict) error {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := r.kv.Set(ctx, []byte(prefixVerdict+taskHash.Hex()), encoded); err != nil {
+		return fmt.Errorf("failed to store verdict: %w", err)
+	}
+	return nil
+}
+
+// TaskHash returns the mailbox hash a task ID stands for: the 32 bytes aggregators send
+// as task IDs, or their hex encoding. Tasks of other IDs, such as those of the task API,
+// did not go through the mailbox.
+func TaskHash(taskID string) (common.Hash, bool) {
+	if len(taskID) == common.HashLength {
+		return common.BytesToHash([]byte(taskID)), true
+	}
+	if raw, ok := strings.CutPrefix(taskID, "0x"); ok && len(raw) == 2*common.HashLength {
+		if decoded, err := hex.DecodeString(raw); err == nil {
+			return common.BytesToHash(decoded), true
+		}
+	}
+	return common.Hash{}, false
+}
+
+// Matches reports whether own is the result settled. JSON results are compared by the
+// result they carry, leaving out the attestation each operator signs on its own.
+func Matches(own, settled []byte) bool {
+	var a, b struct {
+		Result json.RawMessage `json:"result"`
+	}
+	if json.Unmarshal(own, &a) == nil && json.Unmarshal(settled, &b) == nil && a.Result != nil && b.Result != nil {
+		return bytes.Equal(a.Result, b.Result)
+	}
+	return bytes.Equal(own, settled)
+}
+
+// ChainMailbox reads a TaskMailbox contract
+type ChainMailbox struct {
+	chains  *chain.Manager
+	chainID uint64
+	address common.Address
+}
+
+// NewChainMailbox reads the mailbox at address on chainID
+func NewChainMailbox(chains *chain.Manager, chainID uint64, address common.Address) *ChainMailbox {
+	return &ChainMailbox{chains: chains, chainID: chainID, address: address}
+}
+
+// Settled implements Mailbox
+func (m *ChainMailbox) Settled(ctx context.Context, taskHash common.Hash) (Status, []byte, error) {
+	client, err := m.chains.Client(m.chainID)
+	if err != nil {
+		return StatusNone, nil, err
+	}
+	values, err := chain.CallView(ctx, client, m.address, mailboxABI, "getTaskStatus", taskHash)
+	if err != nil {
+		return StatusNone, nil, fmt.Errorf("failed to read task status: %w", err)
+	}
+	raw, ok := values[0].(uint8)
+	if !ok {
+		return StatusNone, nil, fmt.Errorf("unexpected getTaskStatus output type %T", values[0])
+	}
+	status := Status(raw)
+	if status != StatusVerified {
+		return status, nil, nil
+	}
+	values, err = chain.CallView(ctx, client, m.address, mailboxABI, "getTaskResult", taskHash)
+	if err != nil {
+		return status, nil, fmt.Errorf("failed to read task result: %w", err)
+	}
+	result, ok := values[0].([]byte)
+	if !ok {
+		return status, nil, fmt.Errorf("unexpected getTaskResult output type %T", values[0])
+	}
+	return status, result, nil
+}
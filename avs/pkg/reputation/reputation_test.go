@@ -0,0 +1,178 @@
+package reputation
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/chain/chaintest"
+	"github.com/najnomics/crosscow-avs/pkg/store"
+)
+
+// fakeMailbox settles tasks as set, and counts its reads
+type fakeMailbox struct {
+	statuses map[common.Hash]Status
+	results  map[common.Hash][]byte
+	failing  map[common.Hash]bool
+	reads    int
+}
+
+func (m *fakeMailbox) Settled(ctx context.Context, taskHash common.Hash) (Status, []byte, error) {
+	m.reads++
+	if m.failing[taskHash] {
+		return StatusNone, nil, errors.New("rpc unavailable")
+	}
+	return m.statuses[taskHash], m.results[taskHash], nil
+}
+
+func hashID(b byte) string {
+	return string(common.BytesToHash([]byte{b}).Bytes())
+}
+
+func record(taskID, taskType string, status store.TaskStatus, received time.Time, latency time.Duration, result string) *store.TaskRecord {
+	completed := received.Add(latency)
+	return &store.TaskRecord{TaskID: taskID, TaskType: taskType, Status: status, Result: []byte(result), ReceivedAt: received, CompletedAt: &completed}
+}
+
+func Test_ReportTalliesTasks(t *testing.T) {
+	start := time.Unix(1_800_000_000, 0)
+	records := []*store.TaskRecord{
+		record("a", "yield_monitoring", store.TaskStatusCompleted, start, 100*time.Millisecond, "{}"),
+		record("b", "yield_monitoring", store.TaskStatusCompleted, start, 300*time.Millisecond, "{}"),
+		record("c", "yield_monitoring", store.TaskStatusFailed, start, time.Second, ""),
+		record("d", "risk_assessment", store.TaskStatusCompleted, start, 2*time.Second, "{}"),
+		// answered before the window, and still being handled
+		record("e", "risk_assessment", store.TaskStatusFailed, start.Add(-time.Hour), 0, ""),
+		{TaskID: "f", TaskType: "risk_assessment", Status: store.TaskStatusProcessing, ReceivedAt: start},
+	}
+
+	var r *Reporter
+	report, err := r.Report(context.Background(), records, start, start.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	if report.Tasks != 4 || report.Failed != 1 || report.SuccessRate != 0.75 || report.Agreement != nil {
+		t.Errorf("Expected three of four tasks to succeed, got %+v", report.Tally)
+	}
+	if l := report.Latency; l.P50Ms != 300 || l.P90Ms != 2000 || l.MaxMs != 2000 {
+		t.Errorf("Unexpected latency percentiles %+v", l)
+	}
+	if len(report.TaskTypes) != 2 || report.TaskTypes[0].TaskType != "risk_assessment" || report.TaskTypes[1].Tasks != 3 || report.TaskTypes[1].Latency.P50Ms != 100 {
+		t.Errorf("Unexpected tallies by task type %+v", report.TaskTypes)
+	}
+
+	empty, err := r.Report(context.Background(), nil, start, start.Add(time.Hour))
+	if err != nil || empty.Tasks != 0 || empty.SuccessRate != 1 || empty.TaskTypes == nil {
+		t.Errorf("Expected an empty report, got %+v (%v)", empty, err)
+	}
+}
+
+func Test_ReportComparesSettledResults(t *testing.T) {
+	start := time.Unix(1_800_000_000, 0)
+	agreed, disagreed, attested, pending, expired, failing := hashID(1), hashID(2), hashID(3), hashID(4), hashID(5), hashID(6)
+	hash := func(id string) common.Hash { h, _ := TaskHash(id); return h }
+	mailbox := &fakeMailbox{
+		statuses: map[common.Hash]Status{
+			hash(agreed): StatusVerified, hash(disagreed): StatusVerified, hash(attested): StatusVerified,
+			hash(pending): StatusCreated, hash(expired): StatusExpired,
+		},
+		results: map[common.Hash][]byte{
+			hash(agreed):    []byte(`{"result":{"rate":"3.84"}}`),
+			hash(disagreed): []byte(`{"result":{"rate":"4.10"}}`),
+			hash(attested):  []byte(`{"attestation":{"signature":"0x02"},"result":{"rate":"3.84"}}`),
+		},
+		failing: map[common.Hash]bool{hash(failing): true},
+	}
+	own := `{"result":{"rate":"3.84"}}`
+	records := []*store.TaskRecord{
+		record(agreed, "yield_monitoring", store.TaskStatusCompleted, start, time.Second, own),
+		record(disagreed, "yield_monitoring", store.TaskStatusCompleted, start, time.Second, own),
+		record(attested, "yield_monitoring", store.TaskStatusCompleted, start, time.Second, `{"attestation":{"signature":"0x01"},"result":{"rate":"3.84"}}`),
+		record(pending, "yield_monitoring", store.TaskStatusCompleted, start, time.Second, own),
+		record(expired, "yield_monitoring", store.TaskStatusCompleted, start, time.Second, own),
+		record(failing, "yield_monitoring", store.TaskStatusCompleted, start, time.Second, own),
+		// task API tasks do not go through the mailbox
+		record("api-task", "yield_monitoring", store.TaskStatusCompleted, start, time.Second, own),
+	}
+
+	r := New(store.NewMemoryKV(), mailbox, 10)
+	report, err := r.Report(context.Background(), records, start, start.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	a := report.Agreement
+	if a == nil || a.Compared != 3 || a.Disagreed != 1 || a.Pending != 1 || a.Expired != 1 || a.Unchecked != 1 || a.ReadErrors != 1 {
+		t.Fatalf("Unexpected agreement %+v", a)
+	}
+	if a.DisagreementRate < 0.33 || a.DisagreementRate > 0.34 {
+		t.Errorf("Expected one of three compared tasks to disagree, got %v", a.DisagreementRate)
+	}
+
+	// settled verdicts are kept, pending and failed tasks are read again
+	mailbox.reads = 0
+	if _, err := r.Report(context.Background(), records, start, start.Add(time.Hour)); err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	if mailbox.reads != 2 {
+		t.Errorf("Expected the pending and failing tasks alone to be read again, got %d reads", mailbox.reads)
+	}
+
+	// reads past the bound are left unchecked
+	mailbox.reads = 0
+	limited := New(store.NewMemoryKV(), mailbox, 2)
+	report, err = limited.Report(context.Background(), records, start, start.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	if a := report.Agreement; mailbox.reads != 2 || a.Unchecked-a.ReadErrors != 4 {
+		t.Errorf("Expected two reads and four tasks left unchecked, got %d reads and %+v", mailbox.reads, a)
+	}
+}
+
+func Test_ChainMailbox(t *testing.T) {
+	address := common.HexToAddress("0x30")
+	contracts := chaintest.NewContracts(1)
+	contracts.Stub(t, address, mailboxABI, "getTaskStatus", uint8(StatusVerified))
+	contracts.Stub(t, address, mailboxABI, "getTaskResult", []byte(`{"result":{}}`))
+	chains := chain.NewManager()
+	chains.Register(1, "ethereum", contracts)
+
+	status, result, err := NewChainMailbox(chains, 1, address).Settled(context.Background(), common.HexToHash("0x01"))
+	if err != nil || status != StatusVerified || string(result) != `{"result":{}}` {
+		t.Errorf("Expected the settled result, got %d %q (%v)", status, result, err)
+	}
+	if _, _, err := NewChainMailbox(chains, 8453, address).Settled(context.Background(), common.HexToHash("0x01")); err == nil {
+		t.Errorf("Expected a mailbox on an unknown chain to fail")
+	}
+}
+
+func Test_TaskHash(t *testing.T) {
+	raw := common.HexToHash("0xab")
+	if h, ok := TaskHash(string(raw.Bytes())); !ok || h != raw {
+		t.Errorf("Expected raw task IDs to be hashes, got %s", h)
+	}
+	if h, ok := TaskHash(raw.Hex()); !ok || h != raw {
+		t.Errorf("Expected hex task IDs to be hashes, got %s", h)
+	}
+	if _, ok := TaskHash("yield-1"); ok {
+		t.Errorf("Expected other task IDs not to be hashes")
+	}
+}
+
+func Test_ConfigValidate(t *testing.T) {
+	cfg := DefaultConfig()
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected the disabled default to be valid: %v", err)
+	}
+	cfg.Enabled, cfg.ChainID, cfg.TaskMailbox = true, 1, "0x0000000000000000000000000000000000000030"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected the config to be valid: %v", err)
+	}
+	cfg.TaskMailbox = "mailbox"
+	if err := cfg.Validate(); err == nil {
+		t.Errorf("Expected an invalid mailbox address to be rejected")
+	}
+}
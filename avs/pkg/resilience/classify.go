@@ -0,0 +1,144 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Class is how a failed call should be handled
+type Class string
+
+const (
+	// ClassRetryable errors are transient: timeouts, dropped connections, rate limits and
+	// server errors
+	ClassRetryable Class = "retryable"
+	// ClassFatal errors will fail again: reverts, bad requests and cancelled contexts
+	ClassFatal Class = "fatal"
+)
+
+// StatusError is an unexpected HTTP status returned by an endpoint
+type StatusError struct {
+	Endpoint   string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s returned status %d", e.Endpoint, e.StatusCode)
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as fatal regardless of its classification
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// JSON-RPC error codes worth retrying: rate limits (-32005) and internal errors of the
+// node (-32603). -32000 is used for everything from "header not found" on lagging nodes to
+// invalid transactions, so it is classified by message.
+const (
+	rpcCodeLimitExceeded = -32005
+	rpcCodeInternal      = -32603
+	rpcCodeServer        = -32000
+)
+
+// retryableMessages are substrings of errors from endpoints that surface transient
+// failures only as text
+var retryableMessages = []string{
+	"header not found",
+	"timeout",
+	"timed out",
+	"too many requests",
+	"rate limit",
+	"connection reset",
+	"connection refused",
+	"broken pipe",
+	"service unavailable",
+	"bad gateway",
+}
+
+// Classify decides whether a call that failed with err should be retried. Errors caused
+// by ctx being done are fatal, since no retry can succeed.
+func Classify(ctx context.Context, err error) Class {
+	if err == nil {
+		return ClassFatal
+	}
+	var permanent *permanentError
+	if errors.As(err, &permanent) || ctx.Err() != nil || errors.Is(err, context.Canceled) {
+		return ClassFatal
+	}
+	if errors.Is(err, ErrCircuitOpen) {
+		return ClassFatal
+	}
+
+	var status *StatusError
+	if errors.As(err, &status) {
+		return classifyStatus(status.StatusCode)
+	}
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return classifyStatus(httpErr.StatusCode)
+	}
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		switch rpcErr.ErrorCode() {
+		case rpcCodeLimitExceeded, rpcCodeInternal:
+			return ClassRetryable
+		case rpcCodeServer:
+			return classifyMessage(rpcErr.Error())
+		default:
+			// reverts (3), invalid params and unknown methods fail the same way every time
+			return ClassFatal
+		}
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ClassRetryable
+	}
+	if errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) {
+		return ClassRetryable
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return ClassRetryable
+	}
+	return classifyMessage(err.Error())
+}
+
+func classifyStatus(code int) Class {
+	if code == http.StatusTooManyRequests || code == http.StatusRequestTimeout || code >= 500 {
+		return ClassRetryable
+	}
+	return ClassFatal
+}
+
+func classifyMessage(msg string) Class {
+	msg = strings.ToLower(msg)
+	for _, m := range retryableMessages {
+		if strings.Contains(msg, m) {
+			return ClassRetryable
+		}
+	}
+	return ClassFatal
+}
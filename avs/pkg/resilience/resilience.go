@@ -0,0 +1,300 @@
+// Package resilience retries external calls with exponential backoff and jitter, and
+// stops calling endpoints that keep failing with per-endpoint circuit breakers. A single
+// transient RPC, subgraph or API error should not fail a whole task.
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Policy names used by the performer. Settings can be overridden for each of them, and
+// for each protocol adapter by its protocol name.
+const (
+	PolicyRPC      = "rpc"
+	PolicySubgraph = "subgraph"
+	PolicyCircle   = "circle"
+	PolicyAPI      = "api"
+)
+
+// Settings configure retries and circuit breaking of one policy
+type Settings struct {
+	// MaxAttempts is the number of calls made before giving up, including the first
+	MaxAttempts int `yaml:"maxAttempts"`
+
+	// InitialBackoff is the delay before the first retry. Each retry multiplies it by
+	// Multiplier, up to MaxBackoff.
+	InitialBackoff time.Duration `yaml:"initialBackoff"`
+	MaxBackoff     time.Duration `yaml:"maxBackoff"`
+	Multiplier     float64       `yaml:"multiplier"`
+
+	// Jitter randomises each delay by up to this fraction, so operators retrying the same
+	// endpoint do not synchronise
+	Jitter float64 `yaml:"jitter"`
+
+	// BreakerThreshold is the number of consecutive retryable failures that opens the
+	// circuit of an endpoint. Zero disables circuit breaking.
+	BreakerThreshold int `yaml:"breakerThreshold"`
+
+	// BreakerCooldown is how long an open circuit rejects calls before letting a probe through
+	BreakerCooldown time.Duration `yaml:"breakerCooldown"`
+}
+
+// DefaultSettings retry twice within about a second and open the circuit after 5
+// consecutive failures for 30 seconds
+func DefaultSettings() Settings {
+	return Settings{
+		MaxAttempts:      3,
+		InitialBackoff:   200 * time.Millisecond,
+		MaxBackoff:       5 * time.Second,
+		Multiplier:       2,
+		Jitter:           0.2,
+		BreakerThreshold: 5,
+		BreakerCooldown:  30 * time.Second,
+	}
+}
+
+// Validate checks the settings for values a policy cannot run with
+func (s Settings) Validate() error {
+	if s.MaxAttempts < 1 {
+		return fmt.Errorf("maxAttempts must be at least 1")
+	}
+	if s.MaxAttempts > 1 && (s.InitialBackoff <= 0 || s.MaxBackoff < s.InitialBackoff) {
+		return fmt.Errorf("initialBackoff must be positive and not exceed maxBackoff")
+	}
+	if s.Multiplier < 1 {
+		return fmt.Errorf("multiplier must be at least 1")
+	}
+	if s.Jitter < 0 || s.Jitter > 1 {
+		return fmt.Errorf("jitter must be between 0 and 1")
+	}
+	if s.BreakerThreshold < 0 || (s.BreakerThreshold > 0 && s.BreakerCooldown <= 0) {
+		return fmt.Errorf("breakerCooldown must be positive when the breaker is enabled")
+	}
+	return nil
+}
+
+// Config holds the default settings and per policy overrides
+type Config struct {
+	Default Settings `yaml:"default"`
+
+	// Overrides replace the default settings of a policy, e.g. "rpc", "subgraph",
+	// "circle", "api" or a protocol adapter name such as "aave_v3"
+	Overrides map[string]Settings `yaml:"overrides"`
+}
+
+// DefaultConfig applies DefaultSettings to every policy
+func DefaultConfig() Config {
+	return Config{Default: DefaultSettings()}
+}
+
+// Validate checks the default and every override
+func (c Config) Validate() error {
+	if err := c.Default.Validate(); err != nil {
+		return fmt.Errorf("default: %w", err)
+	}
+	for name, s := range c.Overrides {
+		if err := s.Validate(); err != nil {
+			return fmt.Errorf("overrides.%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// ErrCircuitOpen is returned without calling an endpoint whose circuit is open
+var ErrCircuitOpen = errors.New("circuit open")
+
+// Policy retries calls and tracks a circuit breaker per endpoint. It is safe for
+// concurrent use.
+type Policy struct {
+	name     string
+	settings Settings
+
+	mu       sync.Mutex
+	breakers map[string]*Breaker
+
+	// onOpen is called when the circuit of an endpoint opens
+	onOpen func(policy, endpoint string)
+
+	// sleep waits between attempts; replaced in tests
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// NewPolicy creates a policy with the given settings
+func NewPolicy(name string, settings Settings) *Policy {
+	return &Policy{
+		name:     name,
+		settings: settings,
+		breakers: make(map[string]*Breaker),
+		sleep:    sleepContext,
+	}
+}
+
+// Name returns the policy name
+func (p *Policy) Name() string {
+	return p.name
+}
+
+// Breaker returns the circuit breaker of endpoint
+func (p *Policy) Breaker(endpoint string) *Breaker {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	b, ok := p.breakers[endpoint]
+	if !ok {
+		b = NewBreaker(p.settings.BreakerThreshold, p.settings.BreakerCooldown)
+		p.breakers[endpoint] = b
+	}
+	return b
+}
+
+// OnOpen calls fn whenever the circuit of an endpoint opens. fn must not block.
+func (p *Policy) OnOpen(fn func(policy, endpoint string)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onOpen = fn
+}
+
+// opened reports that the circuit of endpoint opened
+func (p *Policy) opened(endpoint string) {
+	p.mu.Lock()
+	onOpen := p.onOpen
+	p.mu.Unlock()
+	if onOpen != nil {
+		onOpen(p.name, endpoint)
+	}
+}
+
+// Do calls fn until it succeeds, fails with an error that is not retryable, the attempts
+// are exhausted or ctx is done. A nil policy calls fn once.
+func (p *Policy) Do(ctx context.Context, endpoint string, fn func(ctx context.Context) error) error {
+	if p == nil {
+		return fn(ctx)
+	}
+	breaker := p.Breaker(endpoint)
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if !breaker.Allow() {
+			if err != nil {
+				return fmt.Errorf("%s: %w after %d attempts: %w", endpoint, ErrCircuitOpen, attempt-1, err)
+			}
+			return fmt.Errorf("%s: %w", endpoint, ErrCircuitOpen)
+		}
+
+		err = fn(ctx)
+		if err == nil {
+			breaker.Success()
+			return nil
+		}
+		if Classify(ctx, err) != ClassRetryable {
+			// the endpoint answered, so a fatal error says nothing about its health
+			breaker.Success()
+			return err
+		}
+		if breaker.Failure() {
+			p.opened(endpoint)
+		}
+
+		if attempt >= p.settings.MaxAttempts {
+			if attempt == 1 {
+				return err
+			}
+			return fmt.Errorf("%s: giving up after %d attempts: %w", endpoint, attempt, err)
+		}
+		delay := p.backoff(attempt)
+		// retries show up on the span of the call, so a slow call can be told from a flaky one
+		trace.SpanFromContext(ctx).AddEvent("retry", trace.WithAttributes(
+			attribute.String("endpoint", endpoint),
+			attribute.Int("attempt", attempt),
+			attribute.String("error", err.Error()),
+			attribute.String("backoff", delay.String()),
+		))
+		if sleepErr := p.sleep(ctx, delay); sleepErr != nil {
+			return err
+		}
+	}
+}
+
+// backoff returns the jittered delay before retry number attempt
+func (p *Policy) backoff(attempt int) time.Duration {
+	delay := float64(p.settings.InitialBackoff) * math.Pow(p.settings.Multiplier, float64(attempt-1))
+	delay = math.Min(delay, float64(p.settings.MaxBackoff))
+	if p.settings.Jitter > 0 {
+		delay *= 1 + p.settings.Jitter*(2*rand.Float64()-1)
+	}
+	return time.Duration(delay)
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Policies creates one policy per name from a Config
+type Policies struct {
+	cfg Config
+
+	mu       sync.Mutex
+	policies map[string]*Policy
+	onOpen   func(policy, endpoint string)
+}
+
+// NewPolicies creates policies for cfg
+func NewPolicies(cfg Config) *Policies {
+	return &Policies{cfg: cfg, policies: make(map[string]*Policy)}
+}
+
+// For returns the policy of name, using its override when one is configured. A nil
+// Policies returns a nil policy, which does not retry.
+func (ps *Policies) For(name string) *Policy {
+	if ps == nil {
+		return nil
+	}
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	p, ok := ps.policies[name]
+	if !ok {
+		settings, overridden := ps.cfg.Overrides[name]
+		if !overridden {
+			settings = ps.cfg.Default
+		}
+		p = NewPolicy(name, settings)
+		p.onOpen = ps.onOpen
+		ps.policies[name] = p
+	}
+	return p
+}
+
+// OnOpen calls fn whenever the circuit of an endpoint of any policy opens. fn must not
+// block.
+func (ps *Policies) OnOpen(fn func(policy, endpoint string)) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.onOpen = fn
+	for _, p := range ps.policies {
+		p.OnOpen(fn)
+	}
+}
+
+// Has reports whether name has its own override
+func (ps *Policies) Has(name string) bool {
+	if ps == nil {
+		return false
+	}
+	_, ok := ps.cfg.Overrides[name]
+	return ok
+}
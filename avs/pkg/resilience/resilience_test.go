@@ -0,0 +1,205 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+type rpcError struct {
+	code int
+	msg  string
+}
+
+func (e *rpcError) Error() string  { return e.msg }
+func (e *rpcError) ErrorCode() int { return e.code }
+
+func testPolicy(settings Settings) (*Policy, *[]time.Duration) {
+	var delays []time.Duration
+	p := NewPolicy("test", settings)
+	p.sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return ctx.Err()
+	}
+	return p, &delays
+}
+
+func Test_Classify(t *testing.T) {
+	testCases := []struct {
+		name  string
+		err   error
+		class Class
+	}{
+		{name: "rate limited", err: &StatusError{Endpoint: "api", StatusCode: 429}, class: ClassRetryable},
+		{name: "server error", err: fmt.Errorf("wrapped: %w", &StatusError{Endpoint: "api", StatusCode: 503}), class: ClassRetryable},
+		{name: "bad request", err: &StatusError{Endpoint: "api", StatusCode: 400}, class: ClassFatal},
+		{name: "revert", err: &rpcError{code: 3, msg: "execution reverted"}, class: ClassFatal},
+		{name: "rpc limit", err: &rpcError{code: -32005, msg: "limit exceeded"}, class: ClassRetryable},
+		{name: "lagging node", err: &rpcError{code: -32000, msg: "header not found"}, class: ClassRetryable},
+		{name: "invalid transaction", err: &rpcError{code: -32000, msg: "nonce too low"}, class: ClassFatal},
+		{name: "deadline", err: context.DeadlineExceeded, class: ClassRetryable},
+		{name: "cancelled", err: context.Canceled, class: ClassFatal},
+		{name: "permanent", err: Permanent(&StatusError{Endpoint: "api", StatusCode: 503}), class: ClassFatal},
+		{name: "unknown", err: errors.New("abi: cannot unmarshal"), class: ClassFatal},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Classify(context.Background(), tc.err); got != tc.class {
+				t.Errorf("Expected %s, got %s", tc.class, got)
+			}
+		})
+	}
+}
+
+func Test_PolicyRetriesTransientErrors(t *testing.T) {
+	settings := DefaultSettings()
+	settings.Jitter = 0
+	p, delays := testPolicy(settings)
+
+	calls := 0
+	err := p.Do(context.Background(), "rpc-1", func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return &StatusError{Endpoint: "rpc-1", StatusCode: 502}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected the third attempt to succeed: %v", err)
+	}
+	if len(*delays) != 2 || (*delays)[0] != 200*time.Millisecond || (*delays)[1] != 400*time.Millisecond {
+		t.Errorf("Expected exponential backoff, got %v", *delays)
+	}
+
+	calls = 0
+	fatal := &rpcError{code: 3, msg: "execution reverted"}
+	if err := p.Do(context.Background(), "rpc-1", func(ctx context.Context) error {
+		calls++
+		return fatal
+	}); !errors.Is(err, fatal) || calls != 1 {
+		t.Errorf("Expected a fatal error to be returned without retries, got %v after %d calls", err, calls)
+	}
+}
+
+func Test_PolicyGivesUp(t *testing.T) {
+	p, _ := testPolicy(DefaultSettings())
+	transient := &StatusError{Endpoint: "api", StatusCode: 503}
+
+	calls := 0
+	err := p.Do(context.Background(), "api", func(ctx context.Context) error {
+		calls++
+		return transient
+	})
+	if !errors.Is(err, transient) || calls != 3 {
+		t.Errorf("Expected 3 attempts and the last error, got %v after %d calls", err, calls)
+	}
+}
+
+func Test_Backoff(t *testing.T) {
+	settings := DefaultSettings()
+	settings.MaxBackoff = time.Second
+	p := NewPolicy("test", settings)
+	for attempt := 1; attempt <= 10; attempt++ {
+		d := p.backoff(attempt)
+		if d <= 0 || d > time.Duration(float64(time.Second)*(1+settings.Jitter)) {
+			t.Errorf("Backoff of attempt %d out of bounds: %s", attempt, d)
+		}
+	}
+}
+
+func Test_Breaker(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := NewBreaker(2, time.Minute)
+	b.now = func() time.Time { return now }
+
+	b.Failure()
+	if !b.Allow() {
+		t.Fatalf("Expected the circuit to stay closed below the threshold")
+	}
+	b.Failure()
+	if b.Allow() || b.State() != StateOpen {
+		t.Fatalf("Expected the circuit to open at the threshold")
+	}
+
+	now = now.Add(time.Minute)
+	if !b.Allow() {
+		t.Fatalf("Expected a probe after the cooldown")
+	}
+	if b.Allow() {
+		t.Errorf("Expected a single probe while half open")
+	}
+	b.Failure()
+	if b.State() != StateOpen {
+		t.Errorf("Expected a failed probe to reopen the circuit")
+	}
+
+	now = now.Add(time.Minute)
+	b.Allow()
+	b.Success()
+	if b.State() != StateClosed {
+		t.Errorf("Expected a successful probe to close the circuit")
+	}
+}
+
+func Test_PolicyFailsFastWhenOpen(t *testing.T) {
+	settings := DefaultSettings()
+	settings.MaxAttempts = 1
+	settings.BreakerThreshold = 1
+	p, _ := testPolicy(settings)
+
+	_ = p.Do(context.Background(), "rpc-1", func(ctx context.Context) error { return context.DeadlineExceeded })
+
+	called := false
+	err := p.Do(context.Background(), "rpc-1", func(ctx context.Context) error {
+		called = true
+		return nil
+	})
+	if !errors.Is(err, ErrCircuitOpen) || called {
+		t.Errorf("Expected the open circuit to reject the call, got %v", err)
+	}
+	if err := p.Do(context.Background(), "rpc-2", func(ctx context.Context) error { return nil }); err != nil {
+		t.Errorf("Expected other endpoints to be unaffected: %v", err)
+	}
+}
+
+func Test_PoliciesReportOpenedCircuits(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Default.MaxAttempts = 1
+	cfg.Default.BreakerThreshold = 2
+	policies := NewPolicies(cfg)
+	existing := policies.For(PolicyRPC)
+
+	var opened []string
+	policies.OnOpen(func(policy, endpoint string) { opened = append(opened, policy+"/"+endpoint) })
+	fail := func(ctx context.Context) error { return context.DeadlineExceeded }
+	for i := 0; i < 3; i++ {
+		existing.Do(context.Background(), "ethereum", fail)
+	}
+	policies.For(PolicySubgraph).Do(context.Background(), "aave", fail)
+	policies.For(PolicySubgraph).Do(context.Background(), "aave", fail)
+
+	if len(opened) != 2 || opened[0] != "rpc/ethereum" || opened[1] != "subgraph/aave" {
+		t.Errorf("Expected each circuit to be reported once as it opened, got %v", opened)
+	}
+}
+
+func Test_PoliciesOverrides(t *testing.T) {
+	cfg := DefaultConfig()
+	override := DefaultSettings()
+	override.MaxAttempts = 7
+	cfg.Overrides = map[string]Settings{"aave_v3": override}
+
+	policies := NewPolicies(cfg)
+	if got := policies.For("aave_v3").settings.MaxAttempts; got != 7 {
+		t.Errorf("Expected the override to apply, got %d attempts", got)
+	}
+	if policies.For(PolicyRPC) != policies.For(PolicyRPC) {
+		t.Errorf("Expected policies to be reused")
+	}
+	if err := (Config{Default: Settings{}}).Validate(); err == nil {
+		t.Errorf("Expected zero settings to be rejected")
+	}
+}
@@ -0,0 +1,328 @@
+// Package resultdiff compares the results several operators answered the same task
+// with, field by field, to find the non-determinism that keeps them from reaching a
+// quorum. Numbers differing within a tolerance match, and fields that always differ
+// between operators, such as their signatures, are left out.
+package resultdiff
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/najnomics/crosscow-avs/pkg/resultsize"
+)
+
+// wordSize is the size of the words of ABI encoded results
+const wordSize = 32
+
+// DefaultIgnore are the fields of the result envelope that differ between operators
+// answering the same result: the trace of how each computed it, and who attested it when
+var DefaultIgnore = []string{"trace", "attestation.operator", "attestation.timestamp", "attestation.signature"}
+
+// decimalPattern matches the numbers results encode as strings, such as rates
+var decimalPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// Options configure how results are matched
+type Options struct {
+	// ToleranceBps is the largest difference between numbers that still match, in basis
+	// points of the largest of them
+	ToleranceBps float64
+
+	// Tolerance is the largest absolute difference between numbers that still match
+	Tolerance float64
+
+	// Ignore are the paths of fields left out of the comparison, with their children,
+	// such as "result.timestamp" or "result.markets[0]"
+	Ignore []string
+}
+
+// Result is the result blob an operator answered
+type Result struct {
+	// Operator names the operator in the report
+	Operator string
+
+	// Data is a JSON result envelope, compressed or not, or an ABI encoded result, raw or
+	// as 0x prefixed hex
+	Data []byte
+}
+
+// Difference is a field whose value is not the same in every result. Values are the
+// JSON values of the field by operator, absent for operators whose result lacks it.
+type Difference struct {
+	Path   string            `json:"path"`
+	Values map[string]string `json:"values"`
+
+	// Tolerated is set when the values are numbers within the tolerance
+	Tolerated bool `json:"tolerated"`
+}
+
+// Report is the comparison of the results of a task
+type Report struct {
+	Operators []string `json:"operators"`
+
+	// Fields is the number of fields compared
+	Fields int `json:"fields"`
+
+	// Differences are the fields not matching, and Tolerated the numbers matching only
+	// within the tolerance, in path order
+	Differences []Difference `json:"differences"`
+	Tolerated   []Difference `json:"tolerated"`
+}
+
+// Agree reports whether the results match on every compared field
+func (r *Report) Agree() bool {
+	return len(r.Differences) == 0
+}
+
+// Compare compares results field by field. JSON results are compared on the fields of
+// their envelope, compressed results once decompressed, and ABI encoded results word by
+// word, words read as unsigned integers. Results of different tasks, as their
+// attestations tell, are refused.
+func Compare(results []Result, opts Options) (*Report, error) {
+	if len(results) < 2 {
+		return nil, fmt.Errorf("at least two results are needed, got %d", len(results))
+	}
+	report := &Report{Differences: []Difference{}, Tolerated: []Difference{}}
+	fields := make([]map[string]interface{}, len(results))
+	seen := make(map[string]bool)
+	for i, result := range results {
+		if result.Operator == "" || seen[result.Operator] {
+			return nil, fmt.Errorf("result %d needs a unique operator name", i)
+		}
+		seen[result.Operator] = true
+		report.Operators = append(report.Operators, result.Operator)
+
+		tree, err := decode(result.Data)
+		if err != nil {
+			return nil, fmt.Errorf("result of %s: %w", result.Operator, err)
+		}
+		fields[i] = make(map[string]interface{})
+		flatten("", tree, fields[i])
+	}
+	if err := checkTaskIDs(report.Operators, fields); err != nil {
+		return nil, err
+	}
+
+	ignore := append(append([]string{}, DefaultIgnore...), opts.Ignore...)
+	paths := make(map[string]bool)
+	for _, f := range fields {
+		for path := range f {
+			if !ignored(path, ignore) {
+				paths[path] = true
+			}
+		}
+	}
+	sorted := make([]string, 0, len(paths))
+	for path := range paths {
+		sorted = append(sorted, path)
+	}
+	sort.Strings(sorted)
+
+	for _, path := range sorted {
+		report.Fields++
+		values := make(map[string]string, len(results))
+		var distinct []interface{}
+		missing := false
+		for i, operator := range report.Operators {
+			value, ok := fields[i][path]
+			if !ok {
+				missing = true
+				continue
+			}
+			values[operator] = encodeValue(value)
+			if len(distinct) == 0 || !equal(distinct[0], value) {
+				distinct = append(distinct, value)
+			}
+		}
+		if !missing && len(distinct) == 1 {
+			continue
+		}
+		difference := Difference{Path: path, Values: values}
+		if !missing && withinTolerance(distinct, opts) {
+			difference.Tolerated = true
+			report.Tolerated = append(report.Tolerated, difference)
+			continue
+		}
+		report.Differences = append(report.Differences, difference)
+	}
+	return report, nil
+}
+
+// decode decodes a result blob into a tree of JSON values
+func decode(data []byte) (interface{}, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty result")
+	}
+	if json.Valid(data) {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		var tree interface{}
+		if err := decoder.Decode(&tree); err != nil {
+			return nil, err
+		}
+		return decompress(tree)
+	}
+	if text := string(data); strings.HasPrefix(text, "0x") {
+		decoded, err := hex.DecodeString(text[2:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex result: %w", err)
+		}
+		data = decoded
+	}
+	if len(data)%wordSize != 0 {
+		return nil, fmt.Errorf("ABI result of %d bytes is not a whole number of words", len(data))
+	}
+	words := make([]interface{}, len(data)/wordSize)
+	for i := range words {
+		words[i] = json.Number(new(big.Int).SetBytes(data[i*wordSize : (i+1)*wordSize]).String())
+	}
+	return map[string]interface{}{"words": words}, nil
+}
+
+// decompress replaces the compressed result of an envelope with the result it
+// decompresses to
+func decompress(tree interface{}) (interface{}, error) {
+	envelope, ok := tree.(map[string]interface{})
+	if !ok || envelope["encoding"] != resultsize.EncodingGzipBase64 {
+		return tree, nil
+	}
+	compressed, ok := envelope["result"].(string)
+	if !ok {
+		return nil, fmt.Errorf("%s result is not a string", resultsize.EncodingGzipBase64)
+	}
+	result, err := resultsize.Decompress(compressed)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(result))
+	decoder.UseNumber()
+	var decompressed interface{}
+	if err := decoder.Decode(&decompressed); err != nil {
+		return nil, fmt.Errorf("failed to decode decompressed result: %w", err)
+	}
+	envelope["result"] = decompressed
+	delete(envelope, "encoding")
+	return envelope, nil
+}
+
+// flatten records the leaves of tree in fields by their path. Empty objects and arrays
+// are leaves.
+func flatten(path string, tree interface{}, fields map[string]interface{}) {
+	switch node := tree.(type) {
+	case map[string]interface{}:
+		if len(node) == 0 {
+			fields[path] = node
+		}
+		for key, child := range node {
+			childPath := key
+			if path != "" {
+				childPath = path + "." + key
+			}
+			flatten(childPath, child, fields)
+		}
+	case []interface{}:
+		if len(node) == 0 {
+			fields[path] = node
+		}
+		for i, child := range node {
+			flatten(fmt.Sprintf("%s[%d]", path, i), child, fields)
+		}
+	default:
+		fields[path] = node
+	}
+}
+
+// checkTaskIDs refuses results whose attestations name different tasks
+func checkTaskIDs(operators []string, fields []map[string]interface{}) error {
+	var first string
+	for i, f := range fields {
+		id, ok := f["attestation.task_id"].(string)
+		if !ok {
+			continue
+		}
+		if first == "" {
+			first = id
+			continue
+		}
+		if id != first {
+			return fmt.Errorf("result of %s is of task %s, not %s", operators[i], id, first)
+		}
+	}
+	return nil
+}
+
+// ignored reports whether path or a parent of it is in ignore
+func ignored(path string, ignore []string) bool {
+	for _, prefix := range ignore {
+		if path == prefix || strings.HasPrefix(path, prefix+".") || strings.HasPrefix(path, prefix+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+func equal(a, b interface{}) bool {
+	return encodeValue(a) == encodeValue(b)
+}
+
+func encodeValue(v interface{}) string {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(encoded)
+}
+
+// number returns the value of numbers and of strings holding decimals
+func number(v interface{}) (*big.Rat, bool) {
+	var s string
+	switch value := v.(type) {
+	case json.Number:
+		s = value.String()
+	case string:
+		if !decimalPattern.MatchString(value) {
+			return nil, false
+		}
+		s = value
+	default:
+		return nil, false
+	}
+	r, ok := new(big.Rat).SetString(s)
+	return r, ok
+}
+
+// withinTolerance reports whether values are numbers whose spread is within the tolerance
+// of opts
+func withinTolerance(values []interface{}, opts Options) bool {
+	if opts.ToleranceBps <= 0 && opts.Tolerance <= 0 {
+		return false
+	}
+	var lowest, highest *big.Rat
+	for _, value := range values {
+		n, ok := number(value)
+		if !ok {
+			return false
+		}
+		if lowest == nil || n.Cmp(lowest) < 0 {
+			lowest = n
+		}
+		if highest == nil || n.Cmp(highest) > 0 {
+			highest = n
+		}
+	}
+	spread, _ := new(big.Rat).Sub(highest, lowest).Float64()
+	if opts.Tolerance > 0 && spread <= opts.Tolerance {
+		return true
+	}
+	magnitude, _ := new(big.Rat).Abs(highest).Float64()
+	if low, _ := new(big.Rat).Abs(lowest).Float64(); low > magnitude {
+		magnitude = low
+	}
+	return opts.ToleranceBps > 0 && spread <= magnitude*opts.ToleranceBps/10_000
+}
@@ -0,0 +1,101 @@
+package resultdiff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/najnomics/crosscow-avs/pkg/resultsize"
+)
+
+// envelope returns a result envelope of task 0x01 attested by operator
+func envelope(operator, result string) []byte {
+	return []byte(fmt.Sprintf(`{"task_type":"yield_monitoring","result":%s,"trace":{"elapsed_ms":%d},"attestation":{"operator":"%s","task_id":"0x01","signature":"0x%s"}}`,
+		result, len(operator), operator, operator))
+}
+
+func Test_CompareMatchesWithinTolerance(t *testing.T) {
+	report, err := Compare([]Result{
+		{Operator: "a", Data: envelope("a", `{"markets":[{"protocol":"aave_v3","supply_rate":"4.5000","tvl":1000000}],"best":"aave_v3"}`)},
+		{Operator: "b", Data: envelope("b", `{"markets":[{"protocol":"aave_v3","supply_rate":"4.5001","tvl":1000000}],"best":"aave_v3"}`)},
+	}, Options{ToleranceBps: 1})
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+	if !report.Agree() || report.Fields != 6 {
+		t.Errorf("Expected the results to agree on 6 fields, got %+v", report)
+	}
+	if len(report.Tolerated) != 1 || report.Tolerated[0].Path != "result.markets[0].supply_rate" || report.Tolerated[0].Values["b"] != `"4.5001"` {
+		t.Errorf("Expected the supply rate to be tolerated, got %+v", report.Tolerated)
+	}
+
+	report, err = Compare([]Result{
+		{Operator: "a", Data: envelope("a", `{"supply_rate":"4.5000","best":"aave_v3"}`)},
+		{Operator: "b", Data: envelope("b", `{"supply_rate":"4.6000","best":"morpho"}`)},
+		{Operator: "c", Data: envelope("c", `{"supply_rate":"4.5000"}`)},
+	}, Options{ToleranceBps: 1, Ignore: []string{"task_type"}})
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+	if report.Agree() || len(report.Differences) != 2 {
+		t.Fatalf("Expected 2 differences, got %+v", report.Differences)
+	}
+	best := report.Differences[0]
+	if best.Path != "result.best" || best.Values["b"] != `"morpho"` || len(best.Values) != 2 {
+		t.Errorf("Expected the missing best market of c to differ, got %+v", best)
+	}
+	if report.Differences[1].Path != "result.supply_rate" {
+		t.Errorf("Expected the supply rate beyond tolerance to differ, got %+v", report.Differences[1])
+	}
+}
+
+func Test_CompareAbsoluteTolerance(t *testing.T) {
+	results := []Result{
+		{Operator: "a", Data: []byte(`{"amount":1000000}`)},
+		{Operator: "b", Data: []byte(`{"amount":1000003}`)},
+	}
+	for tolerance, agree := range map[float64]bool{0: false, 2: false, 3: true} {
+		report, err := Compare(results, Options{Tolerance: tolerance})
+		if err != nil || report.Agree() != agree {
+			t.Errorf("tolerance %v: expected agreement %v, got %+v: %v", tolerance, agree, report, err)
+		}
+	}
+}
+
+func Test_CompareDecodesResults(t *testing.T) {
+	compressed, err := resultsize.Compress([]byte(`{"supply_rate":"4.5000"}`))
+	if err != nil {
+		t.Fatalf("Compress failed: %v", err)
+	}
+	zipped := fmt.Sprintf(`{"encoding":"gzip+base64","result":"%s"}`, compressed)
+	report, err := Compare([]Result{
+		{Operator: "plain", Data: []byte(`{"result":{"supply_rate":"4.5000"}}`)},
+		{Operator: "zipped", Data: []byte(zipped)},
+	}, Options{})
+	if err != nil || !report.Agree() || report.Fields != 1 {
+		t.Errorf("Expected the compressed result to match, got %+v: %v", report, err)
+	}
+
+	word := strings.Repeat("00", 31)
+	report, err = Compare([]Result{
+		{Operator: "a", Data: []byte("0x" + word + "01" + word + "0a")},
+		{Operator: "b", Data: []byte("0x" + word + "01" + word + "0b")},
+	}, Options{})
+	if err != nil || len(report.Differences) != 1 || report.Differences[0].Path != "words[1]" || report.Differences[0].Values["b"] != "11" {
+		t.Errorf("Expected the second word to differ, got %+v: %v", report, err)
+	}
+}
+
+func Test_CompareRefuses(t *testing.T) {
+	testCases := map[string][]Result{
+		"single result":   {{Operator: "a", Data: []byte(`{}`)}},
+		"same operator":   {{Operator: "a", Data: []byte(`{}`)}, {Operator: "a", Data: []byte(`{}`)}},
+		"partial word":    {{Operator: "a", Data: []byte("0x01")}, {Operator: "b", Data: []byte(`{}`)}},
+		"different tasks": {{Operator: "a", Data: envelope("a", "1")}, {Operator: "b", Data: []byte(`{"attestation":{"task_id":"0x02"}}`)}},
+	}
+	for name, results := range testCases {
+		if _, err := Compare(results, Options{}); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// Package resultsize keeps task results within what the aggregator and the yield hook
+// can take. Encoded results are bounded by task type, and the results of tasks asking
+// for it are compressed with gzip and carried as base64 in the result envelope.
+package resultsize
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// EncodingGzipBase64 is the encoding of results compressed with gzip and carried as a
+// base64 string
+const EncodingGzipBase64 = "gzip+base64"
+
+// ErrTooLarge is returned for encoded results over the limit of their task type
+var ErrTooLarge = errors.New("result too large")
+
+// Config bounds the size of encoded task results
+type Config struct {
+	// MaxBytes bounds the encoded results of task types without a limit of their own.
+	// Zero leaves them unbounded.
+	MaxBytes int `yaml:"maxBytes"`
+
+	// Limits bound the encoded results of task types, overriding MaxBytes
+	Limits map[string]int `yaml:"limits"`
+}
+
+// DefaultConfig bounds results to 4 MiB, the largest message gRPC receives by default
+func DefaultConfig() Config {
+	return Config{MaxBytes: 4 << 20}
+}
+
+// Validate checks the config for limits no result fits in
+func (c Config) Validate() error {
+	if c.MaxBytes < 0 {
+		return fmt.Errorf("maxBytes must not be negative")
+	}
+	for taskType, limit := range c.Limits {
+		if limit <= 0 {
+			return fmt.Errorf("limits.%s must be positive", taskType)
+		}
+	}
+	return nil
+}
+
+// Limit returns the size encoded results of taskType are bounded to, zero when unbounded
+func (c Config) Limit(taskType string) int {
+	if limit, ok := c.Limits[taskType]; ok {
+		return limit
+	}
+	return c.MaxBytes
+}
+
+// Check returns ErrTooLarge when an encoded result of size bytes is over the limit of
+// taskType
+func (c Config) Check(taskType string, size int) error {
+	if limit := c.Limit(taskType); limit > 0 && size > limit {
+		return fmt.Errorf("%w: %s result of %d bytes is over the limit of %d bytes", ErrTooLarge, taskType, size, limit)
+	}
+	return nil
+}
+
+// Compress returns data compressed with gzip, as base64. The gzip header carries no name
+// or time, so the same data always compresses to the same string with the compressor of
+// a Go release.
+func Compress(data []byte) (string, error) {
+	var buf bytes.Buffer
+	w, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return "", err
+	}
+	if _, err := w.Write(data); err != nil {
+		return "", fmt.Errorf("failed to compress result: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return "", fmt.Errorf("failed to compress result: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// Decompress returns the data Compress encoded
+func Decompress(encoded string) ([]byte, error) {
+	compressed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode result: %w", err)
+	}
+	r, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress result: %w", err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress result: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,48 @@
+package resultsize
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func Test_CompressRoundTrip(t *testing.T) {
+	data := bytes.Repeat([]byte(`{"protocol":"aave_v3","supply_rate":"4.250000"}`), 100)
+	encoded, err := Compress(data)
+	if err != nil {
+		t.Fatalf("Compress failed: %v", err)
+	}
+	if len(encoded) >= len(data) {
+		t.Errorf("Expected the result compressed, got %d bytes from %d", len(encoded), len(data))
+	}
+	again, err := Compress(data)
+	if err != nil || again != encoded {
+		t.Errorf("Expected the same data compressed to the same string")
+	}
+	decoded, err := Decompress(encoded)
+	if err != nil || !bytes.Equal(decoded, data) {
+		t.Errorf("Expected the data back, got %q: %v", decoded, err)
+	}
+	if _, err := Decompress("not base64!"); err == nil {
+		t.Errorf("Expected invalid base64 rejected")
+	}
+}
+
+func Test_Check(t *testing.T) {
+	cfg := Config{MaxBytes: 100, Limits: map[string]int{"full_market_snapshot": 1000}}
+	if err := cfg.Check("yield_monitoring", 100); err != nil {
+		t.Errorf("Expected a result at the limit accepted, got %v", err)
+	}
+	if err := cfg.Check("yield_monitoring", 101); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Expected a result over the limit rejected, got %v", err)
+	}
+	if err := cfg.Check("full_market_snapshot", 1000); err != nil {
+		t.Errorf("Expected the limit of the task type applied, got %v", err)
+	}
+	if err := (Config{}).Check("yield_monitoring", 1<<30); err != nil {
+		t.Errorf("Expected results unbounded without limits, got %v", err)
+	}
+	if err := (Config{Limits: map[string]int{"batch": 0}}).Validate(); err == nil {
+		t.Errorf("Expected a zero limit rejected")
+	}
+}
@@ -0,0 +1,460 @@
+// Package risk scores the USDC market of a lending protocol from its size, usage, caps,
+// solvency, price oracle, admin keys and security record. Every factor is scored from 0 (no concern) to 100 and the
+// overall score combines the factors that could be measured.
+package risk
+
+import (
+	"math/big"
+)
+
+// Factor names a measured aspect of a market
+type Factor string
+
+const (
+	// FactorTVL scores small markets, where single accounts move utilization and rates
+	FactorTVL Factor = "tvl"
+	// FactorUtilization scores how much of the supply is lent out and cannot be withdrawn
+	FactorUtilization Factor = "utilization"
+	// FactorCapHeadroom scores how close supply is to the supply cap
+	FactorCapHeadroom Factor = "cap_headroom"
+	// FactorBadDebt scores debt no collateral backs, relative to supply
+	FactorBadDebt Factor = "bad_debt"
+	// FactorOracle scores stale prices and prices away from $1
+	FactorOracle Factor = "oracle"
+	// FactorMarketStatus scores paused and frozen markets
+	FactorMarketStatus Factor = "market_status"
+	// FactorAdmin scores how few keys can change the market and how fast
+	FactorAdmin Factor = "admin"
+	// FactorSecurity scores active incidents, recent exploits and missing audits
+	FactorSecurity Factor = "security"
+)
+
+// Factors in the order they are reported
+var Factors = []Factor{
+	FactorTVL,
+	FactorUtilization,
+	FactorCapHeadroom,
+	FactorBadDebt,
+	FactorOracle,
+	FactorMarketStatus,
+	FactorAdmin,
+	FactorSecurity,
+}
+
+// DefaultWeights weigh solvency and withdrawability above size and configuration
+var DefaultWeights = map[Factor]int64{
+	FactorTVL:          15,
+	FactorUtilization:  25,
+	FactorCapHeadroom:  10,
+	FactorBadDebt:      25,
+	FactorOracle:       15,
+	FactorMarketStatus: 10,
+	FactorAdmin:        15,
+	FactorSecurity:     20,
+}
+
+// Level grades an overall score
+type Level string
+
+const (
+	LevelLow      Level = "low"
+	LevelMedium   Level = "medium"
+	LevelHigh     Level = "high"
+	LevelCritical Level = "critical"
+)
+
+// Thresholds set where each factor starts and stops scoring
+type Thresholds struct {
+	// MinTVL scores 100 and SafeTVL 0, in USDC base units, linearly in between
+	MinTVL  *big.Int
+	SafeTVL *big.Int
+
+	// KinkUtilization scores 40; utilization scores linearly from 0 up to it and from
+	// 40 to 100 above it
+	KinkUtilization *big.Rat
+
+	// SafeCapHeadroom is the share of the supply cap left unused that scores 0. No
+	// headroom scores 100.
+	SafeCapHeadroom *big.Rat
+
+	// MaxBadDebt is the share of supply in bad debt that scores 100
+	MaxBadDebt *big.Rat
+
+	// OracleHeartbeat is the age in seconds after which a price is stale and scores 100.
+	// Fresh prices score by their deviation from $1, reaching 100 at
+	// OracleDeviationBps.
+	OracleHeartbeat    uint64
+	OracleDeviationBps int64
+
+	// SafeSignerThreshold is the number of signatures needed to act that scores 0; a single
+	// key scores 100. Timelocks take off up to three quarters of that score, the full
+	// share at SafeAdminDelay seconds.
+	SafeSignerThreshold uint64
+	SafeAdminDelay      uint64
+
+	// ExploitWindow is how long in seconds a resolved exploit keeps scoring, from 60
+	// right after it down to 0. Protocols never audited score 50 and those whose latest
+	// audit is older than StaleAuditAge seconds score 30.
+	ExploitWindow uint64
+	StaleAuditAge uint64
+}
+
+// DefaultThresholds treat $1M as a thin market and $100M as deep, and follow the daily
+// heartbeat of Chainlink stablecoin feeds
+var DefaultThresholds = Thresholds{
+	MinTVL:             big.NewInt(1_000_000_000_000),
+	SafeTVL:            big.NewInt(100_000_000_000_000),
+	KinkUtilization:    big.NewRat(80, 100),
+	SafeCapHeadroom:    big.NewRat(20, 100),
+	MaxBadDebt:         big.NewRat(1, 100),
+	OracleHeartbeat:    24 * 60 * 60,
+	OracleDeviationBps: 300,
+
+	SafeSignerThreshold: 5,
+	SafeAdminDelay:      2 * 24 * 60 * 60,
+
+	ExploitWindow: 180 * 24 * 60 * 60,
+	StaleAuditAge: 2 * 365 * 24 * 60 * 60,
+}
+
+// Metrics are the measurements of a market. Amounts are in USDC base units; optional
+// fields are nil when the protocol does not expose them.
+type Metrics struct {
+	TotalSupply *big.Int
+	TotalBorrow *big.Int
+	SupplyCap   *big.Int
+	BadDebt     *big.Int
+
+	// Status is nil when the protocol does not report whether the market is halted
+	Status *Status
+
+	// OraclePrice is the USD price of USDC the protocol uses and OracleAge the seconds
+	// since it was updated, nil when unknown
+	OraclePrice *big.Rat
+	OracleAge   *uint64
+
+	// Governance is nil when the admin keys of the protocol could not be inspected
+	Governance *Governance
+
+	// Security is nil when no security record is available
+	Security *Security
+}
+
+// Control is who can act on a market, seen through the timelocks in between: the delay
+// they add and the signatures needed by the account at the end
+type Control struct {
+	// Delay is the total delay in seconds of the timelocks in between
+	Delay uint64
+
+	// Threshold is the number of signatures needed, 1 for a single key. It is zero when
+	// the account is a contract whose rules are unknown, such as a token vote.
+	Threshold uint64
+}
+
+// Governance is who can upgrade, configure and pause a market. Upgrade and Pause are nil
+// when the market is not upgradeable or has no known pause guardian.
+type Governance struct {
+	Upgrade *Control
+	Admin   *Control
+	Pause   *Control
+}
+
+// Severity grades an unresolved incident
+type Severity string
+
+const (
+	SeverityLow      Severity = "low"
+	SeverityMedium   Severity = "medium"
+	SeverityHigh     Severity = "high"
+	SeverityCritical Severity = "critical"
+)
+
+// severityScores are the scores of unresolved incidents
+var severityScores = map[Severity]int64{
+	SeverityLow:      25,
+	SeverityMedium:   50,
+	SeverityHigh:     80,
+	SeverityCritical: 100,
+}
+
+// Security is the incident and audit record of a protocol. Ages are in seconds.
+type Security struct {
+	// ActiveSeverity is the severity of the worst unresolved incident, empty when none
+	ActiveSeverity Severity
+
+	// ExploitAge is the age of the latest resolved incident, nil when there is none
+	ExploitAge *uint64
+
+	// AuditAge is the age of the latest audit, nil when the protocol was never audited
+	AuditAge *uint64
+}
+
+// Status is whether a market is halted
+type Status struct {
+	Paused bool
+	Frozen bool
+}
+
+// FactorScore is the score of one factor. Score is nil when the factor could not be
+// measured.
+type FactorScore struct {
+	Factor Factor
+	Score  *big.Rat
+	Weight int64
+}
+
+// Assessment is the scored state of a market
+type Assessment struct {
+	Factors []FactorScore
+	Overall *big.Rat
+	Level   Level
+}
+
+// Assess scores m. The overall score is the weighted mean of the measured factors, but
+// no less than half of the worst one, so a single failing factor is not averaged away.
+func Assess(m *Metrics, t Thresholds, weights map[Factor]int64) *Assessment {
+	scores := map[Factor]*big.Rat{
+		FactorTVL:          t.tvl(m.TotalSupply),
+		FactorUtilization:  t.utilization(m.TotalSupply, m.TotalBorrow),
+		FactorCapHeadroom:  t.capHeadroom(m.TotalSupply, m.SupplyCap),
+		FactorBadDebt:      t.badDebt(m.TotalSupply, m.BadDebt),
+		FactorOracle:       t.oracle(m.OraclePrice, m.OracleAge),
+		FactorMarketStatus: marketStatus(m.Status),
+		FactorAdmin:        t.admin(m.Governance),
+		FactorSecurity:     t.security(m.Security),
+	}
+
+	a := &Assessment{}
+	for _, factor := range Factors {
+		a.Factors = append(a.Factors, FactorScore{Factor: factor, Score: scores[factor], Weight: weights[factor]})
+	}
+	a.Overall = a.Mean(Factors...)
+	if a.Overall == nil {
+		a.Overall = new(big.Rat)
+	}
+	for _, f := range a.Factors {
+		if f.Score == nil {
+			continue
+		}
+		if half := new(big.Rat).Quo(f.Score, big.NewRat(2, 1)); half.Cmp(a.Overall) > 0 {
+			a.Overall = half
+		}
+	}
+	a.Level = levelOf(a.Overall)
+	return a
+}
+
+// Mean is the weighted mean score of the measured factors among factors, nil when none
+// was measured
+func (a *Assessment) Mean(factors ...Factor) *big.Rat {
+	wanted := make(map[Factor]bool, len(factors))
+	for _, f := range factors {
+		wanted[f] = true
+	}
+	sum, total := new(big.Rat), int64(0)
+	for _, f := range a.Factors {
+		if !wanted[f.Factor] || f.Score == nil || f.Weight <= 0 {
+			continue
+		}
+		sum.Add(sum, new(big.Rat).Mul(f.Score, big.NewRat(f.Weight, 1)))
+		total += f.Weight
+	}
+	if total == 0 {
+		return nil
+	}
+	return sum.Quo(sum, big.NewRat(total, 1))
+}
+
+func levelOf(score *big.Rat) Level {
+	switch {
+	case score.Cmp(big.NewRat(75, 1)) >= 0:
+		return LevelCritical
+	case score.Cmp(big.NewRat(50, 1)) >= 0:
+		return LevelHigh
+	case score.Cmp(big.NewRat(25, 1)) >= 0:
+		return LevelMedium
+	default:
+		return LevelLow
+	}
+}
+
+func (t Thresholds) tvl(supply *big.Int) *big.Rat {
+	if supply == nil {
+		return nil
+	}
+	if supply.Cmp(t.SafeTVL) >= 0 {
+		return new(big.Rat)
+	}
+	if supply.Cmp(t.MinTVL) <= 0 {
+		return big.NewRat(100, 1)
+	}
+	shortfall := new(big.Rat).SetFrac(new(big.Int).Sub(t.SafeTVL, supply), new(big.Int).Sub(t.SafeTVL, t.MinTVL))
+	return shortfall.Mul(shortfall, big.NewRat(100, 1))
+}
+
+func (t Thresholds) utilization(supply, borrow *big.Int) *big.Rat {
+	if supply == nil || borrow == nil {
+		return nil
+	}
+	if supply.Sign() == 0 {
+		return new(big.Rat)
+	}
+	u := new(big.Rat).SetFrac(borrow, supply)
+	if u.Cmp(big.NewRat(1, 1)) > 0 {
+		u.SetInt64(1)
+	}
+	if u.Cmp(t.KinkUtilization) <= 0 {
+		score := new(big.Rat).Quo(u, t.KinkUtilization)
+		return score.Mul(score, big.NewRat(40, 1))
+	}
+	above := new(big.Rat).Sub(u, t.KinkUtilization)
+	above.Quo(above, new(big.Rat).Sub(big.NewRat(1, 1), t.KinkUtilization))
+	above.Mul(above, big.NewRat(60, 1))
+	return above.Add(above, big.NewRat(40, 1))
+}
+
+// capHeadroom scores uncapped markets 0
+func (t Thresholds) capHeadroom(supply, supplyCap *big.Int) *big.Rat {
+	if supplyCap == nil || supplyCap.Sign() == 0 {
+		return new(big.Rat)
+	}
+	if supply == nil {
+		return nil
+	}
+	headroom := new(big.Rat).SetFrac(new(big.Int).Sub(supplyCap, supply), supplyCap)
+	if headroom.Cmp(t.SafeCapHeadroom) >= 0 {
+		return new(big.Rat)
+	}
+	if headroom.Sign() <= 0 {
+		return big.NewRat(100, 1)
+	}
+	used := new(big.Rat).Sub(t.SafeCapHeadroom, headroom)
+	used.Quo(used, t.SafeCapHeadroom)
+	return used.Mul(used, big.NewRat(100, 1))
+}
+
+func (t Thresholds) badDebt(supply, badDebt *big.Int) *big.Rat {
+	if badDebt == nil || supply == nil {
+		return nil
+	}
+	if badDebt.Sign() == 0 {
+		return new(big.Rat)
+	}
+	if supply.Sign() == 0 {
+		return big.NewRat(100, 1)
+	}
+	return capped(new(big.Rat).Quo(new(big.Rat).SetFrac(badDebt, supply), t.MaxBadDebt))
+}
+
+func (t Thresholds) oracle(price *big.Rat, age *uint64) *big.Rat {
+	if price == nil {
+		return nil
+	}
+	if age != nil && *age > t.OracleHeartbeat {
+		return big.NewRat(100, 1)
+	}
+	deviation := new(big.Rat).Sub(price, big.NewRat(1, 1))
+	deviation.Abs(deviation)
+	deviation.Mul(deviation, big.NewRat(10_000, t.OracleDeviationBps))
+	return capped(deviation)
+}
+
+func marketStatus(status *Status) *big.Rat {
+	switch {
+	case status == nil:
+		return nil
+	case status.Paused:
+		return big.NewRat(100, 1)
+	case status.Frozen:
+		return big.NewRat(50, 1)
+	default:
+		return new(big.Rat)
+	}
+}
+
+// admin scores the worst of the controls over a market. Pausing cannot move funds, so a
+// pause guardian counts half.
+func (t Thresholds) admin(g *Governance) *big.Rat {
+	if g == nil {
+		return nil
+	}
+	worst := new(big.Rat)
+	for _, c := range []struct {
+		control *Control
+		share   *big.Rat
+	}{
+		{g.Upgrade, big.NewRat(1, 1)},
+		{g.Admin, big.NewRat(1, 1)},
+		{g.Pause, big.NewRat(1, 2)},
+	} {
+		if c.control == nil {
+			continue
+		}
+		score := t.control(c.control)
+		if score.Mul(score, c.share); score.Cmp(worst) > 0 {
+			worst = score
+		}
+	}
+	return worst
+}
+
+// control scores the keys behind c, a contract with unknown rules as 50, and discounts
+// the score for the time its timelocks give users to withdraw
+func (t Thresholds) control(c *Control) *big.Rat {
+	var keys *big.Rat
+	switch {
+	case c.Threshold == 0:
+		keys = big.NewRat(50, 1)
+	case c.Threshold >= t.SafeSignerThreshold:
+		keys = new(big.Rat)
+	default:
+		keys = big.NewRat(int64(t.SafeSignerThreshold-c.Threshold)*100, int64(t.SafeSignerThreshold-1))
+	}
+	if t.SafeAdminDelay == 0 {
+		return keys
+	}
+	delay := new(big.Rat).SetFrac64(int64(min(c.Delay, t.SafeAdminDelay)), int64(t.SafeAdminDelay))
+	discount := delay.Mul(delay, big.NewRat(3, 4))
+	return keys.Mul(keys, discount.Sub(big.NewRat(1, 1), discount))
+}
+
+// security scores the worst of an unresolved incident, a recent exploit and the audit
+// record
+func (t Thresholds) security(s *Security) *big.Rat {
+	if s == nil {
+		return nil
+	}
+	worst := new(big.Rat)
+	raise := func(score *big.Rat) {
+		if score.Cmp(worst) > 0 {
+			worst = score
+		}
+	}
+	if s.ActiveSeverity != "" {
+		score, ok := severityScores[s.ActiveSeverity]
+		if !ok {
+			score = severityScores[SeverityCritical]
+		}
+		raise(big.NewRat(score, 1))
+	}
+	if s.ExploitAge != nil && *s.ExploitAge < t.ExploitWindow {
+		remaining := new(big.Rat).SetFrac64(int64(t.ExploitWindow-*s.ExploitAge), int64(t.ExploitWindow))
+		raise(remaining.Mul(remaining, big.NewRat(60, 1)))
+	}
+	switch {
+	case s.AuditAge == nil:
+		raise(big.NewRat(50, 1))
+	case *s.AuditAge > t.StaleAuditAge:
+		raise(big.NewRat(30, 1))
+	}
+	return worst
+}
+
+// capped scales a ratio of a threshold to 0-100
+func capped(ratio *big.Rat) *big.Rat {
+	score := ratio.Mul(ratio, big.NewRat(100, 1))
+	if score.Cmp(big.NewRat(100, 1)) > 0 {
+		return big.NewRat(100, 1)
+	}
+	return score
+}
@@ -0,0 +1,151 @@
+package risk
+
+import (
+	"math/big"
+	"testing"
+)
+
+func usdc(amount int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(amount), big.NewInt(1_000_000))
+}
+
+func score(t *testing.T, a *Assessment, factor Factor) *big.Rat {
+	t.Helper()
+	for _, f := range a.Factors {
+		if f.Factor == factor {
+			return f.Score
+		}
+	}
+	t.Fatalf("Factor %s missing", factor)
+	return nil
+}
+
+func Test_AssessFactors(t *testing.T) {
+	age := uint64(3600)
+	a := Assess(&Metrics{
+		TotalSupply: usdc(50_500_000),
+		TotalBorrow: usdc(45_450_000),
+		SupplyCap:   usdc(55_000_000),
+		BadDebt:     usdc(50_500),
+		OraclePrice: big.NewRat(9985, 10000),
+		OracleAge:   &age,
+		Status:      &Status{},
+	}, DefaultThresholds, DefaultWeights)
+
+	expected := map[Factor]*big.Rat{
+		// halfway between $1M and $100M
+		FactorTVL: big.NewRat(50, 1),
+		// 90% utilization is halfway from the kink to full
+		FactorUtilization: big.NewRat(70, 1),
+		// 4.5M of 55M left is 8.18% headroom against the 20% considered safe
+		FactorCapHeadroom: new(big.Rat).Mul(new(big.Rat).Quo(new(big.Rat).Sub(big.NewRat(20, 100), big.NewRat(45, 550)), big.NewRat(20, 100)), big.NewRat(100, 1)),
+		// 0.1% of supply is a tenth of the 1% that scores 100
+		FactorBadDebt:      big.NewRat(10, 1),
+		FactorOracle:       big.NewRat(5, 1),
+		FactorMarketStatus: new(big.Rat),
+	}
+	for factor, want := range expected {
+		if got := score(t, a, factor); got == nil || got.Cmp(want) != 0 {
+			t.Errorf("Expected %s to score %s, got %v", factor, want.FloatString(2), got)
+		}
+	}
+	// the weighted mean of 34.16 is below half of the utilization score
+	if a.Overall.Cmp(big.NewRat(35, 1)) != 0 || a.Level != LevelMedium {
+		t.Errorf("Expected a medium overall score of 35, got %s (%s)", a.Overall.FloatString(2), a.Level)
+	}
+}
+
+func Test_AssessUnmeasuredFactors(t *testing.T) {
+	a := Assess(&Metrics{TotalSupply: usdc(200_000_000), TotalBorrow: usdc(100_000_000)}, DefaultThresholds, DefaultWeights)
+
+	if score(t, a, FactorBadDebt) != nil || score(t, a, FactorOracle) != nil || score(t, a, FactorMarketStatus) != nil {
+		t.Errorf("Expected bad debt, oracle and status to be unmeasured")
+	}
+	// uncapped markets have all the headroom they need
+	if s := score(t, a, FactorCapHeadroom); s == nil || s.Sign() != 0 {
+		t.Errorf("Expected an uncapped market to score 0, got %v", s)
+	}
+	// (0*15 + 25*25 + 0*10) / 50
+	if want := big.NewRat(625, 50); a.Mean(Factors...).Cmp(want) != 0 {
+		t.Errorf("Expected the mean of the measured factors, got %s", a.Mean(Factors...).FloatString(2))
+	}
+	if a.Mean(FactorBadDebt, FactorOracle, FactorMarketStatus) != nil {
+		t.Errorf("Expected no mean of unmeasured factors")
+	}
+}
+
+func Test_AssessSingleFailingFactor(t *testing.T) {
+	stale := DefaultThresholds.OracleHeartbeat + 1
+	testCases := []struct {
+		name    string
+		metrics Metrics
+		level   Level
+	}{
+		{name: "paused", metrics: Metrics{TotalSupply: usdc(500_000_000), TotalBorrow: usdc(0), Status: &Status{Paused: true}}, level: LevelHigh},
+		{name: "stale oracle", metrics: Metrics{TotalSupply: usdc(500_000_000), TotalBorrow: usdc(0), OraclePrice: big.NewRat(1, 1), OracleAge: &stale, Status: &Status{}}, level: LevelHigh},
+		{name: "frozen", metrics: Metrics{TotalSupply: usdc(500_000_000), TotalBorrow: usdc(0), Status: &Status{Frozen: true}}, level: LevelMedium},
+		{name: "healthy", metrics: Metrics{TotalSupply: usdc(500_000_000), TotalBorrow: usdc(0), Status: &Status{}}, level: LevelLow},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if a := Assess(&tc.metrics, DefaultThresholds, DefaultWeights); a.Level != tc.level {
+				t.Errorf("Expected %s, got %s (%s)", tc.level, a.Level, a.Overall.FloatString(2))
+			}
+		})
+	}
+}
+
+func Test_AssessAdminControl(t *testing.T) {
+	day := uint64(24 * 60 * 60)
+	testCases := []struct {
+		name       string
+		governance *Governance
+		score      *big.Rat
+	}{
+		{name: "single key upgrades", governance: &Governance{Upgrade: &Control{Threshold: 1}, Admin: &Control{Threshold: 5}}, score: big.NewRat(100, 1)},
+		{name: "3 of 5 safe", governance: &Governance{Admin: &Control{Threshold: 3}}, score: big.NewRat(50, 1)},
+		// a two day timelock takes off three quarters
+		{name: "single key behind timelock", governance: &Governance{Upgrade: &Control{Threshold: 1, Delay: 2 * day}}, score: big.NewRat(25, 1)},
+		{name: "governor behind one day timelock", governance: &Governance{Upgrade: &Control{Delay: day}}, score: big.NewRat(125, 4)},
+		{name: "single key pause guardian", governance: &Governance{Admin: &Control{Threshold: 6, Delay: 2 * day}, Pause: &Control{Threshold: 1}}, score: big.NewRat(50, 1)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := Assess(&Metrics{TotalSupply: usdc(500_000_000), TotalBorrow: usdc(0), Governance: tc.governance}, DefaultThresholds, DefaultWeights)
+			if got := score(t, a, FactorAdmin); got == nil || got.Cmp(tc.score) != 0 {
+				t.Errorf("Expected admin to score %s, got %v", tc.score.FloatString(2), got)
+			}
+		})
+	}
+
+	a := Assess(&Metrics{TotalSupply: usdc(500_000_000), TotalBorrow: usdc(0)}, DefaultThresholds, DefaultWeights)
+	if score(t, a, FactorAdmin) != nil {
+		t.Errorf("Expected admin to be unmeasured without governance")
+	}
+}
+
+func Test_AssessSecurityRecord(t *testing.T) {
+	day := uint64(24 * 60 * 60)
+	recent, old, fresh, stale := 45*day, 400*day, 100*day, 800*day
+	testCases := []struct {
+		name     string
+		security *Security
+		score    *big.Rat
+	}{
+		{name: "clean and audited", security: &Security{AuditAge: &fresh}, score: new(big.Rat)},
+		{name: "never audited", security: &Security{}, score: big.NewRat(50, 1)},
+		{name: "stale audit", security: &Security{AuditAge: &stale}, score: big.NewRat(30, 1)},
+		// a quarter of the window has passed
+		{name: "recent exploit", security: &Security{ExploitAge: &recent, AuditAge: &fresh}, score: big.NewRat(45, 1)},
+		{name: "old exploit", security: &Security{ExploitAge: &old, AuditAge: &fresh}, score: new(big.Rat)},
+		{name: "active incident", security: &Security{ActiveSeverity: SeverityHigh, AuditAge: &fresh}, score: big.NewRat(80, 1)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := Assess(&Metrics{TotalSupply: usdc(500_000_000), TotalBorrow: usdc(0), Security: tc.security}, DefaultThresholds, DefaultWeights)
+			if got := score(t, a, FactorSecurity); got == nil || got.Cmp(tc.score) != 0 {
+				t.Errorf("Expected security to score %s, got %v", tc.score.FloatString(2), got)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+// Package scan keeps the latest scan of every market, as captured by a
+// full_market_snapshot task, so comparison and ranking tasks run within its freshness
+// window reuse the states it read instead of reading every market again.
+package scan
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+)
+
+// Config sets how long a scan is reused
+type Config struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Freshness is how long after it was taken a scan is reused. Keep it near the block
+	// time of the slowest chain, since rates change with every block.
+	Freshness time.Duration `yaml:"freshness"`
+}
+
+// DefaultConfig reuses scans for 12 seconds, about one Ethereum block
+func DefaultConfig() Config {
+	return Config{Enabled: true, Freshness: 12 * time.Second}
+}
+
+// Validate checks the freshness window of enabled scans
+func (c Config) Validate() error {
+	if c.Enabled && c.Freshness <= 0 {
+		return fmt.Errorf("freshness must be positive")
+	}
+	return nil
+}
+
+// Market identifies the USDC market of a protocol on a chain
+type Market struct {
+	Protocol string
+	ChainID  uint64
+}
+
+// Scan is the state of every market read at once. Markets that could not be read are
+// left out.
+type Scan struct {
+	ID      string
+	TakenAt time.Time
+	States  map[Market]*adapters.MarketState
+}
+
+// Store holds the latest scan. A nil store keeps nothing.
+type Store struct {
+	freshness time.Duration
+	now       func() time.Time
+
+	mu     sync.RWMutex
+	latest *Scan
+}
+
+// NewStore creates a store reusing scans for freshness
+func NewStore(freshness time.Duration) *Store {
+	return &Store{freshness: freshness, now: time.Now}
+}
+
+// NewFromConfig creates a store from cfg, nil when scans are not reused
+func NewFromConfig(cfg Config) *Store {
+	if !cfg.Enabled {
+		return nil
+	}
+	return NewStore(cfg.Freshness)
+}
+
+// Put replaces the latest scan with s unless a newer one is kept
+func (s *Store) Put(scan *Scan) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.latest == nil || !scan.TakenAt.Before(s.latest.TakenAt) {
+		s.latest = scan
+	}
+}
+
+// Latest returns the latest scan while it is fresh
+func (s *Store) Latest() (*Scan, bool) {
+	if s == nil {
+		return nil, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.latest == nil || s.now().Sub(s.latest.TakenAt) > s.freshness {
+		return nil, false
+	}
+	return s.latest, true
+}
+
+// MarketState returns the state of the market of protocol on chainID in the latest scan,
+// while it is fresh
+func (s *Store) MarketState(protocol string, chainID uint64) (*adapters.MarketState, bool) {
+	latest, ok := s.Latest()
+	if !ok {
+		return nil, false
+	}
+	state, ok := latest.States[Market{Protocol: protocol, ChainID: chainID}]
+	return state, ok
+}
+
+// FreshUntil returns when scan stops being reused, the zero time when scans are not
+// reused
+func (s *Store) FreshUntil(scan *Scan) time.Time {
+	if s == nil {
+		return time.Time{}
+	}
+	return scan.TakenAt.Add(s.freshness)
+}
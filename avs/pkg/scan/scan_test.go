@@ -0,0 +1,58 @@
+package scan
+
+import (
+	"testing"
+	"time"
+
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+)
+
+func Test_Store(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	store := NewStore(12 * time.Second)
+	store.now = func() time.Time { return now }
+
+	aave := Market{Protocol: adapters.ProtocolAaveV3, ChainID: 1}
+	older := &Scan{ID: "older", TakenAt: now.Add(-5 * time.Second), States: map[Market]*adapters.MarketState{aave: {ChainID: 1}}}
+	newer := &Scan{ID: "newer", TakenAt: now.Add(-2 * time.Second), States: map[Market]*adapters.MarketState{}}
+
+	if _, ok := store.Latest(); ok {
+		t.Fatalf("Expected an empty store to have no scan")
+	}
+	store.Put(older)
+	if _, ok := store.MarketState(adapters.ProtocolAaveV3, 1); !ok {
+		t.Errorf("Expected the aave market to be reused from the fresh scan")
+	}
+	if _, ok := store.MarketState(adapters.ProtocolCompoundV3, 1); ok {
+		t.Errorf("Expected a market the scan left out not to be reused")
+	}
+
+	store.Put(newer)
+	store.Put(older)
+	if latest, ok := store.Latest(); !ok || latest.ID != "newer" {
+		t.Errorf("Expected the newer scan to be kept, got %+v", latest)
+	}
+	if until := store.FreshUntil(newer); !until.Equal(now.Add(10 * time.Second)) {
+		t.Errorf("Expected the newer scan to be fresh for 10 more seconds, got %v", until)
+	}
+
+	now = now.Add(11 * time.Second)
+	if _, ok := store.Latest(); ok {
+		t.Errorf("Expected the scan to go stale after its freshness window")
+	}
+}
+
+func Test_NilStore(t *testing.T) {
+	store := NewFromConfig(Config{Enabled: false})
+	if store != nil {
+		t.Fatalf("Expected a disabled config to create no store")
+	}
+	scan := &Scan{ID: "scan", TakenAt: time.Now()}
+	store.Put(scan)
+	if _, ok := store.Latest(); ok {
+		t.Errorf("Expected a nil store to keep nothing")
+	}
+	if until := store.FreshUntil(scan); !until.IsZero() {
+		t.Errorf("Expected a nil store not to reuse scans, got %v", until)
+	}
+}
@@ -0,0 +1,188 @@
+// Package schema versions the JSON encoding of task results, so result formats can evolve
+// while operators on different releases still produce byte-identical results for the
+// same task, which BLS signature aggregation requires.
+//
+// Results are built in the Current version and downgraded one version at a time to the
+// version a task asks for. Each version registers how its documents turn into documents
+// of the version before it, so a task naming the oldest version its operators run gets
+// the same bytes from all of them.
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+)
+
+const (
+	// Version1 is the envelope of task_type and result, without a schema_version
+	Version1 = 1
+
+	// Version2 adds schema_version to the envelope
+	Version2 = 2
+
+	// Version3 adds the bridge routes, route and routes, to cross_chain_yield_check
+	// results
+	Version3 = 3
+
+	// Version4 adds the stability of supply rates to yield_monitoring results
+	Version4 = 4
+
+	// Version5 adds the rate models of markets to yield_monitoring results, and the rates
+	// projected once the amount is deposited to cross_chain_yield_check results
+	Version5 = 5
+
+	// Version6 adds the commitment to the result to the envelope. Its result hash covers
+	// the result of Version6, so later versions changing results recompute it when
+	// downgrading.
+	Version6 = 6
+
+	// Current is the version results are built in
+	Current = Version6
+
+	// Oldest is the oldest version results can be encoded in
+	Oldest = Version1
+)
+
+// Field is the envelope field carrying the version of versioned results
+const Field = "schema_version"
+
+// Document is a result envelope decoded into maps, slices and json.Numbers
+type Document map[string]interface{}
+
+// downgrades[v] turns a document of version v into one of version v-1
+var downgrades = map[int]func(doc Document) error{
+	Version2: func(doc Document) error {
+		delete(doc, Field)
+		return nil
+	},
+	Version3: func(doc Document) error {
+		for _, result := range resultsOf(doc, "cross_chain_yield_check") {
+			delete(result, "route")
+			delete(result, "routes")
+		}
+		return nil
+	},
+	Version4: func(doc Document) error {
+		for _, result := range resultsOf(doc, "yield_monitoring") {
+			delete(result, "stability")
+			markets, _ := result["markets"].([]interface{})
+			for _, m := range markets {
+				if m, ok := m.(map[string]interface{}); ok {
+					delete(m, "stability")
+				}
+			}
+		}
+		return nil
+	},
+	Version5: func(doc Document) error {
+		for _, result := range resultsOf(doc, "yield_monitoring") {
+			delete(result, "rate_model")
+			markets, _ := result["markets"].([]interface{})
+			for _, m := range markets {
+				if m, ok := m.(map[string]interface{}); ok {
+					delete(m, "rate_model")
+				}
+			}
+		}
+		for _, result := range resultsOf(doc, "cross_chain_yield_check") {
+			delete(result, "target_projected_rate")
+			delete(result, "projected_improvement_bps")
+			markets, _ := result["markets"].([]interface{})
+			for _, m := range markets {
+				if m, ok := m.(map[string]interface{}); ok {
+					delete(m, "projected_rate")
+				}
+			}
+		}
+		return nil
+	},
+	Version6: func(doc Document) error {
+		delete(doc, "commitment")
+		return nil
+	},
+}
+
+// resultsOf returns the results of taskType in an envelope, including those of the items
+// of batch results
+func resultsOf(envelope map[string]interface{}, taskType string) []map[string]interface{} {
+	result, ok := envelope["result"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	switch envelope["task_type"] {
+	case taskType:
+		return []map[string]interface{}{result}
+	case "batch":
+		items, _ := result["results"].([]interface{})
+		var out []map[string]interface{}
+		for _, item := range items {
+			if item, ok := item.(map[string]interface{}); ok {
+				out = append(out, resultsOf(item, taskType)...)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// Supported reports whether results can be encoded in version
+func Supported(version int) bool {
+	return version >= Oldest && version <= Current
+}
+
+// Encode encodes envelope, built in the Current version, as canonical JSON of version
+func Encode(envelope interface{}, version int) ([]byte, error) {
+	if !Supported(version) {
+		return nil, fmt.Errorf("unsupported schema version %d, must be from %d to %d", version, Oldest, Current)
+	}
+	raw, err := json.Marshal(envelope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode result: %w", err)
+	}
+	doc, err := decode(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	doc[Field] = Current
+	for v := Current; v > version; v-- {
+		if err := downgrades[v](doc); err != nil {
+			return nil, fmt.Errorf("failed to downgrade result to schema version %d: %w", v-1, err)
+		}
+		if _, ok := doc[Field]; ok {
+			doc[Field] = v - 1
+		}
+	}
+	return canonical.Marshal(doc)
+}
+
+// VersionOf returns the version of an encoded result. Results without a schema_version
+// are of Version1.
+func VersionOf(encoded []byte) (int, error) {
+	var envelope struct {
+		Version *int `json:"schema_version"`
+	}
+	if err := json.Unmarshal(encoded, &envelope); err != nil {
+		return 0, fmt.Errorf("failed to decode result: %w", err)
+	}
+	if envelope.Version == nil {
+		return Version1, nil
+	}
+	return *envelope.Version, nil
+}
+
+func decode(raw []byte) (Document, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var doc Document
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to decode result: %w", err)
+	}
+	if doc == nil {
+		return nil, fmt.Errorf("result is not an object")
+	}
+	return doc, nil
+}
@@ -0,0 +1,149 @@
+package schema
+
+import (
+	"testing"
+)
+
+type envelope struct {
+	TaskType string      `json:"task_type"`
+	Result   interface{} `json:"result"`
+}
+
+func Test_EncodeVersions(t *testing.T) {
+	result := envelope{TaskType: "yield_monitoring", Result: map[string]interface{}{"chain_id": 8453, "supply_rate": "3.8400"}}
+
+	current, err := Encode(result, Current)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if want := `{"result":{"chain_id":8453,"supply_rate":"3.8400"},"schema_version":6,"task_type":"yield_monitoring"}`; string(current) != want {
+		t.Errorf("Unexpected current encoding:\n got: %s\nwant: %s", current, want)
+	}
+
+	previous, err := Encode(result, Version1)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if want := `{"result":{"chain_id":8453,"supply_rate":"3.8400"},"task_type":"yield_monitoring"}`; string(previous) != want {
+		t.Errorf("Unexpected version 1 encoding:\n got: %s\nwant: %s", previous, want)
+	}
+
+	for encoded, want := range map[string]int{string(current): Current, string(previous): Version1} {
+		if version, err := VersionOf([]byte(encoded)); err != nil || version != want {
+			t.Errorf("Expected version %d of %s, got %d, %v", want, encoded, version, err)
+		}
+	}
+}
+
+func Test_EncodeRejectsUnsupportedVersions(t *testing.T) {
+	for _, version := range []int{0, Current + 1} {
+		if _, err := Encode(envelope{}, version); err == nil {
+			t.Errorf("Expected version %d to be rejected", version)
+		}
+	}
+	if _, err := Encode([]int{1}, Current); err == nil {
+		t.Errorf("Expected a result that is not an object to be rejected")
+	}
+}
+
+func Test_EncodeVersion2WithoutRoutes(t *testing.T) {
+	crossChain := map[string]interface{}{"source_chain": 1, "route": map[string]interface{}{"bridge": "cctp_v2_standard"}, "routes": []interface{}{}}
+	testCases := map[string]struct {
+		envelope envelope
+		want     string
+	}{
+		"task": {
+			envelope: envelope{TaskType: "cross_chain_yield_check", Result: crossChain},
+			want:     `{"result":{"source_chain":1},"schema_version":2,"task_type":"cross_chain_yield_check"}`,
+		},
+		"batch item": {
+			envelope: envelope{TaskType: "batch", Result: map[string]interface{}{"results": []interface{}{
+				envelope{TaskType: "cross_chain_yield_check", Result: crossChain},
+				envelope{TaskType: "yield_monitoring", Result: map[string]interface{}{"route": "kept"}},
+			}}},
+			want: `{"result":{"results":[{"result":{"source_chain":1},"task_type":"cross_chain_yield_check"},{"result":{"route":"kept"},"task_type":"yield_monitoring"}]},"schema_version":2,"task_type":"batch"}`,
+		},
+	}
+	for name, tc := range testCases {
+		encoded, err := Encode(tc.envelope, Version2)
+		if err != nil {
+			t.Fatalf("%s: Encode failed: %v", name, err)
+		}
+		if string(encoded) != tc.want {
+			t.Errorf("%s: unexpected version 2 encoding:\n got: %s\nwant: %s", name, encoded, tc.want)
+		}
+	}
+}
+
+func Test_EncodeVersion3WithoutStability(t *testing.T) {
+	stable := map[string]interface{}{"chain_id": 1, "stability": map[string]interface{}{"evaluated": false}}
+	testCases := map[string]struct {
+		envelope envelope
+		want     string
+	}{
+		"market": {
+			envelope: envelope{TaskType: "yield_monitoring", Result: stable},
+			want:     `{"result":{"chain_id":1},"schema_version":3,"task_type":"yield_monitoring"}`,
+		},
+		"all markets": {
+			envelope: envelope{TaskType: "yield_monitoring", Result: map[string]interface{}{"markets": []interface{}{stable}}},
+			want:     `{"result":{"markets":[{"chain_id":1}]},"schema_version":3,"task_type":"yield_monitoring"}`,
+		},
+	}
+	for name, tc := range testCases {
+		encoded, err := Encode(tc.envelope, Version3)
+		if err != nil {
+			t.Fatalf("%s: Encode failed: %v", name, err)
+		}
+		if string(encoded) != tc.want {
+			t.Errorf("%s: unexpected version 3 encoding:\n got: %s\nwant: %s", name, encoded, tc.want)
+		}
+	}
+}
+
+func Test_EncodeVersion4WithoutRateModels(t *testing.T) {
+	testCases := map[string]struct {
+		envelope envelope
+		want     string
+	}{
+		"monitoring": {
+			envelope: envelope{TaskType: "yield_monitoring", Result: map[string]interface{}{
+				"markets": []interface{}{map[string]interface{}{"chain_id": 1, "rate_model": map[string]interface{}{"kind": "kink"}}},
+			}},
+			want: `{"result":{"markets":[{"chain_id":1}]},"schema_version":4,"task_type":"yield_monitoring"}`,
+		},
+		"cross chain": {
+			envelope: envelope{TaskType: "cross_chain_yield_check", Result: map[string]interface{}{
+				"target_rate": "4.0000", "target_projected_rate": "3.9000", "projected_improvement_bps": "10.00",
+				"markets": []interface{}{map[string]interface{}{"chain_id": 8453, "projected_rate": "3.9000"}},
+			}},
+			want: `{"result":{"markets":[{"chain_id":8453}],"target_rate":"4.0000"},"schema_version":4,"task_type":"cross_chain_yield_check"}`,
+		},
+	}
+	for name, tc := range testCases {
+		encoded, err := Encode(tc.envelope, Version4)
+		if err != nil {
+			t.Fatalf("%s: Encode failed: %v", name, err)
+		}
+		if string(encoded) != tc.want {
+			t.Errorf("%s: unexpected version 4 encoding:\n got: %s\nwant: %s", name, encoded, tc.want)
+		}
+	}
+}
+
+func Test_EncodeVersion5WithoutCommitment(t *testing.T) {
+	committed := struct {
+		envelope
+		Commitment map[string]interface{} `json:"commitment"`
+	}{
+		envelope:   envelope{TaskType: "yield_monitoring", Result: map[string]interface{}{"chain_id": 1}},
+		Commitment: map[string]interface{}{"result_hash": "0x01"},
+	}
+	encoded, err := Encode(committed, Version5)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if want := `{"result":{"chain_id":1},"schema_version":5,"task_type":"yield_monitoring"}`; string(encoded) != want {
+		t.Errorf("Unexpected version 5 encoding:\n got: %s\nwant: %s", encoded, want)
+	}
+}
@@ -0,0 +1,207 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	smtypes "github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+)
+
+// EnvProvider reads secrets from the environment variables they are named after
+type EnvProvider struct{}
+
+func (EnvProvider) Get(ctx context.Context, name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return "", fmt.Errorf("environment variable %s: %w", name, ErrNotFound)
+	}
+	return value, nil
+}
+
+// FileConfig locates the directory secret files are read from, such as a Kubernetes
+// secret volume
+type FileConfig struct {
+	Dir string `yaml:"dir"`
+}
+
+// FileProvider reads secrets from the files they are named after, without their final
+// line break. Files replaced in place, as Kubernetes does with secret volumes, are read
+// again on refresh.
+type FileProvider struct {
+	dir string
+}
+
+// NewFileProvider creates a provider reading files under cfg.Dir
+func NewFileProvider(cfg FileConfig) *FileProvider {
+	return &FileProvider{dir: cfg.Dir}
+}
+
+func (p *FileProvider) Get(ctx context.Context, name string) (string, error) {
+	if !filepath.IsLocal(name) {
+		return "", fmt.Errorf("file %s is not under the secrets directory", name)
+	}
+	data, err := os.ReadFile(filepath.Join(p.dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("file %s: %w", name, ErrNotFound)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file %s: %w", name, err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// VaultConfig locates a KV version 2 secrets engine of HashiCorp Vault
+type VaultConfig struct {
+	Addr string `yaml:"addr"`
+
+	// TokenEnv names the environment variable holding the Vault token
+	TokenEnv string `yaml:"tokenEnv"`
+
+	// Mount is the path the KV engine is mounted at
+	Mount     string        `yaml:"mount"`
+	Namespace string        `yaml:"namespace"`
+	Timeout   time.Duration `yaml:"timeout"`
+}
+
+// VaultProvider reads secrets named path#key from a Vault KV version 2 engine, the key
+// defaulting to value. Reads return the latest version, so rotated secrets are picked up
+// on refresh.
+type VaultProvider struct {
+	cfg        VaultConfig
+	token      string
+	httpClient *http.Client
+}
+
+// NewVaultProvider creates a provider reading from the engine in cfg with the token in
+// its environment variable
+func NewVaultProvider(cfg VaultConfig) (*VaultProvider, error) {
+	token := os.Getenv(cfg.TokenEnv)
+	if token == "" {
+		return nil, fmt.Errorf("environment variable %s is not set", cfg.TokenEnv)
+	}
+	return &VaultProvider{cfg: cfg, token: token, httpClient: &http.Client{Timeout: cfg.Timeout}}, nil
+}
+
+func (p *VaultProvider) Get(ctx context.Context, name string) (string, error) {
+	path, key, _ := strings.Cut(name, "#")
+	if key == "" {
+		key = "value"
+	}
+	endpoint, err := url.JoinPath(p.cfg.Addr, "v1", p.cfg.Mount, "data", path)
+	if err != nil {
+		return "", fmt.Errorf("invalid vault path %s: %w", path, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", p.token)
+	if p.cfg.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", p.cfg.Namespace)
+	}
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("vault unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return "", fmt.Errorf("vault path %s: %w", path, ErrNotFound)
+	case resp.StatusCode != http.StatusOK:
+		return "", fmt.Errorf("vault path %s: status %d", path, resp.StatusCode)
+	}
+	var body struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode vault path %s: %w", path, err)
+	}
+	value, ok := body.Data.Data[key].(string)
+	if !ok {
+		return "", fmt.Errorf("key %s of vault path %s: %w", key, path, ErrNotFound)
+	}
+	return value, nil
+}
+
+// AWSConfig selects the AWS Secrets Manager region. Credentials come from the default
+// AWS chain: environment, shared config or the instance role.
+type AWSConfig struct {
+	Region string `yaml:"region"`
+
+	// Endpoint overrides the Secrets Manager endpoint, for VPC endpoints and local
+	// emulators
+	Endpoint string `yaml:"endpoint"`
+}
+
+// AWSSecretsClient is the part of the AWS Secrets Manager client reading secrets needs
+type AWSSecretsClient interface {
+	GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error)
+}
+
+// AWSProvider reads secrets named secret-id, or secret-id#key for a key of a JSON
+// secret, from AWS Secrets Manager. Reads return the current version, so rotated
+// secrets are picked up on refresh.
+type AWSProvider struct {
+	client AWSSecretsClient
+}
+
+// NewAWSProvider creates a provider reading from the region in cfg
+func NewAWSProvider(ctx context.Context, cfg AWSConfig) (*AWSProvider, error) {
+	var opts []func(*awsconfig.LoadOptions) error
+	if cfg.Region != "" {
+		opts = append(opts, awsconfig.WithRegion(cfg.Region))
+	}
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load aws config: %w", err)
+	}
+	return NewAWSProviderWithClient(secretsmanager.NewFromConfig(awsCfg, func(o *secretsmanager.Options) {
+		if cfg.Endpoint != "" {
+			o.BaseEndpoint = aws.String(cfg.Endpoint)
+		}
+	})), nil
+}
+
+// NewAWSProviderWithClient creates a provider reading with client
+func NewAWSProviderWithClient(client AWSSecretsClient) *AWSProvider {
+	return &AWSProvider{client: client}
+}
+
+func (p *AWSProvider) Get(ctx context.Context, name string) (string, error) {
+	id, key, _ := strings.Cut(name, "#")
+	out, err := p.client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{SecretId: aws.String(id)})
+	var notFound *smtypes.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		return "", fmt.Errorf("aws secret %s: %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read aws secret %s: %w", id, err)
+	}
+	value := aws.ToString(out.SecretString)
+	if key == "" {
+		return value, nil
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(value), &fields); err != nil {
+		// the decoding error may quote the secret
+		return "", fmt.Errorf("aws secret %s is not a JSON object", id)
+	}
+	field, ok := fields[key].(string)
+	if !ok {
+		return "", fmt.Errorf("key %s of aws secret %s: %w", key, id, ErrNotFound)
+	}
+	return field, nil
+}
@@ -0,0 +1,310 @@
+// Package secrets reads the secrets the config refers to, such as RPC API keys, the
+// Circle API key and signer credentials, from a provider: the environment, files,
+// HashiCorp Vault or AWS Secrets Manager.
+//
+// Config values refer to secrets as ${secret:name}, alone or within a value such as an
+// RPC URL, so the config file never holds them. The store expands the references once
+// loaded, remembers the values to hide them in logs, and reads them again periodically
+// so secrets rotated in providers that support it take effect without a restart.
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Providers
+const (
+	ProviderEnv        = "env"
+	ProviderFile       = "file"
+	ProviderVault      = "vault"
+	ProviderAWSSecrets = "aws_secrets_manager"
+)
+
+// Redacted replaces secrets in logs
+const Redacted = "[secret]"
+
+// minRedactedLength is the length under which values are not hidden, since replacing
+// every occurrence of a short string would garble logs and hide nothing
+const minRedactedLength = 6
+
+// ErrNotFound is returned for secrets the provider does not hold
+var ErrNotFound = errors.New("secret not found")
+
+var reference = regexp.MustCompile(`\$\{secret:([^}]+)\}`)
+
+// Provider reads secrets by name. Errors never include secret values.
+type Provider interface {
+	Get(ctx context.Context, name string) (string, error)
+}
+
+// Config selects the provider secrets are read from
+type Config struct {
+	// Provider is env, file, vault or aws_secrets_manager. Names are environment
+	// variables for env, file paths under File.Dir for file, path#key in the KV engine
+	// for vault and secret-id or secret-id#key of JSON secrets for aws_secrets_manager.
+	Provider string `yaml:"provider"`
+
+	File  FileConfig  `yaml:"file"`
+	Vault VaultConfig `yaml:"vault"`
+	AWS   AWSConfig   `yaml:"aws"`
+
+	// RefreshInterval is how often secrets are read again, so rotated ones take effect.
+	// Zero reads them once. Secrets in the environment never change.
+	RefreshInterval time.Duration `yaml:"refreshInterval"`
+}
+
+// DefaultConfig reads secrets from the environment
+func DefaultConfig() Config {
+	return Config{
+		Provider:        ProviderEnv,
+		Vault:           VaultConfig{TokenEnv: "VAULT_TOKEN", Mount: "secret", Timeout: 10 * time.Second},
+		RefreshInterval: 5 * time.Minute,
+	}
+}
+
+// Validate checks the config for values no provider can be created from
+func (c Config) Validate() error {
+	switch c.Provider {
+	case ProviderEnv:
+	case ProviderFile:
+		if c.File.Dir == "" {
+			return fmt.Errorf("file.dir is required for the file provider")
+		}
+	case ProviderVault:
+		if c.Vault.Addr == "" || c.Vault.TokenEnv == "" || c.Vault.Mount == "" {
+			return fmt.Errorf("vault.addr, vault.tokenEnv and vault.mount are required for the vault provider")
+		}
+		if c.Vault.Timeout <= 0 {
+			return fmt.Errorf("vault.timeout must be positive")
+		}
+	case ProviderAWSSecrets:
+	default:
+		return fmt.Errorf("unknown provider: %s", c.Provider)
+	}
+	if c.RefreshInterval < 0 {
+		return fmt.Errorf("refreshInterval must not be negative")
+	}
+	return nil
+}
+
+// NewProvider creates the provider selected in cfg
+func NewProvider(ctx context.Context, cfg Config) (Provider, error) {
+	switch cfg.Provider {
+	case ProviderFile:
+		return NewFileProvider(cfg.File), nil
+	case ProviderVault:
+		return NewVaultProvider(cfg.Vault)
+	case ProviderAWSSecrets:
+		return NewAWSProvider(ctx, cfg.AWS)
+	default:
+		return EnvProvider{}, nil
+	}
+}
+
+// HasReference reports whether value refers to a secret
+func HasReference(value string) bool {
+	return reference.MatchString(value)
+}
+
+// Store expands the secret references of the config and keeps the values read, so
+// they can be hidden in logs and read again when rotated
+type Store struct {
+	provider Provider
+	interval time.Duration
+	logger   *zap.Logger
+
+	mu     sync.RWMutex
+	values map[string]string
+
+	// previous are the values secrets were rotated from, still hidden since lines may
+	// carry them
+	previous map[string]string
+}
+
+// NewStore creates a store reading from provider, every interval when it is positive
+func NewStore(provider Provider, interval time.Duration, logger *zap.Logger) *Store {
+	return &Store{provider: provider, interval: interval, logger: logger, values: make(map[string]string), previous: make(map[string]string)}
+}
+
+// NewFromConfig creates the store reading from the provider of cfg. Secrets in the
+// environment are never read again.
+func NewFromConfig(ctx context.Context, cfg Config, logger *zap.Logger) (*Store, error) {
+	provider, err := NewProvider(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	interval := cfg.RefreshInterval
+	if cfg.Provider == ProviderEnv {
+		interval = 0
+	}
+	return NewStore(provider, interval, logger), nil
+}
+
+// Expand replaces the secret references in text with their values, reading the ones
+// not read yet
+func (s *Store) Expand(ctx context.Context, text string) (string, error) {
+	var errs []error
+	expanded := reference.ReplaceAllStringFunc(text, func(ref string) string {
+		name := reference.FindStringSubmatch(ref)[1]
+		value, err := s.get(ctx, name)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		return value
+	})
+	if err := errors.Join(errs...); err != nil {
+		return "", err
+	}
+	return expanded, nil
+}
+
+// ExpandAll replaces the secret references in every string v points to, in structs,
+// pointers, slices and maps
+func (s *Store) ExpandAll(ctx context.Context, v interface{}) error {
+	return s.expandValue(ctx, reflect.ValueOf(v))
+}
+
+func (s *Store) expandValue(ctx context.Context, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			return s.expandValue(ctx, v.Elem())
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Field(i).CanSet() {
+				if err := s.expandValue(ctx, v.Field(i)); err != nil {
+					return fmt.Errorf("%s: %w", v.Type().Field(i).Name, err)
+				}
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := s.expandValue(ctx, v.Index(i)); err != nil {
+				return fmt.Errorf("[%d]: %w", i, err)
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			// map values cannot be set in place
+			value := reflect.New(iter.Value().Type()).Elem()
+			value.Set(iter.Value())
+			if err := s.expandValue(ctx, value); err != nil {
+				return fmt.Errorf("[%v]: %w", iter.Key(), err)
+			}
+			v.SetMapIndex(iter.Key(), value)
+		}
+	case reflect.String:
+		if v.CanSet() && HasReference(v.String()) {
+			expanded, err := s.Expand(ctx, v.String())
+			if err != nil {
+				return err
+			}
+			v.SetString(expanded)
+		}
+	}
+	return nil
+}
+
+// get returns the value of name, reading it when it was not read yet
+func (s *Store) get(ctx context.Context, name string) (string, error) {
+	s.mu.RLock()
+	value, ok := s.values[name]
+	s.mu.RUnlock()
+	if ok {
+		return value, nil
+	}
+	value, err := s.provider.Get(ctx, name)
+	if err != nil {
+		return "", fmt.Errorf("secret %s: %w", name, err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[name] = value
+	return value, nil
+}
+
+// Redact replaces the values of the secrets read in text
+func (s *Store) Redact(text string) string {
+	if s == nil {
+		return text
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, values := range []map[string]string{s.values, s.previous} {
+		for _, value := range values {
+			if len(value) >= minRedactedLength {
+				text = strings.ReplaceAll(text, value, Redacted)
+			}
+		}
+	}
+	return text
+}
+
+// Refresh reads every secret read before again, and returns the names of those that
+// changed. Secrets that fail to read keep their value.
+func (s *Store) Refresh(ctx context.Context) ([]string, error) {
+	s.mu.RLock()
+	names := make([]string, 0, len(s.values))
+	for name := range s.values {
+		names = append(names, name)
+	}
+	s.mu.RUnlock()
+	slices.Sort(names)
+
+	var changed []string
+	var errs []error
+	for _, name := range names {
+		value, err := s.provider.Get(ctx, name)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("secret %s: %w", name, err))
+			continue
+		}
+		s.mu.Lock()
+		if previous := s.values[name]; previous != value {
+			s.previous[name] = previous
+			s.values[name] = value
+			changed = append(changed, name)
+		}
+		s.mu.Unlock()
+	}
+	return changed, errors.Join(errs...)
+}
+
+// Start reads the secrets again every refresh interval until ctx is done, calling
+// onRotate after any of them changed
+func (s *Store) Start(ctx context.Context, onRotate func(ctx context.Context)) {
+	if s.interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			changed, err := s.Refresh(ctx)
+			if err != nil {
+				s.logger.Sugar().Warnw("Failed to read secrets again, keeping their values", "error", err)
+			}
+			if len(changed) > 0 {
+				s.logger.Sugar().Infow("Secrets rotated", "secrets", changed)
+				onRotate(ctx)
+			}
+		}
+	}()
+}
@@ -0,0 +1,185 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	smtypes "github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"go.uber.org/zap"
+)
+
+// mapProvider holds secrets in a map, counting reads
+type mapProvider struct {
+	values map[string]string
+	reads  int
+}
+
+func (p *mapProvider) Get(ctx context.Context, name string) (string, error) {
+	p.reads++
+	value, ok := p.values[name]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+func Test_ExpandAllAndRotate(t *testing.T) {
+	provider := &mapProvider{values: map[string]string{"alchemy-key": "alchemy-v1", "circle-api-key": "circle-v1"}}
+	store := NewStore(provider, 0, zap.NewNop())
+	type endpoint struct{ Url string }
+	cfg := struct {
+		ApiKey    string
+		Endpoints map[string][]endpoint
+		Circle    *struct{ ApiKey string }
+		Plain     string
+		hidden    string
+	}{
+		ApiKey:    "${secret:circle-api-key}",
+		Endpoints: map[string][]endpoint{"base": {{Url: "https://base.g.alchemy.com/v2/${secret:alchemy-key}"}}},
+		Circle:    &struct{ ApiKey string }{ApiKey: "${secret:circle-api-key}"},
+		Plain:     "no references",
+		hidden:    "${secret:alchemy-key}",
+	}
+	ctx := context.Background()
+	if err := store.ExpandAll(ctx, &cfg); err != nil {
+		t.Fatalf("ExpandAll failed: %v", err)
+	}
+	if cfg.ApiKey != "circle-v1" || cfg.Circle.ApiKey != "circle-v1" || cfg.Endpoints["base"][0].Url != "https://base.g.alchemy.com/v2/alchemy-v1" || cfg.Plain != "no references" {
+		t.Errorf("Expected the references to be expanded, got %+v", cfg)
+	}
+	if provider.reads != 2 {
+		t.Errorf("Expected each secret to be read once, got %d reads", provider.reads)
+	}
+
+	missing := struct{ Key string }{Key: "${secret:missing}"}
+	if err := store.ExpandAll(ctx, &missing); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected a missing secret to fail, got %v", err)
+	}
+
+	provider.values["circle-api-key"] = "circle-v2"
+	changed, err := store.Refresh(ctx)
+	if err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if !reflect.DeepEqual(changed, []string{"circle-api-key"}) {
+		t.Errorf("Expected the rotated secret to be reported, got %v", changed)
+	}
+	if expanded, _ := store.Expand(ctx, "${secret:circle-api-key}"); expanded != "circle-v2" {
+		t.Errorf("Expected the rotated value, got %s", expanded)
+	}
+	if redacted := store.Redact("keys circle-v1 circle-v2 alchemy-v1 short"); redacted != "keys [secret] [secret] [secret] short" {
+		t.Errorf("Expected current and rotated values to be hidden, got %s", redacted)
+	}
+}
+
+func Test_FileProvider(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "circle-api-key"), []byte("circle-key\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	provider := NewFileProvider(FileConfig{Dir: dir})
+	ctx := context.Background()
+	if value, err := provider.Get(ctx, "circle-api-key"); err != nil || value != "circle-key" {
+		t.Errorf("Expected the file without its line break, got %q, %v", value, err)
+	}
+	if _, err := provider.Get(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected a missing file to be not found, got %v", err)
+	}
+	if _, err := provider.Get(ctx, "../etc/passwd"); err == nil {
+		t.Errorf("Expected paths outside the directory to be refused")
+	}
+}
+
+func Test_VaultProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "vault-token" || r.Header.Get("X-Vault-Namespace") != "avs" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.URL.Path != "/v1/kv/data/operator/rpc" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"data":{"value":"default-key","alchemy":"alchemy-key"}}}`))
+	}))
+	defer server.Close()
+	t.Setenv("TEST_VAULT_TOKEN", "vault-token")
+
+	provider, err := NewVaultProvider(VaultConfig{Addr: server.URL, TokenEnv: "TEST_VAULT_TOKEN", Mount: "kv", Namespace: "avs"})
+	if err != nil {
+		t.Fatalf("NewVaultProvider failed: %v", err)
+	}
+	ctx := context.Background()
+	for name, want := range map[string]string{"operator/rpc": "default-key", "operator/rpc#alchemy": "alchemy-key"} {
+		if value, err := provider.Get(ctx, name); err != nil || value != want {
+			t.Errorf("%s: expected %s, got %q, %v", name, want, value, err)
+		}
+	}
+	for _, name := range []string{"operator/other", "operator/rpc#missing"} {
+		if _, err := provider.Get(ctx, name); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: expected not found, got %v", name, err)
+		}
+	}
+	if _, err := NewVaultProvider(VaultConfig{Addr: server.URL, TokenEnv: "TEST_VAULT_TOKEN_UNSET"}); err == nil {
+		t.Errorf("Expected a missing token to fail")
+	}
+}
+
+// fakeSecretsManager holds AWS secrets by id
+type fakeSecretsManager map[string]string
+
+func (f fakeSecretsManager) GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error) {
+	value, ok := f[aws.ToString(params.SecretId)]
+	if !ok {
+		return nil, &smtypes.ResourceNotFoundException{Message: aws.String("not found")}
+	}
+	return &secretsmanager.GetSecretValueOutput{SecretString: aws.String(value)}, nil
+}
+
+func Test_AWSProvider(t *testing.T) {
+	provider := NewAWSProviderWithClient(fakeSecretsManager{
+		"circle-api-key": "circle-key",
+		"operator/rpc":   `{"alchemy":"alchemy-key"}`,
+	})
+	ctx := context.Background()
+	for name, want := range map[string]string{"circle-api-key": "circle-key", "operator/rpc#alchemy": "alchemy-key"} {
+		if value, err := provider.Get(ctx, name); err != nil || value != want {
+			t.Errorf("%s: expected %s, got %q, %v", name, want, value, err)
+		}
+	}
+	for _, name := range []string{"missing", "operator/rpc#missing"} {
+		if _, err := provider.Get(ctx, name); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: expected not found, got %v", name, err)
+		}
+	}
+	if _, err := provider.Get(ctx, "circle-api-key#key"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected a key of a plain secret to fail, got %v", err)
+	}
+}
+
+func Test_ValidateConfig(t *testing.T) {
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Errorf("Expected the default config to be valid: %v", err)
+	}
+	invalid := map[string]func(*Config){
+		"provider":   func(c *Config) { c.Provider = "keychain" },
+		"file dir":   func(c *Config) { c.Provider = ProviderFile },
+		"vault addr": func(c *Config) { c.Provider = ProviderVault },
+		"refresh":    func(c *Config) { c.RefreshInterval = -1 },
+	}
+	for name, change := range invalid {
+		cfg := DefaultConfig()
+		change(&cfg)
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("%s: expected the config to be rejected", name)
+		}
+	}
+}
@@ -0,0 +1,335 @@
+// Package security reads a signed feed of audits and security incidents of lending
+// protocols, such as an exploit tracker or an audit registry republished as JSON, so risk
+// assessments can account for protocols that were recently exploited or never audited.
+package security
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/najnomics/crosscow-avs/pkg/resilience"
+)
+
+// SignatureHeader carries the signature of the feed body: a 65 byte secp256k1 signature
+// of the EIP-191 personal message hash of the body, hex encoded
+const SignatureHeader = "X-Feed-Signature"
+
+// maxFeedSize bounds the body read from the feed
+const maxFeedSize = 8 << 20
+
+var (
+	// ErrInvalidSignature is returned for feeds not signed by a configured signer
+	ErrInvalidSignature = errors.New("security feed signature is invalid")
+
+	// ErrUnknownProtocol is returned for protocols the feed has no entry for
+	ErrUnknownProtocol = errors.New("protocol is not covered by the security feed")
+)
+
+// Severity grades an incident
+type Severity string
+
+const (
+	SeverityLow      Severity = "low"
+	SeverityMedium   Severity = "medium"
+	SeverityHigh     Severity = "high"
+	SeverityCritical Severity = "critical"
+)
+
+// Incident is an exploit or other security event of a protocol
+type Incident struct {
+	ID       string   `json:"id"`
+	Title    string   `json:"title"`
+	Severity Severity `json:"severity"`
+
+	// Active is set until the incident is resolved
+	Active bool `json:"active"`
+
+	// ChainIDs are the affected chains, empty when the whole protocol is affected
+	ChainIDs []uint64 `json:"chain_ids"`
+
+	// LossUsd is the amount lost in USD as a decimal string, empty when none or unknown
+	LossUsd string `json:"loss_usd"`
+
+	// Timestamp is when the incident happened, in unix seconds
+	Timestamp uint64 `json:"timestamp"`
+	Url       string `json:"url"`
+}
+
+// Affects reports whether the incident affects the deployment on chainID
+func (i *Incident) Affects(chainID uint64) bool {
+	if len(i.ChainIDs) == 0 {
+		return true
+	}
+	for _, id := range i.ChainIDs {
+		if id == chainID {
+			return true
+		}
+	}
+	return false
+}
+
+// Audit is a completed security review of a protocol
+type Audit struct {
+	Auditor string `json:"auditor"`
+	Scope   string `json:"scope"`
+
+	// Timestamp is when the report was published, in unix seconds
+	Timestamp uint64 `json:"timestamp"`
+	Url       string `json:"url"`
+}
+
+// Protocol is what the feed knows about one protocol
+type Protocol struct {
+	Audits    []Audit    `json:"audits"`
+	Incidents []Incident `json:"incidents"`
+}
+
+// LatestAudit returns the most recent audit, nil when the protocol was never audited
+func (p *Protocol) LatestAudit() *Audit {
+	var latest *Audit
+	for i := range p.Audits {
+		if latest == nil || p.Audits[i].Timestamp > latest.Timestamp {
+			latest = &p.Audits[i]
+		}
+	}
+	return latest
+}
+
+// Snapshot is a verified version of the feed
+type Snapshot struct {
+	// UpdatedAt is when the publisher last changed the feed, in unix seconds
+	UpdatedAt uint64 `json:"updated_at"`
+
+	// Protocols are keyed by task protocol name, e.g. "aave_v3"
+	Protocols map[string]*Protocol `json:"protocols"`
+
+	// Stale is set when the feed could not be refreshed and an older snapshot is served
+	Stale bool `json:"-"`
+}
+
+// Protocol returns the entry of protocol. Names are case insensitive.
+func (s *Snapshot) Protocol(protocol string) (*Protocol, error) {
+	p, ok := s.Protocols[strings.ToLower(strings.TrimSpace(protocol))]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProtocol, protocol)
+	}
+	return p, nil
+}
+
+func (s *Snapshot) validate() error {
+	for name, p := range s.Protocols {
+		if p == nil {
+			return fmt.Errorf("protocol %s has no entry", name)
+		}
+		for _, incident := range p.Incidents {
+			switch incident.Severity {
+			case SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical:
+			default:
+				return fmt.Errorf("incident %s of %s has unknown severity %q", incident.ID, name, incident.Severity)
+			}
+		}
+	}
+	return nil
+}
+
+// Config configures the security feed. The feed is a JSON Snapshot served over HTTP with
+// its signature in SignatureHeader.
+type Config struct {
+	Enabled bool          `yaml:"enabled"`
+	Url     string        `yaml:"url"`
+	ApiKey  string        `yaml:"apiKey"`
+	Timeout time.Duration `yaml:"timeout"`
+
+	// Signers are the addresses the feed may be signed by
+	Signers []string `yaml:"signers"`
+
+	// AllowUnsigned skips signature verification. Only meant for feeds served from a
+	// trusted network.
+	AllowUnsigned bool `yaml:"allowUnsigned"`
+
+	// CacheTTL is how long a fetched feed is reused and MaxStaleness how long it is
+	// still served while refreshing fails
+	CacheTTL     time.Duration `yaml:"cacheTTL"`
+	MaxStaleness time.Duration `yaml:"maxStaleness"`
+}
+
+// DefaultConfig leaves the feed disabled; there is no public default
+func DefaultConfig() Config {
+	return Config{
+		Timeout:      10 * time.Second,
+		CacheTTL:     10 * time.Minute,
+		MaxStaleness: 6 * time.Hour,
+	}
+}
+
+// Validate checks the config for values the feed cannot run with
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Url == "" {
+		return fmt.Errorf("url is required")
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("timeout must be positive")
+	}
+	if c.CacheTTL < 0 || c.MaxStaleness < 0 {
+		return fmt.Errorf("cacheTTL and maxStaleness must not be negative")
+	}
+	if len(c.Signers) == 0 && !c.AllowUnsigned {
+		return fmt.Errorf("signers are required unless allowUnsigned is set")
+	}
+	for i, signer := range c.Signers {
+		if !common.IsHexAddress(signer) {
+			return fmt.Errorf("signers[%d] is not an address", i)
+		}
+	}
+	return nil
+}
+
+// Feed fetches and verifies the security feed, caching the latest verified snapshot
+type Feed struct {
+	url           string
+	apiKey        string
+	signers       map[common.Address]bool
+	allowUnsigned bool
+	ttl           time.Duration
+	maxStaleness  time.Duration
+	httpClient    *http.Client
+	policy        *resilience.Policy
+
+	mu        sync.Mutex
+	snapshot  *Snapshot
+	fetchedAt time.Time
+}
+
+// NewFeed creates a feed client. Transient failures are retried under policy, which may
+// be nil.
+func NewFeed(cfg Config, policy *resilience.Policy) *Feed {
+	defaults := DefaultConfig()
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaults.Timeout
+	}
+	signers := make(map[common.Address]bool, len(cfg.Signers))
+	for _, signer := range cfg.Signers {
+		signers[common.HexToAddress(signer)] = true
+	}
+	return &Feed{
+		url:           cfg.Url,
+		apiKey:        cfg.ApiKey,
+		signers:       signers,
+		allowUnsigned: cfg.AllowUnsigned,
+		ttl:           cfg.CacheTTL,
+		maxStaleness:  cfg.MaxStaleness,
+		httpClient:    &http.Client{Timeout: cfg.Timeout},
+		policy:        policy,
+	}
+}
+
+// NewFromConfig creates the feed enabled in cfg, nil when it is disabled
+func NewFromConfig(cfg Config, policy *resilience.Policy) *Feed {
+	if !cfg.Enabled {
+		return nil
+	}
+	return NewFeed(cfg, policy)
+}
+
+// Snapshot returns the latest verified snapshot, fetching it when the cached one is older
+// than the cache TTL. When fetching fails, a snapshot younger than the max staleness is
+// returned marked stale.
+func (f *Feed) Snapshot(ctx context.Context) (*Snapshot, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.snapshot != nil && time.Since(f.fetchedAt) < f.ttl {
+		return f.snapshot, nil
+	}
+
+	var snapshot *Snapshot
+	err := f.policy.Do(ctx, f.url, func(ctx context.Context) error {
+		var err error
+		snapshot, err = f.fetch(ctx)
+		return err
+	})
+	if err != nil {
+		if f.snapshot != nil && time.Since(f.fetchedAt) < f.maxStaleness {
+			stale := *f.snapshot
+			stale.Stale = true
+			return &stale, nil
+		}
+		return nil, err
+	}
+	f.snapshot = snapshot
+	f.fetchedAt = time.Now()
+	return snapshot, nil
+}
+
+func (f *Feed) fetch(ctx context.Context) (*Snapshot, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("security feed: failed to build request: %w", err)
+	}
+	if f.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+f.apiKey)
+	}
+	resp, err := f.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("security feed unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("security feed: %w", &resilience.StatusError{Endpoint: f.url, StatusCode: resp.StatusCode})
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxFeedSize))
+	if err != nil {
+		return nil, fmt.Errorf("security feed: failed to read body: %w", err)
+	}
+	if !f.allowUnsigned {
+		if err := f.verify(body, resp.Header.Get(SignatureHeader)); err != nil {
+			return nil, err
+		}
+	}
+
+	var snapshot Snapshot
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&snapshot); err != nil {
+		return nil, fmt.Errorf("security feed: failed to decode: %w", err)
+	}
+	if err := snapshot.validate(); err != nil {
+		return nil, fmt.Errorf("security feed: %w", err)
+	}
+	return &snapshot, nil
+}
+
+// verify checks that signature recovers to a configured signer of body
+func (f *Feed) verify(body []byte, signature string) error {
+	sig, err := hexutil.Decode(signature)
+	if err != nil || len(sig) != crypto.SignatureLength {
+		return fmt.Errorf("%w: malformed %s header", ErrInvalidSignature, SignatureHeader)
+	}
+	// wallets produce recovery ids of 27 and 28
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	pub, err := crypto.SigToPub(accounts.TextHash(body), sig)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	if signer := crypto.PubkeyToAddress(*pub); !f.signers[signer] {
+		return fmt.Errorf("%w: signed by unknown %s", ErrInvalidSignature, signer.Hex())
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package security
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const testFeed = `{"updated_at":1700000000,"protocols":{"aave_v3":{
+	"audits":[{"auditor":"Certora","scope":"v3.2","timestamp":1690000000,"url":""},{"auditor":"SigmaPrime","scope":"v3.0","timestamp":1640000000,"url":""}],
+	"incidents":[{"id":"2023-11-04","title":"Rounding issue","severity":"high","active":false,"chain_ids":[1],"loss_usd":"","timestamp":1699000000,"url":""}]
+}}}`
+
+func sign(t *testing.T, key *ecdsa.PrivateKey, body string) string {
+	t.Helper()
+	sig, err := crypto.Sign(accounts.TextHash([]byte(body)), key)
+	if err != nil {
+		t.Fatalf("Failed to sign feed: %v", err)
+	}
+	sig[crypto.RecoveryIDOffset] += 27
+	return hexutil.Encode(sig)
+}
+
+// feedServer serves body signed with key, counting requests, until healthy is cleared
+func feedServer(t *testing.T, key *ecdsa.PrivateKey, body string) (*httptest.Server, *atomic.Int32, *atomic.Bool) {
+	var requests atomic.Int32
+	var healthy atomic.Bool
+	healthy.Store(true)
+	signature := sign(t, key, body)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Header().Set(SignatureHeader, signature)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests, &healthy
+}
+
+func Test_FeedVerifiesAndCaches(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	server, requests, healthy := feedServer(t, key, testFeed)
+
+	feed := NewFeed(Config{
+		Url:          server.URL,
+		Signers:      []string{crypto.PubkeyToAddress(key.PublicKey).Hex()},
+		CacheTTL:     time.Hour,
+		MaxStaleness: time.Hour,
+	}, nil)
+	snapshot, err := feed.Snapshot(context.Background())
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	aave, err := snapshot.Protocol("AAVE_V3")
+	if err != nil {
+		t.Fatalf("Expected aave to be covered: %v", err)
+	}
+	if latest := aave.LatestAudit(); latest == nil || latest.Auditor != "Certora" {
+		t.Errorf("Expected the Certora audit to be the latest, got %+v", latest)
+	}
+	if incident := aave.Incidents[0]; !incident.Affects(1) || incident.Affects(8453) {
+		t.Errorf("Expected the incident to affect ethereum only")
+	}
+	if _, err := snapshot.Protocol("euler"); !errors.Is(err, ErrUnknownProtocol) {
+		t.Errorf("Expected ErrUnknownProtocol, got %v", err)
+	}
+
+	if _, err := feed.Snapshot(context.Background()); err != nil || requests.Load() != 1 {
+		t.Errorf("Expected the cached snapshot to be reused, got %d requests (%v)", requests.Load(), err)
+	}
+
+	// an expired snapshot is still served, marked stale, while the feed is down
+	feed.ttl = 0
+	healthy.Store(false)
+	stale, err := feed.Snapshot(context.Background())
+	if err != nil || !stale.Stale || stale.UpdatedAt != 1700000000 {
+		t.Errorf("Expected a stale snapshot, got %+v (%v)", stale, err)
+	}
+	feed.maxStaleness = 0
+	if _, err := feed.Snapshot(context.Background()); err == nil {
+		t.Errorf("Expected an error once the snapshot is too old")
+	}
+}
+
+func Test_FeedRejectsUnknownSigner(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+	server, _, _ := feedServer(t, key, testFeed)
+
+	feed := NewFeed(Config{Url: server.URL, Signers: []string{crypto.PubkeyToAddress(other.PublicKey).Hex()}}, nil)
+	if _, err := feed.Snapshot(context.Background()); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature, got %v", err)
+	}
+
+	unsigned := NewFeed(Config{Url: server.URL, AllowUnsigned: true}, nil)
+	if _, err := unsigned.Snapshot(context.Background()); err != nil {
+		t.Errorf("Expected unsigned feeds to be accepted when allowed: %v", err)
+	}
+}
+
+func Test_FeedRejectsTamperedBody(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	signature := sign(t, key, testFeed)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(SignatureHeader, signature)
+		_, _ = w.Write([]byte(`{"updated_at":1700000000,"protocols":{}}`))
+	}))
+	defer server.Close()
+
+	feed := NewFeed(Config{Url: server.URL, Signers: []string{crypto.PubkeyToAddress(key.PublicKey).Hex()}}, nil)
+	if _, err := feed.Snapshot(context.Background()); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature, got %v", err)
+	}
+}
+
+func Test_ConfigValidate(t *testing.T) {
+	testCases := []struct {
+		name  string
+		cfg   Config
+		valid bool
+	}{
+		{name: "disabled", cfg: DefaultConfig(), valid: true},
+		{name: "signed", cfg: Config{Enabled: true, Url: "https://feed", Timeout: time.Second, Signers: []string{"0x00000000000000000000000000000000000000aa"}}, valid: true},
+		{name: "no signers", cfg: Config{Enabled: true, Url: "https://feed", Timeout: time.Second}},
+		{name: "bad signer", cfg: Config{Enabled: true, Url: "https://feed", Timeout: time.Second, Signers: []string{"aa"}}},
+		{name: "no url", cfg: Config{Enabled: true, Timeout: time.Second, AllowUnsigned: true}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.cfg.Validate(); (err == nil) != tc.valid {
+				t.Errorf("Expected valid=%v, got %v", tc.valid, err)
+			}
+		})
+	}
+}
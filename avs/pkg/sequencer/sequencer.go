@@ -0,0 +1,231 @@
+// Package sequencer checks the health of L2 sequencers before funds move to their
+// chains. Chainlink's L2 sequencer uptime feeds report whether the sequencer is up and
+// since when, and the cadence of recent blocks shows a sequencer that stopped producing
+// them while its feed still reports it up.
+package sequencer
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/addressbook"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+)
+
+const uptimeFeedABIJson = `[
+	{"name":"latestRoundData","type":"function","stateMutability":"view","inputs":[],
+	 "outputs":[
+		{"name":"roundId","type":"uint80"},
+		{"name":"answer","type":"int256"},
+		{"name":"startedAt","type":"uint256"},
+		{"name":"updatedAt","type":"uint256"},
+		{"name":"answeredInRound","type":"uint80"}
+	]}
+]`
+
+var uptimeFeedABI = chain.MustParseABI(uptimeFeedABIJson)
+
+// Status grades the health of a sequencer
+type Status string
+
+const (
+	// StatusUp is a sequencer producing blocks at its usual cadence
+	StatusUp Status = "up"
+
+	// StatusRecovering is a sequencer back up for less than the grace period, during
+	// which prices and liquidations on its chain are still catching up
+	StatusRecovering Status = "recovering"
+
+	// StatusDegraded is a sequencer producing blocks slower than the maximum block time
+	StatusDegraded Status = "degraded"
+
+	// StatusStalled is a sequencer that produced no block for longer than the maximum
+	// head age, whatever its uptime feed reports
+	StatusStalled Status = "stalled"
+
+	// StatusDown is a sequencer its uptime feed reports down
+	StatusDown Status = "down"
+)
+
+// Blocked reports whether funds must not move to a chain whose sequencer has status s
+func (s Status) Blocked() bool {
+	return s == StatusDown || s == StatusStalled
+}
+
+// Config configures sequencer health checks
+type Config struct {
+	Enabled bool `yaml:"enabled"`
+
+	// GracePeriod is how long a sequencer back up is reported recovering
+	GracePeriod time.Duration `yaml:"gracePeriod"`
+
+	// MaxHeadAge is the longest the latest block may be old before the sequencer is
+	// reported stalled
+	MaxHeadAge time.Duration `yaml:"maxHeadAge"`
+
+	// CadenceBlocks is how many recent blocks the block time is averaged over
+	CadenceBlocks uint64 `yaml:"cadenceBlocks"`
+
+	// MaxBlockTime is the average block time above which the sequencer is reported
+	// degraded
+	MaxBlockTime time.Duration `yaml:"maxBlockTime"`
+}
+
+// DefaultConfig checks sequencers, reporting them recovering for the hour Chainlink
+// recommends, stalled after two minutes without a block and degraded when the last 100
+// blocks took over 10 seconds each
+func DefaultConfig() Config {
+	return Config{
+		Enabled:       true,
+		GracePeriod:   time.Hour,
+		MaxHeadAge:    2 * time.Minute,
+		CadenceBlocks: 100,
+		MaxBlockTime:  10 * time.Second,
+	}
+}
+
+// Validate checks the config for values sequencers cannot be checked with
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.GracePeriod < 0 {
+		return fmt.Errorf("gracePeriod must not be negative")
+	}
+	if c.MaxHeadAge <= 0 {
+		return fmt.Errorf("maxHeadAge must be positive")
+	}
+	if c.CadenceBlocks == 0 {
+		return fmt.Errorf("cadenceBlocks must be positive")
+	}
+	if c.MaxBlockTime <= 0 {
+		return fmt.Errorf("maxBlockTime must be positive")
+	}
+	return nil
+}
+
+// Health is the state of the sequencer of a chain
+type Health struct {
+	ChainID uint64
+	Status  Status
+
+	// Feed is the uptime feed read, the zero address when the chain has none and only
+	// its blocks are checked
+	Feed common.Address
+
+	// Since is the unix time the feed last changed status, zero without a feed
+	Since uint64
+
+	// HeadAge is how long ago the latest block was produced
+	HeadAge time.Duration
+
+	// BlockTime is the average time between the last CadenceBlocks blocks
+	BlockTime time.Duration
+
+	// Reason explains any status but up
+	Reason string
+}
+
+// Monitor checks the sequencers of the chains the address book lists a sequencer
+// dependency or an uptime feed for
+type Monitor struct {
+	cfg    Config
+	chains *chain.Manager
+	book   *addressbook.Book
+	now    func() time.Time
+}
+
+// NewFromConfig creates the sequencer checks of cfg. It returns nil when they are disabled.
+// cfg must be valid.
+func NewFromConfig(cfg Config, chains *chain.Manager, book *addressbook.Book) *Monitor {
+	if !cfg.Enabled {
+		return nil
+	}
+	return &Monitor{cfg: cfg, chains: chains, book: book, now: time.Now}
+}
+
+// HasSequencer reports whether chainID is run by a sequencer the monitor checks
+func (m *Monitor) HasSequencer(chainID uint64) bool {
+	if _, ok := m.book.Lookup(chainID, addressbook.ProtocolChainlink, addressbook.ContractSequencerUptimeFeed); ok {
+		return true
+	}
+	for _, d := range m.book.Dependencies(chainID, addressbook.ProtocolAny) {
+		if d.Kind() == addressbook.DependencySequencer {
+			return true
+		}
+	}
+	return false
+}
+
+// Check reads the health of the sequencer of chainID. It returns nil for chains without
+// a sequencer, such as Ethereum.
+func (m *Monitor) Check(ctx context.Context, chainID uint64) (*Health, error) {
+	if !m.HasSequencer(chainID) {
+		return nil, nil
+	}
+	client, err := m.chains.Client(chainID)
+	if err != nil {
+		return nil, err
+	}
+	head, err := chain.HeadHeader(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch latest header: %w", err)
+	}
+	health := &Health{ChainID: chainID, Status: StatusUp}
+	// a stalled chain does not advance its own clock, so the head is aged by wall time.
+	// Reads pinned to a past block are not aged.
+	if chain.BlockOf(ctx) == nil {
+		health.HeadAge = m.now().Sub(time.Unix(int64(head.Time), 0))
+	}
+	if health.HeadAge < 0 {
+		health.HeadAge = 0
+	}
+
+	if feed, ok := m.book.Lookup(chainID, addressbook.ProtocolChainlink, addressbook.ContractSequencerUptimeFeed); ok {
+		health.Feed = feed
+		round, err := chain.CallView(ctx, client, feed, uptimeFeedABI, "latestRoundData")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read sequencer uptime feed: %w", err)
+		}
+		answer, okAnswer := round[1].(*big.Int)
+		startedAt, okStarted := round[2].(*big.Int)
+		if !okAnswer || !okStarted {
+			return nil, fmt.Errorf("unexpected latestRoundData output types %T and %T", round[1], round[2])
+		}
+		health.Since = startedAt.Uint64()
+		// the feed answers 0 while the sequencer is up and 1 while it is down
+		if answer.Sign() != 0 {
+			health.Status, health.Reason = StatusDown, fmt.Sprintf("uptime feed reports the sequencer down since %d", health.Since)
+			return health, nil
+		}
+		// the grace period is measured in chain time so every operator reaches the same verdict
+		if up := time.Duration(int64(head.Time)-int64(health.Since)) * time.Second; up < m.cfg.GracePeriod {
+			health.Status, health.Reason = StatusRecovering, fmt.Sprintf("sequencer back up for %s, less than the %s grace period", up, m.cfg.GracePeriod)
+		}
+	}
+
+	if health.HeadAge > m.cfg.MaxHeadAge {
+		health.Status, health.Reason = StatusStalled, fmt.Sprintf("no block for over %s", m.cfg.MaxHeadAge)
+		return health, nil
+	}
+
+	number := head.Number.Uint64()
+	window := m.cfg.CadenceBlocks
+	if window > number {
+		window = number
+	}
+	if window > 0 {
+		past, err := client.HeaderByNumber(ctx, new(big.Int).SetUint64(number-window))
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch header %d: %w", number-window, err)
+		}
+		health.BlockTime = time.Duration(head.Time-past.Time) * time.Second / time.Duration(window)
+	}
+	if health.Status == StatusUp && health.BlockTime > m.cfg.MaxBlockTime {
+		health.Status, health.Reason = StatusDegraded, fmt.Sprintf("blocks averaged %s over the last %d, over the %s allowed", health.BlockTime, window, m.cfg.MaxBlockTime)
+	}
+	return health, nil
+}
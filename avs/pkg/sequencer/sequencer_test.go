@@ -0,0 +1,85 @@
+package sequencer
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/addressbook"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/chain/chaintest"
+)
+
+func Test_Check(t *testing.T) {
+	now := time.Unix(1_800_000_000, 0)
+	book := addressbook.Default()
+	feed := book.Address(8453, addressbook.ProtocolChainlink, addressbook.ContractSequencerUptimeFeed)
+
+	testCases := []struct {
+		name    string
+		answer  int64
+		since   time.Time
+		head    time.Time
+		blocked bool
+		status  Status
+	}{
+		{name: "up", since: now.Add(-48 * time.Hour), head: now.Add(-2 * time.Second), status: StatusUp},
+		{name: "down", answer: 1, since: now.Add(-10 * time.Minute), head: now.Add(-10 * time.Minute), status: StatusDown, blocked: true},
+		{name: "recovering", since: now.Add(-20 * time.Minute), head: now, status: StatusRecovering},
+		{name: "stalled", since: now.Add(-48 * time.Hour), head: now.Add(-5 * time.Minute), status: StatusStalled, blocked: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			contracts := chaintest.NewContracts(8453)
+			contracts.SetHead(1_000, uint64(tc.head.Unix()))
+			contracts.Stub(t, feed, uptimeFeedABI, "latestRoundData", big.NewInt(1), big.NewInt(tc.answer), big.NewInt(tc.since.Unix()), big.NewInt(tc.since.Unix()), big.NewInt(1))
+			chains := chain.NewManager()
+			chains.Register(8453, "base", contracts)
+			monitor := NewFromConfig(DefaultConfig(), chains, book)
+			monitor.now = func() time.Time { return now }
+
+			health, err := monitor.Check(context.Background(), 8453)
+			if err != nil {
+				t.Fatalf("Check failed: %v", err)
+			}
+			if health.Status != tc.status || health.Status.Blocked() != tc.blocked {
+				t.Errorf("Expected %s, got %+v", tc.status, health)
+			}
+			if health.Feed != feed || health.Since != uint64(tc.since.Unix()) {
+				t.Errorf("Expected the uptime feed reported, got %+v", health)
+			}
+			if tc.status != StatusUp && health.Reason == "" {
+				t.Errorf("Expected the status explained")
+			}
+		})
+	}
+
+	monitor := NewFromConfig(DefaultConfig(), chain.NewManager(), book)
+	if health, err := monitor.Check(context.Background(), 1); health != nil || err != nil {
+		t.Errorf("Expected Ethereum to have no sequencer, got %+v, %v", health, err)
+	}
+	if NewFromConfig(Config{}, chain.NewManager(), book) != nil {
+		t.Errorf("Expected no monitor when disabled")
+	}
+}
+
+func Test_CheckWithoutFeed(t *testing.T) {
+	now := time.Unix(1_800_000_000, 0)
+	contracts := chaintest.NewContracts(84532)
+	contracts.SetHead(50, uint64(now.Unix()))
+	chains := chain.NewManager()
+	chains.Register(84532, "base-sepolia", contracts)
+
+	// Base Sepolia has no uptime feed but depends on its sequencer, so only its blocks are checked
+	monitor := NewFromConfig(DefaultConfig(), chains, addressbook.Default())
+	monitor.now = func() time.Time { return now.Add(3 * time.Minute) }
+	health, err := monitor.Check(context.Background(), 84532)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if health.Status != StatusStalled || health.Feed != (common.Address{}) {
+		t.Errorf("Expected the sequencer stalled from its blocks alone, got %+v", health)
+	}
+}
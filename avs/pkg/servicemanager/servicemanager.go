@@ -0,0 +1,315 @@
+// Package servicemanager binds the YieldIntelligenceServiceManager contract of the AVS
+// and submits the yield data of the performer to it, for operators who also run the
+// aggregator role and report on chain rather than only through Hourglass tasks.
+//
+// Protocols are identified on chain by the keccak256 hash of their upper case name, as
+// the yield hook identifies them: aave_v3 is keccak256("AAVE_V3"). Rates, utilization
+// and risk scores are in basis points.
+package servicemanager
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+)
+
+// ConsensusWindow is how recent attestations of a market must be to count towards its
+// consensus, which needs three of them
+const ConsensusWindow = 5 * time.Minute
+
+// serviceManagerABI is the part of the YieldIntelligenceServiceManager operators use
+var serviceManagerABI = chain.MustParseABI(`[
+	{"name":"isYieldIntelligenceOperatorQualified","type":"function","stateMutability":"view","inputs":[{"name":"operator","type":"address"}],"outputs":[{"name":"","type":"bool"}]},
+	{"name":"getYieldOptimizationHook","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"address"}]},
+	{"name":"yieldConsensus","type":"function","stateMutability":"view","inputs":[{"name":"","type":"bytes32"}],"outputs":[
+		{"name":"protocolId","type":"bytes32"},{"name":"chainId","type":"uint256"},{"name":"consensusYieldRate","type":"uint256"},
+		{"name":"consensusTvl","type":"uint256"},{"name":"consensusRiskScore","type":"uint256"},{"name":"totalStake","type":"uint256"},
+		{"name":"attestationCount","type":"uint256"},{"name":"confidenceLevel","type":"uint256"},{"name":"consensusTimestamp","type":"uint256"},
+		{"name":"isValid","type":"bool"},{"name":"isOpportunity","type":"bool"}]},
+	{"name":"operatorPerformance","type":"function","stateMutability":"view","inputs":[{"name":"","type":"address"}],"outputs":[
+		{"name":"totalAttestations","type":"uint256"},{"name":"accurateAttestations","type":"uint256"},{"name":"totalStakeSlashed","type":"uint256"},
+		{"name":"reliabilityScore","type":"uint256"},{"name":"lastAttestationTime","type":"uint256"},{"name":"protocolsMonitored","type":"uint256"},
+		{"name":"chainsMonitored","type":"uint256"}]},
+	{"name":"submitYieldAttestation","type":"function","stateMutability":"nonpayable","inputs":[
+		{"name":"protocolId","type":"bytes32"},{"name":"chainId","type":"uint256"},{"name":"yieldRate","type":"uint256"},
+		{"name":"tvl","type":"uint256"},{"name":"utilization","type":"uint256"},{"name":"riskScore","type":"uint256"},
+		{"name":"dataHash","type":"bytes32"},{"name":"signature","type":"bytes"}],"outputs":[]},
+	{"name":"YieldAttestationSubmitted","type":"event","anonymous":false,"inputs":[
+		{"name":"attestationId","type":"bytes32","indexed":true},{"name":"operator","type":"address","indexed":true},
+		{"name":"protocolId","type":"bytes32","indexed":true},{"name":"chainId","type":"uint256","indexed":false},
+		{"name":"yieldRate","type":"uint256","indexed":false}]},
+	{"name":"YieldConsensusReached","type":"event","anonymous":false,"inputs":[
+		{"name":"consensusId","type":"bytes32","indexed":true},{"name":"protocolId","type":"bytes32","indexed":true},
+		{"name":"chainId","type":"uint256","indexed":false},{"name":"consensusYieldRate","type":"uint256","indexed":false},
+		{"name":"isOpportunity","type":"bool","indexed":false}]}
+]`)
+
+// ProtocolID is the on chain identifier of protocol
+func ProtocolID(protocol string) common.Hash {
+	return crypto.Keccak256Hash([]byte(strings.ToUpper(protocol)))
+}
+
+// consensusKey is the key of the consensus of protocolID on chainID
+func consensusKey(protocolID common.Hash, chainID uint64) common.Hash {
+	return crypto.Keccak256Hash(protocolID.Bytes(), common.BigToHash(new(big.Int).SetUint64(chainID)).Bytes())
+}
+
+// Attestation is the yield data an operator submits for a market
+type Attestation struct {
+	ProtocolID common.Hash
+	ChainID    uint64
+
+	// YieldRate is the supply rate in basis points
+	YieldRate uint64
+
+	// TVL is the total supply of the market in USDC base units
+	TVL *big.Int
+
+	// Utilization and RiskScore are in basis points
+	Utilization uint64
+	RiskScore   uint64
+
+	// DataHash is the hash of the data the attestation was computed from, and Signature
+	// the signature of the operator over it
+	DataHash  common.Hash
+	Signature []byte
+}
+
+// Consensus is the stake weighted consensus of the attestations of a market
+type Consensus struct {
+	ProtocolID      common.Hash
+	ChainID         uint64
+	YieldRate       *big.Int
+	TVL             *big.Int
+	RiskScore       *big.Int
+	TotalStake      *big.Int
+	Attestations    uint64
+	ConfidenceLevel uint64
+	Timestamp       uint64
+	Valid           bool
+	Opportunity     bool
+}
+
+// Performance is how an operator's attestations fared
+type Performance struct {
+	TotalAttestations    uint64
+	AccurateAttestations uint64
+	TotalStakeSlashed    *big.Int
+	ReliabilityScore     uint64
+	LastAttestationTime  uint64
+	ProtocolsMonitored   uint64
+	ChainsMonitored      uint64
+}
+
+// AttestationSubmitted is a YieldAttestationSubmitted event
+type AttestationSubmitted struct {
+	AttestationID common.Hash
+	Operator      common.Address
+	ProtocolID    common.Hash
+	ChainID       uint64
+	YieldRate     *big.Int
+
+	// BlockNumber and BlockHash are the block the attestation was submitted in, and
+	// TxHash the transaction submitting it
+	BlockNumber uint64
+	BlockHash   common.Hash
+	TxHash      common.Hash
+}
+
+// ServiceManager reads and calls a YieldIntelligenceServiceManager
+type ServiceManager struct {
+	chains  *chain.Manager
+	chainID uint64
+	address common.Address
+}
+
+// New binds the service manager at address on chainID
+func New(chains *chain.Manager, chainID uint64, address common.Address) *ServiceManager {
+	return &ServiceManager{chains: chains, chainID: chainID, address: address}
+}
+
+// Address is the address of the service manager
+func (sm *ServiceManager) Address() common.Address {
+	return sm.address
+}
+
+func (sm *ServiceManager) call(ctx context.Context, method string, args ...interface{}) ([]interface{}, error) {
+	client, err := sm.chains.Client(sm.chainID)
+	if err != nil {
+		return nil, err
+	}
+	return chain.CallView(ctx, client, sm.address, serviceManagerABI, method, args...)
+}
+
+// IsOperatorQualified reports whether operator has the stake to submit attestations
+func (sm *ServiceManager) IsOperatorQualified(ctx context.Context, operator common.Address) (bool, error) {
+	values, err := sm.call(ctx, "isYieldIntelligenceOperatorQualified", operator)
+	if err != nil {
+		return false, err
+	}
+	qualified, ok := values[0].(bool)
+	if !ok {
+		return false, fmt.Errorf("unexpected isYieldIntelligenceOperatorQualified output type %T", values[0])
+	}
+	return qualified, nil
+}
+
+// YieldOptimizationHook returns the address of the yield hook the service manager serves
+func (sm *ServiceManager) YieldOptimizationHook(ctx context.Context) (common.Address, error) {
+	values, err := sm.call(ctx, "getYieldOptimizationHook")
+	if err != nil {
+		return common.Address{}, err
+	}
+	hook, ok := values[0].(common.Address)
+	if !ok {
+		return common.Address{}, fmt.Errorf("unexpected getYieldOptimizationHook output type %T", values[0])
+	}
+	return hook, nil
+}
+
+// Consensus returns the consensus of protocol on chainID. It is not Valid before three
+// operators attested the market within the consensus window.
+func (sm *ServiceManager) Consensus(ctx context.Context, protocol string, chainID uint64) (*Consensus, error) {
+	values, err := sm.call(ctx, "yieldConsensus", consensusKey(ProtocolID(protocol), chainID))
+	if err != nil {
+		return nil, err
+	}
+	var out struct {
+		ProtocolId         [32]byte
+		ChainId            *big.Int
+		ConsensusYieldRate *big.Int
+		ConsensusTvl       *big.Int
+		ConsensusRiskScore *big.Int
+		TotalStake         *big.Int
+		AttestationCount   *big.Int
+		ConfidenceLevel    *big.Int
+		ConsensusTimestamp *big.Int
+		IsValid            bool
+		IsOpportunity      bool
+	}
+	if err := serviceManagerABI.Methods["yieldConsensus"].Outputs.Copy(&out, values); err != nil {
+		return nil, fmt.Errorf("failed to decode yieldConsensus: %w", err)
+	}
+	return &Consensus{
+		ProtocolID:      out.ProtocolId,
+		ChainID:         out.ChainId.Uint64(),
+		YieldRate:       out.ConsensusYieldRate,
+		TVL:             out.ConsensusTvl,
+		RiskScore:       out.ConsensusRiskScore,
+		TotalStake:      out.TotalStake,
+		Attestations:    out.AttestationCount.Uint64(),
+		ConfidenceLevel: out.ConfidenceLevel.Uint64(),
+		Timestamp:       out.ConsensusTimestamp.Uint64(),
+		Valid:           out.IsValid,
+		Opportunity:     out.IsOpportunity,
+	}, nil
+}
+
+// OperatorPerformance returns how the attestations of operator fared
+func (sm *ServiceManager) OperatorPerformance(ctx context.Context, operator common.Address) (*Performance, error) {
+	values, err := sm.call(ctx, "operatorPerformance", operator)
+	if err != nil {
+		return nil, err
+	}
+	var out struct {
+		TotalAttestations    *big.Int
+		AccurateAttestations *big.Int
+		TotalStakeSlashed    *big.Int
+		ReliabilityScore     *big.Int
+		LastAttestationTime  *big.Int
+		ProtocolsMonitored   *big.Int
+		ChainsMonitored      *big.Int
+	}
+	if err := serviceManagerABI.Methods["operatorPerformance"].Outputs.Copy(&out, values); err != nil {
+		return nil, fmt.Errorf("failed to decode operatorPerformance: %w", err)
+	}
+	return &Performance{
+		TotalAttestations:    out.TotalAttestations.Uint64(),
+		AccurateAttestations: out.AccurateAttestations.Uint64(),
+		TotalStakeSlashed:    out.TotalStakeSlashed,
+		ReliabilityScore:     out.ReliabilityScore.Uint64(),
+		LastAttestationTime:  out.LastAttestationTime.Uint64(),
+		ProtocolsMonitored:   out.ProtocolsMonitored.Uint64(),
+		ChainsMonitored:      out.ChainsMonitored.Uint64(),
+	}, nil
+}
+
+// SubmitAttestation returns the call submitting a, to be sent from the operator account
+func (sm *ServiceManager) SubmitAttestation(a *Attestation) (chain.Call, error) {
+	tvl := a.TVL
+	if tvl == nil {
+		tvl = new(big.Int)
+	}
+	data, err := serviceManagerABI.Pack("submitYieldAttestation", a.ProtocolID, new(big.Int).SetUint64(a.ChainID), new(big.Int).SetUint64(a.YieldRate),
+		tvl, new(big.Int).SetUint64(a.Utilization), new(big.Int).SetUint64(a.RiskScore), a.DataHash, a.Signature)
+	if err != nil {
+		return chain.Call{}, fmt.Errorf("failed to pack submitYieldAttestation: %w", err)
+	}
+	return chain.Call{To: sm.address, Data: data}, nil
+}
+
+// AttestationsSubmitted returns the attestations submitted from fromBlock to toBlock
+func (sm *ServiceManager) AttestationsSubmitted(ctx context.Context, fromBlock, toBlock uint64) ([]AttestationSubmitted, error) {
+	client, err := sm.chains.Client(sm.chainID)
+	if err != nil {
+		return nil, err
+	}
+	event := serviceManagerABI.Events["YieldAttestationSubmitted"]
+	logs, err := client.FilterLogs(ctx, ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(fromBlock),
+		ToBlock:   new(big.Int).SetUint64(toBlock),
+		Addresses: []common.Address{sm.address},
+		Topics:    [][]common.Hash{{event.ID}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to filter attestations: %w", err)
+	}
+	submitted := make([]AttestationSubmitted, 0, len(logs))
+	for _, log := range logs {
+		if log.Removed {
+			continue
+		}
+		s, err := parseAttestationSubmitted(log)
+		if err != nil {
+			return nil, err
+		}
+		submitted = append(submitted, *s)
+	}
+	return submitted, nil
+}
+
+func parseAttestationSubmitted(log types.Log) (*AttestationSubmitted, error) {
+	if len(log.Topics) != 4 {
+		return nil, fmt.Errorf("malformed YieldAttestationSubmitted log in tx %s", log.TxHash.Hex())
+	}
+	values, err := serviceManagerABI.Unpack("YieldAttestationSubmitted", log.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack YieldAttestationSubmitted: %w", err)
+	}
+	chainID, ok := values[0].(*big.Int)
+	if !ok {
+		return nil, fmt.Errorf("unexpected YieldAttestationSubmitted chainId type %T", values[0])
+	}
+	rate, ok := values[1].(*big.Int)
+	if !ok {
+		return nil, fmt.Errorf("unexpected YieldAttestationSubmitted yieldRate type %T", values[1])
+	}
+	return &AttestationSubmitted{
+		AttestationID: log.Topics[1],
+		Operator:      common.BytesToAddress(log.Topics[2].Bytes()),
+		ProtocolID:    log.Topics[3],
+		ChainID:       chainID.Uint64(),
+		YieldRate:     rate,
+		BlockNumber:   log.BlockNumber,
+		BlockHash:     log.BlockHash,
+		TxHash:        log.TxHash,
+	}, nil
+}
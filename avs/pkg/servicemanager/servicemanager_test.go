@@ -0,0 +1,217 @@
+package servicemanager
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/chain/chaintest"
+	"github.com/najnomics/crosscow-avs/pkg/collector"
+	"github.com/najnomics/crosscow-avs/pkg/irm"
+	"github.com/najnomics/crosscow-avs/pkg/signer"
+	"github.com/najnomics/crosscow-avs/pkg/txmgr"
+	"go.uber.org/zap"
+)
+
+var serviceManagerAddress = common.HexToAddress("0x0000000000000000000000000000000000005e41")
+
+type fakeAdapter struct {
+	protocol string
+}
+
+func (f *fakeAdapter) Protocol() string   { return f.protocol }
+func (f *fakeAdapter) ChainIDs() []uint64 { return []uint64{1, 8453} }
+
+func (f *fakeAdapter) MarketState(ctx context.Context, chainID uint64) (*adapters.MarketState, error) {
+	return &adapters.MarketState{
+		Protocol: f.protocol,
+		ChainID:  chainID,
+		Pool: irm.Pool{
+			TotalSupply: big.NewInt(50_000_000_000_000),
+			TotalBorrow: big.NewInt(40_000_000_000_000),
+			Model:       &irm.CometModel{Kink: 0.9, SlopeLow: 0.1},
+		},
+	}, nil
+}
+
+func newTestSubmitter(t *testing.T, cfg Config) (*Submitter, *chaintest.Contracts, *signer.KeySigner) {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	account := signer.NewKeySigner(key)
+	contracts := chaintest.NewContracts(1)
+	contracts.StubGas(t, serviceManagerAddress, serviceManagerABI, "submitYieldAttestation", 200_000)
+	chains := chain.NewManager()
+	chains.Register(1, "ethereum", contracts)
+
+	cfg.Enabled, cfg.ChainID, cfg.ServiceManager = true, 1, serviceManagerAddress.Hex()
+	s, err := NewSubmitter(cfg, chains, txmgr.NewSet(chains, account, txmgr.DefaultConfig()), adapters.NewRegistry(&fakeAdapter{protocol: "aave_v3"}), zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewSubmitter failed: %v", err)
+	}
+	return s, contracts, account
+}
+
+func sentAttestation(t *testing.T, tx *types.Transaction) *Attestation {
+	t.Helper()
+	args, err := serviceManagerABI.Methods["submitYieldAttestation"].Inputs.Unpack(tx.Data()[4:])
+	if err != nil {
+		t.Fatalf("Failed to unpack submitYieldAttestation: %v", err)
+	}
+	return &Attestation{
+		ProtocolID:  args[0].([32]byte),
+		ChainID:     args[1].(*big.Int).Uint64(),
+		YieldRate:   args[2].(*big.Int).Uint64(),
+		TVL:         args[3].(*big.Int),
+		Utilization: args[4].(*big.Int).Uint64(),
+		RiskScore:   args[5].(*big.Int).Uint64(),
+		DataHash:    args[6].([32]byte),
+		Signature:   args[7].([]byte),
+	}
+}
+
+func Test_ProtocolID(t *testing.T) {
+	if ProtocolID("aave_v3") != crypto.Keccak256Hash([]byte("AAVE_V3")) {
+		t.Errorf("Expected protocols to be identified by the hash of their upper case name")
+	}
+}
+
+func Test_Consensus(t *testing.T) {
+	contracts := chaintest.NewContracts(1)
+	chains := chain.NewManager()
+	chains.Register(1, "ethereum", contracts)
+	sm := New(chains, 1, serviceManagerAddress)
+
+	protocolID := ProtocolID("aave_v3")
+	contracts.Stub(t, serviceManagerAddress, serviceManagerABI, "yieldConsensus", [32]byte(protocolID), big.NewInt(8453), big.NewInt(512),
+		big.NewInt(1_000_000), big.NewInt(2_500), big.NewInt(96_000_000), big.NewInt(3), big.NewInt(8_000), big.NewInt(1_700_000_000), true, true)
+	consensus, err := sm.Consensus(context.Background(), "aave_v3", 8453)
+	if err != nil {
+		t.Fatalf("Consensus failed: %v", err)
+	}
+	if consensus.ProtocolID != protocolID || consensus.ChainID != 8453 || consensus.YieldRate.Int64() != 512 || consensus.Attestations != 3 ||
+		consensus.ConfidenceLevel != 8_000 || !consensus.Valid || !consensus.Opportunity {
+		t.Errorf("Unexpected consensus %+v", consensus)
+	}
+
+	contracts.Stub(t, serviceManagerAddress, serviceManagerABI, "isYieldIntelligenceOperatorQualified", true)
+	if qualified, err := sm.IsOperatorQualified(context.Background(), common.Address{1}); err != nil || !qualified {
+		t.Errorf("Expected the operator to be qualified, got %v: %v", qualified, err)
+	}
+}
+
+func Test_SubmitterAttestsSamples(t *testing.T) {
+	s, contracts, account := newTestSubmitter(t, DefaultConfig())
+	now := time.Unix(1_700_000_000, 0)
+	s.now = func() time.Time { return now }
+
+	sample := collector.Sample{Protocol: "aave_v3", ChainID: 8453, SupplyRate: 0.0512, Utilization: 0.8,
+		TotalSupply: big.NewInt(50_000_000_000_000), TotalBorrow: big.NewInt(40_000_000_000_000), At: now}
+	s.Observe(context.Background(), []collector.Sample{sample})
+
+	sent := contracts.Sent()
+	if len(sent) != 1 || *sent[0].To() != serviceManagerAddress {
+		t.Fatalf("Expected one attestation to the service manager, got %d", len(sent))
+	}
+	a := sentAttestation(t, sent[0])
+	if a.ProtocolID != ProtocolID("aave_v3") || a.ChainID != 8453 || a.YieldRate != 512 || a.Utilization != 8_000 ||
+		a.TVL.Cmp(sample.TotalSupply) != 0 || a.RiskScore == 0 || a.RiskScore > 10_000 {
+		t.Errorf("Unexpected attestation %+v", a)
+	}
+
+	record := NewRecord(sample)
+	encoded, err := canonical.Marshal(&record)
+	if err != nil {
+		t.Fatalf("Failed to encode record: %v", err)
+	}
+	if a.DataHash != crypto.Keccak256Hash(encoded) {
+		t.Errorf("Expected the data hash to be the hash of the record")
+	}
+	pub, err := crypto.SigToPub(accounts.TextHash(a.DataHash.Bytes()), a.Signature)
+	if err != nil || crypto.PubkeyToAddress(*pub) != account.Address() {
+		t.Errorf("Expected the data hash to be signed by the operator: %v", err)
+	}
+
+	// markets are attested once per interval
+	now = now.Add(30 * time.Minute)
+	s.Observe(context.Background(), []collector.Sample{sample})
+	if len(contracts.Sent()) != 1 {
+		t.Errorf("Expected a market attested within the interval not to be attested again")
+	}
+	now = now.Add(30 * time.Minute)
+	s.Observe(context.Background(), []collector.Sample{sample})
+	if len(contracts.Sent()) != 2 {
+		t.Errorf("Expected the market to be attested again after the interval")
+	}
+}
+
+func Test_SubmitterRespondsToAttestations(t *testing.T) {
+	s, contracts, account := newTestSubmitter(t, DefaultConfig())
+	now := time.Unix(1_700_000_000, 0)
+	s.now = func() time.Time { return now }
+	event := serviceManagerABI.Events["YieldAttestationSubmitted"]
+	attested := func(block uint64, operator common.Address, protocol string) types.Log {
+		data, err := event.Inputs.NonIndexed().Pack(big.NewInt(1), big.NewInt(500))
+		if err != nil {
+			t.Fatalf("Failed to pack event: %v", err)
+		}
+		return types.Log{
+			Address:     serviceManagerAddress,
+			Topics:      []common.Hash{event.ID, {byte(block)}, common.BytesToHash(operator.Bytes()), ProtocolID(protocol)},
+			Data:        data,
+			BlockNumber: block,
+		}
+	}
+
+	contracts.SetHead(10, 0)
+	if _, err := s.Poll(context.Background()); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	contracts.AddLog(attested(11, common.Address{1}, "aave_v3"))
+	contracts.AddLog(attested(11, common.Address{2}, "aave_v3"))
+	contracts.AddLog(attested(12, account.Address(), "aave_v3"))
+	contracts.AddLog(attested(12, common.Address{1}, "unknown"))
+	contracts.SetHead(12, 0)
+
+	responded, err := s.Poll(context.Background())
+	if err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	if responded != 1 || len(contracts.Sent()) != 1 {
+		t.Fatalf("Expected one response to the attestations of aave_v3, got %d", responded)
+	}
+	if a := sentAttestation(t, contracts.Sent()[0]); a.ProtocolID != ProtocolID("aave_v3") || a.ChainID != 1 {
+		t.Errorf("Unexpected response %+v", a)
+	}
+
+	// the response counts towards consensus for the window
+	now = now.Add(time.Minute)
+	contracts.AddLog(attested(13, common.Address{3}, "aave_v3"))
+	contracts.SetHead(13, 0)
+	if responded, err := s.Poll(context.Background()); err != nil || responded != 0 {
+		t.Errorf("Expected no response within the consensus window, got %d: %v", responded, err)
+	}
+}
+
+func Test_Validate(t *testing.T) {
+	for name, cfg := range map[string]Config{
+		"chain":    {Enabled: true, ServiceManager: serviceManagerAddress.Hex(), Interval: time.Hour},
+		"address":  {Enabled: true, ChainID: 1, ServiceManager: "sm", Interval: time.Hour},
+		"interval": {Enabled: true, ChainID: 1, ServiceManager: serviceManagerAddress.Hex()},
+		"poll":     {Enabled: true, ChainID: 1, ServiceManager: serviceManagerAddress.Hex(), Interval: time.Hour, RespondToAttestations: true, MaxBlockRange: 1},
+	} {
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("%s: expected the config to be rejected", name)
+		}
+	}
+}
@@ -0,0 +1,364 @@
+package servicemanager
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/najnomics/crosscow-avs/pkg/adapters"
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/collector"
+	"github.com/najnomics/crosscow-avs/pkg/risk"
+	"github.com/najnomics/crosscow-avs/pkg/txmgr"
+	"go.uber.org/zap"
+)
+
+// Config configures submitting yield attestations to the service manager
+type Config struct {
+	Enabled bool `yaml:"enabled"`
+
+	// ChainID is the chain of the service manager and ServiceManager its address
+	ChainID        uint64 `yaml:"chainId"`
+	ServiceManager string `yaml:"serviceManager"`
+
+	// Interval is the minimum time between two attestations of a market from the samples
+	// of the collector
+	Interval time.Duration `yaml:"interval"`
+
+	// RespondToAttestations attests a market as soon as another operator attested it,
+	// unless this operator attested it within the consensus window, so the consensus of
+	// the market can be reached
+	RespondToAttestations bool `yaml:"respondToAttestations"`
+
+	// PollInterval is the time between queries of attestation events, and MaxBlockRange
+	// bounds the blocks of a single query
+	PollInterval  time.Duration `yaml:"pollInterval"`
+	MaxBlockRange uint64        `yaml:"maxBlockRange"`
+}
+
+// DefaultConfig is disabled. Enabled, every market is attested hourly and whenever
+// another operator attests it, polling for attestations every Ethereum block time.
+func DefaultConfig() Config {
+	return Config{
+		Interval:              time.Hour,
+		RespondToAttestations: true,
+		PollInterval:          12 * time.Second,
+		MaxBlockRange:         2_000,
+	}
+}
+
+// Validate checks the config for values attestations cannot be submitted with
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.ChainID == 0 {
+		return fmt.Errorf("chainId is required")
+	}
+	if !common.IsHexAddress(c.ServiceManager) {
+		return fmt.Errorf("invalid serviceManager address %q", c.ServiceManager)
+	}
+	if c.Interval <= 0 {
+		return fmt.Errorf("interval must be positive")
+	}
+	if c.RespondToAttestations {
+		if c.PollInterval <= 0 {
+			return fmt.Errorf("pollInterval must be positive")
+		}
+		if c.MaxBlockRange == 0 {
+			return fmt.Errorf("maxBlockRange must be positive")
+		}
+	}
+	return nil
+}
+
+// Record is the market data an attestation is computed from. Its data hash is the
+// keccak256 hash of its canonical JSON, so the data can be published and checked
+// against the attestation.
+type Record struct {
+	Protocol    string            `json:"protocol"`
+	ChainID     uint64            `json:"chain_id"`
+	SupplyRate  canonical.Decimal `json:"supply_rate"`
+	Utilization canonical.Decimal `json:"utilization"`
+	TotalSupply string            `json:"total_supply"`
+	TotalBorrow string            `json:"total_borrow"`
+	SampledAt   int64             `json:"sampled_at"`
+}
+
+// market is a protocol on a chain
+type market struct {
+	protocol string
+	chainID  uint64
+}
+
+// Submitter submits the yield attestations of the operator to the service manager, from
+// the account of the performer on its chain
+type Submitter struct {
+	cfg          Config
+	sm           *ServiceManager
+	transactions *txmgr.Set
+	registry     *adapters.Registry
+	logger       *zap.Logger
+	now          func() time.Time
+
+	mu        sync.Mutex
+	submitted map[market]time.Time
+	cursor    uint64
+	scanned   bool
+}
+
+// NewSubmitter creates a submitter sending attestations with transactions and reading
+// the markets other operators attested through registry
+func NewSubmitter(cfg Config, chains *chain.Manager, transactions *txmgr.Set, registry *adapters.Registry, logger *zap.Logger) (*Submitter, error) {
+	if transactions == nil {
+		return nil, fmt.Errorf("transactions must be enabled to submit attestations")
+	}
+	return &Submitter{
+		cfg:          cfg,
+		sm:           New(chains, cfg.ChainID, common.HexToAddress(cfg.ServiceManager)),
+		transactions: transactions,
+		registry:     registry,
+		logger:       logger,
+		now:          time.Now,
+		submitted:    make(map[market]time.Time),
+	}, nil
+}
+
+// ServiceManager returns the service manager attestations are submitted to
+func (s *Submitter) ServiceManager() *ServiceManager {
+	return s.sm
+}
+
+// Start polls for the attestations of other operators until ctx is cancelled, when
+// responding to them is enabled. An operator without the stake to attest is only
+// warned about, since stake can be added while running.
+func (s *Submitter) Start(ctx context.Context) {
+	operator := s.transactions.Address(s.cfg.ChainID)
+	if qualified, err := s.sm.IsOperatorQualified(ctx, operator); err != nil {
+		s.logger.Sugar().Warnw("Failed to check operator qualification", "operator", operator.Hex(), "error", err)
+	} else if !qualified {
+		s.logger.Sugar().Warnw("Operator is not qualified to submit yield attestations", "operator", operator.Hex())
+	}
+	if !s.cfg.RespondToAttestations {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(s.cfg.PollInterval)
+		defer ticker.Stop()
+		for {
+			if responded, err := s.Poll(ctx); err != nil {
+				s.logger.Sugar().Warnw("Attestation polling incomplete", "responded", responded, "error", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Observe implements collector.Observer, attesting the sampled markets not attested
+// within the interval
+func (s *Submitter) Observe(ctx context.Context, samples []collector.Sample) {
+	for _, sample := range samples {
+		if !s.due(market{protocol: sample.Protocol, chainID: sample.ChainID}, s.cfg.Interval) {
+			continue
+		}
+		if _, err := s.Submit(ctx, sample); err != nil {
+			s.logger.Sugar().Errorw("Failed to submit yield attestation", "protocol", sample.Protocol, "chainId", sample.ChainID, "error", err)
+		}
+	}
+}
+
+// Poll scans the blocks produced since the previous poll for the attestations of other
+// operators and attests the markets they attested. It returns the number of attestations
+// submitted and the last error encountered.
+func (s *Submitter) Poll(ctx context.Context) (int, error) {
+	client, err := s.sm.chains.Client(s.cfg.ChainID)
+	if err != nil {
+		return 0, err
+	}
+	head, err := client.BlockNumber(ctx)
+	if err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	cursor, scanned := s.cursor, s.scanned
+	s.mu.Unlock()
+	if !scanned {
+		// attestations from before the start are too old to be responded to
+		s.advance(head)
+		return 0, nil
+	}
+	if head <= cursor {
+		return 0, nil
+	}
+	to := head
+	if to-cursor > s.cfg.MaxBlockRange {
+		to = cursor + s.cfg.MaxBlockRange
+	}
+	attested, err := s.sm.AttestationsSubmitted(ctx, cursor+1, to)
+	if err != nil {
+		// the cursor stays, so the blocks are scanned again on the next poll
+		return 0, err
+	}
+	s.advance(to)
+
+	protocols := make(map[common.Hash]string)
+	for _, protocol := range s.registry.Protocols() {
+		protocols[ProtocolID(protocol)] = protocol
+	}
+	operator := s.transactions.Address(s.cfg.ChainID)
+	responded := 0
+	var lastErr error
+	for _, a := range attested {
+		protocol, known := protocols[a.ProtocolID]
+		m := market{protocol: protocol, chainID: a.ChainID}
+		if a.Operator == operator || !known || !s.due(m, ConsensusWindow) {
+			continue
+		}
+		sample, err := s.read(ctx, m)
+		if err != nil {
+			lastErr = fmt.Errorf("%s on chain %d: %w", protocol, a.ChainID, err)
+			continue
+		}
+		if _, err := s.Submit(ctx, *sample); err != nil {
+			lastErr = fmt.Errorf("%s on chain %d: %w", protocol, a.ChainID, err)
+			continue
+		}
+		responded++
+	}
+	return responded, lastErr
+}
+
+func (s *Submitter) advance(block uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cursor, s.scanned = block, true
+}
+
+// due reports whether m was not attested within the last window
+func (s *Submitter) due(m market, window time.Duration) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	last, ok := s.submitted[m]
+	return !ok || s.now().Sub(last) >= window
+}
+
+// read samples the current state of m
+func (s *Submitter) read(ctx context.Context, m market) (*collector.Sample, error) {
+	adapter, err := s.registry.Get(m.protocol)
+	if err != nil {
+		return nil, err
+	}
+	state, err := adapter.MarketState(ctx, m.chainID)
+	if err != nil {
+		return nil, err
+	}
+	return &collector.Sample{
+		Protocol:    m.protocol,
+		ChainID:     m.chainID,
+		SupplyRate:  state.Pool.SupplyRate(),
+		Utilization: state.Pool.Utilization(),
+		TotalSupply: state.Pool.TotalSupply,
+		TotalBorrow: state.Pool.TotalBorrow,
+		At:          s.now(),
+	}, nil
+}
+
+// Submit attests the market of sample and returns the hash of the transaction
+// submitting the attestation
+func (s *Submitter) Submit(ctx context.Context, sample collector.Sample) (common.Hash, error) {
+	manager, err := s.transactions.For(s.cfg.ChainID)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	a, err := s.Attestation(ctx, sample)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	call, err := s.sm.SubmitAttestation(a)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	tx, err := manager.Send(ctx, txmgr.Request{Call: call})
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to send submitYieldAttestation: %w", err)
+	}
+
+	s.mu.Lock()
+	s.submitted[market{protocol: sample.Protocol, chainID: sample.ChainID}] = s.now()
+	s.mu.Unlock()
+	s.logger.Sugar().Infow("Submitted yield attestation", "protocol", sample.Protocol, "chainId", sample.ChainID,
+		"yieldRateBps", a.YieldRate, "tx", tx.Hash().Hex())
+	return tx.Hash(), nil
+}
+
+// Attestation computes the attestation of the market of sample, signing its data hash
+// with the account submitting it. The risk score is the overall score risk.Assess gives
+// the size and usage of the market.
+func (s *Submitter) Attestation(ctx context.Context, sample collector.Sample) (*Attestation, error) {
+	record := NewRecord(sample)
+	encoded, err := canonical.Marshal(&record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode record: %w", err)
+	}
+	dataHash := crypto.Keccak256Hash(encoded)
+	signature, err := s.transactions.Signer(s.cfg.ChainID).SignMessage(ctx, dataHash.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign data hash: %w", err)
+	}
+
+	assessment := risk.Assess(&risk.Metrics{TotalSupply: sample.TotalSupply, TotalBorrow: sample.TotalBorrow}, risk.DefaultThresholds, risk.DefaultWeights)
+	return &Attestation{
+		ProtocolID:  ProtocolID(sample.Protocol),
+		ChainID:     sample.ChainID,
+		YieldRate:   bps(sample.SupplyRate),
+		TVL:         sample.TotalSupply,
+		Utilization: bps(sample.Utilization),
+		RiskScore:   scoreBps(assessment.Overall),
+		DataHash:    dataHash,
+		Signature:   signature,
+	}, nil
+}
+
+// NewRecord returns the record of sample
+func NewRecord(sample collector.Sample) Record {
+	return Record{
+		Protocol:    sample.Protocol,
+		ChainID:     sample.ChainID,
+		SupplyRate:  canonical.APY(sample.SupplyRate * 100),
+		Utilization: canonical.Ratio(sample.Utilization),
+		TotalSupply: amount(sample.TotalSupply),
+		TotalBorrow: amount(sample.TotalBorrow),
+		SampledAt:   sample.At.Unix(),
+	}
+}
+
+func amount(a *big.Int) string {
+	if a == nil {
+		return "0"
+	}
+	return a.String()
+}
+
+// bps converts a fraction to rounded basis points, negative fractions to zero
+func bps(fraction float64) uint64 {
+	if fraction <= 0 {
+		return 0
+	}
+	return uint64(fraction*10_000 + 0.5)
+}
+
+// scoreBps converts a risk score from 0 to 100 to basis points
+func scoreBps(score *big.Rat) uint64 {
+	scaled := new(big.Rat).Mul(score, big.NewRat(100, 1))
+	return new(big.Int).Quo(scaled.Num(), scaled.Denom()).Uint64()
+}
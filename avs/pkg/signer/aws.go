@@ -0,0 +1,83 @@
+package signer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	kmstypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+// AWSKMSConfig selects an ECC_SECG_P256K1 signing key in AWS KMS. Credentials come from
+// the default AWS chain: environment, shared config or the instance role.
+type AWSKMSConfig struct {
+	KeyID  string `yaml:"keyId"`
+	Region string `yaml:"region"`
+
+	// Endpoint overrides the KMS endpoint, for VPC endpoints and local emulators
+	Endpoint string `yaml:"endpoint"`
+}
+
+// AWSKMSClient is the part of the AWS KMS client signing needs
+type AWSKMSClient interface {
+	GetPublicKey(ctx context.Context, params *kms.GetPublicKeyInput, optFns ...func(*kms.Options)) (*kms.GetPublicKeyOutput, error)
+	Sign(ctx context.Context, params *kms.SignInput, optFns ...func(*kms.Options)) (*kms.SignOutput, error)
+}
+
+type awsKey struct {
+	client AWSKMSClient
+	keyID  string
+}
+
+// NewAWSKMSSigner creates a signer for cfg.KeyID, reading its address from KMS
+func NewAWSKMSSigner(ctx context.Context, cfg AWSKMSConfig) (*KMSSigner, error) {
+	var opts []func(*config.LoadOptions) error
+	if cfg.Region != "" {
+		opts = append(opts, config.WithRegion(cfg.Region))
+	}
+	awsCfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load aws config: %w", err)
+	}
+	client := kms.NewFromConfig(awsCfg, func(o *kms.Options) {
+		if cfg.Endpoint != "" {
+			o.BaseEndpoint = aws.String(cfg.Endpoint)
+		}
+	})
+	return NewAWSKMSSignerWithClient(ctx, client, cfg.KeyID)
+}
+
+// NewAWSKMSSignerWithClient creates a signer for keyID using client
+func NewAWSKMSSignerWithClient(ctx context.Context, client AWSKMSClient, keyID string) (*KMSSigner, error) {
+	s, err := newKMSSigner(ctx, &awsKey{client: client, keyID: keyID})
+	if err != nil {
+		return nil, fmt.Errorf("aws kms key %s: %w", keyID, err)
+	}
+	return s, nil
+}
+
+func (k *awsKey) publicKey(ctx context.Context) ([]byte, error) {
+	out, err := k.client.GetPublicKey(ctx, &kms.GetPublicKeyInput{KeyId: aws.String(k.keyID)})
+	if err != nil {
+		return nil, err
+	}
+	if out.KeySpec != kmstypes.KeySpecEccSecgP256k1 {
+		return nil, fmt.Errorf("key spec %s is not %s", out.KeySpec, kmstypes.KeySpecEccSecgP256k1)
+	}
+	return out.PublicKey, nil
+}
+
+func (k *awsKey) sign(ctx context.Context, digest []byte) ([]byte, error) {
+	out, err := k.client.Sign(ctx, &kms.SignInput{
+		KeyId:            aws.String(k.keyID),
+		Message:          digest,
+		MessageType:      kmstypes.MessageTypeDigest,
+		SigningAlgorithm: kmstypes.SigningAlgorithmSpecEcdsaSha256,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.Signature, nil
+}
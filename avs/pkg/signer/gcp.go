@@ -0,0 +1,95 @@
+package signer
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+
+	kms "cloud.google.com/go/kms/apiv1"
+	"cloud.google.com/go/kms/apiv1/kmspb"
+	"github.com/googleapis/gax-go/v2"
+	"google.golang.org/api/option"
+)
+
+// GCPKMSConfig selects an EC_SIGN_SECP256K1_SHA256 key version in Google Cloud KMS.
+// Credentials come from CredentialsFile when set, otherwise from the application
+// default credentials.
+type GCPKMSConfig struct {
+	// KeyName is the full resource name of the key version:
+	// projects/*/locations/*/keyRings/*/cryptoKeys/*/cryptoKeyVersions/*
+	KeyName         string `yaml:"keyName"`
+	CredentialsFile string `yaml:"credentialsFile"`
+
+	// Endpoint overrides the KMS endpoint, for private endpoints and local emulators
+	Endpoint string `yaml:"endpoint"`
+}
+
+// GCPKMSClient is the part of the Cloud KMS client signing needs
+type GCPKMSClient interface {
+	GetPublicKey(ctx context.Context, req *kmspb.GetPublicKeyRequest, opts ...gax.CallOption) (*kmspb.PublicKey, error)
+	AsymmetricSign(ctx context.Context, req *kmspb.AsymmetricSignRequest, opts ...gax.CallOption) (*kmspb.AsymmetricSignResponse, error)
+}
+
+type gcpKey struct {
+	client GCPKMSClient
+	name   string
+}
+
+// NewGCPKMSSigner creates a signer for cfg.KeyName, reading its address from KMS. The
+// client connection stays open for the life of the process.
+func NewGCPKMSSigner(ctx context.Context, cfg GCPKMSConfig) (*KMSSigner, error) {
+	var opts []option.ClientOption
+	if cfg.CredentialsFile != "" {
+		opts = append(opts, option.WithCredentialsFile(cfg.CredentialsFile))
+	}
+	if cfg.Endpoint != "" {
+		opts = append(opts, option.WithEndpoint(cfg.Endpoint))
+	}
+	client, err := kms.NewKeyManagementClient(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gcp kms client: %w", err)
+	}
+	s, err := NewGCPKMSSignerWithClient(ctx, client, cfg.KeyName)
+	if err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// NewGCPKMSSignerWithClient creates a signer for the key version name using client
+func NewGCPKMSSignerWithClient(ctx context.Context, client GCPKMSClient, name string) (*KMSSigner, error) {
+	s, err := newKMSSigner(ctx, &gcpKey{client: client, name: name})
+	if err != nil {
+		return nil, fmt.Errorf("gcp kms key %s: %w", name, err)
+	}
+	return s, nil
+}
+
+func (k *gcpKey) publicKey(ctx context.Context) ([]byte, error) {
+	key, err := k.client.GetPublicKey(ctx, &kmspb.GetPublicKeyRequest{Name: k.name})
+	if err != nil {
+		return nil, err
+	}
+	if key.Algorithm != kmspb.CryptoKeyVersion_EC_SIGN_SECP256K1_SHA256 {
+		return nil, fmt.Errorf("algorithm %s is not %s", key.Algorithm, kmspb.CryptoKeyVersion_EC_SIGN_SECP256K1_SHA256)
+	}
+	block, _ := pem.Decode([]byte(key.Pem))
+	if block == nil {
+		return nil, fmt.Errorf("public key is not PEM encoded")
+	}
+	return block.Bytes, nil
+}
+
+// sign passes the transaction hash as the SHA-256 digest; KMS signs whatever 32 bytes
+// it is given
+func (k *gcpKey) sign(ctx context.Context, digest []byte) ([]byte, error) {
+	resp, err := k.client.AsymmetricSign(ctx, &kmspb.AsymmetricSignRequest{
+		Name:   k.name,
+		Digest: &kmspb.Digest{Digest: &kmspb.Digest_Sha256{Sha256: digest}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Signature, nil
+}
@@ -0,0 +1,108 @@
+package signer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// KeySigner signs with a private key held in memory
+type KeySigner struct {
+	key     *ecdsa.PrivateKey
+	address common.Address
+}
+
+// NewKeySigner creates a signer for key
+func NewKeySigner(key *ecdsa.PrivateKey) *KeySigner {
+	return &KeySigner{key: key, address: crypto.PubkeyToAddress(key.PublicKey)}
+}
+
+// NewHexSigner creates a signer from a hex encoded private key, with or without 0x prefix
+func NewHexSigner(hexKey string) (*KeySigner, error) {
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(strings.TrimSpace(hexKey), "0x"))
+	if err != nil {
+		// The error of HexToECDSA never includes the key
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+	return NewKeySigner(key), nil
+}
+
+// NewEnvSigner creates a signer from the hex private key in the environment variable name
+func NewEnvSigner(name string) (*KeySigner, error) {
+	hexKey, ok := os.LookupEnv(name)
+	if !ok || hexKey == "" {
+		return nil, fmt.Errorf("environment variable %s is not set", name)
+	}
+	s, err := NewHexSigner(hexKey)
+	if err != nil {
+		return nil, fmt.Errorf("environment variable %s: %w", name, err)
+	}
+	return s, nil
+}
+
+// NewKeystoreSigner decrypts the keystore file in cfg
+func NewKeystoreSigner(cfg KeystoreConfig) (*KeySigner, error) {
+	data, err := os.ReadFile(cfg.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keystore: %w", err)
+	}
+	password, err := cfg.ReadPassword()
+	if err != nil {
+		return nil, err
+	}
+	key, err := keystore.DecryptKey(data, password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt keystore %s: %w", cfg.Path, err)
+	}
+	return NewKeySigner(key.PrivateKey), nil
+}
+
+// ReadPassword returns the password of the keystore, reading it from its file or variable
+// when it is not set in the config
+func (cfg KeystoreConfig) ReadPassword() (string, error) {
+	if cfg.Password != "" {
+		return cfg.Password, nil
+	}
+	if cfg.PasswordFile != "" {
+		data, err := os.ReadFile(cfg.PasswordFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read keystore password: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+	password, ok := os.LookupEnv(cfg.PasswordEnv)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", cfg.PasswordEnv)
+	}
+	return password, nil
+}
+
+func (s *KeySigner) Address() common.Address {
+	return s.address
+}
+
+func (s *KeySigner) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return types.SignTx(tx, types.LatestSignerForChainID(chainID), s.key)
+}
+
+func (s *KeySigner) SignMessage(ctx context.Context, message []byte) ([]byte, error) {
+	return crypto.Sign(accounts.TextHash(message), s.key)
+}
+
+func (s *KeySigner) SignTypedData(ctx context.Context, data apitypes.TypedData) ([]byte, error) {
+	hash, err := typedDataHash(data)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.Sign(hash, s.key)
+}
@@ -0,0 +1,139 @@
+package signer
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// kmsKey is a secp256k1 key held by a key management service. The service signs digests
+// and returns DER encoded ECDSA signatures without the recovery id Ethereum needs.
+type kmsKey interface {
+	// publicKey returns the DER encoded SubjectPublicKeyInfo of the key
+	publicKey(ctx context.Context) ([]byte, error)
+
+	// sign returns the DER encoded signature of digest
+	sign(ctx context.Context, digest []byte) ([]byte, error)
+}
+
+// KMSSigner signs with a key that never leaves a key management service
+type KMSSigner struct {
+	key     kmsKey
+	pubkey  []byte
+	address common.Address
+}
+
+// secp256k1N is the order of the curve. Signatures with s above half of it are rejected
+// by Ethereum.
+var (
+	secp256k1N     = crypto.S256().Params().N
+	secp256k1HalfN = new(big.Int).Rsh(secp256k1N, 1)
+)
+
+func newKMSSigner(ctx context.Context, key kmsKey) (*KMSSigner, error) {
+	der, err := key.publicKey(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read public key: %w", err)
+	}
+	pub, err := parsePublicKey(der)
+	if err != nil {
+		return nil, err
+	}
+	return &KMSSigner{
+		key:     key,
+		pubkey:  crypto.FromECDSAPub(pub),
+		address: crypto.PubkeyToAddress(*pub),
+	}, nil
+}
+
+func (s *KMSSigner) Address() common.Address {
+	return s.address
+}
+
+func (s *KMSSigner) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	txSigner := types.LatestSignerForChainID(chainID)
+	digest := txSigner.Hash(tx).Bytes()
+	der, err := s.key.sign(ctx, digest)
+	if err != nil {
+		return nil, fmt.Errorf("kms: failed to sign: %w", err)
+	}
+	sig, err := s.recoverable(digest, der)
+	if err != nil {
+		return nil, err
+	}
+	return tx.WithSignature(txSigner, sig)
+}
+
+func (s *KMSSigner) SignMessage(ctx context.Context, message []byte) ([]byte, error) {
+	digest := accounts.TextHash(message)
+	der, err := s.key.sign(ctx, digest)
+	if err != nil {
+		return nil, fmt.Errorf("kms: failed to sign: %w", err)
+	}
+	return s.recoverable(digest, der)
+}
+
+func (s *KMSSigner) SignTypedData(ctx context.Context, data apitypes.TypedData) ([]byte, error) {
+	digest, err := typedDataHash(data)
+	if err != nil {
+		return nil, err
+	}
+	der, err := s.key.sign(ctx, digest)
+	if err != nil {
+		return nil, fmt.Errorf("kms: failed to sign: %w", err)
+	}
+	return s.recoverable(digest, der)
+}
+
+// recoverable converts a DER signature into the 65 byte r || s || v form, normalising s
+// and finding the recovery id that yields the signer's public key
+func (s *KMSSigner) recoverable(digest, der []byte) ([]byte, error) {
+	var parsed struct {
+		R, S *big.Int
+	}
+	if _, err := asn1.Unmarshal(der, &parsed); err != nil {
+		return nil, fmt.Errorf("kms: invalid signature encoding: %w", err)
+	}
+	if parsed.S.Cmp(secp256k1HalfN) > 0 {
+		parsed.S.Sub(secp256k1N, parsed.S)
+	}
+
+	sig := make([]byte, crypto.SignatureLength)
+	parsed.R.FillBytes(sig[:32])
+	parsed.S.FillBytes(sig[32:64])
+	for v := byte(0); v < 2; v++ {
+		sig[64] = v
+		recovered, err := crypto.Ecrecover(digest, sig)
+		if err == nil && bytes.Equal(recovered, s.pubkey) {
+			return sig, nil
+		}
+	}
+	return nil, fmt.Errorf("kms: signature does not match key %s", s.address.Hex())
+}
+
+// parsePublicKey reads a secp256k1 key from a SubjectPublicKeyInfo, which crypto/x509
+// cannot parse for this curve
+func parsePublicKey(der []byte) (*ecdsa.PublicKey, error) {
+	var info struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, fmt.Errorf("kms: invalid public key encoding: %w", err)
+	}
+	pub, err := crypto.UnmarshalPubkey(info.PublicKey.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("kms: key is not a secp256k1 key: %w", err)
+	}
+	return pub, nil
+}
@@ -0,0 +1,190 @@
+package signer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	kms "cloud.google.com/go/kms/apiv1"
+	"cloud.google.com/go/kms/apiv1/kmspb"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	awskms "github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/ethereum/go-ethereum/crypto"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// mockKey signs like a KMS: DER encoded signatures without a recovery id. With highS set,
+// it returns the s value Ethereum rejects, which KMS is free to do.
+type mockKey struct {
+	key   *ecdsa.PrivateKey
+	highS bool
+}
+
+func newMockKey(t *testing.T, highS bool) *mockKey {
+	t.Helper()
+	key, err := crypto.HexToECDSA(testKey)
+	if err != nil {
+		t.Fatalf("Failed to parse key: %v", err)
+	}
+	return &mockKey{key: key, highS: highS}
+}
+
+func (k *mockKey) publicKeyInfo(t *testing.T) []byte {
+	t.Helper()
+	curve, err := asn1.Marshal(asn1.ObjectIdentifier{1, 3, 132, 0, 10})
+	if err != nil {
+		t.Fatalf("Failed to encode curve: %v", err)
+	}
+	der, err := asn1.Marshal(struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}{
+		Algorithm: pkix.AlgorithmIdentifier{
+			Algorithm:  asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1},
+			Parameters: asn1.RawValue{FullBytes: curve},
+		},
+		PublicKey: asn1.BitString{Bytes: crypto.FromECDSAPub(&k.key.PublicKey), BitLength: 65 * 8},
+	})
+	if err != nil {
+		t.Fatalf("Failed to encode public key: %v", err)
+	}
+	return der
+}
+
+func (k *mockKey) sign(digest []byte) ([]byte, error) {
+	sig, err := crypto.Sign(digest, k.key)
+	if err != nil {
+		return nil, err
+	}
+	r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:64])
+	if k.highS {
+		s.Sub(secp256k1N, s)
+	}
+	return asn1.Marshal(struct{ R, S *big.Int }{r, s})
+}
+
+// newMockAWSKMS serves the GetPublicKey and Sign operations of the AWS KMS JSON protocol
+func newMockAWSKMS(t *testing.T, key *mockKey) *httptest.Server {
+	t.Helper()
+	publicKey := key.publicKeyInfo(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			KeyId   string
+			Message []byte
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.KeyId != "alias/operator" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		switch r.Header.Get("X-Amz-Target") {
+		case "TrentService.GetPublicKey":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"KeyId": req.KeyId, "KeySpec": "ECC_SECG_P256K1", "PublicKey": publicKey})
+		case "TrentService.Sign":
+			sig, err := key.sign(req.Message)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"KeyId": req.KeyId, "Signature": sig, "SigningAlgorithm": "ECDSA_SHA_256"})
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func Test_AWSKMSSigner(t *testing.T) {
+	for _, highS := range []bool{false, true} {
+		key := newMockKey(t, highS)
+		server := newMockAWSKMS(t, key)
+		client := awskms.New(awskms.Options{
+			Region:       "us-east-1",
+			BaseEndpoint: aws.String(server.URL),
+			Credentials:  credentials.NewStaticCredentialsProvider("id", "secret", ""),
+		})
+
+		s, err := NewAWSKMSSignerWithClient(context.Background(), client, "alias/operator")
+		if err != nil {
+			t.Fatalf("NewAWSKMSSignerWithClient failed: %v", err)
+		}
+		if s.Address() != crypto.PubkeyToAddress(key.key.PublicKey) {
+			t.Errorf("Unexpected kms address %s", s.Address().Hex())
+		}
+		signed, err := s.SignTx(context.Background(), testTx(), big.NewInt(1))
+		if err != nil {
+			t.Fatalf("SignTx failed with high s %v: %v", highS, err)
+		}
+		assertSignedBy(t, signed, s.Address())
+		assertMessageSignedBy(t, s, s.Address())
+		assertTypedDataSignedBy(t, s, s.Address())
+	}
+}
+
+type mockGCPKMS struct {
+	kmspb.UnimplementedKeyManagementServiceServer
+	key       *mockKey
+	publicKey []byte
+}
+
+func (m *mockGCPKMS) GetPublicKey(ctx context.Context, req *kmspb.GetPublicKeyRequest) (*kmspb.PublicKey, error) {
+	return &kmspb.PublicKey{
+		Pem:       string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: m.publicKey})),
+		Algorithm: kmspb.CryptoKeyVersion_EC_SIGN_SECP256K1_SHA256,
+		Name:      req.Name,
+	}, nil
+}
+
+func (m *mockGCPKMS) AsymmetricSign(ctx context.Context, req *kmspb.AsymmetricSignRequest) (*kmspb.AsymmetricSignResponse, error) {
+	sig, err := m.key.sign(req.GetDigest().GetSha256())
+	if err != nil {
+		return nil, err
+	}
+	return &kmspb.AsymmetricSignResponse{Signature: sig, Name: req.Name}, nil
+}
+
+func Test_GCPKMSSigner(t *testing.T) {
+	key := newMockKey(t, true)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := grpc.NewServer()
+	kmspb.RegisterKeyManagementServiceServer(server, &mockGCPKMS{key: key, publicKey: key.publicKeyInfo(t)})
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	ctx := context.Background()
+	client, err := kms.NewKeyManagementClient(ctx,
+		option.WithEndpoint(listener.Addr().String()),
+		option.WithoutAuthentication(),
+		option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create kms client: %v", err)
+	}
+	defer client.Close()
+
+	name := "projects/p/locations/global/keyRings/avs/cryptoKeys/operator/cryptoKeyVersions/1"
+	s, err := NewGCPKMSSignerWithClient(ctx, client, name)
+	if err != nil {
+		t.Fatalf("NewGCPKMSSignerWithClient failed: %v", err)
+	}
+	signed, err := s.SignTx(ctx, testTx(), big.NewInt(1))
+	if err != nil {
+		t.Fatalf("SignTx failed: %v", err)
+	}
+	assertSignedBy(t, signed, crypto.PubkeyToAddress(key.key.PublicKey))
+}
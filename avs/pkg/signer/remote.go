@@ -0,0 +1,243 @@
+package signer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/najnomics/crosscow-avs/pkg/resilience"
+)
+
+// RemoteSigner asks a signing service to sign transactions over JSON-RPC
+// eth_signTransaction, messages over eth_sign and typed data over eth_signTypedData_v4.
+// The key never leaves the service.
+type RemoteSigner struct {
+	url        string
+	address    common.Address
+	httpClient *http.Client
+	policy     *resilience.Policy
+}
+
+// NewRemoteSigner creates a signer for cfg.Address served at cfg.Url
+func NewRemoteSigner(cfg RemoteConfig, policy *resilience.Policy) *RemoteSigner {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultConfig().Remote.Timeout
+	}
+	return &RemoteSigner{
+		url:        cfg.Url,
+		address:    common.HexToAddress(cfg.Address),
+		httpClient: &http.Client{Timeout: cfg.Timeout},
+		policy:     policy,
+	}
+}
+
+type txArgs struct {
+	From                 common.Address  `json:"from"`
+	To                   *common.Address `json:"to,omitempty"`
+	Gas                  hexutil.Uint64  `json:"gas"`
+	MaxFeePerGas         *hexutil.Big    `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *hexutil.Big    `json:"maxPriorityFeePerGas"`
+	Value                *hexutil.Big    `json:"value"`
+	Data                 hexutil.Bytes   `json:"data"`
+	Nonce                hexutil.Uint64  `json:"nonce"`
+	ChainID              *hexutil.Big    `json:"chainId"`
+}
+
+type rpcRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      int           `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (s *RemoteSigner) Address() common.Address {
+	return s.address
+}
+
+// SignTx signs EIP-1559 transactions. The signed transaction is checked to be the one
+// requested, from the configured address.
+func (s *RemoteSigner) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	if tx.Type() != types.DynamicFeeTxType {
+		return nil, fmt.Errorf("remote signer: unsupported transaction type %d", tx.Type())
+	}
+	body, err := json.Marshal(rpcRequest{
+		JSONRPC: "2.0",
+		ID:      1,
+		Method:  "eth_signTransaction",
+		Params: []interface{}{txArgs{
+			From:                 s.address,
+			To:                   tx.To(),
+			Gas:                  hexutil.Uint64(tx.Gas()),
+			MaxFeePerGas:         (*hexutil.Big)(tx.GasFeeCap()),
+			MaxPriorityFeePerGas: (*hexutil.Big)(tx.GasTipCap()),
+			Value:                (*hexutil.Big)(tx.Value()),
+			Data:                 tx.Data(),
+			Nonce:                hexutil.Uint64(tx.Nonce()),
+			ChainID:              (*hexutil.Big)(chainID),
+		}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("remote signer: failed to encode request: %w", err)
+	}
+
+	result, err := s.call(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := rawTransaction(result)
+	if err != nil {
+		return nil, err
+	}
+
+	signed := new(types.Transaction)
+	if err := signed.UnmarshalBinary(raw); err != nil {
+		return nil, fmt.Errorf("remote signer: failed to decode signed transaction: %w", err)
+	}
+	from, err := types.Sender(types.LatestSignerForChainID(chainID), signed)
+	if err != nil {
+		return nil, fmt.Errorf("remote signer: invalid signature: %w", err)
+	}
+	if from != s.address {
+		return nil, fmt.Errorf("remote signer: transaction signed by %s, expected %s", from.Hex(), s.address.Hex())
+	}
+	if signed.Nonce() != tx.Nonce() || signed.Gas() != tx.Gas() || signed.GasFeeCap().Cmp(tx.GasFeeCap()) != 0 ||
+		signed.GasTipCap().Cmp(tx.GasTipCap()) != 0 || !bytes.Equal(signed.Data(), tx.Data()) ||
+		signed.Value().Cmp(tx.Value()) != 0 || signed.To() == nil || tx.To() == nil || *signed.To() != *tx.To() {
+		return nil, fmt.Errorf("remote signer: signed transaction differs from the request")
+	}
+	return signed, nil
+}
+
+// SignMessage signs message over eth_sign. The signature is checked to be made by the
+// configured address.
+func (s *RemoteSigner) SignMessage(ctx context.Context, message []byte) ([]byte, error) {
+	body, err := json.Marshal(rpcRequest{
+		JSONRPC: "2.0",
+		ID:      1,
+		Method:  "eth_sign",
+		Params:  []interface{}{s.address, hexutil.Bytes(message)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("remote signer: failed to encode request: %w", err)
+	}
+	result, err := s.call(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	return s.signature(result, accounts.TextHash(message))
+}
+
+// SignTypedData signs data over eth_signTypedData_v4. The signature is checked to be made
+// by the configured address.
+func (s *RemoteSigner) SignTypedData(ctx context.Context, data apitypes.TypedData) ([]byte, error) {
+	hash, err := typedDataHash(data)
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(rpcRequest{
+		JSONRPC: "2.0",
+		ID:      1,
+		Method:  "eth_signTypedData_v4",
+		Params:  []interface{}{s.address, data},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("remote signer: failed to encode request: %w", err)
+	}
+	result, err := s.call(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	return s.signature(result, hash)
+}
+
+// signature decodes the signature of hash in result, checking it is made by the
+// configured address
+func (s *RemoteSigner) signature(result json.RawMessage, hash []byte) ([]byte, error) {
+	var sig hexutil.Bytes
+	if err := json.Unmarshal(result, &sig); err != nil || len(sig) != crypto.SignatureLength {
+		return nil, fmt.Errorf("remote signer: unexpected result %s", string(result))
+	}
+	// signing services return the recovery id as 27 or 28
+	if sig[64] >= 27 {
+		sig[64] -= 27
+	}
+	pub, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		return nil, fmt.Errorf("remote signer: invalid signature: %w", err)
+	}
+	if from := crypto.PubkeyToAddress(*pub); from != s.address {
+		return nil, fmt.Errorf("remote signer: message signed by %s, expected %s", from.Hex(), s.address.Hex())
+	}
+	return sig, nil
+}
+
+// call sends a JSON-RPC request under the retry policy and returns its result
+func (s *RemoteSigner) call(ctx context.Context, body []byte) (json.RawMessage, error) {
+	var result json.RawMessage
+	err := s.policy.Do(ctx, "signer", func(ctx context.Context) error {
+		var err error
+		result, err = s.request(ctx, body)
+		return err
+	})
+	return result, err
+}
+
+func (s *RemoteSigner) request(ctx context.Context, body []byte) (json.RawMessage, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("remote signer: failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("remote signer: unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("remote signer: %w", &resilience.StatusError{Endpoint: s.url, StatusCode: resp.StatusCode})
+	}
+
+	var decoded rpcResponse
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("remote signer: failed to decode response: %w", err)
+	}
+	if decoded.Error != nil {
+		return nil, resilience.Permanent(fmt.Errorf("remote signer: %s (code %d)", decoded.Error.Message, decoded.Error.Code))
+	}
+	return decoded.Result, nil
+}
+
+// rawTransaction reads the signed transaction from a result that is either the encoded
+// transaction, as returned by Web3Signer, or an object with a raw field, as returned by
+// Geth and Clef
+func rawTransaction(result json.RawMessage) ([]byte, error) {
+	var encoded hexutil.Bytes
+	if err := json.Unmarshal(result, &encoded); err == nil {
+		return encoded, nil
+	}
+	var wrapped struct {
+		Raw hexutil.Bytes `json:"raw"`
+	}
+	if err := json.Unmarshal(result, &wrapped); err != nil || len(wrapped.Raw) == 0 {
+		return nil, fmt.Errorf("remote signer: unexpected result %s", string(result))
+	}
+	return wrapped.Raw, nil
+}
@@ -0,0 +1,165 @@
+// Package signer signs transactions and messages with keys held in a local keystore, in
+// the environment or the secrets provider, by a remote signing service or in AWS or
+// Google Cloud KMS, so the performer can submit transactions without Circle Wallets and
+// attest to its results.
+package signer
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/najnomics/crosscow-avs/pkg/resilience"
+)
+
+// Signer types
+const (
+	TypeEnv      = "env"
+	TypeSecret   = "secret"
+	TypeKeystore = "keystore"
+	TypeRemote   = "remote"
+	TypeAWSKMS   = "aws_kms"
+	TypeGCPKMS   = "gcp_kms"
+)
+
+// DefaultEnvVariable holds the hex private key of env signers
+const DefaultEnvVariable = "YIELD_AVS_PRIVATE_KEY"
+
+// Signer signs transactions and messages for a single account
+type Signer interface {
+	// Address is the account transactions are signed for
+	Address() common.Address
+
+	// SignTx returns tx signed for chainID
+	SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+
+	// SignMessage returns the 65 byte signature of the EIP-191 personal message hash of
+	// message, as wallets produce for personal_sign, with a recovery id of 0 or 1
+	SignMessage(ctx context.Context, message []byte) ([]byte, error)
+
+	// SignTypedData returns the 65 byte signature of the EIP-712 hash of data, as wallets
+	// produce for eth_signTypedData_v4, with a recovery id of 0 or 1
+	SignTypedData(ctx context.Context, data apitypes.TypedData) ([]byte, error)
+}
+
+// typedDataHash returns the EIP-712 hash of data
+func typedDataHash(data apitypes.TypedData) ([]byte, error) {
+	hash, _, err := apitypes.TypedDataAndHash(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash typed data: %w", err)
+	}
+	return hash, nil
+}
+
+// KeystoreConfig locates an encrypted JSON keystore file and its password. The password is
+// Password when set, typically a reference to the secrets provider, or else read from
+// PasswordFile when set, otherwise from the PasswordEnv variable.
+type KeystoreConfig struct {
+	Path         string `yaml:"path"`
+	Password     string `yaml:"password"`
+	PasswordFile string `yaml:"passwordFile"`
+	PasswordEnv  string `yaml:"passwordEnv"`
+}
+
+// RemoteConfig points at a signing service answering eth_signTransaction, such as
+// Web3Signer or Clef
+type RemoteConfig struct {
+	Url     string        `yaml:"url"`
+	Address string        `yaml:"address"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// Config selects the signer backend
+type Config struct {
+	Type string `yaml:"type"`
+
+	// Env names the variable holding a hex encoded private key
+	Env string `yaml:"env"`
+
+	// PrivateKey is the hex encoded private key of secret signers, a reference to the
+	// secrets provider such as ${secret:signer-key} expanded when the config is loaded
+	PrivateKey string `yaml:"privateKey"`
+
+	Keystore KeystoreConfig `yaml:"keystore"`
+	Remote   RemoteConfig   `yaml:"remote"`
+	AWSKMS   AWSKMSConfig   `yaml:"awsKms"`
+	GCPKMS   GCPKMSConfig   `yaml:"gcpKms"`
+}
+
+// DefaultConfig reads the private key from DefaultEnvVariable
+func DefaultConfig() Config {
+	return Config{
+		Type:   TypeEnv,
+		Env:    DefaultEnvVariable,
+		Remote: RemoteConfig{Timeout: 10 * time.Second},
+	}
+}
+
+// Validate checks the config for values no signer can be created from
+func (c Config) Validate() error {
+	switch c.Type {
+	case TypeEnv:
+		if c.Env == "" {
+			return fmt.Errorf("env is required for env signers")
+		}
+	case TypeSecret:
+		if c.PrivateKey == "" {
+			return fmt.Errorf("privateKey is required for secret signers")
+		}
+	case TypeKeystore:
+		if c.Keystore.Path == "" {
+			return fmt.Errorf("keystore.path is required for keystore signers")
+		}
+		if c.Keystore.Password == "" && c.Keystore.PasswordFile == "" && c.Keystore.PasswordEnv == "" {
+			return fmt.Errorf("keystore.password, keystore.passwordFile or keystore.passwordEnv is required")
+		}
+	case TypeRemote:
+		if c.Remote.Url == "" {
+			return fmt.Errorf("remote.url is required for remote signers")
+		}
+		if !common.IsHexAddress(c.Remote.Address) {
+			return fmt.Errorf("remote.address must be an address")
+		}
+		if c.Remote.Timeout <= 0 {
+			return fmt.Errorf("remote.timeout must be positive")
+		}
+	case TypeAWSKMS:
+		if c.AWSKMS.KeyID == "" {
+			return fmt.Errorf("awsKms.keyId is required for aws_kms signers")
+		}
+	case TypeGCPKMS:
+		if c.GCPKMS.KeyName == "" {
+			return fmt.Errorf("gcpKms.keyName is required for gcp_kms signers")
+		}
+	default:
+		return fmt.Errorf("unknown signer type: %s", c.Type)
+	}
+	return nil
+}
+
+// New creates the signer selected in cfg. KMS signers read their address from the key
+// service within ctx. Requests to remote signers are retried under policy, which may be
+// nil.
+func New(ctx context.Context, cfg Config, policy *resilience.Policy) (Signer, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	switch cfg.Type {
+	case TypeAWSKMS:
+		return NewAWSKMSSigner(ctx, cfg.AWSKMS)
+	case TypeGCPKMS:
+		return NewGCPKMSSigner(ctx, cfg.GCPKMS)
+	case TypeKeystore:
+		return NewKeystoreSigner(cfg.Keystore)
+	case TypeRemote:
+		return NewRemoteSigner(cfg.Remote, policy), nil
+	case TypeSecret:
+		return NewHexSigner(cfg.PrivateKey)
+	default:
+		return NewEnvSigner(cfg.Env)
+	}
+}
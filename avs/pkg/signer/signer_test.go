@@ -0,0 +1,274 @@
+package signer
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+const testKey = "4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"
+
+func testTx() *types.Transaction {
+	to := common.HexToAddress("0x01")
+	return types.NewTx(&types.DynamicFeeTx{
+		ChainID:   big.NewInt(1),
+		Nonce:     3,
+		GasTipCap: big.NewInt(1_000_000_000),
+		GasFeeCap: big.NewInt(3_000_000_000),
+		Gas:       60_000,
+		To:        &to,
+		Value:     new(big.Int),
+		Data:      []byte{0x09, 0x5e, 0xa7, 0xb3},
+	})
+}
+
+func assertSignedBy(t *testing.T, tx *types.Transaction, expected common.Address) {
+	t.Helper()
+	from, err := types.Sender(types.LatestSignerForChainID(big.NewInt(1)), tx)
+	if err != nil {
+		t.Fatalf("Failed to recover sender: %v", err)
+	}
+	if from != expected {
+		t.Errorf("Expected transaction signed by %s, got %s", expected.Hex(), from.Hex())
+	}
+}
+
+func assertMessageSignedBy(t *testing.T, s Signer, expected common.Address) {
+	t.Helper()
+	message := []byte("result digest")
+	sig, err := s.SignMessage(context.Background(), message)
+	if err != nil {
+		t.Fatalf("SignMessage failed: %v", err)
+	}
+	pub, err := crypto.SigToPub(accounts.TextHash(message), sig)
+	if err != nil {
+		t.Fatalf("Failed to recover signer: %v", err)
+	}
+	if from := crypto.PubkeyToAddress(*pub); from != expected {
+		t.Errorf("Expected message signed by %s, got %s", expected.Hex(), from.Hex())
+	}
+}
+
+func Test_EnvSigner(t *testing.T) {
+	t.Setenv("TEST_SIGNER_KEY", "0x"+testKey)
+
+	s, err := New(context.Background(), Config{Type: TypeEnv, Env: "TEST_SIGNER_KEY"}, nil)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	signed, err := s.SignTx(context.Background(), testTx(), big.NewInt(1))
+	if err != nil {
+		t.Fatalf("SignTx failed: %v", err)
+	}
+	assertSignedBy(t, signed, s.Address())
+	assertMessageSignedBy(t, s, s.Address())
+	assertTypedDataSignedBy(t, s, s.Address())
+
+	if _, err := NewEnvSigner("TEST_SIGNER_UNSET"); err == nil {
+		t.Errorf("Expected an unset variable to be rejected")
+	}
+	t.Setenv("TEST_SIGNER_KEY", "not a key")
+	if _, err := NewEnvSigner("TEST_SIGNER_KEY"); err == nil {
+		t.Errorf("Expected an invalid key to be rejected")
+	}
+}
+
+func Test_KeystoreSigner(t *testing.T) {
+	key, err := crypto.HexToECDSA(testKey)
+	if err != nil {
+		t.Fatalf("Failed to parse key: %v", err)
+	}
+	encrypted, err := keystore.EncryptKey(&keystore.Key{Address: crypto.PubkeyToAddress(key.PublicKey), PrivateKey: key},
+		"secret", keystore.LightScryptN, keystore.LightScryptP)
+	if err != nil {
+		t.Fatalf("Failed to encrypt key: %v", err)
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "key.json")
+	passwordFile := filepath.Join(dir, "password")
+	if err := os.WriteFile(path, encrypted, 0o600); err != nil {
+		t.Fatalf("Failed to write keystore: %v", err)
+	}
+	if err := os.WriteFile(passwordFile, []byte("secret\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write password: %v", err)
+	}
+
+	s, err := New(context.Background(), Config{Type: TypeKeystore, Keystore: KeystoreConfig{Path: path, PasswordFile: passwordFile}}, nil)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if s.Address() != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Errorf("Unexpected keystore address %s", s.Address().Hex())
+	}
+
+	t.Setenv("TEST_KEYSTORE_PASSWORD", "wrong")
+	if _, err := NewKeystoreSigner(KeystoreConfig{Path: path, PasswordEnv: "TEST_KEYSTORE_PASSWORD"}); err == nil {
+		t.Errorf("Expected a wrong password to be rejected")
+	}
+}
+
+func testTypedData() apitypes.TypedData {
+	return apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": {{Name: "name", Type: "string"}, {Name: "chainId", Type: "uint256"}},
+			"Result":       {{Name: "digest", Type: "bytes32"}},
+		},
+		PrimaryType: "Result",
+		Domain:      apitypes.TypedDataDomain{Name: "test", ChainId: math.NewHexOrDecimal256(1)},
+		Message:     apitypes.TypedDataMessage{"digest": common.HexToHash("0x01").Hex()},
+	}
+}
+
+func assertTypedDataSignedBy(t *testing.T, s Signer, expected common.Address) {
+	t.Helper()
+	sig, err := s.SignTypedData(context.Background(), testTypedData())
+	if err != nil {
+		t.Fatalf("SignTypedData failed: %v", err)
+	}
+	hash, _, _ := apitypes.TypedDataAndHash(testTypedData())
+	pub, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		t.Fatalf("Failed to recover signer: %v", err)
+	}
+	if from := crypto.PubkeyToAddress(*pub); from != expected {
+		t.Errorf("Expected typed data signed by %s, got %s", expected.Hex(), from.Hex())
+	}
+}
+
+// newRemote serves eth_signTransaction, eth_sign and eth_signTypedData_v4 by signing with
+// key
+func newRemote(t *testing.T, key string) *httptest.Server {
+	t.Helper()
+	s, err := NewHexSigner(key)
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if req.Method == "eth_sign" && len(req.Params) == 2 {
+			var message hexutil.Bytes
+			if err := json.Unmarshal(req.Params[1], &message); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			sig, _ := s.SignMessage(r.Context(), message)
+			sig[64] += 27
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "result": hexutil.Encode(sig)})
+			return
+		}
+		if req.Method == "eth_signTypedData_v4" && len(req.Params) == 2 {
+			var data apitypes.TypedData
+			if err := json.Unmarshal(req.Params[1], &data); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			sig, _ := s.SignTypedData(r.Context(), data)
+			sig[64] += 27
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "result": hexutil.Encode(sig)})
+			return
+		}
+		var args txArgs
+		if req.Method != "eth_signTransaction" || len(req.Params) != 1 || json.Unmarshal(req.Params[0], &args) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		signed, err := s.SignTx(r.Context(), types.NewTx(&types.DynamicFeeTx{
+			ChainID:   args.ChainID.ToInt(),
+			Nonce:     uint64(args.Nonce),
+			GasTipCap: args.MaxPriorityFeePerGas.ToInt(),
+			GasFeeCap: args.MaxFeePerGas.ToInt(),
+			Gas:       uint64(args.Gas),
+			To:        args.To,
+			Value:     args.Value.ToInt(),
+			Data:      args.Data,
+		}), args.ChainID.ToInt())
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		raw, _ := signed.MarshalBinary()
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "result": hexutil.Encode(raw)})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func Test_RemoteSigner(t *testing.T) {
+	local, _ := NewHexSigner(testKey)
+	server := newRemote(t, testKey)
+
+	s, err := New(context.Background(), Config{Type: TypeRemote, Remote: RemoteConfig{Url: server.URL, Address: local.Address().Hex(), Timeout: DefaultConfig().Remote.Timeout}}, nil)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	tx := testTx()
+	signed, err := s.SignTx(context.Background(), tx, big.NewInt(1))
+	if err != nil {
+		t.Fatalf("SignTx failed: %v", err)
+	}
+	assertSignedBy(t, signed, local.Address())
+	if signed.Nonce() != tx.Nonce() || signed.Gas() != tx.Gas() {
+		t.Errorf("Expected the requested transaction to be signed, got %+v", signed)
+	}
+	assertMessageSignedBy(t, s, local.Address())
+	assertTypedDataSignedBy(t, s, local.Address())
+}
+
+func Test_RemoteSignerRejectsOtherAccount(t *testing.T) {
+	other, _ := crypto.GenerateKey()
+	server := newRemote(t, common.Bytes2Hex(crypto.FromECDSA(other)))
+	local, _ := NewHexSigner(testKey)
+
+	s := NewRemoteSigner(RemoteConfig{Url: server.URL, Address: local.Address().Hex()}, nil)
+	if _, err := s.SignTx(context.Background(), testTx(), big.NewInt(1)); err == nil {
+		t.Errorf("Expected a transaction signed by another account to be rejected")
+	}
+	if _, err := s.SignMessage(context.Background(), []byte("result digest")); err == nil {
+		t.Errorf("Expected a message signed by another account to be rejected")
+	}
+	if _, err := s.SignTypedData(context.Background(), testTypedData()); err == nil {
+		t.Errorf("Expected typed data signed by another account to be rejected")
+	}
+}
+
+func Test_ConfigValidate(t *testing.T) {
+	testCases := map[string]Config{
+		"unknown type":         {Type: "ledger"},
+		"env without variable": {Type: TypeEnv},
+		"keystore password":    {Type: TypeKeystore, Keystore: KeystoreConfig{Path: "key.json"}},
+		"remote address":       {Type: TypeRemote, Remote: RemoteConfig{Url: "http://signer", Address: "signer", Timeout: 1}},
+		"aws key":              {Type: TypeAWSKMS, AWSKMS: AWSKMSConfig{Region: "us-east-1"}},
+		"gcp key":              {Type: TypeGCPKMS},
+	}
+	for name, cfg := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if err := cfg.Validate(); err == nil {
+				t.Errorf("Expected config to be rejected")
+			}
+		})
+	}
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Errorf("Expected the default config to be valid: %v", err)
+	}
+}
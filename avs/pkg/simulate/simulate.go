@@ -0,0 +1,132 @@
+// Package simulate executes transactions against current chain state without broadcasting
+// them, so rebalances can be previewed before any funds move.
+package simulate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/resilience"
+)
+
+// Outcome is the simulated execution of one call
+type Outcome struct {
+	GasUsed uint64
+
+	// Simulated is false when the call could not be executed on top of the state changes
+	// of the calls before it. GasUsed is then zero and callers fall back to an estimate.
+	Simulated bool
+
+	Reverted     bool
+	RevertReason string
+}
+
+// Simulator previews transactions without broadcasting them
+type Simulator interface {
+	// SimulateBundle executes calls in order from sender on chainID. Reverts are reported
+	// in the outcomes; an error means the simulation itself could not run.
+	SimulateBundle(ctx context.Context, chainID uint64, from common.Address, calls []chain.Call) ([]Outcome, error)
+
+	// GasPrice returns the current gas price on chainID in wei
+	GasPrice(ctx context.Context, chainID uint64) (*big.Int, error)
+}
+
+// Config selects how transactions are simulated. Without Tenderly, simulations run on the
+// configured RPC endpoints.
+type Config struct {
+	Tenderly TenderlyConfig `yaml:"tenderly"`
+}
+
+// DefaultConfig simulates over RPC
+func DefaultConfig() Config {
+	return Config{Tenderly: DefaultTenderlyConfig()}
+}
+
+// Validate checks the config for values simulations cannot run with
+func (c Config) Validate() error {
+	return c.Tenderly.Validate()
+}
+
+// NewFromConfig creates the simulator selected in cfg. Requests to Tenderly are retried
+// under policy, which may be nil.
+func NewFromConfig(cfg Config, chains *chain.Manager, policy *resilience.Policy) Simulator {
+	if cfg.Tenderly.Enabled {
+		return NewTenderlySimulator(cfg.Tenderly, chains, policy)
+	}
+	return NewRPCSimulator(chains)
+}
+
+// RPCSimulator simulates calls with eth_estimateGas. Plain RPC cannot carry state between
+// calls, so only the first call of a bundle is simulated. Simulations go to an endpoint
+// of the chain probed to serve the debug namespace, see chain.Manager.DebugClient.
+type RPCSimulator struct {
+	chains *chain.Manager
+}
+
+// NewRPCSimulator creates a simulator over the clients of chains
+func NewRPCSimulator(chains *chain.Manager) *RPCSimulator {
+	return &RPCSimulator{chains: chains}
+}
+
+func (s *RPCSimulator) SimulateBundle(ctx context.Context, chainID uint64, from common.Address, calls []chain.Call) ([]Outcome, error) {
+	outcomes := make([]Outcome, len(calls))
+	if len(calls) == 0 {
+		return outcomes, nil
+	}
+	client, err := s.chains.DebugClient(chainID)
+	if err != nil {
+		return nil, err
+	}
+
+	first := calls[0]
+	gas, err := client.EstimateGas(ctx, callMsg(from, first))
+	switch {
+	case err == nil:
+		outcomes[0] = Outcome{GasUsed: gas, Simulated: true}
+	case IsRevert(err):
+		outcomes[0] = Outcome{Simulated: true, Reverted: true, RevertReason: err.Error()}
+	default:
+		return nil, fmt.Errorf("failed to simulate call to %s on chain %d: %w", first.To.Hex(), chainID, err)
+	}
+	return outcomes, nil
+}
+
+func (s *RPCSimulator) GasPrice(ctx context.Context, chainID uint64) (*big.Int, error) {
+	return gasPrice(ctx, s.chains, chainID)
+}
+
+func gasPrice(ctx context.Context, chains *chain.Manager, chainID uint64) (*big.Int, error) {
+	client, err := chains.Client(chainID)
+	if err != nil {
+		return nil, err
+	}
+	price, err := client.SuggestGasPrice(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read gas price of chain %d: %w", chainID, err)
+	}
+	return price, nil
+}
+
+func callMsg(from common.Address, call chain.Call) ethereum.CallMsg {
+	to := call.To
+	return ethereum.CallMsg{From: from, To: &to, Data: call.Data}
+}
+
+// rpcCodeReverted is the JSON-RPC error code nodes return for reverted calls
+const rpcCodeReverted = 3
+
+// IsRevert reports whether err is a call reverting, as opposed to the node failing
+func IsRevert(err error) bool {
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == rpcCodeReverted {
+		return true
+	}
+	return strings.Contains(strings.ToLower(err.Error()), "execution reverted")
+}
@@ -0,0 +1,107 @@
+package simulate
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/chain/chaintest"
+)
+
+const tokenABIJson = `[
+	{"name":"approve","type":"function","stateMutability":"nonpayable",
+	 "inputs":[{"name":"spender","type":"address"},{"name":"amount","type":"uint256"}],
+	 "outputs":[{"name":"","type":"bool"}]},
+	{"name":"transfer","type":"function","stateMutability":"nonpayable",
+	 "inputs":[{"name":"to","type":"address"},{"name":"amount","type":"uint256"}],
+	 "outputs":[{"name":"","type":"bool"}]}
+]`
+
+var (
+	tokenABI = chain.MustParseABI(tokenABIJson)
+	token    = common.HexToAddress("0x00000000000000000000000000000000000000cc")
+	sender   = common.HexToAddress("0x00000000000000000000000000000000000000aa")
+)
+
+func tokenCall(t *testing.T, method string) chain.Call {
+	t.Helper()
+	data, err := tokenABI.Pack(method, sender, big.NewInt(1))
+	if err != nil {
+		t.Fatalf("Failed to pack %s: %v", method, err)
+	}
+	return chain.Call{To: token, Data: data}
+}
+
+func Test_RPCSimulatorSimulatesFirstCall(t *testing.T) {
+	contracts := chaintest.NewContracts(1)
+	contracts.StubGas(t, token, tokenABI, "approve", 46_000)
+	chains := chain.NewManager()
+	chains.Register(1, "ethereum", contracts)
+	simulator := NewRPCSimulator(chains)
+
+	outcomes, err := simulator.SimulateBundle(context.Background(), 1, sender, []chain.Call{tokenCall(t, "approve"), tokenCall(t, "transfer")})
+	if err != nil {
+		t.Fatalf("SimulateBundle failed: %v", err)
+	}
+	if !outcomes[0].Simulated || outcomes[0].GasUsed != 46_000 {
+		t.Errorf("Expected the first call to be simulated, got %+v", outcomes[0])
+	}
+	if outcomes[1].Simulated {
+		t.Errorf("Expected later calls not to be simulated over plain RPC")
+	}
+
+	outcomes, err = simulator.SimulateBundle(context.Background(), 1, sender, []chain.Call{tokenCall(t, "transfer")})
+	if err != nil {
+		t.Fatalf("Expected a revert to be reported in the outcome, got %v", err)
+	}
+	if !outcomes[0].Reverted {
+		t.Errorf("Expected the transfer to revert, got %+v", outcomes[0])
+	}
+
+	price, err := simulator.GasPrice(context.Background(), 1)
+	if err != nil || price.Int64() != 1_000_000_000 {
+		t.Errorf("Unexpected gas price %v: %v", price, err)
+	}
+}
+
+func Test_TenderlySimulatorSimulatesBundle(t *testing.T) {
+	var received struct {
+		Simulations []tenderlySimulation `json:"simulations"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/account/acme/project/yield/simulate-bundle" || r.Header.Get("X-Access-Key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"simulation_results":[
+			{"transaction":{"gas_used":46000,"status":true}},
+			{"transaction":{"gas_used":30000,"status":false,"error_message":"transfer amount exceeds balance"}}
+		]}`))
+	}))
+	defer server.Close()
+
+	simulator := NewTenderlySimulator(TenderlyConfig{Enabled: true, Url: server.URL, Account: "acme", Project: "yield", AccessKey: "secret"}, chain.NewManager(), nil)
+	outcomes, err := simulator.SimulateBundle(context.Background(), 8453, sender, []chain.Call{tokenCall(t, "approve"), tokenCall(t, "transfer")})
+	if err != nil {
+		t.Fatalf("SimulateBundle failed: %v", err)
+	}
+
+	if len(received.Simulations) != 2 || received.Simulations[0].NetworkID != "8453" || received.Simulations[0].Save {
+		t.Errorf("Unexpected bundle sent: %+v", received.Simulations)
+	}
+	if outcomes[0].GasUsed != 46_000 || outcomes[0].Reverted {
+		t.Errorf("Unexpected approve outcome: %+v", outcomes[0])
+	}
+	if !outcomes[1].Simulated || !outcomes[1].Reverted || outcomes[1].RevertReason != "transfer amount exceeds balance" {
+		t.Errorf("Unexpected transfer outcome: %+v", outcomes[1])
+	}
+}
@@ -0,0 +1,175 @@
+package simulate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/resilience"
+)
+
+// DefaultTenderlyUrl is the Tenderly REST API
+const DefaultTenderlyUrl = "https://api.tenderly.co/api/v1"
+
+// TenderlyConfig configures bundle simulations on Tenderly
+type TenderlyConfig struct {
+	Enabled   bool          `yaml:"enabled"`
+	Url       string        `yaml:"url"`
+	Account   string        `yaml:"account"`
+	Project   string        `yaml:"project"`
+	AccessKey string        `yaml:"accessKey"`
+	Timeout   time.Duration `yaml:"timeout"`
+}
+
+// DefaultTenderlyConfig returns the public API settings, disabled until an account is set
+func DefaultTenderlyConfig() TenderlyConfig {
+	return TenderlyConfig{
+		Url:     DefaultTenderlyUrl,
+		Timeout: 15 * time.Second,
+	}
+}
+
+// Validate checks the config for values simulations cannot run with
+func (c TenderlyConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Account == "" || c.Project == "" {
+		return fmt.Errorf("tenderly.account and tenderly.project are required")
+	}
+	if c.AccessKey == "" {
+		return fmt.Errorf("tenderly.accessKey is required")
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("tenderly.timeout must be positive")
+	}
+	return nil
+}
+
+// TenderlySimulator simulates bundles on Tenderly, which carries state between the calls
+// of a bundle. Gas prices are read from the chains' RPC endpoints.
+type TenderlySimulator struct {
+	url        string
+	accessKey  string
+	httpClient *http.Client
+	policy     *resilience.Policy
+	chains     *chain.Manager
+}
+
+// NewTenderlySimulator creates a Tenderly simulator. Missing values fall back to defaults.
+func NewTenderlySimulator(cfg TenderlyConfig, chains *chain.Manager, policy *resilience.Policy) *TenderlySimulator {
+	defaults := DefaultTenderlyConfig()
+	if cfg.Url == "" {
+		cfg.Url = defaults.Url
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaults.Timeout
+	}
+	return &TenderlySimulator{
+		url:        fmt.Sprintf("%s/account/%s/project/%s/simulate-bundle", strings.TrimRight(cfg.Url, "/"), cfg.Account, cfg.Project),
+		accessKey:  cfg.AccessKey,
+		httpClient: &http.Client{Timeout: cfg.Timeout},
+		policy:     policy,
+		chains:     chains,
+	}
+}
+
+type tenderlySimulation struct {
+	NetworkID      string `json:"network_id"`
+	From           string `json:"from"`
+	To             string `json:"to"`
+	Input          string `json:"input"`
+	Save           bool   `json:"save"`
+	SimulationType string `json:"simulation_type"`
+}
+
+type tenderlyResult struct {
+	Transaction struct {
+		GasUsed      uint64 `json:"gas_used"`
+		Status       bool   `json:"status"`
+		ErrorMessage string `json:"error_message"`
+	} `json:"transaction"`
+}
+
+func (s *TenderlySimulator) SimulateBundle(ctx context.Context, chainID uint64, from common.Address, calls []chain.Call) ([]Outcome, error) {
+	if len(calls) == 0 {
+		return []Outcome{}, nil
+	}
+	simulations := make([]tenderlySimulation, len(calls))
+	for i, call := range calls {
+		simulations[i] = tenderlySimulation{
+			NetworkID:      strconv.FormatUint(chainID, 10),
+			From:           from.Hex(),
+			To:             call.To.Hex(),
+			Input:          hexutil.Encode(call.Data),
+			SimulationType: "quick",
+		}
+	}
+	body, err := json.Marshal(map[string]interface{}{"simulations": simulations})
+	if err != nil {
+		return nil, fmt.Errorf("tenderly: failed to encode bundle: %w", err)
+	}
+
+	var results []tenderlyResult
+	err = s.policy.Do(ctx, "tenderly", func(ctx context.Context) error {
+		var err error
+		results, err = s.request(ctx, body)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(results) != len(calls) {
+		return nil, fmt.Errorf("tenderly: expected %d simulation results, got %d", len(calls), len(results))
+	}
+
+	outcomes := make([]Outcome, len(results))
+	for i, r := range results {
+		outcomes[i] = Outcome{
+			GasUsed:      r.Transaction.GasUsed,
+			Simulated:    true,
+			Reverted:     !r.Transaction.Status,
+			RevertReason: r.Transaction.ErrorMessage,
+		}
+	}
+	return outcomes, nil
+}
+
+func (s *TenderlySimulator) request(ctx context.Context, body []byte) ([]tenderlyResult, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("tenderly: failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Access-Key", s.accessKey)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("tenderly: api unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("tenderly: %w", &resilience.StatusError{Endpoint: s.url, StatusCode: resp.StatusCode})
+	}
+
+	var decoded struct {
+		SimulationResults []tenderlyResult `json:"simulation_results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("tenderly: failed to decode simulation: %w", err)
+	}
+	return decoded.SimulationResults, nil
+}
+
+func (s *TenderlySimulator) GasPrice(ctx context.Context, chainID uint64) (*big.Int, error) {
+	return gasPrice(ctx, s.chains, chainID)
+}
@@ -0,0 +1,123 @@
+// Package snapshot pins the contract reads of a task to a reference block and snaps the
+// rates computed from them to a fixed precision. Operators whose nodes are at different
+// heads then read the same state, and rates that still differ in their last digits, for
+// instance from interest accrued up to the time of the read, round to the same value.
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+)
+
+// ErrNotFinalized is returned for reference blocks past the finalized head, whose state
+// may still be reorganized and which not every operator's node may have yet
+var ErrNotFinalized = errors.New("block is not finalized")
+
+// Config configures reference blocks and rate snapping
+type Config struct {
+	Enabled bool `yaml:"enabled"`
+
+	// RatePlaces is the number of fractional digits of the annual percentage rates are
+	// snapped to. 2 places is a basis point.
+	RatePlaces int `yaml:"ratePlaces"`
+}
+
+// DefaultConfig leaves snapshots disabled and snaps rates to a basis point
+func DefaultConfig() Config {
+	return Config{RatePlaces: 2}
+}
+
+// Validate checks the config for values rates cannot be snapped with
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.RatePlaces < 0 || c.RatePlaces > canonical.APYPlaces {
+		return fmt.Errorf("ratePlaces must be from 0 to %d", canonical.APYPlaces)
+	}
+	return nil
+}
+
+// Snapper resolves reference blocks and snaps rates
+type Snapper struct {
+	cfg    Config
+	chains *chain.Manager
+}
+
+// New creates a snapper resolving reference blocks on chains
+func New(cfg Config, chains *chain.Manager) *Snapper {
+	return &Snapper{cfg: cfg, chains: chains}
+}
+
+// NewFromConfig creates a snapper, or returns nil when snapshots are disabled
+func NewFromConfig(cfg Config, chains *chain.Manager) *Snapper {
+	if !cfg.Enabled {
+		return nil
+	}
+	return New(cfg, chains)
+}
+
+// Request names the block a task reads at, by number or by hash. The zero Request asks
+// for the latest finalized block.
+type Request struct {
+	Number uint64
+	Hash   common.Hash
+}
+
+// Block resolves the reference block of reads on chainID for req. Requested blocks must
+// be finalized.
+func (s *Snapper) Block(ctx context.Context, chainID uint64, req Request) (uint64, error) {
+	client, err := s.chains.Client(chainID)
+	if err != nil {
+		return 0, err
+	}
+	finalized, err := client.HeaderByNumber(ctx, big.NewInt(int64(rpc.FinalizedBlockNumber)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read the finalized block of chain %d: %w", chainID, err)
+	}
+
+	requested := req.Number
+	if req.Hash != (common.Hash{}) {
+		header, err := client.HeaderByHash(ctx, req.Hash)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read block %s of chain %d: %w", req.Hash.Hex(), chainID, err)
+		}
+		if req.Number != 0 && header.Number.Uint64() != req.Number {
+			return 0, fmt.Errorf("block %s of chain %d is block %s, not %d", req.Hash.Hex(), chainID, header.Number, req.Number)
+		}
+		requested = header.Number.Uint64()
+	}
+	if requested == 0 {
+		return finalized.Number.Uint64(), nil
+	}
+	if requested > finalized.Number.Uint64() {
+		return 0, fmt.Errorf("%w: block %d is past the finalized block %s of chain %d", ErrNotFinalized, requested, finalized.Number, chainID)
+	}
+	return requested, nil
+}
+
+// Pin resolves the reference block of reads on chainID for req and pins the reads made
+// with the returned context to it
+func (s *Snapper) Pin(ctx context.Context, chainID uint64, req Request) (context.Context, uint64, error) {
+	block, err := s.Block(ctx, chainID, req)
+	if err != nil {
+		return ctx, 0, err
+	}
+	return chain.WithBlock(ctx, block), block, nil
+}
+
+// Rate snaps a rate, as a fraction, to the configured places of its percentage. A nil
+// snapper returns the rate unchanged.
+func (s *Snapper) Rate(rate float64) float64 {
+	if s == nil {
+		return rate
+	}
+	return canonical.NewDecimalFromFloat(rate, s.cfg.RatePlaces+2).Float64()
+}
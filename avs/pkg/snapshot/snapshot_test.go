@@ -0,0 +1,111 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/chain/chaintest"
+)
+
+func newSnapper(t *testing.T) (*Snapper, *chaintest.Contracts) {
+	t.Helper()
+	client := chaintest.NewContracts(1)
+	client.SetHead(120, 1_700_000_000)
+	client.SetFinalized(100)
+	chains := chain.NewManager()
+	chains.Register(1, "ethereum", client)
+	return New(DefaultConfig(), chains), client
+}
+
+func Test_Block(t *testing.T) {
+	s, client := newSnapper(t)
+	ctx := context.Background()
+
+	block, err := s.Block(ctx, 1, Request{})
+	if err != nil || block != 100 {
+		t.Errorf("Expected the finalized block 100, got %d, %v", block, err)
+	}
+	if block, err := s.Block(ctx, 1, Request{Number: 90}); err != nil || block != 90 {
+		t.Errorf("Expected the requested block 90, got %d, %v", block, err)
+	}
+	if _, err := s.Block(ctx, 1, Request{Number: 110}); !errors.Is(err, ErrNotFinalized) {
+		t.Errorf("Expected ErrNotFinalized for a block past the finalized head, got %v", err)
+	}
+	if _, err := s.Block(ctx, 8453, Request{}); err == nil {
+		t.Errorf("Expected an error for an unconfigured chain")
+	}
+
+	header, err := client.HeaderByNumber(ctx, big.NewInt(90))
+	if err != nil {
+		t.Fatalf("HeaderByNumber failed: %v", err)
+	}
+	if block, err := s.Block(ctx, 1, Request{Hash: header.Hash()}); err != nil || block != 90 {
+		t.Errorf("Expected the block hashing to %s to be 90, got %d, %v", header.Hash().Hex(), block, err)
+	}
+	if _, err := s.Block(ctx, 1, Request{Number: 91, Hash: header.Hash()}); err == nil {
+		t.Errorf("Expected a hash of another block than the requested number to be refused")
+	}
+
+	pinned, block, err := s.Pin(ctx, 1, Request{})
+	if err != nil {
+		t.Fatalf("Pin failed: %v", err)
+	}
+	if at := chain.BlockOf(pinned); at == nil || at.Uint64() != block {
+		t.Errorf("Expected reads to be pinned to block %d, got %v", block, at)
+	}
+}
+
+func Test_PinnedReadsOfPrunedState(t *testing.T) {
+	s, client := newSnapper(t)
+	contractABI := chain.MustParseABI(`[{"name":"totalSupply","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]}]`)
+	token := common.HexToAddress("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48")
+	client.Stub(t, token, contractABI, "totalSupply", big.NewInt(1))
+	// a full node keeping the state of the latest 16 blocks
+	client.PruneBefore(104)
+	ctx := context.Background()
+
+	if _, err := chain.CallUint(ctx, client, token, contractABI, "totalSupply"); err != nil {
+		t.Errorf("Expected reads of the latest block to succeed, got %v", err)
+	}
+	pinned, _, err := s.Pin(ctx, 1, Request{})
+	if err != nil {
+		t.Fatalf("Pin failed: %v", err)
+	}
+	if _, err := chain.CallUint(pinned, client, token, contractABI, "totalSupply"); !errors.Is(err, chain.ErrHistoricalState) {
+		t.Errorf("Expected ErrHistoricalState for the pruned finalized block, got %v", err)
+	}
+	if _, err := chain.CodeAt(pinned, client, token); !errors.Is(err, chain.ErrHistoricalState) {
+		t.Errorf("Expected ErrHistoricalState reading code of the pruned block, got %v", err)
+	}
+}
+
+func Test_Rate(t *testing.T) {
+	s, _ := newSnapper(t)
+	// rates a few seconds of interest apart snap to the same basis point
+	for _, rate := range []float64{0.038412, 0.0384049, 0.03835} {
+		if snapped := s.Rate(rate); snapped != 0.0384 {
+			t.Errorf("Expected %v to snap to 0.0384, got %v", rate, snapped)
+		}
+	}
+
+	var disabled *Snapper
+	if rate := disabled.Rate(0.038412); rate != 0.038412 {
+		t.Errorf("Expected a nil snapper to leave rates unchanged, got %v", rate)
+	}
+}
+
+func Test_Validate(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Enabled = true
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected the default config to be valid: %v", err)
+	}
+	cfg.RatePlaces = 5
+	if err := cfg.Validate(); err == nil {
+		t.Errorf("Expected ratePlaces beyond the APY precision to be rejected")
+	}
+}
@@ -0,0 +1,96 @@
+// Package stability summarizes how a rate moved over a rolling window: how volatile it
+// was, the deepest fall from a peak, and a score preferring steady rates over briefly
+// spiking ones, so venues can be compared on more than their current rate.
+package stability
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// ErrInsufficientHistory is returned when the window holds fewer than MinSamples points
+var ErrInsufficientHistory = errors.New("insufficient rate history")
+
+// Config configures the window statistics are measured over
+type Config struct {
+	// Window is the span of history measured, ending at the latest reading
+	Window time.Duration `yaml:"window"`
+
+	// MinSamples is the minimum number of readings needed to measure a window
+	MinSamples int `yaml:"minSamples"`
+}
+
+// DefaultConfig measures the last 7 days once they hold a day of hourly readings
+func DefaultConfig() Config {
+	return Config{
+		Window:     7 * 24 * time.Hour,
+		MinSamples: 24,
+	}
+}
+
+// Validate checks the config for values statistics cannot be measured with
+func (c Config) Validate() error {
+	if c.Window <= 0 {
+		return fmt.Errorf("window must be positive")
+	}
+	if c.MinSamples < 2 {
+		return fmt.Errorf("minSamples must be at least 2")
+	}
+	return nil
+}
+
+// Stats describe a window of rates. Mean and Volatility are in the unit of the rates.
+type Stats struct {
+	Samples int
+	Mean    float64
+
+	// Volatility is the sample standard deviation of the rates
+	Volatility float64
+
+	// MaxDrawdown is the largest fall from a peak to a later trough, as a fraction of
+	// the peak (0.5 when a 6% rate later fell to 3%)
+	MaxDrawdown float64
+
+	// Score is 1 less the coefficient of variation, floored at 0: 1 for a flat rate, 0
+	// for rates swinging by as much as their mean, or averaging nothing
+	Score float64
+}
+
+// Measure computes the statistics of rates, ordered oldest first
+func Measure(rates []float64, cfg Config) (*Stats, error) {
+	if len(rates) < cfg.MinSamples || len(rates) < 2 {
+		return nil, ErrInsufficientHistory
+	}
+
+	var sum float64
+	for _, r := range rates {
+		sum += r
+	}
+	mean := sum / float64(len(rates))
+
+	var squares, peak, drawdown float64
+	for i, r := range rates {
+		squares += (r - mean) * (r - mean)
+		if i == 0 || r > peak {
+			peak = r
+		}
+		if peak > 0 {
+			drawdown = math.Max(drawdown, (peak-r)/peak)
+		}
+	}
+	volatility := math.Sqrt(squares / float64(len(rates)-1))
+
+	var score float64
+	if mean > 0 {
+		score = math.Max(0, 1-volatility/mean)
+	}
+	return &Stats{
+		Samples:     len(rates),
+		Mean:        mean,
+		Volatility:  volatility,
+		MaxDrawdown: drawdown,
+		Score:       score,
+	}, nil
+}
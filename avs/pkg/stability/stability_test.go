@@ -0,0 +1,53 @@
+package stability
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func Test_Measure(t *testing.T) {
+	cfg := Config{Window: 1, MinSamples: 4}
+
+	steady, err := Measure([]float64{0.04, 0.04, 0.04, 0.04}, cfg)
+	if err != nil {
+		t.Fatalf("Measure failed: %v", err)
+	}
+	if steady.Volatility != 0 || steady.MaxDrawdown != 0 || steady.Score != 1 || steady.Mean != 0.04 {
+		t.Errorf("Expected a flat rate to be perfectly stable, got %+v", steady)
+	}
+
+	// spikes to 8% and falls to 3% before recovering
+	spiking, err := Measure([]float64{0.04, 0.08, 0.03, 0.05}, cfg)
+	if err != nil {
+		t.Fatalf("Measure failed: %v", err)
+	}
+	if math.Abs(spiking.MaxDrawdown-0.625) > 1e-12 || math.Abs(spiking.Mean-0.05) > 1e-12 {
+		t.Errorf("Expected a 62.5%% drawdown from the 8%% peak, got %+v", spiking)
+	}
+	if math.Abs(spiking.Volatility-math.Sqrt(0.0014/3)) > 1e-12 || spiking.Score >= steady.Score || spiking.Score <= 0 {
+		t.Errorf("Unexpected volatility or score %+v", spiking)
+	}
+
+	// swings larger than the mean score nothing
+	wild, _ := Measure([]float64{0, 0.1, 0, 0.1}, cfg)
+	if wild.Score != 0 || wild.MaxDrawdown != 1 {
+		t.Errorf("Expected a wild rate to score 0, got %+v", wild)
+	}
+
+	if _, err := Measure([]float64{0.04, 0.04, 0.04}, cfg); !errors.Is(err, ErrInsufficientHistory) {
+		t.Errorf("Expected ErrInsufficientHistory, got %v", err)
+	}
+}
+
+func Test_ConfigValidate(t *testing.T) {
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Errorf("Expected the default config to be valid, got %v", err)
+	}
+	if err := (Config{Window: 1, MinSamples: 1}).Validate(); err == nil {
+		t.Errorf("Expected a single sample to be rejected")
+	}
+	if err := (Config{MinSamples: 2}).Validate(); err == nil {
+		t.Errorf("Expected a zero window to be rejected")
+	}
+}
@@ -0,0 +1,189 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	badgerv3 "github.com/dgraph-io/badger/v3"
+)
+
+// BadgerConfig configures the BadgerDB backend
+type BadgerConfig struct {
+	// Dir is the directory BadgerDB stores its files in
+	Dir string `yaml:"dir"`
+
+	// InMemory runs BadgerDB without touching disk (useful for tests)
+	InMemory bool `yaml:"inMemory"`
+
+	// GCInterval controls how often value log garbage collection runs. Defaults to 5m.
+	GCInterval time.Duration `yaml:"gcInterval"`
+}
+
+// BadgerKV implements KV on top of BadgerDB so task state survives operator restarts
+type BadgerKV struct {
+	db       *badgerv3.DB
+	mu       sync.RWMutex
+	closed   bool
+	closeCh  chan struct{}
+	gcTicker *time.Ticker
+}
+
+// NewBadgerKV opens (or creates) a BadgerDB database
+func NewBadgerKV(cfg *BadgerConfig) (*BadgerKV, error) {
+	if cfg == nil {
+		return nil, errors.New("badger config is nil")
+	}
+	if cfg.Dir == "" && !cfg.InMemory {
+		return nil, errors.New("badger dir must be set when not running in memory")
+	}
+
+	opts := badgerv3.DefaultOptions(cfg.Dir)
+	opts.Logger = nil // Disable BadgerDB's default logging
+	if cfg.InMemory {
+		opts = opts.WithInMemory(true).WithDir("").WithValueDir("")
+	}
+
+	db, err := badgerv3.Open(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open badger db: %w", err)
+	}
+
+	gcInterval := cfg.GCInterval
+	if gcInterval <= 0 {
+		gcInterval = 5 * time.Minute
+	}
+
+	b := &BadgerKV{
+		db:       db,
+		closeCh:  make(chan struct{}),
+		gcTicker: time.NewTicker(gcInterval),
+	}
+	go b.runGC()
+
+	return b, nil
+}
+
+// runGC periodically reclaims space in the value log
+func (b *BadgerKV) runGC() {
+	for {
+		select {
+		case <-b.gcTicker.C:
+			_ = b.db.RunValueLogGC(0.5)
+		case <-b.closeCh:
+			return
+		}
+	}
+}
+
+func (b *BadgerKV) isClosed() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.closed
+}
+
+func (b *BadgerKV) Get(ctx context.Context, key []byte) ([]byte, error) {
+	if b.isClosed() {
+		return nil, ErrStoreClosed
+	}
+
+	var value []byte
+	err := b.db.View(func(txn *badgerv3.Txn) error {
+		item, err := txn.Get(key)
+		if err != nil {
+			if errors.Is(err, badgerv3.ErrKeyNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+		value, err = item.ValueCopy(nil)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to get key: %w", err)
+	}
+	return value, nil
+}
+
+func (b *BadgerKV) Set(ctx context.Context, key []byte, value []byte) error {
+	if b.isClosed() {
+		return ErrStoreClosed
+	}
+
+	if err := b.db.Update(func(txn *badgerv3.Txn) error {
+		return txn.Set(key, value)
+	}); err != nil {
+		return fmt.Errorf("failed to set key: %w", err)
+	}
+	return nil
+}
+
+func (b *BadgerKV) Delete(ctx context.Context, key []byte) error {
+	if b.isClosed() {
+		return ErrStoreClosed
+	}
+
+	if err := b.db.Update(func(txn *badgerv3.Txn) error {
+		return txn.Delete(key)
+	}); err != nil {
+		return fmt.Errorf("failed to delete key: %w", err)
+	}
+	return nil
+}
+
+func (b *BadgerKV) Iterate(ctx context.Context, prefix []byte, fn func(key []byte, value []byte) error) error {
+	if b.isClosed() {
+		return ErrStoreClosed
+	}
+
+	// Collect entries first so fn may write back into the store without
+	// deadlocking against the read transaction
+	type entry struct{ key, value []byte }
+	var entries []entry
+	err := b.db.View(func(txn *badgerv3.Txn) error {
+		opts := badgerv3.DefaultIteratorOptions
+		opts.Prefix = prefix
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.ValidForPrefix(prefix); it.Next() {
+			value, err := it.Item().ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, entry{key: bytes.Clone(it.Item().Key()), value: value})
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to iterate prefix: %w", err)
+	}
+
+	for _, e := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(e.key, e.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *BadgerKV) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	b.gcTicker.Stop()
+	close(b.closeCh)
+	return b.db.Close()
+}
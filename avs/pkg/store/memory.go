@@ -0,0 +1,100 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MemoryKV is an in-memory KV implementation. State is lost when the process exits,
+// so it is intended for tests and local development only.
+type MemoryKV struct {
+	mu     sync.RWMutex
+	data   map[string][]byte
+	closed bool
+}
+
+// NewMemoryKV creates an empty in-memory store
+func NewMemoryKV() *MemoryKV {
+	return &MemoryKV{
+		data: make(map[string][]byte),
+	}
+}
+
+func (m *MemoryKV) Get(ctx context.Context, key []byte) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.closed {
+		return nil, ErrStoreClosed
+	}
+
+	value, ok := m.data[string(key)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return bytes.Clone(value), nil
+}
+
+func (m *MemoryKV) Set(ctx context.Context, key []byte, value []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return ErrStoreClosed
+	}
+
+	m.data[string(key)] = bytes.Clone(value)
+	return nil
+}
+
+func (m *MemoryKV) Delete(ctx context.Context, key []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return ErrStoreClosed
+	}
+
+	delete(m.data, string(key))
+	return nil
+}
+
+func (m *MemoryKV) Iterate(ctx context.Context, prefix []byte, fn func(key []byte, value []byte) error) error {
+	m.mu.RLock()
+	if m.closed {
+		m.mu.RUnlock()
+		return ErrStoreClosed
+	}
+
+	// Snapshot matching entries so fn may call back into the store
+	p := string(prefix)
+	keys := make([]string, 0)
+	for k := range m.data {
+		if strings.HasPrefix(k, p) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	values := make([][]byte, len(keys))
+	for i, k := range keys {
+		values[i] = bytes.Clone(m.data[k])
+	}
+	m.mu.RUnlock()
+
+	for i, k := range keys {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn([]byte(k), values[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MemoryKV) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	return nil
+}
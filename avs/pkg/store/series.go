@@ -0,0 +1,163 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const prefixSeries = "series:"
+
+// SeriesPoint is a single timestamped observation of a time series
+type SeriesPoint struct {
+	Time  time.Time `json:"t"`
+	Value float64   `json:"v"`
+}
+
+// SeriesStore keeps time series such as historical supply rates. Points are keyed by
+// series name and timestamp so iteration returns them in time order.
+type SeriesStore struct {
+	kv KV
+}
+
+// NewSeriesStore creates a SeriesStore backed by kv
+func NewSeriesStore(kv KV) *SeriesStore {
+	return &SeriesStore{kv: kv}
+}
+
+// UtilizationSeries names the utilization series of a protocol market
+func UtilizationSeries(protocol string, chainID uint64) string {
+	return fmt.Sprintf("utilization/%s/%d", strings.ToLower(protocol), chainID)
+}
+
+// SupplyRateSeries names the supply rate series of a protocol market
+func SupplyRateSeries(protocol string, chainID uint64) string {
+	return fmt.Sprintf("supply_rate/%s/%d", strings.ToLower(protocol), chainID)
+}
+
+// SharePriceSeries names the share price series of a vault protocol market
+func SharePriceSeries(protocol string, chainID uint64) string {
+	return fmt.Sprintf("share_price/%s/%d", strings.ToLower(protocol), chainID)
+}
+
+// RiskScoreSeries names the overall risk score series of a protocol market
+func RiskScoreSeries(protocol string, chainID uint64) string {
+	return fmt.Sprintf("risk_score/%s/%d", strings.ToLower(protocol), chainID)
+}
+
+func seriesPrefix(series string) []byte {
+	return []byte(prefixSeries + series + ":")
+}
+
+func seriesKey(series string, t time.Time) []byte {
+	// zero padded nanoseconds sort lexicographically in time order
+	return []byte(fmt.Sprintf("%s%s:%020d", prefixSeries, series, t.UnixNano()))
+}
+
+// Append records a point. A point at the same timestamp is overwritten.
+func (s *SeriesStore) Append(ctx context.Context, series string, point SeriesPoint) error {
+	if point.Time.Before(time.Unix(0, 0)) {
+		return fmt.Errorf("series points must not predate the unix epoch")
+	}
+	encoded, err := json.Marshal(point)
+	if err != nil {
+		return fmt.Errorf("failed to encode series point: %w", err)
+	}
+	return s.kv.Set(ctx, seriesKey(series, point.Time), encoded)
+}
+
+// Range returns the points of series with from <= time < to, oldest first
+func (s *SeriesStore) Range(ctx context.Context, series string, from, to time.Time) ([]SeriesPoint, error) {
+	var points []SeriesPoint
+	err := s.kv.Iterate(ctx, seriesPrefix(series), func(key, value []byte) error {
+		var point SeriesPoint
+		if err := json.Unmarshal(value, &point); err != nil {
+			return fmt.Errorf("failed to decode series point %s: %w", key, err)
+		}
+		if !point.Time.Before(from) && point.Time.Before(to) {
+			points = append(points, point)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return points, nil
+}
+
+// Series returns the names of all stored series in ascending order
+func (s *SeriesStore) Series(ctx context.Context) ([]string, error) {
+	seen := make(map[string]bool)
+	err := s.kv.Iterate(ctx, []byte(prefixSeries), func(key, value []byte) error {
+		name := strings.TrimPrefix(string(key), prefixSeries)
+		if idx := strings.LastIndexByte(name, ':'); idx >= 0 {
+			seen[name[:idx]] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Prune deletes the points of series older than before and returns how many were removed
+func (s *SeriesStore) Prune(ctx context.Context, series string, before time.Time) (int, error) {
+	points, err := s.Range(ctx, series, time.Unix(0, 0), before)
+	if err != nil {
+		return 0, err
+	}
+	for _, point := range points {
+		if err := s.kv.Delete(ctx, seriesKey(series, point.Time)); err != nil {
+			return 0, fmt.Errorf("failed to delete series point: %w", err)
+		}
+	}
+	return len(points), nil
+}
+
+// Compact downsamples the points of series older than before to one point per resolution
+// bucket, holding the bucket average at the bucket start. Returns the number of points removed.
+func (s *SeriesStore) Compact(ctx context.Context, series string, before time.Time, resolution time.Duration) (int, error) {
+	if resolution <= 0 {
+		return 0, fmt.Errorf("compaction resolution must be positive")
+	}
+	points, err := s.Range(ctx, series, time.Unix(0, 0), before.Truncate(resolution))
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for start := 0; start < len(points); {
+		bucket := points[start].Time.Truncate(resolution)
+		end := start
+		sum := 0.0
+		for end < len(points) && points[end].Time.Truncate(resolution).Equal(bucket) {
+			sum += points[end].Value
+			end++
+		}
+
+		// a bucket already holding a single point at its start is compacted
+		if end-start > 1 || !points[start].Time.Equal(bucket) {
+			for _, point := range points[start:end] {
+				if err := s.kv.Delete(ctx, seriesKey(series, point.Time)); err != nil {
+					return removed, fmt.Errorf("failed to delete series point: %w", err)
+				}
+			}
+			average := SeriesPoint{Time: bucket, Value: sum / float64(end-start)}
+			if err := s.Append(ctx, series, average); err != nil {
+				return removed, err
+			}
+			removed += end - start - 1
+		}
+		start = end
+	}
+	return removed, nil
+}
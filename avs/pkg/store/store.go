@@ -0,0 +1,58 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNotFound is returned when a requested key is not present in the store
+	ErrNotFound = errors.New("item not found")
+
+	// ErrStoreClosed is returned when attempting to use a closed store
+	ErrStoreClosed = errors.New("store is closed")
+)
+
+// StorageType selects the backend used by Open
+type StorageType string
+
+const (
+	StorageTypeMemory StorageType = "memory"
+	StorageTypeBadger StorageType = "badger"
+)
+
+// Config describes which storage backend the performer persists state to
+type Config struct {
+	Type   StorageType   `yaml:"type"`
+	Badger *BadgerConfig `yaml:"badger"`
+}
+
+// KV is the minimal key/value contract every storage backend implements.
+// Domain stores (tasks, yield history, positions, ...) are built on top of it
+// using their own key prefixes so a single database can hold all performer state.
+type KV interface {
+	Get(ctx context.Context, key []byte) ([]byte, error)
+	Set(ctx context.Context, key []byte, value []byte) error
+	Delete(ctx context.Context, key []byte) error
+
+	// Iterate calls fn for every key with the given prefix in ascending key order.
+	// Returning an error from fn stops the iteration and is returned to the caller.
+	Iterate(ctx context.Context, prefix []byte, fn func(key []byte, value []byte) error) error
+
+	Close() error
+}
+
+// Open creates the KV backend described by cfg. An empty type defaults to memory.
+func Open(cfg *Config) (KV, error) {
+	if cfg == nil || cfg.Type == "" || cfg.Type == StorageTypeMemory {
+		return NewMemoryKV(), nil
+	}
+
+	switch cfg.Type {
+	case StorageTypeBadger:
+		return NewBadgerKV(cfg.Badger)
+	default:
+		return nil, fmt.Errorf("unknown storage type: %s", cfg.Type)
+	}
+}
@@ -0,0 +1,323 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func newBackends(t *testing.T) map[string]KV {
+	t.Helper()
+
+	badgerKV, err := NewBadgerKV(&BadgerConfig{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Failed to open badger store: %v", err)
+	}
+
+	return map[string]KV{
+		"memory": NewMemoryKV(),
+		"badger": badgerKV,
+	}
+}
+
+func Test_KVBackends(t *testing.T) {
+	for name, kv := range newBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			defer kv.Close()
+			ctx := context.Background()
+
+			if _, err := kv.Get(ctx, []byte("missing")); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Expected ErrNotFound, got %v", err)
+			}
+
+			for i := 3; i >= 1; i-- {
+				if err := kv.Set(ctx, []byte(fmt.Sprintf("a:%d", i)), []byte(fmt.Sprintf("v%d", i))); err != nil {
+					t.Fatalf("Set failed: %v", err)
+				}
+			}
+			if err := kv.Set(ctx, []byte("b:1"), []byte("other")); err != nil {
+				t.Fatalf("Set failed: %v", err)
+			}
+
+			value, err := kv.Get(ctx, []byte("a:2"))
+			if err != nil || string(value) != "v2" {
+				t.Errorf("Expected v2, got %q (%v)", string(value), err)
+			}
+
+			var keys []string
+			err = kv.Iterate(ctx, []byte("a:"), func(key []byte, value []byte) error {
+				keys = append(keys, string(key))
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Iterate failed: %v", err)
+			}
+			if fmt.Sprint(keys) != "[a:1 a:2 a:3]" {
+				t.Errorf("Unexpected iteration order: %v", keys)
+			}
+
+			if err := kv.Delete(ctx, []byte("a:1")); err != nil {
+				t.Fatalf("Delete failed: %v", err)
+			}
+			if _, err := kv.Get(ctx, []byte("a:1")); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Expected ErrNotFound after delete, got %v", err)
+			}
+
+			if err := kv.Close(); err != nil {
+				t.Fatalf("Close failed: %v", err)
+			}
+			if _, err := kv.Get(ctx, []byte("a:2")); !errors.Is(err, ErrStoreClosed) {
+				t.Errorf("Expected ErrStoreClosed, got %v", err)
+			}
+		})
+	}
+}
+
+func Test_TaskStoreLifecycle(t *testing.T) {
+	for name, kv := range newBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			defer kv.Close()
+			ctx := context.Background()
+			tasks := NewTaskStore(kv)
+
+			record, err := tasks.RecordReceived(ctx, "task-1", "yield_monitoring", []byte(`{"type":"yield_monitoring"}`))
+			if err != nil {
+				t.Fatalf("RecordReceived failed: %v", err)
+			}
+			if record.Status != TaskStatusReceived {
+				t.Errorf("Expected status %s, got %s", TaskStatusReceived, record.Status)
+			}
+
+			if err := tasks.MarkProcessing(ctx, "task-1"); err != nil {
+				t.Fatalf("MarkProcessing failed: %v", err)
+			}
+			if err := tasks.SaveCheckpoint(ctx, "task-1", "step", "fetched_rates"); err != nil {
+				t.Fatalf("SaveCheckpoint failed: %v", err)
+			}
+			if err := tasks.MarkCompleted(ctx, "task-1", []byte("result")); err != nil {
+				t.Fatalf("MarkCompleted failed: %v", err)
+			}
+
+			// Redelivery must not reset the stored record
+			record, err = tasks.RecordReceived(ctx, "task-1", "yield_monitoring", nil)
+			if err != nil {
+				t.Fatalf("RecordReceived failed: %v", err)
+			}
+			if record.Status != TaskStatusCompleted || string(record.Result) != "result" {
+				t.Errorf("Unexpected record after redelivery: %+v", record)
+			}
+			if record.State["step"] != "fetched_rates" {
+				t.Errorf("Expected checkpoint to persist, got %v", record.State)
+			}
+			if record.CompletedAt == nil {
+				t.Errorf("Expected CompletedAt to be set")
+			}
+
+			if _, err := tasks.RecordReceived(ctx, "task-2", "risk_assessment", nil); err != nil {
+				t.Fatalf("RecordReceived failed: %v", err)
+			}
+			if err := tasks.MarkFailed(ctx, "task-2", errors.New("rpc unavailable")); err != nil {
+				t.Fatalf("MarkFailed failed: %v", err)
+			}
+
+			failed, err := tasks.ListTasks(ctx, TaskStatusFailed)
+			if err != nil {
+				t.Fatalf("ListTasks failed: %v", err)
+			}
+			if len(failed) != 1 || failed[0].TaskID != "task-2" || failed[0].Error != "rpc unavailable" {
+				t.Errorf("Unexpected failed tasks: %+v", failed)
+			}
+
+			all, err := tasks.ListTasks(ctx)
+			if err != nil {
+				t.Fatalf("ListTasks failed: %v", err)
+			}
+			if len(all) != 2 {
+				t.Errorf("Expected 2 tasks, got %d", len(all))
+			}
+
+			if err := tasks.MarkStale(ctx, "task-1", "block 10 was reorganized"); err != nil {
+				t.Fatalf("MarkStale failed: %v", err)
+			}
+			if record, _ := tasks.GetTask(ctx, "task-1"); record.Status != TaskStatusStale || string(record.Result) != "result" || record.StaleReason == "" {
+				t.Errorf("Expected the stale result kept with its reason, got %+v", record)
+			}
+
+			if err := tasks.MarkProcessing(ctx, "unknown"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Expected ErrNotFound for unknown task, got %v", err)
+			}
+		})
+	}
+}
+
+func Test_TaskStoreRecentTasks(t *testing.T) {
+	ctx := context.Background()
+	tasks := NewTaskStore(NewMemoryKV())
+	clock := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tasks.now = func() time.Time { return clock }
+
+	for _, id := range []string{"task-c", "task-a", "task-b"} {
+		clock = clock.Add(time.Minute)
+		if _, err := tasks.RecordReceived(ctx, id, "yield_monitoring", nil); err != nil {
+			t.Fatalf("RecordReceived failed: %v", err)
+		}
+	}
+	clock = clock.Add(time.Minute)
+	if err := tasks.MarkCompleted(ctx, "task-c", []byte("result")); err != nil {
+		t.Fatalf("MarkCompleted failed: %v", err)
+	}
+
+	recent, err := tasks.RecentTasks(ctx, 2)
+	if err != nil {
+		t.Fatalf("RecentTasks failed: %v", err)
+	}
+	if len(recent) != 2 || recent[0].TaskID != "task-c" || recent[1].TaskID != "task-b" {
+		t.Errorf("Expected the most recently updated tasks first, got %+v", recent)
+	}
+	if all, err := tasks.RecentTasks(ctx, 0); err != nil || len(all) != 3 {
+		t.Errorf("Expected every task without a limit, got %d, %v", len(all), err)
+	}
+}
+
+func Test_BadgerPersistsAcrossRestart(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	kv, err := NewBadgerKV(&BadgerConfig{Dir: dir})
+	if err != nil {
+		t.Fatalf("Failed to open badger store: %v", err)
+	}
+	if _, err := NewTaskStore(kv).RecordReceived(ctx, "task-1", "yield_monitoring", []byte("payload")); err != nil {
+		t.Fatalf("RecordReceived failed: %v", err)
+	}
+	if err := kv.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	kv, err = NewBadgerKV(&BadgerConfig{Dir: dir})
+	if err != nil {
+		t.Fatalf("Failed to reopen badger store: %v", err)
+	}
+	defer kv.Close()
+
+	record, err := NewTaskStore(kv).GetTask(ctx, "task-1")
+	if err != nil {
+		t.Fatalf("GetTask after restart failed: %v", err)
+	}
+	if string(record.Payload) != "payload" {
+		t.Errorf("Expected payload to survive restart, got %q", string(record.Payload))
+	}
+}
+
+func Test_TaskStoreIdempotencyIndex(t *testing.T) {
+	ctx := context.Background()
+	tasks := NewTaskStore(NewMemoryKV())
+
+	key := IdempotencyKey("task-1", []byte("payload"))
+	if key == IdempotencyKey("task-1", []byte("other payload")) {
+		t.Errorf("Expected different payloads to produce different keys")
+	}
+	if key == IdempotencyKey("task-2", []byte("payload")) {
+		t.Errorf("Expected different task ids to produce different keys")
+	}
+
+	if _, err := tasks.RecordReceived(ctx, "task-1", "yield_monitoring", []byte("payload")); err != nil {
+		t.Fatalf("RecordReceived failed: %v", err)
+	}
+
+	record, err := tasks.GetTaskByIdempotencyKey(ctx, key)
+	if err != nil {
+		t.Fatalf("GetTaskByIdempotencyKey failed: %v", err)
+	}
+	if record.TaskID != "task-1" || record.IdempotencyKey != key {
+		t.Errorf("Unexpected record: %+v", record)
+	}
+
+	if _, err := tasks.GetTaskByIdempotencyKey(ctx, IdempotencyKey("task-1", []byte("other"))); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func Test_SeriesStoreRange(t *testing.T) {
+	for name, kv := range newBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			series := NewSeriesStore(kv)
+			key := SupplyRateSeries("Aave_V3", 1)
+			base := time.Unix(1_700_000_000, 0).UTC()
+
+			// appended out of order, returned oldest first
+			for _, offset := range []int{3, 1, 2, 0} {
+				point := SeriesPoint{Time: base.Add(time.Duration(offset) * time.Hour), Value: float64(offset)}
+				if err := series.Append(ctx, key, point); err != nil {
+					t.Fatalf("Append failed: %v", err)
+				}
+			}
+			if err := series.Append(ctx, SupplyRateSeries("compound_v3", 1), SeriesPoint{Time: base, Value: 9}); err != nil {
+				t.Fatalf("Append failed: %v", err)
+			}
+
+			points, err := series.Range(ctx, key, base.Add(time.Hour), base.Add(3*time.Hour))
+			if err != nil {
+				t.Fatalf("Range failed: %v", err)
+			}
+			if len(points) != 2 || points[0].Value != 1 || points[1].Value != 2 {
+				t.Errorf("Unexpected points: %+v", points)
+			}
+			if key != "supply_rate/aave_v3/1" {
+				t.Errorf("Expected series names to be lower case, got %s", key)
+			}
+		})
+	}
+}
+
+func Test_SeriesStoreRetention(t *testing.T) {
+	for name, kv := range newBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			series := NewSeriesStore(kv)
+			key := SupplyRateSeries("aave_v3", 8453)
+			base := time.Unix(1_700_000_000, 0).UTC().Truncate(time.Hour)
+
+			// four samples per hour over three hours
+			for i := 0; i < 12; i++ {
+				point := SeriesPoint{Time: base.Add(time.Duration(i) * 15 * time.Minute), Value: float64(i)}
+				if err := series.Append(ctx, key, point); err != nil {
+					t.Fatalf("Append failed: %v", err)
+				}
+			}
+
+			removed, err := series.Compact(ctx, key, base.Add(2*time.Hour), time.Hour)
+			if err != nil {
+				t.Fatalf("Compact failed: %v", err)
+			}
+			if removed != 6 {
+				t.Errorf("Expected 6 points to be compacted away, got %d", removed)
+			}
+			points, err := series.Range(ctx, key, base, base.Add(24*time.Hour))
+			if err != nil {
+				t.Fatalf("Range failed: %v", err)
+			}
+			if len(points) != 6 || points[0].Value != 1.5 || !points[1].Time.Equal(base.Add(time.Hour)) || points[1].Value != 5.5 {
+				t.Errorf("Unexpected compacted points: %+v", points)
+			}
+
+			// compaction is idempotent
+			if removed, err := series.Compact(ctx, key, base.Add(2*time.Hour), time.Hour); err != nil || removed != 0 {
+				t.Errorf("Expected second compaction to be a no-op, removed %d: %v", removed, err)
+			}
+
+			pruned, err := series.Prune(ctx, key, base.Add(time.Hour))
+			if err != nil || pruned != 1 {
+				t.Errorf("Expected one point to be pruned, got %d: %v", pruned, err)
+			}
+
+			names, err := series.Series(ctx)
+			if err != nil || len(names) != 1 || names[0] != key {
+				t.Errorf("Unexpected series names %v: %v", names, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,267 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	prefixTask        = "task:"
+	prefixIdempotency = "idem:"
+)
+
+// TaskStatus tracks where a task is in its lifecycle
+type TaskStatus string
+
+const (
+	TaskStatusReceived   TaskStatus = "received"
+	TaskStatusProcessing TaskStatus = "processing"
+	TaskStatusCompleted  TaskStatus = "completed"
+	TaskStatusFailed     TaskStatus = "failed"
+
+	// TaskStatusStale marks completed tasks whose result was computed at a block that a
+	// reorg removed from the chain
+	TaskStatusStale TaskStatus = "stale"
+)
+
+// TaskRecord is the persisted audit record of a single task
+type TaskRecord struct {
+	TaskID   string     `json:"taskId"`
+	TaskType string     `json:"taskType"`
+	Payload  []byte     `json:"payload"`
+	Status   TaskStatus `json:"status"`
+
+	// IdempotencyKey identifies the exact TaskId+payload combination this record was created for
+	IdempotencyKey string `json:"idempotencyKey"`
+
+	// State holds intermediate checkpoints written by handlers while a task runs,
+	// so work can be resumed or inspected after a restart
+	State map[string]string `json:"state,omitempty"`
+
+	Result []byte `json:"result,omitempty"`
+	Error  string `json:"error,omitempty"`
+
+	// StaleReason tells why the result of a stale task no longer holds
+	StaleReason string `json:"staleReason,omitempty"`
+
+	ReceivedAt  time.Time  `json:"receivedAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+}
+
+// TaskStore records every task received, its payload, intermediate state and final result
+type TaskStore struct {
+	kv  KV
+	now func() time.Time
+
+	// mu serializes read-modify-write updates of task records
+	mu sync.Mutex
+}
+
+// NewTaskStore creates a TaskStore backed by kv
+func NewTaskStore(kv KV) *TaskStore {
+	return &TaskStore{
+		kv:  kv,
+		now: time.Now,
+	}
+}
+
+func taskKey(taskID string) []byte {
+	return []byte(prefixTask + taskID)
+}
+
+func idempotencyIndexKey(key string) []byte {
+	return []byte(prefixIdempotency + key)
+}
+
+// IdempotencyKey returns the hex encoded sha256 hash of the TaskId and payload.
+// Redelivered tasks produce the same key; a reused TaskId with a different payload does not.
+func IdempotencyKey(taskID string, payload []byte) string {
+	h := sha256.New()
+	h.Write([]byte(taskID))
+	h.Write([]byte{0})
+	h.Write(payload)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// RecordReceived stores a newly received task. If the task is already known the
+// existing record is returned unchanged so redelivered tasks keep their history.
+func (s *TaskStore) RecordReceived(ctx context.Context, taskID string, taskType string, payload []byte) (*TaskRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, err := s.GetTask(ctx, taskID)
+	if err == nil {
+		return existing, nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	now := s.now().UTC()
+	record := &TaskRecord{
+		TaskID:         taskID,
+		TaskType:       taskType,
+		Payload:        payload,
+		Status:         TaskStatusReceived,
+		IdempotencyKey: IdempotencyKey(taskID, payload),
+		ReceivedAt:     now,
+		UpdatedAt:      now,
+	}
+	if err := s.put(ctx, record); err != nil {
+		return nil, err
+	}
+	if err := s.kv.Set(ctx, idempotencyIndexKey(record.IdempotencyKey), []byte(taskID)); err != nil {
+		return nil, fmt.Errorf("failed to index task record: %w", err)
+	}
+	return record, nil
+}
+
+// GetTaskByIdempotencyKey returns the record created for the given idempotency key or ErrNotFound
+func (s *TaskStore) GetTaskByIdempotencyKey(ctx context.Context, key string) (*TaskRecord, error) {
+	taskID, err := s.kv.Get(ctx, idempotencyIndexKey(key))
+	if err != nil {
+		return nil, err
+	}
+	return s.GetTask(ctx, string(taskID))
+}
+
+// MarkProcessing transitions a task into the processing state
+func (s *TaskStore) MarkProcessing(ctx context.Context, taskID string) error {
+	return s.update(ctx, taskID, func(record *TaskRecord) {
+		record.Status = TaskStatusProcessing
+		record.Error = ""
+		record.StaleReason = ""
+	})
+}
+
+// SaveCheckpoint records an intermediate state value for a running task
+func (s *TaskStore) SaveCheckpoint(ctx context.Context, taskID string, key string, value string) error {
+	return s.update(ctx, taskID, func(record *TaskRecord) {
+		if record.State == nil {
+			record.State = make(map[string]string)
+		}
+		record.State[key] = value
+	})
+}
+
+// MarkCompleted stores the final result of a task
+func (s *TaskStore) MarkCompleted(ctx context.Context, taskID string, result []byte) error {
+	return s.update(ctx, taskID, func(record *TaskRecord) {
+		now := s.now().UTC()
+		record.Status = TaskStatusCompleted
+		record.Result = result
+		record.Error = ""
+		record.CompletedAt = &now
+	})
+}
+
+// MarkFailed stores the error a task failed with
+func (s *TaskStore) MarkFailed(ctx context.Context, taskID string, taskErr error) error {
+	return s.update(ctx, taskID, func(record *TaskRecord) {
+		now := s.now().UTC()
+		record.Status = TaskStatusFailed
+		record.Error = taskErr.Error()
+		record.CompletedAt = &now
+	})
+}
+
+// MarkStale marks the result of a completed task as no longer holding, keeping it for
+// audits
+func (s *TaskStore) MarkStale(ctx context.Context, taskID string, reason string) error {
+	return s.update(ctx, taskID, func(record *TaskRecord) {
+		record.Status = TaskStatusStale
+		record.StaleReason = reason
+	})
+}
+
+// GetTask returns the record for taskID or ErrNotFound
+func (s *TaskStore) GetTask(ctx context.Context, taskID string) (*TaskRecord, error) {
+	value, err := s.kv.Get(ctx, taskKey(taskID))
+	if err != nil {
+		return nil, err
+	}
+
+	var record TaskRecord
+	if err := json.Unmarshal(value, &record); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal task record: %w", err)
+	}
+	return &record, nil
+}
+
+// ListTasks returns all task records, optionally filtered by status.
+// Records are returned in TaskID order.
+func (s *TaskStore) ListTasks(ctx context.Context, statuses ...TaskStatus) ([]*TaskRecord, error) {
+	var records []*TaskRecord
+	err := s.kv.Iterate(ctx, []byte(prefixTask), func(key []byte, value []byte) error {
+		var record TaskRecord
+		if err := json.Unmarshal(value, &record); err != nil {
+			return fmt.Errorf("failed to unmarshal task record %s: %w", string(key), err)
+		}
+		if len(statuses) > 0 && !containsStatus(statuses, record.Status) {
+			return nil
+		}
+		records = append(records, &record)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// RecentTasks returns the limit most recently updated task records, newest first.
+// A limit of zero or less returns every record.
+func (s *TaskStore) RecentTasks(ctx context.Context, limit int) ([]*TaskRecord, error) {
+	records, err := s.ListTasks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].UpdatedAt.After(records[j].UpdatedAt)
+	})
+	if limit > 0 && len(records) > limit {
+		records = records[:limit]
+	}
+	return records, nil
+}
+
+func (s *TaskStore) update(ctx context.Context, taskID string, mutate func(record *TaskRecord)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, err := s.GetTask(ctx, taskID)
+	if err != nil {
+		return fmt.Errorf("failed to load task %s: %w", taskID, err)
+	}
+	mutate(record)
+	record.UpdatedAt = s.now().UTC()
+	return s.put(ctx, record)
+}
+
+func (s *TaskStore) put(ctx context.Context, record *TaskRecord) error {
+	value, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task record: %w", err)
+	}
+	if err := s.kv.Set(ctx, taskKey(record.TaskID), value); err != nil {
+		return fmt.Errorf("failed to save task record: %w", err)
+	}
+	return nil
+}
+
+func containsStatus(statuses []TaskStatus, status TaskStatus) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,170 @@
+package subgraph
+
+import (
+	"context"
+	"math/big"
+	"time"
+)
+
+// rayDecimals is the precision of Aave rates
+const rayDecimals = 27
+
+// AaveReserveHistoryQuery builds a query for the rate and supply history of an Aave v3
+// reserve, for the official Aave v3 subgraph schema
+func AaveReserveHistoryQuery(reserve string, after, to int64) Query {
+	return Query{
+		Query: `query ReserveHistory($reserve: String!, $after: Int!, $to: Int!, $first: Int!) {
+  reserveParamsHistoryItems(
+    where: {reserve: $reserve, timestamp_gt: $after, timestamp_lte: $to}
+    orderBy: timestamp
+    orderDirection: asc
+    first: $first
+  ) {
+    timestamp
+    liquidityRate
+    totalATokenSupply
+    reserve { decimals }
+  }
+}`,
+		Variables: map[string]interface{}{"reserve": reserve, "after": after, "to": to, "first": pageSize},
+	}
+}
+
+// AaveLiquidationsQuery builds a query for liquidations that repaid debt in an Aave v3
+// reserve
+func AaveLiquidationsQuery(reserve string, after, to int64) Query {
+	return Query{
+		Query: `query Liquidations($reserve: String!, $after: Int!, $to: Int!, $first: Int!) {
+  liquidationCalls(
+    where: {principalReserve: $reserve, timestamp_gt: $after, timestamp_lte: $to}
+    orderBy: timestamp
+    orderDirection: asc
+    first: $first
+  ) {
+    timestamp
+    txHash
+    principalAmount
+    principalReserve { decimals }
+  }
+}`,
+		Variables: map[string]interface{}{"reserve": reserve, "after": after, "to": to, "first": pageSize},
+	}
+}
+
+type aaveDecimals struct {
+	Decimals int `json:"decimals"`
+}
+
+type aaveReserveHistoryItem struct {
+	Timestamp         int64        `json:"timestamp"`
+	LiquidityRate     string       `json:"liquidityRate"`
+	TotalATokenSupply string       `json:"totalATokenSupply"`
+	Reserve           aaveDecimals `json:"reserve"`
+}
+
+type aaveLiquidationCall struct {
+	Timestamp        int64        `json:"timestamp"`
+	TxHash           string       `json:"txHash"`
+	PrincipalAmount  string       `json:"principalAmount"`
+	PrincipalReserve aaveDecimals `json:"principalReserve"`
+}
+
+// AaveSubgraph reads the history of one reserve from the Aave v3 subgraph
+type AaveSubgraph struct {
+	client  *Client
+	reserve string
+}
+
+// NewAaveSubgraph creates a reader for reserve, the subgraph id of the USDC reserve
+func NewAaveSubgraph(client *Client, reserve string) *AaveSubgraph {
+	return &AaveSubgraph{client: client, reserve: reserve}
+}
+
+func (a *AaveSubgraph) reserveHistory(ctx context.Context, from, to time.Time) ([]aaveReserveHistoryItem, error) {
+	var items []aaveReserveHistoryItem
+	err := paginate(from, to, func(after, to int64) ([]int64, error) {
+		var page struct {
+			Items []aaveReserveHistoryItem `json:"reserveParamsHistoryItems"`
+		}
+		if err := a.client.Do(ctx, AaveReserveHistoryQuery(a.reserve, after, to), &page); err != nil {
+			return nil, err
+		}
+		items = append(items, page.Items...)
+		timestamps := make([]int64, len(page.Items))
+		for i, item := range page.Items {
+			timestamps[i] = item.Timestamp
+		}
+		return timestamps, nil
+	})
+	return items, err
+}
+
+// RateHistory returns the supply rate every time the reserve was updated
+func (a *AaveSubgraph) RateHistory(ctx context.Context, from, to time.Time) ([]RatePoint, error) {
+	items, err := a.reserveHistory(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	points := make([]RatePoint, 0, len(items))
+	for _, item := range items {
+		rate, err := parseRat(item.LiquidityRate)
+		if err != nil {
+			return nil, err
+		}
+		value, _ := scaleDown(rate, rayDecimals).Float64()
+		points = append(points, RatePoint{Time: time.Unix(item.Timestamp, 0).UTC(), SupplyRate: value})
+	}
+	return points, nil
+}
+
+// TVLHistory returns the aToken supply in token units every time the reserve was updated
+func (a *AaveSubgraph) TVLHistory(ctx context.Context, from, to time.Time) ([]TVLPoint, error) {
+	items, err := a.reserveHistory(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	points := make([]TVLPoint, 0, len(items))
+	for _, item := range items {
+		supply, err := parseRat(item.TotalATokenSupply)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, TVLPoint{Time: time.Unix(item.Timestamp, 0).UTC(), Amount: scaleDown(supply, item.Reserve.Decimals)})
+	}
+	return points, nil
+}
+
+// Liquidations returns liquidations that repaid USDC debt. Amounts are in USDC, taken
+// as USD.
+func (a *AaveSubgraph) Liquidations(ctx context.Context, from, to time.Time) ([]Liquidation, error) {
+	var liquidations []Liquidation
+	err := paginate(from, to, func(after, to int64) ([]int64, error) {
+		var page struct {
+			Calls []aaveLiquidationCall `json:"liquidationCalls"`
+		}
+		if err := a.client.Do(ctx, AaveLiquidationsQuery(a.reserve, after, to), &page); err != nil {
+			return nil, err
+		}
+		timestamps := make([]int64, len(page.Calls))
+		for i, call := range page.Calls {
+			amount, err := parseRat(call.PrincipalAmount)
+			if err != nil {
+				return nil, err
+			}
+			liquidations = append(liquidations, Liquidation{
+				Time:   time.Unix(call.Timestamp, 0).UTC(),
+				TxHash: call.TxHash,
+				Amount: scaleDown(amount, call.PrincipalReserve.Decimals),
+			})
+			timestamps[i] = call.Timestamp
+		}
+		return timestamps, nil
+	})
+	return liquidations, err
+}
+
+// scaleDown divides v by 10^decimals
+func scaleDown(v *big.Rat, decimals int) *big.Rat {
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	return new(big.Rat).Quo(v, new(big.Rat).SetInt(scale))
+}
@@ -0,0 +1,113 @@
+// Package subgraph queries protocol subgraphs over GraphQL for data that is expensive to
+// read from contracts directly: historical rates, TVL history and liquidations.
+package subgraph
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/najnomics/crosscow-avs/pkg/resilience"
+)
+
+// DefaultTimeout bounds a single GraphQL request
+const DefaultTimeout = 15 * time.Second
+
+// Query is a GraphQL request body
+type Query struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables,omitempty"`
+}
+
+// Error is an error reported by a GraphQL server in the errors field of a response
+type Error struct {
+	Messages []string
+}
+
+func (e *Error) Error() string {
+	return "subgraph: " + strings.Join(e.Messages, "; ")
+}
+
+// Client is a minimal GraphQL client for a single endpoint
+type Client struct {
+	url        string
+	apiKey     string
+	httpClient *http.Client
+	policy     *resilience.Policy
+}
+
+// NewClient creates a client for url. A non empty apiKey is sent as a bearer token, as
+// The Graph's gateway expects. Transient failures are retried under policy, which may be
+// nil.
+func NewClient(url, apiKey string, timeout time.Duration, policy *resilience.Policy) *Client {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Client{
+		url:        url,
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: timeout},
+		policy:     policy,
+	}
+}
+
+// Do runs q and decodes the data field of the response into out
+func (c *Client) Do(ctx context.Context, q Query, out interface{}) error {
+	body, err := json.Marshal(q)
+	if err != nil {
+		return fmt.Errorf("subgraph: failed to encode query: %w", err)
+	}
+	return c.policy.Do(ctx, c.url, func(ctx context.Context) error {
+		return c.do(ctx, body, out)
+	})
+}
+
+func (c *Client) do(ctx context.Context, body []byte, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("subgraph: failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("subgraph: endpoint unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return fmt.Errorf("subgraph: %w", &resilience.StatusError{Endpoint: c.url, StatusCode: resp.StatusCode})
+	}
+
+	var envelope struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("subgraph: failed to decode response: %w", err)
+	}
+	if len(envelope.Errors) > 0 {
+		gqlErr := &Error{}
+		for _, e := range envelope.Errors {
+			gqlErr.Messages = append(gqlErr.Messages, e.Message)
+		}
+		return gqlErr
+	}
+	if len(envelope.Data) == 0 {
+		return fmt.Errorf("subgraph: response has no data")
+	}
+	if err := json.Unmarshal(envelope.Data, out); err != nil {
+		return fmt.Errorf("subgraph: failed to decode data: %w", err)
+	}
+	return nil
+}
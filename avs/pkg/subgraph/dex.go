@@ -0,0 +1,230 @@
+package subgraph
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DEX subgraph schemas
+const (
+	// SchemaUniswap is the schema of the Uniswap v3 and v4 subgraphs
+	SchemaUniswap = "uniswap"
+
+	// SchemaMessariDEX is the Messari DEX AMM schema, which the Curve subgraphs implement
+	SchemaMessariDEX = "messari_dex"
+)
+
+// PoolDay is a day of trading in a liquidity pool. Amounts are in USD.
+type PoolDay struct {
+	Time      time.Time
+	VolumeUSD float64
+
+	// FeesUSD are the swap fees paid to liquidity providers
+	FeesUSD float64
+	TVLUSD  float64
+
+	// IncentivesUSD are the rewards emitted to liquidity providers, zero when the
+	// subgraph does not index them
+	IncentivesUSD float64
+
+	// PriceLow and PriceHigh are the lowest and highest price of the first coin of the
+	// pool in the second, zero when the subgraph does not index them
+	PriceLow  float64
+	PriceHigh float64
+}
+
+// PoolSubgraph reads the trading history of one liquidity pool
+type PoolSubgraph interface {
+	PoolDays(ctx context.Context, from, to time.Time) ([]PoolDay, error)
+}
+
+// PoolEndpointConfig locates the subgraph indexing a liquidity pool
+type PoolEndpointConfig struct {
+	// Schema is uniswap or messari_dex
+	Schema  string        `yaml:"schema"`
+	Url     string        `yaml:"url"`
+	ApiKey  string        `yaml:"apiKey"`
+	Pool    string        `yaml:"pool"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// Validate checks the config for values the pool subgraph cannot be queried with
+func (c PoolEndpointConfig) Validate() error {
+	if c.Url == "" {
+		return fmt.Errorf("url is required")
+	}
+	if c.Pool == "" {
+		return fmt.Errorf("pool is required")
+	}
+	switch c.Schema {
+	case SchemaUniswap, SchemaMessariDEX:
+		return nil
+	default:
+		return fmt.Errorf("unsupported schema: %s", c.Schema)
+	}
+}
+
+// NewPoolSubgraph creates the pool reader matching the schema of cfg
+func NewPoolSubgraph(cfg PoolEndpointConfig, client *Client) PoolSubgraph {
+	pool := strings.ToLower(cfg.Pool)
+	if cfg.Schema == SchemaMessariDEX {
+		return &MessariPoolSubgraph{client: client, pool: pool}
+	}
+	return &UniswapPoolSubgraph{client: client, pool: pool}
+}
+
+// UniswapPoolDaysQuery builds a query for the day data of a pool in the Uniswap v3 and
+// v4 schemas, whose pools are identified by address and pool id respectively
+func UniswapPoolDaysQuery(pool string, after, to int64) Query {
+	return Query{
+		Query: `query PoolDays($pool: String!, $after: Int!, $to: Int!, $first: Int!) {
+  poolDayDatas(
+    where: {pool: $pool, date_gt: $after, date_lte: $to}
+    orderBy: date
+    orderDirection: asc
+    first: $first
+  ) {
+    date
+    volumeUSD
+    feesUSD
+    tvlUSD
+    high
+    low
+  }
+}`,
+		Variables: map[string]interface{}{"pool": pool, "after": after, "to": to, "first": pageSize},
+	}
+}
+
+type uniswapPoolDay struct {
+	Date      int64  `json:"date"`
+	VolumeUSD string `json:"volumeUSD"`
+	FeesUSD   string `json:"feesUSD"`
+	TvlUSD    string `json:"tvlUSD"`
+	High      string `json:"high"`
+	Low       string `json:"low"`
+}
+
+// UniswapPoolSubgraph reads a Uniswap v3 or v4 pool. Its fees are those swappers paid,
+// all of which go to liquidity providers unless a protocol fee is switched on.
+type UniswapPoolSubgraph struct {
+	client *Client
+	pool   string
+}
+
+func (u *UniswapPoolSubgraph) PoolDays(ctx context.Context, from, to time.Time) ([]PoolDay, error) {
+	var days []PoolDay
+	err := paginate(from, to, func(after, to int64) ([]int64, error) {
+		var page struct {
+			Days []uniswapPoolDay `json:"poolDayDatas"`
+		}
+		if err := u.client.Do(ctx, UniswapPoolDaysQuery(u.pool, after, to), &page); err != nil {
+			return nil, err
+		}
+		timestamps := make([]int64, len(page.Days))
+		for i, d := range page.Days {
+			values, err := parseFloats(d.VolumeUSD, d.FeesUSD, d.TvlUSD, d.High, d.Low)
+			if err != nil {
+				return nil, err
+			}
+			days = append(days, PoolDay{
+				Time:      time.Unix(d.Date, 0).UTC(),
+				VolumeUSD: values[0],
+				FeesUSD:   values[1],
+				TVLUSD:    values[2],
+				PriceHigh: values[3],
+				PriceLow:  values[4],
+			})
+			timestamps[i] = d.Date
+		}
+		return timestamps, nil
+	})
+	return days, err
+}
+
+// MessariPoolSnapshotsQuery builds a query for the daily snapshots of a pool in the
+// Messari DEX AMM schema
+func MessariPoolSnapshotsQuery(pool string, after, to int64) Query {
+	return Query{
+		Query: `query PoolSnapshots($pool: String!, $after: BigInt!, $to: BigInt!, $first: Int!) {
+  liquidityPoolDailySnapshots(
+    where: {pool: $pool, timestamp_gt: $after, timestamp_lte: $to}
+    orderBy: timestamp
+    orderDirection: asc
+    first: $first
+  ) {
+    timestamp
+    dailyVolumeUSD
+    dailySupplySideRevenueUSD
+    totalValueLockedUSD
+    rewardTokenEmissionsUSD
+  }
+}`,
+		Variables: map[string]interface{}{"pool": pool, "after": after, "to": to, "first": pageSize},
+	}
+}
+
+type messariPoolSnapshot struct {
+	Timestamp                 string   `json:"timestamp"`
+	DailyVolumeUSD            string   `json:"dailyVolumeUSD"`
+	DailySupplySideRevenueUSD string   `json:"dailySupplySideRevenueUSD"`
+	TotalValueLockedUSD       string   `json:"totalValueLockedUSD"`
+	RewardTokenEmissionsUSD   []string `json:"rewardTokenEmissionsUSD"`
+}
+
+// MessariPoolSubgraph reads a pool from a subgraph implementing the Messari DEX AMM
+// schema. Its fees are the supply side revenue, the share of swap fees liquidity
+// providers keep, and its incentives the daily reward emissions.
+type MessariPoolSubgraph struct {
+	client *Client
+	pool   string
+}
+
+func (m *MessariPoolSubgraph) PoolDays(ctx context.Context, from, to time.Time) ([]PoolDay, error) {
+	var days []PoolDay
+	err := paginate(from, to, func(after, to int64) ([]int64, error) {
+		var page struct {
+			Snapshots []messariPoolSnapshot `json:"liquidityPoolDailySnapshots"`
+		}
+		if err := m.client.Do(ctx, MessariPoolSnapshotsQuery(m.pool, after, to), &page); err != nil {
+			return nil, err
+		}
+		timestamps := make([]int64, len(page.Snapshots))
+		for i, s := range page.Snapshots {
+			ts, err := parseTimestamp(s.Timestamp)
+			if err != nil {
+				return nil, err
+			}
+			values, err := parseFloats(s.DailyVolumeUSD, s.DailySupplySideRevenueUSD, s.TotalValueLockedUSD)
+			if err != nil {
+				return nil, err
+			}
+			rewards, err := parseFloats(s.RewardTokenEmissionsUSD...)
+			if err != nil {
+				return nil, err
+			}
+			day := PoolDay{Time: time.Unix(ts, 0).UTC(), VolumeUSD: values[0], FeesUSD: values[1], TVLUSD: values[2]}
+			for _, reward := range rewards {
+				day.IncentivesUSD += reward
+			}
+			days = append(days, day)
+			timestamps[i] = ts
+		}
+		return timestamps, nil
+	})
+	return days, err
+}
+
+func parseFloats(values ...string) ([]float64, error) {
+	out := make([]float64, len(values))
+	for i, s := range values {
+		r, err := parseRat(s)
+		if err != nil {
+			return nil, err
+		}
+		out[i], _ = r.Float64()
+	}
+	return out, nil
+}
@@ -0,0 +1,200 @@
+package subgraph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/najnomics/crosscow-avs/pkg/resilience"
+)
+
+// Protocol names, matching the task parameter names of the protocol adapters
+const (
+	ProtocolAaveV3     = "aave_v3"
+	ProtocolCompoundV3 = "compound_v3"
+	ProtocolMorpho     = "morpho"
+)
+
+// pageSize is the largest page The Graph serves
+const pageSize = 1000
+
+// ErrNoEndpoint is returned when no subgraph is configured for a protocol and chain
+var ErrNoEndpoint = errors.New("no subgraph endpoint configured")
+
+// RatePoint is a historical supply rate, as an annual fraction (0.05 = 5%)
+type RatePoint struct {
+	Time       time.Time
+	SupplyRate float64
+}
+
+// TVLPoint is a historical amount supplied to a market. Amount is in token units for
+// Aave and in USD for subgraphs that only index USD values.
+type TVLPoint struct {
+	Time   time.Time
+	Amount *big.Rat
+}
+
+// Liquidation is a single liquidation that repaid debt in the market
+type Liquidation struct {
+	Time   time.Time
+	TxHash string
+	// Amount is the repaid debt in USD
+	Amount *big.Rat
+}
+
+// LendingSubgraph reads history of a single lending market
+type LendingSubgraph interface {
+	RateHistory(ctx context.Context, from, to time.Time) ([]RatePoint, error)
+	TVLHistory(ctx context.Context, from, to time.Time) ([]TVLPoint, error)
+	Liquidations(ctx context.Context, from, to time.Time) ([]Liquidation, error)
+}
+
+// EndpointConfig locates the subgraph of one market
+type EndpointConfig struct {
+	Protocol string `yaml:"protocol"`
+	ChainID  uint64 `yaml:"chainId"`
+	Url      string `yaml:"url"`
+	ApiKey   string `yaml:"apiKey"`
+	// Market is the subgraph id of the USDC market, e.g. an Aave reserve id or a Morpho
+	// market id
+	Market  string        `yaml:"market"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// Validate checks the config for values the endpoint cannot be queried with
+func (c EndpointConfig) Validate() error {
+	if c.Url == "" {
+		return fmt.Errorf("url is required")
+	}
+	if c.Market == "" {
+		return fmt.Errorf("market is required")
+	}
+	if c.ChainID == 0 {
+		return fmt.Errorf("chainId is required")
+	}
+	switch strings.ToLower(c.Protocol) {
+	case ProtocolAaveV3, ProtocolCompoundV3, ProtocolMorpho:
+		return nil
+	default:
+		return fmt.Errorf("unsupported protocol: %s", c.Protocol)
+	}
+}
+
+// New creates the subgraph reader matching the protocol of cfg
+func New(cfg EndpointConfig, policy *resilience.Policy) (LendingSubgraph, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	client := NewClient(cfg.Url, cfg.ApiKey, cfg.Timeout, policy)
+	if strings.ToLower(cfg.Protocol) == ProtocolAaveV3 {
+		return NewAaveSubgraph(client, cfg.Market), nil
+	}
+	return NewMessariSubgraph(client, cfg.Market), nil
+}
+
+type marketKey struct {
+	protocol string
+	chainID  uint64
+}
+
+// Set holds the subgraphs of every configured market
+type Set struct {
+	markets map[marketKey]LendingSubgraph
+}
+
+// NewSet creates subgraph readers for every endpoint, retrying under policy
+func NewSet(endpoints []EndpointConfig, policy *resilience.Policy) (*Set, error) {
+	s := &Set{markets: make(map[marketKey]LendingSubgraph, len(endpoints))}
+	for i, cfg := range endpoints {
+		sg, err := New(cfg, policy)
+		if err != nil {
+			return nil, fmt.Errorf("subgraphs[%d]: %w", i, err)
+		}
+		s.Add(cfg.Protocol, cfg.ChainID, sg)
+	}
+	return s, nil
+}
+
+// Add registers the subgraph of a market
+func (s *Set) Add(protocol string, chainID uint64, sg LendingSubgraph) {
+	s.markets[marketKey{strings.ToLower(protocol), chainID}] = sg
+}
+
+// Get returns the subgraph of a market
+func (s *Set) Get(protocol string, chainID uint64) (LendingSubgraph, error) {
+	sg, ok := s.markets[marketKey{strings.ToLower(protocol), chainID}]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s on %d", ErrNoEndpoint, protocol, chainID)
+	}
+	return sg, nil
+}
+
+// Len returns the number of configured markets
+func (s *Set) Len() int {
+	return len(s.markets)
+}
+
+// Name identifies subgraphs as a cross validation rate source
+func (s *Set) Name() string {
+	return "subgraph"
+}
+
+// latestRateWindow is how far back SupplyRate looks for the most recent indexed rate
+const latestRateWindow = 24 * time.Hour
+
+// SupplyRate returns the most recent rate indexed by the subgraph of a market
+func (s *Set) SupplyRate(ctx context.Context, protocol string, chainID uint64) (float64, error) {
+	sg, err := s.Get(protocol, chainID)
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	points, err := sg.RateHistory(ctx, now.Add(-latestRateWindow), now)
+	if err != nil {
+		return 0, err
+	}
+	if len(points) == 0 {
+		return 0, fmt.Errorf("subgraph: no rate indexed for %s on %d in the last %s", protocol, chainID, latestRateWindow)
+	}
+	return points[len(points)-1].SupplyRate, nil
+}
+
+// paginate fetches pages ordered by timestamp, resuming after the last timestamp of each
+// page until a short page is returned. fetch returns the timestamps of its page.
+func paginate(from, to time.Time, fetch func(after, to int64) ([]int64, error)) error {
+	after := from.Unix() - 1
+	for {
+		timestamps, err := fetch(after, to.Unix())
+		if err != nil {
+			return err
+		}
+		if len(timestamps) < pageSize {
+			return nil
+		}
+		last := timestamps[len(timestamps)-1]
+		if last <= after {
+			return fmt.Errorf("subgraph: pagination did not advance past %d", after)
+		}
+		after = last
+	}
+}
+
+func parseTimestamp(s string) (int64, error) {
+	ts, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("subgraph: invalid timestamp %q", s)
+	}
+	return ts, nil
+}
+
+func parseRat(s string) (*big.Rat, error) {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, fmt.Errorf("subgraph: invalid number %q", s)
+	}
+	return r, nil
+}
@@ -0,0 +1,178 @@
+package subgraph
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// MessariSnapshotsQuery builds a query for the daily snapshots of a market in the Messari
+// lending schema, which the Compound v3 and Morpho subgraphs implement
+func MessariSnapshotsQuery(market string, after, to int64) Query {
+	return Query{
+		Query: `query Snapshots($market: String!, $after: BigInt!, $to: BigInt!, $first: Int!) {
+  marketDailySnapshots(
+    where: {market: $market, timestamp_gt: $after, timestamp_lte: $to}
+    orderBy: timestamp
+    orderDirection: asc
+    first: $first
+  ) {
+    timestamp
+    totalValueLockedUSD
+    rates { rate side }
+  }
+}`,
+		Variables: map[string]interface{}{"market": market, "after": after, "to": to, "first": pageSize},
+	}
+}
+
+// MessariLiquidationsQuery builds a query for liquidations of a market in the Messari
+// lending schema
+func MessariLiquidationsQuery(market string, after, to int64) Query {
+	return Query{
+		Query: `query Liquidations($market: String!, $after: BigInt!, $to: BigInt!, $first: Int!) {
+  liquidates(
+    where: {market: $market, timestamp_gt: $after, timestamp_lte: $to}
+    orderBy: timestamp
+    orderDirection: asc
+    first: $first
+  ) {
+    timestamp
+    hash
+    amountUSD
+  }
+}`,
+		Variables: map[string]interface{}{"market": market, "after": after, "to": to, "first": pageSize},
+	}
+}
+
+// messariLenderSide is the rate side paid to suppliers
+const messariLenderSide = "LENDER"
+
+type messariSnapshot struct {
+	Timestamp           string `json:"timestamp"`
+	TotalValueLockedUSD string `json:"totalValueLockedUSD"`
+	Rates               []struct {
+		Rate string `json:"rate"`
+		Side string `json:"side"`
+	} `json:"rates"`
+}
+
+type messariLiquidation struct {
+	Timestamp string `json:"timestamp"`
+	Hash      string `json:"hash"`
+	AmountUSD string `json:"amountUSD"`
+}
+
+// MessariSubgraph reads the history of one market from a subgraph implementing the
+// Messari lending schema. Snapshots are daily.
+type MessariSubgraph struct {
+	client *Client
+	market string
+}
+
+// NewMessariSubgraph creates a reader for market, the subgraph id of the USDC market
+func NewMessariSubgraph(client *Client, market string) *MessariSubgraph {
+	return &MessariSubgraph{client: client, market: market}
+}
+
+func (m *MessariSubgraph) snapshots(ctx context.Context, from, to time.Time) ([]messariSnapshot, error) {
+	var snapshots []messariSnapshot
+	err := paginate(from, to, func(after, to int64) ([]int64, error) {
+		var page struct {
+			Snapshots []messariSnapshot `json:"marketDailySnapshots"`
+		}
+		if err := m.client.Do(ctx, MessariSnapshotsQuery(m.market, after, to), &page); err != nil {
+			return nil, err
+		}
+		timestamps := make([]int64, len(page.Snapshots))
+		for i, s := range page.Snapshots {
+			ts, err := parseTimestamp(s.Timestamp)
+			if err != nil {
+				return nil, err
+			}
+			timestamps[i] = ts
+		}
+		snapshots = append(snapshots, page.Snapshots...)
+		return timestamps, nil
+	})
+	return snapshots, err
+}
+
+// RateHistory returns the daily lender rate. Messari reports APYs, which are converted
+// to continuously compounded annual rates to compare with rates read from chain.
+func (m *MessariSubgraph) RateHistory(ctx context.Context, from, to time.Time) ([]RatePoint, error) {
+	snapshots, err := m.snapshots(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	points := make([]RatePoint, 0, len(snapshots))
+	for _, s := range snapshots {
+		ts, err := parseTimestamp(s.Timestamp)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range s.Rates {
+			if r.Side != messariLenderSide {
+				continue
+			}
+			apy, err := parseRat(r.Rate)
+			if err != nil {
+				return nil, err
+			}
+			percent, _ := apy.Float64()
+			points = append(points, RatePoint{Time: time.Unix(ts, 0).UTC(), SupplyRate: math.Log1p(percent / 100)})
+			break
+		}
+	}
+	return points, nil
+}
+
+// TVLHistory returns the daily total value locked in USD
+func (m *MessariSubgraph) TVLHistory(ctx context.Context, from, to time.Time) ([]TVLPoint, error) {
+	snapshots, err := m.snapshots(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	points := make([]TVLPoint, 0, len(snapshots))
+	for _, s := range snapshots {
+		ts, err := parseTimestamp(s.Timestamp)
+		if err != nil {
+			return nil, err
+		}
+		tvl, err := parseRat(s.TotalValueLockedUSD)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, TVLPoint{Time: time.Unix(ts, 0).UTC(), Amount: tvl})
+	}
+	return points, nil
+}
+
+// Liquidations returns liquidations of the market with their USD value
+func (m *MessariSubgraph) Liquidations(ctx context.Context, from, to time.Time) ([]Liquidation, error) {
+	var liquidations []Liquidation
+	err := paginate(from, to, func(after, to int64) ([]int64, error) {
+		var page struct {
+			Liquidates []messariLiquidation `json:"liquidates"`
+		}
+		if err := m.client.Do(ctx, MessariLiquidationsQuery(m.market, after, to), &page); err != nil {
+			return nil, err
+		}
+		timestamps := make([]int64, len(page.Liquidates))
+		for i, l := range page.Liquidates {
+			ts, err := parseTimestamp(l.Timestamp)
+			if err != nil {
+				return nil, err
+			}
+			amount, err := parseRat(l.AmountUSD)
+			if err != nil {
+				return nil, err
+			}
+			liquidations = append(liquidations, Liquidation{Time: time.Unix(ts, 0).UTC(), TxHash: l.Hash, Amount: amount})
+			timestamps[i] = ts
+		}
+		return timestamps, nil
+	})
+	return liquidations, err
+}
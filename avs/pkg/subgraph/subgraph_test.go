@@ -0,0 +1,177 @@
+package subgraph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// graphqlServer answers every query with the response of handle
+func graphqlServer(t *testing.T, handle func(q Query) string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var q Query
+		if err := json.NewDecoder(r.Body).Decode(&q); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(handle(q)))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func Test_AaveRateHistoryPaginates(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	var pages int
+	server := graphqlServer(t, func(q Query) string {
+		pages++
+		after := int64(q.Variables["after"].(float64))
+		count := pageSize
+		if pages > 1 {
+			count = 1
+		}
+		items := make([]string, count)
+		for i := range items {
+			// 3.84% in ray
+			items[i] = fmt.Sprintf(`{"timestamp":%d,"liquidityRate":"38400000000000000000000000","totalATokenSupply":"100000000000000","reserve":{"decimals":6}}`, after+int64(i)+1)
+		}
+		return `{"data":{"reserveParamsHistoryItems":[` + strings.Join(items, ",") + `]}}`
+	})
+
+	sg := NewAaveSubgraph(NewClient(server.URL, "", 0, nil), "0xreserve")
+	points, err := sg.RateHistory(context.Background(), start, start.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("RateHistory failed: %v", err)
+	}
+	if pages != 2 || len(points) != pageSize+1 {
+		t.Errorf("Expected 2 pages and %d points, got %d pages and %d points", pageSize+1, pages, len(points))
+	}
+	if math.Abs(points[0].SupplyRate-0.0384) > 1e-12 {
+		t.Errorf("Expected a 3.84%% rate, got %f", points[0].SupplyRate)
+	}
+
+	tvl, err := sg.TVLHistory(context.Background(), start, start.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("TVLHistory failed: %v", err)
+	}
+	if got := tvl[0].Amount.FloatString(0); got != "100000000" {
+		t.Errorf("Expected 100M USDC supplied, got %s", got)
+	}
+}
+
+func Test_MessariSubgraph(t *testing.T) {
+	server := graphqlServer(t, func(q Query) string {
+		if strings.Contains(q.Query, "liquidates") {
+			return `{"data":{"liquidates":[{"timestamp":"1700000100","hash":"0xabc","amountUSD":"2500.5"}]}}`
+		}
+		return `{"data":{"marketDailySnapshots":[{"timestamp":"1700000000","totalValueLockedUSD":"50000000.25","rates":[
+			{"rate":"9.1","side":"BORROWER"},
+			{"rate":"4.6028","side":"LENDER"}
+		]}]}}`
+	})
+
+	sg := NewMessariSubgraph(NewClient(server.URL, "", 0, nil), "0xmarket")
+	from, to := time.Unix(1_699_000_000, 0), time.Unix(1_701_000_000, 0)
+
+	rates, err := sg.RateHistory(context.Background(), from, to)
+	if err != nil {
+		t.Fatalf("RateHistory failed: %v", err)
+	}
+	if len(rates) != 1 || math.Abs(rates[0].SupplyRate-0.045) > 1e-5 {
+		t.Errorf("Expected the lender APY to convert to a 4.5%% rate, got %+v", rates)
+	}
+
+	liquidations, err := sg.Liquidations(context.Background(), from, to)
+	if err != nil {
+		t.Fatalf("Liquidations failed: %v", err)
+	}
+	if len(liquidations) != 1 || liquidations[0].TxHash != "0xabc" || liquidations[0].Amount.FloatString(1) != "2500.5" {
+		t.Errorf("Unexpected liquidations: %+v", liquidations)
+	}
+}
+
+func Test_ClientReportsGraphQLErrors(t *testing.T) {
+	server := graphqlServer(t, func(q Query) string {
+		return `{"errors":[{"message":"indexing_error"}]}`
+	})
+
+	var out struct{}
+	err := NewClient(server.URL, "", 0, nil).Do(context.Background(), Query{Query: "{ _meta { block { number } } }"}, &out)
+	var gqlErr *Error
+	if !errors.As(err, &gqlErr) || gqlErr.Messages[0] != "indexing_error" {
+		t.Errorf("Expected a GraphQL error, got %v", err)
+	}
+}
+
+func Test_SetSupplyRate(t *testing.T) {
+	now := time.Now().Unix()
+	server := graphqlServer(t, func(q Query) string {
+		return fmt.Sprintf(`{"data":{"reserveParamsHistoryItems":[
+			{"timestamp":%d,"liquidityRate":"30000000000000000000000000","totalATokenSupply":"0","reserve":{"decimals":6}},
+			{"timestamp":%d,"liquidityRate":"38400000000000000000000000","totalATokenSupply":"0","reserve":{"decimals":6}}
+		]}}`, now-7200, now-60)
+	})
+
+	set, err := NewSet([]EndpointConfig{{Protocol: "AAVE_V3", ChainID: 1, Url: server.URL, Market: "0xreserve"}}, nil)
+	if err != nil {
+		t.Fatalf("NewSet failed: %v", err)
+	}
+	rate, err := set.SupplyRate(context.Background(), "aave_v3", 1)
+	if err != nil {
+		t.Fatalf("SupplyRate failed: %v", err)
+	}
+	if math.Abs(rate-0.0384) > 1e-12 {
+		t.Errorf("Expected the latest rate, got %f", rate)
+	}
+	if _, err := set.SupplyRate(context.Background(), "aave_v3", 8453); !errors.Is(err, ErrNoEndpoint) {
+		t.Errorf("Expected ErrNoEndpoint, got %v", err)
+	}
+	if _, err := NewSet([]EndpointConfig{{Protocol: "euler", ChainID: 1, Url: server.URL, Market: "x"}}, nil); err == nil {
+		t.Errorf("Expected an unsupported protocol to be rejected")
+	}
+}
+
+func Test_PoolSubgraphs(t *testing.T) {
+	server := graphqlServer(t, func(q Query) string {
+		if strings.Contains(q.Query, "poolDayDatas") {
+			return `{"data":{"poolDayDatas":[{"date":1700006400,"volumeUSD":"2000000","feesUSD":"200","tvlUSD":"10000000","high":"1.0004","low":"0.9991"}]}}`
+		}
+		return `{"data":{"liquidityPoolDailySnapshots":[{"timestamp":"1700006400","dailyVolumeUSD":"5000000","dailySupplySideRevenueUSD":"50",
+			"totalValueLockedUSD":"80000000","rewardTokenEmissionsUSD":["400.5","99.5"]}]}}`
+	})
+	from, to := time.Unix(1_699_000_000, 0), time.Unix(1_701_000_000, 0)
+
+	uniswap := NewPoolSubgraph(PoolEndpointConfig{Schema: SchemaUniswap, Url: server.URL, Pool: "0xPool"}, NewClient(server.URL, "", 0, nil))
+	days, err := uniswap.PoolDays(context.Background(), from, to)
+	if err != nil {
+		t.Fatalf("PoolDays failed: %v", err)
+	}
+	if len(days) != 1 || days[0].FeesUSD != 200 || days[0].TVLUSD != 10_000_000 || days[0].PriceLow != 0.9991 || days[0].PriceHigh != 1.0004 {
+		t.Errorf("Unexpected uniswap pool days: %+v", days)
+	}
+
+	curve := NewPoolSubgraph(PoolEndpointConfig{Schema: SchemaMessariDEX, Url: server.URL, Pool: "0xPool"}, NewClient(server.URL, "", 0, nil))
+	days, err = curve.PoolDays(context.Background(), from, to)
+	if err != nil {
+		t.Fatalf("PoolDays failed: %v", err)
+	}
+	if len(days) != 1 || days[0].FeesUSD != 50 || days[0].IncentivesUSD != 500 || days[0].Time.Unix() != 1_700_006_400 {
+		t.Errorf("Unexpected curve pool days: %+v", days)
+	}
+
+	if err := (PoolEndpointConfig{Schema: "balancer", Url: server.URL, Pool: "0xPool"}).Validate(); err == nil {
+		t.Errorf("Expected an unsupported schema to be rejected")
+	}
+}
@@ -0,0 +1,114 @@
+// Package taskapi serves the task interface of a performer over HTTP, so integrators can
+// run read-only tasks against a single operator for previews and dashboards without
+// going through the AVS. It listens on localhost unless configured otherwise, and
+// requires a bearer token when one is set and client certificates when TLS names their
+// authorities.
+package taskapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/najnomics/crosscow-avs/pkg/admin"
+	"github.com/najnomics/crosscow-avs/pkg/transport"
+	"go.uber.org/zap"
+)
+
+// Config configures the task API
+type Config struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Host is the interface the API listens on. Defaults to localhost; serving it to
+	// integrators on other machines requires a token.
+	Host string `yaml:"host"`
+	Port int    `yaml:"port"`
+
+	// Token is the bearer token requests must carry. It is required when Host is not a
+	// loopback address, unless TLS requires client certificates.
+	Token string `yaml:"token"`
+
+	// TLS serves the API over HTTPS, requiring client certificates when it names their
+	// authorities
+	TLS transport.TLSConfig `yaml:"tls"`
+
+	// MaxBodyBytes bounds the size of task payloads
+	MaxBodyBytes int64 `yaml:"maxBodyBytes"`
+}
+
+// DefaultConfig serves the API on localhost:8092 when enabled
+func DefaultConfig() Config {
+	return Config{Host: "127.0.0.1", Port: 8092, MaxBodyBytes: 1 << 20}
+}
+
+// Validate checks the config for values the API cannot be served with
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Port <= 0 || c.Port > 65535 {
+		return fmt.Errorf("port must be between 1 and 65535")
+	}
+	if c.MaxBodyBytes <= 0 {
+		return fmt.Errorf("maxBodyBytes must be positive")
+	}
+	if err := c.TLS.Validate(); err != nil {
+		return fmt.Errorf("tls: %w", err)
+	}
+	if c.Token == "" && !c.TLS.Mutual() && !admin.IsLoopback(c.Host) {
+		return fmt.Errorf("token or tls.clientCAFile is required when host is not a loopback address")
+	}
+	return nil
+}
+
+// Server serves the task API
+type Server struct {
+	httpServer *http.Server
+	logger     *zap.Logger
+}
+
+// NewServer creates a server serving handler on cfg.Host and cfg.Port, behind the token
+// check and body limit of cfg. It fails when the TLS files of cfg cannot be read.
+func NewServer(cfg *Config, handler http.Handler, logger *zap.Logger) (*Server, error) {
+	tlsConfig, err := transport.ServerConfig(cfg.TLS)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{
+		httpServer: &http.Server{
+			Addr:              net.JoinHostPort(cfg.Host, fmt.Sprint(cfg.Port)),
+			Handler:           admin.RequireToken(cfg.Token, http.MaxBytesHandler(handler, cfg.MaxBodyBytes)),
+			TLSConfig:         tlsConfig,
+			ReadHeaderTimeout: 5 * time.Second,
+		},
+		logger: logger,
+	}, nil
+}
+
+// Handler returns the handler of the API, token check and body limit included
+func (s *Server) Handler() http.Handler {
+	return s.httpServer.Handler
+}
+
+// Start serves the API until ctx is cancelled
+func (s *Server) Start(ctx context.Context) {
+	go func() {
+		serve := s.httpServer.ListenAndServe
+		if s.httpServer.TLSConfig != nil {
+			serve = func() error { return s.httpServer.ListenAndServeTLS("", "") }
+		}
+		if err := serve(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Sugar().Errorw("Task API server stopped unexpectedly", "error", err)
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = s.httpServer.Shutdown(shutdownCtx)
+	}()
+}
@@ -0,0 +1,74 @@
+package taskapi
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func Test_ConfigValidate(t *testing.T) {
+	valid := DefaultConfig()
+	valid.Enabled = true
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected the default config to be valid: %v", err)
+	}
+
+	exposed := valid
+	exposed.Host = "0.0.0.0"
+	if err := exposed.Validate(); err == nil {
+		t.Errorf("Expected a token to be required beyond localhost")
+	}
+	exposed.Token = "secret"
+	if err := exposed.Validate(); err != nil {
+		t.Errorf("Expected a token to allow any host: %v", err)
+	}
+
+	unbounded := valid
+	unbounded.MaxBodyBytes = 0
+	if err := unbounded.Validate(); err == nil {
+		t.Errorf("Expected an unbounded body to be rejected")
+	}
+}
+
+func Test_ServerLimitsRequests(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Token, cfg.MaxBodyBytes = "secret", 16
+	server, err := NewServer(&cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+		}
+	}), zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewServer failed: %v", err)
+	}
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	for _, tc := range []struct {
+		name   string
+		header string
+		body   string
+		status int
+	}{
+		{name: "no token", body: "{}", status: http.StatusUnauthorized},
+		{name: "token", header: "Bearer secret", body: "{}", status: http.StatusOK},
+		{name: "large body", header: "Bearer secret", body: strings.Repeat("x", 17), status: http.StatusRequestEntityTooLarge},
+	} {
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+"/v1/tasks", strings.NewReader(tc.body))
+		if tc.header != "" {
+			req.Header.Set("Authorization", tc.header)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: request failed: %v", tc.name, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tc.status {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.status, resp.StatusCode)
+		}
+	}
+}
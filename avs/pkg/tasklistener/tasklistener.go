@@ -0,0 +1,310 @@
+// Package tasklistener follows the tasks created in the Hourglass TaskMailbox for the AVS
+// and warms the result of each as soon as it is created, before the aggregator delivers
+// it, so the delivered task is answered without reading any market. Tasks are followed
+// over a log subscription on chains with a WebSocket endpoint, and by polling logs on the
+// others.
+package tasklistener
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"go.uber.org/zap"
+)
+
+// Config configures following the TaskMailbox
+type Config struct {
+	Enabled bool   `yaml:"enabled"`
+	ChainID uint64 `yaml:"chainId"`
+
+	// TaskMailbox is the address of the Hourglass TaskMailbox tasks are created in, and
+	// AVS the address of the AVS whose tasks are warmed
+	TaskMailbox string `yaml:"taskMailbox"`
+	AVS         string `yaml:"avs"`
+
+	// OperatorSetIDs limits warming to tasks for these executor operator sets. Tasks of
+	// every set of the AVS are warmed when it is empty.
+	OperatorSetIDs []uint32 `yaml:"operatorSetIds"`
+
+	// PollInterval is the time between log queries without a subscription, and
+	// MaxBlockRange bounds the blocks of a single query
+	PollInterval  time.Duration `yaml:"pollInterval"`
+	MaxBlockRange uint64        `yaml:"maxBlockRange"`
+
+	// Concurrency bounds the tasks warmed at once
+	Concurrency int `yaml:"concurrency"`
+}
+
+// DefaultConfig is disabled. Enabled, it polls every Ethereum block time and warms up to
+// four tasks at once.
+func DefaultConfig() Config {
+	return Config{
+		PollInterval:  12 * time.Second,
+		MaxBlockRange: 2_000,
+		Concurrency:   4,
+	}
+}
+
+// Validate checks the config for values the mailbox cannot be followed with
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.ChainID == 0 {
+		return fmt.Errorf("chainId is required")
+	}
+	if !common.IsHexAddress(c.TaskMailbox) {
+		return fmt.Errorf("invalid taskMailbox address %q", c.TaskMailbox)
+	}
+	if !common.IsHexAddress(c.AVS) {
+		return fmt.Errorf("invalid avs address %q", c.AVS)
+	}
+	if c.PollInterval <= 0 {
+		return fmt.Errorf("pollInterval must be positive")
+	}
+	if c.MaxBlockRange == 0 {
+		return fmt.Errorf("maxBlockRange must be positive")
+	}
+	if c.Concurrency <= 0 {
+		return fmt.Errorf("concurrency must be positive")
+	}
+	return nil
+}
+
+// mailboxABI holds the TaskCreated event of the TaskMailbox
+var mailboxABI = chain.MustParseABI(`[
+	{"name":"TaskCreated","type":"event","anonymous":false,"inputs":[
+		{"name":"creator","type":"address","indexed":true},{"name":"taskHash","type":"bytes32","indexed":true},
+		{"name":"avs","type":"address","indexed":true},{"name":"executorOperatorSetId","type":"uint32","indexed":false},
+		{"name":"refundCollector","type":"address","indexed":false},{"name":"avsFee","type":"uint96","indexed":false},
+		{"name":"taskDeadline","type":"uint256","indexed":false},{"name":"payload","type":"bytes","indexed":false}]}
+]`)
+
+// Task is a task created in the mailbox
+type Task struct {
+	Hash          common.Hash
+	Creator       common.Address
+	AVS           common.Address
+	OperatorSetID uint32
+
+	// Deadline is the task SLA in seconds from creation, as the mailbox emits it
+	Deadline    uint64
+	Payload     []byte
+	BlockNumber uint64
+}
+
+// ID is the task ID Hourglass delivers the task with, the hex of its hash
+func (t *Task) ID() []byte {
+	return []byte(t.Hash.Hex())
+}
+
+// ParseTaskCreated decodes a TaskCreated log
+func ParseTaskCreated(log types.Log) (*Task, error) {
+	if len(log.Topics) != 4 || log.Topics[0] != mailboxABI.Events["TaskCreated"].ID {
+		return nil, fmt.Errorf("not a TaskCreated log in tx %s", log.TxHash.Hex())
+	}
+	var out struct {
+		ExecutorOperatorSetId uint32
+		RefundCollector       common.Address
+		AvsFee                *big.Int
+		TaskDeadline          *big.Int
+		Payload               []byte
+	}
+	if err := mailboxABI.UnpackIntoInterface(&out, "TaskCreated", log.Data); err != nil {
+		return nil, fmt.Errorf("failed to unpack TaskCreated: %w", err)
+	}
+	return &Task{
+		Hash:          log.Topics[2],
+		Creator:       common.BytesToAddress(log.Topics[1].Bytes()),
+		AVS:           common.BytesToAddress(log.Topics[3].Bytes()),
+		OperatorSetID: out.ExecutorOperatorSetId,
+		Deadline:      out.TaskDeadline.Uint64(),
+		Payload:       out.Payload,
+		BlockNumber:   log.BlockNumber,
+	}, nil
+}
+
+// Warmer computes the result of a task ahead of its delivery, reporting whether it did,
+// as performer.YieldIntelligencePerformer does for the task types it caches
+type Warmer interface {
+	Prewarm(ctx context.Context, taskID, payload []byte) (bool, error)
+}
+
+// Listener warms the tasks created for the AVS
+type Listener struct {
+	cfg     Config
+	chains  *chain.Manager
+	warmer  Warmer
+	logger  *zap.Logger
+	mailbox common.Address
+	avs     common.Address
+	sets    map[uint32]bool
+	slots   chan struct{}
+
+	mu      sync.Mutex
+	cursor  uint64
+	scanned bool
+
+	// running tracks the tasks being warmed, see Wait
+	running sync.WaitGroup
+}
+
+// New creates a listener warming the tasks of the mailbox of cfg with warmer
+func New(cfg Config, chains *chain.Manager, warmer Warmer, logger *zap.Logger) *Listener {
+	sets := make(map[uint32]bool, len(cfg.OperatorSetIDs))
+	for _, id := range cfg.OperatorSetIDs {
+		sets[id] = true
+	}
+	concurrency := cfg.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	return &Listener{
+		cfg:     cfg,
+		chains:  chains,
+		warmer:  warmer,
+		logger:  logger,
+		mailbox: common.HexToAddress(cfg.TaskMailbox),
+		avs:     common.HexToAddress(cfg.AVS),
+		sets:    sets,
+		slots:   make(chan struct{}, concurrency),
+	}
+}
+
+// query matches the TaskCreated logs of the AVS
+func (l *Listener) query() ethereum.FilterQuery {
+	return ethereum.FilterQuery{
+		Addresses: []common.Address{l.mailbox},
+		Topics:    [][]common.Hash{{mailboxABI.Events["TaskCreated"].ID}, nil, nil, {common.BytesToHash(l.avs.Bytes())}},
+	}
+}
+
+// Start follows the mailbox until ctx is cancelled, over a log subscription when the
+// chain has a WebSocket endpoint and by polling every poll interval otherwise
+func (l *Listener) Start(ctx context.Context) {
+	logs, err := l.chains.SubscribeLogs(ctx, l.cfg.ChainID, l.query())
+	if err == nil {
+		go func() {
+			for log := range logs {
+				if log.Removed {
+					continue
+				}
+				l.handle(ctx, log)
+			}
+		}()
+		return
+	}
+	l.logger.Sugar().Debugw("Polling the task mailbox without a log subscription", "chainId", l.cfg.ChainID, "error", err)
+
+	go func() {
+		ticker := time.NewTicker(l.cfg.PollInterval)
+		defer ticker.Stop()
+		for {
+			if _, err := l.Poll(ctx); err != nil {
+				l.logger.Sugar().Warnw("Failed to poll the task mailbox", "error", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Wait blocks until the tasks being warmed are done
+func (l *Listener) Wait() {
+	l.running.Wait()
+}
+
+// Poll scans the blocks produced since the previous poll for created tasks and starts
+// warming them. The first poll only marks the head, since tasks created before it were
+// delivered already. It returns the number of tasks found.
+func (l *Listener) Poll(ctx context.Context) (int, error) {
+	client, err := l.chains.Client(l.cfg.ChainID)
+	if err != nil {
+		return 0, err
+	}
+	head, err := client.BlockNumber(ctx)
+	if err != nil {
+		return 0, err
+	}
+	l.mu.Lock()
+	cursor, scanned := l.cursor, l.scanned
+	l.mu.Unlock()
+	if !scanned || head <= cursor {
+		l.advance(head)
+		return 0, nil
+	}
+	to := head
+	if to-cursor > l.cfg.MaxBlockRange {
+		to = cursor + l.cfg.MaxBlockRange
+	}
+	q := l.query()
+	q.FromBlock, q.ToBlock = new(big.Int).SetUint64(cursor+1), new(big.Int).SetUint64(to)
+	logs, err := client.FilterLogs(ctx, q)
+	if err != nil {
+		// the cursor stays, so the blocks are scanned again on the next poll
+		return 0, fmt.Errorf("failed to filter logs: %w", err)
+	}
+	l.advance(to)
+
+	found := 0
+	for _, log := range logs {
+		if !log.Removed && l.handle(ctx, log) {
+			found++
+		}
+	}
+	return found, nil
+}
+
+func (l *Listener) advance(block uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.scanned || block > l.cursor {
+		l.cursor, l.scanned = block, true
+	}
+}
+
+// handle starts warming the task of log, reporting whether it is a task of the AVS
+func (l *Listener) handle(ctx context.Context, log types.Log) bool {
+	task, err := ParseTaskCreated(log)
+	if err != nil {
+		l.logger.Sugar().Warnw("Skipping malformed task", "error", err)
+		return false
+	}
+	if task.AVS != l.avs || (len(l.sets) > 0 && !l.sets[task.OperatorSetID]) {
+		return false
+	}
+	select {
+	case l.slots <- struct{}{}:
+	case <-ctx.Done():
+		return false
+	}
+	l.running.Add(1)
+	go func() {
+		defer l.running.Done()
+		defer func() { <-l.slots }()
+		l.warm(ctx, task)
+	}()
+	return true
+}
+
+func (l *Listener) warm(ctx context.Context, task *Task) {
+	started := time.Now()
+	warmed, err := l.warmer.Prewarm(ctx, task.ID(), task.Payload)
+	if err != nil {
+		l.logger.Sugar().Debugw("Task not warmed", "task_id", task.Hash.Hex(), "error", err)
+		return
+	}
+	if warmed {
+		l.logger.Sugar().Debugw("Warmed task", "task_id", task.Hash.Hex(), "block", task.BlockNumber, "duration", time.Since(started))
+	}
+}
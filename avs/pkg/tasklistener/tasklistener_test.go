@@ -0,0 +1,117 @@
+package tasklistener
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/chain/chaintest"
+	"go.uber.org/zap"
+)
+
+var (
+	mailboxAddress = common.HexToAddress("0x00000000000000000000000000000000000ba11")
+	avsAddress     = common.HexToAddress("0x00000000000000000000000000000000000000a5")
+)
+
+type recordingWarmer struct {
+	mu     sync.Mutex
+	warmed map[string][]byte
+}
+
+func (r *recordingWarmer) Prewarm(ctx context.Context, taskID, payload []byte) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.warmed[string(taskID)] = payload
+	return true, nil
+}
+
+func taskCreated(t *testing.T, block uint64, hash common.Hash, avs common.Address, operatorSetID uint32, payload string) types.Log {
+	t.Helper()
+	event := mailboxABI.Events["TaskCreated"]
+	data, err := event.Inputs.NonIndexed().Pack(operatorSetID, common.Address{}, new(big.Int), big.NewInt(60), []byte(payload))
+	if err != nil {
+		t.Fatalf("Failed to pack TaskCreated: %v", err)
+	}
+	return types.Log{
+		Address:     mailboxAddress,
+		Topics:      []common.Hash{event.ID, common.BytesToHash(common.Address{9}.Bytes()), hash, common.BytesToHash(avs.Bytes())},
+		Data:        data,
+		BlockNumber: block,
+	}
+}
+
+func Test_ParseTaskCreated(t *testing.T) {
+	hash := common.HexToHash("0x01")
+	task, err := ParseTaskCreated(taskCreated(t, 7, hash, avsAddress, 2, `{"type":"yield_monitoring"}`))
+	if err != nil {
+		t.Fatalf("ParseTaskCreated failed: %v", err)
+	}
+	if task.Hash != hash || task.AVS != avsAddress || task.OperatorSetID != 2 || task.Deadline != 60 || task.BlockNumber != 7 ||
+		string(task.Payload) != `{"type":"yield_monitoring"}` || string(task.ID()) != hash.Hex() {
+		t.Errorf("Unexpected task %+v", task)
+	}
+	if _, err := ParseTaskCreated(types.Log{Topics: []common.Hash{{1}}}); err == nil {
+		t.Errorf("Expected other logs to be rejected")
+	}
+}
+
+func Test_PollWarmsCreatedTasks(t *testing.T) {
+	contracts := chaintest.NewContracts(1)
+	chains := chain.NewManager()
+	chains.Register(1, "ethereum", contracts)
+	warmer := &recordingWarmer{warmed: make(map[string][]byte)}
+	cfg := DefaultConfig()
+	cfg.Enabled, cfg.ChainID, cfg.TaskMailbox, cfg.AVS, cfg.OperatorSetIDs = true, 1, mailboxAddress.Hex(), avsAddress.Hex(), []uint32{1}
+	l := New(cfg, chains, warmer, zap.NewNop())
+
+	// tasks created before the listener started were delivered already
+	contracts.AddLog(taskCreated(t, 4, common.HexToHash("0x04"), avsAddress, 1, "old"))
+	contracts.SetHead(5, 0)
+	if found, err := l.Poll(context.Background()); err != nil || found != 0 {
+		t.Fatalf("Expected the first poll to mark the head, got %d: %v", found, err)
+	}
+
+	contracts.AddLog(taskCreated(t, 6, common.HexToHash("0x06"), avsAddress, 1, "task"))
+	contracts.AddLog(taskCreated(t, 6, common.HexToHash("0x07"), common.Address{1}, 1, "other avs"))
+	contracts.AddLog(taskCreated(t, 7, common.HexToHash("0x08"), avsAddress, 2, "other set"))
+	contracts.SetHead(7, 0)
+	found, err := l.Poll(context.Background())
+	if err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	l.Wait()
+	if found != 1 || len(warmer.warmed) != 1 || string(warmer.warmed[common.HexToHash("0x06").Hex()]) != "task" {
+		t.Errorf("Expected the task of the operator set to be warmed, got %d: %v", found, warmer.warmed)
+	}
+
+	// blocks are scanned once
+	if found, err := l.Poll(context.Background()); err != nil || found != 0 {
+		t.Errorf("Expected no task in a second poll of the same head, got %d: %v", found, err)
+	}
+}
+
+func Test_Validate(t *testing.T) {
+	valid := DefaultConfig()
+	valid.Enabled, valid.ChainID, valid.TaskMailbox, valid.AVS = true, 1, mailboxAddress.Hex(), avsAddress.Hex()
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Expected the config to be valid: %v", err)
+	}
+	for name, mutate := range map[string]func(*Config){
+		"chain":       func(c *Config) { c.ChainID = 0 },
+		"mailbox":     func(c *Config) { c.TaskMailbox = "mailbox" },
+		"avs":         func(c *Config) { c.AVS = "" },
+		"poll":        func(c *Config) { c.PollInterval = 0 },
+		"concurrency": func(c *Config) { c.Concurrency = 0 },
+	} {
+		cfg := valid
+		mutate(&cfg)
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("%s: expected the config to be rejected", name)
+		}
+	}
+}
@@ -0,0 +1,447 @@
+// Package taskqueue bounds how many tasks of each type the performer runs at once,
+// holding the tasks over the bound in a queue or rejecting them. Without it, tasks run
+// as concurrently as the gRPC server accepts them, so a burst of monitoring tasks
+// competes with rebalances for RPC providers and executions of one operator race each
+// other for nonces.
+//
+// Queued tasks run by priority, then in arrival order. Under load, when the tasks of
+// every type together fill the queue, the slot a finishing task frees goes to the
+// waiting task of the highest priority, so executions and depeg checks run ahead of
+// routine yield polling that arrived before them. Running tasks are never interrupted.
+package taskqueue
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// ErrQueueFull is returned for tasks arriving while they cannot run and the queue of
+	// their type is full, or rejects tasks that cannot run
+	ErrQueueFull = errors.New("task queue full")
+
+	// ErrQueueTimeout is returned for tasks that waited in the queue longer than the
+	// queue timeout
+	ErrQueueTimeout = errors.New("timed out waiting in the task queue")
+)
+
+// Overflow is what happens to a task arriving while it cannot run
+type Overflow string
+
+const (
+	// OverflowQueue holds the task until a slot frees, rejecting it only when the queue
+	// of its type is full
+	OverflowQueue Overflow = "queue"
+
+	// OverflowReject rejects the task, so its submitter can retry later
+	OverflowReject Overflow = "reject"
+)
+
+// Lane bounds the tasks of one type
+type Lane struct {
+	// MaxConcurrent is the number of tasks running at the same time
+	MaxConcurrent int `yaml:"maxConcurrent"`
+
+	// MaxQueued is the number of tasks waiting for a slot
+	MaxQueued int `yaml:"maxQueued"`
+
+	// Overflow is queue, the default, or reject
+	Overflow Overflow `yaml:"overflow"`
+}
+
+func (l Lane) validate() error {
+	if l.MaxConcurrent < 1 {
+		return fmt.Errorf("maxConcurrent must be at least 1")
+	}
+	if l.MaxQueued < 0 {
+		return fmt.Errorf("maxQueued must not be negative")
+	}
+	switch l.Overflow {
+	case "", OverflowQueue, OverflowReject:
+	default:
+		return fmt.Errorf("unknown overflow: %s", l.Overflow)
+	}
+	return nil
+}
+
+// Config configures the task queue. Every task type has a lane of its own, set in Types
+// or copied from Default.
+type Config struct {
+	Enabled bool            `yaml:"enabled"`
+	Default Lane            `yaml:"default"`
+	Types   map[string]Lane `yaml:"types"`
+
+	// MaxRunning is the number of tasks of every type together running at the same time,
+	// unbounded when zero
+	MaxRunning int `yaml:"maxRunning"`
+
+	// Priorities ranks task types competing for a slot, the highest first. Types that
+	// are not listed have priority zero.
+	Priorities map[string]int `yaml:"priorities"`
+
+	// QueueTimeout bounds how long a task waits in the queue, unbounded when zero. Tasks
+	// also stop waiting when their submitter gives up on them.
+	QueueTimeout time.Duration `yaml:"queueTimeout"`
+}
+
+// DefaultConfig runs up to 32 tasks at once, the tasks moving funds one at a time, and
+// queues the tasks over the bound. Executions and then depeg checks run ahead of the
+// other tasks waiting.
+func DefaultConfig() Config {
+	serialized := Lane{MaxConcurrent: 1, MaxQueued: 8, Overflow: OverflowQueue}
+	return Config{
+		Enabled: true,
+		Default: Lane{MaxConcurrent: 32, MaxQueued: 256, Overflow: OverflowQueue},
+		Types: map[string]Lane{
+			"rebalance_execution": serialized,
+			"rebalance_commit":    serialized,
+			"resume_execution":    serialized,
+			"transfer_recovery":   serialized,
+		},
+		MaxRunning: 32,
+		Priorities: map[string]int{
+			"rebalance_execution": 20,
+			"rebalance_commit":    20,
+			"resume_execution":    20,
+			"transfer_recovery":   20,
+			"depeg_monitoring":    10,
+		},
+		QueueTimeout: 10 * time.Second,
+	}
+}
+
+// Validate checks the config for values the queue cannot run with
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if err := c.Default.validate(); err != nil {
+		return fmt.Errorf("default: %w", err)
+	}
+	for taskType, lane := range c.Types {
+		if err := lane.validate(); err != nil {
+			return fmt.Errorf("types.%s: %w", taskType, err)
+		}
+	}
+	if c.MaxRunning < 0 {
+		return fmt.Errorf("maxRunning must not be negative")
+	}
+	if c.QueueTimeout < 0 {
+		return fmt.Errorf("queueTimeout must not be negative")
+	}
+	return nil
+}
+
+// lane returns the bounds of taskType
+func (c Config) lane(taskType string) Lane {
+	if l, ok := c.Types[taskType]; ok {
+		return l
+	}
+	return c.Default
+}
+
+// waiter is a task in the queue. granted is set, under the lock of the queue, when a
+// finishing task hands its slot to the waiter.
+type waiter struct {
+	lane     *lane
+	priority int
+	ready    chan struct{}
+	granted  bool
+}
+
+// lane is the state of the tasks of one type
+type lane struct {
+	taskType string
+	cfg      Lane
+	running  int
+	queued   int
+}
+
+// Queue runs the tasks of each type within the bounds of its lane. A nil Queue runs
+// every task at once.
+type Queue struct {
+	mu    sync.Mutex
+	cfg   Config
+	lanes map[string]*lane
+
+	// running counts the tasks of every type, and waiting holds the queued ones by
+	// priority, then arrival. No waiting task can run: slots go to waiting tasks as soon
+	// as they free.
+	running int
+	waiting *list.List
+
+	metrics *metrics
+}
+
+// metrics are the collectors of a queue, labelled by task type
+type metrics struct {
+	running     *prometheus.GaugeVec
+	queued      *prometheus.GaugeVec
+	rejected    *prometheus.CounterVec
+	prioritized *prometheus.CounterVec
+	wait        *prometheus.HistogramVec
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		running: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "yieldavs_task_queue_running",
+			Help: "Tasks running by task type.",
+		}, []string{"task_type"}),
+		queued: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "yieldavs_task_queue_queued",
+			Help: "Tasks waiting in the task queue by task type and priority.",
+		}, []string{"task_type", "priority"}),
+		rejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "yieldavs_task_queue_rejected_total",
+			Help: "Tasks rejected by the task queue by task type and reason (full or timeout).",
+		}, []string{"task_type", "reason"}),
+		prioritized: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "yieldavs_task_queue_prioritized_total",
+			Help: "Tasks queued ahead of tasks of a lower priority that arrived before them, by task type.",
+		}, []string{"task_type"}),
+		wait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "yieldavs_task_queue_wait_seconds",
+			Help:    "Time tasks waited in the task queue before running, by task type and priority.",
+			Buckets: []float64{.005, .025, .1, .5, 1, 2.5, 5, 10, 30},
+		}, []string{"task_type", "priority"}),
+	}
+}
+
+// New creates a queue bounding tasks as cfg does. The running and queued tasks, the
+// rejected and prioritized ones and the time tasks waited are exported as the
+// yieldavs_task_queue_* metrics, registered with registerer when it is not nil.
+func New(cfg Config, registerer prometheus.Registerer) (*Queue, error) {
+	q := &Queue{cfg: cfg, lanes: make(map[string]*lane), waiting: list.New(), metrics: newMetrics()}
+	if registerer != nil {
+		for _, c := range []prometheus.Collector{q.metrics.running, q.metrics.queued, q.metrics.rejected, q.metrics.prioritized, q.metrics.wait} {
+			if err := registerer.Register(c); err != nil {
+				return nil, fmt.Errorf("failed to register task queue metrics: %w", err)
+			}
+		}
+	}
+	return q, nil
+}
+
+// NewFromConfig creates the queue enabled in cfg, nil when it is disabled
+func NewFromConfig(cfg Config, registerer prometheus.Registerer) (*Queue, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	return New(cfg, registerer)
+}
+
+// SetConfig replaces the lanes, the priorities and the bounds of the queue. Types whose
+// lane did not change keep their running and queued tasks. Tasks running or queued
+// under a changed lane finish under it and are not counted against the new one. Queued
+// tasks keep the priority they arrived with.
+func (q *Queue) SetConfig(cfg Config) {
+	if q == nil {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.cfg = cfg
+	for taskType, l := range q.lanes {
+		if l.cfg != cfg.lane(taskType) {
+			delete(q.lanes, taskType)
+		}
+	}
+	q.dispatch()
+}
+
+// lane returns the state of taskType, creating it on first use. Lanes are dropped once
+// idle, so tasks of unknown types do not accumulate lanes. It must be called with the
+// lock held.
+func (q *Queue) lane(taskType string) *lane {
+	l, ok := q.lanes[taskType]
+	if !ok {
+		l = &lane{taskType: taskType, cfg: q.cfg.lane(taskType)}
+		q.lanes[taskType] = l
+	}
+	return l
+}
+
+// dropIfIdle forgets l when no task of it runs or waits. It must be called with the
+// lock held.
+func (q *Queue) dropIfIdle(l *lane) {
+	if l.running == 0 && l.queued == 0 && q.lanes[l.taskType] == l {
+		delete(q.lanes, l.taskType)
+	}
+}
+
+// canRun reports whether a task of l may start now. It must be called with the lock
+// held.
+func (q *Queue) canRun(l *lane) bool {
+	return l.running < l.cfg.MaxConcurrent && (q.cfg.MaxRunning == 0 || q.running < q.cfg.MaxRunning)
+}
+
+// full reports whether a task arriving at l now would be rejected. It must be called
+// with the lock held.
+func (q *Queue) full(l *lane) bool {
+	if q.canRun(l) {
+		return false
+	}
+	return l.cfg.Overflow == OverflowReject || l.queued >= l.cfg.MaxQueued
+}
+
+// start counts a task of l as running. It must be called with the lock held.
+func (q *Queue) start(l *lane) {
+	l.running++
+	q.running++
+}
+
+// dispatch starts the waiting tasks that can run, by priority. A task whose lane is
+// full does not hold back the tasks of other lanes behind it. It must be called with the
+// lock held.
+func (q *Queue) dispatch() {
+	for e := q.waiting.Front(); e != nil && (q.cfg.MaxRunning == 0 || q.running < q.cfg.MaxRunning); {
+		next := e.Next()
+		if w := e.Value.(*waiter); q.canRun(w.lane) {
+			q.waiting.Remove(e)
+			w.lane.queued--
+			q.start(w.lane)
+			w.granted = true
+			close(w.ready)
+		}
+		e = next
+	}
+}
+
+// enqueue adds w behind the waiting tasks of its priority or a higher one, and reports
+// whether it went ahead of tasks of a lower priority. It must be called with the lock
+// held.
+func (q *Queue) enqueue(w *waiter) (*list.Element, bool) {
+	w.lane.queued++
+	for e := q.waiting.Back(); e != nil; e = e.Prev() {
+		if e.Value.(*waiter).priority >= w.priority {
+			element := q.waiting.InsertAfter(w, e)
+			return element, element.Next() != nil
+		}
+	}
+	element := q.waiting.PushFront(w)
+	return element, element.Next() != nil
+}
+
+// Check reports whether a task of taskType would run or be queued now, without taking
+// a slot. Tasks are checked when validated and acquire their slot when handled.
+func (q *Queue) Check(taskType string) error {
+	if q == nil {
+		return nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	l, ok := q.lanes[taskType]
+	if !ok {
+		l = &lane{taskType: taskType, cfg: q.cfg.lane(taskType)}
+	}
+	if q.full(l) {
+		return fullError(taskType, l.cfg)
+	}
+	return nil
+}
+
+// Acquire takes a slot for a task of taskType, waiting in the queue while its lane or
+// the queue as a whole runs as many tasks as it may, and returns the function that
+// frees the slot once the task finished. Tasks arriving at a full queue are rejected
+// with ErrQueueFull, and tasks waiting longer than the queue timeout with
+// ErrQueueTimeout. Cancelling ctx stops the wait with the error of ctx.
+func (q *Queue) Acquire(ctx context.Context, taskType string) (func(), error) {
+	if q == nil {
+		return func() {}, nil
+	}
+	q.mu.Lock()
+	l := q.lane(taskType)
+	priority := strconv.Itoa(q.cfg.Priorities[taskType])
+	if q.canRun(l) {
+		q.start(l)
+		q.mu.Unlock()
+		q.metrics.running.WithLabelValues(taskType).Inc()
+		q.metrics.wait.WithLabelValues(taskType, priority).Observe(0)
+		return q.releaser(l), nil
+	}
+	if q.full(l) {
+		q.mu.Unlock()
+		q.metrics.rejected.WithLabelValues(taskType, "full").Inc()
+		return nil, fullError(taskType, l.cfg)
+	}
+	w := &waiter{lane: l, priority: q.cfg.Priorities[taskType], ready: make(chan struct{})}
+	element, prioritized := q.enqueue(w)
+	timeout := q.cfg.QueueTimeout
+	q.mu.Unlock()
+	q.metrics.queued.WithLabelValues(taskType, priority).Inc()
+	defer q.metrics.queued.WithLabelValues(taskType, priority).Dec()
+	if prioritized {
+		q.metrics.prioritized.WithLabelValues(taskType).Inc()
+	}
+
+	started := time.Now()
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	var err error
+	select {
+	case <-w.ready:
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-expired:
+		err = fmt.Errorf("%w after %v", ErrQueueTimeout, timeout)
+	}
+
+	if err != nil {
+		q.mu.Lock()
+		granted := w.granted
+		if !granted {
+			q.waiting.Remove(element)
+			l.queued--
+			q.dropIfIdle(l)
+		}
+		q.mu.Unlock()
+		if granted {
+			// the slot was handed over as the wait ended, and goes to the next task
+			q.metrics.running.WithLabelValues(taskType).Inc()
+			q.releaser(l)()
+		}
+		if errors.Is(err, ErrQueueTimeout) {
+			q.metrics.rejected.WithLabelValues(taskType, "timeout").Inc()
+		}
+		return nil, err
+	}
+	q.metrics.running.WithLabelValues(taskType).Inc()
+	q.metrics.wait.WithLabelValues(taskType, priority).Observe(time.Since(started).Seconds())
+	return q.releaser(l), nil
+}
+
+// releaser returns the function freeing a slot of l, which goes to the waiting task of
+// the highest priority that can run
+func (q *Queue) releaser(l *lane) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			q.metrics.running.WithLabelValues(l.taskType).Dec()
+			q.mu.Lock()
+			defer q.mu.Unlock()
+			l.running--
+			q.running--
+			q.dispatch()
+			q.dropIfIdle(l)
+		})
+	}
+}
+
+func fullError(taskType string, l Lane) error {
+	if l.Overflow == OverflowReject {
+		return fmt.Errorf("%w: %s tasks cannot run now and are not queued", ErrQueueFull, taskType)
+	}
+	return fmt.Errorf("%w: %d %s tasks are waiting already", ErrQueueFull, l.MaxQueued, taskType)
+}
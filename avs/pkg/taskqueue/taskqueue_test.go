@@ -0,0 +1,221 @@
+package taskqueue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func Test_TasksOverTheBoundAreQueuedInOrder(t *testing.T) {
+	q, err := New(Config{Enabled: true, Default: Lane{MaxConcurrent: 1, MaxQueued: 2}}, nil)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	release, err := q.Acquire(context.Background(), "rebalance_execution")
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+
+	order := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		go func(i int) {
+			next, err := q.Acquire(context.Background(), "rebalance_execution")
+			if err != nil {
+				t.Errorf("Expected task %d to be queued: %v", i, err)
+				return
+			}
+			order <- i
+			next()
+		}(i)
+		waitFor(t, func() bool {
+			return testutil.ToFloat64(q.metrics.queued.WithLabelValues("rebalance_execution", "0")) == float64(i+1)
+		})
+	}
+
+	if err := q.Check("rebalance_execution"); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Expected the full queue to be reported, got %v", err)
+	}
+	if _, err := q.Acquire(context.Background(), "rebalance_execution"); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Expected a task arriving at a full queue to be rejected, got %v", err)
+	}
+	if release, err := q.Acquire(context.Background(), "yield_monitoring"); err != nil {
+		t.Errorf("Expected other types to run in a lane of their own: %v", err)
+	} else {
+		release()
+	}
+
+	release()
+	if first, second := <-order, <-order; first != 0 || second != 1 {
+		t.Errorf("Expected the queued tasks to run in arrival order, got %d then %d", first, second)
+	}
+	if got := testutil.ToFloat64(q.metrics.rejected.WithLabelValues("rebalance_execution", "full")); got != 1 {
+		t.Errorf("Expected one rejection to be counted, got %v", got)
+	}
+	waitFor(t, func() bool { return testutil.ToFloat64(q.metrics.running.WithLabelValues("rebalance_execution")) == 0 })
+}
+
+func Test_RejectOverflow(t *testing.T) {
+	q, _ := New(Config{Enabled: true, Default: Lane{MaxConcurrent: 1}, Types: map[string]Lane{
+		"yield_monitoring": {MaxConcurrent: 2, MaxQueued: 10, Overflow: OverflowReject},
+	}}, nil)
+	for i := 0; i < 2; i++ {
+		if _, err := q.Acquire(context.Background(), "yield_monitoring"); err != nil {
+			t.Fatalf("Expected task %d to run: %v", i, err)
+		}
+	}
+	if _, err := q.Acquire(context.Background(), "yield_monitoring"); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Expected the task over the bound to be rejected rather than queued, got %v", err)
+	}
+
+	var disabled *Queue
+	if _, err := disabled.Acquire(context.Background(), "yield_monitoring"); err != nil {
+		t.Errorf("Expected a nil queue to run every task: %v", err)
+	}
+}
+
+func Test_PriorityTasksRunFirstUnderLoad(t *testing.T) {
+	q, _ := New(Config{
+		Enabled:    true,
+		Default:    Lane{MaxConcurrent: 4, MaxQueued: 4},
+		Types:      map[string]Lane{"rebalance_execution": {MaxConcurrent: 1, MaxQueued: 4}},
+		MaxRunning: 2,
+		Priorities: map[string]int{"rebalance_execution": 20, "depeg_monitoring": 10},
+	}, nil)
+	first, _ := q.Acquire(context.Background(), "yield_monitoring")
+	second, _ := q.Acquire(context.Background(), "rebalance_execution")
+
+	// polling arrives first, then a depeg check and two executions, the second of which
+	// waits for the first to finish
+	started := make(chan string, 4)
+	queue := func(taskType string, queued int) {
+		go func() {
+			release, err := q.Acquire(context.Background(), taskType)
+			if err != nil {
+				t.Errorf("Expected the %s task to be queued: %v", taskType, err)
+				return
+			}
+			started <- taskType
+			if taskType == "rebalance_execution" {
+				release()
+			}
+		}()
+		waitFor(t, func() bool {
+			q.mu.Lock()
+			defer q.mu.Unlock()
+			return q.waiting.Len() == queued
+		})
+	}
+	queue("yield_monitoring", 1)
+	queue("depeg_monitoring", 2)
+	queue("rebalance_execution", 3)
+
+	// the running execution holds its lane, so the freed slot goes to the depeg check
+	first()
+	if got := <-started; got != "depeg_monitoring" {
+		t.Fatalf("Expected the depeg check to run ahead of polling, got %s", got)
+	}
+	second()
+	if got := <-started; got != "rebalance_execution" {
+		t.Fatalf("Expected the queued execution to run next, got %s", got)
+	}
+	if got := <-started; got != "yield_monitoring" {
+		t.Fatalf("Expected polling to run last, got %s", got)
+	}
+	if got := testutil.ToFloat64(q.metrics.prioritized.WithLabelValues("depeg_monitoring")); got != 1 {
+		t.Errorf("Expected the depeg check to be counted as prioritized, got %v", got)
+	}
+	if got := testutil.ToFloat64(q.metrics.prioritized.WithLabelValues("yield_monitoring")); got != 0 {
+		t.Errorf("Expected polling not to be counted as prioritized, got %v", got)
+	}
+}
+
+func Test_WaitEndsOnTimeoutOrCancellation(t *testing.T) {
+	q, _ := New(Config{Enabled: true, Default: Lane{MaxConcurrent: 1, MaxQueued: 4}, QueueTimeout: 20 * time.Millisecond}, nil)
+	release, _ := q.Acquire(context.Background(), "rebalance_commit")
+
+	if _, err := q.Acquire(context.Background(), "rebalance_commit"); !errors.Is(err, ErrQueueTimeout) {
+		t.Errorf("Expected the wait to time out, got %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := q.Acquire(ctx, "rebalance_commit"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the wait to end with its context, got %v", err)
+	}
+	if got := testutil.ToFloat64(q.metrics.queued.WithLabelValues("rebalance_commit", "0")); got != 0 {
+		t.Errorf("Expected tasks that stopped waiting to leave the queue, got %v queued", got)
+	}
+
+	release()
+	next, err := q.Acquire(context.Background(), "rebalance_commit")
+	if err != nil {
+		t.Fatalf("Expected the freed slot to be taken: %v", err)
+	}
+	next()
+}
+
+func Test_SetConfig(t *testing.T) {
+	q, _ := New(Config{Enabled: true, Default: Lane{MaxConcurrent: 1}, Types: map[string]Lane{"batch": {MaxConcurrent: 1}}}, nil)
+	if _, err := q.Acquire(context.Background(), "yield_monitoring"); err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	if _, err := q.Acquire(context.Background(), "batch"); err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+
+	q.SetConfig(Config{Enabled: true, Default: Lane{MaxConcurrent: 1}, Types: map[string]Lane{"batch": {MaxConcurrent: 2}}})
+	if err := q.Check("yield_monitoring"); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Expected an unchanged lane to keep counting running tasks, got %v", err)
+	}
+	if _, err := q.Acquire(context.Background(), "batch"); err != nil {
+		t.Errorf("Expected the new lane to start empty: %v", err)
+	}
+}
+
+func Test_MetricsAreRegistered(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	q, err := New(DefaultConfig(), registry)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	release, _ := q.Acquire(context.Background(), "yield_monitoring")
+	if got := testutil.ToFloat64(q.metrics.running.WithLabelValues("yield_monitoring")); got != 1 {
+		t.Errorf("Expected one running task, got %v", got)
+	}
+	release()
+	if _, err := New(DefaultConfig(), registry); err == nil {
+		t.Errorf("Expected registering the metrics twice to fail")
+	}
+}
+
+func Test_ConfigValidate(t *testing.T) {
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Fatalf("Expected the default config to be valid: %v", err)
+	}
+	for name, cfg := range map[string]Config{
+		"concurrency": {Enabled: true, Default: Lane{}},
+		"queued":      {Enabled: true, Default: Lane{MaxConcurrent: 1, MaxQueued: -1}},
+		"overflow":    {Enabled: true, Default: Lane{MaxConcurrent: 1}, Types: map[string]Lane{"batch": {MaxConcurrent: 1, Overflow: "drop"}}},
+		"timeout":     {Enabled: true, Default: Lane{MaxConcurrent: 1}, QueueTimeout: -time.Second},
+		"running":     {Enabled: true, Default: Lane{MaxConcurrent: 1}, MaxRunning: -1},
+	} {
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("%s: expected the config to be rejected", name)
+		}
+	}
+}
+
+// waitFor polls cond until it holds, failing the test after a second
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,344 @@
+// Package taskschema validates task payloads against JSON Schemas, one per task type,
+// before the performer dispatches them. The schemas ship with the performer and check
+// the types, ranges and shapes of parameters declaratively; checks needing the state of
+// the performer, such as whether an adapter serves a protocol, stay with the handlers.
+//
+// Schemas are JSON Schema 2020-12 documents of the whole payload, its type and its
+// parameters. Parameters every task type accepts, such as result_format, are declared
+// once in common.json, which task schemas refer to. Operators may tighten what their
+// performer accepts with schemas of their own, which payloads must satisfy on top of the
+// built-in ones. The admin API serves the schemas with every reference inlined, so task
+// submitters can validate payloads before sending them.
+package taskschema
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// CommonDocument is the document of the definitions task schemas share
+const CommonDocument = "common.json"
+
+// operatorPrefix names operator schemas apart from the built-in schemas of their task type
+const operatorPrefix = "operator/"
+
+// maxInlineDepth bounds the references followed when inlining a schema, which only a
+// schema referring to itself reaches
+const maxInlineDepth = 32
+
+//go:embed schemas/*.json
+var builtin embed.FS
+
+// Config configures payload validation
+type Config struct {
+	// Dir holds schemas named after the task type they constrain, such as
+	// rebalance_execution.json, which payloads of the type must satisfy besides the
+	// built-in schema. They may refer to the definitions of common.json. Empty for none.
+	Dir string `yaml:"dir"`
+
+	// Strict rejects parameters the schema of their task type does not declare, which
+	// are otherwise ignored
+	Strict bool `yaml:"strict"`
+}
+
+// DefaultConfig validates payloads against the built-in schemas alone, ignoring unknown
+// parameters
+func DefaultConfig() Config {
+	return Config{}
+}
+
+// Validate checks that Dir is a directory
+func (c Config) Validate() error {
+	if c.Dir == "" {
+		return nil
+	}
+	info, err := os.Stat(c.Dir)
+	if err != nil {
+		return fmt.Errorf("dir: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("dir: %s is not a directory", c.Dir)
+	}
+	return nil
+}
+
+// taskSchema is the compiled schemas of a task type
+type taskSchema struct {
+	builtin  *node
+	operator *node
+
+	// declared are the parameters the schemas declare, which strict sets reject others of
+	declared map[string]bool
+
+	// document is the schema served to submitters, every reference inlined
+	document json.RawMessage
+}
+
+// Set is the schemas of every task type
+type Set struct {
+	schemas map[string]*taskSchema
+	strict  bool
+}
+
+// Default returns the set of the built-in schemas
+func Default() *Set {
+	set, err := NewFromConfig(DefaultConfig())
+	if err != nil {
+		panic(fmt.Sprintf("invalid built-in payload schemas: %v", err))
+	}
+	return set
+}
+
+// NewFromConfig compiles the built-in schemas and those of cfg.Dir
+func NewFromConfig(cfg Config) (*Set, error) {
+	builtins, err := readDocuments(builtin, "schemas")
+	if err != nil {
+		return nil, err
+	}
+	var operators map[string]interface{}
+	if cfg.Dir != "" {
+		if operators, err = readDocuments(os.DirFS(cfg.Dir), "."); err != nil {
+			return nil, err
+		}
+		if _, ok := operators[CommonDocument]; ok {
+			return nil, fmt.Errorf("%s: the name is reserved for the built-in definitions", filepath.Join(cfg.Dir, CommonDocument))
+		}
+		for name := range operators {
+			if _, ok := builtins[name]; !ok {
+				return nil, fmt.Errorf("%s: unknown task type %s", filepath.Join(cfg.Dir, name), strings.TrimSuffix(name, ".json"))
+			}
+		}
+	}
+
+	set := &Set{schemas: make(map[string]*taskSchema), strict: cfg.Strict}
+	builtinCompiler := newCompiler(builtins)
+	for name := range builtins {
+		if name == CommonDocument {
+			continue
+		}
+		taskType := strings.TrimSuffix(name, ".json")
+		schema := &taskSchema{declared: make(map[string]bool)}
+		if schema.builtin, err = builtinCompiler.document(name); err != nil {
+			return nil, err
+		}
+		document, err := inline(builtins, name, builtins[name], 0)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if raw, ok := operators[name]; ok {
+			// operator schemas may refer to the built-in ones, whose names they share
+			documents := map[string]interface{}{operatorPrefix + name: raw}
+			for builtinName, document := range builtins {
+				documents[builtinName] = document
+			}
+			if schema.operator, err = newCompiler(documents).document(operatorPrefix + name); err != nil {
+				return nil, fmt.Errorf("%s: %w", filepath.Join(cfg.Dir, name), err)
+			}
+			tightened, err := inline(documents, operatorPrefix+name, raw, 0)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", filepath.Join(cfg.Dir, name), err)
+			}
+			document = map[string]interface{}{
+				"$schema": "https://json-schema.org/draft/2020-12/schema",
+				"title":   taskType + " payload, tightened by the operator",
+				"allOf":   []interface{}{document, tightened},
+			}
+		}
+		if schema.document, err = json.MarshalIndent(document, "", "  "); err != nil {
+			return nil, err
+		}
+		for _, n := range []*node{schema.builtin, schema.operator} {
+			if n == nil {
+				continue
+			}
+			for _, parameters := range n.propertySchemas("parameters") {
+				parameters.declared(schema.declared)
+			}
+		}
+		set.schemas[taskType] = schema
+	}
+	return set, nil
+}
+
+// readDocuments reads the .json documents of dir in fsys by file name
+func readDocuments(fsys fs.FS, dir string) (map[string]interface{}, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read payload schemas: %w", err)
+	}
+	documents := make(map[string]interface{})
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read payload schema %s: %w", entry.Name(), err)
+		}
+		var document interface{}
+		if err := json.Unmarshal(data, &document); err != nil {
+			return nil, fmt.Errorf("invalid payload schema %s: %w", entry.Name(), err)
+		}
+		documents[entry.Name()] = document
+	}
+	return documents, nil
+}
+
+// inline returns schema, of the document named doc, with the schemas its references
+// point to in place of the references. Definitions are dropped, since nothing refers to
+// them anymore.
+func inline(documents map[string]interface{}, doc string, schema interface{}, depth int) (interface{}, error) {
+	if depth > maxInlineDepth {
+		return nil, errors.New("references nest too deep to inline")
+	}
+	switch value := schema.(type) {
+	case map[string]interface{}:
+		inlined := make(map[string]interface{}, len(value))
+		for key, child := range value {
+			if key == "$defs" || key == "$ref" {
+				continue
+			}
+			if depth > 0 && (key == "$schema" || key == "$id") {
+				continue
+			}
+			var err error
+			if inlined[key], err = inline(documents, doc, child, depth); err != nil {
+				return nil, err
+			}
+		}
+		ref, ok := value["$ref"].(string)
+		if !ok {
+			return inlined, nil
+		}
+		name, pointer, _ := strings.Cut(ref, "#")
+		if name == "" {
+			name = doc
+		}
+		target, err := lookup(documents[name], pointer)
+		if err != nil {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+		resolved, err := inline(documents, name, target, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		if len(inlined) == 0 {
+			return resolved, nil
+		}
+		inlined["allOf"] = append([]interface{}{resolved}, listOf(inlined["allOf"])...)
+		return inlined, nil
+	case []interface{}:
+		inlined := make([]interface{}, len(value))
+		for i, child := range value {
+			var err error
+			if inlined[i], err = inline(documents, doc, child, depth); err != nil {
+				return nil, err
+			}
+		}
+		return inlined, nil
+	default:
+		return schema, nil
+	}
+}
+
+// lookup returns the value of document the JSON pointer points to
+func lookup(document interface{}, pointer string) (interface{}, error) {
+	if document == nil {
+		return nil, errors.New("unknown document")
+	}
+	value := document
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		if token == "" {
+			continue
+		}
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("unresolvable pointer")
+		}
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		if value, ok = object[token]; !ok {
+			return nil, errors.New("unresolvable pointer")
+		}
+	}
+	return value, nil
+}
+
+func listOf(v interface{}) []interface{} {
+	list, _ := v.([]interface{})
+	return list
+}
+
+// TaskTypes returns the task types with a schema, sorted
+func (s *Set) TaskTypes() []string {
+	taskTypes := make([]string, 0, len(s.schemas))
+	for taskType := range s.schemas {
+		taskTypes = append(taskTypes, taskType)
+	}
+	sort.Strings(taskTypes)
+	return taskTypes
+}
+
+// Schema returns the schema payloads of taskType are validated against, with every
+// reference inlined, and false for task types without one
+func (s *Set) Schema(taskType string) (json.RawMessage, bool) {
+	schema, ok := s.schemas[taskType]
+	if !ok {
+		return nil, false
+	}
+	return schema.document, true
+}
+
+// Validate checks a payload of taskType, decoded from JSON, against its schemas. It
+// returns a *ValidationError listing every value breaking them, by path, and an error
+// for task types without a schema.
+func (s *Set) Validate(taskType string, payload map[string]interface{}) error {
+	schema, ok := s.schemas[taskType]
+	if !ok {
+		return fmt.Errorf("no payload schema for task type %s", taskType)
+	}
+	var errs []FieldError
+	schema.builtin.validate("", payload, &errs)
+	if schema.operator != nil {
+		schema.operator.validate("", payload, &errs)
+	}
+	if parameters, ok := payload["parameters"].(map[string]interface{}); ok && s.strict {
+		names := make([]string, 0, len(parameters))
+		for name := range parameters {
+			if !schema.declared[name] {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			errs = append(errs, FieldError{Path: "parameters." + name, Message: "is not a parameter of " + taskType})
+		}
+	}
+	if len(errs) > 0 {
+		errs = dedupe(errs)
+		sort.SliceStable(errs, func(i, j int) bool { return errs[i].Path < errs[j].Path })
+		return &ValidationError{TaskType: taskType, Errors: errs}
+	}
+	return nil
+}
+
+// dedupe drops repeated errors, which a value breaking both the built-in and the
+// operator schema the same way produces
+func dedupe(errs []FieldError) []FieldError {
+	seen := make(map[FieldError]bool, len(errs))
+	unique := errs[:0]
+	for _, err := range errs {
+		if !seen[err] {
+			seen[err] = true
+			unique = append(unique, err)
+		}
+	}
+	return unique
+}
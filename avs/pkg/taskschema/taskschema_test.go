@@ -0,0 +1,170 @@
+package taskschema
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func decode(t *testing.T, payload string) map[string]interface{} {
+	t.Helper()
+	var decoded map[string]interface{}
+	if err := json.Unmarshal([]byte(payload), &decoded); err != nil {
+		t.Fatalf("Invalid payload %s: %v", payload, err)
+	}
+	return decoded
+}
+
+func Test_DefaultValidates(t *testing.T) {
+	set := Default()
+	testCases := map[string]struct {
+		taskType string
+		payload  string
+		errors   []string
+	}{
+		"valid": {
+			taskType: "yield_monitoring",
+			payload:  `{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","chain_id":1,"token":"USDC","result_format":"abi","unknown":1}}`,
+		},
+		"wrong types": {
+			taskType: "yield_monitoring",
+			payload:  `{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","chain_id":"1","token":"USDC","anomaly_sigma":0}}`,
+			errors:   []string{"parameters.anomaly_sigma must be above 0", "parameters.chain_id must be an integer"},
+		},
+		"missing parameters": {
+			taskType: "cross_chain_yield_check",
+			payload:  `{"type":"cross_chain_yield_check"}`,
+			errors:   []string{"parameters is required"},
+		},
+		"common parameters": {
+			taskType: "capabilities",
+			payload:  `{"type":"capabilities","parameters":{"result_format":"xml","fields":["a","a"],"block_number":1}}`,
+			errors:   []string{`parameters.fields must not repeat "a"`, `parameters.result_format must be one of "json", "abi"`},
+		},
+		"referenced task schema": {
+			taskType: "rebalance_plan",
+			payload:  `{"type":"rebalance_plan","parameters":{"user_address":"0xabc","amount":"0","target_protocol":"aave_v3","expires_at":1.5}}`,
+			errors:   []string{"parameters.amount must match", "parameters.expires_at must be an integer", "parameters.user_address must match"},
+		},
+		"nested items": {
+			taskType: "allocation_optimization",
+			payload:  `{"type":"allocation_optimization","parameters":{"amount":"1","token":"USDC","chain_ids":[1,0],"transfer_costs":{"base":"1"},"profile":{"preset":"yolo","max_risk":1}}}`,
+			errors:   []string{"parameters.chain_ids[1] must be at least 1", "parameters.profile.max_risk is not allowed", "parameters.profile.preset must be one of", "parameters.transfer_costs.base must match"},
+		},
+		"wrong type": {
+			taskType: "batch",
+			payload:  `{"type":"capabilities","parameters":{"tasks":[{"type":"capabilities"}]}}`,
+			errors:   []string{`type must be "batch"`},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			err := set.Validate(tc.taskType, decode(t, tc.payload))
+			if len(tc.errors) == 0 {
+				if err != nil {
+					t.Fatalf("Expected the payload to validate: %v", err)
+				}
+				return
+			}
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) || len(validationErr.Errors) != len(tc.errors) {
+				t.Fatalf("Expected %d errors, got %v", len(tc.errors), err)
+			}
+			for i, want := range tc.errors {
+				if got := validationErr.Errors[i].Path + " " + validationErr.Errors[i].Message; !strings.HasPrefix(got, want) {
+					t.Errorf("Expected error %d to be %q, got %q", i, want, got)
+				}
+			}
+		})
+	}
+
+	if err := set.Validate("unknown", decode(t, `{"type":"unknown"}`)); err == nil {
+		t.Errorf("Expected a task type without a schema to be refused")
+	}
+}
+
+func Test_StrictRejectsUnknownParameters(t *testing.T) {
+	set, err := NewFromConfig(Config{Strict: true})
+	if err != nil {
+		t.Fatalf("NewFromConfig failed: %v", err)
+	}
+	// parameters of the task type, of every task type and of referenced schemas are known
+	for taskType, payload := range map[string]string{
+		"yield_monitoring": `{"type":"yield_monitoring","parameters":{"protocol":"aave_v3","chain_id":1,"token":"USDC","attest":true,"block_number":5}}`,
+		"rebalance_plan":   `{"type":"rebalance_plan","parameters":{"user_address":"0x00000000000000000000000000000000000000aa","amount":"1","target_protocol":"aave_v3","expires_at":1,"nonce":"3"}}`,
+	} {
+		if err := set.Validate(taskType, decode(t, payload)); err != nil {
+			t.Errorf("%s: expected the payload to validate: %v", taskType, err)
+		}
+	}
+	err = set.Validate("capabilities", decode(t, `{"type":"capabilities","parameters":{"protocol":"aave_v3"}}`))
+	if err == nil || !strings.Contains(err.Error(), "parameters.protocol is not a parameter of capabilities") {
+		t.Errorf("Expected the unknown parameter to be refused, got %v", err)
+	}
+}
+
+func Test_OperatorSchemasTighten(t *testing.T) {
+	dir := t.TempDir()
+	schema := `{"properties":{"parameters":{"required":["user_address"],"properties":{"max_slippage_bps":{"$ref":"common.json#/$defs/bps","maximum":50}}}}}`
+	if err := os.WriteFile(filepath.Join(dir, "cross_chain_yield_check.json"), []byte(schema), 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	set, err := NewFromConfig(Config{Dir: dir})
+	if err != nil {
+		t.Fatalf("NewFromConfig failed: %v", err)
+	}
+	err = set.Validate("cross_chain_yield_check", decode(t, `{"type":"cross_chain_yield_check","parameters":{"source_chain":1,"target_chain":8453,"amount":"1","max_slippage_bps":100}}`))
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || len(validationErr.Errors) != 2 {
+		t.Fatalf("Expected the operator schema to apply, got %v", err)
+	}
+
+	document, ok := set.Schema("cross_chain_yield_check")
+	if !ok || strings.Contains(string(document), "$ref") || !strings.Contains(string(document), "tightened by the operator") {
+		t.Errorf("Expected the tightened schema with references inlined, got %s", document)
+	}
+	// schemas served to submitters validate the payloads the set does
+	var served map[string]interface{}
+	if err := json.Unmarshal(document, &served); err != nil {
+		t.Fatalf("Invalid served schema: %v", err)
+	}
+	n, err := newCompiler(map[string]interface{}{"served.json": served}).document("served.json")
+	if err != nil {
+		t.Fatalf("Failed to compile the served schema: %v", err)
+	}
+	var errs []FieldError
+	n.validate("", decode(t, `{"type":"cross_chain_yield_check","parameters":{"source_chain":1,"target_chain":8453,"amount":"1","max_slippage_bps":100}}`), &errs)
+	if len(errs) != 2 {
+		t.Errorf("Expected the served schema to find the same errors, got %+v", errs)
+	}
+
+	for name, content := range map[string]string{
+		"balances.json":         `{}`,
+		"common.json":           `{}`,
+		"depeg_monitoring.json": `{"type":"object","minProperties":1}`,
+	} {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+		if _, err := NewFromConfig(Config{Dir: dir}); err == nil {
+			t.Errorf("%s: expected the schema to be refused", name)
+		}
+	}
+}
+
+func Test_ServedSchemasAreSelfContained(t *testing.T) {
+	set := Default()
+	if len(set.TaskTypes()) != 22 {
+		t.Errorf("Expected a schema for each of the 22 task types, got %v", set.TaskTypes())
+	}
+	for _, taskType := range set.TaskTypes() {
+		document, _ := set.Schema(taskType)
+		if strings.Contains(string(document), "$ref") || strings.Contains(string(document), "$defs") {
+			t.Errorf("%s: expected every reference to be inlined", taskType)
+		}
+	}
+}
@@ -0,0 +1,559 @@
+package taskschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// annotations are the keywords compiled schemas accept and ignore
+var annotations = map[string]bool{
+	"$schema": true, "$id": true, "$comment": true, "$defs": true,
+	"title": true, "description": true, "examples": true, "default": true,
+}
+
+// FieldError is a value of a payload breaking its schema
+type FieldError struct {
+	// Path locates the value in the payload, such as parameters.chain_ids[0]
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists the values of a payload breaking its schema
+type ValidationError struct {
+	TaskType string
+	Errors   []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, field := range e.Errors {
+		messages[i] = field.Path + " " + field.Message
+	}
+	return fmt.Sprintf("payload does not match the %s schema: %s", e.TaskType, strings.Join(messages, "; "))
+}
+
+// node is a compiled schema. Its keywords are the subset of JSON Schema 2020-12 payload
+// schemas use; compiling a schema with another keyword fails.
+type node struct {
+	types    []string
+	enum     []interface{}
+	constant interface{}
+	hasConst bool
+
+	properties    map[string]*node
+	required      []string
+	additional    *node
+	noAdditional  bool
+	propertyNames *node
+
+	items       *node
+	minItems    *int
+	maxItems    *int
+	uniqueItems bool
+
+	minimum          *float64
+	maximum          *float64
+	exclusiveMinimum *float64
+	exclusiveMaximum *float64
+
+	minLength *int
+	maxLength *int
+	pattern   *regexp.Regexp
+
+	allOf []*node
+	anyOf []*node
+	not   *node
+
+	// target is the schema $ref points to
+	target *node
+}
+
+// compiler compiles the schemas of a set of documents, which refer to each other by
+// file name, such as common.json#/$defs/chain_id
+type compiler struct {
+	documents map[string]interface{}
+	compiled  map[string]*node
+}
+
+func newCompiler(documents map[string]interface{}) *compiler {
+	return &compiler{documents: documents, compiled: make(map[string]*node)}
+}
+
+// document compiles the document named name
+func (c *compiler) document(name string) (*node, error) {
+	return c.resolve(name + "#")
+}
+
+// resolve compiles the schema ref points to, a document name and a JSON pointer
+func (c *compiler) resolve(ref string) (*node, error) {
+	if n, ok := c.compiled[ref]; ok {
+		return n, nil
+	}
+	name, pointer, _ := strings.Cut(ref, "#")
+	raw, ok := c.documents[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown schema document %q", name)
+	}
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		if token == "" {
+			continue
+		}
+		object, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		if raw, ok = object[token]; !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+	}
+	// registered before compiling, so schemas referring to themselves terminate
+	n := &node{}
+	c.compiled[ref] = n
+	if err := c.compile(name, raw, n); err != nil {
+		return nil, fmt.Errorf("%s: %w", ref, err)
+	}
+	return n, nil
+}
+
+// compile compiles raw, a schema of the document named doc, into n
+func (c *compiler) compile(doc string, raw interface{}, n *node) error {
+	schema, ok := raw.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("schema must be an object")
+	}
+	keys := make([]string, 0, len(schema))
+	for key := range schema {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := schema[key]
+		var err error
+		switch key {
+		case "type":
+			n.types, err = stringList(value)
+		case "enum":
+			var ok bool
+			if n.enum, ok = value.([]interface{}); !ok || len(n.enum) == 0 {
+				err = fmt.Errorf("must be a non-empty array")
+			}
+		case "const":
+			n.constant, n.hasConst = value, true
+		case "properties":
+			var properties map[string]interface{}
+			if properties, ok = value.(map[string]interface{}); !ok {
+				return fmt.Errorf("properties must be an object")
+			}
+			n.properties = make(map[string]*node, len(properties))
+			for name, property := range properties {
+				n.properties[name] = &node{}
+				if err := c.compile(doc, property, n.properties[name]); err != nil {
+					return fmt.Errorf("properties.%s: %w", name, err)
+				}
+			}
+		case "required":
+			n.required, err = stringList(value)
+		case "additionalProperties":
+			if allowed, ok := value.(bool); ok {
+				n.noAdditional = !allowed
+				break
+			}
+			n.additional, err = c.subschema(doc, value)
+		case "propertyNames":
+			n.propertyNames, err = c.subschema(doc, value)
+		case "items":
+			n.items, err = c.subschema(doc, value)
+		case "minItems":
+			n.minItems, err = count(value)
+		case "maxItems":
+			n.maxItems, err = count(value)
+		case "uniqueItems":
+			if n.uniqueItems, ok = value.(bool); !ok {
+				err = fmt.Errorf("must be a boolean")
+			}
+		case "minimum":
+			n.minimum, err = number(value)
+		case "maximum":
+			n.maximum, err = number(value)
+		case "exclusiveMinimum":
+			n.exclusiveMinimum, err = number(value)
+		case "exclusiveMaximum":
+			n.exclusiveMaximum, err = number(value)
+		case "minLength":
+			n.minLength, err = count(value)
+		case "maxLength":
+			n.maxLength, err = count(value)
+		case "pattern":
+			pattern, ok := value.(string)
+			if !ok {
+				err = fmt.Errorf("must be a string")
+				break
+			}
+			n.pattern, err = regexp.Compile(pattern)
+		case "allOf":
+			n.allOf, err = c.subschemas(doc, value)
+		case "anyOf":
+			n.anyOf, err = c.subschemas(doc, value)
+		case "not":
+			n.not, err = c.subschema(doc, value)
+		case "$ref":
+			ref, ok := value.(string)
+			if !ok {
+				err = fmt.Errorf("must be a string")
+				break
+			}
+			if strings.HasPrefix(ref, "#") {
+				ref = doc + ref
+			}
+			n.target, err = c.resolve(ref)
+		default:
+			if !annotations[key] {
+				err = fmt.Errorf("unsupported keyword")
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+func (c *compiler) subschema(doc string, raw interface{}) (*node, error) {
+	n := &node{}
+	if err := c.compile(doc, raw, n); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func (c *compiler) subschemas(doc string, raw interface{}) ([]*node, error) {
+	list, ok := raw.([]interface{})
+	if !ok || len(list) == 0 {
+		return nil, fmt.Errorf("must be a non-empty array of schemas")
+	}
+	nodes := make([]*node, len(list))
+	for i, schema := range list {
+		var err error
+		if nodes[i], err = c.subschema(doc, schema); err != nil {
+			return nil, fmt.Errorf("[%d]: %w", i, err)
+		}
+	}
+	return nodes, nil
+}
+
+func stringList(raw interface{}) ([]string, error) {
+	if s, ok := raw.(string); ok {
+		return []string{s}, nil
+	}
+	list, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("must be a string or an array of strings")
+	}
+	strs := make([]string, len(list))
+	for i, v := range list {
+		if strs[i], ok = v.(string); !ok {
+			return nil, fmt.Errorf("must be a string or an array of strings")
+		}
+	}
+	return strs, nil
+}
+
+func count(raw interface{}) (*int, error) {
+	f, ok := toFloat(raw)
+	if !ok || f < 0 || f != math.Trunc(f) {
+		return nil, fmt.Errorf("must be a non-negative integer")
+	}
+	n := int(f)
+	return &n, nil
+}
+
+func number(raw interface{}) (*float64, error) {
+	f, ok := toFloat(raw)
+	if !ok {
+		return nil, fmt.Errorf("must be a number")
+	}
+	return &f, nil
+}
+
+// toFloat returns the value of JSON numbers, as decoded or as Go integers
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// typeOf returns the JSON Schema type of a decoded JSON value. Numbers without a
+// fraction are integers.
+func typeOf(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		if f, ok := toFloat(value); ok {
+			if f == math.Trunc(f) && !math.IsInf(f, 0) {
+				return "integer"
+			}
+			return "number"
+		}
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// hasType reports whether v is of one of types. Integers are numbers.
+func hasType(v interface{}, types []string) bool {
+	actual := typeOf(v)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// equalJSON compares decoded JSON values, numbers by value
+func equalJSON(a, b interface{}) bool {
+	fa, aNumber := toFloat(a)
+	fb, bNumber := toFloat(b)
+	if aNumber || bNumber {
+		return aNumber && bNumber && fa == fb
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// validate appends the errors of v, the value at path, to errs
+func (n *node) validate(path string, v interface{}, errs *[]FieldError) {
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+	if n.target != nil {
+		n.target.validate(path, v, errs)
+	}
+	if len(n.types) > 0 && !hasType(v, n.types) {
+		fail("must be %s", article(n.types))
+		return
+	}
+	if n.hasConst && !equalJSON(v, n.constant) {
+		fail("must be %s", encode(n.constant))
+	}
+	if len(n.enum) > 0 {
+		found := false
+		for _, allowed := range n.enum {
+			if equalJSON(v, allowed) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			values := make([]string, len(n.enum))
+			for i, allowed := range n.enum {
+				values[i] = encode(allowed)
+			}
+			fail("must be one of %s", strings.Join(values, ", "))
+		}
+	}
+
+	switch value := v.(type) {
+	case map[string]interface{}:
+		n.validateObject(path, value, errs)
+	case []interface{}:
+		n.validateArray(path, value, errs)
+	case string:
+		length := len([]rune(value))
+		if n.minLength != nil && length < *n.minLength {
+			if *n.minLength == 1 {
+				fail("must not be empty")
+			} else {
+				fail("must be at least %d characters", *n.minLength)
+			}
+		}
+		if n.maxLength != nil && length > *n.maxLength {
+			fail("must be at most %d characters", *n.maxLength)
+		}
+		if n.pattern != nil && !n.pattern.MatchString(value) {
+			fail("must match %s", n.pattern)
+		}
+	default:
+		if f, ok := toFloat(v); ok {
+			if n.minimum != nil && f < *n.minimum {
+				fail("must be at least %v", *n.minimum)
+			}
+			if n.maximum != nil && f > *n.maximum {
+				fail("must be at most %v", *n.maximum)
+			}
+			if n.exclusiveMinimum != nil && f <= *n.exclusiveMinimum {
+				fail("must be above %v", *n.exclusiveMinimum)
+			}
+			if n.exclusiveMaximum != nil && f >= *n.exclusiveMaximum {
+				fail("must be below %v", *n.exclusiveMaximum)
+			}
+		}
+	}
+
+	for _, sub := range n.allOf {
+		sub.validate(path, v, errs)
+	}
+	if len(n.anyOf) > 0 {
+		matched := false
+		for _, sub := range n.anyOf {
+			var subErrs []FieldError
+			if sub.validate(path, v, &subErrs); len(subErrs) == 0 {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			fail("must match one of the allowed schemas")
+		}
+	}
+	if n.not != nil {
+		var subErrs []FieldError
+		if n.not.validate(path, v, &subErrs); len(subErrs) == 0 {
+			fail("is not allowed")
+		}
+	}
+}
+
+func (n *node) validateObject(path string, object map[string]interface{}, errs *[]FieldError) {
+	for _, name := range n.required {
+		if _, ok := object[name]; !ok {
+			*errs = append(*errs, FieldError{Path: join(path, name), Message: "is required"})
+		}
+	}
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if n.propertyNames != nil {
+			n.propertyNames.validate(join(path, name), name, errs)
+		}
+		if property, ok := n.properties[name]; ok {
+			property.validate(join(path, name), object[name], errs)
+			continue
+		}
+		if n.noAdditional {
+			*errs = append(*errs, FieldError{Path: join(path, name), Message: "is not allowed"})
+		} else if n.additional != nil {
+			n.additional.validate(join(path, name), object[name], errs)
+		}
+	}
+}
+
+func (n *node) validateArray(path string, array []interface{}, errs *[]FieldError) {
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+	if n.minItems != nil && len(array) < *n.minItems {
+		if *n.minItems == 1 {
+			fail("must not be empty")
+		} else {
+			fail("must have at least %d items", *n.minItems)
+		}
+	}
+	if n.maxItems != nil && len(array) > *n.maxItems {
+		fail("must have at most %d items", *n.maxItems)
+	}
+	if n.uniqueItems {
+	unique:
+		for i := range array {
+			for j := 0; j < i; j++ {
+				if equalJSON(array[i], array[j]) {
+					fail("must not repeat %s", encode(array[i]))
+					break unique
+				}
+			}
+		}
+	}
+	if n.items != nil {
+		for i, item := range array {
+			n.items.validate(fmt.Sprintf("%s[%d]", path, i), item, errs)
+		}
+	}
+}
+
+// declared adds the properties n declares, through its references and subschemas, to
+// names
+func (n *node) declared(names map[string]bool) {
+	for name := range n.properties {
+		names[name] = true
+	}
+	if n.target != nil {
+		n.target.declared(names)
+	}
+	for _, sub := range append(append([]*node{}, n.allOf...), n.anyOf...) {
+		sub.declared(names)
+	}
+}
+
+// propertySchemas returns the schemas n declares for a property, through its
+// references and allOf subschemas
+func (n *node) propertySchemas(name string) []*node {
+	var schemas []*node
+	if property, ok := n.properties[name]; ok {
+		schemas = append(schemas, property)
+	}
+	if n.target != nil {
+		schemas = append(schemas, n.target.propertySchemas(name)...)
+	}
+	for _, sub := range n.allOf {
+		schemas = append(schemas, sub.propertySchemas(name)...)
+	}
+	return schemas
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// article describes types for errors, such as "an integer" or "a string or null"
+func article(types []string) string {
+	described := make([]string, len(types))
+	for i, t := range types {
+		switch t {
+		case "null":
+			described[i] = "null"
+		case "integer", "object", "array":
+			described[i] = "an " + t
+		default:
+			described[i] = "a " + t
+		}
+	}
+	return strings.Join(described, " or ")
+}
+
+func encode(v interface{}) string {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(encoded)
+}
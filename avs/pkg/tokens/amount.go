@@ -0,0 +1,40 @@
+package tokens
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// maxUint256 bounds amounts, which are passed to contracts as uint256
+var maxUint256 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+
+// ParseAmount parses a token amount in base units, a decimal string of digits such as
+// "1500000" for 1.5 USDC. Amounts are strings because JSON numbers decode to float64,
+// which cannot represent large amounts to the base unit.
+func ParseAmount(s string) (*big.Int, error) {
+	if s == "" {
+		return nil, fmt.Errorf("empty amount")
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return nil, fmt.Errorf("invalid amount %q: must be a decimal string of base units", s)
+		}
+	}
+	amount, _ := new(big.Int).SetString(s, 10)
+	if amount.Cmp(maxUint256) > 0 {
+		return nil, fmt.Errorf("invalid amount %q: exceeds uint256", s)
+	}
+	return amount, nil
+}
+
+// ParsePositiveAmount parses a non-zero token amount in base units, as ParseAmount
+func ParsePositiveAmount(s string) (*big.Int, error) {
+	amount, err := ParseAmount(s)
+	if err != nil {
+		return nil, err
+	}
+	if amount.Sign() == 0 {
+		return nil, fmt.Errorf("invalid amount %q: must be positive", s)
+	}
+	return amount, nil
+}
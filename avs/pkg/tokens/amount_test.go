@@ -0,0 +1,22 @@
+package tokens
+
+import "testing"
+
+func Test_ParseAmount(t *testing.T) {
+	amount, err := ParseAmount("123456789012345678901")
+	if err != nil || amount.String() != "123456789012345678901" {
+		t.Errorf("Expected amounts past float64 precision parsed exactly, got %v: %v", amount, err)
+	}
+	if amount, err := ParseAmount("0"); err != nil || amount.Sign() != 0 {
+		t.Errorf("Expected zero to parse, got %v: %v", amount, err)
+	}
+	for _, s := range []string{"", "1.5", "-1", "+1", "1e6", " 1", "0x10",
+		"115792089237316195423570985008687907853269984665640564039457584007913129639936"} {
+		if _, err := ParseAmount(s); err == nil {
+			t.Errorf("Expected %q to be rejected", s)
+		}
+	}
+	if _, err := ParsePositiveAmount("0"); err == nil {
+		t.Errorf("Expected a zero amount to be rejected as positive")
+	}
+}
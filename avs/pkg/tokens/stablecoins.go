@@ -0,0 +1,79 @@
+package tokens
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Symbols of the stablecoins other than USDC that yields can be compared for
+const (
+	SymbolUSDT = "USDT"
+	SymbolDAI  = "DAI"
+	SymbolUSDe = "USDe"
+)
+
+// stablecoins lists the stablecoins other than USDC by chain. They are only read to
+// compare yields: nothing is lent or bridged in them, so they carry no kind.
+var stablecoins = []Token{
+	{Symbol: SymbolUSDT, ChainID: 1, Address: common.HexToAddress("0xdAC17F958D2ee523a2206206994597C13D831ec7"), Decimals: 6},
+	{Symbol: SymbolUSDT, ChainID: 42161, Address: common.HexToAddress("0xFd086bC7CD5C481DCC9C85ebE478A1C0b69FCbb9"), Decimals: 6},
+	{Symbol: SymbolDAI, ChainID: 1, Address: common.HexToAddress("0x6B175474E89094C44Da98b954EedeAC495271d0F"), Decimals: 18},
+	{Symbol: SymbolDAI, ChainID: 8453, Address: common.HexToAddress("0x50c5725949A6F0c72E6C4a641F24049A917DB0Cb"), Decimals: 18},
+	{Symbol: SymbolDAI, ChainID: 42161, Address: common.HexToAddress("0xDA10009cBd5D07dd0CeCc66161FC93D7c9000da1"), Decimals: 18},
+	{Symbol: SymbolUSDe, ChainID: 1, Address: common.HexToAddress("0x4c9EDD5852cd905f086C759E8383e09bff1E68B3"), Decimals: 18},
+	{Symbol: SymbolUSDe, ChainID: 8453, Address: common.HexToAddress("0x5d3a1Ff2b6BAb83b63cd9AD0787074081a52ef34"), Decimals: 18},
+	{Symbol: SymbolUSDe, ChainID: 42161, Address: common.HexToAddress("0x5d3a1Ff2b6BAb83b63cd9AD0787074081a52ef34"), Decimals: 18},
+}
+
+// Stablecoin returns the stablecoin named symbol on chainID
+func Stablecoin(symbol string, chainID uint64) (Token, error) {
+	for _, token := range stablecoins {
+		if token.Symbol == symbol && token.ChainID == chainID {
+			return token, nil
+		}
+	}
+	return Token{}, fmt.Errorf("%w: %s is not listed on chain %d", ErrUnknown, symbol, chainID)
+}
+
+// StablecoinSymbols returns the symbols of every listed stablecoin other than USDC
+func StablecoinSymbols() []string {
+	var out []string
+	for _, token := range stablecoins {
+		if !slices.Contains(out, token.Symbol) {
+			out = append(out, token.Symbol)
+		}
+	}
+	return out
+}
+
+// Config enables comparing the yields of stablecoins other than USDC. Only yield
+// monitoring accepts them; rebalances and every other task stay USDC only.
+type Config struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Symbols are the stablecoins tasks may name besides USDC
+	Symbols []string `yaml:"symbols"`
+}
+
+// DefaultConfig returns the config accepting every listed stablecoin once enabled
+func DefaultConfig() Config {
+	return Config{Symbols: StablecoinSymbols()}
+}
+
+// Validate checks that every configured symbol is a listed stablecoin
+func (c Config) Validate() error {
+	listed := StablecoinSymbols()
+	for _, symbol := range c.Symbols {
+		if !slices.Contains(listed, symbol) {
+			return fmt.Errorf("unknown stablecoin %q, must be one of %v", symbol, listed)
+		}
+	}
+	return nil
+}
+
+// Accepts returns whether tasks may name the stablecoin symbol under c
+func (c Config) Accepts(symbol string) bool {
+	return c.Enabled && slices.Contains(c.Symbols, symbol)
+}
@@ -0,0 +1,38 @@
+package tokens
+
+import (
+	"errors"
+	"testing"
+)
+
+func Test_Stablecoin(t *testing.T) {
+	token, err := Stablecoin(SymbolDAI, 8453)
+	if err != nil || token.Decimals != 18 || token.Address.Hex() != "0x50c5725949A6F0c72E6C4a641F24049A917DB0Cb" {
+		t.Errorf("Expected DAI on Base, got %+v, %v", token, err)
+	}
+	if _, err := Stablecoin(SymbolUSDT, 8453); !errors.Is(err, ErrUnknown) {
+		t.Errorf("Expected USDT to be unlisted on Base, got %v", err)
+	}
+	if _, err := Stablecoin(SymbolUSDC, 1); !errors.Is(err, ErrUnknown) {
+		t.Errorf("Expected USDC not to be listed among the other stablecoins, got %v", err)
+	}
+}
+
+func Test_ConfigAccepts(t *testing.T) {
+	cfg := DefaultConfig()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Expected the default config to be valid, got %v", err)
+	}
+	if cfg.Accepts(SymbolUSDT) {
+		t.Errorf("Expected stablecoins to be rejected until enabled")
+	}
+	cfg.Enabled = true
+	cfg.Symbols = []string{SymbolDAI}
+	if !cfg.Accepts(SymbolDAI) || cfg.Accepts(SymbolUSDe) || cfg.Accepts("dai") {
+		t.Errorf("Expected only DAI to be accepted")
+	}
+	cfg.Symbols = []string{"FRAX"}
+	if err := cfg.Validate(); err == nil {
+		t.Errorf("Expected an unlisted stablecoin to be rejected")
+	}
+}
@@ -0,0 +1,166 @@
+// Package tokens lists the USDC variants of every supported chain: native USDC issued by
+// Circle, and the bridged variants that predate it. Only native USDC is lent through
+// adapters and moved through CCTP, so tasks naming a bridged variant are rejected.
+//
+// Other stablecoins are listed too, for tasks comparing their yields with USDC's once
+// enabled by Config.
+package tokens
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/najnomics/crosscow-avs/pkg/addressbook"
+)
+
+// SymbolUSDC names native USDC in task parameters, whatever the chain
+const SymbolUSDC = "USDC"
+
+// Kind tells native USDC from bridged variants
+type Kind string
+
+const (
+	// KindNative is USDC issued by Circle on the chain itself
+	KindNative Kind = "native"
+
+	// KindBridged is USDC locked on Ethereum and minted on the chain by a bridge. It is
+	// not redeemable with Circle and cannot be moved through CCTP.
+	KindBridged Kind = "bridged"
+)
+
+var (
+	// ErrBridged is returned for tokens that are bridged USDC rather than native USDC
+	ErrBridged = errors.New("bridged USDC is not supported")
+
+	// ErrUnknown is returned for tokens that are no USDC variant of the chain
+	ErrUnknown = errors.New("unknown token")
+)
+
+// Token is a USDC variant deployed on a chain
+type Token struct {
+	Symbol   string
+	ChainID  uint64
+	Address  common.Address
+	Decimals uint8
+	Kind     Kind
+
+	// Bridge names the bridge minting a bridged variant, empty for native USDC
+	Bridge string
+
+	// CCTP is true for tokens Circle's Cross-Chain Transfer Protocol burns and mints
+	CCTP bool
+}
+
+// nativeUSDC returns the address of native USDC on chainID in the address book
+func nativeUSDC(chainID uint64) common.Address {
+	return addressbook.Default().Address(chainID, addressbook.ProtocolUSDC, addressbook.ContractToken)
+}
+
+// usdc lists the USDC variants of every supported chain, native USDC first
+var usdc = []Token{
+	{Symbol: SymbolUSDC, ChainID: 1, Address: nativeUSDC(1), Decimals: 6, Kind: KindNative, CCTP: true},
+	{Symbol: SymbolUSDC, ChainID: 8453, Address: nativeUSDC(8453), Decimals: 6, Kind: KindNative, CCTP: true},
+	{Symbol: "USDbC", ChainID: 8453, Address: common.HexToAddress("0xd9aAEc86B65D86f6A7B5B1b0c42FFA531710b6CA"), Decimals: 6, Kind: KindBridged, Bridge: "Base bridge"},
+	{Symbol: SymbolUSDC, ChainID: 42161, Address: nativeUSDC(42161), Decimals: 6, Kind: KindNative, CCTP: true},
+	{Symbol: "USDC.e", ChainID: 42161, Address: common.HexToAddress("0xFF970A61A04b1cA14834A43f5dE4533eBDDB5CC8"), Decimals: 6, Kind: KindBridged, Bridge: "Arbitrum bridge"},
+
+	// Circle's testnet USDC, which its faucet mints
+	{Symbol: SymbolUSDC, ChainID: 11155111, Address: nativeUSDC(11155111), Decimals: 6, Kind: KindNative, CCTP: true},
+	{Symbol: SymbolUSDC, ChainID: 84532, Address: nativeUSDC(84532), Decimals: 6, Kind: KindNative, CCTP: true},
+	{Symbol: SymbolUSDC, ChainID: 421614, Address: nativeUSDC(421614), Decimals: 6, Kind: KindNative, CCTP: true},
+}
+
+// NativeUSDC returns native USDC on chainID
+func NativeUSDC(chainID uint64) (Token, error) {
+	for _, token := range usdc {
+		if token.ChainID == chainID && token.Kind == KindNative {
+			return token, nil
+		}
+	}
+	return Token{}, fmt.Errorf("no native USDC on chain %d", chainID)
+}
+
+// NativeUSDCAddresses returns the address of native USDC by chain id
+func NativeUSDCAddresses() map[uint64]common.Address {
+	out := make(map[uint64]common.Address)
+	for _, token := range usdc {
+		if token.Kind == KindNative {
+			out[token.ChainID] = token.Address
+		}
+	}
+	return out
+}
+
+// CCTPUSDC returns the USDC CCTP burns on chainID, failing for chains CCTP does not
+// move native USDC on
+func CCTPUSDC(chainID uint64) (Token, error) {
+	token, err := NativeUSDC(chainID)
+	if err != nil {
+		return Token{}, err
+	}
+	if !token.CCTP {
+		return Token{}, fmt.Errorf("native USDC on chain %d is not transferable with CCTP", chainID)
+	}
+	return token, nil
+}
+
+// Lookup returns the USDC variant of chainID referenced by ref, a symbol or an address
+func Lookup(chainID uint64, ref string) (Token, bool) {
+	isAddress := common.IsHexAddress(ref)
+	for _, token := range usdc {
+		if token.ChainID != chainID {
+			continue
+		}
+		if (isAddress && token.Address == common.HexToAddress(ref)) || (!isAddress && token.Symbol == ref) {
+			return token, true
+		}
+	}
+	return Token{}, false
+}
+
+// CheckUSDC checks that ref, a symbol or an address, names native USDC on every chain of
+// chainIDs, or on any chain when there are none. The USDC symbol names native USDC on any
+// chain; bridged variants fail with ErrBridged.
+func CheckUSDC(ref string, chainIDs ...uint64) error {
+	if ref == SymbolUSDC {
+		return nil
+	}
+	if len(chainIDs) == 0 {
+		seen := make(map[uint64]bool)
+		for _, token := range usdc {
+			if !seen[token.ChainID] {
+				seen[token.ChainID] = true
+				chainIDs = append(chainIDs, token.ChainID)
+			}
+		}
+		var err error
+		for _, chainID := range chainIDs {
+			if err = checkUSDC(ref, chainID); err == nil || !errors.Is(err, ErrUnknown) {
+				return err
+			}
+		}
+		return fmt.Errorf("%w: %s is no USDC on a supported chain", ErrUnknown, ref)
+	}
+	for _, chainID := range chainIDs {
+		if err := checkUSDC(ref, chainID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkUSDC(ref string, chainID uint64) error {
+	token, ok := Lookup(chainID, ref)
+	if !ok {
+		return fmt.Errorf("%w: %s is no USDC on chain %d", ErrUnknown, ref, chainID)
+	}
+	if token.Kind == KindNative {
+		return nil
+	}
+	native, err := NativeUSDC(chainID)
+	if err != nil {
+		return fmt.Errorf("%w: %s is USDC bridged by the %s on chain %d", ErrBridged, token.Symbol, token.Bridge, chainID)
+	}
+	return fmt.Errorf("%w: %s is USDC bridged by the %s on chain %d, use native USDC %s", ErrBridged, token.Symbol, token.Bridge, chainID, native.Address.Hex())
+}
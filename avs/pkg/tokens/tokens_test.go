@@ -0,0 +1,44 @@
+package tokens
+
+import (
+	"errors"
+	"testing"
+)
+
+func Test_CheckUSDC(t *testing.T) {
+	testCases := []struct {
+		name     string
+		ref      string
+		chainIDs []uint64
+		err      error
+	}{
+		{name: "symbol", ref: "USDC", chainIDs: []uint64{10}},
+		{name: "native address", ref: "0x833589fcd6edb6e08f4c7c32d4f71b54bda02913", chainIDs: []uint64{8453}},
+		{name: "native address on any chain", ref: "0xaf88d065e77c8cC2239327C5EDb3A432268e5831"},
+		{name: "native address on another chain", ref: "0xaf88d065e77c8cC2239327C5EDb3A432268e5831", chainIDs: []uint64{42161, 1}, err: ErrUnknown},
+		{name: "bridged symbol", ref: "USDC.e", chainIDs: []uint64{42161}, err: ErrBridged},
+		{name: "bridged address", ref: "0xd9aAEc86B65D86f6A7B5B1b0c42FFA531710b6CA", err: ErrBridged},
+		{name: "other token", ref: "DAI", err: ErrUnknown},
+		{name: "unsupported chain", ref: "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48", chainIDs: []uint64{10}, err: ErrUnknown},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := CheckUSDC(tc.ref, tc.chainIDs...)
+			if (tc.err == nil && err != nil) || !errors.Is(err, tc.err) {
+				t.Errorf("Expected %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func Test_CCTPUSDC(t *testing.T) {
+	for chainID, address := range NativeUSDCAddresses() {
+		token, err := CCTPUSDC(chainID)
+		if err != nil || token.Address != address || token.Decimals != 6 {
+			t.Errorf("Expected native USDC %s to move through CCTP on chain %d, got %+v, %v", address.Hex(), chainID, token, err)
+		}
+	}
+	if _, err := CCTPUSDC(10); err == nil {
+		t.Errorf("Expected a chain without native USDC to be rejected")
+	}
+}
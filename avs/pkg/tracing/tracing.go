@@ -0,0 +1,104 @@
+// Package tracing exports OpenTelemetry spans of tasks, adapter reads and the RPC and HTTP
+// calls they make over OTLP, so a slow task can be traced down to the call that held it
+// up. Spans are only recorded once Setup installed an exporter; until then they cost
+// nothing.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName is the instrumentation scope of every span of the performer
+const TracerName = "github.com/najnomics/crosscow-avs"
+
+// Config configures span export
+type Config struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Endpoint is the URL of the OTLP/HTTP collector, such as http://localhost:4318. Spans
+	// are posted to its /v1/traces path unless the URL has a path of its own.
+	Endpoint string `yaml:"endpoint"`
+
+	// Headers are sent with every export, typically the API key of a hosted collector
+	Headers map[string]string `yaml:"headers"`
+
+	ServiceName string `yaml:"serviceName"`
+
+	// SampleRatio is the share of tasks traced, between 0 and 1
+	SampleRatio float64 `yaml:"sampleRatio"`
+}
+
+// DefaultConfig leaves tracing disabled, sampling every task once enabled
+func DefaultConfig() Config {
+	return Config{ServiceName: "yield-intelligence-performer", SampleRatio: 1}
+}
+
+// Validate checks the config for values spans cannot be exported with
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	u, err := url.Parse(c.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("endpoint must be an http or https url")
+	}
+	if c.ServiceName == "" {
+		return fmt.Errorf("serviceName is required")
+	}
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		return fmt.Errorf("sampleRatio must be between 0 and 1")
+	}
+	return nil
+}
+
+// Setup installs a tracer provider exporting to the collector of cfg as the global one,
+// and traces HTTP requests sent through http.DefaultTransport, which the HTTP and
+// JSON-RPC clients of the performer use. The returned function flushes pending spans and
+// stops the exporter.
+func Setup(cfg Config) (func(context.Context) error, error) {
+	if !cfg.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	// spans are exported through the untraced transport, or every export would be traced
+	transport := http.DefaultTransport
+	exporter, err := newExporter(cfg.Endpoint, cfg.Headers, transport)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create span exporter: %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", cfg.ServiceName))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	http.DefaultTransport = otelhttp.NewTransport(transport)
+	return provider.Shutdown, nil
+}
+
+// Start starts a span named name as a child of the span in ctx
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(TracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records err on span, when set, and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
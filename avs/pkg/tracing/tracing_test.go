@@ -0,0 +1,133 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func Test_ConfigValidate(t *testing.T) {
+	valid := DefaultConfig()
+	valid.Enabled = true
+	valid.Endpoint = "http://localhost:4318"
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected %+v to be valid: %v", valid, err)
+	}
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Errorf("Expected the disabled default to be valid: %v", err)
+	}
+
+	testCases := map[string]func(c *Config){
+		"endpoint without scheme": func(c *Config) { c.Endpoint = "localhost:4318" },
+		"missing service name":    func(c *Config) { c.ServiceName = "" },
+		"sample ratio above one":  func(c *Config) { c.SampleRatio = 1.5 },
+	}
+	for name, mutate := range testCases {
+		t.Run(name, func(t *testing.T) {
+			cfg := valid
+			mutate(&cfg)
+			if err := cfg.Validate(); err == nil {
+				t.Errorf("Expected %+v to be invalid", cfg)
+			}
+		})
+	}
+}
+
+func Test_ExporterPostsOTLPJSON(t *testing.T) {
+	var (
+		path     string
+		apiKey   string
+		requests []otlpRequest
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, apiKey = r.URL.Path, r.Header.Get("x-api-key")
+		body, _ := io.ReadAll(r.Body)
+		var request otlpRequest
+		if err := json.Unmarshal(body, &request); err != nil {
+			t.Errorf("Expected a JSON body: %v", err)
+		}
+		requests = append(requests, request)
+	}))
+	defer server.Close()
+
+	exporter, err := newExporter(server.URL, map[string]string{"x-api-key": "secret"}, http.DefaultTransport)
+	if err != nil {
+		t.Fatalf("newExporter failed: %v", err)
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	tracer := provider.Tracer(TracerName)
+
+	ctx, parent := tracer.Start(context.Background(), "HandleTask")
+	_, child := tracer.Start(ctx, "rpc BlockNumber")
+	child.SetAttributes(attribute.String("chain", "ethereum"), attribute.Int("attempts", 2))
+	child.AddEvent("retry")
+	End(child, errors.New("connection refused"))
+	parent.End()
+	if err := provider.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	if path != tracesPath || apiKey != "secret" {
+		t.Errorf("Expected a post to %s with the configured headers, got %s with key %q", tracesPath, path, apiKey)
+	}
+	// spans are exported as they end, so the child comes first and the parent follows
+	if len(requests) != 2 {
+		t.Fatalf("Expected a request per span, got %d", len(requests))
+	}
+	request := requests[0]
+	if len(request.ResourceSpans) != 1 || len(request.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("Expected one resource and scope, got %+v", request)
+	}
+	scope := request.ResourceSpans[0].ScopeSpans[0]
+	if scope.Scope.Name != TracerName || len(scope.Spans) != 1 {
+		t.Fatalf("Expected the span of the tracer, got %+v", scope)
+	}
+	span := scope.Spans[0]
+	if span.Name != "rpc BlockNumber" || span.ParentSpanID != parent.SpanContext().SpanID().String() {
+		t.Errorf("Expected the child span, got %+v", span)
+	}
+	if span.TraceID != parent.SpanContext().TraceID().String() {
+		t.Errorf("Expected the trace id of the parent, got %s", span.TraceID)
+	}
+	if span.Status.Code != otlpStatusError || span.Status.Message != "connection refused" {
+		t.Errorf("Expected an error status, got %+v", span.Status)
+	}
+	if len(span.Events) != 2 || span.Events[0].Name != "retry" {
+		t.Errorf("Expected the retry and error events, got %+v", span.Events)
+	}
+	attrs := make(map[string]otlpValue)
+	for _, attr := range span.Attributes {
+		attrs[attr.Key] = attr.Value
+	}
+	if v := attrs["chain"].StringValue; v == nil || *v != "ethereum" {
+		t.Errorf("Expected a string chain attribute, got %+v", attrs["chain"])
+	}
+	if v := attrs["attempts"].IntValue; v == nil || *v != "2" {
+		t.Errorf("Expected an int attempts attribute, got %+v", attrs["attempts"])
+	}
+}
+
+func Test_ExporterReportsCollectorErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	exporter, err := newExporter(server.URL, nil, http.DefaultTransport)
+	if err != nil {
+		t.Fatalf("newExporter failed: %v", err)
+	}
+	provider := sdktrace.NewTracerProvider()
+	_, span := provider.Tracer(TracerName).Start(context.Background(), "task")
+	span.End()
+	if err := exporter.ExportSpans(context.Background(), []sdktrace.ReadOnlySpan{span.(sdktrace.ReadOnlySpan)}); err == nil {
+		t.Error("Expected the collector error to be reported")
+	}
+}
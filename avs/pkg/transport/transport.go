@@ -0,0 +1,142 @@
+// Package transport secures the connections of the performer servers exposed beyond
+// localhost: TLS, and mutual TLS when clients must present a certificate issued by a
+// configured authority.
+package transport
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// TLSConfig configures TLS on a server
+type TLSConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// CertFile and KeyFile hold the PEM certificate chain and key of the server. They are
+	// read again when they change, so renewed certificates are served without a restart.
+	CertFile string `yaml:"certFile"`
+	KeyFile  string `yaml:"keyFile"`
+
+	// ClientCAFile holds the PEM certificates of the authorities client certificates must
+	// be issued by. When set, connections without such a certificate are refused.
+	ClientCAFile string `yaml:"clientCAFile"`
+}
+
+// Validate checks the config for missing files
+func (c TLSConfig) Validate() error {
+	if c.Enabled && (c.CertFile == "" || c.KeyFile == "") {
+		return fmt.Errorf("certFile and keyFile are required")
+	}
+	return nil
+}
+
+// Mutual reports whether clients must present a certificate
+func (c TLSConfig) Mutual() bool {
+	return c.Enabled && c.ClientCAFile != ""
+}
+
+// ServerConfig returns the TLS config of a server, or nil when TLS is disabled. The
+// certificate and client authorities are read now, so missing or invalid files fail at
+// startup.
+func ServerConfig(cfg TLSConfig) (*tls.Config, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	cert := &certificate{certFile: cfg.CertFile, keyFile: cfg.KeyFile}
+	if _, err := cert.load(); err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: cert.get,
+	}
+	if cfg.ClientCAFile != "" {
+		pool, err := readCertPool(cfg.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
+
+// ClientConfig returns the TLS config of a client trusting the authorities in caFile, or
+// the system ones when it is empty, and presenting the certificate in certFile and
+// keyFile when they are set
+func ClientConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		pool, err := readCertPool(caFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+func readCertPool(file string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate authorities: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no PEM certificate in %s", file)
+	}
+	return pool, nil
+}
+
+// certificate serves the key pair in its files, read again when the certificate file
+// changes
+type certificate struct {
+	certFile, keyFile string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+// get returns the current certificate. A renewed pair that fails to load, such as one
+// whose key is not written yet, leaves the previous certificate served.
+func (c *certificate) get(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cert, err := c.load()
+	if err != nil {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.cert != nil {
+			return c.cert, nil
+		}
+	}
+	return cert, err
+}
+
+func (c *certificate) load() (*tls.Certificate, error) {
+	info, err := os.Stat(c.certFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate: %w", err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cert != nil && info.ModTime().Equal(c.modTime) {
+		return c.cert, nil
+	}
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load certificate: %w", err)
+	}
+	c.cert, c.modTime = &cert, info.ModTime()
+	return c.cert, nil
+}
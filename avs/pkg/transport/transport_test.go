@@ -0,0 +1,118 @@
+package transport
+
+import (
+	"crypto/tls"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/najnomics/crosscow-avs/pkg/transport/transporttest"
+)
+
+func Test_MutualTLS(t *testing.T) {
+	ca := transporttest.NewCA(t)
+	certFile, keyFile := ca.Server("server")
+	cfg := TLSConfig{Enabled: true, CertFile: certFile, KeyFile: keyFile, ClientCAFile: ca.CertFile}
+	serverTLS, err := ServerConfig(cfg)
+	if err != nil {
+		t.Fatalf("ServerConfig failed: %v", err)
+	}
+	// StartTLS would serve the certificate of httptest
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	ts.Listener = tls.NewListener(ts.Listener, serverTLS)
+	ts.Config.ErrorLog = log.New(io.Discard, "", 0)
+	ts.Start()
+	defer ts.Close()
+	url := strings.Replace(ts.URL, "http://", "https://", 1)
+
+	get := func(clientTLS *tls.Config) error {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+		resp, err := client.Get(url)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+	anonymous, err := ClientConfig(ca.CertFile, "", "")
+	if err != nil {
+		t.Fatalf("ClientConfig failed: %v", err)
+	}
+	if err := get(anonymous); err == nil {
+		t.Errorf("Expected a client without a certificate to be refused")
+	}
+	clientCert, clientKey := ca.Client("executor")
+	authenticated, err := ClientConfig(ca.CertFile, clientCert, clientKey)
+	if err != nil {
+		t.Fatalf("ClientConfig failed: %v", err)
+	}
+	if err := get(authenticated); err != nil {
+		t.Errorf("Expected a client with a certificate to be served: %v", err)
+	}
+	otherCert, otherKey := transporttest.NewCA(t).Client("stranger")
+	if untrusted, err := ClientConfig(ca.CertFile, otherCert, otherKey); err != nil {
+		t.Fatalf("ClientConfig failed: %v", err)
+	} else if err := get(untrusted); err == nil {
+		t.Errorf("Expected a certificate of another authority to be refused")
+	}
+}
+
+func Test_RenewedCertificateIsServed(t *testing.T) {
+	ca := transporttest.NewCA(t)
+	certFile, keyFile := ca.Server("server")
+	cert := &certificate{certFile: certFile, keyFile: keyFile}
+	first, err := cert.get(nil)
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+
+	renewedCert, renewedKey := ca.Server("renewed")
+	for from, to := range map[string]string{renewedCert: certFile, renewedKey: keyFile} {
+		data, err := os.ReadFile(from)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(to, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(certFile, later, later); err != nil {
+		t.Fatal(err)
+	}
+	renewed, err := cert.get(nil)
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	if renewed.Leaf.Subject.CommonName != "renewed" || first.Leaf.Subject.CommonName != "server" {
+		t.Errorf("Expected the renewed certificate to be served, got %s", renewed.Leaf.Subject.CommonName)
+	}
+
+	// a pair caught mid-write keeps the previous certificate served
+	if err := os.WriteFile(keyFile, []byte("partial"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	later = later.Add(time.Minute)
+	if err := os.Chtimes(certFile, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if served, err := cert.get(nil); err != nil || served != renewed {
+		t.Errorf("Expected the previous certificate to be served, got %v", err)
+	}
+}
+
+func Test_TLSConfigValidate(t *testing.T) {
+	if err := (TLSConfig{}).Validate(); err != nil {
+		t.Errorf("Expected disabled TLS to be valid: %v", err)
+	}
+	if err := (TLSConfig{Enabled: true, CertFile: "cert.pem"}).Validate(); err == nil {
+		t.Errorf("Expected a missing key file to be rejected")
+	}
+	if _, err := ServerConfig(TLSConfig{Enabled: true, CertFile: "missing.pem", KeyFile: "missing-key.pem"}); err == nil {
+		t.Errorf("Expected missing files to fail")
+	}
+}
@@ -0,0 +1,109 @@
+// Package transporttest issues certificates from a throwaway authority, for tests of
+// servers secured with TLS and client certificates.
+package transporttest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// CA is a certificate authority whose certificate is written to CertFile
+type CA struct {
+	CertFile string
+
+	t      *testing.T
+	dir    string
+	cert   *x509.Certificate
+	key    *ecdsa.PrivateKey
+	serial int64
+}
+
+// NewCA creates an authority writing its files to a temporary directory of t
+func NewCA(t *testing.T) *CA {
+	t.Helper()
+	key := newKey(t)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test authority"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create the authority: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse the authority: %v", err)
+	}
+	ca := &CA{t: t, dir: t.TempDir(), cert: cert, key: key, serial: 1}
+	ca.CertFile = ca.write("ca.pem", "CERTIFICATE", der)
+	return ca
+}
+
+// Server issues a certificate for localhost and 127.0.0.1, and returns its certificate
+// and key files
+func (ca *CA) Server(name string) (certFile, keyFile string) {
+	return ca.issue(name, x509.ExtKeyUsageServerAuth)
+}
+
+// Client issues a client certificate, and returns its certificate and key files
+func (ca *CA) Client(name string) (certFile, keyFile string) {
+	return ca.issue(name, x509.ExtKeyUsageClientAuth)
+}
+
+func (ca *CA) issue(name string, usage x509.ExtKeyUsage) (string, string) {
+	ca.t.Helper()
+	ca.serial++
+	key := newKey(ca.t)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(ca.serial),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		ca.t.Fatalf("Failed to issue %s: %v", name, err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		ca.t.Fatalf("Failed to encode the key of %s: %v", name, err)
+	}
+	return ca.write(name+".pem", "CERTIFICATE", der), ca.write(name+"-key.pem", "EC PRIVATE KEY", keyDER)
+}
+
+func (ca *CA) write(name, blockType string, der []byte) string {
+	ca.t.Helper()
+	file := filepath.Join(ca.dir, name)
+	if err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		ca.t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	return file
+}
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate a key: %v", err)
+	}
+	return key
+}
@@ -0,0 +1,130 @@
+package txmgr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ErrNotConfirmed is returned when waiting ends before a transaction is final
+var ErrNotConfirmed = errors.New("transaction not confirmed")
+
+// Confirmation is the state of a sent transaction
+type Confirmation struct {
+	// Tx is the version that was mined, or the latest one sent while pending. It differs
+	// from the transaction passed to Confirm when fees were bumped.
+	Tx *types.Transaction
+
+	// Receipt is nil while no version is in a canonical block
+	Receipt       *types.Receipt
+	Confirmations uint64
+
+	// Final is set once the including block is the configured depth below the head
+	Final bool
+
+	// Reorgs counts the times a receipt was found outside the canonical chain
+	Reorgs int
+}
+
+// Reverted reports whether the transaction was mined and failed
+func (c *Confirmation) Reverted() bool {
+	return c.Receipt != nil && c.Receipt.Status != types.ReceiptStatusSuccessful
+}
+
+// RequiredConfirmations is the depth transactions on the manager's chain need
+func (m *Manager) RequiredConfirmations() uint64 {
+	if confirmations, ok := m.cfg.ChainConfirmations[m.chainID.Uint64()]; ok {
+		return confirmations
+	}
+	return m.cfg.Confirmations
+}
+
+// Confirm waits until tx, or a replacement of it, is final or ctx is done. Receipts are
+// checked against the canonical block at their height on every poll, so a transaction
+// reorged out goes back to pending until it is mined again. Transactions pending longer
+// than BumpAfter are replaced with higher fees until the fee cap is reached.
+//
+// The latest state is returned with ErrNotConfirmed when waiting ends early.
+func (m *Manager) Confirm(ctx context.Context, tx *types.Transaction) (*Confirmation, error) {
+	versions := []*types.Transaction{tx}
+	state := &Confirmation{Tx: tx}
+	sentAt := time.Now()
+	bumping := m.cfg.BumpAfter > 0
+
+	for {
+		err := m.check(ctx, versions, state)
+		if err == nil && state.Final {
+			return state, nil
+		}
+
+		if err == nil && bumping && state.Receipt == nil && time.Since(sentAt) >= m.cfg.BumpAfter {
+			bumped, bumpErr := m.Bump(ctx, versions[len(versions)-1])
+			if bumpErr != nil {
+				// the fee cap is reached or the node refused the replacement; keep
+				// waiting for the versions already sent
+				bumping = false
+			} else {
+				versions = append(versions, bumped)
+				state.Tx = bumped
+				sentAt = time.Now()
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			if err == nil {
+				err = ctx.Err()
+			}
+			return state, fmt.Errorf("%w: %s: %w", ErrNotConfirmed, state.Tx.Hash().Hex(), err)
+		case <-time.After(m.cfg.PollInterval):
+		}
+	}
+}
+
+// check updates state with the receipt of the newest mined version
+func (m *Manager) check(ctx context.Context, versions []*types.Transaction, state *Confirmation) error {
+	for i := len(versions) - 1; i >= 0; i-- {
+		receipt, err := m.client.TransactionReceipt(ctx, versions[i].Hash())
+		if errors.Is(err, ethereum.NotFound) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read receipt: %w", err)
+		}
+
+		header, err := m.client.HeaderByNumber(ctx, receipt.BlockNumber)
+		if err != nil && !errors.Is(err, ethereum.NotFound) {
+			return fmt.Errorf("failed to read block %s: %w", receipt.BlockNumber, err)
+		}
+		if header == nil || header.Hash() != receipt.BlockHash {
+			if state.Receipt != nil {
+				state.Reorgs++
+			}
+			state.Receipt, state.Confirmations, state.Final = nil, 0, false
+			return nil
+		}
+
+		head, err := m.client.BlockNumber(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to read head: %w", err)
+		}
+		state.Tx = versions[i]
+		state.Receipt = receipt
+		state.Confirmations = 0
+		if mined := receipt.BlockNumber.Uint64(); head >= mined {
+			state.Confirmations = head - mined + 1
+		}
+		state.Final = state.Confirmations >= m.RequiredConfirmations()
+		return nil
+	}
+	if state.Receipt != nil {
+		// a receipt seen before is gone: the block was reorged out
+		state.Reorgs++
+	}
+	state.Receipt, state.Confirmations, state.Final = nil, 0, false
+	return nil
+}
@@ -0,0 +1,117 @@
+package txmgr
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func fastConfig() Config {
+	cfg := DefaultConfig()
+	cfg.PollInterval = time.Millisecond
+	cfg.BumpAfter = 0
+	return cfg
+}
+
+// waitSent blocks until n transactions were accepted, or returns nil after a second
+func waitSent(sent func() []*types.Transaction, n int) []*types.Transaction {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if txs := sent(); len(txs) >= n {
+			return txs
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return nil
+}
+
+func Test_ConfirmWaitsForDepth(t *testing.T) {
+	m, client := newTestManager(t, fastConfig())
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	tx, err := m.Send(ctx, Request{Call: approveCall(t)})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	receipt := client.Mine(tx.Hash(), types.ReceiptStatusSuccessful, 45_000)
+
+	short, cancelShort := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancelShort()
+	state, err := m.Confirm(short, tx)
+	if !errors.Is(err, ErrNotConfirmed) || state.Final || state.Confirmations != 1 || state.Receipt == nil {
+		t.Fatalf("Expected a receipt without the required depth, got %+v, %v", state, err)
+	}
+
+	client.AdvanceBlocks(2)
+	state, err = m.Confirm(ctx, tx)
+	if err != nil {
+		t.Fatalf("Confirm failed: %v", err)
+	}
+	if !state.Final || state.Confirmations != 3 || state.Receipt.BlockHash != receipt.BlockHash || state.Reverted() {
+		t.Errorf("Unexpected confirmation: %+v", state)
+	}
+}
+
+func Test_ConfirmSurvivesReorg(t *testing.T) {
+	m, client := newTestManager(t, fastConfig())
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	tx, err := m.Send(ctx, Request{Call: approveCall(t)})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	receipt := client.Mine(tx.Hash(), types.ReceiptStatusSuccessful, 45_000)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// let the monitor see the receipt, then replace its block and include the
+		// transaction again deeper in the new chain
+		time.Sleep(10 * time.Millisecond)
+		client.Reorg(receipt.BlockNumber.Uint64())
+		time.Sleep(10 * time.Millisecond)
+		client.Mine(tx.Hash(), types.ReceiptStatusFailed, 45_000)
+		client.AdvanceBlocks(2)
+	}()
+
+	state, err := m.Confirm(ctx, tx)
+	<-done
+	if err != nil {
+		t.Fatalf("Confirm failed: %v", err)
+	}
+	if state.Reorgs != 1 || state.Receipt.BlockNumber.Cmp(receipt.BlockNumber) <= 0 || !state.Reverted() {
+		t.Errorf("Expected the receipt of the new chain after one reorg, got %+v", state)
+	}
+}
+
+func Test_ConfirmBumpsStuckTransactions(t *testing.T) {
+	cfg := fastConfig()
+	cfg.BumpAfter = 5 * time.Millisecond
+	cfg.Confirmations = 1
+	m, client := newTestManager(t, cfg)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	tx, err := m.Send(ctx, Request{Call: approveCall(t)})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	go func() {
+		if sent := waitSent(client.Sent, 2); sent != nil {
+			client.Mine(sent[1].Hash(), types.ReceiptStatusSuccessful, 45_000)
+		}
+	}()
+
+	state, err := m.Confirm(ctx, tx)
+	if err != nil {
+		t.Fatalf("Confirm failed: %v", err)
+	}
+	if state.Tx.Hash() == tx.Hash() || state.Tx.Nonce() != tx.Nonce() || state.Tx.GasTipCap().Cmp(tx.GasTipCap()) <= 0 {
+		t.Errorf("Expected the bumped replacement to be confirmed, got %s", state.Tx.Hash().Hex())
+	}
+}
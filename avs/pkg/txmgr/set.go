@@ -0,0 +1,146 @@
+package txmgr
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/resilience"
+	"github.com/najnomics/crosscow-avs/pkg/signer"
+)
+
+// Set holds the managers of every configured chain
+type Set struct {
+	chains *chain.Manager
+	cfg    Config
+
+	mu       sync.Mutex
+	signer   signer.Signer
+	signers  map[uint64]signer.Signer
+	private  map[uint64]Broadcaster
+	managers map[uint64]*Manager
+}
+
+// NewSet creates managers for transactions signed by s on demand
+func NewSet(chains *chain.Manager, s signer.Signer, cfg Config) *Set {
+	return &Set{
+		chains:   chains,
+		cfg:      cfg,
+		signer:   s,
+		signers:  make(map[uint64]signer.Signer),
+		private:  make(map[uint64]Broadcaster),
+		managers: make(map[uint64]*Manager),
+	}
+}
+
+// NewFromConfig creates the signers in cfg and a set sending with them. It returns nil
+// when transactions are disabled. Requests to remote signers are retried under policy,
+// which may be nil.
+func NewFromConfig(ctx context.Context, cfg Config, chains *chain.Manager, policy *resilience.Policy) (*Set, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	s, err := signer.New(ctx, cfg.Signer, policy)
+	if err != nil {
+		return nil, err
+	}
+	set := NewSet(chains, s, cfg)
+	for chainID, signerCfg := range cfg.ChainSigners {
+		chainSigner, err := signer.New(ctx, signerCfg, policy)
+		if err != nil {
+			return nil, fmt.Errorf("signer of chain %d: %w", chainID, err)
+		}
+		set.UseSigner(chainID, chainSigner)
+	}
+	for _, chainID := range chains.ChainIDs() {
+		url := cfg.ProtectionFor(chainID).PrivateRpcUrl
+		if url == "" {
+			continue
+		}
+		private, err := ethclient.DialContext(ctx, url)
+		if err != nil {
+			return nil, fmt.Errorf("private rpc of chain %d: %w", chainID, err)
+		}
+		set.UseBroadcaster(chainID, private)
+	}
+	return set, nil
+}
+
+// UseSigner signs transactions on chainID with s instead of the default signer. It must
+// be called before the first transaction on chainID.
+func (s *Set) UseSigner(chainID uint64, chainSigner signer.Signer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.signers[chainID] = chainSigner
+}
+
+// UseBroadcaster sends transactions on chainID through b instead of the chain client. It
+// must be called before the first transaction on chainID.
+func (s *Set) UseBroadcaster(chainID uint64, b Broadcaster) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.private[chainID] = b
+}
+
+// Protection returns the protection configured for chainID
+func (s *Set) Protection(chainID uint64) Protection {
+	return s.cfg.ProtectionFor(chainID)
+}
+
+// Client is the client transactions on chainID are read with
+func (s *Set) Client(chainID uint64) (chain.Client, error) {
+	return s.chains.Client(chainID)
+}
+
+// Address is the account transactions on chainID are sent from
+func (s *Set) Address(chainID uint64) common.Address {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.signerLocked(chainID).Address()
+}
+
+// Signer is the signer of the account transactions on chainID are sent from
+func (s *Set) Signer(chainID uint64) signer.Signer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.signerLocked(chainID)
+}
+
+func (s *Set) signerLocked(chainID uint64) signer.Signer {
+	if chainSigner, ok := s.signers[chainID]; ok {
+		return chainSigner
+	}
+	return s.signer
+}
+
+// For returns the manager of chainID. Managers are kept so nonces stay tracked across
+// calls.
+func (s *Set) For(chainID uint64) (*Manager, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if m, ok := s.managers[chainID]; ok {
+		return m, nil
+	}
+	client, err := s.chains.Client(chainID)
+	if err != nil {
+		return nil, err
+	}
+	m := NewManager(client, chainID, s.signerLocked(chainID), s.cfg)
+	if private, ok := s.private[chainID]; ok {
+		m.UseBroadcaster(private)
+	}
+	s.managers[chainID] = m
+	return m, nil
+}
+
+// ConfirmationTimeout is how long tasks wait for their transactions to be final
+func (s *Set) ConfirmationTimeout() time.Duration {
+	if s.cfg.ConfirmationTimeout <= 0 {
+		return DefaultConfig().ConfirmationTimeout
+	}
+	return s.cfg.ConfirmationTimeout
+}
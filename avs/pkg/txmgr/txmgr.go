@@ -0,0 +1,437 @@
+// Package txmgr builds, signs and broadcasts transactions with locally tracked nonces and
+// EIP-1559 fees, and replaces stuck transactions with higher fees.
+package txmgr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/resilience"
+	"github.com/najnomics/crosscow-avs/pkg/signer"
+)
+
+// MinBumpPercent is the fee increase nodes require to replace a pending transaction
+const MinBumpPercent = 10
+
+// MaxBps is the basis points of a whole amount
+const MaxBps = 10_000
+
+// ErrFeeCapReached is returned when the fees a transaction needs exceed the configured cap
+var ErrFeeCapReached = errors.New("fee cap reached")
+
+// Config enables direct submission of transactions and sets how their gas and fees are
+// chosen
+type Config struct {
+	Enabled bool          `yaml:"enabled"`
+	Signer  signer.Config `yaml:"signer"`
+
+	// ChainSigners selects a different signer per chain id. Chains not listed use Signer.
+	ChainSigners map[uint64]signer.Config `yaml:"chainSigners"`
+
+	// GasLimitMultiplier pads gas estimates so state changes between estimation and
+	// inclusion do not run transactions out of gas
+	GasLimitMultiplier float64 `yaml:"gasLimitMultiplier"`
+
+	// BaseFeeMultiplier sets the fee cap to this multiple of the latest base fee plus the
+	// tip, keeping transactions includable while base fees rise
+	BaseFeeMultiplier float64 `yaml:"baseFeeMultiplier"`
+
+	// MinTipGwei is the lowest priority fee paid, whatever the node suggests
+	MinTipGwei float64 `yaml:"minTipGwei"`
+
+	// MaxFeeGwei caps the fee cap of every transaction, bumps included. Zero leaves fees
+	// uncapped.
+	MaxFeeGwei float64 `yaml:"maxFeeGwei"`
+
+	// BumpPercent raises both fees of replacement transactions
+	BumpPercent int `yaml:"bumpPercent"`
+
+	// Confirmations is the number of blocks, the including block counted, a transaction
+	// needs before its receipt is final. ChainConfirmations overrides it per chain id.
+	Confirmations      uint64            `yaml:"confirmations"`
+	ChainConfirmations map[uint64]uint64 `yaml:"chainConfirmations"`
+
+	// ConfirmationTimeout bounds how long a task waits for its transactions to be final
+	ConfirmationTimeout time.Duration `yaml:"confirmationTimeout"`
+
+	// PollInterval is how often receipts are checked while waiting
+	PollInterval time.Duration `yaml:"pollInterval"`
+
+	// BumpAfter replaces transactions still pending after this long with higher fees.
+	// Zero never bumps.
+	BumpAfter time.Duration `yaml:"bumpAfter"`
+
+	// Protection applies to every chain. ChainProtection overrides it per chain id; fields
+	// left empty there keep the value of Protection.
+	Protection      Protection            `yaml:"protection"`
+	ChainProtection map[uint64]Protection `yaml:"chainProtection"`
+}
+
+// Protection guards transactions against front-running and funds lost in transit
+type Protection struct {
+	// PrivateRpcUrl broadcasts signed transactions through a private mempool, such as
+	// Flashbots Protect or MEV Blocker, instead of the public RPC endpoint of the chain.
+	// Reads still go to the public endpoint.
+	PrivateRpcUrl string `yaml:"privateRpcUrl"`
+
+	// MaxSlippageBps is the most of an amount a transfer may lose, to fees or to
+	// transactions ordered around it, before it is reported as anomalous
+	MaxSlippageBps uint64 `yaml:"maxSlippageBps"`
+}
+
+// ProtectionFor returns the protection of chainID
+func (c Config) ProtectionFor(chainID uint64) Protection {
+	protection := c.Protection
+	if override, ok := c.ChainProtection[chainID]; ok {
+		if override.PrivateRpcUrl != "" {
+			protection.PrivateRpcUrl = override.PrivateRpcUrl
+		}
+		if override.MaxSlippageBps > 0 {
+			protection.MaxSlippageBps = override.MaxSlippageBps
+		}
+	}
+	return protection
+}
+
+func (p Protection) validate() error {
+	if p.MaxSlippageBps > MaxBps {
+		return fmt.Errorf("maxSlippageBps must not exceed %d", MaxBps)
+	}
+	return nil
+}
+
+// DefaultConfig returns the fee settings used when transactions are enabled
+func DefaultConfig() Config {
+	return Config{
+		Signer:             signer.DefaultConfig(),
+		GasLimitMultiplier: 1.2,
+		BaseFeeMultiplier:  2,
+		BumpPercent:        15,
+
+		Confirmations:       3,
+		ConfirmationTimeout: 2 * time.Minute,
+		PollInterval:        2 * time.Second,
+		BumpAfter:           time.Minute,
+
+		Protection: Protection{MaxSlippageBps: 10},
+	}
+}
+
+// Validate checks the config for values transactions cannot be sent with
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if err := c.Signer.Validate(); err != nil {
+		return fmt.Errorf("signer: %w", err)
+	}
+	for chainID, cfg := range c.ChainSigners {
+		if err := cfg.Validate(); err != nil {
+			return fmt.Errorf("chainSigners[%d]: %w", chainID, err)
+		}
+	}
+	if c.GasLimitMultiplier < 1 {
+		return fmt.Errorf("gasLimitMultiplier must be at least 1")
+	}
+	if c.BaseFeeMultiplier < 1 {
+		return fmt.Errorf("baseFeeMultiplier must be at least 1")
+	}
+	if c.MinTipGwei < 0 || c.MaxFeeGwei < 0 {
+		return fmt.Errorf("minTipGwei and maxFeeGwei must not be negative")
+	}
+	if c.MaxFeeGwei > 0 && c.MinTipGwei > c.MaxFeeGwei {
+		return fmt.Errorf("minTipGwei must not exceed maxFeeGwei")
+	}
+	if c.BumpPercent < MinBumpPercent {
+		return fmt.Errorf("bumpPercent must be at least %d", MinBumpPercent)
+	}
+	if c.Confirmations < 1 {
+		return fmt.Errorf("confirmations must be at least 1")
+	}
+	for chainID, confirmations := range c.ChainConfirmations {
+		if confirmations < 1 {
+			return fmt.Errorf("chainConfirmations[%d] must be at least 1", chainID)
+		}
+	}
+	if c.ConfirmationTimeout <= 0 || c.PollInterval <= 0 {
+		return fmt.Errorf("confirmationTimeout and pollInterval must be positive")
+	}
+	if c.BumpAfter < 0 {
+		return fmt.Errorf("bumpAfter must not be negative")
+	}
+	if err := c.Protection.validate(); err != nil {
+		return fmt.Errorf("protection: %w", err)
+	}
+	for chainID, protection := range c.ChainProtection {
+		if err := protection.validate(); err != nil {
+			return fmt.Errorf("chainProtection[%d]: %w", chainID, err)
+		}
+	}
+	return nil
+}
+
+// Request is a call to send as a transaction
+type Request struct {
+	Call chain.Call
+
+	// GasLimit is used as is when set. Otherwise the gas is estimated.
+	GasLimit uint64
+
+	// FallbackGas is used when estimation reverts, for calls that only succeed after
+	// transactions sent before them are mined. Without it a reverting estimate fails
+	// the request.
+	FallbackGas uint64
+}
+
+// Broadcaster sends signed transactions to a network
+type Broadcaster interface {
+	SendTransaction(ctx context.Context, tx *types.Transaction) error
+}
+
+// Manager sends transactions of one account on one chain. Nonces are assigned locally so
+// transactions can be sent back to back without waiting for each to be mined.
+type Manager struct {
+	client  chain.Client
+	chainID *big.Int
+	signer  signer.Signer
+	cfg     Config
+
+	// broadcaster sends transactions instead of client when set
+	broadcaster Broadcaster
+
+	mu     sync.Mutex
+	nonce  uint64
+	synced bool
+}
+
+// NewManager creates a manager sending transactions signed by s on chainID
+func NewManager(client chain.Client, chainID uint64, s signer.Signer, cfg Config) *Manager {
+	defaults := DefaultConfig()
+	if cfg.GasLimitMultiplier < 1 {
+		cfg.GasLimitMultiplier = defaults.GasLimitMultiplier
+	}
+	if cfg.BaseFeeMultiplier < 1 {
+		cfg.BaseFeeMultiplier = defaults.BaseFeeMultiplier
+	}
+	if cfg.BumpPercent < MinBumpPercent {
+		cfg.BumpPercent = defaults.BumpPercent
+	}
+	if cfg.Confirmations < 1 {
+		cfg.Confirmations = defaults.Confirmations
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaults.PollInterval
+	}
+	return &Manager{
+		client:  client,
+		chainID: new(big.Int).SetUint64(chainID),
+		signer:  s,
+		cfg:     cfg,
+	}
+}
+
+// UseBroadcaster sends transactions through b, such as a private mempool, while reads
+// stay on the chain client. It must be called before the first transaction is sent.
+func (m *Manager) UseBroadcaster(b Broadcaster) {
+	m.broadcaster = b
+}
+
+// Address is the account transactions are sent from
+func (m *Manager) Address() common.Address {
+	return m.signer.Address()
+}
+
+// Send signs and broadcasts req with the next nonce of the account. The nonce is only
+// consumed when the node accepts the transaction.
+func (m *Manager) Send(ctx context.Context, req Request) (*types.Transaction, error) {
+	gas, err := m.gasLimit(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	tip, feeCap, err := m.fees(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.synced {
+		nonce, err := m.client.PendingNonceAt(ctx, m.Address())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read nonce: %w", err)
+		}
+		m.nonce, m.synced = nonce, true
+	}
+
+	to := req.Call.To
+	tx, err := m.signAndSend(ctx, &types.DynamicFeeTx{
+		ChainID:   m.chainID,
+		Nonce:     m.nonce,
+		GasTipCap: tip,
+		GasFeeCap: feeCap,
+		Gas:       gas,
+		To:        &to,
+		Value:     new(big.Int),
+		Data:      req.Call.Data,
+	})
+	if err != nil {
+		if isNonceError(err) {
+			// Transactions were sent from the account elsewhere; read the nonce again
+			// on the next send
+			m.synced = false
+		}
+		return nil, err
+	}
+	m.nonce++
+	return tx, nil
+}
+
+// Bump replaces the pending tx with a copy paying at least BumpPercent more in both fees,
+// and no less than current fees
+func (m *Manager) Bump(ctx context.Context, tx *types.Transaction) (*types.Transaction, error) {
+	if tx.Type() != types.DynamicFeeTxType {
+		return nil, fmt.Errorf("cannot bump transaction of type %d", tx.Type())
+	}
+	tip, feeCap, err := m.fees(ctx)
+	if err != nil && !errors.Is(err, ErrFeeCapReached) {
+		return nil, err
+	}
+
+	minTip := bump(tx.GasTipCap(), m.cfg.BumpPercent)
+	minFeeCap := bump(tx.GasFeeCap(), m.cfg.BumpPercent)
+	if err != nil || tip.Cmp(minTip) < 0 {
+		tip = minTip
+	}
+	if err != nil || feeCap.Cmp(minFeeCap) < 0 {
+		feeCap = minFeeCap
+	}
+	if feeCap.Cmp(tip) < 0 {
+		feeCap = new(big.Int).Set(tip)
+	}
+	if maxFee := m.maxFee(); maxFee != nil && feeCap.Cmp(maxFee) > 0 {
+		return nil, fmt.Errorf("%w: replacing %s needs a fee cap of %s wei", ErrFeeCapReached, tx.Hash().Hex(), feeCap)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.signAndSend(ctx, &types.DynamicFeeTx{
+		ChainID:   m.chainID,
+		Nonce:     tx.Nonce(),
+		GasTipCap: tip,
+		GasFeeCap: feeCap,
+		Gas:       tx.Gas(),
+		To:        tx.To(),
+		Value:     tx.Value(),
+		Data:      tx.Data(),
+	})
+}
+
+func (m *Manager) signAndSend(ctx context.Context, unsigned *types.DynamicFeeTx) (*types.Transaction, error) {
+	tx, err := m.signer.SignTx(ctx, types.NewTx(unsigned), m.chainID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign transaction: %w", err)
+	}
+	var broadcaster Broadcaster = m.client
+	if m.broadcaster != nil {
+		broadcaster = m.broadcaster
+	}
+	if err := broadcaster.SendTransaction(ctx, tx); err != nil && !isKnown(err) {
+		return nil, fmt.Errorf("failed to send transaction %s: %w", tx.Hash().Hex(), err)
+	}
+	return tx, nil
+}
+
+func (m *Manager) gasLimit(ctx context.Context, req Request) (uint64, error) {
+	if req.GasLimit > 0 {
+		return req.GasLimit, nil
+	}
+	to := req.Call.To
+	gas, err := m.client.EstimateGas(ctx, ethereum.CallMsg{From: m.Address(), To: &to, Data: req.Call.Data})
+	if err != nil {
+		if req.FallbackGas > 0 && resilience.Classify(ctx, err) == resilience.ClassFatal {
+			return req.FallbackGas, nil
+		}
+		return 0, fmt.Errorf("failed to estimate gas: %w", err)
+	}
+	return uint64(float64(gas) * m.cfg.GasLimitMultiplier), nil
+}
+
+// fees returns the tip and fee cap for a transaction sent now. When the cap in the config
+// is below the fee cap, ErrFeeCapReached is returned with the uncapped fees.
+func (m *Manager) fees(ctx context.Context) (*big.Int, *big.Int, error) {
+	head, err := m.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read base fee: %w", err)
+	}
+	if head.BaseFee == nil {
+		return nil, nil, fmt.Errorf("chain %s does not support EIP-1559 fees", m.chainID)
+	}
+	tip, err := m.client.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read priority fee: %w", err)
+	}
+	if minTip := gwei(m.cfg.MinTipGwei); tip.Cmp(minTip) < 0 {
+		tip = minTip
+	}
+
+	feeCap := mulFloat(head.BaseFee, m.cfg.BaseFeeMultiplier)
+	feeCap.Add(feeCap, tip)
+	if maxFee := m.maxFee(); maxFee != nil && feeCap.Cmp(maxFee) > 0 {
+		if head.BaseFee.Cmp(maxFee) >= 0 {
+			return tip, feeCap, fmt.Errorf("%w: base fee %s wei exceeds %s wei", ErrFeeCapReached, head.BaseFee, maxFee)
+		}
+		feeCap = maxFee
+		if tip.Cmp(feeCap) > 0 {
+			tip = new(big.Int).Set(feeCap)
+		}
+	}
+	return tip, feeCap, nil
+}
+
+func (m *Manager) maxFee() *big.Int {
+	if m.cfg.MaxFeeGwei <= 0 {
+		return nil
+	}
+	return gwei(m.cfg.MaxFeeGwei)
+}
+
+// bump raises fee by percent, rounding up
+func bump(fee *big.Int, percent int) *big.Int {
+	bumped := new(big.Int).Mul(fee, big.NewInt(int64(100+percent)))
+	bumped.Add(bumped, big.NewInt(99))
+	return bumped.Div(bumped, big.NewInt(100))
+}
+
+func gwei(amount float64) *big.Int {
+	wei, _ := new(big.Float).Mul(big.NewFloat(amount), big.NewFloat(params.GWei)).Int(nil)
+	return wei
+}
+
+func mulFloat(x *big.Int, f float64) *big.Int {
+	product, _ := new(big.Float).Mul(new(big.Float).SetInt(x), big.NewFloat(f)).Int(nil)
+	return product
+}
+
+// isNonceError reports whether the node rejected a transaction because its nonce was
+// already used
+func isNonceError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "nonce too low") || strings.Contains(msg, "replacement transaction underpriced")
+}
+
+// isKnown reports whether the node already has the transaction, as happens when a
+// broadcast is retried after a timeout
+func isKnown(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "already known") || strings.Contains(msg, "known transaction")
+}
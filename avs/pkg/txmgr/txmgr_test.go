@@ -0,0 +1,229 @@
+package txmgr
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/chain/chaintest"
+	"github.com/najnomics/crosscow-avs/pkg/signer"
+)
+
+var (
+	target     = common.HexToAddress("0x01")
+	approveABI = chain.MustParseABI(`[{"type":"function","name":"approve","inputs":[{"name":"spender","type":"address"},{"name":"amount","type":"uint256"}],"outputs":[{"name":"","type":"bool"}]}]`)
+)
+
+func newTestManager(t *testing.T, cfg Config) (*Manager, *chaintest.Contracts) {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	client := chaintest.NewContracts(1)
+	client.StubGas(t, target, approveABI, "approve", 50_000)
+	return NewManager(client, 1, signer.NewKeySigner(key), cfg), client
+}
+
+func approveCall(t *testing.T) chain.Call {
+	t.Helper()
+	data, err := approveABI.Pack("approve", target, big.NewInt(1))
+	if err != nil {
+		t.Fatalf("Failed to pack approve: %v", err)
+	}
+	return chain.Call{To: target, Data: data}
+}
+
+func Test_SendAssignsNoncesAndFees(t *testing.T) {
+	m, client := newTestManager(t, DefaultConfig())
+	client.SetNonce(m.Address(), 7)
+	ctx := context.Background()
+
+	first, err := m.Send(ctx, Request{Call: approveCall(t)})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	// base fee of 1 gwei doubled plus the 0.1 gwei tip
+	if first.Nonce() != 7 || first.Gas() != 60_000 || first.GasTipCap().Int64() != 100_000_000 || first.GasFeeCap().Int64() != 2_100_000_000 {
+		t.Errorf("Unexpected transaction: nonce %d gas %d tip %s cap %s", first.Nonce(), first.Gas(), first.GasTipCap(), first.GasFeeCap())
+	}
+
+	// the second call depends on the first and cannot be estimated yet
+	second, err := m.Send(ctx, Request{Call: chain.Call{To: target, Data: []byte("deposit")}, FallbackGas: 250_000})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if second.Nonce() != 8 || second.Gas() != 250_000 {
+		t.Errorf("Expected the next nonce with fallback gas, got nonce %d gas %d", second.Nonce(), second.Gas())
+	}
+	if len(client.Sent()) != 2 {
+		t.Errorf("Expected two transactions to be broadcast, got %d", len(client.Sent()))
+	}
+
+	if _, err := m.Send(ctx, Request{Call: chain.Call{To: target, Data: []byte("deposit")}}); err == nil {
+		t.Errorf("Expected a reverting estimate without fallback to fail")
+	}
+}
+
+func Test_SendKeepsNonceOfRejectedTransactions(t *testing.T) {
+	m, client := newTestManager(t, DefaultConfig())
+	ctx := context.Background()
+
+	client.FailSends(errors.New("insufficient funds for gas * price + value"))
+	if _, err := m.Send(ctx, Request{Call: approveCall(t)}); err == nil {
+		t.Fatalf("Expected the rejected transaction to fail")
+	}
+	client.FailSends(nil)
+	tx, err := m.Send(ctx, Request{Call: approveCall(t)})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if tx.Nonce() != 0 {
+		t.Errorf("Expected the nonce of the rejected transaction to be reused, got %d", tx.Nonce())
+	}
+
+	// transactions sent from the account elsewhere
+	client.SetNonce(m.Address(), 4)
+	if _, err := m.Send(ctx, Request{Call: approveCall(t)}); err == nil {
+		t.Fatalf("Expected a used nonce to be rejected")
+	}
+	tx, err = m.Send(ctx, Request{Call: approveCall(t)})
+	if err != nil {
+		t.Fatalf("Send after resync failed: %v", err)
+	}
+	if tx.Nonce() != 4 {
+		t.Errorf("Expected the nonce to be read again, got %d", tx.Nonce())
+	}
+}
+
+func Test_Bump(t *testing.T) {
+	m, client := newTestManager(t, DefaultConfig())
+	ctx := context.Background()
+
+	tx, err := m.Send(ctx, Request{Call: approveCall(t)})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	bumped, err := m.Bump(ctx, tx)
+	if err != nil {
+		t.Fatalf("Bump failed: %v", err)
+	}
+	if bumped.Nonce() != tx.Nonce() || bumped.Hash() == tx.Hash() {
+		t.Errorf("Expected a replacement with the same nonce")
+	}
+	if bumped.GasTipCap().Int64() != 115_000_000 || bumped.GasFeeCap().Int64() != 2_415_000_000 {
+		t.Errorf("Expected both fees raised by 15%%, got tip %s cap %s", bumped.GasTipCap(), bumped.GasFeeCap())
+	}
+
+	// fees rose more than the bump since the transaction was sent
+	client.SetBaseFee(big.NewInt(5_000_000_000))
+	bumped, err = m.Bump(ctx, bumped)
+	if err != nil {
+		t.Fatalf("Bump failed: %v", err)
+	}
+	if bumped.GasFeeCap().Int64() != 10_100_000_000 {
+		t.Errorf("Expected the current fee cap, got %s", bumped.GasFeeCap())
+	}
+	if len(client.Sent()) != 3 {
+		t.Errorf("Expected the replacements to be broadcast, got %d transactions", len(client.Sent()))
+	}
+}
+
+func Test_MaxFee(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MaxFeeGwei = 2.2
+	m, client := newTestManager(t, cfg)
+	ctx := context.Background()
+
+	tx, err := m.Send(ctx, Request{Call: approveCall(t)})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if _, err := m.Bump(ctx, tx); !errors.Is(err, ErrFeeCapReached) {
+		t.Errorf("Expected the bump to exceed the fee cap, got %v", err)
+	}
+
+	client.SetBaseFee(big.NewInt(1_500_000_000))
+	tx, err = m.Send(ctx, Request{Call: approveCall(t)})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if tx.GasFeeCap().Int64() != 2_200_000_000 {
+		t.Errorf("Expected the fee cap to be capped, got %s", tx.GasFeeCap())
+	}
+
+	client.SetBaseFee(big.NewInt(3_000_000_000))
+	if _, err := m.Send(ctx, Request{Call: approveCall(t)}); !errors.Is(err, ErrFeeCapReached) {
+		t.Errorf("Expected a base fee above the cap to be rejected, got %v", err)
+	}
+}
+
+func Test_SetSelectsSignerPerChain(t *testing.T) {
+	defaultKey, _ := crypto.GenerateKey()
+	baseKey, _ := crypto.GenerateKey()
+	chains := chain.NewManager()
+	chains.Register(1, "ethereum", chaintest.NewContracts(1))
+	base := chaintest.NewContracts(8453)
+	base.StubGas(t, target, approveABI, "approve", 50_000)
+	chains.Register(8453, "base", base)
+
+	set := NewSet(chains, signer.NewKeySigner(defaultKey), DefaultConfig())
+	set.UseSigner(8453, signer.NewKeySigner(baseKey))
+
+	if set.Address(1) != crypto.PubkeyToAddress(defaultKey.PublicKey) || set.Address(8453) != crypto.PubkeyToAddress(baseKey.PublicKey) {
+		t.Errorf("Unexpected accounts: %s and %s", set.Address(1).Hex(), set.Address(8453).Hex())
+	}
+	m, err := set.For(8453)
+	if err != nil {
+		t.Fatalf("For failed: %v", err)
+	}
+	if again, _ := set.For(8453); again != m {
+		t.Errorf("Expected the manager to be kept")
+	}
+	if _, err := m.Send(context.Background(), Request{Call: approveCall(t)}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if len(base.Sent()) != 1 {
+		t.Errorf("Expected the transaction to be sent on base")
+	}
+	if _, err := set.For(42161); err == nil {
+		t.Errorf("Expected an unconfigured chain to be rejected")
+	}
+}
+
+func Test_SetSendsThroughPrivateMempool(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	public := chaintest.NewContracts(1)
+	public.StubGas(t, target, approveABI, "approve", 50_000)
+	private := chaintest.NewContracts(1)
+	chains := chain.NewManager()
+	chains.Register(1, "ethereum", public)
+
+	cfg := DefaultConfig()
+	cfg.ChainProtection = map[uint64]Protection{1: {MaxSlippageBps: 30}}
+	set := NewSet(chains, signer.NewKeySigner(key), cfg)
+	set.UseBroadcaster(1, private)
+
+	if protection := set.Protection(1); protection.MaxSlippageBps != 30 {
+		t.Errorf("Expected the chain slippage bound, got %+v", protection)
+	}
+	if protection := set.Protection(8453); protection.MaxSlippageBps != 10 {
+		t.Errorf("Expected the default slippage bound, got %+v", protection)
+	}
+
+	m, err := set.For(1)
+	if err != nil {
+		t.Fatalf("For failed: %v", err)
+	}
+	if _, err := m.Send(context.Background(), Request{Call: approveCall(t)}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	// gas, fees and the nonce are still read from the public endpoint
+	if len(private.Sent()) != 1 || len(public.Sent()) != 0 {
+		t.Errorf("Expected the transaction to only reach the private mempool, got %d private and %d public", len(private.Sent()), len(public.Sent()))
+	}
+}
@@ -0,0 +1,92 @@
+package types
+
+import "github.com/najnomics/crosscow-avs/pkg/canonical"
+
+// CrossChainYieldResult is the result of a cross_chain_yield_check task. Rates are the
+// best supply rates on each chain as annual percentages, null when no market on the chain
+// could be read. The target market is the one earning the most once the amount is
+// deposited into it.
+type CrossChainYieldResult struct {
+	SourceChain uint64             `json:"source_chain"`
+	TargetChain uint64             `json:"target_chain"`
+	Amount      canonical.Decimal  `json:"amount"`
+	SourceRate  *canonical.Decimal `json:"source_rate"`
+	TargetRate  *canonical.Decimal `json:"target_rate"`
+
+	// TargetProjectedRate and ProjectedImprovementBps are unset before schema version 5
+	TargetProjectedRate     *canonical.Decimal `json:"target_projected_rate"`
+	TargetProtocol          string             `json:"target_protocol"`
+	ImprovementBps          *canonical.Decimal `json:"improvement_bps"`
+	ProjectedImprovementBps *canonical.Decimal `json:"projected_improvement_bps"`
+	Markets                 []ChainMarketRate  `json:"markets"`
+	Failures                []MarketFailure    `json:"failures"`
+
+	PolicyRejections []PolicyViolation `json:"policy_rejections,omitempty"`
+
+	// Routes are the bridges moving the amount, best first, and Route the best of them
+	// the profile waits for. Both are unset before schema version 3.
+	Route  *BridgeRoute  `json:"route"`
+	Routes []BridgeRoute `json:"routes"`
+
+	Hysteresis *HysteresisReport `json:"hysteresis,omitempty"`
+	Sequencers []SequencerReport `json:"sequencers,omitempty"`
+	Status     Status            `json:"status"`
+}
+
+func (*CrossChainYieldResult) TaskType() string { return TaskTypeCrossChainYieldCheck }
+
+// ChainMarketRate is the supply rate of a protocol on a chain, as an annual percentage.
+// ProjectedRate is unset before schema version 5.
+type ChainMarketRate struct {
+	Protocol      string             `json:"protocol"`
+	ChainID       uint64             `json:"chain_id"`
+	SupplyRate    canonical.Decimal  `json:"supply_rate"`
+	ProjectedRate *canonical.Decimal `json:"projected_rate,omitempty"`
+}
+
+// BridgeRoute is a bridge moving USDC between two chains. Scores run from 0 (no concern)
+// to 100.
+type BridgeRoute struct {
+	Bridge           string             `json:"bridge"`
+	Trust            string             `json:"trust"`
+	Token            string             `json:"token"`
+	FeeBps           uint64             `json:"fee_bps"`
+	Fee              canonical.Decimal  `json:"fee"`
+	LatencySeconds   uint64             `json:"latency_seconds"`
+	FeeScore         canonical.Decimal  `json:"fee_score"`
+	LatencyScore     canonical.Decimal  `json:"latency_score"`
+	TrustScore       canonical.Decimal  `json:"trust_score"`
+	Score            canonical.Decimal  `json:"score"`
+	TransitYieldLost *canonical.Decimal `json:"transit_yield_lost,omitempty"`
+}
+
+// PolicyViolation is a rule of the operator's policy, or of the user's profile, a market
+// or move breaks
+type PolicyViolation struct {
+	Rule     string             `json:"rule"`
+	Protocol string             `json:"protocol"`
+	ChainID  uint64             `json:"chain_id"`
+	Limit    *canonical.Decimal `json:"limit"`
+	Actual   *canonical.Decimal `json:"actual"`
+	Reason   string             `json:"reason"`
+}
+
+// HysteresisReport tells whether a recommended move is held back
+type HysteresisReport struct {
+	Suppressed     bool    `json:"suppressed"`
+	Reason         string  `json:"reason,omitempty"`
+	LeadSeconds    uint64  `json:"lead_seconds"`
+	MinLeadSeconds uint64  `json:"min_lead_seconds"`
+	CooldownEndsAt *uint64 `json:"cooldown_ends_at,omitempty"`
+}
+
+// SequencerReport is the health of the sequencer of an L2
+type SequencerReport struct {
+	ChainID          uint64            `json:"chain_id"`
+	Status           string            `json:"status"`
+	Feed             string            `json:"feed,omitempty"`
+	Since            uint64            `json:"since,omitempty"`
+	BlockTimeSeconds canonical.Decimal `json:"block_time_seconds"`
+	Blocked          bool              `json:"blocked"`
+	Reason           string            `json:"reason,omitempty"`
+}
@@ -0,0 +1,258 @@
+package types
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+)
+
+// RebalancePlan is a rebalance agreed on before funds move. Amounts are in USDC and
+// ExpiresAt is a unix timestamp. Profile and TransferSpeed are omitted when the task
+// carries none.
+type RebalancePlan struct {
+	UserAddress    string            `json:"user_address"`
+	Amount         canonical.Decimal `json:"amount"`
+	SourceProtocol string            `json:"source_protocol"`
+	SourceChain    uint64            `json:"source_chain"`
+	TargetProtocol string            `json:"target_protocol"`
+	TargetChain    uint64            `json:"target_chain"`
+	MaxSlippageBps *uint64           `json:"max_slippage_bps"`
+	ResizeToCap    bool              `json:"resize_to_cap"`
+	Profile        *Profile          `json:"profile,omitempty"`
+	TransferSpeed  string            `json:"transfer_speed,omitempty"`
+	ExpiresAt      uint64            `json:"expires_at"`
+}
+
+// Hash is the keccak256 hash of the canonical JSON encoding of the plan, the plan hash
+// a rebalance_commit task names
+func (p *RebalancePlan) Hash() (common.Hash, error) {
+	encoded, err := canonical.Marshal(p)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to encode plan: %w", err)
+	}
+	return crypto.Keccak256Hash(encoded), nil
+}
+
+// Profile is the appetite of the user a plan is made for, with the limits of its preset
+// resolved
+type Profile struct {
+	Preset                  string `json:"preset,omitempty"`
+	MinAPYImprovementBps    uint64 `json:"min_apy_improvement_bps"`
+	MaxRiskScore            uint64 `json:"max_risk_score"`
+	MaxBridgeLatencySeconds uint64 `json:"max_bridge_latency_seconds"`
+}
+
+// RebalancePlanResult is the result of a rebalance_plan task. A quorum on it is a quorum
+// on the plan hash that rebalance_commit executes.
+type RebalancePlanResult struct {
+	PlanHash string        `json:"plan_hash"`
+	Plan     RebalancePlan `json:"plan"`
+	Status   Status        `json:"status"`
+}
+
+func (*RebalancePlanResult) TaskType() string { return TaskTypeRebalancePlan }
+
+// RebalanceExecutionResult is the result of a rebalance_execution task. Amounts are in
+// USDC. Dry runs only carry a simulation; other rebalances carry the execution once the
+// performer submitted its transactions, and report the not_executed status when it has
+// no account to submit them from.
+type RebalanceExecutionResult struct {
+	UserAddress    string            `json:"user_address"`
+	SourceProtocol string            `json:"source_protocol,omitempty"`
+	SourceChain    uint64            `json:"source_chain,omitempty"`
+	TargetProtocol string            `json:"target_protocol"`
+	TargetChain    uint64            `json:"target_chain,omitempty"`
+	Amount         canonical.Decimal `json:"amount"`
+
+	// RequestedAmount is the amount asked for when resize_to_cap lowered it to the supply
+	// cap headroom of the target market
+	RequestedAmount *canonical.Decimal `json:"requested_amount,omitempty"`
+	SupplyCap       *SupplyCapReport   `json:"supply_cap,omitempty"`
+
+	WithdrawalLiquidity *WithdrawalLiquidity `json:"withdrawal_liquidity,omitempty"`
+	BridgeFallback      *BridgeFallback      `json:"bridge_fallback,omitempty"`
+	GasTiming           *GasTiming           `json:"gas_timing,omitempty"`
+
+	DryRun     bool                 `json:"dry_run"`
+	Simulation *RebalanceSimulation `json:"simulation,omitempty"`
+	Execution  *RebalanceExecution  `json:"execution,omitempty"`
+
+	// PlanHash is the plan a rebalance_commit task executed
+	PlanHash string `json:"plan_hash,omitempty"`
+
+	Status Status `json:"status"`
+}
+
+func (*RebalanceExecutionResult) TaskType() string { return TaskTypeRebalanceExecution }
+
+// RebalanceCommitResult is the result of a rebalance_commit task, the execution of the
+// plan it names
+type RebalanceCommitResult struct {
+	RebalanceExecutionResult
+}
+
+func (*RebalanceCommitResult) TaskType() string { return TaskTypeRebalanceCommit }
+
+// ResumeExecutionResult is the result of a resume_execution task, the execution it
+// carried on
+type ResumeExecutionResult struct {
+	RebalanceExecutionResult
+}
+
+func (*ResumeExecutionResult) TaskType() string { return TaskTypeResumeExecution }
+
+// WithdrawalLiquidity compares a withdrawal with the USDC the source market holds idle.
+// MaxWithdrawable is the largest withdrawal to retry with when it is not Sufficient.
+type WithdrawalLiquidity struct {
+	Available       canonical.Decimal  `json:"available"`
+	Sufficient      bool               `json:"sufficient"`
+	MaxWithdrawable *canonical.Decimal `json:"max_withdrawable,omitempty"`
+}
+
+// BridgeFallback records that a rebalance bridged through another bridge than CCTP, as
+// the attestation of its burn was delayed past the SLA
+type BridgeFallback struct {
+	Bridge             string            `json:"bridge"`
+	Trust              string            `json:"trust"`
+	DelayedBurn        string            `json:"delayed_burn"`
+	DelaySeconds       uint64            `json:"delay_seconds"`
+	SLASeconds         uint64            `json:"sla_seconds"`
+	FeeBps             uint64            `json:"fee_bps"`
+	Fee                canonical.Decimal `json:"fee"`
+	RiskAcknowledgment string            `json:"risk_acknowledgment"`
+}
+
+// GasTiming is how a rebalance was timed into a low gas window. Base fees are in gwei and
+// savings in the native token; ActualSavings is null until every step is included.
+type GasTiming struct {
+	ChainID          uint64             `json:"chain_id"`
+	Delayed          bool               `json:"delayed"`
+	Expired          bool               `json:"expired"`
+	WaitedSeconds    uint64             `json:"waited_seconds"`
+	Trend            string             `json:"trend"`
+	MaxBaseFee       *canonical.Decimal `json:"max_base_fee"`
+	RequestedBaseFee canonical.Decimal  `json:"requested_base_fee"`
+	ExpectedBaseFee  canonical.Decimal  `json:"expected_base_fee"`
+	ReleasedBaseFee  canonical.Decimal  `json:"released_base_fee"`
+	ExpectedSavings  canonical.Decimal  `json:"expected_savings"`
+	ActualSavings    *canonical.Decimal `json:"actual_savings"`
+}
+
+// RebalanceSimulation previews a rebalance without moving funds. Rates are annual
+// percentages and amounts are in USDC.
+type RebalanceSimulation struct {
+	Strategy            string             `json:"strategy"`
+	SourceProtocol      string             `json:"source_protocol,omitempty"`
+	SourceChain         uint64             `json:"source_chain"`
+	TargetChain         uint64             `json:"target_chain"`
+	Steps               []SimulatedStep    `json:"steps"`
+	Gas                 []ChainGasCost     `json:"gas"`
+	BridgeFee           canonical.Decimal  `json:"bridge_fee"`
+	AmountReceived      canonical.Decimal  `json:"amount_received"`
+	SlippageBps         canonical.Decimal  `json:"slippage_bps"`
+	DurationSeconds     uint64             `json:"duration_seconds"`
+	Transit             *TransitReport     `json:"transit,omitempty"`
+	FlashLoanPremium    *canonical.Decimal `json:"flash_loan_premium,omitempty"`
+	SourceRate          *canonical.Decimal `json:"source_rate,omitempty"`
+	SourceRateImpactBps *canonical.Decimal `json:"source_rate_impact_bps,omitempty"`
+	TargetRate          canonical.Decimal  `json:"target_rate"`
+	FinalRate           canonical.Decimal  `json:"final_rate"`
+	TargetRateImpactBps canonical.Decimal  `json:"target_rate_impact_bps"`
+	Feasible            bool               `json:"feasible"`
+}
+
+// SimulatedStep is one transaction of a simulated rebalance. Simulated is false when its
+// gas is a fallback estimate.
+type SimulatedStep struct {
+	Action          string `json:"action"`
+	ChainID         uint64 `json:"chain_id"`
+	Protocol        string `json:"protocol,omitempty"`
+	Contract        string `json:"contract"`
+	GasUsed         uint64 `json:"gas_used"`
+	Simulated       bool   `json:"simulated"`
+	Reverted        bool   `json:"reverted"`
+	RevertReason    string `json:"revert_reason,omitempty"`
+	DurationSeconds uint64 `json:"duration_seconds"`
+}
+
+// ChainGasCost is the gas a simulated rebalance spends on one chain, its price in gwei
+// and its cost in the native token
+type ChainGasCost struct {
+	ChainID  uint64             `json:"chain_id"`
+	GasUsed  uint64             `json:"gas_used"`
+	GasPrice *canonical.Decimal `json:"gas_price"`
+	Cost     *canonical.Decimal `json:"cost"`
+}
+
+// TransitReport is how long funds spend bridging through CCTP and the yield lost meanwhile
+type TransitReport struct {
+	Speed           string            `json:"speed"`
+	SourceFinality  string            `json:"source_finality"`
+	FinalitySeconds uint64            `json:"finality_seconds"`
+	DurationSeconds uint64            `json:"duration_seconds"`
+	Fee             canonical.Decimal `json:"fee"`
+	YieldLost       canonical.Decimal `json:"yield_lost"`
+	YieldLostBps    canonical.Decimal `json:"yield_lost_bps"`
+}
+
+// RebalanceExecution is a rebalance submitted from the performer account, with its
+// transactions, the steps still to send, its legs and the balance checks verifying it
+type RebalanceExecution struct {
+	ID             string                 `json:"id"`
+	From           string                 `json:"from"`
+	Strategy       string                 `json:"strategy"`
+	Execution      string                 `json:"execution"`
+	SourceChain    uint64                 `json:"source_chain"`
+	TargetChain    uint64                 `json:"target_chain"`
+	MaxSlippageBps uint64                 `json:"max_slippage_bps"`
+	Transactions   []SubmittedTransaction `json:"transactions"`
+	PendingSteps   []PendingStep          `json:"pending_steps"`
+	Legs           []ExecutionLeg         `json:"legs"`
+	BalanceChecks  []BalanceCheck         `json:"balance_checks"`
+	Verified       bool                   `json:"verified"`
+}
+
+// SubmittedTransaction is a transaction broadcast for a rebalance step. Status is pending,
+// confirmed or reverted, and the effective gas price is in gwei.
+type SubmittedTransaction struct {
+	Action            string             `json:"action"`
+	ChainID           uint64             `json:"chain_id"`
+	Hash              string             `json:"hash"`
+	UserOperationHash string             `json:"user_operation_hash,omitempty"`
+	Nonce             uint64             `json:"nonce"`
+	GasLimit          uint64             `json:"gas_limit"`
+	Status            string             `json:"status"`
+	BlockNumber       uint64             `json:"block_number,omitempty"`
+	GasUsed           uint64             `json:"gas_used,omitempty"`
+	EffectiveGasPrice *canonical.Decimal `json:"effective_gas_price,omitempty"`
+	Confirmations     uint64             `json:"confirmations"`
+	Reorgs            int                `json:"reorgs,omitempty"`
+}
+
+// PendingStep is a rebalance step that was not submitted
+type PendingStep struct {
+	Action  string `json:"action"`
+	ChainID uint64 `json:"chain_id"`
+}
+
+// ExecutionLeg is a hop of a rebalance along its route, completed, submitted, pending or
+// failed
+type ExecutionLeg struct {
+	Leg     string `json:"leg"`
+	ChainID uint64 `json:"chain_id"`
+	Status  string `json:"status"`
+}
+
+// BalanceCheck compares a USDC holding of the performer account before and after the
+// rebalance. It passes when Change is at least MinChange.
+type BalanceCheck struct {
+	ChainID   uint64            `json:"chain_id"`
+	Holding   string            `json:"holding"`
+	Before    canonical.Decimal `json:"before"`
+	After     canonical.Decimal `json:"after"`
+	Change    canonical.Decimal `json:"change"`
+	MinChange canonical.Decimal `json:"min_change"`
+	Passed    bool              `json:"passed"`
+}
@@ -0,0 +1,120 @@
+package types
+
+import "github.com/najnomics/crosscow-avs/pkg/canonical"
+
+// RiskAssessmentResult is the result of a risk_assessment task
+type RiskAssessmentResult struct {
+	Protocol       string      `json:"protocol"`
+	ChainID        uint64      `json:"chain_id"`
+	AssessmentType string      `json:"assessment_type"`
+	Report         *RiskReport `json:"report,omitempty"`
+	Status         Status      `json:"status"`
+	BlockNumber    uint64      `json:"block_number,omitempty"`
+}
+
+func (*RiskAssessmentResult) TaskType() string { return TaskTypeRiskAssessment }
+
+// RiskReport holds the measurements of a USDC market and the 0-100 scores derived from
+// them. Amounts are in USDC; caps, headroom and bad debt are null when the protocol has
+// none or does not expose them.
+type RiskReport struct {
+	TVL         canonical.Decimal  `json:"tvl"`
+	ProtocolTVL *canonical.Decimal `json:"protocol_tvl"`
+	TotalBorrow canonical.Decimal  `json:"total_borrow"`
+	Utilization canonical.Decimal  `json:"utilization"`
+
+	SupplyCap         *canonical.Decimal `json:"supply_cap"`
+	SupplyCapHeadroom *canonical.Decimal `json:"supply_cap_headroom"`
+	BorrowCap         *canonical.Decimal `json:"borrow_cap"`
+	BorrowCapHeadroom *canonical.Decimal `json:"borrow_cap_headroom"`
+
+	BadDebt *canonical.Decimal `json:"bad_debt"`
+	Paused  bool               `json:"paused"`
+	Frozen  bool               `json:"frozen"`
+	Oracle  *OracleReport      `json:"oracle"`
+
+	Governance *GovernanceReport `json:"governance"`
+	Security   *SecurityReport   `json:"security"`
+	RWA        *RWAReport        `json:"rwa,omitempty"`
+
+	Factors []RiskFactorScore `json:"factors"`
+
+	LiquidityScore *canonical.Decimal `json:"liquidity_score"`
+	SolvencyScore  *canonical.Decimal `json:"solvency_score"`
+	AdminScore     *canonical.Decimal `json:"admin_score"`
+	SecurityScore  *canonical.Decimal `json:"security_score"`
+	OverallScore   canonical.Decimal  `json:"overall_score"`
+	RiskLevel      string             `json:"risk_level"`
+}
+
+// OracleReport is the USDC price feed of a market
+type OracleReport struct {
+	Feed       string            `json:"feed"`
+	Price      canonical.Decimal `json:"price"`
+	UpdatedAt  uint64            `json:"updated_at,omitempty"`
+	AgeSeconds *uint64           `json:"age_seconds"`
+	Stale      bool              `json:"stale"`
+}
+
+// ControllerReport is an account with privileges over a market and the account
+// controlling it, if any
+type ControllerReport struct {
+	Address      string            `json:"address"`
+	Kind         string            `json:"kind"`
+	Threshold    uint64            `json:"threshold,omitempty"`
+	Owners       uint64            `json:"owners,omitempty"`
+	DelaySeconds uint64            `json:"delay_seconds,omitempty"`
+	Admin        *ControllerReport `json:"admin,omitempty"`
+}
+
+// GovernanceReport is who can upgrade, configure and pause a market
+type GovernanceReport struct {
+	ProxyAdmin    *ControllerReport `json:"proxy_admin"`
+	Governor      *ControllerReport `json:"governor"`
+	PauseGuardian *ControllerReport `json:"pause_guardian"`
+}
+
+// IncidentReport is a security incident of the protocol affecting the assessed chain
+type IncidentReport struct {
+	ID        string `json:"id"`
+	Title     string `json:"title"`
+	Severity  string `json:"severity"`
+	Active    bool   `json:"active"`
+	LossUsd   string `json:"loss_usd,omitempty"`
+	Timestamp uint64 `json:"timestamp"`
+	Url       string `json:"url,omitempty"`
+}
+
+// AuditReport is a completed audit of the protocol
+type AuditReport struct {
+	Auditor   string `json:"auditor"`
+	Scope     string `json:"scope"`
+	Timestamp uint64 `json:"timestamp"`
+	Url       string `json:"url,omitempty"`
+}
+
+// SecurityReport is the record of the protocol in the security feed as of FeedUpdatedAt
+type SecurityReport struct {
+	FeedUpdatedAt   uint64           `json:"feed_updated_at"`
+	Stale           bool             `json:"stale"`
+	ActiveIncidents []IncidentReport `json:"active_incidents"`
+	RecentExploits  []IncidentReport `json:"recent_exploits"`
+	LatestAudit     *AuditReport     `json:"latest_audit"`
+	AuditCount      uint64           `json:"audit_count"`
+}
+
+// RWAReport is the backing of a yield source backed by real world assets
+type RWAReport struct {
+	Issuer          string `json:"issuer"`
+	Jurisdiction    string `json:"jurisdiction"`
+	Backing         string `json:"backing"`
+	Transferability string `json:"transferability"`
+	KYC             bool   `json:"kyc"`
+}
+
+// RiskFactorScore is the score of one risk factor, null when it could not be measured
+type RiskFactorScore struct {
+	Factor string             `json:"factor"`
+	Score  *canonical.Decimal `json:"score"`
+	Weight uint64             `json:"weight"`
+}
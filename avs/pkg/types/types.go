@@ -0,0 +1,180 @@
+// Package types defines the JSON results of the performer's tasks as Go structs, for
+// services consuming results without depending on the performer itself.
+//
+// It covers the results of yield_monitoring, cross_chain_yield_check, risk_assessment,
+// rebalance_plan, rebalance_execution, rebalance_commit and resume_execution, the tasks
+// whose results are acted on. Results of the other task types are not defined here;
+// consumers read them from Envelope.Result.
+//
+// Results are answered in an envelope naming their task type and schema version. Open
+// decodes the envelope, decompressing results answered compressed, and Decode decodes the
+// result into the struct of its task type; Unmarshal does both. Results are versioned by
+// package schema: fields a later schema version added are left unset in results of older
+// versions, as documented on each field, and results of versions newer than this package
+// knows are refused rather than decoded partially.
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/najnomics/crosscow-avs/pkg/resultsize"
+	"github.com/najnomics/crosscow-avs/pkg/schema"
+)
+
+// Task types of the results of this package
+const (
+	TaskTypeYieldMonitoring      = "yield_monitoring"
+	TaskTypeCrossChainYieldCheck = "cross_chain_yield_check"
+	TaskTypeRiskAssessment       = "risk_assessment"
+	TaskTypeRebalancePlan        = "rebalance_plan"
+	TaskTypeRebalanceExecution   = "rebalance_execution"
+	TaskTypeRebalanceCommit      = "rebalance_commit"
+	TaskTypeResumeExecution      = "resume_execution"
+)
+
+// Status is the outcome a result reports
+type Status string
+
+const (
+	StatusCompleted Status = "completed"
+
+	// StatusAnomalous marks results built on data that failed anomaly checks. Consumers
+	// must not act on them.
+	StatusAnomalous Status = "anomalous"
+
+	// StatusDisputed marks results whose data sources disagreed. No value is reported.
+	StatusDisputed Status = "disputed"
+
+	// StatusPartial marks results that cover only some of the requested markets
+	StatusPartial Status = "partial"
+
+	// StatusSubmitted marks rebalances whose transactions were broadcast but are not all
+	// final yet
+	StatusSubmitted Status = "submitted"
+
+	// StatusReverted marks rebalances with a transaction that was mined and reverted
+	StatusReverted Status = "reverted"
+
+	// StatusNotExecuted marks rebalances the performer accepted but had no account to
+	// execute from
+	StatusNotExecuted Status = "not_executed"
+
+	// StatusFailed marks error results, see ErrorReport
+	StatusFailed Status = "failed"
+)
+
+// Result is the result of a task type
+type Result interface {
+	TaskType() string
+}
+
+// Envelope is a decoded task result, with the result of its task type kept encoded for
+// Decode. SchemaVersion is schema.Version1 for results answered without one.
+type Envelope struct {
+	TaskType      string          `json:"task_type"`
+	SchemaVersion int             `json:"schema_version,omitempty"`
+	Result        json.RawMessage `json:"result"`
+
+	// Encoding is how the result was answered when it was compressed. Result holds it
+	// decompressed.
+	Encoding string `json:"encoding,omitempty"`
+
+	// Commitment, Trace and Attestation are set on results of tasks asking for them, the
+	// commitment from schema version 6 on
+	Commitment  json.RawMessage `json:"commitment,omitempty"`
+	Trace       json.RawMessage `json:"trace,omitempty"`
+	Attestation json.RawMessage `json:"attestation,omitempty"`
+}
+
+// ErrorReport is the error of a task answered in place of its result, such as an
+// unprofitable or policy violating rebalance. Retryable reports whether sending the task
+// again unchanged may succeed.
+type ErrorReport struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Retryable bool   `json:"retryable"`
+}
+
+func (e *ErrorReport) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// Open decodes the envelope of an encoded JSON task result
+func Open(encoded []byte) (*Envelope, error) {
+	var env Envelope
+	if err := json.Unmarshal(encoded, &env); err != nil {
+		return nil, fmt.Errorf("failed to decode result: %w", err)
+	}
+	if env.TaskType == "" || len(env.Result) == 0 {
+		return nil, fmt.Errorf("failed to decode result: missing task_type or result")
+	}
+	if env.SchemaVersion == 0 {
+		env.SchemaVersion = schema.Version1
+	}
+	if !schema.Supported(env.SchemaVersion) {
+		return nil, fmt.Errorf("failed to decode result: schema version %d is not from %d to %d", env.SchemaVersion, schema.Oldest, schema.Current)
+	}
+	switch env.Encoding {
+	case "":
+	case resultsize.EncodingGzipBase64:
+		var compressed string
+		if err := json.Unmarshal(env.Result, &compressed); err != nil {
+			return nil, fmt.Errorf("failed to decode result: %s result is not a string", env.Encoding)
+		}
+		decompressed, err := resultsize.Decompress(compressed)
+		if err != nil {
+			return nil, err
+		}
+		env.Result = decompressed
+	default:
+		return nil, fmt.Errorf("failed to decode result: unknown encoding %s", env.Encoding)
+	}
+	return &env, nil
+}
+
+// Err returns the error answered in place of the result, nil when the task succeeded
+func (e *Envelope) Err() *ErrorReport {
+	var failed struct {
+		Error *ErrorReport `json:"error"`
+	}
+	if err := json.Unmarshal(e.Result, &failed); err != nil || failed.Error == nil || failed.Error.Code == "" {
+		return nil
+	}
+	return failed.Error
+}
+
+// Decode decodes the result into v, which must be a result of the task type of the
+// envelope. It fails with the ErrorReport answered in place of the result when the task
+// failed.
+func (e *Envelope) Decode(v Result) error {
+	if v.TaskType() != e.TaskType {
+		return fmt.Errorf("cannot decode %s result into a %s result", e.TaskType, v.TaskType())
+	}
+	if report := e.Err(); report != nil {
+		return report
+	}
+	if err := json.Unmarshal(e.Result, v); err != nil {
+		return fmt.Errorf("failed to decode %s result: %w", e.TaskType, err)
+	}
+	return nil
+}
+
+// Unmarshal decodes an encoded JSON task result into v and returns its envelope
+func Unmarshal(encoded []byte, v Result) (*Envelope, error) {
+	env, err := Open(encoded)
+	if err != nil {
+		return nil, err
+	}
+	return env, env.Decode(v)
+}
+
+// Marshal encodes v as canonical JSON wrapped in the result envelope of version, as the
+// performer encodes results without a commitment
+func Marshal(v Result, version int) ([]byte, error) {
+	envelope := struct {
+		TaskType string `json:"task_type"`
+		Result   Result `json:"result"`
+	}{v.TaskType(), v}
+	return schema.Encode(&envelope, version)
+}
@@ -0,0 +1,299 @@
+package types
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/najnomics/crosscow-avs/pkg/canonical"
+	"github.com/najnomics/crosscow-avs/pkg/client"
+	"github.com/najnomics/crosscow-avs/pkg/loadgen"
+	"github.com/najnomics/crosscow-avs/pkg/performer"
+	"github.com/najnomics/crosscow-avs/pkg/schema"
+	"github.com/najnomics/crosscow-avs/pkg/taskschema"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const account = "0x000000000000000000000000000000000000dEaD"
+
+// stubClient serves a performer reading stub adapters and returns a client of it
+func stubClient(t *testing.T) *client.Client {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	target, err := loadgen.ServeStub(ctx, 0, zap.NewNop())
+	if err != nil {
+		t.Fatalf("ServeStub failed: %v", err)
+	}
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to dial %s: %v", target, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return client.New(conn)
+}
+
+// execute runs task on c and returns the encoded result
+func execute(t *testing.T, c *client.Client, taskID string, task client.Task, opts ...client.Option) []byte {
+	t.Helper()
+	payload, err := client.Build(task, opts...)
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	raw, err := c.Execute(ctx, taskID, payload)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	return raw
+}
+
+func Test_ResultsDecodeWithoutLoss(t *testing.T) {
+	c := stubClient(t)
+	testCases := map[string]struct {
+		task   client.Task
+		result Result
+	}{
+		"yield monitoring":  {client.YieldMonitoring{Protocol: "aave_v3", ChainID: 1, Token: "USDC", DepositAmount: "1000000000"}, &YieldMonitoringResult{}},
+		"all markets":       {client.YieldMonitoring{Protocol: client.ProtocolAll, Token: "USDC"}, &MultiYieldMonitoringResult{}},
+		"cross chain check": {client.CrossChainYieldCheck{SourceChain: 1, TargetChain: 8453, Amount: "1000000000"}, &CrossChainYieldResult{}},
+		"risk assessment":   {client.RiskAssessment{Protocol: "aave_v3", ChainID: 1, AssessmentType: "full"}, &RiskAssessmentResult{}},
+		"rebalance":         {client.RebalanceExecution{UserAddress: account, Nonce: "1", Amount: "1000000000", SourceProtocol: "compound_v3", TargetProtocol: "aave_v3", TargetChain: 1}, &RebalanceExecutionResult{}},
+		"rebalance plan":    {client.RebalancePlan{RebalanceExecution: client.RebalanceExecution{UserAddress: account, Nonce: "1", Amount: "1000000000", TargetProtocol: "aave_v3", TargetChain: 1, Profile: &client.Profile{Preset: client.ProfileBalanced}}, ExpiresAt: time.Now().Add(time.Minute).Unix()}, &RebalancePlanResult{}},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			raw := execute(t, c, strings.ReplaceAll(name, " ", "-"), tc.task)
+			env, err := Unmarshal(raw, tc.result)
+			if err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+			if env.SchemaVersion != schema.Current || len(env.Commitment) == 0 {
+				t.Errorf("Expected a committed result of schema version %d, got %+v", schema.Current, env)
+			}
+			// every field the performer answered survives decoding
+			reencoded, err := canonical.Marshal(tc.result)
+			if err != nil {
+				t.Fatalf("Marshal failed: %v", err)
+			}
+			if string(reencoded) != string(env.Result) {
+				t.Errorf("Expected the result to encode back unchanged\n got: %s\nwant: %s", reencoded, env.Result)
+			}
+		})
+	}
+}
+
+func Test_RebalancePlanHashMatches(t *testing.T) {
+	c := stubClient(t)
+	raw := execute(t, c, "plan", client.RebalancePlan{
+		RebalanceExecution: client.RebalanceExecution{UserAddress: account, Nonce: "1", Amount: "1000000000", TargetProtocol: "aave_v3", TargetChain: 1},
+		ExpiresAt:          time.Now().Add(time.Minute).Unix(),
+	})
+	var result RebalancePlanResult
+	if _, err := Unmarshal(raw, &result); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	hash, err := result.Plan.Hash()
+	if err != nil {
+		t.Fatalf("Hash failed: %v", err)
+	}
+	if hash.Hex() != result.PlanHash {
+		t.Errorf("Expected plan hash %s, got %s", result.PlanHash, hash.Hex())
+	}
+}
+
+func Test_OlderSchemaVersionsLeaveNewerFieldsUnset(t *testing.T) {
+	c := stubClient(t)
+	check := client.CrossChainYieldCheck{SourceChain: 1, TargetChain: 8453, Amount: "1000000000"}
+
+	var current CrossChainYieldResult
+	if _, err := Unmarshal(execute(t, c, "current", check), &current); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if current.Routes == nil || current.TargetProjectedRate == nil {
+		t.Fatalf("Expected routes and a projected rate in current results, got %+v", current)
+	}
+
+	var v2 CrossChainYieldResult
+	env, err := Unmarshal(execute(t, c, "v2", check, client.WithSchemaVersion(schema.Version2), client.WithCompression()), &v2)
+	if err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if env.SchemaVersion != schema.Version2 || len(env.Commitment) != 0 {
+		t.Errorf("Expected an uncommitted result of schema version 2, got %+v", env)
+	}
+	if v2.Routes != nil || v2.Route != nil || v2.TargetProjectedRate != nil {
+		t.Errorf("Expected no routes or projected rate before schema version 3, got %+v", v2)
+	}
+	if v2.SourceRate == nil || v2.SourceRate.Cmp(*current.SourceRate) != 0 {
+		t.Errorf("Expected source rate %v, got %v", current.SourceRate, v2.SourceRate)
+	}
+
+	var v1 YieldMonitoringResult
+	env, err = Unmarshal(execute(t, c, "v1", client.YieldMonitoring{Protocol: "aave_v3", ChainID: 1, Token: "USDC"}, client.WithSchemaVersion(schema.Version1)), &v1)
+	if err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if env.SchemaVersion != schema.Version1 || v1.Stability != nil || v1.Status != StatusCompleted {
+		t.Errorf("Expected a completed result of schema version 1 without stability, got %+v %+v", env, v1)
+	}
+}
+
+func Test_MarshalEncodesEnvelopes(t *testing.T) {
+	rate := canonical.APY(4.5)
+	result := &YieldMonitoringResult{Protocol: "aave_v3", Token: "USDC", ChainID: 1, SupplyRate: &rate, Status: StatusCompleted, Stability: &StabilityReport{}}
+
+	encoded, err := Marshal(result, schema.Version3)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if strings.Contains(string(encoded), "stability") || !strings.Contains(string(encoded), `"schema_version":3`) {
+		t.Errorf("Expected a result of schema version 3 without stability, got %s", encoded)
+	}
+	var decoded YieldMonitoringResult
+	env, err := Unmarshal(encoded, &decoded)
+	if err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if env.TaskType != TaskTypeYieldMonitoring || decoded.SupplyRate.Cmp(rate) != 0 {
+		t.Errorf("Expected the result back, got %+v", decoded)
+	}
+	if _, err := Marshal(result, schema.Current+1); err == nil {
+		t.Errorf("Expected an unknown schema version to be refused")
+	}
+}
+
+func Test_UnmarshalRejects(t *testing.T) {
+	testCases := map[string]struct {
+		encoded string
+		result  Result
+		want    string
+	}{
+		"not json":         {`yield_monitoring completed`, &YieldMonitoringResult{}, "failed to decode result"},
+		"no result":        {`{"task_type":"yield_monitoring"}`, &YieldMonitoringResult{}, "missing task_type or result"},
+		"newer version":    {`{"task_type":"yield_monitoring","schema_version":99,"result":{}}`, &YieldMonitoringResult{}, "schema version 99"},
+		"unknown encoding": {`{"task_type":"yield_monitoring","encoding":"zstd","result":"x"}`, &YieldMonitoringResult{}, "unknown encoding zstd"},
+		"other task type":  {`{"task_type":"risk_assessment","result":{}}`, &YieldMonitoringResult{}, "cannot decode risk_assessment result"},
+		"mistyped field":   {`{"task_type":"risk_assessment","result":{"chain_id":"1"}}`, &RiskAssessmentResult{}, "failed to decode risk_assessment result"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Unmarshal([]byte(tc.encoded), tc.result); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("Expected %q, got %v", tc.want, err)
+			}
+		})
+	}
+
+	failed := `{"task_type":"rebalance_plan","schema_version":6,"result":{"error":{"code":"policy_violation","message":"target is blocked","retryable":false},"status":"failed"}}`
+	env, err := Unmarshal([]byte(failed), &RebalancePlanResult{})
+	var report *ErrorReport
+	if !errors.As(err, &report) || report.Code != "policy_violation" || env == nil || env.Err() == nil {
+		t.Errorf("Expected the error report of the failed task, got %v", err)
+	}
+}
+
+// mirrored pairs the results of this package with those the performer encodes
+var mirrored = map[string][2]interface{}{
+	"yield monitoring":  {performer.YieldMonitoringResult{}, YieldMonitoringResult{}},
+	"all markets":       {performer.MultiYieldMonitoringResult{}, MultiYieldMonitoringResult{}},
+	"cross chain check": {performer.CrossChainYieldResult{}, CrossChainYieldResult{}},
+	"risk assessment":   {performer.RiskAssessmentResult{}, RiskAssessmentResult{}},
+	"rebalance plan":    {performer.RebalancePlanResult{}, RebalancePlanResult{}},
+	"rebalance":         {performer.RebalanceExecutionResult{}, RebalanceExecutionResult{}},
+	"rebalance commit":  {performer.RebalanceExecutionResult{}, RebalanceCommitResult{}},
+	"resumed execution": {performer.RebalanceExecutionResult{}, ResumeExecutionResult{}},
+}
+
+// unexported are the task types whose results this package does not define
+var unexported = map[string]bool{
+	"accrual_verification": true, "allocation_optimization": true, "apy_forecast": true, "batch": true,
+	"capabilities": true, "depeg_monitoring": true, "full_market_snapshot": true, "liquidity_depth_analysis": true,
+	"performance_report": true, "position_reconciliation": true, "protocol_incident_check": true, "self_report": true,
+	"stress_scenario": true, "transfer_recovery": true, "yield_ranking": true,
+}
+
+func Test_ResultsMirrorThePerformer(t *testing.T) {
+	defined := map[string]bool{}
+	for name, pair := range mirrored {
+		compareFields(t, name, reflect.TypeOf(pair[0]), reflect.TypeOf(pair[1]), map[[2]reflect.Type]bool{})
+		defined[reflect.New(reflect.TypeOf(pair[1])).Interface().(Result).TaskType()] = true
+	}
+	// every task type is either defined here or listed as not exported
+	for _, taskType := range taskschema.Default().TaskTypes() {
+		if defined[taskType] == unexported[taskType] {
+			t.Errorf("Expected %s to be either defined by this package or listed as not exported", taskType)
+		}
+	}
+}
+
+// compareFields checks that got decodes every JSON field of want, a result of the
+// performer, with the same shape. Decimals and types from outside the module must be the
+// same on both sides.
+func compareFields(t *testing.T, path string, want, got reflect.Type, seen map[[2]reflect.Type]bool) {
+	t.Helper()
+	for want.Kind() == reflect.Ptr || want.Kind() == reflect.Slice || want.Kind() == reflect.Map {
+		if got.Kind() != want.Kind() || want.Kind() == reflect.Map && got.Key().Kind() != want.Key().Kind() {
+			t.Errorf("%s: expected %s, got %s", path, want, got)
+			return
+		}
+		want, got = want.Elem(), got.Elem()
+	}
+	if want.Kind() != reflect.Struct || !strings.HasPrefix(want.PkgPath(), "github.com/najnomics/crosscow-avs/") || want == reflect.TypeOf(canonical.Decimal{}) {
+		if want.Kind() == reflect.Struct && got != want || got.Kind() != want.Kind() {
+			t.Errorf("%s: expected %s, got %s", path, want, got)
+		}
+		return
+	}
+	if got.Kind() != reflect.Struct {
+		t.Errorf("%s: expected a struct, got %s", path, got)
+		return
+	}
+	if seen[[2]reflect.Type{want, got}] {
+		return
+	}
+	seen[[2]reflect.Type{want, got}] = true
+	wantFields, gotFields := jsonFields(want), jsonFields(got)
+	for name, field := range wantFields {
+		other, ok := gotFields[name]
+		if !ok {
+			t.Errorf("%s: missing field %s", path, name)
+			continue
+		}
+		compareFields(t, path+"."+name, field.Type, other.Type, seen)
+	}
+	for name := range gotFields {
+		if _, ok := wantFields[name]; !ok {
+			t.Errorf("%s: field %s is not in the results of the performer", path, name)
+		}
+	}
+}
+
+// jsonFields are the fields of a struct by their JSON name, with those of embedded
+// structs promoted
+func jsonFields(typ reflect.Type) map[string]reflect.StructField {
+	fields := map[string]reflect.StructField{}
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			for name, promoted := range jsonFields(field.Type) {
+				fields[name] = promoted
+			}
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field
+	}
+	return fields
+}
@@ -0,0 +1,148 @@
+package types
+
+import "github.com/najnomics/crosscow-avs/pkg/canonical"
+
+// YieldMonitoringResult is the result of a yield_monitoring task for one protocol. The
+// supply rate is an annual percentage and the total supply is in USDC. The supply rate is
+// null when the rate sources disputed each other.
+type YieldMonitoringResult struct {
+	Protocol        string                 `json:"protocol"`
+	Token           string                 `json:"token"`
+	ChainID         uint64                 `json:"chain_id"`
+	SupplyRate      *canonical.Decimal     `json:"supply_rate"`
+	Utilization     canonical.Decimal      `json:"utilization"`
+	TotalSupply     canonical.Decimal      `json:"total_supply"`
+	CrossValidation *CrossValidationReport `json:"cross_validation,omitempty"`
+	Anomaly         *AnomalyReport         `json:"anomaly"`
+
+	// Stability is omitted when the rate is not reported, and before schema version 4
+	Stability *StabilityReport `json:"stability,omitempty"`
+
+	// BlockNumber is the reference block the market was read at, omitted when the
+	// market was read at the latest block
+	BlockNumber uint64 `json:"block_number,omitempty"`
+
+	Incentives *IncentiveReport `json:"incentives,omitempty"`
+	SupplyCap  *SupplyCapReport `json:"supply_cap,omitempty"`
+
+	// RateModel is omitted for markets of unknown rate models, and before schema
+	// version 5
+	RateModel *RateModelReport `json:"rate_model,omitempty"`
+	Status    Status           `json:"status"`
+}
+
+func (*YieldMonitoringResult) TaskType() string { return TaskTypeYieldMonitoring }
+
+// MultiYieldMonitoringResult is the result of a yield_monitoring task for protocol "all".
+// Markets that could not be read are listed in failures, and the status is partial when
+// there are any.
+type MultiYieldMonitoringResult struct {
+	Token    string                   `json:"token"`
+	Markets  []*YieldMonitoringResult `json:"markets"`
+	Failures []MarketFailure          `json:"failures"`
+	Status   Status                   `json:"status"`
+}
+
+func (*MultiYieldMonitoringResult) TaskType() string { return TaskTypeYieldMonitoring }
+
+// MarketFailure reports a market that could not be read
+type MarketFailure struct {
+	Protocol string `json:"protocol"`
+	ChainID  uint64 `json:"chain_id"`
+	Error    string `json:"error"`
+}
+
+// AnomalyReport compares the supply rate to its rolling baseline. Evaluated is false
+// when there was not enough history to form one.
+type AnomalyReport struct {
+	Evaluated      bool              `json:"evaluated"`
+	Detected       bool              `json:"detected"`
+	Direction      string            `json:"direction"`
+	Severity       string            `json:"severity"`
+	ZScore         canonical.Decimal `json:"z_score"`
+	BaselineRate   canonical.Decimal `json:"baseline_rate"`
+	BaselineStdDev canonical.Decimal `json:"baseline_stddev"`
+	Samples        int               `json:"samples"`
+}
+
+// StabilityReport describes how the supply rate moved over the stability window.
+// Volatility is in percentage points and the max drawdown a fraction of the peak; steady
+// rates score close to 1.
+type StabilityReport struct {
+	Evaluated     bool              `json:"evaluated"`
+	Samples       int               `json:"samples"`
+	WindowSeconds uint64            `json:"window_seconds"`
+	MeanRate      canonical.Decimal `json:"mean_rate"`
+	Volatility    canonical.Decimal `json:"volatility"`
+	MaxDrawdown   canonical.Decimal `json:"max_drawdown"`
+	Score         canonical.Decimal `json:"score"`
+}
+
+// CrossValidationReport compares the contract read supply rate with independent sources
+type CrossValidationReport struct {
+	Status     string             `json:"status"`
+	MedianRate *canonical.Decimal `json:"median_rate,omitempty"`
+	SpreadBps  canonical.Decimal  `json:"spread_bps"`
+	Reason     string             `json:"reason,omitempty"`
+	Sources    []SourceRate       `json:"sources"`
+}
+
+// SourceRate is the supply rate a single source reported and its weight in the quorum
+type SourceRate struct {
+	Source     string             `json:"source"`
+	SupplyRate *canonical.Decimal `json:"supply_rate"`
+	Weight     canonical.Decimal  `json:"weight"`
+	Outlier    bool               `json:"outlier,omitempty"`
+	Error      string             `json:"error,omitempty"`
+}
+
+// IncentiveReport is the APR reward emissions add to the supply rate, net of haircuts
+type IncentiveReport struct {
+	IncentiveAPR canonical.Decimal `json:"incentive_apr"`
+	TotalAPR     canonical.Decimal `json:"total_apr"`
+	Confidence   string            `json:"confidence"`
+	Rewards      []RewardAPR       `json:"rewards"`
+}
+
+// RewardAPR is the APR a single reward token adds, null when it could not be priced
+type RewardAPR struct {
+	Token  string             `json:"token"`
+	APR    *canonical.Decimal `json:"apr"`
+	EndsAt uint64             `json:"ends_at,omitempty"`
+}
+
+// SupplyCapReport is the supply cap of a market and the USDC that can still be deposited
+// under it, both null when the market is uncapped
+type SupplyCapReport struct {
+	Cap            *canonical.Decimal `json:"cap"`
+	Headroom       *canonical.Decimal `json:"headroom"`
+	PendingChanges []PendingCapChange `json:"pending_changes,omitempty"`
+}
+
+// PendingCapChange is a supply cap change queued in a timelock, executable from ETA
+type PendingCapChange struct {
+	Cap      *canonical.Decimal `json:"cap"`
+	ETA      uint64             `json:"eta"`
+	Timelock string             `json:"timelock"`
+	TxHash   string             `json:"tx_hash"`
+}
+
+// RateModelReport is the interest rate curve of a market. Projected is the market once
+// the deposit amount of the task is added.
+type RateModelReport struct {
+	Kind          string             `json:"kind"`
+	Kink          *canonical.Decimal `json:"kink,omitempty"`
+	BaseRate      canonical.Decimal  `json:"base_rate"`
+	Slope1        *canonical.Decimal `json:"slope1,omitempty"`
+	Slope2        *canonical.Decimal `json:"slope2,omitempty"`
+	ReserveFactor *canonical.Decimal `json:"reserve_factor,omitempty"`
+	Projected     *RateImpactPoint   `json:"projected,omitempty"`
+}
+
+// RateImpactPoint is a market once Amount USDC is deposited into it
+type RateImpactPoint struct {
+	Amount        canonical.Decimal `json:"amount"`
+	Utilization   canonical.Decimal `json:"utilization"`
+	SupplyRate    canonical.Decimal `json:"supply_rate"`
+	RateImpactBps canonical.Decimal `json:"rate_impact_bps"`
+}
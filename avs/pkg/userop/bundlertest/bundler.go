@@ -0,0 +1,183 @@
+// Package bundlertest serves a fake ERC-4337 bundler and ERC-7677 paymaster over
+// JSON-RPC, so user operations can be sent in tests without a chain
+package bundlertest
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/najnomics/crosscow-avs/pkg/userop"
+)
+
+// Gas limits every user operation is estimated at
+const (
+	PreVerificationGas            = 50_000
+	VerificationGasLimit          = 100_000
+	CallGasLimit                  = 300_000
+	PaymasterVerificationGasLimit = 40_000
+	PaymasterPostOpGasLimit       = 10_000
+)
+
+// Paymaster is the address sponsored user operations are paid by
+var Paymaster = common.HexToAddress("0x0000000000000000000000000000000000000bb0")
+
+// Bundler is a bundler and paymaster of one chain. Sent user operations stay pending
+// until included with IncludeIn.
+type Bundler struct {
+	URL string
+
+	chainID uint64
+
+	mu       sync.Mutex
+	sent     []*userop.UserOperation
+	receipts map[common.Hash]*Receipt
+	block    *types.Header
+	success  bool
+	refuse   error
+}
+
+// New serves a bundler of chainID until t ends
+func New(t testing.TB, chainID uint64) *Bundler {
+	b := &Bundler{chainID: chainID, receipts: make(map[common.Hash]*Receipt)}
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", &bundlerService{b}); err != nil {
+		t.Fatalf("Failed to register bundler: %v", err)
+	}
+	if err := server.RegisterName("pm", &paymasterService{b}); err != nil {
+		t.Fatalf("Failed to register paymaster: %v", err)
+	}
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(func() {
+		httpServer.Close()
+		server.Stop()
+	})
+	b.URL = httpServer.URL
+	return b
+}
+
+// IncludeIn includes every user operation sent from now on in block, with success as
+// the outcome of its calls
+func (b *Bundler) IncludeIn(block *types.Header, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.block, b.success = block, success
+}
+
+// Refuse makes the bundler refuse user operations with err, or accept them again when
+// err is nil
+func (b *Bundler) Refuse(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refuse = err
+}
+
+// Sent returns the user operations sent, in order
+func (b *Bundler) Sent() []*userop.UserOperation {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]*userop.UserOperation{}, b.sent...)
+}
+
+// Receipt is a user operation receipt as bundlers return it
+type Receipt struct {
+	UserOpHash    common.Hash    `json:"userOpHash"`
+	Sender        common.Address `json:"sender"`
+	Nonce         *hexutil.Big   `json:"nonce"`
+	Success       bool           `json:"success"`
+	Reason        string         `json:"reason"`
+	ActualGasUsed *hexutil.Big   `json:"actualGasUsed"`
+	ActualGasCost *hexutil.Big   `json:"actualGasCost"`
+	Receipt       struct {
+		TransactionHash common.Hash    `json:"transactionHash"`
+		BlockHash       common.Hash    `json:"blockHash"`
+		BlockNumber     hexutil.Uint64 `json:"blockNumber"`
+	} `json:"receipt"`
+}
+
+type bundlerService struct {
+	b *Bundler
+}
+
+func (s *bundlerService) EstimateUserOperationGas(op userop.UserOperation, entryPoint common.Address) (map[string]hexutil.Uint64, error) {
+	estimate := map[string]hexutil.Uint64{
+		"preVerificationGas":   PreVerificationGas,
+		"verificationGasLimit": VerificationGasLimit,
+		"callGasLimit":         CallGasLimit,
+	}
+	if op.Paymaster != nil {
+		estimate["paymasterVerificationGasLimit"] = PaymasterVerificationGasLimit
+		estimate["paymasterPostOpGasLimit"] = PaymasterPostOpGasLimit
+	}
+	return estimate, nil
+}
+
+func (s *bundlerService) SendUserOperation(op userop.UserOperation, entryPoint common.Address) (common.Hash, error) {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+	if s.b.refuse != nil {
+		return common.Hash{}, s.b.refuse
+	}
+	if len(op.Signature) != 65 {
+		return common.Hash{}, fmt.Errorf("invalid signature length %d", len(op.Signature))
+	}
+	hash := op.Hash(entryPoint, s.b.chainID)
+	s.b.sent = append(s.b.sent, &op)
+	if block := s.b.block; block != nil {
+		receipt := &Receipt{
+			UserOpHash:    hash,
+			Sender:        op.Sender,
+			Nonce:         (*hexutil.Big)(op.Nonce),
+			Success:       s.b.success,
+			ActualGasUsed: (*hexutil.Big)(hexutil.MustDecodeBig("0x30d40")),
+			ActualGasCost: (*hexutil.Big)(hexutil.MustDecodeBig("0x38d7ea4c68000")),
+		}
+		if !s.b.success {
+			receipt.Reason = "execution reverted"
+		}
+		receipt.Receipt.TransactionHash = crypto.Keccak256Hash(hash.Bytes())
+		receipt.Receipt.BlockHash = block.Hash()
+		receipt.Receipt.BlockNumber = hexutil.Uint64(block.Number.Uint64())
+		s.b.receipts[hash] = receipt
+	}
+	return hash, nil
+}
+
+func (s *bundlerService) GetUserOperationReceipt(hash common.Hash) (*Receipt, error) {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+	return s.b.receipts[hash], nil
+}
+
+type paymasterService struct {
+	b *Bundler
+}
+
+type sponsorship struct {
+	Paymaster                     common.Address  `json:"paymaster"`
+	PaymasterData                 hexutil.Bytes   `json:"paymasterData"`
+	PaymasterVerificationGasLimit *hexutil.Uint64 `json:"paymasterVerificationGasLimit,omitempty"`
+	PaymasterPostOpGasLimit       *hexutil.Uint64 `json:"paymasterPostOpGasLimit,omitempty"`
+}
+
+func (s *paymasterService) GetPaymasterStubData(op userop.UserOperation, entryPoint common.Address, chainID hexutil.Uint64, context map[string]string) (*sponsorship, error) {
+	if uint64(chainID) != s.b.chainID {
+		return nil, fmt.Errorf("unsupported chain %d", chainID)
+	}
+	verification, postOp := hexutil.Uint64(PaymasterVerificationGasLimit), hexutil.Uint64(PaymasterPostOpGasLimit)
+	return &sponsorship{Paymaster: Paymaster, PaymasterData: []byte{}, PaymasterVerificationGasLimit: &verification, PaymasterPostOpGasLimit: &postOp}, nil
+}
+
+// GetPaymasterData sponsors every operation, with the policy of context as paymaster data
+func (s *paymasterService) GetPaymasterData(op userop.UserOperation, entryPoint common.Address, chainID hexutil.Uint64, context map[string]string) (*sponsorship, error) {
+	if uint64(chainID) != s.b.chainID {
+		return nil, fmt.Errorf("unsupported chain %d", chainID)
+	}
+	return &sponsorship{Paymaster: Paymaster, PaymasterData: []byte(context["policy"])}, nil
+}
@@ -0,0 +1,611 @@
+// Package userop executes calls through ERC-4337 smart accounts. The calls of a batch
+// are wrapped in one user operation of the account, signed by the performer account as
+// an owner of it, sponsored by an ERC-7677 paymaster when one is configured for the
+// chain and sent to the bundler of the chain. User operations target the v0.7
+// EntryPoint, and accounts must expose executeBatch(address[],uint256[],bytes[]) as the
+// SimpleAccount reference implementation does and accept EIP-191 signatures of the user
+// operation hash by their owner.
+package userop
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/resilience"
+	"github.com/najnomics/crosscow-avs/pkg/signer"
+)
+
+// EntryPointV07 is the canonical address of the v0.7 EntryPoint on every chain
+const EntryPointV07 = "0x0000000071727De22E5E9d8BAf0edAc6f37da032"
+
+// ErrNotConfirmed is returned when waiting ends before a user operation is final
+var ErrNotConfirmed = errors.New("user operation not confirmed")
+
+// Config configures the bundlers and paymasters user operations go through
+type Config struct {
+	Enabled bool `yaml:"enabled"`
+
+	// EntryPoint is the v0.7 EntryPoint user operations are sent to
+	EntryPoint string `yaml:"entryPoint"`
+
+	// Bundlers are the bundler RPC endpoints per chain id. Chains without one do not
+	// take user operations.
+	Bundlers map[uint64]string `yaml:"bundlers"`
+
+	// Paymasters sponsor the gas of user operations per chain id. Accounts on chains
+	// without one pay their own gas.
+	Paymasters map[uint64]PaymasterConfig `yaml:"paymasters"`
+
+	// Confirmations is the number of blocks, the including block counted, a user
+	// operation needs before it is final
+	Confirmations uint64 `yaml:"confirmations"`
+
+	// PollInterval is how often receipts are checked while waiting
+	PollInterval time.Duration `yaml:"pollInterval"`
+}
+
+// PaymasterConfig is an ERC-7677 paymaster service. Context is passed to it as is, such
+// as the sponsorship policy the operations are charged to.
+type PaymasterConfig struct {
+	Url     string            `yaml:"url"`
+	Context map[string]string `yaml:"context"`
+}
+
+// DefaultConfig sends to the v0.7 EntryPoint once enabled
+func DefaultConfig() Config {
+	return Config{
+		EntryPoint:    EntryPointV07,
+		Confirmations: 3,
+		PollInterval:  2 * time.Second,
+	}
+}
+
+// Validate checks the config for values user operations cannot be sent with
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if !common.IsHexAddress(c.EntryPoint) {
+		return fmt.Errorf("invalid entryPoint address %q", c.EntryPoint)
+	}
+	if len(c.Bundlers) == 0 {
+		return fmt.Errorf("bundlers must not be empty")
+	}
+	for chainID, url := range c.Bundlers {
+		if url == "" {
+			return fmt.Errorf("bundler of chain %d must have a url", chainID)
+		}
+	}
+	for chainID, paymaster := range c.Paymasters {
+		if paymaster.Url == "" {
+			return fmt.Errorf("paymaster of chain %d must have a url", chainID)
+		}
+		if _, ok := c.Bundlers[chainID]; !ok {
+			return fmt.Errorf("paymaster of chain %d has no bundler", chainID)
+		}
+	}
+	if c.Confirmations == 0 {
+		return fmt.Errorf("confirmations must be at least 1")
+	}
+	if c.PollInterval <= 0 {
+		return fmt.Errorf("pollInterval must be positive")
+	}
+	return nil
+}
+
+// Signers returns the signer owning the accounts user operations are sent from on a
+// chain. txmgr.Set is one.
+type Signers interface {
+	Signer(chainID uint64) signer.Signer
+}
+
+// Backend sends user operations of smart accounts to the bundlers of their chains
+type Backend struct {
+	cfg        Config
+	entryPoint common.Address
+	chains     *chain.Manager
+	signers    Signers
+	policy     *resilience.Policy
+
+	mu      sync.Mutex
+	clients map[string]*rpc.Client
+}
+
+// New creates a backend signing user operations with signers. Requests to bundlers and
+// paymasters are retried under policy, which may be nil.
+func New(cfg Config, chains *chain.Manager, signers Signers, policy *resilience.Policy) *Backend {
+	defaults := DefaultConfig()
+	if cfg.EntryPoint == "" {
+		cfg.EntryPoint = defaults.EntryPoint
+	}
+	if cfg.Confirmations == 0 {
+		cfg.Confirmations = defaults.Confirmations
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaults.PollInterval
+	}
+	return &Backend{
+		cfg:        cfg,
+		entryPoint: common.HexToAddress(cfg.EntryPoint),
+		chains:     chains,
+		signers:    signers,
+		policy:     policy,
+		clients:    make(map[string]*rpc.Client),
+	}
+}
+
+// NewFromConfig creates the backend of cfg, or returns nil when user operations are
+// disabled
+func NewFromConfig(cfg Config, chains *chain.Manager, signers Signers, policy *resilience.Policy) *Backend {
+	if !cfg.Enabled {
+		return nil
+	}
+	return New(cfg, chains, signers, policy)
+}
+
+// Supports reports whether user operations can be sent on chainID
+func (b *Backend) Supports(chainID uint64) bool {
+	_, ok := b.cfg.Bundlers[chainID]
+	return ok
+}
+
+// Sent is a user operation accepted by a bundler
+type Sent struct {
+	Hash  common.Hash
+	Nonce *big.Int
+
+	// GasLimit is the total gas the operation may use, verification included
+	GasLimit uint64
+}
+
+// Send wraps calls in one user operation of account on chainID and sends it to the
+// bundler of the chain. The calls are executed in order and revert together.
+func (b *Backend) Send(ctx context.Context, chainID uint64, account common.Address, calls []chain.Call) (*Sent, error) {
+	bundlerURL, ok := b.cfg.Bundlers[chainID]
+	if !ok {
+		return nil, fmt.Errorf("no bundler on chain %d", chainID)
+	}
+	client, err := b.chains.Client(chainID)
+	if err != nil {
+		return nil, err
+	}
+	callData, err := ExecuteBatchCall(calls)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := chain.CallUint(ctx, client, b.entryPoint, entryPointABI, "getNonce", account, new(big.Int))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the nonce of %s: %w", account.Hex(), err)
+	}
+	tip, err := client.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read gas tip of chain %d: %w", chainID, err)
+	}
+	head, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read head of chain %d: %w", chainID, err)
+	}
+	maxFee := new(big.Int).Set(tip)
+	if head.BaseFee != nil {
+		maxFee.Add(maxFee, new(big.Int).Mul(head.BaseFee, big.NewInt(2)))
+	}
+
+	op := &UserOperation{
+		Sender:               account,
+		Nonce:                nonce,
+		CallData:             callData,
+		MaxFeePerGas:         maxFee,
+		MaxPriorityFeePerGas: tip,
+		Signature:            dummySignature,
+	}
+	paymaster, sponsored := b.cfg.Paymasters[chainID]
+	if sponsored {
+		if err := b.sponsor(ctx, chainID, paymaster, op, "pm_getPaymasterStubData"); err != nil {
+			return nil, err
+		}
+	}
+	var estimate gasEstimate
+	if err := b.call(ctx, bundlerURL, &estimate, "eth_estimateUserOperationGas", op, b.entryPoint); err != nil {
+		return nil, fmt.Errorf("failed to estimate user operation gas on chain %d: %w", chainID, err)
+	}
+	op.CallGasLimit = uint64(estimate.CallGasLimit)
+	op.VerificationGasLimit = uint64(estimate.VerificationGasLimit)
+	op.PreVerificationGas = uint64(estimate.PreVerificationGas)
+	if estimate.PaymasterVerificationGasLimit != nil {
+		op.PaymasterVerificationGasLimit = uint64(*estimate.PaymasterVerificationGasLimit)
+	}
+	if estimate.PaymasterPostOpGasLimit != nil {
+		op.PaymasterPostOpGasLimit = uint64(*estimate.PaymasterPostOpGasLimit)
+	}
+	if sponsored {
+		if err := b.sponsor(ctx, chainID, paymaster, op, "pm_getPaymasterData"); err != nil {
+			return nil, err
+		}
+	}
+
+	hash := op.Hash(b.entryPoint, chainID)
+	sig, err := b.signers.Signer(chainID).SignMessage(ctx, hash.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign user operation: %w", err)
+	}
+	sig[64] += 27
+	op.Signature = sig
+
+	var accepted common.Hash
+	if err := b.call(ctx, bundlerURL, &accepted, "eth_sendUserOperation", op, b.entryPoint); err != nil {
+		return nil, fmt.Errorf("bundler of chain %d refused user operation: %w", chainID, err)
+	}
+	if accepted != hash {
+		return nil, fmt.Errorf("bundler of chain %d accepted user operation %s, expected %s", chainID, accepted.Hex(), hash.Hex())
+	}
+	return &Sent{Hash: hash, Nonce: nonce, GasLimit: op.gasLimit()}, nil
+}
+
+// sponsor asks the paymaster of chainID for the paymaster fields of op with method,
+// pm_getPaymasterStubData before gas is estimated and pm_getPaymasterData after
+func (b *Backend) sponsor(ctx context.Context, chainID uint64, paymaster PaymasterConfig, op *UserOperation, method string) error {
+	var sponsorship struct {
+		Paymaster                     common.Address  `json:"paymaster"`
+		PaymasterData                 hexutil.Bytes   `json:"paymasterData"`
+		PaymasterVerificationGasLimit *hexutil.Uint64 `json:"paymasterVerificationGasLimit"`
+		PaymasterPostOpGasLimit       *hexutil.Uint64 `json:"paymasterPostOpGasLimit"`
+	}
+	policy := paymaster.Context
+	if policy == nil {
+		policy = map[string]string{}
+	}
+	if err := b.call(ctx, paymaster.Url, &sponsorship, method, op, b.entryPoint, hexutil.Uint64(chainID), policy); err != nil {
+		return fmt.Errorf("paymaster of chain %d refused to sponsor user operation: %w", chainID, err)
+	}
+	op.Paymaster = &sponsorship.Paymaster
+	op.PaymasterData = sponsorship.PaymasterData
+	if sponsorship.PaymasterVerificationGasLimit != nil {
+		op.PaymasterVerificationGasLimit = uint64(*sponsorship.PaymasterVerificationGasLimit)
+	}
+	if sponsorship.PaymasterPostOpGasLimit != nil {
+		op.PaymasterPostOpGasLimit = uint64(*sponsorship.PaymasterPostOpGasLimit)
+	}
+	return nil
+}
+
+// Receipt is the outcome of a user operation included on chain
+type Receipt struct {
+	Success         bool
+	Reason          string
+	ActualGasUsed   *big.Int
+	ActualGasCost   *big.Int
+	TransactionHash common.Hash
+	BlockHash       common.Hash
+	BlockNumber     uint64
+}
+
+// Confirmation is the state of a sent user operation
+type Confirmation struct {
+	// Receipt is nil while the operation is not in a canonical block
+	Receipt       *Receipt
+	Confirmations uint64
+
+	// Final is set once the operation has the configured confirmations
+	Final bool
+}
+
+// Reverted reports whether the calls of a final operation reverted
+func (c *Confirmation) Reverted() bool {
+	return c.Final && !c.Receipt.Success
+}
+
+// Confirm waits until the user operation hash on chainID is final, polling its receipt.
+// Receipts are checked against the canonical block at their height, so an operation
+// reorged out goes back to pending until it is included again. The latest state is
+// returned with ErrNotConfirmed when waiting ends early.
+func (b *Backend) Confirm(ctx context.Context, chainID uint64, hash common.Hash) (*Confirmation, error) {
+	state := &Confirmation{}
+	for {
+		err := b.check(ctx, chainID, hash, state)
+		if err == nil && state.Final {
+			return state, nil
+		}
+		select {
+		case <-ctx.Done():
+			if err == nil {
+				err = ctx.Err()
+			}
+			return state, fmt.Errorf("%w: %s: %w", ErrNotConfirmed, hash.Hex(), err)
+		case <-time.After(b.cfg.PollInterval):
+		}
+	}
+}
+
+// check updates state with the receipt of hash
+func (b *Backend) check(ctx context.Context, chainID uint64, hash common.Hash, state *Confirmation) error {
+	bundlerURL, ok := b.cfg.Bundlers[chainID]
+	if !ok {
+		return fmt.Errorf("no bundler on chain %d", chainID)
+	}
+	var found *receiptJSON
+	if err := b.call(ctx, bundlerURL, &found, "eth_getUserOperationReceipt", hash); err != nil {
+		return fmt.Errorf("failed to read user operation receipt: %w", err)
+	}
+	state.Receipt, state.Confirmations, state.Final = nil, 0, false
+	if found == nil {
+		return nil
+	}
+	client, err := b.chains.Client(chainID)
+	if err != nil {
+		return err
+	}
+	number := uint64(found.Receipt.BlockNumber)
+	header, err := client.HeaderByNumber(ctx, new(big.Int).SetUint64(number))
+	if err != nil {
+		return fmt.Errorf("failed to read block %d: %w", number, err)
+	}
+	if header.Hash() != found.Receipt.BlockHash {
+		return nil
+	}
+	head, err := client.BlockNumber(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read head of chain %d: %w", chainID, err)
+	}
+	state.Receipt = &Receipt{
+		Success:         found.Success,
+		Reason:          found.Reason,
+		ActualGasUsed:   found.ActualGasUsed.ToInt(),
+		ActualGasCost:   found.ActualGasCost.ToInt(),
+		TransactionHash: found.Receipt.TransactionHash,
+		BlockHash:       found.Receipt.BlockHash,
+		BlockNumber:     number,
+	}
+	if head >= number {
+		state.Confirmations = head - number + 1
+	}
+	state.Final = state.Confirmations >= b.cfg.Confirmations
+	return nil
+}
+
+type gasEstimate struct {
+	PreVerificationGas            hexutil.Uint64  `json:"preVerificationGas"`
+	VerificationGasLimit          hexutil.Uint64  `json:"verificationGasLimit"`
+	CallGasLimit                  hexutil.Uint64  `json:"callGasLimit"`
+	PaymasterVerificationGasLimit *hexutil.Uint64 `json:"paymasterVerificationGasLimit"`
+	PaymasterPostOpGasLimit       *hexutil.Uint64 `json:"paymasterPostOpGasLimit"`
+}
+
+type receiptJSON struct {
+	Success       bool         `json:"success"`
+	Reason        string       `json:"reason"`
+	ActualGasUsed *hexutil.Big `json:"actualGasUsed"`
+	ActualGasCost *hexutil.Big `json:"actualGasCost"`
+	Receipt       struct {
+		TransactionHash common.Hash    `json:"transactionHash"`
+		BlockHash       common.Hash    `json:"blockHash"`
+		BlockNumber     hexutil.Uint64 `json:"blockNumber"`
+	} `json:"receipt"`
+}
+
+// call sends a JSON-RPC request to url under the policy of the backend
+func (b *Backend) call(ctx context.Context, url string, result interface{}, method string, args ...interface{}) error {
+	return b.policy.Do(ctx, url, func(ctx context.Context) error {
+		client, err := b.client(ctx, url)
+		if err != nil {
+			return err
+		}
+		return client.CallContext(ctx, result, method, args...)
+	})
+}
+
+func (b *Backend) client(ctx context.Context, url string) (*rpc.Client, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if client, ok := b.clients[url]; ok {
+		return client, nil
+	}
+	client, err := rpc.DialContext(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial %s: %w", url, err)
+	}
+	b.clients[url] = client
+	return client, nil
+}
+
+// UserOperation is a v0.7 user operation in the unpacked form bundlers take over RPC.
+// Accounts must be deployed: operations carry no factory.
+type UserOperation struct {
+	Sender               common.Address
+	Nonce                *big.Int
+	CallData             []byte
+	CallGasLimit         uint64
+	VerificationGasLimit uint64
+	PreVerificationGas   uint64
+	MaxFeePerGas         *big.Int
+	MaxPriorityFeePerGas *big.Int
+
+	// Paymaster is nil when the account pays its own gas
+	Paymaster                     *common.Address
+	PaymasterVerificationGasLimit uint64
+	PaymasterPostOpGasLimit       uint64
+	PaymasterData                 []byte
+
+	Signature []byte
+}
+
+type userOperationJSON struct {
+	Sender                        common.Address  `json:"sender"`
+	Nonce                         *hexutil.Big    `json:"nonce"`
+	CallData                      hexutil.Bytes   `json:"callData"`
+	CallGasLimit                  hexutil.Uint64  `json:"callGasLimit"`
+	VerificationGasLimit          hexutil.Uint64  `json:"verificationGasLimit"`
+	PreVerificationGas            hexutil.Uint64  `json:"preVerificationGas"`
+	MaxFeePerGas                  *hexutil.Big    `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas          *hexutil.Big    `json:"maxPriorityFeePerGas"`
+	Paymaster                     *common.Address `json:"paymaster,omitempty"`
+	PaymasterVerificationGasLimit *hexutil.Uint64 `json:"paymasterVerificationGasLimit,omitempty"`
+	PaymasterPostOpGasLimit       *hexutil.Uint64 `json:"paymasterPostOpGasLimit,omitempty"`
+	PaymasterData                 hexutil.Bytes   `json:"paymasterData,omitempty"`
+	Signature                     hexutil.Bytes   `json:"signature"`
+}
+
+// MarshalJSON encodes op as bundlers take it
+func (op *UserOperation) MarshalJSON() ([]byte, error) {
+	encoded := userOperationJSON{
+		Sender:               op.Sender,
+		Nonce:                (*hexutil.Big)(op.Nonce),
+		CallData:             op.CallData,
+		CallGasLimit:         hexutil.Uint64(op.CallGasLimit),
+		VerificationGasLimit: hexutil.Uint64(op.VerificationGasLimit),
+		PreVerificationGas:   hexutil.Uint64(op.PreVerificationGas),
+		MaxFeePerGas:         (*hexutil.Big)(op.MaxFeePerGas),
+		MaxPriorityFeePerGas: (*hexutil.Big)(op.MaxPriorityFeePerGas),
+		Signature:            op.Signature,
+	}
+	if op.Paymaster != nil {
+		verification, postOp := hexutil.Uint64(op.PaymasterVerificationGasLimit), hexutil.Uint64(op.PaymasterPostOpGasLimit)
+		encoded.Paymaster = op.Paymaster
+		encoded.PaymasterVerificationGasLimit = &verification
+		encoded.PaymasterPostOpGasLimit = &postOp
+		encoded.PaymasterData = op.PaymasterData
+		if encoded.PaymasterData == nil {
+			encoded.PaymasterData = []byte{}
+		}
+	}
+	return json.Marshal(encoded)
+}
+
+// UnmarshalJSON decodes op as bundlers take it
+func (op *UserOperation) UnmarshalJSON(data []byte) error {
+	var decoded userOperationJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*op = UserOperation{
+		Sender:               decoded.Sender,
+		Nonce:                decoded.Nonce.ToInt(),
+		CallData:             decoded.CallData,
+		CallGasLimit:         uint64(decoded.CallGasLimit),
+		VerificationGasLimit: uint64(decoded.VerificationGasLimit),
+		PreVerificationGas:   uint64(decoded.PreVerificationGas),
+		MaxFeePerGas:         decoded.MaxFeePerGas.ToInt(),
+		MaxPriorityFeePerGas: decoded.MaxPriorityFeePerGas.ToInt(),
+		Paymaster:            decoded.Paymaster,
+		PaymasterData:        decoded.PaymasterData,
+		Signature:            decoded.Signature,
+	}
+	if decoded.PaymasterVerificationGasLimit != nil {
+		op.PaymasterVerificationGasLimit = uint64(*decoded.PaymasterVerificationGasLimit)
+	}
+	if decoded.PaymasterPostOpGasLimit != nil {
+		op.PaymasterPostOpGasLimit = uint64(*decoded.PaymasterPostOpGasLimit)
+	}
+	return nil
+}
+
+// gasLimit is the total gas op may use
+func (op *UserOperation) gasLimit() uint64 {
+	return op.CallGasLimit + op.VerificationGasLimit + op.PreVerificationGas + op.PaymasterVerificationGasLimit + op.PaymasterPostOpGasLimit
+}
+
+// paymasterAndData packs the paymaster fields as the EntryPoint hashes them
+func (op *UserOperation) paymasterAndData() []byte {
+	if op.Paymaster == nil {
+		return nil
+	}
+	packed := append([]byte{}, op.Paymaster.Bytes()...)
+	packed = append(packed, packUints(gasUint(op.PaymasterVerificationGasLimit), gasUint(op.PaymasterPostOpGasLimit))...)
+	return append(packed, op.PaymasterData...)
+}
+
+// Hash is the hash of op the account verifies the signature of, as the v0.7 EntryPoint
+// at entryPoint on chainID computes it
+func (op *UserOperation) Hash(entryPoint common.Address, chainID uint64) common.Hash {
+	packed, err := packedArgs.Pack(
+		op.Sender,
+		op.Nonce,
+		crypto.Keccak256Hash(nil),
+		crypto.Keccak256Hash(op.CallData),
+		[32]byte(packUints(gasUint(op.VerificationGasLimit), gasUint(op.CallGasLimit))),
+		new(big.Int).SetUint64(op.PreVerificationGas),
+		[32]byte(packUints(op.MaxPriorityFeePerGas, op.MaxFeePerGas)),
+		crypto.Keccak256Hash(op.paymasterAndData()),
+	)
+	if err != nil {
+		panic(fmt.Sprintf("failed to pack user operation: %v", err))
+	}
+	encoded, err := hashArgs.Pack(crypto.Keccak256Hash(packed), entryPoint, new(big.Int).SetUint64(chainID))
+	if err != nil {
+		panic(fmt.Sprintf("failed to pack user operation hash: %v", err))
+	}
+	return crypto.Keccak256Hash(encoded)
+}
+
+// packUints packs high and low into 16 bytes each, as v0.7 packs gas limits and fees
+func packUints(high, low *big.Int) []byte {
+	packed := make([]byte, 32)
+	high.FillBytes(packed[:16])
+	low.FillBytes(packed[16:])
+	return packed
+}
+
+func gasUint(gas uint64) *big.Int {
+	return new(big.Int).SetUint64(gas)
+}
+
+// ExecuteBatchCall builds the account call executing calls in order
+func ExecuteBatchCall(calls []chain.Call) ([]byte, error) {
+	targets := make([]common.Address, len(calls))
+	values := make([]*big.Int, len(calls))
+	data := make([][]byte, len(calls))
+	for i, call := range calls {
+		targets[i], values[i], data[i] = call.To, new(big.Int), call.Data
+	}
+	packed, err := accountABI.Pack("executeBatch", targets, values, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack executeBatch: %w", err)
+	}
+	return packed, nil
+}
+
+// dummySignature has the shape of an ECDSA signature, so accounts validate it in gas
+// estimates without recovering the owner
+var dummySignature = hexutil.MustDecode("0xfffffffffffffffffffffffffffffff0000000000000000000000000000000007aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa1c")
+
+const entryPointABIJson = `[
+	{"name":"getNonce","type":"function","stateMutability":"view",
+	 "inputs":[{"name":"sender","type":"address"},{"name":"key","type":"uint192"}],
+	 "outputs":[{"name":"nonce","type":"uint256"}]}
+]`
+
+const accountABIJson = `[
+	{"name":"executeBatch","type":"function","stateMutability":"nonpayable",
+	 "inputs":[{"name":"dest","type":"address[]"},{"name":"value","type":"uint256[]"},{"name":"func","type":"bytes[]"}],
+	 "outputs":[]}
+]`
+
+var (
+	entryPointABI = chain.MustParseABI(entryPointABIJson)
+	accountABI    = chain.MustParseABI(accountABIJson)
+
+	packedArgs = mustArguments("address", "uint256", "bytes32", "bytes32", "bytes32", "uint256", "bytes32", "bytes32")
+	hashArgs   = mustArguments("bytes32", "address", "uint256")
+)
+
+func mustArguments(types ...string) abi.Arguments {
+	args := make(abi.Arguments, len(types))
+	for i, name := range types {
+		t, err := abi.NewType(name, "", nil)
+		if err != nil {
+			panic(fmt.Sprintf("invalid abi type %s: %v", name, err))
+		}
+		args[i] = abi.Argument{Type: t}
+	}
+	return args
+}
@@ -0,0 +1,181 @@
+package userop_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/najnomics/crosscow-avs/pkg/chain"
+	"github.com/najnomics/crosscow-avs/pkg/chain/chaintest"
+	"github.com/najnomics/crosscow-avs/pkg/signer"
+	"github.com/najnomics/crosscow-avs/pkg/userop"
+	"github.com/najnomics/crosscow-avs/pkg/userop/bundlertest"
+)
+
+var entryPointABI = chain.MustParseABI(`[{"name":"getNonce","type":"function","stateMutability":"view",
+	"inputs":[{"name":"sender","type":"address"},{"name":"key","type":"uint192"}],"outputs":[{"name":"nonce","type":"uint256"}]}]`)
+
+// owners signs on every chain with owner
+type owners struct {
+	owner signer.Signer
+}
+
+func (o owners) Signer(chainID uint64) signer.Signer {
+	return o.owner
+}
+
+func newBackend(t *testing.T, sponsored bool) (*userop.Backend, *bundlertest.Bundler, *chaintest.Contracts, signer.Signer) {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	owner := signer.NewKeySigner(key)
+	contracts := chaintest.NewContracts(1)
+	contracts.Stub(t, common.HexToAddress(userop.EntryPointV07), entryPointABI, "getNonce", big.NewInt(7))
+	chains := chain.NewManager()
+	chains.Register(1, "ethereum", contracts)
+
+	bundler := bundlertest.New(t, 1)
+	cfg := userop.DefaultConfig()
+	cfg.Enabled, cfg.Confirmations, cfg.PollInterval = true, 1, time.Millisecond
+	cfg.Bundlers = map[uint64]string{1: bundler.URL}
+	if sponsored {
+		cfg.Paymasters = map[uint64]userop.PaymasterConfig{1: {Url: bundler.URL, Context: map[string]string{"policy": "sp_rebalance"}}}
+	}
+	return userop.New(cfg, chains, owners{owner}, nil), bundler, contracts, owner
+}
+
+func Test_Send(t *testing.T) {
+	for _, sponsored := range []bool{false, true} {
+		backend, bundler, _, owner := newBackend(t, sponsored)
+		account := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+		calls := []chain.Call{
+			{To: common.HexToAddress("0x10"), Data: []byte{0x01}},
+			{To: common.HexToAddress("0x20"), Data: []byte{0x02}},
+		}
+		sent, err := backend.Send(context.Background(), 1, account, calls)
+		if err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+		ops := bundler.Sent()
+		if len(ops) != 1 {
+			t.Fatalf("Expected one user operation, got %d", len(ops))
+		}
+		op := ops[0]
+		if op.Sender != account || op.Nonce.Int64() != 7 || sent.Nonce.Int64() != 7 || op.CallGasLimit != bundlertest.CallGasLimit {
+			t.Errorf("Unexpected user operation %+v", op)
+		}
+		batch, err := userop.ExecuteBatchCall(calls)
+		if err != nil || !bytes.Equal(op.CallData, batch) {
+			t.Errorf("Expected the calls to be batched (%v)", err)
+		}
+
+		hash := op.Hash(common.HexToAddress(userop.EntryPointV07), 1)
+		if sent.Hash != hash {
+			t.Errorf("Expected hash %s, got %s", hash.Hex(), sent.Hash.Hex())
+		}
+		// accounts verify an EIP-191 signature of the hash by their owner
+		sig := append([]byte{}, op.Signature...)
+		sig[64] -= 27
+		pub, err := crypto.SigToPub(accounts.TextHash(hash.Bytes()), sig)
+		if err != nil || crypto.PubkeyToAddress(*pub) != owner.Address() {
+			t.Errorf("Expected the operation to be signed by the owner (%v)", err)
+		}
+
+		gas := uint64(bundlertest.PreVerificationGas + bundlertest.VerificationGasLimit + bundlertest.CallGasLimit)
+		if sponsored {
+			gas += bundlertest.PaymasterVerificationGasLimit + bundlertest.PaymasterPostOpGasLimit
+			if op.Paymaster == nil || *op.Paymaster != bundlertest.Paymaster || string(op.PaymasterData) != "sp_rebalance" {
+				t.Errorf("Expected the operation to be sponsored, got %+v", op)
+			}
+		} else if op.Paymaster != nil {
+			t.Errorf("Expected the account to pay its own gas, got paymaster %s", op.Paymaster.Hex())
+		}
+		if sent.GasLimit != gas {
+			t.Errorf("Expected a gas limit of %d, got %d", gas, sent.GasLimit)
+		}
+	}
+}
+
+func Test_SendRefused(t *testing.T) {
+	backend, bundler, _, _ := newBackend(t, false)
+	bundler.Refuse(errors.New("AA21 didn't pay prefund"))
+	if _, err := backend.Send(context.Background(), 1, common.HexToAddress("0xaa"), []chain.Call{{To: common.HexToAddress("0x10")}}); err == nil {
+		t.Errorf("Expected the refused operation to fail")
+	}
+	if _, err := backend.Send(context.Background(), 10, common.HexToAddress("0xaa"), []chain.Call{{To: common.HexToAddress("0x10")}}); err == nil {
+		t.Errorf("Expected chains without bundler to be refused")
+	}
+}
+
+func Test_Confirm(t *testing.T) {
+	backend, bundler, contracts, _ := newBackend(t, false)
+	ctx := context.Background()
+	call := []chain.Call{{To: common.HexToAddress("0x10")}}
+
+	// operations not included yet are reported pending
+	pending, err := backend.Send(ctx, 1, common.HexToAddress("0xaa"), call)
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	waiting, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	confirmation, err := backend.Confirm(waiting, 1, pending.Hash)
+	if !errors.Is(err, userop.ErrNotConfirmed) || confirmation.Receipt != nil || confirmation.Final {
+		t.Errorf("Expected a pending operation, got %+v (%v)", confirmation, err)
+	}
+
+	contracts.SetHead(20, 1_700_000_000)
+	block, err := contracts.HeaderByNumber(ctx, big.NewInt(20))
+	if err != nil {
+		t.Fatalf("HeaderByNumber failed: %v", err)
+	}
+	for _, success := range []bool{true, false} {
+		bundler.IncludeIn(block, success)
+		sent, err := backend.Send(ctx, 1, common.HexToAddress("0xaa"), call)
+		if err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+		confirmation, err := backend.Confirm(ctx, 1, sent.Hash)
+		if err != nil {
+			t.Fatalf("Confirm failed: %v", err)
+		}
+		if !confirmation.Final || confirmation.Confirmations != 1 || confirmation.Receipt.BlockNumber != 20 ||
+			confirmation.Receipt.ActualGasUsed.Int64() != 200_000 || confirmation.Reverted() == success {
+			t.Errorf("Unexpected confirmation %+v", confirmation)
+		}
+	}
+
+	// a receipt of a block reorged out is not final
+	contracts.Reorg(20)
+	confirmation, err = backend.Confirm(waiting, 1, bundler.Sent()[1].Hash(common.HexToAddress(userop.EntryPointV07), 1))
+	if err == nil || confirmation.Receipt != nil {
+		t.Errorf("Expected the reorged operation to be pending, got %+v", confirmation)
+	}
+}
+
+func Test_ConfigValidate(t *testing.T) {
+	cfg := userop.DefaultConfig()
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected the disabled config to be valid: %v", err)
+	}
+	cfg.Enabled = true
+	if err := cfg.Validate(); err == nil {
+		t.Errorf("Expected missing bundlers to be rejected")
+	}
+	cfg.Bundlers = map[uint64]string{1: "https://bundler.example.com"}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected the config to be valid: %v", err)
+	}
+	cfg.Paymasters = map[uint64]userop.PaymasterConfig{8453: {Url: "https://paymaster.example.com"}}
+	if err := cfg.Validate(); err == nil {
+		t.Errorf("Expected a paymaster without bundler to be rejected")
+	}
+}
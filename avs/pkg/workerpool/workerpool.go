@@ -0,0 +1,58 @@
+// Package workerpool runs independent jobs with bounded parallelism and keeps every
+// job's outcome, so one failing protocol or chain does not hide the results of the others.
+package workerpool
+
+import (
+	"context"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// DefaultLimit is the number of jobs run at once when no limit is set
+const DefaultLimit = 8
+
+// Result is the outcome of a single job
+type Result[R any] struct {
+	Value R
+	Err   error
+}
+
+// Pool bounds how many jobs run at once. The zero value uses DefaultLimit.
+type Pool struct {
+	limit int
+}
+
+// New creates a pool running at most limit jobs at once. A limit below 1 uses
+// DefaultLimit.
+func New(limit int) *Pool {
+	return &Pool{limit: limit}
+}
+
+// Limit returns the number of jobs the pool runs at once
+func (p *Pool) Limit() int {
+	if p == nil || p.limit < 1 {
+		return DefaultLimit
+	}
+	return p.limit
+}
+
+// Map runs fn for every item and returns the results in the order of items. A failing
+// job does not cancel the others; once ctx is done, jobs that have not started fail with
+// its error.
+func Map[T, R any](ctx context.Context, p *Pool, items []T, fn func(ctx context.Context, item T) (R, error)) []Result[R] {
+	results := make([]Result[R], len(items))
+	var g errgroup.Group
+	g.SetLimit(p.Limit())
+	for i, item := range items {
+		g.Go(func() error {
+			if err := ctx.Err(); err != nil {
+				results[i].Err = err
+				return nil
+			}
+			results[i].Value, results[i].Err = fn(ctx, item)
+			return nil
+		})
+	}
+	_ = g.Wait()
+	return results
+}
@@ -0,0 +1,74 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_MapKeepsOrderAndFailures(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+	failure := errors.New("odd")
+
+	results := Map(context.Background(), New(2), items, func(ctx context.Context, n int) (int, error) {
+		if n%2 == 1 {
+			return 0, failure
+		}
+		return n * n, nil
+	})
+
+	if len(results) != len(items) {
+		t.Fatalf("Expected %d results, got %d", len(items), len(results))
+	}
+	for i, r := range results {
+		if items[i]%2 == 1 && !errors.Is(r.Err, failure) {
+			t.Errorf("Expected item %d to fail, got %v", items[i], r.Err)
+		}
+		if items[i]%2 == 0 && (r.Err != nil || r.Value != items[i]*items[i]) {
+			t.Errorf("Expected item %d to succeed in order, got %+v", items[i], r)
+		}
+	}
+}
+
+func Test_MapBoundsParallelism(t *testing.T) {
+	var running, peak atomic.Int32
+	items := make([]int, 20)
+
+	Map(context.Background(), New(3), items, func(ctx context.Context, _ int) (struct{}, error) {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		running.Add(-1)
+		return struct{}{}, nil
+	})
+
+	if got := peak.Load(); got > 3 {
+		t.Errorf("Expected at most 3 jobs at once, got %d", got)
+	}
+}
+
+func Test_MapStopsStartingJobsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var calls atomic.Int32
+	results := Map(ctx, nil, []int{1, 2, 3}, func(ctx context.Context, _ int) (int, error) {
+		calls.Add(1)
+		return 0, nil
+	})
+	if calls.Load() != 0 {
+		t.Errorf("Expected no jobs to start after cancellation")
+	}
+	for _, r := range results {
+		if !errors.Is(r.Err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", r.Err)
+		}
+	}
+}
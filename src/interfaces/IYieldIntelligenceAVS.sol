@@ -45,7 +45,17 @@ interface IYieldIntelligenceAVS {
         uint256 overallRisk;          // Composite risk score
         string riskCategory;          // Risk category (LOW, MEDIUM, HIGH)
     }
-    
+
+    struct RebalanceInstruction {
+        address user;                 // User whose funds move
+        bytes32 sourceProtocolId;     // Protocol funds leave (zero for wallet funds)
+        bytes32 targetProtocolId;     // Protocol funds are deposited into
+        uint256 sourceChainId;        // Chain funds leave
+        uint256 targetChainId;        // Chain funds arrive on
+        uint256 amount;               // USDC amount (base units)
+        bool executed;                // Whether the rebalance was executed
+    }
+
     /*//////////////////////////////////////////////////////////////
                             VIEW FUNCTIONS
     //////////////////////////////////////////////////////////////*/